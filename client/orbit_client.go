@@ -3,7 +3,9 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -202,6 +205,9 @@ var (
 	// progress (e.g. a network filter dropping packets mid-transfer). It resets
 	// on any received bytes, so slow-but-healthy downloads are unaffected.
 	downloadStallTimeout = 60 * time.Second
+	// cacheProxyDownloadTimeout bounds a software-installer download from a
+	// fleetd caching proxy.
+	cacheProxyDownloadTimeout = 4 * time.Hour
 )
 
 // NewOrbitClient creates a new OrbitClient.
@@ -356,31 +362,55 @@ func (oc *OrbitClient) ExecuteConfigReceivers() error {
 	// Backoff tracker for the config polling loop. See #45553.
 	configBackoff := backoff.New(oc.ReceiverUpdateInterval, maxConfigBackoff)
 
+	// The Fleet server pushes a hint when something changes for this host, so
+	// that the config is fetched right away instead of on the next tick.
+	hints := make(chan struct{}, 1)
+	go oc.runNotifications(oc.receiverUpdateContext, func(hint fleet.OrbitNotificationHint) {
+		log.Debug().Str("hint", string(hint)).Msg("received notification from Fleet")
+		select {
+		case hints <- struct{}{}:
+		default:
+		}
+	})
+
 	for {
 		select {
 		case <-oc.receiverUpdateContext.Done():
 			return nil
-		case <-ticker.C:
-			if err := oc.RunConfigReceivers(); err != nil {
-				configBackoff.RecordFailure()
-				nextRetry := configBackoff.Interval()
-				ticker.Reset(nextRetry)
-				log.Error().Err(err).
-					Str("next_retry", nextRetry.String()).
-					Msg("running config receivers, backing off")
-			} else {
-				if configBackoff.InBackoff() {
-					log.Info().
-						Str("backoff_duration", configBackoff.TimeSinceBackoffStarted().String()).
-						Msg("config receivers succeeded, exiting backoff")
-				}
-				configBackoff.RecordSuccess()
-				ticker.Reset(oc.ReceiverUpdateInterval)
+		case <-hints:
+			if configBackoff.InBackoff() {
+				// the next tick is already scheduled by the backoff
+				continue
 			}
+			oc.expireConfigCache()
+			oc.runConfigReceiversAndReset(ticker, configBackoff)
+		case <-ticker.C:
+			oc.runConfigReceiversAndReset(ticker, configBackoff)
 		}
 	}
 }
 
+// runConfigReceiversAndReset runs the config receivers and resets the ticker
+// of ExecuteConfigReceivers, backing off while they fail.
+func (oc *OrbitClient) runConfigReceiversAndReset(ticker *time.Ticker, configBackoff *backoff.Tracker) {
+	if err := oc.RunConfigReceivers(); err != nil {
+		configBackoff.RecordFailure()
+		nextRetry := configBackoff.Interval()
+		ticker.Reset(nextRetry)
+		log.Error().Err(err).
+			Str("next_retry", nextRetry.String()).
+			Msg("running config receivers, backing off")
+		return
+	}
+	if configBackoff.InBackoff() {
+		log.Info().
+			Str("backoff_duration", configBackoff.TimeSinceBackoffStarted().String()).
+			Msg("config receivers succeeded, exiting backoff")
+	}
+	configBackoff.RecordSuccess()
+	ticker.Reset(oc.ReceiverUpdateInterval)
+}
+
 func (oc *OrbitClient) InterruptConfigReceivers(err error) {
 	oc.receiverUpdateCancelFunc()
 }
@@ -431,6 +461,14 @@ func (oc *OrbitClient) GetConfig() (*fleet.OrbitConfig, error) {
 	return oc.configCache.config, oc.configCache.err
 }
 
+// expireConfigCache makes the next GetConfig call fetch the config from the
+// Fleet server.
+func (oc *OrbitClient) expireConfigCache() {
+	oc.configCache.mu.Lock()
+	defer oc.configCache.mu.Unlock()
+	oc.configCache.lastUpdated = time.Time{}
+}
+
 // SetOrUpdateDeviceToken sends a request to the server to set or update the device token.
 func (oc *OrbitClient) SetOrUpdateDeviceToken(deviceAuthToken string) error {
 	verb, path := "POST", "/api/fleet/orbit/device_token"
@@ -532,6 +570,89 @@ func (oc *OrbitClient) DownloadSoftwareInstallerFromURL(url string, filename str
 	return resp.GetFilePath(), nil
 }
 
+// DownloadSoftwareInstallerFromCacheProxy downloads the software installer
+// with the given SHA256 through the fleetd caching proxy at proxyURL. The
+// request only identifies the installer by its SHA256, the proxy downloads it
+// from Fleet with its own node key. The downloaded file is removed and an
+// error is returned if its SHA256 is not the expected one.
+func (oc *OrbitClient) DownloadSoftwareInstallerFromCacheProxy(proxyURL string, sha256Hex string, downloadDirectory string, progressFunc func(int)) (string, error) {
+	if sha256Hex == "" {
+		return "", errors.New("missing installer SHA256")
+	}
+
+	// The proxy doesn't send any bytes while it downloads an installer it
+	// doesn't have yet from Fleet, so the stall timeout is replaced by a bound
+	// on the whole download.
+	ctx, cancel := context.WithTimeout(context.Background(), cacheProxyDownloadTimeout)
+	defer cancel()
+	verb, downloadURL := "GET", strings.TrimRight(proxyURL, "/")+constant.CacheProxySoftwareInstallerPath
+	request, err := http.NewRequestWithContext(ctx, verb, downloadURL, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set(constant.CacheProxyContentSHA256Header, sha256Hex)
+	response, err := oc.DoHTTPRequest(request)
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %w", verb, downloadURL, err)
+	}
+	defer response.Body.Close()
+
+	resp := FileResponse{
+		DestPath:     downloadDirectory,
+		ProgressFunc: progressFunc,
+	}
+	if err := oc.ParseResponse(verb, downloadURL, response, &resp); err != nil {
+		return "", err
+	}
+	if err := checkFileSHA256(resp.GetFilePath(), sha256Hex); err != nil {
+		_ = os.Remove(resp.GetFilePath())
+		return "", err
+	}
+	return resp.GetFilePath(), nil
+}
+
+func checkFileSHA256(path, sha256Hex string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("hash downloaded file: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(got, sha256Hex) {
+		return fmt.Errorf("downloaded file SHA256 mismatch: expected %s, got %s", sha256Hex, got)
+	}
+	return nil
+}
+
+// DownloadSoftwareInstallerForCacheProxy requests the software installer
+// with the given SHA256 from Fleet, authenticated with the node key of this
+// host, which runs fleetd in caching proxy mode. It returns the response,
+// which the caller must close, so that the installer can be streamed to the
+// cache.
+func (oc *OrbitClient) DownloadSoftwareInstallerForCacheProxy(ctx context.Context, sha256Hex string) (*http.Response, error) {
+	nodeKey, err := oc.getNodeKeyOrEnroll()
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(fleet.OrbitCacheProxyDownloadSoftwareInstallerRequest{
+		OrbitNodeKey: nodeKey,
+		HashSHA256:   sha256Hex,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	request, err := http.NewRequestWithContext(ctx, "POST",
+		oc.URL("/api/fleet/orbit/cache_proxy/software_install/package", "alt=media").String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	return oc.DoHTTPRequest(request)
+}
+
 // NullFileResponse discards downloaded file content.
 type NullFileResponse struct{}
 
@@ -921,6 +1042,16 @@ func (oc *OrbitClient) SendLinuxKeyEscrowResponse(lr luks.LuksResponse) error {
 	return nil
 }
 
+// ReportCacheProxyStats sends the statistics of fleetd in caching proxy mode
+// to the Fleet server.
+func (oc *OrbitClient) ReportCacheProxyStats(stats fleet.CacheProxyStats) error {
+	verb, path := "POST", "/api/fleet/orbit/cache_proxy/stats"
+	var resp fleet.OrbitPostCacheProxyStatsResponse
+	return oc.authenticatedRequest(verb, path, &fleet.OrbitPostCacheProxyStatsRequest{
+		CacheProxyStats: stats,
+	}, &resp)
+}
+
 // SendManagedLocalAccountPassword escrows the password of the managed local admin account that fleetd created on this
 // Windows host. A non-empty clientError reports that creating the account failed, which the server records against the
 // host and which makes it ask this host to try again.
@@ -2,12 +2,20 @@ package client
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -294,3 +302,85 @@ func TestExecuteConfigReceiversResetOnSuccess(t *testing.T) {
 	assert.Less(t, intervalAfterRecovery, 2*time.Second,
 		"after success, interval should reset near base, got %v", intervalAfterRecovery)
 }
+
+func TestDownloadSoftwareInstallerFromCacheProxy(t *testing.T) {
+	const content = "installer content"
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+
+	var served string
+	var gotHash string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != constant.CacheProxySoftwareInstallerPath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// the node key of the host is not sent to the proxy
+		if r.ContentLength > 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		gotHash = r.Header.Get(constant.CacheProxyContentSHA256Header)
+		w.Header().Set("Content-Disposition", `attachment; filename="foo.pkg"`)
+		_, _ = io.WriteString(w, served)
+	}))
+	defer srv.Close()
+
+	oc, err := NewOrbitClient(t.TempDir(), "https://fleet.example.com", "", true, "", nil, fleet.OrbitHostInfo{}, nil, nil, "", false)
+	require.NoError(t, err)
+	oc.TestNodeKey = "test-node-key"
+
+	dir := t.TempDir()
+	served = content
+	path, err := oc.DownloadSoftwareInstallerFromCacheProxy(srv.URL+"/", hash, dir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "foo.pkg"), path)
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, content, string(b))
+	assert.Equal(t, hash, gotHash)
+
+	// content that doesn't match the hash is removed
+	require.NoError(t, os.Remove(path))
+	served = "tampered content"
+	_, err = oc.DownloadSoftwareInstallerFromCacheProxy(srv.URL, hash, dir, nil)
+	require.ErrorContains(t, err, "SHA256 mismatch")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = oc.DownloadSoftwareInstallerFromCacheProxy(srv.URL, "", dir, nil)
+	require.Error(t, err)
+}
+
+func TestDownloadSoftwareInstallerForCacheProxy(t *testing.T) {
+	var gotReq fleet.OrbitCacheProxyDownloadSoftwareInstallerRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/fleet/orbit/cache_proxy/software_install/package" ||
+			r.URL.Query().Get("alt") != "media" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&gotReq); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = io.WriteString(w, "installer content")
+	}))
+	defer srv.Close()
+
+	oc, err := NewOrbitClient(t.TempDir(), srv.URL, "", true, "", nil, fleet.OrbitHostInfo{}, nil, nil, "", false)
+	require.NoError(t, err)
+	oc.TestNodeKey = "proxy-node-key"
+
+	resp, err := oc.DownloadSoftwareInstallerForCacheProxy(t.Context(), "abc123")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	b, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "installer content", string(b))
+	// the installer is requested with the node key of the proxy
+	assert.Equal(t, "proxy-node-key", gotReq.OrbitNodeKey)
+	assert.Equal(t, "abc123", gotReq.HashSHA256)
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/backoff"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// orbitNotificationsPath is the path of the Fleet server's websocket that
+	// pushes notification hints to fleetd.
+	orbitNotificationsPath = "/api/fleet/orbit/notifications/websocket"
+	// notificationsRetryInterval is the initial wait before connecting again to
+	// the notifications websocket, doubled up to maxNotificationsRetryInterval.
+	notificationsRetryInterval    = 5 * time.Second
+	maxNotificationsRetryInterval = 5 * time.Minute
+	// notificationsReadTimeout is how long the connection can stay silent
+	// before it is considered broken. The Fleet server pings it every 25s.
+	notificationsReadTimeout = 90 * time.Second
+	// notificationsHealthyDuration is how long a connection must last to reset
+	// the retry backoff.
+	notificationsHealthyDuration = time.Minute
+)
+
+// notificationMessage is a message received on the notifications websocket.
+type notificationMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// notificationsSupported returns true if fleetd can connect to the
+// notifications websocket of the Fleet server.
+func (oc *OrbitClient) notificationsSupported() bool {
+	// The websocket handshake is not signed with the host identity
+	// certificate, so hosts that use one keep relying on polling.
+	return oc.BaseClient != nil &&
+		oc.hostIdentityCertPath == "" &&
+		oc.GetServerCapabilities().Has(fleet.CapabilityOrbitNotifications)
+}
+
+// runNotifications keeps fleetd connected to the notifications websocket of
+// the Fleet server until ctx is done, calling onHint for every hint received.
+// It connects again with a backoff when the connection fails, config polling
+// being the fallback in the meantime.
+func (oc *OrbitClient) runNotifications(ctx context.Context, onHint func(fleet.OrbitNotificationHint)) {
+	retryBackoff := backoff.New(notificationsRetryInterval, maxNotificationsRetryInterval)
+	for {
+		// check again later when the server does not support notifications, as
+		// it may be upgraded in the meantime
+		wait := maxNotificationsRetryInterval
+		if oc.notificationsSupported() {
+			start := time.Now()
+			err := oc.receiveNotifications(ctx, onHint)
+			if ctx.Err() != nil {
+				return
+			}
+			if time.Since(start) >= notificationsHealthyDuration {
+				retryBackoff.RecordSuccess()
+			}
+			retryBackoff.RecordFailure()
+			wait = retryBackoff.Interval()
+			log.Debug().Err(err).
+				Str("next_retry", wait.String()).
+				Msg("notifications websocket disconnected, falling back to config polling")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// receiveNotifications connects to the notifications websocket and calls
+// onHint for every hint received until the connection fails or ctx is done.
+func (oc *OrbitClient) receiveNotifications(ctx context.Context, onHint func(fleet.OrbitNotificationHint)) error {
+	nodeKey, err := oc.getNodeKeyOrEnroll()
+	if err != nil {
+		return fmt.Errorf("get node key: %w", err)
+	}
+
+	wsURL := oc.URL(orbitNotificationsPath, "")
+	switch wsURL.Scheme {
+	case "https":
+		wsURL.Scheme = "wss"
+	case "http":
+		wsURL.Scheme = "ws"
+	}
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: 30 * time.Second,
+		TLSClientConfig:  oc.tlsClientConfig(),
+	}
+	conn, resp, err := dialer.DialContext(ctx, wsURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("dial notifications websocket: %w", err)
+	}
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	defer conn.Close()
+
+	// close the connection to stop reading once ctx is done
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	if err := conn.WriteJSON(map[string]any{
+		"type": "auth",
+		"data": map[string]string{"token": nodeKey},
+	}); err != nil {
+		return fmt.Errorf("send node key: %w", err)
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(notificationsReadTimeout)); err != nil {
+		return err
+	}
+	conn.SetPingHandler(func(data string) error {
+		if err := conn.SetReadDeadline(time.Now().Add(notificationsReadTimeout)); err != nil {
+			return err
+		}
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(10*time.Second))
+	})
+
+	log.Debug().Msg("connected to the notifications websocket")
+	for {
+		var msg notificationMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return fmt.Errorf("read notification: %w", err)
+		}
+		if err := conn.SetReadDeadline(time.Now().Add(notificationsReadTimeout)); err != nil {
+			return err
+		}
+
+		switch msg.Type {
+		case fleet.OrbitNotificationMessageType:
+			var notification fleet.OrbitNotification
+			if err := json.Unmarshal(msg.Data, &notification); err != nil {
+				return fmt.Errorf("unmarshal notification: %w", err)
+			}
+			onHint(notification.Hint)
+		case "error":
+			return fmt.Errorf("notifications websocket error: %s", msg.Data)
+		default:
+			log.Debug().Str("type", msg.Type).Msg("ignoring unknown notifications websocket message")
+		}
+	}
+}
+
+// tlsClientConfig returns the TLS configuration of the client's HTTP
+// transport, if any.
+func (oc *OrbitClient) tlsClientConfig() *tls.Config {
+	c, ok := oc.GetRawHTTPClient().(*http.Client)
+	if !ok {
+		return nil
+	}
+	t, ok := c.Transport.(*http.Transport)
+	if !ok {
+		return nil
+	}
+	return t.TLSClientConfig
+}
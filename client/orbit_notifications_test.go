@@ -0,0 +1,142 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newNotificationsTestServer returns a Fleet server that serves an empty orbit
+// config and sends the hint over the notifications websocket to the hosts
+// that authenticate with the "test-node-key" node key.
+func newNotificationsTestServer(t *testing.T, hint fleet.OrbitNotificationHint, configFetches *atomic.Int32) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/fleet/orbit/config":
+			configFetches.Add(1)
+			w.Write([]byte(`{}`)) //nolint:errcheck
+		case orbitNotificationsPath:
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+
+			var auth struct {
+				Type string `json:"type"`
+				Data struct {
+					Token string `json:"token"`
+				} `json:"data"`
+			}
+			if err := conn.ReadJSON(&auth); err != nil {
+				return
+			}
+			if auth.Type != "auth" || auth.Data.Token != "test-node-key" {
+				conn.WriteJSON(map[string]any{"type": "error", "data": "unauthorized"}) //nolint:errcheck
+				return
+			}
+			conn.WriteJSON(map[string]any{ //nolint:errcheck
+				"type": fleet.OrbitNotificationMessageType,
+				"data": fleet.OrbitNotification{Hint: hint},
+			})
+			// keep the connection open until the client disconnects
+			conn.ReadMessage() //nolint:errcheck
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newNotificationsTestClient(t *testing.T, srv *httptest.Server, capabilities fleet.CapabilityMap) *OrbitClient {
+	oc, err := NewOrbitClient(t.TempDir(), srv.URL, "", true, "", nil, fleet.OrbitHostInfo{}, nil, nil, "", false)
+	require.NoError(t, err)
+	oc.TestNodeKey = "test-node-key"
+	oc.ServerCapabilities = capabilities
+	return oc
+}
+
+func TestReceiveNotifications(t *testing.T) {
+	srv := newNotificationsTestServer(t, fleet.OrbitNotificationHintScripts, new(atomic.Int32))
+	oc := newNotificationsTestClient(t, srv, fleet.CapabilityMap{fleet.CapabilityOrbitNotifications: {}})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	var hints []fleet.OrbitNotificationHint
+	done := make(chan error, 1)
+	go func() {
+		done <- oc.receiveNotifications(ctx, func(hint fleet.OrbitNotificationHint) {
+			hints = append(hints, hint)
+			cancel()
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a notification")
+	}
+	assert.Equal(t, []fleet.OrbitNotificationHint{fleet.OrbitNotificationHintScripts}, hints)
+
+	// an error message from the server ends the connection
+	oc.TestNodeKey = "other-node-key"
+	err := oc.receiveNotifications(t.Context(), func(fleet.OrbitNotificationHint) {
+		t.Fatal("unexpected hint")
+	})
+	require.ErrorContains(t, err, `notifications websocket error: "unauthorized"`)
+}
+
+func TestNotificationsSupported(t *testing.T) {
+	srv := newNotificationsTestServer(t, fleet.OrbitNotificationHintConfig, new(atomic.Int32))
+
+	oc := newNotificationsTestClient(t, srv, fleet.CapabilityMap{})
+	assert.False(t, oc.notificationsSupported())
+
+	oc = newNotificationsTestClient(t, srv, fleet.CapabilityMap{fleet.CapabilityOrbitNotifications: {}})
+	assert.True(t, oc.notificationsSupported())
+
+	// hosts using a host identity certificate keep polling
+	oc.hostIdentityCertPath = "/path/to/cert"
+	assert.False(t, oc.notificationsSupported())
+}
+
+func TestExecuteConfigReceiversOnNotification(t *testing.T) {
+	var configFetches atomic.Int32
+	srv := newNotificationsTestServer(t, fleet.OrbitNotificationHintSoftwareInstalls, &configFetches)
+	oc := newNotificationsTestClient(t, srv, fleet.CapabilityMap{fleet.CapabilityOrbitNotifications: {}})
+	// the config is cached and the next poll is far away, so the receivers
+	// only run because of the notification
+	oc.configCache.config = &fleet.OrbitConfig{Notifications: fleet.OrbitConfigNotifications{NeedsProgrammaticWindowsMDMEnrollment: true}}
+	oc.configCache.lastUpdated = time.Now().Add(time.Hour)
+	oc.ReceiverUpdateInterval = time.Hour
+
+	var received *fleet.OrbitConfig
+	oc.RegisterConfigReceiver(fleet.OrbitConfigReceiverFunc(func(cfg *fleet.OrbitConfig) error {
+		received = cfg
+		oc.receiverUpdateCancelFunc()
+		return nil
+	}))
+
+	done := make(chan error, 1)
+	go func() { done <- oc.ExecuteConfigReceivers() }()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the config receivers")
+	}
+
+	// the cached config was not used
+	require.NotNil(t, received)
+	assert.Equal(t, fleet.OrbitConfig{}, *received)
+	assert.EqualValues(t, 1, configFetches.Load())
+}
@@ -46,6 +46,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/vulnerabilities/osv"
 	"github.com/fleetdm/fleet/v4/server/vulnerabilities/oval"
 	"github.com/fleetdm/fleet/v4/server/vulnerabilities/utils"
+	"github.com/fleetdm/fleet/v4/server/vulnerabilities/vex"
 	"github.com/fleetdm/fleet/v4/server/vulnerabilities/winoffice"
 	"github.com/fleetdm/fleet/v4/server/webhooks"
 	"github.com/fleetdm/fleet/v4/server/worker"
@@ -64,6 +65,7 @@ func newVulnerabilitiesSchedule(
 	ds fleet.Datastore,
 	logger *slog.Logger,
 	config *config.VulnerabilitiesConfig,
+	newActivityFn fleet.NewActivityFunc,
 ) (*schedule.Schedule, error) {
 	const name = string(fleet.CronVulnerabilities)
 	interval := config.Periodicity
@@ -73,7 +75,7 @@ func newVulnerabilitiesSchedule(
 
 	options = append(options, schedule.WithLogger(vulnerabilitiesLogger), schedule.WithTriggerPollInterval(60*time.Second))
 
-	vulnFuncs := getVulnFuncs(ds, vulnerabilitiesLogger, config)
+	vulnFuncs := getVulnFuncs(ds, vulnerabilitiesLogger, config, newActivityFn)
 	for _, fn := range vulnFuncs {
 		options = append(options, schedule.WithJob(fn.Name, fn.VulnFunc))
 	}
@@ -88,6 +90,7 @@ func cronVulnerabilities(
 	ds fleet.Datastore,
 	logger *slog.Logger,
 	config *config.VulnerabilitiesConfig,
+	newActivityFn fleet.NewActivityFunc,
 ) error {
 	if config == nil {
 		return errors.New("nil configuration")
@@ -108,7 +111,7 @@ func cronVulnerabilities(
 	vulnPath := configureVulnPath(ctx, *config, appConfig, logger)
 	if vulnPath != "" {
 		logger.InfoContext(ctx, "scanning vulnerabilities")
-		if err := scanVulnerabilities(ctx, ds, logger, config, appConfig, vulnPath); err != nil {
+		if err := scanVulnerabilities(ctx, ds, logger, config, appConfig, vulnPath, newActivityFn); err != nil {
 			return fmt.Errorf("scanning vulnerabilities: %w", err)
 		}
 
@@ -150,6 +153,7 @@ func scanVulnerabilities(
 	config *config.VulnerabilitiesConfig,
 	appConfig *fleet.AppConfig,
 	vulnPath string,
+	newActivityFn fleet.NewActivityFunc,
 ) error {
 	logger.DebugContext(ctx, "creating vulnerabilities databases path", "databases_path", vulnPath)
 	err := os.MkdirAll(vulnPath, 0o755)
@@ -189,6 +193,18 @@ func scanVulnerabilities(
 		}
 	}
 
+	// check for ServiceNow integrations
+	for _, sn := range appConfig.Integrations.ServiceNow {
+		if sn.EnableSoftwareVulnerabilities {
+			if vulnAutomationEnabled != "" {
+				err := ctxerr.New(ctx, "servicenow check")
+				errHandler(ctx, logger, "more than one automation enabled", err)
+			}
+			vulnAutomationEnabled = "servicenow"
+			break
+		}
+	}
+
 	logger.DebugContext(ctx, "", "vulnAutomationEnabled", vulnAutomationEnabled)
 
 	startTime, err := ds.GetCurrentTime(ctx)
@@ -250,6 +266,15 @@ func scanVulnerabilities(
 		logger.InfoContext(ctx, "phase completed", "phase", "android_osv", "elapsed", time.Since(phaseStart))
 	}
 
+	// VEX statements must be applied after all the matchers, which re-insert the
+	// vulnerabilities they suppress.
+	var vexSuppressed []fleet.SoftwareVulnerability
+	if license.IsPremium(ctx) {
+		phaseStart = time.Now()
+		vexSuppressed = applyVEXDocuments(ctx, ds, logger, config, newActivityFn)
+		logger.InfoContext(ctx, "phase completed", "phase", "vex", "elapsed", time.Since(phaseStart))
+	}
+
 	// Clean up orphaned vulnerabilities (software/OS no longer associated with any host).
 	// This runs here (not in cleanups_then_aggregation) to stay in series with the scanners
 	// that write to the same tables, avoiding cross-schedule lock contention. The LEFT JOIN
@@ -281,10 +306,20 @@ func scanVulnerabilities(
 	vulns = append(vulns, winOfficeVulns...)
 	vulns = append(vulns, govalDictVulns...)
 	vulns = append(vulns, customVulns...)
+	vulns = vex.ExcludeSuppressed(vulns, vexSuppressed)
 
 	var recentV []fleet.SoftwareVulnerability
 	var matchingMeta map[string]fleet.CVEMeta
 	if license.IsPremium(ctx) {
+		// vulnerabilities with an active exception for all fleets are accepted
+		// risks, the automations ignore them
+		filtered, err := ds.FilterExceptedSoftwareVulnerabilities(automationCtx, vulns)
+		if err != nil {
+			errHandler(automationCtx, logger, "could not filter vulnerability exceptions", err)
+			return nil
+		}
+		vulns = filtered
+
 		meta, err := ds.ListCVEs(automationCtx, config.RecentVulnerabilityMaxAge)
 		if err != nil {
 			errHandler(automationCtx, logger, "could not fetch CVE meta", err)
@@ -346,6 +381,18 @@ func scanVulnerabilities(
 				errHandler(automationCtx, logger, "queueing vulnerabilities to Zendesk", err)
 			}
 
+		case "servicenow":
+			// queue job to create or update servicenow incidents
+			if err := worker.QueueServiceNowVulnJobs(
+				automationCtx,
+				ds,
+				logger.With("servicenow", "vulnerabilities"),
+				recentV,
+				matchingMeta,
+			); err != nil {
+				errHandler(automationCtx, logger, "queueing vulnerabilities to ServiceNow", err)
+			}
+
 		default:
 			err = ctxerr.New(automationCtx, "no vuln automations enabled")
 			errHandler(automationCtx, logger, "attempting to process vuln automations", err)
@@ -379,6 +426,32 @@ func checkCustomVulnerabilities(
 	return vulns
 }
 
+func applyVEXDocuments(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger *slog.Logger,
+	config *config.VulnerabilitiesConfig,
+	newActivityFn fleet.NewActivityFunc,
+) []fleet.SoftwareVulnerability {
+	ctx, span := tracer.Start(ctx, "vuln.apply_vex")
+	defer span.End()
+
+	if config.VEXPath != "" {
+		if err := vex.ImportDirectory(ctx, ds, logger, config.VEXPath, newActivityFn); err != nil {
+			errHandler(ctx, logger, "importing VEX documents", err)
+		}
+	}
+
+	suppressed, err := vex.Apply(ctx, ds, logger)
+	if err != nil {
+		errHandler(ctx, logger, "applying VEX documents", err)
+		return nil
+	}
+
+	logger.DebugContext(ctx, "vex-analysis-done", "suppressed", len(suppressed))
+	return suppressed
+}
+
 func checkWinVulnerabilities(
 	ctx context.Context,
 	ds fleet.Datastore,
@@ -831,6 +904,7 @@ func checkNVDVulnerabilities(
 			CPETranslationsURL:   config.CPETranslationsURL,
 			CVEFeedPrefixURL:     config.CVEFeedPrefixURL,
 			CISAKnownExploitsURL: config.CISAKnownExploitsURL,
+			EPSSFeedURL:          config.EPSSFeedURL,
 		}
 		err := nvd.Sync(syncCtx, opts, logger)
 		if err != nil {
@@ -1044,7 +1118,7 @@ func triggerFailingPoliciesAutomation(
 		switch cfg.AutomationType {
 		case policies.FailingPolicyWebhook:
 			return webhooks.SendFailingPoliciesBatchedPOSTs(
-				ctx, policy, failingPoliciesSet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, time.Now(), logger, newActivitySvc)
+				ctx, ds, policy, failingPoliciesSet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, time.Now(), logger, newActivitySvc)
 
 		case policies.FailingPolicyJira:
 			hosts, err := failingPoliciesSet.ListHosts(policy.ID)
@@ -1069,6 +1143,18 @@ func triggerFailingPoliciesAutomation(
 			if err := failingPoliciesSet.RemoveHosts(policy.ID, hosts); err != nil {
 				return ctxerr.Wrapf(ctx, err, "removing %d hosts from failing policies set %d", len(hosts), policy.ID)
 			}
+
+		case policies.FailingPolicyServiceNow:
+			hosts, err := failingPoliciesSet.ListHosts(policy.ID)
+			if err != nil {
+				return ctxerr.Wrapf(ctx, err, "listing hosts for failing policies set %d", policy.ID)
+			}
+			if err := worker.QueueServiceNowFailingPolicyJob(ctx, ds, logger, policy, hosts); err != nil {
+				return err
+			}
+			if err := failingPoliciesSet.RemoveHosts(policy.ID, hosts); err != nil {
+				return ctxerr.Wrapf(ctx, err, "removing %d hosts from failing policies set %d", len(hosts), policy.ID)
+			}
 		}
 		return nil
 	})
@@ -1076,6 +1162,22 @@ func triggerFailingPoliciesAutomation(
 		return fmt.Errorf("triggering failing policies automation: %w", err)
 	}
 
+	// ServiceNow incidents are resolved automatically once their policy passes
+	// again. Only the policies with an incident opened by the integration are
+	// checked.
+	if len(appConfig.Integrations.ServiceNow) > 0 {
+		openPolicyIDs, err := ds.ListServiceNowPolicyIncidents(ctx)
+		if err != nil {
+			return fmt.Errorf("listing servicenow policy incidents: %w", err)
+		}
+		err = policies.TriggerPassingPoliciesAutomation(ctx, ds, logger, policies.FailingPolicyServiceNow, openPolicyIDs, func(policy *fleet.Policy) error {
+			return worker.QueueServiceNowResolvedPolicyJob(ctx, ds, logger, policy)
+		})
+		if err != nil {
+			return fmt.Errorf("triggering passing policies automation: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -1104,7 +1206,7 @@ func newWorkerIntegrationsSchedule(
 
 	logger = logger.With("cron", name)
 
-	// create the worker and register the Jira and Zendesk jobs even if no
+	// create the worker and register the Jira, Zendesk and ServiceNow jobs even if no
 	// integration is enabled, as that config can change live (and if it's not
 	// there won't be any records to process so it will mostly just sleep).
 	w := worker.NewWorker(ds, logger)
@@ -1122,6 +1224,12 @@ func newWorkerIntegrationsSchedule(
 		NewClientFunc:  newZendeskClient,
 		NewActivitySvc: newActivitySvc,
 	}
+	serviceNow := &worker.ServiceNow{
+		Datastore:      ds,
+		Log:            logger,
+		NewClientFunc:  newServiceNowClient,
+		NewActivitySvc: newActivitySvc,
+	}
 	var (
 		depSvc *apple_mdm.DEPService
 		depCli *godep.Client
@@ -1163,7 +1271,12 @@ func newWorkerIntegrationsSchedule(
 		ChartService: chartSvc,
 		Log:          logger,
 	}
-	w.Register(jira, zendesk, macosSetupAsst, dbMigrate, vppVerify, softwareWorker, chartScrubGlobal, chartScrubFleet)
+	webhookDelivery := &worker.WebhookDelivery{
+		Datastore:      ds,
+		Log:            logger,
+		NewActivitySvc: newActivitySvc,
+	}
+	w.Register(jira, zendesk, serviceNow, macosSetupAsst, dbMigrate, vppVerify, softwareWorker, chartScrubGlobal, chartScrubFleet, webhookDelivery)
 
 	// Read app config a first time before starting, to clear up any failer client
 	// configuration if we're not on a fleet-owned server. Technically, the ServerURL
@@ -1194,6 +1307,7 @@ func newWorkerIntegrationsSchedule(
 
 			jira.FleetURL = appConfig.ServerSettings.ServerURL
 			zendesk.FleetURL = appConfig.ServerSettings.ServerURL
+			serviceNow.FleetURL = appConfig.ServerSettings.ServerURL
 
 			workCtx, cancel := context.WithTimeout(ctx, maxRunTime)
 			defer cancel()
@@ -1245,6 +1359,10 @@ func newZendeskClient(opts *externalsvc.ZendeskOptions) (worker.ZendeskClient, e
 	return client, nil
 }
 
+func newServiceNowClient(opts *externalsvc.ServiceNowOptions) (worker.ServiceNowClient, error) {
+	return externalsvc.NewServiceNowClient(opts)
+}
+
 func newFailerClient(forcedFailures string) *worker.TestAutomationFailer {
 	var failerClient *worker.TestAutomationFailer
 	if forcedFailures != "" {
@@ -1315,6 +1433,9 @@ func newAppleMDMWorkerSchedule(
 	return s, nil
 }
 
+// webhookDeliveriesRetention is how long the webhook deliveries are kept.
+const webhookDeliveriesRetention = 7 * 24 * time.Hour
+
 func newCleanupsAndAggregationSchedule(
 	ctx context.Context,
 	instanceID string,
@@ -1328,6 +1449,7 @@ func newCleanupsAndAggregationSchedule(
 	softwareInstallStore fleet.SoftwareInstallerStore,
 	bootstrapPackageStore fleet.MDMBootstrapPackageStore,
 	softwareTitleIconStore fleet.SoftwareTitleIconStore,
+	campaignResultsStore fleet.CampaignResultsStore,
 	androidSvc android.Service,
 	activitySvc activity_api.Service,
 	acmeSvc acme_api.Service,
@@ -1394,6 +1516,13 @@ func newCleanupsAndAggregationSchedule(
 				return err
 			},
 		),
+		schedule.WithJob(
+			"expired_vulnerability_exceptions",
+			func(ctx context.Context) error {
+				// Call service method to handle activity creation
+				return svc.ExpireVulnerabilityExceptions(ctx)
+			},
+		),
 		schedule.WithJob(
 			"policy_membership",
 			func(ctx context.Context) error {
@@ -1528,6 +1657,12 @@ func newCleanupsAndAggregationSchedule(
 			const maxCount = 5000
 			return activitySvc.CleanupExpiredActivities(ctx, maxCount, appConfig.ActivityExpirySettings.ActivityExpiryWindow)
 		}),
+		schedule.WithJob("cleanup_webhook_deliveries", func(ctx context.Context) error {
+			// pending deliveries are kept until they succeed or exhaust their
+			// retries
+			_, err := ds.CleanupWebhookDeliveries(ctx, time.Now().Add(-webhookDeliveriesRetention))
+			return err
+		}),
 		schedule.WithJob("cleanup_live_queries", func(ctx context.Context) error {
 			appConfig, err := ds.AppConfig(ctx)
 			if err != nil {
@@ -1547,6 +1682,19 @@ func newCleanupsAndAggregationSchedule(
 		schedule.WithJob("cleanup_unused_software_title_icons", func(ctx context.Context) error {
 			return ds.CleanupUnusedSoftwareTitleIcons(ctx, softwareTitleIconStore, time.Now().Add(-time.Minute))
 		}),
+		schedule.WithJob("cleanup_expired_query_campaigns", func(ctx context.Context) error {
+			deleted, err := ds.CleanupExpiredQueryCampaigns(ctx, time.Now().Add(-config.Server.LiveQueryResultsRetention).UTC())
+			if err != nil {
+				return err
+			}
+			if deleted > 0 {
+				logger.InfoContext(ctx, "deleted expired query campaigns with stored results", "count", deleted)
+			}
+			return nil
+		}),
+		schedule.WithJob("cleanup_unused_campaign_results_data", func(ctx context.Context) error {
+			return ds.CleanupUnusedCampaignResultsData(ctx, campaignResultsStore, time.Now().Add(-time.Minute))
+		}),
 		schedule.WithJob("cleanup_unused_bootstrap_packages", func(ctx context.Context) error {
 			// remove only those unused created more than a minute ago to avoid a
 			// race where we delete those created after the mysql query to get those
@@ -1724,6 +1872,11 @@ func newFrequentCleanupsSchedule(
 			// - (Unknown) bug in the implementation, or,
 			// - Redis is so overloaded already that the lq.StopQuery in svc.CompleteCampaign fails to execute, or,
 			// - MySQL is so overloaded that ds.SaveDistributedQueryCampaign in svc.CompleteCampaign fails to execute.
+			//
+			// It also stops deferred live queries once they expire.
+			if _, err := ds.CompleteExpiredDeferredQueryCampaigns(ctx, time.Now().UTC()); err != nil {
+				return err
+			}
 			names, err := lq.LoadActiveQueryNames()
 			if err != nil {
 				return err
@@ -1773,6 +1926,18 @@ func newQueryResultsCleanupSchedule(
 			}
 			return nil
 		}),
+		schedule.WithJob("cleanup_query_result_history", func(ctx context.Context) error {
+			appConfig, err := ds.AppConfig(ctx)
+			if err != nil {
+				return err
+			}
+			// With report history disabled, all of it is removed.
+			olderThan := time.Now()
+			if appConfig.ServerSettings.ReportHistoryEnabled() {
+				olderThan = olderThan.AddDate(0, 0, -appConfig.ServerSettings.ReportHistoryRetentionDays)
+			}
+			return ds.CleanupQueryResultHistory(ctx, olderThan.UTC(), appConfig.ServerSettings.GetReportHistoryCap())
+		}),
 	)
 
 	return s, nil
@@ -2213,6 +2378,30 @@ func newBatchActivityCompletionCheckerSchedule(
 	return s, nil
 }
 
+// newGitOpsDriftSchedule periodically compares the live state of the resources
+// applied by fleetctl gitops against their snapshots, and reports new drifts.
+func newGitOpsDriftSchedule(
+	ctx context.Context,
+	instanceID string,
+	ds fleet.Datastore,
+	logger *slog.Logger,
+	newActivityFn fleet.NewActivityFunc,
+) (*schedule.Schedule, error) {
+	const (
+		name            = string(fleet.CronGitOpsDrift)
+		defaultInterval = 1 * time.Hour
+	)
+	logger = logger.With("cron", name)
+	s := schedule.New(
+		ctx, name, instanceID, defaultInterval, ds, ds,
+		schedule.WithLogger(logger),
+		schedule.WithJob("check_gitops_drift", func(ctx context.Context) error {
+			return service.CheckGitOpsDrift(ctx, ds, logger, newActivityFn)
+		}),
+	)
+	return s, nil
+}
+
 func cronBatchActivityCompletionChecker(
 	ctx context.Context,
 	ds fleet.Datastore,
@@ -47,6 +47,7 @@ type cronSchedulesDeps struct {
 	softwareInstallStore   fleet.SoftwareInstallerStore
 	bootstrapPackageStore  fleet.MDMBootstrapPackageStore
 	softwareTitleIconStore fleet.SoftwareTitleIconStore
+	campaignResultsStore   fleet.CampaignResultsStore
 	androidSvc             android.Service
 	activitySvc            activity_api.Service
 	acmeSvc                acme_api.Service
@@ -142,7 +143,7 @@ func registerCleanupAndMaintenanceCrons(ctx context.Context, deps cronSchedulesD
 
 	deps.register("failed to register cleanups_then_aggregations schedule", func() (fleet.CronSchedule, error) {
 		return newCleanupsAndAggregationSchedule(
-			ctx, deps.instanceID, deps.ds, deps.carveStore, deps.svc, deps.logger, deps.enrollHostLimiter, deps.config, deps.commander, deps.softwareInstallStore, deps.bootstrapPackageStore, deps.softwareTitleIconStore, deps.androidSvc, deps.activitySvc, deps.acmeSvc, deps.chartSvc,
+			ctx, deps.instanceID, deps.ds, deps.carveStore, deps.svc, deps.logger, deps.enrollHostLimiter, deps.config, deps.commander, deps.softwareInstallStore, deps.bootstrapPackageStore, deps.softwareTitleIconStore, deps.campaignResultsStore, deps.androidSvc, deps.activitySvc, deps.acmeSvc, deps.chartSvc,
 		)
 	})
 
@@ -187,7 +188,7 @@ func registerVulnerabilityCrons(ctx context.Context, deps cronSchedulesDeps) {
 	if !vulnerabilityProcessingDisabled(deps.config.Vulnerabilities) {
 		// vuln processing by default is run by internal cron mechanism
 		deps.register("failed to register vulnerabilities schedule", func() (fleet.CronSchedule, error) {
-			return newVulnerabilitiesSchedule(ctx, deps.instanceID, deps.ds, deps.logger, &deps.config.Vulnerabilities)
+			return newVulnerabilitiesSchedule(ctx, deps.instanceID, deps.ds, deps.logger, &deps.config.Vulnerabilities, deps.svc.NewActivity)
 		})
 	} else {
 		// Register a remote trigger proxy so triggering still works
@@ -388,8 +389,8 @@ func registerPremiumCrons(ctx context.Context, deps cronSchedulesDeps) {
 	})
 }
 
-// registerMiscCrons covers the host vitals label membership schedule and the
-// batch activity completion checker.
+// registerMiscCrons covers the host vitals label membership schedule, the
+// batch activity completion checker and the GitOps drift check.
 func registerMiscCrons(ctx context.Context, deps cronSchedulesDeps) {
 	// Start the service that calculates and updates host vitals label membership.
 	deps.register("failed to register host vitals label membership schedule", func() (fleet.CronSchedule, error) {
@@ -400,4 +401,9 @@ func registerMiscCrons(ctx context.Context, deps cronSchedulesDeps) {
 	deps.register("failed to register batch activity completion checker schedule", func() (fleet.CronSchedule, error) {
 		return newBatchActivityCompletionCheckerSchedule(ctx, deps.instanceID, deps.ds, deps.logger)
 	})
+
+	// Start the service that reports the changes made outside of GitOps.
+	deps.register("failed to register gitops drift schedule", func() (fleet.CronSchedule, error) {
+		return newGitOpsDriftSchedule(ctx, deps.instanceID, deps.ds, deps.logger, deps.svc.NewActivity)
+	})
 }
@@ -74,6 +74,24 @@ func buildLoggingConfig(cfg config.FleetConfig) logging.Config {
 			SourceType:         cfg.Splunk.SourceType,
 			InsecureSkipVerify: cfg.Splunk.InsecureSkipVerify,
 		},
+		OTLP: logging.OTLPConfig{
+			Endpoint:                  cfg.OTLP.Endpoint,
+			Protocol:                  cfg.OTLP.Protocol,
+			Insecure:                  cfg.OTLP.Insecure,
+			Headers:                   cfg.OTLP.Headers,
+			Compression:               cfg.OTLP.Compression,
+			Timeout:                   cfg.OTLP.Timeout,
+			MaxBatchSize:              cfg.OTLP.MaxBatchSize,
+			ResourceAttributes:        cfg.OTLP.ResourceAttributes,
+			DynamicResourceAttributes: cfg.OTLP.DynamicResourceAttributes,
+		},
+		Spool: logging.SpoolConfig{
+			Directory:   cfg.LogSpool.Directory,
+			MaxSize:     int64(cfg.LogSpool.MaxSize) << 20,
+			SegmentSize: int64(cfg.LogSpool.SegmentSize) << 20,
+			MinBackoff:  cfg.LogSpool.MinBackoff,
+			MaxBackoff:  cfg.LogSpool.MaxBackoff,
+		},
 	}
 }
 
@@ -160,3 +178,45 @@ func initOsqueryLogging(
 
 	return statusLogger, resultLogger, auditLogger
 }
+
+// resultLogDestinationConfig returns the logging.Config for a named result log
+// destination: the plugin's shared settings with the destination's own target.
+func resultLogDestinationConfig(cfg config.FleetConfig, dest config.ResultLogDestination) logging.Config {
+	loggingConfig := buildLoggingConfig(cfg)
+	loggingConfig.Plugin = dest.Plugin
+	loggingConfig.Filesystem.LogFile = dest.File
+	loggingConfig.Webhook.URL = dest.URL
+	loggingConfig.Firehose.StreamName = dest.Stream
+	loggingConfig.Kinesis.StreamName = dest.Stream
+	loggingConfig.Lambda.Function = dest.Function
+	loggingConfig.PubSub.Topic = dest.Topic
+	loggingConfig.PubSub.AddAttributes = cfg.PubSub.AddAttributes
+	loggingConfig.KafkaREST.Topic = dest.Topic
+	loggingConfig.Nats.Subject = dest.Subject
+	return loggingConfig
+}
+
+// initResultLogDestinations constructs a JSON logger for each named result log
+// destination in osquery.result_log_destinations, keyed by name. Failures go
+// through initFatal, and nil is returned on the failure path.
+func initResultLogDestinations(
+	ctx context.Context,
+	cfg config.FleetConfig,
+	logger *slog.Logger,
+	initFatal func(err error, msg string),
+) map[string]fleet.JSONLogger {
+	if len(cfg.Osquery.ResultLogDestinations) == 0 {
+		return nil
+	}
+
+	destinations := make(map[string]fleet.JSONLogger, len(cfg.Osquery.ResultLogDestinations))
+	for _, dest := range cfg.Osquery.ResultLogDestinations {
+		destLogger, err := logging.NewJSONLogger(ctx, "result-"+dest.Name, resultLogDestinationConfig(cfg, dest), logger)
+		if err != nil {
+			initFatal(err, "initializing osqueryd result log destination "+dest.Name)
+			return nil
+		}
+		destinations[dest.Name] = destLogger
+	}
+	return destinations
+}
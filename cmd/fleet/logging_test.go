@@ -36,10 +36,40 @@ func TestBuildLoggingConfigMapsConfig(t *testing.T) {
 	cfg.Firehose.Region = "us-east-1"
 	cfg.PubSub.Project = "fleet-project"
 	cfg.Nats.Server = "nats://localhost:4222"
+	cfg.LogSpool.Directory = "/var/spool/fleet"
+	cfg.LogSpool.MaxSize = 2
 
 	got := buildLoggingConfig(cfg)
 
 	assert.Equal(t, "us-east-1", got.Firehose.Region)
 	assert.Equal(t, "fleet-project", got.PubSub.Project)
 	assert.Equal(t, "nats://localhost:4222", got.Nats.Server)
+	assert.Equal(t, "/var/spool/fleet", got.Spool.Directory)
+	assert.Equal(t, int64(2<<20), got.Spool.MaxSize)
+}
+
+func TestResultLogDestinationConfig(t *testing.T) {
+	cfg := config.FleetConfig{}
+	cfg.Firehose.Region = "us-east-1"
+	cfg.Firehose.ResultStream = "default-results"
+	cfg.Filesystem.EnableLogRotation = true
+
+	got := resultLogDestinationConfig(cfg, config.ResultLogDestination{
+		Name:   "security",
+		Plugin: "firehose",
+		Stream: "security-results",
+	})
+	assert.Equal(t, "firehose", got.Plugin)
+	assert.Equal(t, "us-east-1", got.Firehose.Region)
+	assert.Equal(t, "security-results", got.Firehose.StreamName)
+
+	got = resultLogDestinationConfig(cfg, config.ResultLogDestination{
+		Name:   "compliance",
+		Plugin: "filesystem",
+		File:   "/var/log/fleet/compliance.log",
+	})
+	assert.Equal(t, "filesystem", got.Plugin)
+	assert.Equal(t, "/var/log/fleet/compliance.log", got.Filesystem.LogFile)
+	assert.True(t, got.Filesystem.EnableLogRotation)
+	assert.Empty(t, got.Firehose.StreamName)
 }
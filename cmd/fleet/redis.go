@@ -65,11 +65,11 @@ func validateRedisConfig(cfg config.RedisConfig) error {
 }
 
 // initRedis brings up the Redis pool and the two datastore wrappers that
-// depend on it: cached_mysql (in-memory caching layer over the datastore)
-// and mysqlredis (Redis-backed host lookup and license-enforced host
-// limit). Failures go through initFatal. Returns nil values on the
-// failure path so the function is safe when initFatal does not terminate
-// (e.g., tests using a recorder).
+// depend on it: cached_mysql (in-memory caching layer over the datastore,
+// invalidated across instances via Redis pub/sub) and mysqlredis
+// (Redis-backed host lookup and license-enforced host limit). Failures go
+// through initFatal. Returns nil values on the failure path so the function
+// is safe when initFatal does not terminate (e.g., tests using a recorder).
 //
 // The returned fleet.Datastore is the fully wrapped chain (mysqlredis →
 // cached_mysql → input ds); the returned *mysqlredis.Datastore is the
@@ -103,7 +103,7 @@ func initRedis(
 	}
 	logger.InfoContext(ctx, "redis initialized", "component", "redis", "mode", redisPool.Mode())
 
-	wrappedDS := cached_mysql.New(ds)
+	wrappedDS := cached_mysql.New(ds, cached_mysql.WithInvalidationBus(ctx, redisPool, logger))
 
 	var dsOpts []mysqlredis.Option
 	if license.DeviceCount > 0 && cfg.License.EnforceHostLimit {
@@ -301,11 +301,13 @@ func runServeCmd(cmd *cobra.Command, configManager configpkg.Manager, debug, dev
 		redisPool, config.Redis.DuplicateResults,
 		logger.With("component", "query-results"),
 	)
+	orbitNotifier := pubsub.NewRedisOrbitNotifier(redisPool, logger.With("component", "orbit-notifications"))
 	liveQueryStore := live_query.NewRedisLiveQuery(redisPool, logger, liveQueryMemCacheDuration,
 		config.Redis.LiveQuerySmallTargetThreshold)
 	ssoSessionStore := sso.NewSessionStore(redisPool)
 
 	osquerydStatusLogger, osquerydResultLogger, auditLogger := initOsqueryLogging(cmd.Context(), config, license, logger, initFatal)
+	osquerydResultLogDestinations := initResultLogDestinations(cmd.Context(), config, logger, initFatal)
 	if osquerydStatusLogger == nil || osquerydResultLogger == nil {
 		initFatal(errors.New("osquery loggers were nil after initialization"), "initializing osqueryd logging")
 		return
@@ -483,6 +485,7 @@ func runServeCmd(cmd *cobra.Command, configManager configpkg.Manager, debug, dev
 	}
 
 	orgLogoStore := initOrgLogoStore(ctx, config.S3, mds, logger)
+	campaignResultsStore := initCampaignResultsStore(ctx, config.S3, logger)
 
 	svc, err = service.NewService(
 		ctx,
@@ -491,8 +494,9 @@ func runServeCmd(cmd *cobra.Command, configManager configpkg.Manager, debug, dev
 		resultStore,
 		logger,
 		&service.OsqueryLogger{
-			Status: osquerydStatusLogger,
-			Result: osquerydResultLogger,
+			Status:             osquerydStatusLogger,
+			Result:             osquerydResultLogger,
+			ResultDestinations: osquerydResultLogDestinations,
 		},
 		config,
 		mailService,
@@ -514,6 +518,8 @@ func runServeCmd(cmd *cobra.Command, configManager configpkg.Manager, debug, dev
 		redis_key_value.New(redisPool),
 		androidSvc,
 		orgLogoStore,
+		campaignResultsStore,
+		orbitNotifier,
 	)
 	if err != nil {
 		initFatal(err, "initializing service")
@@ -674,6 +680,7 @@ func runServeCmd(cmd *cobra.Command, configManager configpkg.Manager, debug, dev
 		softwareInstallStore:   softwareInstallStore,
 		bootstrapPackageStore:  bootstrapPackageStore,
 		softwareTitleIconStore: softwareTitleIconStore,
+		campaignResultsStore:   campaignResultsStore,
 		androidSvc:             androidSvc,
 		activitySvc:            activitySvc,
 		acmeSvc:                acmeSvc,
@@ -1121,6 +1128,9 @@ func createChartBoundedContext(dbConns *common_mysql.DBConnections, svc fleet.Se
 	// and to iterate over all chart types when generating chart data.
 	chartSvc.RegisterDataset(&chart.UptimeDataset{})
 	chartSvc.RegisterDataset(&chart.CVEDataset{})
+	chartSvc.RegisterDataset(&chart.PolicyDataset{})
+	chartSvc.RegisterDataset(&chart.MDMEnrollmentDataset{})
+	chartSvc.RegisterDataset(&chart.DiskEncryptionDataset{})
 	// Create auth middleware for chart bounded context
 	// Makes sure that api_only users are subject to endpoint
 	// restrictions on chart routes.
@@ -1147,6 +1157,22 @@ func initOrgLogoStore(ctx context.Context, s3Config configpkg.S3Config, ds *mysq
 	return ds.NewOrgLogoStore()
 }
 
+// initCampaignResultsStore builds the store for large live query results. It
+// shares the software installers bucket (distinct prefix) and is nil when that
+// bucket is not configured, in which case all results are stored in the
+// database.
+func initCampaignResultsStore(ctx context.Context, s3Config configpkg.S3Config, logger *slog.Logger) fleet.CampaignResultsStore {
+	if s3Config.SoftwareInstallersBucket == "" {
+		return nil
+	}
+	store, err := s3.NewCampaignResultsStore(s3Config)
+	if err != nil {
+		initFatal(err, "initializing S3 campaign results store")
+	}
+	logger.InfoContext(ctx, "using S3 campaign results store", "bucket", s3Config.SoftwareInstallersBucket)
+	return store
+}
+
 func createActivityBoundedContext(svc fleet.Service, ds fleet.Datastore, dbConns *common_mysql.DBConnections, logger *slog.Logger) (activity_api.Service, endpointer.HandlerRoutesFunc) {
 	legacyAuthorizer, err := authz.NewAuthorizer()
 	if err != nil {
@@ -276,6 +276,13 @@ func TestAutomationsSchedule(t *testing.T) {
 			},
 		}, nil
 	}
+	ds.NewWebhookDeliveryFunc = func(ctx context.Context, d *fleet.WebhookDelivery) (*fleet.WebhookDelivery, error) {
+		d.Status = fleet.WebhookDeliveryStatusPending
+		return d, nil
+	}
+	ds.UpdateWebhookDeliveryFunc = func(ctx context.Context, d *fleet.WebhookDelivery) error {
+		return nil
+	}
 
 	mockLocker := scheduletest.SetupMockLocker("automations", "test_instance", time.Now().UTC())
 	ds.LockFunc = mockLocker.Lock
@@ -379,7 +386,7 @@ func TestCronVulnerabilitiesCreatesDatabasesPath(t *testing.T) {
 			// nolint:errcheck
 			recover()
 		}()
-		_ = cronVulnerabilities(ctx, ds, lg, &config)
+		_ = cronVulnerabilities(ctx, ds, lg, &config, nil)
 	}()
 
 	assert.Eventually(t, func() bool {
@@ -577,6 +584,9 @@ func TestScanVulnerabilities(t *testing.T) {
 	ds.DeleteOrphanedOSVulnerabilitiesFunc = func(ctx context.Context) error {
 		return nil
 	}
+	ds.FilterExceptedSoftwareVulnerabilitiesFunc = func(ctx context.Context, vulns []fleet.SoftwareVulnerability) ([]fleet.SoftwareVulnerability, error) {
+		return vulns, nil
+	}
 	ds.ListCVEsFunc = func(ctx context.Context, maxAge time.Duration) ([]fleet.CVEMeta, error) {
 		published := time.Date(2022, time.October, 26, 14, 15, 0, 0, time.UTC)
 
@@ -599,6 +609,9 @@ func TestScanVulnerabilities(t *testing.T) {
 			},
 		}, nil
 	}
+	ds.VulnerabilityExceptedHostIDsFunc = func(ctx context.Context, cve string, softwareIDs []uint) ([]uint, error) {
+		return nil, nil
+	}
 	ds.IsHostConnectedToFleetMDMFunc = func(ctx context.Context, host *fleet.Host) (bool, error) {
 		return true, nil
 	}
@@ -613,7 +626,7 @@ func TestScanVulnerabilities(t *testing.T) {
 	}
 
 	ctx = license.NewContext(ctx, &fleet.LicenseInfo{Tier: fleet.TierPremium})
-	err := scanVulnerabilities(ctx, ds, logger, &vulnsConfig, appConfig, vulnPath)
+	err := scanVulnerabilities(ctx, ds, logger, &vulnsConfig, appConfig, vulnPath, nil)
 	require.NoError(t, err)
 
 	// ensure that nvd vulnerabilities are not deleted
@@ -777,6 +790,9 @@ func TestScanVulnerabilitiesFreeTier(t *testing.T) {
 			},
 		}, nil
 	}
+	ds.VulnerabilityExceptedHostIDsFunc = func(ctx context.Context, cve string, softwareIDs []uint) ([]uint, error) {
+		return nil, nil
+	}
 	ds.IsHostConnectedToFleetMDMFunc = func(ctx context.Context, host *fleet.Host) (bool, error) {
 		return true, nil
 	}
@@ -791,7 +807,7 @@ func TestScanVulnerabilitiesFreeTier(t *testing.T) {
 	}
 
 	ctx = license.NewContext(ctx, &fleet.LicenseInfo{Tier: fleet.TierFree})
-	err := scanVulnerabilities(ctx, ds, logger, &vulnsConfig, appConfig, vulnPath)
+	err := scanVulnerabilities(ctx, ds, logger, &vulnsConfig, appConfig, vulnPath, nil)
 	require.NoError(t, err)
 
 	require.False(t, ds.DeleteSoftwareVulnerabilitiesFuncInvoked)
@@ -866,7 +882,7 @@ func TestScanVulnerabilitiesMkdirFailsIfVulnPathIsFile(t *testing.T) {
 	}
 
 	ctx = license.NewContext(ctx, &fleet.LicenseInfo{Tier: fleet.TierPremium})
-	err = scanVulnerabilities(ctx, ds, logger, &config, appConfig, fileVulnPath)
+	err = scanVulnerabilities(ctx, ds, logger, &config, appConfig, fileVulnPath, nil)
 	require.ErrorContains(t, err, "create vulnerabilities databases directory: mkdir")
 }
 
@@ -921,7 +937,7 @@ func TestCronVulnerabilitiesSkipMkdirIfDisabled(t *testing.T) {
 	// Use schedule to test that the schedule does indeed call cronVulnerabilities.
 	ctx = license.NewContext(ctx, &fleet.LicenseInfo{Tier: fleet.TierPremium})
 	ctx, cancel := context.WithCancel(ctx)
-	s, err := newVulnerabilitiesSchedule(ctx, "test_instance", ds, slog.New(slog.DiscardHandler), &config)
+	s, err := newVulnerabilitiesSchedule(ctx, "test_instance", ds, slog.New(slog.DiscardHandler), &config, nil)
 	require.NoError(t, err)
 	s.Start()
 	t.Cleanup(func() {
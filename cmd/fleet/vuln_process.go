@@ -109,7 +109,10 @@ by an exit code of zero.`,
 			}
 			logger.InfoContext(ctx, "scanning vulnerabilities")
 			start := time.Now()
-			vulnFuncs := getVulnFuncs(ds, logger, &vulnConfig)
+			// the Fleet service isn't available in this command, the VEX
+			// documents imported from vulnerabilities.vex_path create no
+			// activities
+			vulnFuncs := getVulnFuncs(ds, logger, &vulnConfig, nil)
 			for _, vulnFunc := range vulnFuncs {
 				if err := vulnFunc.VulnFunc(ctx); err != nil {
 					return err
@@ -155,7 +158,7 @@ type NamedVulnFunc struct {
 	VulnFunc func(ctx context.Context) error
 }
 
-func getVulnFuncs(ds fleet.Datastore, logger *slog.Logger, config *config.VulnerabilitiesConfig) []NamedVulnFunc {
+func getVulnFuncs(ds fleet.Datastore, logger *slog.Logger, config *config.VulnerabilitiesConfig, newActivityFn fleet.NewActivityFunc) []NamedVulnFunc {
 	vulnFuncs := []NamedVulnFunc{
 		{
 			// Run first to ensure aggregated_stats has fresh OS version data
@@ -170,7 +173,7 @@ func getVulnFuncs(ds fleet.Datastore, logger *slog.Logger, config *config.Vulner
 		{
 			Name: "cron_vulnerabilities",
 			VulnFunc: func(ctx context.Context) error {
-				return cronVulnerabilities(ctx, ds, logger, config)
+				return cronVulnerabilities(ctx, ds, logger, config, newActivityFn)
 			},
 		},
 		{
@@ -932,6 +932,27 @@ func (cmd *GenerateGitopsCommand) generateSSOSettings(ssoSettings *fleet.SSOSett
 	if cmd.AppConfig.License.IsPremium() {
 		result[jsonFieldName(t, "EnableJITProvisioning")] = ssoSettings.EnableJITProvisioning
 	}
+	if ssoSettings.OIDC != nil {
+		ot := reflect.TypeOf(fleet.OIDCSettings{})
+		oidc := map[string]interface{}{
+			jsonFieldName(ot, "IssuerURL"):            ssoSettings.OIDC.IssuerURL,
+			jsonFieldName(ot, "ClientID"):             ssoSettings.OIDC.ClientID,
+			jsonFieldName(ot, "Scopes"):               ssoSettings.OIDC.Scopes,
+			jsonFieldName(ot, "EmailClaim"):           ssoSettings.OIDC.EmailClaim,
+			jsonFieldName(ot, "NameClaim"):            ssoSettings.OIDC.NameClaim,
+			jsonFieldName(ot, "GlobalRoleClaim"):      ssoSettings.OIDC.GlobalRoleClaim,
+			jsonFieldName(ot, "FleetRoleClaimPrefix"): ssoSettings.OIDC.FleetRoleClaimPrefix,
+		}
+		if ssoSettings.OIDC.ClientSecret != "" {
+			// The client secret is always masked by the API.
+			oidc[jsonFieldName(ot, "ClientSecret")] = cmd.AddComment("default.yml", "TODO: Add your OIDC client secret here")
+			cmd.Messages.SecretWarnings = append(cmd.Messages.SecretWarnings, SecretWarning{
+				Filename: "default.yml",
+				Key:      "org_settings.sso_settings.oidc.client_secret",
+			})
+		}
+		result[jsonFieldName(t, "OIDC")] = oidc
+	}
 	if !cmd.CLI.Bool("insecure") {
 		if ssoSettings.Metadata != "" {
 			result[jsonFieldName(t, "Metadata")] = cmd.AddComment("default.yml", "TODO: Add your SSO metadata here")
@@ -985,6 +1006,7 @@ func (cmd *GenerateGitopsCommand) generateIntegrations(filePath string, integrat
 		// https://github.com/fleetdm/fleet/issues/20287
 		delete(result, "jira")
 		delete(result, "zendesk")
+		delete(result, "servicenow")
 
 		// Team integrations don't have secrets right now, so just return as-is.
 		return result, nil
@@ -1021,6 +1043,15 @@ func (cmd *GenerateGitopsCommand) generateIntegrations(filePath string, integrat
 				})
 			}
 		}
+		if serviceNow, ok := result["servicenow"]; ok && serviceNow != nil {
+			for _, intg := range serviceNow.([]any) {
+				intg.(map[string]any)["password"] = cmd.AddComment(filePath, "TODO: Add your ServiceNow password here")
+				cmd.Messages.SecretWarnings = append(cmd.Messages.SecretWarnings, SecretWarning{
+					Filename: "default.yml",
+					Key:      "integrations.servicenow.password",
+				})
+			}
+		}
 		if googleWorkspace, ok := result["google_workspace"]; ok && googleWorkspace != nil {
 			for _, intg := range googleWorkspace.([]any) {
 				intgMap := intg.(map[string]any)
@@ -1936,6 +1967,9 @@ func (cmd *GenerateGitopsCommand) generateQueries(teamId *uint) ([]map[string]in
 		if query.LabelsIncludeAll != nil && cmd.AppConfig.License.IsPremium() {
 			querySpec["labels_include_all"] = fleet.LabelIdentsToNames(query.LabelsIncludeAll)
 		}
+		if len(query.LogDestinations) > 0 {
+			querySpec[jsonFieldName(t, "LogDestinations")] = []string(query.LogDestinations)
+		}
 
 		result[i] = querySpec
 	}
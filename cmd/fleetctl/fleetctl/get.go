@@ -499,6 +499,8 @@ func getCommand() *cli.Command {
 			getMDMAppleBMCommand(),
 			getMDMCommandResultsCommand(),
 			getMDMCommandsCommand(),
+			getChartCommand(),
+			getSBOMCommand(),
 		}),
 	}
 }
@@ -679,6 +681,7 @@ func getReportsCommand() *cli.Command {
 							DiscardData:        query.DiscardData,
 							LabelsIncludeAny:   fleet.LabelIdentsToNames(query.LabelsIncludeAny),
 							LabelsIncludeAll:   fleet.LabelIdentsToNames(query.LabelsIncludeAll),
+							LogDestinations:    query.LogDestinations,
 						}); err != nil {
 							return fmt.Errorf("unable to print query: %w", err)
 						}
@@ -1282,6 +1285,177 @@ func getCarveCommand() *cli.Command {
 	}
 }
 
+func getChartCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "chart",
+		Usage:     "Export the raw history of a dashboard chart metric (e.g. uptime, cve, policy) as JSON or CSV",
+		UsageText: "fleetctl get chart [options] <metric>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "start",
+				Usage: "Start of the export range, as YYYY-MM-DD or an RFC 3339 timestamp (default: 7 days before --end)",
+			},
+			&cli.StringFlag{
+				Name:  "end",
+				Usage: "End of the export range, as YYYY-MM-DD or an RFC 3339 timestamp (default: now)",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: "json",
+				Usage: "Output format: json or csv",
+			},
+			&cli.BoolFlag{
+				Name:  "with-host-ids",
+				Usage: "Include the IDs of the hosts of each history row",
+			},
+			&cli.UintFlag{
+				Name:    fleetFlagName,
+				Aliases: []string{"team"},
+				Usage:   "Only count hosts in this fleet ID (0 for hosts with no fleet)",
+			},
+			&cli.StringFlag{
+				Name:  "label-ids",
+				Usage: "Comma-separated label IDs; only count hosts in these labels",
+			},
+			&cli.StringFlag{
+				Name:  "platforms",
+				Usage: "Comma-separated platforms; only count hosts on these platforms",
+			},
+			&cli.StringFlag{
+				Name:  "policy-ids",
+				Usage: "Comma-separated policy IDs (policy metric only)",
+			},
+			outfileFlag(),
+			configFlag(),
+			contextFlag(),
+			debugFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			metric := c.Args().First()
+			if metric == "" {
+				return errors.New("must provide a chart metric as the first argument")
+			}
+
+			query := url.Values{}
+			query.Set("format", c.String("format"))
+			for _, p := range []struct{ flag, param string }{{"start", "start_time"}, {"end", "end_time"}} {
+				if v := c.String(p.flag); v != "" {
+					t, err := parseChartExportTime(v)
+					if err != nil {
+						return fmt.Errorf("invalid --%s: %w", p.flag, err)
+					}
+					query.Set(p.param, t.Format(time.RFC3339))
+				}
+			}
+			if c.Bool("with-host-ids") {
+				query.Set("with_host_ids", "true")
+			}
+			if c.IsSet(fleetFlagName) {
+				query.Set("fleet_id", strconv.FormatUint(uint64(c.Uint(fleetFlagName)), 10))
+			}
+			for _, p := range []struct{ flag, param string }{
+				{"label-ids", "label_ids"}, {"platforms", "platforms"}, {"policy-ids", "policy_ids"},
+			} {
+				if v := c.String(p.flag); v != "" {
+					query.Set(p.param, v)
+				}
+			}
+
+			client, err := clientFromCLI(c)
+			if err != nil {
+				return err
+			}
+
+			out := c.App.Writer
+			if outFile := getOutfile(c); outFile != "" {
+				f, err := secure.OpenFile(outFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, defaultFileMode)
+				if err != nil {
+					return fmt.Errorf("open out file: %w", err)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			return client.ExportChart(metric, query, out)
+		},
+	}
+}
+
+func getSBOMCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "sbom",
+		Usage:     "Export the software bill of materials (SBOM) of a host or fleet as CycloneDX or SPDX JSON",
+		UsageText: "fleetctl get sbom [options] (--host <identifier> | --fleet <id>)",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "host",
+				Usage: "Export the SBOM of the host with this hostname, UUID or serial number",
+			},
+			&cli.UintFlag{
+				Name:    fleetFlagName,
+				Aliases: []string{"team"},
+				Usage:   "Export the SBOM of all hosts in this fleet ID (0 for hosts with no fleet)",
+			},
+			&cli.StringFlag{
+				Name:  "format",
+				Value: string(fleet.SBOMFormatCycloneDX),
+				Usage: "Output format: cyclonedx (CycloneDX 1.5) or spdx (SPDX 2.3)",
+			},
+			outfileFlag(),
+			configFlag(),
+			contextFlag(),
+			debugFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			identifier := c.String("host")
+			if (identifier == "") == !c.IsSet(fleetFlagName) {
+				return fmt.Errorf("must provide exactly one of --host or --%s", fleetFlagName)
+			}
+			format, err := fleet.ParseSBOMFormat(c.String("format"))
+			if err != nil {
+				return err
+			}
+
+			client, err := clientFromCLI(c)
+			if err != nil {
+				return err
+			}
+
+			out := c.App.Writer
+			if outFile := getOutfile(c); outFile != "" {
+				f, err := secure.OpenFile(outFile, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, defaultFileMode)
+				if err != nil {
+					return fmt.Errorf("open out file: %w", err)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			if identifier == "" {
+				return client.TeamSBOM(c.Uint(fleetFlagName), string(format), out)
+			}
+			host, err := client.HostByIdentifier(identifier)
+			if err != nil {
+				return fmt.Errorf("could not get host: %w", err)
+			}
+			return client.HostSBOM(host.ID, string(format), out)
+		},
+	}
+}
+
+// parseChartExportTime accepts either a date (interpreted as midnight UTC) or
+// an RFC 3339 timestamp.
+func parseChartExportTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither YYYY-MM-DD nor an RFC 3339 timestamp", v)
+	}
+	return t, nil
+}
+
 func log(c *cli.Context, msg ...interface{}) {
 	fmt.Fprint(c.App.Writer, msg...)
 }
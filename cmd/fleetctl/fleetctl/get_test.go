@@ -3794,3 +3794,42 @@ func TestFormatXML(t *testing.T) {
 		})
 	}
 }
+
+func TestParseChartExportTime(t *testing.T) {
+	got, err := parseChartExportTime("2026-03-01")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), got)
+
+	got, err = parseChartExportTime("2026-03-01T10:30:00-05:00")
+	require.NoError(t, err)
+	assert.True(t, time.Date(2026, 3, 1, 15, 30, 0, 0, time.UTC).Equal(got))
+
+	_, err = parseChartExportTime("last week")
+	require.ErrorContains(t, err, "neither YYYY-MM-DD nor an RFC 3339 timestamp")
+}
+
+func TestGetChartRequiresMetric(t *testing.T) {
+	testing_utils.RunServerWithMockedDS(t)
+	runAppCheckErr(t, []string{"get", "chart"}, "must provide a chart metric as the first argument")
+}
+
+func TestGetSBOM(t *testing.T) {
+	_, ds := testing_utils.RunServerWithMockedDS(t)
+	ds.ListSoftwareFunc = func(ctx context.Context, opt fleet.SoftwareListOptions) ([]fleet.Software, *fleet.PaginationMetadata, error) {
+		require.NotNil(t, opt.TeamID)
+		require.Zero(t, *opt.TeamID)
+		return []fleet.Software{{ID: 1, Name: "wget", Version: "1.24.5", Source: "homebrew_packages"}}, nil, nil
+	}
+
+	runAppCheckErr(t, []string{"get", "sbom"}, "must provide exactly one of --host or --fleet")
+	runAppCheckErr(t, []string{"get", "sbom", "--host", "foo", "--fleet", "1"}, "must provide exactly one of --host or --fleet")
+	runAppCheckErr(t, []string{"get", "sbom", "--fleet", "0", "--format", "xml"}, `invalid format "xml"`)
+
+	out := runAppForTest(t, []string{"get", "sbom", "--fleet", "0"})
+	assert.Contains(t, out, `"bomFormat": "CycloneDX"`)
+	assert.Contains(t, out, `"purl": "pkg:brew/wget@1.24.5"`)
+
+	out = runAppForTest(t, []string{"get", "sbom", "--fleet", "0", "--format", "spdx"})
+	assert.Contains(t, out, `"spdxVersion": "SPDX-2.3"`)
+	assert.Contains(t, out, `"referenceLocator": "pkg:brew/wget@1.24.5"`)
+}
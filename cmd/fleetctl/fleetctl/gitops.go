@@ -31,6 +31,8 @@ func gitopsCommand() *cli.Command {
 	var (
 		flFilenames             cli.StringSlice
 		flDryRun                bool
+		flPlan                  bool
+		flPlanFormat            string
 		flDeleteOtherTeams      bool
 		flAllowUnknownKeys      bool
 		flConcurrentIconUploads int
@@ -43,7 +45,6 @@ func gitopsCommand() *cli.Command {
 		Flags: []cli.Flag{
 			&cli.StringSliceFlag{
 				Name:        "f",
-				Required:    true,
 				EnvVars:     []string{"FILENAME"},
 				Destination: &flFilenames,
 				Usage:       "The file(s) with the GitOps configuration.",
@@ -61,6 +62,19 @@ func gitopsCommand() *cli.Command {
 				Destination: &flDryRun,
 				Usage:       "Do not apply the file(s), just validate",
 			},
+			&cli.BoolFlag{
+				Name:        "plan",
+				EnvVars:     []string{"PLAN"},
+				Destination: &flPlan,
+				Usage:       "Do not apply the file(s), show the changes they would make to the Fleet server (implies --dry-run). Exits with status 2 when there are changes",
+			},
+			&cli.StringFlag{
+				Name:        "plan-format",
+				EnvVars:     []string{"PLAN_FORMAT"},
+				Destination: &flPlanFormat,
+				Value:       gitopsPlanFormatText,
+				Usage:       "Output format of --plan, either 'text' or 'json'",
+			},
 			&cli.BoolFlag{
 				Name:        "allow-unknown-keys",
 				EnvVars:     []string{"ALLOW_UNKNOWN_KEYS"},
@@ -89,13 +103,36 @@ func gitopsCommand() *cli.Command {
 			enableLogTopicsFlag(),
 			disableLogTopicsFlag(),
 		},
+		Subcommands: []*cli.Command{
+			gitopsDriftCommand(),
+		},
 		Action: func(c *cli.Context) error {
+			// -f is not a required flag since the subcommands don't use it.
+			if !c.IsSet("f") {
+				return errors.New(`Required flag "f" not set`)
+			}
+
 			// Apply log topic overrides from CLI flags.
 			applyLogTopicFlags(c)
 
 			logDeprecatedFlagName(c, "delete-other-teams", "delete-other-fleets")
 			logDeprecatedEnvVar(c, "DELETE_OTHER_TEAMS", "DELETE_OTHER_FLEETS")
 
+			if flPlanFormat != gitopsPlanFormatText && flPlanFormat != gitopsPlanFormatJSON {
+				return fmt.Errorf("invalid --plan-format %q, must be one of 'text' or 'json'", flPlanFormat)
+			}
+			// The plan is computed from the dry run of the files, and rendered to the
+			// standard output once it succeeds. The JSON plan must be the only thing
+			// written to the standard output, so logs go to the standard error.
+			planWriter := c.App.Writer
+			if flPlan {
+				flDryRun = true
+				if flPlanFormat == gitopsPlanFormatJSON {
+					c.App.Writer = c.App.ErrWriter
+					defer func() { c.App.Writer = planWriter }()
+				}
+			}
+
 			gitOpsOpts := spec.GitOpsOptions{AllowUnknownKeys: flAllowUnknownKeys}
 
 			logf := func(format string, a ...interface{}) {
@@ -373,6 +410,21 @@ func gitopsCommand() *cli.Command {
 				return err
 			}
 
+			var planBuilder *gitopsPlanBuilder
+			if flPlan {
+				planBuilder = newGitOpsPlanBuilder(fleetClient, appConfig)
+				for _, configFile := range configs {
+					controls := configFile.Config.Controls
+					if configFile.IsGlobalConfig && !controls.Set() {
+						controls = noTeamControls
+					}
+					teamID := teamIDLookup[configFile.Config.CoercedTeamName()]
+					if err := planBuilder.addConfig(configFile.Config, configFile.Filename, teamID, controls); err != nil {
+						return fmt.Errorf("computing plan for %s: %w", configFile.Filename, err)
+					}
+				}
+			}
+
 			for _, configFile := range configs {
 				config := configFile.Config
 				flFilename := configFile.Filename
@@ -729,6 +781,14 @@ func gitopsCommand() *cli.Command {
 						}
 						if flDryRun {
 							_, _ = fmt.Fprintf(c.App.Writer, "[!] would've deleted team %s\n", team.Name)
+							if planBuilder != nil {
+								planBuilder.add(fleet.GitOpsResourceChange{
+									Kind:  fleet.GitOpsResourceFleetSettings,
+									Fleet: team.Name,
+									Name:  team.Name,
+									Op:    fleet.GitOpsChangeRemove,
+								})
+							}
 						} else {
 							_, _ = fmt.Fprintf(c.App.Writer, "[-] deleting team %s\n", team.Name)
 							if err := fleetClient.DeleteTeam(team.ID); err != nil {
@@ -769,6 +829,8 @@ func gitopsCommand() *cli.Command {
 						return err
 					}
 				}
+
+				saveGitOpsSnapshots(c, fleetClient, configs)
 			}
 
 			if flDryRun {
@@ -777,11 +839,55 @@ func gitopsCommand() *cli.Command {
 				_, _ = fmt.Fprintf(c.App.Writer, "[!] gitops succeeded\n")
 			}
 
+			if planBuilder != nil {
+				plan := planBuilder.Plan()
+				if err := renderGitOpsPlan(planWriter, plan, flPlanFormat); err != nil {
+					return fmt.Errorf("rendering plan: %w", err)
+				}
+				if plan.HasChanges() {
+					return cli.Exit("", gitopsPlanChangesExitCode)
+				}
+			}
+
 			return nil
 		},
 	}
 }
 
+// saveGitOpsSnapshots captures the state applied for the global config and
+// each fleet of the run, which the server compares the live state against to
+// detect changes made outside of GitOps. Failing to capture a snapshot does
+// not fail the run, e.g. if the server doesn't support drift detection.
+func saveGitOpsSnapshots(c *cli.Context, fleetClient *service.Client, configs []ConfigFile) {
+	teams, err := fleetClient.ListTeams("")
+	if err != nil {
+		_, _ = fmt.Fprintf(c.App.ErrWriter, "[!] could not save GitOps snapshots: %s\n", err)
+		return
+	}
+	teamIDs := make(map[string]uint, len(teams))
+	for _, team := range teams {
+		teamIDs[team.Name] = team.ID
+	}
+
+	for _, configFile := range configs {
+		var teamID *uint
+		if !configFile.IsGlobalConfig {
+			// the unassigned hosts have no settings of their own to drift from
+			if configFile.Config.IsNoTeam() || configFile.Config.IsUnassignedTeam() {
+				continue
+			}
+			id, ok := teamIDs[*configFile.Config.TeamName]
+			if !ok {
+				continue
+			}
+			teamID = &id
+		}
+		if err := fleetClient.SaveGitOpsSnapshot(teamID); err != nil {
+			_, _ = fmt.Fprintf(c.App.ErrWriter, "[!] could not save GitOps snapshot of %s: %s\n", configFile.Filename, err)
+		}
+	}
+}
+
 // ConfigFile pairs a parsed gitops config with its source filename, used
 // while orchestrating a multi-file gitops run.
 type ConfigFile struct {
@@ -0,0 +1,128 @@
+package fleetctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// gitopsDriftExitCode is the exit status of `fleetctl gitops drift` when
+// resources were changed outside of GitOps, so that CI can tell it apart from
+// failures (exit status 1).
+const gitopsDriftExitCode = 2
+
+func gitopsDriftCommand() *cli.Command {
+	var (
+		flFleet  string
+		flFormat string
+	)
+	return &cli.Command{
+		Name:      "drift",
+		Usage:     "Show the changes made outside of GitOps since the last `fleetctl gitops` run",
+		UsageText: `fleetctl gitops drift [options]`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:        fleetFlagName,
+				Aliases:     []string{"team"},
+				Destination: &flFleet,
+				Usage:       "Name of the fleet to check, all the fleets and the global config are checked if not set",
+			},
+			&cli.StringFlag{
+				Name:        "format",
+				Destination: &flFormat,
+				Value:       gitopsPlanFormatText,
+				Usage:       "Output format, either 'text' or 'json'. Exits with status 2 when there is drift",
+			},
+			configFlag(),
+			contextFlag(),
+			debugFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			if flFormat != gitopsPlanFormatText && flFormat != gitopsPlanFormatJSON {
+				return fmt.Errorf("invalid --format %q, must be one of 'text' or 'json'", flFormat)
+			}
+
+			fleetClient, err := clientFromCLI(c)
+			if err != nil {
+				return err
+			}
+
+			var teamID *uint
+			if flFleet != "" {
+				teams, err := fleetClient.ListTeams("")
+				if err != nil {
+					return err
+				}
+				for _, team := range teams {
+					if norm.NFC.String(strings.ToLower(team.Name)) == norm.NFC.String(strings.ToLower(flFleet)) {
+						teamID = &team.ID
+						break
+					}
+				}
+				if teamID == nil {
+					return fmt.Errorf("fleet %q not found", flFleet)
+				}
+			}
+
+			drift, err := fleetClient.GetGitOpsDrift(teamID)
+			if err != nil {
+				return err
+			}
+			if err := renderGitOpsDrift(c.App.Writer, drift, flFormat); err != nil {
+				return fmt.Errorf("rendering drift: %w", err)
+			}
+			for _, d := range drift {
+				if len(d.Changes) > 0 {
+					return cli.Exit("", gitopsDriftExitCode)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// renderGitOpsDrift writes the drift to w in the given format. The changes are
+// rendered like the ones of `fleetctl gitops --plan`, the old values being the
+// ones applied by GitOps and the new values the live ones.
+func renderGitOpsDrift(w io.Writer, drift []*fleet.GitOpsDrift, format string) error {
+	if format == gitopsPlanFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(map[string]any{"drift": drift})
+	}
+
+	if len(drift) == 0 {
+		_, err := fmt.Fprintln(w, "No GitOps snapshot found. Run `fleetctl gitops` to apply your configuration first.")
+		return err
+	}
+	for i, d := range drift {
+		scope := "Global config"
+		if d.TeamName != nil {
+			scope = fmt.Sprintf("Fleet %q", *d.TeamName)
+		}
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if len(d.Changes) == 0 {
+			if _, err := fmt.Fprintf(w, "%s: no drift since GitOps applied it on %s.\n", scope, d.AppliedAt.Format("2006-01-02 15:04:05 MST")); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%s: %d resource(s) changed outside of GitOps since it was applied on %s.\n",
+			scope, len(d.Changes), d.AppliedAt.Format("2006-01-02 15:04:05 MST")); err != nil {
+			return err
+		}
+		if err := renderGitOpsChanges(w, d.Changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package fleetctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/cmd/fleetctl/fleetctl/testing_utils"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/service"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+func TestGitOpsDriftCommand(t *testing.T) {
+	// Cannot run t.Parallel() because runServerWithMockedDS sets the FLEET_SERVER_ADDRESS
+	// environment variable.
+
+	license := &fleet.LicenseInfo{Tier: fleet.TierPremium, Expiration: time.Now().Add(24 * time.Hour)}
+	_, ds := testing_utils.RunServerWithMockedDS(t, &service.TestServerOpts{License: license})
+
+	team := &fleet.Team{ID: 1, Name: "Workstations"}
+	ds.ListTeamsFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListOptions) ([]*fleet.Team, error) {
+		return []*fleet.Team{team}, nil
+	}
+	ds.TeamWithExtrasFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return team, nil
+	}
+	ds.ListTeamPoliciesFunc = func(ctx context.Context, teamID uint, opts fleet.ListOptions, iopts fleet.ListOptions, automationType fleet.PolicyAutomationType, platform string) ([]*fleet.Policy, []*fleet.Policy, error) {
+		return []*fleet.Policy{{PolicyData: fleet.PolicyData{ID: 1, Name: "Firewall", Query: "SELECT 2;"}}}, nil, nil
+	}
+	ds.ListQueriesFunc = func(ctx context.Context, opt fleet.ListQueryOptions) ([]*fleet.Query, int, int, *fleet.PaginationMetadata, error) {
+		return nil, 0, 0, nil, nil
+	}
+	ds.ListLabelsFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListOptions, includeHostCounts bool) ([]*fleet.Label, error) {
+		return nil, nil
+	}
+
+	appliedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	ds.GitOpsSnapshotFunc = func(ctx context.Context, teamID uint) (*fleet.GitOpsSnapshot, error) {
+		require.Equal(t, team.ID, teamID)
+		// the snapshot has all the fleet settings, so that only the policy
+		// differs from the live state
+		settings, err := fleet.NormalizeGitOpsFields(team.Config)
+		require.NoError(t, err)
+		return &fleet.GitOpsSnapshot{
+			TeamID: teamID,
+			Resources: fleet.GitOpsResources{
+				fleet.GitOpsResourceFleetSettings: {team.Name: settings},
+				fleet.GitOpsResourcePolicy:        {"Firewall": {"query": "SELECT 1;"}},
+				fleet.GitOpsResourceReport:        {},
+				fleet.GitOpsResourceLabel:         {},
+			},
+			AppliedAt: appliedAt,
+		}, nil
+	}
+
+	out, err := runAppNoChecks([]string{"gitops", "drift", "--fleet", "workstations"})
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, gitopsDriftExitCode, exitErr.ExitCode())
+	require.Equal(t, `Fleet "Workstations": 1 resource(s) changed outside of GitOps since it was applied on 2026-10-01 12:00:00 UTC.
+[~] policy "Firewall" (fleet "Workstations")
+      query: "SELECT 1;" -> "SELECT 2;"
+`, out.String())
+
+	out, err = runAppNoChecks([]string{"gitops", "drift", "--fleet", "Workstations", "--format", "json"})
+	require.ErrorAs(t, err, &exitErr)
+	var decoded struct {
+		Drift []*fleet.GitOpsDrift `json:"drift"`
+	}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	require.Len(t, decoded.Drift, 1)
+	require.Equal(t, "Firewall", decoded.Drift[0].Changes[0].Name)
+
+	runAppCheckErr(t, []string{"gitops", "drift", "--fleet", "Servers"}, `fleet "Servers" not found`)
+	runAppCheckErr(t, []string{"gitops", "drift", "--format", "yaml"}, `invalid --format "yaml"`)
+}
+
+func TestRenderGitOpsDrift(t *testing.T) {
+	appliedAt := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	drift := []*fleet.GitOpsDrift{
+		{AppliedAt: appliedAt, Changes: []fleet.GitOpsResourceChange{}},
+		{
+			TeamID: new(uint(2)), TeamName: new("Servers"), AppliedAt: appliedAt,
+			Changes: []fleet.GitOpsResourceChange{
+				{Kind: fleet.GitOpsResourceLabel, Fleet: "Servers", Name: "Ubuntu", Op: fleet.GitOpsChangeAdd},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, renderGitOpsDrift(&buf, drift, gitopsPlanFormatText))
+	require.Equal(t, `Global config: no drift since GitOps applied it on 2026-10-01 12:00:00 UTC.
+
+Fleet "Servers": 1 resource(s) changed outside of GitOps since it was applied on 2026-10-01 12:00:00 UTC.
+[+] label "Ubuntu" (fleet "Servers")
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, renderGitOpsDrift(&buf, nil, gitopsPlanFormatText))
+	require.Equal(t, "No GitOps snapshot found. Run `fleetctl gitops` to apply your configuration first.\n", buf.String())
+}
@@ -0,0 +1,757 @@
+package fleetctl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/pkg/spec"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/platform/endpointer"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/service"
+)
+
+const (
+	gitopsPlanFormatText = "text"
+	gitopsPlanFormatJSON = "json"
+
+	// gitopsPlanChangesExitCode is the exit status of `fleetctl gitops --plan`
+	// when the GitOps files would change the Fleet server, so that CI can tell
+	// it apart from failures (exit status 1).
+	gitopsPlanChangesExitCode = 2
+)
+
+// gitopsPlanClient is the subset of the Fleet client used to fetch the
+// current state of the resources compared by `fleetctl gitops --plan`.
+type gitopsPlanClient interface {
+	generateGitopsClient
+	ListSecretVariables() ([]fleet.SecretVariableIdentifier, error)
+}
+
+// gitopsPlan is the set of changes `fleetctl gitops` would make to the
+// server, as rendered by the --plan flag.
+type gitopsPlan struct {
+	Changes []fleet.GitOpsResourceChange `json:"changes"`
+	Summary gitopsPlanSummary            `json:"summary"`
+}
+
+type gitopsPlanSummary struct {
+	Add    int `json:"add"`
+	Change int `json:"change"`
+	Remove int `json:"remove"`
+}
+
+func (p *gitopsPlan) HasChanges() bool {
+	return len(p.Changes) > 0
+}
+
+// gitopsPlanBuilder builds the plan of a gitops run, one GitOps file at a
+// time, by comparing the parsed files against the current server state.
+type gitopsPlanBuilder struct {
+	client    gitopsPlanClient
+	appConfig *fleet.EnrichedAppConfig
+	plan      gitopsPlan
+
+	// secretVariables are the names of the secret variables on the server,
+	// loaded on first use.
+	secretVariables map[string]struct{}
+}
+
+func newGitOpsPlanBuilder(client gitopsPlanClient, appConfig *fleet.EnrichedAppConfig) *gitopsPlanBuilder {
+	return &gitopsPlanBuilder{client: client, appConfig: appConfig}
+}
+
+// addConfig adds the changes of a parsed GitOps file to the plan. teamID is
+// the ID of the existing fleet of the file, nil if the fleet does not exist
+// yet (and ignored for the global file). controls are the controls applied
+// along with the file, which for the global file may come from the
+// unassigned file.
+func (b *gitopsPlanBuilder) addConfig(config *spec.GitOps, filename string, teamID *uint, controls spec.GitOpsControls) error {
+	exceptions := b.appConfig.GitOpsConfig.Exceptions
+	baseDir := filepath.Dir(filename)
+
+	switch {
+	case config.IsGlobal():
+		if err := b.addOrgSettings(config); err != nil {
+			return err
+		}
+		if err := b.addPolicies("", nil, config.Policies); err != nil {
+			return err
+		}
+		if err := b.addReports("", nil, config.Queries); err != nil {
+			return err
+		}
+		if config.LabelsPresent || !exceptions.Labels {
+			if err := b.addLabels("", ptr.Uint(0), config.Labels); err != nil {
+				return err
+			}
+		}
+		// Global controls apply to unassigned hosts.
+		if controls.Set() {
+			if err := b.addScripts("", ptr.Uint(0), baseDir, controls.Scripts); err != nil {
+				return err
+			}
+			if err := b.addProfiles("", ptr.Uint(0), baseDir, controls); err != nil {
+				return err
+			}
+		}
+
+	case config.IsNoTeam() || config.IsUnassignedTeam():
+		fleetName := *config.TeamName
+		if err := b.addPolicies(fleetName, ptr.Uint(0), config.Policies); err != nil {
+			return err
+		}
+		if b.appConfig.License.IsPremium() && (config.SoftwarePresent || !exceptions.Software) {
+			if err := b.addSoftware(fleetName, ptr.Uint(0), config.Software); err != nil {
+				return err
+			}
+		}
+
+	default:
+		fleetName := *config.TeamName
+		if err := b.addFleetSettings(config, teamID); err != nil {
+			return err
+		}
+		if err := b.addPolicies(fleetName, teamID, config.Policies); err != nil {
+			return err
+		}
+		if err := b.addReports(fleetName, teamID, config.Queries); err != nil {
+			return err
+		}
+		if config.LabelsPresent || !exceptions.Labels {
+			if err := b.addLabels(fleetName, teamID, config.Labels); err != nil {
+				return err
+			}
+		}
+		if controls.Set() {
+			if err := b.addScripts(fleetName, teamID, baseDir, controls.Scripts); err != nil {
+				return err
+			}
+			if err := b.addProfiles(fleetName, teamID, baseDir, controls); err != nil {
+				return err
+			}
+		}
+		if config.SoftwarePresent || !exceptions.Software {
+			if err := b.addSoftware(fleetName, teamID, config.Software); err != nil {
+				return err
+			}
+		}
+	}
+
+	if config.SecretsPresent || !exceptions.Secrets {
+		if err := b.addEnrollSecrets(config, teamID); err != nil {
+			return err
+		}
+	}
+	return b.addSecretVariables(config.FleetSecrets)
+}
+
+// Plan returns the plan built so far, with its summary.
+func (b *gitopsPlanBuilder) Plan() *gitopsPlan {
+	plan := b.plan
+	plan.Summary = gitopsPlanSummary{}
+	if plan.Changes == nil {
+		plan.Changes = []fleet.GitOpsResourceChange{}
+	}
+	for _, change := range plan.Changes {
+		switch change.Op {
+		case fleet.GitOpsChangeAdd:
+			plan.Summary.Add++
+		case fleet.GitOpsChangeModify:
+			plan.Summary.Change++
+		case fleet.GitOpsChangeRemove:
+			plan.Summary.Remove++
+		}
+	}
+	return &plan
+}
+
+func (b *gitopsPlanBuilder) add(changes ...fleet.GitOpsResourceChange) {
+	b.plan.Changes = append(b.plan.Changes, changes...)
+}
+
+// orgSettingsNotDiffed are org_settings keys that are not part of the app
+// config returned by the server, or that are diffed as separate resources.
+var orgSettingsNotDiffed = []string{"secrets", "certificate_authorities"}
+
+func (b *gitopsPlanBuilder) addOrgSettings(config *spec.GitOps) error {
+	current, err := fleet.NormalizeGitOpsFields(b.appConfig)
+	if err != nil {
+		return err
+	}
+	desired, err := normalizeGitOpsSettings(config.OrgSettings, &fleet.AppConfig{}, orgSettingsNotDiffed)
+	if err != nil {
+		return err
+	}
+	if config.AgentOptions != nil {
+		if err := addAgentOptions(desired, config.AgentOptions); err != nil {
+			return err
+		}
+	}
+	b.add(fleet.DiffGitOpsResources(fleet.GitOpsResourceOrgSettings, "",
+		map[string]map[string]any{"org_settings": fleet.ProjectGitOpsFields(current, desired)},
+		map[string]map[string]any{"org_settings": desired},
+	)...)
+	return nil
+}
+
+func (b *gitopsPlanBuilder) addFleetSettings(config *spec.GitOps, teamID *uint) error {
+	fleetName := *config.TeamName
+	desired, err := normalizeGitOpsSettings(config.TeamSettings, &fleet.TeamConfig{}, []string{"secrets"})
+	if err != nil {
+		return err
+	}
+	if config.AgentOptions != nil {
+		if err := addAgentOptions(desired, config.AgentOptions); err != nil {
+			return err
+		}
+	}
+
+	current := map[string]map[string]any{}
+	if teamID != nil {
+		team, err := b.client.GetTeam(*teamID)
+		if err != nil {
+			return fmt.Errorf("getting fleet %q: %w", fleetName, err)
+		}
+		teamConfig, err := fleet.NormalizeGitOpsFields(team.Config)
+		if err != nil {
+			return err
+		}
+		current[fleetName] = fleet.ProjectGitOpsFields(teamConfig, desired)
+	}
+	b.add(fleet.DiffGitOpsResources(fleet.GitOpsResourceFleetSettings, fleetName, current,
+		map[string]map[string]any{fleetName: desired})...)
+	return nil
+}
+
+// normalizeGitOpsSettings returns the settings of a GitOps file with their
+// new key names rewritten to the names returned by the server (the json tags
+// of target), without the skipped keys.
+func normalizeGitOpsSettings(settings map[string]any, target any, skip []string) (map[string]any, error) {
+	filtered := make(map[string]any, len(settings))
+	for k, v := range settings {
+		filtered[k] = v
+	}
+	for _, k := range skip {
+		delete(filtered, k)
+	}
+	b, err := json.Marshal(filtered)
+	if err != nil {
+		return nil, fmt.Errorf("marshal settings: %w", err)
+	}
+	if rewritten, _, err := endpointer.RewriteDeprecatedKeys(b, endpointer.ExtractAliasRules(target)); err == nil {
+		b = rewritten
+	}
+	var normalized map[string]any
+	if err := json.Unmarshal(b, &normalized); err != nil {
+		return nil, fmt.Errorf("unmarshal settings: %w", err)
+	}
+	return normalized, nil
+}
+
+func addAgentOptions(settings map[string]any, agentOptions *json.RawMessage) error {
+	var opts any
+	if err := json.Unmarshal(*agentOptions, &opts); err != nil {
+		return fmt.Errorf("unmarshal agent_options: %w", err)
+	}
+	settings["agent_options"] = opts
+	return nil
+}
+
+func (b *gitopsPlanBuilder) addPolicies(fleetName string, teamID *uint, policies []*spec.GitOpsPolicySpec) error {
+	current := map[string]map[string]any{}
+	if teamID != nil || fleetName == "" {
+		existing, err := b.client.GetPolicies(teamID)
+		if err != nil {
+			return fmt.Errorf("getting policies: %w", err)
+		}
+		for _, p := range existing {
+			fields, err := fleet.NormalizeGitOpsFields(map[string]any{
+				"query":                      p.Query,
+				"description":                p.Description,
+				"resolution":                 ptr.ValOrZero(p.Resolution),
+				"platform":                   p.Platform,
+				"critical":                   p.Critical,
+				"calendar_events_enabled":    p.CalendarEventsEnabled,
+				"conditional_access_enabled": p.ConditionalAccessEnabled,
+				"labels_include_any":         fleet.LabelIdentsToNames(p.LabelsIncludeAny),
+				"labels_include_all":         fleet.LabelIdentsToNames(p.LabelsIncludeAll),
+				"labels_exclude_any":         fleet.LabelIdentsToNames(p.LabelsExcludeAny),
+				"labels_exclude_all":         fleet.LabelIdentsToNames(p.LabelsExcludeAll),
+			})
+			if err != nil {
+				return err
+			}
+			if p.Type == fleet.PolicyTypePatch {
+				// The query of patch policies is generated by the server.
+				delete(fields, "query")
+			}
+			current[p.Name] = fields
+		}
+	}
+
+	desired := make(map[string]map[string]any, len(policies))
+	for _, p := range policies {
+		fields, err := fleet.NormalizeGitOpsFields(map[string]any{
+			"query":                      p.Query,
+			"description":                p.Description,
+			"resolution":                 p.Resolution,
+			"platform":                   p.Platform,
+			"critical":                   p.Critical,
+			"calendar_events_enabled":    p.CalendarEventsEnabled,
+			"conditional_access_enabled": p.ConditionalAccessEnabled,
+			"labels_include_any":         p.LabelsIncludeAny,
+			"labels_include_all":         p.LabelsIncludeAll,
+			"labels_exclude_any":         p.LabelsExcludeAny,
+			"labels_exclude_all":         p.LabelsExcludeAll,
+		})
+		if err != nil {
+			return err
+		}
+		if p.Type == fleet.PolicyTypePatch {
+			delete(fields, "query")
+		}
+		desired[p.Name] = fields
+	}
+	b.add(fleet.DiffGitOpsResources(fleet.GitOpsResourcePolicy, fleetName, current, desired)...)
+	return nil
+}
+
+func (b *gitopsPlanBuilder) addReports(fleetName string, teamID *uint, reports []*fleet.QuerySpec) error {
+	current := map[string]map[string]any{}
+	if teamID != nil || fleetName == "" {
+		existing, err := b.client.GetQueries(teamID, nil)
+		if err != nil {
+			return fmt.Errorf("getting reports: %w", err)
+		}
+		for _, q := range existing {
+			fields, err := fleet.NormalizeGitOpsFields(reportPlanFields(fleet.QuerySpec{
+				Query:              q.Query,
+				Description:        q.Description,
+				Interval:           q.Interval,
+				ObserverCanRun:     q.ObserverCanRun,
+				Platform:           q.Platform,
+				MinOsqueryVersion:  q.MinOsqueryVersion,
+				AutomationsEnabled: q.AutomationsEnabled,
+				Logging:            q.Logging,
+				DiscardData:        q.DiscardData,
+				LabelsIncludeAny:   fleet.LabelIdentsToNames(q.LabelsIncludeAny),
+				LabelsIncludeAll:   fleet.LabelIdentsToNames(q.LabelsIncludeAll),
+				LogDestinations:    q.LogDestinations,
+			}))
+			if err != nil {
+				return err
+			}
+			current[q.Name] = fields
+		}
+	}
+
+	desired := make(map[string]map[string]any, len(reports))
+	for _, q := range reports {
+		fields, err := fleet.NormalizeGitOpsFields(reportPlanFields(*q))
+		if err != nil {
+			return err
+		}
+		desired[q.Name] = fields
+	}
+	b.add(fleet.DiffGitOpsResources(fleet.GitOpsResourceReport, fleetName, current, desired)...)
+	return nil
+}
+
+func reportPlanFields(q fleet.QuerySpec) map[string]any {
+	logging := q.Logging
+	if logging == "" {
+		logging = fleet.LoggingSnapshot
+	}
+	return map[string]any{
+		"query":               q.Query,
+		"description":         q.Description,
+		"interval":            q.Interval,
+		"observer_can_run":    q.ObserverCanRun,
+		"platform":            q.Platform,
+		"min_osquery_version": q.MinOsqueryVersion,
+		"automations_enabled": q.AutomationsEnabled,
+		"logging":             logging,
+		"discard_data":        q.DiscardData,
+		"labels_include_any":  q.LabelsIncludeAny,
+		"labels_include_all":  q.LabelsIncludeAll,
+		"log_destinations":    q.LogDestinations,
+	}
+}
+
+func (b *gitopsPlanBuilder) addLabels(fleetName string, teamID *uint, labels []*fleet.LabelSpec) error {
+	current := map[string]map[string]any{}
+	if teamID != nil {
+		existing, err := b.client.GetLabels(*teamID)
+		if err != nil {
+			return fmt.Errorf("getting labels: %w", err)
+		}
+		for _, l := range existing {
+			if l.LabelType == fleet.LabelTypeBuiltIn {
+				continue
+			}
+			fields, err := labelPlanFields(l)
+			if err != nil {
+				return err
+			}
+			current[l.Name] = fields
+		}
+	}
+
+	desired := make(map[string]map[string]any, len(labels))
+	for _, l := range labels {
+		fields, err := labelPlanFields(l)
+		if err != nil {
+			return err
+		}
+		desired[l.Name] = fields
+	}
+	b.add(fleet.DiffGitOpsResources(fleet.GitOpsResourceLabel, fleetName, current, desired)...)
+	return nil
+}
+
+func labelPlanFields(l *fleet.LabelSpec) (map[string]any, error) {
+	fields, err := fleet.NormalizeGitOpsFields(l)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range []string{"id", "name", "label_type", "team_id", "fleet_id"} {
+		delete(fields, k)
+	}
+	return fields, nil
+}
+
+func (b *gitopsPlanBuilder) addScripts(fleetName string, teamID *uint, baseDir string, scripts []fleet.BaseItem) error {
+	current := map[string]map[string]any{}
+	if teamID != nil {
+		query := ""
+		if *teamID != 0 {
+			query = fmt.Sprintf("fleet_id=%d", *teamID)
+		}
+		existing, err := b.client.ListScripts(query)
+		if err != nil {
+			return fmt.Errorf("getting scripts: %w", err)
+		}
+		for _, s := range existing {
+			contents, err := b.client.GetScriptContents(s.ID)
+			if err != nil {
+				return fmt.Errorf("getting script %q contents: %w", s.Name, err)
+			}
+			current[s.Name] = map[string]any{"contents_sha256": contentsHash(contents)}
+		}
+	}
+
+	desired := make(map[string]map[string]any, len(scripts))
+	for _, s := range scripts {
+		if s.Path == nil {
+			continue
+		}
+		path := *s.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		contents, err := readGitOpsFile(path)
+		if err != nil {
+			return fmt.Errorf("reading script %q: %w", *s.Path, err)
+		}
+		desired[filepath.Base(*s.Path)] = map[string]any{"contents_sha256": contentsHash(contents)}
+	}
+	b.add(fleet.DiffGitOpsResources(fleet.GitOpsResourceScript, fleetName, current, desired)...)
+	return nil
+}
+
+func (b *gitopsPlanBuilder) addProfiles(fleetName string, teamID *uint, baseDir string, controls spec.GitOpsControls) error {
+	current := map[string]map[string]any{}
+	if teamID != nil {
+		var listTeamID *uint
+		if *teamID != 0 {
+			listTeamID = teamID
+		}
+		existing, err := b.client.ListConfigurationProfiles(listTeamID)
+		if err != nil {
+			return fmt.Errorf("getting configuration profiles: %w", err)
+		}
+		for _, p := range existing {
+			contents, err := b.client.GetProfileContents(p.ProfileUUID)
+			if err != nil {
+				return fmt.Errorf("getting configuration profile %q contents: %w", p.Name, err)
+			}
+			current[p.Name] = map[string]any{
+				"contents_sha256":    contentsHash(contents),
+				"labels_include_all": profileLabelNames(p.LabelsIncludeAll),
+				"labels_include_any": profileLabelNames(p.LabelsIncludeAny),
+				"labels_exclude_any": profileLabelNames(p.LabelsExcludeAny),
+			}
+		}
+	}
+
+	payloads, err := service.GitOpsProfilesContents(baseDir, controls)
+	if err != nil {
+		return err
+	}
+	desired := make(map[string]map[string]any, len(payloads))
+	for _, p := range payloads {
+		desired[p.Name] = map[string]any{
+			"contents_sha256":    contentsHash(p.Contents),
+			"labels_include_all": stringsToAny(append(p.Labels, p.LabelsIncludeAll...)),
+			"labels_include_any": stringsToAny(p.LabelsIncludeAny),
+			"labels_exclude_any": stringsToAny(p.LabelsExcludeAny),
+		}
+	}
+	b.add(fleet.DiffGitOpsResources(fleet.GitOpsResourceProfile, fleetName, current, desired)...)
+	return nil
+}
+
+func profileLabelNames(labels []fleet.ConfigurationProfileLabel) []any {
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		names = append(names, l.LabelName)
+	}
+	return stringsToAny(names)
+}
+
+// stringsToAny returns the sorted strings as a normalized JSON list, so that
+// label lists compare equal regardless of their order.
+func stringsToAny(s []string) []any {
+	sorted := append([]string(nil), s...)
+	sort.Strings(sorted)
+	res := make([]any, 0, len(sorted))
+	for _, v := range sorted {
+		res = append(res, v)
+	}
+	return res
+}
+
+func (b *gitopsPlanBuilder) addSoftware(fleetName string, teamID *uint, software spec.GitOpsSoftware) error {
+	current := map[string]map[string]any{}
+	// currentByHash maps the hash of current packages to their resource name,
+	// to match packages only identified by their hash in GitOps files.
+	currentByHash := map[string]string{}
+	if teamID != nil {
+		existing, _, _, err := generateSoftwareForValidation(b.client, b.appConfig, *teamID)
+		if err != nil {
+			return fmt.Errorf("getting software: %w", err)
+		}
+		for _, item := range softwareSpecItems(existing, "packages") {
+			url, _ := item["url"].(string)
+			hash, _ := item["hash_sha256"].(string)
+			name := url
+			if name == "" {
+				name = "hash_sha256:" + hash
+			}
+			current[name] = map[string]any{"hash_sha256": hash}
+			if hash != "" {
+				currentByHash[hash] = name
+			}
+		}
+		for _, item := range softwareSpecItems(existing, "fleet_maintained_apps") {
+			if slug, _ := item["slug"].(string); slug != "" {
+				current["fleet_maintained_app:"+slug] = map[string]any{}
+			}
+		}
+		for _, item := range softwareSpecItems(existing, "app_store_apps") {
+			if id, _ := item["app_store_id"].(string); id != "" {
+				current["app_store_app:"+id] = map[string]any{}
+			}
+		}
+	}
+
+	desired := map[string]map[string]any{}
+	for _, p := range software.Packages {
+		name := p.URL
+		if name == "" {
+			name = "hash_sha256:" + p.SHA256
+			if currentName, ok := currentByHash[p.SHA256]; ok {
+				name = currentName
+			}
+		}
+		fields := map[string]any{}
+		if p.SHA256 != "" {
+			fields["hash_sha256"] = p.SHA256
+		} else if cur, ok := current[name]; ok {
+			// The hash is computed by the server when not pinned in the file.
+			fields["hash_sha256"] = cur["hash_sha256"]
+		}
+		desired[name] = fields
+	}
+	for _, fma := range software.FleetMaintainedApps {
+		desired["fleet_maintained_app:"+fma.Slug] = map[string]any{}
+	}
+	for _, app := range software.AppStoreApps {
+		desired["app_store_app:"+app.AppStoreID] = map[string]any{}
+	}
+	b.add(fleet.DiffGitOpsResources(fleet.GitOpsResourceSoftware, fleetName, current, desired)...)
+	return nil
+}
+
+func softwareSpecItems(softwareSpec map[string]any, key string) []map[string]any {
+	items, _ := softwareSpec[key].([]map[string]any)
+	return items
+}
+
+func (b *gitopsPlanBuilder) addEnrollSecrets(config *spec.GitOps, teamID *uint) error {
+	settings := config.OrgSettings
+	fleetName := ""
+	if !config.IsGlobal() {
+		settings = config.TeamSettings
+		fleetName = *config.TeamName
+	}
+	rawSecrets, ok := settings["secrets"]
+	if !ok {
+		return nil
+	}
+	desiredSecrets, _ := rawSecrets.([]*fleet.EnrollSecret)
+
+	const name = "enroll_secrets"
+	current := map[string]map[string]any{}
+	switch {
+	case config.IsGlobal():
+		secretSpec, err := b.client.GetEnrollSecretSpec()
+		if err != nil {
+			return fmt.Errorf("getting enroll secrets: %w", err)
+		}
+		current[name] = enrollSecretsPlanFields(secretSpec.Secrets)
+	case teamID != nil:
+		team, err := b.client.GetTeam(*teamID)
+		if err != nil {
+			return fmt.Errorf("getting fleet %q: %w", fleetName, err)
+		}
+		current[name] = enrollSecretsPlanFields(team.Secrets)
+	}
+	b.add(fleet.DiffGitOpsResources(fleet.GitOpsResourceSecret, fleetName, current,
+		map[string]map[string]any{name: enrollSecretsPlanFields(desiredSecrets)})...)
+	return nil
+}
+
+// enrollSecretsPlanFields returns the fields compared for enroll secrets. The
+// secrets field is masked by fleet.DiffGitOpsFields.
+func enrollSecretsPlanFields(secrets []*fleet.EnrollSecret) map[string]any {
+	values := make([]string, 0, len(secrets))
+	for _, s := range secrets {
+		values = append(values, s.Secret)
+	}
+	return map[string]any{
+		"count":   float64(len(values)),
+		"secrets": stringsToAny(values),
+	}
+}
+
+// addSecretVariables adds the FLEET_SECRET_ variables referenced by the GitOps
+// file that do not exist on the server yet. The value of existing secret
+// variables can't be retrieved, so they are never reported as changed.
+func (b *gitopsPlanBuilder) addSecretVariables(fleetSecrets map[string]string) error {
+	if len(fleetSecrets) == 0 {
+		return nil
+	}
+	if b.secretVariables == nil {
+		existing, err := b.client.ListSecretVariables()
+		if err != nil {
+			return fmt.Errorf("getting secret variables: %w", err)
+		}
+		b.secretVariables = make(map[string]struct{}, len(existing))
+		for _, v := range existing {
+			b.secretVariables[v.Name] = struct{}{}
+		}
+	}
+
+	names := make([]string, 0, len(fleetSecrets))
+	for name := range fleetSecrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		secretName := strings.TrimPrefix(name, fleet.ServerSecretPrefix)
+		if _, ok := b.secretVariables[secretName]; ok {
+			continue
+		}
+		// Only report each secret variable once across GitOps files.
+		b.secretVariables[secretName] = struct{}{}
+		b.add(fleet.GitOpsResourceChange{
+			Kind:   fleet.GitOpsResourceSecret,
+			Name:   name,
+			Op:     fleet.GitOpsChangeAdd,
+			Fields: []fleet.GitOpsFieldChange{{Field: "value", New: fleet.MaskedPassword}},
+		})
+	}
+	return nil
+}
+
+func contentsHash(contents []byte) string {
+	sum := sha256.Sum256(bytes.TrimSpace(contents))
+	return hex.EncodeToString(sum[:])
+}
+
+// readGitOpsFile reads a file referenced by a GitOps file, expanding its
+// environment variables the way they are when applied.
+func readGitOpsFile(path string) ([]byte, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return spec.ExpandEnvBytesIgnoreSecrets(contents)
+}
+
+// renderGitOpsPlan writes the plan to w in the given format.
+func renderGitOpsPlan(w io.Writer, plan *gitopsPlan, format string) error {
+	if format == gitopsPlanFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(plan)
+	}
+
+	if !plan.HasChanges() {
+		_, err := fmt.Fprintln(w, "No changes. The Fleet server matches the GitOps configuration.")
+		return err
+	}
+	if err := renderGitOpsChanges(w, plan.Changes); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\nPlan: %d to add, %d to change, %d to remove.\n",
+		plan.Summary.Add, plan.Summary.Change, plan.Summary.Remove)
+	return err
+}
+
+// renderGitOpsChanges writes the changes to w, one line per resource followed
+// by one line per changed field.
+func renderGitOpsChanges(w io.Writer, changes []fleet.GitOpsResourceChange) error {
+	for _, change := range changes {
+		header := fmt.Sprintf("[%s] %s %q", change.Op, change.Kind, change.Name)
+		if change.Fleet != "" {
+			header += fmt.Sprintf(" (fleet %q)", change.Fleet)
+		}
+		if _, err := fmt.Fprintln(w, header); err != nil {
+			return err
+		}
+		for _, field := range change.Fields {
+			var line string
+			if change.Op == fleet.GitOpsChangeAdd {
+				line = fmt.Sprintf("      %s: %s", field.Field, formatGitOpsPlanValue(field.New))
+			} else {
+				line = fmt.Sprintf("      %s: %s -> %s", field.Field, formatGitOpsPlanValue(field.Old), formatGitOpsPlanValue(field.New))
+			}
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func formatGitOpsPlanValue(v any) string {
+	if v == nil {
+		return "(none)"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
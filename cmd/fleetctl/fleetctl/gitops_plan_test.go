@@ -0,0 +1,254 @@
+package fleetctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/pkg/spec"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/require"
+)
+
+type planMockClient struct {
+	MockClient
+	team            *fleet.Team
+	policies        []*fleet.Policy
+	queries         []fleet.Query
+	labels          []*fleet.LabelSpec
+	scripts         map[string]string
+	secretVariables []fleet.SecretVariableIdentifier
+}
+
+func (c *planMockClient) GetTeam(teamID uint) (*fleet.Team, error) {
+	return c.team, nil
+}
+
+func (c *planMockClient) GetPolicies(teamID *uint) ([]*fleet.Policy, error) {
+	return c.policies, nil
+}
+
+func (c *planMockClient) GetQueries(teamID *uint, name *string) ([]fleet.Query, error) {
+	return c.queries, nil
+}
+
+func (c *planMockClient) GetLabels(teamID uint) ([]*fleet.LabelSpec, error) {
+	return c.labels, nil
+}
+
+func (c *planMockClient) ListScripts(query string) ([]*fleet.Script, error) {
+	names := make([]string, 0, len(c.scripts))
+	for name := range c.scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	scripts := make([]*fleet.Script, 0, len(names))
+	for i, name := range names {
+		scripts = append(scripts, &fleet.Script{ID: uint(i + 1), Name: name}) //nolint:gosec // dismiss G115
+	}
+	return scripts, nil
+}
+
+func (c *planMockClient) GetScriptContents(scriptID uint) ([]byte, error) {
+	scripts, _ := c.ListScripts("")
+	for _, s := range scripts {
+		if s.ID == scriptID {
+			return []byte(c.scripts[s.Name]), nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *planMockClient) ListSoftwareTitles(query string) ([]fleet.SoftwareTitleListResult, error) {
+	return []fleet.SoftwareTitleListResult{{
+		ID:              1,
+		Name:            "Foo",
+		HashSHA256:      ptr.String("foo-hash"),
+		SoftwarePackage: &fleet.SoftwarePackageOrApp{Name: "foo.pkg", PackageURL: ptr.String("https://example.com/foo.pkg")},
+	}}, nil
+}
+
+func (c *planMockClient) ListSecretVariables() ([]fleet.SecretVariableIdentifier, error) {
+	return c.secretVariables, nil
+}
+
+func TestGitOpsPlanFleet(t *testing.T) {
+	dir := t.TempDir()
+	unchangedScript := filepath.Join(dir, "unchanged.sh")
+	require.NoError(t, os.WriteFile(unchangedScript, []byte("echo unchanged\n"), 0o644))
+	modifiedScript := filepath.Join(dir, "modified.sh")
+	require.NoError(t, os.WriteFile(modifiedScript, []byte("echo new"), 0o644))
+
+	client := &planMockClient{
+		team: &fleet.Team{
+			ID:   1,
+			Name: "Workstations",
+			Config: fleet.TeamConfig{
+				Features: fleet.Features{EnableHostUsers: true, EnableSoftwareInventory: true},
+			},
+			Secrets: []*fleet.EnrollSecret{{Secret: "old-secret"}},
+		},
+		policies: []*fleet.Policy{
+			{PolicyData: fleet.PolicyData{Name: "Unchanged", Query: "SELECT 1;", Platform: "darwin"}},
+			{PolicyData: fleet.PolicyData{Name: "Removed", Query: "SELECT 2;"}},
+		},
+		queries: []fleet.Query{
+			{Name: "Uptime", Query: "SELECT * FROM uptime;", Interval: 3600, Logging: fleet.LoggingSnapshot},
+		},
+		labels: []*fleet.LabelSpec{
+			{Name: "All Hosts", LabelType: fleet.LabelTypeBuiltIn},
+			{Name: "Servers", Query: "SELECT 1 FROM os_version WHERE platform = 'ubuntu';"},
+		},
+		scripts: map[string]string{
+			"unchanged.sh": "echo unchanged",
+			"modified.sh":  "echo old",
+			"removed.sh":   "echo removed",
+		},
+		secretVariables: []fleet.SecretVariableIdentifier{{Name: "EXISTING"}},
+	}
+	appConfig, err := client.GetAppConfig()
+	require.NoError(t, err)
+
+	config := &spec.GitOps{
+		TeamName: ptr.String("Workstations"),
+		TeamSettings: map[string]any{
+			"features": map[string]any{"enable_host_users": false},
+			"secrets":  []*fleet.EnrollSecret{{Secret: "new-secret"}},
+		},
+		Policies: []*spec.GitOpsPolicySpec{
+			{PolicySpec: fleet.PolicySpec{Name: "Unchanged", Query: "SELECT 1;", Platform: "darwin"}},
+			{PolicySpec: fleet.PolicySpec{Name: "Added", Query: "SELECT 3;", Critical: true}},
+		},
+		Queries: []*fleet.QuerySpec{
+			{Name: "Uptime", Query: "SELECT * FROM uptime;", Interval: 60},
+		},
+		Labels: []*fleet.LabelSpec{
+			{Name: "Servers", Query: "SELECT 1 FROM os_version WHERE platform = 'ubuntu';"},
+		},
+		LabelsPresent: true,
+		Controls: spec.GitOpsControls{
+			Scripts: []fleet.BaseItem{{Path: &unchangedScript}, {Path: &modifiedScript}},
+			Defined: true,
+		},
+		Software: spec.GitOpsSoftware{
+			Packages:            []*fleet.SoftwarePackageSpec{{URL: "https://example.com/foo.pkg"}},
+			FleetMaintainedApps: []*fleet.MaintainedAppSpec{{Slug: "zoom/darwin"}},
+		},
+		SoftwarePresent: true,
+		FleetSecrets:    map[string]string{"FLEET_SECRET_EXISTING": "a", "FLEET_SECRET_NEW": "b"},
+	}
+
+	builder := newGitOpsPlanBuilder(client, appConfig)
+	require.NoError(t, builder.addConfig(config, filepath.Join(dir, "workstations.yml"), ptr.Uint(1), config.Controls))
+	plan := builder.Plan()
+
+	require.Equal(t, []fleet.GitOpsResourceChange{
+		{
+			Kind: fleet.GitOpsResourceFleetSettings, Fleet: "Workstations", Name: "Workstations", Op: fleet.GitOpsChangeModify,
+			Fields: []fleet.GitOpsFieldChange{{Field: "features.enable_host_users", Old: true, New: false}},
+		},
+		{
+			Kind: fleet.GitOpsResourcePolicy, Fleet: "Workstations", Name: "Added", Op: fleet.GitOpsChangeAdd,
+			Fields: []fleet.GitOpsFieldChange{{Field: "critical", New: true}, {Field: "query", New: "SELECT 3;"}},
+		},
+		{Kind: fleet.GitOpsResourcePolicy, Fleet: "Workstations", Name: "Removed", Op: fleet.GitOpsChangeRemove},
+		{
+			Kind: fleet.GitOpsResourceReport, Fleet: "Workstations", Name: "Uptime", Op: fleet.GitOpsChangeModify,
+			Fields: []fleet.GitOpsFieldChange{{Field: "interval", Old: float64(3600), New: float64(60)}},
+		},
+		{
+			Kind: fleet.GitOpsResourceScript, Fleet: "Workstations", Name: "modified.sh", Op: fleet.GitOpsChangeModify,
+			Fields: []fleet.GitOpsFieldChange{{Field: "contents_sha256", Old: contentsHash([]byte("echo old")), New: contentsHash([]byte("echo new"))}},
+		},
+		{Kind: fleet.GitOpsResourceScript, Fleet: "Workstations", Name: "removed.sh", Op: fleet.GitOpsChangeRemove},
+		// The mock client has a profile for the fleet, which isn't in the controls.
+		{Kind: fleet.GitOpsResourceProfile, Fleet: "Workstations", Name: "Team MacOS MobileConfig Profile", Op: fleet.GitOpsChangeRemove},
+		{Kind: fleet.GitOpsResourceSoftware, Fleet: "Workstations", Name: "fleet_maintained_app:zoom/darwin", Op: fleet.GitOpsChangeAdd},
+		{
+			Kind: fleet.GitOpsResourceSecret, Fleet: "Workstations", Name: "enroll_secrets", Op: fleet.GitOpsChangeModify,
+			Fields: []fleet.GitOpsFieldChange{{Field: "secrets", Old: fleet.MaskedPassword, New: fleet.MaskedPassword}},
+		},
+		{
+			Kind: fleet.GitOpsResourceSecret, Name: "FLEET_SECRET_NEW", Op: fleet.GitOpsChangeAdd,
+			Fields: []fleet.GitOpsFieldChange{{Field: "value", New: fleet.MaskedPassword}},
+		},
+	}, plan.Changes)
+	require.Equal(t, gitopsPlanSummary{Add: 3, Change: 4, Remove: 3}, plan.Summary)
+
+	// Secret values never show up in the rendered plans.
+	for _, format := range []string{gitopsPlanFormatText, gitopsPlanFormatJSON} {
+		var buf bytes.Buffer
+		require.NoError(t, renderGitOpsPlan(&buf, plan, format))
+		require.NotContains(t, buf.String(), "old-secret")
+		require.NotContains(t, buf.String(), "new-secret")
+	}
+}
+
+func TestGitOpsPlanNewFleet(t *testing.T) {
+	client := &planMockClient{}
+	appConfig, err := client.GetAppConfig()
+	require.NoError(t, err)
+
+	config := &spec.GitOps{
+		TeamName:     ptr.String("New fleet"),
+		TeamSettings: map[string]any{"features": map[string]any{"enable_host_users": true}},
+		Queries:      []*fleet.QuerySpec{{Name: "Uptime", Query: "SELECT * FROM uptime;"}},
+	}
+
+	builder := newGitOpsPlanBuilder(client, appConfig)
+	require.NoError(t, builder.addConfig(config, "new-fleet.yml", nil, config.Controls))
+	plan := builder.Plan()
+
+	require.Len(t, plan.Changes, 2)
+	for _, change := range plan.Changes {
+		require.Equal(t, fleet.GitOpsChangeAdd, change.Op)
+		require.Equal(t, "New fleet", change.Fleet)
+	}
+	require.Equal(t, fleet.GitOpsResourceFleetSettings, plan.Changes[0].Kind)
+	require.Equal(t, fleet.GitOpsResourceReport, plan.Changes[1].Kind)
+	require.Equal(t, gitopsPlanSummary{Add: 2}, plan.Summary)
+}
+
+func TestRenderGitOpsPlan(t *testing.T) {
+	plan := (&gitopsPlanBuilder{plan: gitopsPlan{Changes: []fleet.GitOpsResourceChange{
+		{
+			Kind: fleet.GitOpsResourcePolicy, Fleet: "Workstations", Name: "Firewall", Op: fleet.GitOpsChangeModify,
+			Fields: []fleet.GitOpsFieldChange{{Field: "critical", Old: false, New: true}},
+		},
+		{
+			Kind: fleet.GitOpsResourceLabel, Name: "Servers", Op: fleet.GitOpsChangeAdd,
+			Fields: []fleet.GitOpsFieldChange{{Field: "query", New: "SELECT 1;"}},
+		},
+		{Kind: fleet.GitOpsResourceReport, Name: "Uptime", Op: fleet.GitOpsChangeRemove},
+	}}}).Plan()
+
+	var buf bytes.Buffer
+	require.NoError(t, renderGitOpsPlan(&buf, plan, gitopsPlanFormatText))
+	require.Equal(t, `[~] policy "Firewall" (fleet "Workstations")
+      critical: false -> true
+[+] label "Servers"
+      query: "SELECT 1;"
+[-] report "Uptime"
+
+Plan: 1 to add, 1 to change, 1 to remove.
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, renderGitOpsPlan(&buf, plan, gitopsPlanFormatJSON))
+	var decoded gitopsPlan
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Len(t, decoded.Changes, 3)
+	require.Equal(t, gitopsPlanSummary{Add: 1, Change: 1, Remove: 1}, decoded.Summary)
+
+	buf.Reset()
+	empty := (&gitopsPlanBuilder{}).Plan()
+	require.NoError(t, renderGitOpsPlan(&buf, empty, gitopsPlanFormatText))
+	require.Equal(t, "No changes. The Fleet server matches the GitOps configuration.\n", buf.String())
+	buf.Reset()
+	require.NoError(t, renderGitOpsPlan(&buf, empty, gitopsPlanFormatJSON))
+	require.JSONEq(t, `{"changes": [], "summary": {"add": 0, "change": 0, "remove": 0}}`, buf.String())
+}
@@ -40,6 +40,7 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
 )
 
 const (
@@ -195,6 +196,23 @@ org_settings:
 	_ = runAppForTest(t, []string{"gitops", "-f", tmpFile.Name(), "--dry-run"})
 	assert.Equal(t, fleet.AppConfig{}, *savedAppConfig, "AppConfig should be empty")
 
+	// Plan, only the JSON plan is written to stdout and the exit status tells there are changes.
+	var planErrWriter strings.Builder
+	planOut, err := runWithErrWriter([]string{"gitops", "-f", tmpFile.Name(), "--plan", "--plan-format", "json"}, &planErrWriter)
+	var exitErr cli.ExitCoder
+	require.ErrorAs(t, err, &exitErr)
+	assert.Equal(t, 2, exitErr.ExitCode())
+	assert.Equal(t, fleet.AppConfig{}, *savedAppConfig, "AppConfig should be empty")
+	assert.Contains(t, planErrWriter.String(), "[!] gitops dry run succeeded")
+	var plan gitopsPlan
+	require.NoError(t, json.Unmarshal(planOut.Bytes(), &plan))
+	require.NotEmpty(t, plan.Changes)
+	assert.Equal(t, fleet.GitOpsResourceOrgSettings, plan.Changes[0].Kind)
+	assert.Contains(t, plan.Changes[0].Fields, fleet.GitOpsFieldChange{Field: "org_info.org_name", Old: "", New: orgName})
+
+	_, err = runAppNoChecks([]string{"gitops", "-f", tmpFile.Name(), "--plan", "--plan-format", "yaml"})
+	require.ErrorContains(t, err, `invalid --plan-format "yaml"`)
+
 	// Real run
 	_ = runAppForTest(t, []string{"gitops", "-f", tmpFile.Name()})
 	assert.Equal(t, orgName, savedAppConfig.OrgInfo.OrgName)
@@ -11,14 +11,17 @@ import (
 
 	"github.com/briandowns/spinner"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/service"
 	"github.com/urfave/cli/v2"
 )
 
 func queryCommand() *cli.Command {
 	var (
 		flHosts, flLabels, flQuery, flQueryName string
-		flQuiet, flExit, flPretty               bool
-		flTimeout                               time.Duration
+		flQuiet, flExit, flPretty, flDeferred   bool
+		flSave                                  bool
+		flTimeout, flTTL                        time.Duration
+		flCampaignID                            uint
 	)
 	return &cli.Command{
 		Name:      "report",
@@ -37,6 +40,13 @@ func queryCommand() *cli.Command {
 Using the --hosts flag individual hosts can be specified with the host's hostname. Groups of hosts can
 specified by using labels. Note if both the --hosts and --labels flags are specified, the query will
 be run on the union of the hosts and hosts with matching labels.
+
+Using the --deferred flag, the query keeps running on the targeted hosts that haven't responded yet,
+including offline ones, as they check in until the --ttl expires. The command exits after creating
+the deferred report, its results are retrieved with the --campaign-id flag.
+
+Using the --save flag, the results of the live report are also stored by the server as they are
+received, so they can be retrieved later with the --campaign-id flag.
 		`,
 		Flags: []cli.Flag{
 			&cli.StringFlag{
@@ -92,6 +102,31 @@ be run on the union of the hosts and hosts with matching labels.
 				Destination: &flTimeout,
 				Usage:       "How long to run query before exiting (10s, 1h, etc.)",
 			},
+			&cli.BoolFlag{
+				Name:        "deferred",
+				EnvVars:     []string{"DEFERRED"},
+				Destination: &flDeferred,
+				Usage:       "Keep running the report on hosts as they check in, until the --ttl expires",
+			},
+			&cli.DurationFlag{
+				Name:        "ttl",
+				EnvVars:     []string{"TTL"},
+				Value:       fleet.DeferredQueryDefaultTTL,
+				Destination: &flTTL,
+				Usage:       "How long a deferred report runs on hosts (10m, 24h, etc.)",
+			},
+			&cli.BoolFlag{
+				Name:        "save",
+				EnvVars:     []string{"SAVE"},
+				Destination: &flSave,
+				Usage:       "Store the results of the live report on the server",
+			},
+			&cli.UintFlag{
+				Name:        "campaign-id",
+				EnvVars:     []string{"CAMPAIGN_ID"},
+				Destination: &flCampaignID,
+				Usage:       "ID of the deferred or saved report campaign to print the results of",
+			},
 			&cli.UintFlag{
 				Name:    fleetFlagName,
 				Aliases: []string{"team"},
@@ -109,6 +144,28 @@ be run on the union of the hosts and hosts with matching labels.
 				return err
 			}
 
+			var output outputWriter
+			if flPretty {
+				output = newPrettyWriter()
+			} else {
+				output = newJsonWriter(c.App.Writer)
+			}
+
+			if flCampaignID != 0 {
+				if flDeferred || flSave || flQuery != "" || flQueryName != "" || flHosts != "" || flLabels != "" {
+					return errors.New("--campaign-id must not be provided with --deferred, --save, --query, --report-name, --hosts or --labels")
+				}
+				return printCampaignQueryResults(client, flCampaignID, output, flQuiet)
+			}
+
+			if c.IsSet("ttl") && !flDeferred {
+				return errors.New("--ttl can only be provided with --deferred")
+			}
+
+			if flSave && flDeferred {
+				return errors.New("--save must not be provided with --deferred, the results of deferred reports are always saved")
+			}
+
 			if flHosts == "" && flLabels == "" {
 				return errors.New("No hosts or labels targeted. Please provide either --hosts or --labels.")
 			}
@@ -142,28 +199,39 @@ be run on the union of the hosts and hosts with matching labels.
 				return errors.New("Query must be specified with --query or --report-name")
 			}
 
-			var output outputWriter
-			if flPretty {
-				output = newPrettyWriter()
-			} else {
-				output = newJsonWriter(c.App.Writer)
-			}
-
 			hostIdentifiers := strings.Split(flHosts, ",")
 			labels := strings.Split(flLabels, ",")
 
-			res, err := client.LiveQuery(flQuery, queryID, labels, hostIdentifiers)
-			if err != nil {
-				if strings.Contains(err.Error(), "no hosts targeted") {
-					return errors.New(fleet.NoHostsTargetedErrMsg)
+			if flDeferred {
+				campaign, err := client.DeferredLiveQuery(flQuery, queryID, labels, hostIdentifiers, flTTL)
+				if err != nil {
+					return liveQueryError(err)
 				}
-				if strings.Contains(err.Error(), fleet.InvalidLabelSpecifiedErrMsg) {
-					pattern := fmt.Sprintf("(%s.*)$", regexp.QuoteMeta(fleet.InvalidLabelSpecifiedErrMsg))
-					regex := regexp.MustCompile(pattern)
-					match := regex.FindString(err.Error())
-					return errors.New(match)
+				if flQuiet {
+					fmt.Fprintln(c.App.Writer, campaign.ID)
+					return nil
 				}
-				return err
+				var expiresAt string
+				if campaign.ExpiresAt != nil {
+					expiresAt = campaign.ExpiresAt.Local().Format(time.RFC1123)
+				}
+				fmt.Fprintf(c.App.Writer, "Deferred report created with campaign ID %d, it runs until %s.\n", campaign.ID, expiresAt)
+				fmt.Fprintf(c.App.Writer, "Retrieve its results with: fleetctl report --campaign-id %d\n", campaign.ID)
+				return nil
+			}
+
+			var res *service.LiveQueryResultsHandler
+			if flSave {
+				res, err = client.SavedLiveQuery(flQuery, queryID, labels, hostIdentifiers)
+			} else {
+				res, err = client.LiveQuery(flQuery, queryID, labels, hostIdentifiers)
+			}
+			if err != nil {
+				return liveQueryError(err)
+			}
+			if flSave && !flQuiet {
+				defer fmt.Fprintf(os.Stderr, "Results saved with campaign ID %d, retrieve them with: fleetctl report --campaign-id %d\n",
+					res.CampaignID(), res.CampaignID())
 			}
 
 			tick := time.NewTicker(100 * time.Millisecond)
@@ -256,3 +324,63 @@ be run on the union of the hosts and hosts with matching labels.
 		},
 	}
 }
+
+// liveQueryError returns a user-friendly error for the errors returned by the
+// server when creating a live query.
+func liveQueryError(err error) error {
+	if strings.Contains(err.Error(), "no hosts targeted") {
+		return errors.New(fleet.NoHostsTargetedErrMsg)
+	}
+	if strings.Contains(err.Error(), fleet.InvalidLabelSpecifiedErrMsg) {
+		pattern := fmt.Sprintf("(%s.*)$", regexp.QuoteMeta(fleet.InvalidLabelSpecifiedErrMsg))
+		regex := regexp.MustCompile(pattern)
+		match := regex.FindString(err.Error())
+		return errors.New(match)
+	}
+	return err
+}
+
+const campaignQueryResultsPageSize = 500
+
+// printCampaignQueryResults writes all the results stored so far for the
+// deferred or saved report campaign with the given ID.
+func printCampaignQueryResults(client *service.Client, campaignID uint, output outputWriter, quiet bool) error {
+	campaign, err := client.GetQueryCampaign(campaignID)
+	if err != nil {
+		return err
+	}
+
+	for page := uint(0); ; page++ {
+		results, meta, err := client.ListCampaignQueryResults(campaignID, page, campaignQueryResultsPageSize)
+		if err != nil {
+			return err
+		}
+		for _, res := range results {
+			if err := output.WriteResult(fleet.DistributedQueryResult{
+				DistributedQueryCampaignID: campaignID,
+				Host: fleet.ResultHostData{
+					ID:          res.HostID,
+					Hostname:    res.Hostname,
+					DisplayName: res.HostDisplayName,
+				},
+				Rows:  res.Rows,
+				Stats: res.Stats,
+				Error: res.Error,
+			}); err != nil {
+				return fmt.Errorf("writing result: %w", err)
+			}
+		}
+		if meta == nil || !meta.HasNextResults {
+			break
+		}
+	}
+
+	if !quiet {
+		state := "running"
+		if campaign.Status == fleet.QueryComplete {
+			state = "completed"
+		}
+		fmt.Fprintf(os.Stderr, "%d/%d targeted hosts responded (%s)\n", campaign.RespondedHosts, campaign.TargetedHosts, state)
+	}
+	return nil
+}
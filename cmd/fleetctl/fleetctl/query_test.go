@@ -2,6 +2,7 @@ package fleetctl
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 	"os"
 	"sync"
@@ -311,3 +312,187 @@ func TestAdHocLiveQuery(t *testing.T) {
 `
 	assert.Equal(t, expected, runAppForTest(t, []string{"report", "--hosts", "1234", "--query", "select 42, * from time"}))
 }
+
+func TestDeferredLiveQuery(t *testing.T) {
+	lq := live_query_mock.New(t)
+	_, ds := testing_utils.RunServerWithMockedDS(t, &service.TestServerOpts{
+		Rs: pubsub.NewInmemQueryResults(),
+		Lq: lq,
+	})
+
+	users, err := ds.ListUsersFunc(context.Background(), fleet.UserListOptions{})
+	require.NoError(t, err)
+	var admin *fleet.User
+	for _, user := range users {
+		if user.GlobalRole != nil && *user.GlobalRole == fleet.RoleAdmin {
+			admin = user
+		}
+	}
+
+	ds.HostIDsByIdentifierFunc = func(ctx context.Context, filter fleet.TeamFilter, hostIdentifiers []string) ([]uint, error) {
+		return []uint{1}, nil
+	}
+	ds.LabelIDsByNameFunc = func(ctx context.Context, names []string, filter fleet.TeamFilter) (map[string]uint, error) {
+		return nil, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.NewQueryFunc = func(ctx context.Context, query *fleet.Query, opts ...fleet.OptionalArg) (*fleet.Query, error) {
+		query.ID = 42
+		return query, nil
+	}
+	var created *fleet.DistributedQueryCampaign
+	ds.NewDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaign, error) {
+		camp.ID = 321
+		created = camp
+		return camp, nil
+	}
+	ds.NewDistributedQueryCampaignTargetFunc = func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
+		return target, nil
+	}
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+		return []uint{1}, nil
+	}
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+		return fleet.TargetMetrics{TotalHosts: 2}, nil
+	}
+	lq.On("RunQuery", "321", "select 1", []uint{1}).Return(nil)
+
+	assert.Equal(t, "321\n", runAppForTest(t, []string{"report", "--hosts", "foo", "--query", "select 1", "--deferred", "--ttl", "2h", "--quiet"}))
+	require.NotNil(t, created)
+	require.True(t, created.Deferred)
+	require.NotNil(t, created.ExpiresAt)
+	require.WithinDuration(t, time.Now().Add(2*time.Hour), *created.ExpiresAt, time.Minute)
+
+	_, err = runAppNoChecks([]string{"report", "--hosts", "foo", "--query", "select 1", "--ttl", "2h"})
+	require.ErrorContains(t, err, "--ttl can only be provided with --deferred")
+	_, err = runAppNoChecks([]string{"report", "--hosts", "foo", "--query", "select 1", "--deferred", "--ttl", "200h"})
+	require.ErrorContains(t, err, "deferred_ttl")
+
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return &fleet.DistributedQueryCampaign{ID: 321, UserID: admin.ID, Deferred: true, Status: fleet.QueryRunning}, nil
+	}
+	ds.DistributedQueryCampaignTargetIDsFunc = func(ctx context.Context, id uint) (*fleet.HostTargets, error) {
+		return &fleet.HostTargets{HostIDs: []uint{1, 2}}, nil
+	}
+	ds.CountCampaignQueryResultsFunc = func(ctx context.Context, campaignID uint) (uint, error) {
+		return 2, nil
+	}
+	errMsg := "no such table: foo"
+	ds.ListCampaignQueryResultsFunc = func(ctx context.Context, campaignID uint, opts fleet.ListOptions) ([]*fleet.CampaignQueryResult, *fleet.PaginationMetadata, error) {
+		// return one result per page
+		switch opts.Page {
+		case 0:
+			return []*fleet.CampaignQueryResult{{HostID: 1, Hostname: "foo", Rows: []map[string]string{{"a": "b"}}}},
+				&fleet.PaginationMetadata{HasNextResults: true}, nil
+		default:
+			return []*fleet.CampaignQueryResult{{HostID: 2, Hostname: "bar", Error: &errMsg}},
+				&fleet.PaginationMetadata{HasPreviousResults: true}, nil
+		}
+	}
+
+	expected := `{"host":"foo","rows":[{"a":"b"}]}
+{"host":"bar","rows":null,"error":"no such table: foo"}
+`
+	assert.Equal(t, expected, runAppForTest(t, []string{"report", "--campaign-id", "321", "--quiet"}))
+
+	_, err = runAppNoChecks([]string{"report", "--campaign-id", "321", "--query", "select 1"})
+	require.ErrorContains(t, err, "--campaign-id must not be provided with")
+}
+
+func TestLiveQuerySaveResults(t *testing.T) {
+	rs := pubsub.NewInmemQueryResults()
+	lq := live_query_mock.New(t)
+	_, ds := testing_utils.RunServerWithMockedDS(t, &service.TestServerOpts{
+		Rs: rs,
+		Lq: lq,
+	})
+
+	users, err := ds.ListUsersFunc(context.Background(), fleet.UserListOptions{})
+	require.NoError(t, err)
+	var admin *fleet.User
+	for _, user := range users {
+		if user.GlobalRole != nil && *user.GlobalRole == fleet.RoleAdmin {
+			admin = user
+		}
+	}
+
+	ds.HostIDsByIdentifierFunc = func(ctx context.Context, filter fleet.TeamFilter, hostIdentifiers []string) ([]uint, error) {
+		return []uint{1}, nil
+	}
+	ds.LabelIDsByNameFunc = func(ctx context.Context, names []string, filter fleet.TeamFilter) (map[string]uint, error) {
+		return nil, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.NewQueryFunc = func(ctx context.Context, query *fleet.Query, opts ...fleet.OptionalArg) (*fleet.Query, error) {
+		query.ID = 42
+		return query, nil
+	}
+	var created *fleet.DistributedQueryCampaign
+	ds.NewDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaign, error) {
+		camp.ID = 321
+		created = camp
+		return camp, nil
+	}
+	ds.NewDistributedQueryCampaignTargetFunc = func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
+		return target, nil
+	}
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+		return []uint{1}, nil
+	}
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+		return fleet.TargetMetrics{TotalHosts: 1, OnlineHosts: 1}, nil
+	}
+	ds.DistributedQueryCampaignTargetIDsFunc = func(ctx context.Context, id uint) (*fleet.HostTargets, error) {
+		return &fleet.HostTargets{HostIDs: []uint{1}}, nil
+	}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return &fleet.DistributedQueryCampaign{ID: 321, UserID: admin.ID, SaveResults: true}, nil
+	}
+	ds.SaveDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) error {
+		return nil
+	}
+	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
+		return &fleet.Query{}, nil
+	}
+	ds.IsSavedQueryFunc = func(ctx context.Context, queryID uint) (bool, error) {
+		return false, nil
+	}
+	// the rows are marshaled when saved, as the datastore does
+	var saved []string
+	ds.SaveCampaignQueryResultFunc = func(ctx context.Context, campaignID uint, result *fleet.CampaignQueryResult) error {
+		require.Equal(t, uint(321), campaignID)
+		b, err := json.Marshal(result.Rows)
+		require.NoError(t, err)
+		saved = append(saved, string(b))
+		return nil
+	}
+	lq.On("RunQuery", "321", "select 1", []uint{1}).Return(nil)
+	lq.On("StopQuery", "321").Return(nil).Maybe()
+
+	go func() {
+		time.Sleep(2 * time.Second)
+		require.NoError(t, rs.WriteResult(fleet.DistributedQueryResult{
+			DistributedQueryCampaignID: 321,
+			Rows:                       []map[string]string{{"a": "b"}},
+			Host:                       fleet.ResultHostData{ID: 1, Hostname: "foo", DisplayName: "foo"},
+		}))
+	}()
+
+	expected := `{"host":"foo","rows":[{"a":"b","host_display_name":"foo","host_hostname":"foo"}]}
+`
+	assert.Equal(t, expected, runAppForTest(t, []string{"report", "--hosts", "foo", "--query", "select 1", "--save"}))
+	require.NotNil(t, created)
+	require.True(t, created.SaveResults)
+	require.False(t, created.Deferred)
+	// the results are stored as returned by the host
+	require.Equal(t, []string{`[{"a":"b"}]`}, saved)
+
+	_, err = runAppNoChecks([]string{"report", "--hosts", "foo", "--query", "select 1", "--save", "--deferred"})
+	require.ErrorContains(t, err, "--save must not be provided with --deferred")
+	_, err = runAppNoChecks([]string{"report", "--campaign-id", "321", "--save"})
+	require.ErrorContains(t, err, "--campaign-id must not be provided with")
+}
@@ -68,7 +68,7 @@ Downloads (if needed) the data streams that can be used by the Fleet server to p
 			log(c, " Done\n")
 
 			log(c, "[-] Downloading EPSS feed...")
-			err = nvd.DownloadEPSSFeed(dir)
+			err = nvd.DownloadEPSSFeed(dir, "")
 			if err != nil {
 				return fmt.Errorf("Error downloading EPSS feed: %v", err)
 			}
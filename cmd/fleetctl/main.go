@@ -29,11 +29,21 @@ func main() {
 }
 
 // exitErrHandler implements cli.ExitErrHandlerFunc. If there is an error, prints it to stderr and exits with status 1.
+// Errors created with cli.Exit exit with their own status instead, and are only printed if they have a message.
 func exitErrHandler(c *cli.Context, err error) {
 	if err == nil {
 		return
 	}
 
+	var exitErr cli.ExitCoder
+	if errors.As(err, &exitErr) {
+		if msg := exitErr.Error(); msg != "" {
+			fmt.Fprintf(c.App.ErrWriter, "Error: %s\n", msg)
+		}
+		cli.OsExiter(exitErr.ExitCode())
+		return
+	}
+
 	fmt.Fprintf(c.App.ErrWriter, "Error: %+v\n", fleetctl.CleanStatusCodeErr(err))
 
 	if errors.Is(err, fs.ErrPermission) {
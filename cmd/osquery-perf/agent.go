@@ -643,6 +643,10 @@ type agent struct {
 	entraIDDeviceID          string
 	entraIDUserPrincipalName string
 	installedAdamIDs         []int
+
+	// scenario applies the overrides of the running scenario phase, nil when
+	// osquery-perf does not run a scenario.
+	scenario *scenarioState
 }
 
 func (a *agent) GetSerialNumber() string {
@@ -928,6 +932,57 @@ func (a *agent) isOrbit() bool {
 	return a.deviceAuthToken != nil
 }
 
+// queryInterval returns the distributed query interval, which a scenario
+// phase may override. The same goes for configInterval and logInterval.
+func (a *agent) queryInterval() time.Duration {
+	if p := a.scenario.phase(); p != nil && p.QueryInterval > 0 {
+		return p.QueryInterval
+	}
+	return a.QueryInterval
+}
+
+func (a *agent) configInterval() time.Duration {
+	if p := a.scenario.phase(); p != nil && p.ConfigInterval > 0 {
+		return p.ConfigInterval
+	}
+	return a.ConfigInterval
+}
+
+func (a *agent) logInterval() time.Duration {
+	if p := a.scenario.phase(); p != nil && p.LogInterval > 0 {
+		return p.LogInterval
+	}
+	return a.LogInterval
+}
+
+// osqueryPaused returns true if the osquery threads must not send requests,
+// which is the case while a scenario phase replays a capture.
+func (a *agent) osqueryPaused() bool {
+	p := a.scenario.phase()
+	return p != nil && p.Kind == phaseReplay
+}
+
+// softwareUninstallProb returns the given software uninstall probability, or
+// the one of the running software churn scenario phase.
+func (a *agent) softwareUninstallProb(prob float64) float64 {
+	if p := a.scenario.phase(); p != nil && p.Kind == phaseSoftwareChurn {
+		if p.SoftwareUninstallProb != nil {
+			return *p.SoftwareUninstallProb
+		}
+		return 1
+	}
+	return prob
+}
+
+// resetTicker resets the ticker if the interval changed since the last tick
+// and returns the interval now in use.
+func resetTicker(ticker *time.Ticker, current, next time.Duration) time.Duration {
+	if next != current {
+		ticker.Reset(next)
+	}
+	return next
+}
+
 func (a *agent) runLoop(i int, onlyAlreadyEnrolled bool) {
 	// Request host identity certificate if this agent uses HTTP message signatures
 	if a.hostIdentityClient.IsEnabled() && !onlyAlreadyEnrolled {
@@ -998,10 +1053,15 @@ func (a *agent) runLoop(i int, onlyAlreadyEnrolled bool) {
 
 	// (1) distributed thread:
 	go func() {
-		liveQueryTicker := time.NewTicker(a.QueryInterval)
+		interval := a.queryInterval()
+		liveQueryTicker := time.NewTicker(interval)
 		defer liveQueryTicker.Stop()
 
 		for range liveQueryTicker.C {
+			interval = resetTicker(liveQueryTicker, interval, a.queryInterval())
+			if a.osqueryPaused() {
+				continue
+			}
 			if resp, err := a.DistributedRead(); err == nil && len(resp.Queries) > 0 {
 				_ = a.DistributedWrite(resp.Queries)
 			}
@@ -1010,18 +1070,28 @@ func (a *agent) runLoop(i int, onlyAlreadyEnrolled bool) {
 
 	// (2) config thread:
 	go func() {
-		configTicker := time.NewTicker(a.ConfigInterval)
+		interval := a.configInterval()
+		configTicker := time.NewTicker(interval)
 		defer configTicker.Stop()
 
 		for range configTicker.C {
+			interval = resetTicker(configTicker, interval, a.configInterval())
+			if a.osqueryPaused() {
+				continue
+			}
 			_ = a.config()
 		}
 	}()
 
 	// (3) logger thread:
-	logTicker := time.NewTicker(a.LogInterval)
+	interval := a.logInterval()
+	logTicker := time.NewTicker(interval)
 	defer logTicker.Stop()
 	for range logTicker.C {
+		interval = resetTicker(logTicker, interval, a.logInterval())
+		if a.osqueryPaused() {
+			continue
+		}
 		// check if we have any scheduled queries that should be returning results
 		var results []resultLog
 		now := time.Now().Unix()
@@ -2380,6 +2450,18 @@ func (a *agent) enroll(i int, onlyAlreadyEnrolled bool) error {
 		return errors.New("not enrolled")
 	}
 
+	if err := a.requestEnroll(); err != nil {
+		return err
+	}
+	a.nodeKeyManager.Add(a.nodeKey)
+
+	return nil
+}
+
+// requestEnroll sends the osquery enroll request and stores the new node key.
+// It is also used by scenarios to have an enrolled agent enroll again, as
+// osquery does when its node key is no longer valid.
+func (a *agent) requestEnroll() error {
 	response := a.waitingDo(func() *http.Request {
 		var body bytes.Buffer
 		if err := a.templates.ExecuteTemplate(&body, "enroll", a); err != nil {
@@ -2408,8 +2490,6 @@ func (a *agent) enroll(i int, onlyAlreadyEnrolled bool) error {
 	a.nodeKey = parsedResp.NodeKey
 	a.stats.IncrementEnrollments()
 
-	a.nodeKeyManager.Add(a.nodeKey)
-
 	return nil
 }
 
@@ -2711,7 +2791,7 @@ func (a *agent) softwareMacOS() []map[string]string {
 			"installed_path":    fmt.Sprintf("/some/path/Unique_%s_%d.app", a.CachedString("hostname"), i),
 		}
 	}
-	if a.softwareCount.uniqueSoftwareUninstallProb > 0.0 && rand.Float64() <= a.softwareCount.uniqueSoftwareUninstallProb {
+	if prob := a.softwareUninstallProb(a.softwareCount.uniqueSoftwareUninstallProb); prob > 0.0 && rand.Float64() <= prob {
 		rand.Shuffle(len(uniqueSoftware), func(i, j int) {
 			uniqueSoftware[i], uniqueSoftware[j] = uniqueSoftware[j], uniqueSoftware[i]
 		})
@@ -2858,7 +2938,7 @@ func (a *agent) softwareVSCodeExtensions() []map[string]string {
 			"source":  "vscode_extensions",
 		}
 	}
-	if a.softwareVSCodeExtensionsCount.commonSoftwareUninstallProb > 0.0 && rand.Float64() <= a.softwareCount.commonSoftwareUninstallProb {
+	if a.softwareUninstallProb(a.softwareVSCodeExtensionsCount.commonSoftwareUninstallProb) > 0.0 && rand.Float64() <= a.softwareUninstallProb(a.softwareCount.commonSoftwareUninstallProb) {
 		rand.Shuffle(len(commonVSCodeExtensionsSoftware), func(i, j int) {
 			commonVSCodeExtensionsSoftware[i], commonVSCodeExtensionsSoftware[j] = commonVSCodeExtensionsSoftware[j], commonVSCodeExtensionsSoftware[i]
 		})
@@ -2873,7 +2953,7 @@ func (a *agent) softwareVSCodeExtensions() []map[string]string {
 			"source":  "vscode_extensions",
 		}
 	}
-	if a.softwareVSCodeExtensionsCount.uniqueSoftwareUninstallProb > 0.0 && rand.Float64() <= a.softwareVSCodeExtensionsCount.uniqueSoftwareUninstallProb {
+	if prob := a.softwareUninstallProb(a.softwareVSCodeExtensionsCount.uniqueSoftwareUninstallProb); prob > 0.0 && rand.Float64() <= prob {
 		rand.Shuffle(len(uniqueVSCodeExtensionsSoftware), func(i, j int) {
 			uniqueVSCodeExtensionsSoftware[i], uniqueVSCodeExtensionsSoftware[j] = uniqueVSCodeExtensionsSoftware[j], uniqueVSCodeExtensionsSoftware[i]
 		})
@@ -2942,7 +3022,7 @@ func (a *agent) softwareAdobePlugins() []map[string]string {
 			commonPlugins[i]["extension_id"] = ""
 		}
 	}
-	if a.softwareAdobePluginsCount.commonSoftwareUninstallProb > 0.0 && rand.Float64() <= a.softwareAdobePluginsCount.commonSoftwareUninstallProb {
+	if prob := a.softwareUninstallProb(a.softwareAdobePluginsCount.commonSoftwareUninstallProb); prob > 0.0 && rand.Float64() <= prob {
 		rand.Shuffle(len(commonPlugins), func(i, j int) {
 			commonPlugins[i], commonPlugins[j] = commonPlugins[j], commonPlugins[i]
 		})
@@ -2954,7 +3034,7 @@ func (a *agent) softwareAdobePlugins() []map[string]string {
 		dirName := fmt.Sprintf("com.fleetdm.osquery-perf.adobe_plugin_%s_%d", a.CachedString("hostname"), i)
 		uniquePlugins[i] = a.adobePlugin(fmt.Sprintf("Unique Adobe Plugin %s %d", a.CachedString("hostname"), i), dirName, "1.1.1", "1.1.2")
 	}
-	if a.softwareAdobePluginsCount.uniqueSoftwareUninstallProb > 0.0 && rand.Float64() <= a.softwareAdobePluginsCount.uniqueSoftwareUninstallProb {
+	if prob := a.softwareUninstallProb(a.softwareAdobePluginsCount.uniqueSoftwareUninstallProb); prob > 0.0 && rand.Float64() <= prob {
 		rand.Shuffle(len(uniquePlugins), func(i, j int) {
 			uniquePlugins[i], uniquePlugins[j] = uniquePlugins[j], uniquePlugins[i]
 		})
@@ -3459,48 +3539,48 @@ func (a *agent) runLiveYaraQuery(query string) (results []map[string]string, sta
 	// Return a response indicating that the file is clean.
 	ss := fleet.OsqueryStatus(0)
 	return []map[string]string{
-			{
-				"count":     "0",
-				"matches":   "",
-				"strings":   "",
-				"tags":      "",
-				"sig_group": "",
-				"sigfile":   "",
-				"sigrule":   "",
-				"sigurl":    url,
-				// Could pull this from the query, but not necessary for load testing.
-				"path": "/some/path",
-			},
-		}, &ss, nil, &fleet.Stats{
-			WallTimeMs: uint64(rand.Intn(1000) * 1000),
-			UserTime:   uint64(rand.Intn(1000)),
-			SystemTime: uint64(rand.Intn(1000)),
-			Memory:     uint64(rand.Intn(1000)),
-		}
+		{
+			"count":     "0",
+			"matches":   "",
+			"strings":   "",
+			"tags":      "",
+			"sig_group": "",
+			"sigfile":   "",
+			"sigrule":   "",
+			"sigurl":    url,
+			// Could pull this from the query, but not necessary for load testing.
+			"path": "/some/path",
+		},
+	}, &ss, nil, &fleet.Stats{
+		WallTimeMs: uint64(rand.Intn(1000) * 1000),
+		UserTime:   uint64(rand.Intn(1000)),
+		SystemTime: uint64(rand.Intn(1000)),
+		Memory:     uint64(rand.Intn(1000)),
+	}
 }
 
 func (a *agent) runLiveMockQuery(query string) (results []map[string]string, status *fleet.OsqueryStatus, message *string, stats *fleet.Stats) {
 	ss := fleet.OsqueryStatus(0)
 	return []map[string]string{
-			{
-				"admindir":   "/var/lib/dpkg",
-				"arch":       "amd64",
-				"maintainer": "foobar",
-				"name":       "netconf",
-				"priority":   "optional",
-				"revision":   "",
-				"section":    "default",
-				"size":       "112594",
-				"source":     "",
-				"status":     "install ok installed",
-				"version":    "20230224000000",
-			},
-		}, &ss, nil, &fleet.Stats{
-			WallTimeMs: uint64(rand.Intn(1000) * 1000),
-			UserTime:   uint64(rand.Intn(1000)),
-			SystemTime: uint64(rand.Intn(1000)),
-			Memory:     uint64(rand.Intn(1000)),
-		}
+		{
+			"admindir":   "/var/lib/dpkg",
+			"arch":       "amd64",
+			"maintainer": "foobar",
+			"name":       "netconf",
+			"priority":   "optional",
+			"revision":   "",
+			"section":    "default",
+			"size":       "112594",
+			"source":     "",
+			"status":     "install ok installed",
+			"version":    "20230224000000",
+		},
+	}, &ss, nil, &fleet.Stats{
+		WallTimeMs: uint64(rand.Intn(1000) * 1000),
+		UserTime:   uint64(rand.Intn(1000)),
+		SystemTime: uint64(rand.Intn(1000)),
+		Memory:     uint64(rand.Intn(1000)),
+	}
 }
 
 func (a *agent) processQuery(name, query string, cachedResults *cachedResults) (
@@ -4205,6 +4285,43 @@ func rows(num int) string {
 	return b.String()
 }
 
+var validTemplateNames = map[string]bool{
+	"macos_13.6.2.tmpl":         true,
+	"macos_14.1.2.tmpl":         true,
+	"windows_11.tmpl":           true,
+	"windows_11_22H2_2861.tmpl": true,
+	"windows_11_22H2_3007.tmpl": true,
+	"ubuntu_22.04.tmpl":         true,
+	"rhel_8.tmpl":               true,
+	"rhel_9.tmpl":               true,
+	"rhel_10.tmpl":              true,
+	"iphone_14.6.tmpl":          true,
+	"ipad_13.18.tmpl":           true,
+	"iphone_17.tmpl":            true,
+	"android.tmpl":              true,
+}
+
+func allowedTemplateNames() []string {
+	names := make([]string, 0, len(validTemplateNames))
+	for k := range validTemplateNames {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// templateFileName returns the file name of the OS template with the given
+// name, with or without the .tmpl extension.
+func templateFileName(nm string) (string, error) {
+	if !strings.HasSuffix(nm, ".tmpl") {
+		nm += ".tmpl"
+	}
+	if !validTemplateNames[nm] {
+		return "", fmt.Errorf("Invalid template name: %s (accepted values: %v)", nm, allowedTemplateNames())
+	}
+	return nm, nil
+}
+
 func main() {
 	// Start HTTP server for pprof. See https://pkg.go.dev/net/http/pprof.
 	go func() {
@@ -4220,26 +4337,6 @@ func main() {
 	http.DefaultClient.Transport = tr
 	http.DefaultClient.Timeout = 30 * time.Second
 
-	validTemplateNames := map[string]bool{
-		"macos_13.6.2.tmpl":         true,
-		"macos_14.1.2.tmpl":         true,
-		"windows_11.tmpl":           true,
-		"windows_11_22H2_2861.tmpl": true,
-		"windows_11_22H2_3007.tmpl": true,
-		"ubuntu_22.04.tmpl":         true,
-		"rhel_8.tmpl":               true,
-		"rhel_9.tmpl":               true,
-		"rhel_10.tmpl":              true,
-		"iphone_14.6.tmpl":          true,
-		"ipad_13.18.tmpl":           true,
-		"iphone_17.tmpl":            true,
-		"android.tmpl":              true,
-	}
-	allowedTemplateNames := make([]string, 0, len(validTemplateNames))
-	for k := range validTemplateNames {
-		allowedTemplateNames = append(allowedTemplateNames, k)
-	}
-
 	var (
 		serverURL       = flag.String("server_url", "https://localhost:8080", "URL (with protocol and port of osquery server)")
 		enrollSecret    = flag.String("enroll_secret", "", "Enroll secret to authenticate enrollment")
@@ -4323,7 +4420,7 @@ func main() {
 		munkiIssueCount             = flag.Int("munki_issue_count", 10, "Number of munki issues reported by hosts identified to have munki issues")
 		// E.g. when running with `-host_count=10`, you can set host count for each template the following way:
 		// `-os_templates=windows_11.tmpl:3,macos_14.1.2.tmpl:4,ubuntu_22.04.tmpl:3`
-		osTemplates       = flag.String("os_templates", "macos_14.1.2", fmt.Sprintf("Comma-separated list of host OS templates to use and optionally their host count separated by ':' (any of %v, with or without the .tmpl extension)", allowedTemplateNames))
+		osTemplates       = flag.String("os_templates", "macos_14.1.2", fmt.Sprintf("Comma-separated list of host OS templates to use and optionally their host count separated by ':' (any of %v, with or without the .tmpl extension)", allowedTemplateNames()))
 		emptySerialProb   = flag.Float64("empty_serial_prob", 0.1, "Probability of a host having no serial number [0, 1]")
 		defaultSerialProb = flag.Float64("default_serial_prob", 0.05,
 			"Probability of osquery returning a default (-1) serial number. See: #19789")
@@ -4364,6 +4461,11 @@ func main() {
 		androidStatusInterval    = flag.Duration("android_status_interval", 5*time.Minute, "Interval between Android STATUS_REPORT messages (real devices report ~every 24h; lower values stress test Fleet harder)")
 		androidAppCount          = flag.Int("android_app_count", 50, "Number of installed apps each Android device reports")
 		androidNonComplianceProb = flag.Float64("android_non_compliance_prob", 0.05, "Probability of an Android STATUS_REPORT including non-compliance details [0, 1]")
+
+		// Scenario flags, see the "Scenarios" section of the README.
+		scenarioPath        = flag.String("scenario", "", "Path to a YAML scenario file defining the phases of the load test. When set, hosts are started by the scenario's ramp_up phases instead of host_count, os_templates and start_period")
+		scenarioSummaryPath = flag.String("scenario_summary", "scenario-summary.json", "Path of the JSON latency and error summary written after each scenario phase")
+		fleetAPIToken       = flag.String("fleet_api_token", "", "Fleet API token used by the scenario phases that generate traffic through the Fleet API (live_query_storm and mdm_command_flood)")
 	)
 
 	flag.Parse()
@@ -4427,9 +4529,25 @@ func main() {
 		log.Fatalf("Argument android_non_compliance_prob must be between 0 and 1, got %f", *androidNonComplianceProb)
 	}
 
+	var sc *scenario
+	if *scenarioPath != "" {
+		var err error
+		if sc, err = loadScenario(*scenarioPath); err != nil {
+			log.Fatalf("Invalid scenario %s: %s", *scenarioPath, err)
+		}
+		if sc.usesFleetAPI() && *fleetAPIToken == "" {
+			log.Fatalf("Argument fleet_api_token must be specified when the scenario has live_query_storm or mdm_command_flood phases")
+		}
+		*hostCount = sc.hostCount()
+	}
+
 	// only fail if mdm is turned on for macOS devices and the mdm_apns_url is not specified.
+	usedTemplates := *osTemplates
+	if sc != nil {
+		usedTemplates += "," + strings.Join(sc.templateNames(), ",")
+	}
 	if *mdmProb > 0 &&
-		strings.Contains(*osTemplates, "macos") &&
+		strings.Contains(usedTemplates, "macos") &&
 		*mdmAPNSURL == "" {
 		log.Fatalf("Argument mdm_apns_url must be specified when mdm_prob is greater than 0")
 	}
@@ -4448,11 +4566,9 @@ func main() {
 			}
 			numberOfHosts = int(hc)
 		}
-		if !strings.HasSuffix(nm, ".tmpl") {
-			nm += ".tmpl"
-		}
-		if !validTemplateNames[nm] {
-			log.Fatalf("Invalid template name: %s (accepted values: %v)", nm, allowedTemplateNames)
+		nm, err := templateFileName(nm)
+		if err != nil {
+			log.Fatal(err)
 		}
 
 		tmpl, err := template.ParseFS(templatesFS, nm)
@@ -4462,7 +4578,7 @@ func main() {
 		tmplsm[tmpl] = numberOfHosts
 		tmplsTotalHostCount += numberOfHosts
 	}
-	if tmplsTotalHostCount != 0 && tmplsTotalHostCount != *hostCount {
+	if sc == nil && tmplsTotalHostCount != 0 && tmplsTotalHostCount != *hostCount {
 		log.Fatalf("Invalid host count in templates: total=%d vs host_count=%d", tmplsTotalHostCount, *hostCount)
 	}
 
@@ -4481,28 +4597,19 @@ func main() {
 		nodeKeyManager.LoadKeys()
 	}
 
+	var state *scenarioState
+	if sc != nil {
+		state = new(scenarioState)
+	}
+
 	var tmplss []*template.Template
 	for tmpl := range tmplsm {
 		tmplss = append(tmplss, tmpl)
 	}
 
-	for i := 0; i < *hostCount; i++ {
-		var tmpl *template.Template
-		if tmplsTotalHostCount > 0 {
-			for tmpl_, hostCount := range tmplsm {
-				if hostCount > 0 {
-					tmpl = tmpl_
-					tmplsm[tmpl_]--
-					break
-				}
-			}
-			if tmpl == nil {
-				log.Fatalf("Failed to determine template for host: %d", i)
-			}
-		} else {
-			tmpl = tmplss[i%len(tmplss)]
-		}
-
+	// startHost starts the host with the given index and OS template, and
+	// returns its agent if it runs osquery.
+	startHost := func(i int, tmpl *template.Template) *agent {
 		if tmpl.Name() == "iphone_14.6.tmpl" || tmpl.Name() == "ipad_13.18.tmpl" || tmpl.Name() == "iphone_17.tmpl" {
 			if *mdmAPNSURL == "" {
 				log.Fatalf("Argument mdm_apns_url must be specified when iOS/iPadOS templates are used.")
@@ -4545,8 +4652,7 @@ func main() {
 				apnsPushURL:           *mdmAPNSURL,
 			}
 			go mobileDevice.runAppleIDeviceMDMLoop(*mdmSCEPChallenge)
-			time.Sleep(sleepTime)
-			continue
+			return nil
 		}
 
 		if tmpl.Name() == "android.tmpl" {
@@ -4566,8 +4672,7 @@ func main() {
 				stats,
 			)
 			go androidDevice.runLoop()
-			time.Sleep(sleepTime)
-			continue
+			return nil
 		}
 
 		a := newAgent(i+1,
@@ -4664,7 +4769,66 @@ func main() {
 		)
 		a.stats = stats
 		a.nodeKeyManager = nodeKeyManager
+		a.scenario = state
 		go a.runLoop(i, *onlyAlreadyEnrolled)
+		return a
+	}
+
+	if sc != nil {
+		// the latency of the requests is recorded for the scenario summaries
+		tr := http.DefaultClient.Transport
+		http.DefaultClient.Transport = &statsTransport{next: tr, stats: stats}
+
+		var apiClient *service.Client
+		if sc.usesFleetAPI() {
+			var err error
+			apiClient, err = service.NewClient(*serverURL, true, "", "")
+			if err != nil {
+				log.Fatalf("create Fleet API client: %s", err)
+			}
+			apiClient.SetToken(*fleetAPIToken)
+		}
+		defaultTemplates := make([]string, 0, len(tmplss))
+		for _, tmpl := range tmplss {
+			defaultTemplates = append(defaultTemplates, tmpl.Name())
+		}
+		sort.Strings(defaultTemplates)
+
+		runner := &scenarioRunner{
+			scenario:         sc,
+			state:            state,
+			stats:            stats,
+			startHost:        startHost,
+			defaultTemplates: defaultTemplates,
+			apiClient:        apiClient,
+			summaryPath:      *scenarioSummaryPath,
+		}
+		if err := runner.run(); err != nil {
+			log.Fatalf("scenario %q: %s", sc.Name, err)
+		}
+		stats.Log()
+		log.Printf("Scenario %q done, summary written to %s", sc.Name, *scenarioSummaryPath)
+		return
+	}
+
+	for i := 0; i < *hostCount; i++ {
+		var tmpl *template.Template
+		if tmplsTotalHostCount > 0 {
+			for tmpl_, hostCount := range tmplsm {
+				if hostCount > 0 {
+					tmpl = tmpl_
+					tmplsm[tmpl_]--
+					break
+				}
+			}
+			if tmpl == nil {
+				log.Fatalf("Failed to determine template for host: %d", i)
+			}
+		} else {
+			tmpl = tmplss[i%len(tmplss)]
+		}
+
+		startHost(i, tmpl)
 		time.Sleep(sleepTime)
 	}
 
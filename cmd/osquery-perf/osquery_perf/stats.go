@@ -3,11 +3,18 @@ package osquery_perf
 import (
 	"fmt"
 	"log"
+	"math/rand"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 )
 
+// maxLatencySamples is the number of request latencies kept per endpoint and
+// phase to compute the percentiles of a phase summary. Past that, reservoir
+// sampling keeps a uniform sample of all the requests of the phase.
+const maxLatencySamples = 10_000
+
 type Stats struct {
 	StartTime                      time.Time
 	errors                         int
@@ -77,9 +84,147 @@ type Stats struct {
 	pssoKeyExchanges               int
 	pssoErrors                     int
 
+	// phase holds the request latencies and errors of the scenario phase
+	// currently running, nil when no phase is running.
+	phase *phaseStats
+
 	l sync.Mutex
 }
 
+type phaseStats struct {
+	name      string
+	startedAt time.Time
+	endpoints map[string]*endpointStats
+}
+
+type endpointStats struct {
+	requests int
+	errors   int
+	total    time.Duration
+	max      time.Duration
+	samples  []time.Duration
+}
+
+// PhaseSummary is the machine-readable latency and error summary of a
+// scenario phase.
+type PhaseSummary struct {
+	Name      string                      `json:"name"`
+	StartedAt time.Time                   `json:"started_at"`
+	EndedAt   time.Time                   `json:"ended_at"`
+	Requests  int                         `json:"requests"`
+	Errors    int                         `json:"errors"`
+	ErrorRate float64                     `json:"error_rate"`
+	Endpoints map[string]*EndpointSummary `json:"endpoints"`
+}
+
+// EndpointSummary is the latency and error summary of the requests sent to an
+// endpoint during a scenario phase. Latencies are in milliseconds.
+type EndpointSummary struct {
+	Requests  int     `json:"requests"`
+	Errors    int     `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	MeanMs    float64 `json:"mean_ms"`
+	P50Ms     float64 `json:"p50_ms"`
+	P90Ms     float64 `json:"p90_ms"`
+	P99Ms     float64 `json:"p99_ms"`
+	MaxMs     float64 `json:"max_ms"`
+}
+
+// StartPhase starts recording the request latencies and errors of the
+// scenario phase with the given name, replacing any phase still running.
+func (s *Stats) StartPhase(name string) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.phase = &phaseStats{
+		name:      name,
+		startedAt: time.Now(),
+		endpoints: make(map[string]*endpointStats),
+	}
+}
+
+// RecordRequest records the latency of a request sent to endpoint and whether
+// it failed. It is a no-op when no scenario phase is running.
+func (s *Stats) RecordRequest(endpoint string, latency time.Duration, failed bool) {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.phase == nil {
+		return
+	}
+	e := s.phase.endpoints[endpoint]
+	if e == nil {
+		e = &endpointStats{}
+		s.phase.endpoints[endpoint] = e
+	}
+	e.requests++
+	if failed {
+		e.errors++
+	}
+	e.total += latency
+	e.max = max(e.max, latency)
+	if len(e.samples) < maxLatencySamples {
+		e.samples = append(e.samples, latency)
+	} else if i := rand.Intn(e.requests); i < maxLatencySamples { // nolint:gosec // load testing, not security-sensitive
+		e.samples[i] = latency
+	}
+}
+
+// EndPhase stops recording the running scenario phase and returns its
+// summary, or nil if no phase is running.
+func (s *Stats) EndPhase() *PhaseSummary {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.phase == nil {
+		return nil
+	}
+	phase := s.phase
+	s.phase = nil
+
+	summary := &PhaseSummary{
+		Name:      phase.name,
+		StartedAt: phase.startedAt,
+		EndedAt:   time.Now(),
+		Endpoints: make(map[string]*EndpointSummary, len(phase.endpoints)),
+	}
+	for name, e := range phase.endpoints {
+		slices.Sort(e.samples)
+		summary.Endpoints[name] = &EndpointSummary{
+			Requests:  e.requests,
+			Errors:    e.errors,
+			ErrorRate: rate(e.errors, e.requests),
+			MeanMs:    milliseconds(e.total / time.Duration(e.requests)),
+			P50Ms:     milliseconds(percentile(e.samples, 50)),
+			P90Ms:     milliseconds(percentile(e.samples, 90)),
+			P99Ms:     milliseconds(percentile(e.samples, 99)),
+			MaxMs:     milliseconds(e.max),
+		}
+		summary.Requests += e.requests
+		summary.Errors += e.errors
+	}
+	summary.ErrorRate = rate(summary.Errors, summary.Requests)
+	return summary
+}
+
+// percentile returns the p-th percentile of the sorted latencies using the
+// nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
 func (s *Stats) IncrementErrors(errors int) {
 	s.l.Lock()
 	defer s.l.Unlock()
@@ -0,0 +1,61 @@
+package osquery_perf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPhaseSummary(t *testing.T) {
+	s := &Stats{StartTime: time.Now()}
+
+	s.RecordRequest("POST /api/osquery/config", time.Second, false)
+	assert.Nil(t, s.EndPhase())
+
+	s.StartPhase("steady")
+	for i := 1; i <= 100; i++ {
+		s.RecordRequest("POST /api/osquery/config", time.Duration(i)*time.Millisecond, i > 95)
+	}
+	summary := s.EndPhase()
+	require.NotNil(t, summary)
+	assert.Equal(t, "steady", summary.Name)
+	assert.False(t, summary.EndedAt.Before(summary.StartedAt))
+	assert.Equal(t, 100, summary.Requests)
+	assert.Equal(t, 5, summary.Errors)
+	assert.Equal(t, 0.05, summary.ErrorRate)
+	assert.Equal(t, &EndpointSummary{
+		Requests:  100,
+		Errors:    5,
+		ErrorRate: 0.05,
+		MeanMs:    50.5,
+		P50Ms:     50,
+		P90Ms:     90,
+		P99Ms:     99,
+		MaxMs:     100,
+	}, summary.Endpoints["POST /api/osquery/config"])
+
+	// the latencies of a phase do not leak in the next one
+	s.StartPhase("storm")
+	s.RecordRequest("POST /api/osquery/distributed/read", 2*time.Millisecond, false)
+	summary = s.EndPhase()
+	assert.Equal(t, 1, summary.Requests)
+	assert.Len(t, summary.Endpoints, 1)
+	assert.Equal(t, 2.0, summary.Endpoints["POST /api/osquery/distributed/read"].P99Ms)
+}
+
+func TestPhaseSummarySampling(t *testing.T) {
+	s := &Stats{StartTime: time.Now()}
+	s.StartPhase("ramp-up")
+	for i := range 3 * maxLatencySamples {
+		s.RecordRequest("POST /api/osquery/enroll", time.Duration(i%1000)*time.Millisecond, false)
+	}
+	require.Len(t, s.phase.endpoints["POST /api/osquery/enroll"].samples, maxLatencySamples)
+
+	summary := s.EndPhase()
+	e := summary.Endpoints["POST /api/osquery/enroll"]
+	assert.Equal(t, 3*maxLatencySamples, e.Requests)
+	assert.Equal(t, 999.0, e.MaxMs)
+	assert.InDelta(t, 500, e.P50Ms, 50)
+}
@@ -0,0 +1,678 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/cmd/osquery-perf/osquery_perf"
+	"github.com/fleetdm/fleet/v4/server/service"
+	"gopkg.in/yaml.v2"
+)
+
+// scenarioPhaseKind is the kind of traffic generated by a scenario phase.
+type scenarioPhaseKind string
+
+const (
+	// phaseRampUp starts host_count new hosts spread over the phase duration.
+	phaseRampUp scenarioPhaseKind = "ramp_up"
+	// phaseSteady keeps the running hosts checking in at their intervals.
+	phaseSteady scenarioPhaseKind = "steady"
+	// phaseReEnroll has a share of the running hosts enroll again.
+	phaseReEnroll scenarioPhaseKind = "re_enroll"
+	// phaseSoftwareChurn has the hosts uninstall software each time they
+	// report it.
+	phaseSoftwareChurn scenarioPhaseKind = "software_churn"
+	// phaseLiveQueryStorm runs live queries against the running hosts through
+	// the Fleet API.
+	phaseLiveQueryStorm scenarioPhaseKind = "live_query_storm"
+	// phaseMDMCommandFlood enqueues MDM commands for the MDM-enrolled hosts
+	// through the Fleet API.
+	phaseMDMCommandFlood scenarioPhaseKind = "mdm_command_flood"
+	// phaseReplay replays the osquery requests of a recorded capture.
+	phaseReplay scenarioPhaseKind = "replay"
+)
+
+const (
+	defaultLiveQuery = "SELECT * FROM osquery_info;"
+	// liveQueryStormTTL is how long the live queries of a storm collect the
+	// results of the hosts.
+	liveQueryStormTTL = time.Minute
+
+	darwinMDMCommand = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Command</key>
+	<dict>
+		<key>Queries</key>
+		<array>
+			<string>OSVersion</string>
+		</array>
+		<key>RequestType</key>
+		<string>DeviceInformation</string>
+	</dict>
+</dict>
+</plist>`
+	windowsMDMCommand = `<Exec>
+	<Item>
+		<Target>
+			<LocURI>./Device/Vendor/MSFT/Reboot/RebootNow</LocURI>
+		</Target>
+		<Meta>
+			<Format xmlns="syncml:metinf">null</Format>
+			<Type>text/plain</Type>
+		</Meta>
+		<Data></Data>
+	</Item>
+</Exec>`
+)
+
+// scenario is a load test made of phases run one after the other, loaded
+// from a YAML file with the -scenario flag.
+type scenario struct {
+	Name   string          `yaml:"name"`
+	Phases []scenarioPhase `yaml:"phases"`
+}
+
+type scenarioPhase struct {
+	Name     string            `yaml:"name"`
+	Kind     scenarioPhaseKind `yaml:"kind"`
+	Duration time.Duration     `yaml:"duration"`
+
+	// HostCount is the number of hosts started by a ramp_up phase, and
+	// OSTemplates their OS template mix, as template name to host count. When
+	// empty, the hosts alternate between the templates of -os_templates.
+	HostCount   int            `yaml:"host_count"`
+	OSTemplates map[string]int `yaml:"os_templates"`
+
+	// The intervals override the ones set by flags while the phase runs.
+	QueryInterval  time.Duration `yaml:"query_interval"`
+	ConfigInterval time.Duration `yaml:"config_interval"`
+	LogInterval    time.Duration `yaml:"logger_tls_period"`
+
+	// ReEnrollProb is the probability of a running host to enroll again
+	// during a re_enroll phase (defaults to 1).
+	ReEnrollProb *float64 `yaml:"re_enroll_prob"`
+	// SoftwareUninstallProb overrides the common and unique software uninstall
+	// probabilities during a software_churn phase (defaults to 1).
+	SoftwareUninstallProb *float64 `yaml:"software_uninstall_prob"`
+
+	LiveQuery            string `yaml:"live_query"`
+	LiveQueriesPerMinute int    `yaml:"live_queries_per_minute"`
+	MDMCommandsPerMinute int    `yaml:"mdm_commands_per_minute"`
+	// TargetHostCount is the number of hosts targeted by each live query or
+	// MDM command, all the eligible hosts when 0.
+	TargetHostCount int `yaml:"target_host_count"`
+
+	// Capture is the path of the capture replayed by a replay phase, relative
+	// to the scenario file, and Speed the replay speed factor (defaults to 1).
+	Capture string  `yaml:"capture"`
+	Speed   float64 `yaml:"speed"`
+
+	capture []captureEntry
+}
+
+// loadScenario reads and validates the scenario file at path, along with the
+// captures of its replay phases.
+func loadScenario(path string) (*scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read scenario: %w", err)
+	}
+	var sc scenario
+	if err := yaml.UnmarshalStrict(b, &sc); err != nil {
+		return nil, fmt.Errorf("parse scenario: %w", err)
+	}
+	if sc.Name == "" {
+		sc.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	if len(sc.Phases) == 0 {
+		return nil, errors.New("scenario has no phases")
+	}
+
+	names := make(map[string]bool, len(sc.Phases))
+	for i := range sc.Phases {
+		p := &sc.Phases[i]
+		if p.Name == "" {
+			p.Name = fmt.Sprintf("%d-%s", i+1, p.Kind)
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("duplicate phase name %q", p.Name)
+		}
+		names[p.Name] = true
+
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("phase %q: %w", p.Name, err)
+		}
+		if p.Kind == phaseReplay {
+			capturePath := p.Capture
+			if !filepath.IsAbs(capturePath) {
+				capturePath = filepath.Join(filepath.Dir(path), capturePath)
+			}
+			if p.capture, err = loadCapture(capturePath); err != nil {
+				return nil, fmt.Errorf("phase %q: %w", p.Name, err)
+			}
+		}
+	}
+	if sc.hostCount() == 0 {
+		return nil, errors.New("scenario has no ramp_up phase to start hosts")
+	}
+	return &sc, nil
+}
+
+func (p *scenarioPhase) validate() error {
+	if p.Duration < 0 || p.QueryInterval < 0 || p.ConfigInterval < 0 || p.LogInterval < 0 {
+		return errors.New("durations and intervals cannot be negative")
+	}
+	if p.Duration == 0 && p.Kind != phaseReplay {
+		return errors.New("duration is required")
+	}
+	for _, prob := range []*float64{p.ReEnrollProb, p.SoftwareUninstallProb} {
+		if prob != nil && (*prob < 0 || *prob > 1) {
+			return errors.New("probabilities must be between 0 and 1")
+		}
+	}
+	if p.TargetHostCount < 0 {
+		return errors.New("target_host_count cannot be negative")
+	}
+
+	switch p.Kind {
+	case phaseRampUp:
+		if p.HostCount <= 0 {
+			return errors.New("host_count must be greater than 0")
+		}
+		if len(p.OSTemplates) > 0 {
+			var total int
+			for nm, count := range p.OSTemplates {
+				if _, err := templateFileName(nm); err != nil {
+					return err
+				}
+				if count <= 0 {
+					return fmt.Errorf("os_templates host count of %s must be greater than 0", nm)
+				}
+				total += count
+			}
+			if total != p.HostCount {
+				return fmt.Errorf("os_templates host counts add up to %d, not host_count %d", total, p.HostCount)
+			}
+		}
+	case phaseSteady, phaseReEnroll, phaseSoftwareChurn:
+	case phaseLiveQueryStorm:
+		if p.LiveQueriesPerMinute <= 0 {
+			return errors.New("live_queries_per_minute must be greater than 0")
+		}
+		if p.LiveQuery == "" {
+			p.LiveQuery = defaultLiveQuery
+		}
+	case phaseMDMCommandFlood:
+		if p.MDMCommandsPerMinute <= 0 {
+			return errors.New("mdm_commands_per_minute must be greater than 0")
+		}
+	case phaseReplay:
+		if p.Capture == "" {
+			return errors.New("capture is required")
+		}
+		if p.Speed < 0 {
+			return errors.New("speed cannot be negative")
+		}
+		if p.Speed == 0 {
+			p.Speed = 1
+		}
+	case "":
+		return errors.New("kind is required")
+	default:
+		return fmt.Errorf("unknown kind %q", p.Kind)
+	}
+	return nil
+}
+
+// hostCount returns the number of hosts started by the scenario.
+func (sc *scenario) hostCount() int {
+	var n int
+	for _, p := range sc.Phases {
+		if p.Kind == phaseRampUp {
+			n += p.HostCount
+		}
+	}
+	return n
+}
+
+// templateNames returns the OS template names used by the ramp_up phases of
+// the scenario.
+func (sc *scenario) templateNames() []string {
+	var names []string
+	for _, p := range sc.Phases {
+		for nm := range p.OSTemplates {
+			names = append(names, nm)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// usesFleetAPI returns true if the scenario has phases that generate traffic
+// through the Fleet API, which requires an API token.
+func (sc *scenario) usesFleetAPI() bool {
+	for _, p := range sc.Phases {
+		if p.Kind == phaseLiveQueryStorm || p.Kind == phaseMDMCommandFlood {
+			return true
+		}
+	}
+	return false
+}
+
+// hostTemplates returns the OS template file names of the hosts started by a
+// ramp_up phase, in start order. The templates are interleaved so that the
+// mix is the same throughout the phase.
+func (p *scenarioPhase) hostTemplates(defaultTemplates []string) []string {
+	if len(p.OSTemplates) == 0 {
+		hosts := make([]string, p.HostCount)
+		for i := range hosts {
+			hosts[i] = defaultTemplates[i%len(defaultTemplates)]
+		}
+		return hosts
+	}
+
+	names := make([]string, 0, len(p.OSTemplates))
+	for nm := range p.OSTemplates {
+		names = append(names, nm)
+	}
+	sort.Strings(names)
+
+	// each host is given the template that is the furthest behind its share
+	// of the hosts started so far
+	started := make(map[string]int, len(names))
+	hosts := make([]string, 0, p.HostCount)
+	for i := 1; i <= p.HostCount; i++ {
+		var next string
+		var nextLag float64
+		for _, nm := range names {
+			lag := float64(p.OSTemplates[nm])*float64(i)/float64(p.HostCount) - float64(started[nm])
+			if next == "" || lag > nextLag {
+				next, nextLag = nm, lag
+			}
+		}
+		started[next]++
+		fileName, _ := templateFileName(next) // validated when loading the scenario
+		hosts = append(hosts, fileName)
+	}
+	return hosts
+}
+
+// scenarioState is shared by the agents to apply the overrides of the
+// running scenario phase. A nil *scenarioState is valid and never overrides
+// anything, which is the case when osquery-perf does not run a scenario.
+type scenarioState struct {
+	current atomic.Pointer[scenarioPhase]
+}
+
+// phase returns the scenario phase currently running, nil if none.
+func (s *scenarioState) phase() *scenarioPhase {
+	if s == nil {
+		return nil
+	}
+	return s.current.Load()
+}
+
+// The capture request paths that can be replayed. Distributed writes are not
+// replayed on their own, as they are sent after a distributed read that
+// returned queries.
+const (
+	captureEnrollPath          = "/api/osquery/enroll"
+	captureConfigPath          = "/api/osquery/config"
+	captureDistributedReadPath = "/api/osquery/distributed/read"
+	captureLogPath             = "/api/osquery/log"
+)
+
+// captureEntry is a request of a recorded capture of osquery traffic. A
+// capture is a file of JSON objects, one per line, e.g.:
+//
+//	{"time": "2026-10-01T12:00:00.125Z", "host": "host-1", "path": "/api/osquery/config"}
+//
+// Host is any value identifying the host that sent the request (e.g. its
+// node key or IP address).
+type captureEntry struct {
+	Time time.Time `json:"time"`
+	Host string    `json:"host"`
+	Path string    `json:"path"`
+
+	offset time.Duration
+}
+
+// loadCapture reads the capture at path, sorted by time. Requests to paths
+// that cannot be replayed are skipped.
+func loadCapture(path string) ([]captureEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open capture: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		entries []captureEntry
+		skipped int
+	)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var e captureEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("parse capture line %d: %w", line, err)
+		}
+		if e.Time.IsZero() || e.Host == "" {
+			return nil, fmt.Errorf("capture line %d: time and host are required", line)
+		}
+		switch e.Path {
+		case captureEnrollPath, captureConfigPath, captureDistributedReadPath, captureLogPath:
+			entries = append(entries, e)
+		default:
+			skipped++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read capture: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("capture has no requests to replay")
+	}
+	if skipped > 0 {
+		log.Printf("capture %s: skipped %d requests that cannot be replayed", path, skipped)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	for i := range entries {
+		entries[i].offset = entries[i].Time.Sub(entries[0].Time)
+	}
+	return entries, nil
+}
+
+// replayCapture calls send for each entry of the capture at the same
+// relative time as it was recorded, divided by speed. It returns when all the
+// entries were sent or when ctx is done.
+func replayCapture(ctx context.Context, entries []captureEntry, speed float64, send func(captureEntry)) {
+	start := time.Now()
+	for _, e := range entries {
+		wait := time.Until(start.Add(time.Duration(float64(e.offset) / speed)))
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		} else if ctx.Err() != nil {
+			return
+		}
+		send(e)
+	}
+}
+
+// replayRequest sends the request of a capture entry as the agent.
+func (a *agent) replayRequest(path string) {
+	switch path {
+	case captureEnrollPath:
+		_ = a.requestEnroll()
+	case captureConfigPath:
+		_ = a.config()
+	case captureDistributedReadPath:
+		if resp, err := a.DistributedRead(); err == nil && len(resp.Queries) > 0 {
+			_ = a.DistributedWrite(resp.Queries)
+		}
+	case captureLogPath:
+		// the buffered results are owned by the logger thread, so a result of
+		// each scheduled query is sent instead
+		var results []resultLog
+		a.scheduledQueryMapMutex.RLock()
+		queryData := a.scheduledQueryData
+		a.scheduledQueryMapMutex.RUnlock()
+		queryData.Range(func(_, value any) bool {
+			query := value.(scheduledQuery)
+			results = append(results, resultLog{packName: query.packName, queryName: query.Name, numRows: int(query.numRows)})
+			return true
+		})
+		if len(results) > 0 {
+			_ = a.submitLogs(results)
+		}
+	}
+}
+
+// statsTransport records the latency of the requests sent through it, up to
+// the response headers, in the running scenario phase stats.
+type statsTransport struct {
+	next  http.RoundTripper
+	stats *osquery_perf.Stats
+}
+
+func (t *statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	t.stats.RecordRequest(requestEndpoint(req), time.Since(start), err != nil || resp.StatusCode >= http.StatusBadRequest)
+	return resp, err
+}
+
+// requestEndpoint returns the endpoint name of the request used in the phase
+// summaries, its method and path with the numeric IDs replaced by ":id".
+func requestEndpoint(req *http.Request) string {
+	segments := strings.Split(req.URL.Path, "/")
+	for i, s := range segments {
+		if s != "" && strings.Trim(s, "0123456789") == "" {
+			segments[i] = ":id"
+		}
+	}
+	return req.Method + " " + strings.Join(segments, "/")
+}
+
+// scenarioSummary is the content of the -scenario_summary file, written after
+// each phase.
+type scenarioSummary struct {
+	Scenario string                       `json:"scenario"`
+	Phases   []*osquery_perf.PhaseSummary `json:"phases"`
+}
+
+// scenarioRunner runs the phases of a scenario.
+type scenarioRunner struct {
+	scenario *scenario
+	state    *scenarioState
+	stats    *osquery_perf.Stats
+	// startHost starts the host with the given index and OS template, and
+	// returns its agent if it runs osquery (nil for iOS, iPadOS and Android
+	// hosts).
+	startHost func(i int, tmpl *template.Template) *agent
+	// defaultTemplates are the OS template file names of -os_templates.
+	defaultTemplates []string
+	// apiClient is used by the phases that generate traffic through the
+	// Fleet API, nil if the scenario has none.
+	apiClient   *service.Client
+	summaryPath string
+
+	hostsStarted int
+	agents       []*agent
+}
+
+func (r *scenarioRunner) run() error {
+	summary := scenarioSummary{Scenario: r.scenario.Name}
+	for i := range r.scenario.Phases {
+		p := &r.scenario.Phases[i]
+		log.Printf("scenario %q: starting phase %q (%s)", r.scenario.Name, p.Name, p.Kind)
+
+		r.state.current.Store(p)
+		r.stats.StartPhase(p.Name)
+		err := r.runPhase(p)
+		phaseSummary := r.stats.EndPhase()
+		r.state.current.Store(nil)
+
+		summary.Phases = append(summary.Phases, phaseSummary)
+		if err := writeScenarioSummary(r.summaryPath, summary); err != nil {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("phase %q: %w", p.Name, err)
+		}
+		log.Printf("scenario %q: phase %q done: %d requests, error rate %.4f",
+			r.scenario.Name, p.Name, phaseSummary.Requests, phaseSummary.ErrorRate)
+	}
+	return nil
+}
+
+func (r *scenarioRunner) runPhase(p *scenarioPhase) error {
+	deadline := time.Now().Add(p.Duration)
+
+	switch p.Kind {
+	case phaseRampUp:
+		tmpls := make(map[string]*template.Template)
+		hosts := p.hostTemplates(r.defaultTemplates)
+		sleepTime := p.Duration / time.Duration(len(hosts))
+		for _, nm := range hosts {
+			tmpl, ok := tmpls[nm]
+			if !ok {
+				var err error
+				if tmpl, err = template.ParseFS(templatesFS, nm); err != nil {
+					return fmt.Errorf("parse template %s: %w", nm, err)
+				}
+				tmpls[nm] = tmpl
+			}
+			if a := r.startHost(r.hostsStarted, tmpl); a != nil {
+				r.agents = append(r.agents, a)
+			}
+			r.hostsStarted++
+			time.Sleep(sleepTime)
+		}
+
+	case phaseReEnroll:
+		prob := 1.0
+		if p.ReEnrollProb != nil {
+			prob = *p.ReEnrollProb
+		}
+		var agents []*agent
+		for _, a := range r.agents {
+			if rand.Float64() < prob {
+				agents = append(agents, a)
+			}
+		}
+		if len(agents) > 0 {
+			sleepTime := p.Duration / time.Duration(len(agents))
+			for _, a := range agents {
+				go func() { _ = a.requestEnroll() }()
+				time.Sleep(sleepTime)
+			}
+		}
+
+	case phaseLiveQueryStorm:
+		r.every(deadline, time.Minute/time.Duration(p.LiveQueriesPerMinute), func() {
+			hosts := sampleAgents(r.agents, p.TargetHostCount, nil)
+			if len(hosts) == 0 {
+				return
+			}
+			identifiers := make([]string, 0, len(hosts))
+			for _, a := range hosts {
+				identifiers = append(identifiers, a.UUID)
+			}
+			start := time.Now()
+			_, err := r.apiClient.DeferredLiveQuery(p.LiveQuery, nil, nil, identifiers, liveQueryStormTTL)
+			r.stats.RecordRequest("fleet api: live query", time.Since(start), err != nil)
+			if err != nil {
+				log.Printf("live query storm: %s", err)
+			}
+		})
+
+	case phaseMDMCommandFlood:
+		r.every(deadline, time.Minute/time.Duration(p.MDMCommandsPerMinute), func() {
+			for platform, cmd := range map[string]string{"darwin": darwinMDMCommand, "windows": windowsMDMCommand} {
+				hosts := sampleAgents(r.agents, p.TargetHostCount, func(a *agent) bool {
+					return a.mdmEnrolled() && (a.os == "macos" && platform == "darwin" || a.os == "windows" && platform == "windows")
+				})
+				if len(hosts) == 0 {
+					continue
+				}
+				uuids := make([]string, 0, len(hosts))
+				for _, a := range hosts {
+					uuids = append(uuids, a.UUID)
+				}
+				start := time.Now()
+				_, err := r.apiClient.RunMDMCommand(uuids, []byte(cmd), platform)
+				r.stats.RecordRequest("fleet api: mdm command "+platform, time.Since(start), err != nil)
+				if err != nil {
+					log.Printf("mdm command flood: %s", err)
+				}
+			}
+		})
+
+	case phaseReplay:
+		if len(r.agents) == 0 {
+			return errors.New("no osquery hosts to replay the capture, start them with a ramp_up phase first")
+		}
+		ctx := context.Background()
+		if p.Duration > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, deadline)
+			defer cancel()
+		}
+		// the capture hosts are mapped to the agents in order of appearance
+		hostAgents := make(map[string]*agent)
+		replayCapture(ctx, p.capture, p.Speed, func(e captureEntry) {
+			a, ok := hostAgents[e.Host]
+			if !ok {
+				a = r.agents[len(hostAgents)%len(r.agents)]
+				hostAgents[e.Host] = a
+			}
+			go a.replayRequest(e.Path)
+		})
+		if p.Duration == 0 {
+			return nil
+		}
+	}
+
+	time.Sleep(time.Until(deadline))
+	return nil
+}
+
+// every calls fn in a new goroutine at each interval until the deadline.
+func (r *scenarioRunner) every(deadline time.Time, interval time.Duration, fn func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for time.Until(deadline) > interval {
+		<-ticker.C
+		go fn()
+	}
+}
+
+// sampleAgents returns n random agents that match filter (all of them if n is
+// 0). A nil filter matches all agents.
+func sampleAgents(agents []*agent, n int, filter func(*agent) bool) []*agent {
+	var matching []*agent
+	for _, a := range agents {
+		if filter == nil || filter(a) {
+			matching = append(matching, a)
+		}
+	}
+	if n == 0 || n >= len(matching) {
+		return matching
+	}
+	rand.Shuffle(len(matching), func(i, j int) { matching[i], matching[j] = matching[j], matching[i] })
+	return matching[:n]
+}
+
+func writeScenarioSummary(path string, summary scenarioSummary) error {
+	b, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal scenario summary: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil { // nolint:gosec // load testing output
+		return fmt.Errorf("write scenario summary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/cmd/osquery-perf/osquery_perf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeScenarioFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadScenario(t *testing.T) {
+	dir := t.TempDir()
+	writeScenarioFile(t, dir, "capture.jsonl", `{"time": "2026-10-01T12:00:01Z", "host": "b", "path": "/api/osquery/config"}
+{"time": "2026-10-01T12:00:00Z", "host": "a", "path": "/api/osquery/distributed/read"}
+
+{"time": "2026-10-01T12:00:02Z", "host": "a", "path": "/api/fleet/orbit/config"}
+`)
+	path := writeScenarioFile(t, dir, "upgrade.yml", `
+phases:
+  - name: ramp-up
+    kind: ramp_up
+    duration: 10m
+    host_count: 10
+    os_templates:
+      macos_14.1.2: 7
+      windows_11.tmpl: 3
+  - kind: steady
+    duration: 30m
+    query_interval: 30s
+  - kind: re_enroll
+    duration: 5m
+    re_enroll_prob: 0.5
+  - kind: live_query_storm
+    duration: 5m
+    live_queries_per_minute: 60
+  - kind: replay
+    capture: capture.jsonl
+    speed: 2
+`)
+
+	sc, err := loadScenario(path)
+	require.NoError(t, err)
+	assert.Equal(t, "upgrade", sc.Name)
+	assert.Equal(t, 10, sc.hostCount())
+	assert.Equal(t, []string{"macos_14.1.2", "windows_11.tmpl"}, sc.templateNames())
+	assert.True(t, sc.usesFleetAPI())
+
+	require.Len(t, sc.Phases, 5)
+	assert.Equal(t, "ramp-up", sc.Phases[0].Name)
+	assert.Equal(t, 10*time.Minute, sc.Phases[0].Duration)
+	assert.Equal(t, "2-steady", sc.Phases[1].Name)
+	assert.Equal(t, 30*time.Second, sc.Phases[1].QueryInterval)
+	assert.Equal(t, 0.5, *sc.Phases[2].ReEnrollProb)
+	assert.Equal(t, defaultLiveQuery, sc.Phases[3].LiveQuery)
+
+	// the capture is sorted and the orbit request skipped
+	replay := sc.Phases[4]
+	assert.Equal(t, 2.0, replay.Speed)
+	require.Len(t, replay.capture, 2)
+	assert.Equal(t, "a", replay.capture[0].Host)
+	assert.Equal(t, time.Duration(0), replay.capture[0].offset)
+	assert.Equal(t, "b", replay.capture[1].Host)
+	assert.Equal(t, time.Second, replay.capture[1].offset)
+
+	// the example scenario is valid
+	sc, err = loadScenario("scenarios/upgrade-check.yml")
+	require.NoError(t, err)
+	assert.Equal(t, 1000, sc.hostCount())
+}
+
+func TestLoadScenarioErrors(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"no phases", `name: empty`, "scenario has no phases"},
+		{"unknown field", "phases:\n  - kind: steady\n    duration: 1m\n    hosts: 3\n", "field hosts not found"},
+		{"no kind", "phases:\n  - duration: 1m\n", "kind is required"},
+		{"unknown kind", "phases:\n  - kind: chaos\n    duration: 1m\n", `unknown kind "chaos"`},
+		{"no duration", "phases:\n  - kind: steady\n", "duration is required"},
+		{"no ramp up", "phases:\n  - kind: steady\n    duration: 1m\n", "no ramp_up phase"},
+		{"no host count", "phases:\n  - kind: ramp_up\n    duration: 1m\n", "host_count must be greater than 0"},
+		{"bad template", "phases:\n  - kind: ramp_up\n    duration: 1m\n    host_count: 1\n    os_templates:\n      beos: 1\n", "Invalid template name: beos.tmpl"},
+		{"template mix", "phases:\n  - kind: ramp_up\n    duration: 1m\n    host_count: 3\n    os_templates:\n      windows_11: 1\n", "add up to 1, not host_count 3"},
+		{"duplicate name", "phases:\n  - name: a\n    kind: steady\n    duration: 1m\n  - name: a\n    kind: steady\n    duration: 1m\n", `duplicate phase name "a"`},
+		{"bad prob", "phases:\n  - kind: software_churn\n    duration: 1m\n    software_uninstall_prob: 2\n", "probabilities must be between 0 and 1"},
+		{"no storm rate", "phases:\n  - kind: live_query_storm\n    duration: 1m\n", "live_queries_per_minute must be greater than 0"},
+		{"no flood rate", "phases:\n  - kind: mdm_command_flood\n    duration: 1m\n", "mdm_commands_per_minute must be greater than 0"},
+		{"no capture", "phases:\n  - kind: replay\n", "capture is required"},
+		{"missing capture", "phases:\n  - kind: replay\n    capture: nope.jsonl\n", "open capture"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := loadScenario(writeScenarioFile(t, dir, "scenario.yml", c.content))
+			require.ErrorContains(t, err, c.wantErr)
+		})
+	}
+}
+
+func TestLoadCaptureErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := loadCapture(writeScenarioFile(t, dir, "bad.jsonl", "{"))
+	require.ErrorContains(t, err, "parse capture line 1")
+
+	_, err = loadCapture(writeScenarioFile(t, dir, "nohost.jsonl", `{"time": "2026-10-01T12:00:00Z", "path": "/api/osquery/config"}`))
+	require.ErrorContains(t, err, "capture line 1: time and host are required")
+
+	_, err = loadCapture(writeScenarioFile(t, dir, "empty.jsonl", `{"time": "2026-10-01T12:00:00Z", "host": "a", "path": "/version"}`))
+	require.ErrorContains(t, err, "capture has no requests to replay")
+}
+
+func TestScenarioPhaseHostTemplates(t *testing.T) {
+	p := scenarioPhase{HostCount: 6, OSTemplates: map[string]int{"macos_14.1.2": 4, "ubuntu_22.04.tmpl": 2}}
+	assert.Equal(t, []string{
+		"macos_14.1.2.tmpl", "ubuntu_22.04.tmpl", "macos_14.1.2.tmpl",
+		"macos_14.1.2.tmpl", "ubuntu_22.04.tmpl", "macos_14.1.2.tmpl",
+	}, p.hostTemplates(nil))
+
+	p = scenarioPhase{HostCount: 3}
+	assert.Equal(t, []string{"rhel_9.tmpl", "windows_11.tmpl", "rhel_9.tmpl"}, p.hostTemplates([]string{"rhel_9.tmpl", "windows_11.tmpl"}))
+}
+
+func TestReplayCapture(t *testing.T) {
+	entries := []captureEntry{
+		{Host: "a", Path: captureConfigPath, offset: 0},
+		{Host: "b", Path: captureLogPath, offset: 200 * time.Millisecond},
+		{Host: "a", Path: captureEnrollPath, offset: time.Hour},
+	}
+
+	var sent []string
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(t.Context(), 500*time.Millisecond)
+	defer cancel()
+	replayCapture(ctx, entries, 2, func(e captureEntry) {
+		sent = append(sent, e.Host+e.Path)
+	})
+	elapsed := time.Since(start)
+
+	// the entry an hour later is not sent before the context is done, and the
+	// second one is sent twice as fast as recorded
+	assert.Equal(t, []string{"a" + captureConfigPath, "b" + captureLogPath}, sent)
+	assert.GreaterOrEqual(t, elapsed, 500*time.Millisecond)
+}
+
+func TestStatsTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/fleet/orbit/software_install/package/12" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	stats := &osquery_perf.Stats{StartTime: time.Now()}
+	client := &http.Client{Transport: &statsTransport{next: http.DefaultTransport, stats: stats}}
+	send := func(method, path string) {
+		req, err := http.NewRequest(method, srv.URL+path, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// requests are not recorded outside of a phase
+	send("POST", "/api/osquery/config")
+	stats.StartPhase("steady")
+	send("POST", "/api/osquery/config")
+	send("POST", "/api/osquery/config")
+	send("GET", "/api/fleet/orbit/software_install/package/12")
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() { send("POST", "/api/osquery/distributed/read") })
+	}
+	wg.Wait()
+
+	summary := stats.EndPhase()
+	require.NotNil(t, summary)
+	assert.Equal(t, "steady", summary.Name)
+	assert.Equal(t, 13, summary.Requests)
+	assert.Equal(t, 1, summary.Errors)
+	assert.InDelta(t, 1.0/13, summary.ErrorRate, 0.0001)
+	require.Len(t, summary.Endpoints, 3)
+	assert.Equal(t, 2, summary.Endpoints["POST /api/osquery/config"].Requests)
+	assert.Equal(t, 10, summary.Endpoints["POST /api/osquery/distributed/read"].Requests)
+	installs := summary.Endpoints["GET /api/fleet/orbit/software_install/package/:id"]
+	require.NotNil(t, installs)
+	assert.Equal(t, 1, installs.Errors)
+	assert.Equal(t, 1.0, installs.ErrorRate)
+	assert.Positive(t, installs.MaxMs)
+
+	assert.Nil(t, stats.EndPhase())
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/fleetdm/fleet/v4/pkg/str"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/microsoft/msgraph"
 	"github.com/google/uuid"
 	"golang.org/x/oauth2/google"
 	"golang.org/x/oauth2/jwt"
@@ -538,6 +539,10 @@ func RemoteError(err error) (isRemote bool, statusCode int, body string) {
 		}
 		return true, ae.Code, str.TruncateErrorResponse(body)
 	}
+	if graphErr, ok := msgraph.AsError(err); ok {
+		// Microsoft 365 calendar failures, including token-endpoint failures.
+		return true, graphErr.StatusCode, str.TruncateErrorResponse(graphErr.Message)
+	}
 	if isInvalidGrant(err) {
 		// invalid_grant is an OAuth error returned by Google when the service
 		// account cannot impersonate the host's email (e.g. the user is not in
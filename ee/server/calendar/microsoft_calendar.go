@@ -0,0 +1,792 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/microsoft/msgraph"
+	"github.com/google/uuid"
+)
+
+// The Microsoft 365 calendar has the same testing features as the Google calendar:
+// 1. The low level MicrosoftCalendarAPI interface can have a custom implementation.
+// 2. Setting the integration's tenant ID to MicrosoftMockTenantID will use the in-memory implementation
+//    MicrosoftCalendarMockAPI of MicrosoftCalendarAPI, no Microsoft Graph credential is needed in that case.
+//
+// Unlike Google Calendar, Microsoft Graph change notifications are not used: the calendar cron polls the events (which
+// it already does for Google Calendar) and fires the webhook when the event starts.
+
+const (
+	MicrosoftMockTenantID = "00000000-0000-0000-0000-000000000ca1"
+
+	// graphTimeLayout is the layout of the dateTime of a Graph dateTimeTimeZone, which has no offset (the time zone is
+	// a separate field). Graph returns 7 fractional digits.
+	graphTimeLayout = "2006-01-02T15:04:05.9999999"
+	// workingHoursLayout is the layout of the start and end times of the mailbox working hours.
+	workingHoursLayout = "15:04:05.9999999"
+	// preferUTC makes Graph return the event times in UTC regardless of the time zone they were created in.
+	preferUTC = `outlook.timezone="UTC"`
+	// eventSelect lists the event properties Fleet uses.
+	eventSelect = "id,changeKey,subject,body,start,end,isAllDay,isCancelled,showAs,responseStatus"
+
+	// maxEventPages bounds the calendarView pagination, a single working day should never need more than one page.
+	maxEventPages = 10
+	// maxRetryAfter bounds the wait requested by Graph when throttling.
+	maxRetryAfter = time.Minute
+)
+
+// windowsTimeZones maps the Windows time zone names returned by Exchange Online to IANA time zones, for the most
+// common time zones. Graph returns an IANA name when the mailbox was configured with one.
+var windowsTimeZones = map[string]string{
+	"Dateline Standard Time":         "Etc/GMT+12",
+	"UTC-11":                         "Etc/GMT+11",
+	"Hawaiian Standard Time":         "Pacific/Honolulu",
+	"Alaskan Standard Time":          "America/Anchorage",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"US Mountain Standard Time":      "America/Phoenix",
+	"Mountain Standard Time":         "America/Denver",
+	"Central America Standard Time":  "America/Guatemala",
+	"Central Standard Time":          "America/Chicago",
+	"Central Standard Time (Mexico)": "America/Mexico_City",
+	"Canada Central Standard Time":   "America/Regina",
+	"SA Pacific Standard Time":       "America/Bogota",
+	"Eastern Standard Time":          "America/New_York",
+	"Atlantic Standard Time":         "America/Halifax",
+	"Newfoundland Standard Time":     "America/St_Johns",
+	"E. South America Standard Time": "America/Sao_Paulo",
+	"Argentina Standard Time":        "America/Argentina/Buenos_Aires",
+	"UTC":                            "UTC",
+	"GMT Standard Time":              "Europe/London",
+	"Greenwich Standard Time":        "Atlantic/Reykjavik",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Romance Standard Time":          "Europe/Paris",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Central European Standard Time": "Europe/Warsaw",
+	"GTB Standard Time":              "Europe/Bucharest",
+	"FLE Standard Time":              "Europe/Kiev",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"Israel Standard Time":           "Asia/Jerusalem",
+	"South Africa Standard Time":     "Africa/Johannesburg",
+	"Turkey Standard Time":           "Europe/Istanbul",
+	"Russian Standard Time":          "Europe/Moscow",
+	"Arabian Standard Time":          "Asia/Dubai",
+	"Pakistan Standard Time":         "Asia/Karachi",
+	"India Standard Time":            "Asia/Calcutta",
+	"Bangladesh Standard Time":       "Asia/Dhaka",
+	"SE Asia Standard Time":          "Asia/Bangkok",
+	"China Standard Time":            "Asia/Shanghai",
+	"Singapore Standard Time":        "Asia/Singapore",
+	"Taipei Standard Time":           "Asia/Taipei",
+	"Korea Standard Time":            "Asia/Seoul",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"W. Australia Standard Time":     "Australia/Perth",
+	"Cen. Australia Standard Time":   "Australia/Adelaide",
+	"E. Australia Standard Time":     "Australia/Brisbane",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+	"New Zealand Standard Time":      "Pacific/Auckland",
+}
+
+type MicrosoftCalendarConfig struct {
+	Context           context.Context
+	IntegrationConfig *fleet.MicrosoftCalendarIntegration
+	// Credential is the stored Microsoft Graph credential of the integration's tenant. It may be nil when the mock
+	// tenant is used.
+	Credential *fleet.MicrosoftGraphCredential
+	Logger     *slog.Logger
+	ServerURL  string
+	// Should be nil for production
+	API MicrosoftCalendarAPI
+}
+
+// MicrosoftCalendar is an implementation of the UserCalendar interface that uses the
+// Microsoft Graph API to manage events on Microsoft 365 (Exchange Online) calendars.
+type MicrosoftCalendar struct {
+	config           *MicrosoftCalendarConfig
+	currentUserEmail string
+	location         *time.Location
+	workingHours     *workingHours
+}
+
+func NewMicrosoftCalendar(config *MicrosoftCalendarConfig) *MicrosoftCalendar {
+	switch {
+	case config.API != nil:
+		// Use the provided API.
+	case strings.EqualFold(config.IntegrationConfig.TenantID, MicrosoftMockTenantID):
+		config.API = &MicrosoftCalendarMockAPI{logger: config.Logger}
+	default:
+		config.API = &MicrosoftCalendarLowLevelAPI{logger: config.Logger}
+	}
+	return &MicrosoftCalendar{
+		config: config,
+	}
+}
+
+// MicrosoftCalendarAPI is the low level Microsoft Graph calendar API, for the user set with Configure.
+type MicrosoftCalendarAPI interface {
+	Configure(ctx context.Context, cred *fleet.MicrosoftGraphCredential, userEmail string) error
+	GetMailboxSettings() (*MicrosoftMailboxSettings, error)
+	ListEvents(start, end time.Time) ([]*MicrosoftEvent, error)
+	CreateEvent(event *MicrosoftEvent) (*MicrosoftEvent, error)
+	GetEvent(id string) (*MicrosoftEvent, error)
+	UpdateEventBody(id string, body *MicrosoftItemBody) (*MicrosoftEvent, error)
+	DeleteEvent(id string) error
+}
+
+// MicrosoftEvent is the subset of the Microsoft Graph event resource used by Fleet.
+type MicrosoftEvent struct {
+	ID             string                   `json:"id,omitempty"`
+	ChangeKey      string                   `json:"changeKey,omitempty"`
+	Subject        string                   `json:"subject,omitempty"`
+	Body           *MicrosoftItemBody       `json:"body,omitempty"`
+	Start          *MicrosoftDateTime       `json:"start,omitempty"`
+	End            *MicrosoftDateTime       `json:"end,omitempty"`
+	IsAllDay       bool                     `json:"isAllDay,omitempty"`
+	IsCancelled    bool                     `json:"isCancelled,omitempty"`
+	ShowAs         string                   `json:"showAs,omitempty"`
+	IsReminderOn   bool                     `json:"isReminderOn,omitempty"`
+	TransactionID  string                   `json:"transactionId,omitempty"`
+	ResponseStatus *MicrosoftResponseStatus `json:"responseStatus,omitempty"`
+}
+
+type MicrosoftItemBody struct {
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+type MicrosoftDateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type MicrosoftResponseStatus struct {
+	Response string `json:"response"`
+}
+
+// MicrosoftMailboxSettings is the subset of the mailbox settings used by Fleet.
+type MicrosoftMailboxSettings struct {
+	TimeZone     string                 `json:"timeZone"`
+	WorkingHours *MicrosoftWorkingHours `json:"workingHours"`
+}
+
+type MicrosoftWorkingHours struct {
+	DaysOfWeek []string `json:"daysOfWeek"`
+	StartTime  string   `json:"startTime"`
+	EndTime    string   `json:"endTime"`
+	TimeZone   *struct {
+		Name string `json:"name"`
+	} `json:"timeZone"`
+}
+
+// workingHours are the parsed working hours of the current user, as offsets from midnight.
+type workingHours struct {
+	start time.Duration
+	end   time.Duration
+	days  map[time.Weekday]bool
+}
+
+type MicrosoftCalendarLowLevelAPI struct {
+	logger    *slog.Logger
+	ctx       context.Context
+	client    *http.Client
+	cred      fleet.MicrosoftGraphCredential
+	userEmail string
+	// loginHost and graphHost are overridable in tests.
+	loginHost string
+	graphHost string
+}
+
+// Configure sets the user whose calendar is managed. The authenticated HTTP client (and so its access token) is reused
+// as long as the credential does not change.
+func (lowLevelAPI *MicrosoftCalendarLowLevelAPI) Configure(ctx context.Context, cred *fleet.MicrosoftGraphCredential, userEmail string) error {
+	if cred == nil || !cred.Configured() {
+		return ctxerr.New(ctx, "microsoft graph credential is not configured")
+	}
+	if lowLevelAPI.client == nil || !lowLevelAPI.cred.Equal(*cred) {
+		client, err := msgraph.NewHTTPClient(ctx, cred, lowLevelAPI.loginHost)
+		if err != nil {
+			return err
+		}
+		lowLevelAPI.client = client
+		lowLevelAPI.cred = *cred
+	}
+	lowLevelAPI.ctx = ctx
+	lowLevelAPI.userEmail = userEmail
+	return nil
+}
+
+func (lowLevelAPI *MicrosoftCalendarLowLevelAPI) GetMailboxSettings() (*MicrosoftMailboxSettings, error) {
+	var settings MicrosoftMailboxSettings
+	err := lowLevelAPI.withRetry(http.MethodGet, "/mailboxSettings?$select=timeZone,workingHours", nil, &settings)
+	if err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (lowLevelAPI *MicrosoftCalendarLowLevelAPI) ListEvents(start, end time.Time) ([]*MicrosoftEvent, error) {
+	q := url.Values{}
+	q.Set("startDateTime", start.UTC().Format(time.RFC3339))
+	q.Set("endDateTime", end.UTC().Format(time.RFC3339))
+	q.Set("$select", eventSelect)
+	q.Set("$orderby", "start/dateTime")
+	q.Set("$top", "100")
+
+	var events []*MicrosoftEvent
+	path := "/calendarView?" + q.Encode()
+	for range maxEventPages {
+		var page struct {
+			Value    []*MicrosoftEvent `json:"value"`
+			NextLink string            `json:"@odata.nextLink"`
+		}
+		if err := lowLevelAPI.withRetry(http.MethodGet, path, nil, &page); err != nil {
+			return nil, err
+		}
+		events = append(events, page.Value...)
+		if page.NextLink == "" {
+			return events, nil
+		}
+		path = page.NextLink
+	}
+	return nil, ctxerr.Errorf(lowLevelAPI.ctx, "too many pages of calendar events for %s", lowLevelAPI.userEmail)
+}
+
+func (lowLevelAPI *MicrosoftCalendarLowLevelAPI) CreateEvent(event *MicrosoftEvent) (*MicrosoftEvent, error) {
+	var created MicrosoftEvent
+	if err := lowLevelAPI.withRetry(http.MethodPost, "/events", event, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (lowLevelAPI *MicrosoftCalendarLowLevelAPI) GetEvent(id string) (*MicrosoftEvent, error) {
+	var event MicrosoftEvent
+	if err := lowLevelAPI.withRetry(http.MethodGet, "/events/"+url.PathEscape(id)+"?$select="+eventSelect, nil, &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (lowLevelAPI *MicrosoftCalendarLowLevelAPI) UpdateEventBody(id string, body *MicrosoftItemBody) (*MicrosoftEvent, error) {
+	var updated MicrosoftEvent
+	if err := lowLevelAPI.withRetry(http.MethodPatch, "/events/"+url.PathEscape(id), &MicrosoftEvent{Body: body}, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (lowLevelAPI *MicrosoftCalendarLowLevelAPI) DeleteEvent(id string) error {
+	return lowLevelAPI.withRetry(http.MethodDelete, "/events/"+url.PathEscape(id), nil, nil)
+}
+
+// do sends a request for the current user. path is relative to the user resource, unless it is an absolute URL (e.g.
+// a pagination link returned by Graph).
+func (lowLevelAPI *MicrosoftCalendarLowLevelAPI) do(method, path string, body any, result any) error {
+	if lowLevelAPI.client == nil {
+		return ctxerr.New(lowLevelAPI.ctx, "microsoft calendar API not configured")
+	}
+	requestURL := path
+	if !strings.HasPrefix(path, "https://") && !strings.HasPrefix(path, "http://") {
+		graphHost := lowLevelAPI.graphHost
+		if graphHost == "" {
+			graphHost = msgraph.DefaultGraphHost
+		}
+		requestURL = graphHost + "/v1.0/users/" + url.PathEscape(lowLevelAPI.userEmail) + path
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return ctxerr.Wrap(lowLevelAPI.ctx, err, "marshal microsoft graph request")
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(lowLevelAPI.ctx, method, requestURL, reqBody)
+	if err != nil {
+		return ctxerr.Wrap(lowLevelAPI.ctx, err, "build microsoft graph request")
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Prefer", preferUTC)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	respBody, err := msgraph.Do(lowLevelAPI.ctx, lowLevelAPI.client, req)
+	if err != nil {
+		return err
+	}
+	if result == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return ctxerr.Wrap(lowLevelAPI.ctx, err, "decode microsoft graph response")
+	}
+	return nil
+}
+
+func (lowLevelAPI *MicrosoftCalendarLowLevelAPI) withRetry(method, path string, body any, result any) error {
+	retryStrategy := backoff.NewExponentialBackOff()
+	retryStrategy.MaxElapsedTime = 10 * time.Minute
+	return backoff.Retry(
+		func() error {
+			err := lowLevelAPI.do(method, path, body, result)
+			if err == nil {
+				return nil
+			}
+			if graphErr, ok := msgraph.AsError(err); ok && graphErr.IsTransient() {
+				lowLevelAPI.logger.DebugContext(lowLevelAPI.ctx, "retrying Microsoft Graph calendar request", "err", err)
+				if graphErr.RetryAfter > 0 {
+					time.Sleep(min(graphErr.RetryAfter, maxRetryAfter))
+				}
+				return err
+			}
+			return backoff.Permanent(err)
+		}, retryStrategy,
+	)
+}
+
+func (c *MicrosoftCalendar) Configure(userEmail string) error {
+	err := c.config.API.Configure(c.config.Context, c.config.Credential, userEmail)
+	if err != nil {
+		return ctxerr.Wrap(c.config.Context, err, "creating Microsoft calendar service")
+	}
+	c.currentUserEmail = userEmail
+	// Clear the timezone and working hours so that they will be reloaded
+	c.location = nil
+	c.workingHours = nil
+	return nil
+}
+
+func (c *MicrosoftCalendar) UpdateEventBody(event *fleet.CalendarEvent, genBodyFn fleet.CalendarGenBodyFn) (string, error) {
+	details, err := c.unmarshalDetails(event)
+	if err != nil {
+		return "", err
+	}
+	mEvent, err := c.config.API.GetEvent(details.ID)
+	if err != nil {
+		return "", ctxerr.Wrap(c.config.Context, err, "retrieving Microsoft calendar event")
+	}
+	// Check if the current body contains the conflict text
+	conflict := mEvent.Body != nil && strings.Contains(mEvent.Body.Content, fleet.CalendarEventConflictText)
+	body, ok, err := genBodyFn(conflict)
+	if err != nil {
+		return "", ctxerr.Wrap(c.config.Context, err, "generating calendar event body")
+	}
+	if !ok {
+		return "", nil
+	}
+	updatedEvent, err := c.config.API.UpdateEventBody(details.ID, eventBody(body))
+	if err != nil {
+		return "", ctxerr.Wrap(c.config.Context, err, "updating Microsoft calendar event")
+	}
+	return updatedEvent.ChangeKey, nil
+}
+
+func (c *MicrosoftCalendar) GetAndUpdateEvent(event *fleet.CalendarEvent, genBodyFn fleet.CalendarGenBodyFn,
+	opts fleet.CalendarGetAndUpdateEventOpts) (
+	*fleet.CalendarEvent, bool, error,
+) {
+	// We assume that the Fleet event has not already ended. We will simply return it if it has not been modified.
+	details, err := c.unmarshalDetails(event)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Set current calendar instance timezone to the latest from the mailbox settings.
+	var tzUpdated bool
+	var latestTzName string
+	updateTimezone := func() error {
+		if err := c.loadMailboxSettings(); err != nil {
+			return err
+		}
+		latestTzName = c.location.String()
+		// nil if cal event created before Fleet tracked timezone
+		tzUpdated = event.TimeZone == nil || (latestTzName != *event.TimeZone)
+		return nil
+	}
+	if opts.UpdateTimezone {
+		if err := updateTimezone(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	mEvent, err := c.config.API.GetEvent(details.ID)
+	var deleted bool
+	switch {
+	case isMicrosoftNotFound(err):
+		deleted = true
+	case err != nil:
+		return nil, false, ctxerr.Wrap(c.config.Context, err, "retrieving Microsoft calendar event")
+	}
+	if !deleted && !mEvent.IsCancelled {
+		if details.ETag != "" && details.ETag == mEvent.ChangeKey {
+			// Event was not modified
+			if tzUpdated {
+				// the event itself hasn't been updated, but the mailbox timezone has been, so just update the event's
+				// timezone
+				event.TimeZone = &latestTzName
+				return event, true, nil
+			}
+			return event, false, nil
+		}
+
+		var startTime, endTime time.Time
+		if mEvent.IsAllDay {
+			// User has modified the event to be an all-day event. All-day events are problematic because they depend on
+			// the user's timezone. We won't handle all-day events at this time, and treat the event as deleted.
+			if err := c.DeleteEvent(event); err != nil {
+				c.config.Logger.WarnContext(c.config.Context, "deleting Microsoft calendar event which was changed to all-day event", "err", err)
+			}
+			deleted = true
+		} else {
+			startTime, err = c.parseDateTime(mEvent.Start)
+			if err != nil {
+				return nil, false, err
+			}
+			endTime, err = c.parseDateTime(mEvent.End)
+			if err != nil {
+				return nil, false, err
+			}
+			if !endTime.After(time.Now()) {
+				// If event already ended, it is effectively deleted
+				// Delete this event to prevent confusion. This operation should be rare.
+				if err := c.DeleteEvent(event); err != nil {
+					c.config.Logger.WarnContext(c.config.Context, "deleting Microsoft calendar event which is in the past", "err", err)
+				}
+				deleted = true
+			}
+		}
+		if !deleted {
+			if c.location == nil {
+				// When we are updating the event, also update the timezone if needed
+				if err := updateTimezone(); err != nil {
+					return nil, false, err
+				}
+			}
+			fleetEvent, err := c.microsoftEventToFleetEvent(startTime, endTime, mEvent, event.UUID, details.BodyTag)
+			if err != nil {
+				return nil, false, err
+			}
+			return fleetEvent, true, nil
+		}
+	}
+
+	// When calculating the new event date, we don't check if the user's timezone has changed, see the Google calendar
+	// for details. The event keeps its UUID since there is no notification channel tied to it.
+	newStartDate := calculateNewEventDate(event.StartTime)
+	fleetEvent, err := c.CreateEvent(newStartDate, genBodyFn, fleet.CalendarCreateEventOpts{EventUUID: event.UUID})
+	if err != nil {
+		return nil, false, err
+	}
+	return fleetEvent, true, nil
+}
+
+func (c *MicrosoftCalendar) CreateEvent(dayOfEvent time.Time, genBodyFn fleet.CalendarGenBodyFn,
+	opts fleet.CalendarCreateEventOpts) (*fleet.CalendarEvent, error) {
+	return c.createEvent(dayOfEvent, genBodyFn, time.Now, opts)
+}
+
+// createEvent reserves the first free slot of the user's working hours on the given date. timeNow is a function that
+// returns the current time, it can be overwritten for testing.
+func (c *MicrosoftCalendar) createEvent(
+	dayOfEvent time.Time, genBodyFn fleet.CalendarGenBodyFn, timeNow func() time.Time,
+	opts fleet.CalendarCreateEventOpts,
+) (*fleet.CalendarEvent, error) {
+	if c.location == nil || c.workingHours == nil {
+		if err := c.loadMailboxSettings(); err != nil {
+			return nil, err
+		}
+	}
+
+	day := time.Date(dayOfEvent.Year(), dayOfEvent.Month(), dayOfEvent.Day(), 0, 0, 0, 0, c.location)
+	if !c.workingHours.days[day.Weekday()] {
+		// The caller moves on to the next business day.
+		return nil, ctxerr.Wrap(c.config.Context, fleet.DayEndedError{Msg: "cannot schedule an event on a non-working day"})
+	}
+	dayStart := day.Add(c.workingHours.start)
+	dayEnd := day.Add(c.workingHours.end)
+
+	now := timeNow().In(c.location)
+	if dayEnd.Before(now) {
+		// The workday has already ended.
+		return nil, ctxerr.Wrap(c.config.Context, fleet.DayEndedError{Msg: "cannot schedule an event for a day that has already ended"})
+	}
+
+	// Adjust day start if workday already started
+	if !dayStart.After(now) {
+		dayStart = now.Truncate(eventLength)
+		if dayStart.Before(now) {
+			dayStart = dayStart.Add(eventLength)
+		}
+		if !dayStart.Before(dayEnd) {
+			return nil, ctxerr.Wrap(c.config.Context, fleet.DayEndedError{Msg: "no time available for event"})
+		}
+	}
+	eventStart := dayStart
+	eventEnd := dayStart.Add(eventLength)
+
+	events, err := c.config.API.ListEvents(dayStart, dayEnd)
+	if err != nil {
+		return nil, ctxerr.Wrap(c.config.Context, err, "listing Microsoft calendar events")
+	}
+	var conflict bool
+	for _, mEvent := range events {
+		// Ignore cancelled and all day events
+		if mEvent.IsCancelled || mEvent.IsAllDay {
+			continue
+		}
+		// Ignore events that do not block time on the calendar
+		if mEvent.ShowAs == "free" || mEvent.ShowAs == "workingElsewhere" {
+			continue
+		}
+		// Ignore events that the user has declined, this time is open for scheduling
+		if mEvent.ResponseStatus != nil && mEvent.ResponseStatus.Response == "declined" {
+			continue
+		}
+
+		// Ignore events that will end before our event
+		endTime, err := c.parseDateTime(mEvent.End)
+		if err != nil {
+			return nil, err
+		}
+		if !endTime.After(eventStart) {
+			continue
+		}
+
+		startTime, err := c.parseDateTime(mEvent.Start)
+		if err != nil {
+			return nil, err
+		}
+		if startTime.Before(eventEnd) {
+			// Event occurs during our event, so we need to adjust.
+			var isLastSlot bool
+			eventStart, eventEnd, isLastSlot, conflict = adjustEventTimes(endTime, dayEnd)
+			if isLastSlot {
+				break
+			}
+			continue
+		}
+		// Since events are sorted by start time, all subsequent events are after our event, so we can stop processing
+		break
+	}
+
+	body, ok, err := genBodyFn(conflict)
+	if err != nil {
+		return nil, ctxerr.Wrap(c.config.Context, err, "generating Microsoft calendar event body")
+	}
+	if !ok {
+		// We don't need to create this event
+		return nil, nil
+	}
+
+	eventUUID := opts.EventUUID
+	if eventUUID == "" {
+		eventUUID = strings.ToUpper(uuid.New().String()) // Standardize on uppercase UUIDs since that's how they come from DB
+	}
+	// The event is shown as busy so that it reserves the time like a focus time block.
+	event, err := c.config.API.CreateEvent(&MicrosoftEvent{
+		Subject:      eventTitle,
+		Body:         eventBody(body),
+		Start:        formatDateTime(eventStart),
+		End:          formatDateTime(eventEnd),
+		ShowAs:       "busy",
+		IsReminderOn: true,
+		// transactionId makes the creation idempotent if the request is retried.
+		TransactionID: eventUUID + "-" + eventStart.UTC().Format("20060102"),
+	})
+	if err != nil {
+		return nil, ctxerr.Wrap(c.config.Context, err, "creating Microsoft calendar event")
+	}
+
+	// Body tag will be updated by the calling function.
+	fleetEvent, err := c.microsoftEventToFleetEvent(eventStart, eventEnd, event, eventUUID, "body_tag")
+	if err != nil {
+		return nil, err
+	}
+	c.config.Logger.DebugContext(c.config.Context,
+		"created Microsoft calendar event", "user", c.currentUserEmail, "startTime", eventStart, "timezone", c.location.String(),
+	)
+	return fleetEvent, nil
+}
+
+// loadMailboxSettings loads the time zone and working hours of the current user. Missing or unusable working hours
+// fall back to the default 9 to 5, Monday to Friday.
+func (c *MicrosoftCalendar) loadMailboxSettings() error {
+	settings, err := c.config.API.GetMailboxSettings()
+	if err != nil {
+		return ctxerr.Wrap(c.config.Context, err, "retrieving Microsoft mailbox settings")
+	}
+
+	tz := settings.TimeZone
+	if settings.WorkingHours != nil && settings.WorkingHours.TimeZone != nil && settings.WorkingHours.TimeZone.Name != "" {
+		// Working hours are expressed in their own time zone.
+		tz = settings.WorkingHours.TimeZone.Name
+	}
+	c.location = c.getLocation(tz)
+	c.workingHours = c.parseWorkingHours(settings.WorkingHours)
+	return nil
+}
+
+func (c *MicrosoftCalendar) parseWorkingHours(mwh *MicrosoftWorkingHours) *workingHours {
+	wh := &workingHours{
+		start: startHour * time.Hour,
+		end:   endHour * time.Hour,
+		days: map[time.Weekday]bool{
+			time.Monday: true, time.Tuesday: true, time.Wednesday: true, time.Thursday: true, time.Friday: true,
+		},
+	}
+	if mwh == nil {
+		return wh
+	}
+
+	start, startErr := time.Parse(workingHoursLayout, mwh.StartTime)
+	end, endErr := time.Parse(workingHoursLayout, mwh.EndTime)
+	if startErr == nil && endErr == nil && start.Before(end) {
+		wh.start = time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+		wh.end = time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute
+	} else {
+		c.config.Logger.WarnContext(c.config.Context, "ignoring Microsoft mailbox working hours",
+			"user", c.currentUserEmail, "start", mwh.StartTime, "end", mwh.EndTime)
+	}
+
+	days := make(map[time.Weekday]bool, len(mwh.DaysOfWeek))
+	var hasWeekday bool
+	for _, d := range mwh.DaysOfWeek {
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if strings.EqualFold(d, wd.String()) {
+				days[wd] = true
+				hasWeekday = hasWeekday || (wd != time.Saturday && wd != time.Sunday)
+			}
+		}
+	}
+	// Events are only scheduled on weekdays, so working days without any weekday would never get an event.
+	if hasWeekday {
+		wh.days = days
+	}
+	return wh
+}
+
+func (c *MicrosoftCalendar) getLocation(tz string) *time.Location {
+	if iana, ok := windowsTimeZones[tz]; ok {
+		tz = iana
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil || tz == "" {
+		// Could not load location, use EST
+		c.config.Logger.WarnContext(c.config.Context, "parsing Microsoft mailbox timezone", "timezone", tz, "err", err)
+		loc, _ = time.LoadLocation("America/New_York")
+	}
+	return loc
+}
+
+func (c *MicrosoftCalendar) parseDateTime(dt *MicrosoftDateTime) (time.Time, error) {
+	if dt == nil || dt.DateTime == "" {
+		return time.Time{}, ctxerr.New(c.config.Context, "missing date/time for Microsoft calendar event")
+	}
+	// Times are requested in UTC, but are parsed in their time zone in case the Prefer header was not honored.
+	loc := time.UTC
+	if dt.TimeZone != "" && !strings.EqualFold(dt.TimeZone, "UTC") {
+		loc = c.getLocation(dt.TimeZone)
+	}
+	t, err := time.ParseInLocation(graphTimeLayout, dt.DateTime, loc)
+	if err != nil {
+		return time.Time{}, ctxerr.Wrap(c.config.Context, err, fmt.Sprintf("parsing Microsoft calendar event time: %s", dt.DateTime))
+	}
+	return t, nil
+}
+
+func formatDateTime(t time.Time) *MicrosoftDateTime {
+	return &MicrosoftDateTime{DateTime: t.UTC().Format("2006-01-02T15:04:05"), TimeZone: "UTC"}
+}
+
+// eventBody converts the generated event body to HTML, which is what Outlook renders: the body already uses <b> tags,
+// only the line breaks need to be converted.
+func eventBody(body string) *MicrosoftItemBody {
+	return &MicrosoftItemBody{ContentType: "html", Content: strings.ReplaceAll(body, "\n", "<br>\n")}
+}
+
+func isMicrosoftNotFound(err error) bool {
+	graphErr, ok := msgraph.AsError(err)
+	return ok && (graphErr.StatusCode == http.StatusNotFound || graphErr.StatusCode == http.StatusGone)
+}
+
+func (c *MicrosoftCalendar) unmarshalDetails(event *fleet.CalendarEvent) (*eventDetails, error) {
+	var details eventDetails
+	err := json.Unmarshal(event.Data, &details)
+	if err != nil {
+		return nil, ctxerr.Wrap(c.config.Context, err, "unmarshaling Microsoft calendar event details")
+	}
+	if details.ID == "" {
+		return nil, ctxerr.Errorf(c.config.Context, "missing Microsoft calendar event ID")
+	}
+	return &details, nil
+}
+
+func (c *MicrosoftCalendar) microsoftEventToFleetEvent(startTime time.Time, endTime time.Time, event *MicrosoftEvent,
+	eventUUID string, bodyTag string) (*fleet.CalendarEvent, error) {
+	tzName := c.location.String()
+	fleetEvent := &fleet.CalendarEvent{}
+	fleetEvent.StartTime = startTime
+	fleetEvent.EndTime = endTime
+	fleetEvent.Email = c.currentUserEmail
+	fleetEvent.TimeZone = &tzName
+	fleetEvent.UUID = eventUUID
+	// The Graph change key plays the role of the Google ETag.
+	details := &eventDetails{
+		ID:      event.ID,
+		ETag:    event.ChangeKey,
+		BodyTag: bodyTag,
+	}
+	detailsJson, err := json.Marshal(details)
+	if err != nil {
+		return nil, ctxerr.Wrap(c.config.Context, err, "marshaling Microsoft calendar event details")
+	}
+	fleetEvent.Data = detailsJson
+	return fleetEvent, nil
+}
+
+func (c *MicrosoftCalendar) DeleteEvent(event *fleet.CalendarEvent) error {
+	details, err := c.unmarshalDetails(event)
+	if err != nil {
+		return err
+	}
+	err = c.config.API.DeleteEvent(details.ID)
+	switch {
+	case isMicrosoftNotFound(err):
+		return nil
+	case msgraph.CredentialRejected(err):
+		c.config.Logger.WarnContext(c.config.Context, "could not delete calendar event, Microsoft Graph rejected the request",
+			"user", c.currentUserEmail, "err", err)
+		return nil
+	case err != nil:
+		return ctxerr.Wrap(c.config.Context, err, "deleting Microsoft calendar event")
+	}
+	return nil
+}
+
+// StopEventChannel is a no-op, Microsoft calendar events are not watched.
+func (c *MicrosoftCalendar) StopEventChannel(*fleet.CalendarEvent) error {
+	return nil
+}
+
+func (c *MicrosoftCalendar) Get(event *fleet.CalendarEvent, key string) (interface{}, error) {
+	if key == "channelID" {
+		details, err := c.unmarshalDetails(event)
+		if err != nil {
+			return nil, err
+		}
+		return details.ChannelID, nil
+	}
+	return nil, ctxerr.Errorf(c.config.Context, "unknown key: %s", key)
+}
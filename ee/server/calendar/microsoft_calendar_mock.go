@@ -0,0 +1,120 @@
+package calendar
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/microsoft/msgraph"
+)
+
+// MicrosoftCalendarMockAPI is an in-memory implementation of MicrosoftCalendarAPI. It shares the lock and ID
+// sequence of GoogleCalendarMockAPI.
+type MicrosoftCalendarMockAPI struct {
+	logger *slog.Logger
+}
+
+var mockMicrosoftEvents = make(map[string]*MicrosoftEvent)
+
+// Configure does nothing, no credential is needed by the mock.
+func (lowLevelAPI *MicrosoftCalendarMockAPI) Configure(_ context.Context, _ *fleet.MicrosoftGraphCredential, userEmail string) error {
+	if lowLevelAPI.logger == nil {
+		lowLevelAPI.logger = slog.New(slog.NewTextHandler(os.Stderr, nil)).With("mock", "MicrosoftCalendarMockAPI", "user", userEmail)
+	}
+	return nil
+}
+
+func (lowLevelAPI *MicrosoftCalendarMockAPI) GetMailboxSettings() (*MicrosoftMailboxSettings, error) {
+	time.Sleep(latency)
+	lowLevelAPI.logger.InfoContext(context.TODO(), "GetMailboxSettings")
+	return &MicrosoftMailboxSettings{
+		TimeZone: "Central Standard Time",
+		WorkingHours: &MicrosoftWorkingHours{
+			DaysOfWeek: []string{"monday", "tuesday", "wednesday", "thursday", "friday"},
+			StartTime:  "09:00:00.0000000",
+			EndTime:    "17:00:00.0000000",
+		},
+	}, nil
+}
+
+func (lowLevelAPI *MicrosoftCalendarMockAPI) ListEvents(time.Time, time.Time) ([]*MicrosoftEvent, error) {
+	time.Sleep(latency)
+	lowLevelAPI.logger.InfoContext(context.TODO(), "ListEvents")
+	return nil, nil
+}
+
+func (lowLevelAPI *MicrosoftCalendarMockAPI) CreateEvent(event *MicrosoftEvent) (*MicrosoftEvent, error) {
+	time.Sleep(latency)
+	mu.Lock()
+	defer mu.Unlock()
+	id += 1
+	event.ID = strconv.FormatUint(id, 10)
+	event.ChangeKey = strconv.FormatUint(id, 10)
+	lowLevelAPI.logger.InfoContext(context.TODO(), "CreateEvent", "id", event.ID, "start", event.Start.DateTime)
+	mockMicrosoftEvents[event.ID] = event
+	return event, nil
+}
+
+func (lowLevelAPI *MicrosoftCalendarMockAPI) GetEvent(eventID string) (*MicrosoftEvent, error) {
+	time.Sleep(latency)
+	mu.Lock()
+	defer mu.Unlock()
+	event, ok := mockMicrosoftEvents[eventID]
+	if !ok {
+		return nil, &msgraph.Error{StatusCode: http.StatusNotFound, Code: "ErrorItemNotFound"}
+	}
+	lowLevelAPI.logger.InfoContext(context.TODO(), "GetEvent", "id", eventID, "start", event.Start.DateTime)
+	return event, nil
+}
+
+func (lowLevelAPI *MicrosoftCalendarMockAPI) UpdateEventBody(eventID string, body *MicrosoftItemBody) (*MicrosoftEvent, error) {
+	time.Sleep(latency)
+	mu.Lock()
+	defer mu.Unlock()
+	event, ok := mockMicrosoftEvents[eventID]
+	if !ok {
+		return nil, &msgraph.Error{StatusCode: http.StatusNotFound, Code: "ErrorItemNotFound"}
+	}
+	lowLevelAPI.logger.InfoContext(context.TODO(), "UpdateEventBody", "id", eventID)
+	id += 1
+	event.Body = body
+	event.ChangeKey = strconv.FormatUint(id, 10)
+	return event, nil
+}
+
+func (lowLevelAPI *MicrosoftCalendarMockAPI) DeleteEvent(eventID string) error {
+	time.Sleep(latency)
+	mu.Lock()
+	defer mu.Unlock()
+	lowLevelAPI.logger.InfoContext(context.TODO(), "DeleteEvent", "id", eventID)
+	delete(mockMicrosoftEvents, eventID)
+	return nil
+}
+
+func ListMicrosoftMockEvents() map[string]*MicrosoftEvent {
+	return mockMicrosoftEvents
+}
+
+func ClearMicrosoftMockEvents() {
+	mu.Lock()
+	defer mu.Unlock()
+	mockMicrosoftEvents = make(map[string]*MicrosoftEvent)
+}
+
+// SetMicrosoftMockEventsToNow moves all the mock events to start now, as if the users had moved them.
+func SetMicrosoftMockEventsToNow() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+	for _, mockEvent := range mockMicrosoftEvents {
+		mockEvent.Start = formatDateTime(now)
+		mockEvent.End = formatDateTime(now.Add(30 * time.Minute))
+		id += 1
+		mockEvent.ChangeKey = strconv.FormatUint(id, 10)
+	}
+}
@@ -0,0 +1,457 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/microsoft/msgraph"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baseTenantID = "11111111-2222-3333-4444-555555555555"
+
+type MockMicrosoftCalendarLowLevelAPI struct {
+	ConfigureFunc          func(ctx context.Context, cred *fleet.MicrosoftGraphCredential, userEmail string) error
+	GetMailboxSettingsFunc func() (*MicrosoftMailboxSettings, error)
+	ListEventsFunc         func(start, end time.Time) ([]*MicrosoftEvent, error)
+	CreateEventFunc        func(event *MicrosoftEvent) (*MicrosoftEvent, error)
+	GetEventFunc           func(id string) (*MicrosoftEvent, error)
+	UpdateEventBodyFunc    func(id string, body *MicrosoftItemBody) (*MicrosoftEvent, error)
+	DeleteEventFunc        func(id string) error
+}
+
+func (m *MockMicrosoftCalendarLowLevelAPI) Configure(ctx context.Context, cred *fleet.MicrosoftGraphCredential, userEmail string) error {
+	return m.ConfigureFunc(ctx, cred, userEmail)
+}
+
+func (m *MockMicrosoftCalendarLowLevelAPI) GetMailboxSettings() (*MicrosoftMailboxSettings, error) {
+	return m.GetMailboxSettingsFunc()
+}
+
+func (m *MockMicrosoftCalendarLowLevelAPI) ListEvents(start, end time.Time) ([]*MicrosoftEvent, error) {
+	return m.ListEventsFunc(start, end)
+}
+
+func (m *MockMicrosoftCalendarLowLevelAPI) CreateEvent(event *MicrosoftEvent) (*MicrosoftEvent, error) {
+	return m.CreateEventFunc(event)
+}
+
+func (m *MockMicrosoftCalendarLowLevelAPI) GetEvent(id string) (*MicrosoftEvent, error) {
+	return m.GetEventFunc(id)
+}
+
+func (m *MockMicrosoftCalendarLowLevelAPI) UpdateEventBody(id string, body *MicrosoftItemBody) (*MicrosoftEvent, error) {
+	return m.UpdateEventBodyFunc(id, body)
+}
+
+func (m *MockMicrosoftCalendarLowLevelAPI) DeleteEvent(id string) error {
+	return m.DeleteEventFunc(id)
+}
+
+func makeMicrosoftConfig(mockAPI *MockMicrosoftCalendarLowLevelAPI) *MicrosoftCalendarConfig {
+	if mockAPI.ConfigureFunc == nil {
+		mockAPI.ConfigureFunc = func(ctx context.Context, cred *fleet.MicrosoftGraphCredential, userEmail string) error {
+			return nil
+		}
+	}
+	if mockAPI.GetMailboxSettingsFunc == nil {
+		mockAPI.GetMailboxSettingsFunc = func() (*MicrosoftMailboxSettings, error) {
+			return &MicrosoftMailboxSettings{
+				TimeZone: "Pacific Standard Time",
+				WorkingHours: &MicrosoftWorkingHours{
+					DaysOfWeek: []string{"monday", "tuesday", "wednesday", "thursday"},
+					StartTime:  "08:00:00.0000000",
+					EndTime:    "12:30:00.0000000",
+				},
+			}, nil
+		}
+	}
+	return &MicrosoftCalendarConfig{
+		Context:           context.Background(),
+		IntegrationConfig: &fleet.MicrosoftCalendarIntegration{Domain: "example.com", TenantID: baseTenantID},
+		Credential:        &fleet.MicrosoftGraphCredential{TenantID: baseTenantID, ClientID: baseTenantID, ClientSecret: "secret"},
+		Logger:            logger,
+		ServerURL:         baseServerURL,
+		API:               mockAPI,
+	}
+}
+
+func graphDateTime(t time.Time) *MicrosoftDateTime {
+	return &MicrosoftDateTime{DateTime: t.UTC().Format("2006-01-02T15:04:05.0000000"), TimeZone: "UTC"}
+}
+
+func TestMicrosoftCalendar_Configure(t *testing.T) {
+	t.Parallel()
+	mockAPI := &MockMicrosoftCalendarLowLevelAPI{}
+	config := makeMicrosoftConfig(mockAPI)
+	mockAPI.ConfigureFunc = func(ctx context.Context, cred *fleet.MicrosoftGraphCredential, userEmail string) error {
+		assert.Equal(t, config.Credential, cred)
+		assert.Equal(t, baseUserEmail, userEmail)
+		return nil
+	}
+
+	var cal fleet.UserCalendar = NewMicrosoftCalendar(config)
+	require.NoError(t, cal.Configure(baseUserEmail))
+
+	mockAPI.ConfigureFunc = func(ctx context.Context, cred *fleet.MicrosoftGraphCredential, userEmail string) error {
+		return assert.AnError
+	}
+	require.ErrorIs(t, cal.Configure(baseUserEmail), assert.AnError)
+}
+
+func TestMicrosoftCalendar_CreateEvent(t *testing.T) {
+	t.Parallel()
+	la, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+	// Events are scheduled on Tuesday, the day after now.
+	monday := time.Date(2026, 10, 19, 0, 0, 0, 0, la)
+	tuesday := monday.AddDate(0, 0, 1)
+	now := func() time.Time { return monday.Add(-time.Hour) }
+	genBodyFn := func(conflict bool) (string, bool, error) {
+		if conflict {
+			return "conflict\nbody", true, nil
+		}
+		return "line1\nline2", true, nil
+	}
+
+	mockAPI := &MockMicrosoftCalendarLowLevelAPI{}
+	var listed []*MicrosoftEvent
+	mockAPI.ListEventsFunc = func(start, end time.Time) ([]*MicrosoftEvent, error) {
+		assert.False(t, start.Before(tuesday.Add(8*time.Hour)), start)
+		assert.True(t, end.Equal(tuesday.Add(12*time.Hour+30*time.Minute)), end)
+		return listed, nil
+	}
+	var created *MicrosoftEvent
+	mockAPI.CreateEventFunc = func(event *MicrosoftEvent) (*MicrosoftEvent, error) {
+		created = event
+		return &MicrosoftEvent{ID: "event-id", ChangeKey: "ck1", Start: event.Start, End: event.End}, nil
+	}
+	cal := NewMicrosoftCalendar(makeMicrosoftConfig(mockAPI))
+	require.NoError(t, cal.Configure(baseUserEmail))
+
+	t.Run("first slot of the working hours", func(t *testing.T) {
+		event, err := cal.createEvent(tuesday, genBodyFn, now, fleet.CalendarCreateEventOpts{})
+		require.NoError(t, err)
+		assert.True(t, event.StartTime.Equal(tuesday.Add(8*time.Hour)), event.StartTime)
+		assert.True(t, event.EndTime.Equal(tuesday.Add(8*time.Hour+eventLength)), event.EndTime)
+		assert.Equal(t, baseUserEmail, event.Email)
+		assert.Equal(t, "America/Los_Angeles", *event.TimeZone)
+		assert.Equal(t, strings.ToUpper(event.UUID), event.UUID)
+		assert.JSONEq(t, `{"id":"event-id","etag":"ck1","channel_id":"","resource_id":"","body_tag":"body_tag"}`, string(event.Data))
+
+		assert.Equal(t, eventTitle, created.Subject)
+		assert.Equal(t, "busy", created.ShowAs)
+		assert.Equal(t, &MicrosoftItemBody{ContentType: "html", Content: "line1<br>\nline2"}, created.Body)
+		assert.Equal(t, &MicrosoftDateTime{DateTime: "2026-10-20T15:00:00", TimeZone: "UTC"}, created.Start)
+	})
+
+	t.Run("conflicting events", func(t *testing.T) {
+		listed = []*MicrosoftEvent{
+			// ignored: free, declined, cancelled, all day
+			{ShowAs: "free", Start: graphDateTime(tuesday.Add(8 * time.Hour)), End: graphDateTime(tuesday.Add(12 * time.Hour))},
+			{
+				ShowAs: "busy", ResponseStatus: &MicrosoftResponseStatus{Response: "declined"},
+				Start: graphDateTime(tuesday.Add(8 * time.Hour)), End: graphDateTime(tuesday.Add(12 * time.Hour)),
+			},
+			{IsCancelled: true, Start: graphDateTime(tuesday.Add(8 * time.Hour)), End: graphDateTime(tuesday.Add(12 * time.Hour))},
+			{IsAllDay: true, Start: graphDateTime(tuesday), End: graphDateTime(tuesday.AddDate(0, 0, 1))},
+			// busy until 9:10
+			{ShowAs: "busy", Start: graphDateTime(tuesday.Add(8 * time.Hour)), End: graphDateTime(tuesday.Add(9*time.Hour + 10*time.Minute))},
+		}
+		event, err := cal.createEvent(tuesday, genBodyFn, now, fleet.CalendarCreateEventOpts{EventUUID: "UUID"})
+		require.NoError(t, err)
+		assert.True(t, event.StartTime.Equal(tuesday.Add(9*time.Hour+30*time.Minute)), event.StartTime)
+		assert.Equal(t, "UUID", event.UUID)
+		assert.Equal(t, "line1<br>\nline2", created.Body.Content)
+
+		// busy all day: the last slot is used, with the conflict text
+		listed = []*MicrosoftEvent{
+			{ShowAs: "oof", Start: graphDateTime(tuesday.Add(7 * time.Hour)), End: graphDateTime(tuesday.Add(13 * time.Hour))},
+		}
+		event, err = cal.createEvent(tuesday, genBodyFn, now, fleet.CalendarCreateEventOpts{})
+		require.NoError(t, err)
+		assert.True(t, event.StartTime.Equal(tuesday.Add(12*time.Hour)), event.StartTime)
+		assert.Equal(t, "conflict<br>\nbody", created.Body.Content)
+		listed = nil
+	})
+
+	t.Run("non-working day", func(t *testing.T) {
+		_, err := cal.createEvent(tuesday.AddDate(0, 0, 3), genBodyFn, now, fleet.CalendarCreateEventOpts{})
+		var dayEnded fleet.DayEndedError
+		require.ErrorAs(t, err, &dayEnded)
+	})
+
+	t.Run("working hours ended", func(t *testing.T) {
+		_, err := cal.createEvent(tuesday, genBodyFn, func() time.Time { return tuesday.Add(13 * time.Hour) }, fleet.CalendarCreateEventOpts{})
+		var dayEnded fleet.DayEndedError
+		require.ErrorAs(t, err, &dayEnded)
+	})
+
+	t.Run("working hours started", func(t *testing.T) {
+		event, err := cal.createEvent(tuesday, genBodyFn, func() time.Time { return tuesday.Add(10*time.Hour + 5*time.Minute) },
+			fleet.CalendarCreateEventOpts{})
+		require.NoError(t, err)
+		assert.True(t, event.StartTime.Equal(tuesday.Add(10*time.Hour+30*time.Minute)), event.StartTime)
+	})
+
+	t.Run("body not needed", func(t *testing.T) {
+		event, err := cal.createEvent(tuesday, func(bool) (string, bool, error) { return "", false, nil }, now,
+			fleet.CalendarCreateEventOpts{})
+		require.NoError(t, err)
+		assert.Nil(t, event)
+	})
+}
+
+func TestMicrosoftCalendar_parseWorkingHours(t *testing.T) {
+	t.Parallel()
+	cal := NewMicrosoftCalendar(makeMicrosoftConfig(&MockMicrosoftCalendarLowLevelAPI{}))
+	weekdays := map[time.Weekday]bool{time.Monday: true, time.Tuesday: true, time.Wednesday: true, time.Thursday: true, time.Friday: true}
+
+	wh := cal.parseWorkingHours(nil)
+	assert.Equal(t, &workingHours{start: startHour * time.Hour, end: endHour * time.Hour, days: weekdays}, wh)
+
+	wh = cal.parseWorkingHours(&MicrosoftWorkingHours{
+		DaysOfWeek: []string{"Sunday", "Wednesday"},
+		StartTime:  "07:30:00.0000000",
+		EndTime:    "15:00:00.0000000",
+	})
+	assert.Equal(t, &workingHours{
+		start: 7*time.Hour + 30*time.Minute,
+		end:   15 * time.Hour,
+		days:  map[time.Weekday]bool{time.Sunday: true, time.Wednesday: true},
+	}, wh)
+
+	// weekend only and inverted hours fall back to the defaults
+	wh = cal.parseWorkingHours(&MicrosoftWorkingHours{
+		DaysOfWeek: []string{"saturday", "sunday"},
+		StartTime:  "17:00:00.0000000",
+		EndTime:    "09:00:00.0000000",
+	})
+	assert.Equal(t, &workingHours{start: startHour * time.Hour, end: endHour * time.Hour, days: weekdays}, wh)
+}
+
+func TestMicrosoftCalendar_getLocation(t *testing.T) {
+	t.Parallel()
+	cal := NewMicrosoftCalendar(makeMicrosoftConfig(&MockMicrosoftCalendarLowLevelAPI{}))
+	assert.Equal(t, "America/Chicago", cal.getLocation("Central Standard Time").String())
+	assert.Equal(t, "Europe/Paris", cal.getLocation("Europe/Paris").String())
+	assert.Equal(t, "America/New_York", cal.getLocation("Customized Time Zone").String())
+	assert.Equal(t, "America/New_York", cal.getLocation("").String())
+}
+
+func TestMicrosoftCalendar_GetAndUpdateEvent(t *testing.T) {
+	t.Parallel()
+	mockAPI := &MockMicrosoftCalendarLowLevelAPI{}
+	cal := NewMicrosoftCalendar(makeMicrosoftConfig(mockAPI))
+	require.NoError(t, cal.Configure(baseUserEmail))
+	genBodyFn := func(bool) (string, bool, error) { return "body", true, nil }
+
+	start := time.Now().Add(time.Hour).Truncate(time.Minute).UTC()
+	tz := "America/Los_Angeles"
+	event := &fleet.CalendarEvent{
+		UUID:      "UUID",
+		Email:     baseUserEmail,
+		StartTime: start,
+		EndTime:   start.Add(eventLength),
+		TimeZone:  &tz,
+		Data:      []byte(`{"id":"event-id","etag":"ck1","body_tag":"tag"}`),
+	}
+
+	// not modified
+	mockAPI.GetEventFunc = func(id string) (*MicrosoftEvent, error) {
+		assert.Equal(t, "event-id", id)
+		return &MicrosoftEvent{ID: id, ChangeKey: "ck1"}, nil
+	}
+	got, updated, err := cal.GetAndUpdateEvent(event, genBodyFn, fleet.CalendarGetAndUpdateEventOpts{})
+	require.NoError(t, err)
+	assert.False(t, updated)
+	assert.Equal(t, event, got)
+
+	// moved by the user
+	mockAPI.GetEventFunc = func(id string) (*MicrosoftEvent, error) {
+		return &MicrosoftEvent{
+			ID: id, ChangeKey: "ck2", Start: graphDateTime(start.Add(time.Hour)), End: graphDateTime(start.Add(2 * time.Hour)),
+		}, nil
+	}
+	got, updated, err = cal.GetAndUpdateEvent(event, genBodyFn, fleet.CalendarGetAndUpdateEventOpts{})
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.True(t, got.StartTime.Equal(start.Add(time.Hour)))
+	assert.True(t, got.EndTime.Equal(start.Add(2*time.Hour)))
+	assert.Equal(t, "UUID", got.UUID)
+	assert.JSONEq(t, `{"id":"event-id","etag":"ck2","channel_id":"","resource_id":"","body_tag":"tag"}`, string(got.Data))
+
+	// deleted by the user: re-created on the next business day with the same UUID
+	mockAPI.GetEventFunc = func(id string) (*MicrosoftEvent, error) {
+		return nil, &msgraph.Error{StatusCode: http.StatusNotFound}
+	}
+	mockAPI.ListEventsFunc = func(start, end time.Time) ([]*MicrosoftEvent, error) { return nil, nil }
+	var createdStart time.Time
+	mockAPI.CreateEventFunc = func(e *MicrosoftEvent) (*MicrosoftEvent, error) {
+		createdStart, err = time.ParseInLocation("2006-01-02T15:04:05", e.Start.DateTime, time.UTC)
+		require.NoError(t, err)
+		return &MicrosoftEvent{ID: "new-id", ChangeKey: "ck3"}, nil
+	}
+	// any working day is fine for that test
+	mockAPI.GetMailboxSettingsFunc = func() (*MicrosoftMailboxSettings, error) {
+		return &MicrosoftMailboxSettings{TimeZone: "UTC"}, nil
+	}
+	got, updated, err = cal.GetAndUpdateEvent(event, genBodyFn, fleet.CalendarGetAndUpdateEventOpts{})
+	require.NoError(t, err)
+	assert.True(t, updated)
+	assert.Equal(t, "UUID", got.UUID)
+	assert.True(t, got.StartTime.Equal(createdStart))
+	assert.True(t, createdStart.After(start))
+	assert.JSONEq(t, `{"id":"new-id","etag":"ck3","channel_id":"","resource_id":"","body_tag":"body_tag"}`, string(got.Data))
+
+	// other errors are returned
+	mockAPI.GetEventFunc = func(id string) (*MicrosoftEvent, error) {
+		return nil, &msgraph.Error{StatusCode: http.StatusForbidden}
+	}
+	_, _, err = cal.GetAndUpdateEvent(event, genBodyFn, fleet.CalendarGetAndUpdateEventOpts{})
+	require.Error(t, err)
+	isRemote, status, _ := RemoteError(err)
+	assert.True(t, isRemote)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestMicrosoftCalendar_UpdateEventBodyAndDelete(t *testing.T) {
+	t.Parallel()
+	mockAPI := &MockMicrosoftCalendarLowLevelAPI{}
+	cal := NewMicrosoftCalendar(makeMicrosoftConfig(mockAPI))
+	require.NoError(t, cal.Configure(baseUserEmail))
+	event := &fleet.CalendarEvent{Data: []byte(`{"id":"event-id","etag":"ck1"}`)}
+
+	mockAPI.GetEventFunc = func(id string) (*MicrosoftEvent, error) {
+		return &MicrosoftEvent{ID: id, Body: &MicrosoftItemBody{Content: "<html>" + fleet.CalendarEventConflictText + "</html>"}}, nil
+	}
+	mockAPI.UpdateEventBodyFunc = func(id string, body *MicrosoftItemBody) (*MicrosoftEvent, error) {
+		assert.Equal(t, "event-id", id)
+		assert.Equal(t, "new<br>\nbody", body.Content)
+		return &MicrosoftEvent{ID: id, ChangeKey: "ck2"}, nil
+	}
+	etag, err := cal.UpdateEventBody(event, func(conflict bool) (string, bool, error) {
+		assert.True(t, conflict)
+		return "new\nbody", true, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ck2", etag)
+
+	for _, status := range []int{http.StatusNotFound, http.StatusForbidden} {
+		mockAPI.DeleteEventFunc = func(id string) error { return &msgraph.Error{StatusCode: status} }
+		require.NoError(t, cal.DeleteEvent(event))
+	}
+	mockAPI.DeleteEventFunc = func(id string) error { return &msgraph.Error{StatusCode: http.StatusBadRequest} }
+	require.Error(t, cal.DeleteEvent(event))
+
+	require.NoError(t, cal.StopEventChannel(event))
+	channelID, err := cal.Get(event, "channelID")
+	require.NoError(t, err)
+	assert.Empty(t, channelID)
+}
+
+func TestMicrosoftCalendarLowLevelAPI(t *testing.T) {
+	t.Parallel()
+	var tokenRequests, throttled atomic.Int32
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/"+baseTenantID+"/oauth2/v2.0/token" {
+			tokenRequests.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"access_token":"test-token","token_type":"Bearer","expires_in":3599}`))
+			return
+		}
+		assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+		assert.Equal(t, preferUTC, r.Header.Get("Prefer"))
+
+		userPath := "/v1.0/users/" + baseUserEmail
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == userPath+"/mailboxSettings":
+			if throttled.Add(1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			_, _ = w.Write([]byte(`{"timeZone":"Pacific Standard Time","workingHours":{"daysOfWeek":["monday"],"startTime":"08:00:00.0000000","endTime":"17:00:00.0000000","timeZone":{"name":"Pacific Standard Time"}}}`))
+		case r.Method == http.MethodGet && r.URL.Path == userPath+"/calendarView":
+			if r.URL.Query().Get("page") == "2" {
+				_, _ = w.Write([]byte(`{"value":[{"id":"2"}]}`))
+				return
+			}
+			assert.Equal(t, "2026-10-20T15:00:00Z", r.URL.Query().Get("startDateTime"))
+			assert.Equal(t, "start/dateTime", r.URL.Query().Get("$orderby"))
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"value":           []map[string]any{{"id": "1"}},
+				"@odata.nextLink": srv.URL + userPath + "/calendarView?page=2",
+			})
+		case r.Method == http.MethodPost && r.URL.Path == userPath+"/events":
+			var event MicrosoftEvent
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+			event.ID = "created"
+			event.ChangeKey = "ck"
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(event)
+		case r.Method == http.MethodGet && r.URL.Path == userPath+"/events/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"ErrorItemNotFound","message":"The specified object was not found in the store."}}`))
+		case r.Method == http.MethodPatch && r.URL.Path == userPath+"/events/created":
+			var event MicrosoftEvent
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+			assert.Nil(t, event.Start)
+			_, _ = w.Write([]byte(`{"id":"created","changeKey":"ck2"}`))
+		case r.Method == http.MethodDelete && r.URL.Path == userPath+"/events/created":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	api := &MicrosoftCalendarLowLevelAPI{logger: logger, loginHost: srv.URL, graphHost: srv.URL}
+	cred := &fleet.MicrosoftGraphCredential{TenantID: baseTenantID, ClientID: baseTenantID, ClientSecret: "secret"}
+	require.Error(t, api.Configure(t.Context(), &fleet.MicrosoftGraphCredential{TenantID: baseTenantID}, baseUserEmail))
+	require.NoError(t, api.Configure(t.Context(), cred, baseUserEmail))
+
+	settings, err := api.GetMailboxSettings()
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), throttled.Load())
+	assert.Equal(t, "Pacific Standard Time", settings.WorkingHours.TimeZone.Name)
+	assert.Equal(t, []string{"monday"}, settings.WorkingHours.DaysOfWeek)
+
+	start := time.Date(2026, 10, 20, 15, 0, 0, 0, time.UTC)
+	events, err := api.ListEvents(start, start.Add(8*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "2", events[1].ID)
+
+	created, err := api.CreateEvent(&MicrosoftEvent{Subject: eventTitle, Start: formatDateTime(start), End: formatDateTime(start.Add(eventLength))})
+	require.NoError(t, err)
+	assert.Equal(t, "created", created.ID)
+	assert.Equal(t, eventTitle, created.Subject)
+
+	_, err = api.GetEvent("missing")
+	require.True(t, isMicrosoftNotFound(err))
+	graphErr, ok := msgraph.AsError(err)
+	require.True(t, ok)
+	assert.Equal(t, "ErrorItemNotFound", graphErr.Code)
+
+	updated, err := api.UpdateEventBody("created", eventBody("body"))
+	require.NoError(t, err)
+	assert.Equal(t, "ck2", updated.ChangeKey)
+
+	require.NoError(t, api.DeleteEvent("created"))
+
+	// the token is reused across users as long as the credential does not change
+	require.NoError(t, api.Configure(t.Context(), cred, "other@example.com"))
+	err = api.DeleteEvent("created")
+	require.True(t, isMicrosoftNotFound(err)) // no such user on the stand-in
+	assert.Equal(t, int32(1), tokenRequests.Load())
+}
@@ -0,0 +1,307 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// fleetUserProvisioner creates, updates and disables the Fleet users of SCIM
+// users from the SCIM group to Fleet role mappings, when SCIM user
+// provisioning is enabled in the app config
+// (sso_settings.scim_user_provisioning).
+//
+// Only SSO users are managed: API-only and password users matching a SCIM
+// user are left untouched.
+type fleetUserProvisioner struct {
+	ds          fleet.Datastore
+	newActivity fleet.NewActivityFunc
+	authConfig  config.AuthConfig
+	logger      *slog.Logger
+}
+
+func newFleetUserProvisioner(ds fleet.Datastore, newActivity fleet.NewActivityFunc, authConfig config.AuthConfig, logger *slog.Logger) *fleetUserProvisioner {
+	return &fleetUserProvisioner{ds: ds, newActivity: newActivity, authConfig: authConfig, logger: logger}
+}
+
+// settings returns the SCIM user provisioning settings, or nil if
+// provisioning is disabled.
+func (p *fleetUserProvisioner) settings(ctx context.Context) (*fleet.SCIMUserProvisioningSettings, error) {
+	appConfig, err := p.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	if appConfig.SSOSettings == nil || appConfig.SSOSettings.SCIMUserProvisioning == nil ||
+		!appConfig.SSOSettings.SCIMUserProvisioning.Enable {
+		return nil, nil
+	}
+	return appConfig.SSOSettings.SCIMUserProvisioning, nil
+}
+
+// syncFleetUsers syncs the Fleet users of the given SCIM users. Failures are
+// logged and don't stop the sync of the other users, since the SCIM request
+// itself succeeded.
+func (p *fleetUserProvisioner) syncFleetUsers(ctx context.Context, settings *fleet.SCIMUserProvisioningSettings, scimUserIDs []uint) {
+	for _, id := range server.RemoveDuplicatesFromSlice(scimUserIDs) {
+		if err := p.syncFleetUser(ctx, settings, id, nil); err != nil {
+			p.logger.ErrorContext(ctx, "failed to sync fleet user of scim user", "scim_user_id", id, "err", err)
+		}
+	}
+}
+
+// syncFleetUser creates, updates or disables the Fleet user of the SCIM user
+// with the given ID, from its current state and groups. previous is the
+// persisted state of the SCIM user before the request, it is used first to
+// find the Fleet user so that a request can't evade deprovisioning by
+// changing the SCIM user's userName and emails. It is nil if the request
+// didn't change the SCIM user.
+func (p *fleetUserProvisioner) syncFleetUser(ctx context.Context, settings *fleet.SCIMUserProvisioningSettings, scimUserID uint, previous *fleet.ScimUser) error {
+	scimUser, err := p.ds.ScimUserByID(ctx, scimUserID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get scim user")
+	}
+
+	var fleetUser *fleet.User
+	if previous != nil {
+		if fleetUser, err = matchingFleetUser(ctx, p.ds, previous); err != nil {
+			return err
+		}
+	}
+	if fleetUser == nil {
+		if fleetUser, err = matchingFleetUser(ctx, p.ds, scimUser); err != nil {
+			return err
+		}
+	}
+	if fleetUser != nil && (fleetUser.APIOnly || !fleetUser.SSOEnabled) {
+		p.logger.InfoContext(ctx, "skipping provisioning of API-only or non-SSO user",
+			"user_id", fleetUser.ID, "email", fleetUser.Email)
+		return nil
+	}
+
+	var (
+		globalRole *string
+		teams      []fleet.UserTeam
+	)
+	if scimUser.Active == nil || *scimUser.Active {
+		groups := make([]string, 0, len(scimUser.Groups))
+		for _, g := range scimUser.Groups {
+			groups = append(groups, g.DisplayName)
+		}
+		var teamRoles map[string]string
+		globalRole, teamRoles = settings.RolesForGroups(groups)
+		if teams, err = p.userTeams(ctx, teamRoles); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case globalRole == nil && len(teams) == 0:
+		if fleetUser == nil || fleetUser.Disabled {
+			return nil
+		}
+		return p.disableFleetUser(ctx, fleetUser)
+	case fleetUser == nil:
+		return p.createFleetUser(ctx, scimUser, globalRole, teams)
+	default:
+		return p.updateFleetUser(ctx, fleetUser, globalRole, teams)
+	}
+}
+
+// deprovisionFleetUser disables the Fleet user of a deleted SCIM user.
+func (p *fleetUserProvisioner) deprovisionFleetUser(ctx context.Context, scimUser *fleet.ScimUser) error {
+	fleetUser, err := matchingFleetUser(ctx, p.ds, scimUser)
+	if err != nil {
+		return err
+	}
+	if fleetUser == nil || fleetUser.Disabled {
+		return nil
+	}
+	if fleetUser.APIOnly || !fleetUser.SSOEnabled {
+		p.logger.InfoContext(ctx, "skipping deprovisioning of API-only or non-SSO user",
+			"user_id", fleetUser.ID, "email", fleetUser.Email)
+		return nil
+	}
+	return p.disableFleetUser(ctx, fleetUser)
+}
+
+// userTeams resolves the fleet names of the role mappings. Mappings to
+// fleets that don't exist are ignored.
+func (p *fleetUserProvisioner) userTeams(ctx context.Context, teamRoles map[string]string) ([]fleet.UserTeam, error) {
+	teams := make([]fleet.UserTeam, 0, len(teamRoles))
+	for name, role := range teamRoles {
+		team, err := p.ds.TeamByName(ctx, name)
+		if fleet.IsNotFound(err) {
+			p.logger.WarnContext(ctx, "ignoring scim role mapping to unknown fleet", "fleet", name)
+			continue
+		}
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get team of scim role mapping")
+		}
+		teams = append(teams, fleet.UserTeam{Team: fleet.Team{ID: team.ID, Name: team.Name}, Role: role})
+	}
+	return teams, nil
+}
+
+func (p *fleetUserProvisioner) createFleetUser(ctx context.Context, scimUser *fleet.ScimUser, globalRole *string, teams []fleet.UserTeam) error {
+	email := scimUserEmail(scimUser)
+	if err := fleet.ValidateEmail(email); err != nil {
+		p.logger.InfoContext(ctx, "skipping provisioning of scim user without a valid email",
+			"scim_user_id", scimUser.ID, "user_name", scimUser.UserName)
+		return nil
+	}
+	name := scimUser.DisplayName()
+	if name == "" {
+		name = scimUser.UserName
+	}
+
+	user := &fleet.User{
+		Name:       name,
+		Email:      email,
+		SSOEnabled: true,
+		GlobalRole: globalRole,
+		Teams:      teams,
+	}
+	// SSO users require a stand-in password to satisfy the NOT NULL constraint.
+	if err := user.SetFakePassword(p.authConfig.SaltKeySize, p.authConfig.BcryptCost); err != nil {
+		return ctxerr.Wrap(ctx, err, "set password of provisioned user")
+	}
+	user, err := p.ds.NewUser(ctx, user)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "create provisioned user")
+	}
+
+	p.logger.InfoContext(ctx, "created fleet user from scim user", "user_id", user.ID, "email", user.Email)
+	p.provisionedActivity(ctx, user, true)
+	return nil
+}
+
+func (p *fleetUserProvisioner) updateFleetUser(ctx context.Context, user *fleet.User, globalRole *string, teams []fleet.UserTeam) error {
+	if !user.Disabled && !rolesChanged(user.GlobalRole, user.Teams, globalRole, teams) {
+		return nil
+	}
+
+	wasAdmin := user.GlobalRole != nil && *user.GlobalRole == fleet.RoleAdmin
+	user.Disabled = false
+	user.GlobalRole = globalRole
+	user.Teams = teams
+	if wasAdmin && (globalRole == nil || *globalRole != fleet.RoleAdmin) {
+		if err := p.ds.SaveUserIfNotLastAdmin(ctx, user); err != nil {
+			if errors.Is(err, fleet.ErrLastGlobalAdmin) {
+				p.logger.WarnContext(ctx, "cannot remove the admin role of the last global admin via SCIM",
+					"user_id", user.ID, "email", user.Email)
+			}
+			return ctxerr.Wrap(ctx, err, "save provisioned admin user")
+		}
+	} else if err := p.ds.SaveUser(ctx, user); err != nil {
+		return ctxerr.Wrap(ctx, err, "save provisioned user")
+	}
+
+	p.logger.InfoContext(ctx, "updated fleet user from scim user", "user_id", user.ID, "email", user.Email)
+	p.provisionedActivity(ctx, user, false)
+	return nil
+}
+
+func (p *fleetUserProvisioner) disableFleetUser(ctx context.Context, user *fleet.User) error {
+	if err := p.ds.DisableUser(ctx, user.ID); err != nil {
+		if errors.Is(err, fleet.ErrLastGlobalAdmin) {
+			p.logger.WarnContext(ctx, "cannot disable the last global admin via SCIM",
+				"user_id", user.ID, "email", user.Email)
+		}
+		return ctxerr.Wrap(ctx, err, "disable deprovisioned user")
+	}
+
+	p.logger.InfoContext(ctx, "disabled fleet user via SCIM", "user_id", user.ID, "email", user.Email)
+	if err := p.newActivity(ctx, nil, fleet.ActivityTypeDeprovisionedUser{
+		UserID:    user.ID,
+		UserName:  user.Name,
+		UserEmail: user.Email,
+	}); err != nil {
+		p.logger.ErrorContext(ctx, "failed to create activity for fleet user deprovisioning", "err", err)
+	}
+	return nil
+}
+
+func (p *fleetUserProvisioner) provisionedActivity(ctx context.Context, user *fleet.User, created bool) {
+	teams := make([]fleet.ActivityUserTeamRole, 0, len(user.Teams))
+	for _, t := range user.Teams {
+		teams = append(teams, fleet.ActivityUserTeamRole{TeamID: t.ID, TeamName: t.Name, Role: t.Role})
+	}
+	if err := p.newActivity(ctx, nil, fleet.ActivityTypeProvisionedUser{
+		UserID:     user.ID,
+		UserName:   user.Name,
+		UserEmail:  user.Email,
+		Created:    created,
+		GlobalRole: user.GlobalRole,
+		Teams:      teams,
+	}); err != nil {
+		p.logger.ErrorContext(ctx, "failed to create activity for fleet user provisioning", "err", err)
+	}
+}
+
+// rolesChanged returns whether the roles differ. It assumes there is at most
+// one role per team in each set.
+func rolesChanged(oldGlobal *string, oldTeams []fleet.UserTeam, newGlobal *string, newTeams []fleet.UserTeam) bool {
+	if (oldGlobal == nil) != (newGlobal == nil) || (oldGlobal != nil && *oldGlobal != *newGlobal) {
+		return true
+	}
+	if len(oldTeams) != len(newTeams) {
+		return true
+	}
+	oldRoles := make(map[uint]string, len(oldTeams))
+	for _, t := range oldTeams {
+		oldRoles[t.ID] = t.Role
+	}
+	for _, t := range newTeams {
+		if role, ok := oldRoles[t.ID]; !ok || role != t.Role {
+			return true
+		}
+	}
+	return false
+}
+
+// scimUserEmail returns the email of the Fleet user provisioned for the SCIM
+// user: its primary email, else its first email, else its userName.
+func scimUserEmail(scimUser *fleet.ScimUser) string {
+	for _, e := range scimUser.Emails {
+		if e.Primary != nil && *e.Primary {
+			return e.Email
+		}
+	}
+	if len(scimUser.Emails) > 0 {
+		return scimUser.Emails[0].Email
+	}
+	return scimUser.UserName
+}
+
+// matchingFleetUser returns the Fleet user whose email is the userName or one
+// of the emails of the SCIM user, or nil if there is none.
+func matchingFleetUser(ctx context.Context, ds fleet.Datastore, scimUser *fleet.ScimUser) (*fleet.User, error) {
+	// Collect unique emails from SCIM user (userName is often the email in many IdP configurations, e.g. Okta).
+	// userName is added first so it's checked first when looking up Fleet users.
+	emails := make([]string, 0, len(scimUser.Emails)+1)
+
+	if strings.Contains(scimUser.UserName, "@") {
+		emails = append(emails, strings.ToLower(scimUser.UserName))
+	}
+
+	for _, e := range scimUser.Emails {
+		emails = append(emails, strings.ToLower(e.Email))
+	}
+
+	for _, email := range server.RemoveDuplicatesFromSlice(emails) {
+		user, err := ds.UserByEmail(ctx, email)
+		if err == nil {
+			return user, nil
+		}
+		if !fleet.IsNotFound(err) {
+			return nil, ctxerr.Wrap(ctx, err, "lookup fleet user by email")
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,378 @@
+package scim
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/elimity-com/scim"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	platform_mysql "github.com/fleetdm/fleet/v4/server/platform/mysql"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/scim2/filter-parser/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testProvisioningSettings() *fleet.SCIMUserProvisioningSettings {
+	return &fleet.SCIMUserProvisioningSettings{
+		Enable: true,
+		RoleMappings: []fleet.SCIMRoleMapping{
+			{Group: "fleet-admins", Role: fleet.RoleAdmin},
+			{Group: "ws-it", Role: fleet.RoleMaintainer, Team: "Workstations"},
+			{Group: "ws-helpdesk", Role: fleet.RoleObserver, Team: "Workstations"},
+			{Group: "unknown-team", Role: fleet.RoleObserver, Team: "Unknown"},
+		},
+	}
+}
+
+// enableProvisioning sets up the mocks for SCIM user provisioning with the
+// test settings and a "Workstations" fleet.
+func (m *testMocks) enableProvisioning() {
+	m.ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{SSOSettings: &fleet.SSOSettings{SCIMUserProvisioning: testProvisioningSettings()}}, nil
+	}
+	m.ds.TeamByNameFunc = func(ctx context.Context, name string) (*fleet.Team, error) {
+		if name == "Workstations" {
+			return &fleet.Team{ID: 7, Name: "Workstations"}, nil
+		}
+		return nil, platform_mysql.NotFound("Team")
+	}
+}
+
+func scimUserInGroups(opts *scimUserOpts, groups ...string) *fleet.ScimUser {
+	user := newTestScimUser(opts)
+	for i, g := range groups {
+		user.Groups = append(user.Groups, fleet.ScimUserGroup{ID: uint(i + 1), DisplayName: g}) //nolint:gosec // dismiss G115
+	}
+	return user
+}
+
+func TestSyncFleetUser(t *testing.T) {
+	ctx := t.Context()
+
+	t.Run("creates an SSO user with the mapped roles", func(t *testing.T) {
+		mocks := newTestMocks()
+		mocks.enableProvisioning()
+		scimUser := scimUserInGroups(&scimUserOpts{
+			userName:   "jdoe",
+			givenName:  "John",
+			familyName: "Doe",
+			emails: []fleet.ScimUserEmail{
+				{Email: "john@personal.example.com"},
+				{Email: "john.doe@example.com", Primary: ptr.Bool(true)},
+			},
+		}, "ws-helpdesk", "ws-it", "unknown-team")
+		mocks.ds.ScimUserByIDFunc = func(ctx context.Context, id uint) (*fleet.ScimUser, error) {
+			return scimUser, nil
+		}
+		mocks.ds.UserByEmailFunc = func(ctx context.Context, email string) (*fleet.User, error) {
+			return nil, platform_mysql.NotFound("User")
+		}
+		var created *fleet.User
+		mocks.ds.NewUserFunc = func(ctx context.Context, user *fleet.User) (*fleet.User, error) {
+			created = user
+			user.ID = 42
+			return user, nil
+		}
+		var activity fleet.ActivityDetails
+		mocks.svc.NewActivityFunc = func(ctx context.Context, user *fleet.User, a fleet.ActivityDetails) error {
+			assert.Nil(t, user)
+			activity = a
+			return nil
+		}
+
+		p := mocks.newTestProvisioner()
+		require.NoError(t, p.syncFleetUser(ctx, testProvisioningSettings(), scimUser.ID, nil))
+
+		require.NotNil(t, created)
+		assert.Equal(t, "John Doe", created.Name)
+		assert.Equal(t, "john.doe@example.com", created.Email)
+		assert.True(t, created.SSOEnabled)
+		assert.NotEmpty(t, created.Password)
+		assert.Nil(t, created.GlobalRole)
+		require.Len(t, created.Teams, 1)
+		assert.Equal(t, uint(7), created.Teams[0].ID)
+		assert.Equal(t, fleet.RoleMaintainer, created.Teams[0].Role)
+
+		provisioned, ok := activity.(fleet.ActivityTypeProvisionedUser)
+		require.True(t, ok)
+		assert.Equal(t, uint(42), provisioned.UserID)
+		assert.True(t, provisioned.Created)
+		assert.Equal(t, []fleet.ActivityUserTeamRole{{TeamID: 7, TeamName: "Workstations", Role: fleet.RoleMaintainer}}, provisioned.Teams)
+	})
+
+	t.Run("does not create a user for an unmapped SCIM user", func(t *testing.T) {
+		mocks := newTestMocks()
+		mocks.enableProvisioning()
+		scimUser := scimUserInGroups(nil, "sales", "unknown-team")
+		mocks.ds.ScimUserByIDFunc = func(ctx context.Context, id uint) (*fleet.ScimUser, error) {
+			return scimUser, nil
+		}
+		mocks.ds.UserByEmailFunc = func(ctx context.Context, email string) (*fleet.User, error) {
+			return nil, platform_mysql.NotFound("User")
+		}
+
+		p := mocks.newTestProvisioner()
+		require.NoError(t, p.syncFleetUser(ctx, testProvisioningSettings(), scimUser.ID, nil))
+		assert.False(t, mocks.ds.NewUserFuncInvoked)
+		assert.False(t, mocks.ds.DisableUserFuncInvoked)
+	})
+
+	t.Run("updates the roles of an existing SSO user and enables it", func(t *testing.T) {
+		mocks := newTestMocks()
+		mocks.enableProvisioning()
+		scimUser := scimUserInGroups(nil, "ws-it", "fleet-admins")
+		fleetUser := newTestFleetUser(&fleetUserOpts{ssoEnabled: true})
+		fleetUser.Disabled = true
+		mocks.ds.ScimUserByIDFunc = func(ctx context.Context, id uint) (*fleet.ScimUser, error) {
+			return scimUser, nil
+		}
+		mocks.ds.UserByEmailFunc = func(ctx context.Context, email string) (*fleet.User, error) {
+			return fleetUser, nil
+		}
+		mocks.ds.SaveUserFunc = func(ctx context.Context, user *fleet.User) error {
+			assert.False(t, user.Disabled)
+			assert.Equal(t, ptr.String(fleet.RoleAdmin), user.GlobalRole)
+			assert.Empty(t, user.Teams)
+			return nil
+		}
+		mocks.svc.NewActivityFunc = func(ctx context.Context, user *fleet.User, a fleet.ActivityDetails) error {
+			provisioned, ok := a.(fleet.ActivityTypeProvisionedUser)
+			require.True(t, ok)
+			assert.False(t, provisioned.Created)
+			assert.Equal(t, ptr.String(fleet.RoleAdmin), provisioned.GlobalRole)
+			return nil
+		}
+
+		p := mocks.newTestProvisioner()
+		require.NoError(t, p.syncFleetUser(ctx, testProvisioningSettings(), scimUser.ID, nil))
+		assert.True(t, mocks.ds.SaveUserFuncInvoked)
+		assert.True(t, mocks.svc.NewActivityFuncInvoked)
+	})
+
+	t.Run("does nothing if the roles are unchanged", func(t *testing.T) {
+		mocks := newTestMocks()
+		mocks.enableProvisioning()
+		scimUser := scimUserInGroups(nil, "fleet-admins")
+		fleetUser := newTestFleetUser(&fleetUserOpts{ssoEnabled: true, globalRole: fleet.RoleAdmin})
+		mocks.ds.ScimUserByIDFunc = func(ctx context.Context, id uint) (*fleet.ScimUser, error) {
+			return scimUser, nil
+		}
+		mocks.ds.UserByEmailFunc = func(ctx context.Context, email string) (*fleet.User, error) {
+			return fleetUser, nil
+		}
+
+		p := mocks.newTestProvisioner()
+		require.NoError(t, p.syncFleetUser(ctx, testProvisioningSettings(), scimUser.ID, nil))
+		assert.False(t, mocks.ds.SaveUserFuncInvoked)
+	})
+
+	t.Run("demoting a global admin keeps the last admin", func(t *testing.T) {
+		mocks := newTestMocks()
+		mocks.enableProvisioning()
+		scimUser := scimUserInGroups(nil, "ws-it")
+		fleetUser := newTestFleetUser(&fleetUserOpts{ssoEnabled: true, globalRole: fleet.RoleAdmin})
+		mocks.ds.ScimUserByIDFunc = func(ctx context.Context, id uint) (*fleet.ScimUser, error) {
+			return scimUser, nil
+		}
+		mocks.ds.UserByEmailFunc = func(ctx context.Context, email string) (*fleet.User, error) {
+			return fleetUser, nil
+		}
+		mocks.ds.SaveUserIfNotLastAdminFunc = func(ctx context.Context, user *fleet.User) error {
+			return fleet.ErrLastGlobalAdmin
+		}
+
+		p := mocks.newTestProvisioner()
+		err := p.syncFleetUser(ctx, testProvisioningSettings(), scimUser.ID, nil)
+		require.ErrorIs(t, err, fleet.ErrLastGlobalAdmin)
+		assert.False(t, mocks.ds.SaveUserFuncInvoked)
+		assert.False(t, mocks.svc.NewActivityFuncInvoked)
+	})
+
+	for _, c := range []struct {
+		name     string
+		scimUser *fleet.ScimUser
+	}{
+		{"disables a user removed from all mapped groups", scimUserInGroups(nil, "sales")},
+		{"disables a deactivated user", scimUserInGroups(&scimUserOpts{active: ptr.Bool(false)}, "fleet-admins")},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			mocks := newTestMocks()
+			mocks.enableProvisioning()
+			fleetUser := newTestFleetUser(&fleetUserOpts{ssoEnabled: true})
+			mocks.ds.ScimUserByIDFunc = func(ctx context.Context, id uint) (*fleet.ScimUser, error) {
+				return c.scimUser, nil
+			}
+			mocks.ds.UserByEmailFunc = func(ctx context.Context, email string) (*fleet.User, error) {
+				return fleetUser, nil
+			}
+			mocks.ds.DisableUserFunc = func(ctx context.Context, id uint) error {
+				assert.Equal(t, fleetUser.ID, id)
+				return nil
+			}
+			mocks.svc.NewActivityFunc = func(ctx context.Context, user *fleet.User, a fleet.ActivityDetails) error {
+				assert.Equal(t, fleet.ActivityTypeDeprovisionedUser{
+					UserID: fleetUser.ID, UserName: fleetUser.Name, UserEmail: fleetUser.Email,
+				}, a)
+				return nil
+			}
+
+			p := mocks.newTestProvisioner()
+			require.NoError(t, p.syncFleetUser(ctx, testProvisioningSettings(), c.scimUser.ID, nil))
+			assert.True(t, mocks.ds.DisableUserFuncInvoked)
+			assert.True(t, mocks.svc.NewActivityFuncInvoked)
+		})
+	}
+
+	t.Run("skips API-only and non-SSO users", func(t *testing.T) {
+		for _, fleetUser := range []*fleet.User{
+			newTestFleetUser(&fleetUserOpts{apiOnly: true, ssoEnabled: true}),
+			newTestFleetUser(&fleetUserOpts{ssoEnabled: false}),
+		} {
+			mocks := newTestMocks()
+			mocks.enableProvisioning()
+			scimUser := scimUserInGroups(&scimUserOpts{active: ptr.Bool(false)}, "fleet-admins")
+			mocks.ds.ScimUserByIDFunc = func(ctx context.Context, id uint) (*fleet.ScimUser, error) {
+				return scimUser, nil
+			}
+			mocks.ds.UserByEmailFunc = func(ctx context.Context, email string) (*fleet.User, error) {
+				return fleetUser, nil
+			}
+
+			p := mocks.newTestProvisioner()
+			require.NoError(t, p.syncFleetUser(ctx, testProvisioningSettings(), scimUser.ID, nil))
+			assert.False(t, mocks.ds.DisableUserFuncInvoked)
+			assert.False(t, mocks.ds.SaveUserFuncInvoked)
+		}
+	})
+}
+
+func TestUserHandlerProvisioning(t *testing.T) {
+	t.Run("deleting a SCIM user disables the Fleet user", func(t *testing.T) {
+		mocks := newTestMocks()
+		mocks.enableProvisioning()
+		scimUser := newTestScimUser(nil)
+		fleetUser := newTestFleetUser(&fleetUserOpts{ssoEnabled: true})
+		mocks.ds.ScimUserByIDFunc = func(ctx context.Context, id uint) (*fleet.ScimUser, error) {
+			return scimUser, nil
+		}
+		mocks.ds.UserByEmailFunc = func(ctx context.Context, email string) (*fleet.User, error) {
+			return fleetUser, nil
+		}
+		mocks.ds.DisableUserFunc = func(ctx context.Context, id uint) error {
+			return nil
+		}
+		mocks.ds.DeleteScimUserFunc = func(ctx context.Context, id uint) ([]fleet.ActivityTypeResentCertificate, error) {
+			return nil, nil
+		}
+		mocks.svc.NewActivityFunc = func(ctx context.Context, user *fleet.User, a fleet.ActivityDetails) error {
+			_, ok := a.(fleet.ActivityTypeDeprovisionedUser)
+			assert.True(t, ok)
+			return nil
+		}
+
+		handler := mocks.newTestHandler()
+		req := httptest.NewRequest(http.MethodDelete, "/scim/v2/Users/1", nil)
+		require.NoError(t, handler.Delete(req, "1"))
+
+		assert.True(t, mocks.ds.DisableUserFuncInvoked)
+		assert.False(t, mocks.ds.DeleteUserFuncInvoked)
+		assert.False(t, mocks.ds.DeleteUserIfNotLastAdminFuncInvoked)
+		assert.True(t, mocks.ds.DeleteScimUserFuncInvoked)
+	})
+
+	t.Run("deactivating a SCIM user resolves the Fleet user from the persisted identifiers", func(t *testing.T) {
+		mocks := newTestMocks()
+		mocks.enableProvisioning()
+		persisted := scimUserInGroups(&scimUserOpts{
+			active:     ptr.Bool(true),
+			userName:   "user@example.com",
+			givenName:  "John",
+			familyName: "Doe",
+		}, "fleet-admins")
+		fleetUser := newTestFleetUser(&fleetUserOpts{ssoEnabled: true})
+
+		// The first load is the persisted user, then the replaced one.
+		var replaced *fleet.ScimUser
+		mocks.ds.ScimUserByIDFunc = func(ctx context.Context, id uint) (*fleet.ScimUser, error) {
+			if replaced != nil {
+				return replaced, nil
+			}
+			return persisted, nil
+		}
+		mocks.ds.ScimUserByUserNameFunc = func(ctx context.Context, userName string) (*fleet.ScimUser, error) {
+			return nil, platform_mysql.NotFound("ScimUser")
+		}
+		mocks.ds.ReplaceScimUserFunc = func(ctx context.Context, user *fleet.ScimUser) ([]fleet.ActivityTypeResentCertificate, error) {
+			replaced = user
+			return nil, nil
+		}
+		mocks.ds.UserByEmailFunc = func(ctx context.Context, email string) (*fleet.User, error) {
+			if email == "user@example.com" {
+				return fleetUser, nil
+			}
+			return nil, platform_mysql.NotFound("User")
+		}
+		mocks.ds.DisableUserFunc = func(ctx context.Context, id uint) error {
+			assert.Equal(t, fleetUser.ID, id)
+			return nil
+		}
+		mocks.svc.NewActivityFunc = func(ctx context.Context, user *fleet.User, a fleet.ActivityDetails) error {
+			return nil
+		}
+
+		handler := mocks.newTestHandler()
+		req := httptest.NewRequest(http.MethodPut, "/scim/v2/Users/1", nil)
+		_, err := handler.Replace(req, "1", newTestAttrs("renamed", ptr.Bool(false), "John", "Doe"))
+		require.NoError(t, err)
+
+		assert.True(t, mocks.ds.DisableUserFuncInvoked)
+		assert.False(t, mocks.ds.DeleteUserFuncInvoked)
+	})
+}
+
+func TestGroupHandlerProvisioning(t *testing.T) {
+	mocks := newTestMocks()
+	mocks.enableProvisioning()
+
+	// User 1 is removed from the group, user 2 is added.
+	group := &fleet.ScimGroup{ID: 5, DisplayName: "fleet-admins", ScimUsers: []uint{1}}
+	members := []uint{1}
+	mocks.ds.ScimGroupByIDFunc = func(ctx context.Context, id uint, excludeUsers bool) (*fleet.ScimGroup, error) {
+		return group, nil
+	}
+	mocks.ds.ScimUsersExistFunc = func(ctx context.Context, ids []uint) (bool, error) {
+		return true, nil
+	}
+	mocks.ds.ScimGroupMemberUserIDsFunc = func(ctx context.Context, groupID uint) ([]uint, error) {
+		assert.Equal(t, group.ID, groupID)
+		return members, nil
+	}
+	mocks.ds.ReplaceScimGroupFunc = func(ctx context.Context, g *fleet.ScimGroup) error {
+		members = g.ScimUsers
+		return nil
+	}
+
+	var synced []uint
+	mocks.ds.ScimUserByIDFunc = func(ctx context.Context, id uint) (*fleet.ScimUser, error) {
+		synced = append(synced, id)
+		return newTestScimUser(&scimUserOpts{id: id}), nil
+	}
+	mocks.ds.UserByEmailFunc = func(ctx context.Context, email string) (*fleet.User, error) {
+		return nil, platform_mysql.NotFound("User")
+	}
+
+	handler := &GroupHandler{ds: mocks.ds, provisioner: mocks.newTestProvisioner(), logger: slog.New(slog.DiscardHandler)}
+	path, err := filter.ParsePath([]byte(membersAttr))
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPatch, "/scim/v2/Groups/group-5", nil)
+	_, err = handler.Patch(req, "group-5", []scim.PatchOperation{
+		{Op: scim.PatchOperationReplace, Path: &path, Value: []any{map[string]any{"value": "2"}}},
+	})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []uint{1, 2}, synced)
+}
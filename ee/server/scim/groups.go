@@ -12,6 +12,7 @@ import (
 	"github.com/elimity-com/scim"
 	"github.com/elimity-com/scim/errors"
 	"github.com/elimity-com/scim/optional"
+	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/scim2/filter-parser/v2"
 )
@@ -23,15 +24,55 @@ const (
 )
 
 type GroupHandler struct {
-	ds     fleet.Datastore
-	logger *slog.Logger
+	ds          fleet.Datastore
+	provisioner *fleetUserProvisioner
+	logger      *slog.Logger
 }
 
 // Compile-time check
 var _ scim.ResourceHandler = &GroupHandler{}
 
-func NewGroupHandler(ds fleet.Datastore, logger *slog.Logger) scim.ResourceHandler {
-	return &GroupHandler{ds: ds, logger: logger}
+func NewGroupHandler(ds fleet.Datastore, newActivity fleet.NewActivityFunc, authConfig config.AuthConfig, logger *slog.Logger) scim.ResourceHandler {
+	return &GroupHandler{
+		ds:          ds,
+		provisioner: newFleetUserProvisioner(ds, newActivity, authConfig, logger),
+		logger:      logger,
+	}
+}
+
+// provisioningState returns the SCIM user provisioning settings and, if
+// provisioning is enabled, the members of the group before it is changed.
+// Failures are logged and disable the sync of the members' Fleet users, the
+// SCIM request is still processed.
+func (g *GroupHandler) provisioningState(ctx context.Context, groupID uint) (*fleet.SCIMUserProvisioningSettings, []uint) {
+	settings, err := g.provisioner.settings(ctx)
+	if err != nil {
+		g.logger.ErrorContext(ctx, "failed to get scim user provisioning settings", "err", err)
+		return nil, nil
+	}
+	if settings == nil || groupID == 0 {
+		return settings, nil
+	}
+	members, err := g.ds.ScimGroupMemberUserIDs(ctx, groupID)
+	if err != nil {
+		g.logger.ErrorContext(ctx, "failed to get scim group members", "group_id", groupID, "err", err)
+		return nil, nil
+	}
+	return settings, members
+}
+
+// syncGroupMembers syncs the Fleet users of the current and previous members
+// of a changed group, when SCIM user provisioning is enabled.
+func (g *GroupHandler) syncGroupMembers(ctx context.Context, settings *fleet.SCIMUserProvisioningSettings, groupID uint, previousMembers []uint) {
+	if settings == nil {
+		return
+	}
+	members, err := g.ds.ScimGroupMemberUserIDs(ctx, groupID)
+	if err != nil {
+		g.logger.ErrorContext(ctx, "failed to get scim group members", "group_id", groupID, "err", err)
+		return
+	}
+	g.provisioner.syncFleetUsers(ctx, settings, append(previousMembers, members...))
 }
 
 // Create creates a SCIM group
@@ -66,6 +107,9 @@ func (g *GroupHandler) Create(r *http.Request, attributes scim.ResourceAttribute
 		return scim.Resource{}, err
 	}
 
+	settings, _ := g.provisioningState(r.Context(), 0)
+	g.syncGroupMembers(r.Context(), settings, group.ID, nil)
+
 	return createGroupResource(group), nil
 }
 
@@ -280,6 +324,7 @@ func (g *GroupHandler) Replace(r *http.Request, id string, attributes scim.Resou
 		// Otherwise, we assume that we are replacing the displayName with this operation.
 	}
 
+	settings, previousMembers := g.provisioningState(r.Context(), group.ID)
 	err = g.ds.ReplaceScimGroup(r.Context(), group)
 	switch {
 	case fleet.IsNotFound(err):
@@ -289,6 +334,7 @@ func (g *GroupHandler) Replace(r *http.Request, id string, attributes scim.Resou
 		g.logger.ErrorContext(r.Context(), "failed to replace group", "id", id, "err", err)
 		return scim.Resource{}, err
 	}
+	g.syncGroupMembers(r.Context(), settings, group.ID, previousMembers)
 
 	return createGroupResource(group), nil
 }
@@ -299,6 +345,7 @@ func (g *GroupHandler) Delete(r *http.Request, id string) error {
 		g.logger.InfoContext(r.Context(), "failed to parse id", "id", id, "err", err)
 		return errors.ScimErrorResourceNotFound(id)
 	}
+	settings, previousMembers := g.provisioningState(r.Context(), idUint)
 	err = g.ds.DeleteScimGroup(r.Context(), idUint)
 	switch {
 	case fleet.IsNotFound(err):
@@ -308,6 +355,9 @@ func (g *GroupHandler) Delete(r *http.Request, id string) error {
 		g.logger.ErrorContext(r.Context(), "failed to delete group", "id", id, "err", err)
 		return err
 	}
+	if settings != nil {
+		g.provisioner.syncFleetUsers(r.Context(), settings, previousMembers)
+	}
 	return nil
 }
 
@@ -395,6 +445,7 @@ func (g *GroupHandler) Patch(r *http.Request, id string, operations []scim.Patch
 	}
 
 	if len(operations) != 0 {
+		settings, previousMembers := g.provisioningState(ctx, group.ID)
 		err = g.ds.ReplaceScimGroup(ctx, group)
 		switch {
 		case fleet.IsNotFound(err):
@@ -404,6 +455,7 @@ func (g *GroupHandler) Patch(r *http.Request, id string, operations []scim.Patch
 			g.logger.ErrorContext(ctx, "failed to patch group", "id", id, "err", err)
 			return scim.Resource{}, err
 		}
+		g.syncGroupMembers(ctx, settings, group.ID, previousMembers)
 	}
 
 	return createGroupResource(group), nil
@@ -15,7 +15,7 @@ import (
 	"github.com/elimity-com/scim"
 	scimerrors "github.com/elimity-com/scim/errors"
 	"github.com/elimity-com/scim/optional"
-	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/ptr"
@@ -45,14 +45,20 @@ const (
 type UserHandler struct {
 	ds          fleet.Datastore
 	newActivity fleet.NewActivityFunc
+	provisioner *fleetUserProvisioner
 	logger      *slog.Logger
 }
 
 // Compile-time check
 var _ scim.ResourceHandler = &UserHandler{}
 
-func NewUserHandler(ds fleet.Datastore, newActivity fleet.NewActivityFunc, logger *slog.Logger) scim.ResourceHandler {
-	return &UserHandler{ds: ds, newActivity: newActivity, logger: logger}
+func NewUserHandler(ds fleet.Datastore, newActivity fleet.NewActivityFunc, authConfig config.AuthConfig, logger *slog.Logger) scim.ResourceHandler {
+	return &UserHandler{
+		ds:          ds,
+		newActivity: newActivity,
+		provisioner: newFleetUserProvisioner(ds, newActivity, authConfig, logger),
+		logger:      logger,
+	}
 }
 
 func (u *UserHandler) Create(r *http.Request, attributes scim.ResourceAttributes) (scim.Resource, error) {
@@ -98,6 +104,7 @@ func (u *UserHandler) Create(r *http.Request, attributes scim.ResourceAttributes
 					u.logger.ErrorContext(ctx, "failed to create resent_certificate activity", "err", err)
 				}
 			}
+			u.updateMatchingFleetUser(ctx, existingUser, user)
 			return createUserResource(user), nil
 		}
 		u.logger.InfoContext(ctx, "user already exists", userNameAttr, userName)
@@ -462,11 +469,10 @@ func (u *UserHandler) Replace(r *http.Request, id string, attributes scim.Resour
 	user.ID = idUint
 
 	// Username is unique, so we must check if another user already exists with that username to return a clear error
-	// We also use this to get the previous active state when the username isn't changing.
+	// We also use this to get the previous state when the username isn't changing.
 	// prePatchUser captures the persisted (pre-replace) record so deactivation
 	// resolves the matching Fleet user from durable state rather than the incoming
 	// (mutated) userName/emails, which a client could clear to evade deprovisioning.
-	var previousActive *bool
 	var prePatchUser *fleet.ScimUser
 	userWithSameUsername, err := u.ds.ScimUserByUserName(ctx, user.UserName)
 	switch {
@@ -477,11 +483,10 @@ func (u *UserHandler) Replace(r *http.Request, id string, attributes scim.Resour
 		u.logger.InfoContext(ctx, "user already exists with this username", userNameAttr, user.UserName)
 		return scim.Resource{}, scimerrors.ScimErrorUniqueness
 	case err == nil && user.ID == userWithSameUsername.ID:
-		// Same user, username not changing - use this for previous active state
-		previousActive = userWithSameUsername.Active
+		// Same user, username not changing - use this for previous state
 		prePatchUser = userWithSameUsername
 	case fleet.IsNotFound(err):
-		// Username is being changed - need to fetch existing user by ID for previous active state
+		// Username is being changed - need to fetch existing user by ID for previous state
 		existingUser, err := u.ds.ScimUserByID(ctx, idUint)
 		if fleet.IsNotFound(err) {
 			u.logger.InfoContext(ctx, "failed to find scim user by id", "id", id)
@@ -491,7 +496,6 @@ func (u *UserHandler) Replace(r *http.Request, id string, attributes scim.Resour
 			u.logger.ErrorContext(ctx, "failed to get existing scim user by id", "id", id, "err", err)
 			return scim.Resource{}, err
 		}
-		previousActive = existingUser.Active
 		prePatchUser = existingUser
 	}
 
@@ -515,12 +519,7 @@ func (u *UserHandler) Replace(r *http.Request, id string, attributes scim.Resour
 		}
 	}
 
-	// Check if user was deactivated and delete matching Fleet user if so
-	if wasDeactivated(previousActive, user.Active) {
-		if err := u.deleteMatchingFleetUser(ctx, prePatchUser); err != nil {
-			u.logger.ErrorContext(ctx, "failed to delete fleet user on deactivation", "err", err)
-		}
-	}
+	u.updateMatchingFleetUser(ctx, prePatchUser, user)
 
 	return createUserResource(user), nil
 }
@@ -547,9 +546,22 @@ func (u *UserHandler) Delete(r *http.Request, id string) error {
 	}
 
 	if scimUser != nil {
-		if err := u.deleteMatchingFleetUser(ctx, scimUser); err != nil {
-			// Log but don't fail - SCIM deletion should still proceed
-			u.logger.ErrorContext(ctx, "failed to delete matching fleet user", "err", err)
+		settings, err := u.provisioner.settings(ctx)
+		switch {
+		case err != nil:
+			u.logger.ErrorContext(ctx, "failed to get scim user provisioning settings", "err", err)
+		case settings != nil:
+			// With SCIM user provisioning, the Fleet user is disabled rather
+			// than deleted so that it can be enabled again if the user is
+			// provisioned back.
+			if err := u.provisioner.deprovisionFleetUser(ctx, scimUser); err != nil {
+				u.logger.ErrorContext(ctx, "failed to deprovision matching fleet user", "err", err)
+			}
+		default:
+			if err := u.deleteMatchingFleetUser(ctx, scimUser); err != nil {
+				// Log but don't fail - SCIM deletion should still proceed
+				u.logger.ErrorContext(ctx, "failed to delete matching fleet user", "err", err)
+			}
 		}
 	}
 
@@ -582,42 +594,41 @@ func wasDeactivated(previous, current *bool) bool {
 	return previous == nil || *previous
 }
 
+// updateMatchingFleetUser updates the Fleet user matching a SCIM user after it
+// was replaced or patched. previous is the persisted state of the SCIM user
+// before the request. With SCIM user provisioning enabled, the Fleet user is
+// synced from the SCIM user's state and groups (created, updated or
+// disabled). Otherwise, it is deleted if the SCIM user was deactivated.
+// Failures are logged, the SCIM request itself succeeded.
+func (u *UserHandler) updateMatchingFleetUser(ctx context.Context, previous, current *fleet.ScimUser) {
+	settings, err := u.provisioner.settings(ctx)
+	if err != nil {
+		u.logger.ErrorContext(ctx, "failed to get scim user provisioning settings", "err", err)
+		return
+	}
+	if settings != nil {
+		if err := u.provisioner.syncFleetUser(ctx, settings, current.ID, previous); err != nil {
+			u.logger.ErrorContext(ctx, "failed to sync fleet user of scim user", "err", err)
+		}
+		return
+	}
+
+	if wasDeactivated(previous.Active, current.Active) {
+		if err := u.deleteMatchingFleetUser(ctx, previous); err != nil {
+			u.logger.ErrorContext(ctx, "failed to delete fleet user on deactivation", "err", err)
+		}
+	}
+}
+
 // deleteMatchingFleetUser deletes the SSO Fleet user matching the given SCIM
 // user. Callers MUST pass the SCIM record's persisted (pre-mutation) state:
 // resolving from mutated PATCH/PUT state would let a client evade
 // deprovisioning by clearing userName/emails in the same request that sets
 // active=false.
 func (u *UserHandler) deleteMatchingFleetUser(ctx context.Context, scimUser *fleet.ScimUser) error {
-	// Collect unique emails from SCIM user (userName is often the email in many IdP configurations, e.g. Okta).
-	// userName is added first so it's checked first when looking up Fleet users.
-	emails := make([]string, 0, len(scimUser.Emails)+1)
-
-	if strings.Contains(scimUser.UserName, "@") {
-		emails = append(emails, strings.ToLower(scimUser.UserName))
-	}
-
-	for _, e := range scimUser.Emails {
-		emails = append(emails, strings.ToLower(e.Email))
-	}
-
-	emails = server.RemoveDuplicatesFromSlice(emails)
-
-	if len(emails) == 0 {
-		u.logger.DebugContext(ctx, "no emails found for scim user",
-			"scim_user_id", scimUser.ID, "user_name", scimUser.UserName)
-		return nil
-	}
-
-	var fleetUser *fleet.User
-	for _, email := range emails {
-		user, err := u.ds.UserByEmail(ctx, email)
-		if err == nil {
-			fleetUser = user
-			break
-		}
-		if !fleet.IsNotFound(err) {
-			return ctxerr.Wrap(ctx, err, "lookup fleet user by email")
-		}
+	fleetUser, err := matchingFleetUser(ctx, u.ds, scimUser)
+	if err != nil {
+		return err
 	}
 
 	if fleetUser == nil {
@@ -689,13 +700,12 @@ func (u *UserHandler) Patch(r *http.Request, id string, operations []scim.PatchO
 		return scim.Resource{}, err
 	}
 
-	// Store the previous active state and a copy of the persisted identifiers
+	// Store a copy of the persisted state (active state and identifiers)
 	// before applying patches. The operations below mutate `user` in place, so
 	// the matching Fleet user on deactivation must be resolved from this
 	// pre-patch snapshot — otherwise a client could evade deprovisioning by
 	// clearing userName/emails in the same PATCH that sets active=false. Emails
 	// is cloned because patch operations mutate its elements in place.
-	previousActive := user.Active
 	prePatchUser := *user
 	prePatchUser.Emails = slices.Clone(user.Emails)
 
@@ -806,15 +816,11 @@ func (u *UserHandler) Patch(r *http.Request, id string, operations []scim.PatchO
 			}
 		}
 
-		// Check if user was deactivated and delete matching Fleet user if so.
-		// This sits inside `if !allUnknown` because patchActive only runs when at
-		// least one recognized op was applied; if every op was unrecognized,
-		// user.Active equals previousActive and no deactivation can have occurred.
-		if wasDeactivated(previousActive, user.Active) {
-			if err := u.deleteMatchingFleetUser(ctx, &prePatchUser); err != nil {
-				u.logger.ErrorContext(ctx, "failed to delete fleet user on deactivation", "err", err)
-			}
-		}
+		// Update the matching Fleet user, e.g. delete it if the user was
+		// deactivated. This sits inside `if !allUnknown` because patchActive
+		// only runs when at least one recognized op was applied; if every op
+		// was unrecognized, the user is unchanged.
+		u.updateMatchingFleetUser(ctx, &prePatchUser, user)
 	}
 
 	return createUserResource(user), nil
@@ -10,6 +10,7 @@ import (
 
 	"github.com/elimity-com/scim"
 	scimerrors "github.com/elimity-com/scim/errors"
+	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/mock"
 	mockservice "github.com/fleetdm/fleet/v4/server/mock/service"
@@ -18,6 +19,7 @@ import (
 	"github.com/scim2/filter-parser/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // alreadyExistsErr implements fleet.AlreadyExistsError for testing.
@@ -34,16 +36,27 @@ type testMocks struct {
 }
 
 func newTestMocks() *testMocks {
-	return &testMocks{
+	m := &testMocks{
 		ds:  new(mock.Store),
 		svc: new(mockservice.Service),
 	}
+	// SCIM user provisioning is disabled unless a test enables it.
+	m.ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	return m
+}
+
+func (m *testMocks) newTestProvisioner() *fleetUserProvisioner {
+	return newFleetUserProvisioner(m.ds, m.svc.NewActivity, config.AuthConfig{SaltKeySize: 24, BcryptCost: bcrypt.MinCost},
+		slog.New(slog.DiscardHandler))
 }
 
 func (m *testMocks) newTestHandler() *UserHandler {
 	return &UserHandler{
 		ds:          m.ds,
 		newActivity: m.svc.NewActivity,
+		provisioner: m.newTestProvisioner(),
 		logger:      slog.New(slog.DiscardHandler),
 	}
 }
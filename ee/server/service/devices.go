@@ -235,7 +235,11 @@ func (svc *Service) TriggerLinuxDiskEncryptionEscrow(ctx context.Context, host *
 		return err
 	}
 
-	return svc.ds.QueueEscrow(ctx, host.ID)
+	if err := svc.ds.QueueEscrow(ctx, host.ID); err != nil {
+		return err
+	}
+	svc.NotifyOrbitHosts(ctx, fleet.OrbitNotificationHintDiskEncryption, host.ID)
+	return nil
 }
 
 func (svc *Service) validateReadyForLinuxEscrow(ctx context.Context, host *fleet.Host) error {
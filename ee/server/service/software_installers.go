@@ -1592,6 +1592,28 @@ func (svc *Service) OrbitDownloadSoftwareInstaller(ctx context.Context, installe
 	return svc.getSoftwareInstallerBinary(ctx, meta.StorageID, meta.Name)
 }
 
+func (svc *Service) OrbitCacheProxyDownloadSoftwareInstaller(ctx context.Context, hashSHA256 string) (*fleet.DownloadSoftwareInstallerPayload, error) {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return nil, fleet.OrbitError{Message: "internal error: missing host from request context"}
+	}
+
+	// The caching proxy may only download the installers of its fleet that
+	// hosts are about to install.
+	meta, err := svc.ds.GetCacheProxySoftwareInstaller(ctx, host.ID, hashSHA256)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			return nil, fleet.NewUserMessageError(errors.New("Host doesn't have access to this installer"), http.StatusForbidden)
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get software installer for cache proxy")
+	}
+
+	return svc.getSoftwareInstallerBinary(ctx, meta.StorageID, meta.Name)
+}
+
 func (svc *Service) validateAndGetSoftwareInstallerMetadata(ctx context.Context, installerID uint) (*fleet.SoftwareInstaller, error) {
 	host, ok := hostctx.FromContext(ctx)
 	if !ok {
@@ -2228,7 +2250,11 @@ func (svc *Service) installSoftwareTitleUsingInstaller(ctx context.Context, host
 		SelfService: false,
 		WithRetries: true,
 	})
-	return ctxerr.Wrap(ctx, err, "inserting software install request")
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "inserting software install request")
+	}
+	svc.NotifyOrbitHosts(ctx, fleet.OrbitNotificationHintSoftwareInstalls, host.ID)
+	return nil
 }
 
 // UninstallSoftwareTitle queues an uninstall of the title's package on the given
@@ -2400,6 +2426,8 @@ func (svc *Service) insertSoftwareUninstallRequest(ctx context.Context, executio
 	if err := svc.ds.InsertSoftwareUninstallRequest(ctx, executionID, host.ID, installer.InstallerID, selfService); err != nil {
 		return ctxerr.Wrap(ctx, err, "inserting software uninstall request")
 	}
+	// uninstalls are run by fleetd as scripts
+	svc.NotifyOrbitHosts(ctx, fleet.OrbitNotificationHintScripts, host.ID)
 	return nil
 }
 
@@ -4383,7 +4411,11 @@ func (svc *Service) SelfServiceInstallSoftwareTitle(ctx context.Context, host *f
 			SelfService: true,
 			WithRetries: true,
 		})
-		return ctxerr.Wrap(ctx, err, "inserting self-service software install request")
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "inserting self-service software install request")
+		}
+		svc.NotifyOrbitHosts(ctx, fleet.OrbitNotificationHintSoftwareInstalls, host.ID)
+		return nil
 	}
 
 	vppApp, err := svc.ds.GetVPPAppByTeamAndTitleID(ctx, host.TeamID, softwareTitleID)
@@ -959,10 +959,13 @@ func newTestService(t *testing.T, ds fleet.Datastore) *Service {
 	authorizer, err := authz.NewAuthorizer()
 	require.NoError(t, err)
 	defaultMockCustomHostVitalsValidation(ds)
+	baseSvc := new(svcmock.Service)
+	baseSvc.NotifyOrbitHostsFunc = func(ctx context.Context, hint fleet.OrbitNotificationHint, hostIDs ...uint) {}
 	svc := &Service{
-		authz:  authorizer,
-		ds:     ds,
-		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Service: baseSvc,
+		authz:   authorizer,
+		ds:      ds,
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
 	}
 	return svc
 }
@@ -986,6 +989,7 @@ func newTestServiceWithMock(t *testing.T, ds fleet.Datastore) (*Service, *svcmoc
 	require.NoError(t, err)
 	defaultMockCustomHostVitalsValidation(ds)
 	baseSvc := new(svcmock.Service)
+	baseSvc.NotifyOrbitHostsFunc = func(ctx context.Context, hint fleet.OrbitNotificationHint, hostIDs ...uint) {}
 	svc := &Service{
 		Service: baseSvc,
 		authz:   authorizer,
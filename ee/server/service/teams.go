@@ -51,6 +51,8 @@ func obfuscateSecrets(user *fleet.User, teams []*fleet.Team) error {
 		if t == nil {
 			continue
 		}
+		// webhook secrets are write-only, like the global ones
+		t.Config.WebhookSettings.MaskSecrets()
 		if canReadGlobal || canReadTeam[t.ID] {
 			continue
 		}
@@ -223,6 +225,7 @@ func (svc *Service) ModifyTeam(ctx context.Context, teamID uint, payload fleet.T
 		if payload.WebhookSettings.HostActivitiesWebhook == nil {
 			payload.WebhookSettings.HostActivitiesWebhook = team.Config.WebhookSettings.HostActivitiesWebhook
 		}
+		payload.WebhookSettings.RestoreMaskedSecrets(team.Config.WebhookSettings)
 		team.Config.WebhookSettings = *payload.WebhookSettings
 	}
 
@@ -441,7 +444,7 @@ func (svc *Service) ModifyTeam(ctx context.Context, teamID uint, payload fleet.T
 	}
 
 	if payload.Integrations != nil {
-		if payload.Integrations.Jira != nil || payload.Integrations.Zendesk != nil {
+		if payload.Integrations.Jira != nil || payload.Integrations.Zendesk != nil || payload.Integrations.ServiceNow != nil {
 			// the team integrations must reference an existing global config integration.
 			if _, err := payload.Integrations.MatchWithIntegrations(appCfg.Integrations); err != nil {
 				return nil, fleet.NewInvalidArgumentError("integrations", err.Error())
@@ -452,8 +455,14 @@ func (svc *Service) ModifyTeam(ctx context.Context, teamID uint, payload fleet.T
 				return nil, fleet.NewInvalidArgumentError("integrations", err.Error())
 			}
 
-			team.Config.Integrations.Jira = payload.Integrations.Jira
-			team.Config.Integrations.Zendesk = payload.Integrations.Zendesk
+			if payload.Integrations.Jira != nil || payload.Integrations.Zendesk != nil {
+				team.Config.Integrations.Jira = payload.Integrations.Jira
+				team.Config.Integrations.Zendesk = payload.Integrations.Zendesk
+			}
+			// ServiceNow is only modified when it is explicitly set.
+			if payload.Integrations.ServiceNow != nil {
+				team.Config.Integrations.ServiceNow = payload.Integrations.ServiceNow
+			}
 		}
 
 		// Only update the calendar integration if it's not nil.
@@ -515,6 +524,15 @@ func (svc *Service) ModifyTeam(ctx context.Context, teamID uint, payload fleet.T
 		if payload.Features.HistoricalData.Vulnerabilities.Valid {
 			team.Config.Features.HistoricalData.Vulnerabilities = payload.Features.HistoricalData.Vulnerabilities.Value
 		}
+		if payload.Features.HistoricalData.Policies.Valid {
+			team.Config.Features.HistoricalData.Policies = payload.Features.HistoricalData.Policies.Value
+		}
+		if payload.Features.HistoricalData.MDMEnrollment.Valid {
+			team.Config.Features.HistoricalData.MDMEnrollment = payload.Features.HistoricalData.MDMEnrollment.Value
+		}
+		if payload.Features.HistoricalData.DiskEncryption.Valid {
+			team.Config.Features.HistoricalData.DiskEncryption = payload.Features.HistoricalData.DiskEncryption.Value
+		}
 	}
 
 	if payload.Features != nil && payload.Features.EnableSoftwareInventory.Valid {
@@ -1098,6 +1116,7 @@ func (svc *Service) GetTeam(ctx context.Context, teamID uint) (*fleet.Team, erro
 			Name:   fleet.ReservedNameNoTeam,
 			Config: *config,
 		}
+		team.Config.WebhookSettings.MaskSecrets()
 
 		return team, nil
 	}
@@ -1682,6 +1701,10 @@ func (svc *Service) createTeamFromSpec(
 		fleet.ValidateEnabledHostActivitiesWebhook(*spec.WebhookSettings.HostActivitiesWebhook, invalid)
 		hostActivitiesWebhook = spec.WebhookSettings.HostActivitiesWebhook
 	}
+	validateTeamWebhookDeliverySettings(fleet.TeamWebhookSettings{
+		HostStatusWebhook:     hostStatusWebhook,
+		HostActivitiesWebhook: hostActivitiesWebhook,
+	}, invalid)
 
 	if spec.Integrations.GoogleCalendar != nil {
 		err = svc.validateTeamCalendarIntegrations(spec.Integrations.GoogleCalendar, appCfg, dryRun, invalid)
@@ -2117,21 +2140,33 @@ func (svc *Service) editTeamFromSpec(
 	fleet.ValidateMDMProfileSpecs(invalid, "windows", team.Config.MDM.WindowsSettings.CustomSettings.Value)
 	fleet.ValidateMDMProfileSpecs(invalid, "android", team.Config.MDM.AndroidSettings.CustomSettings.Value)
 
+	// The secrets and custom header values of the webhooks may be sent masked,
+	// as returned by the API, in which case the stored ones are kept.
+	storedWebhooks := team.Config.WebhookSettings
+
 	// If host status webhook is not provided, do not change it
 	if spec.WebhookSettings.HostStatusWebhook != nil {
 		fleet.ValidateEnabledHostStatusIntegrations(*spec.WebhookSettings.HostStatusWebhook, invalid)
+		if storedWebhooks.HostStatusWebhook != nil {
+			spec.WebhookSettings.HostStatusWebhook.RestoreMaskedSecrets(storedWebhooks.HostStatusWebhook.WebhookDeliverySettings)
+		}
 		team.Config.WebhookSettings.HostStatusWebhook = spec.WebhookSettings.HostStatusWebhook
 	}
 
 	if spec.WebhookSettings.FailingPoliciesWebhook != nil {
 		fleet.ValidateEnabledFailingPoliciesTeamIntegrations(*spec.WebhookSettings.FailingPoliciesWebhook, fleet.TeamIntegrations{}, invalid)
+		spec.WebhookSettings.FailingPoliciesWebhook.RestoreMaskedSecrets(storedWebhooks.FailingPoliciesWebhook.WebhookDeliverySettings)
 		team.Config.WebhookSettings.FailingPoliciesWebhook = *spec.WebhookSettings.FailingPoliciesWebhook
 	}
 
 	if spec.WebhookSettings.HostActivitiesWebhook != nil {
 		fleet.ValidateEnabledHostActivitiesWebhook(*spec.WebhookSettings.HostActivitiesWebhook, invalid)
+		if storedWebhooks.HostActivitiesWebhook != nil {
+			spec.WebhookSettings.HostActivitiesWebhook.RestoreMaskedSecrets(storedWebhooks.HostActivitiesWebhook.WebhookDeliverySettings)
+		}
 		team.Config.WebhookSettings.HostActivitiesWebhook = spec.WebhookSettings.HostActivitiesWebhook
 	}
+	validateTeamWebhookDeliverySettings(team.Config.WebhookSettings, invalid)
 
 	if spec.Integrations.GoogleCalendar != nil {
 		err = svc.validateTeamCalendarIntegrations(spec.Integrations.GoogleCalendar, appCfg, opts.DryRun, invalid)
@@ -2368,8 +2403,8 @@ func (svc *Service) validateTeamCalendarIntegrations(
 		return nil
 	}
 	// Check that global configs exist. During dry run, the global config may not be available yet.
-	if len(appCfg.Integrations.GoogleCalendar) == 0 && !dryRun {
-		invalid.Append("integrations.google_calendar.enable_calendar_events", "global Google Calendar integration is not configured")
+	if !appCfg.Integrations.CalendarConfigured() && !dryRun {
+		invalid.Append("integrations.google_calendar.enable_calendar_events", "global calendar integration (Google Calendar or Microsoft 365) is not configured")
 	}
 	// Validate URL
 	if u, err := url.ParseRequestURI(calendarIntegration.WebhookURL); err != nil {
@@ -2674,17 +2709,30 @@ func validateTeamWebhookSettings(ctx context.Context, webhookSettings *fleet.Tea
 		}
 	}
 
+	invalid := &fleet.InvalidArgumentError{}
 	if webhookSettings.HostActivitiesWebhook != nil {
-		invalid := &fleet.InvalidArgumentError{}
 		fleet.ValidateEnabledHostActivitiesWebhook(*webhookSettings.HostActivitiesWebhook, invalid)
-		if invalid.HasErrors() {
-			return ctxerr.Wrap(ctx, invalid)
-		}
+	}
+	validateTeamWebhookDeliverySettings(*webhookSettings, invalid)
+	if invalid.HasErrors() {
+		return ctxerr.Wrap(ctx, invalid)
 	}
 
 	return nil
 }
 
+// validateTeamWebhookDeliverySettings validates the custom headers of the
+// fleet webhooks.
+func validateTeamWebhookDeliverySettings(webhookSettings fleet.TeamWebhookSettings, invalid *fleet.InvalidArgumentError) {
+	if webhookSettings.HostStatusWebhook != nil {
+		fleet.ValidateWebhookDeliverySettings("webhook_settings.host_status_webhook", webhookSettings.HostStatusWebhook.WebhookDeliverySettings, invalid)
+	}
+	fleet.ValidateWebhookDeliverySettings("webhook_settings.failing_policies_webhook", webhookSettings.FailingPoliciesWebhook.WebhookDeliverySettings, invalid)
+	if webhookSettings.HostActivitiesWebhook != nil {
+		fleet.ValidateWebhookDeliverySettings("webhook_settings.host_activities_webhook", webhookSettings.HostActivitiesWebhook.WebhookDeliverySettings, invalid)
+	}
+}
+
 func (svc *Service) modifyDefaultTeamConfig(ctx context.Context, payload fleet.TeamPayload) (*fleet.Team, error) {
 	// Use same authorization as AppConfig modifications
 	if err := svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite); err != nil {
@@ -2705,6 +2753,7 @@ func (svc *Service) modifyDefaultTeamConfig(ctx context.Context, payload fleet.T
 		if payload.WebhookSettings.HostActivitiesWebhook == nil {
 			payload.WebhookSettings.HostActivitiesWebhook = config.WebhookSettings.HostActivitiesWebhook
 		}
+		payload.WebhookSettings.RestoreMaskedSecrets(config.WebhookSettings)
 		config.WebhookSettings = *payload.WebhookSettings
 	}
 
@@ -2724,7 +2773,7 @@ func (svc *Service) modifyDefaultTeamConfig(ctx context.Context, payload fleet.T
 			return nil, err
 		}
 
-		if payload.Integrations.Jira != nil || payload.Integrations.Zendesk != nil {
+		if payload.Integrations.Jira != nil || payload.Integrations.Zendesk != nil || payload.Integrations.ServiceNow != nil {
 			// the team integrations must reference an existing global config integration.
 			if _, err := payload.Integrations.MatchWithIntegrations(appCfg.Integrations); err != nil {
 				return nil, fleet.NewInvalidArgumentError("integrations", err.Error())
@@ -2739,6 +2788,10 @@ func (svc *Service) modifyDefaultTeamConfig(ctx context.Context, payload fleet.T
 		// Always update integrations when provided (even if empty arrays to clear them)
 		config.Integrations.Jira = payload.Integrations.Jira
 		config.Integrations.Zendesk = payload.Integrations.Zendesk
+		// ServiceNow is only modified when it is explicitly set.
+		if payload.Integrations.ServiceNow != nil {
+			config.Integrations.ServiceNow = payload.Integrations.ServiceNow
+		}
 	}
 
 	// Validate mutual exclusivity of automations if either webhooks or integrations were updated
@@ -2767,6 +2820,7 @@ func (svc *Service) modifyDefaultTeamConfig(ctx context.Context, payload fleet.T
 		Name:   fleet.ReservedNameNoTeam,
 		Config: *config,
 	}
+	team.Config.WebhookSettings.MaskSecrets()
 
 	return team, nil
 }
@@ -94,6 +94,9 @@ const DEFAULT_CONFIG_MOCK: IConfig = {
     query_reports_disabled: false,
     scripts_disabled: false,
     ai_features_disabled: false,
+    require_mfa_above_observer: false,
+    report_history_retention_days: 0,
+    report_history_cap: 0,
   },
   smtp_settings: {
     enable_smtp: false,
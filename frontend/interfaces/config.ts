@@ -164,6 +164,9 @@ export interface IConfigServerSettings {
   query_reports_disabled: boolean;
   scripts_disabled: boolean;
   ai_features_disabled: boolean;
+  require_mfa_above_observer?: boolean;
+  report_history_retention_days?: number;
+  report_history_cap?: number;
 }
 
 export interface IConfig {
@@ -58,8 +58,15 @@ export interface IUser {
   gravatar_url_dark?: string;
   sso_enabled: boolean;
   mfa_enabled?: boolean;
+  /** Whether the user enrolled an authenticator app as a second factor. */
+  totp_enabled?: boolean;
+  /** Whether the user registered a security key or passkey as a second factor. */
+  webauthn_enabled?: boolean;
   global_role: UserRole | null;
   api_only: boolean;
+  /** Whether the user was disabled by SCIM user provisioning. Disabled users
+   * can't log in. */
+  disabled?: boolean;
   /** Last time the user logged in. `null` if the user has never logged in. */
   last_login_at: string | null;
   /** Last time the user made an authenticated request with a live session.
@@ -36,6 +36,7 @@ import (
 	"github.com/fleetdm/fleet/v4/orbit/pkg/augeas"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/bitlocker"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/build"
+	"github.com/fleetdm/fleet/v4/orbit/pkg/cacheproxy"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/execuser"
 	"github.com/fleetdm/fleet/v4/orbit/pkg/insecure"
@@ -257,6 +258,32 @@ func main() {
 			Usage:   "Bypasses end-user authentication during fleetd enrollment on Linux and Windows",
 			EnvVars: []string{"ORBIT_BYPASS_END_USER_AUTH"},
 		},
+		&cli.StringFlag{
+			Name:    "cache-proxy-listen",
+			Usage:   "Runs fleetd as a caching proxy of software installers and updates for the other hosts, listening on this address (e.g. :8443)",
+			EnvVars: []string{"ORBIT_CACHE_PROXY_LISTEN"},
+		},
+		&cli.StringFlag{
+			Name:    "cache-proxy-dir",
+			Usage:   "Directory of the caching proxy cache (defaults to the cache-proxy directory in the root directory)",
+			EnvVars: []string{"ORBIT_CACHE_PROXY_DIR"},
+		},
+		&cli.Int64Flag{
+			Name:    "cache-proxy-max-size",
+			Usage:   "Maximum size of the caching proxy cache in MB, the least recently used files are evicted above it (0 for no limit)",
+			Value:   50 * 1024,
+			EnvVars: []string{"ORBIT_CACHE_PROXY_MAX_SIZE"},
+		},
+		&cli.StringFlag{
+			Name:    "cache-proxy-tls-certificate",
+			Usage:   "Path to the TLS certificate served by the caching proxy, it must be trusted by the other hosts",
+			EnvVars: []string{"ORBIT_CACHE_PROXY_TLS_CERTIFICATE"},
+		},
+		&cli.StringFlag{
+			Name:    "cache-proxy-tls-key",
+			Usage:   "Path to the private key of the TLS certificate served by the caching proxy",
+			EnvVars: []string{"ORBIT_CACHE_PROXY_TLS_KEY"},
+		},
 	}
 	app.Before = func(c *cli.Context) error {
 		// handle old installations, which had default root dir set to /var/lib/orbit
@@ -1477,6 +1504,39 @@ func orbitAction(c *cli.Context) error {
 	checkerClient.GetServerCapabilities().Copy(orbitClient.GetServerCapabilities())
 	addSubsystem(&g, "capabilities checker", capabilitiesChecker)
 
+	if listenAddr := c.String("cache-proxy-listen"); listenAddr != "" {
+		if c.String("cache-proxy-tls-certificate") == "" || c.String("cache-proxy-tls-key") == "" {
+			return errors.New("--cache-proxy-tls-certificate and --cache-proxy-tls-key are required with --cache-proxy-listen")
+		}
+		cacheDir := c.String("cache-proxy-dir")
+		if cacheDir == "" {
+			cacheDir = filepath.Join(c.String("root-dir"), "cache-proxy")
+		}
+		cache, err := cacheproxy.NewCache(cacheDir, c.Int64("cache-proxy-max-size")*1024*1024)
+		if err != nil {
+			return fmt.Errorf("create cache proxy cache: %w", err)
+		}
+		proxyOpt := cacheproxy.Options{
+			Cache:       cache,
+			FleetClient: orbitClient,
+		}
+		if !c.Bool("disable-updates") {
+			updateClient, err := update.NewHTTPClient(opt)
+			if err != nil {
+				return fmt.Errorf("create cache proxy update client: %w", err)
+			}
+			proxyOpt.UpdateURL = opt.ServerURL
+			proxyOpt.UpdateClient = updateClient
+		}
+		addSubsystem(&g, "cache proxy", newCacheProxyServer(
+			listenAddr,
+			c.String("cache-proxy-tls-certificate"),
+			c.String("cache-proxy-tls-key"),
+			cacheproxy.New(proxyOpt),
+			orbitClient,
+		))
+	}
+
 	var desktopVersion string
 	if c.Bool("fleet-desktop") {
 		runPath := desktopPath
@@ -1592,6 +1652,7 @@ func orbitAction(c *cli.Context) error {
 
 	softwareRunner := installer.NewRunner(orbitClient, r.ExtensionSocketPath(), scriptsEnabledFn, c.String("root-dir"))
 	orbitClient.RegisterConfigReceiver(softwareRunner)
+	orbitClient.RegisterConfigReceiver(update.ApplyCacheProxyConfig(updateRunner))
 
 	if runtime.GOOS == "darwin" {
 		log.Info().Msgf("orbitClient.GetServerCapabilities() %+v", orbitClient.GetServerCapabilities())
@@ -2539,6 +2600,62 @@ func addSubsystem(g *run.Group, name string, s subSystem) {
 	)
 }
 
+// cacheProxyStatsInterval is the interval at which fleetd in caching proxy
+// mode reports its statistics to Fleet.
+const cacheProxyStatsInterval = time.Minute
+
+// cacheProxyServer serves fleetd's caching proxy over TLS and reports its
+// statistics to Fleet.
+type cacheProxyServer struct {
+	server            *http.Server
+	certFile, keyFile string
+	proxy             *cacheproxy.Proxy
+	reporter          cacheproxy.StatsReporter
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newCacheProxyServer(addr, certFile, keyFile string, proxy *cacheproxy.Proxy, reporter cacheproxy.StatsReporter) *cacheProxyServer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &cacheProxyServer{
+		server: &http.Server{
+			Addr:              addr,
+			Handler:           proxy,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+		certFile: certFile,
+		keyFile:  keyFile,
+		proxy:    proxy,
+		reporter: reporter,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Execute partially implements subSystem.
+func (s *cacheProxyServer) Execute() error {
+	go s.proxy.ReportStats(s.ctx, s.reporter, cacheProxyStatsInterval)
+
+	log.Info().Str("addr", s.server.Addr).Msg("starting cache proxy")
+	if err := s.server.ListenAndServeTLS(s.certFile, s.keyFile); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		// The hosts fall back to downloading from Fleet, so this doesn't stop fleetd.
+		log.Error().Err(err).Msg("cache proxy server")
+		<-s.ctx.Done()
+	}
+	return nil
+}
+
+// Interrupt partially implements subSystem.
+func (s *cacheProxyServer) Interrupt(err error) {
+	s.cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Debug().Err(err).Msg("shutdown cache proxy server")
+	}
+}
+
 // wrapSubsystem wraps functions to implement the subSystem interface.
 type wrapSubsystem struct {
 	execute   func() error
@@ -0,0 +1,218 @@
+package cacheproxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/fleetdm/fleet/v4/pkg/secure"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// nameSuffix is the suffix of the file holding the original filename of a
+	// cached file.
+	nameSuffix = ".name"
+	// tmpPrefix is the prefix of the files being written to the cache.
+	tmpPrefix = "tmp-"
+)
+
+var sha256Regexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// validHash returns the normalized form of a hex-encoded SHA256, and false
+// if it isn't one. Only normalized hashes are used as file names.
+func validHash(hash string) (string, bool) {
+	hash = strings.ToLower(hash)
+	return hash, sha256Regexp.MatchString(hash)
+}
+
+// errHashMismatch is returned when the content written to the cache doesn't
+// match its expected SHA256.
+var errHashMismatch = errors.New("content sha256 mismatch")
+
+// Cache is a content-addressed disk cache: each file is stored under the
+// SHA256 of its content, which is verified when the file is added. When the
+// size of the cached files exceeds the maximum size, the least recently used
+// files are evicted.
+type Cache struct {
+	dir     string
+	maxSize int64
+
+	// evictMu serializes the evictions.
+	evictMu sync.Mutex
+}
+
+// NewCache creates a cache in dir, creating the directory if needed. A
+// maxSize of zero or less means that files are never evicted.
+func NewCache(dir string, maxSize int64) (*Cache, error) {
+	if err := secure.MkdirAll(dir, constant.DefaultDirMode); err != nil {
+		return nil, fmt.Errorf("create cache directory: %w", err)
+	}
+	// remove the leftovers of writes interrupted by a restart
+	tmps, err := filepath.Glob(filepath.Join(dir, tmpPrefix+"*"))
+	if err != nil {
+		return nil, fmt.Errorf("list temporary files: %w", err)
+	}
+	for _, tmp := range tmps {
+		_ = os.Remove(tmp)
+	}
+	return &Cache{dir: dir, maxSize: maxSize}, nil
+}
+
+func (c *Cache) path(hash string) string {
+	return filepath.Join(c.dir, hash)
+}
+
+// Open opens the cached file with the given SHA256, along with its original
+// filename. It returns an error satisfying errors.Is(err, fs.ErrNotExist) if
+// the file is not cached.
+func (c *Cache) Open(hash string) (*os.File, string, error) {
+	hash, ok := validHash(hash)
+	if !ok {
+		return nil, "", fs.ErrNotExist
+	}
+	f, err := os.Open(c.path(hash))
+	if err != nil {
+		return nil, "", err
+	}
+	name, err := os.ReadFile(c.path(hash) + nameSuffix)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		f.Close()
+		return nil, "", err
+	}
+	// mark the file as recently used
+	now := time.Now()
+	if err := os.Chtimes(c.path(hash), now, now); err != nil {
+		log.Debug().Err(err).Str("hash", hash).Msg("cache proxy: touch cached file")
+	}
+	return f, string(name), nil
+}
+
+// Put adds the content read from r to the cache under the given SHA256,
+// along with its original filename. The content is not added if its SHA256
+// doesn't match, in which case errHashMismatch is returned. It returns the
+// number of bytes read from r.
+func (c *Cache) Put(hash string, filename string, r io.Reader) (int64, error) {
+	hash, ok := validHash(hash)
+	if !ok {
+		return 0, fmt.Errorf("invalid sha256 %q", hash)
+	}
+	tmp, err := os.CreateTemp(c.dir, tmpPrefix+"*")
+	if err != nil {
+		return 0, fmt.Errorf("create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, fmt.Errorf("write temporary file: %w", err)
+	}
+	if hex.EncodeToString(h.Sum(nil)) != hash {
+		return n, errHashMismatch
+	}
+
+	if filename != "" {
+		if err := os.WriteFile(c.path(hash)+nameSuffix, []byte(filename), constant.DefaultFileMode); err != nil {
+			return n, fmt.Errorf("write filename: %w", err)
+		}
+	}
+	if err := os.Rename(tmp.Name(), c.path(hash)); err != nil {
+		return n, fmt.Errorf("rename temporary file: %w", err)
+	}
+
+	c.evict(hash)
+	return n, nil
+}
+
+type cachedFile struct {
+	hash    string
+	size    int64
+	modTime time.Time
+}
+
+// list returns the cached files.
+func (c *Cache) list() ([]cachedFile, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []cachedFile
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !sha256Regexp.MatchString(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			// removed since the directory was read
+			continue
+		}
+		files = append(files, cachedFile{hash: e.Name(), size: info.Size(), modTime: info.ModTime()})
+	}
+	return files, nil
+}
+
+// Usage returns the number and the total size of the cached files.
+func (c *Cache) Usage() (items uint64, size uint64, err error) {
+	files, err := c.list()
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, f := range files {
+		items++
+		size += uint64(f.size) // nolint:gosec // dismiss G115
+	}
+	return items, size, nil
+}
+
+// evict removes the least recently used files until the size of the cache
+// is below its maximum size. The file with the keep hash, just added, is
+// never removed.
+func (c *Cache) evict(keep string) {
+	if c.maxSize <= 0 {
+		return
+	}
+	c.evictMu.Lock()
+	defer c.evictMu.Unlock()
+
+	files, err := c.list()
+	if err != nil {
+		log.Info().Err(err).Msg("cache proxy: list cached files for eviction")
+		return
+	}
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		if total <= c.maxSize {
+			return
+		}
+		if f.hash == keep {
+			continue
+		}
+		if err := os.Remove(c.path(f.hash)); err != nil {
+			// e.g. the file is being served on Windows, it will be evicted later
+			log.Debug().Err(err).Str("hash", f.hash).Msg("cache proxy: evict cached file")
+			continue
+		}
+		_ = os.Remove(c.path(f.hash) + nameSuffix)
+		total -= f.size
+		log.Debug().Str("hash", f.hash).Int64("size", f.size).Msg("cache proxy: evicted cached file")
+	}
+}
@@ -0,0 +1,113 @@
+package cacheproxy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func hashOf(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	// leftovers of an interrupted write are removed
+	require.NoError(t, os.WriteFile(filepath.Join(dir, tmpPrefix+"123"), []byte("partial"), 0o600))
+
+	c, err := NewCache(dir, 0)
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(dir, tmpPrefix+"123"))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, _, err = c.Open(hashOf("foo"))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	_, _, err = c.Open("../../etc/passwd")
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	n, err := c.Put(hashOf("foo"), "foo.pkg", strings.NewReader("foo"))
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, n)
+
+	// the hash is case-insensitive
+	f, name, err := c.Open(strings.ToUpper(hashOf("foo")))
+	require.NoError(t, err)
+	b, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, "foo", string(b))
+	assert.Equal(t, "foo.pkg", name)
+
+	// content that doesn't match its hash is not cached
+	_, err = c.Put(hashOf("bar"), "bar.pkg", strings.NewReader("tampered"))
+	require.ErrorIs(t, err, errHashMismatch)
+	_, _, err = c.Open(hashOf("bar"))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = c.Put("not-a-hash", "", strings.NewReader("bar"))
+	require.Error(t, err)
+
+	items, size, err := c.Usage()
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, items)
+	assert.EqualValues(t, 3, size)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2) // the file and its name
+}
+
+func TestCacheEviction(t *testing.T) {
+	c, err := NewCache(t.TempDir(), 10)
+	require.NoError(t, err)
+
+	put := func(content string) {
+		_, err := c.Put(hashOf(content), content, strings.NewReader(content))
+		require.NoError(t, err)
+	}
+	setUsed := func(content string, at time.Time) {
+		require.NoError(t, os.Chtimes(c.path(hashOf(content)), at, at))
+	}
+	cached := func(content string) bool {
+		f, _, err := c.Open(hashOf(content))
+		if err != nil {
+			require.ErrorIs(t, err, fs.ErrNotExist)
+			return false
+		}
+		require.NoError(t, f.Close())
+		return true
+	}
+
+	now := time.Now()
+	put("aaaa")
+	setUsed("aaaa", now.Add(-3*time.Hour))
+	put("bbbb")
+	setUsed("bbbb", now.Add(-2*time.Hour))
+	// using aaaa makes bbbb the least recently used file
+	require.True(t, cached("aaaa"))
+
+	put("cccc")
+	assert.False(t, cached("bbbb"))
+	assert.True(t, cached("aaaa"))
+	assert.True(t, cached("cccc"))
+	_, err = os.Stat(c.path(hashOf("bbbb")) + nameSuffix)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	// a file larger than the cache is kept until the next one is added
+	put("larger than the cache")
+	assert.True(t, cached("larger than the cache"))
+	assert.False(t, cached("aaaa"))
+	assert.False(t, cached("cccc"))
+	put("dddd")
+	assert.False(t, cached("larger than the cache"))
+	assert.True(t, cached("dddd"))
+}
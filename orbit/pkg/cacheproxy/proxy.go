@@ -0,0 +1,260 @@
+// Package cacheproxy implements the caching proxy mode of fleetd, where a
+// host serves the software installers and the TUF targets to the other hosts
+// of its network, so that each file crosses the link to Fleet and to the
+// update server once.
+//
+// The proxy is content-addressed: the clients send the expected SHA256 of the
+// content, which they get from Fleet (installers) or from the signed TUF
+// metadata (targets). The proxy only caches content matching that SHA256, and
+// the clients verify it again once downloaded. The clients don't send any
+// credentials to the proxy: it downloads the installers from Fleet with its
+// own node key, and Fleet decides which installers the proxy may download.
+package cacheproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// maxErrorBodySize bounds the body of the upstream error responses
+	// relayed to the clients.
+	maxErrorBodySize = 64 * 1024
+	// upstreamTimeout bounds the download of a file from Fleet or from the
+	// update server.
+	upstreamTimeout = 4 * time.Hour
+)
+
+// FleetClient is the client used to download the software installers from
+// Fleet, authenticated as the host running the proxy.
+type FleetClient interface {
+	DownloadSoftwareInstallerForCacheProxy(ctx context.Context, sha256Hex string) (*http.Response, error)
+}
+
+// Options are the options of a Proxy.
+type Options struct {
+	// Cache is where the downloaded files are stored.
+	Cache *Cache
+	// FleetClient is used to download the software installers from Fleet.
+	FleetClient FleetClient
+	// UpdateURL is the URL of the update server, used to download the TUF
+	// targets. TUF targets are not served if it is empty.
+	UpdateURL string
+	// UpdateClient is the HTTP client used to connect to the update server.
+	UpdateClient *http.Client
+}
+
+// Proxy is the HTTP handler of fleetd in caching proxy mode.
+type Proxy struct {
+	opt   Options
+	group singleflight.Group
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	hitBytes  atomic.Uint64
+	missBytes atomic.Uint64
+}
+
+// New returns a new Proxy.
+func New(opt Options) *Proxy {
+	opt.UpdateURL = strings.TrimRight(opt.UpdateURL, "/")
+	return &Proxy{opt: opt}
+}
+
+// upstreamError is an error response of Fleet or of the update server,
+// relayed to the client.
+type upstreamError struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+func (e *upstreamError) Error() string {
+	return fmt.Sprintf("upstream returned status %d", e.status)
+}
+
+// fetchFunc downloads a file from upstream, the caller closes the response
+// body.
+type fetchFunc func(ctx context.Context) (*http.Response, error)
+
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	hash, ok := validHash(r.Header.Get(constant.CacheProxyContentSHA256Header))
+	if !ok {
+		http.Error(w, "missing or invalid "+constant.CacheProxyContentSHA256Header+" header", http.StatusBadRequest)
+		return
+	}
+
+	var fetch fetchFunc
+	var filename string
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == constant.CacheProxySoftwareInstallerPath:
+		fetch = func(ctx context.Context) (*http.Response, error) {
+			return p.opt.FleetClient.DownloadSoftwareInstallerForCacheProxy(ctx, hash)
+		}
+
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, constant.CacheProxyTUFTargetsPath) && p.opt.UpdateURL != "":
+		target := path.Clean(strings.TrimPrefix(r.URL.Path, constant.CacheProxyTUFTargetsPath))
+		if target == "." || strings.HasPrefix(target, "..") || strings.HasPrefix(target, "/") {
+			http.Error(w, "invalid target", http.StatusBadRequest)
+			return
+		}
+		filename = path.Base(target)
+		targetURL := p.opt.UpdateURL + "/targets/" + (&url.URL{Path: target}).EscapedPath()
+		fetch = func(ctx context.Context) (*http.Response, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+			if err != nil {
+				return nil, err
+			}
+			return p.opt.UpdateClient.Do(req)
+		}
+
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	p.serve(w, r, hash, filename, fetch)
+}
+
+// serve serves the cached file with the given SHA256, downloading it with
+// fetch first if it isn't cached yet. The concurrent requests for a file that
+// isn't cached wait for a single download.
+func (p *Proxy) serve(w http.ResponseWriter, r *http.Request, hash string, filename string, fetch fetchFunc) {
+	f, cachedName, err := p.opt.Cache.Open(hash)
+	fetched := false
+	if errors.Is(err, fs.ErrNotExist) {
+		_, err, _ = p.group.Do(hash, func() (any, error) {
+			fetched = true
+			return nil, p.fetch(hash, filename, fetch)
+		})
+		if err == nil {
+			f, cachedName, err = p.opt.Cache.Open(hash)
+		}
+	}
+	if err != nil {
+		writeError(w, hash, err)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "cache proxy: stat cached file", http.StatusInternalServerError)
+		return
+	}
+	if !fetched {
+		p.hits.Add(1)
+		p.hitBytes.Add(uint64(info.Size())) // nolint:gosec // dismiss G115
+	}
+
+	if cachedName != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": cachedName}))
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
+
+// writeError writes the response of a failed request, relaying the upstream
+// error responses as is.
+func writeError(w http.ResponseWriter, hash string, err error) {
+	var upErr *upstreamError
+	if errors.As(err, &upErr) {
+		if upErr.contentType != "" {
+			w.Header().Set("Content-Type", upErr.contentType)
+		}
+		w.WriteHeader(upErr.status)
+		_, _ = w.Write(upErr.body)
+		return
+	}
+	log.Info().Err(err).Str("hash", hash).Msg("cache proxy: serve file")
+	http.Error(w, "cache proxy: "+err.Error(), http.StatusBadGateway)
+}
+
+// fetch downloads a file from upstream and adds it to the cache. The download
+// is detached from the request that triggered it, as other requests may be
+// waiting for it.
+func (p *Proxy) fetch(hash string, filename string, fetch fetchFunc) error {
+	ctx, cancel := context.WithTimeout(context.Background(), upstreamTimeout)
+	defer cancel()
+
+	resp, err := fetch(ctx)
+	if err != nil {
+		return fmt.Errorf("download from upstream: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return &upstreamError{status: resp.StatusCode, contentType: resp.Header.Get("Content-Type"), body: body}
+	}
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		filename = path.Base(params["filename"])
+	}
+
+	log.Info().Str("hash", hash).Str("filename", filename).Msg("cache proxy: downloading file")
+	n, err := p.opt.Cache.Put(hash, filename, resp.Body)
+	p.misses.Add(1)
+	p.missBytes.Add(uint64(n)) // nolint:gosec // dismiss G115
+	if err != nil {
+		return fmt.Errorf("cache downloaded file: %w", err)
+	}
+	return nil
+}
+
+// Stats returns the statistics of the proxy since it started.
+func (p *Proxy) Stats() (fleet.CacheProxyStats, error) {
+	items, size, err := p.opt.Cache.Usage()
+	if err != nil {
+		return fleet.CacheProxyStats{}, fmt.Errorf("get cache usage: %w", err)
+	}
+	return fleet.CacheProxyStats{
+		Hits:        p.hits.Load(),
+		Misses:      p.misses.Load(),
+		HitBytes:    p.hitBytes.Load(),
+		MissBytes:   p.missBytes.Load(),
+		CachedItems: items,
+		CachedBytes: size,
+	}, nil
+}
+
+// StatsReporter is the client used to report the statistics of the proxy to
+// Fleet.
+type StatsReporter interface {
+	ReportCacheProxyStats(stats fleet.CacheProxyStats) error
+}
+
+// ReportStats reports the statistics of the proxy to Fleet at each interval,
+// until ctx is canceled.
+func (p *Proxy) ReportStats(ctx context.Context, reporter StatsReporter, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		stats, err := p.Stats()
+		if err == nil {
+			err = reporter.ReportCacheProxyStats(stats)
+		}
+		if err != nil {
+			log.Info().Err(err).Msg("cache proxy: report stats")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
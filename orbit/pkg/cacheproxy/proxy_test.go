@@ -0,0 +1,222 @@
+package cacheproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFleetClient downloads the installers from an httptest server standing
+// for Fleet, with the node key of the proxy.
+type testFleetClient struct {
+	url     string
+	nodeKey string
+}
+
+func (c *testFleetClient) DownloadSoftwareInstallerForCacheProxy(ctx context.Context, sha256Hex string) (*http.Response, error) {
+	body, err := json.Marshal(fleet.OrbitCacheProxyDownloadSoftwareInstallerRequest{OrbitNodeKey: c.nodeKey, HashSHA256: sha256Hex})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/api/fleet/orbit/cache_proxy/software_install/package?alt=media",
+		bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	return http.DefaultClient.Do(req)
+}
+
+type testStatsReporter struct {
+	mu    sync.Mutex
+	stats []fleet.CacheProxyStats
+}
+
+func (r *testStatsReporter) ReportCacheProxyStats(stats fleet.CacheProxyStats) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stats = append(r.stats, stats)
+	return nil
+}
+
+func TestProxy(t *testing.T) {
+	const installer = "installer content"
+	const target = "osqueryd binary"
+
+	var fleetCalls, updateCalls atomic.Int32
+	var fleetContent atomic.Value
+	fleetContent.Store(installer)
+	// allowedHashes are the SHA256 of the installers that the proxy may
+	// download
+	allowedHashes := map[string]bool{hashOf(installer): true, hashOf("other installer"): true}
+	// release is closed to let Fleet respond, to test concurrent misses
+	release := make(chan struct{})
+	fleetSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fleetCalls.Add(1)
+		<-release
+		var req fleet.OrbitCacheProxyDownloadSoftwareInstallerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.URL.Query().Get("alt") != "media" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case req.OrbitNodeKey != "proxy-key":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"error":"unauthorized"}`)
+			return
+		case !allowedHashes[req.HashSHA256]:
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `{"error":"forbidden"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="../foo.pkg"`)
+		_, _ = io.WriteString(w, fleetContent.Load().(string))
+	}))
+	defer fleetSrv.Close()
+	updateSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		updateCalls.Add(1)
+		if r.URL.Path != "/targets/osqueryd/linux/stable/osqueryd" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = io.WriteString(w, target)
+	}))
+	defer updateSrv.Close()
+
+	cache, err := NewCache(t.TempDir(), 0)
+	require.NoError(t, err)
+	proxy := New(Options{
+		Cache:        cache,
+		FleetClient:  &testFleetClient{url: fleetSrv.URL, nodeKey: "proxy-key"},
+		UpdateURL:    updateSrv.URL + "/",
+		UpdateClient: updateSrv.Client(),
+	})
+	srv := httptest.NewServer(proxy)
+	defer srv.Close()
+
+	downloadInstaller := func(hash string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+constant.CacheProxySoftwareInstallerPath, nil)
+		require.NoError(t, err)
+		req.Header.Set(constant.CacheProxyContentSHA256Header, hash)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(b)
+	}
+	downloadTarget := func(name, hash string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+constant.CacheProxyTUFTargetsPath+name, nil)
+		require.NoError(t, err)
+		req.Header.Set(constant.CacheProxyContentSHA256Header, hash)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(b)
+	}
+
+	// concurrent misses wait for a single download from Fleet
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			resp, body := downloadInstaller(hashOf(installer))
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, installer, body)
+			_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
+			assert.NoError(t, err)
+			assert.Equal(t, "foo.pkg", params["filename"])
+		})
+	}
+	require.Eventually(t, func() bool { return fleetCalls.Load() > 0 }, 5*time.Second, 10*time.Millisecond)
+	// give the other requests time to join the download
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.EqualValues(t, 1, fleetCalls.Load())
+
+	// cached installers are served without downloading them from Fleet
+	resp, body := downloadInstaller(hashOf(installer))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, installer, body)
+	assert.EqualValues(t, 1, fleetCalls.Load())
+
+	// the installers that Fleet doesn't let the proxy download are not
+	// served, and Fleet errors are relayed
+	resp, body = downloadInstaller(hashOf("unknown installer"))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.JSONEq(t, `{"error":"forbidden"}`, body)
+	_, _, err = cache.Open(hashOf("unknown installer"))
+	require.Error(t, err)
+
+	// content that doesn't match the hash is not served nor cached
+	fleetContent.Store("tampered content")
+	resp, _ = downloadInstaller(hashOf("other installer"))
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	_, _, err = cache.Open(hashOf("other installer"))
+	require.Error(t, err)
+
+	// TUF targets
+	resp, body = downloadTarget("osqueryd/linux/stable/osqueryd", hashOf(target))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, target, body)
+	resp, body = downloadTarget("osqueryd/linux/stable/osqueryd", hashOf(target))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, target, body)
+	assert.EqualValues(t, 1, updateCalls.Load())
+	resp, _ = downloadTarget("unknown", hashOf("unknown"))
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	// invalid requests
+	resp, _ = downloadTarget("osqueryd/linux/stable/osqueryd", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = downloadTarget("%2e%2e/secret", hashOf(target))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = downloadInstaller("not-a-hash")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	stats, err := proxy.Stats()
+	require.NoError(t, err)
+	installerLen, targetLen := uint64(len(installer)), uint64(len(target))
+	assert.Equal(t, fleet.CacheProxyStats{
+		// 4 concurrent and 1 later requests for the installer, 1 for the target
+		Hits:     6,
+		HitBytes: 5*installerLen + targetLen,
+		// the installer, the tampered installer and the target
+		Misses:      3,
+		MissBytes:   installerLen + uint64(len("tampered content")) + targetLen,
+		CachedItems: 2,
+		CachedBytes: installerLen + targetLen,
+	}, stats)
+
+	reporter := &testStatsReporter{}
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		proxy.ReportStats(ctx, reporter, time.Hour)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		reporter.mu.Lock()
+		defer reporter.mu.Unlock()
+		return len(reporter.stats) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+	assert.Equal(t, stats, reporter.stats[0])
+}
@@ -89,4 +89,13 @@ const (
 
 	// UnusedFlagKeyword is used by the MSI builder and installer to populate parameters, which cannot be empty.
 	UnusedFlagKeyword = "dummy"
+
+	// CacheProxyContentSHA256Header is the header that fleetd sets on the requests to a fleetd
+	// caching proxy with the expected SHA256 of the content, used as the cache key.
+	CacheProxyContentSHA256Header = "X-Fleet-Content-SHA256"
+	// CacheProxySoftwareInstallerPath is the path of the software installers served by a fleetd
+	// caching proxy, the installer is identified by the CacheProxyContentSHA256Header header.
+	CacheProxySoftwareInstallerPath = "/software_install/package"
+	// CacheProxyTUFTargetsPath is the path prefix of the TUF targets served by a fleetd caching proxy.
+	CacheProxyTUFTargetsPath = "/tuf/targets/"
 )
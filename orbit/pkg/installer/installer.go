@@ -42,6 +42,7 @@ type Client interface {
 	GetInstallerDetails(installID string) (*fleet.SoftwareInstallDetails, error)
 	DownloadSoftwareInstaller(installerID uint, downloadDir string, progressFunc func(int)) (string, error)
 	DownloadSoftwareInstallerFromURL(url string, filename string, downloadDir string, progressFunc func(int)) (string, error)
+	DownloadSoftwareInstallerFromCacheProxy(proxyURL string, sha256 string, downloadDir string, progressFunc func(int)) (string, error)
 	SaveInstallerResult(payload *fleet.HostSoftwareInstallResultPayload) error
 }
 
@@ -97,6 +98,10 @@ type Runner struct {
 	// installerNotFoundMu.
 	installerNotFoundFirstSeen map[string]time.Time
 	installerNotFoundMu        sync.Mutex
+
+	// cacheProxyURL is the URL of the fleetd caching proxy to download the
+	// installers from, set from the orbit config at the start of each run.
+	cacheProxyURL string
 }
 
 const extractionDirectoryName = "extracted"
@@ -158,6 +163,8 @@ func connectOsquery(r *Runner) error {
 }
 
 func (r *Runner) run(ctx context.Context, config *fleet.OrbitConfig) error {
+	r.cacheProxyURL = config.CacheProxyURL
+
 	if len(config.Notifications.PendingSoftwareInstallerIDs) > 0 {
 		r.logger.Info().Msgf("received notification for software installers: %v", config.Notifications.PendingSoftwareInstallerIDs)
 	} else {
@@ -436,7 +443,22 @@ func (r *Runner) attemptInstall(ctx context.Context, installer *fleet.SoftwareIn
 	}
 
 	var installerPath string
-	if installer.SoftwareInstallerURL != nil && installer.SoftwareInstallerURL.URL != "" {
+	if r.cacheProxyURL != "" && installer.HashSHA256 != "" {
+		logger.Info().Str("proxyURL", r.cacheProxyURL).Msg("about to download software installer from cache proxy")
+		installerPath, err = r.OrbitClient.DownloadSoftwareInstallerFromCacheProxy(
+			r.cacheProxyURL,
+			installer.HashSHA256,
+			tmpDir,
+			progressFn(),
+		)
+		if err != nil {
+			logger.Err(err).Msg("downloading software installer from cache proxy")
+			// If download fails, we will fall back to downloading the installer from the URL or Fleet server
+			installerPath = ""
+		}
+	}
+
+	if installerPath == "" && installer.SoftwareInstallerURL != nil && installer.SoftwareInstallerURL.URL != "" {
 		logger.Info().Msg("about to download software installer from URL")
 		installerPath, err = r.OrbitClient.DownloadSoftwareInstallerFromURL(
 			installer.SoftwareInstallerURL.URL,
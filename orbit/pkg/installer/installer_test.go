@@ -26,16 +26,21 @@ import (
 )
 
 type TestOrbitClient struct {
-	downloadInstallerFn        func(uint, string) (string, error)
-	downloadInstallerFromURLFn func(url string, filename string, downloadDir string) (string, error)
-	getInstallerDetailsFn      func(string) (*fleet.SoftwareInstallDetails, error)
-	saveInstallerResultFn      func(*fleet.HostSoftwareInstallResultPayload) error
+	downloadInstallerFn          func(uint, string) (string, error)
+	downloadInstallerFromURLFn   func(url string, filename string, downloadDir string) (string, error)
+	downloadInstallerFromProxyFn func(proxyURL string, sha256 string, downloadDir string) (string, error)
+	getInstallerDetailsFn        func(string) (*fleet.SoftwareInstallDetails, error)
+	saveInstallerResultFn        func(*fleet.HostSoftwareInstallResultPayload) error
 }
 
 func (oc *TestOrbitClient) DownloadSoftwareInstallerFromURL(url string, filename string, downloadDir string, progressFunc func(int)) (string, error) {
 	return oc.downloadInstallerFromURLFn(url, filename, downloadDir)
 }
 
+func (oc *TestOrbitClient) DownloadSoftwareInstallerFromCacheProxy(proxyURL string, sha256 string, downloadDir string, progressFunc func(int)) (string, error) {
+	return oc.downloadInstallerFromProxyFn(proxyURL, sha256, downloadDir)
+}
+
 func (oc *TestOrbitClient) DownloadSoftwareInstaller(installerID uint, downloadDir string, progressFunc func(int)) (string, error) {
 	return oc.downloadInstallerFn(installerID, downloadDir)
 }
@@ -608,6 +613,15 @@ func TestInstallerRunWithInstallerFromURL(t *testing.T) {
 	}
 	oc.downloadInstallerFn = downloadInstallerDefaultFn
 
+	var downloadInstallerFromProxyFnCalled bool
+	downloadInstallerFromProxyDefaultFn := func(proxyURL string, sha256 string, downloadDir string) (string, error) {
+		assert.Equal(t, "https://proxy.example.com", proxyURL)
+		assert.Equal(t, installDetails.HashSHA256, sha256)
+		downloadInstallerFromProxyFnCalled = true
+		return filepath.Join(downloadDir, "proxied.pkg"), nil
+	}
+	oc.downloadInstallerFromProxyFn = downloadInstallerFromProxyDefaultFn
+
 	var savedInstallerResult *fleet.HostSoftwareInstallResultPayload
 	oc.saveInstallerResultFn = func(hsirp *fleet.HostSoftwareInstallResultPayload) error {
 		savedInstallerResult = hsirp
@@ -631,6 +645,8 @@ func TestInstallerRunWithInstallerFromURL(t *testing.T) {
 		downloadInstallerFnCalled = false
 		downloadInstallerFromURLFnCalled = false
 		oc.downloadInstallerFromURLFn = downloadInstallerFromURLDefaultFn
+		downloadInstallerFromProxyFnCalled = false
+		oc.downloadInstallerFromProxyFn = downloadInstallerFromProxyDefaultFn
 		savedInstallerResult = nil
 	}
 
@@ -687,6 +703,7 @@ func TestInstallerRunWithInstallerFromURL(t *testing.T) {
 
 	resetConfig := func() {
 		config.Notifications.PendingSoftwareInstallerIDs = []string{installDetails.ExecutionID}
+		config.CacheProxyURL = ""
 	}
 
 	resetAll := func() {
@@ -768,6 +785,49 @@ func TestInstallerRunWithInstallerFromURL(t *testing.T) {
 		assert.True(t, getInstallerDetailsFnCalled)
 		assert.Equal(t, installDetails.ExecutionID, installIdRequested)
 	})
+
+	t.Run("cache proxy is used first", func(t *testing.T) {
+		resetAll()
+		config.CacheProxyURL = "https://proxy.example.com"
+		installDetails.HashSHA256 = "abc"
+
+		err := r.run(context.Background(), &config)
+		require.NoError(t, err)
+
+		require.Contains(t, execEnv, "INSTALLER_PATH="+filepath.Join(tmpDir, "proxied.pkg"))
+		assert.True(t, downloadInstallerFromProxyFnCalled)
+		assert.False(t, downloadInstallerFromURLFnCalled)
+		assert.False(t, downloadInstallerFnCalled)
+	})
+
+	t.Run("cache proxy fails and we fall back to CDN download", func(t *testing.T) {
+		resetAll()
+		config.CacheProxyURL = "https://proxy.example.com"
+		installDetails.HashSHA256 = "abc"
+		oc.downloadInstallerFromProxyFn = func(proxyURL string, sha256 string, downloadDir string) (string, error) {
+			downloadInstallerFromProxyFnCalled = true
+			return "", errors.New("test error")
+		}
+
+		err := r.run(context.Background(), &config)
+		require.NoError(t, err)
+
+		require.Contains(t, execEnv, "INSTALLER_PATH="+filepath.Join(tmpDir, installDetails.SoftwareInstallerURL.Filename))
+		assert.True(t, downloadInstallerFromProxyFnCalled)
+		assert.True(t, downloadInstallerFromURLFnCalled)
+		assert.False(t, downloadInstallerFnCalled)
+	})
+
+	t.Run("cache proxy is not used without the installer hash", func(t *testing.T) {
+		resetAll()
+		config.CacheProxyURL = "https://proxy.example.com"
+
+		err := r.run(context.Background(), &config)
+		require.NoError(t, err)
+
+		assert.False(t, downloadInstallerFromProxyFnCalled)
+		assert.True(t, downloadInstallerFromURLFnCalled)
+	})
 }
 
 func TestScriptsDisabled(t *testing.T) {
@@ -0,0 +1,162 @@
+package update
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/rs/zerolog/log"
+	"github.com/theupdateframework/go-tuf/client"
+	"github.com/theupdateframework/go-tuf/data"
+)
+
+// cacheProxyRemoteStore is a TUF remote store that downloads the targets
+// through a fleetd caching proxy when one is set, and from the update server
+// otherwise. The metadata is always fetched from the update server.
+//
+// A target fetched from the proxy is checked against the length and SHA256 of
+// the signed targets metadata before being handed to go-tuf (which verifies it
+// again), so that a proxy serving bad content falls back to the update server
+// instead of failing the update.
+type cacheProxyRemoteStore struct {
+	client.RemoteStore
+
+	httpClient *http.Client
+	// targets returns the targets of the verified local metadata. It is called
+	// by go-tuf's Download, with Updater.clientMu held.
+	targets func() (data.TargetFiles, error)
+	// tmpDir is where the targets are buffered while being verified.
+	tmpDir string
+
+	mu       sync.Mutex
+	proxyURL string
+}
+
+func (s *cacheProxyRemoteStore) setProxyURL(proxyURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.proxyURL = strings.TrimRight(proxyURL, "/")
+}
+
+func (s *cacheProxyRemoteStore) getProxyURL() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.proxyURL
+}
+
+// GetTarget implements client.RemoteStore.
+func (s *cacheProxyRemoteStore) GetTarget(name string) (io.ReadCloser, int64, error) {
+	if proxyURL := s.getProxyURL(); proxyURL != "" {
+		rc, size, err := s.getTargetFromProxy(proxyURL, name)
+		if err == nil {
+			return rc, size, nil
+		}
+		log.Info().Err(err).Str("target", name).Msg("download from cache proxy failed, falling back to update server")
+	}
+	return s.RemoteStore.GetTarget(name)
+}
+
+func (s *cacheProxyRemoteStore) getTargetFromProxy(proxyURL, name string) (io.ReadCloser, int64, error) {
+	targets, err := s.targets()
+	if err != nil {
+		return nil, 0, fmt.Errorf("get targets: %w", err)
+	}
+	meta, ok := targets[strings.TrimPrefix(name, "/")]
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown target %s", name)
+	}
+	expectedHash := meta.Hashes["sha256"]
+	if len(expectedHash) == 0 {
+		return nil, 0, errors.New("no sha256 hash in target metadata")
+	}
+
+	targetURL := proxyURL + constant.CacheProxyTUFTargetsPath + (&url.URL{Path: strings.TrimPrefix(name, "/")}).EscapedPath()
+	req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set(constant.CacheProxyContentSHA256Header, expectedHash.String())
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("cache proxy returned status %d", resp.StatusCode)
+	}
+
+	tmp, err := os.CreateTemp(s.tmpDir, "cache-proxy-*")
+	if err != nil {
+		return nil, 0, fmt.Errorf("create temp file: %w", err)
+	}
+	h := sha256.New()
+	// Read one byte past the expected length to detect oversized content.
+	n, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(resp.Body, meta.Length+1))
+	if err == nil && n != meta.Length {
+		err = fmt.Errorf("expected %d bytes, got %d", meta.Length, n)
+	}
+	if err == nil && !strings.EqualFold(hex.EncodeToString(h.Sum(nil)), expectedHash.String()) {
+		err = errors.New("sha256 mismatch")
+	}
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, 0, err
+	}
+	return &tempFileReadCloser{tmp}, n, nil
+}
+
+// tempFileReadCloser removes the file when closed.
+type tempFileReadCloser struct {
+	*os.File
+}
+
+func (f *tempFileReadCloser) Close() error {
+	err := f.File.Close()
+	if rmErr := os.Remove(f.Name()); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// SetCacheProxyURL sets the URL of the fleetd caching proxy used to download
+// the targets, an empty URL downloads them from the update server.
+func (u *Updater) SetCacheProxyURL(proxyURL string) {
+	if u.proxyStore == nil {
+		return
+	}
+	if u.proxyStore.getProxyURL() != strings.TrimRight(proxyURL, "/") {
+		log.Info().Str("url", proxyURL).Msg("setting TUF targets cache proxy")
+	}
+	u.proxyStore.setProxyURL(proxyURL)
+}
+
+type cacheProxyConfigReceiver struct {
+	updateRunner *Runner
+}
+
+// ApplyCacheProxyConfig returns a config receiver that sets the fleetd
+// caching proxy of the Runner's updater from the orbit config.
+func ApplyCacheProxyConfig(runner *Runner) fleet.OrbitConfigReceiver {
+	return &cacheProxyConfigReceiver{updateRunner: runner}
+}
+
+func (r *cacheProxyConfigReceiver) Run(cfg *fleet.OrbitConfig) error {
+	if cfg == nil || r.updateRunner == nil || r.updateRunner.updater == nil {
+		return nil
+	}
+	r.updateRunner.updater.SetCacheProxyURL(cfg.CacheProxyURL)
+	return nil
+}
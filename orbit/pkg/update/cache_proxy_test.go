@@ -0,0 +1,106 @@
+package update
+
+import (
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/orbit/pkg/constant"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/theupdateframework/go-tuf/data"
+)
+
+type staticRemoteStore struct {
+	content string
+	calls   int
+}
+
+func (s *staticRemoteStore) GetMeta(name string) (io.ReadCloser, int64, error) {
+	return nil, 0, os.ErrNotExist
+}
+
+func (s *staticRemoteStore) GetTarget(path string) (io.ReadCloser, int64, error) {
+	s.calls++
+	return io.NopCloser(strings.NewReader(s.content)), int64(len(s.content)), nil
+}
+
+func TestCacheProxyRemoteStore(t *testing.T) {
+	const content = "osqueryd binary"
+	const targetName = "osqueryd/linux/stable/osqueryd"
+	sum := sha256.Sum256([]byte(content))
+
+	var proxyContent string
+	var gotPath, gotHash string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotHash = r.Header.Get(constant.CacheProxyContentSHA256Header)
+		_, _ = io.WriteString(w, proxyContent)
+	}))
+	defer srv.Close()
+
+	direct := &staticRemoteStore{content: content}
+	tmpDir := t.TempDir()
+	store := &cacheProxyRemoteStore{
+		RemoteStore: direct,
+		httpClient:  srv.Client(),
+		tmpDir:      tmpDir,
+		targets: func() (data.TargetFiles, error) {
+			return data.TargetFiles{
+				targetName: {FileMeta: data.FileMeta{Length: int64(len(content)), Hashes: data.Hashes{"sha256": sum[:]}}},
+			}, nil
+		},
+	}
+	readTarget := func(name string) string {
+		rc, size, err := store.GetTarget(name)
+		require.NoError(t, err)
+		defer rc.Close()
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		require.EqualValues(t, len(b), size)
+		return string(b)
+	}
+
+	// without a proxy, the target is downloaded from the update server
+	assert.Equal(t, content, readTarget(targetName))
+	assert.Equal(t, 1, direct.calls)
+	assert.Empty(t, gotPath)
+
+	updater := &Updater{proxyStore: store}
+	updater.SetCacheProxyURL(srv.URL + "/")
+
+	// the proxy serves the expected content
+	proxyContent = content
+	assert.Equal(t, content, readTarget(targetName))
+	assert.Equal(t, 1, direct.calls)
+	assert.Equal(t, constant.CacheProxyTUFTargetsPath+targetName, gotPath)
+	assert.Equal(t, data.HexBytes(sum[:]).String(), gotHash)
+
+	// the proxy serves bad content, the update server is used
+	proxyContent = "tampered binary"
+	assert.Equal(t, content, readTarget(targetName))
+	assert.Equal(t, 2, direct.calls)
+	proxyContent = content + "!"
+	assert.Equal(t, content, readTarget(targetName))
+	assert.Equal(t, 3, direct.calls)
+
+	// unknown targets are downloaded from the update server
+	gotPath = ""
+	assert.Equal(t, content, readTarget("other"))
+	assert.Equal(t, 4, direct.calls)
+	assert.Empty(t, gotPath)
+
+	// the buffered targets are removed
+	entries, err := os.ReadDir(tmpDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// the config receiver unsets the proxy
+	require.NoError(t, ApplyCacheProxyConfig(&Runner{updater: updater}).Run(&fleet.OrbitConfig{}))
+	assert.Empty(t, store.getProxyURL())
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"io/fs"
+	"net/http"
 	"os"
 	"os/exec"
 	"path"
@@ -79,6 +80,10 @@ type Updater struct {
 	clientMu sync.Mutex
 
 	retryer *retry.LimitedWithCooldown
+
+	// proxyStore is the remote store of client, used to download the targets
+	// through a fleetd caching proxy.
+	proxyStore *cacheProxyRemoteStore
 }
 
 // Options are the options that can be provided when creating an Updater.
@@ -153,11 +158,21 @@ func NewUpdater(opt Options) (*Updater, error) {
 		return nil, errors.New("opt.LocalStore must be non-nil")
 	}
 
-	remoteStore, err := createTUFRemoteStore(opt, opt.ServerURL)
+	httpClient, err := NewHTTPClient(opt)
 	if err != nil {
 		return nil, fmt.Errorf("get tls config: %w", err)
 	}
-	tufClient := client.NewClient(opt.LocalStore, remoteStore)
+	remoteStore, err := createTUFRemoteStore(httpClient, opt.ServerURL)
+	if err != nil {
+		return nil, err
+	}
+	proxyStore := &cacheProxyRemoteStore{
+		RemoteStore: remoteStore,
+		httpClient:  httpClient,
+		tmpDir:      filepath.Join(opt.RootDirectory, stagingDir),
+	}
+	tufClient := client.NewClient(opt.LocalStore, proxyStore)
+	proxyStore.targets = tufClient.Targets
 
 	// TODO(lucas): Related to the NOTE below.
 	//
@@ -190,7 +205,8 @@ func NewUpdater(opt Options) (*Updater, error) {
 		client: tufClient,
 		// per product spec, retry up to three consecutive times, then
 		// wait 24 hours to try again.
-		retryer: retry.NewLimitedWithCooldown(3, 24*time.Hour),
+		retryer:    retry.NewLimitedWithCooldown(3, 24*time.Hour),
+		proxyStore: proxyStore,
 	}
 
 	if err := updater.initializeDirectories(); err != nil {
@@ -826,7 +842,9 @@ func CanRun(rootDirPath, targetName string, targetInfo TargetInfo) bool {
 	return true
 }
 
-func createTUFRemoteStore(opt Options, serverURL string) (client.RemoteStore, error) {
+// NewHTTPClient returns the HTTP client used to connect to the update server
+// configured in opt.
+func NewHTTPClient(opt Options) (*http.Client, error) {
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: opt.InsecureTransport,
 	}
@@ -840,10 +858,13 @@ func createTUFRemoteStore(opt Options, serverURL string) (client.RemoteStore, er
 	if opt.ClientCertificate != nil {
 		tlsConfig.Certificates = []tls.Certificate{*opt.ClientCertificate}
 	}
+	return fleethttp.NewClient(fleethttp.WithTLSClientConfig(tlsConfig)), nil
+}
+
+func createTUFRemoteStore(httpClient *http.Client, serverURL string) (client.RemoteStore, error) {
 	remoteOpt := &client.HTTPRemoteOptions{
 		UserAgent: fmt.Sprintf("orbit/%s (%s %s)", build.Version, runtime.GOOS, runtime.GOARCH),
 	}
-	httpClient := fleethttp.NewClient(fleethttp.WithTLSClientConfig(tlsConfig))
 	remoteStore, err := client.HTTPRemoteStore(serverURL, remoteOpt, httpClient)
 	if err != nil {
 		return nil, fmt.Errorf("init remote store: %w", err)
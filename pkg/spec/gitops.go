@@ -443,6 +443,14 @@ type GitOps struct {
 	// values are never set via GitOps). Global-only: cannot be set on a team/fleet file.
 	CustomHostVitals []fleet.CustomHostVital
 
+	// CustomRoles are the custom role definitions. Global-only: cannot be set on
+	// a team/fleet file.
+	CustomRoles []fleet.CustomRoleSpec
+
+	// VulnerabilityExceptions are the vulnerability exceptions (accepted
+	// risks). Global-only: cannot be set on a team/fleet file.
+	VulnerabilityExceptions []fleet.VulnerabilityExceptionSpec
+
 	// Software is only allowed on teams, not on global config.
 	Software GitOpsSoftware
 	// FleetSecrets is a map of secret names to their values, extracted from FLEET_SECRET_ environment variables used in profiles and scripts.
@@ -456,6 +464,10 @@ type GitOps struct {
 	SecretsPresent bool
 	// CustomHostVitalsPresent indicates that the `custom_host_vitals:` key was explicitly present in the YAML file.
 	CustomHostVitalsPresent bool
+	// CustomRolesPresent indicates that the `custom_roles:` key was explicitly present in the YAML file.
+	CustomRolesPresent bool
+	// VulnerabilityExceptionsPresent indicates that the `vulnerability_exceptions:` key was explicitly present in the YAML file.
+	VulnerabilityExceptionsPresent bool
 }
 
 // GitOpsCustomHostVital defines the valid keys for an item in the top-level
@@ -536,7 +548,7 @@ func GitOpsFromFile(filePath, baseDir string, appConfig *fleet.EnrichedAppConfig
 	result := &GitOps{}
 	result.FleetSecrets = make(map[string]string)
 
-	topKeys := []string{"name", "settings", "org_settings", "agent_options", "controls", "policies", "reports", "software", "labels", "custom_host_vitals"}
+	topKeys := []string{"name", "settings", "org_settings", "agent_options", "controls", "policies", "reports", "software", "labels", "custom_host_vitals", "custom_roles", "vulnerability_exceptions"}
 	for k := range top {
 		if !slices.Contains(topKeys, k) {
 			multiError = multierror.Append(multiError, fmt.Errorf("unknown top-level field: %s", k))
@@ -603,8 +615,12 @@ func GitOpsFromFile(filePath, baseDir string, appConfig *fleet.EnrichedAppConfig
 		// exception settings), rather than a directive to clear settings.
 		// "custom_host_vitals" has no exception setting -- omitting it always means clear-all -- but still needs its own
 		// presence tracking (parseCustomHostVitals below), so it's excluded from the generic
-		// null-default handling too. settings keys were handled above.
-		if topKey == "name" || topKey == "labels" || topKey == "software" || topKey == "custom_host_vitals" || topKey == "settings" || topKey == "org_settings" {
+		// null-default handling too. "custom_roles" and "vulnerability_exceptions" are
+		// no-ops when omitted, so that they can also be managed outside of GitOps.
+		// settings keys were handled above.
+		if topKey == "name" || topKey == "labels" || topKey == "software" || topKey == "custom_host_vitals" || topKey == "custom_roles" ||
+			topKey == "vulnerability_exceptions" ||
+			topKey == "settings" || topKey == "org_settings" {
 			continue
 		}
 		// "controls" can be set on _either_ global or "no team" file, and we can't say which it is if both
@@ -637,6 +653,16 @@ func GitOpsFromFile(filePath, baseDir string, appConfig *fleet.EnrichedAppConfig
 		result.CustomHostVitalsPresent = true
 		multiError = parseCustomHostVitals(top, result, filePath, multiError)
 	}
+	// Get the custom roles. CustomRolesPresent tracks whether the key was in the YAML.
+	if _, ok := top["custom_roles"]; ok {
+		result.CustomRolesPresent = true
+		multiError = parseCustomRoles(top, result, filePath, multiError)
+	}
+	// Get the vulnerability exceptions. VulnerabilityExceptionsPresent tracks whether the key was in the YAML.
+	if _, ok := top["vulnerability_exceptions"]; ok {
+		result.VulnerabilityExceptionsPresent = true
+		multiError = parseVulnerabilityExceptions(top, result, filePath, multiError)
+	}
 	// Get other top-level entities.
 	multiError = parseControls(top, result, logFn, filePath, multiError)
 	multiError = parseAgentOptions(top, result, baseDir, logFn, filePath, multiError)
@@ -1248,6 +1274,78 @@ func parseCustomHostVitals(top map[string]json.RawMessage, result *GitOps, fileP
 	return multiError
 }
 
+// parseCustomRoles parses the top-level `custom_roles:` key. Global-only:
+// custom roles are assigned per team, but defined globally. An empty (or
+// explicitly null) list deletes all custom roles.
+func parseCustomRoles(top map[string]json.RawMessage, result *GitOps, filePath string, multiError *multierror.Error) *multierror.Error {
+	raw := top["custom_roles"]
+
+	if !result.global() {
+		return multierror.Append(multiError, errors.New("'custom_roles' cannot be set on a team file"))
+	}
+
+	result.CustomRoles = []fleet.CustomRoleSpec{}
+	if len(raw) == 0 || string(raw) == "null" {
+		return multiError
+	}
+
+	var roles []fleet.CustomRoleSpec
+	if err := json.Unmarshal(raw, &roles); err != nil {
+		return multierror.Append(multiError, MaybeParseTypeError(filePath, []string{"custom_roles"}, err))
+	}
+	// Validate unknown keys in the custom_roles section.
+	multiError = multierror.Append(multiError, validateRawKeys(raw, reflect.TypeFor[[]fleet.CustomRoleSpec](), filePath, []string{"custom_roles"})...)
+
+	for _, r := range roles {
+		if err := fleet.ValidateCustomRole(r.Name, r.Permissions); err != nil {
+			multiError = multierror.Append(multiError, fmt.Errorf("'custom_roles': %w", err))
+			continue
+		}
+		result.CustomRoles = append(result.CustomRoles, r)
+	}
+
+	return multiError
+}
+
+// parseVulnerabilityExceptions parses the top-level `vulnerability_exceptions:`
+// key. Global-only: an exception is scoped to a fleet with its `fleet` key. An
+// empty (or explicitly null) list deletes all vulnerability exceptions.
+func parseVulnerabilityExceptions(top map[string]json.RawMessage, result *GitOps, filePath string, multiError *multierror.Error) *multierror.Error {
+	raw := top["vulnerability_exceptions"]
+
+	if !result.global() {
+		return multierror.Append(multiError, errors.New("'vulnerability_exceptions' cannot be set on a team file"))
+	}
+
+	result.VulnerabilityExceptions = []fleet.VulnerabilityExceptionSpec{}
+	if len(raw) == 0 || string(raw) == "null" {
+		return multiError
+	}
+
+	var exceptions []fleet.VulnerabilityExceptionSpec
+	if err := json.Unmarshal(raw, &exceptions); err != nil {
+		return multierror.Append(multiError, MaybeParseTypeError(filePath, []string{"vulnerability_exceptions"}, err))
+	}
+	// Validate unknown keys in the vulnerability_exceptions section.
+	multiError = multierror.Append(multiError, validateRawKeys(raw, reflect.TypeFor[[]fleet.VulnerabilityExceptionSpec](), filePath, []string{"vulnerability_exceptions"})...)
+
+	for _, e := range exceptions {
+		e.CVE = fleet.NormalizeCVE(e.CVE)
+		if err := fleet.ValidateVulnerabilityException(fleet.VulnerabilityException{
+			CVE:       e.CVE,
+			Reason:    e.Reason,
+			Owner:     e.Owner,
+			ExpiresAt: e.ExpiresAt,
+		}); err != nil {
+			multiError = multierror.Append(multiError, fmt.Errorf("'vulnerability_exceptions': %w", err))
+			continue
+		}
+		result.VulnerabilityExceptions = append(result.VulnerabilityExceptions, e)
+	}
+
+	return multiError
+}
+
 func parseAgentOptions(top map[string]json.RawMessage, result *GitOps, baseDir string, logFn Logf, filePath string, multiError *multierror.Error) *multierror.Error {
 	agentOptionsRaw, ok := top["agent_options"]
 	if result.IsNoTeam() {
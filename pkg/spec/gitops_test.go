@@ -8,6 +8,7 @@ import (
 	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/pkg/file"
 	"github.com/fleetdm/fleet/v4/pkg/optjson"
@@ -5393,6 +5394,224 @@ custom_host_vitals:
 	})
 }
 
+func TestGitOpsCustomRoles(t *testing.T) {
+	t.Run("present", func(t *testing.T) {
+		gitops, err := gitOpsFromString(t, `
+org_settings:
+  server_settings:
+    server_url: https://example.com
+  org_info:
+    org_name: Test
+custom_roles:
+  - name: Helpdesk
+    description: Runs scripts and locks hosts
+    permissions:
+      - object: host_script_result
+        action: write
+      - object: mdm_command
+        action: write
+`)
+		require.NoError(t, err)
+		assert.True(t, gitops.CustomRolesPresent)
+		assert.Equal(t, []fleet.CustomRoleSpec{{
+			Name:        "Helpdesk",
+			Description: "Runs scripts and locks hosts",
+			Permissions: []fleet.CustomRolePermission{
+				{Object: "host_script_result", Action: "write"},
+				{Object: "mdm_command", Action: "write"},
+			},
+		}}, gitops.CustomRoles)
+	})
+
+	t.Run("absent", func(t *testing.T) {
+		gitops, err := gitOpsFromString(t, `
+org_settings:
+  server_settings:
+    server_url: https://example.com
+  org_info:
+    org_name: Test
+`)
+		require.NoError(t, err)
+		assert.False(t, gitops.CustomRolesPresent)
+		assert.Nil(t, gitops.CustomRoles, "absent custom_roles should be nil")
+	})
+
+	t.Run("present but empty", func(t *testing.T) {
+		gitops, err := gitOpsFromString(t, `
+org_settings:
+  server_settings:
+    server_url: https://example.com
+  org_info:
+    org_name: Test
+custom_roles:
+`)
+		require.NoError(t, err)
+		assert.True(t, gitops.CustomRolesPresent)
+		assert.NotNil(t, gitops.CustomRoles)
+		assert.Empty(t, gitops.CustomRoles)
+	})
+
+	t.Run("rejected on a team file", func(t *testing.T) {
+		path, basePath := createTempFile(t, "", `
+name: TestTeam
+custom_roles:
+  - name: Helpdesk
+    permissions:
+      - object: host_script_result
+        action: write
+`)
+		_, err := GitOpsFromFile(path, basePath, nil, nopLogf)
+		require.ErrorContains(t, err, "'custom_roles' cannot be set on a team file")
+	})
+
+	t.Run("rejects a permission that can't be granted", func(t *testing.T) {
+		_, err := gitOpsFromString(t, `
+org_settings:
+  server_settings:
+    server_url: https://example.com
+  org_info:
+    org_name: Test
+custom_roles:
+  - name: Escalation
+    permissions:
+      - object: user
+        action: write
+`)
+		require.ErrorContains(t, err, `Object "user" can't be granted by a custom role`)
+	})
+
+	t.Run("rejects an unknown key", func(t *testing.T) {
+		_, err := gitOpsFromString(t, `
+org_settings:
+  server_settings:
+    server_url: https://example.com
+  org_info:
+    org_name: Test
+custom_roles:
+  - name: Helpdesk
+    fleet: Workstations
+    permissions:
+      - object: host_script_result
+        action: write
+`)
+		require.Error(t, err)
+	})
+}
+
+func TestGitOpsVulnerabilityExceptions(t *testing.T) {
+	t.Run("present", func(t *testing.T) {
+		gitops, err := gitOpsFromString(t, `
+org_settings:
+  server_settings:
+    server_url: https://example.com
+  org_info:
+    org_name: Test
+vulnerability_exceptions:
+  - cve: cve-2024-1234
+    reason: Not exploitable, the vulnerable feature is disabled
+    owner: security@example.com
+    expires_at: 2026-12-31T00:00:00Z
+  - cve: CVE-2024-5678
+    software_title_id: 12
+    fleet: Workstations
+    reason: Vendor patch scheduled
+    owner: it@example.com
+    expires_at: 2027-01-15T12:00:00Z
+`)
+		require.NoError(t, err)
+		assert.True(t, gitops.VulnerabilityExceptionsPresent)
+		assert.Equal(t, []fleet.VulnerabilityExceptionSpec{
+			{
+				CVE:       "CVE-2024-1234",
+				Reason:    "Not exploitable, the vulnerable feature is disabled",
+				Owner:     "security@example.com",
+				ExpiresAt: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+			},
+			{
+				CVE:             "CVE-2024-5678",
+				SoftwareTitleID: ptr.Uint(12),
+				Fleet:           "Workstations",
+				Reason:          "Vendor patch scheduled",
+				Owner:           "it@example.com",
+				ExpiresAt:       time.Date(2027, 1, 15, 12, 0, 0, 0, time.UTC),
+			},
+		}, gitops.VulnerabilityExceptions)
+	})
+
+	t.Run("absent", func(t *testing.T) {
+		gitops, err := gitOpsFromString(t, `
+org_settings:
+  server_settings:
+    server_url: https://example.com
+  org_info:
+    org_name: Test
+`)
+		require.NoError(t, err)
+		assert.False(t, gitops.VulnerabilityExceptionsPresent)
+		assert.Nil(t, gitops.VulnerabilityExceptions)
+	})
+
+	t.Run("present but empty", func(t *testing.T) {
+		gitops, err := gitOpsFromString(t, `
+org_settings:
+  server_settings:
+    server_url: https://example.com
+  org_info:
+    org_name: Test
+vulnerability_exceptions:
+`)
+		require.NoError(t, err)
+		assert.True(t, gitops.VulnerabilityExceptionsPresent)
+		assert.NotNil(t, gitops.VulnerabilityExceptions)
+		assert.Empty(t, gitops.VulnerabilityExceptions)
+	})
+
+	t.Run("rejected on a team file", func(t *testing.T) {
+		path, basePath := createTempFile(t, "", `
+name: TestTeam
+vulnerability_exceptions:
+  - cve: CVE-2024-1234
+    reason: Accepted
+    owner: security@example.com
+    expires_at: 2026-12-31T00:00:00Z
+`)
+		_, err := GitOpsFromFile(path, basePath, nil, nopLogf)
+		require.ErrorContains(t, err, "'vulnerability_exceptions' cannot be set on a team file")
+	})
+
+	t.Run("rejects an invalid exception", func(t *testing.T) {
+		_, err := gitOpsFromString(t, `
+org_settings:
+  server_settings:
+    server_url: https://example.com
+  org_info:
+    org_name: Test
+vulnerability_exceptions:
+  - cve: not-a-cve
+    reason: Accepted
+    expires_at: 2026-12-31T00:00:00Z
+`)
+		require.ErrorContains(t, err, `Invalid CVE "NOT-A-CVE"`)
+	})
+
+	t.Run("rejects an unknown key", func(t *testing.T) {
+		_, err := gitOpsFromString(t, `
+org_settings:
+  server_settings:
+    server_url: https://example.com
+  org_info:
+    org_name: Test
+vulnerability_exceptions:
+  - cve: CVE-2024-1234
+    team: Workstations
+    reason: Accepted
+    owner: security@example.com
+    expires_at: 2026-12-31T00:00:00Z
+`)
+		require.Error(t, err)
+	})
+}
+
 func TestGitOpsFMACategoriesPresence(t *testing.T) {
 	t.Parallel()
 
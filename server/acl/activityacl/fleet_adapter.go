@@ -43,6 +43,7 @@ var (
 	_ activity.UserProvider              = (*FleetServiceAdapter)(nil)
 	_ activity.HostProvider              = (*FleetServiceAdapter)(nil)
 	_ activity.AppConfigProvider         = (*FleetServiceAdapter)(nil)
+	_ activity.WebhookDeliverer          = (*FleetServiceAdapter)(nil)
 	_ activity.UpcomingActivityActivator = (*FleetServiceAdapter)(nil)
 )
 
@@ -134,6 +135,7 @@ func (a *FleetServiceAdapter) GetHostActivitiesWebhooks(ctx context.Context, hos
 	hooks := make([]activity.HostActivitiesWebhook, 0, len(settings))
 	for _, s := range settings {
 		hooks = append(hooks, activity.HostActivitiesWebhook{
+			FleetID:        s.TeamID,
 			DestinationURL: s.DestinationURL,
 			HostIDs:        s.HostIDs,
 		})
@@ -141,6 +143,11 @@ func (a *FleetServiceAdapter) GetHostActivitiesWebhooks(ctx context.Context, hos
 	return hooks, nil
 }
 
+// DeliverActivitiesWebhook delivers an activities webhook payload.
+func (a *FleetServiceAdapter) DeliverActivitiesWebhook(ctx context.Context, fleetID *uint, destinationURL string, payload []byte) error {
+	return a.svc.DeliverActivitiesWebhook(ctx, fleetID, destinationURL, payload)
+}
+
 // ActivateNextUpcomingActivity activates the next upcoming activity in the queue.
 func (a *FleetServiceAdapter) ActivateNextUpcomingActivity(ctx context.Context, hostID uint, fromCompletedExecID string) error {
 	return a.svc.ActivateNextUpcomingActivityForHost(ctx, hostID, fromCompletedExecID)
@@ -12,6 +12,8 @@ type ActivitiesWebhookSettings struct {
 // destination, together with the subset of the activity's hosts belonging to
 // the fleet(s) configured with it.
 type HostActivitiesWebhook struct {
+	// FleetID is the fleet of the webhook settings, 0 for "Unassigned".
+	FleetID        uint
 	DestinationURL string
 	HostIDs        []uint
 }
@@ -21,3 +23,12 @@ type AppConfigProvider interface {
 	GetActivitiesWebhookConfig(ctx context.Context) (*ActivitiesWebhookSettings, error)
 	GetHostActivitiesWebhooks(ctx context.Context, hostIDs []uint) ([]HostActivitiesWebhook, error)
 }
+
+// WebhookDeliverer delivers the activities webhooks, signing each payload with
+// the webhook's secret and retrying failed deliveries.
+type WebhookDeliverer interface {
+	// DeliverActivitiesWebhook delivers the payload to the global activities
+	// webhook if fleetID is nil, to the fleet's host activities webhook
+	// otherwise.
+	DeliverActivitiesWebhook(ctx context.Context, fleetID *uint, destinationURL string, payload []byte) error
+}
@@ -3,19 +3,15 @@ package service
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log/slog"
-	"net/http"
 	"time"
 
-	"github.com/cenkalti/backoff/v4"
 	"github.com/fleetdm/fleet/v4/server/activity/api"
 	"github.com/fleetdm/fleet/v4/server/activity/internal/types"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	eu "github.com/fleetdm/fleet/v4/server/platform/endpointer"
 	platformhttp "github.com/fleetdm/fleet/v4/server/platform/http"
-	kithttp "github.com/go-kit/kit/transport/http"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -50,7 +46,7 @@ func (s *Service) NewActivity(ctx context.Context, user *api.User, activity api.
 	}
 
 	if webhookConfig != nil && webhookConfig.Enable {
-		s.fireActivityWebhook(ctx, user, activity, detailsBytes, timestamp, webhookConfig.DestinationURL)
+		s.fireActivityWebhook(ctx, user, activity, detailsBytes, timestamp, nil, webhookConfig.DestinationURL)
 	}
 
 	// Fire the per-fleet host activities webhooks if the activity is linked to
@@ -78,7 +74,7 @@ func (s *Service) NewActivity(ctx context.Context, user *api.User, activity api.
 			// its pre-existing format).
 			for _, hook := range hooks {
 				webhookDetails := s.detailsWithHostIDs(ctx, activity.ActivityName(), detailsBytes, hook.HostIDs)
-				s.fireActivityWebhook(ctx, user, activity, webhookDetails, timestamp, hook.DestinationURL)
+				s.fireActivityWebhook(ctx, user, activity, webhookDetails, timestamp, &hook.FleetID, hook.DestinationURL)
 			}
 		}
 	}
@@ -147,11 +143,13 @@ func (s *Service) detailsWithHostIDs(ctx context.Context, activityName string, d
 	return withIDs
 }
 
-// fireActivityWebhook sends the activity to the configured webhook URL asynchronously.
-// It uses exponential backoff with a max elapsed time of 30 minutes for retries.
+// fireActivityWebhook sends the activity to the configured webhook URL
+// asynchronously, to the global activities webhook if fleetID is nil or to the
+// fleet's host activities webhook otherwise. Deliveries that fail with a
+// retryable error are retried in the background by the webhook deliverer.
 func (s *Service) fireActivityWebhook(
 	ctx context.Context, user *api.User, activity api.ActivityDetails,
-	detailsBytes []byte, timestamp time.Time, webhookURL string,
+	detailsBytes []byte, timestamp time.Time, fleetID *uint, webhookURL string,
 ) {
 	var userID *uint
 	var userName *string
@@ -172,6 +170,20 @@ func (s *Service) fireActivityWebhook(
 		userName = &automationAuthor
 	}
 
+	payload, err := json.Marshal(&webhookPayload{
+		Timestamp:     timestamp,
+		ActorFullName: userName,
+		ActorID:       userID,
+		ActorEmail:    userEmail,
+		Type:          activityType,
+		Details:       (*json.RawMessage)(&detailsBytes),
+	})
+	if err != nil {
+		s.logger.ErrorContext(ctx, "marshal activity webhook payload",
+			slog.String("activity", activityType), slog.String("err", err.Error()))
+		return
+	}
+
 	// Capture the parent span for linking before launching the goroutine.
 	parentSpanCtx := trace.SpanContextFromContext(ctx)
 
@@ -192,31 +204,7 @@ func (s *Service) fireActivityWebhook(
 		)
 		defer span.End()
 
-		retryStrategy := backoff.NewExponentialBackOff()
-		retryStrategy.MaxElapsedTime = 30 * time.Minute
-		err := backoff.Retry(
-			func() error {
-				if err := platformhttp.PostJSONWithTimeout(
-					spanCtx, webhookURL, &webhookPayload{
-						Timestamp:     timestamp,
-						ActorFullName: userName,
-						ActorID:       userID,
-						ActorEmail:    userEmail,
-						Type:          activityType,
-						Details:       (*json.RawMessage)(&detailsBytes),
-					}, s.logger,
-				); err != nil {
-					var statusCoder kithttp.StatusCoder
-					if errors.As(err, &statusCoder) && statusCoder.StatusCode() == http.StatusTooManyRequests {
-						s.logger.DebugContext(spanCtx, "fire activity webhook", slog.String("err", err.Error()))
-						return err
-					}
-					return backoff.Permanent(err)
-				}
-				return nil
-			}, retryStrategy,
-		)
-		if err != nil {
+		if err := s.providers.DeliverActivitiesWebhook(spanCtx, fleetID, webhookURL, payload); err != nil {
 			maskedErr := platformhttp.MaskURLError(err)
 			span.RecordError(maskedErr)
 			s.logger.ErrorContext(spanCtx,
@@ -261,7 +261,6 @@ func TestNewActivityWebhook(t *testing.T) {
 
 	webhookChannel := make(chan struct{}, 1)
 	var webhookBody webhookPayload
-	fail429 := false
 
 	startMockServer := func(t *testing.T) string {
 		srv := httptest.NewServer(
@@ -282,17 +281,6 @@ func TestNewActivityWebhook(t *testing.T) {
 					case "/error":
 						webhookBody.Type = "error"
 						w.WriteHeader(http.StatusTeapot)
-					case "/429":
-						fail429 = !fail429
-						if fail429 {
-							w.WriteHeader(http.StatusTooManyRequests)
-							return
-						}
-						err := json.NewDecoder(r.Body).Decode(&webhookBody)
-						if err != nil {
-							t.Log(err)
-							w.WriteHeader(http.StatusBadRequest)
-						}
 					default:
 						w.WriteHeader(http.StatusNotFound)
 						return
@@ -347,15 +335,6 @@ func TestNewActivityWebhook(t *testing.T) {
 			url:     mockURL + "/error",
 			doError: true,
 		},
-		{
-			name: "429",
-			url:  mockURL + "/429",
-			user: &api.User{
-				ID:    2,
-				Name:  "testUserRetry",
-				Email: "testUserRetry@example.com",
-			},
-		},
 	}
 
 	for _, tt := range tests {
@@ -13,6 +13,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/activity/api"
 	"github.com/fleetdm/fleet/v4/server/activity/internal/types"
 	platform_authz "github.com/fleetdm/fleet/v4/server/platform/authz"
+	platformhttp "github.com/fleetdm/fleet/v4/server/platform/http"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -121,6 +122,13 @@ func (m *mockDataProviders) GetHostActivitiesWebhooks(ctx context.Context, hostI
 	return m.hostWebhooks, m.hostWebhooksErr
 }
 
+// DeliverActivitiesWebhook POSTs the payload once, the retries of failed
+// deliveries are not part of the activity bounded context.
+func (m *mockDataProviders) DeliverActivitiesWebhook(ctx context.Context, fleetID *uint, destinationURL string, payload []byte) error {
+	_, err := platformhttp.PostWebhook(ctx, platformhttp.WebhookRequest{URL: destinationURL, Body: payload}, slog.New(slog.DiscardHandler))
+	return err
+}
+
 func (m *mockDataProviders) ActivateNextUpcomingActivity(ctx context.Context, hostID uint, fromCompletedExecID string) error {
 	return nil
 }
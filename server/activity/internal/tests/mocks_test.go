@@ -96,6 +96,10 @@ func (m *mockDataProviders) GetHostActivitiesWebhooks(ctx context.Context, hostI
 	return nil, nil
 }
 
+func (m *mockDataProviders) DeliverActivitiesWebhook(ctx context.Context, fleetID *uint, destinationURL string, payload []byte) error {
+	return nil
+}
+
 func (m *mockDataProviders) ActivateNextUpcomingActivity(ctx context.Context, hostID uint, fromCompletedExecID string) error {
 	return nil
 }
@@ -16,5 +16,6 @@ type DataProviders interface {
 	UserProvider
 	HostProvider
 	AppConfigProvider
+	WebhookDeliverer
 	UpcomingActivityActivator
 }
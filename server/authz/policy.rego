@@ -1525,3 +1525,96 @@ allow {
   team_role(subject, subject.teams[_].id) == admin
   action == read
 }
+
+##
+# VEX documents
+##
+
+# Global admins, maintainers, and gitops can upload and delete VEX documents.
+allow {
+  object.type == "vex_document"
+  subject.global_role == [admin, maintainer, gitops][_]
+  action == write
+}
+
+# Any global user can read VEX documents and the vulnerabilities they suppress.
+allow {
+  object.type == "vex_document"
+  subject.global_role == [admin, maintainer, gitops, technician, observer_plus, observer][_]
+  action == read
+}
+
+##
+# Vulnerability exceptions
+##
+
+# Global admins, maintainers, and gitops can manage vulnerability exceptions.
+allow {
+  object.type == "vulnerability_exception"
+  subject.global_role == [admin, maintainer, gitops][_]
+  action == write
+}
+
+# Any global user can read vulnerability exceptions.
+allow {
+  object.type == "vulnerability_exception"
+  subject.global_role == [admin, maintainer, gitops, technician, observer_plus, observer][_]
+  action == read
+}
+
+##
+# Webhook deliveries
+##
+
+# Global admins can read and redeliver webhook deliveries, which include the
+# payloads sent for all fleets.
+allow {
+  object.type == "webhook_delivery"
+  subject.global_role == admin
+  action == [read, write][_]
+}
+
+##
+# Custom roles
+##
+
+# Global admins and gitops can read and write custom roles.
+allow {
+  object.type == "custom_role"
+  subject.global_role == [admin, gitops][_]
+  action == [read, write][_]
+}
+
+# custom_role_grants is true if one of the permissions of the custom role
+# grants the action on the object's type. Transferring hosts is never granted,
+# even by custom roles saved before it was made non-grantable.
+custom_role_grants(role) {
+  perm := role.permissions[_]
+  perm.object == object.type
+  perm.action == action
+  action != transfer_host
+}
+
+# Custom roles assigned globally grant their permissions on all objects.
+allow {
+  role := subject.custom_roles[_]
+  is_null(role.team_id)
+  custom_role_grants(role)
+}
+
+# Custom roles assigned for a team grant their permissions on the objects of
+# that team. Like team roles, they also grant list on the overall object, the
+# service filters the results based on access.
+allow {
+  role := subject.custom_roles[_]
+  not is_null(role.team_id)
+  role.team_id == object.team_id
+  custom_role_grants(role)
+}
+
+allow {
+  role := subject.custom_roles[_]
+  not is_null(role.team_id)
+  action == list
+  custom_role_grants(role)
+}
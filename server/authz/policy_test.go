@@ -10,6 +10,7 @@ import (
 	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	platform_authz "github.com/fleetdm/fleet/v4/server/platform/authz"
+	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -3612,3 +3613,175 @@ func TestAuthorizeCertificateTemplate(t *testing.T) {
 		{user: test.UserTeamTechnicianTeam1, object: fleet1, action: list, allow: false},
 	})
 }
+
+func TestAuthorizeVEXDocuments(t *testing.T) {
+	t.Parallel()
+
+	vexDocument := &fleet.VEXDocument{}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: vexDocument, action: read, allow: false},
+
+		{user: test.UserNoRoles, object: vexDocument, action: read, allow: false},
+
+		// Global admins, maintainers, and gitops can read/write.
+		{user: test.UserAdmin, object: vexDocument, action: read, allow: true},
+		{user: test.UserAdmin, object: vexDocument, action: write, allow: true},
+		{user: test.UserMaintainer, object: vexDocument, action: read, allow: true},
+		{user: test.UserMaintainer, object: vexDocument, action: write, allow: true},
+		{user: test.UserGitOps, object: vexDocument, action: read, allow: true},
+		{user: test.UserGitOps, object: vexDocument, action: write, allow: true},
+
+		// Other global users can read but cannot write.
+		{user: test.UserObserver, object: vexDocument, action: read, allow: true},
+		{user: test.UserObserver, object: vexDocument, action: write, allow: false},
+		{user: test.UserObserverPlus, object: vexDocument, action: read, allow: true},
+		{user: test.UserObserverPlus, object: vexDocument, action: write, allow: false},
+		{user: test.UserTechnician, object: vexDocument, action: read, allow: true},
+		{user: test.UserTechnician, object: vexDocument, action: write, allow: false},
+
+		// VEX documents apply to all fleets, team users cannot access them.
+		{user: test.UserTeamAdminTeam1, object: vexDocument, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: vexDocument, action: write, allow: false},
+		{user: test.UserTeamObserverTeam1, object: vexDocument, action: read, allow: false},
+	})
+}
+
+func TestAuthorizeVulnerabilityExceptions(t *testing.T) {
+	t.Parallel()
+
+	exception := &fleet.VulnerabilityException{}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: exception, action: read, allow: false},
+
+		{user: test.UserNoRoles, object: exception, action: read, allow: false},
+
+		// Global admins, maintainers, and gitops can read/write.
+		{user: test.UserAdmin, object: exception, action: read, allow: true},
+		{user: test.UserAdmin, object: exception, action: write, allow: true},
+		{user: test.UserMaintainer, object: exception, action: read, allow: true},
+		{user: test.UserMaintainer, object: exception, action: write, allow: true},
+		{user: test.UserGitOps, object: exception, action: read, allow: true},
+		{user: test.UserGitOps, object: exception, action: write, allow: true},
+
+		// Other global users can read but cannot write.
+		{user: test.UserObserver, object: exception, action: read, allow: true},
+		{user: test.UserObserver, object: exception, action: write, allow: false},
+		{user: test.UserObserverPlus, object: exception, action: read, allow: true},
+		{user: test.UserObserverPlus, object: exception, action: write, allow: false},
+		{user: test.UserTechnician, object: exception, action: read, allow: true},
+		{user: test.UserTechnician, object: exception, action: write, allow: false},
+
+		// Team users cannot access exceptions, even those of their team.
+		{user: test.UserTeamAdminTeam1, object: exception, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: exception, action: write, allow: false},
+		{user: test.UserTeamMaintainerTeam1, object: exception, action: read, allow: false},
+	})
+}
+
+func TestAuthorizeWebhookDeliveries(t *testing.T) {
+	t.Parallel()
+
+	delivery := &fleet.WebhookDelivery{}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: delivery, action: read, allow: false},
+
+		// Only global admins can read and redeliver.
+		{user: test.UserAdmin, object: delivery, action: read, allow: true},
+		{user: test.UserAdmin, object: delivery, action: write, allow: true},
+
+		{user: test.UserMaintainer, object: delivery, action: read, allow: false},
+		{user: test.UserMaintainer, object: delivery, action: write, allow: false},
+		{user: test.UserGitOps, object: delivery, action: read, allow: false},
+		{user: test.UserObserver, object: delivery, action: read, allow: false},
+		{user: test.UserObserverPlus, object: delivery, action: read, allow: false},
+		{user: test.UserTechnician, object: delivery, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: delivery, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: delivery, action: write, allow: false},
+	})
+}
+
+func TestAuthorizeCustomRoles(t *testing.T) {
+	t.Parallel()
+
+	customRole := &fleet.CustomRole{}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: customRole, action: read, allow: false},
+
+		{user: test.UserNoRoles, object: customRole, action: read, allow: false},
+
+		// Global admins and gitops can read/write custom roles.
+		{user: test.UserAdmin, object: customRole, action: read, allow: true},
+		{user: test.UserAdmin, object: customRole, action: write, allow: true},
+		{user: test.UserGitOps, object: customRole, action: read, allow: true},
+		{user: test.UserGitOps, object: customRole, action: write, allow: true},
+
+		// Other roles cannot.
+		{user: test.UserMaintainer, object: customRole, action: read, allow: false},
+		{user: test.UserMaintainer, object: customRole, action: write, allow: false},
+		{user: test.UserObserver, object: customRole, action: read, allow: false},
+		{user: test.UserTechnician, object: customRole, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: customRole, action: read, allow: false},
+		{user: test.UserTeamAdminTeam1, object: customRole, action: write, allow: false},
+	})
+}
+
+func TestAuthorizeWithCustomRoles(t *testing.T) {
+	t.Parallel()
+
+	helpdesk := fleet.CustomRolePermissions{
+		{Object: "host", Action: fleet.ActionList},
+		{Object: "host", Action: fleet.ActionRead},
+		{Object: "host_script_result", Action: fleet.ActionWrite},
+		{Object: "mdm_command", Action: fleet.ActionWrite},
+	}
+
+	// an observer with the helpdesk role on all hosts
+	globalHelpdesk := &fleet.User{
+		ID:          100,
+		GlobalRole:  ptr.String(fleet.RoleObserver),
+		CustomRoles: []fleet.UserCustomRole{{CustomRoleID: 1, Permissions: helpdesk}},
+	}
+	// an observer of team 1 with the helpdesk role on the hosts of team 1
+	teamHelpdesk := &fleet.User{
+		ID:          101,
+		Teams:       []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}},
+		CustomRoles: []fleet.UserCustomRole{{CustomRoleID: 1, TeamID: ptr.Uint(1), Permissions: helpdesk}},
+	}
+
+	// an observer with a role saved when transferring hosts was grantable
+	transferer := &fleet.User{
+		ID:          102,
+		GlobalRole:  ptr.String(fleet.RoleObserver),
+		CustomRoles: []fleet.UserCustomRole{{CustomRoleID: 2, Permissions: fleet.CustomRolePermissions{{Object: "host", Action: fleet.ActionTransferHost}}}},
+	}
+
+	runTestCases(t, []authTestCase{
+		// the custom role grants running scripts and locking hosts
+		{user: test.UserObserver, object: &fleet.HostScriptResult{}, action: write, allow: false},
+		{user: globalHelpdesk, object: &fleet.HostScriptResult{}, action: write, allow: true},
+		{user: globalHelpdesk, object: &fleet.HostScriptResult{TeamID: ptr.Uint(1)}, action: write, allow: true},
+		{user: globalHelpdesk, object: &fleet.MDMCommandAuthz{}, action: write, allow: true},
+		{user: globalHelpdesk, object: &fleet.MDMCommandAuthz{TeamID: ptr.Uint(2)}, action: write, allow: true},
+
+		// but nothing else
+		{user: globalHelpdesk, object: &fleet.Policy{}, action: write, allow: false},
+		{user: globalHelpdesk, object: &fleet.HostScriptResult{}, action: read, allow: true}, // from the observer role
+		{user: globalHelpdesk, object: &fleet.Host{}, action: write, allow: false},
+		{user: globalHelpdesk, object: &fleet.CustomRole{}, action: write, allow: false},
+
+		// team custom roles only apply to their team
+		{user: teamHelpdesk, object: &fleet.HostScriptResult{TeamID: ptr.Uint(1)}, action: write, allow: true},
+		{user: teamHelpdesk, object: &fleet.HostScriptResult{TeamID: ptr.Uint(2)}, action: write, allow: false},
+		{user: teamHelpdesk, object: &fleet.HostScriptResult{}, action: write, allow: false},
+		{user: teamHelpdesk, object: &fleet.MDMCommandAuthz{TeamID: ptr.Uint(1)}, action: write, allow: true},
+		{user: teamHelpdesk, object: &fleet.MDMCommandAuthz{TeamID: ptr.Uint(2)}, action: write, allow: false},
+		{user: teamHelpdesk, object: &fleet.Policy{PolicyData: fleet.PolicyData{TeamID: ptr.Uint(1)}}, action: write, allow: false},
+
+		// list is granted on the overall object, like team roles
+		{user: teamHelpdesk, object: &fleet.Host{}, action: list, allow: true},
+
+		// transferring hosts is never granted, even if saved in the role
+		{user: transferer, object: &fleet.Host{}, action: transferHost, allow: false},
+		{user: transferer, object: &fleet.Host{TeamID: ptr.Uint(1)}, action: transferHost, allow: false},
+	})
+}
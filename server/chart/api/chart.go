@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"iter"
 	"time"
 
 	"github.com/RoaringBitmap/roaring"
@@ -96,6 +97,22 @@ type DatasetStore interface {
 	// collector deliberately records the wide set. See the mysql implementation.
 	CollectibleCVEs(ctx context.Context) ([]string, error)
 
+	// FailingHostIDsByPolicy returns a bitmap of host IDs currently failing
+	// each policy, keyed by the policy ID in decimal form. Hosts that have not
+	// reported a result for a policy (policy_membership.passes IS NULL) are not
+	// counted as failing. Used by the policy compliance dataset.
+	FailingHostIDsByPolicy(ctx context.Context, disabledFleetIDs []uint) (map[string]*roaring.Bitmap, error)
+
+	// HostIDsByMDMEnrollmentStatus returns a bitmap of MDM-enrolled host IDs
+	// per enrollment status (see the MDMEnrollmentStatus* constants). Hosts
+	// that are not enrolled, or only pending enrollment, are omitted. Used by
+	// the MDM enrollment dataset.
+	HostIDsByMDMEnrollmentStatus(ctx context.Context, disabledFleetIDs []uint) (map[string]*roaring.Bitmap, error)
+
+	// FindDiskEncryptedHostIDs returns host IDs whose disk is reported as
+	// encrypted. Used by the disk encryption dataset.
+	FindDiskEncryptedHostIDs(ctx context.Context, disabledFleetIDs []uint) ([]uint, error)
+
 	// RecordBucketData writes one or more entity bitmaps for the given bucket
 	// using the specified sample strategy. See SampleStrategy for semantics.
 	// Bitmaps are passed in op form (*roaring.Bitmap); the datastore
@@ -114,6 +131,23 @@ type DatasetStore interface {
 // The CVE entity filters apply only to this metric.
 const MetricCVE = "cve"
 
+// Metric names of the compliance datasets. The policy metric is keyed by policy
+// ID (entity_id is the decimal policy ID) so a chart can be narrowed to a
+// subset of policies via RequestOpts.PolicyIDs.
+const (
+	MetricPolicy         = "policy"
+	MetricMDMEnrollment  = "mdm_enrollment"
+	MetricDiskEncryption = "disk_encryption"
+)
+
+// MDM enrollment status entity keys recorded by the mdm_enrollment dataset.
+// They mirror the "On (…)" values of host_mdm.enrollment_status.
+const (
+	MDMEnrollmentStatusManual    = "manual"
+	MDMEnrollmentStatusAutomatic = "automatic"
+	MDMEnrollmentStatusPersonal  = "personal"
+)
+
 // CVE chart software category keys. These are the API contract for the
 // `software_filters` query parameter and are mirrored by the frontend. The
 // "os" category covers both operating-system vulnerabilities and the kernel
@@ -187,6 +221,51 @@ type RequestOpts struct {
 	// ExcludeCVEs is a subtractive filter — these CVEs are removed from the
 	// resolved entity set.
 	ExcludeCVEs []string
+
+	// PolicyIDs narrows the policy metric to the given policies. Empty means
+	// every policy, i.e. the chart counts hosts failing any policy.
+	PolicyIDs []uint
+}
+
+// ExportOpts captures the parsed query parameters for a chart history export.
+// The embedded RequestOpts supplies the host and entity filters; its Days,
+// Resolution and TZOffsetMinutes fields are ignored because an export streams
+// the raw SCD rows overlapping [StartTime, EndTime) rather than buckets.
+type ExportOpts struct {
+	RequestOpts
+	// StartTime and EndTime bound the export. A zero EndTime means now; a zero
+	// StartTime means seven days before EndTime.
+	StartTime time.Time
+	EndTime   time.Time
+	// WithHostIDs expands every row with the IDs of the hosts it holds.
+	WithHostIDs bool
+}
+
+// ExportRow is a single row of a chart history export: the hosts recorded for
+// an entity (e.g. a policy ID or a CVE, empty for single-entity metrics like
+// uptime) over [ValidFrom, ValidTo). ValidTo is nil while the row is still
+// open. HostIDs is only set when the export was requested with
+// ExportOpts.WithHostIDs.
+type ExportRow struct {
+	EntityID  string     `json:"entity_id"`
+	ValidFrom time.Time  `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to"`
+	HostCount int        `json:"host_count"`
+	HostIDs   []uint     `json:"host_ids,omitempty"`
+}
+
+// Export is the result of a chart history export. The envelope fields are
+// resolved up front; Rows is consumed once, in valid_from order, while the
+// response is written so the rows are streamed from the database cursor
+// instead of being held in memory. An error ends the rows.
+type Export struct {
+	Metric      string                      `json:"metric"`
+	StartTime   time.Time                   `json:"start_time"`
+	EndTime     time.Time                   `json:"end_time"`
+	TotalHosts  int                         `json:"total_hosts"`
+	WithHostIDs bool                        `json:"with_host_ids"`
+	Filters     Filters                     `json:"filters"`
+	Rows        iter.Seq2[ExportRow, error] `json:"-"`
 }
 
 // Filters captures the applied filters for a chart request.
@@ -204,4 +283,6 @@ type Filters struct {
 	SeverityMin     *float64 `json:"severity_min,omitempty"`
 	SeverityMax     *float64 `json:"severity_max,omitempty"`
 	ExcludeCVEs     []string `json:"exclude_vulnerabilities,omitempty"`
+
+	PolicyIDs []uint `json:"policy_ids,omitempty"`
 }
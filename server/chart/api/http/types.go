@@ -1,7 +1,18 @@
 // Package http provides HTTP request/response types for the chart bounded context.
 package http
 
-import "github.com/fleetdm/fleet/v4/server/chart/api"
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/chart/api"
+)
 
 // GetChartDataRequest is the HTTP request for the chart data endpoint.
 type GetChartDataRequest struct {
@@ -9,6 +20,12 @@ type GetChartDataRequest struct {
 	Days       int    `query:"days,optional"`
 	Resolution int    `query:"resolution,optional"`
 	TZOffset   int    `query:"tz_offset,optional"`
+	FilterParams
+}
+
+// FilterParams holds the host and entity filter query parameters shared by the
+// chart data and chart export endpoints.
+type FilterParams struct {
 	// TeamID is a pointer so we can distinguish "absent" (auto-scope to the
 	// viewer) from fleet_id=0 (no-team hosts, a valid Fleet filter).
 	// Exposed as fleet_id on the wire per the teams→fleets rename; the Go
@@ -29,6 +46,10 @@ type GetChartDataRequest struct {
 	SeverityMin     *float64 `query:"severity_min,optional"`
 	SeverityMax     *float64 `query:"severity_max,optional"`
 	ExcludeCVEs     string   `query:"exclude_vulnerabilities,optional"`
+
+	// PolicyIDs narrows the policy metric to a comma-separated list of policy
+	// IDs. Ignored by every other metric.
+	PolicyIDs string `query:"policy_ids,optional"`
 }
 
 // GetChartDataResponse is the HTTP response for the chart data endpoint.
@@ -38,3 +59,134 @@ type GetChartDataResponse struct {
 }
 
 func (r GetChartDataResponse) Error() error { return r.Err }
+
+// Export formats accepted by the chart export endpoint.
+const (
+	ExportFormatJSON = "json"
+	ExportFormatCSV  = "csv"
+)
+
+// ExportChartDataRequest is the HTTP request for the chart export endpoint.
+// StartTime and EndTime are RFC 3339 timestamps; both are optional (see
+// api.ExportOpts for the defaults).
+type ExportChartDataRequest struct {
+	Metric      string `url:"metric"`
+	Format      string `query:"format,optional"`
+	StartTime   string `query:"start_time,optional"`
+	EndTime     string `query:"end_time,optional"`
+	WithHostIDs bool   `query:"with_host_ids,optional"`
+	FilterParams
+}
+
+// ExportChartDataResponse is the HTTP response for the chart export endpoint.
+// It renders itself so rows can be streamed as they are read.
+type ExportChartDataResponse struct {
+	Export *api.Export `json:"-"`
+	Format string      `json:"-"`
+	Err    error       `json:"error,omitempty"`
+}
+
+func (r ExportChartDataResponse) Error() error { return r.Err }
+
+// HijackRender streams the export as CSV or JSON. Errors reading the rows can
+// only occur once the header is written: the JSON body then ends with an
+// "error" field, and the CSV response is aborted so it can't be mistaken for
+// a complete export.
+func (r ExportChartDataResponse) HijackRender(_ context.Context, w http.ResponseWriter) {
+	if r.Format == ExportFormatCSV {
+		r.renderCSV(w)
+		return
+	}
+	r.renderJSON(w)
+}
+
+func (r ExportChartDataResponse) filename(ext string) string {
+	return fmt.Sprintf(`attachment; filename="%s %s-%s.%s"`, r.Export.Metric,
+		r.Export.StartTime.Format("2006-01-02"), r.Export.EndTime.Format("2006-01-02"), ext)
+}
+
+func (r ExportChartDataResponse) renderCSV(w http.ResponseWriter) {
+	w.Header().Add("Content-Disposition", r.filename("csv"))
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	header := []string{"entity_id", "valid_from", "valid_to", "host_count"}
+	if r.Export.WithHostIDs {
+		header = append(header, "host_ids")
+	}
+	_ = cw.Write(header)
+	for row, err := range r.Export.Rows {
+		if err != nil {
+			cw.Flush()
+			// Abort the response so the client sees a truncated transfer
+			// rather than a complete-looking file.
+			panic(http.ErrAbortHandler)
+		}
+		var validTo string
+		if row.ValidTo != nil {
+			validTo = row.ValidTo.Format(time.RFC3339)
+		}
+		rec := []string{row.EntityID, row.ValidFrom.Format(time.RFC3339), validTo, strconv.Itoa(row.HostCount)}
+		if r.Export.WithHostIDs {
+			// Space-separated so the column stays a single unquoted-friendly
+			// field for warehouse loaders.
+			ids := make([]string, len(row.HostIDs))
+			for i, id := range row.HostIDs {
+				ids[i] = strconv.FormatUint(uint64(id), 10)
+			}
+			rec = append(rec, strings.Join(ids, " "))
+		}
+		_ = cw.Write(rec)
+	}
+	cw.Flush()
+}
+
+func (r ExportChartDataResponse) renderJSON(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	flush := func() {}
+	if f, ok := w.(http.Flusher); ok {
+		flush = f.Flush
+	}
+
+	// The envelope marshals without the rows (json:"-"); reopen it to append
+	// the streamed "data" array.
+	envelope, err := json.Marshal(r.Export)
+	if err != nil {
+		fmt.Fprintf(w, `{"error":%s}`, marshalErrorString(err))
+		return
+	}
+	_, _ = w.Write(envelope[:len(envelope)-1])
+	fmt.Fprint(w, `,"data":[`)
+	first := true
+	for row, err := range r.Export.Rows {
+		var data []byte
+		if err == nil {
+			data, err = json.Marshal(row)
+		}
+		if err != nil {
+			fmt.Fprintf(w, `],"error":%s}`, marshalErrorString(err))
+			return
+		}
+		if !first {
+			fmt.Fprint(w, `,`)
+		}
+		_, _ = w.Write(data)
+		flush()
+		first = false
+	}
+	fmt.Fprint(w, `]}`)
+}
+
+// marshalErrorString JSON-encodes an error message for inclusion in a response
+// that is already partially written.
+func marshalErrorString(err error) []byte {
+	b, mErr := json.Marshal(err.Error())
+	if mErr != nil {
+		return []byte(`"unknown error"`)
+	}
+	return b
+}
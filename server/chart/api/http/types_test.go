@@ -0,0 +1,98 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/chart/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testExport(withHostIDs bool, rowsErr error) *api.Export {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	closedAt := start.Add(time.Hour)
+	rows := []api.ExportRow{
+		{EntityID: "12", ValidFrom: start, ValidTo: &closedAt, HostCount: 1},
+		{EntityID: "12", ValidFrom: closedAt, HostCount: 2},
+	}
+	if withHostIDs {
+		rows[0].HostIDs = []uint{7}
+		rows[1].HostIDs = []uint{7, 9}
+	}
+	return &api.Export{
+		Metric:      "policy",
+		StartTime:   start,
+		EndTime:     start.Add(2 * time.Hour),
+		TotalHosts:  10,
+		WithHostIDs: withHostIDs,
+		Rows: func(yield func(api.ExportRow, error) bool) {
+			for _, row := range rows {
+				if !yield(row, nil) {
+					return
+				}
+			}
+			if rowsErr != nil {
+				yield(api.ExportRow{}, rowsErr)
+			}
+		},
+	}
+}
+
+func TestExportChartDataResponseCSV(t *testing.T) {
+	w := httptest.NewRecorder()
+	ExportChartDataResponse{Export: testExport(false, nil), Format: ExportFormatCSV}.HijackRender(t.Context(), w)
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="policy 2026-03-01-2026-03-01.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "entity_id,valid_from,valid_to,host_count\n"+
+		"12,2026-03-01T00:00:00Z,2026-03-01T01:00:00Z,1\n"+
+		"12,2026-03-01T01:00:00Z,,2\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	ExportChartDataResponse{Export: testExport(true, nil), Format: ExportFormatCSV}.HijackRender(t.Context(), w)
+	assert.Equal(t, "entity_id,valid_from,valid_to,host_count,host_ids\n"+
+		"12,2026-03-01T00:00:00Z,2026-03-01T01:00:00Z,1,7\n"+
+		"12,2026-03-01T01:00:00Z,,2,7 9\n", w.Body.String())
+
+	// a read error aborts the response
+	w = httptest.NewRecorder()
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		ExportChartDataResponse{Export: testExport(false, errors.New("boom")), Format: ExportFormatCSV}.HijackRender(t.Context(), w)
+	})
+}
+
+func TestExportChartDataResponseJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	ExportChartDataResponse{Export: testExport(true, nil), Format: ExportFormatJSON}.HijackRender(t.Context(), w)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var got struct {
+		Metric      string          `json:"metric"`
+		TotalHosts  int             `json:"total_hosts"`
+		WithHostIDs bool            `json:"with_host_ids"`
+		Data        []api.ExportRow `json:"data"`
+		Error       string          `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(t, "policy", got.Metric)
+	assert.Equal(t, 10, got.TotalHosts)
+	assert.True(t, got.WithHostIDs)
+	require.Len(t, got.Data, 2)
+	require.NotNil(t, got.Data[0].ValidTo)
+	assert.Equal(t, time.Date(2026, 3, 1, 1, 0, 0, 0, time.UTC), *got.Data[0].ValidTo)
+	assert.Nil(t, got.Data[1].ValidTo)
+	assert.Equal(t, []uint{7, 9}, got.Data[1].HostIDs)
+	assert.Empty(t, got.Error)
+
+	// a read error ends the data array with an error
+	w = httptest.NewRecorder()
+	ExportChartDataResponse{Export: testExport(false, errors.New("boom")), Format: ExportFormatJSON}.HijackRender(t.Context(), w)
+	got.Data, got.Error = nil, ""
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Len(t, got.Data, 2)
+	assert.Equal(t, "boom", got.Error)
+}
@@ -13,6 +13,11 @@ type Service interface {
 	// GetChartData returns time-series chart data for the given metric.
 	GetChartData(ctx context.Context, metric string, opts RequestOpts) (*Response, error)
 
+	// ExportChartData returns the raw SCD history of the given metric over an
+	// explicit time range, optionally expanded with the host IDs of each row.
+	// Authorization and filtering match GetChartData.
+	ExportChartData(ctx context.Context, metric string, opts ExportOpts) (*Export, error)
+
 	// RegisterDataset registers a chart dataset.
 	RegisterDataset(ds Dataset)
 
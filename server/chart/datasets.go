@@ -60,3 +60,65 @@ func (c *CVEDataset) Collect(ctx context.Context, store api.DatasetStore, now ti
 	// longer in the tracked set (recordSnapshot's "absent entities" branch).
 	return store.RecordBucketData(ctx, c.Name(), bucketStart, time.Hour, c.SampleStrategy(), bitmaps)
 }
+
+// PolicyDataset implements api.Dataset for policy compliance tracking. Each
+// entity is a policy ID and its bitmap is the set of hosts failing that policy,
+// so the chart answers "how many hosts were failing (these) policies".
+type PolicyDataset struct{}
+
+func (p *PolicyDataset) Name() string                       { return api.MetricPolicy }
+func (p *PolicyDataset) DefaultResolutionHours() int        { return 3 }
+func (p *PolicyDataset) SampleStrategy() api.SampleStrategy { return api.SampleStrategySnapshot }
+func (p *PolicyDataset) DefaultVisualization() string       { return "line" }
+
+func (p *PolicyDataset) Collect(ctx context.Context, store api.DatasetStore, now time.Time, disabledFleetIDs []uint) error {
+	bitmaps, err := store.FailingHostIDsByPolicy(ctx, disabledFleetIDs)
+	if err != nil {
+		return err
+	}
+	bucketStart := now.UTC().Truncate(time.Hour)
+	// Snapshot: always record, even when empty, so rows for policies that were
+	// deleted or are now passing everywhere get closed.
+	return store.RecordBucketData(ctx, p.Name(), bucketStart, time.Hour, p.SampleStrategy(), bitmaps)
+}
+
+// MDMEnrollmentDataset implements api.Dataset for MDM enrollment tracking. Each
+// entity is an enrollment status (manual, automatic, personal), so the chart
+// value is the number of MDM-enrolled hosts.
+type MDMEnrollmentDataset struct{}
+
+func (m *MDMEnrollmentDataset) Name() string                       { return api.MetricMDMEnrollment }
+func (m *MDMEnrollmentDataset) DefaultResolutionHours() int        { return 24 }
+func (m *MDMEnrollmentDataset) SampleStrategy() api.SampleStrategy { return api.SampleStrategySnapshot }
+func (m *MDMEnrollmentDataset) DefaultVisualization() string       { return "line" }
+
+func (m *MDMEnrollmentDataset) Collect(ctx context.Context, store api.DatasetStore, now time.Time, disabledFleetIDs []uint) error {
+	bitmaps, err := store.HostIDsByMDMEnrollmentStatus(ctx, disabledFleetIDs)
+	if err != nil {
+		return err
+	}
+	bucketStart := now.UTC().Truncate(time.Hour)
+	return store.RecordBucketData(ctx, m.Name(), bucketStart, time.Hour, m.SampleStrategy(), bitmaps)
+}
+
+// DiskEncryptionDataset implements api.Dataset for disk encryption tracking.
+type DiskEncryptionDataset struct{}
+
+func (d *DiskEncryptionDataset) Name() string                { return api.MetricDiskEncryption }
+func (d *DiskEncryptionDataset) DefaultResolutionHours() int { return 24 }
+func (d *DiskEncryptionDataset) SampleStrategy() api.SampleStrategy {
+	return api.SampleStrategySnapshot
+}
+func (d *DiskEncryptionDataset) DefaultVisualization() string { return "line" }
+
+func (d *DiskEncryptionDataset) Collect(ctx context.Context, store api.DatasetStore, now time.Time, disabledFleetIDs []uint) error {
+	hostIDs, err := store.FindDiskEncryptedHostIDs(ctx, disabledFleetIDs)
+	if err != nil {
+		return err
+	}
+	bucketStart := now.UTC().Truncate(time.Hour)
+	// Single entity, keyed by the empty string like uptime. Unlike uptime this
+	// is a snapshot, so an empty host set is still recorded to close the row.
+	return store.RecordBucketData(ctx, d.Name(), bucketStart, time.Hour, d.SampleStrategy(),
+		map[string]*roaring.Bitmap{"": NewBitmap(hostIDs)})
+}
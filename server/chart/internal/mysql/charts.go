@@ -159,7 +159,7 @@ func (ds *Datastore) FindOnlineHostIDs(ctx context.Context, now time.Time, disab
 
 // The matcher list exists as a performance optimization that bounds which CVEs
 // the chart collects. Collection RAM is no longer the constraint (bits are set
-// into roaring bitmaps while streaming — see streamCVEHostPairs); the list now
+// into roaring bitmaps while streaming — see streamEntityHostPairs); the list now
 // bounds the join size and the host_scd_data row count per bucket.
 
 // cveSoftwareMatcher filters `software` rows by a MySQL LIKE pattern and an
@@ -211,7 +211,8 @@ var trackedCVESoftwareMatchers = []cveSoftwareMatcher{
 // vulnerabilities) and merges the results into a single map, setting bits
 // while scanning so the raw (cve, host_id) rows — millions on a large fleet —
 // are never materialized. Duplicates across sources are harmless — Bitmap.Add
-// is idempotent.
+// is idempotent. (CVE, host) pairs hidden by an active vulnerability exception
+// are skipped.
 //
 // nil or empty cves returns an empty map without running any query.
 // TODO: support `nil` meaning "all CVEs".
@@ -238,25 +239,50 @@ func (ds *Datastore) AffectedHostIDsByCVE(ctx context.Context, disabledFleetIDs
 	swArgs := []any{cves}
 	osArgs := []any{cves}
 
-	if len(disabledFleetIDs) > 0 {
+	// Likewise, the vulnerability exceptions are only joined when there are
+	// active exceptions.
+	hasExceptions, err := ds.hasActiveVulnerabilityExceptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(disabledFleetIDs) > 0 || hasExceptions {
 		swQuery += `
 			JOIN hosts h ON h.id = hs.host_id`
-		swWhere = append(swWhere, "(h.team_id IS NULL OR h.team_id NOT IN (?))")
-		swArgs = append(swArgs, disabledFleetIDs)
-
 		osQuery += `
 			JOIN hosts h ON h.id = hos.host_id`
+	}
+	if len(disabledFleetIDs) > 0 {
+		swWhere = append(swWhere, "(h.team_id IS NULL OR h.team_id NOT IN (?))")
+		swArgs = append(swArgs, disabledFleetIDs)
 		osWhere = append(osWhere, "(h.team_id IS NULL OR h.team_id NOT IN (?))")
 		osArgs = append(osArgs, disabledFleetIDs)
 	}
+	if hasExceptions {
+		// An exception hides the CVE on the hosts of its fleet (or all hosts),
+		// for the software of its software title (or all software and the
+		// operating systems).
+		swQuery += `
+			JOIN software s ON s.id = sc.software_id`
+		swWhere = append(swWhere, `NOT EXISTS (
+			SELECT 1 FROM vulnerability_exceptions ve
+			WHERE ve.cve = sc.cve AND ve.expires_at > NOW(6)
+			AND (ve.software_title_id IS NULL OR ve.software_title_id = s.title_id)
+			AND (ve.team_id IS NULL OR ve.team_id = h.team_id))`)
+		osWhere = append(osWhere, `NOT EXISTS (
+			SELECT 1 FROM vulnerability_exceptions ve
+			WHERE ve.cve = osv.cve AND ve.expires_at > NOW(6)
+			AND ve.software_title_id IS NULL
+			AND (ve.team_id IS NULL OR ve.team_id = h.team_id))`)
+	}
 
 	swQuery += " WHERE " + strings.Join(swWhere, " AND ")
 	osQuery += " WHERE " + strings.Join(osWhere, " AND ")
 
-	if err := ds.streamCVEHostPairs(ctx, swQuery, swArgs, result); err != nil {
+	if err := ds.streamEntityHostPairs(ctx, swQuery, swArgs, result); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "stream software CVE host pairs")
 	}
-	if err := ds.streamCVEHostPairs(ctx, osQuery, osArgs, result); err != nil {
+	if err := ds.streamEntityHostPairs(ctx, osQuery, osArgs, result); err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "stream OS CVE host pairs")
 	}
 
@@ -269,6 +295,110 @@ func (ds *Datastore) AffectedHostIDsByCVE(ctx context.Context, disabledFleetIDs
 	return result, nil
 }
 
+// hasActiveVulnerabilityExceptions returns whether any vulnerability exception
+// is active (i.e. not expired).
+func (ds *Datastore) hasActiveVulnerabilityExceptions(ctx context.Context) (bool, error) {
+	var exists bool
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &exists,
+		`SELECT EXISTS (SELECT 1 FROM vulnerability_exceptions WHERE expires_at > NOW(6))`); err != nil {
+		return false, ctxerr.Wrap(ctx, err, "check active vulnerability exceptions")
+	}
+	return exists, nil
+}
+
+// FailingHostIDsByPolicy returns a bitmap of failing host IDs per policy, keyed
+// by the decimal policy ID. Only explicit failures (passes = 0) are counted; a
+// NULL result means the host hasn't run the policy yet (or the policy doesn't
+// apply to its platform), which is neither compliant nor failing.
+//
+// Team policies only ever have membership rows for hosts in that team, so
+// excluding hosts from disabled fleets also drops those fleets' policies.
+func (ds *Datastore) FailingHostIDsByPolicy(ctx context.Context, disabledFleetIDs []uint) (map[string]*roaring.Bitmap, error) {
+	result := make(map[string]*roaring.Bitmap)
+
+	query := `
+		SELECT CAST(pm.policy_id AS CHAR), pm.host_id
+		FROM policy_membership pm`
+	where := []string{"pm.passes = 0"}
+	var args []any
+	if len(disabledFleetIDs) > 0 {
+		query += `
+			JOIN hosts h ON h.id = pm.host_id`
+		where = append(where, "(h.team_id IS NULL OR h.team_id NOT IN (?))")
+		args = append(args, disabledFleetIDs)
+	}
+	query += " WHERE " + strings.Join(where, " AND ")
+
+	if err := ds.streamEntityHostPairs(ctx, query, args, result); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "stream failing policy host pairs")
+	}
+	for _, rb := range result {
+		rb.RunOptimize()
+	}
+	return result, nil
+}
+
+// HostIDsByMDMEnrollmentStatus returns a bitmap of MDM-enrolled host IDs per
+// enrollment status, read from the host_mdm.enrollment_status generated column
+// so the classification matches the rest of the product. Pending, off and
+// server rows (NULL status) are not enrolled and are filtered out.
+func (ds *Datastore) HostIDsByMDMEnrollmentStatus(ctx context.Context, disabledFleetIDs []uint) (map[string]*roaring.Bitmap, error) {
+	result := make(map[string]*roaring.Bitmap)
+
+	query := `
+		SELECT
+			CASE hm.enrollment_status
+				WHEN 'On (manual)' THEN ?
+				WHEN 'On (automatic)' THEN ?
+				WHEN 'On (manual - personal)' THEN ?
+			END AS status,
+			hm.host_id
+		FROM host_mdm hm
+		JOIN hosts h ON h.id = hm.host_id
+		WHERE hm.enrollment_status IN ('On (manual)', 'On (automatic)', 'On (manual - personal)')`
+	args := []any{api.MDMEnrollmentStatusManual, api.MDMEnrollmentStatusAutomatic, api.MDMEnrollmentStatusPersonal}
+	if len(disabledFleetIDs) > 0 {
+		query += ` AND (h.team_id IS NULL OR h.team_id NOT IN (?))`
+		args = append(args, disabledFleetIDs)
+	}
+
+	if err := ds.streamEntityHostPairs(ctx, query, args, result); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "stream MDM enrollment host pairs")
+	}
+	for _, rb := range result {
+		rb.RunOptimize()
+	}
+	return result, nil
+}
+
+// FindDiskEncryptedHostIDs returns host IDs whose host_disks.encrypted flag is
+// set. NULL means the host hasn't reported encryption status and is treated as
+// not encrypted.
+func (ds *Datastore) FindDiskEncryptedHostIDs(ctx context.Context, disabledFleetIDs []uint) ([]uint, error) {
+	query := `
+		SELECT hd.host_id
+		FROM host_disks hd
+		JOIN hosts h ON h.id = hd.host_id
+		WHERE hd.encrypted = 1`
+	var args []any
+	if len(disabledFleetIDs) > 0 {
+		query += ` AND (h.team_id IS NULL OR h.team_id NOT IN (?))`
+		args = append(args, disabledFleetIDs)
+	}
+
+	expanded, expandedArgs, err := sqlx.In(query, args...)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "expand disk encrypted host args")
+	}
+	expanded = ds.rebind(expanded)
+
+	var ids []uint
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &ids, expanded, expandedArgs...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "find disk encrypted host IDs")
+	}
+	return ids, nil
+}
+
 // CollectibleCVEs returns the deduplicated set of CVE IDs, at all severities,
 // that are (a) linked to any `software` row matching trackedCVESoftwareMatchers,
 // OR (b) present in `operating_system_vulnerabilities`. This is the wide set the
@@ -487,24 +617,25 @@ func streamCVEStrings(ctx context.Context, q sqlx.QueryerContext, query string,
 	return rows.Err()
 }
 
-// streamCVEHostPairs runs a query yielding (cve, host_id) pairs and sets each
-// host's bit in out's bitmap for that CVE, allocating the bitmap on first
-// sight of the CVE. Setting bits while scanning keeps peak memory at one
-// bitmap per CVE (KBs even at 50k hosts) instead of retaining every raw
-// (cve, host_id) pair, whose row count can reach many millions on a large
-// fleet. Duplicate pairs — several matching software rows on one host, or
-// overlap between the software and OS queries — are no-op Adds.
+// streamEntityHostPairs runs a query yielding (entity, host_id) pairs — e.g.
+// (cve, host_id) or (policy_id, host_id) — and sets each host's bit in out's
+// bitmap for that entity, allocating the bitmap on first sight of the entity.
+// Setting bits while scanning keeps peak memory at one bitmap per entity (KBs
+// even at 50k hosts) instead of retaining every raw pair, whose row count can
+// reach many millions on a large fleet. Duplicate pairs — several matching
+// software rows on one host, or overlap between the software and OS queries —
+// are no-op Adds.
 //
 // Host IDs of 0 or above MaxUint32 are skipped, mirroring chart.NewBitmap —
 // Fleet host IDs are AUTO_INCREMENT starting at 1.
 //
 // args are expanded via sqlx.In for slice arguments (e.g. team IDs) and
 // rebinds to the driver dialect.
-func (ds *Datastore) streamCVEHostPairs(ctx context.Context, query string, args []any, out map[string]*roaring.Bitmap) error {
+func (ds *Datastore) streamEntityHostPairs(ctx context.Context, query string, args []any, out map[string]*roaring.Bitmap) error {
 	if len(args) > 0 {
 		expanded, expandedArgs, err := sqlx.In(query, args...)
 		if err != nil {
-			return ctxerr.Wrap(ctx, err, "expand CVE-host-pair args")
+			return ctxerr.Wrap(ctx, err, "expand entity-host-pair args")
 		}
 		query = ds.rebind(expanded)
 		args = expandedArgs
@@ -518,19 +649,19 @@ func (ds *Datastore) streamCVEHostPairs(ctx context.Context, query string, args
 	}
 	defer rows.Close()
 
-	var cve string
+	var entityID string
 	var hostID uint
 	for rows.Next() {
-		if err := rows.Scan(&cve, &hostID); err != nil {
+		if err := rows.Scan(&entityID, &hostID); err != nil {
 			return err
 		}
 		if hostID == 0 || hostID > math.MaxUint32 {
 			continue
 		}
-		rb, ok := out[cve]
+		rb, ok := out[entityID]
 		if !ok {
 			rb = roaring.New()
-			out[cve] = rb
+			out[entityID] = rb
 		}
 		rb.Add(uint32(hostID))
 	}
@@ -415,7 +415,7 @@ func u32(id uint) uint32 {
 // grouped per CVE as bitmaps, duplicate (cve, host) rows (several vulnerable
 // software rows on one host) collapse to a single bit, software- and OS-level
 // sources merge, the cves argument scopes the result, and hosts in disabled
-// fleets are excluded.
+// fleets or covered by an active vulnerability exception are excluded.
 func TestAffectedHostIDsByCVE(t *testing.T) {
 	tdb := testutils.SetupTestDB(t, "chart_mysql")
 	defer tdb.TruncateTables(t)
@@ -466,6 +466,28 @@ func TestAffectedHostIDsByCVE(t *testing.T) {
 		assert.Empty(t, got, "a CVE whose only affected hosts are excluded must not appear")
 	})
 
+	t.Run("ActiveExceptionsExcluded", func(t *testing.T) {
+		const insertStmt = `INSERT INTO vulnerability_exceptions
+			(cve, team_id, global_or_team_id, reason, owner, expires_at) VALUES (?, ?, ?, 'accepted', 'secops', ?)`
+		// CVE-A is excepted on fleet 1 only, CVE-B everywhere (software and OS),
+		// and the expired exception of CVE-A everywhere has no effect.
+		_, err := tdb.DB.ExecContext(ctx, insertStmt, "CVE-A", 1, 1, now.Add(time.Hour))
+		require.NoError(t, err)
+		_, err = tdb.DB.ExecContext(ctx, insertStmt, "CVE-B", nil, 0, now.Add(time.Hour))
+		require.NoError(t, err)
+		_, err = tdb.DB.ExecContext(ctx, insertStmt, "CVE-A", nil, 0, now.Add(-time.Hour))
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_, err := tdb.DB.ExecContext(ctx, `DELETE FROM vulnerability_exceptions`)
+			require.NoError(t, err)
+		})
+
+		got, err := ds.AffectedHostIDsByCVE(ctx, nil, []string{"CVE-A", "CVE-B"})
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		assert.Equal(t, []uint32{u32(ids[0])}, got["CVE-A"].ToArray())
+	})
+
 	t.Run("EmptyCVEsShortCircuits", func(t *testing.T) {
 		got, err := ds.AffectedHostIDsByCVE(ctx, nil, nil)
 		require.NoError(t, err)
@@ -488,3 +510,140 @@ func testFindOnlineMobileDisabledFleet(t *testing.T, tdb *testutils.TestDB, ds *
 	require.NoError(t, err)
 	assert.ElementsMatch(t, []uint{ids[1]}, got)
 }
+
+// seedPolicyResult creates the policy on first use (policy_membership has an FK
+// to policies) and records the host's result. passes == nil stores NULL, i.e.
+// the host hasn't reported a result yet.
+func seedPolicyResult(t *testing.T, tdb *testutils.TestDB, policyID, hostID uint, passes *bool) {
+	t.Helper()
+	ctx := t.Context()
+
+	sum := sha256.Sum256([]byte("policy-" + itoa(policyID)))
+	_, err := tdb.DB.ExecContext(ctx, `
+		INSERT IGNORE INTO policies (id, name, query, description, checksum)
+		VALUES (?, ?, 'SELECT 1', '', ?)`,
+		policyID, "policy-"+itoa(policyID), sum[:16])
+	require.NoError(t, err)
+	_, err = tdb.DB.ExecContext(ctx,
+		`INSERT INTO policy_membership (policy_id, host_id, passes) VALUES (?, ?, ?)`,
+		policyID, hostID, passes)
+	require.NoError(t, err)
+}
+
+// TestFailingHostIDsByPolicy covers the policy collector: only explicit
+// failures count, results are grouped per policy ID, and hosts in disabled
+// fleets are excluded.
+func TestFailingHostIDsByPolicy(t *testing.T) {
+	tdb := testutils.SetupTestDB(t, "chart_mysql")
+	defer tdb.TruncateTables(t)
+	ds := NewDatastore(tdb.Conns(), tdb.Logger)
+	ctx := t.Context()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	ids := seedHosts(t, tdb, []hostSeed{
+		{teamID: 0, seenTime: now},
+		{teamID: 1, seenTime: now},
+		{teamID: 2, seenTime: now},
+	})
+	pass, fail := true, false
+	seedPolicyResult(t, tdb, 1, ids[0], &fail)
+	seedPolicyResult(t, tdb, 1, ids[1], &fail)
+	seedPolicyResult(t, tdb, 1, ids[2], &pass)
+	seedPolicyResult(t, tdb, 2, ids[2], &fail)
+	seedPolicyResult(t, tdb, 3, ids[0], nil)
+
+	t.Run("GroupsFailuresByPolicy", func(t *testing.T) {
+		got, err := ds.FailingHostIDsByPolicy(ctx, nil)
+		require.NoError(t, err)
+		require.Len(t, got, 2, "policy 3 only has a NULL result and must not appear")
+		assert.Equal(t, []uint32{u32(ids[0]), u32(ids[1])}, got["1"].ToArray())
+		assert.Equal(t, []uint32{u32(ids[2])}, got["2"].ToArray())
+	})
+
+	t.Run("DisabledFleetsExcluded", func(t *testing.T) {
+		got, err := ds.FailingHostIDsByPolicy(ctx, []uint{2})
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		assert.Equal(t, []uint32{u32(ids[0]), u32(ids[1])}, got["1"].ToArray())
+	})
+}
+
+// TestHostIDsByMDMEnrollmentStatus covers the MDM enrollment collector: hosts
+// are bucketed by enrollment type, and pending, off and server rows are
+// skipped.
+func TestHostIDsByMDMEnrollmentStatus(t *testing.T) {
+	tdb := testutils.SetupTestDB(t, "chart_mysql")
+	defer tdb.TruncateTables(t)
+	ds := NewDatastore(tdb.Conns(), tdb.Logger)
+	ctx := t.Context()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	ids := seedHosts(t, tdb, []hostSeed{
+		{teamID: 0, seenTime: now}, // manual
+		{teamID: 1, seenTime: now}, // automatic
+		{teamID: 0, seenTime: now}, // personal
+		{teamID: 0, seenTime: now}, // pending
+		{teamID: 0, seenTime: now}, // off
+		{teamID: 0, seenTime: now}, // enrolled server
+	})
+	rows := []struct {
+		hostID                                uint
+		enrolled, fromDEP, personal, isServer int
+	}{
+		{ids[0], 1, 0, 0, 0},
+		{ids[1], 1, 1, 0, 0},
+		{ids[2], 1, 0, 1, 0},
+		{ids[3], 0, 1, 0, 0},
+		{ids[4], 0, 0, 0, 0},
+		{ids[5], 1, 0, 0, 1},
+	}
+	for _, r := range rows {
+		_, err := tdb.DB.ExecContext(ctx, `
+			INSERT INTO host_mdm (host_id, enrolled, installed_from_dep, is_personal_enrollment, is_server)
+			VALUES (?, ?, ?, ?, ?)`, r.hostID, r.enrolled, r.fromDEP, r.personal, r.isServer)
+		require.NoError(t, err)
+	}
+
+	got, err := ds.HostIDsByMDMEnrollmentStatus(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, []uint32{u32(ids[0])}, got["manual"].ToArray())
+	assert.Equal(t, []uint32{u32(ids[1])}, got["automatic"].ToArray())
+	assert.Equal(t, []uint32{u32(ids[2])}, got["personal"].ToArray())
+
+	got, err = ds.HostIDsByMDMEnrollmentStatus(ctx, []uint{1})
+	require.NoError(t, err)
+	assert.NotContains(t, got, "automatic", "the only automatic host is in a disabled fleet")
+}
+
+// TestFindDiskEncryptedHostIDs covers the disk encryption collector: only
+// hosts reporting encrypted = 1 are returned and a NULL report is treated as
+// unencrypted.
+func TestFindDiskEncryptedHostIDs(t *testing.T) {
+	tdb := testutils.SetupTestDB(t, "chart_mysql")
+	defer tdb.TruncateTables(t)
+	ds := NewDatastore(tdb.Conns(), tdb.Logger)
+	ctx := t.Context()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	ids := seedHosts(t, tdb, []hostSeed{
+		{teamID: 0, seenTime: now},
+		{teamID: 1, seenTime: now},
+		{teamID: 0, seenTime: now},
+		{teamID: 0, seenTime: now},
+	})
+	encrypted := []any{1, 1, 0, nil}
+	for i, enc := range encrypted {
+		_, err := tdb.DB.ExecContext(ctx,
+			`INSERT INTO host_disks (host_id, encrypted) VALUES (?, ?)`, ids[i], enc)
+		require.NoError(t, err)
+	}
+
+	got, err := ds.FindDiskEncryptedHostIDs(ctx, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{ids[0], ids[1]}, got)
+
+	got, err = ds.FindDiskEncryptedHostIDs(ctx, []uint{1})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint{ids[0]}, got)
+}
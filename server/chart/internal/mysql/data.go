@@ -3,12 +3,14 @@ package mysql
 import (
 	"context"
 	"fmt"
+	"iter"
 	"strings"
 	"time"
 
 	"github.com/RoaringBitmap/roaring"
 	"github.com/fleetdm/fleet/v4/server/chart"
 	"github.com/fleetdm/fleet/v4/server/chart/api"
+	"github.com/fleetdm/fleet/v4/server/chart/internal/types"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxdb"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/jmoiron/sqlx"
@@ -38,7 +40,8 @@ const scdScrubWriteByteBudget = 2_000_000
 // tests can shrink it to exercise multi-batch behavior.
 var scdScrubWriteBatchCap = 1000
 
-// scdRow is a single row of host_scd_data as fetched by GetSCDData.
+// scdRow is a single row of host_scd_data as fetched by GetSCDData and
+// StreamSCDRows.
 type scdRow struct {
 	EntityID     string    `db:"entity_id"`
 	HostBitmap   []byte    `db:"host_bitmap"`
@@ -321,18 +324,7 @@ func (ds *Datastore) GetSCDData(
 	// walker filters precisely per bucket; this just narrows the scan.
 	firstBucketStart := startDate.Add(bucketSize)
 	lastBucketEnd := endDate.Add(bucketSize)
-	args := []any{dataset, lastBucketEnd, firstBucketStart}
-	var entityClause string
-	switch {
-	case entityIDs == nil:
-		// no clause — match every entity for this dataset
-	case len(entityIDs) == 0:
-		// explicit empty set — match nothing; avoids MySQL syntax error from `IN ()`
-		entityClause = " AND 1=0"
-	default:
-		entityClause = " AND entity_id IN (?)"
-		args = append(args, entityIDs)
-	}
+	entityClause, args := scdEntityClause(entityIDs, []any{dataset, lastBucketEnd, firstBucketStart})
 
 	query := fmt.Sprintf(`
 		SELECT entity_id, host_bitmap, encoding_type, valid_from, valid_to
@@ -381,6 +373,96 @@ func (ds *Datastore) GetSCDData(
 	return results, nil
 }
 
+// StreamSCDRows runs the query for the rows of dataset whose validity
+// interval overlaps [startTime, endTime), ordered by valid_from then entity_id,
+// and returns an iterator that reads and decodes one row at a time from the
+// cursor. Query errors are returned before any row is read; read and decode
+// errors are yielded and end the iteration. The cursor is closed once the
+// iteration ends or ctx is done.
+//
+// filterMask is AND-ed into every row's bitmap, and rows left with no hosts
+// are skipped so a caller never sees entities that only concern hosts it
+// can't see.
+func (ds *Datastore) StreamSCDRows(
+	ctx context.Context,
+	dataset string,
+	startTime, endTime time.Time,
+	filterMask *roaring.Bitmap,
+	entityIDs []string,
+) (iter.Seq2[types.SCDRecord, error], error) {
+	entityClause, args := scdEntityClause(entityIDs, []any{dataset, endTime.UTC(), startTime.UTC()})
+
+	query := fmt.Sprintf(`
+		SELECT entity_id, host_bitmap, encoding_type, valid_from, valid_to
+		FROM host_scd_data
+		WHERE dataset = ?
+			AND valid_from <  ?
+			AND valid_to   >  ?%s
+		ORDER BY valid_from, entity_id`, entityClause)
+
+	expanded, expandedArgs, err := sqlx.In(query, args...)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "expand SCD query args")
+	}
+	expanded = ds.rebind(expanded)
+
+	// sqlclosecheck can't see through the QueryerContext interface to verify
+	// Close() is reached. The iterator below closes the rows.
+	rows, err := ds.reader(ctx).QueryxContext(ctx, expanded, expandedArgs...) //nolint:sqlclosecheck
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "stream SCD rows")
+	}
+
+	return func(yield func(types.SCDRecord, error) bool) {
+		defer rows.Close()
+
+		for rows.Next() {
+			var r scdRow
+			if err := rows.StructScan(&r); err != nil {
+				yield(types.SCDRecord{}, ctxerr.Wrap(ctx, err, "scan SCD row"))
+				return
+			}
+			hosts, err := chart.DecodeBitmap(chart.Blob{Bytes: r.HostBitmap, Encoding: r.EncodingType})
+			if err != nil {
+				yield(types.SCDRecord{}, ctxerr.Wrapf(ctx, err, "decode bitmap for entity %q", r.EntityID))
+				return
+			}
+			if filterMask != nil {
+				hosts = chart.BlobAND(hosts, filterMask)
+			}
+			if hosts.IsEmpty() {
+				continue
+			}
+			rec := types.SCDRecord{EntityID: r.EntityID, ValidFrom: r.ValidFrom, Hosts: hosts}
+			if !r.ValidTo.Equal(scdOpenSentinel) {
+				rec.ValidTo = &r.ValidTo
+			}
+			if !yield(rec, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(types.SCDRecord{}, ctxerr.Wrap(ctx, err, "stream SCD rows"))
+		}
+	}, nil
+}
+
+// scdEntityClause returns the host_scd_data entity filter for entityIDs and
+// appends its arguments to args: nil matches every entity, an empty non-nil
+// slice matches none.
+func scdEntityClause(entityIDs []string, args []any) (string, []any) {
+	switch {
+	case entityIDs == nil:
+		// no clause — match every entity for this dataset
+		return "", args
+	case len(entityIDs) == 0:
+		// explicit empty set — match nothing; avoids MySQL syntax error from `IN ()`
+		return " AND 1=0", args
+	default:
+		return " AND entity_id IN (?)", append(args, entityIDs)
+	}
+}
+
 // decodedSCDRow is the in-memory op-form view of an scdRow, produced by
 // decoding the storage-form bytes once at SELECT time and shared across the
 // per-bucket aggregation walk.
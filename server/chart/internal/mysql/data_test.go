@@ -10,6 +10,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/chart"
 	"github.com/fleetdm/fleet/v4/server/chart/api"
 	"github.com/fleetdm/fleet/v4/server/chart/internal/testutils"
+	"github.com/fleetdm/fleet/v4/server/chart/internal/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -355,6 +356,68 @@ func TestGetSCDDataEmptyEntityIDsReturnsZeroBuckets(t *testing.T) {
 	}
 }
 
+// TestStreamSCDRows checks that the export stream yields the raw rows
+// overlapping the range in valid_from order, masked, without the rows left
+// empty by the mask, and that stopping early is honored.
+func TestStreamSCDRows(t *testing.T) {
+	tdb := testutils.SetupTestDB(t, "chart_mysql")
+	ds := NewDatastore(tdb.Conns(), tdb.Logger)
+
+	start := time.Date(2026, 4, 21, 0, 0, 0, 0, time.UTC)
+	end := start.Add(3 * time.Hour)
+
+	// before the range
+	tdb.InsertSCDRowWithHostIDs(t, "policy", "1", []uint{1}, start.Add(-2*time.Hour), start)
+	tdb.InsertSCDRowWithHostIDs(t, "policy", "2", []uint{1, 2, 3}, start.Add(-time.Hour), start.Add(time.Hour))
+	tdb.InsertSCDRowWithHostIDs(t, "policy", "1", []uint{3, 4}, start.Add(time.Hour), scdOpenSentinel)
+	// only hosts outside the mask
+	tdb.InsertSCDRowWithHostIDs(t, "policy", "3", []uint{2}, start.Add(time.Hour), scdOpenSentinel)
+	// after the range
+	tdb.InsertSCDRowWithHostIDs(t, "policy", "2", []uint{1}, end, scdOpenSentinel)
+	// another dataset
+	tdb.InsertSCDRowWithHostIDs(t, "uptime", "", []uint{1}, start, scdOpenSentinel)
+
+	mask := chart.NewBitmap([]uint{1, 3, 4})
+	rows, err := ds.StreamSCDRows(t.Context(), "policy", start, end, mask, nil)
+	require.NoError(t, err)
+
+	var got []types.SCDRecord
+	for rec, err := range rows {
+		require.NoError(t, err)
+		got = append(got, rec)
+	}
+	require.Len(t, got, 2)
+	assert.Equal(t, "2", got[0].EntityID)
+	assert.Equal(t, start.Add(-time.Hour), got[0].ValidFrom)
+	require.NotNil(t, got[0].ValidTo)
+	assert.Equal(t, start.Add(time.Hour), *got[0].ValidTo)
+	assert.Equal(t, []uint32{1, 3}, got[0].Hosts.ToArray())
+	assert.Equal(t, "1", got[1].EntityID)
+	assert.Nil(t, got[1].ValidTo, "open rows have no valid_to")
+	assert.Equal(t, []uint32{3, 4}, got[1].Hosts.ToArray())
+
+	// entity filter
+	rows, err = ds.StreamSCDRows(t.Context(), "policy", start, end, mask, []string{"1"})
+	require.NoError(t, err)
+	var n int
+	for rec, err := range rows {
+		require.NoError(t, err)
+		assert.Equal(t, "1", rec.EntityID)
+		n++
+	}
+	assert.Equal(t, 1, n)
+
+	// stopping early closes the cursor
+	rows, err = ds.StreamSCDRows(t.Context(), "policy", start, end, nil, nil)
+	require.NoError(t, err)
+	n = 0
+	for range rows {
+		n++
+		break
+	}
+	assert.Equal(t, 1, n)
+}
+
 func testScrubOtherDatasetUnaffected(t *testing.T, tdb *testutils.TestDB, ds *Datastore) {
 	now := time.Now().UTC()
 
@@ -2,6 +2,8 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/pkg/str"
 	"github.com/fleetdm/fleet/v4/server/chart/api"
@@ -25,6 +27,7 @@ func attachFleetAPIRoutes(r *mux.Router, svc api.Service, authMiddleware endpoin
 	apiVersions := []string{"v1", "2022-04"}
 	ue := newChartEndpointer(svc, authMiddleware, opts, r, apiVersions...)
 	ue.GET("/api/_version_/fleet/charts/{metric}", getChartDataEndpoint, api_http.GetChartDataRequest{})
+	ue.GET("/api/_version_/fleet/charts/{metric}/export", exportChartDataEndpoint, api_http.ExportChartDataRequest{})
 }
 
 func getChartDataEndpoint(ctx context.Context, request any, svc api.Service) (platform_http.Errorer, error) {
@@ -35,24 +38,10 @@ func getChartDataEndpoint(ctx context.Context, request any, svc api.Service) (pl
 		days = 7
 	}
 
-	opts := api.RequestOpts{
-		Days:            days,
-		Resolution:      req.Resolution,
-		TZOffsetMinutes: req.TZOffset,
-		TeamID:          req.TeamID,
-		LabelIDs:        str.ParseUintList(req.LabelIDs),
-		Platforms:       str.ParseStringList(req.Platforms),
-		IncludeHostIDs:  str.ParseUintList(req.IncludeHostIDs),
-		ExcludeHostIDs:  str.ParseUintList(req.ExcludeHostIDs),
-
-		SoftwareFilters: str.ParseStringList(req.SoftwareFilters),
-		KnownExploit:    req.KnownExploit,
-		EPSSMin:         req.EPSSMin,
-		EPSSMax:         req.EPSSMax,
-		SeverityMin:     req.SeverityMin,
-		SeverityMax:     req.SeverityMax,
-		ExcludeCVEs:     str.ParseStringList(req.ExcludeCVEs),
-	}
+	opts := filterOpts(req.FilterParams)
+	opts.Days = days
+	opts.Resolution = req.Resolution
+	opts.TZOffsetMinutes = req.TZOffset
 
 	resp, err := svc.GetChartData(ctx, req.Metric, opts)
 	if err != nil {
@@ -60,3 +49,72 @@ func getChartDataEndpoint(ctx context.Context, request any, svc api.Service) (pl
 	}
 	return api_http.GetChartDataResponse{Response: resp}, nil
 }
+
+func exportChartDataEndpoint(ctx context.Context, request any, svc api.Service) (platform_http.Errorer, error) {
+	req := request.(*api_http.ExportChartDataRequest)
+
+	format := req.Format
+	if format == "" {
+		format = api_http.ExportFormatJSON
+	}
+	if format != api_http.ExportFormatJSON && format != api_http.ExportFormatCSV {
+		return api_http.ExportChartDataResponse{Err: &platform_http.BadRequestError{
+			Message: fmt.Sprintf("invalid format: %q (must be %q or %q)", req.Format, api_http.ExportFormatJSON, api_http.ExportFormatCSV),
+		}}, nil
+	}
+
+	opts := api.ExportOpts{
+		RequestOpts: filterOpts(req.FilterParams),
+		WithHostIDs: req.WithHostIDs,
+	}
+
+	var err error
+	if opts.StartTime, err = parseExportTime("start_time", req.StartTime); err != nil {
+		return api_http.ExportChartDataResponse{Err: err}, nil
+	}
+	if opts.EndTime, err = parseExportTime("end_time", req.EndTime); err != nil {
+		return api_http.ExportChartDataResponse{Err: err}, nil
+	}
+
+	export, err := svc.ExportChartData(ctx, req.Metric, opts)
+	if err != nil {
+		return api_http.ExportChartDataResponse{Err: err}, nil
+	}
+	return api_http.ExportChartDataResponse{Export: export, Format: format}, nil
+}
+
+// filterOpts converts the shared filter query parameters to request options.
+func filterOpts(p api_http.FilterParams) api.RequestOpts {
+	return api.RequestOpts{
+		TeamID:         p.TeamID,
+		LabelIDs:       str.ParseUintList(p.LabelIDs),
+		Platforms:      str.ParseStringList(p.Platforms),
+		IncludeHostIDs: str.ParseUintList(p.IncludeHostIDs),
+		ExcludeHostIDs: str.ParseUintList(p.ExcludeHostIDs),
+
+		SoftwareFilters: str.ParseStringList(p.SoftwareFilters),
+		KnownExploit:    p.KnownExploit,
+		EPSSMin:         p.EPSSMin,
+		EPSSMax:         p.EPSSMax,
+		SeverityMin:     p.SeverityMin,
+		SeverityMax:     p.SeverityMax,
+		ExcludeCVEs:     str.ParseStringList(p.ExcludeCVEs),
+
+		PolicyIDs: str.ParseUintList(p.PolicyIDs),
+	}
+}
+
+// parseExportTime parses an optional RFC 3339 query parameter. Empty yields
+// the zero time, which the service replaces with its default.
+func parseExportTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, &platform_http.BadRequestError{
+			Message: fmt.Sprintf("invalid %s value: %q (must be an RFC 3339 timestamp)", name, value),
+		}
+	}
+	return t, nil
+}
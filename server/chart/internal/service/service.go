@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/RoaringBitmap/roaring"
@@ -77,16 +78,126 @@ func (s *Service) CollectDatasets(ctx context.Context, now time.Time, scope api.
 }
 
 func (s *Service) GetChartData(ctx context.Context, metric string, opts api.RequestOpts) (*api.Response, error) {
+	dataset, scope, err := s.authorizeChart(ctx, metric, opts.TeamID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't allow requesting more days than the charts are designed to handle.
+	// This mostly prevents expensive queries for large day ranges.
+	if opts.Days < 1 || opts.Days > 31 {
+		return nil, &platform_http.BadRequestError{Message: fmt.Sprintf("invalid days value: %d (must be between 1 and 31)", opts.Days)}
+	}
+
+	bucketSize, err := validateChartOpts(metric, dataset, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate, endDate := computeBucketRange(time.Now(), bucketSize, opts.Days, opts.TZOffsetMinutes)
+
+	filterMask, entityIDs, err := s.resolveChartScope(ctx, metric, opts, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := s.store.GetSCDData(ctx, metric, startDate, endDate, bucketSize, dataset.SampleStrategy(), filterMask, entityIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Response{
+		Metric:        metric,
+		Visualization: dataset.DefaultVisualization(),
+		TotalHosts:    int(chart.BlobPopcount(filterMask)), //nolint:gosec // host counts fit comfortably in int
+		Resolution:    formatResolution(bucketSize),
+		Days:          opts.Days,
+		Filters:       appliedFilters(opts),
+		Data:          data,
+	}, nil
+}
+
+func (s *Service) ExportChartData(ctx context.Context, metric string, opts api.ExportOpts) (*api.Export, error) {
+	dataset, scope, err := s.authorizeChart(ctx, metric, opts.TeamID)
+	if err != nil {
+		return nil, err
+	}
+
+	// An export isn't bucketed, only the entity filters need validating.
+	opts.Resolution = 0
+	if _, err := validateChartOpts(metric, dataset, opts.RequestOpts); err != nil {
+		return nil, err
+	}
+
+	startTime, endTime := computeExportRange(time.Now(), opts.StartTime, opts.EndTime)
+	if !startTime.Before(endTime) {
+		return nil, &platform_http.BadRequestError{Message: "invalid export range: start_time must be before end_time"}
+	}
+
+	filterMask, entityIDs, err := s.resolveChartScope(ctx, metric, opts.RequestOpts, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.store.StreamSCDRows(ctx, metric, startTime, endTime, filterMask, entityIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Export{
+		Metric:      metric,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		TotalHosts:  int(chart.BlobPopcount(filterMask)), //nolint:gosec // host counts fit comfortably in int
+		WithHostIDs: opts.WithHostIDs,
+		Filters:     appliedFilters(opts.RequestOpts),
+		Rows: func(yield func(api.ExportRow, error) bool) {
+			for rec, err := range rows {
+				if err != nil {
+					yield(api.ExportRow{}, err)
+					return
+				}
+				row := api.ExportRow{
+					EntityID:  rec.EntityID,
+					ValidFrom: rec.ValidFrom,
+					ValidTo:   rec.ValidTo,
+					HostCount: int(chart.BlobPopcount(rec.Hosts)), //nolint:gosec // host counts fit comfortably in int
+				}
+				if opts.WithHostIDs {
+					row.HostIDs = make([]uint, 0, rec.Hosts.GetCardinality())
+					for it := rec.Hosts.Iterator(); it.HasNext(); {
+						row.HostIDs = append(row.HostIDs, uint(it.Next()))
+					}
+				}
+				if !yield(row, nil) {
+					return
+				}
+			}
+		},
+	}, nil
+}
+
+// viewerScope is the viewer's team visibility, as reported by the
+// ViewerProvider.
+type viewerScope struct {
+	isGlobal bool
+	teamIDs  []uint
+}
+
+// authorizeChart resolves the viewer's scope, authorizes the request and looks
+// up the dataset for metric. Shared by every read path so chart data and
+// exports are gated identically.
+func (s *Service) authorizeChart(ctx context.Context, metric string, teamID *uint) (api.Dataset, viewerScope, error) {
 	// Resolve scope first: for authz we need the right action + subject, and
 	// for data we need the effective team set. Fail closed if there's no
 	// viewer — the authenticated middleware should have placed one in ctx.
 	isGlobal, viewerTeamIDs, err := s.viewer.ViewerScope(ctx)
 	if err != nil {
-		return nil, err
+		return nil, viewerScope{}, err
 	}
 
 	// Build the authz subject + action. Two distinct cases:
-	//   - Explicit team_id: Host{TeamID: opts.TeamID} + ActionRead. Rego's
+	//   - Explicit team_id: Host{TeamID: teamID} + ActionRead. Rego's
 	//     read rule for hosts requires team_role(subject, object.team_id) to
 	//     match, so a team user asking for a team they don't have a role on
 	//     is rejected by policy (not by us). Global users pass via the
@@ -94,44 +205,43 @@ func (s *Service) GetChartData(ctx context.Context, metric string, opts api.Requ
 	//   - No team_id: Host{} + ActionList. Rego's list rules pass global
 	//     users unconditionally and pass team users who have a list-capable
 	//     role on any of their teams. The service then scopes data below.
-	authzSubject := &api.Host{TeamID: opts.TeamID}
+	authzSubject := &api.Host{TeamID: teamID}
 	authzAction := platform_authz.ActionRead
-	if opts.TeamID == nil {
+	if teamID == nil {
 		authzAction = platform_authz.ActionList
 	}
 	if err := s.authz.Authorize(ctx, authzSubject, authzAction); err != nil {
-		return nil, err
+		return nil, viewerScope{}, err
 	}
 
 	dataset, ok := s.datasets[metric]
 	if !ok {
-		return nil, &platform_http.BadRequestError{Message: fmt.Sprintf("unknown chart metric: %s", metric)}
+		return nil, viewerScope{}, &platform_http.BadRequestError{Message: fmt.Sprintf("unknown chart metric: %s", metric)}
 	}
 
 	if metric == api.MetricCVE && !license.IsPremium(ctx) {
-		return nil, platform_http.NewUserMessageError(
+		return nil, viewerScope{}, platform_http.NewUserMessageError(
 			errors.New("the vulnerability exposure chart requires a Fleet Premium license"),
 			http.StatusPaymentRequired,
 		)
 	}
+	return dataset, viewerScope{isGlobal: isGlobal, teamIDs: viewerTeamIDs}, nil
+}
 
-	// Don't allow requesting more days than the charts are designed to handle.
-	// This mostly prevents expensive queries for large day ranges.
-	if opts.Days < 1 || opts.Days > 31 {
-		return nil, &platform_http.BadRequestError{Message: fmt.Sprintf("invalid days value: %d (must be between 1 and 31)", opts.Days)}
-	}
-
+// validateChartOpts validates the resolution and the metric-specific entity
+// filters, and returns the bucket size to use.
+func validateChartOpts(metric string, dataset api.Dataset, opts api.RequestOpts) (time.Duration, error) {
 	// Resolution must be 0 or a positive divisor of 24.
 	if opts.Resolution < 0 || (opts.Resolution != 0 && 24%opts.Resolution != 0) {
-		return nil, &platform_http.BadRequestError{Message: fmt.Sprintf("invalid resolution value: %d (must be 0 or a positive divisor of 24)", opts.Resolution)}
+		return 0, &platform_http.BadRequestError{Message: fmt.Sprintf("invalid resolution value: %d (must be 0 or a positive divisor of 24)", opts.Resolution)}
 	}
 
 	if metric == api.MetricCVE {
 		if err := validateScoreBounds("severity", opts.SeverityMin, opts.SeverityMax, cvssMinScore, cvssMaxScore); err != nil {
-			return nil, err
+			return 0, err
 		}
 		if err := validateScoreBounds("epss", opts.EPSSMin, opts.EPSSMax, epssMinScore, epssMaxScore); err != nil {
-			return nil, err
+			return 0, err
 		}
 	}
 
@@ -139,15 +249,17 @@ func (s *Service) GetChartData(ctx context.Context, metric string, opts api.Requ
 	if hours <= 0 {
 		hours = dataset.DefaultResolutionHours()
 	}
-	bucketSize := time.Duration(hours) * time.Hour
-
-	startDate, endDate := computeBucketRange(time.Now(), bucketSize, opts.Days, opts.TZOffsetMinutes)
+	return time.Duration(hours) * time.Hour, nil
+}
 
+// resolveChartScope builds the host filter mask and the entity allow-set for
+// a validated request.
+func (s *Service) resolveChartScope(ctx context.Context, metric string, opts api.RequestOpts, scope viewerScope) (*roaring.Bitmap, []string, error) {
 	// Build the host filter. The bitmap mask always encodes "currently visible
 	// hosts" — team scoping, label/platform/include/exclude, and incidentally
 	// dropping hosts deleted since the SCD rows were written.
 	hostFilter := &types.HostFilter{
-		TeamIDs:        effectiveTeamIDs(opts.TeamID, isGlobal, viewerTeamIDs),
+		TeamIDs:        effectiveTeamIDs(opts.TeamID, scope.isGlobal, scope.teamIDs),
 		LabelIDs:       opts.LabelIDs,
 		Platforms:      opts.Platforms,
 		IncludeHostIDs: opts.IncludeHostIDs,
@@ -162,7 +274,7 @@ func (s *Service) GetChartData(ctx context.Context, metric string, opts api.Requ
 		return chart.NewBitmap(hostIDs), nil
 	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// entityIDs semantics at the storage layer: nil = no filter (all entities);
@@ -187,38 +299,39 @@ func (s *Service) GetChartData(ctx context.Context, metric string, opts api.Requ
 		}
 		entityIDs, err = s.store.ResolveCVEChartEntities(ctx, cveFilter)
 		if err != nil {
-			return nil, ctxerr.Wrap(ctx, err, "resolve CVE chart entities")
+			return nil, nil, ctxerr.Wrap(ctx, err, "resolve CVE chart entities")
 		}
 	}
-
-	data, err := s.store.GetSCDData(ctx, metric, startDate, endDate, bucketSize, dataset.SampleStrategy(), filterMask, entityIDs)
-	if err != nil {
-		return nil, err
+	if metric == api.MetricPolicy && len(opts.PolicyIDs) > 0 {
+		// Policy entities are keyed by the decimal policy ID. No policy_ids
+		// means no entity filter, i.e. hosts failing any policy.
+		entityIDs = make([]string, 0, len(opts.PolicyIDs))
+		for _, id := range opts.PolicyIDs {
+			entityIDs = append(entityIDs, strconv.FormatUint(uint64(id), 10))
+		}
 	}
+	return filterMask, entityIDs, nil
+}
 
-	return &api.Response{
-		Metric:        metric,
-		Visualization: dataset.DefaultVisualization(),
-		TotalHosts:    int(chart.BlobPopcount(filterMask)), //nolint:gosec // host counts fit comfortably in int
-		Resolution:    formatResolution(bucketSize),
-		Days:          opts.Days,
-		Filters: api.Filters{
-			TeamID:         opts.TeamID,
-			LabelIDs:       opts.LabelIDs,
-			Platforms:      opts.Platforms,
-			IncludeHostIDs: opts.IncludeHostIDs,
-			ExcludeHostIDs: opts.ExcludeHostIDs,
-
-			SoftwareFilters: opts.SoftwareFilters,
-			KnownExploit:    opts.KnownExploit,
-			EPSSMin:         opts.EPSSMin,
-			EPSSMax:         opts.EPSSMax,
-			SeverityMin:     opts.SeverityMin,
-			SeverityMax:     opts.SeverityMax,
-			ExcludeCVEs:     opts.ExcludeCVEs,
-		},
-		Data: data,
-	}, nil
+// appliedFilters echoes the request's filters back in the response.
+func appliedFilters(opts api.RequestOpts) api.Filters {
+	return api.Filters{
+		TeamID:         opts.TeamID,
+		LabelIDs:       opts.LabelIDs,
+		Platforms:      opts.Platforms,
+		IncludeHostIDs: opts.IncludeHostIDs,
+		ExcludeHostIDs: opts.ExcludeHostIDs,
+
+		SoftwareFilters: opts.SoftwareFilters,
+		KnownExploit:    opts.KnownExploit,
+		EPSSMin:         opts.EPSSMin,
+		EPSSMax:         opts.EPSSMax,
+		SeverityMin:     opts.SeverityMin,
+		SeverityMax:     opts.SeverityMax,
+		ExcludeCVEs:     opts.ExcludeCVEs,
+
+		PolicyIDs: opts.PolicyIDs,
+	}
 }
 
 func validateScoreBounds(label string, minScore, maxScore *float64, lo, hi float64) error {
@@ -328,6 +441,18 @@ func computeBucketRange(now time.Time, bucketSize time.Duration, days, tzOffsetM
 	return startDate, endDate
 }
 
+// computeExportRange resolves the defaults for an export's range, in UTC. A
+// zero end means now; a zero start means seven days before the end.
+func computeExportRange(now time.Time, start, end time.Time) (time.Time, time.Time) {
+	if end.IsZero() {
+		end = now
+	}
+	if start.IsZero() {
+		start = end.Add(-7 * 24 * time.Hour)
+	}
+	return start.UTC(), end.UTC()
+}
+
 func formatResolution(bucketSize time.Duration) string {
 	switch {
 	case bucketSize == time.Hour:
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"iter"
 	"math"
 	"net/http"
 	"testing"
@@ -95,11 +96,15 @@ func freeCtx(t *testing.T) context.Context {
 
 type mockDatastore struct {
 	getSCDDataFunc          func(ctx context.Context, dataset string, startDate, endDate time.Time, bucketSize time.Duration, strategy api.SampleStrategy, filterMask *roaring.Bitmap, entityIDs []string) ([]api.DataPoint, error)
+	streamSCDRowsFunc       func(ctx context.Context, dataset string, startTime, endTime time.Time, filterMask *roaring.Bitmap, entityIDs []string) (iter.Seq2[types.SCDRecord, error], error)
 	getHostIDsForFilterFunc func(ctx context.Context, hostFilter *types.HostFilter) ([]uint, error)
 	findOnlineHostIDsFn     func(ctx context.Context, now time.Time, disabledFleetIDs []uint) ([]uint, error)
 	affectedHostIDsByCVEFn  func(ctx context.Context, disabledFleetIDs []uint, cves []string) (map[string]*roaring.Bitmap, error)
 	collectibleCVEsFn       func(ctx context.Context) ([]string, error)
 	resolveCVEEntitiesFn    func(ctx context.Context, filter types.CVEChartFilter) ([]string, error)
+	failingByPolicyFn       func(ctx context.Context, disabledFleetIDs []uint) (map[string]*roaring.Bitmap, error)
+	mdmEnrollmentFn         func(ctx context.Context, disabledFleetIDs []uint) (map[string]*roaring.Bitmap, error)
+	diskEncryptedFn         func(ctx context.Context, disabledFleetIDs []uint) ([]uint, error)
 	recordBucketDataFn      func(ctx context.Context, dataset string, bucketStart time.Time, bucketSize time.Duration, strategy api.SampleStrategy, entityBitmaps map[string]*roaring.Bitmap) error
 	recordBucketDataInvoked bool
 	deleteAllForDatasetFn   func(ctx context.Context, dataset string, batchSize int) error
@@ -138,6 +143,27 @@ func (m *mockDatastore) ResolveCVEChartEntities(ctx context.Context, filter type
 	return []string{}, nil
 }
 
+func (m *mockDatastore) FailingHostIDsByPolicy(ctx context.Context, disabledFleetIDs []uint) (map[string]*roaring.Bitmap, error) {
+	if m.failingByPolicyFn != nil {
+		return m.failingByPolicyFn(ctx, disabledFleetIDs)
+	}
+	return map[string]*roaring.Bitmap{}, nil
+}
+
+func (m *mockDatastore) HostIDsByMDMEnrollmentStatus(ctx context.Context, disabledFleetIDs []uint) (map[string]*roaring.Bitmap, error) {
+	if m.mdmEnrollmentFn != nil {
+		return m.mdmEnrollmentFn(ctx, disabledFleetIDs)
+	}
+	return map[string]*roaring.Bitmap{}, nil
+}
+
+func (m *mockDatastore) FindDiskEncryptedHostIDs(ctx context.Context, disabledFleetIDs []uint) ([]uint, error) {
+	if m.diskEncryptedFn != nil {
+		return m.diskEncryptedFn(ctx, disabledFleetIDs)
+	}
+	return nil, nil
+}
+
 func (m *mockDatastore) RecordBucketData(ctx context.Context, dataset string, bucketStart time.Time, bucketSize time.Duration, strategy api.SampleStrategy, entityBitmaps map[string]*roaring.Bitmap) error {
 	m.recordBucketDataInvoked = true
 	if m.recordBucketDataFn != nil {
@@ -153,6 +179,13 @@ func (m *mockDatastore) GetSCDData(ctx context.Context, dataset string, startDat
 	return nil, nil
 }
 
+func (m *mockDatastore) StreamSCDRows(ctx context.Context, dataset string, startTime, endTime time.Time, filterMask *roaring.Bitmap, entityIDs []string) (iter.Seq2[types.SCDRecord, error], error) {
+	if m.streamSCDRowsFunc != nil {
+		return m.streamSCDRowsFunc(ctx, dataset, startTime, endTime, filterMask, entityIDs)
+	}
+	return func(func(types.SCDRecord, error) bool) {}, nil
+}
+
 func (m *mockDatastore) GetHostIDsForFilter(ctx context.Context, hostFilter *types.HostFilter) ([]uint, error) {
 	if m.getHostIDsForFilterFunc != nil {
 		return m.getHostIDsForFilterFunc(ctx, hostFilter)
@@ -363,6 +396,44 @@ func TestGetChartDataUptimePassesNilEntityIDs(t *testing.T) {
 	assert.True(t, gotEntityIDsIsNil, "uptime must pass nil entityIDs — the CVE branch must not leak")
 }
 
+func TestGetChartDataPolicyEntityFilter(t *testing.T) {
+	ds := &mockDatastore{}
+	svc := NewService(&mockAuthorizer{}, ds, globalViewer(), nil)
+	svc.RegisterDataset(&chart.PolicyDataset{})
+
+	var gotEntityIDs []string
+	ds.getSCDDataFunc = func(_ context.Context, dataset string, _, _ time.Time, _ time.Duration, strategy api.SampleStrategy, _ *roaring.Bitmap, entityIDs []string) ([]api.DataPoint, error) {
+		assert.Equal(t, "policy", dataset)
+		assert.Equal(t, api.SampleStrategySnapshot, strategy)
+		gotEntityIDs = entityIDs
+		return nil, nil
+	}
+
+	t.Run("NoPolicyIDsMeansAllPolicies", func(t *testing.T) {
+		_, err := svc.GetChartData(t.Context(), "policy", api.RequestOpts{Days: 7})
+		require.NoError(t, err)
+		assert.Nil(t, gotEntityIDs)
+	})
+
+	t.Run("PolicyIDsBecomeEntityIDs", func(t *testing.T) {
+		resp, err := svc.GetChartData(t.Context(), "policy", api.RequestOpts{Days: 7, PolicyIDs: []uint{3, 12}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"3", "12"}, gotEntityIDs)
+		assert.Equal(t, []uint{3, 12}, resp.Filters.PolicyIDs)
+	})
+
+	t.Run("PolicyIDsIgnoredForOtherMetrics", func(t *testing.T) {
+		svc.RegisterDataset(&chart.UptimeDataset{})
+		ds.getSCDDataFunc = func(_ context.Context, _ string, _, _ time.Time, _ time.Duration, _ api.SampleStrategy, _ *roaring.Bitmap, entityIDs []string) ([]api.DataPoint, error) {
+			gotEntityIDs = entityIDs
+			return nil, nil
+		}
+		_, err := svc.GetChartData(t.Context(), "uptime", api.RequestOpts{Days: 7, PolicyIDs: []uint{3}})
+		require.NoError(t, err)
+		assert.Nil(t, gotEntityIDs)
+	})
+}
+
 func newCVEService(ds *mockDatastore) *Service {
 	svc := NewService(&mockAuthorizer{}, ds, globalViewer(), nil)
 	svc.RegisterDataset(&chart.CVEDataset{})
@@ -844,6 +915,128 @@ func TestComputeBucketRange(t *testing.T) {
 	})
 }
 
+func TestExportChartData(t *testing.T) {
+	ds := &mockDatastore{}
+	svc := NewService(&mockAuthorizer{}, ds, globalViewer(), nil)
+	svc.RegisterDataset(&chart.UptimeDataset{})
+	svc.RegisterDataset(&chart.PolicyDataset{})
+
+	ds.getHostIDsForFilterFunc = func(_ context.Context, _ *types.HostFilter) ([]uint, error) {
+		return []uint{1, 2, 3, 4}, nil
+	}
+	start := time.Date(2026, 3, 1, 1, 30, 0, 0, time.UTC)
+	end := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	closedAt := start.Add(time.Hour)
+
+	var gotStart, gotEnd time.Time
+	var gotEntityIDs []string
+	var rowsErr error
+	ds.streamSCDRowsFunc = func(_ context.Context, _ string, startTime, endTime time.Time, _ *roaring.Bitmap, entityIDs []string) (iter.Seq2[types.SCDRecord, error], error) {
+		gotStart, gotEnd, gotEntityIDs = startTime, endTime, entityIDs
+		return func(yield func(types.SCDRecord, error) bool) {
+			if !yield(types.SCDRecord{EntityID: "5", ValidFrom: start, ValidTo: &closedAt, Hosts: chart.NewBitmap([]uint{2})}, nil) {
+				return
+			}
+			if !yield(types.SCDRecord{EntityID: "5", ValidFrom: closedAt, Hosts: chart.NewBitmap([]uint{2, 4})}, nil) {
+				return
+			}
+			if rowsErr != nil {
+				yield(types.SCDRecord{}, rowsErr)
+			}
+		}, nil
+	}
+
+	collect := func(export *api.Export) ([]api.ExportRow, error) {
+		var rows []api.ExportRow
+		for row, err := range export.Rows {
+			if err != nil {
+				return rows, err
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+
+	t.Run("rows cover the requested range", func(t *testing.T) {
+		export, err := svc.ExportChartData(t.Context(), "policy", api.ExportOpts{
+			RequestOpts: api.RequestOpts{PolicyIDs: []uint{5}},
+			StartTime:   start,
+			EndTime:     end,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "policy", export.Metric)
+		assert.Equal(t, 4, export.TotalHosts)
+		assert.Equal(t, start, export.StartTime)
+		assert.Equal(t, end, export.EndTime)
+		assert.Equal(t, start, gotStart)
+		assert.Equal(t, end, gotEnd)
+		assert.Equal(t, []string{"5"}, gotEntityIDs)
+
+		rows, err := collect(export)
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.Equal(t, api.ExportRow{EntityID: "5", ValidFrom: start, ValidTo: &closedAt, HostCount: 1}, rows[0])
+		assert.Equal(t, api.ExportRow{EntityID: "5", ValidFrom: closedAt, HostCount: 2}, rows[1])
+	})
+
+	t.Run("host IDs expansion", func(t *testing.T) {
+		export, err := svc.ExportChartData(t.Context(), "uptime", api.ExportOpts{StartTime: start, EndTime: end, WithHostIDs: true})
+		require.NoError(t, err)
+		assert.True(t, export.WithHostIDs)
+		rows, err := collect(export)
+		require.NoError(t, err)
+		require.Len(t, rows, 2)
+		assert.Equal(t, []uint{2}, rows[0].HostIDs)
+		assert.Equal(t, []uint{2, 4}, rows[1].HostIDs)
+	})
+
+	t.Run("read errors end the rows", func(t *testing.T) {
+		rowsErr = errors.New("connection lost")
+		defer func() { rowsErr = nil }()
+		export, err := svc.ExportChartData(t.Context(), "uptime", api.ExportOpts{StartTime: start, EndTime: end})
+		require.NoError(t, err)
+		rows, err := collect(export)
+		require.ErrorContains(t, err, "connection lost")
+		assert.Len(t, rows, 2)
+	})
+
+	t.Run("invalid ranges", func(t *testing.T) {
+		_, err := svc.ExportChartData(t.Context(), "uptime", api.ExportOpts{StartTime: end, EndTime: start})
+		require.ErrorContains(t, err, "start_time must be before end_time")
+
+		_, err = svc.ExportChartData(t.Context(), "uptime", api.ExportOpts{StartTime: end, EndTime: end})
+		require.ErrorContains(t, err, "start_time must be before end_time")
+	})
+
+	t.Run("ranges are not capped", func(t *testing.T) {
+		_, err := svc.ExportChartData(t.Context(), "uptime", api.ExportOpts{StartTime: end.AddDate(0, 0, -90), EndTime: end})
+		require.NoError(t, err)
+	})
+
+	t.Run("default range is the last seven days", func(t *testing.T) {
+		export, err := svc.ExportChartData(t.Context(), "uptime", api.ExportOpts{})
+		require.NoError(t, err)
+		assert.Equal(t, 7*24*time.Hour, export.EndTime.Sub(export.StartTime))
+		assert.WithinDuration(t, time.Now(), export.EndTime, time.Minute)
+	})
+
+	t.Run("shares chart validation", func(t *testing.T) {
+		_, err := svc.ExportChartData(t.Context(), "nonexistent", api.ExportOpts{})
+		require.ErrorContains(t, err, "unknown chart metric")
+	})
+}
+
+func TestExportChartDataAuthz(t *testing.T) {
+	authz := &recordingAuthorizer{allow: false}
+	svc := NewService(authz, &mockDatastore{}, globalViewer(), nil)
+	svc.RegisterDataset(&chart.UptimeDataset{})
+
+	_, err := svc.ExportChartData(t.Context(), "uptime", api.ExportOpts{RequestOpts: api.RequestOpts{TeamID: new(uint(3))}})
+	require.ErrorContains(t, err, "forbidden")
+	assert.Equal(t, platform_authz.ActionRead, authz.gotAction)
+	assert.Equal(t, &api.Host{TeamID: new(uint(3))}, authz.gotSubject)
+}
+
 func TestCollectDatasetsUptime(t *testing.T) {
 	ds := &mockDatastore{}
 	svc := NewService(&mockAuthorizer{}, ds, globalViewer(), nil)
@@ -937,6 +1130,48 @@ func TestCollectDatasetsCVEEmptyTracked(t *testing.T) {
 	assert.Empty(t, gotBitmaps)
 }
 
+func TestCollectDatasetsCompliance(t *testing.T) {
+	now := time.Date(2026, 4, 8, 14, 37, 0, 0, time.UTC)
+	wantBucketStart := time.Date(2026, 4, 8, 14, 0, 0, 0, time.UTC)
+
+	ds := &mockDatastore{}
+	ds.failingByPolicyFn = func(_ context.Context, disabled []uint) (map[string]*roaring.Bitmap, error) {
+		assert.Nil(t, disabled)
+		return map[string]*roaring.Bitmap{"1": roaring.BitmapOf(1, 2), "7": roaring.BitmapOf(3)}, nil
+	}
+	ds.mdmEnrollmentFn = func(_ context.Context, _ []uint) (map[string]*roaring.Bitmap, error) {
+		return map[string]*roaring.Bitmap{api.MDMEnrollmentStatusAutomatic: roaring.BitmapOf(4)}, nil
+	}
+	// No encrypted hosts: the snapshot must still be recorded so the open row
+	// from a previous tick gets closed.
+	ds.diskEncryptedFn = func(_ context.Context, _ []uint) ([]uint, error) {
+		return nil, nil
+	}
+	got := map[string]map[string]*roaring.Bitmap{}
+	ds.recordBucketDataFn = func(_ context.Context, dataset string, bucketStart time.Time, bucketSize time.Duration, strategy api.SampleStrategy, entityBitmaps map[string]*roaring.Bitmap) error {
+		assert.Equal(t, wantBucketStart, bucketStart)
+		assert.Equal(t, time.Hour, bucketSize)
+		assert.Equal(t, api.SampleStrategySnapshot, strategy)
+		got[dataset] = entityBitmaps
+		return nil
+	}
+
+	svc := NewService(&mockAuthorizer{}, ds, globalViewer(), nil)
+	svc.RegisterDataset(&chart.PolicyDataset{})
+	svc.RegisterDataset(&chart.MDMEnrollmentDataset{})
+	svc.RegisterDataset(&chart.DiskEncryptionDataset{})
+	require.NoError(t, svc.CollectDatasets(t.Context(), now, nil))
+
+	require.Len(t, got, 3)
+	require.Len(t, got["policy"], 2)
+	assert.Equal(t, []uint32{1, 2}, got["policy"]["1"].ToArray())
+	assert.Equal(t, []uint32{3}, got["policy"]["7"].ToArray())
+	require.Len(t, got["mdm_enrollment"], 1)
+	assert.Equal(t, []uint32{4}, got["mdm_enrollment"]["automatic"].ToArray())
+	require.Len(t, got["disk_encryption"], 1)
+	assert.True(t, got["disk_encryption"][""].IsEmpty())
+}
+
 // TestCollectDatasetsForwardsScope verifies the scope resolver wiring:
 //   - skip=true → Collect not invoked
 //   - skip=false → disabledFleetIDs forwarded to the store query
@@ -1112,3 +1347,23 @@ func TestCVEDatasetMetadata(t *testing.T) {
 	assert.Equal(t, api.SampleStrategySnapshot, d.SampleStrategy())
 	assert.Equal(t, "line", d.DefaultVisualization())
 }
+
+func TestComplianceDatasetsMetadata(t *testing.T) {
+	cases := []struct {
+		ds         api.Dataset
+		name       string
+		resolution int
+	}{
+		{&chart.PolicyDataset{}, "policy", 3},
+		{&chart.MDMEnrollmentDataset{}, "mdm_enrollment", 24},
+		{&chart.DiskEncryptionDataset{}, "disk_encryption", 24},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.name, c.ds.Name())
+			assert.Equal(t, c.resolution, c.ds.DefaultResolutionHours())
+			assert.Equal(t, api.SampleStrategySnapshot, c.ds.SampleStrategy())
+			assert.Equal(t, "line", c.ds.DefaultVisualization())
+		})
+	}
+}
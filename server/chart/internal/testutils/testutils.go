@@ -51,7 +51,8 @@ func (tdb *TestDB) TruncateTables(t *testing.T) {
 	t.Helper()
 	mysql_testing_utils.TruncateTables(t, tdb.DB, tdb.Logger, nil,
 		"host_scd_data", "hosts", "host_seen_times", "nano_devices", "nano_enrollments", "teams",
-		"software", "software_cve", "cve_meta", "operating_system_vulnerabilities")
+		"software", "software_cve", "cve_meta", "operating_system_vulnerabilities",
+		"policies", "policy_membership", "host_mdm", "host_disks")
 }
 
 // InsertSCDRow inserts a single host_scd_data row for tests. host_bitmap is
@@ -3,6 +3,7 @@ package types
 
 import (
 	"context"
+	"iter"
 	"time"
 
 	"github.com/RoaringBitmap/roaring"
@@ -49,6 +50,16 @@ type CVEChartFilter struct {
 	ExcludeCVEs  []string
 }
 
+// SCDRecord is one host_scd_data row of a history export: the entity, its
+// validity interval and the filter-masked host bitmap. ValidTo is nil for the
+// currently open row.
+type SCDRecord struct {
+	EntityID  string
+	ValidFrom time.Time
+	ValidTo   *time.Time
+	Hosts     *roaring.Bitmap
+}
+
 // Datastore is the internal datastore interface for the chart bounded context.
 type Datastore interface {
 	// FindOnlineHostIDs returns host IDs that are "online right now" using a
@@ -85,6 +96,22 @@ type Datastore interface {
 	// nil, so lower-severity CVEs never leak into the chart.
 	ResolveCVEChartEntities(ctx context.Context, filter CVEChartFilter) ([]string, error)
 
+	// FailingHostIDsByPolicy returns a bitmap of host IDs whose latest result
+	// for a policy is failing (policy_membership.passes = 0), keyed by the
+	// decimal policy ID. Hosts with no result yet are not counted. Used by the
+	// policy compliance dataset.
+	FailingHostIDsByPolicy(ctx context.Context, disabledFleetIDs []uint) (map[string]*roaring.Bitmap, error)
+
+	// HostIDsByMDMEnrollmentStatus returns a bitmap of MDM-enrolled host IDs
+	// keyed by api.MDMEnrollmentStatus* (manual, automatic, personal). Pending
+	// and unenrolled hosts, as well as servers, are omitted. Used by the MDM
+	// enrollment dataset.
+	HostIDsByMDMEnrollmentStatus(ctx context.Context, disabledFleetIDs []uint) (map[string]*roaring.Bitmap, error)
+
+	// FindDiskEncryptedHostIDs returns host IDs whose host_disks.encrypted
+	// flag is set. Used by the disk encryption dataset.
+	FindDiskEncryptedHostIDs(ctx context.Context, disabledFleetIDs []uint) ([]uint, error)
+
 	// RecordBucketData writes one or more entity bitmaps for the given bucket using
 	// the specified sample strategy. See api.SampleStrategy for the semantics of
 	// each strategy. Bitmaps are passed in op form (*roaring.Bitmap); the
@@ -118,6 +145,21 @@ type Datastore interface {
 		entityIDs []string,
 	) ([]api.DataPoint, error)
 
+	// StreamSCDRows returns the raw rows of a dataset whose validity interval
+	// overlaps [startTime, endTime), in valid_from order, for history exports.
+	// The query runs eagerly (so its errors surface before any output is
+	// written) and the iterator reads one row at a time from the cursor,
+	// yielding read errors as they occur. filterMask is AND-ed into every row
+	// and rows left with no hosts are skipped; entityIDs has the same
+	// semantics as for GetSCDData.
+	StreamSCDRows(
+		ctx context.Context,
+		dataset string,
+		startTime, endTime time.Time,
+		filterMask *roaring.Bitmap,
+		entityIDs []string,
+	) (iter.Seq2[SCDRecord, error], error)
+
 	// GetHostIDsForFilter returns the host IDs that match the given host filter.
 	GetHostIDsForFilter(ctx context.Context, hostFilter *HostFilter) ([]uint, error)
 
@@ -116,11 +116,30 @@ const (
 	TLSProfileIntermediate = "intermediate"
 
 	EndpointRequestSizeOverridesKey = "server.endpoint_request_size_overrides"
+
+	ResultLogDestinationsKey = "osquery.result_log_destinations"
+	// DefaultResultLogDestination is the reserved name of the result log destination
+	// configured by osquery.result_log_plugin.
+	DefaultResultLogDestination = "default"
 )
 
 // EndpointRequestSizeOverrides maps a literal registered endpoint path (e.g. "/api/_version_/fleet/...") to its maximum allowed body size.
 type EndpointRequestSizeOverrides map[string]int64
 
+// ResultLogDestination is a named destination for osquery result logs, in addition
+// to the default one. It uses the plugin's shared settings (credentials, region, etc.)
+// and overrides the target that the plugin writes to. Reports opt into it by name.
+type ResultLogDestination struct {
+	Name     string `json:"name" yaml:"name"`
+	Plugin   string `json:"plugin" yaml:"plugin"`
+	File     string `json:"file" yaml:"file"`         // filesystem
+	URL      string `json:"url" yaml:"url"`           // webhook
+	Stream   string `json:"stream" yaml:"stream"`     // firehose, kinesis
+	Function string `json:"function" yaml:"function"` // lambda
+	Topic    string `json:"topic" yaml:"topic"`       // pubsub, kafkarest
+	Subject  string `json:"subject" yaml:"subject"`   // nats
+}
+
 // ServerConfig defines configs related to the Fleet server
 type ServerConfig struct {
 	Address                          string
@@ -143,6 +162,7 @@ type ServerConfig struct {
 	VPPVerifyRequestDelay            time.Duration `yaml:"vpp_verify_request_delay"`
 	VPPInstallReapTimeout            time.Duration `yaml:"vpp_install_reap_timeout"`
 	CleanupDistTargetsAge            time.Duration `yaml:"cleanup_dist_targets_age"`
+	LiveQueryResultsRetention        time.Duration `yaml:"live_query_results_retention"`
 	MaxInstallerSizeBytes            int64         `yaml:"max_installer_size"`
 	TrustedProxies                   string        `yaml:"trusted_proxies"`
 	GzipResponses                    bool          `yaml:"gzip_responses"`
@@ -319,6 +339,10 @@ type OsqueryConfig struct {
 	//     body's node_key field is ignored. Pre-auth rejects
 	//     absent/invalid headers BEFORE the body is read.
 	AllowBodyAuthFallback bool `yaml:"allow_body_auth_fallback"`
+
+	// ResultLogDestinations are the named result log destinations that reports can
+	// send their results to instead of (or as well as) the default one.
+	ResultLogDestinations []ResultLogDestination `yaml:"result_log_destinations"`
 }
 
 // Validate checks that osquery_host_identifier is one of the supported values.
@@ -751,6 +775,29 @@ type SplunkConfig struct {
 	InsecureSkipVerify bool   `json:"insecure_skip_verify" yaml:"insecure_skip_verify"`
 }
 
+// OTLPConfig defines configs for the OpenTelemetry (OTLP) logging plugin.
+type OTLPConfig struct {
+	Endpoint                  string        `json:"endpoint" yaml:"endpoint"`
+	Protocol                  string        `json:"protocol" yaml:"protocol"`
+	Insecure                  bool          `json:"insecure" yaml:"insecure"`
+	Headers                   string        `json:"headers" yaml:"headers"`
+	Compression               string        `json:"compression" yaml:"compression"`
+	Timeout                   time.Duration `json:"timeout" yaml:"timeout"`
+	MaxBatchSize              int           `json:"max_batch_size" yaml:"max_batch_size"`
+	ResourceAttributes        string        `json:"resource_attributes" yaml:"resource_attributes"`
+	DynamicResourceAttributes string        `json:"dynamic_resource_attributes" yaml:"dynamic_resource_attributes"`
+}
+
+// LogSpoolConfig defines configs for the on-disk spool in front of the
+// osquery and audit logging plugins.
+type LogSpoolConfig struct {
+	Directory   string        `json:"directory" yaml:"directory"`
+	MaxSize     int           `json:"max_size" yaml:"max_size"`
+	SegmentSize int           `json:"segment_size" yaml:"segment_size"`
+	MinBackoff  time.Duration `json:"min_backoff" yaml:"min_backoff"`
+	MaxBackoff  time.Duration `json:"max_backoff" yaml:"max_backoff"`
+}
+
 // NatsConfig defines configs for the NATS logging plugin.
 type NatsConfig struct {
 	StatusSubject    string        `json:"status_subject" yaml:"status_subject"`
@@ -781,6 +828,8 @@ type VulnerabilitiesConfig struct {
 	CPETranslationsURL          string        `json:"cpe_translations_url" yaml:"cpe_translations_url"`
 	CVEFeedPrefixURL            string        `json:"cve_feed_prefix_url" yaml:"cve_feed_prefix_url"`
 	CISAKnownExploitsURL        string        `json:"cisa_known_exploits_url" yaml:"cisa_known_exploits_url"`
+	EPSSFeedURL                 string        `json:"epss_feed_url" yaml:"epss_feed_url"`
+	VEXPath                     string        `json:"vex_path" yaml:"vex_path"`
 	CurrentInstanceChecks       string        `json:"current_instance_checks" yaml:"current_instance_checks"`
 	DisableSchedule             bool          `json:"disable_schedule" yaml:"disable_schedule"`
 	DisableDataSync             bool          `json:"disable_data_sync" yaml:"disable_data_sync"`
@@ -864,6 +913,8 @@ type FleetConfig struct {
 	KafkaREST                  KafkaRESTConfig
 	Nats                       NatsConfig
 	Splunk                     SplunkConfig
+	OTLP                       OTLPConfig
+	LogSpool                   LogSpoolConfig `yaml:"log_spool"`
 	License                    LicenseConfig
 	Vulnerabilities            VulnerabilitiesConfig
 	Upgrades                   UpgradesConfig
@@ -1522,6 +1573,7 @@ func (man Manager) addConfigs() {
 	man.addConfigDuration("server.vpp_install_reap_timeout", 24*time.Hour,
 		"Minimum time a stuck App Store or in-house app install must have been activated before Fleet fails it to release the host's activity queue. Zero or less turns the reaper off, and a value below server.vpp_verify_timeout is raised to it")
 	man.addConfigDuration("server.cleanup_dist_targets_age", 24*time.Hour, "Specifies the cleanup age for completed live query distributed targets.")
+	man.addConfigDuration("server.live_query_results_retention", 30*24*time.Hour, "How long the stored results of live queries are kept before they are deleted.")
 	man.addConfigByteSize("server.max_installer_size", installersize.Human(installersize.MaxSoftwareInstallerSize), "Maximum size in bytes for software installer uploads (e.g. 10GiB, 500MB, 1G)")
 	man.addConfigString("server.trusted_proxies", "",
 		"Trusted proxy configuration for client IP extraction: 'none' (RemoteAddr only), a header name (e.g., 'True-Client-IP'), a hop count (e.g., '2'), or comma-separated IP/CIDR ranges")
@@ -1573,6 +1625,8 @@ func (man Manager) addConfigs() {
 		"Log plugin to use for status logs")
 	man.addConfigString("osquery.result_log_plugin", "filesystem",
 		"Log plugin to use for result logs")
+	man.addConfigString(ResultLogDestinationsKey, "",
+		"Named result log destinations, as a list of {name, plugin, file, url, stream, function, topic, subject} objects")
 	man.addConfigDuration("osquery.label_update_interval", 1*time.Hour,
 		"Interval to update host label membership (i.e. 1h)")
 	man.addConfigDuration("osquery.policy_update_interval", 1*time.Hour,
@@ -1822,6 +1876,25 @@ func (man Manager) addConfigs() {
 	man.addConfigString("splunk.source_type", "", "Splunk sourcetype value for events")
 	man.addConfigBool("splunk.insecure_skip_verify", false, "Skip TLS certificate verification for Splunk HEC (for self-signed certs)")
 
+	// OTLP
+	man.addConfigString("otlp.endpoint", "", "OTLP logs endpoint (host:port for grpc, URL for http)")
+	man.addConfigString("otlp.protocol", "grpc", "OTLP transport protocol (grpc or http)")
+	man.addConfigBool("otlp.insecure", false, "Disable TLS for the OTLP endpoint")
+	man.addConfigString("otlp.headers", "", "Comma-separated key=value headers sent with OTLP requests")
+	man.addConfigString("otlp.compression", "gzip", "Compression of OTLP requests (gzip or none)")
+	man.addConfigDuration("otlp.timeout", 30*time.Second, "Timeout of OTLP export requests")
+	man.addConfigInt("otlp.max_batch_size", 512, "Maximum number of log records per OTLP export request")
+	man.addConfigString("otlp.resource_attributes", "", "Comma-separated key=value resource attributes added to OTLP log records")
+	man.addConfigString("otlp.dynamic_resource_attributes", "host_identifier,team,query_name",
+		"Comma-separated per-log resource attributes added to OTLP log records (host_identifier, team, query_name)")
+
+	// Logging spool
+	man.addConfigString("log_spool.directory", "", "Directory of the on-disk spool in front of the logging plugins (disabled if empty)")
+	man.addConfigInt("log_spool.max_size", 1024, "Maximum size of the spool of each logger in megabytes, the oldest logs are dropped when full")
+	man.addConfigInt("log_spool.segment_size", 16, "Size of the spool segment files in megabytes")
+	man.addConfigDuration("log_spool.min_backoff", 1*time.Second, "Initial delay before retrying to deliver spooled logs")
+	man.addConfigDuration("log_spool.max_backoff", 5*time.Minute, "Maximum delay before retrying to deliver spooled logs")
+
 	// License
 	man.addConfigString("license.key", "", "Fleet license key (to enable Fleet Premium features)")
 	man.addConfigBool("license.enforce_host_limit", false, "Enforce license limit of enrolled hosts")
@@ -1839,6 +1912,10 @@ func (man Manager) addConfigs() {
 		"Prefix URL for the CVE data feed. If empty, default to https://nvd.nist.gov/")
 	man.addConfigString("vulnerabilities.cisa_known_exploits_url", "",
 		"URL from which to get the latest CISA (Known exploited vulnerabilities) database. If empty, it will be downloaded from https://www.cisa.gov/sites/default/files/feeds/known_exploited_vulnerabilities.json")
+	man.addConfigString("vulnerabilities.epss_feed_url", "",
+		"URL or local path from which to get the latest EPSS scores feed. A local directory is searched for the most recent epss_scores-YYYY-MM-DD.csv.gz file. If empty, it will be downloaded from https://epss.cyentia.com/epss_scores-current.csv.gz")
+	man.addConfigString("vulnerabilities.vex_path", "",
+		"Local directory of OpenVEX or CSAF VEX documents (*.json) to import before each vulnerabilities scan.")
 	man.addConfigString("vulnerabilities.current_instance_checks", "auto",
 		"Allows to manually select an instance to do the vulnerability processing.")
 	man.addConfigBool("vulnerabilities.disable_schedule", false,
@@ -2056,6 +2133,7 @@ func (man Manager) LoadConfig() FleetConfig {
 			VPPVerifyRequestDelay:            man.getConfigDuration("server.vpp_verify_request_delay"),
 			VPPInstallReapTimeout:            man.getConfigDuration("server.vpp_install_reap_timeout"),
 			CleanupDistTargetsAge:            man.getConfigDuration("server.cleanup_dist_targets_age"),
+			LiveQueryResultsRetention:        man.getConfigDuration("server.live_query_results_retention"),
 			MaxInstallerSizeBytes:            man.getConfigByteSize("server.max_installer_size"),
 			TrustedProxies:                   man.getConfigString("server.trusted_proxies"),
 			GzipResponses:                    man.getConfigBool("server.gzip_responses"),
@@ -2109,6 +2187,7 @@ func (man Manager) LoadConfig() FleetConfig {
 			MaxLogWriteBodySize:              man.getConfigByteSize("osquery.max_log_write_body_size"),
 			MaxDistributedWriteBodySize:      man.getConfigByteSize("osquery.max_distributed_write_body_size"),
 			AllowBodyAuthFallback:            man.getConfigBool("osquery.allow_body_auth_fallback"),
+			ResultLogDestinations:            man.getConfigResultLogDestinations(),
 		},
 		Activity: ActivityConfig{
 			EnableAuditLog: man.getConfigBool("activity.enable_audit_log"),
@@ -2222,6 +2301,24 @@ func (man Manager) LoadConfig() FleetConfig {
 			SourceType:         man.getConfigString("splunk.source_type"),
 			InsecureSkipVerify: man.getConfigBool("splunk.insecure_skip_verify"),
 		},
+		OTLP: OTLPConfig{
+			Endpoint:                  man.getConfigString("otlp.endpoint"),
+			Protocol:                  man.getConfigString("otlp.protocol"),
+			Insecure:                  man.getConfigBool("otlp.insecure"),
+			Headers:                   man.getConfigString("otlp.headers"),
+			Compression:               man.getConfigString("otlp.compression"),
+			Timeout:                   man.getConfigDuration("otlp.timeout"),
+			MaxBatchSize:              man.getConfigInt("otlp.max_batch_size"),
+			ResourceAttributes:        man.getConfigString("otlp.resource_attributes"),
+			DynamicResourceAttributes: man.getConfigString("otlp.dynamic_resource_attributes"),
+		},
+		LogSpool: LogSpoolConfig{
+			Directory:   man.getConfigString("log_spool.directory"),
+			MaxSize:     man.getConfigInt("log_spool.max_size"),
+			SegmentSize: man.getConfigInt("log_spool.segment_size"),
+			MinBackoff:  man.getConfigDuration("log_spool.min_backoff"),
+			MaxBackoff:  man.getConfigDuration("log_spool.max_backoff"),
+		},
 		License: LicenseConfig{
 			Key:              man.getConfigString("license.key"),
 			EnforceHostLimit: man.getConfigBool("license.enforce_host_limit"),
@@ -2233,6 +2330,8 @@ func (man Manager) LoadConfig() FleetConfig {
 			CPETranslationsURL:          man.getConfigString("vulnerabilities.cpe_translations_url"),
 			CVEFeedPrefixURL:            man.getConfigString("vulnerabilities.cve_feed_prefix_url"),
 			CISAKnownExploitsURL:        man.getConfigString("vulnerabilities.cisa_known_exploits_url"),
+			EPSSFeedURL:                 man.getConfigString("vulnerabilities.epss_feed_url"),
+			VEXPath:                     man.getConfigString("vulnerabilities.vex_path"),
 			CurrentInstanceChecks:       man.getConfigString("vulnerabilities.current_instance_checks"),
 			DisableSchedule:             man.getConfigBool("vulnerabilities.disable_schedule"),
 			DisableDataSync:             man.getConfigBool("vulnerabilities.disable_data_sync"),
@@ -2624,6 +2723,69 @@ func (man Manager) getConfigEndpointRequestSizeOverrides() EndpointRequestSizeOv
 	return cfgOverrides
 }
 
+// getConfigResultLogDestinations retrieves and parses osquery.result_log_destinations.
+func (man Manager) getConfigResultLogDestinations() []ResultLogDestination {
+	interfaceVal := man.getInterfaceVal(ResultLogDestinationsKey)
+
+	var destinations []ResultLogDestination
+	switch v := interfaceVal.(type) {
+	case string: // Viper returns a string when the value is from env variable or CLI flag.
+		if v == "" {
+			return nil
+		}
+		if err := json.Unmarshal([]byte(v), &destinations); err != nil {
+			panic(fmt.Sprintf("Unable to parse %s: %s", ResultLogDestinationsKey, err.Error()))
+		}
+	case []any: // Viper returns a native []any when the value is from YAML config file.
+		if len(v) == 0 {
+			return nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			panic(fmt.Sprintf("Unable to encode value for key %s: %s", ResultLogDestinationsKey, err.Error()))
+		}
+		if err := json.Unmarshal(b, &destinations); err != nil {
+			panic(fmt.Sprintf("Unable to encode value for key %s: %s", ResultLogDestinationsKey, err.Error()))
+		}
+	case nil:
+		return nil
+	default:
+		panic(fmt.Sprintf("Unexpected type %T for key %s", interfaceVal, ResultLogDestinationsKey))
+	}
+
+	seen := make(map[string]struct{}, len(destinations))
+	for _, d := range destinations {
+		switch {
+		case d.Name == "":
+			panic(fmt.Sprintf("Empty name in %s", ResultLogDestinationsKey))
+		case d.Name == DefaultResultLogDestination:
+			panic(fmt.Sprintf("The name %q is reserved in %s", DefaultResultLogDestination, ResultLogDestinationsKey))
+		case d.Plugin == "":
+			panic(fmt.Sprintf("Empty plugin for destination %s in %s", d.Name, ResultLogDestinationsKey))
+		}
+		if _, ok := seen[d.Name]; ok {
+			panic(fmt.Sprintf("Duplicate config entry found for destination %s in %s", d.Name, ResultLogDestinationsKey))
+		}
+		seen[d.Name] = struct{}{}
+	}
+
+	return destinations
+}
+
+// HasResultLogDestination reports whether name is the default result log destination
+// or one of the configured named ones.
+func (o OsqueryConfig) HasResultLogDestination(name string) bool {
+	if name == DefaultResultLogDestination {
+		return true
+	}
+	for _, d := range o.ResultLogDestinations {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // panics if the config is invalid, this is handled by Viper (this is how all
 // getConfigT helpers indicate errors). The default value is only applied if
 // there is no task-specific config (i.e., no "task=true" config format for that
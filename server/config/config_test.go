@@ -453,6 +453,124 @@ server:
 	}
 }
 
+func TestConfigResultLogDestinations(t *testing.T) {
+	cases := []struct {
+		desc     string
+		yaml     string
+		env      []string
+		panics   bool
+		expected []ResultLogDestination
+	}{
+		{
+			desc:     "unset",
+			expected: nil,
+		},
+		{
+			desc: "YAML list",
+			yaml: `
+osquery:
+  result_log_destinations:
+    - name: security
+      plugin: firehose
+      stream: security-results
+    - name: compliance
+      plugin: filesystem
+      file: /var/log/fleet/compliance.log`,
+			expected: []ResultLogDestination{
+				{Name: "security", Plugin: "firehose", Stream: "security-results"},
+				{Name: "compliance", Plugin: "filesystem", File: "/var/log/fleet/compliance.log"},
+			},
+		},
+		{
+			desc: "Environment variable - JSON array",
+			env: []string{
+				`FLEET_OSQUERY_RESULT_LOG_DESTINATIONS=[{"name": "security", "plugin": "kafkarest", "topic": "security"}]`,
+			},
+			expected: []ResultLogDestination{
+				{Name: "security", Plugin: "kafkarest", Topic: "security"},
+			},
+		},
+		{
+			desc: "Duplicate name panics",
+			yaml: `
+osquery:
+  result_log_destinations:
+    - name: security
+      plugin: stdout
+    - name: security
+      plugin: filesystem`,
+			panics: true,
+		},
+		{
+			desc: "Reserved name panics",
+			yaml: `
+osquery:
+  result_log_destinations:
+    - name: default
+      plugin: stdout`,
+			panics: true,
+		},
+		{
+			desc: "Empty name panics",
+			yaml: `
+osquery:
+  result_log_destinations:
+    - plugin: stdout`,
+			panics: true,
+		},
+		{
+			desc: "Empty plugin panics",
+			yaml: `
+osquery:
+  result_log_destinations:
+    - name: security`,
+			panics: true,
+		},
+		{
+			desc: "Malformed JSON in environment variable panics",
+			env: []string{
+				`FLEET_OSQUERY_RESULT_LOG_DESTINATIONS=not-json`,
+			},
+			panics: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			var cmd cobra.Command
+			cmd.PersistentFlags().StringP("config", "c", "", "Path to a configuration file")
+			man := NewManager(&cmd)
+
+			man.viper.SetConfigType("yaml")
+			require.NoError(t, man.viper.ReadConfig(strings.NewReader(c.yaml)))
+
+			testutils.SaveEnv(t)
+			os.Clearenv()
+			for _, env := range c.env {
+				kv := strings.SplitN(env, "=", 2)
+				t.Setenv(kv[0], kv[1])
+			}
+
+			var loadedCfg FleetConfig
+			if c.panics {
+				require.Panics(t, func() {
+					loadedCfg = man.LoadConfig()
+				})
+			} else {
+				require.NotPanics(t, func() {
+					loadedCfg = man.LoadConfig()
+				})
+				require.Equal(t, c.expected, loadedCfg.Osquery.ResultLogDestinations)
+				require.True(t, loadedCfg.Osquery.HasResultLogDestination(DefaultResultLogDestination))
+				for _, d := range c.expected {
+					require.True(t, loadedCfg.Osquery.HasResultLogDestination(d.Name))
+				}
+				require.False(t, loadedCfg.Osquery.HasResultLogDestination("unknown"))
+			}
+		})
+	}
+}
+
 func TestConfigSESSenderDomain(t *testing.T) {
 	cases := []struct {
 		desc    string
@@ -136,11 +136,14 @@ func cronCalendarEvents(ctx context.Context, ds fleet.Datastore, distributedLock
 		return fmt.Errorf("load app config: %w", err)
 	}
 
-	if len(appConfig.Integrations.GoogleCalendar) == 0 {
+	localConfig, err := calendar.NewConfig(ctx, ds, appConfig, serverConfig)
+	if err != nil {
+		return fmt.Errorf("load calendar config: %w", err)
+	}
+	if localConfig == nil {
 		return nil
 	}
-	googleCalendarIntegrationConfig := appConfig.Integrations.GoogleCalendar[0]
-	domain := googleCalendarIntegrationConfig.Domain
+	domain := appConfig.Integrations.CalendarDomain()
 
 	teams, err := ds.ListTeams(
 		ctx, fleet.TeamFilter{
@@ -153,11 +156,6 @@ func cronCalendarEvents(ctx context.Context, ds fleet.Datastore, distributedLock
 		return fmt.Errorf("list teams: %w", err)
 	}
 
-	localConfig := &calendar.Config{
-		CalendarConfig:            serverConfig,
-		GoogleCalendarIntegration: *googleCalendarIntegrationConfig,
-		ServerURL:                 appConfig.ServerSettings.ServerURL,
-	}
 	for _, team := range teams {
 		if err := cronCalendarEventsForTeam(
 			ctx, ds, distributedLock, localConfig, *team, appConfig.OrgInfo.OrgName, domain, logger, newActivitySvc,
@@ -842,12 +840,12 @@ func cronCalendarEventsCleanup(ctx context.Context, ds fleet.Datastore, logger *
 	}
 
 	var userCalendar fleet.UserCalendar
-	var calConfig *calendar.Config
-	if len(appConfig.Integrations.GoogleCalendar) > 0 {
-		calConfig = &calendar.Config{
-			GoogleCalendarIntegration: *appConfig.Integrations.GoogleCalendar[0],
-			ServerURL:                 appConfig.ServerSettings.ServerURL,
-		}
+	calConfig, err := calendar.NewConfig(ctx, ds, appConfig, config.CalendarConfig{})
+	if err != nil {
+		// Do not delete the events from the DB, the integration is still configured.
+		return fmt.Errorf("load calendar config: %w", err)
+	}
+	if calConfig != nil {
 		userCalendar = calendar.CreateUserCalendarFromConfig(ctx, calConfig, logger)
 	}
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -35,13 +36,18 @@ import (
 //     need special care (usually pointers, slices, maps).
 //  5. Add the required Datastore methods to get the cached item and to set it,
 //     and add tests in cached_mysql_test.go to ensure it works as expected.
+//     Writes must evict the item with ds.invalidate (or publish its key on
+//     ds.bus if they store the fresh value) so that other Fleet instances
+//     don't keep serving the stale value until it expires.
 const (
 	appConfigKey                       = "AppConfig:%s"
 	defaultAppConfigExpiration         = 1 * time.Second
 	windowsEnrollmentDefaultFleetKey   = "WindowsEnrollmentDefaultFleet"
-	packsHostKey                       = "Packs:host:%d"
+	packsHostCachePrefix               = "Packs:host:"
+	packsHostKey                       = packsHostCachePrefix + "%d"
 	defaultPacksExpiration             = 1 * time.Minute
-	scheduledQueriesKey                = "ScheduledQueries:pack:%d"
+	scheduledQueriesCachePrefix        = "ScheduledQueries:pack:"
+	scheduledQueriesKey                = scheduledQueriesCachePrefix + "%d"
 	defaultScheduledQueriesExpiration  = 1 * time.Minute
 	teamAgentOptionsKey                = "TeamAgentOptions:team:%d"
 	defaultTeamAgentOptionsExpiration  = 1 * time.Minute
@@ -51,13 +57,16 @@ const (
 	defaultTeamMDMConfigExpiration     = 1 * time.Minute
 	defaultTeamConfigKey               = "DefaultTeamConfig"
 	defaultDefaultTeamConfigExpiration = 1 * time.Minute
-	queryByNameKey                     = "QueryByName:team:%d:%s"
+	queryByNameCachePrefix             = "QueryByName:"
+	queryByNameTeamCachePrefix         = queryByNameCachePrefix + "team:%d:"
+	queryByNameKey                     = queryByNameTeamCachePrefix + "%s"
 	defaultQueryByNameExpiration       = 1 * time.Second
 	queryResultsCountKey               = "QueryResultsCount:%d"
 	defaultQueryResultsCountExpiration = 1 * time.Second
 	// The host's team is part of the key so that a transferred host never reads the
 	// schedule of its previous team.
-	queriesPerHostKey               = "QueriesPerHost:host:%d:team:%d"
+	queriesPerHostCachePrefix       = "QueriesPerHost:"
+	queriesPerHostKey               = queriesPerHostCachePrefix + "host:%d:team:%d"
 	defaultQueriesPerHostExpiration = 1 * time.Minute
 	yaraRuleCachePrefix             = "YaraRuleByName:"
 	yaraRuleByNameKey               = yaraRuleCachePrefix + "%s"
@@ -91,6 +100,7 @@ func (c *cloneCache) Get(ctx context.Context, k string) (fleet.Cloner, bool) {
 
 	x, found := c.Cache.Get(k)
 	if !found {
+		recordCacheLookup(ctx, "miss", k)
 		return nil, false
 	}
 	xc, ok := x.(fleet.Cloner)
@@ -102,11 +112,18 @@ func (c *cloneCache) Get(ctx context.Context, k string) (fleet.Cloner, bool) {
 	clone, err := xc.Clone()
 	if err != nil {
 		// Unfortunely, we can't return an error here. Return a cache miss instead of panic'ing.
+		recordCacheLookup(ctx, "miss", k)
 		return nil, false
 	}
+	recordCacheLookup(ctx, "hit", k)
 	return clone, true
 }
 
+func recordCacheLookup(ctx context.Context, result, key string) {
+	//nolint:nilaway // initialized in package init(); panic on registration failure guarantees non-nil
+	cacheLookups.Add(ctx, 1, cacheLookupAttrs(result, key))
+}
+
 func (c *cloneCache) Set(ctx context.Context, k string, x fleet.Cloner, d time.Duration) {
 	clone, err := x.Clone()
 	if err != nil {
@@ -125,6 +142,12 @@ type cachedMysql struct {
 
 	c *cloneCache
 
+	// bus propagates invalidations to the other Fleet instances, nil if
+	// WithInvalidationBus was not provided.
+	bus *invalidationBus
+	// busCtx bounds the lifetime of the bus subscription.
+	busCtx context.Context
+
 	appConfigExp            time.Duration
 	packsExp                time.Duration
 	scheduledQueriesExp     time.Duration
@@ -220,6 +243,17 @@ func WithFMANamesByIdentifierExpiration(d time.Duration) Option {
 	}
 }
 
+// WithInvalidationBus makes the writes that go through the cache evict the
+// stale keys on every other Fleet instance sharing the Redis pool, via Redis
+// pub/sub. The subscription to the other instances' invalidations runs until
+// ctx is done.
+func WithInvalidationBus(ctx context.Context, pool fleet.RedisPool, logger *slog.Logger) Option {
+	return func(o *cachedMysql) {
+		o.bus = newInvalidationBus(pool, logger, o.c)
+		o.busCtx = ctx
+	}
+}
+
 func New(ds fleet.Datastore, opts ...Option) fleet.Datastore {
 	c := &cachedMysql{
 		Datastore:               ds,
@@ -241,9 +275,44 @@ func New(ds fleet.Datastore, opts ...Option) fleet.Datastore {
 	for _, fn := range opts {
 		fn(c)
 	}
+	if c.bus != nil {
+		go c.bus.run(c.busCtx)
+	}
 	return c
 }
 
+// invalidate evicts keys from the cache of this instance and of every other
+// instance.
+func (ds *cachedMysql) invalidate(ctx context.Context, keys ...string) {
+	for _, k := range keys {
+		ds.c.Delete(k)
+		ds.recordInvalidation(ctx, k)
+	}
+	ds.bus.publish(ctx, keys, nil)
+}
+
+// invalidatePrefix evicts all keys starting with one of the prefixes from the
+// cache of this instance and of every other instance.
+func (ds *cachedMysql) invalidatePrefix(ctx context.Context, prefixes ...string) {
+	for k := range ds.c.Items() {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(k, prefix) {
+				ds.c.Delete(k)
+				break
+			}
+		}
+	}
+	for _, prefix := range prefixes {
+		ds.recordInvalidation(ctx, prefix)
+	}
+	ds.bus.publish(ctx, nil, prefixes)
+}
+
+func (ds *cachedMysql) recordInvalidation(ctx context.Context, key string) {
+	//nolint:nilaway // initialized in package init(); panic on registration failure guarantees non-nil
+	cacheInvalidations.Add(ctx, 1, cacheInvalidationAttrs("local", key))
+}
+
 func (ds *cachedMysql) NewAppConfig(ctx context.Context, info *fleet.AppConfig) (*fleet.AppConfig, error) {
 	ac, err := ds.Datastore.NewAppConfig(ctx, info)
 	if err != nil {
@@ -251,6 +320,7 @@ func (ds *cachedMysql) NewAppConfig(ctx context.Context, info *fleet.AppConfig)
 	}
 
 	ds.c.Set(ctx, appConfigKey, ac, ds.appConfigExp)
+	ds.bus.publish(ctx, []string{appConfigKey}, nil)
 
 	return ac, nil
 }
@@ -280,6 +350,7 @@ func (ds *cachedMysql) SaveAppConfig(ctx context.Context, info *fleet.AppConfig)
 	}
 
 	ds.c.Set(ctx, appConfigKey, info, ds.appConfigExp)
+	ds.bus.publish(ctx, []string{appConfigKey}, nil)
 
 	return nil
 }
@@ -305,7 +376,7 @@ func (ds *cachedMysql) SetWindowsEnrollmentDefaultFleet(ctx context.Context, fle
 	if err := ds.Datastore.SetWindowsEnrollmentDefaultFleet(ctx, fleetID); err != nil {
 		return err
 	}
-	ds.c.Delete(windowsEnrollmentDefaultFleetKey)
+	ds.invalidate(ctx, windowsEnrollmentDefaultFleetKey)
 	return nil
 }
 
@@ -414,6 +485,7 @@ func (ds *cachedMysql) SaveTeam(ctx context.Context, team *fleet.Team) (*fleet.T
 	ds.c.Set(ctx, agentOptionsKey, (*rawJSONMessage)(team.Config.AgentOptions), ds.teamAgentOptionsExp)
 	ds.c.Set(ctx, featuresKey, &team.Config.Features, ds.teamFeaturesExp)
 	ds.c.Set(ctx, mdmConfigKey, &team.Config.MDM, ds.teamMDMConfigExp)
+	ds.bus.publish(ctx, []string{agentOptionsKey, featuresKey, mdmConfigKey}, nil)
 
 	return team, nil
 }
@@ -428,14 +500,11 @@ func (ds *cachedMysql) DeleteTeam(ctx context.Context, teamID uint) error {
 	featuresKey := fmt.Sprintf(teamFeaturesKey, teamID)
 	mdmConfigKey := fmt.Sprintf(teamMDMConfigKey, teamID)
 
-	ds.c.Delete(agentOptionsKey)
-	ds.c.Delete(featuresKey)
-	ds.c.Delete(mdmConfigKey)
+	ds.invalidate(ctx, agentOptionsKey, featuresKey, mdmConfigKey)
 
 	return nil
 }
 
-// TODO: should we handle DeleteQuery/DeleteQueries/SaveQuery to invalidate that cache?
 func (ds *cachedMysql) QueryByName(ctx context.Context, teamID *uint, name string) (*fleet.Query, error) {
 	teamID_ := uint(0) // global team is 0
 	if teamID != nil {
@@ -459,6 +528,159 @@ func (ds *cachedMysql) QueryByName(ctx context.Context, teamID *uint, name strin
 	return query, nil
 }
 
+func queryByNameKeyForQuery(q *fleet.Query) string {
+	teamID := uint(0) // global team is 0
+	if q.TeamID != nil {
+		teamID = *q.TeamID
+	}
+	return fmt.Sprintf(queryByNameKey, teamID, q.Name)
+}
+
+// Query writes change the schedule of the hosts and the scheduled queries of
+// the packs, which include the name and SQL of their query.
+
+func (ds *cachedMysql) NewQuery(ctx context.Context, query *fleet.Query, opts ...fleet.OptionalArg) (*fleet.Query, error) {
+	query, err := ds.Datastore.NewQuery(ctx, query, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.invalidate(ctx, queryByNameKeyForQuery(query))
+	ds.invalidatePrefix(ctx, queriesPerHostCachePrefix)
+
+	return query, nil
+}
+
+func (ds *cachedMysql) SaveQuery(ctx context.Context, query *fleet.Query, shouldDiscardResults bool, shouldDeleteStats bool) error {
+	if err := ds.Datastore.SaveQuery(ctx, query, shouldDiscardResults, shouldDeleteStats); err != nil {
+		return err
+	}
+
+	// the query may have been renamed, evict all the queries of its team
+	teamID := uint(0) // global team is 0
+	if query.TeamID != nil {
+		teamID = *query.TeamID
+	}
+	ds.invalidatePrefix(ctx, fmt.Sprintf(queryByNameTeamCachePrefix, teamID), queriesPerHostCachePrefix, scheduledQueriesCachePrefix)
+
+	return nil
+}
+
+func (ds *cachedMysql) DeleteQuery(ctx context.Context, teamID *uint, name string) error {
+	if err := ds.Datastore.DeleteQuery(ctx, teamID, name); err != nil {
+		return err
+	}
+
+	ds.invalidate(ctx, queryByNameKeyForQuery(&fleet.Query{TeamID: teamID, Name: name}))
+	ds.invalidatePrefix(ctx, queriesPerHostCachePrefix, scheduledQueriesCachePrefix)
+
+	return nil
+}
+
+func (ds *cachedMysql) DeleteQueries(ctx context.Context, ids []uint) (uint, error) {
+	n, err := ds.Datastore.DeleteQueries(ctx, ids)
+	if err != nil {
+		return n, err
+	}
+
+	// only the IDs of the queries are known
+	ds.invalidatePrefix(ctx, queryByNameCachePrefix, queriesPerHostCachePrefix, scheduledQueriesCachePrefix)
+
+	return n, nil
+}
+
+func (ds *cachedMysql) ApplyQueries(ctx context.Context, authorID uint, queries []*fleet.Query, queriesToDiscardResults map[uint]struct{}) error {
+	if err := ds.Datastore.ApplyQueries(ctx, authorID, queries, queriesToDiscardResults); err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(queries))
+	for _, q := range queries {
+		keys = append(keys, queryByNameKeyForQuery(q))
+	}
+	ds.invalidate(ctx, keys...)
+	ds.invalidatePrefix(ctx, queriesPerHostCachePrefix, scheduledQueriesCachePrefix)
+
+	return nil
+}
+
+// Pack writes change the packs of the hosts (through the pack targets) and
+// the scheduled queries of the packs.
+
+func (ds *cachedMysql) ApplyPackSpecs(ctx context.Context, specs []*fleet.PackSpec) error {
+	if err := ds.Datastore.ApplyPackSpecs(ctx, specs); err != nil {
+		return err
+	}
+
+	ds.invalidatePrefix(ctx, packsHostCachePrefix, scheduledQueriesCachePrefix)
+
+	return nil
+}
+
+func (ds *cachedMysql) NewPack(ctx context.Context, pack *fleet.Pack, opts ...fleet.OptionalArg) (*fleet.Pack, error) {
+	pack, err := ds.Datastore.NewPack(ctx, pack, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.invalidatePrefix(ctx, packsHostCachePrefix)
+
+	return pack, nil
+}
+
+func (ds *cachedMysql) SavePack(ctx context.Context, pack *fleet.Pack) error {
+	if err := ds.Datastore.SavePack(ctx, pack); err != nil {
+		return err
+	}
+
+	ds.invalidatePrefix(ctx, packsHostCachePrefix)
+
+	return nil
+}
+
+func (ds *cachedMysql) DeletePack(ctx context.Context, name string) error {
+	if err := ds.Datastore.DeletePack(ctx, name); err != nil {
+		return err
+	}
+
+	ds.invalidatePrefix(ctx, packsHostCachePrefix, scheduledQueriesCachePrefix)
+
+	return nil
+}
+
+func (ds *cachedMysql) NewScheduledQuery(ctx context.Context, sq *fleet.ScheduledQuery, opts ...fleet.OptionalArg) (*fleet.ScheduledQuery, error) {
+	sq, err := ds.Datastore.NewScheduledQuery(ctx, sq, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.invalidate(ctx, fmt.Sprintf(scheduledQueriesKey, sq.PackID))
+
+	return sq, nil
+}
+
+func (ds *cachedMysql) SaveScheduledQuery(ctx context.Context, sq *fleet.ScheduledQuery) (*fleet.ScheduledQuery, error) {
+	sq, err := ds.Datastore.SaveScheduledQuery(ctx, sq)
+	if err != nil {
+		return nil, err
+	}
+
+	ds.invalidate(ctx, fmt.Sprintf(scheduledQueriesKey, sq.PackID))
+
+	return sq, nil
+}
+
+func (ds *cachedMysql) DeleteScheduledQuery(ctx context.Context, id uint) error {
+	if err := ds.Datastore.DeleteScheduledQuery(ctx, id); err != nil {
+		return err
+	}
+
+	// only the ID of the scheduled query is known
+	ds.invalidatePrefix(ctx, scheduledQueriesCachePrefix)
+
+	return nil
+}
+
 func (ds *cachedMysql) ResultCountForQuery(ctx context.Context, queryID uint) (int, error) {
 	key := fmt.Sprintf(queryResultsCountKey, queryID)
 
@@ -575,7 +797,7 @@ func (ds *cachedMysql) SaveDefaultTeamConfig(ctx context.Context, config *fleet.
 	}
 
 	// Invalidate the cache
-	ds.c.Delete(defaultTeamConfigKey)
+	ds.invalidate(ctx, defaultTeamConfigKey)
 
 	return nil
 }
@@ -607,12 +829,7 @@ func (ds *cachedMysql) ApplyYaraRules(ctx context.Context, rules []fleet.YaraRul
 
 	// Invalidate all cached YARA rules
 	// We need to flush all because we don't know which rules were added/removed/modified
-	items := ds.c.Items()
-	for k := range items {
-		if strings.HasPrefix(k, yaraRuleCachePrefix) {
-			ds.c.Delete(k)
-		}
-	}
+	ds.invalidatePrefix(ctx, yaraRuleCachePrefix)
 
 	return nil
 }
@@ -641,7 +858,7 @@ func (ds *cachedMysql) UpsertMaintainedApp(ctx context.Context, app *fleet.Maint
 	}
 
 	// Invalidate the FMA names cache since an app was added/updated
-	ds.c.Delete(fmaNamesByIdentifierKey)
+	ds.invalidate(ctx, fmaNamesByIdentifierKey)
 
 	return result, nil
 }
@@ -653,7 +870,7 @@ func (ds *cachedMysql) ClearRemovedFleetMaintainedApps(ctx context.Context, slug
 	}
 
 	// Invalidate the FMA names cache since apps may have been removed
-	ds.c.Delete(fmaNamesByIdentifierKey)
+	ds.invalidate(ctx, fmaNamesByIdentifierKey)
 
 	return nil
 }
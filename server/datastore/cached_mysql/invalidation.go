@@ -0,0 +1,213 @@
+package cached_mysql
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/datastore/redis"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/google/uuid"
+)
+
+const (
+	// invalidationChannel is the Redis pub/sub channel on which every Fleet
+	// instance publishes the cache keys its writes made stale.
+	invalidationChannel = "fleet:cached_mysql:invalidations"
+
+	// invalidationPingInterval is how often the subscriber pings Redis. The
+	// subscription is considered dead (and re-established) if nothing, not
+	// even the ping's reply, is received for twice that interval.
+	invalidationPingInterval = 30 * time.Second
+
+	invalidationMinBackoff = time.Second
+	invalidationMaxBackoff = 30 * time.Second
+)
+
+// invalidationMessage is the payload published on invalidationChannel. Keys
+// are evicted as-is, Prefixes evict every key that starts with them.
+type invalidationMessage struct {
+	Origin   string   `json:"origin"`
+	Keys     []string `json:"keys,omitempty"`
+	Prefixes []string `json:"prefixes,omitempty"`
+}
+
+// invalidationBus propagates cache invalidations between the cachedMysql
+// instances of a Fleet deployment, so that a write on one instance evicts the
+// stale keys on all the others instead of letting them serve stale data until
+// expiration. Delivery is best-effort (Redis pub/sub is fire-and-forget); the
+// cache expirations remain the upper bound on staleness if a message is lost,
+// and the whole local cache is flushed whenever the subscription is
+// re-established, as messages may have been missed in the meantime.
+type invalidationBus struct {
+	pool   fleet.RedisPool
+	logger *slog.Logger
+	c      *cloneCache
+
+	// origin identifies this instance, so it can ignore its own messages (its
+	// cache has already been updated by the write).
+	origin string
+}
+
+func newInvalidationBus(pool fleet.RedisPool, logger *slog.Logger, c *cloneCache) *invalidationBus {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &invalidationBus{
+		pool:   pool,
+		logger: logger.With("component", "cached_mysql"),
+		c:      c,
+		origin: uuid.NewString(),
+	}
+}
+
+// publish broadcasts the invalidation of keys and prefixes to the other
+// instances. Errors are logged and counted but not returned, as the write that
+// triggered the invalidation has already succeeded. It is a no-op if b is nil
+// (the bus is not configured).
+func (b *invalidationBus) publish(ctx context.Context, keys, prefixes []string) {
+	if b == nil || (len(keys) == 0 && len(prefixes) == 0) {
+		return
+	}
+
+	msg, err := json.Marshal(invalidationMessage{Origin: b.origin, Keys: keys, Prefixes: prefixes})
+	if err != nil {
+		// should never happen, but make sure it's visible if it does
+		b.recordError(ctx, "publish")
+		b.logger.ErrorContext(ctx, "marshal cache invalidation message", "err", err)
+		return
+	}
+
+	// pub-sub can publish and listen on any node in the cluster
+	conn := redis.ReadOnlyConn(b.pool, b.pool.Get())
+	defer conn.Close()
+
+	if _, err := conn.Do("PUBLISH", invalidationChannel, msg); err != nil {
+		b.recordError(ctx, "publish")
+		b.logger.ErrorContext(ctx, "publish cache invalidation", "err", err, "keys", keys, "prefixes", prefixes)
+	}
+}
+
+// run subscribes to the invalidation channel and evicts the keys received
+// from other instances until ctx is done, re-subscribing with a backoff when
+// the connection fails.
+func (b *invalidationBus) run(ctx context.Context) {
+	backoff := invalidationMinBackoff
+	resync := false
+	for {
+		start := time.Now()
+		err := b.subscribe(ctx, resync)
+		if ctx.Err() != nil {
+			return
+		}
+
+		b.recordError(ctx, "subscribe")
+		b.logger.ErrorContext(ctx, "cache invalidation subscription failed, retrying", "err", err, "retry_in", backoff)
+		resync = true
+
+		// reset the backoff if the subscription was healthy for a while
+		if time.Since(start) > invalidationMaxBackoff {
+			backoff = invalidationMinBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, invalidationMaxBackoff)
+	}
+}
+
+// subscribe runs a single subscription until it fails or ctx is done. If
+// resync is true, the local cache is flushed once the subscription is
+// confirmed, to drop anything invalidated while it was down.
+func (b *invalidationBus) subscribe(ctx context.Context, resync bool) error {
+	conn := redis.ReadOnlyConn(b.pool, b.pool.Get())
+	psc := &redigo.PubSubConn{Conn: conn}
+	defer psc.Close()
+
+	if err := psc.Subscribe(invalidationChannel); err != nil {
+		return err
+	}
+
+	// Unblock the receive loop when ctx is done, and ping Redis periodically
+	// so that a dead connection makes the receive time out. A redigo
+	// connection supports one concurrent reader and one concurrent writer.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(invalidationPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				_ = psc.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := psc.Ping(""); err != nil {
+					// the receive loop will fail on the same connection
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		switch msg := psc.ReceiveWithTimeout(2 * invalidationPingInterval).(type) {
+		case redigo.Message:
+			b.apply(ctx, msg.Data)
+
+		case redigo.Subscription:
+			if msg.Kind == "subscribe" && resync {
+				resync = false
+				b.c.Flush()
+				b.recordInvalidation(ctx, "resync", "All")
+			}
+
+		case error:
+			return msg
+		}
+	}
+}
+
+// apply evicts the keys of an invalidation message received from another
+// instance.
+func (b *invalidationBus) apply(ctx context.Context, data []byte) {
+	var msg invalidationMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		b.recordError(ctx, "decode")
+		b.logger.ErrorContext(ctx, "decode cache invalidation message", "err", err)
+		return
+	}
+	if msg.Origin == b.origin {
+		return
+	}
+
+	for _, k := range msg.Keys {
+		b.c.Delete(k)
+		b.recordInvalidation(ctx, "remote", k)
+	}
+	for _, prefix := range msg.Prefixes {
+		for k := range b.c.Items() {
+			if strings.HasPrefix(k, prefix) {
+				b.c.Delete(k)
+			}
+		}
+		b.recordInvalidation(ctx, "remote", prefix)
+	}
+}
+
+func (b *invalidationBus) recordInvalidation(ctx context.Context, source, key string) {
+	//nolint:nilaway // initialized in package init(); panic on registration failure guarantees non-nil
+	cacheInvalidations.Add(ctx, 1, cacheInvalidationAttrs(source, key))
+}
+
+func (b *invalidationBus) recordError(ctx context.Context, op string) {
+	//nolint:nilaway // initialized in package init(); panic on registration failure guarantees non-nil
+	cacheInvalidationErrors.Add(ctx, 1, cacheInvalidationErrorAttrs(op))
+}
@@ -0,0 +1,235 @@
+package cached_mysql
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/datastore/redis/redistest"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	redigo "github.com/gomodule/redigo/redis"
+	"github.com/patrickmn/go-cache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvalidationBusApply(t *testing.T) {
+	c := &cloneCache{cache.New(time.Hour, time.Hour)}
+	bus := newInvalidationBus(redistest.NopRedis(), nil, c)
+
+	ctx := t.Context()
+	reset := func() {
+		c.Flush()
+		c.Set(ctx, appConfigKey, &fleet.AppConfig{}, time.Hour)
+		c.Set(ctx, defaultTeamConfigKey, &fleet.TeamConfig{}, time.Hour)
+		c.Set(ctx, "YaraRuleByName:a.yar", &fleet.YaraRule{Name: "a.yar"}, time.Hour)
+		c.Set(ctx, "YaraRuleByName:b.yar", &fleet.YaraRule{Name: "b.yar"}, time.Hour)
+	}
+	encode := func(msg invalidationMessage) []byte {
+		b, err := json.Marshal(msg)
+		require.NoError(t, err)
+		return b
+	}
+
+	// messages from this instance are ignored
+	reset()
+	bus.apply(ctx, encode(invalidationMessage{Origin: bus.origin, Keys: []string{appConfigKey}}))
+	require.Equal(t, 4, c.ItemCount())
+
+	// invalid messages are ignored
+	bus.apply(ctx, []byte("not json"))
+	require.Equal(t, 4, c.ItemCount())
+
+	// keys are evicted
+	bus.apply(ctx, encode(invalidationMessage{Origin: "other", Keys: []string{appConfigKey, "NoSuchKey"}}))
+	_, found := c.Get(ctx, appConfigKey)
+	require.False(t, found)
+	require.Equal(t, 3, c.ItemCount())
+
+	// prefixes are evicted
+	reset()
+	bus.apply(ctx, encode(invalidationMessage{Origin: "other", Prefixes: []string{yaraRuleCachePrefix}}))
+	require.Equal(t, 2, c.ItemCount())
+	_, found = c.Get(ctx, defaultTeamConfigKey)
+	require.True(t, found)
+}
+
+func TestInvalidationBusAcrossInstances(t *testing.T) {
+	pool := redistest.SetupRedis(t, "cached_mysql_invalidation:", false, false, false)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	// both instances share the same database
+	var mu sync.Mutex
+	var (
+		appConfig    = &fleet.AppConfig{OrgInfo: fleet.OrgInfo{OrgName: "v1"}}
+		agentOptions = json.RawMessage(`{"v": 1}`)
+		yaraRule     = &fleet.YaraRule{Name: "rule.yar", Contents: "v1"}
+		query        = &fleet.Query{ID: 1, Name: "uptime", Query: "SELECT 1"}
+		packs        = []*fleet.Pack{{ID: 1, Name: "pack1"}}
+		scheduled    = fleet.ScheduledQueryList{{ID: 1, PackID: 1, QueryName: "uptime"}}
+	)
+	newInstance := func() (fleet.Datastore, *mock.Store) {
+		mockedDS := new(mock.Store)
+		mockedDS.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return appConfig.Copy(), nil
+		}
+		mockedDS.SaveAppConfigFunc = func(ctx context.Context, info *fleet.AppConfig) error {
+			mu.Lock()
+			defer mu.Unlock()
+			appConfig = info.Copy()
+			return nil
+		}
+		mockedDS.TeamAgentOptionsFunc = func(ctx context.Context, teamID uint) (*json.RawMessage, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			opts := agentOptions
+			return &opts, nil
+		}
+		mockedDS.DeleteTeamFunc = func(ctx context.Context, teamID uint) error {
+			mu.Lock()
+			defer mu.Unlock()
+			agentOptions = json.RawMessage(`{"v": 2}`)
+			return nil
+		}
+		mockedDS.YaraRuleByNameFunc = func(ctx context.Context, name string) (*fleet.YaraRule, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			rule := *yaraRule
+			return &rule, nil
+		}
+		mockedDS.ApplyYaraRulesFunc = func(ctx context.Context, rules []fleet.YaraRule) error {
+			mu.Lock()
+			defer mu.Unlock()
+			yaraRule = &rules[0]
+			return nil
+		}
+		mockedDS.QueryByNameFunc = func(ctx context.Context, teamID *uint, name string) (*fleet.Query, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			q := *query
+			return &q, nil
+		}
+		mockedDS.SaveQueryFunc = func(ctx context.Context, q *fleet.Query, shouldDiscardResults bool, shouldDeleteStats bool) error {
+			mu.Lock()
+			defer mu.Unlock()
+			query = q
+			return nil
+		}
+		mockedDS.ListPacksForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Pack, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return slices.Clone(packs), nil
+		}
+		mockedDS.DeletePackFunc = func(ctx context.Context, name string) error {
+			mu.Lock()
+			defer mu.Unlock()
+			packs = nil
+			scheduled = nil
+			return nil
+		}
+		mockedDS.ListScheduledQueriesInPackFunc = func(ctx context.Context, packID uint) (fleet.ScheduledQueryList, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return slices.Clone(scheduled), nil
+		}
+
+		// expirations are long enough that only invalidations can refresh the
+		// cached items during the test
+		return New(mockedDS,
+			WithInvalidationBus(ctx, pool, nil),
+			WithAppConfigExpiration(time.Hour),
+			WithTeamAgentOptionsExpiration(time.Hour),
+			WithYaraRuleByNameExpiration(time.Hour),
+			WithQueryByNameExpiration(time.Hour),
+			WithPacksExpiration(time.Hour),
+			WithScheduledQueriesExpiration(time.Hour),
+		), mockedDS
+	}
+	ds1, _ := newInstance()
+	ds2, mock2 := newInstance()
+
+	// wait for both instances to be subscribed
+	require.Eventually(t, func() bool {
+		conn := pool.Get()
+		defer conn.Close()
+		res, err := redigo.Values(conn.Do("PUBSUB", "NUMSUB", invalidationChannel))
+		if err != nil || len(res) != 2 {
+			return false
+		}
+		n, _ := redigo.Int(res[1], nil)
+		return n == 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// warm up the cache of the second instance
+	ac, err := ds2.AppConfig(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "v1", ac.OrgInfo.OrgName)
+	opts, err := ds2.TeamAgentOptions(ctx, 1)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"v": 1}`, string(*opts))
+	rule, err := ds2.YaraRuleByName(ctx, "rule.yar")
+	require.NoError(t, err)
+	require.Equal(t, "v1", rule.Contents)
+	q, err := ds2.QueryByName(ctx, nil, "uptime")
+	require.NoError(t, err)
+	require.Equal(t, "SELECT 1", q.Query)
+	hostPacks, err := ds2.ListPacksForHost(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, hostPacks, 1)
+	sqs, err := ds2.ListScheduledQueriesInPack(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, sqs, 1)
+	mock2.AppConfigFuncInvoked = false
+
+	// served from the cache
+	ac, err = ds2.AppConfig(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "v1", ac.OrgInfo.OrgName)
+	require.False(t, mock2.AppConfigFuncInvoked)
+
+	// writes on the first instance are visible on the second one
+	require.NoError(t, ds1.SaveAppConfig(ctx, &fleet.AppConfig{OrgInfo: fleet.OrgInfo{OrgName: "v2"}}))
+	require.NoError(t, ds1.DeleteTeam(ctx, 1))
+	require.NoError(t, ds1.ApplyYaraRules(ctx, []fleet.YaraRule{{Name: "rule.yar", Contents: "v2"}}))
+	require.NoError(t, ds1.SaveQuery(ctx, &fleet.Query{ID: 1, Name: "uptime", Query: "SELECT 2"}, false, false))
+	require.NoError(t, ds1.DeletePack(ctx, "pack1"))
+
+	require.EventuallyWithT(t, func(c *assert.CollectT) {
+		ac, err := ds2.AppConfig(ctx)
+		require.NoError(c, err)
+		assert.Equal(c, "v2", ac.OrgInfo.OrgName)
+
+		opts, err := ds2.TeamAgentOptions(ctx, 1)
+		require.NoError(c, err)
+		assert.JSONEq(c, `{"v": 2}`, string(*opts))
+
+		rule, err := ds2.YaraRuleByName(ctx, "rule.yar")
+		require.NoError(c, err)
+		assert.Equal(c, "v2", rule.Contents)
+
+		q, err := ds2.QueryByName(ctx, nil, "uptime")
+		require.NoError(c, err)
+		assert.Equal(c, "SELECT 2", q.Query)
+
+		hostPacks, err := ds2.ListPacksForHost(ctx, 1)
+		require.NoError(c, err)
+		assert.Empty(c, hostPacks)
+
+		sqs, err := ds2.ListScheduledQueriesInPack(ctx, 1)
+		require.NoError(c, err)
+		assert.Empty(c, sqs)
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// the write is cached on the instance that made it
+	ac, err = ds1.AppConfig(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "v2", ac.OrgInfo.OrgName)
+}
@@ -0,0 +1,82 @@
+package cached_mysql
+
+import (
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// OpenTelemetry instruments for the in-memory cache. When the global
+// MeterProvider is not configured (tests, services started without OTEL),
+// otel.Meter returns a no-op meter and all operations on these counters
+// silently succeed.
+
+var (
+	meter = otel.Meter("fleet")
+
+	// cacheLookups counts cache read attempts, labeled by result and item.
+	// Attribute `result` is one of: hit, miss. Attribute `item` is the cache
+	// key name without its parameters (e.g. AppConfig, TeamAgentOptions).
+	cacheLookups metric.Int64Counter
+
+	// cacheInvalidations counts evicted cache keys, labeled by source and item.
+	// Attribute `source` is one of: local (a write on this instance), remote
+	// (a write on another instance, received over the invalidation bus),
+	// resync (the whole cache was flushed after the bus reconnected).
+	cacheInvalidations metric.Int64Counter
+
+	// cacheInvalidationErrors counts invalidation bus errors, labeled by
+	// operation. Attribute `op` is one of: publish, subscribe, decode.
+	cacheInvalidationErrors metric.Int64Counter
+)
+
+func init() {
+	var err error
+	cacheLookups, err = meter.Int64Counter(
+		"fleet.cached_mysql.lookups",
+		metric.WithDescription("In-memory datastore cache reads, labeled by result and item"),
+		metric.WithUnit("{event}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	cacheInvalidations, err = meter.Int64Counter(
+		"fleet.cached_mysql.invalidations",
+		metric.WithDescription("In-memory datastore cache invalidations, labeled by source and item"),
+		metric.WithUnit("{event}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	cacheInvalidationErrors, err = meter.Int64Counter(
+		"fleet.cached_mysql.invalidation_errors",
+		metric.WithDescription("In-memory datastore cache invalidation bus errors, labeled by operation"),
+		metric.WithUnit("{event}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// cacheItem returns the name of the cached item for a key, i.e. the key
+// without its parameters, to keep the metric attributes' cardinality low.
+func cacheItem(key string) string {
+	item, _, _ := strings.Cut(key, ":")
+	return item
+}
+
+func cacheLookupAttrs(result, key string) metric.AddOption {
+	return metric.WithAttributes(attribute.String("result", result), attribute.String("item", cacheItem(key)))
+}
+
+func cacheInvalidationAttrs(source, key string) metric.AddOption {
+	return metric.WithAttributes(attribute.String("source", source), attribute.String("item", cacheItem(key)))
+}
+
+func cacheInvalidationErrorAttrs(op string) metric.AddOption {
+	return metric.WithAttributes(attribute.String("op", op))
+}
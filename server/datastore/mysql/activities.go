@@ -345,9 +345,16 @@ func (ds *Datastore) CleanupExpiredLiveQueries(ctx context.Context, expiredWindo
 
 	const selectUnsavedQueryIDs = `
 		SELECT id
-		FROM queries
+		FROM queries q
 		WHERE NOT saved
 		AND created_at < DATE_SUB(NOW(), INTERVAL ? DAY)
+		AND NOT EXISTS (
+			-- campaigns with stored results are deleted by CleanupExpiredQueryCampaigns
+			SELECT 1
+			FROM distributed_query_campaigns dqc
+			WHERE dqc.query_id = q.id
+			AND (dqc.deferred OR dqc.save_results)
+		)
 		ORDER BY id`
 	var allUnsavedQueryIDs []uint
 
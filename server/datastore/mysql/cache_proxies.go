@@ -0,0 +1,93 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) UpsertHostCacheProxyStats(ctx context.Context, hostID uint, stats fleet.CacheProxyStats) error {
+	// updated_at is set explicitly so that it changes even if the statistics
+	// did not, it is the last time the host reported them.
+	const stmt = `
+		INSERT INTO host_cache_proxy_stats (host_id, hits, misses, hit_bytes, miss_bytes, cached_items, cached_bytes)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			hits = VALUES(hits),
+			misses = VALUES(misses),
+			hit_bytes = VALUES(hit_bytes),
+			miss_bytes = VALUES(miss_bytes),
+			cached_items = VALUES(cached_items),
+			cached_bytes = VALUES(cached_bytes),
+			updated_at = CURRENT_TIMESTAMP(6)`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, hostID, stats.Hits, stats.Misses, stats.HitBytes,
+		stats.MissBytes, stats.CachedItems, stats.CachedBytes); err != nil {
+		return ctxerr.Wrap(ctx, err, "upsert host cache proxy stats")
+	}
+	return nil
+}
+
+func (ds *Datastore) ListHostCacheProxies(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.HostCacheProxy, error) {
+	stmt := `
+		SELECT
+			h.id AS host_id,
+			COALESCE(NULLIF(h.computer_name, ''), h.hostname) AS display_name,
+			h.team_id,
+			s.hits,
+			s.misses,
+			s.hit_bytes,
+			s.miss_bytes,
+			s.cached_items,
+			s.cached_bytes,
+			s.updated_at
+		FROM host_cache_proxy_stats s
+		JOIN hosts h ON h.id = s.host_id
+		WHERE ` + ds.whereFilterHostsByTeams(filter, "h") + `
+		ORDER BY h.id`
+
+	var proxies []*fleet.HostCacheProxy
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &proxies, stmt); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list host cache proxies")
+	}
+	return proxies, nil
+}
+
+func (ds *Datastore) GetCacheProxySoftwareInstaller(ctx context.Context, hostID uint, hashSHA256 string) (*fleet.SoftwareInstaller, error) {
+	// NOTE: like ValidateOrbitSoftwareInstallerAccess, this only looks in
+	// host_software_installs (and ignores upcoming_activities), the hosts
+	// don't download the installer until it is ready to install.
+	const stmt = `
+		SELECT
+			si.id,
+			si.team_id,
+			si.filename,
+			si.storage_id
+		FROM host_cache_proxy_stats s
+		JOIN hosts h ON h.id = s.host_id
+		JOIN software_installers si ON si.global_or_team_id = COALESCE(h.team_id, 0) AND si.storage_id = ?
+		WHERE
+			s.host_id = ? AND
+			EXISTS (
+				SELECT 1
+				FROM host_software_installs hsi
+				WHERE
+					hsi.software_installer_id = si.id AND
+					hsi.install_script_exit_code IS NULL AND
+					hsi.canceled = 0
+			)
+		LIMIT 1`
+
+	var installer fleet.SoftwareInstaller
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &installer, stmt, hashSHA256, hostID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("SoftwareInstaller").WithName(hashSHA256), "get cache proxy software installer")
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get cache proxy software installer")
+	}
+	return &installer, nil
+}
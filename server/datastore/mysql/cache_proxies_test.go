@@ -0,0 +1,153 @@
+package mysql
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheProxies(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"UpsertAndList", testCacheProxiesUpsertAndList},
+		{"GetSoftwareInstaller", testCacheProxiesGetSoftwareInstaller},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testCacheProxiesUpsertAndList(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "branch"})
+	require.NoError(t, err)
+
+	h1 := test.NewHost(t, ds, "proxy1.local", "10.0.0.1", "1", "1", time.Now())
+	h2 := test.NewHost(t, ds, "proxy2.local", "10.0.1.1", "2", "2", time.Now())
+	require.NoError(t, ds.AddHostsToTeam(ctx, fleet.NewAddHostsToTeamParams(&team.ID, []uint{h2.ID})))
+	// a host that never reported statistics is not a caching proxy
+	test.NewHost(t, ds, "other.local", "10.0.0.2", "3", "3", time.Now())
+
+	admin := fleet.TeamFilter{User: test.UserAdmin}
+	proxies, err := ds.ListHostCacheProxies(ctx, admin)
+	require.NoError(t, err)
+	require.Empty(t, proxies)
+
+	require.NoError(t, ds.UpsertHostCacheProxyStats(ctx, h1.ID, fleet.CacheProxyStats{Hits: 1, Misses: 1, HitBytes: 10, MissBytes: 10}))
+	require.NoError(t, ds.UpsertHostCacheProxyStats(ctx, h2.ID, fleet.CacheProxyStats{Misses: 1, MissBytes: 5, CachedItems: 1, CachedBytes: 5}))
+	// the last report replaces the previous one
+	require.NoError(t, ds.UpsertHostCacheProxyStats(ctx, h1.ID, fleet.CacheProxyStats{
+		Hits: 3, Misses: 1, HitBytes: 30, MissBytes: 10, CachedItems: 1, CachedBytes: 10,
+	}))
+
+	proxies, err = ds.ListHostCacheProxies(ctx, admin)
+	require.NoError(t, err)
+	require.Len(t, proxies, 2)
+	assert.Equal(t, h1.ID, proxies[0].HostID)
+	assert.Equal(t, h1.Hostname, proxies[0].DisplayName)
+	assert.Nil(t, proxies[0].TeamID)
+	assert.Equal(t, fleet.CacheProxyStats{Hits: 3, Misses: 1, HitBytes: 30, MissBytes: 10, CachedItems: 1, CachedBytes: 10},
+		proxies[0].CacheProxyStats)
+	assert.False(t, proxies[0].UpdatedAt.IsZero())
+	assert.Equal(t, h2.ID, proxies[1].HostID)
+	assert.Equal(t, ptr.Uint(team.ID), proxies[1].TeamID)
+
+	// filter by team
+	proxies, err = ds.ListHostCacheProxies(ctx, fleet.TeamFilter{User: test.UserAdmin, TeamID: &team.ID})
+	require.NoError(t, err)
+	require.Len(t, proxies, 1)
+	assert.Equal(t, h2.ID, proxies[0].HostID)
+
+	// a team user only sees the proxies of its teams
+	teamUser := &fleet.User{Teams: []fleet.UserTeam{{Team: *team, Role: fleet.RoleObserver}}}
+	proxies, err = ds.ListHostCacheProxies(ctx, fleet.TeamFilter{User: teamUser, IncludeObserver: true})
+	require.NoError(t, err)
+	require.Len(t, proxies, 1)
+	assert.Equal(t, h2.ID, proxies[0].HostID)
+
+	// the statistics are deleted with the host
+	require.NoError(t, ds.DeleteHosts(ctx, []uint{h1.ID}))
+	proxies, err = ds.ListHostCacheProxies(ctx, admin)
+	require.NoError(t, err)
+	require.Len(t, proxies, 1)
+	assert.Equal(t, h2.ID, proxies[0].HostID)
+}
+
+func testCacheProxiesGetSoftwareInstaller(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "branch"})
+	require.NoError(t, err)
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+
+	proxy := test.NewHost(t, ds, "proxy.local", "10.0.0.1", "1", "1", time.Now())
+	otherProxy := test.NewHost(t, ds, "other-proxy.local", "10.0.1.1", "2", "2", time.Now())
+	host := test.NewHost(t, ds, "host.local", "10.0.0.2", "3", "3", time.Now())
+	require.NoError(t, ds.AddHostsToTeam(ctx, fleet.NewAddHostsToTeamParams(&team.ID, []uint{proxy.ID, host.ID})))
+
+	tfr, err := fleet.NewTempFileReader(strings.NewReader("hello"), t.TempDir)
+	require.NoError(t, err)
+	installerID, _, err := ds.MatchOrCreateSoftwareInstaller(ctx, &fleet.UploadSoftwareInstallerPayload{
+		InstallScript:   "install",
+		InstallerFile:   tfr,
+		StorageID:       "hash1",
+		Filename:        "file1.pkg",
+		Title:           "file1",
+		Version:         "1.0",
+		Source:          "apps",
+		UserID:          user.ID,
+		TeamID:          &team.ID,
+		ValidatedLabels: &fleet.LabelIdentsWithScope{},
+	})
+	require.NoError(t, err)
+
+	requireNotFound := func(hostID uint, hash string) {
+		_, err := ds.GetCacheProxySoftwareInstaller(ctx, hostID, hash)
+		require.True(t, fleet.IsNotFound(err))
+	}
+
+	// no host is about to install it
+	require.NoError(t, ds.UpsertHostCacheProxyStats(ctx, otherProxy.ID, fleet.CacheProxyStats{}))
+	requireNotFound(proxy.ID, "hash1")
+
+	installUUID, err := ds.InsertSoftwareInstallRequest(ctx, host.ID, installerID, fleet.HostSoftwareInstallOptions{})
+	require.NoError(t, err)
+
+	// the host never reported caching proxy statistics
+	requireNotFound(proxy.ID, "hash1")
+
+	require.NoError(t, ds.UpsertHostCacheProxyStats(ctx, proxy.ID, fleet.CacheProxyStats{}))
+	installer, err := ds.GetCacheProxySoftwareInstaller(ctx, proxy.ID, "hash1")
+	require.NoError(t, err)
+	assert.Equal(t, installerID, installer.InstallerID)
+	assert.Equal(t, ptr.Uint(team.ID), installer.TeamID)
+	assert.Equal(t, "file1.pkg", installer.Name)
+	assert.Equal(t, "hash1", installer.StorageID)
+
+	// unknown installer, and caching proxy of another fleet
+	requireNotFound(proxy.ID, "hash2")
+	requireNotFound(otherProxy.ID, "hash1")
+
+	// the install is done
+	_, err = ds.SetHostSoftwareInstallResult(ctx, &fleet.HostSoftwareInstallResultPayload{
+		HostID:                host.ID,
+		InstallUUID:           installUUID,
+		InstallScriptExitCode: ptr.Int(0),
+	}, nil)
+	require.NoError(t, err)
+	requireNotFound(proxy.ID, "hash1")
+}
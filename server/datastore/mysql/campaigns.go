@@ -2,12 +2,15 @@ package mysql
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	common_mysql "github.com/fleetdm/fleet/v4/server/platform/mysql"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -28,7 +31,7 @@ const (
 )
 
 func (ds *Datastore) NewDistributedQueryCampaign(ctx context.Context, camp *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaign, error) {
-	args := []any{camp.QueryID, camp.Status, camp.UserID}
+	args := []any{camp.QueryID, camp.Status, camp.UserID, camp.Deferred, camp.ExpiresAt, camp.SaveResults}
 
 	// for tests, we sometimes provide specific timestamps for CreatedAt, honor
 	// those if provided.
@@ -43,10 +46,13 @@ func (ds *Datastore) NewDistributedQueryCampaign(ctx context.Context, camp *flee
 		INSERT INTO distributed_query_campaigns (
 			query_id,
 			status,
-			user_id
+			user_id,
+			deferred,
+			expires_at,
+			save_results
 			%s
 		)
-		VALUES(?,?,?%s)
+		VALUES(?,?,?,?,?,?%s)
 	`, createdAtField, createdAtPlaceholder)
 	result, err := ds.writer(ctx).ExecContext(ctx, sqlStatement, args...)
 	if err != nil {
@@ -192,12 +198,15 @@ func (ds *Datastore) GetCompletedCampaigns(ctx context.Context, filter []uint) (
 }
 
 func (ds *Datastore) CleanupDistributedQueryCampaigns(ctx context.Context, now time.Time) (expired uint, err error) {
-	// Expire old waiting/running campaigns
+	// Expire old waiting/running campaigns. Deferred campaigns are expired by
+	// CompleteExpiredDeferredQueryCampaigns.
 	const sqlStatement = `
 		UPDATE distributed_query_campaigns
 		SET status = ?
-		WHERE (status = ? AND created_at < ?)
-		OR (status = ? AND created_at < ?)
+		WHERE NOT deferred AND (
+			(status = ? AND created_at < ?)
+			OR (status = ? AND created_at < ?)
+		)
 	`
 	result, err := ds.writer(ctx).ExecContext(ctx, sqlStatement, fleet.QueryComplete,
 		fleet.QueryWaiting, now.Add(-1*time.Minute),
@@ -213,6 +222,249 @@ func (ds *Datastore) CleanupDistributedQueryCampaigns(ctx context.Context, now t
 	return uint(exp), nil //nolint:gosec // dismiss G115
 }
 
+func (ds *Datastore) CompleteExpiredDeferredQueryCampaigns(ctx context.Context, now time.Time) (completed uint, err error) {
+	const stmt = `
+		UPDATE distributed_query_campaigns
+		SET status = ?
+		WHERE expires_at < ? AND status <> ? AND deferred
+	`
+	result, err := ds.writer(ctx).ExecContext(ctx, stmt, fleet.QueryComplete, now, fleet.QueryComplete)
+	if err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "completing expired deferred query campaigns")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "rows affected completing expired deferred query campaigns")
+	}
+	return uint(n), nil //nolint:gosec // dismiss G115
+}
+
+func (ds *Datastore) SaveCampaignQueryResult(ctx context.Context, campaignID uint, result *fleet.CampaignQueryResult) error {
+	// A host answers a deferred campaign once, but it may send its results
+	// again if it checks in before the completion is recorded. The latest
+	// results are kept.
+	const stmt = `
+		INSERT INTO distributed_query_campaign_results
+			(distributed_query_campaign_id, host_id, hostname, host_display_name, data, error, stats, data_key)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			hostname = VALUES(hostname),
+			host_display_name = VALUES(host_display_name),
+			data = VALUES(data),
+			error = VALUES(error),
+			stats = VALUES(stats),
+			data_key = VALUES(data_key),
+			created_at = NOW(6)
+	`
+	var data, stats []byte
+	// The rows are in the CampaignResultsStore when the result has a data key.
+	if result.Rows != nil && result.DataKey == nil {
+		var err error
+		if data, err = json.Marshal(result.Rows); err != nil {
+			return ctxerr.Wrap(ctx, err, "marshal campaign query result rows")
+		}
+	}
+	if result.Stats != nil {
+		var err error
+		if stats, err = json.Marshal(result.Stats); err != nil {
+			return ctxerr.Wrap(ctx, err, "marshal campaign query result stats")
+		}
+	}
+	_, err := ds.writer(ctx).ExecContext(ctx, stmt, campaignID, result.HostID, result.Hostname, result.HostDisplayName, data, result.Error, stats, result.DataKey)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "insert campaign query result")
+	}
+	return nil
+}
+
+var campaignQueryResultAllowedOrderKeys = common_mysql.OrderKeyAllowlist{
+	"host_id":    "host_id",
+	"hostname":   "hostname",
+	"created_at": "created_at",
+}
+
+func (ds *Datastore) ListCampaignQueryResults(ctx context.Context, campaignID uint, opts fleet.ListOptions) ([]*fleet.CampaignQueryResult, *fleet.PaginationMetadata, error) {
+	stmt := `
+		SELECT host_id, hostname, host_display_name, data, error, stats, data_key, created_at
+		FROM distributed_query_campaign_results
+		WHERE distributed_query_campaign_id = ?`
+	args := []any{campaignID}
+
+	if opts.OrderKey == "" {
+		opts.OrderKey = "host_id"
+	}
+	stmt, args, err := appendListOptionsWithCursorToSQLSecure(stmt, args, &opts, campaignQueryResultAllowedOrderKeys)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "apply list options")
+	}
+
+	var rows []struct {
+		fleet.CampaignQueryResult
+		Data  *json.RawMessage `db:"data"`
+		Stats *json.RawMessage `db:"stats"`
+	}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &rows, stmt, args...); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "list campaign query results")
+	}
+
+	var meta *fleet.PaginationMetadata
+	if opts.IncludeMetadata {
+		meta = &fleet.PaginationMetadata{HasPreviousResults: opts.Page > 0}
+		// `appendListOptionsWithCursorToSQL` fetches one more row than requested
+		// to know if there are more results.
+		if len(rows) > int(opts.PerPage) { //nolint:gosec // dismiss G115
+			meta.HasNextResults = true
+			rows = rows[:len(rows)-1]
+		}
+	}
+
+	results := make([]*fleet.CampaignQueryResult, 0, len(rows))
+	for _, r := range rows {
+		res := r.CampaignQueryResult
+		if r.Data != nil {
+			if err := json.Unmarshal(*r.Data, &res.Rows); err != nil {
+				return nil, nil, ctxerr.Wrap(ctx, err, "unmarshal campaign query result rows")
+			}
+		}
+		if r.Stats != nil {
+			if err := json.Unmarshal(*r.Stats, &res.Stats); err != nil {
+				return nil, nil, ctxerr.Wrap(ctx, err, "unmarshal campaign query result stats")
+			}
+		}
+		results = append(results, &res)
+	}
+	return results, meta, nil
+}
+
+func (ds *Datastore) CountCampaignQueryResults(ctx context.Context, campaignID uint) (uint, error) {
+	var count uint
+	err := sqlx.GetContext(ctx, ds.reader(ctx), &count,
+		`SELECT COUNT(*) FROM distributed_query_campaign_results WHERE distributed_query_campaign_id = ?`, campaignID)
+	if err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "count campaign query results")
+	}
+	return count, nil
+}
+
+var queryCampaignSummaryAllowedOrderKeys = common_mysql.OrderKeyAllowlist{
+	"id":         "dqc.id",
+	"created_at": "dqc.created_at",
+	"query_name": "q.name",
+}
+
+func (ds *Datastore) ListQueryCampaigns(ctx context.Context, opts fleet.ListQueryCampaignsOptions) ([]*fleet.QueryCampaignSummary, *fleet.PaginationMetadata, error) {
+	stmt := `
+		SELECT
+			dqc.id,
+			dqc.query_id,
+			q.name AS query_name,
+			q.query,
+			q.saved,
+			dqc.status,
+			dqc.deferred,
+			dqc.save_results,
+			dqc.created_at,
+			dqc.expires_at,
+			(
+				SELECT COUNT(*)
+				FROM distributed_query_campaign_results dqcr
+				WHERE dqcr.distributed_query_campaign_id = dqc.id
+			) AS responded_hosts
+		FROM distributed_query_campaigns dqc
+		INNER JOIN queries q ON q.id = dqc.query_id
+		WHERE dqc.user_id = ? AND (dqc.deferred OR dqc.save_results)`
+	args := []any{opts.UserID}
+	if opts.QueryID != nil {
+		stmt += ` AND dqc.query_id = ?`
+		args = append(args, *opts.QueryID)
+	}
+
+	if opts.OrderKey == "" {
+		opts.OrderKey = "id"
+		opts.OrderDirection = fleet.OrderDescending
+	}
+	stmt, args, err := appendListOptionsWithCursorToSQLSecure(stmt, args, &opts.ListOptions, queryCampaignSummaryAllowedOrderKeys)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "apply list options")
+	}
+
+	var campaigns []*fleet.QueryCampaignSummary
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &campaigns, stmt, args...); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "list query campaigns")
+	}
+
+	var meta *fleet.PaginationMetadata
+	if opts.IncludeMetadata {
+		meta = &fleet.PaginationMetadata{HasPreviousResults: opts.Page > 0}
+		if len(campaigns) > int(opts.PerPage) { //nolint:gosec // dismiss G115
+			meta.HasNextResults = true
+			campaigns = campaigns[:len(campaigns)-1]
+		}
+	}
+	return campaigns, meta, nil
+}
+
+// CleanupExpiredQueryCampaigns deletes the completed campaigns with stored
+// results created before olderThan, along with their targets and results.
+func (ds *Datastore) CleanupExpiredQueryCampaigns(ctx context.Context, olderThan time.Time) (deleted uint, err error) {
+	const selectStmt = `
+		SELECT id
+		FROM distributed_query_campaigns
+		WHERE (deferred OR save_results)
+		AND status = ?
+		AND created_at < ?
+		ORDER BY id`
+	var campaignIDs []uint
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &campaignIDs, selectStmt, fleet.QueryComplete, olderThan); err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "selecting expired query campaigns")
+	}
+
+	for ids := range slices.Chunk(campaignIDs, deleteIDsBatchSize) {
+		err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+			stmt, args, err := sqlx.In(`DELETE FROM distributed_query_campaign_targets WHERE distributed_query_campaign_id IN (?)`, ids)
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "building delete expired query campaign targets")
+			}
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "deleting expired query campaign targets")
+			}
+
+			// results are deleted by the foreign key cascade
+			stmt, args, err = sqlx.In(`DELETE FROM distributed_query_campaigns WHERE id IN (?)`, ids)
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "building delete expired query campaigns")
+			}
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "deleting expired query campaigns")
+			}
+			return nil
+		})
+		if err != nil {
+			return deleted, err
+		}
+		deleted += uint(len(ids)) //nolint:gosec // dismiss G115
+	}
+	return deleted, nil
+}
+
+func (ds *Datastore) CleanupUnusedCampaignResultsData(ctx context.Context, resultsStore fleet.CampaignResultsStore, removeCreatedBefore time.Time) error {
+	if resultsStore == nil {
+		return nil
+	}
+
+	// Read from the writer: a stale replica missing a freshly-inserted
+	// data_key would cause its in-use file to be deleted.
+	var dataKeys []string
+	if err := sqlx.SelectContext(ctx, ds.writer(ctx), &dataKeys,
+		`SELECT data_key FROM distributed_query_campaign_results WHERE data_key IS NOT NULL`); err != nil {
+		return ctxerr.Wrap(ctx, err, "get list of campaign results data in use")
+	}
+
+	_, err := resultsStore.Cleanup(ctx, dataKeys, removeCreatedBefore)
+	return ctxerr.Wrap(ctx, err, "cleanup unused campaign results data")
+}
+
 // CleanupCompletedCampaignTargets removes campaign targets for campaigns that have been
 // completed for more than the specified duration. This helps improve campaign performance by
 // cleaning up historical data that is no longer needed.
@@ -225,6 +477,7 @@ func (ds *Datastore) CleanupCompletedCampaignTargets(ctx context.Context, olderT
 			ON dqc.id = dqct.distributed_query_campaign_id
 		WHERE dqc.status = ?
 		AND dqc.updated_at < ?
+		AND NOT dqc.deferred
 	`
 
 	var totalEligible uint
@@ -251,6 +504,7 @@ func (ds *Datastore) CleanupCompletedCampaignTargets(ctx context.Context, olderT
 	// Select targets to delete. We select targets from campaigns that:
 	// 1. Have status = QueryComplete
 	// 2. Were updated before the olderThan timestamp
+	// 3. Are not deferred (their targets are needed to report their progress)
 	const selectTargetsStmt = `
 		SELECT dqct.id
 		FROM distributed_query_campaign_targets dqct
@@ -258,6 +512,7 @@ func (ds *Datastore) CleanupCompletedCampaignTargets(ctx context.Context, olderT
 			ON dqc.id = dqct.distributed_query_campaign_id
 		WHERE dqc.status = ?
 		AND dqc.updated_at < ?
+		AND NOT dqc.deferred
 		ORDER BY dqct.id
 		LIMIT ?
 	`
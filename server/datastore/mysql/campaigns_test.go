@@ -2,6 +2,7 @@ package mysql
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"math/rand"
 	"sort"
@@ -28,6 +29,8 @@ func TestCampaigns(t *testing.T) {
 		{"CompletedCampaigns", testCompletedCampaigns},
 		{"CleanupCompletedCampaignTargets", testCleanupCompletedCampaignTargets},
 		{"CleanupCompletedCampaignTargetsLargeBatch", testCleanupCompletedCampaignTargetsLargeBatch},
+		{"DeferredCampaigns", testDeferredCampaigns},
+		{"SavedCampaignResults", testSavedCampaignResults},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -378,3 +381,235 @@ func testCompletedCampaigns(t *testing.T, ds *Datastore) {
 	assert.Equal(t, complete, result)
 
 }
+
+func testDeferredCampaigns(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	query := test.NewQuery(t, ds, nil, "test", "select * from time", user.ID, false)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	deferred, err := ds.NewDistributedQueryCampaign(ctx, &fleet.DistributedQueryCampaign{
+		UpdateCreateTimestamps: fleet.UpdateCreateTimestamps{
+			CreateTimestamp: fleet.CreateTimestamp{CreatedAt: now.Add(-2 * time.Hour)},
+		},
+		QueryID:   query.ID,
+		Status:    fleet.QueryRunning,
+		UserID:    user.ID,
+		Deferred:  true,
+		ExpiresAt: new(now.Add(time.Hour)),
+	})
+	require.NoError(t, err)
+	live := test.NewCampaign(t, ds, query.ID, fleet.QueryWaiting, now.Add(-2*time.Hour))
+
+	got, err := ds.DistributedQueryCampaign(ctx, deferred.ID)
+	require.NoError(t, err)
+	require.True(t, got.Deferred)
+	require.NotNil(t, got.ExpiresAt)
+	require.WithinDuration(t, now.Add(time.Hour), *got.ExpiresAt, time.Second)
+
+	// the live campaigns cleanup leaves deferred campaigns alone
+	expired, err := ds.CleanupDistributedQueryCampaigns(ctx, now.Add(24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, uint(1), expired)
+	got, err = ds.DistributedQueryCampaign(ctx, deferred.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.QueryRunning, got.Status)
+
+	// deferred campaigns are completed once expired
+	completed, err := ds.CompleteExpiredDeferredQueryCampaigns(ctx, now)
+	require.NoError(t, err)
+	require.Zero(t, completed)
+	completed, err = ds.CompleteExpiredDeferredQueryCampaigns(ctx, now.Add(time.Hour+time.Second))
+	require.NoError(t, err)
+	require.Equal(t, uint(1), completed)
+	got, err = ds.DistributedQueryCampaign(ctx, deferred.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.QueryComplete, got.Status)
+	got, err = ds.DistributedQueryCampaign(ctx, live.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.QueryComplete, got.Status)
+
+	// the targets of completed deferred campaigns are kept
+	for _, c := range []*fleet.DistributedQueryCampaign{deferred, live} {
+		_, err = ds.NewDistributedQueryCampaignTarget(ctx, &fleet.DistributedQueryCampaignTarget{
+			Type: fleet.TargetHost, DistributedQueryCampaignID: c.ID, TargetID: 1,
+		})
+		require.NoError(t, err)
+	}
+	deleted, err := ds.CleanupCompletedCampaignTargets(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, uint(1), deleted)
+	targets, err := ds.DistributedQueryCampaignTargetIDs(ctx, deferred.ID)
+	require.NoError(t, err)
+	require.Equal(t, []uint{1}, targets.HostIDs)
+
+	// store the results of 3 hosts, the third one sends its results twice
+	errMsg := "no such table: foo"
+	require.NoError(t, ds.SaveCampaignQueryResult(ctx, deferred.ID, &fleet.CampaignQueryResult{
+		HostID: 3, Hostname: "host3", HostDisplayName: "Host 3", Rows: []map[string]string{{"a": "1"}},
+	}))
+	require.NoError(t, ds.SaveCampaignQueryResult(ctx, deferred.ID, &fleet.CampaignQueryResult{
+		HostID: 1, Hostname: "host1", HostDisplayName: "Host 1", Error: &errMsg,
+	}))
+	require.NoError(t, ds.SaveCampaignQueryResult(ctx, deferred.ID, &fleet.CampaignQueryResult{
+		HostID: 2, Hostname: "host2", HostDisplayName: "Host 2", Rows: []map[string]string{},
+	}))
+	require.NoError(t, ds.SaveCampaignQueryResult(ctx, deferred.ID, &fleet.CampaignQueryResult{
+		HostID: 3, Hostname: "host3", HostDisplayName: "Host 3", Rows: []map[string]string{{"a": "2"}, {"a": "3"}},
+	}))
+	// results of other campaigns are not returned
+	require.NoError(t, ds.SaveCampaignQueryResult(ctx, live.ID, &fleet.CampaignQueryResult{HostID: 4}))
+
+	count, err := ds.CountCampaignQueryResults(ctx, deferred.ID)
+	require.NoError(t, err)
+	require.Equal(t, uint(3), count)
+
+	results, meta, err := ds.ListCampaignQueryResults(ctx, deferred.ID, fleet.ListOptions{PerPage: 2, IncludeMetadata: true})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, &fleet.PaginationMetadata{HasNextResults: true}, meta)
+	require.Equal(t, uint(1), results[0].HostID)
+	require.Equal(t, "Host 1", results[0].HostDisplayName)
+	require.Nil(t, results[0].Rows)
+	require.Equal(t, &errMsg, results[0].Error)
+	require.Equal(t, uint(2), results[1].HostID)
+	require.NotNil(t, results[1].Rows)
+	require.Empty(t, results[1].Rows)
+	require.Nil(t, results[1].Error)
+
+	results, meta, err = ds.ListCampaignQueryResults(ctx, deferred.ID, fleet.ListOptions{Page: 1, PerPage: 2, IncludeMetadata: true})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, &fleet.PaginationMetadata{HasPreviousResults: true}, meta)
+	require.Equal(t, "host3", results[0].Hostname)
+	require.Equal(t, []map[string]string{{"a": "2"}, {"a": "3"}}, results[0].Rows)
+	require.False(t, results[0].CreatedAt.IsZero())
+
+	// results are deleted with their campaign
+	_, err = ds.writer(ctx).ExecContext(ctx, `DELETE FROM distributed_query_campaigns WHERE id = ?`, deferred.ID)
+	require.NoError(t, err)
+	count, err = ds.CountCampaignQueryResults(ctx, deferred.ID)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+type cleanupCampaignResultsStore struct {
+	fleet.CampaignResultsStore
+	usedKeys []string
+}
+
+func (s *cleanupCampaignResultsStore) Cleanup(_ context.Context, usedKeys []string, _ time.Time) (int, error) {
+	s.usedKeys = usedKeys
+	return 0, nil
+}
+
+func testSavedCampaignResults(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	otherUser := test.NewUser(t, ds, "Other", "other@fleet.co", true)
+	saved := test.NewQuery(t, ds, nil, "saved", "select * from time", user.ID, true)
+	adHoc := test.NewQuery(t, ds, nil, "ad hoc", "select * from osquery_info", user.ID, false)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	newCampaign := func(queryID, userID uint, saveResults bool, createdAt time.Time) *fleet.DistributedQueryCampaign {
+		c, err := ds.NewDistributedQueryCampaign(ctx, &fleet.DistributedQueryCampaign{
+			UpdateCreateTimestamps: fleet.UpdateCreateTimestamps{
+				CreateTimestamp: fleet.CreateTimestamp{CreatedAt: createdAt},
+			},
+			QueryID:     queryID,
+			Status:      fleet.QueryComplete,
+			UserID:      userID,
+			SaveResults: saveResults,
+		})
+		require.NoError(t, err)
+		return c
+	}
+	oldRun := newCampaign(saved.ID, user.ID, true, now.Add(-48*time.Hour))
+	newRun := newCampaign(saved.ID, user.ID, true, now.Add(-time.Hour))
+	adHocRun := newCampaign(adHoc.ID, user.ID, true, now.Add(-time.Hour))
+	newCampaign(saved.ID, user.ID, false, now.Add(-time.Hour))
+	newCampaign(saved.ID, otherUser.ID, true, now.Add(-time.Hour))
+
+	got, err := ds.DistributedQueryCampaign(ctx, newRun.ID)
+	require.NoError(t, err)
+	require.True(t, got.SaveResults)
+	require.True(t, got.HasStoredResults())
+
+	// results are stored with their stats, large rows are referenced by key
+	stats := &fleet.Stats{WallTimeMs: 5, UserTime: 2, SystemTime: 1, Memory: 100}
+	require.NoError(t, ds.SaveCampaignQueryResult(ctx, newRun.ID, &fleet.CampaignQueryResult{
+		HostID: 1, Hostname: "host1", Rows: []map[string]string{{"a": "1"}}, Stats: stats,
+	}))
+	require.NoError(t, ds.SaveCampaignQueryResult(ctx, newRun.ID, &fleet.CampaignQueryResult{
+		HostID: 2, Hostname: "host2", DataKey: new(fmt.Sprintf("%d-2", newRun.ID)),
+	}))
+	require.NoError(t, ds.SaveCampaignQueryResult(ctx, oldRun.ID, &fleet.CampaignQueryResult{
+		HostID: 1, Hostname: "host1", DataKey: new(fmt.Sprintf("%d-1", oldRun.ID)),
+	}))
+
+	results, _, err := ds.ListCampaignQueryResults(ctx, newRun.ID, fleet.ListOptions{PerPage: 10})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, stats, results[0].Stats)
+	require.Nil(t, results[0].DataKey)
+	require.Equal(t, []map[string]string{{"a": "1"}}, results[0].Rows)
+	require.Nil(t, results[1].Stats)
+	require.Nil(t, results[1].Rows)
+	require.Equal(t, fmt.Sprintf("%d-2", newRun.ID), *results[1].DataKey)
+
+	// only the user's campaigns with stored results are listed, most recent first
+	campaigns, meta, err := ds.ListQueryCampaigns(ctx, fleet.ListQueryCampaignsOptions{
+		ListOptions: fleet.ListOptions{PerPage: 10, IncludeMetadata: true},
+		UserID:      user.ID,
+	})
+	require.NoError(t, err)
+	require.False(t, meta.HasNextResults)
+	require.Len(t, campaigns, 3)
+	require.Equal(t, adHocRun.ID, campaigns[0].ID)
+	require.False(t, campaigns[0].Saved)
+	require.Equal(t, "select * from osquery_info", campaigns[0].QuerySQL)
+	require.Equal(t, newRun.ID, campaigns[1].ID)
+	require.Equal(t, "saved", campaigns[1].QueryName)
+	require.True(t, campaigns[1].SaveResults)
+	require.Equal(t, uint(2), campaigns[1].RespondedHosts)
+	require.Equal(t, oldRun.ID, campaigns[2].ID)
+
+	campaigns, _, err = ds.ListQueryCampaigns(ctx, fleet.ListQueryCampaignsOptions{
+		ListOptions: fleet.ListOptions{PerPage: 1, IncludeMetadata: true},
+		UserID:      user.ID,
+		QueryID:     &saved.ID,
+	})
+	require.NoError(t, err)
+	require.Len(t, campaigns, 1)
+	require.Equal(t, newRun.ID, campaigns[0].ID)
+
+	// ad hoc queries with stored results are not deleted with the expired live queries
+	_, err = ds.writer(ctx).ExecContext(ctx, `UPDATE queries SET created_at = ? WHERE id = ?`, now.Add(-72*time.Hour), adHoc.ID)
+	require.NoError(t, err)
+	require.NoError(t, ds.CleanupExpiredLiveQueries(ctx, 1))
+	_, err = ds.Query(ctx, adHoc.ID)
+	require.NoError(t, err)
+
+	// campaigns past the retention period are deleted with their results
+	_, err = ds.NewDistributedQueryCampaignTarget(ctx, &fleet.DistributedQueryCampaignTarget{
+		Type: fleet.TargetHost, DistributedQueryCampaignID: oldRun.ID, TargetID: 1,
+	})
+	require.NoError(t, err)
+	deleted, err := ds.CleanupExpiredQueryCampaigns(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, uint(1), deleted)
+	_, err = ds.DistributedQueryCampaign(ctx, oldRun.ID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	count, err := ds.CountCampaignQueryResults(ctx, oldRun.ID)
+	require.NoError(t, err)
+	require.Zero(t, count)
+	targets, err := ds.DistributedQueryCampaignTargetIDs(ctx, oldRun.ID)
+	require.NoError(t, err)
+	require.Empty(t, targets.HostIDs)
+
+	// the data of deleted results is unused
+	store := &cleanupCampaignResultsStore{}
+	require.NoError(t, ds.CleanupUnusedCampaignResultsData(ctx, store, now))
+	require.Equal(t, []string{fmt.Sprintf("%d-2", newRun.ID)}, store.usedKeys)
+	require.NoError(t, ds.CleanupUnusedCampaignResultsData(ctx, nil, now))
+}
@@ -0,0 +1,236 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/text/unicode/norm"
+)
+
+const customRoleSelectColumns = `id, name, description, permissions, created_at, updated_at`
+
+func (ds *Datastore) NewCustomRole(ctx context.Context, role *fleet.CustomRole) (*fleet.CustomRole, error) {
+	res, err := ds.writer(ctx).ExecContext(ctx,
+		`INSERT INTO custom_roles (name, description, permissions) VALUES (?, ?, ?)`,
+		role.Name, role.Description, role.Permissions,
+	)
+	if err != nil {
+		if IsDuplicate(err) {
+			return nil, ctxerr.Wrap(ctx, alreadyExists("CustomRole", role.Name), "found duplicate")
+		}
+		return nil, ctxerr.Wrap(ctx, err, "insert custom role")
+	}
+	id, _ := res.LastInsertId()
+	return ds.customRoleDB(ctx, ds.writer(ctx), uint(id)) //nolint:gosec // dismiss G115
+}
+
+func (ds *Datastore) CustomRole(ctx context.Context, id uint) (*fleet.CustomRole, error) {
+	return ds.customRoleDB(ctx, ds.reader(ctx), id)
+}
+
+func (ds *Datastore) customRoleDB(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.CustomRole, error) {
+	var role fleet.CustomRole
+	err := sqlx.GetContext(ctx, q, &role, `SELECT `+customRoleSelectColumns+` FROM custom_roles WHERE id = ?`, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("CustomRole").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get custom role")
+	}
+	return &role, nil
+}
+
+func (ds *Datastore) ListCustomRoles(ctx context.Context) ([]fleet.CustomRole, error) {
+	roles := []fleet.CustomRole{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &roles,
+		`SELECT `+customRoleSelectColumns+` FROM custom_roles ORDER BY name`); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list custom roles")
+	}
+	return roles, nil
+}
+
+func (ds *Datastore) SaveCustomRole(ctx context.Context, role *fleet.CustomRole) (*fleet.CustomRole, error) {
+	_, err := ds.writer(ctx).ExecContext(ctx,
+		`UPDATE custom_roles SET name = ?, description = ?, permissions = ? WHERE id = ?`,
+		role.Name, role.Description, role.Permissions, role.ID,
+	)
+	if err != nil {
+		if IsDuplicate(err) {
+			return nil, ctxerr.Wrap(ctx, alreadyExists("CustomRole", role.Name), "found duplicate")
+		}
+		return nil, ctxerr.Wrap(ctx, err, "update custom role")
+	}
+	// read back on the writer, the row may not be on the replica yet (and no
+	// rows affected doesn't distinguish a missing role from an unchanged one)
+	return ds.customRoleDB(ctx, ds.writer(ctx), role.ID)
+}
+
+func (ds *Datastore) DeleteCustomRole(ctx context.Context, id uint) error {
+	res, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM custom_roles WHERE id = ?`, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "delete custom role")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("CustomRole").WithID(id))
+	}
+	return nil
+}
+
+func (ds *Datastore) SetUserCustomRoles(ctx context.Context, userID uint, roles []fleet.UserCustomRole) error {
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_custom_roles WHERE user_id = ?`, userID); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete existing user custom roles")
+		}
+		if len(roles) == 0 {
+			return nil
+		}
+
+		var args []any
+		for _, r := range roles {
+			var globalOrTeamID uint
+			if r.TeamID != nil {
+				globalOrTeamID = *r.TeamID
+			}
+			args = append(args, userID, r.CustomRoleID, r.TeamID, globalOrTeamID)
+		}
+		stmt := `INSERT INTO user_custom_roles (user_id, custom_role_id, team_id, global_or_team_id) VALUES ` +
+			strings.TrimSuffix(strings.Repeat("(?,?,?,?),", len(roles)), ",")
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			switch {
+			case IsDuplicate(err):
+				return ctxerr.Wrap(ctx, alreadyExists("UserCustomRole", fmt.Sprintf("user %d", userID)), "duplicate user custom role")
+			case isChildForeignKeyError(err):
+				return ctxerr.Wrap(ctx, foreignKey("user_custom_roles", fmt.Sprintf("user_id=%d", userID)), "unknown user, custom role or team")
+			}
+			return ctxerr.Wrap(ctx, err, "insert user custom roles")
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) ApplyCustomRoles(ctx context.Context, specs []fleet.CustomRoleSpec) (created, edited, deleted []fleet.CustomRole, err error) {
+	err = ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		created, edited, deleted = nil, nil, nil
+
+		var existing []fleet.CustomRole
+		if err := sqlx.SelectContext(ctx, tx, &existing, `SELECT `+customRoleSelectColumns+` FROM custom_roles`); err != nil {
+			return ctxerr.Wrap(ctx, err, "list existing custom roles")
+		}
+		// names are compared the same way as the unique index does
+		// (utf8mb4_unicode_ci), so that a change of letter case is an edit
+		byName := make(map[string]fleet.CustomRole, len(existing))
+		for _, e := range existing {
+			byName[customRoleNameKey(e.Name)] = e
+		}
+
+		incoming := make(map[string]struct{}, len(specs))
+		for _, spec := range specs {
+			key := customRoleNameKey(spec.Name)
+			incoming[key] = struct{}{}
+
+			role := fleet.CustomRole{
+				Name:        spec.Name,
+				Description: spec.Description,
+				Permissions: spec.Permissions,
+			}
+			prev, ok := byName[key]
+			if !ok {
+				res, err := tx.ExecContext(ctx,
+					`INSERT INTO custom_roles (name, description, permissions) VALUES (?, ?, ?)`,
+					role.Name, role.Description, role.Permissions)
+				if err != nil {
+					return ctxerr.Wrap(ctx, err, "insert custom role")
+				}
+				id, _ := res.LastInsertId()
+				role.ID = uint(id) //nolint:gosec // dismiss G115
+				created = append(created, role)
+				continue
+			}
+
+			role.ID = prev.ID
+			if prev.Name == role.Name && prev.Description == role.Description &&
+				slices.Equal(prev.Permissions, role.Permissions) {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE custom_roles SET name = ?, description = ?, permissions = ? WHERE id = ?`,
+				role.Name, role.Description, role.Permissions, role.ID); err != nil {
+				return ctxerr.Wrap(ctx, err, "update custom role")
+			}
+			edited = append(edited, role)
+		}
+
+		for _, e := range existing {
+			if _, ok := incoming[customRoleNameKey(e.Name)]; !ok {
+				deleted = append(deleted, e)
+			}
+		}
+		if len(deleted) > 0 {
+			ids := make([]uint, 0, len(deleted))
+			for _, r := range deleted {
+				ids = append(ids, r.ID)
+			}
+			stmt, args, err := sqlx.In(`DELETE FROM custom_roles WHERE id IN (?)`, ids)
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "build delete custom roles query")
+			}
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "delete custom roles")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return created, edited, deleted, nil
+}
+
+func customRoleNameKey(name string) string {
+	return norm.NFC.String(strings.ToLower(name))
+}
+
+// loadCustomRolesForUsers loads the custom roles assigned to the provided
+// users, with their permissions.
+func (ds *Datastore) loadCustomRolesForUsers(ctx context.Context, users []*fleet.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	idToUser := make(map[uint]*fleet.User, len(users))
+	userIDs := make([]uint, 0, len(users))
+	for _, u := range users {
+		u.CustomRoles = nil
+		userIDs = append(userIDs, u.ID)
+		idToUser[u.ID] = u
+	}
+
+	stmt, args, err := sqlx.In(`
+		SELECT ucr.user_id, ucr.custom_role_id, ucr.team_id, cr.name, cr.permissions
+		FROM user_custom_roles ucr INNER JOIN custom_roles cr ON ucr.custom_role_id = cr.id
+		WHERE ucr.user_id IN (?)
+		ORDER BY ucr.user_id, cr.name, ucr.global_or_team_id
+	`, userIDs)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "sqlx.In loadCustomRolesForUsers")
+	}
+
+	var rows []struct {
+		fleet.UserCustomRole
+		UserID uint `db:"user_id"`
+	}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &rows, stmt, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "get loadCustomRolesForUsers")
+	}
+	for _, r := range rows {
+		user := idToUser[r.UserID]
+		user.CustomRoles = append(user.CustomRoles, r.UserCustomRole)
+	}
+	return nil
+}
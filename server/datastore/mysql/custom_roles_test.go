@@ -0,0 +1,244 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomRoles(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"CRUD", testCustomRolesCRUD},
+		{"UserCustomRoles", testUserCustomRoles},
+		{"ApplyCustomRoles", testApplyCustomRoles},
+		{"ListHostsCustomRoleOnly", testListHostsCustomRoleOnly},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testCustomRolesCRUD(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	perms := fleet.CustomRolePermissions{{Object: "host_script_result", Action: fleet.ActionWrite}}
+	helpdesk, err := ds.NewCustomRole(ctx, &fleet.CustomRole{Name: "Helpdesk", Description: "Runs scripts", Permissions: perms})
+	require.NoError(t, err)
+	require.NotZero(t, helpdesk.ID)
+	require.Equal(t, perms, helpdesk.Permissions)
+	require.False(t, helpdesk.CreatedAt.IsZero())
+
+	// names are unique, case-insensitively
+	_, err = ds.NewCustomRole(ctx, &fleet.CustomRole{Name: "helpdesk", Permissions: perms})
+	var aee fleet.AlreadyExistsError
+	require.ErrorAs(t, err, &aee)
+
+	security, err := ds.NewCustomRole(ctx, &fleet.CustomRole{Name: "Security", Permissions: fleet.CustomRolePermissions{
+		{Object: "query", Action: fleet.ActionWrite},
+		{Object: "software_inventory", Action: fleet.ActionRead},
+	}})
+	require.NoError(t, err)
+
+	roles, err := ds.ListCustomRoles(ctx)
+	require.NoError(t, err)
+	require.Len(t, roles, 2)
+	require.Equal(t, "Helpdesk", roles[0].Name)
+	require.Equal(t, "Security", roles[1].Name)
+
+	helpdesk.Description = "Runs scripts and locks hosts"
+	helpdesk.Permissions = append(helpdesk.Permissions, fleet.CustomRolePermission{Object: "mdm_command", Action: fleet.ActionWrite})
+	updated, err := ds.SaveCustomRole(ctx, helpdesk)
+	require.NoError(t, err)
+	require.Equal(t, helpdesk.Description, updated.Description)
+	require.Len(t, updated.Permissions, 2)
+
+	security.Name = "HELPDESK"
+	_, err = ds.SaveCustomRole(ctx, security)
+	require.ErrorAs(t, err, &aee)
+
+	require.NoError(t, ds.DeleteCustomRole(ctx, helpdesk.ID))
+	_, err = ds.CustomRole(ctx, helpdesk.ID)
+	require.True(t, fleet.IsNotFound(err))
+	require.True(t, fleet.IsNotFound(ds.DeleteCustomRole(ctx, helpdesk.ID)))
+	_, err = ds.SaveCustomRole(ctx, helpdesk)
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testUserCustomRoles(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	user := test.NewUser(t, ds, "Jane", "jane@example.com", false)
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	perms := fleet.CustomRolePermissions{{Object: "host_script_result", Action: fleet.ActionWrite}}
+	role, err := ds.NewCustomRole(ctx, &fleet.CustomRole{Name: "Helpdesk", Permissions: perms})
+	require.NoError(t, err)
+
+	loaded, err := ds.UserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Empty(t, loaded.CustomRoles)
+
+	require.NoError(t, ds.SetUserCustomRoles(ctx, user.ID, []fleet.UserCustomRole{
+		{CustomRoleID: role.ID},
+		{CustomRoleID: role.ID, TeamID: &team.ID},
+	}))
+	loaded, err = ds.UserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, []fleet.UserCustomRole{
+		{CustomRoleID: role.ID, Name: "Helpdesk", Permissions: perms},
+		{CustomRoleID: role.ID, TeamID: ptr.Uint(team.ID), Name: "Helpdesk", Permissions: perms},
+	}, loaded.CustomRoles)
+
+	users, err := ds.ListUsers(ctx, fleet.UserListOptions{})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Len(t, users[0].CustomRoles, 2)
+
+	// the same role can't be assigned twice for the same scope
+	err = ds.SetUserCustomRoles(ctx, user.ID, []fleet.UserCustomRole{{CustomRoleID: role.ID}, {CustomRoleID: role.ID}})
+	var aee fleet.AlreadyExistsError
+	require.ErrorAs(t, err, &aee)
+
+	// unknown roles and teams are rejected
+	err = ds.SetUserCustomRoles(ctx, user.ID, []fleet.UserCustomRole{{CustomRoleID: role.ID + 1}})
+	require.True(t, fleet.IsForeignKey(err))
+	err = ds.SetUserCustomRoles(ctx, user.ID, []fleet.UserCustomRole{{CustomRoleID: role.ID, TeamID: ptr.Uint(team.ID + 1)}})
+	require.True(t, fleet.IsForeignKey(err))
+
+	// deleting the team removes the team assignment
+	require.NoError(t, ds.DeleteTeam(ctx, team.ID))
+	loaded, err = ds.UserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, loaded.CustomRoles, 1)
+	require.Nil(t, loaded.CustomRoles[0].TeamID)
+
+	// deleting the role unassigns it
+	require.NoError(t, ds.DeleteCustomRole(ctx, role.ID))
+	loaded, err = ds.UserByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Empty(t, loaded.CustomRoles)
+}
+
+func testApplyCustomRoles(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	names := func(roles []fleet.CustomRole) []string {
+		out := make([]string, 0, len(roles))
+		for _, r := range roles {
+			out = append(out, r.Name)
+		}
+		return out
+	}
+
+	scripts := []fleet.CustomRolePermission{{Object: "host_script_result", Action: fleet.ActionWrite}}
+	queries := []fleet.CustomRolePermission{{Object: "query", Action: fleet.ActionWrite}}
+
+	created, edited, deleted, err := ds.ApplyCustomRoles(ctx, []fleet.CustomRoleSpec{
+		{Name: "Helpdesk", Permissions: scripts},
+		{Name: "Security", Permissions: queries},
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"Helpdesk", "Security"}, names(created))
+	require.Empty(t, edited)
+	require.Empty(t, deleted)
+
+	roles, err := ds.ListCustomRoles(ctx)
+	require.NoError(t, err)
+	require.Len(t, roles, 2)
+	helpdeskID := roles[0].ID
+
+	// unchanged roles are not edited, roles are matched case-insensitively and
+	// roles not in the specs are deleted
+	created, edited, deleted, err = ds.ApplyCustomRoles(ctx, []fleet.CustomRoleSpec{
+		{Name: "HelpDesk", Description: "Runs scripts", Permissions: scripts},
+		{Name: "Auditors", Permissions: queries},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Auditors"}, names(created))
+	require.Equal(t, []string{"HelpDesk"}, names(edited))
+	require.Equal(t, helpdeskID, edited[0].ID)
+	require.Equal(t, []string{"Security"}, names(deleted))
+
+	created, edited, deleted, err = ds.ApplyCustomRoles(ctx, []fleet.CustomRoleSpec{
+		{Name: "HelpDesk", Description: "Runs scripts", Permissions: scripts},
+		{Name: "Auditors", Permissions: queries},
+	})
+	require.NoError(t, err)
+	require.Empty(t, created)
+	require.Empty(t, edited)
+	require.Empty(t, deleted)
+
+	_, _, deleted, err = ds.ApplyCustomRoles(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, deleted, 2)
+	roles, err = ds.ListCustomRoles(ctx)
+	require.NoError(t, err)
+	require.Empty(t, roles)
+}
+
+func testListHostsCustomRoleOnly(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	team1, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	team2, err := ds.NewTeam(ctx, &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+	h1 := test.NewHost(t, ds, "h1", "10.0.0.1", "h1key", "h1uuid", time.Now())
+	h2 := test.NewHost(t, ds, "h2", "10.0.0.2", "h2key", "h2uuid", time.Now())
+	test.NewHost(t, ds, "h3", "10.0.0.3", "h3key", "h3uuid", time.Now())
+	require.NoError(t, ds.AddHostsToTeam(ctx, fleet.NewAddHostsToTeamParams(&team1.ID, []uint{h1.ID})))
+	require.NoError(t, ds.AddHostsToTeam(ctx, fleet.NewAddHostsToTeamParams(&team2.ID, []uint{h2.ID})))
+
+	reader, err := ds.NewCustomRole(ctx, &fleet.CustomRole{Name: "Reader", Permissions: fleet.CustomRolePermissions{
+		{Object: "host", Action: fleet.ActionRead},
+	}})
+	require.NoError(t, err)
+	scripter, err := ds.NewCustomRole(ctx, &fleet.CustomRole{Name: "Scripter", Permissions: fleet.CustomRolePermissions{
+		{Object: "script", Action: fleet.ActionWrite},
+	}})
+	require.NoError(t, err)
+
+	// the user has no built-in role, only custom roles
+	user := &fleet.User{Name: "Jane", Email: "jane@example.com", Password: []byte("foobar")}
+	user, err = ds.NewUser(ctx, user)
+	require.NoError(t, err)
+
+	listHostIDs := func() []uint {
+		loaded, err := ds.UserByID(ctx, user.ID)
+		require.NoError(t, err)
+		hosts, err := ds.ListHosts(ctx, fleet.TeamFilter{User: loaded}, fleet.HostListOptions{})
+		require.NoError(t, err)
+		ids := make([]uint, 0, len(hosts))
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+	require.Empty(t, listHostIDs())
+
+	// a role that grants no read access doesn't make hosts visible
+	require.NoError(t, ds.SetUserCustomRoles(ctx, user.ID, []fleet.UserCustomRole{{CustomRoleID: scripter.ID, TeamID: &team2.ID}}))
+	require.Empty(t, listHostIDs())
+
+	require.NoError(t, ds.SetUserCustomRoles(ctx, user.ID, []fleet.UserCustomRole{
+		{CustomRoleID: reader.ID, TeamID: &team1.ID},
+		{CustomRoleID: scripter.ID, TeamID: &team2.ID},
+	}))
+	require.ElementsMatch(t, []uint{h1.ID}, listHostIDs())
+
+	// assigned globally, the role makes all hosts visible
+	require.NoError(t, ds.SetUserCustomRoles(ctx, user.ID, []fleet.UserCustomRole{{CustomRoleID: reader.ID}}))
+	require.Len(t, listHostIDs(), 3)
+}
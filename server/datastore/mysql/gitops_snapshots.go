@@ -0,0 +1,127 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+// gitOpsSnapshotRow is a row of the gitops_snapshots table, with its JSON
+// columns not yet decoded.
+type gitOpsSnapshotRow struct {
+	TeamID        uint             `db:"team_id"`
+	Resources     json.RawMessage  `db:"resources"`
+	Checksum      string           `db:"checksum"`
+	AppliedAt     time.Time        `db:"applied_at"`
+	Drift         *json.RawMessage `db:"drift"`
+	DriftChecksum string           `db:"drift_checksum"`
+	CheckedAt     *time.Time       `db:"checked_at"`
+}
+
+const gitOpsSnapshotColumns = `team_id, resources, checksum, applied_at, drift, drift_checksum, checked_at`
+
+func (r *gitOpsSnapshotRow) toSnapshot() (*fleet.GitOpsSnapshot, error) {
+	s := &fleet.GitOpsSnapshot{
+		TeamID:        r.TeamID,
+		Checksum:      r.Checksum,
+		AppliedAt:     r.AppliedAt,
+		DriftChecksum: r.DriftChecksum,
+		CheckedAt:     r.CheckedAt,
+	}
+	if err := json.Unmarshal(r.Resources, &s.Resources); err != nil {
+		return nil, err
+	}
+	if r.Drift != nil {
+		if err := json.Unmarshal(*r.Drift, &s.Drift); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (ds *Datastore) SaveGitOpsSnapshot(ctx context.Context, snapshot *fleet.GitOpsSnapshot) error {
+	const stmt = `
+		INSERT INTO gitops_snapshots (team_id, resources, checksum, applied_at)
+		VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			resources = VALUES(resources),
+			checksum = VALUES(checksum),
+			applied_at = VALUES(applied_at),
+			drift = NULL,
+			drift_checksum = '',
+			checked_at = NULL`
+
+	resources, err := json.Marshal(snapshot.Resources)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal gitops snapshot resources")
+	}
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, snapshot.TeamID, resources, snapshot.Checksum, snapshot.AppliedAt); err != nil {
+		return ctxerr.Wrap(ctx, err, "save gitops snapshot")
+	}
+	return nil
+}
+
+func (ds *Datastore) GitOpsSnapshot(ctx context.Context, teamID uint) (*fleet.GitOpsSnapshot, error) {
+	var row gitOpsSnapshotRow
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &row, `SELECT `+gitOpsSnapshotColumns+` FROM gitops_snapshots WHERE team_id = ?`, teamID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("GitOpsSnapshot").WithID(teamID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get gitops snapshot")
+	}
+	s, err := row.toSnapshot()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "unmarshal gitops snapshot")
+	}
+	return s, nil
+}
+
+func (ds *Datastore) ListGitOpsSnapshots(ctx context.Context) ([]*fleet.GitOpsSnapshot, error) {
+	var rows []gitOpsSnapshotRow
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &rows, `SELECT `+gitOpsSnapshotColumns+` FROM gitops_snapshots ORDER BY team_id`); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list gitops snapshots")
+	}
+	snapshots := make([]*fleet.GitOpsSnapshot, 0, len(rows))
+	for i := range rows {
+		s, err := rows[i].toSnapshot()
+		if err != nil {
+			return nil, ctxerr.Wrapf(ctx, err, "unmarshal gitops snapshot of fleet %d", rows[i].TeamID)
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, nil
+}
+
+func (ds *Datastore) UpdateGitOpsSnapshotDrift(ctx context.Context, teamID uint, drift []fleet.GitOpsResourceChange, driftChecksum string, checkedAt time.Time) error {
+	const stmt = `UPDATE gitops_snapshots SET drift = ?, drift_checksum = ?, checked_at = ? WHERE team_id = ?`
+
+	var driftJSON any // NULL if there is no drift
+	if len(drift) > 0 {
+		b, err := json.Marshal(drift)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "marshal gitops drift")
+		}
+		driftJSON = b
+	}
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, driftJSON, driftChecksum, checkedAt, teamID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "update gitops snapshot drift")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("GitOpsSnapshot").WithID(teamID))
+	}
+	return nil
+}
+
+func (ds *Datastore) DeleteGitOpsSnapshot(ctx context.Context, teamID uint) error {
+	if _, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM gitops_snapshots WHERE team_id = ?`, teamID); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete gitops snapshot")
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitOpsSnapshots(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"CRUD", testGitOpsSnapshotsCRUD},
+		{"DeletedWithFleet", testGitOpsSnapshotsDeletedWithFleet},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testGitOpsSnapshotsCRUD(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	_, err := ds.GitOpsSnapshot(ctx, 0)
+	require.True(t, fleet.IsNotFound(err))
+	require.True(t, fleet.IsNotFound(ds.UpdateGitOpsSnapshotDrift(ctx, 0, nil, "", time.Now())))
+
+	appliedAt := time.Now().UTC().Truncate(time.Microsecond)
+	global := &fleet.GitOpsSnapshot{
+		TeamID: 0,
+		Resources: fleet.GitOpsResources{
+			fleet.GitOpsResourcePolicy: {"foo": {"query": "SELECT 1", "critical": true}},
+		},
+		Checksum:  "global",
+		AppliedAt: appliedAt,
+	}
+	require.NoError(t, ds.SaveGitOpsSnapshot(ctx, global))
+
+	got, err := ds.GitOpsSnapshot(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, global.Resources, got.Resources)
+	assert.Equal(t, "global", got.Checksum)
+	assert.Equal(t, appliedAt, got.AppliedAt.UTC())
+	assert.Empty(t, got.Drift)
+	assert.Empty(t, got.DriftChecksum)
+	assert.Nil(t, got.CheckedAt)
+
+	// save a drift
+	drift := []fleet.GitOpsResourceChange{{
+		Kind: fleet.GitOpsResourcePolicy, Name: "foo", Op: fleet.GitOpsChangeModify,
+		Fields: []fleet.GitOpsFieldChange{{Field: "critical", Old: true, New: false}},
+	}}
+	checkedAt := appliedAt.Add(time.Hour)
+	require.NoError(t, ds.UpdateGitOpsSnapshotDrift(ctx, 0, drift, "drift", checkedAt))
+
+	got, err = ds.GitOpsSnapshot(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, drift, got.Drift)
+	assert.Equal(t, "drift", got.DriftChecksum)
+	require.NotNil(t, got.CheckedAt)
+	assert.Equal(t, checkedAt, got.CheckedAt.UTC())
+
+	// clearing the drift
+	require.NoError(t, ds.UpdateGitOpsSnapshotDrift(ctx, 0, nil, "", checkedAt.Add(time.Hour)))
+	got, err = ds.GitOpsSnapshot(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, got.Drift)
+	assert.Empty(t, got.DriftChecksum)
+
+	// a new snapshot replaces the previous one and clears its drift
+	require.NoError(t, ds.UpdateGitOpsSnapshotDrift(ctx, 0, drift, "drift", checkedAt))
+	global.Resources = fleet.GitOpsResources{fleet.GitOpsResourceLabel: {"bar": {"query": "SELECT 2"}}}
+	global.Checksum = "global2"
+	require.NoError(t, ds.SaveGitOpsSnapshot(ctx, global))
+	got, err = ds.GitOpsSnapshot(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, global.Resources, got.Resources)
+	assert.Equal(t, "global2", got.Checksum)
+	assert.Empty(t, got.Drift)
+	assert.Empty(t, got.DriftChecksum)
+	assert.Nil(t, got.CheckedAt)
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	require.NoError(t, ds.SaveGitOpsSnapshot(ctx, &fleet.GitOpsSnapshot{
+		TeamID:    team.ID,
+		Resources: fleet.GitOpsResources{},
+		Checksum:  "team",
+		AppliedAt: appliedAt,
+	}))
+
+	snapshots, err := ds.ListGitOpsSnapshots(ctx)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 2)
+	assert.Zero(t, snapshots[0].TeamID)
+	assert.Equal(t, team.ID, snapshots[1].TeamID)
+	assert.Equal(t, "team", snapshots[1].Checksum)
+
+	require.NoError(t, ds.DeleteGitOpsSnapshot(ctx, 0))
+	snapshots, err = ds.ListGitOpsSnapshots(ctx)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Equal(t, team.ID, snapshots[0].TeamID)
+}
+
+func testGitOpsSnapshotsDeletedWithFleet(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	for _, teamID := range []uint{0, team.ID} {
+		require.NoError(t, ds.SaveGitOpsSnapshot(ctx, &fleet.GitOpsSnapshot{
+			TeamID:    teamID,
+			Resources: fleet.GitOpsResources{},
+			Checksum:  "checksum",
+			AppliedAt: time.Now(),
+		}))
+	}
+
+	require.NoError(t, ds.DeleteTeam(ctx, team.ID))
+
+	snapshots, err := ds.ListGitOpsSnapshots(ctx)
+	require.NoError(t, err)
+	require.Len(t, snapshots, 1)
+	assert.Zero(t, snapshots[0].TeamID)
+}
@@ -590,6 +590,7 @@ var hostRefs = []string{
 	"host_disk_encryption_keys",
 	"host_software_installed_paths",
 	"query_results",
+	"query_result_history",
 	"host_mdm_actions",
 	"host_calendar_events",
 	"upcoming_activities",
@@ -614,6 +615,7 @@ var hostRefs = []string{
 	// Microsoft Graph on the next sync, and the row is keyed by host_id, so keeping it would only strand a row
 	// pointing at an id that no longer exists.
 	"host_autopilot_devices",
+	"host_cache_proxy_stats",
 }
 
 // NOTE: The following tables are explicity excluded from hostRefs list and accordingly are not
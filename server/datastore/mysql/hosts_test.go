@@ -9502,6 +9502,8 @@ func testHostsDeleteHosts(t *testing.T, ds *Datastore) {
 	// update policy_results
 	_, err = ds.writer(context.Background()).Exec(`INSERT INTO query_results (host_id, query_id, last_fetched, data) VALUES (?, ?, ?, ?)`, host.ID, policy.ID, time.Now(), `{"foo": "bar"}`)
 	require.NoError(t, err)
+	_, err = ds.writer(context.Background()).Exec(`INSERT INTO query_result_history (host_id, query_id, fetched_at, row_count, data) VALUES (?, ?, ?, ?, ?)`, host.ID, policy.ID, time.Now(), 1, `[{"foo": "bar"}]`)
+	require.NoError(t, err)
 
 	require.NoError(t, errOnly(ds.RecordPolicyQueryExecutions(context.Background(), host, map[uint]*bool{policy.ID: new(true)}, time.Now(), false, nil)))
 	// Update host_mdm.
@@ -9853,6 +9855,9 @@ func testHostsDeleteHosts(t *testing.T, ds *Datastore) {
 	}})
 	require.NoError(t, err)
 
+	err = ds.UpsertHostCacheProxyStats(ctx, host.ID, fleet.CacheProxyStats{Hits: 1})
+	require.NoError(t, err)
+
 	// Check there's an entry for the host in all the associated tables.
 	for _, hostRef := range hostRefs {
 		var ok bool
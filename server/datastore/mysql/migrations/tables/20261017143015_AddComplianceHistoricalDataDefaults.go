@@ -0,0 +1,47 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261017143015, Down_20261017143015)
+}
+
+func Up_20261017143015(tx *sql.Tx) error {
+	// The policies, mdm_enrollment and disk_encryption chart datasets reuse
+	// host_scd_data, so no table changes are needed. Like the uptime and
+	// vulnerabilities sub-keys (see 20260423161823), their historical_data
+	// toggles default to true on upgrade, so backfill them on AppConfig and
+	// every team config before the zero value (false) can be read back.
+	if err := updateAppConfigJSON(tx, func(config *fleet.AppConfig) error {
+		config.Features.HistoricalData.Policies = true
+		config.Features.HistoricalData.MDMEnrollment = true
+		config.Features.HistoricalData.DiskEncryption = true
+		return nil
+	}); err != nil {
+		return fmt.Errorf("set compliance historical_data defaults in AppConfig: %w", err)
+	}
+
+	// JSON_MERGE_PATCH only adds/replaces the three new sub-keys; uptime and
+	// vulnerabilities keep whatever value the admin set.
+	if _, err := tx.Exec(`
+		UPDATE teams
+		SET config = JSON_MERGE_PATCH(
+			config,
+			'{"features":{"historical_data":{"policies":true,"mdm_enrollment":true,"disk_encryption":true}}}'
+		)
+		WHERE config IS NOT NULL
+	`); err != nil {
+		return fmt.Errorf("set compliance historical_data defaults in team configs: %w", err)
+	}
+
+	return nil
+}
+
+func Down_20261017143015(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,52 @@
+package tables
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261017143015(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// An existing team that disabled uptime must keep it disabled; only the
+	// new sub-keys are added.
+	execNoErr(t, db, `
+		INSERT INTO teams (name, description, config)
+		VALUES (?, ?, ?)
+	`, "team1", "test", `{"features":{"enable_host_users":true,"historical_data":{"uptime":false,"vulnerabilities":true}}}`)
+
+	applyNext(t, db)
+
+	var raw json.RawMessage
+	require.NoError(t, sqlx.Get(db, &raw, `SELECT json_value FROM app_config_json LIMIT 1;`))
+	var cfg struct {
+		Features struct {
+			HistoricalData map[string]bool `json:"historical_data"`
+		} `json:"features"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &cfg))
+	require.True(t, cfg.Features.HistoricalData["policies"])
+	require.True(t, cfg.Features.HistoricalData["mdm_enrollment"])
+	require.True(t, cfg.Features.HistoricalData["disk_encryption"])
+
+	var teamRaw json.RawMessage
+	require.NoError(t, sqlx.Get(db, &teamRaw, `SELECT config FROM teams WHERE name = 'team1' LIMIT 1;`))
+	var teamCfg struct {
+		Features struct {
+			EnableHostUsers bool            `json:"enable_host_users"`
+			HistoricalData  map[string]bool `json:"historical_data"`
+		} `json:"features"`
+	}
+	require.NoError(t, json.Unmarshal(teamRaw, &teamCfg))
+	require.Equal(t, map[string]bool{
+		"uptime":          false,
+		"vulnerabilities": true,
+		"policies":        true,
+		"mdm_enrollment":  true,
+		"disk_encryption": true,
+	}, teamCfg.Features.HistoricalData)
+	require.True(t, teamCfg.Features.EnableHostUsers)
+}
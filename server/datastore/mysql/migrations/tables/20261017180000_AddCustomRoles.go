@@ -0,0 +1,60 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261017180000, Down_20261017180000)
+}
+
+func Up_20261017180000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE custom_roles (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			name VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+			description TEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+			permissions JSON NOT NULL,
+			-- Using DATETIME instead of TIMESTAMP to prevent future Y2K38 issues.
+			created_at DATETIME(6) NOT NULL DEFAULT NOW(6),
+			updated_at DATETIME(6) NOT NULL DEFAULT NOW(6) ON UPDATE NOW(6),
+			PRIMARY KEY (id),
+			CONSTRAINT idx_custom_roles_name UNIQUE (name)
+		) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create custom_roles table: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE user_custom_roles (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			user_id INT UNSIGNED NOT NULL,
+			custom_role_id INT UNSIGNED NOT NULL,
+			-- NULL for a global assignment.
+			team_id INT UNSIGNED NULL,
+			-- 0 for a global assignment, the team_id otherwise, so that the
+			-- assignment can be unique (team_id is nullable).
+			global_or_team_id INT UNSIGNED NOT NULL DEFAULT 0,
+			created_at DATETIME(6) NOT NULL DEFAULT NOW(6),
+			PRIMARY KEY (id),
+			CONSTRAINT idx_user_custom_roles_user_role_team UNIQUE (user_id, custom_role_id, global_or_team_id),
+			CONSTRAINT fk_user_custom_roles_user_id
+				FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+			CONSTRAINT fk_user_custom_roles_custom_role_id
+				FOREIGN KEY (custom_role_id) REFERENCES custom_roles (id) ON DELETE CASCADE,
+			CONSTRAINT fk_user_custom_roles_team_id
+				FOREIGN KEY (team_id) REFERENCES teams (id) ON DELETE CASCADE
+		) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create user_custom_roles table: %w", err)
+	}
+
+	return nil
+}
+
+func Down_20261017180000(tx *sql.Tx) error {
+	return nil
+}
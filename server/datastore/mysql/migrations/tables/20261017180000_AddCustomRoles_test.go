@@ -0,0 +1,40 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261017180000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	userID := execNoErrLastID(t, db, `INSERT INTO users (name, email, password, salt) VALUES ('u', 'u@example.com', 'p', 's')`)
+	teamID := execNoErrLastID(t, db, `INSERT INTO teams (name) VALUES ('team1')`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	roleID := execNoErrLastID(t, db, `INSERT INTO custom_roles (name, description, permissions) VALUES ('Helpdesk', '', '[{"object": "host", "action": "read"}]')`)
+
+	_, err := db.Exec(`INSERT INTO custom_roles (name, description, permissions) VALUES ('helpdesk', '', '[]')`)
+	require.Error(t, err, "duplicate name should be rejected")
+
+	// A role can be assigned globally and per team, but only once for each.
+	execNoErr(t, db, `INSERT INTO user_custom_roles (user_id, custom_role_id) VALUES (?, ?)`, userID, roleID)
+	execNoErr(t, db, `INSERT INTO user_custom_roles (user_id, custom_role_id, team_id, global_or_team_id) VALUES (?, ?, ?, ?)`,
+		userID, roleID, teamID, teamID)
+	_, err = db.Exec(`INSERT INTO user_custom_roles (user_id, custom_role_id) VALUES (?, ?)`, userID, roleID)
+	require.Error(t, err, "duplicate global assignment should be rejected")
+
+	// Deleting the team removes its assignments, deleting the role removes the
+	// rest.
+	execNoErr(t, db, `DELETE FROM teams WHERE id = ?`, teamID)
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM user_custom_roles`).Scan(&count))
+	require.Equal(t, 1, count)
+
+	execNoErr(t, db, `DELETE FROM custom_roles WHERE id = ?`, roleID)
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM user_custom_roles`).Scan(&count))
+	require.Zero(t, count)
+}
@@ -0,0 +1,74 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261017190000, Down_20261017190000)
+}
+
+func Up_20261017190000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE vex_documents (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			name VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+			format VARCHAR(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+			document_id VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+			author VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+			sha256 CHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+			-- 'api' or 'directory' (imported from vulnerabilities.vex_path).
+			source VARCHAR(16) COLLATE utf8mb4_unicode_ci NOT NULL,
+			-- Using DATETIME instead of TIMESTAMP to prevent future Y2K38 issues.
+			created_at DATETIME(6) NOT NULL DEFAULT NOW(6),
+			updated_at DATETIME(6) NOT NULL DEFAULT NOW(6) ON UPDATE NOW(6),
+			PRIMARY KEY (id),
+			CONSTRAINT idx_vex_documents_name UNIQUE (name)
+		) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create vex_documents table: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE vex_statements (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			vex_document_id INT UNSIGNED NOT NULL,
+			cve VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+			-- package URL or CPE of the product
+			product VARCHAR(1024) COLLATE utf8mb4_unicode_ci NOT NULL,
+			status VARCHAR(32) COLLATE utf8mb4_unicode_ci NOT NULL,
+			justification VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+			impact_statement TEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+			PRIMARY KEY (id),
+			KEY idx_vex_statements_status_cve (status, cve),
+			CONSTRAINT fk_vex_statements_vex_document_id
+				FOREIGN KEY (vex_document_id) REFERENCES vex_documents (id) ON DELETE CASCADE
+		) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create vex_statements table: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE vex_suppressions (
+			software_id BIGINT UNSIGNED NOT NULL,
+			cve VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+			vex_statement_id INT UNSIGNED NOT NULL,
+			created_at DATETIME(6) NOT NULL DEFAULT NOW(6),
+			PRIMARY KEY (software_id, cve),
+			CONSTRAINT fk_vex_suppressions_vex_statement_id
+				FOREIGN KEY (vex_statement_id) REFERENCES vex_statements (id) ON DELETE CASCADE
+		) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create vex_suppressions table: %w", err)
+	}
+
+	return nil
+}
+
+func Down_20261017190000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,31 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261017190000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	docID := execNoErrLastID(t, db, `INSERT INTO vex_documents (name, format, sha256, source) VALUES ('vendor.json', 'openvex', 'abc', 'api')`)
+	_, err := db.Exec(`INSERT INTO vex_documents (name, format, sha256, source) VALUES ('vendor.json', 'csaf', 'def', 'directory')`)
+	require.Error(t, err, "duplicate name should be rejected")
+
+	stID := execNoErrLastID(t, db, `INSERT INTO vex_statements (vex_document_id, cve, product, status, impact_statement) VALUES (?, 'CVE-2024-1234', 'pkg:deb/debian/curl', 'not_affected', '')`, docID)
+	execNoErr(t, db, `INSERT INTO vex_suppressions (software_id, cve, vex_statement_id) VALUES (1, 'CVE-2024-1234', ?)`, stID)
+	_, err = db.Exec(`INSERT INTO vex_suppressions (software_id, cve, vex_statement_id) VALUES (1, 'CVE-2024-1234', ?)`, stID)
+	require.Error(t, err, "duplicate suppression should be rejected")
+
+	// Deleting the document deletes its statements and their suppressions.
+	execNoErr(t, db, `DELETE FROM vex_documents WHERE id = ?`, docID)
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM vex_statements`).Scan(&count))
+	require.Zero(t, count)
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM vex_suppressions`).Scan(&count))
+	require.Zero(t, count)
+}
@@ -0,0 +1,49 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261017200000, Down_20261017200000)
+}
+
+func Up_20261017200000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE vulnerability_exceptions (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			cve VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+			-- NULL applies the exception to all software affected by the CVE.
+			software_title_id INT UNSIGNED NULL,
+			-- NULL applies the exception to all fleets.
+			team_id INT UNSIGNED NULL,
+			-- Unique key columns, the NULL software title and team are stored as 0.
+			all_or_software_title_id INT UNSIGNED NOT NULL DEFAULT 0,
+			global_or_team_id INT UNSIGNED NOT NULL DEFAULT 0,
+			reason TEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+			owner VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+			expires_at DATETIME(6) NOT NULL,
+			-- Set once the activity for the expiration of the exception was created.
+			expiry_activity_created TINYINT(1) NOT NULL DEFAULT 0,
+			-- Using DATETIME instead of TIMESTAMP to prevent future Y2K38 issues.
+			created_at DATETIME(6) NOT NULL DEFAULT NOW(6),
+			updated_at DATETIME(6) NOT NULL DEFAULT NOW(6) ON UPDATE NOW(6),
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_vulnerability_exceptions_scope (cve, all_or_software_title_id, global_or_team_id),
+			KEY idx_vulnerability_exceptions_expires_at (expires_at),
+			CONSTRAINT fk_vulnerability_exceptions_software_title_id
+				FOREIGN KEY (software_title_id) REFERENCES software_titles (id) ON DELETE CASCADE,
+			CONSTRAINT fk_vulnerability_exceptions_team_id
+				FOREIGN KEY (team_id) REFERENCES teams (id) ON DELETE CASCADE
+		) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create vulnerability_exceptions table: %w", err)
+	}
+	return nil
+}
+
+func Down_20261017200000(tx *sql.Tx) error {
+	return nil
+}
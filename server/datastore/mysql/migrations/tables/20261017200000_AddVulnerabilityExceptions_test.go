@@ -0,0 +1,31 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261017200000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	teamID := execNoErrLastID(t, db, `INSERT INTO teams (name) VALUES ('Workstations')`)
+	titleID := execNoErrLastID(t, db, `INSERT INTO software_titles (name, source) VALUES ('curl', 'deb_packages')`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	const insertStmt = `INSERT INTO vulnerability_exceptions
+		(cve, software_title_id, team_id, all_or_software_title_id, global_or_team_id, reason, owner, expires_at)
+		VALUES (?, ?, ?, ?, ?, 'accepted', 'secops', '2030-01-01')`
+	execNoErr(t, db, insertStmt, "CVE-2024-0001", nil, nil, 0, 0)
+	execNoErr(t, db, insertStmt, "CVE-2024-0001", titleID, teamID, titleID, teamID)
+	_, err := db.Exec(insertStmt, "CVE-2024-0001", nil, nil, 0, 0)
+	require.Error(t, err, "duplicate scope should be rejected")
+
+	// Deleting the team or the software title deletes their exceptions.
+	execNoErr(t, db, `DELETE FROM teams WHERE id = ?`, teamID)
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM vulnerability_exceptions`).Scan(&count))
+	require.Equal(t, 1, count)
+}
@@ -0,0 +1,45 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261017210000, Down_20261017210000)
+}
+
+func Up_20261017210000(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE webhook_deliveries (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			webhook_type VARCHAR(32) COLLATE utf8mb4_unicode_ci NOT NULL,
+			-- NULL for the global webhook settings, 0 for "Unassigned". Not a
+			-- foreign key so the history is kept when a fleet is deleted.
+			team_id INT UNSIGNED NULL,
+			url TEXT COLLATE utf8mb4_unicode_ci NOT NULL,
+			payload JSON NOT NULL,
+			status VARCHAR(16) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT 'pending',
+			attempts INT UNSIGNED NOT NULL DEFAULT 0,
+			response_status_code INT NULL,
+			latency_ms INT UNSIGNED NULL,
+			error TEXT COLLATE utf8mb4_unicode_ci NULL,
+			redelivery_of_id INT UNSIGNED NULL,
+			last_attempt_at DATETIME(6) NULL,
+			-- Using DATETIME instead of TIMESTAMP to prevent future Y2K38 issues.
+			created_at DATETIME(6) NOT NULL DEFAULT NOW(6),
+			updated_at DATETIME(6) NOT NULL DEFAULT NOW(6) ON UPDATE NOW(6),
+			PRIMARY KEY (id),
+			KEY idx_webhook_deliveries_created_at (created_at),
+			KEY idx_webhook_deliveries_type_status (webhook_type, status)
+		) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook_deliveries table: %w", err)
+	}
+	return nil
+}
+
+func Down_20261017210000(tx *sql.Tx) error {
+	return nil
+}
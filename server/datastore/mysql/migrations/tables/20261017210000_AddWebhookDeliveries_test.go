@@ -0,0 +1,23 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261017210000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	id := execNoErrLastID(t, db, `INSERT INTO webhook_deliveries (webhook_type, team_id, url, payload)
+		VALUES ('host_status', 0, 'https://example.com', '{"text":"hello"}')`)
+
+	var status string
+	var attempts uint
+	require.NoError(t, db.QueryRow(`SELECT status, attempts FROM webhook_deliveries WHERE id = ?`, id).Scan(&status, &attempts))
+	require.Equal(t, "pending", status)
+	require.Zero(t, attempts)
+}
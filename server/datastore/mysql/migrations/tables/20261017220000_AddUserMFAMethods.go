@@ -0,0 +1,85 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261017220000, Down_20261017220000)
+}
+
+func Up_20261017220000(tx *sql.Tx) error {
+	stmts := []struct {
+		table string
+		stmt  string
+	}{
+		{"user_totp_secrets", `
+		CREATE TABLE user_totp_secrets (
+			user_id INT UNSIGNED NOT NULL,
+			-- encrypted with the server private key
+			secret BLOB NOT NULL,
+			-- NULL until the user entered a first valid code
+			confirmed_at DATETIME(6) NULL,
+			-- time step of the last accepted code, to prevent replays
+			last_used_step BIGINT NOT NULL DEFAULT 0,
+			created_at DATETIME(6) NOT NULL DEFAULT NOW(6),
+			updated_at DATETIME(6) NOT NULL DEFAULT NOW(6) ON UPDATE NOW(6),
+			PRIMARY KEY (user_id),
+			CONSTRAINT fk_user_totp_secrets_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci`},
+		{"user_webauthn_credentials", `
+		CREATE TABLE user_webauthn_credentials (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			user_id INT UNSIGNED NOT NULL,
+			name VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL,
+			credential_id VARBINARY(1023) NOT NULL,
+			-- COSE-encoded public key
+			public_key BLOB NOT NULL,
+			sign_count INT UNSIGNED NOT NULL DEFAULT 0,
+			-- the BE flag of the credential, it must not change after the registration
+			backup_eligible TINYINT(1) NOT NULL DEFAULT 0,
+			created_at DATETIME(6) NOT NULL DEFAULT NOW(6),
+			last_used_at DATETIME(6) NULL,
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_user_webauthn_credentials_credential_id (credential_id),
+			KEY idx_user_webauthn_credentials_user_id (user_id),
+			CONSTRAINT fk_user_webauthn_credentials_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci`},
+		{"user_mfa_recovery_codes", `
+		CREATE TABLE user_mfa_recovery_codes (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			user_id INT UNSIGNED NOT NULL,
+			-- SHA-256 of the normalized code
+			code_hash BINARY(32) NOT NULL,
+			used_at DATETIME(6) NULL,
+			created_at DATETIME(6) NOT NULL DEFAULT NOW(6),
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_user_mfa_recovery_codes_user_id_code_hash (user_id, code_hash),
+			CONSTRAINT fk_user_mfa_recovery_codes_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci`},
+		{"user_mfa_challenges", `
+		CREATE TABLE user_mfa_challenges (
+			token VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+			user_id INT UNSIGNED NOT NULL,
+			purpose VARCHAR(32) COLLATE utf8mb4_unicode_ci NOT NULL,
+			webauthn_challenge VARBINARY(64) NULL,
+			failed_attempts INT UNSIGNED NOT NULL DEFAULT 0,
+			created_at DATETIME(6) NOT NULL DEFAULT NOW(6),
+			PRIMARY KEY (token),
+			KEY idx_user_mfa_challenges_user_id (user_id),
+			KEY idx_user_mfa_challenges_created_at (created_at),
+			CONSTRAINT fk_user_mfa_challenges_user_id FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+		) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci`},
+	}
+	for _, s := range stmts {
+		if _, err := tx.Exec(s.stmt); err != nil {
+			return fmt.Errorf("failed to create %s table: %w", s.table, err)
+		}
+	}
+	return nil
+}
+
+func Down_20261017220000(tx *sql.Tx) error {
+	return nil
+}
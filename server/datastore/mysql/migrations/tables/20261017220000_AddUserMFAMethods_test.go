@@ -0,0 +1,33 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261017220000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	userID := execNoErrLastID(t, db, `INSERT INTO users (name, email, password, salt) VALUES ('u', 'u@example.com', 'p', 's')`)
+
+	// Apply current migration.
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO user_totp_secrets (user_id, secret) VALUES (?, 'secret')`, userID)
+	execNoErr(t, db, `INSERT INTO user_webauthn_credentials (user_id, name, credential_id, public_key) VALUES (?, 'key', 'id', 'pub')`, userID)
+	execNoErr(t, db, `INSERT INTO user_mfa_recovery_codes (user_id, code_hash) VALUES (?, UNHEX(SHA2('code', 256)))`, userID)
+	execNoErr(t, db, `INSERT INTO user_mfa_challenges (token, user_id, purpose) VALUES ('token', ?, 'login')`, userID)
+
+	var lastUsedStep int64
+	require.NoError(t, db.QueryRow(`SELECT last_used_step FROM user_totp_secrets WHERE user_id = ?`, userID).Scan(&lastUsedStep))
+	require.Zero(t, lastUsedStep)
+
+	// the second factors are deleted with the user
+	execNoErr(t, db, `DELETE FROM users WHERE id = ?`, userID)
+	for _, table := range []string{"user_totp_secrets", "user_webauthn_credentials", "user_mfa_recovery_codes", "user_mfa_challenges"} {
+		var count int
+		require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM `+table).Scan(&count))
+		require.Zero(t, count, table)
+	}
+}
@@ -0,0 +1,26 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261017230000, Down_20261017230000)
+}
+
+func Up_20261017230000(tx *sql.Tx) error {
+	// disabled is set when the user is deprovisioned from the identity
+	// provider (SCIM). Disabled users can't log in, their sessions and API
+	// tokens are revoked, but the account (and its activities) is kept so it
+	// can be enabled again if the user is provisioned back.
+	if _, err := tx.Exec(`ALTER TABLE users ADD COLUMN disabled TINYINT(1) NOT NULL DEFAULT '0'`); err != nil {
+		return errors.Wrap(err, "add disabled to users")
+	}
+	return nil
+}
+
+func Down_20261017230000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,24 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261017230000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	userID := execNoErrLastID(t, db,
+		`INSERT INTO users (name, email, password, salt) VALUES (?, ?, ?, ?)`,
+		"user", "user@example.com", "p", "s",
+	)
+
+	applyNext(t, db)
+
+	// existing users are enabled
+	var disabled bool
+	require.NoError(t, sqlx.Get(db, &disabled, `SELECT disabled FROM users WHERE id = ?`, userID))
+	require.False(t, disabled)
+}
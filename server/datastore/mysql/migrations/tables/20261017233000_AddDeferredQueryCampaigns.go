@@ -0,0 +1,51 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261017233000, Down_20261017233000)
+}
+
+func Up_20261017233000(tx *sql.Tx) error {
+	// Deferred campaigns keep targeting their hosts until expires_at, which is
+	// NULL for live campaigns.
+	_, err := tx.Exec(`
+		ALTER TABLE distributed_query_campaigns
+			ADD COLUMN deferred TINYINT(1) NOT NULL DEFAULT '0',
+			ADD COLUMN expires_at TIMESTAMP NULL DEFAULT NULL,
+			ADD KEY idx_distributed_query_campaigns_expires_at (expires_at)`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add deferred columns to distributed_query_campaigns: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		CREATE TABLE distributed_query_campaign_results (
+			id INT UNSIGNED NOT NULL AUTO_INCREMENT,
+			distributed_query_campaign_id INT UNSIGNED NOT NULL,
+			-- Not a foreign key so the results are kept when a host is deleted.
+			host_id INT UNSIGNED NOT NULL,
+			hostname VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+			host_display_name VARCHAR(255) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+			data JSON NULL,
+			error TEXT COLLATE utf8mb4_unicode_ci NULL,
+			-- Using DATETIME instead of TIMESTAMP to prevent future Y2K38 issues.
+			created_at DATETIME(6) NOT NULL DEFAULT NOW(6),
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_distributed_query_campaign_results_campaign_host (distributed_query_campaign_id, host_id),
+			CONSTRAINT fk_distributed_query_campaign_results_campaign_id
+				FOREIGN KEY (distributed_query_campaign_id) REFERENCES distributed_query_campaigns (id) ON DELETE CASCADE
+		) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create distributed_query_campaign_results table: %w", err)
+	}
+	return nil
+}
+
+func Down_20261017233000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,38 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261017233000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	campaignID := execNoErrLastID(t, db,
+		`INSERT INTO distributed_query_campaigns (query_id, status, user_id) VALUES (?, ?, ?)`, 1, 0, 1,
+	)
+
+	applyNext(t, db)
+
+	// existing campaigns are live campaigns
+	var campaign struct {
+		Deferred  bool    `db:"deferred"`
+		ExpiresAt *string `db:"expires_at"`
+	}
+	require.NoError(t, sqlx.Get(db, &campaign, `SELECT deferred, expires_at FROM distributed_query_campaigns WHERE id = ?`, campaignID))
+	require.False(t, campaign.Deferred)
+	require.Nil(t, campaign.ExpiresAt)
+
+	execNoErr(t, db,
+		`INSERT INTO distributed_query_campaign_results (distributed_query_campaign_id, host_id, data) VALUES (?, ?, ?)`,
+		campaignID, 1, `[{"a": "b"}]`,
+	)
+
+	// results are deleted with their campaign
+	execNoErr(t, db, `DELETE FROM distributed_query_campaigns WHERE id = ?`, campaignID)
+	var count int
+	require.NoError(t, sqlx.Get(db, &count, `SELECT COUNT(*) FROM distributed_query_campaign_results`))
+	require.Zero(t, count)
+}
@@ -0,0 +1,38 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261017234500, Down_20261017234500)
+}
+
+func Up_20261017234500(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		ALTER TABLE distributed_query_campaigns
+			ADD COLUMN save_results TINYINT(1) NOT NULL DEFAULT '0',
+			ADD KEY idx_distributed_query_campaigns_user_id (user_id)`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add save_results column to distributed_query_campaigns: %w", err)
+	}
+
+	// data_key is set instead of data when the rows are too large to be
+	// stored in the database and are stored in the campaign results store.
+	_, err = tx.Exec(`
+		ALTER TABLE distributed_query_campaign_results
+			ADD COLUMN stats JSON NULL,
+			ADD COLUMN data_key VARCHAR(255) COLLATE utf8mb4_unicode_ci NULL DEFAULT NULL,
+			ADD KEY idx_distributed_query_campaign_results_created_at (created_at)`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add stats and data_key columns to distributed_query_campaign_results: %w", err)
+	}
+	return nil
+}
+
+func Down_20261017234500(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,41 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261017234500(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	campaignID := execNoErrLastID(t, db,
+		`INSERT INTO distributed_query_campaigns (query_id, status, user_id, deferred) VALUES (?, ?, ?, ?)`, 1, 1, 1, true,
+	)
+	execNoErr(t, db,
+		`INSERT INTO distributed_query_campaign_results (distributed_query_campaign_id, host_id, data) VALUES (?, ?, ?)`,
+		campaignID, 1, `[{"a": "b"}]`,
+	)
+
+	applyNext(t, db)
+
+	// existing campaigns don't save their results, existing results are
+	// stored in the database
+	var saveResults bool
+	require.NoError(t, sqlx.Get(db, &saveResults, `SELECT save_results FROM distributed_query_campaigns WHERE id = ?`, campaignID))
+	require.False(t, saveResults)
+
+	var result struct {
+		Stats   *string `db:"stats"`
+		DataKey *string `db:"data_key"`
+	}
+	require.NoError(t, sqlx.Get(db, &result, `SELECT stats, data_key FROM distributed_query_campaign_results WHERE distributed_query_campaign_id = ?`, campaignID))
+	require.Nil(t, result.Stats)
+	require.Nil(t, result.DataKey)
+
+	execNoErr(t, db,
+		`UPDATE distributed_query_campaign_results SET stats = ?, data_key = ? WHERE distributed_query_campaign_id = ?`,
+		`{"wall_time_ms": 1}`, "1/1", campaignID,
+	)
+}
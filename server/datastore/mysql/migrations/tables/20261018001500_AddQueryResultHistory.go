@@ -0,0 +1,40 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261018001500, Down_20261018001500)
+}
+
+func Up_20261018001500(tx *sql.Tx) error {
+	// Each row is a result batch of a report from a host, data holds the
+	// result rows as a JSON array and is NULL if the query returned no rows.
+	// Like query_results, there are no foreign keys on query_id and host_id,
+	// rows are removed when the report or the host are deleted.
+	_, err := tx.Exec(`
+		CREATE TABLE query_result_history (
+			id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+			query_id INT UNSIGNED NOT NULL,
+			host_id INT UNSIGNED NOT NULL,
+			fetched_at TIMESTAMP(6) NOT NULL,
+			row_count INT UNSIGNED NOT NULL DEFAULT 0,
+			data JSON DEFAULT NULL,
+			PRIMARY KEY (id),
+			KEY idx_query_id_fetched_at (query_id, fetched_at),
+			KEY idx_query_id_host_id_fetched_at (query_id, host_id, fetched_at),
+			KEY idx_host_id (host_id),
+			KEY idx_fetched_at (fetched_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create table query_result_history: %w", err)
+	}
+	return nil
+}
+
+func Down_20261018001500(tx *sql.Tx) error {
+	return nil
+}
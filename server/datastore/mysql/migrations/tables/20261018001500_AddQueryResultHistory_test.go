@@ -0,0 +1,36 @@
+package tables
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261018001500(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	applyNext(t, db)
+
+	fetchedAt := time.Date(2026, 10, 18, 1, 2, 3, 456789000, time.UTC)
+	execNoErr(t, db,
+		`INSERT INTO query_result_history (query_id, host_id, fetched_at, row_count, data) VALUES (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)`,
+		1, 1, fetchedAt, 2, `[{"a": "b"}, {"a": "c"}]`,
+		1, 2, fetchedAt, 0, nil,
+	)
+
+	var batches []struct {
+		HostID    uint      `db:"host_id"`
+		FetchedAt time.Time `db:"fetched_at"`
+		RowCount  int       `db:"row_count"`
+		Data      *string   `db:"data"`
+	}
+	require.NoError(t, sqlx.Select(db, &batches, `SELECT host_id, fetched_at, row_count, data FROM query_result_history WHERE query_id = ? ORDER BY host_id`, 1))
+	require.Len(t, batches, 2)
+	require.Equal(t, fetchedAt, batches[0].FetchedAt.UTC())
+	require.Equal(t, 2, batches[0].RowCount)
+	require.NotNil(t, batches[0].Data)
+	require.Zero(t, batches[1].RowCount)
+	require.Nil(t, batches[1].Data)
+}
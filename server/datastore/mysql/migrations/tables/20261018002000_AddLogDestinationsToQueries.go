@@ -0,0 +1,24 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261018002000, Down_20261018002000)
+}
+
+func Up_20261018002000(tx *sql.Tx) error {
+	// log_destinations holds the names of the result log destinations of a
+	// report as a JSON array. NULL means the default destination only.
+	_, err := tx.Exec(`ALTER TABLE queries ADD COLUMN log_destinations JSON DEFAULT NULL`)
+	if err != nil {
+		return fmt.Errorf("failed to add log_destinations to queries: %w", err)
+	}
+	return nil
+}
+
+func Down_20261018002000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,25 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261018002000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	queryID := execNoErrLastID(t, db, `INSERT INTO queries (name, description, query, team_id_char) VALUES ('q1', '', 'SELECT 1', '')`)
+
+	applyNext(t, db)
+
+	var dest *string
+	require.NoError(t, sqlx.Get(db, &dest, `SELECT log_destinations FROM queries WHERE id = ?`, queryID))
+	require.Nil(t, dest)
+
+	execNoErr(t, db, `UPDATE queries SET log_destinations = ? WHERE id = ?`, `["security", "default"]`, queryID)
+	require.NoError(t, sqlx.Get(db, &dest, `SELECT log_destinations FROM queries WHERE id = ?`, queryID))
+	require.NotNil(t, dest)
+	require.JSONEq(t, `["security", "default"]`, *dest)
+}
@@ -0,0 +1,39 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261018002500, Down_20261018002500)
+}
+
+func Up_20261018002500(tx *sql.Tx) error {
+	// Each row is the normalized state of the resources of a fleet (team_id 0
+	// for the global config) as last applied by fleetctl gitops, along with
+	// the drift found by the last check. The rows of a fleet are deleted with
+	// the fleet.
+	_, err := tx.Exec(`
+		CREATE TABLE gitops_snapshots (
+			team_id INT UNSIGNED NOT NULL,
+			resources JSON NOT NULL,
+			checksum VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL,
+			applied_at DATETIME(6) NOT NULL,
+			drift JSON DEFAULT NULL,
+			drift_checksum VARCHAR(64) COLLATE utf8mb4_unicode_ci NOT NULL DEFAULT '',
+			checked_at DATETIME(6) DEFAULT NULL,
+			created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+			updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+			PRIMARY KEY (team_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create table gitops_snapshots: %w", err)
+	}
+	return nil
+}
+
+func Down_20261018002500(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,37 @@
+package tables
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261018002500(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	applyNext(t, db)
+
+	appliedAt := time.Date(2026, 10, 18, 1, 2, 3, 456789000, time.UTC)
+	execNoErr(t, db,
+		`INSERT INTO gitops_snapshots (team_id, resources, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+		0, `{"policy": {"foo": {"query": "SELECT 1"}}}`, "abc", appliedAt,
+	)
+
+	var snapshot struct {
+		TeamID        uint       `db:"team_id"`
+		Checksum      string     `db:"checksum"`
+		AppliedAt     time.Time  `db:"applied_at"`
+		Drift         *string    `db:"drift"`
+		DriftChecksum string     `db:"drift_checksum"`
+		CheckedAt     *time.Time `db:"checked_at"`
+	}
+	require.NoError(t, sqlx.Get(db, &snapshot, `SELECT team_id, checksum, applied_at, drift, drift_checksum, checked_at FROM gitops_snapshots`))
+	require.Zero(t, snapshot.TeamID)
+	require.Equal(t, "abc", snapshot.Checksum)
+	require.Equal(t, appliedAt, snapshot.AppliedAt.UTC())
+	require.Nil(t, snapshot.Drift)
+	require.Empty(t, snapshot.DriftChecksum)
+	require.Nil(t, snapshot.CheckedAt)
+}
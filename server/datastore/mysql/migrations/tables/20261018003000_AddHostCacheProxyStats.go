@@ -0,0 +1,38 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261018003000, Down_20261018003000)
+}
+
+func Up_20261018003000(tx *sql.Tx) error {
+	// Each row is the last statistics reported by a host running fleetd in
+	// caching proxy mode. The counters are cumulative since fleetd started on
+	// the host. The row of a host is deleted with the host.
+	_, err := tx.Exec(`
+		CREATE TABLE host_cache_proxy_stats (
+			host_id INT UNSIGNED NOT NULL,
+			hits BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			misses BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			hit_bytes BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			miss_bytes BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			cached_items BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			cached_bytes BIGINT UNSIGNED NOT NULL DEFAULT 0,
+			created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+			updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+			PRIMARY KEY (host_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create table host_cache_proxy_stats: %w", err)
+	}
+	return nil
+}
+
+func Down_20261018003000(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,28 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261018003000(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO host_cache_proxy_stats (host_id, hits, hit_bytes) VALUES (?, ?, ?)`, 1, 2, uint64(1<<40))
+
+	var stats struct {
+		HostID   uint   `db:"host_id"`
+		Hits     uint64 `db:"hits"`
+		Misses   uint64 `db:"misses"`
+		HitBytes uint64 `db:"hit_bytes"`
+	}
+	require.NoError(t, sqlx.Get(db, &stats, `SELECT host_id, hits, misses, hit_bytes FROM host_cache_proxy_stats`))
+	require.EqualValues(t, 1, stats.HostID)
+	require.EqualValues(t, 2, stats.Hits)
+	require.Zero(t, stats.Misses)
+	require.EqualValues(t, uint64(1<<40), stats.HitBytes)
+}
@@ -0,0 +1,34 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20261018003500, Down_20261018003500)
+}
+
+func Up_20261018003500(tx *sql.Tx) error {
+	// Each row is a policy for which the ServiceNow integration opened an
+	// incident that is not resolved yet, so that only those policies are
+	// checked for resolution once they pass again. The row of a policy is
+	// deleted with the policy.
+	_, err := tx.Exec(`
+		CREATE TABLE servicenow_policy_incidents (
+			policy_id INT UNSIGNED NOT NULL,
+			created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+			updated_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6) ON UPDATE CURRENT_TIMESTAMP(6),
+			PRIMARY KEY (policy_id),
+			CONSTRAINT fk_servicenow_policy_incidents_policy_id FOREIGN KEY (policy_id) REFERENCES policies (id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci`,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create table servicenow_policy_incidents: %w", err)
+	}
+	return nil
+}
+
+func Down_20261018003500(tx *sql.Tx) error {
+	return nil
+}
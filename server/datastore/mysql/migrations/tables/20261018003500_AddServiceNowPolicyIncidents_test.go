@@ -0,0 +1,26 @@
+package tables
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUp_20261018003500(t *testing.T) {
+	db := applyUpToPrev(t)
+
+	policyID := execNoErrLastID(t, db, "INSERT INTO policies (name, query, description, checksum) VALUES (?,?,?,?)", "p1", "SELECT 1", "", "checksum1")
+
+	applyNext(t, db)
+
+	execNoErr(t, db, `INSERT INTO servicenow_policy_incidents (policy_id) VALUES (?)`, policyID)
+	var count int
+	require.NoError(t, sqlx.Get(db, &count, `SELECT COUNT(*) FROM servicenow_policy_incidents`))
+	require.Equal(t, 1, count)
+
+	// the incident record is deleted with its policy
+	execNoErr(t, db, `DELETE FROM policies WHERE id = ?`, policyID)
+	require.NoError(t, sqlx.Get(db, &count, `SELECT COUNT(*) FROM servicenow_policy_incidents`))
+	require.Zero(t, count)
+}
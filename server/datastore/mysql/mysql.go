@@ -917,6 +917,22 @@ func appendListOptionsWithCursorToSQLSecure(sql string, params []any, opts *flee
 	return common_mysql.AppendListOptionsWithParamsSecure(sql, params, opts, allowlist)
 }
 
+// customRolesReadScope returns the scope of the custom roles of the user that
+// grant read or list access: global is true if one of them is assigned
+// globally, otherwise teamIDs are the teams they are assigned for.
+func customRolesReadScope(user *fleet.User) (global bool, teamIDs []uint) {
+	for _, r := range user.CustomRoles {
+		if !r.GrantsRead() {
+			continue
+		}
+		if r.TeamID == nil {
+			return true, nil
+		}
+		teamIDs = append(teamIDs, *r.TeamID)
+	}
+	return false, teamIDs
+}
+
 // whereFilterHostsByTeams returns the appropriate condition to use in the WHERE
 // clause to render only the appropriate teams.
 //
@@ -936,6 +952,12 @@ func (ds *Datastore) whereFilterHostsByTeams(filter fleet.TeamFilter, hostKey st
 		defaultAllowClause = fmt.Sprintf("%s.team_id = %d", hostKey, *filter.TeamID)
 	}
 
+	// Custom roles grant their permissions on top of the built-in role.
+	customGlobal, customTeamIDs := customRolesReadScope(filter.User)
+	if customGlobal {
+		return defaultAllowClause
+	}
+
 	if filter.User.GlobalRole != nil {
 		switch *filter.User.GlobalRole {
 		case fleet.RoleAdmin, fleet.RoleMaintainer, fleet.RoleTechnician, fleet.RoleObserverPlus:
@@ -948,7 +970,7 @@ func (ds *Datastore) whereFilterHostsByTeams(filter fleet.TeamFilter, hostKey st
 				}
 				return defaultAllowClause
 			}
-			return "FALSE"
+			// Fall through to the teams of the custom roles
 		default:
 			// Fall through to specific teams
 		}
@@ -957,6 +979,12 @@ func (ds *Datastore) whereFilterHostsByTeams(filter fleet.TeamFilter, hostKey st
 	// Collect matching teams
 	var idStrs []string
 	var teamIDSeen bool
+	for _, teamID := range customTeamIDs {
+		idStrs = append(idStrs, fmt.Sprint(teamID))
+		if filter.TeamID != nil && *filter.TeamID == teamID {
+			teamIDSeen = true
+		}
+	}
 	for _, team := range filter.User.Teams {
 		if team.Role == fleet.RoleAdmin ||
 			team.Role == fleet.RoleMaintainer ||
@@ -1025,6 +1053,12 @@ func (ds *Datastore) whereFilterGlobalOrTeamIDByTeamsWithSqlFilter(
 		defaultAllowClause = fmt.Sprintf("%s = %d", teamIDSqlFilter, *filter.TeamID)
 	}
 
+	// Custom roles grant their permissions on top of the built-in role.
+	customGlobal, customTeamIDs := customRolesReadScope(filter.User)
+	if customGlobal {
+		return defaultAllowClause
+	}
+
 	if filter.User.GlobalRole != nil {
 		switch *filter.User.GlobalRole {
 		case fleet.RoleAdmin, fleet.RoleMaintainer, fleet.RoleTechnician, fleet.RoleObserverPlus:
@@ -1033,7 +1067,7 @@ func (ds *Datastore) whereFilterGlobalOrTeamIDByTeamsWithSqlFilter(
 			if filter.IncludeObserver {
 				return defaultAllowClause
 			}
-			return "FALSE"
+			// Fall through to the teams of the custom roles
 		default:
 			// Fall through to specific teams
 		}
@@ -1042,6 +1076,12 @@ func (ds *Datastore) whereFilterGlobalOrTeamIDByTeamsWithSqlFilter(
 	// Collect matching teams
 	var idStrs []string
 	var teamIDSeen bool
+	for _, teamID := range customTeamIDs {
+		idStrs = append(idStrs, fmt.Sprint(teamID))
+		if filter.TeamID != nil && *filter.TeamID == teamID {
+			teamIDSeen = true
+		}
+	}
 	for _, team := range filter.User.Teams {
 		if team.Role == fleet.RoleAdmin ||
 			team.Role == fleet.RoleMaintainer ||
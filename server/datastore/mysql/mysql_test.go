@@ -657,6 +657,51 @@ func TestWhereFilterHostsByTeams(t *testing.T) {
 			},
 			expected: "hosts.team_id IN (2)",
 		},
+
+		// Custom roles: those granting read or list give access to their scope
+		{
+			filter: fleet.TeamFilter{
+				User: &fleet.User{CustomRoles: []fleet.UserCustomRole{
+					{TeamID: ptr.Uint(1), Permissions: fleet.CustomRolePermissions{{Object: "host", Action: fleet.ActionRead}}},
+					{TeamID: ptr.Uint(2), Permissions: fleet.CustomRolePermissions{{Object: "script", Action: fleet.ActionWrite}}},
+				}},
+			},
+			expected: "hosts.team_id IN (1)",
+		},
+		{
+			filter: fleet.TeamFilter{
+				User: &fleet.User{
+					Teams: []fleet.UserTeam{{Role: fleet.RoleMaintainer, Team: fleet.Team{ID: 2}}},
+					CustomRoles: []fleet.UserCustomRole{
+						{TeamID: ptr.Uint(1), Permissions: fleet.CustomRolePermissions{{Object: "host", Action: fleet.ActionList}}},
+					},
+				},
+				TeamID: ptr.Uint(1),
+			},
+			expected: "hosts.team_id = 1",
+		},
+		{
+			filter: fleet.TeamFilter{
+				User: &fleet.User{
+					GlobalRole: ptr.String(fleet.RoleObserver),
+					CustomRoles: []fleet.UserCustomRole{
+						{Permissions: fleet.CustomRolePermissions{{Object: "software_inventory", Action: fleet.ActionRead}}},
+					},
+				},
+			},
+			expected: "TRUE",
+		},
+		{
+			filter: fleet.TeamFilter{
+				User: &fleet.User{
+					GlobalRole: ptr.String(fleet.RoleObserver),
+					CustomRoles: []fleet.UserCustomRole{
+						{TeamID: ptr.Uint(3), Permissions: fleet.CustomRolePermissions{{Object: "host", Action: fleet.ActionRead}}},
+					},
+				},
+			},
+			expected: "hosts.team_id IN (3)",
+		},
 	}
 
 	for _, tt := range testCases {
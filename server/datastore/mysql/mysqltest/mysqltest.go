@@ -38,6 +38,7 @@ import (
 	nanodep_client "github.com/fleetdm/fleet/v4/server/mdm/nanodep/client"
 	mdmtesting "github.com/fleetdm/fleet/v4/server/mdm/testing_utils"
 	platform_authz "github.com/fleetdm/fleet/v4/server/platform/authz"
+	platformhttp "github.com/fleetdm/fleet/v4/server/platform/http"
 	"github.com/fleetdm/fleet/v4/server/platform/logging"
 	common_mysql "github.com/fleetdm/fleet/v4/server/platform/mysql"
 	"github.com/fleetdm/fleet/v4/server/platform/mysql/testing_utils"
@@ -889,6 +890,14 @@ func (t *testingLookupService) GetHostActivitiesWebhookSettings(ctx context.Cont
 	return fleet.ResolveHostActivitiesWebhooks(ctx, t.ds, hostIDs)
 }
 
+func (t *testingLookupService) DeliverActivitiesWebhook(ctx context.Context, fleetID *uint, destinationURL string, payload json.RawMessage) error {
+	// Deliveries are POSTed once, without being recorded nor retried: the
+	// webhook delivery worker can't be imported from here as its tests use
+	// this package.
+	_, err := platformhttp.PostWebhook(ctx, platformhttp.WebhookRequest{URL: destinationURL, Body: payload}, slog.New(slog.DiscardHandler))
+	return err
+}
+
 func (t *testingLookupService) ActivateNextUpcomingActivityForHost(ctx context.Context, hostID uint, fromCompletedExecID string) error {
 	return t.ds.ActivateNextUpcomingActivityForHost(ctx, hostID, fromCompletedExecID)
 }
@@ -80,7 +80,8 @@ func (ds *Datastore) applyQueriesInTx(
 			schedule_interval,
 			automations_enabled,
 			logging_type,
-			discard_data
+			discard_data,
+			log_destinations
 		) VALUES %s
 		ON DUPLICATE KEY UPDATE
 			name = VALUES(name),
@@ -96,7 +97,8 @@ func (ds *Datastore) applyQueriesInTx(
 			schedule_interval = VALUES(schedule_interval),
 			automations_enabled = VALUES(automations_enabled),
 			logging_type = VALUES(logging_type),
-			discard_data = VALUES(discard_data)`
+			discard_data = VALUES(discard_data),
+			log_destinations = VALUES(log_destinations)`
 
 	// 'queries' are uniquely identified by {name, team_id}
 	unqKeyGen := func(name string, teamID *uint) string {
@@ -123,17 +125,17 @@ func (ds *Datastore) applyQueriesInTx(
 		if err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
 			// For upserting
 			pToInsert := make([]string, 0, len(batch))
-			aToInsert := make([]interface{}, 0, len(batch)*13)
+			aToInsert := make([]interface{}, 0, len(batch)*14)
 
 			// For fetching the ID after the upsert
 			pToSelect := make([]string, 0, len(batch))
 			aToSelect := make([]interface{}, 0, len(batch)*2)
 
 			for _, q := range batch {
-				pToInsert = append(pToInsert, "( ?, ?, ?, ?, true, ?, ?, ?, ?, ?, ?, ?, ?, ? )")
+				pToInsert = append(pToInsert, "( ?, ?, ?, ?, true, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? )")
 				aToInsert = append(aToInsert, q.Name, q.Description, q.Query, authorID, q.ObserverCanRun, q.TeamID,
 					q.TeamIDStr(), q.Platform, q.MinOsqueryVersion, q.Interval, q.AutomationsEnabled, q.Logging,
-					q.DiscardData)
+					q.DiscardData, q.LogDestinations)
 
 				pToSelect = append(pToSelect, "(name = ? AND team_id_char = ?)")
 				aToSelect = append(aToSelect, q.Name, q.TeamIDStr())
@@ -193,6 +195,15 @@ func (ds *Datastore) deleteMultipleQueryResults(ctx context.Context, queryIDs []
 	if _, err := ds.writer(ctx).ExecContext(ctx, query, args...); err != nil {
 		return ctxerr.Wrap(ctx, err, "executing delete query_results")
 	}
+
+	deleteHistoryStmt := `DELETE FROM query_result_history WHERE query_id IN (?)`
+	query, args, err = sqlx.In(deleteHistoryStmt, queryIDs)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "building delete query_result_history stmt")
+	}
+	if _, err := ds.writer(ctx).ExecContext(ctx, query, args...); err != nil {
+		return ctxerr.Wrap(ctx, err, "executing delete query_result_history")
+	}
 	return nil
 }
 
@@ -217,6 +228,7 @@ func (ds *Datastore) QueryByName(
 			automations_enabled,
 			logging_type,
 			discard_data,
+			log_destinations,
 			created_at,
 			updated_at
 		FROM queries
@@ -340,9 +352,10 @@ func (ds *Datastore) NewQuery(
 			automations_enabled,
 			logging_type,
 			discard_data,
+			log_destinations,
 			created_at,
 			updated_at
-		) VALUES ( ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? )
+		) VALUES ( ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ? )
 	`
 
 	result, err := ds.writer(ctx).ExecContext(
@@ -362,6 +375,7 @@ func (ds *Datastore) NewQuery(
 		query.AutomationsEnabled,
 		query.Logging,
 		query.DiscardData,
+		query.LogDestinations,
 		query.CreatedAt,
 		query.UpdatedAt,
 	)
@@ -530,7 +544,8 @@ func (ds *Datastore) SaveQuery(ctx context.Context, q *fleet.Query, shouldDiscar
 			schedule_interval   = ?,
 			automations_enabled = ?,
 			logging_type        = ?,
-			discard_data		= ?
+			discard_data		= ?,
+			log_destinations    = ?
 		WHERE id = ?
 	`
 	result, err := ds.writer(ctx).ExecContext(
@@ -550,6 +565,7 @@ func (ds *Datastore) SaveQuery(ctx context.Context, q *fleet.Query, shouldDiscar
 		q.AutomationsEnabled,
 		q.Logging,
 		q.DiscardData,
+		q.LogDestinations,
 		q.ID)
 	if err != nil {
 		return ctxerr.Wrap(ctx, err, "updating query")
@@ -589,6 +605,10 @@ func (ds *Datastore) deleteQueryResults(ctx context.Context, queryID uint) error
 	if _, err := ds.writer(ctx).ExecContext(ctx, resultsSQL, queryID); err != nil {
 		return ctxerr.Wrap(ctx, err, "executing delete query_results")
 	}
+	historySQL := `DELETE FROM query_result_history WHERE query_id = ?`
+	if _, err := ds.writer(ctx).ExecContext(ctx, historySQL, queryID); err != nil {
+		return ctxerr.Wrap(ctx, err, "executing delete query_result_history")
+	}
 	return nil
 }
 
@@ -707,9 +727,9 @@ func query(ctx context.Context, db sqlx.QueryerContext, id uint) (*fleet.Query,
 			q.automations_enabled,
 			q.logging_type,
 			q.discard_data,
+			q.log_destinations,
 			q.created_at,
 			q.updated_at,
-			q.discard_data,
 			COALESCE(NULLIF(u.name, ''), u.email, '') AS author_name,
 			COALESCE(u.email, '') AS author_email,
 			JSON_EXTRACT(json_value, '$.user_time_p50') as user_time_p50,
@@ -763,6 +783,7 @@ func (ds *Datastore) ListQueries(ctx context.Context, opt fleet.ListQueryOptions
 			q.automations_enabled,
 			q.logging_type,
 			q.discard_data,
+			q.log_destinations,
 			q.created_at,
 			q.updated_at,
 			COALESCE(u.name, '<deleted>') AS author_name,
@@ -1075,6 +1096,9 @@ func (ds *Datastore) CleanupGlobalDiscardQueryResults(ctx context.Context) error
 	if err != nil {
 		return ctxerr.Wrapf(ctx, err, "delete all from query_results")
 	}
+	if _, err := ds.writer(ctx).ExecContext(ctx, "DELETE FROM query_result_history"); err != nil {
+		return ctxerr.Wrapf(ctx, err, "delete all from query_result_history")
+	}
 
 	return nil
 }
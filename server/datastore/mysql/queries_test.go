@@ -43,6 +43,7 @@ func TestQueries(t *testing.T) {
 		{"ListScheduledQueriesForAgentsWithLabels", testListScheduledQueriesForAgentsWithLabels},
 		{"QueriesPerHost", testQueriesPerHost},
 		{"HasLabelScopedScheduledQueries", testHasLabelScopedScheduledQueries},
+		{"LogDestinations", testQueriesLogDestinations},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -1791,3 +1792,57 @@ func testHasLabelScopedScheduledQueries(t *testing.T, ds *Datastore) {
 	require.NoError(t, err)
 	assert.False(t, has, "snapshot report with labels should NOT count when queryReportsDisabled=true")
 }
+
+func testQueriesLogDestinations(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+
+	// A new query without log destinations reads back as nil.
+	q1, err := ds.NewQuery(ctx, &fleet.Query{
+		Name:     "q1",
+		Query:    "SELECT 1",
+		AuthorID: &user.ID,
+		Logging:  fleet.LoggingSnapshot,
+		Saved:    true,
+	})
+	require.NoError(t, err)
+	got, err := ds.Query(ctx, q1.ID)
+	require.NoError(t, err)
+	require.Nil(t, got.LogDestinations)
+
+	// Saving sets them, and every read path returns them.
+	got.LogDestinations = fleet.LogDestinations{"security", "default"}
+	require.NoError(t, ds.SaveQuery(ctx, got, false, false))
+	got, err = ds.Query(ctx, q1.ID)
+	require.NoError(t, err)
+	require.Equal(t, fleet.LogDestinations{"security", "default"}, got.LogDestinations)
+	got, err = ds.QueryByName(ctx, nil, "q1")
+	require.NoError(t, err)
+	require.Equal(t, fleet.LogDestinations{"security", "default"}, got.LogDestinations)
+	list, _, _, _, err := ds.ListQueries(ctx, fleet.ListQueryOptions{})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, fleet.LogDestinations{"security", "default"}, list[0].LogDestinations)
+
+	// Applying a spec without log destinations resets them to the default.
+	require.NoError(t, ds.ApplyQueries(ctx, user.ID, []*fleet.Query{{
+		Name:    "q1",
+		Query:   "SELECT 1",
+		Logging: fleet.LoggingSnapshot,
+	}, {
+		Name:            "q2",
+		Query:           "SELECT 2",
+		Logging:         fleet.LoggingSnapshot,
+		LogDestinations: fleet.LogDestinations{"compliance"},
+	}}, nil))
+	got, err = ds.QueryByName(ctx, nil, "q1")
+	require.NoError(t, err)
+	require.Nil(t, got.LogDestinations)
+	got, err = ds.QueryByName(ctx, nil, "q2")
+	require.NoError(t, err)
+	require.Equal(t, fleet.LogDestinations{"compliance"}, got.LogDestinations)
+
+	// Duplicate names are rejected.
+	got.LogDestinations = fleet.LogDestinations{"compliance", "compliance"}
+	require.Error(t, ds.SaveQuery(ctx, got, false, false))
+}
@@ -8,6 +8,7 @@ import (
 	"maps"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/fleet"
@@ -145,6 +146,15 @@ func (ds *Datastore) CleanupDiscardedQueryResults(ctx context.Context) error {
 	if err != nil {
 		return ctxerr.Wrap(ctx, err, "cleaning up discarded query results")
 	}
+
+	deleteHistoryStmt := `
+		DELETE FROM query_result_history
+		WHERE query_id IN
+			(SELECT id FROM queries WHERE discard_data = true)
+		`
+	if _, err := ds.writer(ctx).ExecContext(ctx, deleteHistoryStmt); err != nil {
+		return ctxerr.Wrap(ctx, err, "cleaning up discarded query result history")
+	}
 	return nil
 }
 
@@ -270,6 +280,183 @@ func (ds *Datastore) CleanupExcessQueryResultRows(ctx context.Context, maxQueryR
 	return queryCounts, nil
 }
 
+// InsertQueryResultHistory stores result batches in the history of their
+// reports. The rows of a batch are stored as a single JSON array.
+func (ds *Datastore) InsertQueryResultHistory(ctx context.Context, batches []*fleet.QueryResultHistoryBatch) error {
+	if len(batches) == 0 {
+		return nil
+	}
+
+	valueStrings := make([]string, 0, len(batches))
+	valueArgs := make([]any, 0, len(batches)*5)
+	for _, batch := range batches {
+		var data []byte
+		if len(batch.Rows) > 0 {
+			var err error
+			if data, err = json.Marshal(batch.Rows); err != nil {
+				return ctxerr.Wrap(ctx, err, "marshal query result history rows")
+			}
+		}
+		valueStrings = append(valueStrings, "(?, ?, ?, ?, ?)")
+		valueArgs = append(valueArgs, batch.QueryID, batch.HostID, batch.FetchedAt, len(batch.Rows), data)
+	}
+
+	//nolint:gosec // SQL query is constructed using constant strings
+	insertStmt := `
+		INSERT INTO query_result_history (query_id, host_id, fetched_at, row_count, data) VALUES
+	` + strings.Join(valueStrings, ",")
+	if _, err := ds.writer(ctx).ExecContext(ctx, insertStmt, valueArgs...); err != nil {
+		return ctxerr.Wrap(ctx, err, "inserting query result history")
+	}
+	return nil
+}
+
+// queryResultHistoryBatch is a scan target for the report history queries.
+type queryResultHistoryBatch struct {
+	HostID         uint             `db:"host_id"`
+	Hostname       sql.NullString   `db:"hostname"`
+	ComputerName   sql.NullString   `db:"computer_name"`
+	HardwareModel  sql.NullString   `db:"hardware_model"`
+	HardwareSerial sql.NullString   `db:"hardware_serial"`
+	FetchedAt      time.Time        `db:"fetched_at"`
+	Data           *json.RawMessage `db:"data"`
+}
+
+// queryResultHistoryRows splits the batches into their result rows. Batches
+// without rows are skipped unless includeEmpty is set, then they get a single
+// row with nil Data.
+func queryResultHistoryRows(queryID uint, batches []queryResultHistoryBatch, includeEmpty bool) ([]*fleet.ScheduledQueryResultRow, error) {
+	results := []*fleet.ScheduledQueryResultRow{}
+	for _, batch := range batches {
+		var rows []*json.RawMessage
+		if batch.Data != nil {
+			if err := json.Unmarshal(*batch.Data, &rows); err != nil {
+				return nil, err
+			}
+		}
+		if len(rows) == 0 && includeEmpty {
+			rows = []*json.RawMessage{nil}
+		}
+		for _, row := range rows {
+			results = append(results, &fleet.ScheduledQueryResultRow{
+				QueryID:        queryID,
+				HostID:         batch.HostID,
+				Hostname:       batch.Hostname,
+				ComputerName:   batch.ComputerName,
+				HardwareModel:  batch.HardwareModel,
+				HardwareSerial: batch.HardwareSerial,
+				Data:           row,
+				LastFetched:    batch.FetchedAt,
+			})
+		}
+	}
+	return results, nil
+}
+
+// QueryResultHistoryRows returns the result rows of the history of a report
+// fetched between from and to (inclusive), ordered by fetch time.
+func (ds *Datastore) QueryResultHistoryRows(ctx context.Context, queryID uint, from, to time.Time, filter fleet.TeamFilter) ([]*fleet.ScheduledQueryResultRow, error) {
+	selectStmt := fmt.Sprintf(`
+		SELECT qrh.host_id, qrh.fetched_at, qrh.data,
+			h.hostname, h.computer_name, h.hardware_model, h.hardware_serial
+			FROM query_result_history qrh
+			LEFT JOIN hosts h ON (qrh.host_id=h.id)
+			WHERE qrh.query_id = ? AND qrh.fetched_at BETWEEN ? AND ? AND qrh.row_count > 0 AND %s
+			ORDER BY qrh.fetched_at, qrh.id
+		`, ds.whereFilterHostsByTeams(filter, "h"))
+
+	var batches []queryResultHistoryBatch
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &batches, selectStmt, queryID, from, to); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "selecting query result history")
+	}
+	results, err := queryResultHistoryRows(queryID, batches, false)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "unmarshal query result history rows")
+	}
+	return results, nil
+}
+
+// QueryResultHistorySnapshot returns the rows of the latest batch of each host
+// fetched at or before the given time, ordered by host ID.
+func (ds *Datastore) QueryResultHistorySnapshot(ctx context.Context, queryID uint, at time.Time, filter fleet.TeamFilter) ([]*fleet.ScheduledQueryResultRow, error) {
+	selectStmt := fmt.Sprintf(`
+		SELECT qrh.host_id, qrh.fetched_at, qrh.data,
+			h.hostname, h.computer_name, h.hardware_model, h.hardware_serial
+			FROM (
+				SELECT host_id, fetched_at, data,
+					ROW_NUMBER() OVER (PARTITION BY host_id ORDER BY fetched_at DESC, id DESC) AS rn
+				FROM query_result_history
+				WHERE query_id = ? AND fetched_at <= ?
+			) qrh
+			LEFT JOIN hosts h ON (qrh.host_id=h.id)
+			WHERE qrh.rn = 1 AND %s
+			ORDER BY qrh.host_id
+		`, ds.whereFilterHostsByTeams(filter, "h"))
+
+	var batches []queryResultHistoryBatch
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &batches, selectStmt, queryID, at); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "selecting query result history snapshot")
+	}
+	results, err := queryResultHistoryRows(queryID, batches, true)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "unmarshal query result history rows")
+	}
+	return results, nil
+}
+
+// CleanupQueryResultHistory deletes the report history batches fetched before
+// olderThan, then the oldest batches (by id, which correlates with insert order)
+// of reports with more than maxRowsPerQuery rows. Batches are never split, and
+// deletes are batched to avoid large binlogs and long lock times.
+func (ds *Datastore) CleanupQueryResultHistory(ctx context.Context, olderThan time.Time, maxRowsPerQuery int) error {
+	const batchSize = 500
+
+	deleteExpiredStmt := `DELETE FROM query_result_history WHERE fetched_at < ? LIMIT ?`
+	for {
+		result, err := ds.writer(ctx).ExecContext(ctx, deleteExpiredStmt, olderThan, batchSize)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "deleting expired query result history")
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			break
+		}
+	}
+
+	// The cutoff of a query is the most recent batch that doesn't fit in the
+	// budget, along with all the batches before it.
+	type cutoffRow struct {
+		QueryID  uint   `db:"query_id"`
+		CutoffID uint64 `db:"cutoff_id"`
+	}
+	var cutoffs []cutoffRow
+	cutoffStmt := `
+		SELECT query_id, MAX(id) AS cutoff_id FROM (
+			SELECT query_id, id,
+				SUM(row_count) OVER (PARTITION BY query_id ORDER BY id DESC) AS total_rows
+			FROM query_result_history
+		) totals
+		WHERE total_rows > ?
+		GROUP BY query_id
+	`
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &cutoffs, cutoffStmt, maxRowsPerQuery); err != nil {
+		return ctxerr.Wrap(ctx, err, "selecting query result history cutoffs")
+	}
+
+	deleteExcessStmt := `DELETE FROM query_result_history WHERE query_id = ? AND id <= ? LIMIT ?`
+	for _, c := range cutoffs {
+		for {
+			result, err := ds.writer(ctx).ExecContext(ctx, deleteExcessStmt, c.QueryID, c.CutoffID, batchSize)
+			if err != nil {
+				return ctxerr.Wrapf(ctx, err, "cleaning up query result history of query %d", c.QueryID)
+			}
+			if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+				break
+			}
+		}
+	}
+	return nil
+}
+
 // hostReportAllowedOrderKeys defines the allowed order keys for ListHostReports.
 // The last_fetched entry is overridden dynamically in ListHostReports with a
 // direction-aware COALESCE sentinel so that NULLs sort last in both ASC and DESC
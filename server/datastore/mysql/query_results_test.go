@@ -33,6 +33,8 @@ func TestQueryResults(t *testing.T) {
 		{"CleanupExcessQueryResultRows", testCleanupExcessQueryResultRows},
 		{"CleanupExcessQueryResultRowsManyQueries", testCleanupExcessQueryResultRowsManyQueries},
 		{"ListHostReports", testListHostReports},
+		{"QueryResultHistory", testQueryResultHistory},
+		{"CleanupQueryResultHistory", testCleanupQueryResultHistory},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -1395,3 +1397,130 @@ func testListHostReports(t *testing.T, ds *Datastore) {
 		assert.False(t, names["combined-qLinuxLabelB"], "non-member label must exclude even if platform matches")
 	})
 }
+
+func testQueryResultHistory(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+	user := test.NewUser(t, ds, "Test User", "test@example.com", true)
+	query := test.NewQuery(t, ds, nil, "History Query", "SELECT 1", user.ID, true)
+	host1 := test.NewHost(t, ds, "host1", "192.168.1.101", "1", "1", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "192.168.1.102", "2", "2", time.Now())
+	filter := fleet.TeamFilter{User: user, IncludeObserver: true}
+
+	t0 := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	t1, t2 := t0.Add(10*time.Minute), t0.Add(20*time.Minute)
+	require.NoError(t, ds.InsertQueryResultHistory(ctx, []*fleet.QueryResultHistoryBatch{
+		{QueryID: query.ID, HostID: host1.ID, FetchedAt: t0, Rows: []*json.RawMessage{
+			ptr.RawMessage([]byte(`{"name": "a"}`)),
+			ptr.RawMessage([]byte(`{"name": "b"}`)),
+		}},
+		{QueryID: query.ID, HostID: host2.ID, FetchedAt: t0, Rows: []*json.RawMessage{
+			ptr.RawMessage([]byte(`{"name": "c"}`)),
+		}},
+		{QueryID: query.ID, HostID: host1.ID, FetchedAt: t1, Rows: []*json.RawMessage{
+			ptr.RawMessage([]byte(`{"name": "a"}`)),
+		}},
+		{QueryID: query.ID, HostID: host2.ID, FetchedAt: t2},
+	}))
+
+	rowNames := func(rows []*fleet.ScheduledQueryResultRow) []string {
+		var names []string
+		for _, row := range rows {
+			if row.Data == nil {
+				names = append(names, "")
+				continue
+			}
+			var columns map[string]string
+			require.NoError(t, json.Unmarshal(*row.Data, &columns))
+			names = append(names, columns["name"])
+		}
+		return names
+	}
+
+	// batches without rows are skipped
+	rows, err := ds.QueryResultHistoryRows(ctx, query.ID, t0, t2, filter)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c", "a"}, rowNames(rows))
+	require.Equal(t, t0, rows[0].LastFetched.UTC())
+	require.Equal(t, host1.ID, rows[0].HostID)
+	require.Equal(t, "host1", rows[0].Hostname.String)
+	require.Equal(t, t1, rows[3].LastFetched.UTC())
+
+	rows, err = ds.QueryResultHistoryRows(ctx, query.ID, t1, t2, filter)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a"}, rowNames(rows))
+
+	rows, err = ds.QueryResultHistoryRows(ctx, query.ID, t2.Add(time.Second), time.Now(), filter)
+	require.NoError(t, err)
+	require.Empty(t, rows)
+
+	// snapshots are made of the latest batch of each host
+	rows, err = ds.QueryResultHistorySnapshot(ctx, query.ID, t0.Add(-time.Second), filter)
+	require.NoError(t, err)
+	require.Empty(t, rows)
+
+	rows, err = ds.QueryResultHistorySnapshot(ctx, query.ID, t0, filter)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c"}, rowNames(rows))
+
+	rows, err = ds.QueryResultHistorySnapshot(ctx, query.ID, t1, filter)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "c"}, rowNames(rows))
+
+	rows, err = ds.QueryResultHistorySnapshot(ctx, query.ID, t2, filter)
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", ""}, rowNames(rows))
+	require.Equal(t, host2.ID, rows[1].HostID)
+	require.Equal(t, t2, rows[1].LastFetched.UTC())
+
+	// the history is removed along with the query results when the query changes
+	query.Query = "SELECT 2"
+	require.NoError(t, ds.SaveQuery(ctx, query, true, false))
+	rows, err = ds.QueryResultHistoryRows(ctx, query.ID, t0, t2, filter)
+	require.NoError(t, err)
+	require.Empty(t, rows)
+}
+
+func testCleanupQueryResultHistory(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+	user := test.NewUser(t, ds, "Test User", "test@example.com", true)
+	query1 := test.NewQuery(t, ds, nil, "History Query 1", "SELECT 1", user.ID, true)
+	query2 := test.NewQuery(t, ds, nil, "History Query 2", "SELECT 1", user.ID, true)
+	filter := fleet.TeamFilter{User: user, IncludeObserver: true}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	batch := func(queryID uint, fetchedAt time.Time, rowCount int) *fleet.QueryResultHistoryBatch {
+		rows := make([]*json.RawMessage, 0, rowCount)
+		for range rowCount {
+			rows = append(rows, ptr.RawMessage([]byte(`{"a": "b"}`)))
+		}
+		return &fleet.QueryResultHistoryBatch{QueryID: queryID, HostID: 1, FetchedAt: fetchedAt, Rows: rows}
+	}
+	require.NoError(t, ds.InsertQueryResultHistory(ctx, []*fleet.QueryResultHistoryBatch{
+		batch(query1.ID, now.Add(-48*time.Hour), 2),
+		batch(query1.ID, now.Add(-3*time.Hour), 2),
+		batch(query1.ID, now.Add(-2*time.Hour), 2),
+		batch(query1.ID, now.Add(-time.Hour), 3),
+		batch(query2.ID, now.Add(-time.Hour), 1),
+	}))
+
+	countRows := func(queryID uint) int {
+		rows, err := ds.QueryResultHistoryRows(ctx, queryID, now.Add(-72*time.Hour), now, filter)
+		require.NoError(t, err)
+		return len(rows)
+	}
+
+	// the expired batch is removed, the other batches fit in the budget
+	require.NoError(t, ds.CleanupQueryResultHistory(ctx, now.Add(-24*time.Hour), 10))
+	require.Equal(t, 7, countRows(query1.ID))
+	require.Equal(t, 1, countRows(query2.ID))
+
+	// the oldest batches are removed whole until the history fits in the budget
+	require.NoError(t, ds.CleanupQueryResultHistory(ctx, now.Add(-24*time.Hour), 4))
+	require.Equal(t, 3, countRows(query1.ID))
+	require.Equal(t, 1, countRows(query2.ID))
+
+	// everything is removed with a cutoff of now
+	require.NoError(t, ds.CleanupQueryResultHistory(ctx, now.Add(time.Second), 4))
+	require.Zero(t, countRows(query1.ID))
+	require.Zero(t, countRows(query2.ID))
+}
@@ -887,6 +887,12 @@ func getScimGroupChildren(ctx context.Context, q sqlx.QueryerContext, groupID ui
 	return childIDs, nil
 }
 
+// ScimGroupMemberUserIDs returns the IDs of the SCIM users who are members of
+// the group, directly or through its nested groups.
+func (ds *Datastore) ScimGroupMemberUserIDs(ctx context.Context, groupID uint) ([]uint, error) {
+	return getTransitiveScimGroupUserIDs(ctx, ds.reader(ctx), groupID)
+}
+
 // getTransitiveScimGroupUserIDs returns the IDs of all SCIM users who are
 // effective members of the given group -- that is, direct members of the group
 // or of any of its (recursively) nested child groups.
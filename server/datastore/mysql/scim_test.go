@@ -189,6 +189,13 @@ func testScimNestedGroups(t *testing.T, ds *Datastore) {
 	}
 	require.ElementsMatch(t, []uint{childID, parentID, grandparentID}, groupIDs)
 
+	// The user is a member of all three groups when listing group members.
+	for _, groupID := range []uint{childID, parentID, grandparentID} {
+		memberIDs, err := ds.ScimGroupMemberUserIDs(ctx, groupID)
+		require.NoError(t, err)
+		require.Equal(t, []uint{userID}, memberIDs)
+	}
+
 	// Removing the parent -> child edge via ReplaceScimGroup drops the user's
 	// effective membership in parent and grandparent, but keeps the child.
 	parent.ChildGroups = []uint{}
@@ -201,6 +208,10 @@ func testScimNestedGroups(t *testing.T, ds *Datastore) {
 		groupIDs = append(groupIDs, g.ID)
 	}
 	require.ElementsMatch(t, []uint{childID}, groupIDs)
+
+	memberIDs, err := ds.ScimGroupMemberUserIDs(ctx, grandparentID)
+	require.NoError(t, err)
+	require.Empty(t, memberIDs)
 }
 
 func testScimUserByID(t *testing.T, ds *Datastore) {
@@ -0,0 +1,38 @@
+package mysql
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) RecordServiceNowPolicyIncident(ctx context.Context, policyID uint) error {
+	const stmt = `
+		INSERT INTO servicenow_policy_incidents (policy_id) VALUES (?)
+		ON DUPLICATE KEY UPDATE updated_at = CURRENT_TIMESTAMP(6)`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, policyID); err != nil {
+		return ctxerr.Wrap(ctx, err, "record servicenow policy incident")
+	}
+	return nil
+}
+
+func (ds *Datastore) DeleteServiceNowPolicyIncident(ctx context.Context, policyID uint) error {
+	const stmt = `DELETE FROM servicenow_policy_incidents WHERE policy_id = ?`
+
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, policyID); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete servicenow policy incident")
+	}
+	return nil
+}
+
+func (ds *Datastore) ListServiceNowPolicyIncidents(ctx context.Context) ([]uint, error) {
+	const stmt = `SELECT policy_id FROM servicenow_policy_incidents ORDER BY policy_id`
+
+	var policyIDs []uint
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &policyIDs, stmt); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list servicenow policy incidents")
+	}
+	return policyIDs, nil
+}
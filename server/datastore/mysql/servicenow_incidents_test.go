@@ -0,0 +1,61 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceNowIncidents(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"PolicyIncidents", testServiceNowPolicyIncidents},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testServiceNowPolicyIncidents(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	p1, err := ds.NewGlobalPolicy(ctx, nil, fleet.PolicyPayload{Name: "p1", Query: "SELECT 1"})
+	require.NoError(t, err)
+	p2, err := ds.NewGlobalPolicy(ctx, nil, fleet.PolicyPayload{Name: "p2", Query: "SELECT 2"})
+	require.NoError(t, err)
+
+	ids, err := ds.ListServiceNowPolicyIncidents(ctx)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	require.NoError(t, ds.RecordServiceNowPolicyIncident(ctx, p2.ID))
+	require.NoError(t, ds.RecordServiceNowPolicyIncident(ctx, p1.ID))
+	// recording an incident again is a no-op
+	require.NoError(t, ds.RecordServiceNowPolicyIncident(ctx, p1.ID))
+
+	ids, err = ds.ListServiceNowPolicyIncidents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []uint{p1.ID, p2.ID}, ids)
+
+	require.NoError(t, ds.DeleteServiceNowPolicyIncident(ctx, p1.ID))
+	// deleting a missing record is a no-op
+	require.NoError(t, ds.DeleteServiceNowPolicyIncident(ctx, p1.ID))
+	ids, err = ds.ListServiceNowPolicyIncidents(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []uint{p2.ID}, ids)
+
+	// the record is deleted with its policy
+	_, err = ds.DeleteGlobalPolicies(ctx, []uint{p2.ID})
+	require.NoError(t, err)
+	ids, err = ds.ListServiceNowPolicyIncidents(ctx)
+	require.NoError(t, err)
+	require.Empty(t, ids)
+}
@@ -2050,7 +2050,7 @@ func canUseOptimizedListQuery(opts fleet.SoftwareListOptions) bool {
 	// improves performance.
 	//
 	// Filters (VulnerableOnly / KnownExploit / MinimumCVSS / MaximumCVSS /
-	// MatchQuery) are now supported in the inner query via EXISTS pushdown —
+	// MinimumEPSS / MaximumEPSS / MatchQuery) are now supported in the inner query via EXISTS pushdown —
 	// see buildOptimizedListSoftwareSQL.
 	return opts.HostID == nil &&
 		orderKey == "hosts_count" &&
@@ -2115,15 +2115,15 @@ func buildOptimizedListSoftwareSQL(opts fleet.SoftwareListOptions) (string, []in
 	}
 
 	// Filter pushdown: when the caller requests vulnerable software, a CISA
-	// known exploit, a CVSS range, or a search, push these into the inner
+	// known exploit, a CVSS or EPSS range, or a search, push these into the inner
 	// query as semi-joins so they prune candidate rows BEFORE pagination
 	// instead of expanding row count via outer JOIN+GROUP BY (as the goqu
 	// fallback does). The covering index scan on idx_software_host_counts_
 	// team_global_hosts_desc still drives the query; each EXISTS probe uses
 	// idx_software_cve_cve / unq_software_id_cve / idx_cve_meta_exploit /
 	// idx_cve_meta_cvss_score from #45415.
-	if opts.VulnerableOnly || opts.KnownExploit || opts.MinimumCVSS > 0 || opts.MaximumCVSS > 0 {
-		needsCVEMeta := opts.KnownExploit || opts.MinimumCVSS > 0 || opts.MaximumCVSS > 0
+	if opts.VulnerableOnly || opts.KnownExploit || opts.MinimumCVSS > 0 || opts.MaximumCVSS > 0 || opts.MinimumEPSS > 0 || opts.MaximumEPSS > 0 {
+		needsCVEMeta := opts.KnownExploit || opts.MinimumCVSS > 0 || opts.MaximumCVSS > 0 || opts.MinimumEPSS > 0 || opts.MaximumEPSS > 0
 		innerSQL += ` AND EXISTS (
 			SELECT 1 FROM software_cve sc`
 		if needsCVEMeta {
@@ -2139,6 +2139,14 @@ func buildOptimizedListSoftwareSQL(opts fleet.SoftwareListOptions) (string, []in
 				innerSQL += ` AND cm.cvss_score <= ?`
 				args = append(args, opts.MaximumCVSS)
 			}
+			if opts.MinimumEPSS > 0 {
+				innerSQL += ` AND cm.epss_probability >= ?`
+				args = append(args, opts.MinimumEPSS)
+			}
+			if opts.MaximumEPSS > 0 {
+				innerSQL += ` AND cm.epss_probability <= ?`
+				args = append(args, opts.MaximumEPSS)
+			}
 		}
 		innerSQL += ` WHERE sc.software_id = shc.software_id)`
 	}
@@ -2411,7 +2419,7 @@ func selectSoftwareSQL(opts fleet.SoftwareListOptions) (string, []interface{}, e
 			"c.cve": goqu.I("scv.cve"),
 		}
 
-		if opts.KnownExploit || opts.MinimumCVSS > 0 || opts.MaximumCVSS > 0 {
+		if opts.KnownExploit || opts.MinimumCVSS > 0 || opts.MaximumCVSS > 0 || opts.MinimumEPSS > 0 || opts.MaximumEPSS > 0 {
 
 			if opts.KnownExploit {
 				baseJoinConditions["c.cisa_known_exploit"] = true
@@ -2425,6 +2433,15 @@ func selectSoftwareSQL(opts fleet.SoftwareListOptions) (string, []interface{}, e
 				baseJoinConditions["c.cvss_score"] = goqu.Op{"lte": opts.MaximumCVSS}
 			}
 
+			switch {
+			case opts.MinimumEPSS > 0 && opts.MaximumEPSS > 0:
+				baseJoinConditions["c.epss_probability"] = goqu.Op{"between": goqu.Range(opts.MinimumEPSS, opts.MaximumEPSS)}
+			case opts.MinimumEPSS > 0:
+				baseJoinConditions["c.epss_probability"] = goqu.Op{"gte": opts.MinimumEPSS}
+			case opts.MaximumEPSS > 0:
+				baseJoinConditions["c.epss_probability"] = goqu.Op{"lte": opts.MaximumEPSS}
+			}
+
 			ds = ds.InnerJoin(
 				goqu.I("cve_meta").As("c"),
 				goqu.On(baseJoinConditions),
@@ -2572,7 +2589,7 @@ func countSoftwareDB(
 	needsSoftwareJoin := opts.ListOptions.MatchQuery != ""
 	needsTitleJoin := opts.ListOptions.MatchQuery != "" // Join software_titles for search by title name
 	needsCVEJoin := opts.VulnerableOnly || opts.ListOptions.MatchQuery != ""
-	needsCVEMetaJoin := opts.KnownExploit || opts.MinimumCVSS > 0 || opts.MaximumCVSS > 0
+	needsCVEMetaJoin := opts.KnownExploit || opts.MinimumCVSS > 0 || opts.MaximumCVSS > 0 || opts.MinimumEPSS > 0 || opts.MaximumEPSS > 0
 
 	// Ensure CVE join exists if we need to join cve_meta
 	if needsCVEMetaJoin {
@@ -2638,6 +2655,14 @@ func countSoftwareDB(
 		whereClauses = append(whereClauses, "c.cvss_score <= ?")
 		args = append(args, opts.MaximumCVSS)
 	}
+	if opts.MinimumEPSS > 0 {
+		whereClauses = append(whereClauses, "c.epss_probability >= ?")
+		args = append(args, opts.MinimumEPSS)
+	}
+	if opts.MaximumEPSS > 0 {
+		whereClauses = append(whereClauses, "c.epss_probability <= ?")
+		args = append(args, opts.MaximumEPSS)
+	}
 
 	// Apply search filter (also search by software title name for consistency with titles endpoint)
 	// See: https://github.com/fleetdm/fleet/issues/35028
@@ -2857,6 +2882,11 @@ func (ds *Datastore) ListSoftware(ctx context.Context, opt fleet.SoftwareListOpt
 			"query", "min_cvss_score, max_cvss_score, and exploit can only be provided with vulnerable=true",
 		)
 	}
+	if !opt.VulnerableOnly && (opt.MinimumEPSS > 0 || opt.MaximumEPSS > 0) {
+		return nil, nil, fleet.NewInvalidArgumentError(
+			"query", "min_epss_probability and max_epss_probability can only be provided with vulnerable=true",
+		)
+	}
 
 	software, err := listSoftwareDB(ctx, ds.reader(ctx), opt)
 	if err != nil {
@@ -72,7 +72,8 @@ func (ds *Datastore) GetSoftwareInstallDetails(ctx context.Context, executionId
     COALESCE(p.patch_when_closed, 0) AS patch_when_closed,
     inst.contents AS install_script,
     uninst.contents AS uninstall_script,
-    COALESCE(pisnt.contents, '') AS post_install_script
+    COALESCE(pisnt.contents, '') AS post_install_script,
+    si.storage_id AS hash_sha256
   FROM
     host_software_installs hsi
   INNER JOIN
@@ -106,7 +107,8 @@ func (ds *Datastore) GetSoftwareInstallDetails(ctx context.Context, executionId
     COALESCE(p.patch_when_closed, 0) AS patch_when_closed,
     inst.contents AS install_script,
     uninst.contents AS uninstall_script,
-    COALESCE(pisnt.contents, '') AS post_install_script
+    COALESCE(pisnt.contents, '') AS post_install_script,
+    si.storage_id AS hash_sha256
   FROM
     upcoming_activities ua
   INNER JOIN
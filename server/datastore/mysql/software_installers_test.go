@@ -215,6 +215,7 @@ func testGetSoftwareInstallDetailsCustomHostVitals(t *testing.T, ds *Datastore)
 	require.Equal(t, "install A-123", details.InstallScript)
 	require.Equal(t, "post A-123", details.PostInstallScript)
 	require.Equal(t, "uninstall A-123", details.UninstallScript)
+	require.Equal(t, "chv-storage-1", details.HashSHA256)
 
 	// A referenced vital with no value for the host fails delivery.
 	dept, err := ds.CreateCustomHostVital(ctx, "Department")
@@ -7406,30 +7406,35 @@ func testListSoftwareVersionsVulnerabilityFilters(t *testing.T, ds *Datastore) {
 			// chrome
 			CVE:              "CVE-2024-1234",
 			CVSSScore:        ptr.Float64(7.5),
+			EPSSProbability:  ptr.Float64(0.9),
 			CISAKnownExploit: ptr.Bool(true),
 		},
 		{
 			// safari
 			CVE:              "CVE-2024-1235",
 			CVSSScore:        ptr.Float64(7.5),
+			EPSSProbability:  ptr.Float64(0.05),
 			CISAKnownExploit: ptr.Bool(false),
 		},
 		{
 			// firefox
 			CVE:              "CVE-2024-1236",
 			CVSSScore:        ptr.Float64(8.0),
+			EPSSProbability:  ptr.Float64(0.5),
 			CISAKnownExploit: ptr.Bool(true),
 		},
 		{
 			// edge
 			CVE:              "CVE-2024-1237",
 			CVSSScore:        ptr.Float64(8.0),
+			EPSSProbability:  ptr.Float64(0.01),
 			CISAKnownExploit: ptr.Bool(false),
 		},
 		{
 			// brave
 			CVE:              "CVE-2024-1238",
 			CVSSScore:        ptr.Float64(9.0),
+			EPSSProbability:  ptr.Float64(0.2),
 			CISAKnownExploit: ptr.Bool(true),
 		},
 		// CVE-2024-1239 for opera has no CVE Meta
@@ -7709,6 +7714,77 @@ func testListSoftwareVersionsVulnerabilityFilters(t *testing.T, ds *Datastore) {
 				},
 			},
 		},
+		{
+			name: "minimum epss 0.2",
+			opts: fleet.SoftwareListOptions{
+				ListOptions:      fleet.ListOptions{OrderKey: "name", OrderDirection: fleet.OrderAscending},
+				IncludeCVEScores: true,
+				VulnerableOnly:   true,
+				MinimumEPSS:      0.2,
+			},
+			expected: []swVersion{
+				{
+					Name:    "brave",
+					Version: "0.0.3",
+				},
+				{
+					Name:    "chrome",
+					Version: "0.0.1",
+				},
+				{
+					Name:    "firefox",
+					Version: "0.0.3",
+				},
+			},
+		},
+		{
+			name: "maximum epss 0.1",
+			opts: fleet.SoftwareListOptions{
+				ListOptions:      fleet.ListOptions{OrderKey: "name", OrderDirection: fleet.OrderAscending},
+				IncludeCVEScores: true,
+				VulnerableOnly:   true,
+				MaximumEPSS:      0.1,
+			},
+			expected: []swVersion{
+				{
+					Name:    "edge",
+					Version: "0.0.3",
+				},
+				{
+					Name:    "safari",
+					Version: "0.0.1",
+				},
+			},
+		},
+		{
+			name: "minimum epss 0.1 and maximum epss 0.6 and minimum cvss 8.0",
+			opts: fleet.SoftwareListOptions{
+				ListOptions:      fleet.ListOptions{OrderKey: "name", OrderDirection: fleet.OrderAscending},
+				IncludeCVEScores: true,
+				VulnerableOnly:   true,
+				MinimumEPSS:      0.1,
+				MaximumEPSS:      0.6,
+				MinimumCVSS:      8.0,
+			},
+			expected: []swVersion{
+				{
+					Name:    "brave",
+					Version: "0.0.3",
+				},
+				{
+					Name:    "firefox",
+					Version: "0.0.3",
+				},
+			},
+		},
+		{
+			name: "err if vulnerableOnly is not set with MinimumEPSS",
+			opts: fleet.SoftwareListOptions{
+				ListOptions: fleet.ListOptions{},
+				MinimumEPSS: 0.5,
+			},
+			err: fleet.NewInvalidArgumentError("query", "min_epss_probability and max_epss_probability can only be provided with vulnerable=true"),
+		},
 		{
 			name: "err if vulnerableOnly is not set with MinimumCVSS",
 			opts: fleet.SoftwareListOptions{
@@ -249,6 +249,8 @@ func canUseOptimizedListTitlesQuery(opts fleet.SoftwareTitleListOptions) bool {
 		!opts.VulnerableOnly &&
 		opts.MinimumCVSS == 0 &&
 		opts.MaximumCVSS == 0 &&
+		opts.MinimumEPSS == 0 &&
+		opts.MaximumEPSS == 0 &&
 		!opts.KnownExploit &&
 		opts.ListOptions.MatchQuery == "" &&
 		!opts.AvailableForInstall &&
@@ -282,6 +284,10 @@ func (ds *Datastore) ListSoftwareTitles(
 		return nil, 0, nil, fleet.NewInvalidArgumentError("query", "min_cvss_score, max_cvss_score, and exploit can only be provided with vulnerable=true")
 	}
 
+	if (opt.MinimumEPSS > 0 || opt.MaximumEPSS > 0) && !opt.VulnerableOnly {
+		return nil, 0, nil, fleet.NewInvalidArgumentError("query", "min_epss_probability and max_epss_probability can only be provided with vulnerable=true")
+	}
+
 	if opt.TeamID == nil {
 		if opt.PackagesOnly {
 			return nil, 0, nil, fleet.NewInvalidArgumentError("query", "packages_only can only be provided with team_id")
@@ -785,7 +791,7 @@ FROM software_titles st
 		{{$cveJoin := yesNo $.VulnerableOnly "INNER" "LEFT"}}
 		{{$softwareJoin = printf "%s JOIN software s ON s.title_id = st.id %[1]s JOIN software_cve scve ON s.id = scve.software_id" $cveJoin }}
 	{{end}}
-	{{if and $.VulnerableOnly (or $.KnownExploit $.MinimumCVSS $.MaximumCVSS)}}
+	{{if and $.VulnerableOnly (or $.KnownExploit $.MinimumCVSS $.MaximumCVSS $.MinimumEPSS $.MaximumEPSS)}}
 		{{$softwareJoin = printf "%s INNER JOIN cve_meta cm ON scve.cve = cm.cve" $softwareJoin}}
 	  	{{if $.KnownExploit}}
 		  {{$softwareJoin = printf "%s AND cm.cisa_known_exploit = 1" $softwareJoin}}
@@ -796,6 +802,12 @@ FROM software_titles st
 		{{if $.MaximumCVSS}}
 		  {{$softwareJoin = printf "%s AND cm.cvss_score <= ?" $softwareJoin}}
 		{{end}}
+		{{if $.MinimumEPSS}}
+		  {{$softwareJoin = printf "%s AND cm.epss_probability >= ?" $softwareJoin}}
+		{{end}}
+		{{if $.MaximumEPSS}}
+		  {{$softwareJoin = printf "%s AND cm.epss_probability <= ?" $softwareJoin}}
+		{{end}}
 	{{end}}
 	{{$softwareJoin}}
 {{end}}
@@ -869,7 +881,7 @@ GROUP BY
 	{{end}}
 `
 	var args []any
-	if opt.VulnerableOnly && (opt.KnownExploit || opt.MinimumCVSS > 0 || opt.MaximumCVSS > 0) {
+	if opt.VulnerableOnly && (opt.KnownExploit || opt.MinimumCVSS > 0 || opt.MaximumCVSS > 0 || opt.MinimumEPSS > 0 || opt.MaximumEPSS > 0) {
 		if opt.MinimumCVSS > 0 {
 			args = append(args, opt.MinimumCVSS)
 		}
@@ -877,6 +889,14 @@ GROUP BY
 		if opt.MaximumCVSS > 0 {
 			args = append(args, opt.MaximumCVSS)
 		}
+
+		if opt.MinimumEPSS > 0 {
+			args = append(args, opt.MinimumEPSS)
+		}
+
+		if opt.MaximumEPSS > 0 {
+			args = append(args, opt.MaximumEPSS)
+		}
 	}
 
 	if opt.ListOptions.MatchQuery != "" {
@@ -153,7 +153,8 @@ func (ds *Datastore) ShouldSendStatistics(ctx context.Context, frequency time.Du
 			stats.Organization = lic.GetOrganization()
 		}
 		stats.AIFeaturesDisabled = appConfig.ServerSettings.AIFeaturesDisabled
-		stats.MaintenanceWindowsConfigured = len(appConfig.Integrations.GoogleCalendar) > 0 && appConfig.Integrations.GoogleCalendar[0].Domain != "" && !appConfig.Integrations.GoogleCalendar[0].ApiKey.IsEmpty()
+		stats.MaintenanceWindowsConfigured = len(appConfig.Integrations.GoogleCalendar) > 0 && appConfig.Integrations.GoogleCalendar[0].Domain != "" && !appConfig.Integrations.GoogleCalendar[0].ApiKey.IsEmpty() ||
+			len(appConfig.Integrations.MicrosoftCalendar) > 0 && appConfig.Integrations.MicrosoftCalendar[0].Domain != "" && appConfig.Integrations.MicrosoftCalendar[0].TenantID != ""
 		stats.GoogleWorkspaceConfigured = appConfig.Integrations.IsGoogleWorkspaceConfigured()
 
 		stats.MaintenanceWindowsEnabled = false
@@ -202,6 +202,7 @@ var teamRefs = []string{
 	"software_title_team_pins",
 	"vpp_app_configurations",
 	"software_categories",
+	"gitops_snapshots",
 }
 
 // teamLabelsRefs are the tables that could be referenced by team labels that
@@ -688,9 +689,10 @@ func (ds *Datastore) DeleteIntegrationsFromTeams(ctx context.Context, deletedInt
 		// ignore errors, it's ok for some integrations to not match with the
 		// batch of deleted integrations, we're only interested in knowing if
 		// some did match.
-		if matches, _ := tm.Config.Integrations.MatchWithIntegrations(deletedIntgs); len(matches.Jira)+len(matches.Zendesk) > 0 {
+		if matches, _ := tm.Config.Integrations.MatchWithIntegrations(deletedIntgs); len(matches.Jira)+len(matches.Zendesk)+len(matches.ServiceNow) > 0 {
 			delJira, _ := fleet.IndexJiraIntegrations(matches.Jira)
 			delZendesk, _ := fleet.IndexZendeskIntegrations(matches.Zendesk)
+			delServiceNow, _ := fleet.IndexServiceNowIntegrations(matches.ServiceNow)
 
 			var keepJira []*fleet.TeamJiraIntegration
 			for _, tmIntg := range tm.Config.Integrations.Jira {
@@ -706,8 +708,16 @@ func (ds *Datastore) DeleteIntegrationsFromTeams(ctx context.Context, deletedInt
 				}
 			}
 
+			var keepServiceNow []*fleet.TeamServiceNowIntegration
+			for _, tmIntg := range tm.Config.Integrations.ServiceNow {
+				if _, ok := delServiceNow[tmIntg.UniqueKey()]; !ok {
+					keepServiceNow = append(keepServiceNow, tmIntg)
+				}
+			}
+
 			tm.Config.Integrations.Jira = keepJira
 			tm.Config.Integrations.Zendesk = keepZendesk
+			tm.Config.Integrations.ServiceNow = keepServiceNow
 			if _, err := ds.writer(ctx).ExecContext(ctx, updateTeam, tm.Config, tm.ID); err != nil {
 				return ctxerr.Wrap(ctx, err, "update team config")
 			}
@@ -581,7 +581,7 @@ func testTeamsAgentOptions(t *testing.T, ds *Datastore) {
 func testTeamsDeleteIntegrationsFromTeams(t *testing.T, ds *Datastore) {
 	ctx := context.Background()
 
-	urla, urlb, urlc, urld, urle, urlf, urlg := "http://a.com", "http://b.com", "http://c.com", "http://d.com", "http://e.com", "http://f.com", "http://g.com"
+	urla, urlb, urlc, urld, urle, urlf, urlg, urlh := "http://a.com", "http://b.com", "http://c.com", "http://d.com", "http://e.com", "http://f.com", "http://g.com", "http://h.com"
 
 	// create some teams
 	team1, err := ds.NewTeam(ctx, &fleet.Team{
@@ -613,6 +613,9 @@ func testTeamsDeleteIntegrationsFromTeams(t *testing.T, ds *Datastore) {
 					{URL: urlc, GroupID: 1},
 					{URL: urlf, GroupID: 3},
 				},
+				ServiceNow: []*fleet.TeamServiceNowIntegration{
+					{URL: urlh, AssignmentGroup: "Service Desk"},
+				},
 			},
 		},
 	})
@@ -648,6 +651,9 @@ func testTeamsDeleteIntegrationsFromTeams(t *testing.T, ds *Datastore) {
 			for _, z := range tm.Config.Integrations.Zendesk {
 				urls = append(urls, z.URL)
 			}
+			for _, sn := range tm.Config.Integrations.ServiceNow {
+				urls = append(urls, sn.URL)
+			}
 
 			want := expected[i]
 			require.ElementsMatch(t, want, urls)
@@ -657,9 +663,9 @@ func testTeamsDeleteIntegrationsFromTeams(t *testing.T, ds *Datastore) {
 	// delete nothing
 	err = ds.DeleteIntegrationsFromTeams(context.Background(), fleet.Integrations{})
 	require.NoError(t, err)
-	assertIntgURLs([]string{urla, urlb, urlc, urld}, []string{urla, urle, urlc, urlf}, []string{urle, urlf})
+	assertIntgURLs([]string{urla, urlb, urlc, urld}, []string{urla, urle, urlc, urlf, urlh}, []string{urle, urlf})
 
-	// delete a, b, c (in the url) so that team1 and team2 are impacted
+	// delete a, b, c, h (in the url) so that team1 and team2 are impacted
 	err = ds.DeleteIntegrationsFromTeams(context.Background(), fleet.Integrations{
 		Jira: []*fleet.JiraIntegration{
 			{URL: urla, ProjectKey: "A"},
@@ -668,6 +674,9 @@ func testTeamsDeleteIntegrationsFromTeams(t *testing.T, ds *Datastore) {
 		Zendesk: []*fleet.ZendeskIntegration{
 			{URL: urlc, GroupID: 1},
 		},
+		ServiceNow: []*fleet.ServiceNowIntegration{
+			{URL: urlh, AssignmentGroup: "Service Desk"},
+		},
 	})
 	require.NoError(t, err)
 	assertIntgURLs([]string{urld}, []string{urle, urlf}, []string{urle, urlf})
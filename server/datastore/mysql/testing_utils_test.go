@@ -924,6 +924,11 @@ func (t *testingLookupService) GetHostActivitiesWebhookSettings(ctx context.Cont
 	return nil, nil
 }
 
+func (t *testingLookupService) DeliverActivitiesWebhook(ctx context.Context, fleetID *uint, destinationURL string, payload json.RawMessage) error {
+	// Activities webhooks are not exercised through this test adapter.
+	return nil
+}
+
 func (t *testingLookupService) ActivateNextUpcomingActivityForHost(ctx context.Context, hostID uint, fromCompletedExecID string) error {
 	return t.ds.ActivateNextUpcomingActivityForHost(ctx, hostID, fromCompletedExecID)
 }
@@ -0,0 +1,282 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+)
+
+func (ds *Datastore) SetUserPendingTOTPSecret(ctx context.Context, userID uint, secret []byte) error {
+	encrypted, err := encrypt(secret, ds.serverPrivateKey)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "encrypt TOTP secret")
+	}
+
+	// A confirmed secret is never replaced, it must be deleted first.
+	const stmt = `
+		INSERT INTO user_totp_secrets (user_id, secret) VALUES (?, ?)
+		ON DUPLICATE KEY UPDATE
+			secret = IF(confirmed_at IS NULL, VALUES(secret), secret)`
+	if _, err := ds.writer(ctx).ExecContext(ctx, stmt, userID, encrypted); err != nil {
+		return ctxerr.Wrap(ctx, err, "set pending TOTP secret")
+	}
+	return nil
+}
+
+func (ds *Datastore) UserTOTP(ctx context.Context, userID uint) (*fleet.UserTOTP, error) {
+	var row struct {
+		Secret       []byte       `db:"secret"`
+		ConfirmedAt  sql.NullTime `db:"confirmed_at"`
+		LastUsedStep int64        `db:"last_used_step"`
+	}
+	// read from the primary, the secret was usually just written
+	err := sqlx.GetContext(ctx, ds.writer(ctx), &row,
+		`SELECT secret, confirmed_at, last_used_step FROM user_totp_secrets WHERE user_id = ?`, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("UserTOTP").WithID(userID))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get TOTP secret")
+	}
+
+	secret, err := decrypt(row.Secret, ds.serverPrivateKey)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "decrypt TOTP secret")
+	}
+	totp := &fleet.UserTOTP{
+		UserID:       userID,
+		Secret:       secret,
+		LastUsedStep: row.LastUsedStep,
+	}
+	if row.ConfirmedAt.Valid {
+		totp.ConfirmedAt = &row.ConfirmedAt.Time
+	}
+	return totp, nil
+}
+
+func (ds *Datastore) ConfirmUserTOTP(ctx context.Context, userID uint, step int64) error {
+	res, err := ds.writer(ctx).ExecContext(ctx, `
+		UPDATE user_totp_secrets
+		SET confirmed_at = NOW(6), last_used_step = ?
+		WHERE user_id = ? AND confirmed_at IS NULL`, step, userID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "confirm TOTP secret")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("UserTOTP").WithMessage("no pending TOTP secret"))
+	}
+	return nil
+}
+
+func (ds *Datastore) UseUserTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	// Conditional update so that concurrent logins can't use the same code.
+	res, err := ds.writer(ctx).ExecContext(ctx, `
+		UPDATE user_totp_secrets
+		SET last_used_step = ?
+		WHERE user_id = ? AND confirmed_at IS NOT NULL AND last_used_step < ?`, step, userID, step)
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "use TOTP step")
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (ds *Datastore) DeleteUserTOTP(ctx context.Context, userID uint) error {
+	if _, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM user_totp_secrets WHERE user_id = ?`, userID); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete TOTP secret")
+	}
+	return nil
+}
+
+func (ds *Datastore) ReplaceUserMFARecoveryCodes(ctx context.Context, userID uint, codeHashes [][]byte) error {
+	return ds.withTx(ctx, func(tx sqlx.ExtContext) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_mfa_recovery_codes WHERE user_id = ?`, userID); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete recovery codes")
+		}
+		if len(codeHashes) == 0 {
+			return nil
+		}
+
+		values := strings.TrimSuffix(strings.Repeat("(?, ?),", len(codeHashes)), ",")
+		args := make([]any, 0, 2*len(codeHashes))
+		for _, h := range codeHashes {
+			args = append(args, userID, h)
+		}
+		stmt := fmt.Sprintf(`INSERT INTO user_mfa_recovery_codes (user_id, code_hash) VALUES %s`, values)
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "insert recovery codes")
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) CountUserMFARecoveryCodes(ctx context.Context, userID uint) (int, error) {
+	var count int
+	if err := sqlx.GetContext(ctx, ds.reader(ctx), &count,
+		`SELECT COUNT(*) FROM user_mfa_recovery_codes WHERE user_id = ? AND used_at IS NULL`, userID); err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "count recovery codes")
+	}
+	return count, nil
+}
+
+func (ds *Datastore) ConsumeUserMFARecoveryCode(ctx context.Context, userID uint, codeHash []byte) (bool, error) {
+	res, err := ds.writer(ctx).ExecContext(ctx, `
+		UPDATE user_mfa_recovery_codes
+		SET used_at = NOW(6)
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`, userID, codeHash)
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "consume recovery code")
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (ds *Datastore) NewUserWebAuthnCredential(ctx context.Context, cred *fleet.WebAuthnCredential) (*fleet.WebAuthnCredential, error) {
+	res, err := ds.writer(ctx).ExecContext(ctx, `
+		INSERT INTO user_webauthn_credentials (user_id, name, credential_id, public_key, sign_count, backup_eligible)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		cred.UserID, cred.Name, cred.CredentialID, cred.PublicKey, cred.SignCount, cred.BackupEligible)
+	if err != nil {
+		if IsDuplicate(err) {
+			return nil, ctxerr.Wrap(ctx, alreadyExists("WebAuthnCredential", cred.Name))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "insert webauthn credential")
+	}
+	id, _ := res.LastInsertId()
+	return ds.userWebAuthnCredential(ctx, cred.UserID, uint(id)) //nolint:gosec // dismiss G115
+}
+
+func (ds *Datastore) userWebAuthnCredential(ctx context.Context, userID, id uint) (*fleet.WebAuthnCredential, error) {
+	var cred fleet.WebAuthnCredential
+	err := sqlx.GetContext(ctx, ds.writer(ctx), &cred, `
+		SELECT id, user_id, name, credential_id, public_key, sign_count, backup_eligible, created_at, last_used_at
+		FROM user_webauthn_credentials
+		WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("WebAuthnCredential").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get webauthn credential")
+	}
+	return &cred, nil
+}
+
+func (ds *Datastore) ListUserWebAuthnCredentials(ctx context.Context, userID uint) ([]*fleet.WebAuthnCredential, error) {
+	creds := []*fleet.WebAuthnCredential{}
+	// read from the primary as sign counters must be up to date
+	if err := sqlx.SelectContext(ctx, ds.writer(ctx), &creds, `
+		SELECT id, user_id, name, credential_id, public_key, sign_count, backup_eligible, created_at, last_used_at
+		FROM user_webauthn_credentials
+		WHERE user_id = ?
+		ORDER BY id`, userID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list webauthn credentials")
+	}
+	return creds, nil
+}
+
+func (ds *Datastore) UpdateUserWebAuthnCredentialSignCount(ctx context.Context, id uint, signCount uint32) error {
+	if _, err := ds.writer(ctx).ExecContext(ctx, `
+		UPDATE user_webauthn_credentials
+		SET sign_count = ?, last_used_at = NOW(6)
+		WHERE id = ?`, signCount, id); err != nil {
+		return ctxerr.Wrap(ctx, err, "update webauthn credential sign count")
+	}
+	return nil
+}
+
+func (ds *Datastore) DeleteUserWebAuthnCredential(ctx context.Context, userID, id uint) error {
+	res, err := ds.writer(ctx).ExecContext(ctx,
+		`DELETE FROM user_webauthn_credentials WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "delete webauthn credential")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("WebAuthnCredential").WithID(id))
+	}
+	return nil
+}
+
+func (ds *Datastore) ResetUserMFA(ctx context.Context, userID uint) error {
+	return ds.withTx(ctx, func(tx sqlx.ExtContext) error {
+		for _, table := range []string{
+			"user_totp_secrets",
+			"user_webauthn_credentials",
+			"user_mfa_recovery_codes",
+			"user_mfa_challenges",
+		} {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id = ?`, userID); err != nil {
+				return ctxerr.Wrapf(ctx, err, "delete from %s", table)
+			}
+		}
+		return nil
+	})
+}
+
+func (ds *Datastore) NewUserMFAChallenge(ctx context.Context, userID uint, purpose string, webAuthnChallenge []byte) (*fleet.UserMFAChallenge, error) {
+	token, err := server.GenerateRandomURLSafeText(mfaTokenEntropyInBytes)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "generate MFA challenge token")
+	}
+
+	err = ds.withTx(ctx, func(tx sqlx.ExtContext) error {
+		// Expired challenges are cleaned up opportunistically, there are few of
+		// them and they are only read by token.
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_mfa_challenges WHERE created_at < NOW(6) - INTERVAL ? SECOND`,
+			fleet.MFAChallengeTTL.Seconds()); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete expired MFA challenges")
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO user_mfa_challenges (token, user_id, purpose, webauthn_challenge) VALUES (?, ?, ?, ?)`,
+			token, userID, purpose, webAuthnChallenge); err != nil {
+			return ctxerr.Wrap(ctx, err, "insert MFA challenge")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ds.UserMFAChallenge(ctx, token)
+}
+
+func (ds *Datastore) UserMFAChallenge(ctx context.Context, token string) (*fleet.UserMFAChallenge, error) {
+	var challenge fleet.UserMFAChallenge
+	err := sqlx.GetContext(ctx, ds.writer(ctx), &challenge, `
+		SELECT token, user_id, purpose, webauthn_challenge, failed_attempts, created_at
+		FROM user_mfa_challenges
+		WHERE token = ? AND created_at >= NOW(6) - INTERVAL ? SECOND AND failed_attempts < ?`,
+		token, fleet.MFAChallengeTTL.Seconds(), fleet.MFAChallengeMaxFailures)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("MFA challenge"))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get MFA challenge")
+	}
+	return &challenge, nil
+}
+
+func (ds *Datastore) RecordUserMFAChallengeFailure(ctx context.Context, token string) error {
+	if _, err := ds.writer(ctx).ExecContext(ctx,
+		`UPDATE user_mfa_challenges SET failed_attempts = failed_attempts + 1 WHERE token = ?`, token); err != nil {
+		return ctxerr.Wrap(ctx, err, "record MFA challenge failure")
+	}
+	return nil
+}
+
+func (ds *Datastore) ConsumeUserMFAChallenge(ctx context.Context, token string) error {
+	// Only one of concurrent redemptions of the same challenge deletes it.
+	res, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM user_mfa_challenges WHERE token = ?`, token)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "delete MFA challenge")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("MFA challenge"))
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mfa"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserMFA(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"TOTP", testUserMFATOTP},
+		{"RecoveryCodes", testUserMFARecoveryCodes},
+		{"WebAuthn", testUserMFAWebAuthn},
+		{"Challenges", testUserMFAChallenges},
+		{"Reset", testUserMFAReset},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testUserMFATOTP(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+
+	_, err := ds.UserTOTP(ctx, user.ID)
+	require.True(t, fleet.IsNotFound(err))
+	require.True(t, fleet.IsNotFound(ds.ConfirmUserTOTP(ctx, user.ID, 1)))
+
+	require.NoError(t, ds.SetUserPendingTOTPSecret(ctx, user.ID, []byte("first")))
+	require.NoError(t, ds.SetUserPendingTOTPSecret(ctx, user.ID, []byte("second")))
+	totp, err := ds.UserTOTP(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), totp.Secret)
+	assert.Nil(t, totp.ConfirmedAt)
+
+	// the secret is stored encrypted
+	var raw []byte
+	require.NoError(t, ds.writer(ctx).GetContext(ctx, &raw, `SELECT secret FROM user_totp_secrets WHERE user_id = ?`, user.ID))
+	assert.NotContains(t, string(raw), "second")
+
+	// pending secrets don't enable TOTP nor can be used
+	loaded, err := ds.UserByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, loaded.TOTPEnabled)
+	ok, err := ds.UseUserTOTPStep(ctx, user.ID, 10)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, ds.ConfirmUserTOTP(ctx, user.ID, 10))
+	totp, err = ds.UserTOTP(ctx, user.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, totp.ConfirmedAt)
+	assert.Equal(t, int64(10), totp.LastUsedStep)
+	loaded, err = ds.UserByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	assert.True(t, loaded.TOTPEnabled)
+	assert.False(t, loaded.WebAuthnEnabled)
+
+	// a confirmed secret is not replaced
+	require.NoError(t, ds.SetUserPendingTOTPSecret(ctx, user.ID, []byte("third")))
+	totp, err = ds.UserTOTP(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second"), totp.Secret)
+
+	// steps can't be reused
+	ok, err = ds.UseUserTOTPStep(ctx, user.ID, 10)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = ds.UseUserTOTPStep(ctx, user.ID, 11)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	require.NoError(t, ds.DeleteUserTOTP(ctx, user.ID))
+	_, err = ds.UserTOTP(ctx, user.ID)
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testUserMFARecoveryCodes(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	alice := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	bob := test.NewUser(t, ds, "Bob", "bob@example.com", true)
+
+	codes, err := mfa.GenerateRecoveryCodes()
+	require.NoError(t, err)
+	var hashes [][]byte
+	for _, c := range codes {
+		hashes = append(hashes, mfa.HashRecoveryCode(c))
+	}
+	require.NoError(t, ds.ReplaceUserMFARecoveryCodes(ctx, alice.ID, hashes))
+	count, err := ds.CountUserMFARecoveryCodes(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, len(codes), count)
+
+	// codes are per user and can only be used once
+	ok, err := ds.ConsumeUserMFARecoveryCode(ctx, bob.ID, hashes[0])
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = ds.ConsumeUserMFARecoveryCode(ctx, alice.ID, hashes[0])
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = ds.ConsumeUserMFARecoveryCode(ctx, alice.ID, hashes[0])
+	require.NoError(t, err)
+	assert.False(t, ok)
+	count, err = ds.CountUserMFARecoveryCodes(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, len(codes)-1, count)
+
+	require.NoError(t, ds.ReplaceUserMFARecoveryCodes(ctx, alice.ID, hashes[:2]))
+	count, err = ds.CountUserMFARecoveryCodes(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	require.NoError(t, ds.ReplaceUserMFARecoveryCodes(ctx, alice.ID, nil))
+	count, err = ds.CountUserMFARecoveryCodes(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func testUserMFAWebAuthn(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	alice := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	bob := test.NewUser(t, ds, "Bob", "bob@example.com", true)
+
+	cred, err := ds.NewUserWebAuthnCredential(ctx, &fleet.WebAuthnCredential{
+		UserID:         alice.ID,
+		Name:           "YubiKey",
+		CredentialID:   []byte("cred-1"),
+		PublicKey:      []byte("key-1"),
+		SignCount:      3,
+		BackupEligible: true,
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, cred.ID)
+	assert.Equal(t, "YubiKey", cred.Name)
+	assert.True(t, cred.BackupEligible)
+	assert.Nil(t, cred.LastUsedAt)
+
+	_, err = ds.NewUserWebAuthnCredential(ctx, &fleet.WebAuthnCredential{
+		UserID:       bob.ID,
+		Name:         "Copy",
+		CredentialID: []byte("cred-1"),
+		PublicKey:    []byte("key-1"),
+	})
+	var aee fleet.AlreadyExistsError
+	require.ErrorAs(t, err, &aee)
+
+	loaded, err := ds.UserByID(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.True(t, loaded.WebAuthnEnabled)
+	assert.False(t, loaded.TOTPEnabled)
+	users, err := ds.ListUsers(ctx, fleet.UserListOptions{})
+	require.NoError(t, err)
+	for _, u := range users {
+		assert.Equal(t, u.ID == alice.ID, u.WebAuthnEnabled, u.Email)
+	}
+
+	require.NoError(t, ds.UpdateUserWebAuthnCredentialSignCount(ctx, cred.ID, 4))
+	creds, err := ds.ListUserWebAuthnCredentials(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, creds, 1)
+	assert.Equal(t, uint32(4), creds[0].SignCount)
+	assert.Equal(t, []byte("cred-1"), creds[0].CredentialID)
+	assert.Equal(t, []byte("key-1"), creds[0].PublicKey)
+	assert.NotNil(t, creds[0].LastUsedAt)
+
+	creds, err = ds.ListUserWebAuthnCredentials(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Empty(t, creds)
+
+	require.True(t, fleet.IsNotFound(ds.DeleteUserWebAuthnCredential(ctx, bob.ID, cred.ID)))
+	require.NoError(t, ds.DeleteUserWebAuthnCredential(ctx, alice.ID, cred.ID))
+	require.True(t, fleet.IsNotFound(ds.DeleteUserWebAuthnCredential(ctx, alice.ID, cred.ID)))
+}
+
+func testUserMFAChallenges(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	user := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+
+	challenge, err := ds.NewUserMFAChallenge(ctx, user.ID, fleet.MFAChallengeLogin, []byte("webauthn"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, challenge.Token)
+	assert.Equal(t, user.ID, challenge.UserID)
+	assert.Equal(t, fleet.MFAChallengeLogin, challenge.Purpose)
+	assert.Equal(t, []byte("webauthn"), challenge.WebAuthnChallenge)
+
+	got, err := ds.UserMFAChallenge(ctx, challenge.Token)
+	require.NoError(t, err)
+	assert.Equal(t, challenge.Token, got.Token)
+
+	_, err = ds.UserMFAChallenge(ctx, "unknown")
+	require.True(t, fleet.IsNotFound(err))
+
+	// the challenge is invalidated after too many failures
+	for range fleet.MFAChallengeMaxFailures - 1 {
+		require.NoError(t, ds.RecordUserMFAChallengeFailure(ctx, challenge.Token))
+	}
+	got, err = ds.UserMFAChallenge(ctx, challenge.Token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(fleet.MFAChallengeMaxFailures-1), got.FailedAttempts)
+	require.NoError(t, ds.RecordUserMFAChallengeFailure(ctx, challenge.Token))
+	_, err = ds.UserMFAChallenge(ctx, challenge.Token)
+	require.True(t, fleet.IsNotFound(err))
+
+	// expired challenges are not returned and cleaned up
+	expired, err := ds.NewUserMFAChallenge(ctx, user.ID, fleet.MFAChallengeEnrollment, nil)
+	require.NoError(t, err)
+	assert.Nil(t, expired.WebAuthnChallenge)
+	_, err = ds.writer(ctx).ExecContext(ctx,
+		`UPDATE user_mfa_challenges SET created_at = NOW(6) - INTERVAL 1 HOUR WHERE token = ?`, expired.Token)
+	require.NoError(t, err)
+	_, err = ds.UserMFAChallenge(ctx, expired.Token)
+	require.True(t, fleet.IsNotFound(err))
+
+	challenge, err = ds.NewUserMFAChallenge(ctx, user.ID, fleet.MFAChallengeWebAuthnRegistration, []byte("webauthn"))
+	require.NoError(t, err)
+	var count int
+	require.NoError(t, ds.writer(ctx).GetContext(ctx, &count, `SELECT COUNT(*) FROM user_mfa_challenges WHERE token = ?`, expired.Token))
+	assert.Zero(t, count)
+
+	// challenges can only be consumed once
+	require.NoError(t, ds.ConsumeUserMFAChallenge(ctx, challenge.Token))
+	require.True(t, fleet.IsNotFound(ds.ConsumeUserMFAChallenge(ctx, challenge.Token)))
+	_, err = ds.UserMFAChallenge(ctx, challenge.Token)
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testUserMFAReset(t *testing.T, ds *Datastore) {
+	ctx := context.Background()
+	alice := test.NewUser(t, ds, "Alice", "alice@example.com", true)
+	bob := test.NewUser(t, ds, "Bob", "bob@example.com", true)
+
+	for _, u := range []*fleet.User{alice, bob} {
+		require.NoError(t, ds.SetUserPendingTOTPSecret(ctx, u.ID, []byte("secret")))
+		require.NoError(t, ds.ConfirmUserTOTP(ctx, u.ID, 1))
+		require.NoError(t, ds.ReplaceUserMFARecoveryCodes(ctx, u.ID, [][]byte{mfa.HashRecoveryCode("code")}))
+		_, err := ds.NewUserWebAuthnCredential(ctx, &fleet.WebAuthnCredential{
+			UserID:       u.ID,
+			Name:         "Passkey",
+			CredentialID: []byte("cred-" + u.Email),
+			PublicKey:    []byte("key"),
+		})
+		require.NoError(t, err)
+		_, err = ds.NewUserMFAChallenge(ctx, u.ID, fleet.MFAChallengeLogin, nil)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, ds.ResetUserMFA(ctx, alice.ID))
+
+	for _, u := range []*fleet.User{alice, bob} {
+		loaded, err := ds.UserByID(ctx, u.ID)
+		require.NoError(t, err)
+		enrolled := u.ID == bob.ID
+		assert.Equal(t, enrolled, loaded.TOTPEnabled, u.Email)
+		assert.Equal(t, enrolled, loaded.WebAuthnEnabled, u.Email)
+
+		count, err := ds.CountUserMFARecoveryCodes(ctx, u.ID)
+		require.NoError(t, err)
+		assert.Equal(t, enrolled, count > 0, u.Email)
+
+		var challenges int
+		require.NoError(t, ds.writer(ctx).GetContext(ctx, &challenges, `SELECT COUNT(*) FROM user_mfa_challenges WHERE user_id = ?`, u.ID))
+		assert.Equal(t, enrolled, challenges > 0, u.Email)
+	}
+
+	// deleting the user deletes their second factors
+	require.NoError(t, ds.DeleteUser(ctx, bob.ID))
+	creds, err := ds.ListUserWebAuthnCredentials(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Empty(t, creds)
+}
@@ -25,13 +25,18 @@ var userSearchColumns = []string{"name", "email"}
 // various places, which we do not want.
 const userSelectColumns = `id, created_at, updated_at, password, salt, name, email,
 	admin_forced_password_reset, gravatar_url, position, sso_enabled, global_role,
-	api_only, mfa_enabled, invite_id, last_login_at`
+	api_only, mfa_enabled, invite_id, last_login_at, disabled`
 
 // userLastActivitySelect computes the user's most recent authenticated
 // request from its live sessions (accessed_at is bumped on every request).
 // NULL when the user has no live session.
 const userLastActivitySelect = `(SELECT MAX(s.accessed_at) FROM sessions s WHERE s.user_id = users.id) AS last_activity_at`
 
+// userMFAMethodsSelect computes whether the user enrolled each of the second
+// factors that can be used to complete a password login.
+const userMFAMethodsSelect = `EXISTS(SELECT 1 FROM user_totp_secrets t WHERE t.user_id = users.id AND t.confirmed_at IS NOT NULL) AS totp_enabled,
+	EXISTS(SELECT 1 FROM user_webauthn_credentials w WHERE w.user_id = users.id) AS webauthn_enabled`
+
 // userSummaryColumns are the columns selected for UserSummary.
 const userSummaryColumns = `id, name, email, gravatar_url, api_only`
 
@@ -105,8 +110,8 @@ func (ds *Datastore) NewUser(ctx context.Context, user *fleet.User) (*fleet.User
 
 func (ds *Datastore) findUser(ctx context.Context, searchCol string, searchVal interface{}) (*fleet.User, error) {
 	sqlStatement := fmt.Sprintf(
-		"SELECT %s, %s FROM users WHERE %s = ? LIMIT 1",
-		userSelectColumns, userLastActivitySelect, searchCol,
+		"SELECT %s, %s, %s FROM users WHERE %s = ? LIMIT 1",
+		userSelectColumns, userLastActivitySelect, userMFAMethodsSelect, searchCol,
 	)
 
 	user := &fleet.User{}
@@ -127,6 +132,10 @@ func (ds *Datastore) findUser(ctx context.Context, searchCol string, searchVal i
 		return nil, ctxerr.Wrap(ctx, err, "load api endpoints")
 	}
 
+	if err := ds.loadCustomRolesForUsers(ctx, []*fleet.User{user}); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "load custom roles")
+	}
+
 	// When SSO is enabled, we can ignore forced password resets
 	// However, we want to leave the db untouched, to cover cases where SSO is toggled
 	if user.SSOEnabled {
@@ -165,7 +174,7 @@ var userAllowedOrderKeys = common_mysql.OrderKeyAllowlist{
 // UserListOptions.
 func (ds *Datastore) ListUsers(ctx context.Context, opt fleet.UserListOptions) ([]*fleet.User, error) {
 	sqlStatement := `
-		SELECT users.*, ` + userLastActivitySelect + `
+		SELECT users.*, ` + userLastActivitySelect + `, ` + userMFAMethodsSelect + `
 		FROM users
 		WHERE TRUE
 	`
@@ -195,6 +204,10 @@ func (ds *Datastore) ListUsers(ctx context.Context, opt fleet.UserListOptions) (
 		return nil, ctxerr.Wrap(ctx, err, "load api endpoints")
 	}
 
+	if err := ds.loadCustomRolesForUsers(ctx, users); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "load custom roles")
+	}
+
 	return users, nil
 }
 
@@ -284,7 +297,8 @@ func saveUserDB(ctx context.Context, tx sqlx.ExtContext, user *fleet.User) error
         mfa_enabled = ?,
         api_only = ?,
         settings = ?,
-		global_role = ?
+		global_role = ?,
+		disabled = ?
       WHERE id = ?
       `
 	settingsBytes, err := json.Marshal(user.Settings)
@@ -304,6 +318,7 @@ func saveUserDB(ctx context.Context, tx sqlx.ExtContext, user *fleet.User) error
 		user.APIOnly,
 		settingsBytes,
 		user.GlobalRole,
+		user.Disabled,
 		user.ID)
 	if err != nil {
 		return ctxerr.Wrap(ctx, err, "save user")
@@ -527,6 +542,44 @@ func (ds *Datastore) SaveUserIfNotLastAdmin(ctx context.Context, user *fleet.Use
 	})
 }
 
+// DisableUser marks the user as disabled and deletes its sessions and MFA
+// verification tokens in a single transaction, so that a disabled user is
+// never left with a usable session. Like DeleteUserIfNotLastAdmin, it locks
+// the global admin rows to prevent disabling the last enabled admin.
+func (ds *Datastore) DisableUser(ctx context.Context, id uint) error {
+	return ds.withTx(ctx, func(tx sqlx.ExtContext) error {
+		var globalRole sql.NullString
+		if err := sqlx.GetContext(ctx, tx, &globalRole,
+			`SELECT global_role FROM users WHERE id = ? FOR UPDATE`, id); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ctxerr.Wrap(ctx, notFound("User").WithID(id))
+			}
+			return ctxerr.Wrap(ctx, err, "select user to disable")
+		}
+		if globalRole.String == fleet.RoleAdmin {
+			var count int
+			if err := sqlx.GetContext(ctx, tx, &count,
+				`SELECT COUNT(*) FROM users WHERE global_role = 'admin' AND disabled = 0 AND id != ? FOR UPDATE`, id); err != nil {
+				return ctxerr.Wrap(ctx, err, "count global admins for disable")
+			}
+			if count == 0 {
+				return fleet.ErrLastGlobalAdmin
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET disabled = 1 WHERE id = ?`, id); err != nil {
+			return ctxerr.Wrap(ctx, err, "disable user")
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE user_id = ?`, id); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete sessions of disabled user")
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM verification_tokens WHERE user_id = ?`, id); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete verification tokens of disabled user")
+		}
+		return nil
+	})
+}
+
 func tableRowsCount(ctx context.Context, db sqlx.QueryerContext, tableName string) (int, error) {
 	var count int
 	err := sqlx.GetContext(ctx, db, &count, fmt.Sprintf(`SELECT count(*) FROM %s`, tableName))
@@ -34,6 +34,7 @@ func TestUsers(t *testing.T) {
 		{"Teams", testUsersTeams},
 		{"CreateWithTeams", testUsersCreateWithTeams},
 		{"SaveMany", testUsersSaveMany},
+		{"Disable", testUsersDisable},
 	}
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
@@ -466,3 +467,56 @@ func testUsersSaveMany(t *testing.T, ds *Datastore) {
 	require.NoError(t, err)
 	assert.True(t, strings.HasSuffix(gotU3.Email, "fleet.com"))
 }
+
+func testUsersDisable(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	admin1 := test.NewUser(t, ds, "Admin1", "admin1@example.com", true)
+	admin2 := test.NewUser(t, ds, "Admin2", "admin2@example.com", true)
+	observer := test.NewUser(t, ds, "Observer", "observer@example.com", false)
+
+	for _, u := range []*fleet.User{admin1, admin2, observer} {
+		_, err := ds.NewSession(ctx, u.ID, 64)
+		require.NoError(t, err)
+		_, err = ds.NewMFAToken(ctx, u.ID)
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, ds.DisableUser(ctx, observer.ID))
+	got, err := ds.UserByID(ctx, observer.ID)
+	require.NoError(t, err)
+	assert.True(t, got.Disabled)
+	sessions, err := ds.ListSessionsForUser(ctx, observer.ID)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+	var tokens int
+	require.NoError(t, sqlx.GetContext(ctx, ds.reader(ctx), &tokens,
+		`SELECT COUNT(*) FROM verification_tokens WHERE user_id = ?`, observer.ID))
+	assert.Zero(t, tokens)
+
+	// the other users are untouched
+	sessions, err = ds.ListSessionsForUser(ctx, admin1.ID)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	// one admin can be disabled, but not the last enabled one
+	require.NoError(t, ds.DisableUser(ctx, admin1.ID))
+	err = ds.DisableUser(ctx, admin2.ID)
+	require.ErrorIs(t, err, fleet.ErrLastGlobalAdmin)
+	got, err = ds.UserByID(ctx, admin2.ID)
+	require.NoError(t, err)
+	assert.False(t, got.Disabled)
+
+	// saving the user enables it again
+	got, err = ds.UserByID(ctx, admin1.ID)
+	require.NoError(t, err)
+	got.Disabled = false
+	require.NoError(t, ds.SaveUser(ctx, got))
+	got, err = ds.UserByID(ctx, admin1.ID)
+	require.NoError(t, err)
+	assert.False(t, got.Disabled)
+
+	err = ds.DisableUser(ctx, 999999)
+	var nfe fleet.NotFoundError
+	require.ErrorAs(t, err, &nfe)
+}
@@ -0,0 +1,227 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	common_mysql "github.com/fleetdm/fleet/v4/server/platform/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// vexBatchSize is the number of rows inserted or deleted at once when
+// replacing the VEX statements and suppressions.
+const vexBatchSize = 1000
+
+const vexDocumentSelect = `
+SELECT
+	vd.id, vd.name, vd.format, vd.document_id, vd.author, vd.sha256, vd.source, vd.created_at, vd.updated_at,
+	(SELECT COUNT(*) FROM vex_statements vst WHERE vst.vex_document_id = vd.id) AS statements_count,
+	(SELECT COUNT(*) FROM vex_suppressions vsu JOIN vex_statements vst ON vst.id = vsu.vex_statement_id
+		WHERE vst.vex_document_id = vd.id) AS suppressed_count
+FROM vex_documents vd`
+
+func (ds *Datastore) NewVEXDocument(ctx context.Context, doc *fleet.VEXDocument) (*fleet.VEXDocument, error) {
+	var id uint
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		res, err := tx.ExecContext(ctx,
+			`INSERT INTO vex_documents (name, format, document_id, author, sha256, source) VALUES (?, ?, ?, ?, ?, ?)`,
+			doc.Name, doc.Format, doc.DocumentID, doc.Author, doc.SHA256, doc.Source,
+		)
+		if err != nil {
+			if IsDuplicate(err) {
+				return ctxerr.Wrap(ctx, alreadyExists("VEXDocument", doc.Name), "found duplicate")
+			}
+			return ctxerr.Wrap(ctx, err, "insert VEX document")
+		}
+		lastID, _ := res.LastInsertId()
+		id = uint(lastID) //nolint:gosec // dismiss G115
+
+		generateValueArgs := func(st fleet.VEXStatement) (string, []any) {
+			return "(?, ?, ?, ?, ?, ?),", []any{id, st.CVE, st.Product, st.Status, st.Justification, st.ImpactStatement}
+		}
+		executeBatch := func(values string, args []any) error {
+			stmt := `INSERT INTO vex_statements (vex_document_id, cve, product, status, justification, impact_statement) VALUES ` +
+				strings.TrimSuffix(values, ",")
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "insert VEX statements")
+			}
+			return nil
+		}
+		return batchProcessDB(doc.Statements, vexBatchSize, generateValueArgs, executeBatch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ds.vexDocumentDB(ctx, ds.writer(ctx), id)
+}
+
+func (ds *Datastore) VEXDocument(ctx context.Context, id uint) (*fleet.VEXDocument, error) {
+	return ds.vexDocumentDB(ctx, ds.reader(ctx), id)
+}
+
+func (ds *Datastore) vexDocumentDB(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.VEXDocument, error) {
+	var doc fleet.VEXDocument
+	if err := sqlx.GetContext(ctx, q, &doc, vexDocumentSelect+` WHERE vd.id = ?`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("VEXDocument").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get VEX document")
+	}
+	return &doc, nil
+}
+
+func (ds *Datastore) ListVEXDocuments(ctx context.Context) ([]*fleet.VEXDocument, error) {
+	docs := []*fleet.VEXDocument{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &docs, vexDocumentSelect+` ORDER BY vd.name`); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list VEX documents")
+	}
+	return docs, nil
+}
+
+func (ds *Datastore) DeleteVEXDocument(ctx context.Context, id uint) error {
+	// statements and suppressions are deleted by the foreign keys
+	res, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM vex_documents WHERE id = ?`, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "delete VEX document")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("VEXDocument").WithID(id))
+	}
+	return nil
+}
+
+func (ds *Datastore) ListVEXStatements(ctx context.Context, statuses []fleet.VEXStatus) ([]fleet.VEXStatement, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+	stmt, args, err := sqlx.In(`
+		SELECT id, vex_document_id, cve, product, status, justification, impact_statement
+		FROM vex_statements
+		WHERE status IN (?)
+		ORDER BY vex_document_id, id`, statuses)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "build list VEX statements query")
+	}
+	var statements []fleet.VEXStatement
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &statements, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list VEX statements")
+	}
+	return statements, nil
+}
+
+func (ds *Datastore) ListVEXCandidates(ctx context.Context, cves []string) ([]fleet.VEXCandidate, error) {
+	if len(cves) == 0 {
+		return nil, nil
+	}
+	stmt, args, err := sqlx.In(`
+		SELECT
+			s.id, s.name, s.version, s.source, s.vendor, s.`+"`release`"+`, s.arch, s.extension_id,
+			scv.cve, COALESCE(scp.cpe, '') AS cpe
+		FROM software_cve scv
+		JOIN software s ON s.id = scv.software_id
+		LEFT JOIN software_cpe scp ON scp.software_id = s.id
+		WHERE scv.cve IN (?)`, cves)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "build list VEX candidates query")
+	}
+	var candidates []fleet.VEXCandidate
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &candidates, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list VEX candidates")
+	}
+	return candidates, nil
+}
+
+func (ds *Datastore) ReplaceVEXSuppressions(ctx context.Context, suppressions []fleet.VEXSuppression) error {
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM vex_suppressions`); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete VEX suppressions")
+		}
+
+		generateInsertArgs := func(s fleet.VEXSuppression) (string, []any) {
+			return "(?, ?, ?),", []any{s.SoftwareID, s.CVE, s.VEXStatementID}
+		}
+		executeInsert := func(values string, args []any) error {
+			stmt := `INSERT INTO vex_suppressions (software_id, cve, vex_statement_id) VALUES ` + strings.TrimSuffix(values, ",")
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "insert VEX suppressions")
+			}
+			return nil
+		}
+		if err := batchProcessDB(suppressions, vexBatchSize, generateInsertArgs, executeInsert); err != nil {
+			return err
+		}
+
+		generateDeleteArgs := func(s fleet.VEXSuppression) (string, []any) {
+			return "(?, ?),", []any{s.SoftwareID, s.CVE}
+		}
+		executeDelete := func(values string, args []any) error {
+			stmt := `DELETE FROM software_cve WHERE (software_id, cve) IN (` + strings.TrimSuffix(values, ",") + `)`
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "delete suppressed software vulnerabilities")
+			}
+			return nil
+		}
+		return batchProcessDB(suppressions, vexBatchSize, generateDeleteArgs, executeDelete)
+	})
+}
+
+var vexSuppressionAllowedOrderKeys = common_mysql.OrderKeyAllowlist{
+	"cve":               "vsu.cve",
+	"software_id":       "vsu.software_id",
+	"software_name":     "s.name",
+	"vex_document_name": "vd.name",
+	"created_at":        "vsu.created_at",
+}
+
+func (ds *Datastore) ListVEXSuppressions(ctx context.Context, opts fleet.VEXSuppressionListOptions) ([]fleet.VEXSuppression, *fleet.PaginationMetadata, error) {
+	stmt := `
+		SELECT
+			vsu.software_id, s.name AS software_name, s.version AS software_version, vsu.cve, vsu.vex_statement_id,
+			vd.id AS vex_document_id, vd.name AS vex_document_name,
+			vst.status, vst.justification, vst.impact_statement, vsu.created_at
+		FROM vex_suppressions vsu
+		JOIN vex_statements vst ON vst.id = vsu.vex_statement_id
+		JOIN vex_documents vd ON vd.id = vst.vex_document_id
+		JOIN software s ON s.id = vsu.software_id
+		WHERE true`
+	var args []any
+	if opts.CVE != "" {
+		stmt += ` AND vsu.cve = ?`
+		args = append(args, opts.CVE)
+	}
+	if opts.SoftwareID != nil {
+		stmt += ` AND vsu.software_id = ?`
+		args = append(args, *opts.SoftwareID)
+	}
+	if opts.VEXDocumentID != nil {
+		stmt += ` AND vd.id = ?`
+		args = append(args, *opts.VEXDocumentID)
+	}
+	stmt, args = searchLike(stmt, args, opts.ListOptions.MatchQuery, "vsu.cve", "s.name")
+
+	stmt, args, err := appendListOptionsWithCursorToSQLSecure(stmt, args, &opts.ListOptions, vexSuppressionAllowedOrderKeys)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "apply list options")
+	}
+
+	suppressions := []fleet.VEXSuppression{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &suppressions, stmt, args...); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "list VEX suppressions")
+	}
+
+	var meta *fleet.PaginationMetadata
+	if opts.ListOptions.IncludeMetadata {
+		meta = &fleet.PaginationMetadata{HasPreviousResults: opts.ListOptions.Page > 0}
+		// `appendListOptionsWithCursorToSQL` fetches one more row than requested
+		// to know if there are more results.
+		if len(suppressions) > int(opts.ListOptions.PerPage) { //nolint:gosec // dismiss G115
+			meta.HasNextResults = true
+			suppressions = suppressions[:len(suppressions)-1]
+		}
+	}
+	return suppressions, meta, nil
+}
@@ -0,0 +1,170 @@
+package mysql
+
+import (
+	"crypto/md5" //nolint:gosec // used only for test software checksums
+	"fmt"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVEX(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"Documents", testVEXDocuments},
+		{"Suppressions", testVEXSuppressions},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testVEXDocuments(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	doc, err := ds.NewVEXDocument(ctx, &fleet.VEXDocument{
+		Name:       "acme.json",
+		Format:     fleet.VEXFormatOpenVEX,
+		DocumentID: "https://acme.example/vex/1",
+		Author:     "ACME",
+		SHA256:     "abc",
+		Source:     fleet.VEXDocumentSourceAPI,
+		Statements: []fleet.VEXStatement{
+			{CVE: "CVE-2024-0001", Product: "pkg:deb/debian/curl", Status: fleet.VEXStatusNotAffected, Justification: "vulnerable_code_not_present"},
+			{CVE: "CVE-2024-0002", Product: "pkg:deb/debian/curl@7.88.1", Status: fleet.VEXStatusAffected},
+		},
+	})
+	require.NoError(t, err)
+	require.NotZero(t, doc.ID)
+	require.Equal(t, "ACME", doc.Author)
+	require.EqualValues(t, 2, doc.StatementsCount)
+	require.Zero(t, doc.SuppressedCount)
+	require.False(t, doc.CreatedAt.IsZero())
+
+	_, err = ds.NewVEXDocument(ctx, &fleet.VEXDocument{Name: "acme.json", Format: fleet.VEXFormatCSAF, Source: fleet.VEXDocumentSourceDirectory})
+	var aee fleet.AlreadyExistsError
+	require.ErrorAs(t, err, &aee)
+
+	other, err := ds.NewVEXDocument(ctx, &fleet.VEXDocument{Name: "other.json", Format: fleet.VEXFormatCSAF, Source: fleet.VEXDocumentSourceDirectory})
+	require.NoError(t, err)
+	require.Zero(t, other.StatementsCount)
+
+	docs, err := ds.ListVEXDocuments(ctx)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	require.Equal(t, other.ID, docs[0].ID)
+	require.Equal(t, doc.ID, docs[1].ID)
+
+	statements, err := ds.ListVEXStatements(ctx, fleet.VEXSuppressingStatuses)
+	require.NoError(t, err)
+	require.Len(t, statements, 1)
+	require.Equal(t, "CVE-2024-0001", statements[0].CVE)
+	require.Equal(t, doc.ID, statements[0].VEXDocumentID)
+	require.Equal(t, "vulnerable_code_not_present", statements[0].Justification)
+
+	require.NoError(t, ds.DeleteVEXDocument(ctx, doc.ID))
+	_, err = ds.VEXDocument(ctx, doc.ID)
+	require.True(t, fleet.IsNotFound(err))
+	require.True(t, fleet.IsNotFound(ds.DeleteVEXDocument(ctx, doc.ID)))
+
+	statements, err = ds.ListVEXStatements(ctx, fleet.VEXSuppressingStatuses)
+	require.NoError(t, err)
+	require.Empty(t, statements)
+}
+
+func testVEXSuppressions(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	insertSoftware := func(name, version string) uint {
+		cksum := md5.Sum([]byte(name + version)) //nolint:gosec // test checksum
+		res, err := ds.writer(ctx).ExecContext(ctx,
+			`INSERT INTO software (name, version, source, checksum) VALUES (?, ?, 'deb_packages', ?)`, name, version, cksum[:])
+		require.NoError(t, err)
+		id, _ := res.LastInsertId()
+		return uint(id) //nolint:gosec // dismiss G115
+	}
+	curl := insertSoftware("curl", "7.88.1")
+	openssl := insertSoftware("openssl", "3.0.11")
+	_, err := ds.UpsertSoftwareCPEs(ctx, []fleet.SoftwareCPE{{SoftwareID: openssl, CPE: "cpe:2.3:a:openssl:openssl:3.0.11:*:*:*:*:*:*:*"}})
+	require.NoError(t, err)
+	_, err = ds.InsertSoftwareVulnerabilities(ctx, []fleet.SoftwareVulnerability{
+		{SoftwareID: curl, CVE: "CVE-2024-0001"},
+		{SoftwareID: curl, CVE: "CVE-2024-0002"},
+		{SoftwareID: openssl, CVE: "CVE-2024-0001"},
+	}, fleet.NVDSource)
+	require.NoError(t, err)
+
+	candidates, err := ds.ListVEXCandidates(ctx, []string{"CVE-2024-0001"})
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+	byName := make(map[string]fleet.VEXCandidate)
+	for _, c := range candidates {
+		byName[c.Name] = c
+	}
+	require.Equal(t, "7.88.1", byName["curl"].Version)
+	require.Equal(t, "deb_packages", byName["curl"].Source)
+	require.Empty(t, byName["curl"].CPE)
+	require.Equal(t, "cpe:2.3:a:openssl:openssl:3.0.11:*:*:*:*:*:*:*", byName["openssl"].CPE)
+
+	doc, err := ds.NewVEXDocument(ctx, &fleet.VEXDocument{
+		Name:   "acme.json",
+		Format: fleet.VEXFormatOpenVEX,
+		Source: fleet.VEXDocumentSourceAPI,
+		Statements: []fleet.VEXStatement{
+			{CVE: "CVE-2024-0001", Product: "pkg:deb/debian/curl", Status: fleet.VEXStatusNotAffected, ImpactStatement: "not reachable"},
+		},
+	})
+	require.NoError(t, err)
+	statements, err := ds.ListVEXStatements(ctx, fleet.VEXSuppressingStatuses)
+	require.NoError(t, err)
+	require.Len(t, statements, 1)
+
+	require.NoError(t, ds.ReplaceVEXSuppressions(ctx, []fleet.VEXSuppression{
+		{SoftwareID: curl, CVE: "CVE-2024-0001", VEXStatementID: statements[0].ID},
+	}))
+
+	// the suppressed vulnerability is deleted, the others are kept
+	var cves []string
+	require.NoError(t, ds.writer(ctx).SelectContext(ctx, &cves,
+		`SELECT CONCAT(software_id, ':', cve) FROM software_cve ORDER BY software_id, cve`))
+	require.ElementsMatch(t, []string{
+		fmt.Sprintf("%d:CVE-2024-0002", curl),
+		fmt.Sprintf("%d:CVE-2024-0001", openssl),
+	}, cves)
+
+	suppressions, meta, err := ds.ListVEXSuppressions(ctx, fleet.VEXSuppressionListOptions{ListOptions: fleet.ListOptions{IncludeMetadata: true}})
+	require.NoError(t, err)
+	require.False(t, meta.HasNextResults)
+	require.Len(t, suppressions, 1)
+	require.Equal(t, curl, suppressions[0].SoftwareID)
+	require.Equal(t, "curl", suppressions[0].SoftwareName)
+	require.Equal(t, "7.88.1", suppressions[0].SoftwareVersion)
+	require.Equal(t, "acme.json", suppressions[0].VEXDocumentName)
+	require.Equal(t, fleet.VEXStatusNotAffected, suppressions[0].Status)
+	require.Equal(t, "not reachable", suppressions[0].ImpactStatement)
+
+	suppressions, _, err = ds.ListVEXSuppressions(ctx, fleet.VEXSuppressionListOptions{CVE: "CVE-2024-0002"})
+	require.NoError(t, err)
+	require.Empty(t, suppressions)
+	suppressions, _, err = ds.ListVEXSuppressions(ctx, fleet.VEXSuppressionListOptions{SoftwareID: &curl, VEXDocumentID: &doc.ID})
+	require.NoError(t, err)
+	require.Len(t, suppressions, 1)
+
+	doc, err = ds.VEXDocument(ctx, doc.ID)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, doc.SuppressedCount)
+
+	// replacing with no suppressions clears them
+	require.NoError(t, ds.ReplaceVEXSuppressions(ctx, nil))
+	suppressions, _, err = ds.ListVEXSuppressions(ctx, fleet.VEXSuppressionListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, suppressions)
+}
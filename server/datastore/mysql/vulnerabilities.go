@@ -315,7 +315,7 @@ func buildListVulnerabilitiesSQL(opt *fleet.VulnListOptions) (string, []any, err
 	}
 
 	_, cmOrderKey := vulnerabilitiesCMOrderKeys[opt.ListOptions.OrderKey]
-	needCMInInner := cmOrderKey || opt.KnownExploit
+	needCMInInner := cmOrderKey || opt.HasCVEMetaFilters()
 
 	var inner strings.Builder
 	inner.WriteString(`
@@ -350,11 +350,8 @@ func buildListVulnerabilitiesSQL(opt *fleet.VulnListOptions) (string, []any, err
 		inner.WriteString(" AND vhc.global_stats = 0 AND vhc.team_id = ?")
 		args = append(args, *opt.TeamID)
 	}
-	if opt.KnownExploit {
-		inner.WriteString(" AND cm.cisa_known_exploit = 1")
-	}
-
-	innerSQL := inner.String()
+	innerSQL, args := appendVulnCVEMetaFilters(inner.String(), args, *opt)
+	innerSQL, args = appendVulnExceptionsFilter(innerSQL, args, opt.TeamID)
 	if match := opt.ListOptions.MatchQuery; match != "" {
 		innerSQL, args = searchLike(innerSQL, args, match, "vhc.cve")
 	}
@@ -490,9 +487,8 @@ func buildListVulnerabilitiesLegacySQL(opt *fleet.VulnListOptions) (string, []an
 		selectStmt += " AND vhc.global_stats = 0 AND vhc.team_id = ?"
 		args = append(args, *opt.TeamID)
 	}
-	if opt.KnownExploit {
-		selectStmt += " AND cm.cisa_known_exploit = 1"
-	}
+	selectStmt, args = appendVulnCVEMetaFilters(selectStmt, args, *opt)
+	selectStmt, args = appendVulnExceptionsFilter(selectStmt, args, opt.TeamID)
 	if match := opt.ListOptions.MatchQuery; match != "" {
 		selectStmt, args = searchLike(selectStmt, args, match, "vhc.cve")
 	}
@@ -507,6 +503,60 @@ func buildListVulnerabilitiesLegacySQL(opt *fleet.VulnListOptions) (string, []an
 	return appendListOptionsWithCursorToSQLSecure(selectStmt, args, &opt.ListOptions, vulnerabilitiesAllowedOrderKeys)
 }
 
+// appendVulnCVEMetaFilters appends the conditions filtering vulnerabilities by
+// their cve_meta (aliased cm) to stmt.
+func appendVulnCVEMetaFilters(stmt string, args []any, opt fleet.VulnListOptions) (string, []any) {
+	if opt.KnownExploit {
+		stmt += " AND cm.cisa_known_exploit = 1"
+	}
+	if opt.MinimumEPSS > 0 {
+		stmt += " AND cm.epss_probability >= ?"
+		args = append(args, opt.MinimumEPSS)
+	}
+	if opt.MaximumEPSS > 0 {
+		stmt += " AND cm.epss_probability <= ?"
+		args = append(args, opt.MaximumEPSS)
+	}
+	return stmt, args
+}
+
+// appendVulnExceptionsFilter appends the condition excluding the CVEs (of
+// vulnerability_host_counts, aliased vhc) hidden by an active vulnerability
+// exception, for all fleets or for the listed fleet. An exception limited to
+// a software title only hides the CVE if no operating system is affected, and
+// if all the affected software is covered by exceptions.
+func appendVulnExceptionsFilter(stmt string, args []any, teamID *uint) (string, []any) {
+	teamCond := func(alias string) string {
+		if teamID == nil {
+			return alias + ".team_id IS NULL"
+		}
+		args = append(args, *teamID)
+		return "(" + alias + ".team_id IS NULL OR " + alias + ".team_id = ?)"
+	}
+
+	stmt += `
+		AND NOT EXISTS (
+			SELECT 1 FROM vulnerability_exceptions ve
+			WHERE ve.cve = vhc.cve AND ve.expires_at > NOW(6) AND ` + teamCond("ve") + `
+			AND (
+				ve.software_title_id IS NULL
+				OR (
+					NOT EXISTS (SELECT 1 FROM operating_system_vulnerabilities osv WHERE osv.cve = vhc.cve)
+					AND NOT EXISTS (
+						SELECT 1 FROM software_cve sc
+						JOIN software s ON s.id = sc.software_id
+						WHERE sc.cve = vhc.cve AND NOT EXISTS (
+							SELECT 1 FROM vulnerability_exceptions ve2
+							WHERE ve2.cve = sc.cve AND ve2.expires_at > NOW(6) AND ` + teamCond("ve2") + `
+							AND (ve2.software_title_id IS NULL OR ve2.software_title_id = s.title_id)
+						)
+					)
+				)
+			)
+		)`
+	return stmt, args
+}
+
 func (ds *Datastore) CountVulnerabilities(ctx context.Context, opt fleet.VulnListOptions) (uint, error) {
 	// vhc.cve is already unique within a (global_stats, team_id) scope due to
 	// the existing UNIQUE KEY (cve, team_id, global_stats), so COUNT(*) gives
@@ -516,7 +566,7 @@ func (ds *Datastore) CountVulnerabilities(ctx context.Context, opt fleet.VulnLis
 		SELECT COUNT(*)
 		FROM vulnerability_host_counts vhc
 		`
-	if opt.KnownExploit {
+	if opt.HasCVEMetaFilters() {
 		selectStmt += `LEFT JOIN cve_meta cm ON cm.cve = vhc.cve
 		`
 	}
@@ -533,9 +583,8 @@ func (ds *Datastore) CountVulnerabilities(ctx context.Context, opt fleet.VulnLis
 		selectStmt += " AND vhc.global_stats = 0 AND vhc.team_id = ?"
 		args = append(args, *opt.TeamID)
 	}
-	if opt.KnownExploit {
-		selectStmt += " AND cm.cisa_known_exploit = 1"
-	}
+	selectStmt, args = appendVulnCVEMetaFilters(selectStmt, args, opt)
+	selectStmt, args = appendVulnExceptionsFilter(selectStmt, args, opt.TeamID)
 	if match := opt.ListOptions.MatchQuery; match != "" {
 		selectStmt, args = searchLike(selectStmt, args, match, "vhc.cve")
 	}
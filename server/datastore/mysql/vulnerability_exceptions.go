@@ -0,0 +1,337 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	common_mysql "github.com/fleetdm/fleet/v4/server/platform/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+const vulnerabilityExceptionSelect = `
+	SELECT
+		ve.id, ve.cve, ve.software_title_id, ve.team_id, ve.reason, ve.owner, ve.expires_at,
+		ve.expires_at <= NOW(6) AS expired, ve.created_at, ve.updated_at,
+		st.name AS software_title_name, t.name AS team_name
+	FROM vulnerability_exceptions ve
+	LEFT JOIN software_titles st ON st.id = ve.software_title_id
+	LEFT JOIN teams t ON t.id = ve.team_id`
+
+const insertVulnerabilityExceptionStmt = `
+	INSERT INTO vulnerability_exceptions
+		(cve, software_title_id, team_id, all_or_software_title_id, global_or_team_id, reason, owner, expires_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+func (ds *Datastore) NewVulnerabilityException(ctx context.Context, e *fleet.VulnerabilityException) (*fleet.VulnerabilityException, error) {
+	res, err := ds.writer(ctx).ExecContext(ctx, insertVulnerabilityExceptionStmt, vulnerabilityExceptionInsertArgs(e)...)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, vulnerabilityExceptionWriteError(err, e), "insert vulnerability exception")
+	}
+	id, _ := res.LastInsertId()
+	return ds.vulnerabilityExceptionDB(ctx, ds.writer(ctx), uint(id)) //nolint:gosec // dismiss G115
+}
+
+func vulnerabilityExceptionInsertArgs(e *fleet.VulnerabilityException) []any {
+	var allOrTitleID, globalOrTeamID uint
+	if e.SoftwareTitleID != nil {
+		allOrTitleID = *e.SoftwareTitleID
+	}
+	if e.TeamID != nil {
+		globalOrTeamID = *e.TeamID
+	}
+	return []any{e.CVE, e.SoftwareTitleID, e.TeamID, allOrTitleID, globalOrTeamID, e.Reason, e.Owner, e.ExpiresAt}
+}
+
+func vulnerabilityExceptionWriteError(err error, e *fleet.VulnerabilityException) error {
+	switch {
+	case IsDuplicate(err):
+		return alreadyExists("VulnerabilityException", e.CVE)
+	case isChildForeignKeyError(err):
+		return foreignKey("vulnerability_exceptions", fmt.Sprintf("cve=%s", e.CVE))
+	}
+	return err
+}
+
+func (ds *Datastore) VulnerabilityException(ctx context.Context, id uint) (*fleet.VulnerabilityException, error) {
+	return ds.vulnerabilityExceptionDB(ctx, ds.reader(ctx), id)
+}
+
+func (ds *Datastore) vulnerabilityExceptionDB(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.VulnerabilityException, error) {
+	var e fleet.VulnerabilityException
+	if err := sqlx.GetContext(ctx, q, &e, vulnerabilityExceptionSelect+` WHERE ve.id = ?`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("VulnerabilityException").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get vulnerability exception")
+	}
+	return &e, nil
+}
+
+var vulnerabilityExceptionAllowedOrderKeys = common_mysql.OrderKeyAllowlist{
+	"id":         "ve.id",
+	"cve":        "ve.cve",
+	"owner":      "ve.owner",
+	"expires_at": "ve.expires_at",
+	"created_at": "ve.created_at",
+}
+
+func (ds *Datastore) ListVulnerabilityExceptions(ctx context.Context, opts fleet.VulnerabilityExceptionListOptions) ([]fleet.VulnerabilityException, *fleet.PaginationMetadata, error) {
+	stmt := vulnerabilityExceptionSelect + ` WHERE true`
+	var args []any
+	if opts.CVE != "" {
+		stmt += ` AND ve.cve = ?`
+		args = append(args, opts.CVE)
+	}
+	if opts.TeamID != nil {
+		stmt += ` AND ve.team_id = ?`
+		args = append(args, *opts.TeamID)
+	}
+	if opts.ActiveOnly {
+		stmt += ` AND ve.expires_at > NOW(6)`
+	}
+	stmt, args = searchLike(stmt, args, opts.ListOptions.MatchQuery, "ve.cve", "ve.owner", "st.name")
+
+	stmt, args, err := appendListOptionsWithCursorToSQLSecure(stmt, args, &opts.ListOptions, vulnerabilityExceptionAllowedOrderKeys)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "apply list options")
+	}
+
+	exceptions := []fleet.VulnerabilityException{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &exceptions, stmt, args...); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "list vulnerability exceptions")
+	}
+
+	var meta *fleet.PaginationMetadata
+	if opts.ListOptions.IncludeMetadata {
+		meta = &fleet.PaginationMetadata{HasPreviousResults: opts.ListOptions.Page > 0}
+		// `appendListOptionsWithCursorToSQL` fetches one more row than requested
+		// to know if there are more results.
+		if len(exceptions) > int(opts.ListOptions.PerPage) { //nolint:gosec // dismiss G115
+			meta.HasNextResults = true
+			exceptions = exceptions[:len(exceptions)-1]
+		}
+	}
+	return exceptions, meta, nil
+}
+
+// updateVulnerabilityExceptionStmt updates the modifiable fields of an
+// exception. Assignments are evaluated left to right, so the expiry activity
+// flag is reset before expires_at is changed, allowing an extended exception
+// to expire again.
+const updateVulnerabilityExceptionStmt = `
+	UPDATE vulnerability_exceptions SET
+		reason = ?,
+		owner = ?,
+		expiry_activity_created = IF(expires_at = ?, expiry_activity_created, 0),
+		expires_at = ?
+	WHERE id = ?`
+
+func (ds *Datastore) SaveVulnerabilityException(ctx context.Context, e *fleet.VulnerabilityException) (*fleet.VulnerabilityException, error) {
+	if _, err := ds.writer(ctx).ExecContext(ctx, updateVulnerabilityExceptionStmt,
+		e.Reason, e.Owner, e.ExpiresAt, e.ExpiresAt, e.ID); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "update vulnerability exception")
+	}
+	// read back on the writer, no rows affected doesn't distinguish a missing
+	// exception from an unchanged one
+	return ds.vulnerabilityExceptionDB(ctx, ds.writer(ctx), e.ID)
+}
+
+func (ds *Datastore) DeleteVulnerabilityException(ctx context.Context, id uint) error {
+	res, err := ds.writer(ctx).ExecContext(ctx, `DELETE FROM vulnerability_exceptions WHERE id = ?`, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "delete vulnerability exception")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("VulnerabilityException").WithID(id))
+	}
+	return nil
+}
+
+func (ds *Datastore) ApplyVulnerabilityExceptions(ctx context.Context, exceptions []fleet.VulnerabilityException) (created, edited, deleted []fleet.VulnerabilityException, err error) {
+	err = ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		created, edited, deleted = nil, nil, nil
+
+		var existing []fleet.VulnerabilityException
+		if err := sqlx.SelectContext(ctx, tx, &existing, vulnerabilityExceptionSelect); err != nil {
+			return ctxerr.Wrap(ctx, err, "list existing vulnerability exceptions")
+		}
+		byScope := make(map[string]fleet.VulnerabilityException, len(existing))
+		for _, e := range existing {
+			byScope[e.ScopeKey()] = e
+		}
+
+		incoming := make(map[string]struct{}, len(exceptions))
+		for _, e := range exceptions {
+			incoming[e.ScopeKey()] = struct{}{}
+
+			prev, ok := byScope[e.ScopeKey()]
+			if !ok {
+				res, err := tx.ExecContext(ctx, insertVulnerabilityExceptionStmt, vulnerabilityExceptionInsertArgs(&e)...)
+				if err != nil {
+					return ctxerr.Wrap(ctx, vulnerabilityExceptionWriteError(err, &e), "insert vulnerability exception")
+				}
+				id, _ := res.LastInsertId()
+				e.ID = uint(id) //nolint:gosec // dismiss G115
+				created = append(created, e)
+				continue
+			}
+
+			e.ID = prev.ID
+			if prev.Reason == e.Reason && prev.Owner == e.Owner && prev.ExpiresAt.Equal(e.ExpiresAt) {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, updateVulnerabilityExceptionStmt,
+				e.Reason, e.Owner, e.ExpiresAt, e.ExpiresAt, e.ID); err != nil {
+				return ctxerr.Wrap(ctx, err, "update vulnerability exception")
+			}
+			edited = append(edited, e)
+		}
+
+		for _, e := range existing {
+			if _, ok := incoming[e.ScopeKey()]; !ok {
+				deleted = append(deleted, e)
+			}
+		}
+		if len(deleted) > 0 {
+			ids := make([]uint, 0, len(deleted))
+			for _, e := range deleted {
+				ids = append(ids, e.ID)
+			}
+			stmt, args, err := sqlx.In(`DELETE FROM vulnerability_exceptions WHERE id IN (?)`, ids)
+			if err != nil {
+				return ctxerr.Wrap(ctx, err, "build delete vulnerability exceptions query")
+			}
+			if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+				return ctxerr.Wrap(ctx, err, "delete vulnerability exceptions")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return created, edited, deleted, nil
+}
+
+func (ds *Datastore) ExpireVulnerabilityExceptions(ctx context.Context, now time.Time) ([]fleet.VulnerabilityException, error) {
+	var expired []fleet.VulnerabilityException
+	err := ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		expired = nil
+		if err := sqlx.SelectContext(ctx, tx, &expired, vulnerabilityExceptionSelect+`
+			WHERE ve.expires_at <= ? AND ve.expiry_activity_created = 0
+			FOR UPDATE`, now); err != nil {
+			return ctxerr.Wrap(ctx, err, "select expired vulnerability exceptions")
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, 0, len(expired))
+		for _, e := range expired {
+			ids = append(ids, e.ID)
+		}
+		stmt, args, err := sqlx.In(`UPDATE vulnerability_exceptions SET expiry_activity_created = 1 WHERE id IN (?)`, ids)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "build expire vulnerability exceptions query")
+		}
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return ctxerr.Wrap(ctx, err, "expire vulnerability exceptions")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+func (ds *Datastore) FilterExceptedSoftwareVulnerabilities(ctx context.Context, vulns []fleet.SoftwareVulnerability) ([]fleet.SoftwareVulnerability, error) {
+	if len(vulns) == 0 {
+		return vulns, nil
+	}
+
+	// only the exceptions applying to all fleets can be enforced here, the
+	// others depend on the team of each host
+	type softwareCVE struct {
+		SoftwareID uint   `db:"software_id"`
+		CVE        string `db:"cve"`
+	}
+	excepted := make(map[softwareCVE]struct{})
+	const batchSize = 1000
+	for batch := range slices.Chunk(vulns, batchSize) {
+		var values strings.Builder
+		args := make([]any, 0, 2*len(batch))
+		for _, v := range batch {
+			values.WriteString("(?, ?),")
+			args = append(args, v.SoftwareID, v.CVE)
+		}
+		stmt := `
+			SELECT s.id AS software_id, ve.cve
+			FROM software s
+			JOIN vulnerability_exceptions ve
+				ON (ve.software_title_id IS NULL OR ve.software_title_id = s.title_id)
+				AND ve.team_id IS NULL
+				AND ve.expires_at > NOW(6)
+			WHERE (s.id, ve.cve) IN (` + strings.TrimSuffix(values.String(), ",") + `)`
+		var rows []softwareCVE
+		if err := sqlx.SelectContext(ctx, ds.reader(ctx), &rows, stmt, args...); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "select excepted software vulnerabilities")
+		}
+		for _, r := range rows {
+			excepted[r] = struct{}{}
+		}
+	}
+	if len(excepted) == 0 {
+		return vulns, nil
+	}
+
+	filtered := make([]fleet.SoftwareVulnerability, 0, len(vulns))
+	for _, v := range vulns {
+		if _, ok := excepted[softwareCVE{SoftwareID: v.SoftwareID, CVE: v.CVE}]; !ok {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered, nil
+}
+
+func (ds *Datastore) VulnerabilityExceptedHostIDs(ctx context.Context, cve string, softwareIDs []uint) ([]uint, error) {
+	if len(softwareIDs) == 0 {
+		return nil, nil
+	}
+
+	// a host is excepted if each of its vulnerable software is covered by an
+	// exception, so the hosts with vulnerable software not covered are
+	// excluded
+	stmt, args, err := sqlx.In(`
+		SELECT DISTINCT hs.host_id
+		FROM host_software hs
+		JOIN hosts h ON h.id = hs.host_id
+		WHERE hs.software_id IN (?)
+		AND NOT EXISTS (
+			SELECT 1
+			FROM host_software hs2
+			JOIN software s ON s.id = hs2.software_id
+			WHERE hs2.host_id = hs.host_id AND hs2.software_id IN (?)
+			AND NOT EXISTS (
+				SELECT 1 FROM vulnerability_exceptions ve
+				WHERE ve.cve = ? AND ve.expires_at > NOW(6)
+				AND (ve.software_title_id IS NULL OR ve.software_title_id = s.title_id)
+				AND (ve.team_id IS NULL OR ve.team_id = h.team_id)
+			)
+		)`, softwareIDs, softwareIDs, cve)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "build excepted host IDs query")
+	}
+	var hostIDs []uint
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &hostIDs, stmt, args...); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "select excepted host IDs")
+	}
+	return hostIDs, nil
+}
@@ -0,0 +1,305 @@
+package mysql
+
+import (
+	"crypto/md5" //nolint:gosec // used only for test software checksums
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVulnerabilityExceptions(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"CRUD", testVulnerabilityExceptionsCRUD},
+		{"Apply", testApplyVulnerabilityExceptions},
+		{"Expire", testExpireVulnerabilityExceptions},
+		{"Enforcement", testVulnerabilityExceptionsEnforcement},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+// insertVulnerabilityExceptionTestSoftware inserts a software title with a
+// single software version, and returns the IDs of the title and software.
+func insertVulnerabilityExceptionTestSoftware(t *testing.T, ds *Datastore, name, version string) (titleID, softwareID uint) {
+	ctx := t.Context()
+	res, err := ds.writer(ctx).ExecContext(ctx,
+		`INSERT INTO software_titles (name, source) VALUES (?, 'deb_packages')`, name)
+	require.NoError(t, err)
+	id, _ := res.LastInsertId()
+	titleID = uint(id) //nolint:gosec // dismiss G115
+
+	cksum := md5.Sum([]byte(name + version)) //nolint:gosec // test checksum
+	res, err = ds.writer(ctx).ExecContext(ctx,
+		`INSERT INTO software (name, version, source, checksum, title_id) VALUES (?, ?, 'deb_packages', ?, ?)`,
+		name, version, cksum[:], titleID)
+	require.NoError(t, err)
+	id, _ = res.LastInsertId()
+	softwareID = uint(id) //nolint:gosec // dismiss G115
+	return titleID, softwareID
+}
+
+func testVulnerabilityExceptionsCRUD(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "Workstations"})
+	require.NoError(t, err)
+	curlTitle, _ := insertVulnerabilityExceptionTestSoftware(t, ds, "curl", "7.88.1")
+
+	future := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Microsecond)
+	global, err := ds.NewVulnerabilityException(ctx, &fleet.VulnerabilityException{
+		CVE:       "CVE-2024-0001",
+		Reason:    "Not exploitable",
+		Owner:     "secops",
+		ExpiresAt: future,
+	})
+	require.NoError(t, err)
+	require.NotZero(t, global.ID)
+	require.False(t, global.Expired)
+	require.Nil(t, global.TeamName)
+
+	scoped, err := ds.NewVulnerabilityException(ctx, &fleet.VulnerabilityException{
+		CVE:             "CVE-2024-0001",
+		SoftwareTitleID: &curlTitle,
+		TeamID:          &team.ID,
+		Reason:          "Patch scheduled",
+		Owner:           "it",
+		ExpiresAt:       future,
+	})
+	require.NoError(t, err)
+	require.Equal(t, ptr.String("curl"), scoped.SoftwareTitleName)
+	require.Equal(t, ptr.String("Workstations"), scoped.TeamName)
+
+	// there's only one exception per CVE, software title and team
+	_, err = ds.NewVulnerabilityException(ctx, &fleet.VulnerabilityException{
+		CVE:       "CVE-2024-0001",
+		Reason:    "Duplicate",
+		Owner:     "secops",
+		ExpiresAt: future,
+	})
+	var aee fleet.AlreadyExistsError
+	require.ErrorAs(t, err, &aee)
+
+	_, err = ds.NewVulnerabilityException(ctx, &fleet.VulnerabilityException{
+		CVE:       "CVE-2024-0002",
+		TeamID:    ptr.Uint(team.ID + 1000),
+		Reason:    "Unknown team",
+		Owner:     "secops",
+		ExpiresAt: future,
+	})
+	var fke fleet.ForeignKeyError
+	require.ErrorAs(t, err, &fke)
+
+	expired, err := ds.NewVulnerabilityException(ctx, &fleet.VulnerabilityException{
+		CVE:       "CVE-2023-0001",
+		Reason:    "Old",
+		Owner:     "secops",
+		ExpiresAt: time.Now().Add(-time.Hour).UTC().Truncate(time.Microsecond),
+	})
+	require.NoError(t, err)
+	require.True(t, expired.Expired)
+
+	list, meta, err := ds.ListVulnerabilityExceptions(ctx, fleet.VulnerabilityExceptionListOptions{ListOptions: fleet.ListOptions{IncludeMetadata: true}})
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	require.False(t, meta.HasNextResults)
+
+	list, _, err = ds.ListVulnerabilityExceptions(ctx, fleet.VulnerabilityExceptionListOptions{ActiveOnly: true})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+
+	list, _, err = ds.ListVulnerabilityExceptions(ctx, fleet.VulnerabilityExceptionListOptions{TeamID: &team.ID})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, scoped.ID, list[0].ID)
+
+	list, _, err = ds.ListVulnerabilityExceptions(ctx, fleet.VulnerabilityExceptionListOptions{CVE: "CVE-2023-0001"})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, expired.ID, list[0].ID)
+
+	list, _, err = ds.ListVulnerabilityExceptions(ctx, fleet.VulnerabilityExceptionListOptions{ListOptions: fleet.ListOptions{MatchQuery: "curl"}})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, scoped.ID, list[0].ID)
+
+	global.Reason = "Not exploitable, feature disabled"
+	global.ExpiresAt = future.Add(time.Hour)
+	updated, err := ds.SaveVulnerabilityException(ctx, global)
+	require.NoError(t, err)
+	require.Equal(t, "Not exploitable, feature disabled", updated.Reason)
+	require.True(t, future.Add(time.Hour).Equal(updated.ExpiresAt))
+
+	require.NoError(t, ds.DeleteVulnerabilityException(ctx, global.ID))
+	_, err = ds.VulnerabilityException(ctx, global.ID)
+	require.True(t, fleet.IsNotFound(err))
+	require.True(t, fleet.IsNotFound(ds.DeleteVulnerabilityException(ctx, global.ID)))
+
+	// deleting the team deletes its exceptions
+	require.NoError(t, ds.DeleteTeam(ctx, team.ID))
+	_, err = ds.VulnerabilityException(ctx, scoped.ID)
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testApplyVulnerabilityExceptions(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "Workstations"})
+	require.NoError(t, err)
+	future := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Microsecond)
+
+	created, edited, deleted, err := ds.ApplyVulnerabilityExceptions(ctx, []fleet.VulnerabilityException{
+		{CVE: "CVE-2024-0001", Reason: "Accepted", Owner: "secops", ExpiresAt: future},
+		{CVE: "CVE-2024-0001", TeamID: &team.ID, Reason: "Accepted", Owner: "it", ExpiresAt: future},
+	})
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+	require.Empty(t, edited)
+	require.Empty(t, deleted)
+
+	// applying the same exceptions is a no-op
+	created, edited, deleted, err = ds.ApplyVulnerabilityExceptions(ctx, []fleet.VulnerabilityException{
+		{CVE: "CVE-2024-0001", Reason: "Accepted", Owner: "secops", ExpiresAt: future},
+		{CVE: "CVE-2024-0001", TeamID: &team.ID, Reason: "Accepted", Owner: "it", ExpiresAt: future},
+	})
+	require.NoError(t, err)
+	require.Empty(t, created)
+	require.Empty(t, edited)
+	require.Empty(t, deleted)
+
+	created, edited, deleted, err = ds.ApplyVulnerabilityExceptions(ctx, []fleet.VulnerabilityException{
+		{CVE: "CVE-2024-0001", Reason: "Accepted until the upgrade", Owner: "secops", ExpiresAt: future},
+		{CVE: "CVE-2024-0002", Reason: "Accepted", Owner: "secops", ExpiresAt: future},
+	})
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	require.Equal(t, "CVE-2024-0002", created[0].CVE)
+	require.Len(t, edited, 1)
+	require.Equal(t, "Accepted until the upgrade", edited[0].Reason)
+	require.Len(t, deleted, 1)
+	require.Equal(t, &team.ID, deleted[0].TeamID)
+	require.Equal(t, ptr.String("Workstations"), deleted[0].TeamName)
+
+	created, edited, deleted, err = ds.ApplyVulnerabilityExceptions(ctx, nil)
+	require.NoError(t, err)
+	require.Empty(t, created)
+	require.Empty(t, edited)
+	require.Len(t, deleted, 2)
+
+	list, _, err := ds.ListVulnerabilityExceptions(ctx, fleet.VulnerabilityExceptionListOptions{})
+	require.NoError(t, err)
+	require.Empty(t, list)
+}
+
+func testExpireVulnerabilityExceptions(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	old, err := ds.NewVulnerabilityException(ctx, &fleet.VulnerabilityException{
+		CVE: "CVE-2024-0001", Reason: "Accepted", Owner: "secops", ExpiresAt: now.Add(-time.Hour),
+	})
+	require.NoError(t, err)
+	_, err = ds.NewVulnerabilityException(ctx, &fleet.VulnerabilityException{
+		CVE: "CVE-2024-0002", Reason: "Accepted", Owner: "secops", ExpiresAt: now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	expired, err := ds.ExpireVulnerabilityExceptions(ctx, now)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	require.Equal(t, old.ID, expired[0].ID)
+
+	// expired exceptions are returned only once
+	expired, err = ds.ExpireVulnerabilityExceptions(ctx, now)
+	require.NoError(t, err)
+	require.Empty(t, expired)
+
+	// extending the expiration date re-arms the exception
+	old.ExpiresAt = now.Add(30 * time.Minute)
+	_, err = ds.SaveVulnerabilityException(ctx, old)
+	require.NoError(t, err)
+	expired, err = ds.ExpireVulnerabilityExceptions(ctx, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, expired, 2)
+}
+
+func testVulnerabilityExceptionsEnforcement(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	team, err := ds.NewTeam(ctx, &fleet.Team{Name: "Workstations"})
+	require.NoError(t, err)
+	curlTitle, curl := insertVulnerabilityExceptionTestSoftware(t, ds, "curl", "7.88.1")
+	_, openssl := insertVulnerabilityExceptionTestSoftware(t, ds, "openssl", "3.0.11")
+
+	vulns := []fleet.SoftwareVulnerability{
+		{SoftwareID: curl, CVE: "CVE-2024-0001"},
+		{SoftwareID: openssl, CVE: "CVE-2024-0001"},
+		{SoftwareID: curl, CVE: "CVE-2024-0002"},
+	}
+	_, err = ds.InsertSoftwareVulnerabilities(ctx, vulns, fleet.NVDSource)
+	require.NoError(t, err)
+
+	noTeamHost := test.NewHost(t, ds, "h1", "10.0.0.1", "h1", "h1", time.Now())
+	teamHost := test.NewHost(t, ds, "h2", "10.0.0.2", "h2", "h2", time.Now(), test.WithTeamID(team.ID))
+	_, err = ds.writer(ctx).ExecContext(ctx,
+		`INSERT INTO host_software (host_id, software_id) VALUES (?, ?), (?, ?), (?, ?)`,
+		noTeamHost.ID, curl, teamHost.ID, curl, teamHost.ID, openssl)
+	require.NoError(t, err)
+
+	future := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Microsecond)
+
+	// no exceptions, nothing is filtered
+	filtered, err := ds.FilterExceptedSoftwareVulnerabilities(ctx, vulns)
+	require.NoError(t, err)
+	require.Equal(t, vulns, filtered)
+
+	// an exception for curl in all fleets only filters curl for that CVE
+	_, err = ds.NewVulnerabilityException(ctx, &fleet.VulnerabilityException{
+		CVE: "CVE-2024-0001", SoftwareTitleID: &curlTitle, Reason: "Accepted", Owner: "secops", ExpiresAt: future,
+	})
+	require.NoError(t, err)
+	filtered, err = ds.FilterExceptedSoftwareVulnerabilities(ctx, vulns)
+	require.NoError(t, err)
+	require.Equal(t, vulns[1:], filtered)
+
+	// the team host still has openssl vulnerable to the CVE
+	hostIDs, err := ds.VulnerabilityExceptedHostIDs(ctx, "CVE-2024-0001", []uint{curl, openssl})
+	require.NoError(t, err)
+	require.Equal(t, []uint{noTeamHost.ID}, hostIDs)
+
+	// an exception for all software in the team excepts the team host
+	_, err = ds.NewVulnerabilityException(ctx, &fleet.VulnerabilityException{
+		CVE: "CVE-2024-0001", TeamID: &team.ID, Reason: "Accepted", Owner: "secops", ExpiresAt: future,
+	})
+	require.NoError(t, err)
+	hostIDs, err = ds.VulnerabilityExceptedHostIDs(ctx, "CVE-2024-0001", []uint{curl, openssl})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []uint{noTeamHost.ID, teamHost.ID}, hostIDs)
+
+	// team-scoped exceptions are not filtered from the vulnerabilities
+	filtered, err = ds.FilterExceptedSoftwareVulnerabilities(ctx, vulns)
+	require.NoError(t, err)
+	require.Equal(t, vulns[1:], filtered)
+
+	// expired exceptions are not enforced
+	_, err = ds.writer(ctx).ExecContext(ctx, `UPDATE vulnerability_exceptions SET expires_at = ?`, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	filtered, err = ds.FilterExceptedSoftwareVulnerabilities(ctx, vulns)
+	require.NoError(t, err)
+	require.Equal(t, vulns, filtered)
+	hostIDs, err = ds.VulnerabilityExceptedHostIDs(ctx, "CVE-2024-0001", []uint{curl, openssl})
+	require.NoError(t, err)
+	require.Empty(t, hostIDs)
+}
@@ -0,0 +1,144 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	common_mysql "github.com/fleetdm/fleet/v4/server/platform/mysql"
+	"github.com/jmoiron/sqlx"
+)
+
+// webhookDeliveryColumns are the columns of a webhook delivery, without its
+// payload.
+const webhookDeliveryColumns = `
+	id, webhook_type, team_id, url, status, attempts, response_status_code, latency_ms,
+	COALESCE(error, '') AS error, redelivery_of_id, last_attempt_at, created_at, updated_at`
+
+func (ds *Datastore) NewWebhookDelivery(ctx context.Context, d *fleet.WebhookDelivery) (*fleet.WebhookDelivery, error) {
+	const stmt = `
+		INSERT INTO webhook_deliveries (webhook_type, team_id, url, payload, status, redelivery_of_id)
+		VALUES (?, ?, ?, ?, ?, ?)`
+
+	status := d.Status
+	if status == "" {
+		status = fleet.WebhookDeliveryStatusPending
+	}
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, d.WebhookType, d.TeamID, d.URL, d.Payload, status, d.RedeliveryOfID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "insert webhook delivery")
+	}
+	id, _ := res.LastInsertId()
+	return ds.webhookDeliveryDB(ctx, ds.writer(ctx), uint(id)) //nolint:gosec // dismiss G115
+}
+
+func (ds *Datastore) UpdateWebhookDelivery(ctx context.Context, d *fleet.WebhookDelivery) error {
+	const stmt = `
+		UPDATE webhook_deliveries SET
+			status = ?,
+			attempts = ?,
+			response_status_code = ?,
+			latency_ms = ?,
+			error = ?,
+			last_attempt_at = ?
+		WHERE id = ?`
+
+	res, err := ds.writer(ctx).ExecContext(ctx, stmt, d.Status, d.Attempts, d.ResponseStatusCode, d.LatencyMs, d.Error, d.LastAttemptAt, d.ID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "update webhook delivery")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ctxerr.Wrap(ctx, notFound("WebhookDelivery").WithID(d.ID))
+	}
+	return nil
+}
+
+func (ds *Datastore) WebhookDelivery(ctx context.Context, id uint) (*fleet.WebhookDelivery, error) {
+	return ds.webhookDeliveryDB(ctx, ds.reader(ctx), id)
+}
+
+func (ds *Datastore) webhookDeliveryDB(ctx context.Context, q sqlx.QueryerContext, id uint) (*fleet.WebhookDelivery, error) {
+	var d fleet.WebhookDelivery
+	if err := sqlx.GetContext(ctx, q, &d, `SELECT `+webhookDeliveryColumns+`, payload FROM webhook_deliveries WHERE id = ?`, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ctxerr.Wrap(ctx, notFound("WebhookDelivery").WithID(id))
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get webhook delivery")
+	}
+	return &d, nil
+}
+
+var webhookDeliveryAllowedOrderKeys = common_mysql.OrderKeyAllowlist{
+	"id":              "id",
+	"created_at":      "created_at",
+	"last_attempt_at": "last_attempt_at",
+	"latency_ms":      "latency_ms",
+}
+
+func (ds *Datastore) ListWebhookDeliveries(ctx context.Context, opts fleet.WebhookDeliveryListOptions) ([]fleet.WebhookDelivery, *fleet.PaginationMetadata, error) {
+	stmt := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries WHERE true`
+	var args []any
+	if opts.WebhookType != "" {
+		stmt += ` AND webhook_type = ?`
+		args = append(args, opts.WebhookType)
+	}
+	if opts.Status != "" {
+		stmt += ` AND status = ?`
+		args = append(args, opts.Status)
+	}
+	if opts.TeamID != nil {
+		stmt += ` AND team_id = ?`
+		args = append(args, *opts.TeamID)
+	}
+
+	// most recent deliveries first by default
+	if opts.ListOptions.OrderKey == "" {
+		opts.ListOptions.OrderKey = "id"
+		opts.ListOptions.OrderDirection = fleet.OrderDescending
+	}
+	stmt, args, err := appendListOptionsWithCursorToSQLSecure(stmt, args, &opts.ListOptions, webhookDeliveryAllowedOrderKeys)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "apply list options")
+	}
+
+	deliveries := []fleet.WebhookDelivery{}
+	if err := sqlx.SelectContext(ctx, ds.reader(ctx), &deliveries, stmt, args...); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "list webhook deliveries")
+	}
+
+	var meta *fleet.PaginationMetadata
+	if opts.ListOptions.IncludeMetadata {
+		meta = &fleet.PaginationMetadata{HasPreviousResults: opts.ListOptions.Page > 0}
+		// `appendListOptionsWithCursorToSQL` fetches one more row than requested
+		// to know if there are more results.
+		if len(deliveries) > int(opts.ListOptions.PerPage) { //nolint:gosec // dismiss G115
+			meta.HasNextResults = true
+			deliveries = deliveries[:len(deliveries)-1]
+		}
+	}
+	return deliveries, meta, nil
+}
+
+// webhookDeliveriesCleanupBatchSize is the number of webhook deliveries
+// deleted per statement by CleanupWebhookDeliveries.
+var webhookDeliveriesCleanupBatchSize = 1000
+
+func (ds *Datastore) CleanupWebhookDeliveries(ctx context.Context, olderThan time.Time) (int64, error) {
+	const stmt = `DELETE FROM webhook_deliveries WHERE created_at < ? AND status <> ? LIMIT ?`
+
+	var deleted int64
+	for {
+		res, err := ds.writer(ctx).ExecContext(ctx, stmt, olderThan, fleet.WebhookDeliveryStatusPending, webhookDeliveriesCleanupBatchSize)
+		if err != nil {
+			return deleted, ctxerr.Wrap(ctx, err, "delete webhook deliveries")
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+		if n < int64(webhookDeliveriesCleanupBatchSize) {
+			return deleted, nil
+		}
+	}
+}
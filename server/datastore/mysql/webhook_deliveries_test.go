@@ -0,0 +1,185 @@
+package mysql
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeliveries(t *testing.T) {
+	ds := CreateMySQLDS(t)
+
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, ds *Datastore)
+	}{
+		{"CRUD", testWebhookDeliveriesCRUD},
+		{"List", testListWebhookDeliveries},
+		{"Cleanup", testCleanupWebhookDeliveries},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			defer TruncateTables(t, ds)
+			c.fn(t, ds)
+		})
+	}
+}
+
+func testWebhookDeliveriesCRUD(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	d, err := ds.NewWebhookDelivery(ctx, &fleet.WebhookDelivery{
+		WebhookType: fleet.WebhookTypeHostStatus,
+		TeamID:      ptr.Uint(0),
+		URL:         "https://example.com/hook",
+		Payload:     json.RawMessage(`{"text":"hello"}`),
+	})
+	require.NoError(t, err)
+	require.NotZero(t, d.ID)
+	assert.Equal(t, fleet.WebhookDeliveryStatusPending, d.Status)
+	assert.Zero(t, d.Attempts)
+	assert.Nil(t, d.ResponseStatusCode)
+	assert.Nil(t, d.LastAttemptAt)
+	assert.Equal(t, ptr.Uint(0), d.TeamID)
+	assert.JSONEq(t, `{"text":"hello"}`, string(d.Payload))
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	d.Status = fleet.WebhookDeliveryStatusSuccess
+	d.Attempts = 1
+	d.ResponseStatusCode = ptr.Int(200)
+	d.LatencyMs = ptr.Uint(42)
+	d.LastAttemptAt = &now
+	require.NoError(t, ds.UpdateWebhookDelivery(ctx, d))
+
+	got, err := ds.WebhookDelivery(ctx, d.ID)
+	require.NoError(t, err)
+	assert.Equal(t, fleet.WebhookDeliveryStatusSuccess, got.Status)
+	assert.EqualValues(t, 1, got.Attempts)
+	assert.Equal(t, ptr.Int(200), got.ResponseStatusCode)
+	assert.Equal(t, ptr.Uint(42), got.LatencyMs)
+	assert.Empty(t, got.Error)
+	require.NotNil(t, got.LastAttemptAt)
+	assert.True(t, now.Equal(*got.LastAttemptAt))
+
+	got.Status = fleet.WebhookDeliveryStatusFailed
+	got.Attempts++
+	got.ResponseStatusCode = nil
+	got.Error = "connection refused"
+	require.NoError(t, ds.UpdateWebhookDelivery(ctx, got))
+	got, err = ds.WebhookDelivery(ctx, d.ID)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, got.Attempts)
+	assert.Nil(t, got.ResponseStatusCode)
+	assert.Equal(t, "connection refused", got.Error)
+
+	_, err = ds.WebhookDelivery(ctx, d.ID+1)
+	require.True(t, fleet.IsNotFound(err))
+	err = ds.UpdateWebhookDelivery(ctx, &fleet.WebhookDelivery{ID: d.ID + 1, Status: fleet.WebhookDeliveryStatusFailed})
+	require.True(t, fleet.IsNotFound(err))
+}
+
+func testListWebhookDeliveries(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	newDelivery := func(typ fleet.WebhookType, teamID *uint, status fleet.WebhookDeliveryStatus) *fleet.WebhookDelivery {
+		d, err := ds.NewWebhookDelivery(ctx, &fleet.WebhookDelivery{
+			WebhookType: typ,
+			TeamID:      teamID,
+			URL:         "https://example.com/hook",
+			Payload:     json.RawMessage(`{}`),
+			Status:      status,
+		})
+		require.NoError(t, err)
+		return d
+	}
+	d1 := newDelivery(fleet.WebhookTypeActivities, nil, fleet.WebhookDeliveryStatusSuccess)
+	d2 := newDelivery(fleet.WebhookTypeHostActivities, ptr.Uint(1), fleet.WebhookDeliveryStatusFailed)
+	d3 := newDelivery(fleet.WebhookTypeHostActivities, ptr.Uint(2), fleet.WebhookDeliveryStatusPending)
+
+	ids := func(deliveries []fleet.WebhookDelivery) []uint {
+		var ids []uint
+		for _, d := range deliveries {
+			// the payload is not loaded when listing
+			assert.Nil(t, d.Payload)
+			ids = append(ids, d.ID)
+		}
+		return ids
+	}
+
+	deliveries, _, err := ds.ListWebhookDeliveries(ctx, fleet.WebhookDeliveryListOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{d3.ID, d2.ID, d1.ID}, ids(deliveries))
+
+	deliveries, _, err = ds.ListWebhookDeliveries(ctx, fleet.WebhookDeliveryListOptions{WebhookType: fleet.WebhookTypeHostActivities})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{d3.ID, d2.ID}, ids(deliveries))
+
+	deliveries, _, err = ds.ListWebhookDeliveries(ctx, fleet.WebhookDeliveryListOptions{Status: fleet.WebhookDeliveryStatusFailed})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{d2.ID}, ids(deliveries))
+
+	deliveries, _, err = ds.ListWebhookDeliveries(ctx, fleet.WebhookDeliveryListOptions{TeamID: ptr.Uint(2)})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{d3.ID}, ids(deliveries))
+
+	deliveries, meta, err := ds.ListWebhookDeliveries(ctx, fleet.WebhookDeliveryListOptions{
+		ListOptions: fleet.ListOptions{OrderKey: "id", PerPage: 2, IncludeMetadata: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{d1.ID, d2.ID}, ids(deliveries))
+	require.NotNil(t, meta)
+	assert.True(t, meta.HasNextResults)
+	assert.False(t, meta.HasPreviousResults)
+}
+
+func testCleanupWebhookDeliveries(t *testing.T, ds *Datastore) {
+	ctx := t.Context()
+
+	defer func(orig int) { webhookDeliveriesCleanupBatchSize = orig }(webhookDeliveriesCleanupBatchSize)
+	webhookDeliveriesCleanupBatchSize = 2
+
+	var oldIDs []uint
+	for _, status := range []fleet.WebhookDeliveryStatus{
+		fleet.WebhookDeliveryStatusSuccess,
+		fleet.WebhookDeliveryStatusSuccess,
+		fleet.WebhookDeliveryStatusFailed,
+		fleet.WebhookDeliveryStatusPending,
+	} {
+		d, err := ds.NewWebhookDelivery(ctx, &fleet.WebhookDelivery{
+			WebhookType: fleet.WebhookTypeVulnerabilities,
+			URL:         "https://example.com/hook",
+			Payload:     json.RawMessage(`{}`),
+			Status:      status,
+		})
+		require.NoError(t, err)
+		oldIDs = append(oldIDs, d.ID)
+	}
+	ExecAdhocSQL(t, ds, func(q sqlx.ExtContext) error {
+		_, err := q.ExecContext(ctx, `UPDATE webhook_deliveries SET created_at = ?`, time.Now().Add(-48*time.Hour))
+		return err
+	})
+	recent, err := ds.NewWebhookDelivery(ctx, &fleet.WebhookDelivery{
+		WebhookType: fleet.WebhookTypeVulnerabilities,
+		URL:         "https://example.com/hook",
+		Payload:     json.RawMessage(`{}`),
+		Status:      fleet.WebhookDeliveryStatusSuccess,
+	})
+	require.NoError(t, err)
+
+	deleted, err := ds.CleanupWebhookDeliveries(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, deleted)
+
+	deliveries, _, err := ds.ListWebhookDeliveries(ctx, fleet.WebhookDeliveryListOptions{})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	// pending deliveries are kept
+	assert.Equal(t, recent.ID, deliveries[0].ID)
+	assert.Equal(t, oldIDs[3], deliveries[1].ID)
+}
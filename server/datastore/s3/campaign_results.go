@@ -0,0 +1,26 @@
+package s3
+
+import (
+	"github.com/fleetdm/fleet/v4/server/config"
+)
+
+// CampaignResultsStore stores the results of live query campaigns that are
+// too large to be stored in the database.
+type CampaignResultsStore struct {
+	*commonFileStore
+}
+
+func NewCampaignResultsStore(config config.S3Config) (*CampaignResultsStore, error) {
+	// campaign results use the same S3 config as software installers
+	s3store, err := newS3Store(config.SoftwareInstallersToInternalCfg())
+	if err != nil {
+		return nil, err
+	}
+	return &CampaignResultsStore{
+		&commonFileStore{
+			s3store:    s3store,
+			pathPrefix: "campaign-results",
+			fileLabel:  "campaign results",
+		},
+	}, nil
+}
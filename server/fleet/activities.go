@@ -291,6 +291,7 @@ type ActivityTypeLiveQuery struct {
 	QuerySQL     string           `json:"query_sql" renameto:"query"`
 	QueryName    *string          `json:"query_name,omitempty" renameto:"report_name"`
 	Stats        *AggregatedStats `json:"stats,omitempty"`
+	Deferred     bool             `json:"deferred,omitempty"`
 }
 
 func (a ActivityTypeLiveQuery) ActivityName() string {
@@ -329,6 +330,62 @@ func (a ActivityTypeUserMFARequested) ActivityName() string {
 	return "user_mfa_requested"
 }
 
+// ActivityTypeResetUserMFA is created when an admin deletes the second
+// factors (TOTP, WebAuthn credentials and recovery codes) of a user, e.g.
+// after they lost their device.
+type ActivityTypeResetUserMFA struct {
+	UserID    uint   `json:"user_id"`
+	UserName  string `json:"user_name"`
+	UserEmail string `json:"user_email"`
+}
+
+func (a ActivityTypeResetUserMFA) ActivityName() string {
+	return "reset_user_mfa"
+}
+
+// ActivityTypeProvisionedUser is created by Fleet when it creates a user, or
+// changes the roles of a user, from the groups of a SCIM user.
+type ActivityTypeProvisionedUser struct {
+	UserID     uint                   `json:"user_id"`
+	UserName   string                 `json:"user_name"`
+	UserEmail  string                 `json:"user_email"`
+	Created    bool                   `json:"created"`
+	GlobalRole *string                `json:"global_role"`
+	Teams      []ActivityUserTeamRole `json:"teams" renameto:"fleets"`
+}
+
+// ActivityUserTeamRole is the role of a user in a fleet, as recorded in the
+// provisioned_user activity.
+type ActivityUserTeamRole struct {
+	TeamID   uint   `json:"team_id" renameto:"fleet_id"`
+	TeamName string `json:"team_name" renameto:"fleet_name"`
+	Role     string `json:"role"`
+}
+
+func (a ActivityTypeProvisionedUser) ActivityName() string {
+	return "provisioned_user"
+}
+
+func (a ActivityTypeProvisionedUser) WasFromAutomation() bool {
+	return true
+}
+
+// ActivityTypeDeprovisionedUser is created by Fleet when it disables a user
+// whose SCIM user was deactivated, deleted or removed from all mapped groups.
+type ActivityTypeDeprovisionedUser struct {
+	UserID    uint   `json:"user_id"`
+	UserName  string `json:"user_name"`
+	UserEmail string `json:"user_email"`
+}
+
+func (a ActivityTypeDeprovisionedUser) ActivityName() string {
+	return "deprovisioned_user"
+}
+
+func (a ActivityTypeDeprovisionedUser) WasFromAutomation() bool {
+	return true
+}
+
 type ActivityTypeCreatedUser struct {
 	UserID    uint   `json:"user_id"`
 	UserName  string `json:"user_name"`
@@ -795,10 +852,25 @@ func (a ActivityTypeDisabledGitOpsException) ActivityName() string {
 	return "disabled_gitops_exception"
 }
 
+// ActivityTypeDetectedGitOpsDrift is emitted by the GitOps drift check when
+// resources applied by GitOps were changed outside of GitOps, either globally
+// (TeamID/TeamName nil) or for a specific fleet. It is emitted once per
+// distinct drift.
+type ActivityTypeDetectedGitOpsDrift struct {
+	TeamID   *uint   `json:"team_id" renameto:"fleet_id"`
+	TeamName *string `json:"team_name" renameto:"fleet_name"`
+	// Changes is the number of changed resources.
+	Changes int `json:"changes"`
+}
+
+func (a ActivityTypeDetectedGitOpsDrift) ActivityName() string {
+	return "detected_gitops_drift"
+}
+
 // ActivityTypeEnabledHistoricalDataset is emitted when collection of a chart
 // historical dataset is enabled, either globally (FleetID/FleetName nil) or
 // for a specific fleet. Dataset carries the public config sub-key (e.g.
-// "uptime", "vulnerabilities"), not the internal dataset name.
+// "uptime", "vulnerabilities", "policies"), not the internal dataset name.
 type ActivityTypeEnabledHistoricalDataset struct {
 	Dataset   string  `json:"dataset"`
 	FleetID   *uint   `json:"fleet_id"`
@@ -2539,3 +2611,140 @@ func (a ActivityTypeReleasedDeviceFromAB) ActivityName() string {
 func (a ActivityTypeReleasedDeviceFromAB) HostIDs() []uint {
 	return []uint{a.HostID}
 }
+
+type ActivityTypeCreatedCustomRole struct {
+	CustomRoleID   uint   `json:"custom_role_id"`
+	CustomRoleName string `json:"custom_role_name"`
+}
+
+func (a ActivityTypeCreatedCustomRole) ActivityName() string {
+	return "created_custom_role"
+}
+
+type ActivityTypeEditedCustomRole struct {
+	CustomRoleID   uint   `json:"custom_role_id"`
+	CustomRoleName string `json:"custom_role_name"`
+}
+
+func (a ActivityTypeEditedCustomRole) ActivityName() string {
+	return "edited_custom_role"
+}
+
+type ActivityTypeDeletedCustomRole struct {
+	CustomRoleID   uint   `json:"custom_role_id"`
+	CustomRoleName string `json:"custom_role_name"`
+}
+
+func (a ActivityTypeDeletedCustomRole) ActivityName() string {
+	return "deleted_custom_role"
+}
+
+type ActivityTypeChangedUserCustomRoles struct {
+	UserID      uint                          `json:"user_id"`
+	UserName    string                        `json:"user_name"`
+	UserEmail   string                        `json:"user_email"`
+	CustomRoles []ActivityUserCustomRoleGrant `json:"custom_roles"`
+}
+
+// ActivityUserCustomRoleGrant is a custom role assignment as recorded in the
+// changed_user_custom_roles activity. A nil TeamID is a global assignment.
+type ActivityUserCustomRoleGrant struct {
+	CustomRoleID   uint    `json:"custom_role_id"`
+	CustomRoleName string  `json:"custom_role_name"`
+	TeamID         *uint   `json:"team_id" renameto:"fleet_id"`
+	TeamName       *string `json:"team_name" renameto:"fleet_name"`
+}
+
+func (a ActivityTypeChangedUserCustomRoles) ActivityName() string {
+	return "changed_user_custom_roles"
+}
+
+type ActivityTypeAddedVEXDocument struct {
+	VEXDocumentID   uint   `json:"vex_document_id"`
+	VEXDocumentName string `json:"vex_document_name"`
+	StatementsCount int    `json:"statements_count"`
+}
+
+func (a ActivityTypeAddedVEXDocument) ActivityName() string {
+	return "added_vex_document"
+}
+
+type ActivityTypeDeletedVEXDocument struct {
+	VEXDocumentID   uint   `json:"vex_document_id"`
+	VEXDocumentName string `json:"vex_document_name"`
+}
+
+func (a ActivityTypeDeletedVEXDocument) ActivityName() string {
+	return "deleted_vex_document"
+}
+
+type ActivityTypeCreatedVulnerabilityException struct {
+	VulnerabilityExceptionID uint      `json:"vulnerability_exception_id"`
+	CVE                      string    `json:"cve"`
+	SoftwareTitleID          *uint     `json:"software_title_id"`
+	SoftwareTitleName        *string   `json:"software_title_name"`
+	TeamID                   *uint     `json:"team_id" renameto:"fleet_id"`
+	TeamName                 *string   `json:"team_name" renameto:"fleet_name"`
+	ExpiresAt                time.Time `json:"expires_at"`
+}
+
+func (a ActivityTypeCreatedVulnerabilityException) ActivityName() string {
+	return "created_vulnerability_exception"
+}
+
+type ActivityTypeEditedVulnerabilityException struct {
+	VulnerabilityExceptionID uint      `json:"vulnerability_exception_id"`
+	CVE                      string    `json:"cve"`
+	SoftwareTitleID          *uint     `json:"software_title_id"`
+	SoftwareTitleName        *string   `json:"software_title_name"`
+	TeamID                   *uint     `json:"team_id" renameto:"fleet_id"`
+	TeamName                 *string   `json:"team_name" renameto:"fleet_name"`
+	ExpiresAt                time.Time `json:"expires_at"`
+}
+
+func (a ActivityTypeEditedVulnerabilityException) ActivityName() string {
+	return "edited_vulnerability_exception"
+}
+
+type ActivityTypeDeletedVulnerabilityException struct {
+	VulnerabilityExceptionID uint    `json:"vulnerability_exception_id"`
+	CVE                      string  `json:"cve"`
+	SoftwareTitleID          *uint   `json:"software_title_id"`
+	SoftwareTitleName        *string `json:"software_title_name"`
+	TeamID                   *uint   `json:"team_id" renameto:"fleet_id"`
+	TeamName                 *string `json:"team_name" renameto:"fleet_name"`
+}
+
+func (a ActivityTypeDeletedVulnerabilityException) ActivityName() string {
+	return "deleted_vulnerability_exception"
+}
+
+// ActivityTypeExpiredVulnerabilityException is created by Fleet when a
+// vulnerability exception expires, and the CVE it hid reappears.
+type ActivityTypeExpiredVulnerabilityException struct {
+	VulnerabilityExceptionID uint      `json:"vulnerability_exception_id"`
+	CVE                      string    `json:"cve"`
+	SoftwareTitleID          *uint     `json:"software_title_id"`
+	SoftwareTitleName        *string   `json:"software_title_name"`
+	TeamID                   *uint     `json:"team_id" renameto:"fleet_id"`
+	TeamName                 *string   `json:"team_name" renameto:"fleet_name"`
+	Owner                    string    `json:"owner"`
+	ExpiresAt                time.Time `json:"expires_at"`
+}
+
+func (a ActivityTypeExpiredVulnerabilityException) ActivityName() string {
+	return "expired_vulnerability_exception"
+}
+
+// ActivityTypeRedeliveredWebhook is created when a user re-sends the payload
+// of a previous webhook delivery.
+type ActivityTypeRedeliveredWebhook struct {
+	WebhookDeliveryID uint        `json:"webhook_delivery_id"`
+	RedeliveryOfID    uint        `json:"redelivery_of_id"`
+	WebhookType       WebhookType `json:"webhook_type"`
+	TeamID            *uint       `json:"team_id" renameto:"fleet_id"`
+}
+
+func (a ActivityTypeRedeliveredWebhook) ActivityName() string {
+	return "redelivered_webhook"
+}
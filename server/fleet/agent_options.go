@@ -6,6 +6,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"strings"
 	"time"
 
@@ -39,6 +41,43 @@ type OrbitAgentOptions struct {
 	// MaxOrbitDebugLoggingOnEnrollDurationSeconds) that every host enrolling
 	// under this scope is stamped with orbit_debug_until = now() + duration.
 	DebugLoggingOnEnrollDuration int `json:"debug_logging_on_enroll_duration"`
+	// CacheProxies are the fleetd caching proxies that hosts under this scope
+	// download software installers and fleetd updates through. Each host uses
+	// the first proxy that serves its primary IP.
+	CacheProxies []OrbitCacheProxy `json:"cache_proxies,omitempty"`
+}
+
+// OrbitCacheProxy is a host running fleetd in caching proxy mode (see the
+// --cache-proxy-listen flag of orbit).
+type OrbitCacheProxy struct {
+	// URL is the https URL of the caching proxy.
+	URL string `json:"url"`
+	// Subnets are the CIDRs of the hosts that use this proxy. A proxy without
+	// subnets serves all the hosts.
+	Subnets []string `json:"subnets,omitempty"`
+}
+
+// CacheProxyURL returns the URL of the first caching proxy that serves the
+// host with the given primary IP, or an empty string if there is none.
+func (o *OrbitAgentOptions) CacheProxyURL(hostIP string) string {
+	if o == nil {
+		return ""
+	}
+	ip := net.ParseIP(hostIP)
+	for _, proxy := range o.CacheProxies {
+		if len(proxy.Subnets) == 0 {
+			return proxy.URL
+		}
+		if ip == nil {
+			continue
+		}
+		for _, subnet := range proxy.Subnets {
+			if _, ipNet, err := net.ParseCIDR(subnet); err == nil && ipNet.Contains(ip) {
+				return proxy.URL
+			}
+		}
+	}
+	return ""
 }
 
 const (
@@ -140,6 +179,15 @@ func ValidateJSONAgentOptions(ctx context.Context, ds Datastore, rawJSON json.Ra
 				return fmt.Errorf("orbit.debug_logging_on_enroll_duration must not exceed %d seconds", MaxOrbitDebugLoggingOnEnrollDurationSeconds)
 			}
 		}
+		if len(opts.Orbit.CacheProxies) > 0 {
+			if !isPremium {
+				// The caching proxies are premium only.
+				return ErrMissingLicense
+			}
+			if err := validateOrbitCacheProxies(opts.Orbit.CacheProxies); err != nil {
+				return err
+			}
+		}
 	}
 
 	if len(opts.Config) > 0 {
@@ -169,6 +217,23 @@ func ValidateJSONAgentOptions(ctx context.Context, ds Datastore, rawJSON json.Ra
 	return nil
 }
 
+func validateOrbitCacheProxies(proxies []OrbitCacheProxy) error {
+	for i, proxy := range proxies {
+		u, err := url.Parse(proxy.URL)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			// the proxy serves installers, which must not be sent in the
+			// clear.
+			return fmt.Errorf("orbit.cache_proxies[%d].url must be an https URL", i)
+		}
+		for _, subnet := range proxy.Subnets {
+			if _, _, err := net.ParseCIDR(subnet); err != nil {
+				return fmt.Errorf("orbit.cache_proxies[%d].subnets: invalid CIDR %q", i, subnet)
+			}
+		}
+	}
+	return nil
+}
+
 func checkEmptyFields(prefix string, data json.RawMessage) error {
 	var m map[string]interface{}
 	if err := json.Unmarshal(data, &m); err != nil {
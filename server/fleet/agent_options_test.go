@@ -206,6 +206,15 @@ func TestValidateAgentOptions(t *testing.T) {
 		{"orbit debug_logging_on_enroll_duration negative", `{"orbit": {"debug_logging_on_enroll_duration": -1}}`, true, `must not be negative`},
 		{"orbit debug_logging_on_enroll_duration string rejected", `{"orbit": {"debug_logging_on_enroll_duration": "1h"}}`, true, `cannot unmarshal string`},
 		{"orbit unknown subkey rejected", `{"orbit": {"foo": true}}`, true, `unknown field "foo"`},
+		{"orbit cache_proxies valid", `{"orbit": {"cache_proxies": [
+			{"url": "https://cache.branch1.example.com:8089", "subnets": ["10.1.0.0/16", "fd00:1::/64"]},
+			{"url": "https://cache.example.com"}
+		]}}`, true, ``},
+		{"orbit cache_proxies non-premium", `{"orbit": {"cache_proxies": [{"url": "https://cache.example.com"}]}}`, false, `Requires Fleet Premium license`},
+		{"orbit cache_proxies http URL", `{"orbit": {"cache_proxies": [{"url": "http://cache.example.com"}]}}`, true, `orbit.cache_proxies[0].url must be an https URL`},
+		{"orbit cache_proxies missing URL", `{"orbit": {"cache_proxies": [{"subnets": ["10.1.0.0/16"]}]}}`, true, `orbit.cache_proxies[0].url must be an https URL`},
+		{"orbit cache_proxies invalid subnet", `{"orbit": {"cache_proxies": [{"url": "https://cache.example.com", "subnets": ["10.1.0.0"]}]}}`, true, `orbit.cache_proxies[0].subnets: invalid CIDR "10.1.0.0"`},
+		{"orbit cache_proxies unknown field", `{"orbit": {"cache_proxies": [{"url": "https://cache.example.com", "foo": 1}]}}`, true, `unknown field "foo"`},
 	}
 
 	for _, c := range cases {
@@ -220,3 +229,25 @@ func TestValidateAgentOptions(t *testing.T) {
 		})
 	}
 }
+
+func TestOrbitCacheProxyURL(t *testing.T) {
+	var nilOpts *OrbitAgentOptions
+	require.Empty(t, nilOpts.CacheProxyURL("10.1.2.3"))
+
+	opts := &OrbitAgentOptions{CacheProxies: []OrbitCacheProxy{
+		{URL: "https://branch1.example.com", Subnets: []string{"10.1.0.0/16"}},
+		{URL: "https://branch2.example.com", Subnets: []string{"10.2.0.0/16", "fd00:2::/64"}},
+	}}
+	require.Equal(t, "https://branch1.example.com", opts.CacheProxyURL("10.1.2.3"))
+	require.Equal(t, "https://branch2.example.com", opts.CacheProxyURL("10.2.2.3"))
+	require.Equal(t, "https://branch2.example.com", opts.CacheProxyURL("fd00:2::1"))
+	require.Empty(t, opts.CacheProxyURL("10.3.2.3"))
+	// the primary IP is not known until osquery reports it
+	require.Empty(t, opts.CacheProxyURL(""))
+
+	// listed last, a proxy without subnets serves the remaining hosts
+	opts.CacheProxies = append(opts.CacheProxies, OrbitCacheProxy{URL: "https://hq.example.com"})
+	require.Equal(t, "https://branch1.example.com", opts.CacheProxyURL("10.1.2.3"))
+	require.Equal(t, "https://hq.example.com", opts.CacheProxyURL("10.3.2.3"))
+	require.Equal(t, "https://hq.example.com", opts.CacheProxyURL(""))
+}
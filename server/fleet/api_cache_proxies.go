@@ -0,0 +1,19 @@
+package fleet
+
+//////////////////////////////////////////////////////////////////////////////////
+// List fleetd caching proxies
+//////////////////////////////////////////////////////////////////////////////////
+
+type ListCacheProxiesRequest struct {
+	// TeamID restricts the list to the proxies of a fleet, all the proxies
+	// the user can see are listed if nil.
+	TeamID *uint `query:"team_id,optional" renameto:"fleet_id"`
+}
+
+type ListCacheProxiesResponse struct {
+	CacheProxies []*HostCacheProxy `json:"cache_proxies"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r ListCacheProxiesResponse) Error() error { return r.Err }
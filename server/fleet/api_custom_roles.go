@@ -0,0 +1,110 @@
+package fleet
+
+//////////////////////////////////////////////////////////////////////////////////
+// List custom roles
+//////////////////////////////////////////////////////////////////////////////////
+
+type ListCustomRolesRequest struct{}
+
+type ListCustomRolesResponse struct {
+	CustomRoles []CustomRole `json:"custom_roles"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r ListCustomRolesResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Get custom role
+//////////////////////////////////////////////////////////////////////////////////
+
+type GetCustomRoleRequest struct {
+	ID uint `url:"id"`
+}
+
+type GetCustomRoleResponse struct {
+	CustomRole *CustomRole `json:"custom_role,omitempty"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r GetCustomRoleResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Create custom role
+//////////////////////////////////////////////////////////////////////////////////
+
+type CreateCustomRoleRequest struct {
+	CustomRolePayload
+}
+
+type CreateCustomRoleResponse struct {
+	CustomRole *CustomRole `json:"custom_role,omitempty"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r CreateCustomRoleResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Modify custom role
+//////////////////////////////////////////////////////////////////////////////////
+
+type ModifyCustomRoleRequest struct {
+	ID uint `url:"id"`
+	CustomRolePayload
+}
+
+type ModifyCustomRoleResponse struct {
+	CustomRole *CustomRole `json:"custom_role,omitempty"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r ModifyCustomRoleResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Delete custom role
+//////////////////////////////////////////////////////////////////////////////////
+
+type DeleteCustomRoleRequest struct {
+	ID uint `url:"id"`
+}
+
+type DeleteCustomRoleResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r DeleteCustomRoleResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Set user custom roles
+//////////////////////////////////////////////////////////////////////////////////
+
+type SetUserCustomRolesRequest struct {
+	ID          uint             `url:"id"`
+	CustomRoles []UserCustomRole `json:"custom_roles"`
+}
+
+type SetUserCustomRolesResponse struct {
+	User *User `json:"user,omitempty"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r SetUserCustomRolesResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Apply custom roles (spec)
+//////////////////////////////////////////////////////////////////////////////////
+
+type ApplyCustomRolesRequest struct {
+	DryRun      bool             `json:"dry_run"`
+	CustomRoles []CustomRoleSpec `json:"custom_roles"`
+}
+
+type ApplyCustomRolesResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r ApplyCustomRolesResponse) Error() error { return r.Err }
@@ -0,0 +1,41 @@
+package fleet
+
+import "time"
+
+//////////////////////////////////////////////////////////////////////////////////
+// Save GitOps snapshot
+//////////////////////////////////////////////////////////////////////////////////
+
+type SaveGitOpsSnapshotRequest struct {
+	// TeamID is the ID of the fleet applied by GitOps, nil for the global
+	// config.
+	TeamID *uint `json:"team_id" renameto:"fleet_id"`
+}
+
+type SaveGitOpsSnapshotResponse struct {
+	TeamID    *uint     `json:"team_id" renameto:"fleet_id"`
+	Checksum  string    `json:"checksum"`
+	AppliedAt time.Time `json:"applied_at"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r SaveGitOpsSnapshotResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Get GitOps drift
+//////////////////////////////////////////////////////////////////////////////////
+
+type GetGitOpsDriftRequest struct {
+	// TeamID restricts the drift to a fleet, all the snapshots are checked if
+	// nil.
+	TeamID *uint `query:"team_id,optional" renameto:"fleet_id"`
+}
+
+type GetGitOpsDriftResponse struct {
+	Drift []*GitOpsDrift `json:"drift"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r GetGitOpsDriftResponse) Error() error { return r.Err }
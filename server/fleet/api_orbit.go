@@ -265,6 +265,30 @@ type OrbitPostLUKSResponse struct {
 func (r OrbitPostLUKSResponse) Error() error { return r.Err }
 func (r OrbitPostLUKSResponse) Status() int  { return http.StatusNoContent }
 
+/////////////////////////////////////////////////////////////////////////////////
+// Post Orbit caching proxy statistics
+/////////////////////////////////////////////////////////////////////////////////
+
+type OrbitPostCacheProxyStatsRequest struct {
+	OrbitNodeKey string `json:"orbit_node_key"`
+	CacheProxyStats
+}
+
+func (r *OrbitPostCacheProxyStatsRequest) SetOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+func (r *OrbitPostCacheProxyStatsRequest) OrbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
+type OrbitPostCacheProxyStatsResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r OrbitPostCacheProxyStatsResponse) Error() error { return r.Err }
+func (r OrbitPostCacheProxyStatsResponse) Status() int  { return http.StatusNoContent }
+
 /////////////////////////////////////////////////////////////////////////////////
 // Post Orbit Windows managed local account password
 /////////////////////////////////////////////////////////////////////////////////
@@ -340,6 +364,24 @@ func (r *OrbitDownloadSoftwareInstallerRequest) OrbitHostNodeKey() string {
 	return r.OrbitNodeKey
 }
 
+/////////////////////////////////////////////////////////////////////////////////
+// Download Orbit software installer for a caching proxy request
+/////////////////////////////////////////////////////////////////////////////////
+
+type OrbitCacheProxyDownloadSoftwareInstallerRequest struct {
+	Alt          string `query:"alt"`
+	OrbitNodeKey string `json:"orbit_node_key"`
+	HashSHA256   string `json:"hash_sha256"`
+}
+
+func (r *OrbitCacheProxyDownloadSoftwareInstallerRequest) SetOrbitNodeKey(nodeKey string) {
+	r.OrbitNodeKey = nodeKey
+}
+
+func (r *OrbitCacheProxyDownloadSoftwareInstallerRequest) OrbitHostNodeKey() string {
+	return r.OrbitNodeKey
+}
+
 /////////////////////////////////////////////////////////////////////////////////
 // Post Orbit software install result
 /////////////////////////////////////////////////////////////////////////////////
@@ -50,6 +50,10 @@ func (r ListQueriesResponse) Error() error { return r.Err }
 type GetQueryReportRequest struct {
 	ID     uint  `url:"id"`
 	TeamID *uint `query:"team_id,optional" renameto:"fleet_id"`
+	// From and To are RFC 3339 timestamps, when either is set the results are
+	// read from the report history.
+	From string `query:"from,optional"`
+	To   string `query:"to,optional"`
 }
 
 type GetQueryReportResponse struct {
@@ -61,6 +65,20 @@ type GetQueryReportResponse struct {
 
 func (r GetQueryReportResponse) Error() error { return r.Err }
 
+type DiffQueryReportRequest struct {
+	ID     uint   `url:"id"`
+	TeamID *uint  `query:"team_id,optional" renameto:"fleet_id"`
+	From   string `query:"from"`
+	To     string `query:"to"`
+}
+
+type DiffQueryReportResponse struct {
+	*QueryReportDiff
+	Err error `json:"error,omitempty"`
+}
+
+func (r DiffQueryReportResponse) Error() error { return r.Err }
+
 ////////////////////////////////////////////////////////////////////////////////
 // Create Query
 ////////////////////////////////////////////////////////////////////////////////
@@ -23,7 +23,10 @@ type LoginResponse struct {
 	AvailableTeams []*TeamSummary `json:"available_teams" renameto:"available_fleets"`
 	Token          string         `json:"token,omitempty"`
 	TokenExpiresAt *time.Time     `json:"token_expires_at,omitempty"`
-	Err            error          `json:"error,omitempty"`
+	// RecoveryCodes are the new recovery codes of the user, when the login
+	// was completed by enrolling their first second factor.
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	Err           error    `json:"error,omitempty"`
 }
 
 func (r LoginResponse) Error() error { return r.Err }
@@ -0,0 +1,96 @@
+package fleet
+
+//////////////////////////////////////////////////////////////////////////////////
+// List vulnerability exceptions
+//////////////////////////////////////////////////////////////////////////////////
+
+type ListVulnerabilityExceptionsRequest struct {
+	VulnerabilityExceptionListOptions
+}
+
+type ListVulnerabilityExceptionsResponse struct {
+	VulnerabilityExceptions []VulnerabilityException `json:"vulnerability_exceptions"`
+	Meta                    *PaginationMetadata      `json:"meta,omitempty"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r ListVulnerabilityExceptionsResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Get vulnerability exception
+//////////////////////////////////////////////////////////////////////////////////
+
+type GetVulnerabilityExceptionRequest struct {
+	ID uint `url:"id"`
+}
+
+type GetVulnerabilityExceptionResponse struct {
+	VulnerabilityException *VulnerabilityException `json:"vulnerability_exception,omitempty"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r GetVulnerabilityExceptionResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Create vulnerability exception
+//////////////////////////////////////////////////////////////////////////////////
+
+type CreateVulnerabilityExceptionRequest struct {
+	VulnerabilityExceptionPayload
+}
+
+type CreateVulnerabilityExceptionResponse struct {
+	VulnerabilityException *VulnerabilityException `json:"vulnerability_exception,omitempty"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r CreateVulnerabilityExceptionResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Modify vulnerability exception
+//////////////////////////////////////////////////////////////////////////////////
+
+type ModifyVulnerabilityExceptionRequest struct {
+	ID uint `url:"id"`
+	VulnerabilityExceptionPayload
+}
+
+type ModifyVulnerabilityExceptionResponse struct {
+	VulnerabilityException *VulnerabilityException `json:"vulnerability_exception,omitempty"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r ModifyVulnerabilityExceptionResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Delete vulnerability exception
+//////////////////////////////////////////////////////////////////////////////////
+
+type DeleteVulnerabilityExceptionRequest struct {
+	ID uint `url:"id"`
+}
+
+type DeleteVulnerabilityExceptionResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r DeleteVulnerabilityExceptionResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Apply vulnerability exceptions (spec)
+//////////////////////////////////////////////////////////////////////////////////
+
+type ApplyVulnerabilityExceptionsRequest struct {
+	DryRun                  bool                         `json:"dry_run"`
+	VulnerabilityExceptions []VulnerabilityExceptionSpec `json:"vulnerability_exceptions"`
+}
+
+type ApplyVulnerabilityExceptionsResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r ApplyVulnerabilityExceptionsResponse) Error() error { return r.Err }
@@ -0,0 +1,50 @@
+package fleet
+
+//////////////////////////////////////////////////////////////////////////////////
+// List webhook deliveries
+//////////////////////////////////////////////////////////////////////////////////
+
+type ListWebhookDeliveriesRequest struct {
+	WebhookDeliveryListOptions
+}
+
+type ListWebhookDeliveriesResponse struct {
+	WebhookDeliveries []WebhookDelivery   `json:"webhook_deliveries"`
+	Meta              *PaginationMetadata `json:"meta,omitempty"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r ListWebhookDeliveriesResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Get webhook delivery
+//////////////////////////////////////////////////////////////////////////////////
+
+type GetWebhookDeliveryRequest struct {
+	ID uint `url:"id"`
+}
+
+type GetWebhookDeliveryResponse struct {
+	WebhookDelivery *WebhookDelivery `json:"webhook_delivery,omitempty"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r GetWebhookDeliveryResponse) Error() error { return r.Err }
+
+//////////////////////////////////////////////////////////////////////////////////
+// Redeliver webhook delivery
+//////////////////////////////////////////////////////////////////////////////////
+
+type RedeliverWebhookDeliveryRequest struct {
+	ID uint `url:"id"`
+}
+
+type RedeliverWebhookDeliveryResponse struct {
+	WebhookDelivery *WebhookDelivery `json:"webhook_delivery,omitempty"`
+
+	Err error `json:"error,omitempty"`
+}
+
+func (r RedeliverWebhookDeliveryResponse) Error() error { return r.Err }
@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"reflect"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -84,6 +85,141 @@ type SSOSettings struct {
 	// When set, SSO will only work from this URL, not from the server URL.
 	// This is useful for organizations with separate URLs for admin access vs agent/API access.
 	SSOServerURL string `json:"sso_server_url"`
+	// OIDC, when set, makes Fleet authenticate users against an OpenID Connect
+	// provider instead of the SAML IdP described by SSOProviderSettings.
+	OIDC *OIDCSettings `json:"oidc,omitempty"`
+	// SCIMUserProvisioning, when enabled, makes Fleet create, update and
+	// disable SSO users from the users and groups provisioned over SCIM.
+	SCIMUserProvisioning *SCIMUserProvisioningSettings `json:"scim_user_provisioning,omitempty"`
+}
+
+// OIDCConfigured returns true if SSO logins go through an OpenID Connect
+// provider rather than a SAML IdP.
+func (s *SSOSettings) OIDCConfigured() bool {
+	return s != nil && s.OIDC != nil && s.OIDC.IssuerURL != ""
+}
+
+// OIDCSettings holds the settings of an OpenID Connect provider used for SSO.
+type OIDCSettings struct {
+	// IssuerURL is the OpenID Connect issuer, its discovery document is
+	// loaded from <issuer_url>/.well-known/openid-configuration.
+	IssuerURL string `json:"issuer_url"`
+	// ClientID is the client ID of the Fleet application registered with the
+	// provider. It must be an audience of the ID tokens.
+	ClientID string `json:"client_id"`
+	// ClientSecret is the client secret of the Fleet application. It can be
+	// left empty for public clients, PKCE is always used.
+	ClientSecret string `json:"client_secret"`
+	// Scopes are requested in addition to the "openid" scope. Defaults to
+	// "email" and "profile".
+	Scopes []string `json:"scopes"`
+	// EmailClaim is the ID token claim holding the user's email. Defaults to
+	// "email".
+	EmailClaim string `json:"email_claim"`
+	// NameClaim is the ID token claim holding the user's display name.
+	// Defaults to "name".
+	NameClaim string `json:"name_claim"`
+	// GlobalRoleClaim is the ID token claim holding the user's global role,
+	// used for JIT provisioning. Defaults to "FLEET_JIT_USER_ROLE_GLOBAL".
+	GlobalRoleClaim string `json:"global_role_claim"`
+	// FleetRoleClaimPrefix is the prefix of the ID token claims holding the
+	// user's role in a fleet, suffixed with the fleet ID. Defaults to
+	// "FLEET_JIT_USER_ROLE_FLEET_".
+	FleetRoleClaimPrefix string `json:"fleet_role_claim_prefix"`
+}
+
+// Copy returns a deep copy of the OIDC settings.
+func (s *OIDCSettings) Copy() *OIDCSettings {
+	if s == nil {
+		return nil
+	}
+	clone := *s
+	clone.Scopes = slices.Clone(s.Scopes)
+	return &clone
+}
+
+// SCIMUserProvisioningSettings holds the settings of Fleet user provisioning
+// from the SCIM users and groups of the identity provider.
+type SCIMUserProvisioningSettings struct {
+	// Enable turns on provisioning. When on, the identity provider is the
+	// source of truth for the roles of SSO users: a SCIM user in a mapped
+	// group gets a Fleet user with the mapped roles, and the Fleet user is
+	// disabled when the SCIM user is deactivated, deleted or no longer in a
+	// mapped group.
+	Enable bool `json:"enable"`
+	// RoleMappings map SCIM groups to Fleet roles.
+	RoleMappings []SCIMRoleMapping `json:"role_mappings"`
+}
+
+// SCIMRoleMapping maps the members of a SCIM group, including the members of
+// its nested groups, to a global role or to a role in a fleet.
+type SCIMRoleMapping struct {
+	// Group is the display name of the SCIM group.
+	Group string `json:"group"`
+	// Role is the Fleet role given to the members of the group.
+	Role string `json:"role"`
+	// Team is the name of the fleet the role applies to. The role is global
+	// if it's empty.
+	Team string `json:"team,omitempty" renameto:"fleet"`
+}
+
+// Copy returns a deep copy of the SCIM user provisioning settings.
+func (s *SCIMUserProvisioningSettings) Copy() *SCIMUserProvisioningSettings {
+	if s == nil {
+		return nil
+	}
+	clone := *s
+	clone.RoleMappings = slices.Clone(s.RoleMappings)
+	return &clone
+}
+
+// scimRoleRanks orders the roles that can be mapped from SCIM groups, so that
+// a user in several mapped groups gets the most privileged one.
+var scimRoleRanks = map[string]int{
+	RoleObserver:     1,
+	RoleObserverPlus: 2,
+	RoleTechnician:   3,
+	RoleMaintainer:   4,
+	RoleAdmin:        5,
+}
+
+// ValidSCIMMappedRole returns whether the role can be given to users
+// provisioned from SCIM groups. The GitOps role is reserved to API-only
+// users, so it can't be mapped.
+func ValidSCIMMappedRole(role string) bool {
+	_, ok := scimRoleRanks[role]
+	return ok
+}
+
+// RolesForGroups returns the roles of a member of the given SCIM groups. A
+// global role takes precedence over fleet roles, since a Fleet user can't
+// have both. teamRoles is keyed by fleet name. Both are empty if the groups
+// aren't mapped.
+func (s *SCIMUserProvisioningSettings) RolesForGroups(groups []string) (globalRole *string, teamRoles map[string]string) {
+	if s == nil {
+		return nil, nil
+	}
+	for _, m := range s.RoleMappings {
+		if !slices.Contains(groups, m.Group) || !ValidSCIMMappedRole(m.Role) {
+			continue
+		}
+		if m.Team == "" {
+			if globalRole == nil || scimRoleRanks[m.Role] > scimRoleRanks[*globalRole] {
+				globalRole = &m.Role
+			}
+			continue
+		}
+		if teamRoles == nil {
+			teamRoles = make(map[string]string)
+		}
+		if current, ok := teamRoles[m.Team]; !ok || scimRoleRanks[m.Role] > scimRoleRanks[current] {
+			teamRoles[m.Team] = m.Role
+		}
+	}
+	if globalRole != nil {
+		return globalRole, nil
+	}
+	return nil, teamRoles
 }
 
 // ConditionalAccessSettings holds the global settings for the "Conditional access" feature.
@@ -878,12 +1014,19 @@ func (c *AppConfig) Obfuscate() {
 	for _, zdIntegration := range c.Integrations.Zendesk {
 		zdIntegration.APIToken = MaskedPassword
 	}
+	for _, snIntegration := range c.Integrations.ServiceNow {
+		snIntegration.Password = MaskedPassword
+	}
 	for _, gcIntegration := range c.Integrations.GoogleCalendar {
 		gcIntegration.ApiKey.SetMasked()
 	}
 	for _, gwIntegration := range c.Integrations.GoogleWorkspace {
 		gwIntegration.ApiKey.SetMasked()
 	}
+	c.WebhookSettings.MaskSecrets()
+	if c.SSOSettings != nil && c.SSOSettings.OIDC != nil && c.SSOSettings.OIDC.ClientSecret != "" {
+		c.SSOSettings.OIDC.ClientSecret = MaskedPassword
+	}
 	// The Apple account provisioning IdP client secret lives in
 	// mdm_config_assets, never in the AppConfig JSON. Surface the masked value
 	// whenever the feature is configured (token URL present implies a stored
@@ -949,6 +1092,8 @@ func (c *AppConfig) Copy() *AppConfig {
 
 	if c.SSOSettings != nil {
 		ssoSettings := *c.SSOSettings
+		ssoSettings.OIDC = c.SSOSettings.OIDC.Copy()
+		ssoSettings.SCIMUserProvisioning = c.SSOSettings.SCIMUserProvisioning.Copy()
 		clone.SSOSettings = &ssoSettings
 	}
 
@@ -959,6 +1104,11 @@ func (c *AppConfig) Copy() *AppConfig {
 		clone.WebhookSettings.FailingPoliciesWebhook.PolicyIDs = make([]uint, len(c.WebhookSettings.FailingPoliciesWebhook.PolicyIDs))
 		copy(clone.WebhookSettings.FailingPoliciesWebhook.PolicyIDs, c.WebhookSettings.FailingPoliciesWebhook.PolicyIDs)
 	}
+	clone.WebhookSettings.ActivitiesWebhook.Headers = maps.Clone(c.WebhookSettings.ActivitiesWebhook.Headers)
+	clone.WebhookSettings.HostStatusWebhook.Headers = maps.Clone(c.WebhookSettings.HostStatusWebhook.Headers)
+	clone.WebhookSettings.FailingPoliciesWebhook.Headers = maps.Clone(c.WebhookSettings.FailingPoliciesWebhook.Headers)
+	clone.WebhookSettings.VulnerabilitiesWebhook.Headers = maps.Clone(c.WebhookSettings.VulnerabilitiesWebhook.Headers)
+	clone.WebhookSettings.GitOpsDriftWebhook.Headers = maps.Clone(c.WebhookSettings.GitOpsDriftWebhook.Headers)
 	if c.Integrations.Jira != nil {
 		clone.Integrations.Jira = make([]*JiraIntegration, len(c.Integrations.Jira))
 		for i, j := range c.Integrations.Jira {
@@ -973,6 +1123,14 @@ func (c *AppConfig) Copy() *AppConfig {
 			clone.Integrations.Zendesk[i] = &zd
 		}
 	}
+	if c.Integrations.ServiceNow != nil {
+		clone.Integrations.ServiceNow = make([]*ServiceNowIntegration, len(c.Integrations.ServiceNow))
+		for i, sn := range c.Integrations.ServiceNow {
+			snCopy := *sn
+			snCopy.FieldMapping = maps.Clone(sn.FieldMapping)
+			clone.Integrations.ServiceNow[i] = &snCopy
+		}
+	}
 	if len(c.Integrations.GoogleCalendar) > 0 {
 		clone.Integrations.GoogleCalendar = make([]*GoogleCalendarIntegration, len(c.Integrations.GoogleCalendar))
 		for i, g := range c.Integrations.GoogleCalendar {
@@ -984,6 +1142,13 @@ func (c *AppConfig) Copy() *AppConfig {
 			}
 		}
 	}
+	if len(c.Integrations.MicrosoftCalendar) > 0 {
+		clone.Integrations.MicrosoftCalendar = make([]*MicrosoftCalendarIntegration, len(c.Integrations.MicrosoftCalendar))
+		for i, m := range c.Integrations.MicrosoftCalendar {
+			mCal := *m
+			clone.Integrations.MicrosoftCalendar[i] = &mCal
+		}
+	}
 	if len(c.Integrations.GoogleWorkspace) > 0 {
 		clone.Integrations.GoogleWorkspace = make([]*GoogleWorkspaceIntegration, len(c.Integrations.GoogleWorkspace))
 		for i, g := range c.Integrations.GoogleWorkspace {
@@ -1194,15 +1359,36 @@ type WebhookSettings struct {
 	HostStatusWebhook      HostStatusWebhookSettings      `json:"host_status_webhook"`
 	FailingPoliciesWebhook FailingPoliciesWebhookSettings `json:"failing_policies_webhook"`
 	VulnerabilitiesWebhook VulnerabilitiesWebhookSettings `json:"vulnerabilities_webhook"`
+	GitOpsDriftWebhook     GitOpsDriftWebhookSettings     `json:"gitops_drift_webhook"`
 	// Interval is the interval for running the webhooks.
 	//
 	// This value currently configures both the host status and failing policies webhooks.
 	Interval Duration `json:"interval"`
 }
 
+// MaskSecrets masks the secrets and custom header values of all webhooks.
+func (w *WebhookSettings) MaskSecrets() {
+	w.ActivitiesWebhook.MaskSecrets()
+	w.HostStatusWebhook.MaskSecrets()
+	w.FailingPoliciesWebhook.MaskSecrets()
+	w.VulnerabilitiesWebhook.MaskSecrets()
+	w.GitOpsDriftWebhook.MaskSecrets()
+}
+
+// RestoreMaskedSecrets replaces the masked secrets and custom header values of
+// all webhooks with the stored ones.
+func (w *WebhookSettings) RestoreMaskedSecrets(stored WebhookSettings) {
+	w.ActivitiesWebhook.RestoreMaskedSecrets(stored.ActivitiesWebhook.WebhookDeliverySettings)
+	w.HostStatusWebhook.RestoreMaskedSecrets(stored.HostStatusWebhook.WebhookDeliverySettings)
+	w.FailingPoliciesWebhook.RestoreMaskedSecrets(stored.FailingPoliciesWebhook.WebhookDeliverySettings)
+	w.VulnerabilitiesWebhook.RestoreMaskedSecrets(stored.VulnerabilitiesWebhook.WebhookDeliverySettings)
+	w.GitOpsDriftWebhook.RestoreMaskedSecrets(stored.GitOpsDriftWebhook.WebhookDeliverySettings)
+}
+
 type ActivitiesWebhookSettings struct {
 	Enable         bool   `json:"enable_activities_webhook"`
 	DestinationURL string `json:"destination_url"`
+	WebhookDeliverySettings
 }
 
 type HostStatusWebhookSettings struct {
@@ -1210,6 +1396,7 @@ type HostStatusWebhookSettings struct {
 	DestinationURL string  `json:"destination_url"`
 	HostPercentage float64 `json:"host_percentage"`
 	DaysCount      int     `json:"days_count"`
+	WebhookDeliverySettings
 }
 
 // FailingPoliciesWebhookSettings holds the settings for failing policy webhooks.
@@ -1223,6 +1410,7 @@ type FailingPoliciesWebhookSettings struct {
 	// HostBatchSize allows sending multiple requests in batches of hosts for each policy.
 	// A value of 0 means no batching.
 	HostBatchSize int `json:"host_batch_size"`
+	WebhookDeliverySettings
 }
 
 // VulnerabilitiesWebhookSettings holds the settings for vulnerabilities webhooks.
@@ -1234,6 +1422,16 @@ type VulnerabilitiesWebhookSettings struct {
 	// HostBatchSize allows sending multiple requests in batches of hosts for each vulnerable software found.
 	// A value of 0 means no batching.
 	HostBatchSize int `json:"host_batch_size"`
+	WebhookDeliverySettings
+}
+
+// GitOpsDriftWebhookSettings holds the settings for GitOps drift webhooks.
+type GitOpsDriftWebhookSettings struct {
+	// Enable indicates whether the webhook for GitOps drift is enabled.
+	Enable bool `json:"enable_gitops_drift_webhook"`
+	// DestinationURL is the webhook's URL.
+	DestinationURL string `json:"destination_url"`
+	WebhookDeliverySettings
 }
 
 func (c *AppConfig) ApplyDefaultsForNewInstalls() {
@@ -1469,6 +1667,18 @@ type ServerSettings struct {
 	ScriptsDisabled      bool   `json:"scripts_disabled"`
 	AIFeaturesDisabled   bool   `json:"ai_features_disabled"`
 	QueryReportCap       int    `json:"query_report_cap" renameto:"report_cap"`
+	// RequireMFAAboveObserver requires users with a role above observer (see
+	// User.HasRoleAboveObserver) who log in with a password to use a second
+	// factor. Users without one must enroll one to complete their next login.
+	// SSO and API-only users are not affected.
+	RequireMFAAboveObserver bool `json:"require_mfa_above_observer"`
+	// ReportHistoryRetentionDays is the number of days every result batch of
+	// reports is kept, in addition to the latest results of each host. Report
+	// history is disabled when it is 0.
+	ReportHistoryRetentionDays int `json:"report_history_retention_days"`
+	// ReportHistoryCap is the maximum number of result rows kept in the history
+	// of each report, the oldest batches are removed first.
+	ReportHistoryCap int `json:"report_history_cap"`
 }
 
 const DefaultMaxQueryReportRows int = 1000
@@ -1480,6 +1690,20 @@ func (f *ServerSettings) GetQueryReportCap() int {
 	return f.QueryReportCap
 }
 
+const DefaultMaxReportHistoryRows int = 100_000
+
+func (f *ServerSettings) GetReportHistoryCap() int {
+	if f.ReportHistoryCap <= 0 {
+		return DefaultMaxReportHistoryRows
+	}
+	return f.ReportHistoryCap
+}
+
+// ReportHistoryEnabled returns whether every result batch of reports is kept.
+func (f *ServerSettings) ReportHistoryEnabled() bool {
+	return f.ReportHistoryRetentionDays > 0
+}
+
 // HostExpirySettings contains settings pertaining to automatic host expiry.
 type HostExpirySettings struct {
 	HostExpiryEnabled bool `json:"host_expiry_enabled"`
@@ -1553,6 +1777,9 @@ type VulnExposureFilterSettings struct {
 type HistoricalDataSettings struct {
 	Uptime          bool `json:"uptime"`
 	Vulnerabilities bool `json:"vulnerabilities"`
+	Policies        bool `json:"policies"`
+	MDMEnrollment   bool `json:"mdm_enrollment"`
+	DiskEncryption  bool `json:"disk_encryption"`
 }
 
 // Enabled returns whether collection is enabled for the given internal
@@ -1566,6 +1793,12 @@ func (h HistoricalDataSettings) Enabled(dataset string) (bool, error) {
 		return h.Uptime, nil
 	case "cve":
 		return h.Vulnerabilities, nil
+	case "policy":
+		return h.Policies, nil
+	case "mdm_enrollment":
+		return h.MDMEnrollment, nil
+	case "disk_encryption":
+		return h.DiskEncryption, nil
 	default:
 		return false, fmt.Errorf("unknown dataset %q", dataset)
 	}
@@ -1583,6 +1816,9 @@ func (f *Features) ApplyDefaults() {
 	f.EnableHostUsers = true
 	f.HistoricalData.Uptime = true
 	f.HistoricalData.Vulnerabilities = true
+	f.HistoricalData.Policies = true
+	f.HistoricalData.MDMEnrollment = true
+	f.HistoricalData.DiskEncryption = true
 }
 
 // Clone implements cloner for Features.
@@ -2134,6 +2370,12 @@ type SplunkConfig struct {
 	SourceType string `json:"source_type"`
 }
 
+// OTLPConfig shadows config.OTLPConfig only exposing a subset of fields
+type OTLPConfig struct {
+	Endpoint string `json:"endpoint"`
+	Protocol string `json:"protocol"`
+}
+
 // DeviceGlobalConfig is a subset of AppConfig with information used by the
 // device endpoints
 type DeviceGlobalConfig struct {
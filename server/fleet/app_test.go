@@ -1056,3 +1056,44 @@ func TestManagedLocalAccountSettingsMarshalDefaults(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, map[string]any{"enabled": false}, windowsSettings(v.([]byte)))
 }
+
+func TestSCIMUserProvisioningRolesForGroups(t *testing.T) {
+	settings := &SCIMUserProvisioningSettings{
+		Enable: true,
+		RoleMappings: []SCIMRoleMapping{
+			{Group: "fleet-admins", Role: RoleAdmin},
+			{Group: "fleet-observers", Role: RoleObserver},
+			{Group: "ws-it", Role: RoleMaintainer, Team: "Workstations"},
+			{Group: "it", Role: RoleObserver, Team: "Workstations"},
+			{Group: "it", Role: RoleObserverPlus, Team: "Servers"},
+			{Group: "legacy", Role: RoleGitOps},
+		},
+	}
+
+	cases := []struct {
+		name       string
+		groups     []string
+		globalRole *string
+		teamRoles  map[string]string
+	}{
+		{"no groups", nil, nil, nil},
+		{"unmapped groups", []string{"sales", "legacy"}, nil, nil},
+		{"global role", []string{"fleet-observers"}, ptr.String(RoleObserver), nil},
+		{"most privileged global role", []string{"fleet-observers", "fleet-admins"}, ptr.String(RoleAdmin), nil},
+		{"global role takes precedence", []string{"ws-it", "fleet-observers"}, ptr.String(RoleObserver), nil},
+		{"team roles", []string{"it"}, nil, map[string]string{"Workstations": RoleObserver, "Servers": RoleObserverPlus}},
+		{"most privileged team role", []string{"it", "ws-it"}, nil, map[string]string{"Workstations": RoleMaintainer, "Servers": RoleObserverPlus}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			globalRole, teamRoles := settings.RolesForGroups(c.groups)
+			require.Equal(t, c.globalRole, globalRole)
+			require.Equal(t, c.teamRoles, teamRoles)
+		})
+	}
+
+	var disabled *SCIMUserProvisioningSettings
+	globalRole, teamRoles := disabled.RolesForGroups([]string{"fleet-admins"})
+	require.Nil(t, globalRole)
+	require.Nil(t, teamRoles)
+}
@@ -0,0 +1,45 @@
+package fleet
+
+import "time"
+
+// CacheProxyStats are the statistics reported by a host running fleetd in
+// caching proxy mode. The counters are cumulative since fleetd started on the
+// host.
+type CacheProxyStats struct {
+	// Hits is the number of downloads served from the cache.
+	Hits uint64 `json:"hits" db:"hits"`
+	// Misses is the number of downloads that had to be fetched from Fleet or
+	// from the update server.
+	Misses uint64 `json:"misses" db:"misses"`
+	// HitBytes is the number of bytes served from the cache.
+	HitBytes uint64 `json:"hit_bytes" db:"hit_bytes"`
+	// MissBytes is the number of bytes fetched from Fleet or from the update
+	// server.
+	MissBytes uint64 `json:"miss_bytes" db:"miss_bytes"`
+	// CachedItems is the number of files currently in the cache.
+	CachedItems uint64 `json:"cached_items" db:"cached_items"`
+	// CachedBytes is the size of the files currently in the cache.
+	CachedBytes uint64 `json:"cached_bytes" db:"cached_bytes"`
+}
+
+// HitRate returns the ratio of downloads served from the cache, 0 if there
+// were no downloads.
+func (s CacheProxyStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// HostCacheProxy is a host acting as a fleetd caching proxy, along with the
+// last statistics it reported.
+type HostCacheProxy struct {
+	HostID      uint   `json:"host_id" db:"host_id"`
+	DisplayName string `json:"display_name" db:"display_name"`
+	TeamID      *uint  `json:"team_id" renameto:"fleet_id" db:"team_id"`
+	CacheProxyStats
+	// HitRate is the ratio of downloads served from the cache.
+	HitRate float64 `json:"hit_rate" db:"-"`
+	// UpdatedAt is the last time the host reported its statistics.
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -1,5 +1,11 @@
 package fleet
 
+import (
+	"context"
+	"io"
+	"time"
+)
+
 // DistributedQueryStatus is the lifecycle status of a distributed query
 // campaign.
 type DistributedQueryStatus int
@@ -19,6 +25,142 @@ type DistributedQueryCampaign struct {
 	QueryID uint                   `json:"query_id" renameto:"report_id" db:"query_id"`
 	Status  DistributedQueryStatus `json:"status"`
 	UserID  uint                   `json:"user_id" db:"user_id"`
+	// Deferred is true for campaigns that keep targeting the hosts that haven't
+	// answered yet, including offline ones, until ExpiresAt. Their results are
+	// stored in the database instead of being streamed to the client.
+	Deferred bool `json:"deferred" db:"deferred"`
+	// ExpiresAt is the time at which a deferred campaign stops targeting hosts.
+	// It is nil for live campaigns.
+	ExpiresAt *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	// SaveResults is true for live campaigns whose results are stored as they
+	// are streamed to the client. The results of deferred campaigns are always
+	// stored.
+	SaveResults bool `json:"save_results" db:"save_results"`
+}
+
+// HasStoredResults returns true if the results of the campaign are stored.
+func (c *DistributedQueryCampaign) HasStoredResults() bool {
+	return c.Deferred || c.SaveResults
+}
+
+// DistributedQueryCampaignOptions are the options of a new distributed query
+// campaign.
+type DistributedQueryCampaignOptions struct {
+	// Deferred creates a deferred campaign that runs for DeferredTTL.
+	Deferred    bool
+	DeferredTTL time.Duration
+	// SaveResults stores the results of a live campaign.
+	SaveResults bool
+}
+
+const (
+	// DeferredQueryDefaultTTL is the default duration during which a deferred
+	// campaign targets its hosts.
+	DeferredQueryDefaultTTL = 24 * time.Hour
+	// DeferredQueryMaxTTL is the maximum duration of a deferred campaign. It
+	// must not exceed the expiration of the live queries in the live query
+	// store.
+	DeferredQueryMaxTTL = 7 * 24 * time.Hour
+)
+
+// CampaignQueryResult is the result of a campaign's query on a single host,
+// as stored for deferred campaigns and campaigns that save their results.
+type CampaignQueryResult struct {
+	HostID uint `json:"host_id" db:"host_id"`
+	// Hostname and HostDisplayName are the host's names when it answered, they
+	// are kept if the host is deleted.
+	Hostname        string              `json:"hostname" db:"hostname"`
+	HostDisplayName string              `json:"host_display_name" db:"host_display_name"`
+	Rows            []map[string]string `json:"rows" db:"-"`
+	// Error is the error reported by osquery when running the query, if any.
+	Error     *string   `json:"error" db:"error"`
+	Stats     *Stats    `json:"stats,omitempty" db:"-"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	// DataKey is the key of the Rows in the CampaignResultsStore when they are
+	// too large to be stored in the database, nil otherwise.
+	DataKey *string `json:"-" db:"data_key"`
+}
+
+// MaxStoredCampaignResultSize is the maximum size of the rows of a host's
+// CampaignQueryResult stored in the database. Larger rows are stored in the
+// CampaignResultsStore when it is configured.
+const MaxStoredCampaignResultSize = 1 << 20
+
+// QueryCampaignProgress is a campaign with stored results and the progress of
+// its hosts.
+type QueryCampaignProgress struct {
+	*DistributedQueryCampaign
+	// TargetedHosts is the number of hosts targeted by the campaign.
+	TargetedHosts uint `json:"targeted_hosts"`
+	// RespondedHosts is the number of hosts that sent back results or an
+	// error.
+	RespondedHosts uint `json:"responded_hosts"`
+}
+
+// QueryCampaignSummary describes a campaign with stored results.
+type QueryCampaignSummary struct {
+	ID          uint                   `json:"id" db:"id"`
+	QueryID     uint                   `json:"query_id" renameto:"report_id" db:"query_id"`
+	QueryName   string                 `json:"query_name" renameto:"report_name" db:"query_name"`
+	QuerySQL    string                 `json:"query" db:"query"`
+	Saved       bool                   `json:"saved" db:"saved"`
+	Status      DistributedQueryStatus `json:"status" db:"status"`
+	Deferred    bool                   `json:"deferred" db:"deferred"`
+	SaveResults bool                   `json:"save_results" db:"save_results"`
+	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
+	ExpiresAt   *time.Time             `json:"expires_at,omitempty" db:"expires_at"`
+	// RespondedHosts is the number of hosts with stored results.
+	RespondedHosts uint `json:"responded_hosts" db:"responded_hosts"`
+}
+
+// ListQueryCampaignsOptions are the options to list the campaigns with stored
+// results.
+type ListQueryCampaignsOptions struct {
+	ListOptions
+	// UserID lists the campaigns of this user.
+	UserID uint
+	// QueryID lists the campaigns of this query, if set.
+	QueryID *uint
+}
+
+// QueryCampaignResultsDiff is the difference between the stored results of
+// two campaigns of the same query.
+type QueryCampaignResultsDiff struct {
+	CampaignID         uint `json:"campaign_id"`
+	PreviousCampaignID uint `json:"previous_campaign_id"`
+	// Hosts are the hosts whose results changed, hosts whose results are the
+	// same in both campaigns are omitted.
+	Hosts []HostQueryResultsDiff `json:"hosts"`
+}
+
+// HostQueryResultsDiff is the difference between the results of a host in two
+// campaigns.
+type HostQueryResultsDiff struct {
+	HostID          uint   `json:"host_id"`
+	Hostname        string `json:"hostname"`
+	HostDisplayName string `json:"host_display_name"`
+	// Status is "new" if the host only responded to the campaign, "missing" if
+	// it only responded to the previous campaign, "changed" otherwise.
+	Status  string              `json:"status"`
+	Added   []map[string]string `json:"added"`
+	Removed []map[string]string `json:"removed"`
+	// Error and PreviousError are the errors of the host in each campaign.
+	Error         *string `json:"error"`
+	PreviousError *string `json:"previous_error"`
+}
+
+const (
+	HostQueryResultsNew     = "new"
+	HostQueryResultsMissing = "missing"
+	HostQueryResultsChanged = "changed"
+)
+
+// CampaignResultsStore stores the results of campaigns that are too large to
+// be stored in the database.
+type CampaignResultsStore interface {
+	Put(ctx context.Context, key string, content io.ReadSeeker) error
+	Get(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	Cleanup(ctx context.Context, usedKeys []string, removeCreatedBefore time.Time) (int, error)
 }
 
 // DistributedQueryCampaignTarget stores a target (host or label) for a
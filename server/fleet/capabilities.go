@@ -111,6 +111,9 @@ const (
 	CapabilityWindowsMDMSync Capability = "windows_mdm_sync"
 	// CapabilityWindowsManagedLocalAccount is set when fleetd can create and hide the Windows managed local admin account and escrow its password.
 	CapabilityWindowsManagedLocalAccount Capability = "windows_managed_local_account"
+	// CapabilityOrbitNotifications denotes the ability of the server to push notification hints to fleetd over the
+	// `/api/fleet/orbit/notifications` websocket, so that fleetd fetches its config as soon as something changes for it.
+	CapabilityOrbitNotifications Capability = "orbit_notifications"
 )
 
 func GetServerOrbitCapabilities() CapabilityMap {
@@ -124,6 +127,7 @@ func GetServerOrbitCapabilities() CapabilityMap {
 		CapabilitySetupExperience:           {},
 		CapabilityWebSetupExperience:        {},
 		CapabilityMacOSWebSetupExperience:   {},
+		CapabilityOrbitNotifications:        {},
 	}
 }
 
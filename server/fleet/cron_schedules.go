@@ -74,6 +74,9 @@ const (
 	// CronMDMAndroidCommandReconciler polls AMAPI for the outcome of Android MDM commands whose Pub/Sub
 	// COMMAND notification never arrived, so they don't stay pending forever. Runs every 24h.
 	CronMDMAndroidCommandReconciler CronScheduleName = "mdm_android_command_reconciler"
+	// CronGitOpsDrift compares the live state of the resources applied by GitOps against their
+	// snapshots and reports new drifts through an activity and the GitOps drift webhook. Runs every 1 hour.
+	CronGitOpsDrift CronScheduleName = "gitops_drift"
 )
 
 type CronSchedulesService interface {
@@ -0,0 +1,164 @@
+package fleet
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const customRoleNameMaxLen = 255
+
+// CustomRole is an admin-defined, named bundle of permissions. Custom roles are
+// assigned to users globally or for a team (see UserCustomRole), and grant
+// their permissions on top of the user's built-in role.
+type CustomRole struct {
+	ID          uint                  `json:"id" db:"id"`
+	Name        string                `json:"name" db:"name"`
+	Description string                `json:"description" db:"description"`
+	Permissions CustomRolePermissions `json:"permissions" db:"permissions"`
+	CreatedAt   time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+func (CustomRole) AuthzType() string {
+	return "custom_role"
+}
+
+// CustomRolePermission grants an action on an authorization object type. For
+// example, "write" on "host_script_result" allows running scripts.
+type CustomRolePermission struct {
+	Object string `json:"object"`
+	Action string `json:"action"`
+}
+
+// CustomRolePermissions is the list of permissions of a custom role. It is
+// stored as JSON in the database.
+type CustomRolePermissions []CustomRolePermission
+
+// Scan implements the sql.Scanner interface
+func (p *CustomRolePermissions) Scan(val any) error {
+	switch v := val.(type) {
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	case nil: // sql NULL
+		return nil
+	default:
+		return fmt.Errorf("unsupported type: %T", v)
+	}
+}
+
+// Value implements the sql.Valuer interface
+func (p CustomRolePermissions) Value() (driver.Value, error) {
+	if p == nil {
+		p = CustomRolePermissions{}
+	}
+	return json.Marshal(p)
+}
+
+// customRoleGrantableActions lists the object types a custom role can grant
+// permissions on, with the actions that can be granted for each. Users,
+// invites, sessions, teams, app config and custom roles themselves are
+// deliberately not grantable, so that a custom role can never be used to
+// escalate privileges. Transferring hosts isn't grantable either, as a custom
+// role assigned for a fleet would allow moving hosts to fleets its holder
+// doesn't control.
+var customRoleGrantableActions = map[string][]string{
+	"activity":                       {ActionRead},
+	"host":                           {ActionRead, ActionList, ActionWrite, ActionWriteHostLabel, ActionCancelHostActivity},
+	"host_health":                    {ActionRead},
+	"host_script_result":             {ActionRead, ActionWrite},
+	"host_software_installer_result": {ActionRead, ActionWrite},
+	"installable_entity":             {ActionRead, ActionWrite},
+	"label":                          {ActionRead, ActionWrite, ActionCreate},
+	"mdm_command":                    {ActionRead, ActionWrite},
+	"mdm_config_profile":             {ActionRead, ActionWrite, ActionResend},
+	"pack":                           {ActionRead, ActionWrite},
+	"policy":                         {ActionRead, ActionWrite},
+	"query":                          {ActionRead, ActionWrite, ActionRunNew},
+	"script":                         {ActionRead, ActionWrite},
+	"software_category":              {ActionRead},
+	"software_inventory":             {ActionRead, ActionWrite},
+	"target":                         {ActionRead},
+	"targeted_query":                 {ActionRun},
+}
+
+// ValidateCustomRole verifies the name and permissions of a custom role.
+func ValidateCustomRole(name string, permissions []CustomRolePermission) error {
+	invalid := &InvalidArgumentError{}
+	switch {
+	case strings.TrimSpace(name) == "":
+		invalid.Append("name", "Custom role name can't be empty")
+	case utf8.RuneCountInString(name) > customRoleNameMaxLen:
+		invalid.Append("name", fmt.Sprintf("Custom role name can't be longer than %d characters", customRoleNameMaxLen))
+	case ValidGlobalRole(strings.ToLower(strings.TrimSpace(name))):
+		invalid.Append("name", fmt.Sprintf("Custom role name %q is reserved for a built-in role", name))
+	}
+
+	if len(permissions) == 0 {
+		invalid.Append("permissions", "Custom role must have at least one permission")
+	}
+	seen := make(map[CustomRolePermission]bool, len(permissions))
+	for _, p := range permissions {
+		actions, ok := customRoleGrantableActions[p.Object]
+		switch {
+		case !ok:
+			invalid.Append("permissions", fmt.Sprintf("Object %q can't be granted by a custom role", p.Object))
+		case !slices.Contains(actions, p.Action):
+			invalid.Append("permissions", fmt.Sprintf("Action %q can't be granted on object %q (must be one of: %s)",
+				p.Action, p.Object, strings.Join(actions, ", ")))
+		case seen[p]:
+			invalid.Append("permissions", fmt.Sprintf("Duplicate permission %q on object %q", p.Action, p.Object))
+		}
+		seen[p] = true
+	}
+
+	if invalid.HasErrors() {
+		return invalid
+	}
+	return nil
+}
+
+// UserCustomRole is the assignment of a custom role to a user. A nil TeamID
+// grants the role's permissions on all objects, otherwise only on the objects
+// of that team.
+type UserCustomRole struct {
+	CustomRoleID uint  `json:"custom_role_id" db:"custom_role_id"`
+	TeamID       *uint `json:"team_id" db:"team_id" renameto:"fleet_id"`
+
+	// Name and Permissions are loaded from the custom role, they are ignored
+	// when setting a user's custom roles.
+	Name        string                `json:"name" db:"name"`
+	Permissions CustomRolePermissions `json:"permissions" db:"permissions"`
+}
+
+// GrantsRead returns true if the custom role grants read or list on at least
+// one object type, which makes the objects of its scope visible in listings.
+func (r UserCustomRole) GrantsRead() bool {
+	for _, p := range r.Permissions {
+		if p.Action == ActionRead || p.Action == ActionList {
+			return true
+		}
+	}
+	return false
+}
+
+// CustomRolePayload is used to create or modify a custom role.
+type CustomRolePayload struct {
+	Name        *string                 `json:"name"`
+	Description *string                 `json:"description"`
+	Permissions *[]CustomRolePermission `json:"permissions"`
+}
+
+// CustomRoleSpec is the GitOps representation of a custom role. Custom roles
+// are matched by name.
+type CustomRoleSpec struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Permissions []CustomRolePermission `json:"permissions"`
+}
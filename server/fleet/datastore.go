@@ -92,6 +92,10 @@ type Datastore interface {
 	// SaveUserIfNotLastAdmin atomically checks that there's more than one admin
 	// before saving the user. Returns ErrLastGlobalAdmin if there's only one last global admin.
 	SaveUserIfNotLastAdmin(ctx context.Context, user *User) error
+	// DisableUser marks the user as disabled and revokes all its sessions
+	// (including API tokens) and pending MFA email tokens. Returns
+	// ErrLastGlobalAdmin if the user is the last enabled global admin.
+	DisableUser(ctx context.Context, id uint) error
 	// PendingEmailChange creates a record with a pending email change for a user identified by uid. The change record
 	// is keyed by a unique token. The token is emailed to the user with a link that they can use to confirm the change.
 	PendingEmailChange(ctx context.Context, userID uint, newEmail, token string) error
@@ -179,10 +183,41 @@ type Datastore interface {
 
 	// CleanupCompletedCampaignTargets removes campaign targets for campaigns that have been
 	// completed for more than the specified duration. This helps reduce database size by
-	// cleaning up historical data that is no longer needed. Returns the number of
-	// targets deleted.
+	// cleaning up historical data that is no longer needed. Targets of deferred campaigns
+	// are kept. Returns the number of targets deleted.
 	CleanupCompletedCampaignTargets(ctx context.Context, olderThan time.Time) (deleted uint, err error)
 
+	// CompleteExpiredDeferredQueryCampaigns moves the deferred campaigns that
+	// expired before now to the QueryComplete state, so that their hosts stop
+	// receiving the query. It returns the number of completed campaigns.
+	CompleteExpiredDeferredQueryCampaigns(ctx context.Context, now time.Time) (completed uint, err error)
+
+	// SaveCampaignQueryResult stores the result of a campaign's query on a
+	// host, replacing any previous result of that host.
+	SaveCampaignQueryResult(ctx context.Context, campaignID uint, result *CampaignQueryResult) error
+
+	// ListCampaignQueryResults returns a page of the stored results of a
+	// campaign, ordered by host ID by default.
+	ListCampaignQueryResults(ctx context.Context, campaignID uint, opts ListOptions) ([]*CampaignQueryResult, *PaginationMetadata, error)
+
+	// CountCampaignQueryResults returns the number of hosts with stored results
+	// for a campaign.
+	CountCampaignQueryResults(ctx context.Context, campaignID uint) (uint, error)
+
+	// ListQueryCampaigns returns a page of the campaigns with stored results of
+	// a user, most recent first by default.
+	ListQueryCampaigns(ctx context.Context, opts ListQueryCampaignsOptions) ([]*QueryCampaignSummary, *PaginationMetadata, error)
+
+	// CleanupExpiredQueryCampaigns deletes the completed campaigns with stored
+	// results that were created before olderThan, along with their results. It
+	// returns the number of deleted campaigns.
+	CleanupExpiredQueryCampaigns(ctx context.Context, olderThan time.Time) (deleted uint, err error)
+
+	// CleanupUnusedCampaignResultsData removes the campaign results data from
+	// the store that is not referenced by any stored result and was created
+	// before removeCreatedBefore.
+	CleanupUnusedCampaignResultsData(ctx context.Context, resultsStore CampaignResultsStore, removeCreatedBefore time.Time) error
+
 	// GetCompletedCampaigns returns the IDs of the campaigns that are in the fleet.QueryComplete state and that are in the
 	// provided list of IDs. The return value is a slice of the IDs of the completed campaigns and any error.
 	GetCompletedCampaigns(ctx context.Context, filter []uint) ([]uint, error)
@@ -604,6 +639,61 @@ type Datastore interface {
 	// NewMFAToken creates a new MFA token for a given user and stores it
 	NewMFAToken(ctx context.Context, userID uint) (string, error)
 
+	///////////////////////////////////////////////////////////////////////////////
+	// UserMFAStore contains the methods to manage the second factors of users
+
+	// SetUserPendingTOTPSecret stores a new TOTP secret for the user, to be
+	// confirmed with ConfirmUserTOTP. It replaces any other pending secret but
+	// never a confirmed one.
+	SetUserPendingTOTPSecret(ctx context.Context, userID uint, secret []byte) error
+	// UserTOTP returns the TOTP secret of the user, confirmed or pending.
+	UserTOTP(ctx context.Context, userID uint) (*UserTOTP, error)
+	// ConfirmUserTOTP confirms the pending TOTP secret of the user, step is
+	// the time step of the code used to confirm it.
+	ConfirmUserTOTP(ctx context.Context, userID uint, step int64) error
+	// UseUserTOTPStep records that the code of the time step was used to log
+	// in. It returns false if the code of that step or a later one was already
+	// used.
+	UseUserTOTPStep(ctx context.Context, userID uint, step int64) (bool, error)
+	// DeleteUserTOTP deletes the TOTP secret of the user.
+	DeleteUserTOTP(ctx context.Context, userID uint) error
+
+	// ReplaceUserMFARecoveryCodes replaces the recovery codes of the user by
+	// the given hashes.
+	ReplaceUserMFARecoveryCodes(ctx context.Context, userID uint, codeHashes [][]byte) error
+	// CountUserMFARecoveryCodes returns the number of unused recovery codes of
+	// the user.
+	CountUserMFARecoveryCodes(ctx context.Context, userID uint) (int, error)
+	// ConsumeUserMFARecoveryCode marks the recovery code with the hash as
+	// used. It returns false if the user has no such unused code.
+	ConsumeUserMFARecoveryCode(ctx context.Context, userID uint, codeHash []byte) (bool, error)
+
+	// NewUserWebAuthnCredential stores a new WebAuthn credential.
+	NewUserWebAuthnCredential(ctx context.Context, cred *WebAuthnCredential) (*WebAuthnCredential, error)
+	// ListUserWebAuthnCredentials returns the WebAuthn credentials of the user.
+	ListUserWebAuthnCredentials(ctx context.Context, userID uint) ([]*WebAuthnCredential, error)
+	// UpdateUserWebAuthnCredentialSignCount records a use of the credential
+	// and its new signature counter.
+	UpdateUserWebAuthnCredentialSignCount(ctx context.Context, id uint, signCount uint32) error
+	// DeleteUserWebAuthnCredential deletes a WebAuthn credential of the user.
+	DeleteUserWebAuthnCredential(ctx context.Context, userID, id uint) error
+
+	// ResetUserMFA deletes all the second factors, recovery codes and pending
+	// MFA challenges of the user.
+	ResetUserMFA(ctx context.Context, userID uint) error
+
+	// NewUserMFAChallenge creates a new MFA challenge for the user.
+	NewUserMFAChallenge(ctx context.Context, userID uint, purpose string, webAuthnChallenge []byte) (*UserMFAChallenge, error)
+	// UserMFAChallenge returns the MFA challenge identified by the token, if it
+	// is not expired nor exhausted by too many failures.
+	UserMFAChallenge(ctx context.Context, token string) (*UserMFAChallenge, error)
+	// RecordUserMFAChallengeFailure records a failed attempt to complete the
+	// MFA challenge.
+	RecordUserMFAChallengeFailure(ctx context.Context, token string) error
+	// ConsumeUserMFAChallenge deletes the MFA challenge once completed. It
+	// returns a NotFoundError if it was already consumed.
+	ConsumeUserMFAChallenge(ctx context.Context, token string) error
+
 	///////////////////////////////////////////////////////////////////////////////
 	// AppConfigStore contains method for saving and retrieving application configuration
 
@@ -685,6 +775,20 @@ type Datastore interface {
 	// Deletes are batched to avoid large binlogs and long lock times. This runs as a cron job.
 	// Returns a map of query IDs to their current row count after cleanup (for syncing Redis counters).
 	CleanupExcessQueryResultRows(ctx context.Context, maxQueryReportRows int, opts ...CleanupExcessQueryResultRowsOptions) (map[uint]int, error)
+	// InsertQueryResultHistory stores result batches in the history of their reports.
+	InsertQueryResultHistory(ctx context.Context, batches []*QueryResultHistoryBatch) error
+	// QueryResultHistoryRows returns the result rows of the history of a report
+	// fetched between from and to (inclusive), with LastFetched set to the time
+	// of their batch.
+	QueryResultHistoryRows(ctx context.Context, queryID uint, from, to time.Time, filter TeamFilter) ([]*ScheduledQueryResultRow, error)
+	// QueryResultHistorySnapshot returns the rows of the latest batch of each
+	// host fetched at or before the given time. A host whose batch has no rows
+	// gets a single row with nil Data.
+	QueryResultHistorySnapshot(ctx context.Context, queryID uint, at time.Time, filter TeamFilter) ([]*ScheduledQueryResultRow, error)
+	// CleanupQueryResultHistory deletes the report history batches fetched
+	// before olderThan, and the oldest batches of reports with more than
+	// maxRowsPerQuery rows in their history.
+	CleanupQueryResultHistory(ctx context.Context, olderThan time.Time, maxRowsPerQuery int) error
 	// ListHostReports returns the queries/reports associated with the given host, applying
 	// the provided options for filtering, sorting, and pagination. teamID is the team of the
 	// host (nil for global). maxQueryReportRows is the configured report cap used to determine
@@ -1102,6 +1206,19 @@ type Datastore interface {
 	ListCalendarEvents(ctx context.Context, teamID *uint) ([]*CalendarEvent, error)
 	ListOutOfDateCalendarEvents(ctx context.Context, t time.Time) ([]*CalendarEvent, error)
 
+	///////////////////////////////////////////////////////////////////////////////
+	// ServiceNow incidents
+
+	// RecordServiceNowPolicyIncident records that the ServiceNow integration
+	// opened (or updated) an incident for the failing policy.
+	RecordServiceNowPolicyIncident(ctx context.Context, policyID uint) error
+	// DeleteServiceNowPolicyIncident deletes the record of the ServiceNow
+	// incident of the policy, once it is resolved or no longer open.
+	DeleteServiceNowPolicyIncident(ctx context.Context, policyID uint) error
+	// ListServiceNowPolicyIncidents returns the IDs of the policies with a
+	// recorded ServiceNow incident.
+	ListServiceNowPolicyIncidents(ctx context.Context) ([]uint, error)
+
 	///////////////////////////////////////////////////////////////////////////////
 	// Team Policies
 
@@ -3550,6 +3667,141 @@ type Datastore interface {
 
 	UpsertCustomHostVitals(ctx context.Context, vitals []CustomHostVital) (created []CustomHostVital, deleted []CustomHostVital, err error)
 
+	// /////////////////////////////////////////////////////////////////////////////
+	// Custom roles
+
+	// NewCustomRole creates a custom role. Returns an AlreadyExistsError if a
+	// custom role with the same name exists.
+	NewCustomRole(ctx context.Context, role *CustomRole) (*CustomRole, error)
+	// CustomRole returns the custom role with the given ID.
+	CustomRole(ctx context.Context, id uint) (*CustomRole, error)
+	// ListCustomRoles returns all custom roles, ordered by name.
+	ListCustomRoles(ctx context.Context) ([]CustomRole, error)
+	// SaveCustomRole updates the name, description and permissions of a custom
+	// role.
+	SaveCustomRole(ctx context.Context, role *CustomRole) (*CustomRole, error)
+	// DeleteCustomRole deletes a custom role, which is unassigned from all users.
+	DeleteCustomRole(ctx context.Context, id uint) error
+	// SetUserCustomRoles replaces the custom roles assigned to a user.
+	SetUserCustomRoles(ctx context.Context, userID uint, roles []UserCustomRole) error
+	// ApplyCustomRoles reconciles the custom roles with specs, matching them by
+	// name: roles are created or updated, and roles absent from specs are
+	// deleted.
+	ApplyCustomRoles(ctx context.Context, specs []CustomRoleSpec) (created, edited, deleted []CustomRole, err error)
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// VEX documents
+
+	// NewVEXDocument creates a VEX document with its statements. Returns an
+	// AlreadyExistsError if a VEX document with the same name exists.
+	NewVEXDocument(ctx context.Context, doc *VEXDocument) (*VEXDocument, error)
+	// VEXDocument returns the VEX document with the given ID.
+	VEXDocument(ctx context.Context, id uint) (*VEXDocument, error)
+	// ListVEXDocuments returns all VEX documents, ordered by name, with their
+	// statements and suppressed vulnerabilities counts.
+	ListVEXDocuments(ctx context.Context) ([]*VEXDocument, error)
+	// DeleteVEXDocument deletes a VEX document, its statements and the
+	// suppressions they caused. The suppressed software vulnerabilities are
+	// restored on the next vulnerabilities scan.
+	DeleteVEXDocument(ctx context.Context, id uint) error
+	// ListVEXStatements returns the statements of all VEX documents with one of
+	// the given statuses.
+	ListVEXStatements(ctx context.Context, statuses []VEXStatus) ([]VEXStatement, error)
+	// ListVEXCandidates returns the software vulnerabilities for the given CVEs,
+	// with the software details and CPE needed to match VEX statements.
+	ListVEXCandidates(ctx context.Context, cves []string) ([]VEXCandidate, error)
+	// ReplaceVEXSuppressions replaces all VEX suppressions with the given ones
+	// and deletes the suppressed software vulnerabilities.
+	ReplaceVEXSuppressions(ctx context.Context, suppressions []VEXSuppression) error
+	// ListVEXSuppressions returns the software vulnerabilities suppressed by VEX
+	// statements.
+	ListVEXSuppressions(ctx context.Context, opts VEXSuppressionListOptions) ([]VEXSuppression, *PaginationMetadata, error)
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Vulnerability exceptions
+
+	// NewVulnerabilityException creates a vulnerability exception. Returns an
+	// AlreadyExistsError if an exception with the same CVE, software title and
+	// team exists.
+	NewVulnerabilityException(ctx context.Context, e *VulnerabilityException) (*VulnerabilityException, error)
+	// VulnerabilityException returns the vulnerability exception with the given
+	// ID.
+	VulnerabilityException(ctx context.Context, id uint) (*VulnerabilityException, error)
+	ListVulnerabilityExceptions(ctx context.Context, opts VulnerabilityExceptionListOptions) ([]VulnerabilityException, *PaginationMetadata, error)
+	// SaveVulnerabilityException updates the reason, owner and expiration date
+	// of a vulnerability exception.
+	SaveVulnerabilityException(ctx context.Context, e *VulnerabilityException) (*VulnerabilityException, error)
+	DeleteVulnerabilityException(ctx context.Context, id uint) error
+	// ApplyVulnerabilityExceptions reconciles the vulnerability exceptions with
+	// the given ones, matching them by CVE, software title and team: exceptions
+	// are created or updated, and exceptions absent from the list are deleted.
+	ApplyVulnerabilityExceptions(ctx context.Context, exceptions []VulnerabilityException) (created, edited, deleted []VulnerabilityException, err error)
+	// ExpireVulnerabilityExceptions returns the vulnerability exceptions that
+	// expired at or before now since the last call, and marks them so that they
+	// are returned only once.
+	ExpireVulnerabilityExceptions(ctx context.Context, now time.Time) ([]VulnerabilityException, error)
+	// FilterExceptedSoftwareVulnerabilities removes the software
+	// vulnerabilities covered by an active vulnerability exception applying to
+	// all fleets.
+	FilterExceptedSoftwareVulnerabilities(ctx context.Context, vulns []SoftwareVulnerability) ([]SoftwareVulnerability, error)
+	// VulnerabilityExceptedHostIDs returns the IDs of the hosts with any of the
+	// given software, where all that software is covered for the CVE by an
+	// active vulnerability exception.
+	VulnerabilityExceptedHostIDs(ctx context.Context, cve string, softwareIDs []uint) ([]uint, error)
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Webhook deliveries
+
+	// NewWebhookDelivery records a webhook delivery before its first attempt.
+	NewWebhookDelivery(ctx context.Context, d *WebhookDelivery) (*WebhookDelivery, error)
+	// UpdateWebhookDelivery saves the status, the number of attempts and the
+	// result of the last attempt of a webhook delivery.
+	UpdateWebhookDelivery(ctx context.Context, d *WebhookDelivery) error
+	// WebhookDelivery returns the webhook delivery with the given ID, including
+	// its payload.
+	WebhookDelivery(ctx context.Context, id uint) (*WebhookDelivery, error)
+	// ListWebhookDeliveries returns the webhook deliveries without their
+	// payload, most recent first by default.
+	ListWebhookDeliveries(ctx context.Context, opts WebhookDeliveryListOptions) ([]WebhookDelivery, *PaginationMetadata, error)
+	// CleanupWebhookDeliveries deletes the webhook deliveries created before
+	// olderThan that are not pending, and returns the number deleted.
+	CleanupWebhookDeliveries(ctx context.Context, olderThan time.Time) (int64, error)
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// GitOps snapshots
+
+	// SaveGitOpsSnapshot creates or replaces the GitOps snapshot of a fleet (0
+	// for the global config), clearing the drift found for the previous one.
+	SaveGitOpsSnapshot(ctx context.Context, snapshot *GitOpsSnapshot) error
+	// GitOpsSnapshot returns the GitOps snapshot of a fleet (0 for the global
+	// config).
+	GitOpsSnapshot(ctx context.Context, teamID uint) (*GitOpsSnapshot, error)
+	// ListGitOpsSnapshots returns all the GitOps snapshots, sorted by fleet ID.
+	ListGitOpsSnapshots(ctx context.Context) ([]*GitOpsSnapshot, error)
+	// UpdateGitOpsSnapshotDrift saves the drift found by a check of the GitOps
+	// snapshot of a fleet (0 for the global config), along with its checksum
+	// and the time of the check.
+	UpdateGitOpsSnapshotDrift(ctx context.Context, teamID uint, drift []GitOpsResourceChange, driftChecksum string, checkedAt time.Time) error
+	// DeleteGitOpsSnapshot deletes the GitOps snapshot of a fleet (0 for the
+	// global config).
+	DeleteGitOpsSnapshot(ctx context.Context, teamID uint) error
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// fleetd caching proxies
+
+	// UpsertHostCacheProxyStats stores the last statistics reported by a host
+	// running fleetd in caching proxy mode.
+	UpsertHostCacheProxyStats(ctx context.Context, hostID uint, stats CacheProxyStats) error
+	// ListHostCacheProxies returns the hosts that reported caching proxy
+	// statistics and that match the team filter, sorted by host ID.
+	ListHostCacheProxies(ctx context.Context, filter TeamFilter) ([]*HostCacheProxy, error)
+	// GetCacheProxySoftwareInstaller returns the software installer with the
+	// given SHA256 that the host, running fleetd in caching proxy mode, may
+	// download: an installer of the host's fleet with an unfinished install
+	// request. It returns a NotFoundError if there is no such installer or if
+	// the host never reported caching proxy statistics.
+	GetCacheProxySoftwareInstaller(ctx context.Context, hostID uint, hashSHA256 string) (*SoftwareInstaller, error)
+
 	// /////////////////////////////////////////////////////////////////////////////
 	// Android
 
@@ -3696,6 +3948,9 @@ type Datastore interface {
 	ReplaceScimGroup(ctx context.Context, group *ScimGroup) error
 	// DeleteScimGroup deletes a SCIM group from the database
 	DeleteScimGroup(ctx context.Context, id uint) error
+	// ScimGroupMemberUserIDs returns the IDs of the SCIM users who are members
+	// of the group, directly or through its (recursively) nested groups.
+	ScimGroupMemberUserIDs(ctx context.Context, groupID uint) ([]uint, error)
 	// ListScimGroups retrieves a list of SCIM groups with pagination
 	ListScimGroups(ctx context.Context, opts ScimGroupsListOptions) (groups []ScimGroup, totalResults uint, err error)
 	// ScimLastRequest retrieves the last SCIM request info
@@ -0,0 +1,217 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// GitOpsChangeOp is the kind of change made to a GitOps-managed resource.
+type GitOpsChangeOp string
+
+const (
+	GitOpsChangeAdd    GitOpsChangeOp = "+"
+	GitOpsChangeRemove GitOpsChangeOp = "-"
+	GitOpsChangeModify GitOpsChangeOp = "~"
+)
+
+// Kinds of GitOps-managed resources that are diffed.
+const (
+	GitOpsResourceOrgSettings   = "org_settings"
+	GitOpsResourceFleetSettings = "fleet_settings"
+	GitOpsResourcePolicy        = "policy"
+	GitOpsResourceReport        = "report"
+	GitOpsResourceLabel         = "label"
+	GitOpsResourceScript        = "script"
+	GitOpsResourceProfile       = "profile"
+	GitOpsResourceSoftware      = "software"
+	GitOpsResourceSecret        = "secret"
+)
+
+// GitOpsFieldChange is the change of a single field of a GitOps-managed
+// resource. Nested fields are dot-separated, e.g. "features.enable_host_users".
+type GitOpsFieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+// GitOpsResourceChange is the change of a GitOps-managed resource.
+type GitOpsResourceChange struct {
+	Kind string `json:"kind"`
+	// Fleet is the name of the fleet of the resource, empty for global resources.
+	Fleet  string              `json:"fleet,omitempty"`
+	Name   string              `json:"name"`
+	Op     GitOpsChangeOp      `json:"op"`
+	Fields []GitOpsFieldChange `json:"fields,omitempty"`
+}
+
+// NormalizeGitOpsFields returns v (a struct or a map) as a map of its JSON
+// fields, so that values coming from the server and from GitOps files can be
+// compared regardless of their Go types (e.g. uint and float64 numbers).
+func NormalizeGitOpsFields(v any) (map[string]any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshal gitops fields: %w", err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("unmarshal gitops fields: %w", err)
+	}
+	return m, nil
+}
+
+// DiffGitOpsFields returns the changes between the current and the desired
+// fields of a resource, sorted by field. Nested objects are compared field by
+// field, any other value (including lists) is compared as a whole. Fields
+// whose current value is MaskedPassword are skipped since the actual value is
+// not known. Values of sensitive fields are masked in the result.
+func DiffGitOpsFields(current, desired map[string]any) []GitOpsFieldChange {
+	var changes []GitOpsFieldChange
+	diffGitOpsFields("", current, desired, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func diffGitOpsFields(prefix string, current, desired map[string]any, changes *[]GitOpsFieldChange) {
+	keys := make(map[string]struct{}, len(current)+len(desired))
+	for k := range current {
+		keys[k] = struct{}{}
+	}
+	for k := range desired {
+		keys[k] = struct{}{}
+	}
+
+	for k := range keys {
+		field := prefix + k
+		oldV, newV := current[k], desired[k]
+		oldM, oldIsMap := oldV.(map[string]any)
+		newM, newIsMap := newV.(map[string]any)
+		if oldIsMap && newIsMap {
+			diffGitOpsFields(field+".", oldM, newM, changes)
+			continue
+		}
+		if s, ok := oldV.(string); ok && s == MaskedPassword {
+			continue
+		}
+		if gitOpsValuesEqual(oldV, newV) {
+			continue
+		}
+		if IsSensitiveGitOpsField(field) {
+			oldV, newV = maskGitOpsValue(oldV), maskGitOpsValue(newV)
+		}
+		*changes = append(*changes, GitOpsFieldChange{Field: field, Old: oldV, New: newV})
+	}
+}
+
+// ProjectGitOpsFields returns the fields of current restricted to the
+// (possibly nested) fields set in desired. It is used for settings where keys
+// omitted from GitOps files are left untouched by the server.
+func ProjectGitOpsFields(current, desired map[string]any) map[string]any {
+	projected := make(map[string]any, len(desired))
+	for k, newV := range desired {
+		oldV, ok := current[k]
+		if !ok {
+			continue
+		}
+		oldM, oldIsMap := oldV.(map[string]any)
+		newM, newIsMap := newV.(map[string]any)
+		if oldIsMap && newIsMap {
+			projected[k] = ProjectGitOpsFields(oldM, newM)
+			continue
+		}
+		projected[k] = oldV
+	}
+	return projected
+}
+
+// gitOpsValuesEqual compares normalized values, treating nil and zero values
+// (false, 0, empty strings, lists and objects) as equal since GitOps files and
+// the API use them interchangeably for "not set".
+func gitOpsValuesEqual(a, b any) bool {
+	if isEmptyGitOpsValue(a) && isEmptyGitOpsValue(b) {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func isEmptyGitOpsValue(v any) bool {
+	switch tv := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !tv
+	case float64:
+		return tv == 0
+	case string:
+		return tv == ""
+	case []any:
+		return len(tv) == 0
+	case map[string]any:
+		return len(tv) == 0
+	}
+	return false
+}
+
+// DiffGitOpsResources returns the changes between the current and the desired
+// resources of a kind, keyed by name with their normalized fields. Resources
+// are sorted by name.
+func DiffGitOpsResources(kind, fleetName string, current, desired map[string]map[string]any) []GitOpsResourceChange {
+	names := make([]string, 0, len(current)+len(desired))
+	for name := range current {
+		names = append(names, name)
+	}
+	for name := range desired {
+		if _, ok := current[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []GitOpsResourceChange
+	for _, name := range names {
+		cur, inCurrent := current[name]
+		des, inDesired := desired[name]
+		change := GitOpsResourceChange{Kind: kind, Fleet: fleetName, Name: name}
+		switch {
+		case !inDesired:
+			change.Op = GitOpsChangeRemove
+		case !inCurrent:
+			change.Op = GitOpsChangeAdd
+			change.Fields = DiffGitOpsFields(nil, des)
+		default:
+			change.Op = GitOpsChangeModify
+			change.Fields = DiffGitOpsFields(cur, des)
+			if len(change.Fields) == 0 {
+				continue
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// sensitiveGitOpsFieldParts are the parts of field names whose values are
+// never shown in GitOps diffs.
+var sensitiveGitOpsFieldParts = []string{"secret", "password", "token", "api_key", "private_key", "credential", "passphrase"}
+
+// IsSensitiveGitOpsField reports whether the values of the (possibly nested)
+// field must be masked in GitOps diffs.
+func IsSensitiveGitOpsField(field string) bool {
+	lower := strings.ToLower(field)
+	for _, part := range sensitiveGitOpsFieldParts {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func maskGitOpsValue(v any) any {
+	if isEmptyGitOpsValue(v) {
+		return v
+	}
+	return MaskedPassword
+}
@@ -0,0 +1,102 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffGitOpsFields(t *testing.T) {
+	current, err := NormalizeGitOpsFields(map[string]any{
+		"name":        "foo",
+		"interval":    uint(3600),
+		"platform":    "",
+		"labels":      []string{"a", "b"},
+		"api_token":   "old-token",
+		"private_key": MaskedPassword,
+		"features": map[string]any{
+			"enable_host_users":         true,
+			"enable_software_inventory": false,
+		},
+	})
+	require.NoError(t, err)
+	desired, err := NormalizeGitOpsFields(map[string]any{
+		"name":        "foo",
+		"interval":    3600.0,
+		"labels":      []string{"a"},
+		"api_token":   "new-token",
+		"private_key": "whatever",
+		"features": map[string]any{
+			"enable_host_users":         false,
+			"enable_software_inventory": false,
+		},
+	})
+	require.NoError(t, err)
+
+	changes := DiffGitOpsFields(current, desired)
+	require.Equal(t, []GitOpsFieldChange{
+		{Field: "api_token", Old: MaskedPassword, New: MaskedPassword},
+		{Field: "features.enable_host_users", Old: true, New: false},
+		{Field: "labels", Old: []any{"a", "b"}, New: []any{"a"}},
+	}, changes)
+
+	require.Empty(t, DiffGitOpsFields(current, current))
+}
+
+func TestDiffGitOpsResources(t *testing.T) {
+	current := map[string]map[string]any{
+		"removed":   {"query": "SELECT 1"},
+		"unchanged": {"query": "SELECT 2"},
+		"modified":  {"query": "SELECT 3", "interval": 60.0},
+	}
+	desired := map[string]map[string]any{
+		"unchanged": {"query": "SELECT 2"},
+		"modified":  {"query": "SELECT 3", "interval": 120.0},
+		"added":     {"query": "SELECT 4"},
+	}
+
+	changes := DiffGitOpsResources(GitOpsResourceReport, "Workstations", current, desired)
+	require.Equal(t, []GitOpsResourceChange{
+		{
+			Kind: GitOpsResourceReport, Fleet: "Workstations", Name: "added", Op: GitOpsChangeAdd,
+			Fields: []GitOpsFieldChange{{Field: "query", New: "SELECT 4"}},
+		},
+		{
+			Kind: GitOpsResourceReport, Fleet: "Workstations", Name: "modified", Op: GitOpsChangeModify,
+			Fields: []GitOpsFieldChange{{Field: "interval", Old: 60.0, New: 120.0}},
+		},
+		{Kind: GitOpsResourceReport, Fleet: "Workstations", Name: "removed", Op: GitOpsChangeRemove},
+	}, changes)
+}
+
+func TestIsSensitiveGitOpsField(t *testing.T) {
+	for field, want := range map[string]bool{
+		"name":                         false,
+		"secret":                       true,
+		"integrations.jira.api_token":  true,
+		"smtp_settings.password":       true,
+		"webhook_settings.destination": false,
+		"mdm.apple_server_private_key": true,
+	} {
+		require.Equal(t, want, IsSensitiveGitOpsField(field), field)
+	}
+}
+
+func TestProjectGitOpsFields(t *testing.T) {
+	current := map[string]any{
+		"org_info": map[string]any{"org_name": "Acme", "org_logo_url": "https://example.com/logo.png"},
+		"features": map[string]any{"enable_host_users": true},
+		"server_settings": map[string]any{
+			"server_url": "https://fleet.example.com",
+		},
+	}
+	desired := map[string]any{
+		"org_info":        map[string]any{"org_name": "Acme Corp"},
+		"server_settings": "invalid",
+		"new_setting":     true,
+	}
+	require.Equal(t, map[string]any{
+		"org_info":        map[string]any{"org_name": "Acme"},
+		"server_settings": map[string]any{"server_url": "https://fleet.example.com"},
+	}, ProjectGitOpsFields(current, desired))
+}
@@ -0,0 +1,96 @@
+package fleet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// GitOpsResources are the normalized fields of GitOps-managed resources, by
+// kind (e.g. GitOpsResourcePolicy) and name.
+type GitOpsResources map[string]map[string]map[string]any
+
+// Checksum returns the hex-encoded SHA-256 checksum of the resources. It is
+// stable since the keys of JSON objects are marshaled in sorted order.
+func (r GitOpsResources) Checksum() (string, error) {
+	return gitOpsChecksum(r)
+}
+
+// Diff returns the changes made to the resources to get to the live ones,
+// sorted by kind and name. Resources removed since r was captured are
+// GitOpsChangeRemove changes and resources added are GitOpsChangeAdd changes.
+func (r GitOpsResources) Diff(fleetName string, live GitOpsResources) []GitOpsResourceChange {
+	kinds := make([]string, 0, len(r)+len(live))
+	for kind := range r {
+		kinds = append(kinds, kind)
+	}
+	for kind := range live {
+		if _, ok := r[kind]; !ok {
+			kinds = append(kinds, kind)
+		}
+	}
+	sort.Strings(kinds)
+
+	var changes []GitOpsResourceChange
+	for _, kind := range kinds {
+		changes = append(changes, DiffGitOpsResources(kind, fleetName, r[kind], live[kind])...)
+	}
+	return changes
+}
+
+// GitOpsSnapshot is the normalized state of the GitOps-managed resources of a
+// fleet, or of the global config, captured right after `fleetctl gitops`
+// applied it. The live state is compared against it to find the changes made
+// outside of GitOps (drift).
+type GitOpsSnapshot struct {
+	// TeamID is the ID of the fleet of the snapshot, 0 for the global config.
+	TeamID    uint
+	Resources GitOpsResources
+	// Checksum is the checksum of Resources.
+	Checksum  string
+	AppliedAt time.Time
+
+	// Drift is the drift found by the last check, empty if the live state
+	// matched the snapshot.
+	Drift []GitOpsResourceChange
+	// DriftChecksum is the checksum of Drift, empty if there is no drift. It is
+	// used to notify about a drift only once.
+	DriftChecksum string
+	// CheckedAt is the time of the last drift check, nil if it never ran.
+	CheckedAt *time.Time
+}
+
+// GitOpsDrift is the drift of a fleet, or of the global config, from what
+// `fleetctl gitops` last applied.
+type GitOpsDrift struct {
+	// TeamID is the ID of the fleet, nil for the global config.
+	TeamID *uint `json:"team_id" renameto:"fleet_id"`
+	// TeamName is the name of the fleet, nil for the global config.
+	TeamName  *string   `json:"team_name" renameto:"fleet_name"`
+	AppliedAt time.Time `json:"applied_at"`
+	CheckedAt time.Time `json:"checked_at"`
+	// Changes are the changes made since GitOps applied the resources, the old
+	// values being the applied ones and the new values the live ones.
+	Changes []GitOpsResourceChange `json:"changes"`
+}
+
+// GitOpsDriftChecksum returns the checksum of the changes of a drift, empty if
+// there are no changes.
+func GitOpsDriftChecksum(changes []GitOpsResourceChange) (string, error) {
+	if len(changes) == 0 {
+		return "", nil
+	}
+	return gitOpsChecksum(changes)
+}
+
+func gitOpsChecksum(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("marshal gitops checksum: %w", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
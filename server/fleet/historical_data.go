@@ -55,6 +55,9 @@ func OnHistoricalDataChanged(
 	}{
 		{"uptime", "uptime", oldHD.Uptime, newHD.Uptime},
 		{"vulnerabilities", "cve", oldHD.Vulnerabilities, newHD.Vulnerabilities},
+		{"policies", "policy", oldHD.Policies, newHD.Policies},
+		{"mdm_enrollment", "mdm_enrollment", oldHD.MDMEnrollment, newHD.MDMEnrollment},
+		{"disk_encryption", "disk_encryption", oldHD.DiskEncryption, newHD.DiskEncryption},
 	}
 	var errs []error
 	for _, c := range changes {
@@ -5,9 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"net/url"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/fleetdm/fleet/v4/pkg/optjson"
 	"github.com/fleetdm/fleet/v4/server/service/externalsvc"
@@ -18,6 +22,7 @@ import (
 type TeamIntegrations struct {
 	Jira           []*TeamJiraIntegration         `json:"jira"`
 	Zendesk        []*TeamZendeskIntegration      `json:"zendesk"`
+	ServiceNow     []*TeamServiceNowIntegration   `json:"servicenow,omitempty"`
 	GoogleCalendar *TeamGoogleCalendarIntegration `json:"google_calendar"`
 	// ConditionalAccessEnabled indicates whether the conditional access feature is enabled on this team.
 	ConditionalAccessEnabled optjson.Bool `json:"conditional_access_enabled,omitempty"`
@@ -49,6 +54,17 @@ func (ti TeamIntegrations) Copy() TeamIntegrations {
 		}
 	}
 
+	// Deep copy ServiceNow integrations
+	if ti.ServiceNow != nil {
+		result.ServiceNow = make([]*TeamServiceNowIntegration, len(ti.ServiceNow))
+		for i, sn := range ti.ServiceNow {
+			if sn != nil {
+				snCopy := *sn
+				result.ServiceNow[i] = &snCopy
+			}
+		}
+	}
+
 	// Deep copy Google Calendar integration
 	if ti.GoogleCalendar != nil {
 		gcalCopy := *ti.GoogleCalendar
@@ -77,6 +93,10 @@ func (ti TeamIntegrations) MatchWithIntegrations(globalIntgs Integrations) (Inte
 	if err != nil {
 		return result, err
 	}
+	serviceNowIntgs, err := IndexServiceNowIntegrations(globalIntgs.ServiceNow)
+	if err != nil {
+		return result, err
+	}
 
 	var errs []string
 	for _, tmJira := range ti.Jira {
@@ -99,6 +119,16 @@ func (ti TeamIntegrations) MatchWithIntegrations(globalIntgs Integrations) (Inte
 		intg.EnableFailingPolicies = tmZendesk.EnableFailingPolicies
 		result.Zendesk = append(result.Zendesk, &intg)
 	}
+	for _, tmServiceNow := range ti.ServiceNow {
+		key := tmServiceNow.UniqueKey()
+		intg, ok := serviceNowIntgs[key]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("unknown ServiceNow integration for url %s and assignment group %s", tmServiceNow.URL, tmServiceNow.AssignmentGroup))
+			continue
+		}
+		intg.EnableFailingPolicies = tmServiceNow.EnableFailingPolicies
+		result.ServiceNow = append(result.ServiceNow, &intg)
+	}
 
 	if len(errs) > 0 {
 		err = errors.New(strings.Join(errs, "\n"))
@@ -125,6 +155,15 @@ func (ti TeamIntegrations) Validate() error {
 		}
 		zendesk[key] = z
 	}
+
+	serviceNow := make(map[string]*TeamServiceNowIntegration, len(ti.ServiceNow))
+	for _, sn := range ti.ServiceNow {
+		key := sn.UniqueKey()
+		if _, ok := serviceNow[key]; ok {
+			return fmt.Errorf("duplicate ServiceNow integration for url %s and assignment group %s", sn.URL, sn.AssignmentGroup)
+		}
+		serviceNow[key] = sn
+	}
 	return nil
 }
 
@@ -154,6 +193,19 @@ func (z TeamZendeskIntegration) UniqueKey() string {
 	return z.URL + "\n" + strconv.FormatInt(z.GroupID, 10)
 }
 
+// TeamServiceNowIntegration configures an instance of an integration with the
+// external ServiceNow service for a team.
+type TeamServiceNowIntegration struct {
+	URL                   string `json:"url"`
+	AssignmentGroup       string `json:"assignment_group"`
+	EnableFailingPolicies bool   `json:"enable_failing_policies"`
+}
+
+// UniqueKey returns the unique key of this integration.
+func (sn TeamServiceNowIntegration) UniqueKey() string {
+	return sn.URL + "\n" + sn.AssignmentGroup
+}
+
 type TeamGoogleCalendarIntegration struct {
 	Enable     bool   `json:"enable_calendar_events"`
 	WebhookURL string `json:"webhook_url"`
@@ -384,6 +436,153 @@ func makeTestZendeskRequest(ctx context.Context, intg *ZendeskIntegration) error
 	return nil
 }
 
+// ServiceNowIntegration configures an instance of an integration with the
+// external ServiceNow service. Incidents are created with the Table API.
+type ServiceNowIntegration struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// AssignmentGroup is the name or sys_id of the group incidents are
+	// assigned to.
+	AssignmentGroup string `json:"assignment_group"`
+	Category        string `json:"category"`
+	// FieldMapping maps incident fields (e.g. "urgency" or "short_description")
+	// to text templates rendered when the incident is created. It overrides the
+	// default values Fleet sets for those fields.
+	FieldMapping                  map[string]string `json:"field_mapping,omitempty"`
+	EnableFailingPolicies         bool              `json:"enable_failing_policies"`
+	EnableSoftwareVulnerabilities bool              `json:"enable_software_vulnerabilities"`
+}
+
+func (sn ServiceNowIntegration) uniqueKey() string {
+	return sn.URL + "\n" + sn.AssignmentGroup
+}
+
+func (sn ServiceNowIntegration) equal(other ServiceNowIntegration) bool {
+	return sn.URL == other.URL &&
+		sn.Username == other.Username &&
+		sn.Password == other.Password &&
+		sn.AssignmentGroup == other.AssignmentGroup &&
+		sn.Category == other.Category &&
+		maps.Equal(sn.FieldMapping, other.FieldMapping) &&
+		sn.EnableFailingPolicies == other.EnableFailingPolicies &&
+		sn.EnableSoftwareVulnerabilities == other.EnableSoftwareVulnerabilities
+}
+
+// ServiceNowReservedFields are the incident fields that Fleet sets to track
+// the incidents it creates, and that cannot be set via the field mapping.
+var ServiceNowReservedFields = []string{"correlation_id", "correlation_display"}
+
+var serviceNowFieldNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// IndexServiceNowIntegrations indexes the provided ServiceNow integrations in
+// a map keyed by 'URL\nAssignmentGroup'. It returns an error if a duplicate
+// configuration is found for the same combination. This is typically used to
+// index the original integrations before applying the changes requested to
+// modify the AppConfig.
+//
+// Note that the returned map uses non-pointer ServiceNowIntegration struct
+// values, so that any changes to the original value does not modify the value
+// in the map.
+func IndexServiceNowIntegrations(serviceNowIntgs []*ServiceNowIntegration) (map[string]ServiceNowIntegration, error) {
+	indexed := make(map[string]ServiceNowIntegration, len(serviceNowIntgs))
+	for _, intg := range serviceNowIntgs {
+		key := intg.uniqueKey()
+		if _, ok := indexed[key]; ok {
+			return nil, fmt.Errorf("duplicate ServiceNow integration for url %s and assignment group %s", intg.URL, intg.AssignmentGroup)
+		}
+		v := *intg
+		v.FieldMapping = maps.Clone(intg.FieldMapping)
+		indexed[key] = v
+	}
+	return indexed, nil
+}
+
+// ValidateServiceNowIntegrations validates that the merge of the original and
+// new ServiceNow integrations does not result in any duplicate configuration,
+// that the field mappings are valid templates and that each modified or added
+// integration can successfully connect to the external ServiceNow service. It
+// returns the list of integrations that were deleted, if any.
+//
+// On successful return, the newServiceNowIntgs slice is ready to be saved - it
+// may have been updated using the original integrations if the password was
+// missing.
+func ValidateServiceNowIntegrations(ctx context.Context, oriServiceNowIntgsIndexed map[string]ServiceNowIntegration, newServiceNowIntgs []*ServiceNowIntegration) (deleted []*ServiceNowIntegration, err error) {
+	newIndexed := make(map[string]*ServiceNowIntegration, len(newServiceNowIntgs))
+	for i, new := range newServiceNowIntgs {
+		key := new.uniqueKey()
+		// first check for uniqueness
+		if _, ok := newIndexed[key]; ok {
+			return nil, fmt.Errorf("duplicate ServiceNow integration for url %s and assignment group %s", new.URL, new.AssignmentGroup)
+		}
+		newIndexed[key] = new
+
+		if err := validateServiceNowFieldMapping(new.FieldMapping); err != nil {
+			return nil, fmt.Errorf("ServiceNow integration at index %d: %w", i, err)
+		}
+
+		// check if existing integration is being edited
+		if old, ok := oriServiceNowIntgsIndexed[key]; ok {
+			if new.Password == "" || new.Password == MaskedPassword {
+				new.Password = old.Password
+			}
+			if old.equal(*new) {
+				// no further validation for unchanged integration
+				continue
+			}
+		}
+
+		// new or updated, test it
+		if err := makeTestServiceNowRequest(ctx, new); err != nil {
+			return nil, fmt.Errorf("ServiceNow integration at index %d: %w", i, err)
+		}
+	}
+
+	// collect any deleted integration
+	for key, intg := range oriServiceNowIntgsIndexed {
+		if _, ok := newIndexed[key]; !ok {
+			deleted = append(deleted, &intg)
+		}
+	}
+	return deleted, nil
+}
+
+func validateServiceNowFieldMapping(mapping map[string]string) error {
+	for field, tpl := range mapping {
+		if !serviceNowFieldNameRegexp.MatchString(field) {
+			return fmt.Errorf("invalid field_mapping field name %q", field)
+		}
+		if slices.Contains(ServiceNowReservedFields, field) {
+			return fmt.Errorf("field_mapping cannot set the %s field, it is set by Fleet", field)
+		}
+		if _, err := template.New(field).Parse(tpl); err != nil {
+			return fmt.Errorf("invalid field_mapping template for %s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+func makeTestServiceNowRequest(ctx context.Context, intg *ServiceNowIntegration) error {
+	if intg.Password == "" || intg.Password == MaskedPassword {
+		return IntegrationTestError{Err: errors.New("ServiceNow integration request failed: missing or invalid password")}
+	}
+	if intg.AssignmentGroup == "" {
+		return errors.New("assignment_group is required")
+	}
+	client, err := externalsvc.NewServiceNowClient(&externalsvc.ServiceNowOptions{
+		URL:      intg.URL,
+		Username: intg.Username,
+		Password: intg.Password,
+	})
+	if err != nil {
+		return IntegrationTestError{Err: fmt.Errorf("ServiceNow integration request failed: %w", err)}
+	}
+	if err := client.GetAssignmentGroup(ctx, intg.AssignmentGroup); err != nil {
+		return IntegrationTestError{Err: fmt.Errorf("ServiceNow integration request failed: %w", err)}
+	}
+	return nil
+}
+
 const (
 	GoogleCalendarEmail      = "client_email"
 	GoogleCalendarPrivateKey = "private_key"
@@ -468,6 +667,20 @@ type GoogleCalendarIntegration struct {
 	ApiKey GoogleCalendarApiKey `json:"api_key_json"`
 }
 
+// MicrosoftCalendarIntegration configures calendar events (maintenance
+// windows) on Microsoft 365 (Exchange Online) calendars via Microsoft Graph.
+// It authenticates with the stored Microsoft Graph credential of the tenant
+// (see MicrosoftGraphCredential), which must be granted the
+// Calendars.ReadWrite and MailboxSettings.Read application permissions.
+//
+// The fleets (teams) enable calendar events with the same
+// TeamGoogleCalendarIntegration settings, whichever calendar integration is
+// configured.
+type MicrosoftCalendarIntegration struct {
+	Domain   string `json:"domain"`
+	TenantID string `json:"tenant_id"`
+}
+
 // GoogleWorkspaceIntegration configures syncing IdP host vitals (users, groups,
 // and departments) from Google Workspace via the Admin SDK Directory API, using a
 // service account with domain-wide delegation. Unlike SCIM — which is a push from
@@ -489,8 +702,12 @@ type GoogleWorkspaceIntegration struct {
 type Integrations struct {
 	Jira            []*JiraIntegration            `json:"jira"`
 	Zendesk         []*ZendeskIntegration         `json:"zendesk"`
+	ServiceNow      []*ServiceNowIntegration      `json:"servicenow,omitempty"`
 	GoogleCalendar  []*GoogleCalendarIntegration  `json:"google_calendar"`
 	GoogleWorkspace []*GoogleWorkspaceIntegration `json:"google_workspace,omitempty"`
+	// MicrosoftCalendar is an alternative to GoogleCalendar, at most one of
+	// them can be configured.
+	MicrosoftCalendar []*MicrosoftCalendarIntegration `json:"microsoft_calendar,omitempty"`
 	// ConditionalAccessEnabled indicates whether conditional access is enabled/disabled for "No team".
 	ConditionalAccessEnabled optjson.Bool `json:"conditional_access_enabled"`
 }
@@ -504,6 +721,24 @@ func (i Integrations) IsGoogleWorkspaceConfigured() bool {
 		!i.GoogleWorkspace[0].ApiKey.IsEmpty()
 }
 
+// CalendarConfigured reports whether a calendar integration (Google Calendar
+// or Microsoft 365) is configured.
+func (i Integrations) CalendarConfigured() bool {
+	return len(i.GoogleCalendar) > 0 || len(i.MicrosoftCalendar) > 0
+}
+
+// CalendarDomain returns the domain of the configured calendar integration,
+// or an empty string if none is configured.
+func (i Integrations) CalendarDomain() string {
+	switch {
+	case len(i.GoogleCalendar) > 0:
+		return i.GoogleCalendar[0].Domain
+	case len(i.MicrosoftCalendar) > 0:
+		return i.MicrosoftCalendar[0].Domain
+	}
+	return ""
+}
+
 // ValidateConditionalAccessIntegration validates "Conditional access" can be enabled on a team/"No team".
 // It checks the global setup of the feature has been made (either Microsoft Entra or Okta).
 func ValidateConditionalAccessIntegration(
@@ -560,6 +795,27 @@ func ValidateEnabledActivitiesWebhook(webhook ActivitiesWebhookSettings, invalid
 	}
 }
 
+// ValidateEnabledGitOpsDriftWebhook checks that the GitOps drift webhook is
+// properly configured if enabled.
+func ValidateEnabledGitOpsDriftWebhook(webhook GitOpsDriftWebhookSettings, invalid *InvalidArgumentError) {
+	if !webhook.Enable {
+		return
+	}
+	if webhook.DestinationURL == "" {
+		invalid.Append(
+			"webhook_settings.gitops_drift_webhook.destination_url", "destination_url is required to enable the GitOps drift webhook",
+		)
+		return
+	}
+	if u, err := url.ParseRequestURI(webhook.DestinationURL); err != nil {
+		invalid.Append("webhook_settings.gitops_drift_webhook.destination_url", err.Error())
+	} else if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		invalid.Append(
+			"webhook_settings.gitops_drift_webhook.destination_url", "destination_url must be https or http, and have a host",
+		)
+	}
+}
+
 func ValidateEnabledHostActivitiesWebhook(webhook HostActivitiesWebhookSettings, invalid *InvalidArgumentError) {
 	if webhook.Enable {
 		if webhook.DestinationURL == "" {
@@ -638,6 +894,35 @@ func ValidateGoogleCalendarIntegrations(intgs []*GoogleCalendarIntegration, inva
 	}
 }
 
+// ValidateMicrosoftCalendarIntegrations validates the Microsoft 365 calendar
+// integrations. It enforces a single integration, the presence of the domain
+// and a well-formed tenant ID, and that the Google Calendar integration is not
+// configured at the same time. Any error found is appended to invalid, to be
+// checked by the caller via invalid.HasErrors.
+func ValidateMicrosoftCalendarIntegrations(intgs Integrations, invalid *InvalidArgumentError) {
+	if len(intgs.MicrosoftCalendar) == 0 {
+		return
+	}
+	if len(intgs.MicrosoftCalendar) > 1 {
+		invalid.Append("integrations.microsoft_calendar", "integrating with >1 Microsoft 365 tenant is not yet supported.")
+	}
+	if len(intgs.GoogleCalendar) > 0 {
+		invalid.Append("integrations.microsoft_calendar", "Google Calendar and Microsoft 365 calendar integrations cannot be configured at the same time.")
+	}
+	for _, intg := range intgs.MicrosoftCalendar {
+		intg.Domain = strings.TrimSpace(intg.Domain)
+		if intg.Domain == "" {
+			invalid.Append("integrations.microsoft_calendar.domain", "domain is required")
+		}
+		intg.TenantID = strings.TrimSpace(intg.TenantID)
+		if intg.TenantID == "" {
+			invalid.Append("integrations.microsoft_calendar.tenant_id", "tenant_id is required")
+		} else if !IsValidEntraGUID(intg.TenantID) {
+			invalid.Append("integrations.microsoft_calendar.tenant_id", "tenant_id must be a Microsoft Entra tenant ID (GUID)")
+		}
+	}
+}
+
 // ValidateGoogleWorkspaceIntegrations validates the Google Workspace IdP
 // integrations. It enforces a single integration and the presence of the service
 // account credentials (client_email, private_key), the Workspace domain, and the
@@ -707,19 +992,31 @@ func ValidateEnabledVulnerabilitiesIntegrations(webhook VulnerabilitiesWebhookSe
 			zendeskEnabledCount++
 		}
 	}
+	var serviceNowEnabledCount int
+	for _, sn := range intgs.ServiceNow {
+		if sn.EnableSoftwareVulnerabilities {
+			serviceNowEnabledCount++
+		}
+	}
 
-	if webhookEnabled && (jiraEnabledCount > 0 || zendeskEnabledCount > 0) {
+	if webhookEnabled && (jiraEnabledCount > 0 || zendeskEnabledCount > 0 || serviceNowEnabledCount > 0) {
 		invalid.Append("vulnerabilities", "cannot enable both webhook vulnerabilities and integration automations")
 	}
 	if jiraEnabledCount > 0 && zendeskEnabledCount > 0 {
 		invalid.Append("vulnerabilities", "cannot enable both jira integration and zendesk automations")
 	}
+	if serviceNowEnabledCount > 0 && (jiraEnabledCount > 0 || zendeskEnabledCount > 0) {
+		invalid.Append("vulnerabilities", "cannot enable both servicenow integration and jira or zendesk automations")
+	}
 	if jiraEnabledCount > 1 {
 		invalid.Append("vulnerabilities", "cannot enable more than one jira integration")
 	}
 	if zendeskEnabledCount > 1 {
 		invalid.Append("vulnerabilities", "cannot enable more than one zendesk integration")
 	}
+	if serviceNowEnabledCount > 1 {
+		invalid.Append("vulnerabilities", "cannot enable more than one servicenow integration")
+	}
 	if webhookEnabled && webhook.DestinationURL == "" {
 		invalid.Append("destination_url", "destination_url is required to enable the vulnerabilities webhook")
 	}
@@ -743,19 +1040,31 @@ func ValidateEnabledFailingPoliciesIntegrations(webhook FailingPoliciesWebhookSe
 			zendeskEnabledCount++
 		}
 	}
+	var serviceNowEnabledCount int
+	for _, sn := range intgs.ServiceNow {
+		if sn.EnableFailingPolicies {
+			serviceNowEnabledCount++
+		}
+	}
 
-	if webhookEnabled && (jiraEnabledCount > 0 || zendeskEnabledCount > 0) {
+	if webhookEnabled && (jiraEnabledCount > 0 || zendeskEnabledCount > 0 || serviceNowEnabledCount > 0) {
 		invalid.Append("failing policies", "cannot enable both webhook failing policies and integration automations")
 	}
 	if jiraEnabledCount > 0 && zendeskEnabledCount > 0 {
 		invalid.Append("failing policies", "cannot enable both jira and zendesk automations")
 	}
+	if serviceNowEnabledCount > 0 && (jiraEnabledCount > 0 || zendeskEnabledCount > 0) {
+		invalid.Append("failing policies", "cannot enable both servicenow and jira or zendesk automations")
+	}
 	if jiraEnabledCount > 1 {
 		invalid.Append("failing policies", "cannot enable more than one jira integration")
 	}
 	if zendeskEnabledCount > 1 {
 		invalid.Append("failing policies", "cannot enable more than one zendesk integration")
 	}
+	if serviceNowEnabledCount > 1 {
+		invalid.Append("failing policies", "cannot enable more than one servicenow integration")
+	}
 	if webhookEnabled && webhook.DestinationURL == "" {
 		invalid.Append("destination_url", "destination_url is required to enable the failing policies webhook")
 	}
@@ -766,8 +1075,9 @@ func ValidateEnabledFailingPoliciesIntegrations(webhook FailingPoliciesWebhookSe
 // integration structs.
 func ValidateEnabledFailingPoliciesTeamIntegrations(webhook FailingPoliciesWebhookSettings, teamIntgs TeamIntegrations, invalid *InvalidArgumentError) {
 	intgs := Integrations{
-		Jira:    make([]*JiraIntegration, len(teamIntgs.Jira)),
-		Zendesk: make([]*ZendeskIntegration, len(teamIntgs.Zendesk)),
+		Jira:       make([]*JiraIntegration, len(teamIntgs.Jira)),
+		Zendesk:    make([]*ZendeskIntegration, len(teamIntgs.Zendesk)),
+		ServiceNow: make([]*ServiceNowIntegration, len(teamIntgs.ServiceNow)),
 	}
 	for i, j := range teamIntgs.Jira {
 		intgs.Jira[i] = &JiraIntegration{
@@ -783,5 +1093,12 @@ func ValidateEnabledFailingPoliciesTeamIntegrations(webhook FailingPoliciesWebho
 			EnableFailingPolicies: z.EnableFailingPolicies,
 		}
 	}
+	for i, sn := range teamIntgs.ServiceNow {
+		intgs.ServiceNow[i] = &ServiceNowIntegration{
+			URL:                   sn.URL,
+			AssignmentGroup:       sn.AssignmentGroup,
+			EnableFailingPolicies: sn.EnableFailingPolicies,
+		}
+	}
 	ValidateEnabledFailingPoliciesIntegrations(webhook, intgs, invalid)
 }
@@ -169,3 +169,166 @@ func TestIntegrationsIsGoogleWorkspaceConfigured(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateMicrosoftCalendarIntegrations(t *testing.T) {
+	const tenantID = "11111111-2222-3333-4444-555555555555"
+
+	cases := []struct {
+		name      string
+		intgs     Integrations
+		wantField string // empty means no error expected
+	}{
+		{
+			name:  "valid",
+			intgs: Integrations{MicrosoftCalendar: []*MicrosoftCalendarIntegration{{Domain: "example.com", TenantID: " " + tenantID}}},
+		},
+		{
+			name:  "empty list is valid",
+			intgs: Integrations{GoogleCalendar: []*GoogleCalendarIntegration{{Domain: "example.com"}}},
+		},
+		{
+			name: "more than one integration",
+			intgs: Integrations{MicrosoftCalendar: []*MicrosoftCalendarIntegration{
+				{Domain: "a.com", TenantID: tenantID},
+				{Domain: "b.com", TenantID: tenantID},
+			}},
+			wantField: "integrations.microsoft_calendar",
+		},
+		{
+			name: "google calendar also configured",
+			intgs: Integrations{
+				GoogleCalendar:    []*GoogleCalendarIntegration{{Domain: "example.com"}},
+				MicrosoftCalendar: []*MicrosoftCalendarIntegration{{Domain: "example.com", TenantID: tenantID}},
+			},
+			wantField: "integrations.microsoft_calendar",
+		},
+		{
+			name:      "blank domain",
+			intgs:     Integrations{MicrosoftCalendar: []*MicrosoftCalendarIntegration{{Domain: "  ", TenantID: tenantID}}},
+			wantField: "integrations.microsoft_calendar.domain",
+		},
+		{
+			name:      "missing tenant_id",
+			intgs:     Integrations{MicrosoftCalendar: []*MicrosoftCalendarIntegration{{Domain: "example.com"}}},
+			wantField: "integrations.microsoft_calendar.tenant_id",
+		},
+		{
+			name:      "invalid tenant_id",
+			intgs:     Integrations{MicrosoftCalendar: []*MicrosoftCalendarIntegration{{Domain: "example.com", TenantID: "contoso.onmicrosoft.com"}}},
+			wantField: "integrations.microsoft_calendar.tenant_id",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			invalid := &InvalidArgumentError{}
+			ValidateMicrosoftCalendarIntegrations(c.intgs, invalid)
+			if c.wantField == "" {
+				assert.False(t, invalid.HasErrors(), "expected no validation errors, got: %v", invalid)
+				for _, intg := range c.intgs.MicrosoftCalendar {
+					assert.Equal(t, tenantID, intg.TenantID)
+				}
+				return
+			}
+			require.True(t, invalid.HasErrors(), "expected a validation error for field %q", c.wantField)
+			var found bool
+			for _, e := range invalid.Errors {
+				if e.name == c.wantField {
+					found = true
+					break
+				}
+			}
+			assert.True(t, found, "expected error on field %q, got %v", c.wantField, invalid.Errors)
+		})
+	}
+}
+
+func TestIntegrationsCalendar(t *testing.T) {
+	assert.False(t, Integrations{}.CalendarConfigured())
+	assert.Empty(t, Integrations{}.CalendarDomain())
+
+	google := Integrations{GoogleCalendar: []*GoogleCalendarIntegration{{Domain: "google.example.com"}}}
+	assert.True(t, google.CalendarConfigured())
+	assert.Equal(t, "google.example.com", google.CalendarDomain())
+
+	microsoft := Integrations{MicrosoftCalendar: []*MicrosoftCalendarIntegration{{Domain: "microsoft.example.com"}}}
+	assert.True(t, microsoft.CalendarConfigured())
+	assert.Equal(t, "microsoft.example.com", microsoft.CalendarDomain())
+}
+
+func TestValidateServiceNowIntegrations(t *testing.T) {
+	ctx := t.Context()
+
+	existing := &ServiceNowIntegration{
+		URL:             "https://example.service-now.com",
+		Username:        "fleet",
+		Password:        "secret",
+		AssignmentGroup: "Service Desk",
+		FieldMapping:    map[string]string{"urgency": "1"},
+	}
+	removed := &ServiceNowIntegration{
+		URL:             "https://example.service-now.com",
+		Username:        "fleet",
+		Password:        "secret",
+		AssignmentGroup: "Network",
+	}
+	ori, err := IndexServiceNowIntegrations([]*ServiceNowIntegration{existing, removed})
+	require.NoError(t, err)
+
+	t.Run("unchanged with masked password", func(t *testing.T) {
+		intg := *existing
+		intg.Password = MaskedPassword
+		intg.FieldMapping = map[string]string{"urgency": "1"}
+		deleted, err := ValidateServiceNowIntegrations(ctx, ori, []*ServiceNowIntegration{&intg})
+		require.NoError(t, err)
+		assert.Equal(t, "secret", intg.Password)
+		require.Len(t, deleted, 1)
+		assert.Equal(t, "Network", deleted[0].AssignmentGroup)
+	})
+
+	cases := []struct {
+		name    string
+		intgs   []*ServiceNowIntegration
+		wantErr string
+	}{
+		{
+			name:    "duplicate",
+			intgs:   []*ServiceNowIntegration{existing, existing},
+			wantErr: "duplicate ServiceNow integration",
+		},
+		{
+			name: "invalid field name",
+			intgs: []*ServiceNowIntegration{{
+				URL: existing.URL, AssignmentGroup: "Other", FieldMapping: map[string]string{"Bad-Field": "x"},
+			}},
+			wantErr: "invalid field_mapping field name",
+		},
+		{
+			name: "reserved field",
+			intgs: []*ServiceNowIntegration{{
+				URL: existing.URL, AssignmentGroup: "Other", FieldMapping: map[string]string{"correlation_id": "x"},
+			}},
+			wantErr: "cannot set the correlation_id field",
+		},
+		{
+			name: "invalid template",
+			intgs: []*ServiceNowIntegration{{
+				URL: existing.URL, AssignmentGroup: "Other", FieldMapping: map[string]string{"urgency": "{{ .PolicyCritical "},
+			}},
+			wantErr: "invalid field_mapping template for urgency",
+		},
+		{
+			name: "new without password",
+			intgs: []*ServiceNowIntegration{{
+				URL: existing.URL, AssignmentGroup: "Other",
+			}},
+			wantErr: "missing or invalid password",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := ValidateServiceNowIntegrations(ctx, ori, c.intgs)
+			require.ErrorContains(t, err, c.wantErr)
+		})
+	}
+}
@@ -79,6 +79,9 @@ type OrbitConfig struct {
 	UpdateChannels *OrbitUpdateChannels `json:"update_channels,omitempty"`
 	// nil = no opinion (orbit keeps its current level); true/false sets it.
 	DebugLogging *bool `json:"debug_logging,omitempty"`
+	// CacheProxyURL is the URL of the fleetd caching proxy to download software
+	// installers and fleetd updates through, if any.
+	CacheProxyURL string `json:"cache_proxy_url,omitempty"`
 }
 
 type OrbitConfigReceiver interface {
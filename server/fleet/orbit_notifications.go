@@ -0,0 +1,45 @@
+package fleet
+
+import "context"
+
+// OrbitNotificationHint tells fleetd what changed for its host, so that it can
+// fetch its orbit config right away instead of waiting for the next poll. A
+// hint carries no data, the host still gets the details from its config.
+type OrbitNotificationHint string
+
+const (
+	// OrbitNotificationHintScripts is sent when a script execution is queued
+	// for the host.
+	OrbitNotificationHintScripts OrbitNotificationHint = "scripts"
+	// OrbitNotificationHintSoftwareInstalls is sent when a software install is
+	// queued for the host.
+	OrbitNotificationHintSoftwareInstalls OrbitNotificationHint = "software_installs"
+	// OrbitNotificationHintDiskEncryption is sent when the host is asked to
+	// escrow its disk encryption key.
+	OrbitNotificationHintDiskEncryption OrbitNotificationHint = "disk_encryption"
+	// OrbitNotificationHintConfig is sent when the host's orbit config changed,
+	// e.g. when it is transferred to another fleet.
+	OrbitNotificationHintConfig OrbitNotificationHint = "config"
+)
+
+// OrbitNotificationMessageType is the type of the websocket messages that
+// carry an OrbitNotification.
+const OrbitNotificationMessageType = "notification"
+
+// OrbitNotification is the message sent to fleetd over its notifications
+// websocket.
+type OrbitNotification struct {
+	Hint OrbitNotificationHint `json:"hint"`
+}
+
+// OrbitNotifier delivers notification hints to the fleetd agents connected to
+// any of the Fleet instances.
+type OrbitNotifier interface {
+	// Notify sends the hint to the provided hosts. Hosts that are not connected
+	// are ignored, they get the change on their next config poll.
+	Notify(ctx context.Context, hint OrbitNotificationHint, hostIDs ...uint) error
+	// Subscribe returns a channel that receives the hints sent to the host
+	// until ctx is done, at which point the channel is closed. Hints that are
+	// sent while a previous one is still pending are coalesced into it.
+	Subscribe(ctx context.Context, hostID uint) (<-chan OrbitNotificationHint, error)
+}
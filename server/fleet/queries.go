@@ -2,6 +2,7 @@ package fleet
 
 import (
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -47,6 +48,9 @@ type QueryPayload struct {
 	LabelsIncludeAny []string `json:"labels_include_any"`
 	// LabelsIncludeAll scopes the query to hosts that are members of ALL of the listed labels.
 	LabelsIncludeAll []string `json:"labels_include_all"`
+	// LogDestinations are the names of the result log destinations the query's results
+	// are sent to. An empty list resets the query to the default destination.
+	LogDestinations *[]string `json:"log_destinations"`
 }
 
 // Query represents a osquery query to run on devices.
@@ -109,6 +113,10 @@ type Query struct {
 	LabelsIncludeAny []LabelIdent `json:"labels_include_any"`
 	// LabelsIncludeAll scopes the query to hosts that are members of ALL of the listed labels.
 	LabelsIncludeAll []LabelIdent `json:"labels_include_all"`
+	// LogDestinations are the names of the result log destinations (configured in
+	// osquery.result_log_destinations) the query's results are sent to. If empty, they
+	// are sent to the default destination only.
+	LogDestinations LogDestinations `json:"log_destinations,omitempty" db:"log_destinations"`
 
 	/////////////////////////////////////////////////////////////////
 	// WARNING: If you add to this struct make sure it's taken into
@@ -167,9 +175,36 @@ func (q *Query) Copy() *Query {
 		clone.LabelsIncludeAll = make([]LabelIdent, len(q.LabelsIncludeAll))
 		copy(clone.LabelsIncludeAll, q.LabelsIncludeAll)
 	}
+	if q.LogDestinations != nil {
+		clone.LogDestinations = make(LogDestinations, len(q.LogDestinations))
+		copy(clone.LogDestinations, q.LogDestinations)
+	}
 	return &clone
 }
 
+// LogDestinations is the list of result log destination names of a query. It's
+// stored as a JSON array, with NULL meaning the default destination only.
+type LogDestinations []string
+
+func (d *LogDestinations) Scan(v any) error {
+	switch tv := v.(type) {
+	case nil:
+		*d = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(tv, d)
+	default:
+		return fmt.Errorf("unsupported type %T for log destinations", v)
+	}
+}
+
+func (d LogDestinations) Value() (driver.Value, error) {
+	if len(d) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(d)
+}
+
 type LiveQueryStats struct {
 	// host_id, average_memory, execution, system_time, user_time
 	HostID        uint      `db:"host_id"`
@@ -253,6 +288,11 @@ func (q *QueryPayload) Verify() error {
 			return err
 		}
 	}
+	if q.LogDestinations != nil {
+		if err := verifyLogDestinations(*q.LogDestinations); err != nil {
+			return err
+		}
+	}
 	return verifyQueryLabelScopeMutualExclusion(q.LabelsIncludeAny, q.LabelsIncludeAll)
 }
 
@@ -276,6 +316,9 @@ func (q *Query) Verify() error {
 	if err := verifyQueryPlatforms(q.Platform); err != nil {
 		return err
 	}
+	if err := verifyLogDestinations(q.LogDestinations); err != nil {
+		return err
+	}
 	if len(q.LabelsIncludeAny) > 0 && len(q.LabelsIncludeAll) > 0 {
 		return ErrQueryConflictingLabels
 	}
@@ -308,6 +351,7 @@ var (
 	ErrQueryInvalidPlatform   = errors.New("report's platform must be a comma-separated list of 'darwin', 'linux', 'windows', and/or 'chrome' in a single string")
 	errInvalidLogging         = fmt.Errorf("invalid logging value, must be one of '%s', '%s', '%s'", LoggingSnapshot, LoggingDifferential, LoggingDifferentialIgnoreRemovals)
 	ErrQueryConflictingLabels = errors.New("report can include at most one of labels_include_any or labels_include_all")
+	errEmptyLogDestination    = errors.New("report's log destination names cannot be empty")
 )
 
 // verifyQueryLabelScopeMutualExclusion enforces that at most one scope rule is set.
@@ -339,6 +383,20 @@ func verifyLogging(logging string) error {
 	return nil
 }
 
+func verifyLogDestinations(names []string) error {
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if emptyString(name) {
+			return errEmptyLogDestination
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("report's log destination %q is listed more than once", name)
+		}
+		seen[name] = struct{}{}
+	}
+	return nil
+}
+
 func verifyQueryPlatforms(platforms string) error {
 	if emptyString(platforms) {
 		return nil
@@ -399,9 +457,15 @@ type QuerySpec struct {
 	DiscardData      bool     `json:"discard_data"`
 	LabelsIncludeAny []string `json:"labels_include_any,omitempty"`
 	LabelsIncludeAll []string `json:"labels_include_all,omitempty"`
+	// LogDestinations are the names of the result log destinations the query's
+	// results are sent to. If not set, they are sent to the default destination.
+	LogDestinations []string `json:"log_destinations,omitempty"`
 }
 
 func (q *QuerySpec) Verify() error {
+	if err := verifyLogDestinations(q.LogDestinations); err != nil {
+		return err
+	}
 	return verifyQueryLabelScopeMutualExclusion(q.LabelsIncludeAny, q.LabelsIncludeAll)
 }
 
@@ -512,6 +576,17 @@ type HostQueryResultRow struct {
 	Columns map[string]string `json:"columns"`
 }
 
+// QueryReportDiff is the difference between the results of a report at two
+// points in time, built from the report history.
+type QueryReportDiff struct {
+	QueryID uint      `json:"query_id" renameto:"report_id"`
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	// Hosts are the hosts whose latest results at To differ from their latest
+	// results at From, hosts whose results are the same are omitted.
+	Hosts []HostQueryResultsDiff `json:"hosts"`
+}
+
 type HostQueryReportResult struct {
 	// Columns contains the key-value pairs of a result row.
 	// The map key is the name of the column, and the map value is the value.
@@ -584,6 +659,17 @@ type ScheduledQueryResultRow struct {
 	LastFetched time.Time `db:"last_fetched"`
 }
 
+// QueryResultHistoryBatch is a result batch of a report from a host, kept in
+// the report history.
+type QueryResultHistoryBatch struct {
+	QueryID uint
+	HostID  uint
+	// FetchedAt is the time the host ran the query.
+	FetchedAt time.Time
+	// Rows holds the result rows, it is empty if the query returned no rows.
+	Rows []*json.RawMessage
+}
+
 func (s *ScheduledQueryResultRow) HostDisplayName() string {
 	// If host does not exist, all values below default to empty string
 	return HostDisplayName(
@@ -0,0 +1,43 @@
+package fleet
+
+import "fmt"
+
+// SBOMFormat is the format of a software bill of materials exported by Fleet.
+type SBOMFormat string
+
+const (
+	// SBOMFormatCycloneDX is the CycloneDX 1.5 JSON format.
+	SBOMFormatCycloneDX SBOMFormat = "cyclonedx"
+	// SBOMFormatSPDX is the SPDX 2.3 JSON format.
+	SBOMFormatSPDX SBOMFormat = "spdx"
+)
+
+// ParseSBOMFormat validates the requested SBOM format. An empty value
+// defaults to CycloneDX.
+func ParseSBOMFormat(s string) (SBOMFormat, error) {
+	switch f := SBOMFormat(s); f {
+	case "":
+		return SBOMFormatCycloneDX, nil
+	case SBOMFormatCycloneDX, SBOMFormatSPDX:
+		return f, nil
+	default:
+		return "", NewInvalidArgumentError("format", fmt.Sprintf("invalid format %q, must be %q or %q", s, SBOMFormatCycloneDX, SBOMFormatSPDX))
+	}
+}
+
+// ContentType returns the media type of an SBOM document in that format.
+func (f SBOMFormat) ContentType() string {
+	if f == SBOMFormatSPDX {
+		return "application/spdx+json"
+	}
+	return "application/vnd.cyclonedx+json; version=1.5"
+}
+
+// FileExtension returns the conventional file extension of an SBOM document
+// in that format.
+func (f SBOMFormat) FileExtension() string {
+	if f == SBOMFormatSPDX {
+		return ".spdx.json"
+	}
+	return ".cdx.json"
+}
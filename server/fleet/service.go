@@ -11,6 +11,7 @@ import (
 
 	"github.com/fleetdm/fleet/v4/pkg/optjson"
 	"github.com/fleetdm/fleet/v4/server/mdm/nanodep/godep"
+	"github.com/fleetdm/fleet/v4/server/mfa"
 	"github.com/fleetdm/fleet/v4/server/version"
 	"github.com/fleetdm/fleet/v4/server/websocket"
 )
@@ -115,6 +116,11 @@ type ActivityLookupService interface {
 	// webhook settings of the fleets the given hosts belong to, deduplicated by
 	// fleet. Returns nil on Fleet Free.
 	GetHostActivitiesWebhookSettings(ctx context.Context, hostIDs []uint) ([]HostActivitiesWebhookDelivery, error)
+	// DeliverActivitiesWebhook delivers an activities webhook payload, to the
+	// global activities webhook if fleetID is nil or to the fleet's host
+	// activities webhook otherwise. Failed deliveries are retried in the
+	// background.
+	DeliverActivitiesWebhook(ctx context.Context, fleetID *uint, destinationURL string, payload json.RawMessage) error
 	// ActivateNextUpcomingActivityForHost activates the next upcoming activity for the given host.
 	ActivateNextUpcomingActivityForHost(ctx context.Context, hostID uint, fromCompletedExecID string) error
 }
@@ -140,6 +146,16 @@ type Service interface {
 	// to fleetd (formerly orbit).
 	GetOrbitConfig(ctx context.Context) (OrbitConfig, error)
 
+	// StreamOrbitNotifications streams the notification hints of the host in the context over the provided
+	// websocket, until the host disconnects. Like StreamCampaignResults, it does not follow the typical go-kit
+	// RPC style.
+	StreamOrbitNotifications(ctx context.Context, conn *websocket.Conn)
+
+	// NotifyOrbitHosts tells the fleetd agents of the hosts that something changed for them. It is
+	// best-effort: errors are logged, and the hosts that miss the hint get the change on their next
+	// config poll.
+	NotifyOrbitHosts(ctx context.Context, hint OrbitNotificationHint, hostIDs ...uint)
+
 	// LogFleetdError logs an error report from a `fleetd` component
 	LogFleetdError(ctx context.Context, errData FleetdError) error
 
@@ -218,6 +234,34 @@ type Service interface {
 
 	GetUserSettings(ctx context.Context, id uint) (settings *UserSettings, err error)
 
+	// GetUserMFA returns the second factors enrolled by the current user.
+	GetUserMFA(ctx context.Context) (*UserMFAStatus, error)
+	// BeginTOTPEnrollment generates a new TOTP secret for the current user, to
+	// be confirmed with ConfirmTOTPEnrollment.
+	BeginTOTPEnrollment(ctx context.Context) (*TOTPEnrollment, error)
+	// ConfirmTOTPEnrollment enables the pending TOTP secret of the current user
+	// with a first code from their authenticator app. It returns the new
+	// recovery codes of the user if they had none.
+	ConfirmTOTPEnrollment(ctx context.Context, code string) (recoveryCodes []string, err error)
+	// DisableTOTP removes the TOTP secret of the current user.
+	DisableTOTP(ctx context.Context, password string) error
+	// BeginWebAuthnRegistration starts the registration of a WebAuthn
+	// credential for the current user.
+	BeginWebAuthnRegistration(ctx context.Context) (*WebAuthnRegistration, error)
+	// FinishWebAuthnRegistration verifies and stores the WebAuthn credential
+	// created by the browser. It returns the new recovery codes of the user if
+	// they had none.
+	FinishWebAuthnRegistration(ctx context.Context, token, name string, resp mfa.RegistrationResponse) (*WebAuthnCredential, []string, error)
+	// DeleteWebAuthnCredential removes a WebAuthn credential of the current
+	// user.
+	DeleteWebAuthnCredential(ctx context.Context, id uint, password string) error
+	// RegenerateMFARecoveryCodes replaces the recovery codes of the current
+	// user.
+	RegenerateMFARecoveryCodes(ctx context.Context, password string) ([]string, error)
+	// ResetUserMFA removes all the second factors of the user identified by
+	// the provided ID, e.g. after they lost their device.
+	ResetUserMFA(ctx context.Context, userID uint) error
+
 	// /////////////////////////////////////////////////////////////////////////////
 	// Session
 
@@ -238,6 +282,11 @@ type Service interface {
 	// The sessionID is used to identify the SSO session and samlResponse is the raw SAMLResponse.
 	InitSSOCallback(ctx context.Context, sessionID string, samlResponse []byte) (auth Auth, redirectURL string, err error)
 
+	// InitOIDCCallback handles the redirect back from the OpenID Connect provider: it exchanges the authorization
+	// code for an ID token and validates it. The sessionID is used to identify the SSO session, state and code are the
+	// query parameters of the redirect and idpError the error returned by the provider, if any.
+	InitOIDCCallback(ctx context.Context, sessionID, state, code, idpError string) (auth Auth, redirectURL string, err error)
+
 	// MDMSSOCallback handles the IdP SAMLResponse and ensures the
 	// credentials are valid, then responds with a URL to the Fleet UI to
 	// handle next steps based on the query parameters provided.
@@ -259,6 +308,10 @@ type Service interface {
 	GetSessionDuration(ctx context.Context) time.Duration
 	Logout(ctx context.Context) (err error)
 	CompleteMFA(ctx context.Context, token string) (*Session, *User, error)
+	// CompleteLoginMFA completes a password login that requires a second
+	// factor. It returns the new recovery codes of the user when the login
+	// required the enrollment of their first second factor.
+	CompleteLoginMFA(ctx context.Context, v MFAVerification) (*Session, *User, []string, error)
 	DestroySession(ctx context.Context) (err error)
 	GetInfoAboutSessionsForUser(ctx context.Context, id uint) (sessions []*Session, err error)
 	DeleteSessionsForUser(ctx context.Context, id uint) (err error)
@@ -357,6 +410,12 @@ type Service interface {
 	// GetQueryReportResults returns all the stored results of a query for hosts the requestor has access to.
 	// Returns a boolean indicating whether the report is clipped.
 	GetQueryReportResults(ctx context.Context, id uint, teamID *uint) ([]HostQueryResultRow, bool, error)
+	// GetQueryReportHistory returns the result rows of a query kept in its report history that were fetched
+	// between from and to (inclusive), for hosts the requestor has access to. A zero to means now.
+	GetQueryReportHistory(ctx context.Context, id uint, teamID *uint, from, to time.Time) ([]HostQueryResultRow, error)
+	// DiffQueryReport returns how the results of a query changed between from and to, comparing the latest
+	// result of each host at both times in the report history.
+	DiffQueryReport(ctx context.Context, id uint, teamID *uint, from, to time.Time) (*QueryReportDiff, error)
 	// GetHostQueryReportResults returns all stored results of a query for a specific host
 	GetHostQueryReportResults(ctx context.Context, hid uint, queryID uint) (rows []HostQueryReportResult, lastFetched *time.Time, err error)
 	// QueryReportIsClipped returns true if the number of query report rows exceeds the maximum
@@ -388,6 +447,35 @@ type Service interface {
 		ctx context.Context, queryString string, queryID *uint, targets HostTargets,
 	) (*DistributedQueryCampaign, error)
 
+	// NewDistributedQueryCampaignWithOptions is like NewDistributedQueryCampaign but with options to create a
+	// deferred campaign, which keeps being sent to its targets until each host answers or the TTL elapses, or to
+	// store the results of a live campaign. Stored results can be retrieved after the campaign ends.
+	NewDistributedQueryCampaignWithOptions(
+		ctx context.Context, queryString string, queryID *uint, targets HostTargets, opts DistributedQueryCampaignOptions,
+	) (*DistributedQueryCampaign, error)
+
+	// NewDistributedQueryCampaignByIdentifiersWithOptions is like NewDistributedQueryCampaignWithOptions but with
+	// host/label targets specified by hostname, UUID, hardware serial and label name.
+	NewDistributedQueryCampaignByIdentifiersWithOptions(
+		ctx context.Context, queryString string, queryID *uint, hosts []string, labels []string, opts DistributedQueryCampaignOptions,
+	) (*DistributedQueryCampaign, error)
+
+	// GetQueryCampaign returns the campaign with stored results with the given ID along with its progress. Only
+	// the user that created the campaign can access it.
+	GetQueryCampaign(ctx context.Context, id uint) (*QueryCampaignProgress, error)
+
+	// ListQueryCampaigns returns the campaigns with stored results of the current user, optionally only those of
+	// the query with ID queryID.
+	ListQueryCampaigns(ctx context.Context, queryID *uint, opts ListOptions) ([]*QueryCampaignSummary, *PaginationMetadata, error)
+
+	// ListCampaignQueryResults returns the stored results of the campaign with the given ID. Only the user that
+	// created the campaign can access them.
+	ListCampaignQueryResults(ctx context.Context, id uint, opts ListOptions) ([]*CampaignQueryResult, *PaginationMetadata, error)
+
+	// DiffCampaignQueryResults compares the stored results of two campaigns of the same query, per host. Only the
+	// user that created both campaigns can compare them.
+	DiffCampaignQueryResults(ctx context.Context, id uint, previousID uint) (*QueryCampaignResultsDiff, error)
+
 	// StreamCampaignResults streams updates with query results and expected host totals over the provided websocket.
 	// Note that the type signature is somewhat inconsistent due to this being a streaming API and not the typical
 	// go-kit RPC style.
@@ -493,6 +581,10 @@ type Service interface {
 	HostEncryptionKey(ctx context.Context, id uint) (*HostDiskEncryptionKey, error)
 	EscrowLUKSData(ctx context.Context, passphrase string, salt string, keySlot *uint, clientError string, keyType string) error
 
+	// ReportCacheProxyStats stores the statistics reported by a host running
+	// fleetd in caching proxy mode.
+	ReportCacheProxyStats(ctx context.Context, stats CacheProxyStats) error
+
 	// EscrowWindowsManagedLocalAccountPassword stores the device-generated password that Windows fleetd escrows after
 	// creating the managed local admin account. When clientError is set no password is stored; the account is marked failed
 	// and the device-reported reason is recorded on it.
@@ -786,6 +878,14 @@ type Service interface {
 	// software installation on a host.
 	SaveHostSoftwareInstallResult(ctx context.Context, result *HostSoftwareInstallResultPayload) error
 
+	// HostSBOM returns the software bill of materials of a host, with the
+	// vulnerabilities found on its software, encoded in the requested format.
+	HostSBOM(ctx context.Context, hostID uint, format SBOMFormat) ([]byte, error)
+	// TeamSBOM returns the software bill of materials of all the hosts in a
+	// fleet (0 for hosts not assigned to a fleet), encoded in the requested
+	// format.
+	TeamSBOM(ctx context.Context, teamID uint, format SBOMFormat) ([]byte, error)
+
 	// /////////////////////////////////////////////////////////////////////////////
 	// Software Titles
 
@@ -1444,6 +1544,11 @@ type Service interface {
 	DownloadSoftwareInstaller(ctx context.Context, skipAuthz bool, alt string, titleID uint,
 		teamID *uint, installerID *uint) (*DownloadSoftwareInstallerPayload, error)
 	OrbitDownloadSoftwareInstaller(ctx context.Context, installerID uint) (*DownloadSoftwareInstallerPayload, error)
+	// OrbitCacheProxyDownloadSoftwareInstaller downloads the software installer
+	// with the given SHA256 for the host making the request, which runs fleetd
+	// in caching proxy mode and serves the installer to the other hosts of its
+	// network.
+	OrbitCacheProxyDownloadSoftwareInstaller(ctx context.Context, hashSHA256 string) (*DownloadSoftwareInstallerPayload, error)
 
 	/////////////////////////////////////////////////////////////////////////////////
 	// Software title icons
@@ -1548,6 +1653,79 @@ type Service interface {
 	// names present are upserted, names absent from customHostVitals are deleted.
 	UpsertCustomHostVitals(ctx context.Context, customHostVitals []CustomHostVital, dryRun bool) error
 
+	// /////////////////////////////////////////////////////////////////////////////
+	// Custom roles
+
+	ListCustomRoles(ctx context.Context) ([]CustomRole, error)
+	GetCustomRole(ctx context.Context, id uint) (*CustomRole, error)
+	CreateCustomRole(ctx context.Context, p CustomRolePayload) (*CustomRole, error)
+	ModifyCustomRole(ctx context.Context, id uint, p CustomRolePayload) (*CustomRole, error)
+	DeleteCustomRole(ctx context.Context, id uint) error
+	// SetUserCustomRoles replaces the custom roles assigned to a user, globally
+	// (nil TeamID) or for a team.
+	SetUserCustomRoles(ctx context.Context, userID uint, roles []UserCustomRole) (*User, error)
+	// ApplyCustomRoles declaratively reconciles custom roles (GitOps): roles
+	// present are created or updated by name, roles absent from specs are
+	// deleted.
+	ApplyCustomRoles(ctx context.Context, specs []CustomRoleSpec, dryRun bool) error
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// VEX documents
+
+	// UploadVEXDocument parses and stores an OpenVEX or CSAF VEX document. Its
+	// statements are applied on the next vulnerabilities scan.
+	UploadVEXDocument(ctx context.Context, name string, r io.Reader) (*VEXDocument, error)
+	ListVEXDocuments(ctx context.Context) ([]*VEXDocument, error)
+	DeleteVEXDocument(ctx context.Context, id uint) error
+	// ListVEXSuppressions lists the software vulnerabilities suppressed by VEX
+	// statements.
+	ListVEXSuppressions(ctx context.Context, opts VEXSuppressionListOptions) ([]VEXSuppression, *PaginationMetadata, error)
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Vulnerability exceptions
+
+	ListVulnerabilityExceptions(ctx context.Context, opts VulnerabilityExceptionListOptions) ([]VulnerabilityException, *PaginationMetadata, error)
+	GetVulnerabilityException(ctx context.Context, id uint) (*VulnerabilityException, error)
+	CreateVulnerabilityException(ctx context.Context, p VulnerabilityExceptionPayload) (*VulnerabilityException, error)
+	// ModifyVulnerabilityException updates the reason, owner and expiration
+	// date of a vulnerability exception.
+	ModifyVulnerabilityException(ctx context.Context, id uint, p VulnerabilityExceptionPayload) (*VulnerabilityException, error)
+	DeleteVulnerabilityException(ctx context.Context, id uint) error
+	// ApplyVulnerabilityExceptions declaratively reconciles vulnerability
+	// exceptions (GitOps): exceptions present are created or updated by CVE,
+	// software title and fleet, exceptions absent from specs are deleted.
+	ApplyVulnerabilityExceptions(ctx context.Context, specs []VulnerabilityExceptionSpec, dryRun bool) error
+	// ExpireVulnerabilityExceptions creates an activity for each vulnerability
+	// exception that expired since the last call.
+	ExpireVulnerabilityExceptions(ctx context.Context) error
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// Webhook deliveries
+
+	// ListWebhookDeliveries lists the webhook deliveries, without their payload.
+	ListWebhookDeliveries(ctx context.Context, opts WebhookDeliveryListOptions) ([]WebhookDelivery, *PaginationMetadata, error)
+	GetWebhookDelivery(ctx context.Context, id uint) (*WebhookDelivery, error)
+	// RedeliverWebhookDelivery sends the payload of a webhook delivery again,
+	// as a new delivery signed with the current secret of the webhook.
+	RedeliverWebhookDelivery(ctx context.Context, id uint) (*WebhookDelivery, error)
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// GitOps drift
+
+	// SaveGitOpsSnapshot captures the live state of the resources of a fleet
+	// (the global config if teamID is nil) as applied by GitOps.
+	SaveGitOpsSnapshot(ctx context.Context, teamID *uint) (*GitOpsSnapshot, error)
+	// GetGitOpsDrift returns the changes made outside of GitOps since the
+	// snapshot of a fleet (all the snapshots if teamID is nil) was captured.
+	GetGitOpsDrift(ctx context.Context, teamID *uint) ([]*GitOpsDrift, error)
+
+	// /////////////////////////////////////////////////////////////////////////////
+	// fleetd caching proxies
+
+	// ListCacheProxies returns the hosts acting as fleetd caching proxies (the
+	// ones of a fleet if teamID is set) along with their cache statistics.
+	ListCacheProxies(ctx context.Context, teamID *uint) ([]*HostCacheProxy, error)
+
 	// ListAPIEndpoints returns all API endpoints
 	ListAPIEndpoints(ctx context.Context) (endpoints []APIEndpoint, err error)
 
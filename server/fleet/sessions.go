@@ -117,6 +117,14 @@ const (
 	ssoAttrNullRoleValue            = "null"
 )
 
+// SSOGlobalRoleAttrName and SSOFleetRoleAttrNamePrefix are the attribute names
+// understood by RolesFromSSOAttributes. Identity providers that don't use SAML
+// map their role claims to these names.
+const (
+	SSOGlobalRoleAttrName      = globalUserRoleSSOAttrName
+	SSOFleetRoleAttrNamePrefix = teamUserRoleSSOAttrNamePrefixV2
+)
+
 // RolesFromSSOAttributes loads Global and Team roles from SAML custom attributes.
 //   - Custom attribute `FLEET_JIT_USER_ROLE_GLOBAL` is used for setting global role.
 //   - Custom attributes of the form `FLEET_JIT_USER_ROLE_TEAM_<FLEET_ID>` or
@@ -582,6 +582,8 @@ type SoftwareTitleListOptions struct {
 	KnownExploit        bool    `query:"exploit,optional"`
 	MinimumCVSS         float64 `query:"min_cvss_score,optional"`
 	MaximumCVSS         float64 `query:"max_cvss_score,optional"`
+	MinimumEPSS         float64 `query:"min_epss_probability,optional"`
+	MaximumEPSS         float64 `query:"max_epss_probability,optional"`
 	PackagesOnly        bool    `query:"packages_only,optional"`
 	Platform            string  `query:"platform,optional"`
 	HashSHA256          string  `query:"hash_sha256,optional"`
@@ -726,6 +728,8 @@ type SoftwareListOptions struct {
 	KnownExploit                bool    `query:"exploit,optional"`
 	MinimumCVSS                 float64 `query:"min_cvss_score,optional"`
 	MaximumCVSS                 float64 `query:"max_cvss_score,optional"`
+	MinimumEPSS                 float64 `query:"min_epss_probability,optional"`
+	MaximumEPSS                 float64 `query:"max_epss_probability,optional"`
 
 	// WithHostCounts indicates that the list of software should include the
 	// counts of hosts per software, and include only those software that have
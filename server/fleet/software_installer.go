@@ -53,6 +53,9 @@ type SoftwareInstallDetails struct {
 	SoftwareInstallerURL *SoftwareInstallerURL `json:"installer_url,omitempty"`
 	// MaxRetries is the number of additional attempts allowed after the initial attempt (0 = no retries).
 	MaxRetries uint `json:"max_retries,omitempty"`
+	// HashSHA256 is the SHA256 hash of the installer, used by fleetd to
+	// download it through a caching proxy and verify it.
+	HashSHA256 string `json:"hash_sha256,omitempty" db:"hash_sha256"`
 
 	AppOpenQuery    string `json:"-" db:"app_open_query"`
 	PatchWhenClosed bool   `json:"-" db:"patch_when_closed"`
@@ -5,6 +5,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"strings"
 	"time"
 
@@ -83,6 +84,9 @@ type TeamPayloadFeatures struct {
 type HistoricalDataPayload struct {
 	Uptime          optjson.Bool `json:"uptime"`
 	Vulnerabilities optjson.Bool `json:"vulnerabilities"`
+	Policies        optjson.Bool `json:"policies"`
+	MDMEnrollment   optjson.Bool `json:"mdm_enrollment"`
+	DiskEncryption  optjson.Bool `json:"disk_encryption"`
 }
 
 // TeamPayloadMDM is a distinct struct than TeamMDM because in ModifyTeam we
@@ -296,12 +300,47 @@ type TeamWebhookSettings struct {
 	HostActivitiesWebhook *HostActivitiesWebhookSettings `json:"host_activities_webhook"`
 }
 
+// MaskSecrets masks the secrets and custom header values of all webhooks.
+func (w *TeamWebhookSettings) MaskSecrets() {
+	if w.HostStatusWebhook != nil {
+		w.HostStatusWebhook.MaskSecrets()
+	}
+	w.FailingPoliciesWebhook.MaskSecrets()
+	if w.HostActivitiesWebhook != nil {
+		w.HostActivitiesWebhook.MaskSecrets()
+	}
+}
+
+// RestoreMaskedSecrets replaces the masked secrets and custom header values of
+// all webhooks with the stored ones. As fleet webhook settings are replaced as
+// a whole, a webhook sent without a secret nor custom headers (e.g. by the UI,
+// which doesn't edit them) also keeps the stored ones.
+func (w *TeamWebhookSettings) RestoreMaskedSecrets(stored TeamWebhookSettings) {
+	if w.HostStatusWebhook != nil && stored.HostStatusWebhook != nil {
+		w.HostStatusWebhook.restoreTeamSecrets(stored.HostStatusWebhook.WebhookDeliverySettings)
+	}
+	w.FailingPoliciesWebhook.restoreTeamSecrets(stored.FailingPoliciesWebhook.WebhookDeliverySettings)
+	if w.HostActivitiesWebhook != nil && stored.HostActivitiesWebhook != nil {
+		w.HostActivitiesWebhook.restoreTeamSecrets(stored.HostActivitiesWebhook.WebhookDeliverySettings)
+	}
+}
+
+func (s *WebhookDeliverySettings) restoreTeamSecrets(stored WebhookDeliverySettings) {
+	if s.Secret == "" && s.Headers == nil {
+		s.Secret = stored.Secret
+		s.Headers = maps.Clone(stored.Headers)
+		return
+	}
+	s.RestoreMaskedSecrets(stored)
+}
+
 // HostActivitiesWebhookSettings is the per-fleet webhook fired when an
 // activity linked to one of the fleet's hosts is created. The payload has the
 // same format as the global activities webhook (ActivitiesWebhookSettings).
 type HostActivitiesWebhookSettings struct {
 	Enable         bool   `json:"enable_host_activities_webhook"`
 	DestinationURL string `json:"destination_url"`
+	WebhookDeliverySettings
 }
 
 // HostActivitiesWebhookLookup is the subset of Datastore reads needed to
@@ -316,6 +355,8 @@ type HostActivitiesWebhookLookup interface {
 // belong to that fleet. Payloads are scoped this way so a delivery is always
 // exactly one fleet's subscription — it never mixes fleets' host IDs.
 type HostActivitiesWebhookDelivery struct {
+	// TeamID is the fleet of the webhook, 0 for "Unassigned".
+	TeamID         uint
 	DestinationURL string
 	HostIDs        []uint
 }
@@ -368,6 +409,7 @@ func ResolveHostActivitiesWebhooks(ctx context.Context, ds HostActivitiesWebhook
 			continue
 		}
 		deliveries = append(deliveries, HostActivitiesWebhookDelivery{
+			TeamID:         fleetKey,
 			DestinationURL: webhook.DestinationURL,
 			HostIDs:        hostsByFleet[fleetKey],
 		})
@@ -396,8 +438,9 @@ type DefaultTeamWebhookSettings struct {
 
 // DefaultTeamIntegrations contains only the integrations supported for team ID 0
 type DefaultTeamIntegrations struct {
-	Jira    []*TeamJiraIntegration    `json:"jira"`
-	Zendesk []*TeamZendeskIntegration `json:"zendesk"`
+	Jira       []*TeamJiraIntegration       `json:"jira"`
+	Zendesk    []*TeamZendeskIntegration    `json:"zendesk"`
+	ServiceNow []*TeamServiceNowIntegration `json:"servicenow,omitempty"`
 }
 
 type TeamSpecSoftwareAsset struct {
@@ -592,12 +635,15 @@ func (t *TeamConfig) Copy() *TeamConfig {
 	// Deep copy WebhookSettings
 	if t.WebhookSettings.HostStatusWebhook != nil {
 		hostStatusCopy := *t.WebhookSettings.HostStatusWebhook
+		hostStatusCopy.Headers = maps.Clone(hostStatusCopy.Headers)
 		clone.WebhookSettings.HostStatusWebhook = &hostStatusCopy
 	}
 	if t.WebhookSettings.HostActivitiesWebhook != nil {
 		hostActivitiesCopy := *t.WebhookSettings.HostActivitiesWebhook
+		hostActivitiesCopy.Headers = maps.Clone(hostActivitiesCopy.Headers)
 		clone.WebhookSettings.HostActivitiesWebhook = &hostActivitiesCopy
 	}
+	clone.WebhookSettings.FailingPoliciesWebhook.Headers = maps.Clone(t.WebhookSettings.FailingPoliciesWebhook.Headers)
 	if len(t.WebhookSettings.FailingPoliciesWebhook.PolicyIDs) > 0 {
 		clone.WebhookSettings.FailingPoliciesWebhook.PolicyIDs = make([]uint, len(t.WebhookSettings.FailingPoliciesWebhook.PolicyIDs))
 		copy(clone.WebhookSettings.FailingPoliciesWebhook.PolicyIDs, t.WebhookSettings.FailingPoliciesWebhook.PolicyIDs)
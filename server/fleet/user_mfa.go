@@ -0,0 +1,151 @@
+package fleet
+
+import (
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/mfa"
+)
+
+// Second factors that users can enroll to protect their password logins, in
+// addition to the emailed link enabled by User.MFAEnabled.
+const (
+	MFAMethodTOTP         = "totp"
+	MFAMethodWebAuthn     = "webauthn"
+	MFAMethodRecoveryCode = "recovery_code"
+)
+
+// Purposes of the MFA challenges.
+const (
+	// MFAChallengeLogin is the challenge of a password login that must be
+	// completed with one of the second factors of the user.
+	MFAChallengeLogin = "login"
+	// MFAChallengeEnrollment is the challenge of a password login that must
+	// be completed by enrolling a second factor, because the MFA policy
+	// requires one for the user.
+	MFAChallengeEnrollment = "enrollment"
+	// MFAChallengeWebAuthnRegistration is the challenge of the registration of
+	// a WebAuthn credential by a logged in user.
+	MFAChallengeWebAuthnRegistration = "webauthn_registration"
+)
+
+const (
+	// MFAChallengeTTL is how long MFA challenges stay valid.
+	MFAChallengeTTL = 10 * time.Minute
+	// MFAChallengeMaxFailures is the number of invalid second factors after
+	// which a challenge is invalidated and the login must be restarted.
+	MFAChallengeMaxFailures = 5
+)
+
+// UserTOTP is the TOTP secret of a user.
+type UserTOTP struct {
+	UserID uint
+	Secret []byte
+	// ConfirmedAt is the time the user entered a first valid code for the
+	// secret, it is nil while the enrollment is pending.
+	ConfirmedAt *time.Time
+	// LastUsedStep is the TOTP time step of the last code that was accepted,
+	// so that codes can't be replayed.
+	LastUsedStep int64
+}
+
+// WebAuthnCredential is a WebAuthn credential (security key or passkey)
+// registered by a user.
+type WebAuthnCredential struct {
+	ID             uint       `json:"id" db:"id"`
+	UserID         uint       `json:"-" db:"user_id"`
+	Name           string     `json:"name" db:"name"`
+	CredentialID   []byte     `json:"-" db:"credential_id"`
+	PublicKey      []byte     `json:"-" db:"public_key"`
+	SignCount      uint32     `json:"-" db:"sign_count"`
+	BackupEligible bool       `json:"-" db:"backup_eligible"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at" db:"last_used_at"`
+}
+
+// Credential returns the credential in the form used by the WebAuthn
+// ceremonies.
+func (c *WebAuthnCredential) Credential() mfa.Credential {
+	return mfa.Credential{
+		ID:             c.CredentialID,
+		PublicKey:      c.PublicKey,
+		SignCount:      c.SignCount,
+		BackupEligible: c.BackupEligible,
+	}
+}
+
+// UserMFAChallenge is a pending MFA step, identified by a random token.
+type UserMFAChallenge struct {
+	Token   string `db:"token"`
+	UserID  uint   `db:"user_id"`
+	Purpose string `db:"purpose"`
+	// WebAuthnChallenge is the challenge of the WebAuthn ceremony started
+	// with this MFA challenge, if any.
+	WebAuthnChallenge []byte    `db:"webauthn_challenge"`
+	FailedAttempts    uint      `db:"failed_attempts"`
+	CreatedAt         time.Time `db:"created_at"`
+}
+
+// UserMFAStatus describes the second factors enrolled by a user.
+type UserMFAStatus struct {
+	TOTPEnabled            bool                  `json:"totp_enabled"`
+	WebAuthnCredentials    []*WebAuthnCredential `json:"webauthn_credentials"`
+	RecoveryCodesRemaining int                   `json:"recovery_codes_remaining"`
+	// EmailEnabled is true if the user must click a link sent by email to
+	// log in (see User.MFAEnabled).
+	EmailEnabled bool `json:"email_enabled"`
+	// Required is true if the MFA policy requires the user to keep at least
+	// one second factor.
+	Required bool `json:"required"`
+}
+
+// TOTPEnrollment is a new TOTP secret to add to an authenticator app.
+type TOTPEnrollment struct {
+	// Secret is the base32 secret, for users who can't scan the QR code.
+	Secret string `json:"secret"`
+	// URI is the otpauth:// URI of the secret.
+	URI string `json:"uri"`
+	// QRCode is a PNG image of the URI encoded as a QR code.
+	QRCode []byte `json:"qr_code"`
+}
+
+// WebAuthnRegistration is a started registration of a WebAuthn credential.
+type WebAuthnRegistration struct {
+	// Token identifies the registration, it must be sent back with the
+	// credential created by the browser.
+	Token   string              `json:"mfa_token"`
+	Options mfa.CreationOptions `json:"webauthn_registration"`
+}
+
+// LoginMFAChallenge is returned by a password login when a second factor is
+// needed to complete it.
+type LoginMFAChallenge struct {
+	Token string `json:"mfa_token"`
+	// Methods lists the second factors that can be used to complete the
+	// login.
+	Methods []string `json:"mfa_methods"`
+	// WebAuthn are the options to authenticate with one of the WebAuthn
+	// credentials of the user, if any.
+	WebAuthn *mfa.RequestOptions `json:"webauthn,omitempty"`
+
+	// EnrollmentRequired is true if the user has no second factor but the
+	// MFA policy requires one: the login is completed by enrolling one,
+	// using TOTP or WebAuthnRegistration.
+	EnrollmentRequired   bool                 `json:"mfa_enrollment_required,omitempty"`
+	TOTP                 *TOTPEnrollment      `json:"totp,omitempty"`
+	WebAuthnRegistration *mfa.CreationOptions `json:"webauthn_registration,omitempty"`
+}
+
+// MFAVerification is the second step of a password login that returned a
+// LoginMFAChallenge. Exactly one of the second factors must be set.
+type MFAVerification struct {
+	Token string `json:"mfa_token"`
+
+	TOTPCode     string                 `json:"totp_code,omitempty"`
+	RecoveryCode string                 `json:"recovery_code,omitempty"`
+	WebAuthn     *mfa.AssertionResponse `json:"webauthn,omitempty"`
+
+	// WebAuthnRegistration is the credential created by the browser when the
+	// challenge requires enrollment, CredentialName is its display name.
+	WebAuthnRegistration *mfa.RegistrationResponse `json:"webauthn_registration,omitempty"`
+	CredentialName       string                    `json:"credential_name,omitempty"`
+}
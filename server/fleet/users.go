@@ -64,9 +64,21 @@ type User struct {
 	// SSOEnabled if true, the user may only log in via SSO
 	SSOEnabled bool `json:"sso_enabled" db:"sso_enabled"`
 	// MFAEnabled if true, the user (if non-SSO) must click a magic link via email to complete login
-	MFAEnabled bool    `json:"mfa_enabled" db:"mfa_enabled"`
-	GlobalRole *string `json:"global_role" db:"global_role"`
-	APIOnly    bool    `json:"api_only" db:"api_only"`
+	MFAEnabled bool `json:"mfa_enabled" db:"mfa_enabled"`
+	// TOTPEnabled if true, the user (if non-SSO) must enter a code from their
+	// authenticator app (or a recovery code) to complete login. It is computed
+	// from the user's confirmed TOTP secret.
+	TOTPEnabled bool `json:"totp_enabled" db:"totp_enabled"`
+	// WebAuthnEnabled if true, the user (if non-SSO) can use one of their
+	// security keys or passkeys to complete login. It is computed from the
+	// user's registered WebAuthn credentials.
+	WebAuthnEnabled bool    `json:"webauthn_enabled" db:"webauthn_enabled"`
+	GlobalRole      *string `json:"global_role" db:"global_role"`
+	APIOnly         bool    `json:"api_only" db:"api_only"`
+	// Disabled is set when the user was deprovisioned from the identity
+	// provider over SCIM. Disabled users can't log in and all their sessions
+	// and API tokens are revoked.
+	Disabled bool `json:"disabled" db:"disabled"`
 	// LastLoginAt is the last time the user logged in (i.e. the last time a
 	// session was created for the user). It is nil if the user has never
 	// logged in (or hasn't logged in since the column was introduced).
@@ -82,6 +94,10 @@ type User struct {
 	// Teams is the teams this user has roles in. For users with a global role, Teams is expected to be empty.
 	Teams []UserTeam `json:"teams" renameto:"fleets"`
 
+	// CustomRoles are the custom roles assigned to this user, globally or per
+	// team. They grant permissions on top of GlobalRole and Teams.
+	CustomRoles []UserCustomRole `json:"custom_roles,omitempty" db:"-"`
+
 	// Only used to to prevent duplicate invite acceptance
 	InviteID *uint `json:"-" db:"invite_id"`
 
@@ -182,6 +198,27 @@ func (u *User) TeamMembership(pred func(UserTeam) bool) map[uint]bool {
 	return result
 }
 
+// HasSecondFactor returns true if the user enrolled an authenticator app or
+// a WebAuthn credential as a second factor.
+func (u *User) HasSecondFactor() bool {
+	return u.TOTPEnabled || u.WebAuthnEnabled
+}
+
+// HasRoleAboveObserver returns true if the user has a role that grants more
+// than read access, globally or on any team. Custom roles always grant more
+// than read access.
+func (u *User) HasRoleAboveObserver() bool {
+	if u.GlobalRole != nil && *u.GlobalRole != RoleObserver {
+		return true
+	}
+	for _, t := range u.Teams {
+		if t.Role != RoleObserver {
+			return true
+		}
+	}
+	return len(u.CustomRoles) > 0
+}
+
 func (u *User) IsAdminForcedPasswordReset() bool {
 	if u.SSOEnabled {
 		return false
@@ -0,0 +1,115 @@
+package fleet
+
+import "time"
+
+// VEXStatus is the status of a product with regard to a vulnerability, as
+// stated in a VEX (Vulnerability Exploitability eXchange) document.
+type VEXStatus string
+
+const (
+	VEXStatusNotAffected        VEXStatus = "not_affected"
+	VEXStatusAffected           VEXStatus = "affected"
+	VEXStatusFixed              VEXStatus = "fixed"
+	VEXStatusUnderInvestigation VEXStatus = "under_investigation"
+)
+
+// VEXSuppressingStatuses are the statuses of the VEX statements that suppress
+// the matching software vulnerabilities.
+var VEXSuppressingStatuses = []VEXStatus{VEXStatusNotAffected, VEXStatusFixed}
+
+// VEXFormat is the format of a VEX document.
+type VEXFormat string
+
+const (
+	VEXFormatOpenVEX VEXFormat = "openvex"
+	VEXFormatCSAF    VEXFormat = "csaf"
+)
+
+// VEXDocumentSource indicates how a VEX document was added to Fleet.
+type VEXDocumentSource string
+
+const (
+	// VEXDocumentSourceAPI is a VEX document uploaded via the API.
+	VEXDocumentSourceAPI VEXDocumentSource = "api"
+	// VEXDocumentSourceDirectory is a VEX document imported from the
+	// vulnerabilities.vex_path directory.
+	VEXDocumentSourceDirectory VEXDocumentSource = "directory"
+)
+
+// VEXDocument is an OpenVEX or CSAF VEX document ingested by Fleet.
+type VEXDocument struct {
+	ID     uint      `json:"id" db:"id"`
+	Name   string    `json:"name" db:"name"`
+	Format VEXFormat `json:"format" db:"format"`
+	// DocumentID is the identifier of the document set by its author (the
+	// OpenVEX @id or the CSAF tracking ID).
+	DocumentID string            `json:"document_id" db:"document_id"`
+	Author     string            `json:"author" db:"author"`
+	SHA256     string            `json:"sha256" db:"sha256"`
+	Source     VEXDocumentSource `json:"source" db:"source"`
+	// StatementsCount is the number of statements of the document that Fleet
+	// can match against software (i.e. with a package URL or CPE).
+	StatementsCount uint `json:"statements_count" db:"statements_count"`
+	// SuppressedCount is the number of software vulnerabilities currently
+	// suppressed by the statements of the document.
+	SuppressedCount uint      `json:"suppressed_count" db:"suppressed_count"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+
+	// Statements are the statements of the document, only set when creating
+	// it.
+	Statements []VEXStatement `json:"-" db:"-"`
+}
+
+func (VEXDocument) AuthzType() string {
+	return "vex_document"
+}
+
+// VEXStatement is a statement of a VEX document about a single product and
+// vulnerability.
+type VEXStatement struct {
+	ID            uint   `json:"id" db:"id"`
+	VEXDocumentID uint   `json:"vex_document_id" db:"vex_document_id"`
+	CVE           string `json:"cve" db:"cve"`
+	// Product identifies the product, it is a package URL or a CPE 2.3 name.
+	Product         string    `json:"product" db:"product"`
+	Status          VEXStatus `json:"status" db:"status"`
+	Justification   string    `json:"justification" db:"justification"`
+	ImpactStatement string    `json:"impact_statement" db:"impact_statement"`
+}
+
+// VEXCandidate is a software vulnerability that may be suppressed by a VEX
+// statement.
+type VEXCandidate struct {
+	Software
+	CVE string `db:"cve"`
+	// CPE is the CPE matched to the software, if any.
+	CPE string `db:"cpe"`
+}
+
+// VEXSuppression is a software vulnerability suppressed by a VEX statement.
+type VEXSuppression struct {
+	SoftwareID      uint      `json:"software_id" db:"software_id"`
+	SoftwareName    string    `json:"software_name" db:"software_name"`
+	SoftwareVersion string    `json:"software_version" db:"software_version"`
+	CVE             string    `json:"cve" db:"cve"`
+	VEXStatementID  uint      `json:"-" db:"vex_statement_id"`
+	VEXDocumentID   uint      `json:"vex_document_id" db:"vex_document_id"`
+	VEXDocumentName string    `json:"vex_document_name" db:"vex_document_name"`
+	Status          VEXStatus `json:"status" db:"status"`
+	Justification   string    `json:"justification" db:"justification"`
+	ImpactStatement string    `json:"impact_statement" db:"impact_statement"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+}
+
+type VEXSuppressionListOptions struct {
+	// ListOptions cannot be embedded in order to unmarshall with validation.
+	ListOptions ListOptions `url:"list_options"`
+
+	CVE           string `query:"cve,optional"`
+	SoftwareID    *uint  `query:"software_id,optional"`
+	VEXDocumentID *uint  `query:"vex_document_id,optional"`
+}
+
+// VEXDocumentMaxSize is the maximum size of an uploaded VEX document.
+const VEXDocumentMaxSize = 20 * 1024 * 1024
@@ -154,8 +154,36 @@ type VulnListOptions struct {
 	ListOptions      ListOptions `url:"list_options"`
 	IsEE             bool
 	ValidSortColumns []string
-	TeamID           *uint `query:"team_id,optional" renameto:"fleet_id"`
-	KnownExploit     bool  `query:"exploit,optional"`
+	TeamID           *uint   `query:"team_id,optional" renameto:"fleet_id"`
+	KnownExploit     bool    `query:"exploit,optional"`
+	MinimumEPSS      float64 `query:"min_epss_probability,optional"`
+	MaximumEPSS      float64 `query:"max_epss_probability,optional"`
+}
+
+// HasCVEMetaFilters returns true if the options filter the vulnerabilities by
+// their CVE metadata (known exploit or EPSS probability), which is only
+// available in Fleet Premium.
+func (opt VulnListOptions) HasCVEMetaFilters() bool {
+	return opt.KnownExploit || opt.MinimumEPSS > 0 || opt.MaximumEPSS > 0
+}
+
+// ValidateEPSSProbabilityRange verifies the min_epss_probability and
+// max_epss_probability filters. Zero means that the filter isn't set.
+func ValidateEPSSProbabilityRange(minEPSS, maxEPSS float64) error {
+	invalid := &InvalidArgumentError{}
+	if minEPSS < 0 || minEPSS > 1 {
+		invalid.Append("min_epss_probability", "must be between 0 and 1")
+	}
+	if maxEPSS < 0 || maxEPSS > 1 {
+		invalid.Append("max_epss_probability", "must be between 0 and 1")
+	}
+	if minEPSS > 0 && maxEPSS > 0 && minEPSS > maxEPSS {
+		invalid.Append("min_epss_probability", "must be less than or equal to max_epss_probability")
+	}
+	if invalid.HasErrors() {
+		return invalid
+	}
+	return nil
 }
 
 func (opt VulnListOptions) HasValidSortColumn() bool {
@@ -0,0 +1,130 @@
+package fleet
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	vulnerabilityExceptionReasonMaxLen = 65535
+	vulnerabilityExceptionOwnerMaxLen  = 255
+)
+
+var vulnerabilityExceptionCVERegex = regexp.MustCompile(`^CVE-\d{4}-\d{4}\d*$`)
+
+// VulnerabilityException is an accepted risk: while it is active (i.e. until
+// it expires), the CVE is hidden from the vulnerabilities list, the CVE chart
+// and the vulnerability automations.
+//
+// The exception applies to all software affected by the CVE unless
+// SoftwareTitleID is set, and to all fleets unless TeamID is set.
+type VulnerabilityException struct {
+	ID              uint      `json:"id" db:"id"`
+	CVE             string    `json:"cve" db:"cve"`
+	SoftwareTitleID *uint     `json:"software_title_id" db:"software_title_id"`
+	TeamID          *uint     `json:"team_id" db:"team_id" renameto:"fleet_id"`
+	Reason          string    `json:"reason" db:"reason"`
+	Owner           string    `json:"owner" db:"owner"`
+	ExpiresAt       time.Time `json:"expires_at" db:"expires_at"`
+	// Expired is true when the exception has expired, and no longer hides the
+	// CVE.
+	Expired   bool      `json:"expired" db:"expired"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+
+	// SoftwareTitleName and TeamName are loaded from the software title and
+	// team, they are ignored when creating or modifying the exception.
+	SoftwareTitleName *string `json:"software_title_name" db:"software_title_name"`
+	TeamName          *string `json:"team_name" db:"team_name" renameto:"fleet_name"`
+}
+
+func (VulnerabilityException) AuthzType() string {
+	return "vulnerability_exception"
+}
+
+// ScopeKey returns the key identifying the scope of the exception: there can
+// only be one exception per CVE, software title and team.
+func (e VulnerabilityException) ScopeKey() string {
+	var titleID, teamID uint
+	if e.SoftwareTitleID != nil {
+		titleID = *e.SoftwareTitleID
+	}
+	if e.TeamID != nil {
+		teamID = *e.TeamID
+	}
+	return fmt.Sprintf("%s|%d|%d", e.CVE, titleID, teamID)
+}
+
+// NormalizeCVE returns the canonical (trimmed, upper-case) form of a CVE ID.
+func NormalizeCVE(cve string) string {
+	return strings.ToUpper(strings.TrimSpace(cve))
+}
+
+// ValidateVulnerabilityException verifies the fields of a vulnerability
+// exception. The CVE must already be normalized.
+func ValidateVulnerabilityException(e VulnerabilityException) error {
+	invalid := &InvalidArgumentError{}
+	if !vulnerabilityExceptionCVERegex.MatchString(e.CVE) {
+		invalid.Append("cve", fmt.Sprintf("Invalid CVE %q (must be like CVE-2024-12345)", e.CVE))
+	}
+	switch {
+	case strings.TrimSpace(e.Reason) == "":
+		invalid.Append("reason", "Vulnerability exception reason can't be empty")
+	case utf8.RuneCountInString(e.Reason) > vulnerabilityExceptionReasonMaxLen:
+		invalid.Append("reason", fmt.Sprintf("Vulnerability exception reason can't be longer than %d characters", vulnerabilityExceptionReasonMaxLen))
+	}
+	switch {
+	case strings.TrimSpace(e.Owner) == "":
+		invalid.Append("owner", "Vulnerability exception owner can't be empty")
+	case utf8.RuneCountInString(e.Owner) > vulnerabilityExceptionOwnerMaxLen:
+		invalid.Append("owner", fmt.Sprintf("Vulnerability exception owner can't be longer than %d characters", vulnerabilityExceptionOwnerMaxLen))
+	}
+	if e.ExpiresAt.IsZero() {
+		invalid.Append("expires_at", "Vulnerability exception must have an expiration date")
+	}
+
+	if invalid.HasErrors() {
+		return invalid
+	}
+	return nil
+}
+
+// VulnerabilityExceptionPayload is used to create or modify a vulnerability
+// exception. The CVE, software title and team of an exception can't be
+// modified.
+type VulnerabilityExceptionPayload struct {
+	CVE             *string    `json:"cve"`
+	SoftwareTitleID *uint      `json:"software_title_id"`
+	TeamID          *uint      `json:"team_id" renameto:"fleet_id"`
+	Reason          *string    `json:"reason"`
+	Owner           *string    `json:"owner"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+}
+
+// VulnerabilityExceptionSpec is the GitOps representation of a vulnerability
+// exception. Exceptions are matched by CVE, software title and fleet.
+type VulnerabilityExceptionSpec struct {
+	CVE             string `json:"cve"`
+	SoftwareTitleID *uint  `json:"software_title_id,omitempty"`
+	// Fleet is the name of the fleet the exception applies to, all fleets if
+	// empty.
+	Fleet     string    `json:"fleet,omitempty"`
+	Reason    string    `json:"reason"`
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// VulnerabilityExceptionListOptions are the options to list vulnerability
+// exceptions.
+type VulnerabilityExceptionListOptions struct {
+	// ListOptions cannot be embedded in order to unmarshall with validation.
+	ListOptions ListOptions `url:"list_options"`
+
+	CVE    string `query:"cve,optional"`
+	TeamID *uint  `query:"team_id,optional" renameto:"fleet_id"`
+	// ActiveOnly excludes the expired exceptions.
+	ActiveOnly bool `query:"active,optional"`
+}
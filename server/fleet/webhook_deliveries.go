@@ -0,0 +1,173 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/http/httpguts"
+)
+
+// WebhookType identifies the webhook a delivery was sent for.
+type WebhookType string
+
+const (
+	WebhookTypeActivities      WebhookType = "activities"
+	WebhookTypeHostActivities  WebhookType = "host_activities"
+	WebhookTypeHostStatus      WebhookType = "host_status"
+	WebhookTypeFailingPolicies WebhookType = "failing_policies"
+	WebhookTypeVulnerabilities WebhookType = "vulnerabilities"
+	WebhookTypeGitOpsDrift     WebhookType = "gitops_drift"
+)
+
+// IsValid returns true if t is a known webhook type.
+func (t WebhookType) IsValid() bool {
+	switch t {
+	case WebhookTypeActivities, WebhookTypeHostActivities, WebhookTypeHostStatus,
+		WebhookTypeFailingPolicies, WebhookTypeVulnerabilities, WebhookTypeGitOpsDrift:
+		return true
+	}
+	return false
+}
+
+// WebhookDeliveryStatus is the status of a webhook delivery.
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryStatusPending is the status of a delivery that has not
+	// been accepted yet, and is waiting for a retry.
+	WebhookDeliveryStatusPending WebhookDeliveryStatus = "pending"
+	// WebhookDeliveryStatusSuccess is the status of a delivery accepted by the
+	// destination with a 2xx response.
+	WebhookDeliveryStatusSuccess WebhookDeliveryStatus = "success"
+	// WebhookDeliveryStatusFailed is the status of a delivery that failed with
+	// a non-retryable error or that exhausted its retries.
+	WebhookDeliveryStatusFailed WebhookDeliveryStatus = "failed"
+)
+
+// IsValid returns true if s is a known webhook delivery status.
+func (s WebhookDeliveryStatus) IsValid() bool {
+	switch s {
+	case WebhookDeliveryStatusPending, WebhookDeliveryStatusSuccess, WebhookDeliveryStatusFailed:
+		return true
+	}
+	return false
+}
+
+// WebhookDelivery is a request sent by Fleet to a webhook destination, with
+// the result of its last attempt.
+type WebhookDelivery struct {
+	ID          uint        `json:"id" db:"id"`
+	WebhookType WebhookType `json:"webhook_type" db:"webhook_type"`
+	// TeamID is the fleet of the webhook settings used for the delivery, nil
+	// for the global settings and 0 for "Unassigned".
+	TeamID             *uint                 `json:"team_id" db:"team_id" renameto:"fleet_id"`
+	URL                string                `json:"url" db:"url"`
+	Status             WebhookDeliveryStatus `json:"status" db:"status"`
+	Attempts           uint                  `json:"attempts" db:"attempts"`
+	ResponseStatusCode *int                  `json:"response_status_code" db:"response_status_code"`
+	// LatencyMs is the duration of the last attempt, in milliseconds.
+	LatencyMs *uint  `json:"latency_ms" db:"latency_ms"`
+	Error     string `json:"error" db:"error"`
+	// RedeliveryOfID is the ID of the delivery this delivery re-sends, if it
+	// was created by a redelivery.
+	RedeliveryOfID *uint      `json:"redelivery_of_id" db:"redelivery_of_id"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at" db:"last_attempt_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+
+	// Payload is the body of the request. It is only loaded when getting a
+	// single delivery.
+	Payload json.RawMessage `json:"payload,omitempty" db:"payload"`
+}
+
+func (WebhookDelivery) AuthzType() string {
+	return "webhook_delivery"
+}
+
+// WebhookDeliveryListOptions are the options to list webhook deliveries.
+type WebhookDeliveryListOptions struct {
+	// ListOptions cannot be embedded in order to unmarshall with validation.
+	ListOptions ListOptions `url:"list_options"`
+
+	WebhookType WebhookType           `query:"webhook_type,optional"`
+	Status      WebhookDeliveryStatus `query:"status,optional"`
+	TeamID      *uint                 `query:"team_id,optional" renameto:"fleet_id"`
+}
+
+// WebhookDeliverySettings are the settings shared by all webhooks to
+// authenticate their deliveries.
+type WebhookDeliverySettings struct {
+	// Secret is the key used to sign the deliveries with HMAC-SHA256, they are
+	// not signed if empty.
+	Secret string `json:"secret,omitempty"`
+	// Headers are custom headers added to every delivery.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// ReservedWebhookHeaders are the headers set by Fleet on every webhook
+// delivery, that can't be configured as custom headers.
+var ReservedWebhookHeaders = []string{
+	"Content-Type",
+	"Content-Length",
+	"Host",
+	"X-Fleet-Signature",
+	"X-Fleet-Delivery-Id",
+}
+
+// MaskSecrets masks the secret and the custom header values so they are not
+// returned by the API.
+func (s *WebhookDeliverySettings) MaskSecrets() {
+	if s.Secret != "" {
+		s.Secret = MaskedPassword
+	}
+	if len(s.Headers) > 0 {
+		masked := make(map[string]string, len(s.Headers))
+		for k := range s.Headers {
+			masked[k] = MaskedPassword
+		}
+		s.Headers = masked
+	}
+}
+
+// RestoreMaskedSecrets replaces the masked secret and custom header values,
+// as returned by the API, with the stored ones.
+func (s *WebhookDeliverySettings) RestoreMaskedSecrets(stored WebhookDeliverySettings) {
+	if s.Secret == MaskedPassword {
+		s.Secret = stored.Secret
+	}
+	if len(s.Headers) == 0 {
+		return
+	}
+	restored := make(map[string]string, len(s.Headers))
+	for k, v := range s.Headers {
+		if v == MaskedPassword {
+			v = stored.Headers[k]
+		}
+		restored[k] = v
+	}
+	s.Headers = restored
+}
+
+// ValidateWebhookDeliverySettings checks that the custom headers of a webhook
+// are valid HTTP headers and don't override the ones set by Fleet. It adds
+// any error it finds to the invalid argument error, using name as the key
+// prefix.
+func ValidateWebhookDeliverySettings(name string, s WebhookDeliverySettings, invalid *InvalidArgumentError) {
+	for k, v := range s.Headers {
+		key := fmt.Sprintf("%s.headers.%s", name, k)
+		if !httpguts.ValidHeaderFieldName(k) {
+			invalid.Append(key, "invalid header name")
+			continue
+		}
+		for _, reserved := range ReservedWebhookHeaders {
+			if http.CanonicalHeaderKey(k) == reserved {
+				invalid.Append(key, "header is set by Fleet and can't be overridden")
+			}
+		}
+		if !httpguts.ValidHeaderFieldValue(v) {
+			invalid.Append(key, "invalid header value")
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"path/filepath"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
@@ -97,6 +98,30 @@ type SplunkConfig struct {
 	InsecureSkipVerify bool
 }
 
+type OTLPConfig struct {
+	Endpoint string
+
+	Protocol                  string
+	Insecure                  bool
+	Headers                   string
+	Compression               string
+	Timeout                   time.Duration
+	MaxBatchSize              int
+	ResourceAttributes        string
+	DynamicResourceAttributes string
+}
+
+// SpoolConfig configures the on-disk spool in front of the logging plugins.
+// The spool is disabled if Directory is empty.
+type SpoolConfig struct {
+	Directory string
+
+	MaxSize     int64
+	SegmentSize int64
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
 type Config struct {
 	Plugin string
 
@@ -109,9 +134,31 @@ type Config struct {
 	KafkaREST  KafkaRESTConfig
 	Nats       NatsConfig
 	Splunk     SplunkConfig
+	OTLP       OTLPConfig
+
+	Spool SpoolConfig
 }
 
+// NewJSONLogger creates the logger of the configured plugin. If the spool is
+// enabled, the logs are buffered on disk (in a subdirectory named after the
+// logger) and delivered to the plugin in the background until ctx is
+// canceled.
 func NewJSONLogger(ctx context.Context, name string, config Config, logger *slog.Logger) (fleet.JSONLogger, error) {
+	writer, err := newPluginLogger(ctx, name, config, logger)
+	if err != nil {
+		return nil, err
+	}
+	if config.Spool.Directory == "" {
+		return writer, nil
+	}
+	spool, err := NewSpoolLogWriter(ctx, filepath.Join(config.Spool.Directory, name), config.Spool, name, writer, logger)
+	if err != nil {
+		return nil, fmt.Errorf("create %s logging spool: %w", name, err)
+	}
+	return fleet.JSONLogger(spool), nil
+}
+
+func newPluginLogger(ctx context.Context, name string, config Config, logger *slog.Logger) (fleet.JSONLogger, error) {
 	switch config.Plugin {
 	case "":
 		// Allow "" to mean filesystem for backwards compatibility
@@ -250,6 +297,15 @@ func NewJSONLogger(ctx context.Context, name string, config Config, logger *slog
 			return nil, fmt.Errorf("create splunk %s logger: %w", name, err)
 		}
 		return fleet.JSONLogger(writer), nil
+	case "otlp":
+		if config.OTLP.Endpoint == "" {
+			return nil, fmt.Errorf("otlp %s logger: endpoint must not be empty", name)
+		}
+		writer, err := NewOTLPLogWriter(config.OTLP, name, logger)
+		if err != nil {
+			return nil, fmt.Errorf("create otlp %s logger: %w", name, err)
+		}
+		return fleet.JSONLogger(writer), nil
 	default:
 		return nil, fmt.Errorf(
 			"unknown %s log plugin: %s", name, config.Plugin,
@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// OpenTelemetry instruments for the logging spool. When the global
+// MeterProvider is not configured, otel.Meter returns a no-op meter and all
+// operations on these instruments silently succeed.
+
+var (
+	meter = otel.Meter("fleet")
+
+	// spoolBacklogRecords tracks the number of log records waiting in the
+	// spool, labeled by log type (status, result or audit).
+	spoolBacklogRecords metric.Int64UpDownCounter
+
+	// spoolBacklogBytes tracks the on-disk size of the spool, labeled by log
+	// type.
+	spoolBacklogBytes metric.Int64UpDownCounter
+
+	// spoolDroppedRecords counts the log records dropped because the spool
+	// was full, labeled by log type.
+	spoolDroppedRecords metric.Int64Counter
+
+	// spoolDeliveryErrors counts the failed attempts to deliver spooled
+	// records to the logging plugin, labeled by log type.
+	spoolDeliveryErrors metric.Int64Counter
+)
+
+func init() {
+	var err error
+	spoolBacklogRecords, err = meter.Int64UpDownCounter(
+		"fleet.logging.spool.backlog_records",
+		metric.WithDescription("Log records waiting in the logging spool, labeled by log type"),
+		metric.WithUnit("{record}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	spoolBacklogBytes, err = meter.Int64UpDownCounter(
+		"fleet.logging.spool.backlog_size",
+		metric.WithDescription("On-disk size of the logging spool, labeled by log type"),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	spoolDroppedRecords, err = meter.Int64Counter(
+		"fleet.logging.spool.dropped_records",
+		metric.WithDescription("Log records dropped because the logging spool was full, labeled by log type"),
+		metric.WithUnit("{record}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	spoolDeliveryErrors, err = meter.Int64Counter(
+		"fleet.logging.spool.delivery_errors",
+		metric.WithDescription("Failed deliveries of spooled log records to the logging plugin, labeled by log type"),
+		metric.WithUnit("{error}"),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func spoolMetricAttrs(logType string) metric.MeasurementOption {
+	return metric.WithAttributes(attribute.String("log_type", logType))
+}
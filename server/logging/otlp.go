@@ -0,0 +1,375 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	otellog "go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	// otlpDefaultMaxBatchSize is the default maximum number of log records per
+	// export request.
+	otlpDefaultMaxBatchSize = 512
+	// otlpMaxQueueSize is the maximum number of log records queued by the
+	// batch processor. Writes are flushed by chunks of that size, as the
+	// processor drops the oldest records when its queue is full.
+	otlpMaxQueueSize = 2048
+	// otlpHTTPLogsPath is the default path of the OTLP/HTTP logs endpoint.
+	otlpHTTPLogsPath = "/v1/logs"
+	// otlpInstrumentationScope is the name of the scope of the log records.
+	otlpInstrumentationScope = "github.com/fleetdm/fleet/v4/server/logging"
+)
+
+// The dynamic resource attributes that can be enabled with
+// OTLPConfig.DynamicResourceAttributes.
+const (
+	OTLPAttributeHostIdentifier = "host_identifier"
+	OTLPAttributeTeam           = "team"
+	OTLPAttributeQueryName      = "query_name"
+)
+
+// otlpRetryConfig configures the retries of the exports on transient errors
+// (as defined by the OTLP specification), done by the exporters. Tests
+// override it to avoid waiting.
+var otlpRetryConfig = otlploggrpc.RetryConfig{
+	Enabled:         true,
+	InitialInterval: 5 * time.Second,
+	MaxInterval:     30 * time.Second,
+	MaxElapsedTime:  time.Minute,
+}
+
+// otlpErrExporter records the errors of the exports, that the batch processor
+// only reports to the global OpenTelemetry error handler, so that Write can
+// return them and the logs be retried.
+type otlpErrExporter struct {
+	sdklog.Exporter
+
+	mu  sync.Mutex
+	err error
+}
+
+func (e *otlpErrExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	if err := e.Exporter.Export(ctx, records); err != nil {
+		e.mu.Lock()
+		e.err = errors.Join(e.err, err)
+		e.mu.Unlock()
+	}
+	// the error is returned by Write, don't report it twice
+	return nil
+}
+
+// takeErr returns the errors of the exports since the last call.
+func (e *otlpErrExporter) takeErr() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	err := e.err
+	e.err = nil
+	return err
+}
+
+type otlpLogWriter struct {
+	logType   string
+	exporter  *otlpErrExporter
+	processor *sdklog.BatchProcessor
+	resource  []attribute.KeyValue
+	hostID    bool
+	team      bool
+	queryName bool
+
+	// mu serializes the writes, so that the export errors are returned by
+	// the write that emitted the records.
+	mu sync.Mutex
+}
+
+// NewOTLPLogWriter creates a log writer that sends the logs as OTLP log
+// records, over gRPC or HTTP. logType ("status", "result" or "audit") is added
+// as the fleet.log.type resource attribute.
+func NewOTLPLogWriter(config OTLPConfig, logType string, logger *slog.Logger) (*otlpLogWriter, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("endpoint must not be empty")
+	}
+	headers, err := parseOTLPKeyValues(config.Headers)
+	if err != nil {
+		return nil, fmt.Errorf("parse headers: %w", err)
+	}
+	resourceAttrs, err := parseOTLPKeyValues(config.ResourceAttributes)
+	if err != nil {
+		return nil, fmt.Errorf("parse resource attributes: %w", err)
+	}
+	switch config.Compression {
+	case "", "gzip", "none":
+	default:
+		return nil, fmt.Errorf("unsupported compression %q (must be gzip or none)", config.Compression)
+	}
+	maxBatchSize := config.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = otlpDefaultMaxBatchSize
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	w := &otlpLogWriter{logType: logType}
+	for attr := range strings.SplitSeq(config.DynamicResourceAttributes, ",") {
+		switch strings.TrimSpace(attr) {
+		case "":
+		case OTLPAttributeHostIdentifier:
+			w.hostID = true
+		case OTLPAttributeTeam:
+			w.team = true
+		case OTLPAttributeQueryName:
+			w.queryName = true
+		default:
+			return nil, fmt.Errorf("unknown dynamic resource attribute %q (must be one of %s, %s or %s)",
+				attr, OTLPAttributeHostIdentifier, OTLPAttributeTeam, OTLPAttributeQueryName)
+		}
+	}
+
+	// service.name is required by most backends, default it to fleet
+	if _, ok := resourceAttrs["service.name"]; !ok {
+		resourceAttrs["service.name"] = "fleet"
+	}
+	resourceAttrs["fleet.log.type"] = logType
+	for _, k := range slices.Sorted(maps.Keys(resourceAttrs)) {
+		w.resource = append(w.resource, attribute.String(k, resourceAttrs[k]))
+	}
+
+	var exporter sdklog.Exporter
+	switch config.Protocol {
+	case "", "grpc":
+		exporter, err = newOTLPGRPCExporter(config, headers, timeout)
+	case "http":
+		exporter, err = newOTLPHTTPExporter(config, headers, timeout)
+	default:
+		return nil, fmt.Errorf("unsupported protocol %q (must be grpc or http)", config.Protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+	w.exporter = &otlpErrExporter{Exporter: exporter}
+	w.processor = sdklog.NewBatchProcessor(w.exporter,
+		sdklog.WithMaxQueueSize(otlpMaxQueueSize),
+		sdklog.WithExportMaxBatchSize(maxBatchSize),
+		sdklog.WithExportTimeout(timeout),
+	)
+	return w, nil
+}
+
+func newOTLPGRPCExporter(config OTLPConfig, headers map[string]string, timeout time.Duration) (*otlploggrpc.Exporter, error) {
+	compressor := "gzip"
+	if config.Compression == "none" {
+		compressor = "none"
+	}
+	opts := []otlploggrpc.Option{
+		otlploggrpc.WithHeaders(headers),
+		otlploggrpc.WithCompressor(compressor),
+		otlploggrpc.WithTimeout(timeout),
+		otlploggrpc.WithRetry(otlpRetryConfig),
+	}
+	// the endpoint is a host:port, a scheme can be used to select TLS
+	if u, err := url.Parse(config.Endpoint); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		opts = append(opts, otlploggrpc.WithEndpointURL(config.Endpoint))
+	} else {
+		opts = append(opts, otlploggrpc.WithEndpoint(config.Endpoint))
+	}
+	if config.Insecure {
+		opts = append(opts, otlploggrpc.WithInsecure())
+	}
+
+	exporter, err := otlploggrpc.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create otlp grpc exporter: %w", err)
+	}
+	return exporter, nil
+}
+
+func newOTLPHTTPExporter(config OTLPConfig, headers map[string]string, timeout time.Duration) (*otlploghttp.Exporter, error) {
+	compression := otlploghttp.GzipCompression
+	if config.Compression == "none" {
+		compression = otlploghttp.NoCompression
+	}
+	opts := []otlploghttp.Option{
+		otlploghttp.WithHeaders(headers),
+		otlploghttp.WithCompression(compression),
+		otlploghttp.WithTimeout(timeout),
+		otlploghttp.WithRetry(otlploghttp.RetryConfig(otlpRetryConfig)),
+	}
+	if strings.Contains(config.Endpoint, "://") {
+		u, err := url.Parse(config.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("parse otlp endpoint: %w", err)
+		}
+		opts = append(opts, otlploghttp.WithEndpointURL(config.Endpoint))
+		if u.Path == "" || u.Path == "/" {
+			opts = append(opts, otlploghttp.WithURLPath(otlpHTTPLogsPath))
+		}
+		if config.Insecure && u.Scheme == "https" {
+			opts = append(opts, otlploghttp.WithTLSClientConfig(&tls.Config{
+				InsecureSkipVerify: true, //nolint:gosec // user-configured option for self-signed certs
+			}))
+		}
+	} else {
+		opts = append(opts, otlploghttp.WithEndpoint(config.Endpoint))
+		if config.Insecure {
+			opts = append(opts, otlploghttp.WithInsecure())
+		}
+	}
+
+	exporter, err := otlploghttp.New(context.Background(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("create otlp http exporter: %w", err)
+	}
+	return exporter, nil
+}
+
+// parseOTLPKeyValues parses comma-separated key=value pairs, the format of the
+// OTEL_EXPORTER_OTLP_HEADERS and OTEL_RESOURCE_ATTRIBUTES environment
+// variables. Values are URL-decoded.
+func parseOTLPKeyValues(s string) (map[string]string, error) {
+	kvs := make(map[string]string)
+	for pair := range strings.SplitSeq(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid key=value pair %q", pair)
+		}
+		v, err := url.PathUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %q: %w", k, err)
+		}
+		kvs[k] = v
+	}
+	return kvs, nil
+}
+
+// otlpLogFields are the fields of osquery logs used to fill the OTLP log
+// records and their resource attributes.
+type otlpLogFields struct {
+	HostIdentifier string      `json:"hostIdentifier"`
+	Name           string      `json:"name"`
+	UnixTime       json.Number `json:"unixTime"`
+	Severity       json.Number `json:"severity"`
+}
+
+func (w *otlpLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	// all the logs of a request are sent by the same host, if any (audit logs
+	// aren't)
+	var teamAttrs []attribute.KeyValue
+	if host, ok := hostctx.FromContext(ctx); ok && w.team && host.TeamID != nil {
+		teamAttrs = append(teamAttrs, attribute.Int64("fleet.team.id", int64(*host.TeamID)))
+		if host.TeamName != nil {
+			teamAttrs = append(teamAttrs, attribute.String("fleet.team.name", *host.TeamName))
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	loggers := make(map[[2]string]otellog.Logger)
+	for chunk := range slices.Chunk(logs, otlpMaxQueueSize) {
+		for _, l := range chunk {
+			w.emit(ctx, l, teamAttrs, loggers)
+		}
+		if err := w.processor.ForceFlush(ctx); err != nil {
+			return ctxerr.Wrap(ctx, err, "flush otlp logs")
+		}
+		if err := w.exporter.takeErr(); err != nil {
+			return ctxerr.Wrap(ctx, err, "export otlp logs")
+		}
+	}
+	return nil
+}
+
+// emit emits the log record of the log, with the logger of its resource.
+// Records of the same resource are grouped in the export requests.
+func (w *otlpLogWriter) emit(ctx context.Context, l json.RawMessage, teamAttrs []attribute.KeyValue, loggers map[[2]string]otellog.Logger) {
+	var fields otlpLogFields
+	if w.hostID || w.queryName || w.logType == "status" || w.logType == "result" {
+		// logs that don't match the expected format are sent without the
+		// extracted fields
+		_ = json.Unmarshal(l, &fields)
+	}
+
+	var key [2]string
+	if w.hostID {
+		key[0] = fields.HostIdentifier
+	}
+	if w.queryName {
+		key[1] = fields.Name
+	}
+	logger, ok := loggers[key]
+	if !ok {
+		attrs := slices.Clone(w.resource)
+		if key[0] != "" {
+			attrs = append(attrs, attribute.String("host.id", key[0]))
+		}
+		attrs = append(attrs, teamAttrs...)
+		if key[1] != "" {
+			attrs = append(attrs, attribute.String("fleet.query.name", key[1]))
+		}
+		// the providers share the processor of the writer, they hold no
+		// other state
+		provider := sdklog.NewLoggerProvider(
+			sdklog.WithResource(resource.NewWithAttributes("", attrs...)),
+			sdklog.WithProcessor(w.processor),
+		)
+		logger = provider.Logger(otlpInstrumentationScope)
+		loggers[key] = logger
+	}
+
+	var record otellog.Record
+	record.SetBody(otellog.StringValue(string(bytes.TrimSpace(l))))
+	if ts, err := fields.UnixTime.Int64(); err == nil && ts > 0 {
+		record.SetTimestamp(time.Unix(ts, 0))
+	}
+	if w.logType == "status" {
+		severity, text := otlpStatusLogSeverity(fields.Severity)
+		record.SetSeverity(severity)
+		record.SetSeverityText(text)
+	}
+	logger.Emit(ctx, record)
+}
+
+// otlpStatusLogSeverity maps the severity of an osquery status log (0 for
+// info, 1 for warning, 2 for error and 3 for fatal) to the OTLP severity.
+func otlpStatusLogSeverity(severity json.Number) (otellog.Severity, string) {
+	s, _ := strconv.Atoi(severity.String())
+	switch s {
+	case 1:
+		return otellog.SeverityWarn, "WARN"
+	case 2:
+		return otellog.SeverityError, "ERROR"
+	case 3:
+		return otellog.SeverityFatal, "FATAL"
+	default:
+		return otellog.SeverityInfo, "INFO"
+	}
+}
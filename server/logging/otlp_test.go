@@ -0,0 +1,286 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type otlpTestLogsServer struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu       sync.Mutex
+	requests []*collogspb.ExportLogsServiceRequest
+	metadata []metadata.MD
+	failures int
+}
+
+func (s *otlpTestLogsServer) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return nil, status.Error(codes.Unavailable, "unavailable")
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.requests = append(s.requests, req)
+	s.metadata = append(s.metadata, md)
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func startOTLPTestGRPCServer(t *testing.T) (*otlpTestLogsServer, string) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	logsSrv := &otlpTestLogsServer{}
+	collogspb.RegisterLogsServiceServer(srv, logsSrv)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return logsSrv, lis.Addr().String()
+}
+
+func otlpResourceAttrs(rl *logspb.ResourceLogs) map[string]any {
+	attrs := make(map[string]any)
+	for _, kv := range rl.GetResource().GetAttributes() {
+		switch v := kv.GetValue().GetValue().(type) {
+		case *commonpb.AnyValue_StringValue:
+			attrs[kv.GetKey()] = v.StringValue
+		case *commonpb.AnyValue_IntValue:
+			attrs[kv.GetKey()] = v.IntValue
+		}
+	}
+	return attrs
+}
+
+// otlpResourceLogsByQuery returns the resource logs by their fleet.query.name
+// resource attribute, as the order of the resources of a request is not
+// defined.
+func otlpResourceLogsByQuery(rls []*logspb.ResourceLogs) map[string]*logspb.ResourceLogs {
+	byQuery := make(map[string]*logspb.ResourceLogs, len(rls))
+	for _, rl := range rls {
+		name, _ := otlpResourceAttrs(rl)["fleet.query.name"].(string)
+		byQuery[name] = rl
+	}
+	return byQuery
+}
+
+// withFastOTLPRetries makes the exporters retry without waiting for the
+// duration of the test.
+func withFastOTLPRetries(t *testing.T) {
+	orig := otlpRetryConfig
+	otlpRetryConfig.InitialInterval = time.Millisecond
+	otlpRetryConfig.MaxInterval = time.Millisecond
+	otlpRetryConfig.MaxElapsedTime = 200 * time.Millisecond
+	t.Cleanup(func() { otlpRetryConfig = orig })
+}
+
+func TestOTLPWriteGRPC(t *testing.T) {
+	ctx := hostctx.NewContext(t.Context(), &fleet.Host{TeamID: ptr.Uint(3), TeamName: ptr.String("Workstations")})
+	logsSrv, addr := startOTLPTestGRPCServer(t)
+
+	writer, err := NewOTLPLogWriter(OTLPConfig{
+		Endpoint:                  addr,
+		Insecure:                  true,
+		Headers:                   "x-api-key=secret",
+		ResourceAttributes:        "deployment.environment=prod",
+		DynamicResourceAttributes: "host_identifier,team,query_name",
+	}, "result", slog.Default())
+	require.NoError(t, err)
+
+	resultLogs := []json.RawMessage{
+		json.RawMessage(`{"name":"pack/Global/uptime","hostIdentifier":"host1","unixTime":1700000000,"columns":{"days":"1"}}`),
+		json.RawMessage(`{"name":"pack/Global/uptime","hostIdentifier":"host1","unixTime":1700000001,"columns":{"days":"2"}}`),
+		json.RawMessage(`{"name":"pack/Global/users","hostIdentifier":"host1","unixTime":1700000002,"columns":{"uid":"0"}}`),
+	}
+	require.NoError(t, writer.Write(ctx, resultLogs))
+
+	require.Len(t, logsSrv.requests, 1)
+	assert.Equal(t, []string{"secret"}, logsSrv.metadata[0].Get("x-api-key"))
+
+	// one resource per query name
+	rls := otlpResourceLogsByQuery(logsSrv.requests[0].GetResourceLogs())
+	require.Len(t, rls, 2)
+	uptime, users := rls["pack/Global/uptime"], rls["pack/Global/users"]
+	require.NotNil(t, uptime)
+	require.NotNil(t, users)
+	assert.Equal(t, map[string]any{
+		"service.name":           "fleet",
+		"deployment.environment": "prod",
+		"fleet.log.type":         "result",
+		"host.id":                "host1",
+		"fleet.team.id":          int64(3),
+		"fleet.team.name":        "Workstations",
+		"fleet.query.name":       "pack/Global/uptime",
+	}, otlpResourceAttrs(uptime))
+
+	assert.Equal(t, otlpInstrumentationScope, uptime.GetScopeLogs()[0].GetScope().GetName())
+	records := uptime.GetScopeLogs()[0].GetLogRecords()
+	require.Len(t, records, 2)
+	assert.JSONEq(t, string(resultLogs[0]), records[0].GetBody().GetStringValue())
+	assert.Equal(t, uint64(1700000000*time.Second), records[0].GetTimeUnixNano())
+	assert.NotZero(t, records[0].GetObservedTimeUnixNano())
+	require.Len(t, users.GetScopeLogs()[0].GetLogRecords(), 1)
+}
+
+func TestOTLPWriteBatchesAndRetries(t *testing.T) {
+	withFastOTLPRetries(t)
+
+	logsSrv, addr := startOTLPTestGRPCServer(t)
+	logsSrv.failures = 2
+
+	writer, err := NewOTLPLogWriter(OTLPConfig{
+		Endpoint:     addr,
+		Insecure:     true,
+		Compression:  "none",
+		MaxBatchSize: 2,
+	}, "status", slog.Default())
+	require.NoError(t, err)
+
+	statusLogs := []json.RawMessage{
+		json.RawMessage(`{"hostIdentifier":"host1","severity":"0","message":"info"}`),
+		json.RawMessage(`{"hostIdentifier":"host1","severity":"1","message":"warning"}`),
+		json.RawMessage(`{"hostIdentifier":"host1","severity":"2","message":"error"}`),
+	}
+	require.NoError(t, writer.Write(t.Context(), statusLogs))
+
+	// the first batch was retried until it succeeded, then the second batch
+	// was sent
+	require.Len(t, logsSrv.requests, 2)
+	first := logsSrv.requests[0].GetResourceLogs()
+	require.Len(t, first, 1)
+	// no dynamic attributes were configured
+	assert.NotContains(t, otlpResourceAttrs(first[0]), "host.id")
+	records := first[0].GetScopeLogs()[0].GetLogRecords()
+	require.Len(t, records, 2)
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_INFO, records[0].GetSeverityNumber())
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, records[1].GetSeverityNumber())
+	second := logsSrv.requests[1].GetResourceLogs()[0].GetScopeLogs()[0].GetLogRecords()
+	require.Len(t, second, 1)
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, second[0].GetSeverityNumber())
+
+	// the write fails once the retries are exhausted
+	logsSrv.mu.Lock()
+	logsSrv.failures = 1000
+	logsSrv.mu.Unlock()
+	require.Error(t, writer.Write(t.Context(), statusLogs))
+}
+
+func TestOTLPWriteHTTP(t *testing.T) {
+	withFastOTLPRetries(t)
+
+	var (
+		mu       sync.Mutex
+		calls    int
+		failWith int
+		requests []*collogspb.ExportLogsServiceRequest
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if failWith != 0 {
+			w.WriteHeader(failWith)
+			return
+		}
+		assert.Equal(t, otlpHTTPLogsPath, r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		zr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		var req collogspb.ExportLogsServiceRequest
+		require.NoError(t, proto.Unmarshal(body, &req))
+		requests = append(requests, &req)
+
+		resp, err := proto.Marshal(&collogspb.ExportLogsServiceResponse{})
+		require.NoError(t, err)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = io.Copy(w, bytes.NewReader(resp))
+	}))
+	defer server.Close()
+
+	writer, err := NewOTLPLogWriter(OTLPConfig{
+		Endpoint: server.URL,
+		Protocol: "http",
+		Headers:  "Authorization=Bearer%20token",
+	}, "audit", slog.Default())
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Write(t.Context(), logs))
+	assert.Equal(t, 2, calls)
+	require.Len(t, requests, 1)
+	rls := requests[0].GetResourceLogs()
+	require.Len(t, rls, 1)
+	assert.Equal(t, "audit", otlpResourceAttrs(rls[0])["fleet.log.type"])
+	records := rls[0].GetScopeLogs()[0].GetLogRecords()
+	require.Len(t, records, len(logs))
+	for i, rec := range records {
+		assert.JSONEq(t, string(logs[i]), rec.GetBody().GetStringValue())
+	}
+
+	// client errors are not retried
+	mu.Lock()
+	failWith = http.StatusBadRequest
+	mu.Unlock()
+	require.Error(t, writer.Write(t.Context(), logs))
+	assert.Equal(t, 3, calls)
+}
+
+func TestOTLPWriteEmpty(t *testing.T) {
+	logsSrv, addr := startOTLPTestGRPCServer(t)
+
+	writer, err := NewOTLPLogWriter(OTLPConfig{Endpoint: addr, Insecure: true}, "result", slog.Default())
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(t.Context(), []json.RawMessage{}))
+	assert.Empty(t, logsSrv.requests)
+}
+
+func TestNewOTLPLogWriterValidation(t *testing.T) {
+	cases := []struct {
+		name   string
+		config OTLPConfig
+		errMsg string
+	}{
+		{"no endpoint", OTLPConfig{}, "endpoint must not be empty"},
+		{"bad protocol", OTLPConfig{Endpoint: "localhost:4317", Protocol: "udp"}, "unsupported protocol"},
+		{"bad compression", OTLPConfig{Endpoint: "localhost:4317", Compression: "zstd"}, "unsupported compression"},
+		{"bad headers", OTLPConfig{Endpoint: "localhost:4317", Headers: "novalue"}, "parse headers"},
+		{"bad attribute", OTLPConfig{Endpoint: "localhost:4317", DynamicResourceAttributes: "hostname"}, "unknown dynamic resource attribute"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := NewOTLPLogWriter(c.config, "result", slog.Default())
+			require.ErrorContains(t, err, c.errMsg)
+		})
+	}
+}
@@ -0,0 +1,509 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+const (
+	// spoolSegmentExt is the extension of the spool segment files, named after
+	// their zero-padded sequence number.
+	spoolSegmentExt = ".seg"
+	// spoolCheckpointFile stores the sequence number of the segment being
+	// delivered and the offset of the next record to deliver in it.
+	spoolCheckpointFile = "checkpoint"
+	// spoolRecordHeaderSize is the size of the length prefix of each record.
+	spoolRecordHeaderSize = 4
+	// spoolBatchSize is the maximum number of records delivered to the
+	// logging plugin in a single Write.
+	spoolBatchSize = 500
+
+	spoolDefaultMaxSize     = 1 << 30 // 1GiB
+	spoolDefaultSegmentSize = 16 << 20
+	spoolDefaultMinBackoff  = time.Second
+	spoolDefaultMaxBackoff  = 5 * time.Minute
+)
+
+// spoolSegment is a spool file. Records are appended to the active (last)
+// segment only, and segments are deleted once all their records are
+// delivered.
+type spoolSegment struct {
+	seq     uint64
+	size    int64
+	records int
+}
+
+// spoolLogWriter is a write-ahead spool in front of a logging plugin. Logs are
+// appended to on-disk segments and Write returns as soon as they are synced
+// to disk. A background goroutine delivers them to the plugin in order,
+// retrying with exponential backoff while the plugin fails. The spool
+// survives restarts: undelivered records are delivered when the spool is
+// reopened.
+type spoolLogWriter struct {
+	dir     string
+	logType string
+	next    fleet.JSONLogger
+	logger  *slog.Logger
+
+	maxSize     int64
+	segmentSize int64
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+
+	// notify wakes up the delivery goroutine after a write.
+	notify chan struct{}
+
+	mu       sync.Mutex
+	segments []*spoolSegment
+	active   *os.File // the file of the last segment, nil if it is closed
+	nextSeq  uint64
+	size     int64
+	// offset and sentRecords are the delivery progress in segments[0].
+	offset      int64
+	sentRecords int
+}
+
+// NewSpoolLogWriter opens (or creates) the spool in dir, and starts delivering
+// its records to next until ctx is canceled. logType is used for logging and
+// metrics.
+func NewSpoolLogWriter(ctx context.Context, dir string, config SpoolConfig, logType string, next fleet.JSONLogger, logger *slog.Logger) (*spoolLogWriter, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("create spool directory: %w", err)
+	}
+
+	s := &spoolLogWriter{
+		dir:         dir,
+		logType:     logType,
+		next:        next,
+		logger:      logger.With("component", "logging-spool", "log_type", logType),
+		maxSize:     config.MaxSize,
+		segmentSize: config.SegmentSize,
+		minBackoff:  config.MinBackoff,
+		maxBackoff:  config.MaxBackoff,
+		notify:      make(chan struct{}, 1),
+	}
+	if s.maxSize <= 0 {
+		s.maxSize = spoolDefaultMaxSize
+	}
+	if s.segmentSize <= 0 {
+		s.segmentSize = spoolDefaultSegmentSize
+	}
+	if s.minBackoff <= 0 {
+		s.minBackoff = spoolDefaultMinBackoff
+	}
+	if s.maxBackoff < s.minBackoff {
+		s.maxBackoff = max(spoolDefaultMaxBackoff, s.minBackoff)
+	}
+
+	if err := s.load(); err != nil {
+		return nil, fmt.Errorf("load spool: %w", err)
+	}
+	var records int
+	for _, seg := range s.segments {
+		records += seg.records
+	}
+	records -= s.sentRecords
+	spoolBacklogRecords.Add(ctx, int64(records), spoolMetricAttrs(logType))
+	spoolBacklogBytes.Add(ctx, s.size, spoolMetricAttrs(logType))
+	if records > 0 {
+		s.logger.InfoContext(ctx, "delivering spooled logs", "records", records)
+	}
+
+	go s.deliver(ctx)
+	return s, nil
+}
+
+func (s *spoolLogWriter) segmentPath(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, spoolSegmentExt))
+}
+
+// load reads the existing segments and the checkpoint of the spool.
+func (s *spoolLogWriter) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	var seqs []uint64
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), spoolSegmentExt)
+		if !ok || e.IsDir() {
+			continue
+		}
+		seq, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			continue
+		}
+		seqs = append(seqs, seq)
+	}
+	slices.Sort(seqs)
+
+	cpSeq, cpOffset, err := s.readCheckpoint()
+	if err != nil {
+		return err
+	}
+	for _, seq := range seqs {
+		if seq < cpSeq {
+			// delivered, but not deleted before a restart
+			if err := os.Remove(s.segmentPath(seq)); err != nil {
+				return err
+			}
+			continue
+		}
+		seg := &spoolSegment{seq: seq}
+		offsets, err := scanSpoolSegment(s.segmentPath(seq))
+		if err != nil {
+			return err
+		}
+		seg.records = len(offsets)
+		if len(offsets) > 0 {
+			seg.size = offsets[len(offsets)-1]
+		}
+		if len(s.segments) == 0 && seq == cpSeq {
+			s.offset = min(cpOffset, seg.size)
+			for _, end := range offsets {
+				if end <= s.offset {
+					s.sentRecords++
+				}
+			}
+		}
+		s.segments = append(s.segments, seg)
+		s.size += seg.size
+		s.nextSeq = seq + 1
+	}
+	s.nextSeq = max(s.nextSeq, cpSeq+1)
+	return nil
+}
+
+// scanSpoolSegment returns the end offsets of the records in a segment. A
+// record that was only partially written (e.g. Fleet crashed while writing)
+// is truncated from the file.
+func scanSpoolSegment(path string) ([]int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		offsets []int64
+		offset  int64
+		header  [spoolRecordHeaderSize]byte
+	)
+	r := bufio.NewReader(f)
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			break
+		}
+		end := offset + spoolRecordHeaderSize + int64(binary.BigEndian.Uint32(header[:]))
+		if end > info.Size() {
+			break
+		}
+		if _, err := r.Discard(int(end - offset - spoolRecordHeaderSize)); err != nil {
+			break
+		}
+		offset = end
+		offsets = append(offsets, end)
+	}
+	if offset < info.Size() {
+		if err := f.Truncate(offset); err != nil {
+			return nil, fmt.Errorf("truncate partial record: %w", err)
+		}
+	}
+	return offsets, nil
+}
+
+func (s *spoolLogWriter) readCheckpoint() (seq uint64, offset int64, err error) {
+	b, err := os.ReadFile(filepath.Join(s.dir, spoolCheckpointFile))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return 0, 0, nil
+		}
+		return 0, 0, err
+	}
+	if _, err := fmt.Sscan(string(b), &seq, &offset); err != nil {
+		return 0, 0, fmt.Errorf("invalid spool checkpoint: %w", err)
+	}
+	return seq, offset, nil
+}
+
+// writeCheckpoint must be called with s.mu held.
+func (s *spoolLogWriter) writeCheckpoint(seq uint64, offset int64) error {
+	path := filepath.Join(s.dir, spoolCheckpointFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, fmt.Appendf(nil, "%d %d\n", seq, offset), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Write appends the logs to the spool. If the spool is full, the oldest
+// segments are dropped to make room for them.
+func (s *spoolLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, l := range logs {
+		var header [spoolRecordHeaderSize]byte
+		binary.BigEndian.PutUint32(header[:], uint32(len(l))) //nolint:gosec // dismiss G115
+		buf.Write(header[:])
+		buf.Write(l)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if int64(buf.Len()) > s.maxSize {
+		spoolDroppedRecords.Add(ctx, int64(len(logs)), spoolMetricAttrs(s.logType))
+		s.logger.WarnContext(ctx, "dropping logs larger than the spool", "records", len(logs), "size", buf.Len())
+		return nil
+	}
+	for s.size+int64(buf.Len()) > s.maxSize && len(s.segments) > 0 {
+		if err := s.dropOldest(ctx); err != nil {
+			return fmt.Errorf("drop oldest spool segment: %w", err)
+		}
+	}
+
+	if s.active == nil || s.segments[len(s.segments)-1].size >= s.segmentSize {
+		if err := s.rotate(); err != nil {
+			return fmt.Errorf("create spool segment: %w", err)
+		}
+	}
+	seg := s.segments[len(s.segments)-1]
+	if _, err := s.active.Write(buf.Bytes()); err != nil {
+		// don't leave a partial record behind, the next write starts a new
+		// segment
+		_ = s.active.Truncate(seg.size)
+		s.closeActive()
+		return fmt.Errorf("write spool segment: %w", err)
+	}
+	if err := s.active.Sync(); err != nil {
+		s.closeActive()
+		return fmt.Errorf("sync spool segment: %w", err)
+	}
+	seg.size += int64(buf.Len())
+	seg.records += len(logs)
+	s.size += int64(buf.Len())
+	spoolBacklogRecords.Add(ctx, int64(len(logs)), spoolMetricAttrs(s.logType))
+	spoolBacklogBytes.Add(ctx, int64(buf.Len()), spoolMetricAttrs(s.logType))
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// rotate closes the active segment and starts a new one. It must be called
+// with s.mu held.
+func (s *spoolLogWriter) rotate() error {
+	s.closeActive()
+	f, err := os.OpenFile(s.segmentPath(s.nextSeq), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	s.active = f
+	s.segments = append(s.segments, &spoolSegment{seq: s.nextSeq})
+	s.nextSeq++
+	return nil
+}
+
+// closeActive must be called with s.mu held.
+func (s *spoolLogWriter) closeActive() {
+	if s.active != nil {
+		_ = s.active.Close()
+		s.active = nil
+	}
+}
+
+// dropOldest deletes the oldest segment, undelivered records included. It
+// must be called with s.mu held.
+func (s *spoolLogWriter) dropOldest(ctx context.Context) error {
+	seg := s.segments[0]
+	dropped := seg.records - s.sentRecords
+	if len(s.segments) == 1 {
+		s.closeActive()
+	}
+	if err := s.removeHead(ctx); err != nil {
+		return err
+	}
+	spoolDroppedRecords.Add(ctx, int64(dropped), spoolMetricAttrs(s.logType))
+	s.logger.WarnContext(ctx, "spool is full, dropped oldest logs", "records", dropped)
+	return nil
+}
+
+// removeHead deletes the oldest segment and resets the delivery progress. It
+// must be called with s.mu held.
+func (s *spoolLogWriter) removeHead(ctx context.Context) error {
+	seg := s.segments[0]
+	if err := s.writeCheckpoint(seg.seq+1, 0); err != nil {
+		return err
+	}
+	if err := os.Remove(s.segmentPath(seg.seq)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	spoolBacklogRecords.Add(ctx, -int64(seg.records-s.sentRecords), spoolMetricAttrs(s.logType))
+	spoolBacklogBytes.Add(ctx, -seg.size, spoolMetricAttrs(s.logType))
+	s.size -= seg.size
+	s.segments = s.segments[1:]
+	s.offset, s.sentRecords = 0, 0
+	return nil
+}
+
+// deliver sends the spooled records to the logging plugin until ctx is
+// canceled.
+func (s *spoolLogWriter) deliver(ctx context.Context) {
+	defer func() {
+		s.mu.Lock()
+		s.closeActive()
+		s.mu.Unlock()
+	}()
+
+	var failures int
+	for {
+		err := s.deliverPending(ctx)
+		wait := s.notify
+		var timer <-chan time.Time
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			spoolDeliveryErrors.Add(ctx, 1, spoolMetricAttrs(s.logType))
+			delay := s.maxBackoff
+			if failures < 32 {
+				delay = min(s.minBackoff*time.Duration(1<<failures), s.maxBackoff)
+			}
+			failures++
+			s.logger.WarnContext(ctx, "failed to deliver spooled logs, retrying", "err", err, "retry_in", delay)
+			// don't retry earlier on new writes
+			wait = nil
+			timer = time.After(delay)
+		} else {
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wait:
+		case <-timer:
+		}
+	}
+}
+
+// deliverPending sends all the spooled records to the logging plugin, and
+// returns the first error returned by the plugin.
+func (s *spoolLogWriter) deliverPending(ctx context.Context) error {
+	for {
+		seq, offset, ok := s.nextSegment()
+		if !ok {
+			return nil
+		}
+		if err := s.deliverSegment(ctx, seq, offset); err != nil {
+			return err
+		}
+	}
+}
+
+// nextSegment returns the oldest segment with records to deliver, and the
+// offset of its next record to deliver. The active segment is closed before
+// it's delivered, so that delivered segments are immutable.
+func (s *spoolLogWriter) nextSegment() (seq uint64, offset int64, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.segments) == 0 {
+		return 0, 0, false
+	}
+	head := s.segments[0]
+	if len(s.segments) == 1 {
+		if s.offset >= head.size {
+			return 0, 0, false
+		}
+		s.closeActive()
+	}
+	return head.seq, s.offset, true
+}
+
+func (s *spoolLogWriter) deliverSegment(ctx context.Context, seq uint64, offset int64) error {
+	data, err := os.ReadFile(s.segmentPath(seq))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			// dropped while full
+			return nil
+		}
+		return err
+	}
+
+	batch := make([]json.RawMessage, 0, spoolBatchSize)
+	for offset < int64(len(data)) {
+		batch = batch[:0]
+		end := offset
+		for len(batch) < spoolBatchSize && end+spoolRecordHeaderSize <= int64(len(data)) {
+			n := int64(binary.BigEndian.Uint32(data[end : end+spoolRecordHeaderSize]))
+			if end+spoolRecordHeaderSize+n > int64(len(data)) {
+				break
+			}
+			batch = append(batch, data[end+spoolRecordHeaderSize:end+spoolRecordHeaderSize+n])
+			end += spoolRecordHeaderSize + n
+		}
+		if len(batch) == 0 {
+			// can't happen as segments are scanned on load and the records
+			// are written atomically, but don't loop forever
+			end = int64(len(data))
+		} else if err := s.next.Write(ctx, batch); err != nil {
+			return err
+		}
+		if !s.commit(ctx, seq, end, len(batch)) {
+			return nil
+		}
+		offset = end
+	}
+	return nil
+}
+
+// commit records the delivery of records up to offset in the segment, and
+// deletes the segment once it is delivered. It returns false if the segment
+// was dropped in the meantime.
+func (s *spoolLogWriter) commit(ctx context.Context, seq uint64, offset int64, records int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.segments) == 0 || s.segments[0].seq != seq {
+		return false
+	}
+	head := s.segments[0]
+	if offset >= head.size {
+		if err := s.removeHead(ctx); err != nil {
+			s.logger.ErrorContext(ctx, "failed to delete delivered spool segment", "err", err)
+		}
+		return false
+	}
+	s.offset = offset
+	s.sentRecords += records
+	spoolBacklogRecords.Add(ctx, -int64(records), spoolMetricAttrs(s.logType))
+	if err := s.writeCheckpoint(seq, offset); err != nil {
+		// the records will be delivered again after a restart
+		s.logger.ErrorContext(ctx, "failed to write spool checkpoint", "err", err)
+	}
+	return true
+}
@@ -0,0 +1,234 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// spoolTestWriter records the logs written to it, and fails while fail is
+// set.
+type spoolTestWriter struct {
+	mu     sync.Mutex
+	fail   bool
+	logs   []string
+	writes chan struct{}
+}
+
+func newSpoolTestWriter() *spoolTestWriter {
+	return &spoolTestWriter{writes: make(chan struct{}, 100)}
+}
+
+func (w *spoolTestWriter) Write(_ context.Context, logs []json.RawMessage) error {
+	w.mu.Lock()
+	defer func() {
+		w.mu.Unlock()
+		select {
+		case w.writes <- struct{}{}:
+		default:
+		}
+	}()
+	if w.fail {
+		return errors.New("destination unavailable")
+	}
+	for _, l := range logs {
+		w.logs = append(w.logs, string(l))
+	}
+	return nil
+}
+
+func (w *spoolTestWriter) setFail(fail bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.fail = fail
+}
+
+func (w *spoolTestWriter) received() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.logs...)
+}
+
+func spoolTestLogs(from, to int) []json.RawMessage {
+	var logs []json.RawMessage
+	for i := from; i < to; i++ {
+		logs = append(logs, json.RawMessage(fmt.Sprintf(`{"n":%d}`, i)))
+	}
+	return logs
+}
+
+func spoolTestStrings(logs []json.RawMessage) []string {
+	var s []string
+	for _, l := range logs {
+		s = append(s, string(l))
+	}
+	return s
+}
+
+var spoolTestConfig = SpoolConfig{
+	MinBackoff: time.Millisecond,
+	MaxBackoff: 10 * time.Millisecond,
+}
+
+func TestSpoolDelivers(t *testing.T) {
+	next := newSpoolTestWriter()
+	spool, err := NewSpoolLogWriter(t.Context(), t.TempDir(), spoolTestConfig, "result", next, slog.Default())
+	require.NoError(t, err)
+
+	require.NoError(t, spool.Write(t.Context(), spoolTestLogs(0, 3)))
+	require.NoError(t, spool.Write(t.Context(), spoolTestLogs(3, 5)))
+	require.NoError(t, spool.Write(t.Context(), nil))
+
+	expected := spoolTestStrings(spoolTestLogs(0, 5))
+	require.Eventually(t, func() bool { return len(next.received()) == len(expected) }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, expected, next.received())
+
+	// delivered segments are deleted
+	require.Eventually(t, func() bool {
+		spool.mu.Lock()
+		defer spool.mu.Unlock()
+		return len(spool.segments) == 0 && spool.size == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSpoolRetriesWithBackoff(t *testing.T) {
+	next := newSpoolTestWriter()
+	next.setFail(true)
+	spool, err := NewSpoolLogWriter(t.Context(), t.TempDir(), spoolTestConfig, "status", next, slog.Default())
+	require.NoError(t, err)
+
+	// writes succeed while the destination is down
+	require.NoError(t, spool.Write(t.Context(), spoolTestLogs(0, 2)))
+	for range 3 {
+		<-next.writes
+	}
+	require.NoError(t, spool.Write(t.Context(), spoolTestLogs(2, 4)))
+	assert.Empty(t, next.received())
+
+	next.setFail(false)
+	expected := spoolTestStrings(spoolTestLogs(0, 4))
+	require.Eventually(t, func() bool { return len(next.received()) == len(expected) }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, expected, next.received())
+}
+
+func TestSpoolSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	// the destination is down until the restart
+	ctx, cancel := context.WithCancel(t.Context())
+	down := newSpoolTestWriter()
+	down.setFail(true)
+	spool, err := NewSpoolLogWriter(ctx, dir, SpoolConfig{SegmentSize: 32, MinBackoff: time.Hour}, "result", down, slog.Default())
+	require.NoError(t, err)
+	for i := range 10 {
+		require.NoError(t, spool.Write(ctx, spoolTestLogs(i, i+1)))
+	}
+	cancel()
+
+	// simulate a crash in the middle of a write
+	spool.mu.Lock()
+	last := spool.segmentPath(spool.segments[len(spool.segments)-1].seq)
+	spool.mu.Unlock()
+	f, err := os.OpenFile(last, os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{0, 0, 0, 20, '{'})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	next := newSpoolTestWriter()
+	_, err = NewSpoolLogWriter(t.Context(), dir, spoolTestConfig, "result", next, slog.Default())
+	require.NoError(t, err)
+	expected := spoolTestStrings(spoolTestLogs(0, 10))
+	require.Eventually(t, func() bool { return len(next.received()) == len(expected) }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, expected, next.received())
+
+	require.Eventually(t, func() bool {
+		entries, err := filepath.Glob(filepath.Join(dir, "*"+spoolSegmentExt))
+		return err == nil && len(entries) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSpoolResumesFromCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(t.Context())
+	next := newSpoolTestWriter()
+	next.setFail(true)
+	spool, err := NewSpoolLogWriter(ctx, dir, SpoolConfig{MinBackoff: time.Hour}, "result", next, slog.Default())
+	require.NoError(t, err)
+	require.NoError(t, spool.Write(ctx, spoolTestLogs(0, spoolBatchSize+10)))
+	<-next.writes
+	cancel()
+
+	// deliver the first batch by hand, as if Fleet stopped right after it
+	seq, offset, ok := spool.nextSegment()
+	require.True(t, ok)
+	require.Zero(t, offset)
+	offsets, err := scanSpoolSegment(spool.segmentPath(seq))
+	require.NoError(t, err)
+	require.True(t, spool.commit(ctx, seq, offsets[spoolBatchSize-1], spoolBatchSize))
+
+	next = newSpoolTestWriter()
+	_, err = NewSpoolLogWriter(t.Context(), dir, spoolTestConfig, "result", next, slog.Default())
+	require.NoError(t, err)
+	expected := spoolTestStrings(spoolTestLogs(spoolBatchSize, spoolBatchSize+10))
+	require.Eventually(t, func() bool { return len(next.received()) == len(expected) }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, expected, next.received())
+}
+
+func TestSpoolDropsOldestWhenFull(t *testing.T) {
+	next := newSpoolTestWriter()
+	next.setFail(true)
+	// each record takes 4+7 bytes, so a segment holds 2 records and the
+	// spool 4 records
+	spool, err := NewSpoolLogWriter(t.Context(), t.TempDir(), SpoolConfig{
+		MaxSize:     44,
+		SegmentSize: 22,
+		MinBackoff:  time.Hour,
+	}, "result", next, slog.Default())
+	require.NoError(t, err)
+
+	for i := range 6 {
+		require.NoError(t, spool.Write(t.Context(), spoolTestLogs(i, i+1)))
+	}
+	spool.mu.Lock()
+	assert.Equal(t, int64(44), spool.size)
+	require.Len(t, spool.segments, 2)
+	spool.mu.Unlock()
+
+	// logs larger than the spool are dropped
+	require.NoError(t, spool.Write(t.Context(), spoolTestLogs(100, 110)))
+
+	// the spool waits for an hour before retrying, so deliver directly
+	next.setFail(false)
+	require.NoError(t, spool.deliverPending(t.Context()))
+	assert.Equal(t, spoolTestStrings(spoolTestLogs(2, 6)), next.received())
+}
+
+func TestNewJSONLoggerSpool(t *testing.T) {
+	dir := t.TempDir()
+	logFile := filepath.Join(t.TempDir(), "result.log")
+	writer, err := NewJSONLogger(t.Context(), "result", Config{
+		Plugin:     "filesystem",
+		Filesystem: FilesystemConfig{LogFile: logFile},
+		Spool:      SpoolConfig{Directory: dir},
+	}, slog.Default())
+	require.NoError(t, err)
+	require.IsType(t, &spoolLogWriter{}, writer)
+
+	require.NoError(t, writer.Write(t.Context(), logs))
+	assert.DirExists(t, filepath.Join(dir, "result"))
+	require.Eventually(t, func() bool {
+		b, err := os.ReadFile(logFile)
+		return err == nil && len(b) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,131 @@
+// Package mfatest provides a software WebAuthn authenticator to test the
+// registration and use of WebAuthn second factors without a browser.
+package mfatest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/mfa"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/stretchr/testify/require"
+)
+
+// Authenticator is a software authenticator holding a single ES256
+// credential. It answers the ceremonies as a browser running on Origin
+// would.
+type Authenticator struct {
+	// Origin is the origin reported in the client data.
+	Origin string
+	// CredentialID is the ID of the credential of the authenticator.
+	CredentialID []byte
+	// SignCount is the signature counter of the credential, incremented on
+	// every assertion.
+	SignCount uint32
+
+	t   testing.TB
+	key *ecdsa.PrivateKey
+}
+
+// NewAuthenticator creates an authenticator with a new credential.
+func NewAuthenticator(t testing.TB, origin string) *Authenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	require.NoError(t, err)
+	return &Authenticator{Origin: origin, CredentialID: id, t: t, key: key}
+}
+
+// Register answers a registration ceremony.
+func (a *Authenticator) Register(opts mfa.CreationOptions) mfa.RegistrationResponse {
+	ecdh, err := a.key.PublicKey.ECDH()
+	require.NoError(a.t, err)
+	point := ecdh.Bytes() // 0x04 | x | y
+	coseKey, err := webauthncbor.Marshal(map[int]any{
+		1:  2,  // kty: EC2
+		3:  -7, // alg: ES256
+		-1: 1,  // crv: P-256
+		-2: point[1:33],
+		-3: point[33:],
+	})
+	require.NoError(a.t, err)
+
+	authData := a.authenticatorData(opts.RelyingParty.ID, 0x01|0x04|0x40, 0)
+	authData = append(authData, make([]byte, 16)...)                                // aaguid
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(a.CredentialID))) //nolint:gosec // dismiss G115
+	authData = append(authData, a.CredentialID...)
+	authData = append(authData, coseKey...)
+
+	attestationObject, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	require.NoError(a.t, err)
+
+	return mfa.RegistrationResponse{
+		PublicKeyCredential: a.publicKeyCredential(),
+		AttestationResponse: protocol.AuthenticatorAttestationResponse{
+			AuthenticatorResponse: protocol.AuthenticatorResponse{
+				ClientDataJSON: a.clientData("webauthn.create", opts.Challenge),
+			},
+			AttestationObject: attestationObject,
+		},
+	}
+}
+
+// Assert answers an authentication ceremony.
+func (a *Authenticator) Assert(opts mfa.RequestOptions) mfa.AssertionResponse {
+	a.SignCount++
+	authData := a.authenticatorData(opts.RelyingPartyID, 0x01|0x04, a.SignCount)
+	clientData := a.clientData("webauthn.get", opts.Challenge)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(a.t, err)
+
+	return mfa.AssertionResponse{
+		PublicKeyCredential: a.publicKeyCredential(),
+		AssertionResponse: protocol.AuthenticatorAssertionResponse{
+			AuthenticatorResponse: protocol.AuthenticatorResponse{ClientDataJSON: clientData},
+			AuthenticatorData:     authData,
+			Signature:             sig,
+		},
+	}
+}
+
+func (a *Authenticator) publicKeyCredential() protocol.PublicKeyCredential {
+	return protocol.PublicKeyCredential{
+		Credential: protocol.Credential{
+			ID:   base64.RawURLEncoding.EncodeToString(a.CredentialID),
+			Type: "public-key",
+		},
+		RawID: a.CredentialID,
+	}
+}
+
+func (a *Authenticator) authenticatorData(rpID string, flags byte, signCount uint32) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(data, signCount)
+}
+
+func (a *Authenticator) clientData(ceremony string, challenge []byte) []byte {
+	b, err := json.Marshal(map[string]any{
+		"type":        ceremony,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      a.Origin,
+		"crossOrigin": false,
+	})
+	require.NoError(a.t, err)
+	return b
+}
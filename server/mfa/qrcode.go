@@ -0,0 +1,19 @@
+package mfa
+
+import (
+	"fmt"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// qrCodeSize is the width and height, in pixels, of the QR code images.
+const qrCodeSize = 256
+
+// QRCodePNG renders the content as a QR code in a PNG image.
+func QRCodePNG(content string) ([]byte, error) {
+	b, err := qrcode.Encode(content, qrcode.Medium, qrCodeSize)
+	if err != nil {
+		return nil, fmt.Errorf("encode qr code: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,24 @@
+package mfa
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestQRCodePNG(t *testing.T) {
+	uri := TOTPKeyURI("Fleet", "alice@example.com", []byte("12345678901234567890"))
+	b, err := QRCodePNG(uri)
+	require.NoError(t, err)
+
+	img, err := png.Decode(bytes.NewReader(b))
+	require.NoError(t, err)
+	require.Equal(t, qrCodeSize, img.Bounds().Dx())
+	require.Equal(t, qrCodeSize, img.Bounds().Dy())
+
+	_, err = QRCodePNG(strings.Repeat("a", 5000))
+	require.Error(t, err)
+}
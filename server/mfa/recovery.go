@@ -0,0 +1,61 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+const (
+	// RecoveryCodeCount is the number of recovery codes generated at once.
+	RecoveryCodeCount = 10
+
+	// recoveryCodeAlphabet excludes the characters that are easily confused
+	// (i, l, o and u).
+	recoveryCodeAlphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+	// recoveryCodeLength is the number of characters of a recovery code, each
+	// carrying 5 bits of entropy.
+	recoveryCodeLength = 16
+	// recoveryCodeGroupSize is the size of the dash-separated groups of
+	// characters in the formatted codes.
+	recoveryCodeGroupSize = 4
+)
+
+// GenerateRecoveryCodes returns RecoveryCodeCount new random recovery codes,
+// formatted for display (e.g. "abcd-efgh-jkmn-pqrs").
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, 0, RecoveryCodeCount)
+	for range RecoveryCodeCount {
+		b := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("generate recovery code: %w", err)
+		}
+
+		var sb strings.Builder
+		for i, c := range b {
+			if i > 0 && i%recoveryCodeGroupSize == 0 {
+				sb.WriteByte('-')
+			}
+			// the alphabet has 32 characters, so this is not biased
+			sb.WriteByte(recoveryCodeAlphabet[int(c)%len(recoveryCodeAlphabet)])
+		}
+		codes = append(codes, sb.String())
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the hash of the recovery code that is stored in
+// place of the code itself. The code is normalized first, so that it can be
+// entered without dashes or in upper case.
+func HashRecoveryCode(code string) []byte {
+	code = strings.ToLower(code)
+	code = strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+	sum := sha256.Sum256([]byte(code))
+	return sum[:]
+}
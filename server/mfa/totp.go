@@ -0,0 +1,115 @@
+// Package mfa implements the second factors that Fleet users can enroll to
+// protect their password logins: time-based one-time passwords (RFC 6238),
+// WebAuthn credentials (security keys and passkeys) and single-use recovery
+// codes.
+package mfa
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // SHA-1 is the HMAC algorithm mandated by RFC 6238 authenticator apps.
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TOTPDigits is the number of digits of the one-time passwords.
+	TOTPDigits = 6
+	// TOTPPeriod is the validity period of a one-time password.
+	TOTPPeriod = 30 * time.Second
+
+	// totpSecretSize is the size of the generated secrets, 160 bits as
+	// recommended by RFC 4226.
+	totpSecretSize = 20
+	// totpSkewSteps is the number of time steps accepted before and after the
+	// current one, to tolerate some clock drift on the user's device.
+	totpSkewSteps = 1
+)
+
+var base32NoPadding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a new random TOTP secret.
+func GenerateTOTPSecret() ([]byte, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generate TOTP secret: %w", err)
+	}
+	return secret, nil
+}
+
+// EncodeTOTPSecret returns the base32 representation of the secret that users
+// can type in their authenticator app when they can't scan the QR code.
+func EncodeTOTPSecret(secret []byte) string {
+	return base32NoPadding.EncodeToString(secret)
+}
+
+// TOTPKeyURI returns the otpauth:// URI of the secret, as understood by
+// authenticator apps. The issuer and account name are displayed by the app to
+// identify the entry.
+func TOTPKeyURI(issuer, accountName string, secret []byte) string {
+	q := url.Values{}
+	q.Set("secret", EncodeTOTPSecret(secret))
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", strconv.Itoa(TOTPDigits))
+	q.Set("period", strconv.Itoa(int(TOTPPeriod.Seconds())))
+	return "otpauth://totp/" + url.PathEscape(issuer+":"+accountName) + "?" + q.Encode()
+}
+
+// TOTPStep returns the time step that contains t.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTPPeriod.Seconds())
+}
+
+// TOTPCode returns the one-time password of the secret for the time step
+// that contains t.
+func TOTPCode(secret []byte, t time.Time) string {
+	return hotp(secret, uint64(TOTPStep(t))) //nolint:gosec // dismiss G115, steps are positive
+}
+
+// ValidateTOTP reports whether code is a valid one-time password of the
+// secret at time now and, if so, returns the time step it was generated for.
+// Steps up to and including lastUsedStep are rejected so that a code can't be
+// replayed, the caller must record the returned step once the code is
+// accepted.
+func ValidateTOTP(secret []byte, code string, now time.Time, lastUsedStep int64) (step int64, ok bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != TOTPDigits {
+		return 0, false
+	}
+
+	current := TOTPStep(now)
+	for s := current - totpSkewSteps; s <= current+totpSkewSteps; s++ {
+		if s <= lastUsedStep {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(hotp(secret, uint64(s))), []byte(code)) == 1 { //nolint:gosec // dismiss G115, steps are positive
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// hotp implements the HMAC-based one-time password algorithm of RFC 4226.
+func hotp(secret []byte, counter uint64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for range TOTPDigits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTPDigits, value%mod)
+}
@@ -0,0 +1,99 @@
+package mfa
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// test vectors of RFC 6238 (SHA-1), truncated to 6 digits
+	secret := []byte("12345678901234567890")
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.code, TOTPCode(secret, time.Unix(c.unix, 0)), c.unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	require.Len(t, secret, totpSecretSize)
+
+	now := time.Now()
+	step := TOTPStep(now)
+	code := TOTPCode(secret, now)
+
+	got, ok := ValidateTOTP(secret, code, now, 0)
+	require.True(t, ok)
+	require.Equal(t, step, got)
+
+	// spaces are ignored
+	_, ok = ValidateTOTP(secret, " "+code[:3]+" "+code[3:], now, 0)
+	require.True(t, ok)
+
+	// a code can't be replayed
+	_, ok = ValidateTOTP(secret, code, now, step)
+	require.False(t, ok)
+
+	// the previous and next codes are accepted to tolerate clock drift
+	got, ok = ValidateTOTP(secret, TOTPCode(secret, now.Add(-TOTPPeriod)), now, 0)
+	require.True(t, ok)
+	require.Equal(t, step-1, got)
+	got, ok = ValidateTOTP(secret, TOTPCode(secret, now.Add(TOTPPeriod)), now, 0)
+	require.True(t, ok)
+	require.Equal(t, step+1, got)
+
+	// but not older or later ones
+	_, ok = ValidateTOTP(secret, TOTPCode(secret, now.Add(-3*TOTPPeriod)), now, 0)
+	require.False(t, ok)
+	_, ok = ValidateTOTP(secret, TOTPCode(secret, now.Add(3*TOTPPeriod)), now, 0)
+	require.False(t, ok)
+
+	for _, invalid := range []string{"", "12345", "1234567", "abcdef"} {
+		_, ok = ValidateTOTP(secret, invalid, now, 0)
+		require.False(t, ok, invalid)
+	}
+}
+
+func TestTOTPKeyURI(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	uri := TOTPKeyURI("Acme Fleet", "alice@example.com", secret)
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/Acme%20Fleet:alice@example.com?"), uri)
+	require.Contains(t, uri, "secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	require.Contains(t, uri, "issuer=Acme+Fleet")
+	require.Contains(t, uri, "digits=6")
+	require.Contains(t, uri, "period=30")
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		require.Regexp(t, `^[0-9a-hjkmnp-tv-z]{4}(-[0-9a-hjkmnp-tv-z]{4}){3}$`, code)
+		require.False(t, seen[code])
+		seen[code] = true
+	}
+
+	code := codes[0]
+	hash := HashRecoveryCode(code)
+	require.Equal(t, hash, HashRecoveryCode(strings.ToUpper(code)))
+	require.Equal(t, hash, HashRecoveryCode(strings.ReplaceAll(code, "-", "")))
+	require.Equal(t, hash, HashRecoveryCode(strings.ReplaceAll(code, "-", " ")))
+	require.NotEqual(t, hash, HashRecoveryCode(codes[1]))
+}
@@ -0,0 +1,233 @@
+package mfa
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// This file adapts the go-webauthn relying party implementation
+// (https://github.com/go-webauthn/webauthn) to the way Fleet stores the
+// WebAuthn credentials and challenges. Fleet asks for no attestation, so any
+// authenticator is accepted.
+
+// WebAuthnTimeout is the time given to the user to complete a ceremony.
+const WebAuthnTimeout = 5 * time.Minute
+
+// ErrWebAuthnVerification is wrapped by all the errors returned when a
+// WebAuthn response can't be verified.
+var ErrWebAuthnVerification = errors.New("webauthn verification failed")
+
+func verificationError(err error) error {
+	var protoErr *protocol.Error
+	if errors.As(err, &protoErr) && protoErr.DevInfo != "" {
+		return fmt.Errorf("%w: %s: %s", ErrWebAuthnVerification, protoErr.Details, protoErr.DevInfo)
+	}
+	return fmt.Errorf("%w: %s", ErrWebAuthnVerification, err)
+}
+
+// The options and responses of the ceremonies, in the JSON format of
+// PublicKeyCredential.parseCreationOptionsFromJSON,
+// PublicKeyCredential.parseRequestOptionsFromJSON and
+// PublicKeyCredential.toJSON.
+type (
+	CreationOptions      = protocol.PublicKeyCredentialCreationOptions
+	RequestOptions       = protocol.PublicKeyCredentialRequestOptions
+	RegistrationResponse = protocol.CredentialCreationResponse
+	AssertionResponse    = protocol.CredentialAssertionResponse
+)
+
+// RelyingParty identifies Fleet to the authenticators. The credentials are
+// scoped to its ID, the host name of the Fleet server URL.
+type RelyingParty struct {
+	ID     string
+	Name   string
+	Origin string
+}
+
+// NewRelyingParty returns the relying party of the Fleet server available at
+// serverURL.
+func NewRelyingParty(name, serverURL string) (RelyingParty, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return RelyingParty{}, fmt.Errorf("parse server URL: %w", err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" {
+		return RelyingParty{}, fmt.Errorf("invalid server URL %q", serverURL)
+	}
+	return RelyingParty{
+		ID:     u.Hostname(),
+		Name:   name,
+		Origin: u.Scheme + "://" + u.Host,
+	}, nil
+}
+
+func (rp RelyingParty) webAuthn() (*webauthn.WebAuthn, error) {
+	timeout := webauthn.TimeoutConfig{Timeout: WebAuthnTimeout, TimeoutUVD: WebAuthnTimeout}
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:                  rp.ID,
+		RPDisplayName:         rp.Name,
+		RPOrigins:             []string{rp.Origin},
+		AttestationPreference: protocol.PreferNoAttestation,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementPreferred,
+			UserVerification: protocol.VerificationPreferred,
+		},
+		Timeouts: webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("webauthn relying party: %w", err)
+	}
+	return w, nil
+}
+
+// Credential is a registered WebAuthn credential.
+type Credential struct {
+	// ID is the credential ID chosen by the authenticator.
+	ID []byte
+	// PublicKey is the COSE-encoded public key of the credential.
+	PublicKey []byte
+	// SignCount is the last signature counter reported by the authenticator.
+	SignCount uint32
+	// BackupEligible is true if the credential can be synced between
+	// devices (e.g. a passkey). It can't change after the registration.
+	BackupEligible bool
+}
+
+func (c Credential) webAuthn() webauthn.Credential {
+	return webauthn.Credential{
+		ID:            c.ID,
+		PublicKey:     c.PublicKey,
+		Flags:         webauthn.CredentialFlags{BackupEligible: c.BackupEligible},
+		Authenticator: webauthn.Authenticator{SignCount: c.SignCount},
+	}
+}
+
+// User is the account for which credentials are registered or asserted.
+type User struct {
+	// Handle is the opaque identifier of the user in the credentials.
+	Handle      []byte
+	Name        string
+	DisplayName string
+	// Credentials are the registered credentials of the user.
+	Credentials []Credential
+}
+
+// webAuthnUser implements webauthn.User.
+type webAuthnUser User
+
+func (u webAuthnUser) WebAuthnID() []byte          { return u.Handle }
+func (u webAuthnUser) WebAuthnName() string        { return u.Name }
+func (u webAuthnUser) WebAuthnDisplayName() string { return u.DisplayName }
+
+func (u webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	creds := make([]webauthn.Credential, 0, len(u.Credentials))
+	for _, c := range u.Credentials {
+		creds = append(creds, c.webAuthn())
+	}
+	return creds
+}
+
+// session rebuilds the state of a ceremony started with the challenge, as
+// only the challenge is stored between the two steps.
+func (rp RelyingParty) session(user User, challenge []byte) webauthn.SessionData {
+	return webauthn.SessionData{
+		Challenge:        base64.RawURLEncoding.EncodeToString(challenge),
+		RelyingPartyID:   rp.ID,
+		UserID:           user.Handle,
+		UserVerification: protocol.VerificationPreferred,
+		CredParams:       webauthn.CredentialParametersRecommendedL3(),
+	}
+}
+
+// BeginRegistration starts the registration of a new credential for the user.
+// The existing credentials of the user are excluded so that the same
+// authenticator isn't registered twice. The challenge must be stored to
+// finish the registration.
+func (rp RelyingParty) BeginRegistration(user User) (challenge []byte, opts *CreationOptions, err error) {
+	w, err := rp.webAuthn()
+	if err != nil {
+		return nil, nil, err
+	}
+	creation, _, err := w.BeginRegistration(webAuthnUser(user),
+		webauthn.WithCredentialParameters(webauthn.CredentialParametersRecommendedL3()),
+		webauthn.WithExclusions(webauthn.Credentials(webAuthnUser(user).WebAuthnCredentials()).CredentialDescriptors()),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("begin webauthn registration: %w", err)
+	}
+	return creation.Response.Challenge, &creation.Response, nil
+}
+
+// FinishRegistration verifies the response to a registration ceremony
+// started with the challenge and returns the new credential.
+func (rp RelyingParty) FinishRegistration(user User, challenge []byte, resp RegistrationResponse) (*Credential, error) {
+	w, err := rp.webAuthn()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := resp.Parse()
+	if err != nil {
+		return nil, verificationError(err)
+	}
+	cred, err := w.CreateCredential(webAuthnUser(user), rp.session(user, challenge), parsed)
+	if err != nil {
+		return nil, verificationError(err)
+	}
+	return &Credential{
+		ID:             cred.ID,
+		PublicKey:      cred.PublicKey,
+		SignCount:      cred.Authenticator.SignCount,
+		BackupEligible: cred.Flags.BackupEligible,
+	}, nil
+}
+
+// BeginLogin starts an authentication ceremony with one of the credentials
+// of the user. The challenge must be stored to finish the login.
+func (rp RelyingParty) BeginLogin(user User) (challenge []byte, opts *RequestOptions, err error) {
+	w, err := rp.webAuthn()
+	if err != nil {
+		return nil, nil, err
+	}
+	assertion, _, err := w.BeginLogin(webAuthnUser(user))
+	if err != nil {
+		return nil, nil, fmt.Errorf("begin webauthn login: %w", err)
+	}
+	return assertion.Response.Challenge, &assertion.Response, nil
+}
+
+// FinishLogin verifies the response to an authentication ceremony started
+// with the challenge. It returns the credential that was used, with its new
+// signature counter that must be stored to detect cloned authenticators.
+func (rp RelyingParty) FinishLogin(user User, challenge []byte, resp AssertionResponse) (*Credential, error) {
+	w, err := rp.webAuthn()
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := resp.Parse()
+	if err != nil {
+		return nil, verificationError(err)
+	}
+	session := rp.session(user, challenge)
+	for _, c := range user.Credentials {
+		session.AllowedCredentialIDs = append(session.AllowedCredentialIDs, c.ID)
+	}
+	cred, err := w.ValidateLogin(webAuthnUser(user), session, parsed)
+	if err != nil {
+		return nil, verificationError(err)
+	}
+	if cred.Authenticator.CloneWarning {
+		return nil, verificationError(errors.New("signature counter did not increase, the authenticator may have been cloned"))
+	}
+	return &Credential{
+		ID:             cred.ID,
+		PublicKey:      cred.PublicKey,
+		SignCount:      cred.Authenticator.SignCount,
+		BackupEligible: cred.Flags.BackupEligible,
+	}, nil
+}
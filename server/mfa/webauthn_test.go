@@ -0,0 +1,155 @@
+package mfa_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/mfa"
+	"github.com/fleetdm/fleet/v4/server/mfa/mfatest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRelyingParty(t *testing.T) {
+	rp, err := mfa.NewRelyingParty("Fleet", "https://fleet.example.com:8443/prefix")
+	require.NoError(t, err)
+	require.Equal(t, mfa.RelyingParty{ID: "fleet.example.com", Name: "Fleet", Origin: "https://fleet.example.com:8443"}, rp)
+
+	for _, invalid := range []string{"", "fleet.example.com", "ftp://fleet.example.com", "https://"} {
+		_, err := mfa.NewRelyingParty("Fleet", invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestWebAuthnCeremonies(t *testing.T) {
+	rp, err := mfa.NewRelyingParty("Fleet", "https://fleet.example.com")
+	require.NoError(t, err)
+	authn := mfatest.NewAuthenticator(t, rp.Origin)
+	alice := mfa.User{Handle: []byte{1}, Name: "alice@example.com", DisplayName: "Alice"}
+
+	register := func(t *testing.T) mfa.User {
+		challenge, opts, err := rp.BeginRegistration(alice)
+		require.NoError(t, err)
+		cred, err := rp.FinishRegistration(alice, challenge, authn.Register(*opts))
+		require.NoError(t, err)
+		user := alice
+		user.Credentials = []mfa.Credential{*cred}
+		return user
+	}
+
+	t.Run("registration and assertion", func(t *testing.T) {
+		user := register(t)
+		cred := user.Credentials[0]
+		require.Equal(t, authn.CredentialID, cred.ID)
+		require.Zero(t, cred.SignCount)
+
+		challenge, opts, err := rp.BeginLogin(user)
+		require.NoError(t, err)
+		require.Len(t, opts.AllowedCredentials, 1)
+		require.Equal(t, "fleet.example.com", opts.RelyingPartyID)
+
+		used, err := rp.FinishLogin(user, challenge, authn.Assert(*opts))
+		require.NoError(t, err)
+		require.Equal(t, cred.ID, used.ID)
+		require.Equal(t, authn.SignCount, used.SignCount)
+
+		// the counter must increase
+		user.Credentials[0].SignCount = used.SignCount
+		authn.SignCount--
+		_, err = rp.FinishLogin(user, challenge, authn.Assert(*opts))
+		require.ErrorIs(t, err, mfa.ErrWebAuthnVerification)
+		require.ErrorContains(t, err, "cloned")
+	})
+
+	t.Run("options JSON", func(t *testing.T) {
+		user := alice
+		user.Credentials = []mfa.Credential{{ID: []byte{2}}}
+		_, opts, err := rp.BeginRegistration(user)
+		require.NoError(t, err)
+		b, err := json.Marshal(opts)
+		require.NoError(t, err)
+		require.Contains(t, string(b), `"rp":{"name":"Fleet","id":"fleet.example.com"}`)
+		require.Contains(t, string(b), `"excludeCredentials":[{"type":"public-key","id":"Ag"}]`)
+		require.Contains(t, string(b), `"attestation":"none"`)
+
+		var resp mfa.AssertionResponse
+		require.NoError(t, json.Unmarshal([]byte(`{"id":"Ag","rawId":"Ag","type":"public-key","response":{"signature":null}}`), &resp))
+		require.Equal(t, []byte{2}, []byte(resp.RawID))
+	})
+
+	t.Run("invalid registrations", func(t *testing.T) {
+		challenge, opts, err := rp.BeginRegistration(alice)
+		require.NoError(t, err)
+
+		// wrong challenge
+		otherChallenge, _, err := rp.BeginRegistration(alice)
+		require.NoError(t, err)
+		_, err = rp.FinishRegistration(alice, otherChallenge, authn.Register(*opts))
+		require.ErrorIs(t, err, mfa.ErrWebAuthnVerification)
+
+		// wrong origin
+		other := mfatest.NewAuthenticator(t, "https://evil.example.com")
+		_, err = rp.FinishRegistration(alice, challenge, other.Register(*opts))
+		require.ErrorIs(t, err, mfa.ErrWebAuthnVerification)
+
+		// wrong relying party ID
+		badOpts := *opts
+		badOpts.RelyingParty.ID = "evil.example.com"
+		_, err = rp.FinishRegistration(alice, challenge, authn.Register(badOpts))
+		require.ErrorIs(t, err, mfa.ErrWebAuthnVerification)
+
+		// assertion response to a registration ceremony
+		resp := authn.Register(*opts)
+		resp.AttestationResponse.ClientDataJSON = authn.Assert(mfa.RequestOptions{Challenge: challenge, RelyingPartyID: rp.ID}).AssertionResponse.ClientDataJSON
+		_, err = rp.FinishRegistration(alice, challenge, resp)
+		require.ErrorIs(t, err, mfa.ErrWebAuthnVerification)
+
+		// unsupported credential type
+		resp = authn.Register(*opts)
+		resp.Type = "password"
+		_, err = rp.FinishRegistration(alice, challenge, resp)
+		require.ErrorIs(t, err, mfa.ErrWebAuthnVerification)
+	})
+
+	t.Run("invalid assertions", func(t *testing.T) {
+		user := register(t)
+		challenge, opts, err := rp.BeginLogin(user)
+		require.NoError(t, err)
+
+		// wrong challenge
+		otherChallenge, _, err := rp.BeginLogin(user)
+		require.NoError(t, err)
+		_, err = rp.FinishLogin(user, otherChallenge, authn.Assert(*opts))
+		require.ErrorIs(t, err, mfa.ErrWebAuthnVerification)
+
+		// tampered signature
+		resp := authn.Assert(*opts)
+		resp.AssertionResponse.Signature[len(resp.AssertionResponse.Signature)-1] ^= 0xff
+		_, err = rp.FinishLogin(user, challenge, resp)
+		require.ErrorIs(t, err, mfa.ErrWebAuthnVerification)
+
+		// tampered authenticator data: user not present
+		resp = authn.Assert(*opts)
+		resp.AssertionResponse.AuthenticatorData[32] &^= 0x01
+		_, err = rp.FinishLogin(user, challenge, resp)
+		require.ErrorIs(t, err, mfa.ErrWebAuthnVerification)
+
+		// signature from another credential
+		other := mfatest.NewAuthenticator(t, rp.Origin)
+		other.CredentialID = authn.CredentialID
+		_, err = rp.FinishLogin(user, challenge, other.Assert(*opts))
+		require.ErrorIs(t, err, mfa.ErrWebAuthnVerification)
+
+		// unknown credential
+		other = mfatest.NewAuthenticator(t, rp.Origin)
+		_, err = rp.FinishLogin(user, challenge, other.Assert(*opts))
+		require.ErrorIs(t, err, mfa.ErrWebAuthnVerification)
+
+		// relying party ID of another site
+		rpIDHash := sha256.Sum256([]byte("evil.example.com"))
+		resp = authn.Assert(*opts)
+		copy(resp.AssertionResponse.AuthenticatorData, rpIDHash[:])
+		_, err = rp.FinishLogin(user, challenge, resp)
+		require.ErrorIs(t, err, mfa.ErrWebAuthnVerification)
+	})
+}
@@ -1,5 +1,6 @@
 // Package msgraph is Fleet's client for Microsoft Graph. It authenticates as an Entra app registration using the
 // OAuth2 client-credentials grant and reads Windows Autopilot device identities, which Fleet surfaces as pending hosts.
+// Other Graph consumers, such as the Microsoft 365 calendar integration, reuse its authenticated HTTP client.
 package msgraph
 
 import (
@@ -23,8 +24,8 @@ import (
 const (
 	// defaultLoginHost is Entra's token endpoint host. Overridable in tests.
 	defaultLoginHost = "https://login.microsoftonline.com"
-	// defaultGraphHost is the Microsoft Graph host. Overridable in tests.
-	defaultGraphHost = "https://graph.microsoft.com"
+	// DefaultGraphHost is the Microsoft Graph host. Overridable in tests.
+	DefaultGraphHost = "https://graph.microsoft.com"
 
 	// graphScope is the only scope value Entra accepts for the client-credentials flow. This is not a broad grant.
 	// App-only permissions are assigned to the app registration and admin-consented up front, so there is no incremental
@@ -85,7 +86,7 @@ type client struct {
 
 // NewClient builds a Graph client for the given credential. The returned client refreshes its own access token.
 func NewClient(cred *fleet.MicrosoftGraphCredential) (Client, error) {
-	return newClientWithHosts(cred, defaultLoginHost, defaultGraphHost)
+	return newClientWithHosts(cred, defaultLoginHost, DefaultGraphHost)
 }
 
 func newClientWithHosts(cred *fleet.MicrosoftGraphCredential, loginHost, graphHost string) (Client, error) {
@@ -224,36 +225,61 @@ func (c *client) getPage(ctx context.Context, httpClient *http.Client, requestUR
 	}
 	req.Header.Set("Accept", "application/json")
 
+	body, err := Do(ctx, httpClient, req)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var parsed autopilotDevicesResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, "", ctxerr.Wrap(ctx, err, "decode microsoft graph response")
+	}
+	return parsed.Value, parsed.NextLink, nil
+}
+
+// NewHTTPClient returns an HTTP client that authenticates its requests with an app-only token minted for the credential,
+// refreshing it as needed. It lets the other Graph consumers (e.g. the Microsoft 365 calendar integration) share the
+// credential plumbing of the Autopilot client. An empty loginHost means Entra's token endpoint; token acquisition
+// inherits ctx.
+func NewHTTPClient(ctx context.Context, cred *fleet.MicrosoftGraphCredential, loginHost string) (*http.Client, error) {
+	if loginHost == "" {
+		loginHost = defaultLoginHost
+	}
+	c, err := newClientWithHosts(cred, loginHost, DefaultGraphHost)
+	if err != nil {
+		return nil, err
+	}
+	return c.(*client).httpClientFor(ctx), nil
+}
+
+// Do sends a Graph request with a client returned by NewHTTPClient and returns the body of the successful (2xx)
+// response. Token-endpoint and Graph failures are both returned as an *Error, so callers classify them with AsError.
+func Do(ctx context.Context, httpClient *http.Client, req *http.Request) ([]byte, error) {
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		// The oauth2 transport fetches the access token lazily on the first request, so a rejected client secret
 		// surfaces here as a token-endpoint failure rather than as a Graph response.
 		if retrieveErr, ok := errors.AsType[*oauth2.RetrieveError](err); ok {
-			return nil, "", ctxerr.Wrap(ctx, newTokenError(retrieveErr), "acquire microsoft graph token")
+			return nil, ctxerr.Wrap(ctx, newTokenError(retrieveErr), "acquire microsoft graph token")
 		}
-		return nil, "", ctxerr.Wrap(ctx, err, "call microsoft graph")
+		return nil, ctxerr.Wrap(ctx, err, "call microsoft graph")
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
 		// Bound the read rather than truncating afterwards: an edge proxy can answer a 5xx with a very large HTML page,
 		// and reading it in full to then keep 512 bytes would allocate the whole thing. One byte over the limit is read
 		// so truncateBody can still mark the message as truncated.
 		body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes+1))
 		if err != nil {
-			return nil, "", ctxerr.Wrap(ctx, err, "read microsoft graph error response")
+			return nil, ctxerr.Wrap(ctx, err, "read microsoft graph error response")
 		}
-		return nil, "", ctxerr.Wrap(ctx, newGraphError(resp, body), "microsoft graph request failed")
+		return nil, ctxerr.Wrap(ctx, newGraphError(resp, body), "microsoft graph request failed")
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, "", ctxerr.Wrap(ctx, err, "read microsoft graph response")
-	}
-
-	var parsed autopilotDevicesResponse
-	if err := json.Unmarshal(body, &parsed); err != nil {
-		return nil, "", ctxerr.Wrap(ctx, err, "decode microsoft graph response")
+		return nil, ctxerr.Wrap(ctx, err, "read microsoft graph response")
 	}
-	return parsed.Value, parsed.NextLink, nil
+	return body, nil
 }
@@ -92,6 +92,8 @@ type DeleteUserIfNotLastAdminFunc func(ctx context.Context, id uint) error
 
 type SaveUserIfNotLastAdminFunc func(ctx context.Context, user *fleet.User) error
 
+type DisableUserFunc func(ctx context.Context, id uint) error
+
 type PendingEmailChangeFunc func(ctx context.Context, userID uint, newEmail string, token string) error
 
 type ConfirmPendingEmailChangeFunc func(ctx context.Context, userID uint, token string) (string, error)
@@ -146,6 +148,20 @@ type CleanupDistributedQueryCampaignsFunc func(ctx context.Context, now time.Tim
 
 type CleanupCompletedCampaignTargetsFunc func(ctx context.Context, olderThan time.Time) (deleted uint, err error)
 
+type CompleteExpiredDeferredQueryCampaignsFunc func(ctx context.Context, now time.Time) (completed uint, err error)
+
+type SaveCampaignQueryResultFunc func(ctx context.Context, campaignID uint, result *fleet.CampaignQueryResult) error
+
+type ListCampaignQueryResultsFunc func(ctx context.Context, campaignID uint, opts fleet.ListOptions) ([]*fleet.CampaignQueryResult, *fleet.PaginationMetadata, error)
+
+type CountCampaignQueryResultsFunc func(ctx context.Context, campaignID uint) (uint, error)
+
+type ListQueryCampaignsFunc func(ctx context.Context, opts fleet.ListQueryCampaignsOptions) ([]*fleet.QueryCampaignSummary, *fleet.PaginationMetadata, error)
+
+type CleanupExpiredQueryCampaignsFunc func(ctx context.Context, olderThan time.Time) (deleted uint, err error)
+
+type CleanupUnusedCampaignResultsDataFunc func(ctx context.Context, resultsStore fleet.CampaignResultsStore, removeCreatedBefore time.Time) error
+
 type GetCompletedCampaignsFunc func(ctx context.Context, filter []uint) ([]uint, error)
 
 type DistributedQueryCampaignsForQueryFunc func(ctx context.Context, queryID uint) ([]*fleet.DistributedQueryCampaign, error)
@@ -430,6 +446,40 @@ type SessionByMFATokenFunc func(ctx context.Context, token string, sessionKeySiz
 
 type NewMFATokenFunc func(ctx context.Context, userID uint) (string, error)
 
+type SetUserPendingTOTPSecretFunc func(ctx context.Context, userID uint, secret []byte) error
+
+type UserTOTPFunc func(ctx context.Context, userID uint) (*fleet.UserTOTP, error)
+
+type ConfirmUserTOTPFunc func(ctx context.Context, userID uint, step int64) error
+
+type UseUserTOTPStepFunc func(ctx context.Context, userID uint, step int64) (bool, error)
+
+type DeleteUserTOTPFunc func(ctx context.Context, userID uint) error
+
+type ReplaceUserMFARecoveryCodesFunc func(ctx context.Context, userID uint, codeHashes [][]byte) error
+
+type CountUserMFARecoveryCodesFunc func(ctx context.Context, userID uint) (int, error)
+
+type ConsumeUserMFARecoveryCodeFunc func(ctx context.Context, userID uint, codeHash []byte) (bool, error)
+
+type NewUserWebAuthnCredentialFunc func(ctx context.Context, cred *fleet.WebAuthnCredential) (*fleet.WebAuthnCredential, error)
+
+type ListUserWebAuthnCredentialsFunc func(ctx context.Context, userID uint) ([]*fleet.WebAuthnCredential, error)
+
+type UpdateUserWebAuthnCredentialSignCountFunc func(ctx context.Context, id uint, signCount uint32) error
+
+type DeleteUserWebAuthnCredentialFunc func(ctx context.Context, userID uint, id uint) error
+
+type ResetUserMFAFunc func(ctx context.Context, userID uint) error
+
+type NewUserMFAChallengeFunc func(ctx context.Context, userID uint, purpose string, webAuthnChallenge []byte) (*fleet.UserMFAChallenge, error)
+
+type UserMFAChallengeFunc func(ctx context.Context, token string) (*fleet.UserMFAChallenge, error)
+
+type RecordUserMFAChallengeFailureFunc func(ctx context.Context, token string) error
+
+type ConsumeUserMFAChallengeFunc func(ctx context.Context, token string) error
+
 type NewAppConfigFunc func(ctx context.Context, info *fleet.AppConfig) (*fleet.AppConfig, error)
 
 type SaveAppConfigFunc func(ctx context.Context, info *fleet.AppConfig) error
@@ -488,6 +538,14 @@ type CleanupDiscardedQueryResultsFunc func(ctx context.Context) error
 
 type CleanupExcessQueryResultRowsFunc func(ctx context.Context, maxQueryReportRows int, opts ...fleet.CleanupExcessQueryResultRowsOptions) (map[uint]int, error)
 
+type InsertQueryResultHistoryFunc func(ctx context.Context, batches []*fleet.QueryResultHistoryBatch) error
+
+type QueryResultHistoryRowsFunc func(ctx context.Context, queryID uint, from time.Time, to time.Time, filter fleet.TeamFilter) ([]*fleet.ScheduledQueryResultRow, error)
+
+type QueryResultHistorySnapshotFunc func(ctx context.Context, queryID uint, at time.Time, filter fleet.TeamFilter) ([]*fleet.ScheduledQueryResultRow, error)
+
+type CleanupQueryResultHistoryFunc func(ctx context.Context, olderThan time.Time, maxRowsPerQuery int) error
+
 type ListHostReportsFunc func(ctx context.Context, hostID uint, teamID *uint, hostPlatform string, opts fleet.ListHostReportsOptions, maxQueryReportRows int) ([]*fleet.HostReport, int, *fleet.PaginationMetadata, error)
 
 type NewTeamFunc func(ctx context.Context, team *fleet.Team) (*fleet.Team, error)
@@ -788,6 +846,12 @@ type ListCalendarEventsFunc func(ctx context.Context, teamID *uint) ([]*fleet.Ca
 
 type ListOutOfDateCalendarEventsFunc func(ctx context.Context, t time.Time) ([]*fleet.CalendarEvent, error)
 
+type RecordServiceNowPolicyIncidentFunc func(ctx context.Context, policyID uint) error
+
+type DeleteServiceNowPolicyIncidentFunc func(ctx context.Context, policyID uint) error
+
+type ListServiceNowPolicyIncidentsFunc func(ctx context.Context) ([]uint, error)
+
 type NewTeamPolicyFunc func(ctx context.Context, teamID uint, authorID *uint, args fleet.PolicyPayload) (*fleet.Policy, error)
 
 type ListTeamPoliciesFunc func(ctx context.Context, teamID uint, opts fleet.ListOptions, iopts fleet.ListOptions, automationType fleet.PolicyAutomationType, platform string) (teamPolicies []*fleet.Policy, inheritedPolicies []*fleet.Policy, err error)
@@ -1976,6 +2040,80 @@ type ExpandCustomHostVitalsFunc func(ctx context.Context, hostID uint, document
 
 type UpsertCustomHostVitalsFunc func(ctx context.Context, vitals []fleet.CustomHostVital) (created []fleet.CustomHostVital, deleted []fleet.CustomHostVital, err error)
 
+type NewCustomRoleFunc func(ctx context.Context, role *fleet.CustomRole) (*fleet.CustomRole, error)
+
+type CustomRoleFunc func(ctx context.Context, id uint) (*fleet.CustomRole, error)
+
+type ListCustomRolesFunc func(ctx context.Context) ([]fleet.CustomRole, error)
+
+type SaveCustomRoleFunc func(ctx context.Context, role *fleet.CustomRole) (*fleet.CustomRole, error)
+
+type DeleteCustomRoleFunc func(ctx context.Context, id uint) error
+
+type SetUserCustomRolesFunc func(ctx context.Context, userID uint, roles []fleet.UserCustomRole) error
+
+type ApplyCustomRolesFunc func(ctx context.Context, specs []fleet.CustomRoleSpec) (created []fleet.CustomRole, edited []fleet.CustomRole, deleted []fleet.CustomRole, err error)
+
+type NewVEXDocumentFunc func(ctx context.Context, doc *fleet.VEXDocument) (*fleet.VEXDocument, error)
+
+type VEXDocumentFunc func(ctx context.Context, id uint) (*fleet.VEXDocument, error)
+
+type ListVEXDocumentsFunc func(ctx context.Context) ([]*fleet.VEXDocument, error)
+
+type DeleteVEXDocumentFunc func(ctx context.Context, id uint) error
+
+type ListVEXStatementsFunc func(ctx context.Context, statuses []fleet.VEXStatus) ([]fleet.VEXStatement, error)
+
+type ListVEXCandidatesFunc func(ctx context.Context, cves []string) ([]fleet.VEXCandidate, error)
+
+type ReplaceVEXSuppressionsFunc func(ctx context.Context, suppressions []fleet.VEXSuppression) error
+
+type ListVEXSuppressionsFunc func(ctx context.Context, opts fleet.VEXSuppressionListOptions) ([]fleet.VEXSuppression, *fleet.PaginationMetadata, error)
+
+type NewVulnerabilityExceptionFunc func(ctx context.Context, e *fleet.VulnerabilityException) (*fleet.VulnerabilityException, error)
+
+type VulnerabilityExceptionFunc func(ctx context.Context, id uint) (*fleet.VulnerabilityException, error)
+
+type ListVulnerabilityExceptionsFunc func(ctx context.Context, opts fleet.VulnerabilityExceptionListOptions) ([]fleet.VulnerabilityException, *fleet.PaginationMetadata, error)
+
+type SaveVulnerabilityExceptionFunc func(ctx context.Context, e *fleet.VulnerabilityException) (*fleet.VulnerabilityException, error)
+
+type DeleteVulnerabilityExceptionFunc func(ctx context.Context, id uint) error
+
+type ApplyVulnerabilityExceptionsFunc func(ctx context.Context, exceptions []fleet.VulnerabilityException) (created []fleet.VulnerabilityException, edited []fleet.VulnerabilityException, deleted []fleet.VulnerabilityException, err error)
+
+type ExpireVulnerabilityExceptionsFunc func(ctx context.Context, now time.Time) ([]fleet.VulnerabilityException, error)
+
+type FilterExceptedSoftwareVulnerabilitiesFunc func(ctx context.Context, vulns []fleet.SoftwareVulnerability) ([]fleet.SoftwareVulnerability, error)
+
+type VulnerabilityExceptedHostIDsFunc func(ctx context.Context, cve string, softwareIDs []uint) ([]uint, error)
+
+type NewWebhookDeliveryFunc func(ctx context.Context, d *fleet.WebhookDelivery) (*fleet.WebhookDelivery, error)
+
+type UpdateWebhookDeliveryFunc func(ctx context.Context, d *fleet.WebhookDelivery) error
+
+type WebhookDeliveryFunc func(ctx context.Context, id uint) (*fleet.WebhookDelivery, error)
+
+type ListWebhookDeliveriesFunc func(ctx context.Context, opts fleet.WebhookDeliveryListOptions) ([]fleet.WebhookDelivery, *fleet.PaginationMetadata, error)
+
+type CleanupWebhookDeliveriesFunc func(ctx context.Context, olderThan time.Time) (int64, error)
+
+type SaveGitOpsSnapshotFunc func(ctx context.Context, snapshot *fleet.GitOpsSnapshot) error
+
+type GitOpsSnapshotFunc func(ctx context.Context, teamID uint) (*fleet.GitOpsSnapshot, error)
+
+type ListGitOpsSnapshotsFunc func(ctx context.Context) ([]*fleet.GitOpsSnapshot, error)
+
+type UpdateGitOpsSnapshotDriftFunc func(ctx context.Context, teamID uint, drift []fleet.GitOpsResourceChange, driftChecksum string, checkedAt time.Time) error
+
+type DeleteGitOpsSnapshotFunc func(ctx context.Context, teamID uint) error
+
+type UpsertHostCacheProxyStatsFunc func(ctx context.Context, hostID uint, stats fleet.CacheProxyStats) error
+
+type ListHostCacheProxiesFunc func(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.HostCacheProxy, error)
+
+type GetCacheProxySoftwareInstallerFunc func(ctx context.Context, hostID uint, hashSHA256 string) (*fleet.SoftwareInstaller, error)
+
 type CreateEnterpriseFunc func(ctx context.Context, userID uint) (uint, error)
 
 type GetEnterpriseByIDFunc func(ctx context.Context, id uint) (*android.EnterpriseDetails, error)
@@ -2160,6 +2298,8 @@ type ReplaceScimGroupFunc func(ctx context.Context, group *fleet.ScimGroup) erro
 
 type DeleteScimGroupFunc func(ctx context.Context, id uint) error
 
+type ScimGroupMemberUserIDsFunc func(ctx context.Context, groupID uint) ([]uint, error)
+
 type ListScimGroupsFunc func(ctx context.Context, opts fleet.ScimGroupsListOptions) (groups []fleet.ScimGroup, totalResults uint, err error)
 
 type ScimLastRequestFunc func(ctx context.Context) (*fleet.ScimLastRequest, error)
@@ -2461,6 +2601,9 @@ type DataStore struct {
 	SaveUserIfNotLastAdminFunc        SaveUserIfNotLastAdminFunc
 	SaveUserIfNotLastAdminFuncInvoked bool
 
+	DisableUserFunc        DisableUserFunc
+	DisableUserFuncInvoked bool
+
 	PendingEmailChangeFunc        PendingEmailChangeFunc
 	PendingEmailChangeFuncInvoked bool
 
@@ -2542,6 +2685,27 @@ type DataStore struct {
 	CleanupCompletedCampaignTargetsFunc        CleanupCompletedCampaignTargetsFunc
 	CleanupCompletedCampaignTargetsFuncInvoked bool
 
+	CompleteExpiredDeferredQueryCampaignsFunc        CompleteExpiredDeferredQueryCampaignsFunc
+	CompleteExpiredDeferredQueryCampaignsFuncInvoked bool
+
+	SaveCampaignQueryResultFunc        SaveCampaignQueryResultFunc
+	SaveCampaignQueryResultFuncInvoked bool
+
+	ListCampaignQueryResultsFunc        ListCampaignQueryResultsFunc
+	ListCampaignQueryResultsFuncInvoked bool
+
+	CountCampaignQueryResultsFunc        CountCampaignQueryResultsFunc
+	CountCampaignQueryResultsFuncInvoked bool
+
+	ListQueryCampaignsFunc        ListQueryCampaignsFunc
+	ListQueryCampaignsFuncInvoked bool
+
+	CleanupExpiredQueryCampaignsFunc        CleanupExpiredQueryCampaignsFunc
+	CleanupExpiredQueryCampaignsFuncInvoked bool
+
+	CleanupUnusedCampaignResultsDataFunc        CleanupUnusedCampaignResultsDataFunc
+	CleanupUnusedCampaignResultsDataFuncInvoked bool
+
 	GetCompletedCampaignsFunc        GetCompletedCampaignsFunc
 	GetCompletedCampaignsFuncInvoked bool
 
@@ -2968,6 +3132,57 @@ type DataStore struct {
 	NewMFATokenFunc        NewMFATokenFunc
 	NewMFATokenFuncInvoked bool
 
+	SetUserPendingTOTPSecretFunc        SetUserPendingTOTPSecretFunc
+	SetUserPendingTOTPSecretFuncInvoked bool
+
+	UserTOTPFunc        UserTOTPFunc
+	UserTOTPFuncInvoked bool
+
+	ConfirmUserTOTPFunc        ConfirmUserTOTPFunc
+	ConfirmUserTOTPFuncInvoked bool
+
+	UseUserTOTPStepFunc        UseUserTOTPStepFunc
+	UseUserTOTPStepFuncInvoked bool
+
+	DeleteUserTOTPFunc        DeleteUserTOTPFunc
+	DeleteUserTOTPFuncInvoked bool
+
+	ReplaceUserMFARecoveryCodesFunc        ReplaceUserMFARecoveryCodesFunc
+	ReplaceUserMFARecoveryCodesFuncInvoked bool
+
+	CountUserMFARecoveryCodesFunc        CountUserMFARecoveryCodesFunc
+	CountUserMFARecoveryCodesFuncInvoked bool
+
+	ConsumeUserMFARecoveryCodeFunc        ConsumeUserMFARecoveryCodeFunc
+	ConsumeUserMFARecoveryCodeFuncInvoked bool
+
+	NewUserWebAuthnCredentialFunc        NewUserWebAuthnCredentialFunc
+	NewUserWebAuthnCredentialFuncInvoked bool
+
+	ListUserWebAuthnCredentialsFunc        ListUserWebAuthnCredentialsFunc
+	ListUserWebAuthnCredentialsFuncInvoked bool
+
+	UpdateUserWebAuthnCredentialSignCountFunc        UpdateUserWebAuthnCredentialSignCountFunc
+	UpdateUserWebAuthnCredentialSignCountFuncInvoked bool
+
+	DeleteUserWebAuthnCredentialFunc        DeleteUserWebAuthnCredentialFunc
+	DeleteUserWebAuthnCredentialFuncInvoked bool
+
+	ResetUserMFAFunc        ResetUserMFAFunc
+	ResetUserMFAFuncInvoked bool
+
+	NewUserMFAChallengeFunc        NewUserMFAChallengeFunc
+	NewUserMFAChallengeFuncInvoked bool
+
+	UserMFAChallengeFunc        UserMFAChallengeFunc
+	UserMFAChallengeFuncInvoked bool
+
+	RecordUserMFAChallengeFailureFunc        RecordUserMFAChallengeFailureFunc
+	RecordUserMFAChallengeFailureFuncInvoked bool
+
+	ConsumeUserMFAChallengeFunc        ConsumeUserMFAChallengeFunc
+	ConsumeUserMFAChallengeFuncInvoked bool
+
 	NewAppConfigFunc        NewAppConfigFunc
 	NewAppConfigFuncInvoked bool
 
@@ -3055,6 +3270,18 @@ type DataStore struct {
 	CleanupExcessQueryResultRowsFunc        CleanupExcessQueryResultRowsFunc
 	CleanupExcessQueryResultRowsFuncInvoked bool
 
+	InsertQueryResultHistoryFunc        InsertQueryResultHistoryFunc
+	InsertQueryResultHistoryFuncInvoked bool
+
+	QueryResultHistoryRowsFunc        QueryResultHistoryRowsFunc
+	QueryResultHistoryRowsFuncInvoked bool
+
+	QueryResultHistorySnapshotFunc        QueryResultHistorySnapshotFunc
+	QueryResultHistorySnapshotFuncInvoked bool
+
+	CleanupQueryResultHistoryFunc        CleanupQueryResultHistoryFunc
+	CleanupQueryResultHistoryFuncInvoked bool
+
 	ListHostReportsFunc        ListHostReportsFunc
 	ListHostReportsFuncInvoked bool
 
@@ -3505,6 +3732,15 @@ type DataStore struct {
 	ListOutOfDateCalendarEventsFunc        ListOutOfDateCalendarEventsFunc
 	ListOutOfDateCalendarEventsFuncInvoked bool
 
+	RecordServiceNowPolicyIncidentFunc        RecordServiceNowPolicyIncidentFunc
+	RecordServiceNowPolicyIncidentFuncInvoked bool
+
+	DeleteServiceNowPolicyIncidentFunc        DeleteServiceNowPolicyIncidentFunc
+	DeleteServiceNowPolicyIncidentFuncInvoked bool
+
+	ListServiceNowPolicyIncidentsFunc        ListServiceNowPolicyIncidentsFunc
+	ListServiceNowPolicyIncidentsFuncInvoked bool
+
 	NewTeamPolicyFunc        NewTeamPolicyFunc
 	NewTeamPolicyFuncInvoked bool
 
@@ -5287,6 +5523,117 @@ type DataStore struct {
 	UpsertCustomHostVitalsFunc        UpsertCustomHostVitalsFunc
 	UpsertCustomHostVitalsFuncInvoked bool
 
+	NewCustomRoleFunc        NewCustomRoleFunc
+	NewCustomRoleFuncInvoked bool
+
+	CustomRoleFunc        CustomRoleFunc
+	CustomRoleFuncInvoked bool
+
+	ListCustomRolesFunc        ListCustomRolesFunc
+	ListCustomRolesFuncInvoked bool
+
+	SaveCustomRoleFunc        SaveCustomRoleFunc
+	SaveCustomRoleFuncInvoked bool
+
+	DeleteCustomRoleFunc        DeleteCustomRoleFunc
+	DeleteCustomRoleFuncInvoked bool
+
+	SetUserCustomRolesFunc        SetUserCustomRolesFunc
+	SetUserCustomRolesFuncInvoked bool
+
+	ApplyCustomRolesFunc        ApplyCustomRolesFunc
+	ApplyCustomRolesFuncInvoked bool
+
+	NewVEXDocumentFunc        NewVEXDocumentFunc
+	NewVEXDocumentFuncInvoked bool
+
+	VEXDocumentFunc        VEXDocumentFunc
+	VEXDocumentFuncInvoked bool
+
+	ListVEXDocumentsFunc        ListVEXDocumentsFunc
+	ListVEXDocumentsFuncInvoked bool
+
+	DeleteVEXDocumentFunc        DeleteVEXDocumentFunc
+	DeleteVEXDocumentFuncInvoked bool
+
+	ListVEXStatementsFunc        ListVEXStatementsFunc
+	ListVEXStatementsFuncInvoked bool
+
+	ListVEXCandidatesFunc        ListVEXCandidatesFunc
+	ListVEXCandidatesFuncInvoked bool
+
+	ReplaceVEXSuppressionsFunc        ReplaceVEXSuppressionsFunc
+	ReplaceVEXSuppressionsFuncInvoked bool
+
+	ListVEXSuppressionsFunc        ListVEXSuppressionsFunc
+	ListVEXSuppressionsFuncInvoked bool
+
+	NewVulnerabilityExceptionFunc        NewVulnerabilityExceptionFunc
+	NewVulnerabilityExceptionFuncInvoked bool
+
+	VulnerabilityExceptionFunc        VulnerabilityExceptionFunc
+	VulnerabilityExceptionFuncInvoked bool
+
+	ListVulnerabilityExceptionsFunc        ListVulnerabilityExceptionsFunc
+	ListVulnerabilityExceptionsFuncInvoked bool
+
+	SaveVulnerabilityExceptionFunc        SaveVulnerabilityExceptionFunc
+	SaveVulnerabilityExceptionFuncInvoked bool
+
+	DeleteVulnerabilityExceptionFunc        DeleteVulnerabilityExceptionFunc
+	DeleteVulnerabilityExceptionFuncInvoked bool
+
+	ApplyVulnerabilityExceptionsFunc        ApplyVulnerabilityExceptionsFunc
+	ApplyVulnerabilityExceptionsFuncInvoked bool
+
+	ExpireVulnerabilityExceptionsFunc        ExpireVulnerabilityExceptionsFunc
+	ExpireVulnerabilityExceptionsFuncInvoked bool
+
+	FilterExceptedSoftwareVulnerabilitiesFunc        FilterExceptedSoftwareVulnerabilitiesFunc
+	FilterExceptedSoftwareVulnerabilitiesFuncInvoked bool
+
+	VulnerabilityExceptedHostIDsFunc        VulnerabilityExceptedHostIDsFunc
+	VulnerabilityExceptedHostIDsFuncInvoked bool
+
+	NewWebhookDeliveryFunc        NewWebhookDeliveryFunc
+	NewWebhookDeliveryFuncInvoked bool
+
+	UpdateWebhookDeliveryFunc        UpdateWebhookDeliveryFunc
+	UpdateWebhookDeliveryFuncInvoked bool
+
+	WebhookDeliveryFunc        WebhookDeliveryFunc
+	WebhookDeliveryFuncInvoked bool
+
+	ListWebhookDeliveriesFunc        ListWebhookDeliveriesFunc
+	ListWebhookDeliveriesFuncInvoked bool
+
+	CleanupWebhookDeliveriesFunc        CleanupWebhookDeliveriesFunc
+	CleanupWebhookDeliveriesFuncInvoked bool
+
+	SaveGitOpsSnapshotFunc        SaveGitOpsSnapshotFunc
+	SaveGitOpsSnapshotFuncInvoked bool
+
+	GitOpsSnapshotFunc        GitOpsSnapshotFunc
+	GitOpsSnapshotFuncInvoked bool
+
+	ListGitOpsSnapshotsFunc        ListGitOpsSnapshotsFunc
+	ListGitOpsSnapshotsFuncInvoked bool
+
+	UpdateGitOpsSnapshotDriftFunc        UpdateGitOpsSnapshotDriftFunc
+	UpdateGitOpsSnapshotDriftFuncInvoked bool
+
+	DeleteGitOpsSnapshotFunc        DeleteGitOpsSnapshotFunc
+	DeleteGitOpsSnapshotFuncInvoked bool
+
+	UpsertHostCacheProxyStatsFunc        UpsertHostCacheProxyStatsFunc
+	UpsertHostCacheProxyStatsFuncInvoked bool
+
+	ListHostCacheProxiesFunc        ListHostCacheProxiesFunc
+	ListHostCacheProxiesFuncInvoked bool
+
+	GetCacheProxySoftwareInstallerFunc        GetCacheProxySoftwareInstallerFunc
+	GetCacheProxySoftwareInstallerFuncInvoked bool
+
 	CreateEnterpriseFunc        CreateEnterpriseFunc
 	CreateEnterpriseFuncInvoked bool
 
@@ -5563,6 +5910,9 @@ type DataStore struct {
 	DeleteScimGroupFunc        DeleteScimGroupFunc
 	DeleteScimGroupFuncInvoked bool
 
+	ScimGroupMemberUserIDsFunc        ScimGroupMemberUserIDsFunc
+	ScimGroupMemberUserIDsFuncInvoked bool
+
 	ListScimGroupsFunc        ListScimGroupsFunc
 	ListScimGroupsFuncInvoked bool
 
@@ -6101,6 +6451,13 @@ func (s *DataStore) SaveUserIfNotLastAdmin(ctx context.Context, user *fleet.User
 	return s.SaveUserIfNotLastAdminFunc(ctx, user)
 }
 
+func (s *DataStore) DisableUser(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	s.DisableUserFuncInvoked = true
+	s.mu.Unlock()
+	return s.DisableUserFunc(ctx, id)
+}
+
 func (s *DataStore) PendingEmailChange(ctx context.Context, userID uint, newEmail string, token string) error {
 	s.mu.Lock()
 	s.PendingEmailChangeFuncInvoked = true
@@ -6290,6 +6647,55 @@ func (s *DataStore) CleanupCompletedCampaignTargets(ctx context.Context, olderTh
 	return s.CleanupCompletedCampaignTargetsFunc(ctx, olderThan)
 }
 
+func (s *DataStore) CompleteExpiredDeferredQueryCampaigns(ctx context.Context, now time.Time) (completed uint, err error) {
+	s.mu.Lock()
+	s.CompleteExpiredDeferredQueryCampaignsFuncInvoked = true
+	s.mu.Unlock()
+	return s.CompleteExpiredDeferredQueryCampaignsFunc(ctx, now)
+}
+
+func (s *DataStore) SaveCampaignQueryResult(ctx context.Context, campaignID uint, result *fleet.CampaignQueryResult) error {
+	s.mu.Lock()
+	s.SaveCampaignQueryResultFuncInvoked = true
+	s.mu.Unlock()
+	return s.SaveCampaignQueryResultFunc(ctx, campaignID, result)
+}
+
+func (s *DataStore) ListCampaignQueryResults(ctx context.Context, campaignID uint, opts fleet.ListOptions) ([]*fleet.CampaignQueryResult, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListCampaignQueryResultsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListCampaignQueryResultsFunc(ctx, campaignID, opts)
+}
+
+func (s *DataStore) CountCampaignQueryResults(ctx context.Context, campaignID uint) (uint, error) {
+	s.mu.Lock()
+	s.CountCampaignQueryResultsFuncInvoked = true
+	s.mu.Unlock()
+	return s.CountCampaignQueryResultsFunc(ctx, campaignID)
+}
+
+func (s *DataStore) ListQueryCampaigns(ctx context.Context, opts fleet.ListQueryCampaignsOptions) ([]*fleet.QueryCampaignSummary, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListQueryCampaignsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListQueryCampaignsFunc(ctx, opts)
+}
+
+func (s *DataStore) CleanupExpiredQueryCampaigns(ctx context.Context, olderThan time.Time) (deleted uint, err error) {
+	s.mu.Lock()
+	s.CleanupExpiredQueryCampaignsFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupExpiredQueryCampaignsFunc(ctx, olderThan)
+}
+
+func (s *DataStore) CleanupUnusedCampaignResultsData(ctx context.Context, resultsStore fleet.CampaignResultsStore, removeCreatedBefore time.Time) error {
+	s.mu.Lock()
+	s.CleanupUnusedCampaignResultsDataFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupUnusedCampaignResultsDataFunc(ctx, resultsStore, removeCreatedBefore)
+}
+
 func (s *DataStore) GetCompletedCampaigns(ctx context.Context, filter []uint) ([]uint, error) {
 	s.mu.Lock()
 	s.GetCompletedCampaignsFuncInvoked = true
@@ -7284,6 +7690,125 @@ func (s *DataStore) NewMFAToken(ctx context.Context, userID uint) (string, error
 	return s.NewMFATokenFunc(ctx, userID)
 }
 
+func (s *DataStore) SetUserPendingTOTPSecret(ctx context.Context, userID uint, secret []byte) error {
+	s.mu.Lock()
+	s.SetUserPendingTOTPSecretFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetUserPendingTOTPSecretFunc(ctx, userID, secret)
+}
+
+func (s *DataStore) UserTOTP(ctx context.Context, userID uint) (*fleet.UserTOTP, error) {
+	s.mu.Lock()
+	s.UserTOTPFuncInvoked = true
+	s.mu.Unlock()
+	return s.UserTOTPFunc(ctx, userID)
+}
+
+func (s *DataStore) ConfirmUserTOTP(ctx context.Context, userID uint, step int64) error {
+	s.mu.Lock()
+	s.ConfirmUserTOTPFuncInvoked = true
+	s.mu.Unlock()
+	return s.ConfirmUserTOTPFunc(ctx, userID, step)
+}
+
+func (s *DataStore) UseUserTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	s.mu.Lock()
+	s.UseUserTOTPStepFuncInvoked = true
+	s.mu.Unlock()
+	return s.UseUserTOTPStepFunc(ctx, userID, step)
+}
+
+func (s *DataStore) DeleteUserTOTP(ctx context.Context, userID uint) error {
+	s.mu.Lock()
+	s.DeleteUserTOTPFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteUserTOTPFunc(ctx, userID)
+}
+
+func (s *DataStore) ReplaceUserMFARecoveryCodes(ctx context.Context, userID uint, codeHashes [][]byte) error {
+	s.mu.Lock()
+	s.ReplaceUserMFARecoveryCodesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ReplaceUserMFARecoveryCodesFunc(ctx, userID, codeHashes)
+}
+
+func (s *DataStore) CountUserMFARecoveryCodes(ctx context.Context, userID uint) (int, error) {
+	s.mu.Lock()
+	s.CountUserMFARecoveryCodesFuncInvoked = true
+	s.mu.Unlock()
+	return s.CountUserMFARecoveryCodesFunc(ctx, userID)
+}
+
+func (s *DataStore) ConsumeUserMFARecoveryCode(ctx context.Context, userID uint, codeHash []byte) (bool, error) {
+	s.mu.Lock()
+	s.ConsumeUserMFARecoveryCodeFuncInvoked = true
+	s.mu.Unlock()
+	return s.ConsumeUserMFARecoveryCodeFunc(ctx, userID, codeHash)
+}
+
+func (s *DataStore) NewUserWebAuthnCredential(ctx context.Context, cred *fleet.WebAuthnCredential) (*fleet.WebAuthnCredential, error) {
+	s.mu.Lock()
+	s.NewUserWebAuthnCredentialFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewUserWebAuthnCredentialFunc(ctx, cred)
+}
+
+func (s *DataStore) ListUserWebAuthnCredentials(ctx context.Context, userID uint) ([]*fleet.WebAuthnCredential, error) {
+	s.mu.Lock()
+	s.ListUserWebAuthnCredentialsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListUserWebAuthnCredentialsFunc(ctx, userID)
+}
+
+func (s *DataStore) UpdateUserWebAuthnCredentialSignCount(ctx context.Context, id uint, signCount uint32) error {
+	s.mu.Lock()
+	s.UpdateUserWebAuthnCredentialSignCountFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpdateUserWebAuthnCredentialSignCountFunc(ctx, id, signCount)
+}
+
+func (s *DataStore) DeleteUserWebAuthnCredential(ctx context.Context, userID uint, id uint) error {
+	s.mu.Lock()
+	s.DeleteUserWebAuthnCredentialFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteUserWebAuthnCredentialFunc(ctx, userID, id)
+}
+
+func (s *DataStore) ResetUserMFA(ctx context.Context, userID uint) error {
+	s.mu.Lock()
+	s.ResetUserMFAFuncInvoked = true
+	s.mu.Unlock()
+	return s.ResetUserMFAFunc(ctx, userID)
+}
+
+func (s *DataStore) NewUserMFAChallenge(ctx context.Context, userID uint, purpose string, webAuthnChallenge []byte) (*fleet.UserMFAChallenge, error) {
+	s.mu.Lock()
+	s.NewUserMFAChallengeFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewUserMFAChallengeFunc(ctx, userID, purpose, webAuthnChallenge)
+}
+
+func (s *DataStore) UserMFAChallenge(ctx context.Context, token string) (*fleet.UserMFAChallenge, error) {
+	s.mu.Lock()
+	s.UserMFAChallengeFuncInvoked = true
+	s.mu.Unlock()
+	return s.UserMFAChallengeFunc(ctx, token)
+}
+
+func (s *DataStore) RecordUserMFAChallengeFailure(ctx context.Context, token string) error {
+	s.mu.Lock()
+	s.RecordUserMFAChallengeFailureFuncInvoked = true
+	s.mu.Unlock()
+	return s.RecordUserMFAChallengeFailureFunc(ctx, token)
+}
+
+func (s *DataStore) ConsumeUserMFAChallenge(ctx context.Context, token string) error {
+	s.mu.Lock()
+	s.ConsumeUserMFAChallengeFuncInvoked = true
+	s.mu.Unlock()
+	return s.ConsumeUserMFAChallengeFunc(ctx, token)
+}
+
 func (s *DataStore) NewAppConfig(ctx context.Context, info *fleet.AppConfig) (*fleet.AppConfig, error) {
 	s.mu.Lock()
 	s.NewAppConfigFuncInvoked = true
@@ -7487,6 +8012,34 @@ func (s *DataStore) CleanupExcessQueryResultRows(ctx context.Context, maxQueryRe
 	return s.CleanupExcessQueryResultRowsFunc(ctx, maxQueryReportRows, opts...)
 }
 
+func (s *DataStore) InsertQueryResultHistory(ctx context.Context, batches []*fleet.QueryResultHistoryBatch) error {
+	s.mu.Lock()
+	s.InsertQueryResultHistoryFuncInvoked = true
+	s.mu.Unlock()
+	return s.InsertQueryResultHistoryFunc(ctx, batches)
+}
+
+func (s *DataStore) QueryResultHistoryRows(ctx context.Context, queryID uint, from time.Time, to time.Time, filter fleet.TeamFilter) ([]*fleet.ScheduledQueryResultRow, error) {
+	s.mu.Lock()
+	s.QueryResultHistoryRowsFuncInvoked = true
+	s.mu.Unlock()
+	return s.QueryResultHistoryRowsFunc(ctx, queryID, from, to, filter)
+}
+
+func (s *DataStore) QueryResultHistorySnapshot(ctx context.Context, queryID uint, at time.Time, filter fleet.TeamFilter) ([]*fleet.ScheduledQueryResultRow, error) {
+	s.mu.Lock()
+	s.QueryResultHistorySnapshotFuncInvoked = true
+	s.mu.Unlock()
+	return s.QueryResultHistorySnapshotFunc(ctx, queryID, at, filter)
+}
+
+func (s *DataStore) CleanupQueryResultHistory(ctx context.Context, olderThan time.Time, maxRowsPerQuery int) error {
+	s.mu.Lock()
+	s.CleanupQueryResultHistoryFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupQueryResultHistoryFunc(ctx, olderThan, maxRowsPerQuery)
+}
+
 func (s *DataStore) ListHostReports(ctx context.Context, hostID uint, teamID *uint, hostPlatform string, opts fleet.ListHostReportsOptions, maxQueryReportRows int) ([]*fleet.HostReport, int, *fleet.PaginationMetadata, error) {
 	s.mu.Lock()
 	s.ListHostReportsFuncInvoked = true
@@ -8537,6 +9090,27 @@ func (s *DataStore) ListOutOfDateCalendarEvents(ctx context.Context, t time.Time
 	return s.ListOutOfDateCalendarEventsFunc(ctx, t)
 }
 
+func (s *DataStore) RecordServiceNowPolicyIncident(ctx context.Context, policyID uint) error {
+	s.mu.Lock()
+	s.RecordServiceNowPolicyIncidentFuncInvoked = true
+	s.mu.Unlock()
+	return s.RecordServiceNowPolicyIncidentFunc(ctx, policyID)
+}
+
+func (s *DataStore) DeleteServiceNowPolicyIncident(ctx context.Context, policyID uint) error {
+	s.mu.Lock()
+	s.DeleteServiceNowPolicyIncidentFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteServiceNowPolicyIncidentFunc(ctx, policyID)
+}
+
+func (s *DataStore) ListServiceNowPolicyIncidents(ctx context.Context) ([]uint, error) {
+	s.mu.Lock()
+	s.ListServiceNowPolicyIncidentsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListServiceNowPolicyIncidentsFunc(ctx)
+}
+
 func (s *DataStore) NewTeamPolicy(ctx context.Context, teamID uint, authorID *uint, args fleet.PolicyPayload) (*fleet.Policy, error) {
 	s.mu.Lock()
 	s.NewTeamPolicyFuncInvoked = true
@@ -12695,6 +13269,265 @@ func (s *DataStore) UpsertCustomHostVitals(ctx context.Context, vitals []fleet.C
 	return s.UpsertCustomHostVitalsFunc(ctx, vitals)
 }
 
+func (s *DataStore) NewCustomRole(ctx context.Context, role *fleet.CustomRole) (*fleet.CustomRole, error) {
+	s.mu.Lock()
+	s.NewCustomRoleFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewCustomRoleFunc(ctx, role)
+}
+
+func (s *DataStore) CustomRole(ctx context.Context, id uint) (*fleet.CustomRole, error) {
+	s.mu.Lock()
+	s.CustomRoleFuncInvoked = true
+	s.mu.Unlock()
+	return s.CustomRoleFunc(ctx, id)
+}
+
+func (s *DataStore) ListCustomRoles(ctx context.Context) ([]fleet.CustomRole, error) {
+	s.mu.Lock()
+	s.ListCustomRolesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListCustomRolesFunc(ctx)
+}
+
+func (s *DataStore) SaveCustomRole(ctx context.Context, role *fleet.CustomRole) (*fleet.CustomRole, error) {
+	s.mu.Lock()
+	s.SaveCustomRoleFuncInvoked = true
+	s.mu.Unlock()
+	return s.SaveCustomRoleFunc(ctx, role)
+}
+
+func (s *DataStore) DeleteCustomRole(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	s.DeleteCustomRoleFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteCustomRoleFunc(ctx, id)
+}
+
+func (s *DataStore) SetUserCustomRoles(ctx context.Context, userID uint, roles []fleet.UserCustomRole) error {
+	s.mu.Lock()
+	s.SetUserCustomRolesFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetUserCustomRolesFunc(ctx, userID, roles)
+}
+
+func (s *DataStore) ApplyCustomRoles(ctx context.Context, specs []fleet.CustomRoleSpec) (created []fleet.CustomRole, edited []fleet.CustomRole, deleted []fleet.CustomRole, err error) {
+	s.mu.Lock()
+	s.ApplyCustomRolesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ApplyCustomRolesFunc(ctx, specs)
+}
+
+func (s *DataStore) NewVEXDocument(ctx context.Context, doc *fleet.VEXDocument) (*fleet.VEXDocument, error) {
+	s.mu.Lock()
+	s.NewVEXDocumentFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewVEXDocumentFunc(ctx, doc)
+}
+
+func (s *DataStore) VEXDocument(ctx context.Context, id uint) (*fleet.VEXDocument, error) {
+	s.mu.Lock()
+	s.VEXDocumentFuncInvoked = true
+	s.mu.Unlock()
+	return s.VEXDocumentFunc(ctx, id)
+}
+
+func (s *DataStore) ListVEXDocuments(ctx context.Context) ([]*fleet.VEXDocument, error) {
+	s.mu.Lock()
+	s.ListVEXDocumentsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListVEXDocumentsFunc(ctx)
+}
+
+func (s *DataStore) DeleteVEXDocument(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	s.DeleteVEXDocumentFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteVEXDocumentFunc(ctx, id)
+}
+
+func (s *DataStore) ListVEXStatements(ctx context.Context, statuses []fleet.VEXStatus) ([]fleet.VEXStatement, error) {
+	s.mu.Lock()
+	s.ListVEXStatementsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListVEXStatementsFunc(ctx, statuses)
+}
+
+func (s *DataStore) ListVEXCandidates(ctx context.Context, cves []string) ([]fleet.VEXCandidate, error) {
+	s.mu.Lock()
+	s.ListVEXCandidatesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListVEXCandidatesFunc(ctx, cves)
+}
+
+func (s *DataStore) ReplaceVEXSuppressions(ctx context.Context, suppressions []fleet.VEXSuppression) error {
+	s.mu.Lock()
+	s.ReplaceVEXSuppressionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ReplaceVEXSuppressionsFunc(ctx, suppressions)
+}
+
+func (s *DataStore) ListVEXSuppressions(ctx context.Context, opts fleet.VEXSuppressionListOptions) ([]fleet.VEXSuppression, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListVEXSuppressionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListVEXSuppressionsFunc(ctx, opts)
+}
+
+func (s *DataStore) NewVulnerabilityException(ctx context.Context, e *fleet.VulnerabilityException) (*fleet.VulnerabilityException, error) {
+	s.mu.Lock()
+	s.NewVulnerabilityExceptionFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewVulnerabilityExceptionFunc(ctx, e)
+}
+
+func (s *DataStore) VulnerabilityException(ctx context.Context, id uint) (*fleet.VulnerabilityException, error) {
+	s.mu.Lock()
+	s.VulnerabilityExceptionFuncInvoked = true
+	s.mu.Unlock()
+	return s.VulnerabilityExceptionFunc(ctx, id)
+}
+
+func (s *DataStore) ListVulnerabilityExceptions(ctx context.Context, opts fleet.VulnerabilityExceptionListOptions) ([]fleet.VulnerabilityException, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListVulnerabilityExceptionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListVulnerabilityExceptionsFunc(ctx, opts)
+}
+
+func (s *DataStore) SaveVulnerabilityException(ctx context.Context, e *fleet.VulnerabilityException) (*fleet.VulnerabilityException, error) {
+	s.mu.Lock()
+	s.SaveVulnerabilityExceptionFuncInvoked = true
+	s.mu.Unlock()
+	return s.SaveVulnerabilityExceptionFunc(ctx, e)
+}
+
+func (s *DataStore) DeleteVulnerabilityException(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	s.DeleteVulnerabilityExceptionFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteVulnerabilityExceptionFunc(ctx, id)
+}
+
+func (s *DataStore) ApplyVulnerabilityExceptions(ctx context.Context, exceptions []fleet.VulnerabilityException) (created []fleet.VulnerabilityException, edited []fleet.VulnerabilityException, deleted []fleet.VulnerabilityException, err error) {
+	s.mu.Lock()
+	s.ApplyVulnerabilityExceptionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ApplyVulnerabilityExceptionsFunc(ctx, exceptions)
+}
+
+func (s *DataStore) ExpireVulnerabilityExceptions(ctx context.Context, now time.Time) ([]fleet.VulnerabilityException, error) {
+	s.mu.Lock()
+	s.ExpireVulnerabilityExceptionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ExpireVulnerabilityExceptionsFunc(ctx, now)
+}
+
+func (s *DataStore) FilterExceptedSoftwareVulnerabilities(ctx context.Context, vulns []fleet.SoftwareVulnerability) ([]fleet.SoftwareVulnerability, error) {
+	s.mu.Lock()
+	s.FilterExceptedSoftwareVulnerabilitiesFuncInvoked = true
+	s.mu.Unlock()
+	return s.FilterExceptedSoftwareVulnerabilitiesFunc(ctx, vulns)
+}
+
+func (s *DataStore) VulnerabilityExceptedHostIDs(ctx context.Context, cve string, softwareIDs []uint) ([]uint, error) {
+	s.mu.Lock()
+	s.VulnerabilityExceptedHostIDsFuncInvoked = true
+	s.mu.Unlock()
+	return s.VulnerabilityExceptedHostIDsFunc(ctx, cve, softwareIDs)
+}
+
+func (s *DataStore) NewWebhookDelivery(ctx context.Context, d *fleet.WebhookDelivery) (*fleet.WebhookDelivery, error) {
+	s.mu.Lock()
+	s.NewWebhookDeliveryFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewWebhookDeliveryFunc(ctx, d)
+}
+
+func (s *DataStore) UpdateWebhookDelivery(ctx context.Context, d *fleet.WebhookDelivery) error {
+	s.mu.Lock()
+	s.UpdateWebhookDeliveryFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpdateWebhookDeliveryFunc(ctx, d)
+}
+
+func (s *DataStore) WebhookDelivery(ctx context.Context, id uint) (*fleet.WebhookDelivery, error) {
+	s.mu.Lock()
+	s.WebhookDeliveryFuncInvoked = true
+	s.mu.Unlock()
+	return s.WebhookDeliveryFunc(ctx, id)
+}
+
+func (s *DataStore) ListWebhookDeliveries(ctx context.Context, opts fleet.WebhookDeliveryListOptions) ([]fleet.WebhookDelivery, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListWebhookDeliveriesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListWebhookDeliveriesFunc(ctx, opts)
+}
+
+func (s *DataStore) CleanupWebhookDeliveries(ctx context.Context, olderThan time.Time) (int64, error) {
+	s.mu.Lock()
+	s.CleanupWebhookDeliveriesFuncInvoked = true
+	s.mu.Unlock()
+	return s.CleanupWebhookDeliveriesFunc(ctx, olderThan)
+}
+
+func (s *DataStore) SaveGitOpsSnapshot(ctx context.Context, snapshot *fleet.GitOpsSnapshot) error {
+	s.mu.Lock()
+	s.SaveGitOpsSnapshotFuncInvoked = true
+	s.mu.Unlock()
+	return s.SaveGitOpsSnapshotFunc(ctx, snapshot)
+}
+
+func (s *DataStore) GitOpsSnapshot(ctx context.Context, teamID uint) (*fleet.GitOpsSnapshot, error) {
+	s.mu.Lock()
+	s.GitOpsSnapshotFuncInvoked = true
+	s.mu.Unlock()
+	return s.GitOpsSnapshotFunc(ctx, teamID)
+}
+
+func (s *DataStore) ListGitOpsSnapshots(ctx context.Context) ([]*fleet.GitOpsSnapshot, error) {
+	s.mu.Lock()
+	s.ListGitOpsSnapshotsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListGitOpsSnapshotsFunc(ctx)
+}
+
+func (s *DataStore) UpdateGitOpsSnapshotDrift(ctx context.Context, teamID uint, drift []fleet.GitOpsResourceChange, driftChecksum string, checkedAt time.Time) error {
+	s.mu.Lock()
+	s.UpdateGitOpsSnapshotDriftFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpdateGitOpsSnapshotDriftFunc(ctx, teamID, drift, driftChecksum, checkedAt)
+}
+
+func (s *DataStore) DeleteGitOpsSnapshot(ctx context.Context, teamID uint) error {
+	s.mu.Lock()
+	s.DeleteGitOpsSnapshotFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteGitOpsSnapshotFunc(ctx, teamID)
+}
+
+func (s *DataStore) UpsertHostCacheProxyStats(ctx context.Context, hostID uint, stats fleet.CacheProxyStats) error {
+	s.mu.Lock()
+	s.UpsertHostCacheProxyStatsFuncInvoked = true
+	s.mu.Unlock()
+	return s.UpsertHostCacheProxyStatsFunc(ctx, hostID, stats)
+}
+
+func (s *DataStore) ListHostCacheProxies(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.HostCacheProxy, error) {
+	s.mu.Lock()
+	s.ListHostCacheProxiesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListHostCacheProxiesFunc(ctx, filter)
+}
+
+func (s *DataStore) GetCacheProxySoftwareInstaller(ctx context.Context, hostID uint, hashSHA256 string) (*fleet.SoftwareInstaller, error) {
+	s.mu.Lock()
+	s.GetCacheProxySoftwareInstallerFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetCacheProxySoftwareInstallerFunc(ctx, hostID, hashSHA256)
+}
+
 func (s *DataStore) CreateEnterprise(ctx context.Context, userID uint) (uint, error) {
 	s.mu.Lock()
 	s.CreateEnterpriseFuncInvoked = true
@@ -13339,6 +14172,13 @@ func (s *DataStore) DeleteScimGroup(ctx context.Context, id uint) error {
 	return s.DeleteScimGroupFunc(ctx, id)
 }
 
+func (s *DataStore) ScimGroupMemberUserIDs(ctx context.Context, groupID uint) ([]uint, error) {
+	s.mu.Lock()
+	s.ScimGroupMemberUserIDsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ScimGroupMemberUserIDsFunc(ctx, groupID)
+}
+
 func (s *DataStore) ListScimGroups(ctx context.Context, opts fleet.ScimGroupsListOptions) (groups []fleet.ScimGroup, totalResults uint, err error) {
 	s.mu.Lock()
 	s.ListScimGroupsFuncInvoked = true
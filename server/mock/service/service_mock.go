@@ -15,6 +15,7 @@ import (
 	"github.com/fleetdm/fleet/v4/pkg/optjson"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/mdm/nanodep/godep"
+	"github.com/fleetdm/fleet/v4/server/mfa"
 	"github.com/fleetdm/fleet/v4/server/version"
 	"github.com/fleetdm/fleet/v4/server/websocket"
 )
@@ -47,6 +48,8 @@ type GetActivitiesWebhookSettingsFunc func(ctx context.Context) (fleet.Activitie
 
 type GetHostActivitiesWebhookSettingsFunc func(ctx context.Context, hostIDs []uint) ([]fleet.HostActivitiesWebhookDelivery, error)
 
+type DeliverActivitiesWebhookFunc func(ctx context.Context, fleetID *uint, destinationURL string, payload json.RawMessage) error
+
 type ActivateNextUpcomingActivityForHostFunc func(ctx context.Context, hostID uint, fromCompletedExecID string) error
 
 type GetTransparencyURLFunc func(ctx context.Context) (string, error)
@@ -57,6 +60,10 @@ type EnrollOrbitFunc func(ctx context.Context, hostInfo fleet.OrbitHostInfo, enr
 
 type GetOrbitConfigFunc func(ctx context.Context) (fleet.OrbitConfig, error)
 
+type StreamOrbitNotificationsFunc func(ctx context.Context, conn *websocket.Conn)
+
+type NotifyOrbitHostsFunc func(ctx context.Context, hint fleet.OrbitNotificationHint, hostIDs ...uint)
+
 type LogFleetdErrorFunc func(ctx context.Context, errData fleet.FleetdError) error
 
 type SetOrUpdateDeviceAuthTokenFunc func(ctx context.Context, authToken string) error
@@ -99,12 +106,32 @@ type ChangeUserEmailFunc func(ctx context.Context, token string) (string, error)
 
 type GetUserSettingsFunc func(ctx context.Context, id uint) (settings *fleet.UserSettings, err error)
 
+type GetUserMFAFunc func(ctx context.Context) (*fleet.UserMFAStatus, error)
+
+type BeginTOTPEnrollmentFunc func(ctx context.Context) (*fleet.TOTPEnrollment, error)
+
+type ConfirmTOTPEnrollmentFunc func(ctx context.Context, code string) (recoveryCodes []string, err error)
+
+type DisableTOTPFunc func(ctx context.Context, password string) error
+
+type BeginWebAuthnRegistrationFunc func(ctx context.Context) (*fleet.WebAuthnRegistration, error)
+
+type FinishWebAuthnRegistrationFunc func(ctx context.Context, token string, name string, resp mfa.RegistrationResponse) (*fleet.WebAuthnCredential, []string, error)
+
+type DeleteWebAuthnCredentialFunc func(ctx context.Context, id uint, password string) error
+
+type RegenerateMFARecoveryCodesFunc func(ctx context.Context, password string) ([]string, error)
+
+type ResetUserMFAFunc func(ctx context.Context, userID uint) error
+
 type InitiateSSOFunc func(ctx context.Context, redirectURL string) (sessionID string, sessionDurationSeconds int, idpURL string, err error)
 
 type InitiateMDMSSOFunc func(ctx context.Context, initiator string, customOriginalURL string, hostUUID string) (sessionID string, sessionDurationSeconds int, idpURL string, err error)
 
 type InitSSOCallbackFunc func(ctx context.Context, sessionID string, samlResponse []byte) (auth fleet.Auth, redirectURL string, err error)
 
+type InitOIDCCallbackFunc func(ctx context.Context, sessionID string, state string, code string, idpError string) (auth fleet.Auth, redirectURL string, err error)
+
 type MDMSSOCallbackFunc func(ctx context.Context, sessionID string, samlResponse []byte) (redirectURL string, byodCookieValue string)
 
 type GetMDMAccountDrivenEnrollmentSSOURLFunc func(ctx context.Context, enrollmentToken string) (string, error)
@@ -123,6 +150,8 @@ type LogoutFunc func(ctx context.Context) (err error)
 
 type CompleteMFAFunc func(ctx context.Context, token string) (*fleet.Session, *fleet.User, error)
 
+type CompleteLoginMFAFunc func(ctx context.Context, v fleet.MFAVerification) (*fleet.Session, *fleet.User, []string, error)
+
 type DestroySessionFunc func(ctx context.Context) (err error)
 
 type GetInfoAboutSessionsForUserFunc func(ctx context.Context, id uint) (sessions []*fleet.Session, err error)
@@ -193,6 +222,10 @@ type GetQueryFunc func(ctx context.Context, id uint) (*fleet.Query, error)
 
 type GetQueryReportResultsFunc func(ctx context.Context, id uint, teamID *uint) ([]fleet.HostQueryResultRow, bool, error)
 
+type GetQueryReportHistoryFunc func(ctx context.Context, id uint, teamID *uint, from time.Time, to time.Time) ([]fleet.HostQueryResultRow, error)
+
+type DiffQueryReportFunc func(ctx context.Context, id uint, teamID *uint, from time.Time, to time.Time) (*fleet.QueryReportDiff, error)
+
 type GetHostQueryReportResultsFunc func(ctx context.Context, hid uint, queryID uint) (rows []fleet.HostQueryReportResult, lastFetched *time.Time, err error)
 
 type QueryReportIsClippedFunc func(ctx context.Context, queryID uint, maxQueryReportRows int) (bool, error)
@@ -213,6 +246,18 @@ type NewDistributedQueryCampaignByIdentifiersFunc func(ctx context.Context, quer
 
 type NewDistributedQueryCampaignFunc func(ctx context.Context, queryString string, queryID *uint, targets fleet.HostTargets) (*fleet.DistributedQueryCampaign, error)
 
+type NewDistributedQueryCampaignWithOptionsFunc func(ctx context.Context, queryString string, queryID *uint, targets fleet.HostTargets, opts fleet.DistributedQueryCampaignOptions) (*fleet.DistributedQueryCampaign, error)
+
+type NewDistributedQueryCampaignByIdentifiersWithOptionsFunc func(ctx context.Context, queryString string, queryID *uint, hosts []string, labels []string, opts fleet.DistributedQueryCampaignOptions) (*fleet.DistributedQueryCampaign, error)
+
+type GetQueryCampaignFunc func(ctx context.Context, id uint) (*fleet.QueryCampaignProgress, error)
+
+type ListQueryCampaignsFunc func(ctx context.Context, queryID *uint, opts fleet.ListOptions) ([]*fleet.QueryCampaignSummary, *fleet.PaginationMetadata, error)
+
+type ListCampaignQueryResultsFunc func(ctx context.Context, id uint, opts fleet.ListOptions) ([]*fleet.CampaignQueryResult, *fleet.PaginationMetadata, error)
+
+type DiffCampaignQueryResultsFunc func(ctx context.Context, id uint, previousID uint) (*fleet.QueryCampaignResultsDiff, error)
+
 type StreamCampaignResultsFunc func(ctx context.Context, conn *websocket.Conn, campaignID uint)
 
 type GetCampaignReaderFunc func(ctx context.Context, campaign *fleet.DistributedQueryCampaign) (<-chan interface{}, context.CancelFunc, error)
@@ -291,6 +336,8 @@ type HostEncryptionKeyFunc func(ctx context.Context, id uint) (*fleet.HostDiskEn
 
 type EscrowLUKSDataFunc func(ctx context.Context, passphrase string, salt string, keySlot *uint, clientError string, keyType string) error
 
+type ReportCacheProxyStatsFunc func(ctx context.Context, stats fleet.CacheProxyStats) error
+
 type EscrowWindowsManagedLocalAccountPasswordFunc func(ctx context.Context, password string, clientError string) error
 
 type AddLabelsToHostFunc func(ctx context.Context, id uint, labels []string) error
@@ -489,6 +536,10 @@ type CountSoftwareFunc func(ctx context.Context, opt fleet.SoftwareListOptions)
 
 type SaveHostSoftwareInstallResultFunc func(ctx context.Context, result *fleet.HostSoftwareInstallResultPayload) error
 
+type HostSBOMFunc func(ctx context.Context, hostID uint, format fleet.SBOMFormat) ([]byte, error)
+
+type TeamSBOMFunc func(ctx context.Context, teamID uint, format fleet.SBOMFormat) ([]byte, error)
+
 type ListSoftwareTitlesFunc func(ctx context.Context, opt fleet.SoftwareTitleListOptions) ([]fleet.SoftwareTitleListResult, int, *fleet.PaginationMetadata, error)
 
 type SoftwareTitleByIDFunc func(ctx context.Context, id uint, teamID *uint) (*fleet.SoftwareTitle, error)
@@ -867,6 +918,8 @@ type DownloadSoftwareInstallerFunc func(ctx context.Context, skipAuthz bool, alt
 
 type OrbitDownloadSoftwareInstallerFunc func(ctx context.Context, installerID uint) (*fleet.DownloadSoftwareInstallerPayload, error)
 
+type OrbitCacheProxyDownloadSoftwareInstallerFunc func(ctx context.Context, hashSHA256 string) (*fleet.DownloadSoftwareInstallerPayload, error)
+
 type GetSoftwareTitleIconFunc func(ctx context.Context, teamID uint, titleID uint) ([]byte, int64, string, error)
 
 type UploadSoftwareTitleIconFunc func(ctx context.Context, payload *fleet.UploadSoftwareTitleIconPayload) (fleet.SoftwareTitleIcon, error)
@@ -939,6 +992,54 @@ type SetHostCustomHostVitalValueFunc func(ctx context.Context, hostID uint, vita
 
 type UpsertCustomHostVitalsFunc func(ctx context.Context, customHostVitals []fleet.CustomHostVital, dryRun bool) error
 
+type ListCustomRolesFunc func(ctx context.Context) ([]fleet.CustomRole, error)
+
+type GetCustomRoleFunc func(ctx context.Context, id uint) (*fleet.CustomRole, error)
+
+type CreateCustomRoleFunc func(ctx context.Context, p fleet.CustomRolePayload) (*fleet.CustomRole, error)
+
+type ModifyCustomRoleFunc func(ctx context.Context, id uint, p fleet.CustomRolePayload) (*fleet.CustomRole, error)
+
+type DeleteCustomRoleFunc func(ctx context.Context, id uint) error
+
+type SetUserCustomRolesFunc func(ctx context.Context, userID uint, roles []fleet.UserCustomRole) (*fleet.User, error)
+
+type ApplyCustomRolesFunc func(ctx context.Context, specs []fleet.CustomRoleSpec, dryRun bool) error
+
+type UploadVEXDocumentFunc func(ctx context.Context, name string, r io.Reader) (*fleet.VEXDocument, error)
+
+type ListVEXDocumentsFunc func(ctx context.Context) ([]*fleet.VEXDocument, error)
+
+type DeleteVEXDocumentFunc func(ctx context.Context, id uint) error
+
+type ListVEXSuppressionsFunc func(ctx context.Context, opts fleet.VEXSuppressionListOptions) ([]fleet.VEXSuppression, *fleet.PaginationMetadata, error)
+
+type ListVulnerabilityExceptionsFunc func(ctx context.Context, opts fleet.VulnerabilityExceptionListOptions) ([]fleet.VulnerabilityException, *fleet.PaginationMetadata, error)
+
+type GetVulnerabilityExceptionFunc func(ctx context.Context, id uint) (*fleet.VulnerabilityException, error)
+
+type CreateVulnerabilityExceptionFunc func(ctx context.Context, p fleet.VulnerabilityExceptionPayload) (*fleet.VulnerabilityException, error)
+
+type ModifyVulnerabilityExceptionFunc func(ctx context.Context, id uint, p fleet.VulnerabilityExceptionPayload) (*fleet.VulnerabilityException, error)
+
+type DeleteVulnerabilityExceptionFunc func(ctx context.Context, id uint) error
+
+type ApplyVulnerabilityExceptionsFunc func(ctx context.Context, specs []fleet.VulnerabilityExceptionSpec, dryRun bool) error
+
+type ExpireVulnerabilityExceptionsFunc func(ctx context.Context) error
+
+type ListWebhookDeliveriesFunc func(ctx context.Context, opts fleet.WebhookDeliveryListOptions) ([]fleet.WebhookDelivery, *fleet.PaginationMetadata, error)
+
+type GetWebhookDeliveryFunc func(ctx context.Context, id uint) (*fleet.WebhookDelivery, error)
+
+type RedeliverWebhookDeliveryFunc func(ctx context.Context, id uint) (*fleet.WebhookDelivery, error)
+
+type SaveGitOpsSnapshotFunc func(ctx context.Context, teamID *uint) (*fleet.GitOpsSnapshot, error)
+
+type GetGitOpsDriftFunc func(ctx context.Context, teamID *uint) ([]*fleet.GitOpsDrift, error)
+
+type ListCacheProxiesFunc func(ctx context.Context, teamID *uint) ([]*fleet.HostCacheProxy, error)
+
 type ListAPIEndpointsFunc func(ctx context.Context) (endpoints []fleet.APIEndpoint, err error)
 
 type ScimDetailsFunc func(ctx context.Context) (fleet.ScimDetails, error)
@@ -1041,6 +1142,9 @@ type Service struct {
 	GetHostActivitiesWebhookSettingsFunc        GetHostActivitiesWebhookSettingsFunc
 	GetHostActivitiesWebhookSettingsFuncInvoked bool
 
+	DeliverActivitiesWebhookFunc        DeliverActivitiesWebhookFunc
+	DeliverActivitiesWebhookFuncInvoked bool
+
 	ActivateNextUpcomingActivityForHostFunc        ActivateNextUpcomingActivityForHostFunc
 	ActivateNextUpcomingActivityForHostFuncInvoked bool
 
@@ -1056,6 +1160,12 @@ type Service struct {
 	GetOrbitConfigFunc        GetOrbitConfigFunc
 	GetOrbitConfigFuncInvoked bool
 
+	StreamOrbitNotificationsFunc        StreamOrbitNotificationsFunc
+	StreamOrbitNotificationsFuncInvoked bool
+
+	NotifyOrbitHostsFunc        NotifyOrbitHostsFunc
+	NotifyOrbitHostsFuncInvoked bool
+
 	LogFleetdErrorFunc        LogFleetdErrorFunc
 	LogFleetdErrorFuncInvoked bool
 
@@ -1119,6 +1229,33 @@ type Service struct {
 	GetUserSettingsFunc        GetUserSettingsFunc
 	GetUserSettingsFuncInvoked bool
 
+	GetUserMFAFunc        GetUserMFAFunc
+	GetUserMFAFuncInvoked bool
+
+	BeginTOTPEnrollmentFunc        BeginTOTPEnrollmentFunc
+	BeginTOTPEnrollmentFuncInvoked bool
+
+	ConfirmTOTPEnrollmentFunc        ConfirmTOTPEnrollmentFunc
+	ConfirmTOTPEnrollmentFuncInvoked bool
+
+	DisableTOTPFunc        DisableTOTPFunc
+	DisableTOTPFuncInvoked bool
+
+	BeginWebAuthnRegistrationFunc        BeginWebAuthnRegistrationFunc
+	BeginWebAuthnRegistrationFuncInvoked bool
+
+	FinishWebAuthnRegistrationFunc        FinishWebAuthnRegistrationFunc
+	FinishWebAuthnRegistrationFuncInvoked bool
+
+	DeleteWebAuthnCredentialFunc        DeleteWebAuthnCredentialFunc
+	DeleteWebAuthnCredentialFuncInvoked bool
+
+	RegenerateMFARecoveryCodesFunc        RegenerateMFARecoveryCodesFunc
+	RegenerateMFARecoveryCodesFuncInvoked bool
+
+	ResetUserMFAFunc        ResetUserMFAFunc
+	ResetUserMFAFuncInvoked bool
+
 	InitiateSSOFunc        InitiateSSOFunc
 	InitiateSSOFuncInvoked bool
 
@@ -1128,6 +1265,9 @@ type Service struct {
 	InitSSOCallbackFunc        InitSSOCallbackFunc
 	InitSSOCallbackFuncInvoked bool
 
+	InitOIDCCallbackFunc        InitOIDCCallbackFunc
+	InitOIDCCallbackFuncInvoked bool
+
 	MDMSSOCallbackFunc        MDMSSOCallbackFunc
 	MDMSSOCallbackFuncInvoked bool
 
@@ -1155,6 +1295,9 @@ type Service struct {
 	CompleteMFAFunc        CompleteMFAFunc
 	CompleteMFAFuncInvoked bool
 
+	CompleteLoginMFAFunc        CompleteLoginMFAFunc
+	CompleteLoginMFAFuncInvoked bool
+
 	DestroySessionFunc        DestroySessionFunc
 	DestroySessionFuncInvoked bool
 
@@ -1260,6 +1403,12 @@ type Service struct {
 	GetQueryReportResultsFunc        GetQueryReportResultsFunc
 	GetQueryReportResultsFuncInvoked bool
 
+	GetQueryReportHistoryFunc        GetQueryReportHistoryFunc
+	GetQueryReportHistoryFuncInvoked bool
+
+	DiffQueryReportFunc        DiffQueryReportFunc
+	DiffQueryReportFuncInvoked bool
+
 	GetHostQueryReportResultsFunc        GetHostQueryReportResultsFunc
 	GetHostQueryReportResultsFuncInvoked bool
 
@@ -1290,6 +1439,24 @@ type Service struct {
 	NewDistributedQueryCampaignFunc        NewDistributedQueryCampaignFunc
 	NewDistributedQueryCampaignFuncInvoked bool
 
+	NewDistributedQueryCampaignWithOptionsFunc        NewDistributedQueryCampaignWithOptionsFunc
+	NewDistributedQueryCampaignWithOptionsFuncInvoked bool
+
+	NewDistributedQueryCampaignByIdentifiersWithOptionsFunc        NewDistributedQueryCampaignByIdentifiersWithOptionsFunc
+	NewDistributedQueryCampaignByIdentifiersWithOptionsFuncInvoked bool
+
+	GetQueryCampaignFunc        GetQueryCampaignFunc
+	GetQueryCampaignFuncInvoked bool
+
+	ListQueryCampaignsFunc        ListQueryCampaignsFunc
+	ListQueryCampaignsFuncInvoked bool
+
+	ListCampaignQueryResultsFunc        ListCampaignQueryResultsFunc
+	ListCampaignQueryResultsFuncInvoked bool
+
+	DiffCampaignQueryResultsFunc        DiffCampaignQueryResultsFunc
+	DiffCampaignQueryResultsFuncInvoked bool
+
 	StreamCampaignResultsFunc        StreamCampaignResultsFunc
 	StreamCampaignResultsFuncInvoked bool
 
@@ -1407,6 +1574,9 @@ type Service struct {
 	EscrowLUKSDataFunc        EscrowLUKSDataFunc
 	EscrowLUKSDataFuncInvoked bool
 
+	ReportCacheProxyStatsFunc        ReportCacheProxyStatsFunc
+	ReportCacheProxyStatsFuncInvoked bool
+
 	EscrowWindowsManagedLocalAccountPasswordFunc        EscrowWindowsManagedLocalAccountPasswordFunc
 	EscrowWindowsManagedLocalAccountPasswordFuncInvoked bool
 
@@ -1704,6 +1874,12 @@ type Service struct {
 	SaveHostSoftwareInstallResultFunc        SaveHostSoftwareInstallResultFunc
 	SaveHostSoftwareInstallResultFuncInvoked bool
 
+	HostSBOMFunc        HostSBOMFunc
+	HostSBOMFuncInvoked bool
+
+	TeamSBOMFunc        TeamSBOMFunc
+	TeamSBOMFuncInvoked bool
+
 	ListSoftwareTitlesFunc        ListSoftwareTitlesFunc
 	ListSoftwareTitlesFuncInvoked bool
 
@@ -2271,6 +2447,9 @@ type Service struct {
 	OrbitDownloadSoftwareInstallerFunc        OrbitDownloadSoftwareInstallerFunc
 	OrbitDownloadSoftwareInstallerFuncInvoked bool
 
+	OrbitCacheProxyDownloadSoftwareInstallerFunc        OrbitCacheProxyDownloadSoftwareInstallerFunc
+	OrbitCacheProxyDownloadSoftwareInstallerFuncInvoked bool
+
 	GetSoftwareTitleIconFunc        GetSoftwareTitleIconFunc
 	GetSoftwareTitleIconFuncInvoked bool
 
@@ -2379,6 +2558,78 @@ type Service struct {
 	UpsertCustomHostVitalsFunc        UpsertCustomHostVitalsFunc
 	UpsertCustomHostVitalsFuncInvoked bool
 
+	ListCustomRolesFunc        ListCustomRolesFunc
+	ListCustomRolesFuncInvoked bool
+
+	GetCustomRoleFunc        GetCustomRoleFunc
+	GetCustomRoleFuncInvoked bool
+
+	CreateCustomRoleFunc        CreateCustomRoleFunc
+	CreateCustomRoleFuncInvoked bool
+
+	ModifyCustomRoleFunc        ModifyCustomRoleFunc
+	ModifyCustomRoleFuncInvoked bool
+
+	DeleteCustomRoleFunc        DeleteCustomRoleFunc
+	DeleteCustomRoleFuncInvoked bool
+
+	SetUserCustomRolesFunc        SetUserCustomRolesFunc
+	SetUserCustomRolesFuncInvoked bool
+
+	ApplyCustomRolesFunc        ApplyCustomRolesFunc
+	ApplyCustomRolesFuncInvoked bool
+
+	UploadVEXDocumentFunc        UploadVEXDocumentFunc
+	UploadVEXDocumentFuncInvoked bool
+
+	ListVEXDocumentsFunc        ListVEXDocumentsFunc
+	ListVEXDocumentsFuncInvoked bool
+
+	DeleteVEXDocumentFunc        DeleteVEXDocumentFunc
+	DeleteVEXDocumentFuncInvoked bool
+
+	ListVEXSuppressionsFunc        ListVEXSuppressionsFunc
+	ListVEXSuppressionsFuncInvoked bool
+
+	ListVulnerabilityExceptionsFunc        ListVulnerabilityExceptionsFunc
+	ListVulnerabilityExceptionsFuncInvoked bool
+
+	GetVulnerabilityExceptionFunc        GetVulnerabilityExceptionFunc
+	GetVulnerabilityExceptionFuncInvoked bool
+
+	CreateVulnerabilityExceptionFunc        CreateVulnerabilityExceptionFunc
+	CreateVulnerabilityExceptionFuncInvoked bool
+
+	ModifyVulnerabilityExceptionFunc        ModifyVulnerabilityExceptionFunc
+	ModifyVulnerabilityExceptionFuncInvoked bool
+
+	DeleteVulnerabilityExceptionFunc        DeleteVulnerabilityExceptionFunc
+	DeleteVulnerabilityExceptionFuncInvoked bool
+
+	ApplyVulnerabilityExceptionsFunc        ApplyVulnerabilityExceptionsFunc
+	ApplyVulnerabilityExceptionsFuncInvoked bool
+
+	ExpireVulnerabilityExceptionsFunc        ExpireVulnerabilityExceptionsFunc
+	ExpireVulnerabilityExceptionsFuncInvoked bool
+
+	ListWebhookDeliveriesFunc        ListWebhookDeliveriesFunc
+	ListWebhookDeliveriesFuncInvoked bool
+
+	GetWebhookDeliveryFunc        GetWebhookDeliveryFunc
+	GetWebhookDeliveryFuncInvoked bool
+
+	RedeliverWebhookDeliveryFunc        RedeliverWebhookDeliveryFunc
+	RedeliverWebhookDeliveryFuncInvoked bool
+
+	SaveGitOpsSnapshotFunc        SaveGitOpsSnapshotFunc
+	SaveGitOpsSnapshotFuncInvoked bool
+
+	GetGitOpsDriftFunc        GetGitOpsDriftFunc
+	GetGitOpsDriftFuncInvoked bool
+
+	ListCacheProxiesFunc        ListCacheProxiesFunc
+	ListCacheProxiesFuncInvoked bool
+
 	ListAPIEndpointsFunc        ListAPIEndpointsFunc
 	ListAPIEndpointsFuncInvoked bool
 
@@ -2566,6 +2817,13 @@ func (s *Service) GetHostActivitiesWebhookSettings(ctx context.Context, hostIDs
 	return s.GetHostActivitiesWebhookSettingsFunc(ctx, hostIDs)
 }
 
+func (s *Service) DeliverActivitiesWebhook(ctx context.Context, fleetID *uint, destinationURL string, payload json.RawMessage) error {
+	s.mu.Lock()
+	s.DeliverActivitiesWebhookFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeliverActivitiesWebhookFunc(ctx, fleetID, destinationURL, payload)
+}
+
 func (s *Service) ActivateNextUpcomingActivityForHost(ctx context.Context, hostID uint, fromCompletedExecID string) error {
 	s.mu.Lock()
 	s.ActivateNextUpcomingActivityForHostFuncInvoked = true
@@ -2601,6 +2859,20 @@ func (s *Service) GetOrbitConfig(ctx context.Context) (fleet.OrbitConfig, error)
 	return s.GetOrbitConfigFunc(ctx)
 }
 
+func (s *Service) StreamOrbitNotifications(ctx context.Context, conn *websocket.Conn) {
+	s.mu.Lock()
+	s.StreamOrbitNotificationsFuncInvoked = true
+	s.mu.Unlock()
+	s.StreamOrbitNotificationsFunc(ctx, conn)
+}
+
+func (s *Service) NotifyOrbitHosts(ctx context.Context, hint fleet.OrbitNotificationHint, hostIDs ...uint) {
+	s.mu.Lock()
+	s.NotifyOrbitHostsFuncInvoked = true
+	s.mu.Unlock()
+	s.NotifyOrbitHostsFunc(ctx, hint, hostIDs...)
+}
+
 func (s *Service) LogFleetdError(ctx context.Context, errData fleet.FleetdError) error {
 	s.mu.Lock()
 	s.LogFleetdErrorFuncInvoked = true
@@ -2748,6 +3020,69 @@ func (s *Service) GetUserSettings(ctx context.Context, id uint) (settings *fleet
 	return s.GetUserSettingsFunc(ctx, id)
 }
 
+func (s *Service) GetUserMFA(ctx context.Context) (*fleet.UserMFAStatus, error) {
+	s.mu.Lock()
+	s.GetUserMFAFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetUserMFAFunc(ctx)
+}
+
+func (s *Service) BeginTOTPEnrollment(ctx context.Context) (*fleet.TOTPEnrollment, error) {
+	s.mu.Lock()
+	s.BeginTOTPEnrollmentFuncInvoked = true
+	s.mu.Unlock()
+	return s.BeginTOTPEnrollmentFunc(ctx)
+}
+
+func (s *Service) ConfirmTOTPEnrollment(ctx context.Context, code string) (recoveryCodes []string, err error) {
+	s.mu.Lock()
+	s.ConfirmTOTPEnrollmentFuncInvoked = true
+	s.mu.Unlock()
+	return s.ConfirmTOTPEnrollmentFunc(ctx, code)
+}
+
+func (s *Service) DisableTOTP(ctx context.Context, password string) error {
+	s.mu.Lock()
+	s.DisableTOTPFuncInvoked = true
+	s.mu.Unlock()
+	return s.DisableTOTPFunc(ctx, password)
+}
+
+func (s *Service) BeginWebAuthnRegistration(ctx context.Context) (*fleet.WebAuthnRegistration, error) {
+	s.mu.Lock()
+	s.BeginWebAuthnRegistrationFuncInvoked = true
+	s.mu.Unlock()
+	return s.BeginWebAuthnRegistrationFunc(ctx)
+}
+
+func (s *Service) FinishWebAuthnRegistration(ctx context.Context, token string, name string, resp mfa.RegistrationResponse) (*fleet.WebAuthnCredential, []string, error) {
+	s.mu.Lock()
+	s.FinishWebAuthnRegistrationFuncInvoked = true
+	s.mu.Unlock()
+	return s.FinishWebAuthnRegistrationFunc(ctx, token, name, resp)
+}
+
+func (s *Service) DeleteWebAuthnCredential(ctx context.Context, id uint, password string) error {
+	s.mu.Lock()
+	s.DeleteWebAuthnCredentialFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteWebAuthnCredentialFunc(ctx, id, password)
+}
+
+func (s *Service) RegenerateMFARecoveryCodes(ctx context.Context, password string) ([]string, error) {
+	s.mu.Lock()
+	s.RegenerateMFARecoveryCodesFuncInvoked = true
+	s.mu.Unlock()
+	return s.RegenerateMFARecoveryCodesFunc(ctx, password)
+}
+
+func (s *Service) ResetUserMFA(ctx context.Context, userID uint) error {
+	s.mu.Lock()
+	s.ResetUserMFAFuncInvoked = true
+	s.mu.Unlock()
+	return s.ResetUserMFAFunc(ctx, userID)
+}
+
 func (s *Service) InitiateSSO(ctx context.Context, redirectURL string) (sessionID string, sessionDurationSeconds int, idpURL string, err error) {
 	s.mu.Lock()
 	s.InitiateSSOFuncInvoked = true
@@ -2769,6 +3104,13 @@ func (s *Service) InitSSOCallback(ctx context.Context, sessionID string, samlRes
 	return s.InitSSOCallbackFunc(ctx, sessionID, samlResponse)
 }
 
+func (s *Service) InitOIDCCallback(ctx context.Context, sessionID string, state string, code string, idpError string) (auth fleet.Auth, redirectURL string, err error) {
+	s.mu.Lock()
+	s.InitOIDCCallbackFuncInvoked = true
+	s.mu.Unlock()
+	return s.InitOIDCCallbackFunc(ctx, sessionID, state, code, idpError)
+}
+
 func (s *Service) MDMSSOCallback(ctx context.Context, sessionID string, samlResponse []byte) (redirectURL string, byodCookieValue string) {
 	s.mu.Lock()
 	s.MDMSSOCallbackFuncInvoked = true
@@ -2832,6 +3174,13 @@ func (s *Service) CompleteMFA(ctx context.Context, token string) (*fleet.Session
 	return s.CompleteMFAFunc(ctx, token)
 }
 
+func (s *Service) CompleteLoginMFA(ctx context.Context, v fleet.MFAVerification) (*fleet.Session, *fleet.User, []string, error) {
+	s.mu.Lock()
+	s.CompleteLoginMFAFuncInvoked = true
+	s.mu.Unlock()
+	return s.CompleteLoginMFAFunc(ctx, v)
+}
+
 func (s *Service) DestroySession(ctx context.Context) (err error) {
 	s.mu.Lock()
 	s.DestroySessionFuncInvoked = true
@@ -3077,6 +3426,20 @@ func (s *Service) GetQueryReportResults(ctx context.Context, id uint, teamID *ui
 	return s.GetQueryReportResultsFunc(ctx, id, teamID)
 }
 
+func (s *Service) GetQueryReportHistory(ctx context.Context, id uint, teamID *uint, from time.Time, to time.Time) ([]fleet.HostQueryResultRow, error) {
+	s.mu.Lock()
+	s.GetQueryReportHistoryFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetQueryReportHistoryFunc(ctx, id, teamID, from, to)
+}
+
+func (s *Service) DiffQueryReport(ctx context.Context, id uint, teamID *uint, from time.Time, to time.Time) (*fleet.QueryReportDiff, error) {
+	s.mu.Lock()
+	s.DiffQueryReportFuncInvoked = true
+	s.mu.Unlock()
+	return s.DiffQueryReportFunc(ctx, id, teamID, from, to)
+}
+
 func (s *Service) GetHostQueryReportResults(ctx context.Context, hid uint, queryID uint) (rows []fleet.HostQueryReportResult, lastFetched *time.Time, err error) {
 	s.mu.Lock()
 	s.GetHostQueryReportResultsFuncInvoked = true
@@ -3147,6 +3510,48 @@ func (s *Service) NewDistributedQueryCampaign(ctx context.Context, queryString s
 	return s.NewDistributedQueryCampaignFunc(ctx, queryString, queryID, targets)
 }
 
+func (s *Service) NewDistributedQueryCampaignWithOptions(ctx context.Context, queryString string, queryID *uint, targets fleet.HostTargets, opts fleet.DistributedQueryCampaignOptions) (*fleet.DistributedQueryCampaign, error) {
+	s.mu.Lock()
+	s.NewDistributedQueryCampaignWithOptionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewDistributedQueryCampaignWithOptionsFunc(ctx, queryString, queryID, targets, opts)
+}
+
+func (s *Service) NewDistributedQueryCampaignByIdentifiersWithOptions(ctx context.Context, queryString string, queryID *uint, hosts []string, labels []string, opts fleet.DistributedQueryCampaignOptions) (*fleet.DistributedQueryCampaign, error) {
+	s.mu.Lock()
+	s.NewDistributedQueryCampaignByIdentifiersWithOptionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.NewDistributedQueryCampaignByIdentifiersWithOptionsFunc(ctx, queryString, queryID, hosts, labels, opts)
+}
+
+func (s *Service) GetQueryCampaign(ctx context.Context, id uint) (*fleet.QueryCampaignProgress, error) {
+	s.mu.Lock()
+	s.GetQueryCampaignFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetQueryCampaignFunc(ctx, id)
+}
+
+func (s *Service) ListQueryCampaigns(ctx context.Context, queryID *uint, opts fleet.ListOptions) ([]*fleet.QueryCampaignSummary, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListQueryCampaignsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListQueryCampaignsFunc(ctx, queryID, opts)
+}
+
+func (s *Service) ListCampaignQueryResults(ctx context.Context, id uint, opts fleet.ListOptions) ([]*fleet.CampaignQueryResult, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListCampaignQueryResultsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListCampaignQueryResultsFunc(ctx, id, opts)
+}
+
+func (s *Service) DiffCampaignQueryResults(ctx context.Context, id uint, previousID uint) (*fleet.QueryCampaignResultsDiff, error) {
+	s.mu.Lock()
+	s.DiffCampaignQueryResultsFuncInvoked = true
+	s.mu.Unlock()
+	return s.DiffCampaignQueryResultsFunc(ctx, id, previousID)
+}
+
 func (s *Service) StreamCampaignResults(ctx context.Context, conn *websocket.Conn, campaignID uint) {
 	s.mu.Lock()
 	s.StreamCampaignResultsFuncInvoked = true
@@ -3420,6 +3825,13 @@ func (s *Service) EscrowLUKSData(ctx context.Context, passphrase string, salt st
 	return s.EscrowLUKSDataFunc(ctx, passphrase, salt, keySlot, clientError, keyType)
 }
 
+func (s *Service) ReportCacheProxyStats(ctx context.Context, stats fleet.CacheProxyStats) error {
+	s.mu.Lock()
+	s.ReportCacheProxyStatsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ReportCacheProxyStatsFunc(ctx, stats)
+}
+
 func (s *Service) EscrowWindowsManagedLocalAccountPassword(ctx context.Context, password string, clientError string) error {
 	s.mu.Lock()
 	s.EscrowWindowsManagedLocalAccountPasswordFuncInvoked = true
@@ -4113,6 +4525,20 @@ func (s *Service) SaveHostSoftwareInstallResult(ctx context.Context, result *fle
 	return s.SaveHostSoftwareInstallResultFunc(ctx, result)
 }
 
+func (s *Service) HostSBOM(ctx context.Context, hostID uint, format fleet.SBOMFormat) ([]byte, error) {
+	s.mu.Lock()
+	s.HostSBOMFuncInvoked = true
+	s.mu.Unlock()
+	return s.HostSBOMFunc(ctx, hostID, format)
+}
+
+func (s *Service) TeamSBOM(ctx context.Context, teamID uint, format fleet.SBOMFormat) ([]byte, error) {
+	s.mu.Lock()
+	s.TeamSBOMFuncInvoked = true
+	s.mu.Unlock()
+	return s.TeamSBOMFunc(ctx, teamID, format)
+}
+
 func (s *Service) ListSoftwareTitles(ctx context.Context, opt fleet.SoftwareTitleListOptions) ([]fleet.SoftwareTitleListResult, int, *fleet.PaginationMetadata, error) {
 	s.mu.Lock()
 	s.ListSoftwareTitlesFuncInvoked = true
@@ -5436,6 +5862,13 @@ func (s *Service) OrbitDownloadSoftwareInstaller(ctx context.Context, installerI
 	return s.OrbitDownloadSoftwareInstallerFunc(ctx, installerID)
 }
 
+func (s *Service) OrbitCacheProxyDownloadSoftwareInstaller(ctx context.Context, hashSHA256 string) (*fleet.DownloadSoftwareInstallerPayload, error) {
+	s.mu.Lock()
+	s.OrbitCacheProxyDownloadSoftwareInstallerFuncInvoked = true
+	s.mu.Unlock()
+	return s.OrbitCacheProxyDownloadSoftwareInstallerFunc(ctx, hashSHA256)
+}
+
 func (s *Service) GetSoftwareTitleIcon(ctx context.Context, teamID uint, titleID uint) ([]byte, int64, string, error) {
 	s.mu.Lock()
 	s.GetSoftwareTitleIconFuncInvoked = true
@@ -5688,6 +6121,174 @@ func (s *Service) UpsertCustomHostVitals(ctx context.Context, customHostVitals [
 	return s.UpsertCustomHostVitalsFunc(ctx, customHostVitals, dryRun)
 }
 
+func (s *Service) ListCustomRoles(ctx context.Context) ([]fleet.CustomRole, error) {
+	s.mu.Lock()
+	s.ListCustomRolesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListCustomRolesFunc(ctx)
+}
+
+func (s *Service) GetCustomRole(ctx context.Context, id uint) (*fleet.CustomRole, error) {
+	s.mu.Lock()
+	s.GetCustomRoleFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetCustomRoleFunc(ctx, id)
+}
+
+func (s *Service) CreateCustomRole(ctx context.Context, p fleet.CustomRolePayload) (*fleet.CustomRole, error) {
+	s.mu.Lock()
+	s.CreateCustomRoleFuncInvoked = true
+	s.mu.Unlock()
+	return s.CreateCustomRoleFunc(ctx, p)
+}
+
+func (s *Service) ModifyCustomRole(ctx context.Context, id uint, p fleet.CustomRolePayload) (*fleet.CustomRole, error) {
+	s.mu.Lock()
+	s.ModifyCustomRoleFuncInvoked = true
+	s.mu.Unlock()
+	return s.ModifyCustomRoleFunc(ctx, id, p)
+}
+
+func (s *Service) DeleteCustomRole(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	s.DeleteCustomRoleFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteCustomRoleFunc(ctx, id)
+}
+
+func (s *Service) SetUserCustomRoles(ctx context.Context, userID uint, roles []fleet.UserCustomRole) (*fleet.User, error) {
+	s.mu.Lock()
+	s.SetUserCustomRolesFuncInvoked = true
+	s.mu.Unlock()
+	return s.SetUserCustomRolesFunc(ctx, userID, roles)
+}
+
+func (s *Service) ApplyCustomRoles(ctx context.Context, specs []fleet.CustomRoleSpec, dryRun bool) error {
+	s.mu.Lock()
+	s.ApplyCustomRolesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ApplyCustomRolesFunc(ctx, specs, dryRun)
+}
+
+func (s *Service) UploadVEXDocument(ctx context.Context, name string, r io.Reader) (*fleet.VEXDocument, error) {
+	s.mu.Lock()
+	s.UploadVEXDocumentFuncInvoked = true
+	s.mu.Unlock()
+	return s.UploadVEXDocumentFunc(ctx, name, r)
+}
+
+func (s *Service) ListVEXDocuments(ctx context.Context) ([]*fleet.VEXDocument, error) {
+	s.mu.Lock()
+	s.ListVEXDocumentsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListVEXDocumentsFunc(ctx)
+}
+
+func (s *Service) DeleteVEXDocument(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	s.DeleteVEXDocumentFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteVEXDocumentFunc(ctx, id)
+}
+
+func (s *Service) ListVEXSuppressions(ctx context.Context, opts fleet.VEXSuppressionListOptions) ([]fleet.VEXSuppression, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListVEXSuppressionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListVEXSuppressionsFunc(ctx, opts)
+}
+
+func (s *Service) ListVulnerabilityExceptions(ctx context.Context, opts fleet.VulnerabilityExceptionListOptions) ([]fleet.VulnerabilityException, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListVulnerabilityExceptionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListVulnerabilityExceptionsFunc(ctx, opts)
+}
+
+func (s *Service) GetVulnerabilityException(ctx context.Context, id uint) (*fleet.VulnerabilityException, error) {
+	s.mu.Lock()
+	s.GetVulnerabilityExceptionFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetVulnerabilityExceptionFunc(ctx, id)
+}
+
+func (s *Service) CreateVulnerabilityException(ctx context.Context, p fleet.VulnerabilityExceptionPayload) (*fleet.VulnerabilityException, error) {
+	s.mu.Lock()
+	s.CreateVulnerabilityExceptionFuncInvoked = true
+	s.mu.Unlock()
+	return s.CreateVulnerabilityExceptionFunc(ctx, p)
+}
+
+func (s *Service) ModifyVulnerabilityException(ctx context.Context, id uint, p fleet.VulnerabilityExceptionPayload) (*fleet.VulnerabilityException, error) {
+	s.mu.Lock()
+	s.ModifyVulnerabilityExceptionFuncInvoked = true
+	s.mu.Unlock()
+	return s.ModifyVulnerabilityExceptionFunc(ctx, id, p)
+}
+
+func (s *Service) DeleteVulnerabilityException(ctx context.Context, id uint) error {
+	s.mu.Lock()
+	s.DeleteVulnerabilityExceptionFuncInvoked = true
+	s.mu.Unlock()
+	return s.DeleteVulnerabilityExceptionFunc(ctx, id)
+}
+
+func (s *Service) ApplyVulnerabilityExceptions(ctx context.Context, specs []fleet.VulnerabilityExceptionSpec, dryRun bool) error {
+	s.mu.Lock()
+	s.ApplyVulnerabilityExceptionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ApplyVulnerabilityExceptionsFunc(ctx, specs, dryRun)
+}
+
+func (s *Service) ExpireVulnerabilityExceptions(ctx context.Context) error {
+	s.mu.Lock()
+	s.ExpireVulnerabilityExceptionsFuncInvoked = true
+	s.mu.Unlock()
+	return s.ExpireVulnerabilityExceptionsFunc(ctx)
+}
+
+func (s *Service) ListWebhookDeliveries(ctx context.Context, opts fleet.WebhookDeliveryListOptions) ([]fleet.WebhookDelivery, *fleet.PaginationMetadata, error) {
+	s.mu.Lock()
+	s.ListWebhookDeliveriesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListWebhookDeliveriesFunc(ctx, opts)
+}
+
+func (s *Service) GetWebhookDelivery(ctx context.Context, id uint) (*fleet.WebhookDelivery, error) {
+	s.mu.Lock()
+	s.GetWebhookDeliveryFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetWebhookDeliveryFunc(ctx, id)
+}
+
+func (s *Service) RedeliverWebhookDelivery(ctx context.Context, id uint) (*fleet.WebhookDelivery, error) {
+	s.mu.Lock()
+	s.RedeliverWebhookDeliveryFuncInvoked = true
+	s.mu.Unlock()
+	return s.RedeliverWebhookDeliveryFunc(ctx, id)
+}
+
+func (s *Service) SaveGitOpsSnapshot(ctx context.Context, teamID *uint) (*fleet.GitOpsSnapshot, error) {
+	s.mu.Lock()
+	s.SaveGitOpsSnapshotFuncInvoked = true
+	s.mu.Unlock()
+	return s.SaveGitOpsSnapshotFunc(ctx, teamID)
+}
+
+func (s *Service) GetGitOpsDrift(ctx context.Context, teamID *uint) ([]*fleet.GitOpsDrift, error) {
+	s.mu.Lock()
+	s.GetGitOpsDriftFuncInvoked = true
+	s.mu.Unlock()
+	return s.GetGitOpsDriftFunc(ctx, teamID)
+}
+
+func (s *Service) ListCacheProxies(ctx context.Context, teamID *uint) ([]*fleet.HostCacheProxy, error) {
+	s.mu.Lock()
+	s.ListCacheProxiesFuncInvoked = true
+	s.mu.Unlock()
+	return s.ListCacheProxiesFunc(ctx, teamID)
+}
+
 func (s *Service) ListAPIEndpoints(ctx context.Context) (endpoints []fleet.APIEndpoint, err error) {
 	s.mu.Lock()
 	s.ListAPIEndpointsFuncInvoked = true
@@ -1,16 +1,9 @@
 package http
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
-	"io"
 	"log/slog"
-	"net/http"
-	"time"
-
-	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
 )
 
 // errWithStatus is an error with a particular status code.
@@ -43,30 +36,6 @@ func PostJSONWithTimeout(ctx context.Context, url string, v any, logger *slog.Lo
 		return err
 	}
 
-	client := fleethttp.NewClient(fleethttp.WithTimeout(30 * time.Second))
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(jsonBytes))
-	if err != nil {
-		return err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to POST to %s: %s, request-size=%d", MaskSecretURLParams(url), MaskURLError(err), len(jsonBytes))
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode < 200 || resp.StatusCode > 299 {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
-		bodyStr := string(body)
-		logger.DebugContext(ctx, "non-success response from POST",
-			"url", MaskSecretURLParams(url),
-			"status_code", resp.StatusCode,
-			"body", bodyStr,
-		)
-		return &errWithStatus{err: fmt.Sprintf("error posting to %s", MaskSecretURLParams(url)), statusCode: resp.StatusCode, body: bodyStr}
-	}
-
-	return nil
+	_, err = PostWebhook(ctx, WebhookRequest{URL: url, Body: jsonBytes}, logger)
+	return err
 }
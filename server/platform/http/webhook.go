@@ -0,0 +1,160 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
+)
+
+const (
+	// WebhookSignatureHeader is the header carrying the signature of a webhook
+	// delivery, in the format "t=<unix timestamp>,v1=<hex HMAC-SHA256>". The
+	// HMAC is computed with the webhook secret over "<timestamp>.<body>".
+	WebhookSignatureHeader = "X-Fleet-Signature"
+	// WebhookDeliveryIDHeader is the header carrying the ID of a webhook
+	// delivery, which stays the same across retries so receivers can
+	// deduplicate them.
+	WebhookDeliveryIDHeader = "X-Fleet-Delivery-Id"
+
+	webhookTimeout = 30 * time.Second
+)
+
+// WebhookRequest is a webhook delivery to POST.
+type WebhookRequest struct {
+	URL        string
+	Body       []byte
+	DeliveryID string
+	// Secret is used to sign the body, it is not signed if empty.
+	Secret string
+	// Headers are custom headers to add to the request.
+	Headers map[string]string
+}
+
+// WebhookResult is the result of a webhook delivery attempt.
+type WebhookResult struct {
+	// StatusCode is the status code of the response, 0 if no response was
+	// received.
+	StatusCode int
+	// Latency is the time it took to get the response (or the error).
+	Latency time.Duration
+}
+
+// SignWebhookPayload returns the value of the WebhookSignatureHeader for the
+// body signed with the secret at the given time.
+func SignWebhookPayload(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, computeWebhookSignature(secret, ts, body))
+}
+
+func computeWebhookSignature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks that the signature header value was computed
+// with the secret over the body, and that its timestamp is within tolerance of
+// now (the check is skipped if tolerance is 0). It is what webhook receivers
+// are expected to do, and is provided for tests and Go receivers.
+func VerifyWebhookSignature(secret, signature string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts string
+	var sigs []string
+	for part := range strings.SplitSeq(signature, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sigs = append(sigs, v)
+		}
+	}
+	if ts == "" || len(sigs) == 0 {
+		return errors.New("malformed webhook signature")
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("malformed webhook signature timestamp: %w", err)
+	}
+	if tolerance > 0 {
+		if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+			return errors.New("webhook signature timestamp outside of tolerance")
+		}
+	}
+	expected := computeWebhookSignature(secret, ts, body)
+	for _, sig := range sigs {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return errors.New("webhook signature mismatch")
+}
+
+// PostWebhook POSTs the JSON body of a webhook delivery with a 30-second
+// timeout, signing it if a secret is set. It returns an error with the status
+// code and (truncated) body of the response if it is not a 2xx.
+func PostWebhook(ctx context.Context, r WebhookRequest, logger *slog.Logger) (WebhookResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(r.Body))
+	if err != nil {
+		return WebhookResult{}, fmt.Errorf("create request to %s: %w", MaskSecretURLParams(r.URL), MaskURLError(err))
+	}
+
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if r.DeliveryID != "" {
+		req.Header.Set(WebhookDeliveryIDHeader, r.DeliveryID)
+	}
+	if r.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(r.Secret, time.Now(), r.Body))
+	}
+
+	client := fleethttp.NewClient(fleethttp.WithTimeout(webhookTimeout))
+	start := time.Now()
+	resp, err := client.Do(req)
+	result := WebhookResult{Latency: time.Since(start)}
+	if err != nil {
+		return result, fmt.Errorf("failed to POST to %s: %s, request-size=%d", MaskSecretURLParams(r.URL), MaskURLError(err), len(r.Body))
+	}
+	defer resp.Body.Close()
+	result.StatusCode = resp.StatusCode
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		bodyStr := string(body)
+		logger.DebugContext(ctx, "non-success response from webhook",
+			"url", MaskSecretURLParams(r.URL),
+			"status_code", resp.StatusCode,
+			"body", bodyStr,
+		)
+		return result, &errWithStatus{err: fmt.Sprintf("error posting to %s", MaskSecretURLParams(r.URL)), statusCode: resp.StatusCode, body: bodyStr}
+	}
+	return result, nil
+}
+
+// IsRetryableWebhookStatus returns true if a webhook delivery that got the
+// given status code (0 for no response) may succeed if retried.
+func IsRetryableWebhookStatus(statusCode int) bool {
+	switch {
+	case statusCode == 0,
+		statusCode == http.StatusRequestTimeout,
+		statusCode == http.StatusTooManyRequests,
+		statusCode >= 500:
+		return true
+	}
+	return false
+}
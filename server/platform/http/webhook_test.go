@@ -0,0 +1,88 @@
+package http
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"text":"hello"}`)
+
+	sig := SignWebhookPayload("s3cr3t", now, body)
+	assert.Regexp(t, `^t=1700000000,v1=[0-9a-f]{64}$`, sig)
+
+	require.NoError(t, VerifyWebhookSignature("s3cr3t", sig, body, 5*time.Minute, now.Add(time.Minute)))
+	require.NoError(t, VerifyWebhookSignature("s3cr3t", sig, body, 0, now.Add(time.Hour)))
+	require.ErrorContains(t, VerifyWebhookSignature("other", sig, body, 0, now), "mismatch")
+	require.ErrorContains(t, VerifyWebhookSignature("s3cr3t", sig, []byte(`{"text":"bye"}`), 0, now), "mismatch")
+	require.ErrorContains(t, VerifyWebhookSignature("s3cr3t", sig, body, 5*time.Minute, now.Add(time.Hour)), "tolerance")
+	require.ErrorContains(t, VerifyWebhookSignature("s3cr3t", "v1=abc", body, 0, now), "malformed")
+}
+
+func TestPostWebhook(t *testing.T) {
+	var got *http.Request
+	var gotBody []byte
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("response"))
+	}))
+	defer srv.Close()
+
+	body := []byte(`{"a":1}`)
+	res, err := PostWebhook(t.Context(), WebhookRequest{
+		URL:        srv.URL,
+		Body:       body,
+		DeliveryID: "42",
+		Secret:     "s3cr3t",
+		Headers:    map[string]string{"Authorization": "Bearer token"},
+	}, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Positive(t, res.Latency)
+
+	assert.Equal(t, body, gotBody)
+	assert.Equal(t, "application/json", got.Header.Get("Content-Type"))
+	assert.Equal(t, "Bearer token", got.Header.Get("Authorization"))
+	assert.Equal(t, "42", got.Header.Get(WebhookDeliveryIDHeader))
+	require.NoError(t, VerifyWebhookSignature("s3cr3t", got.Header.Get(WebhookSignatureHeader), gotBody, time.Minute, time.Now()))
+
+	// unsigned without a secret
+	_, err = PostWebhook(t.Context(), WebhookRequest{URL: srv.URL, Body: body}, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	assert.Empty(t, got.Header.Get(WebhookSignatureHeader))
+
+	status = http.StatusServiceUnavailable
+	res, err = PostWebhook(t.Context(), WebhookRequest{URL: srv.URL, Body: body}, slog.New(slog.DiscardHandler))
+	require.Error(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	statusErr, ok := errors.AsType[*errWithStatus](err)
+	require.True(t, ok)
+	assert.Equal(t, "response", statusErr.Body())
+}
+
+func TestIsRetryableWebhookStatus(t *testing.T) {
+	for code, retryable := range map[int]bool{
+		0:                              true,
+		http.StatusRequestTimeout:      true,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+		http.StatusBadRequest:          false,
+		http.StatusUnauthorized:        false,
+		http.StatusNotFound:            false,
+	} {
+		assert.Equal(t, retryable, IsRetryableWebhookStatus(code), code)
+	}
+}
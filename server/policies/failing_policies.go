@@ -19,9 +19,10 @@ type FailingPolicyAutomationType string
 
 // List of supported failing policy automation types.
 const (
-	FailingPolicyWebhook FailingPolicyAutomationType = "webhook"
-	FailingPolicyJira    FailingPolicyAutomationType = "jira"
-	FailingPolicyZendesk FailingPolicyAutomationType = "zendesk"
+	FailingPolicyWebhook    FailingPolicyAutomationType = "webhook"
+	FailingPolicyJira       FailingPolicyAutomationType = "jira"
+	FailingPolicyZendesk    FailingPolicyAutomationType = "zendesk"
+	FailingPolicyServiceNow FailingPolicyAutomationType = "servicenow"
 )
 
 // FailingPolicyAutomationConfig holds the configuration for processing a
@@ -287,5 +288,97 @@ func getActiveAutomation(webhook fleet.FailingPoliciesWebhookSettings, intgs fle
 			return FailingPolicyZendesk
 		}
 	}
+
+	// check for servicenow integrations
+	for _, sn := range intgs.ServiceNow {
+		if sn.EnableFailingPolicies {
+			return FailingPolicyServiceNow
+		}
+	}
 	return ""
 }
+
+// TriggerPassingPoliciesAutomation calls sendFunc for each of the openPolicyIDs
+// that has the provided automation type enabled and that currently passes on
+// all hosts it ran on. It is used by automations that keep track of an
+// external state for failing policies (e.g. an open incident) and need to
+// resolve it once the policy passes again: openPolicyIDs are the policies
+// with such a state, so that the other passing policies are not sent on every
+// run.
+func TriggerPassingPoliciesAutomation(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger *slog.Logger,
+	automationType FailingPolicyAutomationType,
+	openPolicyIDs []uint,
+	sendFunc func(*fleet.Policy) error,
+) error {
+	if len(openPolicyIDs) == 0 {
+		return nil
+	}
+	open := make(map[uint]bool, len(openPolicyIDs))
+	for _, id := range openPolicyIDs {
+		open[id] = true
+	}
+
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "getting app config")
+	}
+
+	var policyIDs []uint
+	addPolicyIDs := func(cfg FailingPolicyAutomationConfig) {
+		if cfg.AutomationType != automationType {
+			return
+		}
+		for pID := range cfg.PolicyIDs {
+			if open[pID] {
+				policyIDs = append(policyIDs, pID)
+			}
+		}
+	}
+
+	globalCfg, err := buildFailingPolicyAutomationConfig(appConfig.WebhookSettings.FailingPoliciesWebhook, appConfig.Integrations)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "build global automation config")
+	}
+	addPolicyIDs(globalCfg)
+
+	// errors for the default team and the teams are logged and the
+	// corresponding policies skipped, so that one misconfigured team does not
+	// prevent the others from being processed.
+	if defaultCfg, err := makeDefaultTeamConfigCache(ds, appConfig.Integrations, logger)(ctx); err == nil {
+		addPolicyIDs(defaultCfg)
+	}
+
+	teams, err := ds.TeamsSummary(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list teams")
+	}
+	getTeam := makeTeamConfigCache(ds, appConfig.Integrations)
+	for _, team := range teams {
+		teamCfg, err := getTeam(ctx, team.ID)
+		if err != nil {
+			logger.ErrorContext(ctx, "failed to get team automation config", "teamID", team.ID, "err", err)
+			continue
+		}
+		addPolicyIDs(teamCfg)
+	}
+
+	if len(policyIDs) == 0 {
+		return nil
+	}
+	policies, err := ds.PoliciesByID(ctx, policyIDs)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get policies by ID")
+	}
+	for _, policy := range policies {
+		if policy.FailingHostCount > 0 || policy.PassingHostCount == 0 {
+			continue
+		}
+		if err := sendFunc(policy); err != nil {
+			logger.ErrorContext(ctx, "failed to send passing policy", "policyID", policy.ID, "err", err)
+		}
+	}
+	return nil
+}
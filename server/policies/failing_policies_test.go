@@ -261,3 +261,67 @@ func TestTriggerFailingPolicies(t *testing.T) {
 	// order of calls is undefined
 	require.ElementsMatch(t, wantCalls, triggerCalls)
 }
+
+func TestTriggerPassingPolicies(t *testing.T) {
+	ds := new(mock.Store)
+
+	// global policies 1 to 4 have the ServiceNow automation enabled, 5 doesn't
+	ac := &fleet.AppConfig{
+		WebhookSettings: fleet.WebhookSettings{
+			FailingPoliciesWebhook: fleet.FailingPoliciesWebhookSettings{
+				PolicyIDs: []uint{1, 2, 3, 4},
+			},
+		},
+		Integrations: fleet.Integrations{
+			ServiceNow: []*fleet.ServiceNowIntegration{
+				{URL: "https://sn.example.com", Username: "fleet", Password: "secret", EnableFailingPolicies: true},
+			},
+		},
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return ac, nil
+	}
+	ds.DefaultTeamConfigFunc = func(ctx context.Context) (*fleet.TeamConfig, error) {
+		return &fleet.TeamConfig{}, nil
+	}
+	ds.TeamsSummaryFunc = func(ctx context.Context) ([]*fleet.TeamSummary, error) {
+		return nil, nil
+	}
+	var gotIDs []uint
+	ds.PoliciesByIDFunc = func(ctx context.Context, ids []uint) (map[uint]*fleet.Policy, error) {
+		gotIDs = append(gotIDs, ids...)
+		pols := map[uint]*fleet.Policy{
+			1: {PolicyData: fleet.PolicyData{ID: 1}, PassingHostCount: 2},
+			2: {PolicyData: fleet.PolicyData{ID: 2}, PassingHostCount: 2},
+			3: {PolicyData: fleet.PolicyData{ID: 3}, PassingHostCount: 1, FailingHostCount: 1},
+			4: {PolicyData: fleet.PolicyData{ID: 4}},
+			5: {PolicyData: fleet.PolicyData{ID: 5}, PassingHostCount: 2},
+		}
+		res := make(map[uint]*fleet.Policy, len(ids))
+		for _, id := range ids {
+			res[id] = pols[id]
+		}
+		return res, nil
+	}
+
+	trigger := func(openPolicyIDs []uint) []uint {
+		var sent []uint
+		err := TriggerPassingPoliciesAutomation(t.Context(), ds, slog.New(slog.DiscardHandler), FailingPolicyServiceNow, openPolicyIDs,
+			func(pol *fleet.Policy) error {
+				sent = append(sent, pol.ID)
+				return nil
+			})
+		require.NoError(t, err)
+		return sent
+	}
+
+	// without open incidents, nothing is loaded nor sent
+	require.Empty(t, trigger(nil))
+	require.False(t, ds.AppConfigFuncInvoked)
+	require.False(t, ds.PoliciesByIDFuncInvoked)
+
+	// policy 2 passes but has no open incident, 3 still fails, 4 never ran
+	// and 5 doesn't have the automation enabled
+	require.Equal(t, []uint{1}, trigger([]uint{1, 3, 4, 5}))
+	require.ElementsMatch(t, []uint{1, 3, 4}, gotIDs)
+}
@@ -0,0 +1,94 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// orbitSubscribers keeps track of the hosts connected to this Fleet instance
+// and delivers the hints that are sent to them.
+type orbitSubscribers struct {
+	mu   sync.Mutex
+	subs map[uint]map[chan fleet.OrbitNotificationHint]struct{}
+}
+
+func newOrbitSubscribers() *orbitSubscribers {
+	return &orbitSubscribers{subs: make(map[uint]map[chan fleet.OrbitNotificationHint]struct{})}
+}
+
+// add registers a new subscriber for the host, and removes it (closing its
+// channel) when ctx is done. If set, onRemove is called after the removal.
+func (s *orbitSubscribers) add(ctx context.Context, hostID uint, onRemove func()) <-chan fleet.OrbitNotificationHint {
+	// The buffer holds the pending hint, any other one sent before it is read
+	// is dropped as it would trigger the same config fetch.
+	ch := make(chan fleet.OrbitNotificationHint, 1)
+
+	s.mu.Lock()
+	if s.subs[hostID] == nil {
+		s.subs[hostID] = make(map[chan fleet.OrbitNotificationHint]struct{})
+	}
+	s.subs[hostID][ch] = struct{}{}
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.mu.Lock()
+		delete(s.subs[hostID], ch)
+		if len(s.subs[hostID]) == 0 {
+			delete(s.subs, hostID)
+		}
+		close(ch)
+		s.mu.Unlock()
+
+		if onRemove != nil {
+			onRemove()
+		}
+	}()
+	return ch
+}
+
+// deliver sends the hint to the subscribers of the hosts, without blocking.
+func (s *orbitSubscribers) deliver(hint fleet.OrbitNotificationHint, hostIDs []uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, hostID := range hostIDs {
+		for ch := range s.subs[hostID] {
+			select {
+			case ch <- hint:
+			default:
+			}
+		}
+	}
+}
+
+func (s *orbitSubscribers) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subs)
+}
+
+type inmemOrbitNotifier struct {
+	subscribers *orbitSubscribers
+}
+
+var _ fleet.OrbitNotifier = &inmemOrbitNotifier{}
+
+// NewInmemOrbitNotifier initializes a new in-memory implementation of the
+// OrbitNotifier interface, which only reaches the hosts connected to the
+// current Fleet instance.
+func NewInmemOrbitNotifier() *inmemOrbitNotifier {
+	return &inmemOrbitNotifier{subscribers: newOrbitSubscribers()}
+}
+
+func (im *inmemOrbitNotifier) Notify(ctx context.Context, hint fleet.OrbitNotificationHint, hostIDs ...uint) error {
+	im.subscribers.deliver(hint, hostIDs)
+	return nil
+}
+
+func (im *inmemOrbitNotifier) Subscribe(ctx context.Context, hostID uint) (<-chan fleet.OrbitNotificationHint, error) {
+	return im.subscribers.add(ctx, hostID, nil), nil
+}
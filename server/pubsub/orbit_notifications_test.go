@@ -0,0 +1,119 @@
+package pubsub
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/datastore/redis/redistest"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrbitNotifier(t *testing.T) {
+	t.Run("inmem", func(t *testing.T) {
+		testOrbitNotifier(t, NewInmemOrbitNotifier(), NewInmemOrbitNotifier)
+	})
+
+	for _, cluster := range []bool{false, true} {
+		name := "standalone"
+		if cluster {
+			name = "cluster"
+		}
+		t.Run(name, func(t *testing.T) {
+			pool := redistest.SetupRedis(t, "zz", cluster, false, cluster)
+			newNotifier := func() *redisOrbitNotifier {
+				return NewRedisOrbitNotifier(pool, slog.New(slog.DiscardHandler))
+			}
+			notifier := newNotifier()
+			testOrbitNotifier(t, notifier, newNotifier)
+
+			// the subscription is dropped once no host is connected anymore
+			require.Eventually(t, func() bool {
+				notifier.listenMu.Lock()
+				defer notifier.listenMu.Unlock()
+				return notifier.stopListening == nil
+			}, 5*time.Second, 50*time.Millisecond)
+		})
+	}
+}
+
+func testOrbitNotifier[N fleet.OrbitNotifier](t *testing.T, notifier N, newNotifier func() N) {
+	ctx := t.Context()
+
+	receive := func(ch <-chan fleet.OrbitNotificationHint) fleet.OrbitNotificationHint {
+		select {
+		case hint := <-ch:
+			return hint
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a hint")
+			return ""
+		}
+	}
+	assertNoHint := func(ch <-chan fleet.OrbitNotificationHint) {
+		select {
+		case hint := <-ch:
+			t.Fatalf("unexpected hint %q", hint)
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+
+	ctx1, cancel1 := context.WithCancel(ctx)
+	host1, err := notifier.Subscribe(ctx1, 1)
+	require.NoError(t, err)
+	host2, err := notifier.Subscribe(ctx, 2)
+	require.NoError(t, err)
+
+	// wait for the subscription to be active before publishing
+	require.Eventually(t, func() bool {
+		require.NoError(t, notifier.Notify(ctx, fleet.OrbitNotificationHintConfig, 2))
+		select {
+		case <-host2:
+			return true
+		case <-time.After(100 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	// drop the hints of the extra attempts
+	for {
+		select {
+		case <-host2:
+			continue
+		case <-time.After(200 * time.Millisecond):
+		}
+		break
+	}
+
+	require.NoError(t, notifier.Notify(ctx, fleet.OrbitNotificationHintScripts, 1))
+	assert.Equal(t, fleet.OrbitNotificationHintScripts, receive(host1))
+	assertNoHint(host2)
+
+	// pending hints are coalesced
+	require.NoError(t, notifier.Notify(ctx, fleet.OrbitNotificationHintSoftwareInstalls, 1, 2, 3))
+	require.NoError(t, notifier.Notify(ctx, fleet.OrbitNotificationHintDiskEncryption, 1))
+	time.Sleep(200 * time.Millisecond) // let both hints be delivered before reading them
+	assert.Equal(t, fleet.OrbitNotificationHintSoftwareInstalls, receive(host1))
+	assert.Equal(t, fleet.OrbitNotificationHintSoftwareInstalls, receive(host2))
+	assertNoHint(host1)
+	assertNoHint(host2)
+
+	// the channel is closed once the host disconnects
+	cancel1()
+	select {
+	case _, ok := <-host1:
+		assert.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the channel to close")
+	}
+
+	// the hints only reach the other instances with a shared backend
+	other := newNotifier()
+	require.NoError(t, other.Notify(ctx, fleet.OrbitNotificationHintConfig, 2))
+	if _, ok := any(notifier).(*inmemOrbitNotifier); ok {
+		assertNoHint(host2)
+	} else {
+		assert.Equal(t, fleet.OrbitNotificationHintConfig, receive(host2))
+	}
+}
@@ -0,0 +1,172 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/datastore/redis"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	redigo "github.com/gomodule/redigo/redis"
+)
+
+const (
+	// orbitNotificationsChannel is the pub/sub channel shared by all the Fleet
+	// instances to exchange the hints sent to fleetd. Each instance has a single
+	// subscription to it, no matter how many hosts are connected to it.
+	orbitNotificationsChannel = "orbit_notifications"
+	// orbitNotificationsPingInterval is how often the subscription is pinged, so
+	// that a broken connection to Redis is detected.
+	orbitNotificationsPingInterval = 30 * time.Second
+	// orbitNotificationsRetryInterval is how long to wait before subscribing
+	// again after the subscription failed.
+	orbitNotificationsRetryInterval = 5 * time.Second
+)
+
+// orbitNotificationMessage is the message published on orbitNotificationsChannel.
+type orbitNotificationMessage struct {
+	Hint    fleet.OrbitNotificationHint `json:"hint"`
+	HostIDs []uint                      `json:"host_ids"`
+}
+
+type redisOrbitNotifier struct {
+	pool        fleet.RedisPool
+	logger      *slog.Logger
+	subscribers *orbitSubscribers
+
+	// listenMu protects stopListening, which is set while this instance is
+	// subscribed to orbitNotificationsChannel, i.e. while hosts are connected.
+	listenMu      sync.Mutex
+	stopListening context.CancelFunc
+}
+
+var _ fleet.OrbitNotifier = &redisOrbitNotifier{}
+
+// NewRedisOrbitNotifier creates a new Redis implementation of the
+// OrbitNotifier interface using the provided Redis connection pool.
+func NewRedisOrbitNotifier(pool fleet.RedisPool, logger *slog.Logger) *redisOrbitNotifier {
+	return &redisOrbitNotifier{
+		pool:        pool,
+		logger:      logger,
+		subscribers: newOrbitSubscribers(),
+	}
+}
+
+func (r *redisOrbitNotifier) Notify(ctx context.Context, hint fleet.OrbitNotificationHint, hostIDs ...uint) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+
+	payload, err := json.Marshal(orbitNotificationMessage{Hint: hint, HostIDs: hostIDs})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal orbit notification")
+	}
+
+	// pub-sub can publish and listen on any node in the cluster
+	conn := redis.ReadOnlyConn(r.pool, r.pool.Get())
+	defer conn.Close()
+
+	if _, err := conn.Do("PUBLISH", orbitNotificationsChannel, payload); err != nil {
+		return ctxerr.Wrap(ctx, err, "publish orbit notification")
+	}
+	return nil
+}
+
+func (r *redisOrbitNotifier) Subscribe(ctx context.Context, hostID uint) (<-chan fleet.OrbitNotificationHint, error) {
+	ch := r.subscribers.add(ctx, hostID, r.stopListeningIfIdle)
+
+	r.listenMu.Lock()
+	defer r.listenMu.Unlock()
+	if r.stopListening == nil {
+		listenCtx, cancel := context.WithCancel(context.Background())
+		r.stopListening = cancel
+		go r.listen(listenCtx)
+	}
+	return ch, nil
+}
+
+// stopListeningIfIdle unsubscribes from orbitNotificationsChannel once no
+// host is connected to this instance anymore.
+func (r *redisOrbitNotifier) stopListeningIfIdle() {
+	r.listenMu.Lock()
+	defer r.listenMu.Unlock()
+	if r.stopListening != nil && r.subscribers.count() == 0 {
+		r.stopListening()
+		r.stopListening = nil
+	}
+}
+
+// listen delivers the hints published on orbitNotificationsChannel to the
+// hosts connected to this instance until ctx is done, subscribing again if
+// the subscription fails.
+func (r *redisOrbitNotifier) listen(ctx context.Context) {
+	for {
+		err := r.receive(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		r.logger.ErrorContext(ctx, "orbit notifications subscription failed, retrying", "err", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(orbitNotificationsRetryInterval):
+		}
+	}
+}
+
+func (r *redisOrbitNotifier) receive(ctx context.Context) error {
+	// pub-sub can publish and listen on any node in the cluster
+	conn := redis.ReadOnlyConn(r.pool, r.pool.Get())
+	psc := &redigo.PubSubConn{Conn: conn}
+	defer psc.Close()
+
+	if err := psc.Subscribe(orbitNotificationsChannel); err != nil {
+		return fmt.Errorf("subscribe to channel %s: %w", orbitNotificationsChannel, err)
+	}
+
+	// Unsubscribe when ctx is done, which ends the receive loop below, and
+	// ping the connection in the meantime so that the receive loop does not
+	// time out on a quiet channel.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(orbitNotificationsPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				psc.Unsubscribe(orbitNotificationsChannel) //nolint:errcheck
+				return
+			case <-ticker.C:
+				if err := psc.Ping(""); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		switch msg := psc.ReceiveWithTimeout(2 * orbitNotificationsPingInterval).(type) {
+		case error:
+			return msg
+		case redigo.Subscription:
+			if msg.Count == 0 {
+				return nil
+			}
+		case redigo.Message:
+			var m orbitNotificationMessage
+			if err := json.Unmarshal(msg.Data, &m); err != nil {
+				r.logger.ErrorContext(ctx, "unmarshal orbit notification", "err", err)
+				continue
+			}
+			r.subscribers.deliver(m.Hint, m.HostIDs)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package sbom
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// The CycloneDX 1.5 JSON format, see
+// https://cyclonedx.org/docs/1.5/json/. Only the fields Fleet fills are
+// declared.
+
+type cdxDocument struct {
+	BOMFormat       string             `json:"bomFormat"`
+	SpecVersion     string             `json:"specVersion"`
+	SerialNumber    string             `json:"serialNumber"`
+	Version         int                `json:"version"`
+	Metadata        cdxMetadata        `json:"metadata"`
+	Components      []cdxComponent     `json:"components"`
+	Vulnerabilities []cdxVulnerability `json:"vulnerabilities,omitempty"`
+}
+
+type cdxMetadata struct {
+	Timestamp  string        `json:"timestamp"`
+	Tools      cdxTools      `json:"tools"`
+	Component  *cdxComponent `json:"component,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Publisher  string        `json:"publisher,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	CPE        string        `json:"cpe,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxVulnerability struct {
+	ID          string        `json:"id"`
+	Source      cdxSource     `json:"source"`
+	Ratings     []cdxRating   `json:"ratings,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Published   string        `json:"published,omitempty"`
+	Affects     []cdxAffect   `json:"affects"`
+	Properties  []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxSource struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+type cdxRating struct {
+	Source   cdxSource `json:"source"`
+	Score    float64   `json:"score"`
+	Severity string    `json:"severity"`
+	Method   string    `json:"method"`
+}
+
+type cdxAffect struct {
+	Ref string `json:"ref"`
+}
+
+func newCycloneDX(in Input) cdxDocument {
+	doc := cdxDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + in.DocumentID,
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: in.Created.UTC().Format(time.RFC3339),
+			Tools: cdxTools{Components: []cdxComponent{{
+				Type:      "application",
+				Publisher: "Fleet Device Management Inc.",
+				Name:      "fleet",
+				Version:   in.ToolVersion,
+			}}},
+		},
+		Components: make([]cdxComponent, 0, len(in.Software)),
+	}
+
+	switch {
+	case in.Host != nil:
+		h := in.Host
+		doc.Metadata.Component = &cdxComponent{
+			Type:   "device",
+			BOMRef: fmt.Sprintf("fleet-host-%d", h.ID),
+			Name:   h.Hostname,
+			Properties: nonEmptyProperties(
+				"fleet:host_id", strconv.FormatUint(uint64(h.ID), 10),
+				"fleet:uuid", h.UUID,
+				"fleet:hardware_serial", h.HardwareSerial,
+				"fleet:platform", h.Platform,
+				"fleet:os_version", h.OSVersion,
+			),
+		}
+	case in.Team != nil:
+		doc.Metadata.Properties = nonEmptyProperties(
+			"fleet:fleet_id", strconv.FormatUint(uint64(in.Team.ID), 10),
+			"fleet:fleet_name", in.name(),
+		)
+	}
+
+	// vulnerabilities are listed once, with all the components they affect
+	vulnIndex := make(map[string]int)
+	for _, s := range in.Software {
+		ref := fmt.Sprintf("fleet-software-%d", s.ID)
+		comp := cdxComponent{
+			Type:      cdxComponentType(s.Source),
+			BOMRef:    ref,
+			Publisher: s.Vendor,
+			Name:      s.Name,
+			Version:   s.Version,
+			CPE:       s.GenerateCPE,
+			PURL:      PackageURL(s, in.distro()),
+			Properties: nonEmptyProperties(
+				"fleet:source", s.Source,
+				"fleet:bundle_identifier", s.BundleIdentifier,
+				"fleet:extension_for", s.ExtensionFor,
+			),
+		}
+		if s.HostsCount > 0 {
+			comp.Properties = append(comp.Properties, cdxProperty{Name: "fleet:hosts_count", Value: strconv.Itoa(s.HostsCount)})
+		}
+		doc.Components = append(doc.Components, comp)
+
+		for _, cve := range s.Vulnerabilities {
+			i, ok := vulnIndex[cve.CVE]
+			if !ok {
+				i = len(doc.Vulnerabilities)
+				vulnIndex[cve.CVE] = i
+				doc.Vulnerabilities = append(doc.Vulnerabilities, newCDXVulnerability(cve))
+			}
+			doc.Vulnerabilities[i].Affects = append(doc.Vulnerabilities[i].Affects, cdxAffect{Ref: ref})
+		}
+	}
+	return doc
+}
+
+func newCDXVulnerability(cve fleet.CVE) cdxVulnerability {
+	nvd := cdxSource{Name: "NVD", URL: "https://nvd.nist.gov/vuln/detail/" + cve.CVE}
+	v := cdxVulnerability{ID: cve.CVE, Source: nvd}
+	if cve.CVSSScore != nil && *cve.CVSSScore != nil {
+		score := **cve.CVSSScore
+		v.Ratings = []cdxRating{{Source: nvd, Score: score, Severity: cvssSeverity(score), Method: "CVSSv3"}}
+	}
+	if cve.Description != nil && *cve.Description != nil {
+		v.Description = **cve.Description
+	}
+	if cve.CVEPublished != nil && *cve.CVEPublished != nil {
+		v.Published = (**cve.CVEPublished).UTC().Format(time.RFC3339)
+	}
+	if cve.EPSSProbability != nil && *cve.EPSSProbability != nil {
+		v.Properties = append(v.Properties, cdxProperty{
+			Name: "fleet:epss_probability", Value: strconv.FormatFloat(**cve.EPSSProbability, 'f', -1, 64),
+		})
+	}
+	if cve.CISAKnownExploit != nil && *cve.CISAKnownExploit != nil {
+		v.Properties = append(v.Properties, cdxProperty{
+			Name: "fleet:cisa_known_exploit", Value: strconv.FormatBool(**cve.CISAKnownExploit),
+		})
+	}
+	return v
+}
+
+// cdxComponentType returns the CycloneDX component type of software from
+// that source: language packages are libraries, everything else is an
+// application.
+func cdxComponentType(source string) string {
+	switch source {
+	case "npm_packages", "python_packages":
+		return "library"
+	default:
+		return "application"
+	}
+}
+
+// nonEmptyProperties builds properties from name/value pairs, skipping empty
+// values.
+func nonEmptyProperties(pairs ...string) []cdxProperty {
+	var props []cdxProperty
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			props = append(props, cdxProperty{Name: pairs[i], Value: pairs[i+1]})
+		}
+	}
+	return props
+}
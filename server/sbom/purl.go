@@ -0,0 +1,177 @@
+package sbom
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// rpmNamespaces maps the prefix of an RPM vendor, lowercased, to the
+// distribution namespace used in package URLs.
+var rpmNamespaces = []struct{ prefix, namespace string }{
+	{"red hat", "redhat"},
+	{"centos", "centos"},
+	{"fedora", "fedora"},
+	{"rocky", "rocky"},
+	{"almalinux", "almalinux"},
+	{"amazon", "amzn"},
+	{"oracle", "oracle"},
+	{"opensuse", "opensuse"},
+	{"suse", "suse"},
+}
+
+// debNamespaces maps a word of a Debian package maintainer, lowercased, to the
+// distribution namespace used in package URLs. Ubuntu is first as its packages
+// may mention their original Debian maintainer.
+var debNamespaces = []struct{ word, namespace string }{
+	{"ubuntu", "ubuntu"},
+	{"debian", "debian"},
+}
+
+// PackageURL returns the package URL (https://github.com/package-url/purl-spec)
+// of the software. distro is the host platform as reported by osquery (e.g.
+// "ubuntu"), used as the namespace of Linux packages. It may be empty, in which
+// case the namespace is derived from the software's vendor when possible, and
+// omitted otherwise.
+func PackageURL(s fleet.Software, distro string) string {
+	p := purl{name: s.Name, version: s.Version}
+
+	switch s.Source {
+	case "deb_packages":
+		p.typ = "deb"
+		p.namespace = debNamespace(s.Vendor, distro)
+		p.addQualifier("arch", s.Arch)
+	case "rpm_packages":
+		p.typ = "rpm"
+		p.namespace = rpmNamespace(s.Vendor, distro)
+		if s.Release != "" {
+			p.version += "-" + s.Release
+		}
+		p.addQualifier("arch", s.Arch)
+	case "pacman_packages":
+		p.typ = "alpm"
+		p.namespace = "arch"
+		p.addQualifier("arch", s.Arch)
+	case "homebrew_packages":
+		p.typ = "brew"
+	case "npm_packages":
+		p.typ = "npm"
+		if scope, name, ok := strings.Cut(s.Name, "/"); ok && strings.HasPrefix(scope, "@") {
+			p.namespace, p.name = scope, name
+		}
+	case "python_packages":
+		// PyPI names are case-insensitive and treat '_' as '-', see
+		// https://peps.python.org/pep-0503/#normalized-names
+		p.typ = "pypi"
+		p.name = strings.ReplaceAll(strings.ToLower(s.Name), "_", "-")
+	case "chrome_extensions":
+		if s.ExtensionID != "" {
+			p.typ = "chrome-extension"
+			p.name = s.ExtensionID
+		}
+	case "firefox_addons":
+		if s.ExtensionID != "" {
+			p.typ = "firefox-addon"
+			p.name = s.ExtensionID
+		}
+	}
+	if p.typ == "" {
+		p.typ = "generic"
+	}
+	return p.String()
+}
+
+func debNamespace(maintainer, distro string) string {
+	m := strings.ToLower(maintainer)
+	for _, ns := range debNamespaces {
+		if strings.Contains(m, ns.word) {
+			return ns.namespace
+		}
+	}
+	return distro
+}
+
+func rpmNamespace(vendor, distro string) string {
+	v := strings.ToLower(vendor)
+	for _, ns := range rpmNamespaces {
+		if strings.HasPrefix(v, ns.prefix) {
+			return ns.namespace
+		}
+	}
+	return distro
+}
+
+type purl struct {
+	typ        string
+	namespace  string
+	name       string
+	version    string
+	qualifiers map[string]string
+}
+
+func (p *purl) addQualifier(key, value string) {
+	if value == "" {
+		return
+	}
+	if p.qualifiers == nil {
+		p.qualifiers = make(map[string]string)
+	}
+	p.qualifiers[key] = value
+}
+
+func (p purl) String() string {
+	var sb strings.Builder
+	sb.WriteString("pkg:")
+	sb.WriteString(p.typ)
+	sb.WriteByte('/')
+	if p.namespace != "" {
+		for _, seg := range strings.Split(p.namespace, "/") {
+			sb.WriteString(purlEscape(seg))
+			sb.WriteByte('/')
+		}
+	}
+	sb.WriteString(purlEscape(p.name))
+	if p.version != "" {
+		sb.WriteByte('@')
+		sb.WriteString(purlEscape(p.version))
+	}
+	if len(p.qualifiers) > 0 {
+		keys := make([]string, 0, len(p.qualifiers))
+		for k := range p.qualifiers {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			if i == 0 {
+				sb.WriteByte('?')
+			} else {
+				sb.WriteByte('&')
+			}
+			sb.WriteString(k)
+			sb.WriteByte('=')
+			sb.WriteString(purlEscape(p.qualifiers[k]))
+		}
+	}
+	return sb.String()
+}
+
+// purlEscape percent-encodes everything but the unreserved characters of
+// RFC 3986 and ':', which the purl spec allows unencoded.
+func purlEscape(s string) string {
+	const hex = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '.', c == '_', c == '~', c == ':':
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(hex[c>>4])
+			sb.WriteByte(hex[c&0x0f])
+		}
+	}
+	return sb.String()
+}
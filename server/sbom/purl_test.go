@@ -0,0 +1,119 @@
+package sbom
+
+import (
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageURL(t *testing.T) {
+	cases := []struct {
+		name     string
+		software fleet.Software
+		distro   string
+		want     string
+	}{
+		{
+			name:     "deb with distro",
+			software: fleet.Software{Name: "curl", Version: "7.81.0-1ubuntu1.16", Source: "deb_packages", Arch: "amd64"},
+			distro:   "ubuntu",
+			want:     "pkg:deb/ubuntu/curl@7.81.0-1ubuntu1.16?arch=amd64",
+		},
+		{
+			name:     "deb without distro",
+			software: fleet.Software{Name: "libc6", Version: "2.36-9+deb12u4", Source: "deb_packages"},
+			want:     "pkg:deb/libc6@2.36-9%2Bdeb12u4",
+		},
+		{
+			name: "deb with Ubuntu maintainer",
+			software: fleet.Software{
+				Name: "curl", Version: "7.81.0-1ubuntu1.16", Source: "deb_packages",
+				Vendor: "Ubuntu Developers <ubuntu-devel-discuss@lists.ubuntu.com>",
+			},
+			want: "pkg:deb/ubuntu/curl@7.81.0-1ubuntu1.16",
+		},
+		{
+			name: "deb with Debian maintainer",
+			software: fleet.Software{
+				Name: "libc6", Version: "2.36-9+deb12u4", Source: "deb_packages",
+				Vendor: "GNU Libc Maintainers <debian-glibc@lists.debian.org>",
+			},
+			want: "pkg:deb/debian/libc6@2.36-9%2Bdeb12u4",
+		},
+		{
+			name:     "deb with unknown maintainer",
+			software: fleet.Software{Name: "code", Version: "1.92.0", Source: "deb_packages", Vendor: "Microsoft Corporation <vscode-linux@microsoft.com>"},
+			distro:   "ubuntu",
+			want:     "pkg:deb/ubuntu/code@1.92.0",
+		},
+		{
+			name:     "deb with epoch",
+			software: fleet.Software{Name: "vim", Version: "2:8.2.3995-1ubuntu2", Source: "deb_packages"},
+			distro:   "ubuntu",
+			want:     "pkg:deb/ubuntu/vim@2:8.2.3995-1ubuntu2",
+		},
+		{
+			name:     "rpm with vendor",
+			software: fleet.Software{Name: "openssl", Version: "3.0.7", Release: "27.el9", Source: "rpm_packages", Vendor: "Red Hat, Inc.", Arch: "x86_64"},
+			distro:   "rhel",
+			want:     "pkg:rpm/redhat/openssl@3.0.7-27.el9?arch=x86_64",
+		},
+		{
+			name:     "rpm with unknown vendor",
+			software: fleet.Software{Name: "zlib", Version: "1.2.11", Source: "rpm_packages", Vendor: "Acme"},
+			distro:   "fedora",
+			want:     "pkg:rpm/fedora/zlib@1.2.11",
+		},
+		{
+			name:     "pacman",
+			software: fleet.Software{Name: "bash", Version: "5.2.026-2", Source: "pacman_packages", Arch: "x86_64"},
+			want:     "pkg:alpm/arch/bash@5.2.026-2?arch=x86_64",
+		},
+		{
+			name:     "homebrew",
+			software: fleet.Software{Name: "wget", Version: "1.24.5", Source: "homebrew_packages"},
+			want:     "pkg:brew/wget@1.24.5",
+		},
+		{
+			name:     "npm",
+			software: fleet.Software{Name: "lodash", Version: "4.17.21", Source: "npm_packages"},
+			want:     "pkg:npm/lodash@4.17.21",
+		},
+		{
+			name:     "scoped npm",
+			software: fleet.Software{Name: "@babel/core", Version: "7.24.0", Source: "npm_packages"},
+			want:     "pkg:npm/%40babel/core@7.24.0",
+		},
+		{
+			name:     "python",
+			software: fleet.Software{Name: "Typing_Extensions", Version: "4.10.0", Source: "python_packages"},
+			want:     "pkg:pypi/typing-extensions@4.10.0",
+		},
+		{
+			name:     "chrome extension",
+			software: fleet.Software{Name: "uBlock Origin", Version: "1.56.0", Source: "chrome_extensions", ExtensionID: "cjpalhdlnbpafiamejdnhcphjbkeiagm"},
+			want:     "pkg:chrome-extension/cjpalhdlnbpafiamejdnhcphjbkeiagm@1.56.0",
+		},
+		{
+			name:     "firefox addon without id",
+			software: fleet.Software{Name: "Dark Reader", Version: "4.9.80", Source: "firefox_addons"},
+			want:     "pkg:generic/Dark%20Reader@4.9.80",
+		},
+		{
+			name:     "macOS app",
+			software: fleet.Software{Name: "Google Chrome.app", Version: "124.0.6367.91", Source: "apps", BundleIdentifier: "com.google.Chrome"},
+			want:     "pkg:generic/Google%20Chrome.app@124.0.6367.91",
+		},
+		{
+			name:     "no version",
+			software: fleet.Software{Name: "tool", Source: "programs"},
+			want:     "pkg:generic/tool",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, PackageURL(c.software, c.distro))
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Package sbom builds software bills of materials, in the CycloneDX and SPDX
+// formats, from the software inventory Fleet collects.
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// Host describes the host an SBOM is generated for.
+type Host struct {
+	ID             uint
+	Hostname       string
+	UUID           string
+	HardwareSerial string
+	Platform       string
+	OSVersion      string
+}
+
+// Team describes the fleet an SBOM is generated for. A zero ID is the hosts
+// that are not assigned to a fleet.
+type Team struct {
+	ID   uint
+	Name string
+}
+
+// Input is the content of an SBOM. Exactly one of Host or Team is set.
+type Input struct {
+	Host *Host
+	Team *Team
+
+	// Software is the software inventory of the host or fleet, with the
+	// vulnerabilities found on each software.
+	Software []fleet.Software
+
+	// DocumentID uniquely identifies the document, it is a UUID.
+	DocumentID string
+	// Created is when the document was generated.
+	Created time.Time
+	// ToolVersion is the version of Fleet that generated the document.
+	ToolVersion string
+	// Namespace is the base URI of SPDX document namespaces, typically the
+	// Fleet server URL.
+	Namespace string
+	// OrgName is the organization name configured in Fleet, if any.
+	OrgName string
+}
+
+// Encode builds the SBOM document in the requested format and encodes it to
+// JSON.
+func Encode(format fleet.SBOMFormat, in Input) ([]byte, error) {
+	var doc any
+	switch format {
+	case fleet.SBOMFormatCycloneDX:
+		doc = newCycloneDX(in)
+	case fleet.SBOMFormatSPDX:
+		doc = newSPDX(in)
+	default:
+		return nil, fmt.Errorf("unsupported SBOM format %q", format)
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal %s document: %w", format, err)
+	}
+	return b, nil
+}
+
+// name returns the human-readable name of the SBOM subject.
+func (in Input) name() string {
+	switch {
+	case in.Host != nil:
+		return in.Host.Hostname
+	case in.Team != nil && in.Team.ID == 0:
+		return "Unassigned"
+	case in.Team != nil:
+		return in.Team.Name
+	}
+	return ""
+}
+
+// distro returns the platform used as namespace of Linux package URLs.
+func (in Input) distro() string {
+	if in.Host != nil {
+		return in.Host.Platform
+	}
+	return ""
+}
+
+// cvssSeverity returns the qualitative severity rating of a CVSS v3 score, see
+// https://nvd.nist.gov/vuln-metrics/cvss.
+func cvssSeverity(score float64) string {
+	switch {
+	case score == 0:
+		return "none"
+	case score < 4:
+		return "low"
+	case score < 7:
+		return "medium"
+	case score < 9:
+		return "high"
+	default:
+		return "critical"
+	}
+}
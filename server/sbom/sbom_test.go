@@ -0,0 +1,160 @@
+package sbom
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testInput() Input {
+	score, epss, exploit := 9.8, 0.42, true
+	published := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	desc := "Heap overflow"
+	scorePtr, epssPtr, exploitPtr, publishedPtr, descPtr := &score, &epss, &exploit, &published, &desc
+	cve := fleet.CVE{
+		CVE:              "CVE-2024-0001",
+		CVSSScore:        &scorePtr,
+		EPSSProbability:  &epssPtr,
+		CISAKnownExploit: &exploitPtr,
+		CVEPublished:     &publishedPtr,
+		Description:      &descPtr,
+	}
+
+	return Input{
+		Software: []fleet.Software{
+			{
+				ID: 1, Name: "curl", Version: "7.81.0", Source: "deb_packages",
+				GenerateCPE:     "cpe:2.3:a:haxx:curl:7.81.0:*:*:*:*:*:*:*",
+				Vulnerabilities: fleet.Vulnerabilities{cve, {CVE: "CVE-2024-0002"}},
+			},
+			{
+				ID: 2, Name: "libcurl4", Version: "7.81.0", Source: "deb_packages", Vendor: "Ubuntu",
+				Vulnerabilities: fleet.Vulnerabilities{cve},
+			},
+			{ID: 3, Name: "lodash", Version: "4.17.21", Source: "npm_packages"},
+		},
+		DocumentID:  "3e671687-395b-41f5-a30f-a58921a69b79",
+		Created:     time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC),
+		ToolVersion: "4.80.0",
+		Namespace:   "https://fleet.example.com/",
+		OrgName:     "Acme",
+	}
+}
+
+func TestCycloneDX(t *testing.T) {
+	in := testInput()
+	in.Host = &Host{ID: 7, Hostname: "web-1", UUID: "abc", Platform: "ubuntu", OSVersion: "Ubuntu 22.04.4 LTS"}
+
+	b, err := Encode(fleet.SBOMFormatCycloneDX, in)
+	require.NoError(t, err)
+	var doc cdxDocument
+	require.NoError(t, json.Unmarshal(b, &doc))
+
+	assert.Equal(t, "CycloneDX", doc.BOMFormat)
+	assert.Equal(t, "1.5", doc.SpecVersion)
+	assert.Equal(t, "urn:uuid:3e671687-395b-41f5-a30f-a58921a69b79", doc.SerialNumber)
+	assert.Equal(t, "2026-10-17T12:00:00Z", doc.Metadata.Timestamp)
+	require.NotNil(t, doc.Metadata.Component)
+	assert.Equal(t, "device", doc.Metadata.Component.Type)
+	assert.Equal(t, "web-1", doc.Metadata.Component.Name)
+	assert.Contains(t, doc.Metadata.Component.Properties, cdxProperty{Name: "fleet:platform", Value: "ubuntu"})
+
+	require.Len(t, doc.Components, 3)
+	assert.Equal(t, "fleet-software-1", doc.Components[0].BOMRef)
+	assert.Equal(t, "pkg:deb/ubuntu/curl@7.81.0", doc.Components[0].PURL)
+	assert.Equal(t, "cpe:2.3:a:haxx:curl:7.81.0:*:*:*:*:*:*:*", doc.Components[0].CPE)
+	assert.Equal(t, "Ubuntu", doc.Components[1].Publisher)
+	assert.Equal(t, "library", doc.Components[2].Type)
+
+	// vulnerabilities are listed once, with all the affected components
+	require.Len(t, doc.Vulnerabilities, 2)
+	v := doc.Vulnerabilities[0]
+	assert.Equal(t, "CVE-2024-0001", v.ID)
+	assert.Equal(t, []cdxAffect{{Ref: "fleet-software-1"}, {Ref: "fleet-software-2"}}, v.Affects)
+	require.Len(t, v.Ratings, 1)
+	assert.Equal(t, 9.8, v.Ratings[0].Score)
+	assert.Equal(t, "critical", v.Ratings[0].Severity)
+	assert.Equal(t, "Heap overflow", v.Description)
+	assert.Equal(t, "2024-01-02T03:04:05Z", v.Published)
+	assert.Contains(t, v.Properties, cdxProperty{Name: "fleet:cisa_known_exploit", Value: "true"})
+
+	// scores are omitted when unknown (e.g. Fleet Free)
+	v = doc.Vulnerabilities[1]
+	assert.Equal(t, "CVE-2024-0002", v.ID)
+	assert.Empty(t, v.Ratings)
+	assert.Equal(t, []cdxAffect{{Ref: "fleet-software-1"}}, v.Affects)
+}
+
+func TestCycloneDXTeam(t *testing.T) {
+	in := testInput()
+	in.Team = &Team{ID: 0}
+
+	b, err := Encode(fleet.SBOMFormatCycloneDX, in)
+	require.NoError(t, err)
+	var doc cdxDocument
+	require.NoError(t, json.Unmarshal(b, &doc))
+
+	assert.Nil(t, doc.Metadata.Component)
+	assert.Contains(t, doc.Metadata.Properties, cdxProperty{Name: "fleet:fleet_name", Value: "Unassigned"})
+	// without a host platform nor a known maintainer, deb packages have no
+	// namespace
+	assert.Equal(t, "pkg:deb/curl@7.81.0", doc.Components[0].PURL)
+}
+
+func TestSPDX(t *testing.T) {
+	in := testInput()
+	in.Host = &Host{ID: 7, Hostname: "web-1", Platform: "ubuntu", OSVersion: "Ubuntu 22.04.4 LTS"}
+
+	b, err := Encode(fleet.SBOMFormatSPDX, in)
+	require.NoError(t, err)
+	var doc spdxDocument
+	require.NoError(t, json.Unmarshal(b, &doc))
+
+	assert.Equal(t, "SPDX-2.3", doc.SPDXVersion)
+	assert.Equal(t, "CC0-1.0", doc.DataLicense)
+	assert.Equal(t, "web-1", doc.Name)
+	assert.Equal(t, "https://fleet.example.com/spdxdocs/host-7-3e671687-395b-41f5-a30f-a58921a69b79", doc.DocumentNamespace)
+	assert.Equal(t, []string{"Tool: fleet-4.80.0", "Organization: Acme"}, doc.CreationInfo.Creators)
+
+	require.Len(t, doc.Packages, 4)
+	assert.Equal(t, "SPDXRef-Host-7", doc.Packages[0].SPDXID)
+	assert.Equal(t, "DEVICE", doc.Packages[0].PrimaryPackagePurpose)
+
+	curl := doc.Packages[1]
+	assert.Equal(t, "SPDXRef-Package-1", curl.SPDXID)
+	assert.Equal(t, "NOASSERTION", curl.DownloadLocation)
+	assert.Equal(t, []spdxExternalRef{
+		{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: "pkg:deb/ubuntu/curl@7.81.0"},
+		{ReferenceCategory: "SECURITY", ReferenceType: "cpe23Type", ReferenceLocator: "cpe:2.3:a:haxx:curl:7.81.0:*:*:*:*:*:*:*"},
+		{ReferenceCategory: "SECURITY", ReferenceType: "advisory", ReferenceLocator: "https://nvd.nist.gov/vuln/detail/CVE-2024-0001"},
+		{ReferenceCategory: "SECURITY", ReferenceType: "advisory", ReferenceLocator: "https://nvd.nist.gov/vuln/detail/CVE-2024-0002"},
+	}, curl.ExternalRefs)
+	assert.Equal(t, "Organization: Ubuntu", doc.Packages[2].Supplier)
+
+	require.Len(t, doc.Relationships, 4)
+	assert.Equal(t, spdxRelationship{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: "SPDXRef-Host-7"}, doc.Relationships[0])
+	assert.Equal(t, spdxRelationship{SPDXElementID: "SPDXRef-Host-7", RelationshipType: "CONTAINS", RelatedSPDXElement: "SPDXRef-Package-1"}, doc.Relationships[1])
+}
+
+func TestSPDXTeam(t *testing.T) {
+	in := testInput()
+	in.Team = &Team{ID: 3, Name: "Workstations"}
+	in.Namespace = ""
+
+	b, err := Encode(fleet.SBOMFormatSPDX, in)
+	require.NoError(t, err)
+	var doc spdxDocument
+	require.NoError(t, json.Unmarshal(b, &doc))
+
+	assert.Equal(t, "Workstations", doc.Name)
+	assert.Equal(t, "https://fleetdm.com/spdxdocs/fleet-3-3e671687-395b-41f5-a30f-a58921a69b79", doc.DocumentNamespace)
+	require.Len(t, doc.Packages, 3)
+	for _, r := range doc.Relationships {
+		assert.Equal(t, "SPDXRef-DOCUMENT", r.SPDXElementID)
+		assert.Equal(t, "DESCRIBES", r.RelationshipType)
+	}
+}
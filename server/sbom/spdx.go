@@ -0,0 +1,144 @@
+package sbom
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The SPDX 2.3 JSON format, see https://spdx.github.io/spdx-spec/v2.3/. SPDX
+// has no vulnerability section, so vulnerabilities are attached to packages as
+// "advisory" security references, as recommended in
+// https://spdx.github.io/spdx-spec/v2.3/how-to-use/#k1-including-security-information-in-a-spdx-document.
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name                  string            `json:"name"`
+	SPDXID                string            `json:"SPDXID"`
+	VersionInfo           string            `json:"versionInfo,omitempty"`
+	Supplier              string            `json:"supplier,omitempty"`
+	DownloadLocation      string            `json:"downloadLocation"`
+	FilesAnalyzed         bool              `json:"filesAnalyzed"`
+	PrimaryPackagePurpose string            `json:"primaryPackagePurpose,omitempty"`
+	ExternalRefs          []spdxExternalRef `json:"externalRefs,omitempty"`
+	Comment               string            `json:"comment,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+const (
+	spdxDocumentID = "SPDXRef-DOCUMENT"
+	spdxNoAssert   = "NOASSERTION"
+)
+
+func newSPDX(in Input) spdxDocument {
+	var subject string
+	switch {
+	case in.Host != nil:
+		subject = fmt.Sprintf("host-%d", in.Host.ID)
+	case in.Team != nil:
+		subject = fmt.Sprintf("fleet-%d", in.Team.ID)
+	}
+
+	creators := []string{"Tool: fleet-" + in.ToolVersion}
+	if in.OrgName != "" {
+		creators = append(creators, "Organization: "+in.OrgName)
+	}
+	namespace := strings.TrimSuffix(in.Namespace, "/")
+	if namespace == "" {
+		namespace = "https://fleetdm.com"
+	}
+
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            spdxDocumentID,
+		Name:              in.name(),
+		DocumentNamespace: fmt.Sprintf("%s/spdxdocs/%s-%s", namespace, subject, in.DocumentID),
+		CreationInfo: spdxCreationInfo{
+			Created:  in.Created.UTC().Format(time.RFC3339),
+			Creators: creators,
+		},
+		Packages: make([]spdxPackage, 0, len(in.Software)+1),
+	}
+
+	// A host is described by a root package that contains its software. A
+	// fleet isn't a package, so the document describes its software directly.
+	parent := spdxDocumentID
+	relationship := "DESCRIBES"
+	if h := in.Host; h != nil {
+		root := spdxPackage{
+			Name:                  h.Hostname,
+			SPDXID:                fmt.Sprintf("SPDXRef-Host-%d", h.ID),
+			VersionInfo:           h.OSVersion,
+			DownloadLocation:      spdxNoAssert,
+			PrimaryPackagePurpose: "DEVICE",
+		}
+		if h.HardwareSerial != "" {
+			root.Comment = "Hardware serial: " + h.HardwareSerial
+		}
+		doc.Packages = append(doc.Packages, root)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID: spdxDocumentID, RelationshipType: "DESCRIBES", RelatedSPDXElement: root.SPDXID,
+		})
+		parent, relationship = root.SPDXID, "CONTAINS"
+	}
+
+	for _, s := range in.Software {
+		pkg := spdxPackage{
+			Name:             s.Name,
+			SPDXID:           fmt.Sprintf("SPDXRef-Package-%d", s.ID),
+			VersionInfo:      s.Version,
+			DownloadLocation: spdxNoAssert,
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  PackageURL(s, in.distro()),
+			}},
+			Comment: "Source: " + s.Source,
+		}
+		if s.Vendor != "" {
+			pkg.Supplier = "Organization: " + s.Vendor
+		}
+		if s.GenerateCPE != "" {
+			pkg.ExternalRefs = append(pkg.ExternalRefs, spdxExternalRef{
+				ReferenceCategory: "SECURITY", ReferenceType: "cpe23Type", ReferenceLocator: s.GenerateCPE,
+			})
+		}
+		for _, cve := range s.Vulnerabilities {
+			pkg.ExternalRefs = append(pkg.ExternalRefs, spdxExternalRef{
+				ReferenceCategory: "SECURITY", ReferenceType: "advisory", ReferenceLocator: "https://nvd.nist.gov/vuln/detail/" + cve.CVE,
+			})
+		}
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID: parent, RelationshipType: relationship, RelatedSPDXElement: pkg.SPDXID,
+		})
+	}
+	return doc
+}
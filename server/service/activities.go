@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 
 	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
@@ -10,6 +11,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/mdm/apple/vpp"
+	"github.com/fleetdm/fleet/v4/server/worker"
 )
 
 func (svc *Service) GetActivitiesWebhookSettings(ctx context.Context) (fleet.ActivitiesWebhookSettings, error) {
@@ -41,6 +43,25 @@ func (svc *Service) GetHostActivitiesWebhookSettings(ctx context.Context, hostID
 	return settings, nil
 }
 
+// DeliverActivitiesWebhook delivers an activities webhook payload. Like
+// GetActivitiesWebhookSettings, it is an internal provider hook for the
+// activity bounded context, without an authz check.
+func (svc *Service) DeliverActivitiesWebhook(ctx context.Context, fleetID *uint, destinationURL string, payload json.RawMessage) error {
+	typ := fleet.WebhookTypeActivities
+	if fleetID != nil {
+		typ = fleet.WebhookTypeHostActivities
+	}
+	if _, err := worker.DeliverWebhook(ctx, svc.ds, svc.logger, worker.WebhookDeliveryRequest{
+		Type:    typ,
+		TeamID:  fleetID,
+		URL:     destinationURL,
+		Payload: payload,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "deliver activities webhook")
+	}
+	return nil
+}
+
 func (svc *Service) ActivateNextUpcomingActivityForHost(ctx context.Context, hostID uint, fromCompletedExecID string) error {
 	return svc.ds.ActivateNextUpcomingActivityForHost(ctx, hostID, fromCompletedExecID)
 }
@@ -599,6 +599,11 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte, applyOpts fle
 		return nil, ctxerr.Wrap(ctx, err, "modify AppConfig")
 	}
 
+	storedServiceNowByGroup, err := fleet.IndexServiceNowIntegrations(appConfig.Integrations.ServiceNow)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "modify AppConfig")
+	}
+
 	// Rewrite deprecated JSON field names (e.g. team_id → fleet_id) before
 	// unmarshaling into AppConfig, since the request body was captured as
 	// json.RawMessage and wasn't processed by the request decoder's rewriter.
@@ -691,6 +696,28 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte, applyOpts fle
 		return nil, ctxerr.Wrap(ctx, err)
 	}
 
+	// Custom webhook headers are replaced rather than merged so that headers
+	// can be removed, and the masked secrets and header values returned by the
+	// API are kept as stored.
+	replaceWebhookHeaders := func(dst *map[string]string, incoming map[string]string) {
+		if incoming != nil {
+			*dst = incoming
+		}
+	}
+	replaceWebhookHeaders(&appConfig.WebhookSettings.ActivitiesWebhook.Headers, newAppConfig.WebhookSettings.ActivitiesWebhook.Headers)
+	replaceWebhookHeaders(&appConfig.WebhookSettings.HostStatusWebhook.Headers, newAppConfig.WebhookSettings.HostStatusWebhook.Headers)
+	replaceWebhookHeaders(&appConfig.WebhookSettings.FailingPoliciesWebhook.Headers, newAppConfig.WebhookSettings.FailingPoliciesWebhook.Headers)
+	replaceWebhookHeaders(&appConfig.WebhookSettings.VulnerabilitiesWebhook.Headers, newAppConfig.WebhookSettings.VulnerabilitiesWebhook.Headers)
+	replaceWebhookHeaders(&appConfig.WebhookSettings.GitOpsDriftWebhook.Headers, newAppConfig.WebhookSettings.GitOpsDriftWebhook.Headers)
+	appConfig.WebhookSettings.RestoreMaskedSecrets(oldAppConfig.WebhookSettings)
+
+	// A masked OIDC client secret means "keep the existing secret".
+	if appConfig.SSOSettings != nil && appConfig.SSOSettings.OIDC != nil &&
+		appConfig.SSOSettings.OIDC.ClientSecret == fleet.MaskedPassword &&
+		oldAppConfig.SSOSettings != nil && oldAppConfig.SSOSettings.OIDC != nil {
+		appConfig.SSOSettings.OIDC.ClientSecret = oldAppConfig.SSOSettings.OIDC.ClientSecret
+	}
+
 	// AppleOSUpdateSettings.UpdateNewHosts only applies to macOS ... so just ignore w/e posted for iOS/iPadOS
 	appConfig.MDM.IOSUpdates.UpdateNewHosts = optjson.Bool{}
 	appConfig.MDM.IPadOSUpdates.UpdateNewHosts = optjson.Bool{}
@@ -974,6 +1001,17 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte, applyOpts fle
 		invalid.Append("activity_expiry_settings.activity_expiry_window", "must be greater than 0")
 	}
 
+	if appConfig.ServerSettings.ReportHistoryRetentionDays < 0 {
+		invalid.Append("server_settings.report_history_retention_days", "must be 0 or greater")
+	}
+	if appConfig.ServerSettings.ReportHistoryCap < 0 {
+		invalid.Append("server_settings.report_history_cap", "must be 0 or greater")
+	}
+
+	if newAppConfig.ServerSettings.RequireMFAAboveObserver && !lic.IsPremium() {
+		invalid.Append("server_settings.require_mfa_above_observer", ErrMissingLicense.Error())
+	}
+
 	if appConfig.OrgInfo.ContactURL == "" {
 		appConfig.OrgInfo.ContactURL = fleet.DefaultOrgInfoContactURL
 	}
@@ -1006,11 +1044,18 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte, applyOpts fle
 	}
 
 	fleet.ValidateGoogleCalendarIntegrations(appConfig.Integrations.GoogleCalendar, invalid)
+	fleet.ValidateMicrosoftCalendarIntegrations(appConfig.Integrations, invalid)
 	fleet.ValidateGoogleWorkspaceIntegrations(appConfig.Integrations.GoogleWorkspace, invalid)
 	fleet.ValidateEnabledVulnerabilitiesIntegrations(appConfig.WebhookSettings.VulnerabilitiesWebhook, appConfig.Integrations, invalid)
 	fleet.ValidateEnabledFailingPoliciesIntegrations(appConfig.WebhookSettings.FailingPoliciesWebhook, appConfig.Integrations, invalid)
 	fleet.ValidateEnabledHostStatusIntegrations(appConfig.WebhookSettings.HostStatusWebhook, invalid)
 	fleet.ValidateEnabledActivitiesWebhook(appConfig.WebhookSettings.ActivitiesWebhook, invalid)
+	fleet.ValidateEnabledGitOpsDriftWebhook(appConfig.WebhookSettings.GitOpsDriftWebhook, invalid)
+	fleet.ValidateWebhookDeliverySettings("webhook_settings.activities_webhook", appConfig.WebhookSettings.ActivitiesWebhook.WebhookDeliverySettings, invalid)
+	fleet.ValidateWebhookDeliverySettings("webhook_settings.host_status_webhook", appConfig.WebhookSettings.HostStatusWebhook.WebhookDeliverySettings, invalid)
+	fleet.ValidateWebhookDeliverySettings("webhook_settings.failing_policies_webhook", appConfig.WebhookSettings.FailingPoliciesWebhook.WebhookDeliverySettings, invalid)
+	fleet.ValidateWebhookDeliverySettings("webhook_settings.vulnerabilities_webhook", appConfig.WebhookSettings.VulnerabilitiesWebhook.WebhookDeliverySettings, invalid)
+	fleet.ValidateWebhookDeliverySettings("webhook_settings.gitops_drift_webhook", appConfig.WebhookSettings.GitOpsDriftWebhook.WebhookDeliverySettings, invalid)
 
 	if err := applyAndValidateConditionalAccessOktaFields(ctx, appConfig, &newAppConfig, invalid, lic); err != nil {
 		return nil, err
@@ -1148,10 +1193,34 @@ func (svc *Service) ModifyAppConfig(ctx context.Context, p []byte, applyOpts fle
 			}
 		}
 	}
+	// ServiceNow is not managed by the frontend integrations page, so it is only
+	// modified when it is explicitly set (an empty array deletes all of them).
+	if newAppConfig.Integrations.ServiceNow != nil {
+		delServiceNow, err := fleet.ValidateServiceNowIntegrations(ctx, storedServiceNowByGroup, newAppConfig.Integrations.ServiceNow)
+		if err != nil {
+			if errors.As(err, &fleet.IntegrationTestError{}) {
+				return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{
+					Message: err.Error(),
+				})
+			}
+			return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("ServiceNow integration", err.Error()))
+		}
+		appConfig.Integrations.ServiceNow = newAppConfig.Integrations.ServiceNow
+
+		if len(delServiceNow) > 0 {
+			if err := svc.ds.DeleteIntegrationsFromTeams(ctx, fleet.Integrations{ServiceNow: delServiceNow}); err != nil {
+				return nil, ctxerr.Wrap(ctx, err, "delete integrations from teams")
+			}
+		}
+	}
 	// If google_calendar is null, we keep the existing setting.
 	if newAppConfig.Integrations.GoogleCalendar == nil {
 		appConfig.Integrations.GoogleCalendar = oldAppConfig.Integrations.GoogleCalendar
 	}
+	// If microsoft_calendar is null, we keep the existing setting.
+	if newAppConfig.Integrations.MicrosoftCalendar == nil {
+		appConfig.Integrations.MicrosoftCalendar = oldAppConfig.Integrations.MicrosoftCalendar
+	}
 	// If google_workspace is null, we keep the existing setting.
 	if newAppConfig.Integrations.GoogleWorkspace == nil {
 		appConfig.Integrations.GoogleWorkspace = oldAppConfig.Integrations.GoogleWorkspace
@@ -2623,13 +2692,34 @@ func validateSSOProviderSettings(incoming *fleet.SSOProviderSettings, existing f
 }
 
 func validateSSOSettings(p fleet.AppConfig, existing *fleet.AppConfig, invalid *fleet.InvalidArgumentError, lic *fleet.LicenseInfo, overwrite bool) {
+	if p.SSOSettings != nil && p.SSOSettings.OIDC != nil {
+		var existingOIDC *fleet.OIDCSettings
+		if existing.SSOSettings != nil && !overwrite {
+			existingOIDC = existing.SSOSettings.OIDC
+		}
+		validateOIDCSettings(p.SSOSettings.OIDC, existingOIDC, invalid)
+	}
+
+	if p.SSOSettings != nil && p.SSOSettings.SCIMUserProvisioning != nil {
+		validateSCIMUserProvisioningSettings(p.SSOSettings.SCIMUserProvisioning, invalid, lic)
+	}
+
 	if p.SSOSettings != nil && p.SSOSettings.EnableSSO {
 
 		var existingSSOProviderSettings fleet.SSOProviderSettings
 		if existing.SSOSettings != nil {
 			existingSSOProviderSettings = existing.SSOSettings.SSOProviderSettings
 		}
-		validateSSOProviderSettings(&p.SSOSettings.SSOProviderSettings, existingSSOProviderSettings, invalid, overwrite)
+		if p.SSOSettings.OIDC != nil {
+			// The SAML metadata and entity ID are not used with an OIDC
+			// provider, only the name displayed on the login page is required.
+			p.SSOSettings.IDPName = strings.TrimSpace(p.SSOSettings.IDPName)
+			if p.SSOSettings.IDPName == "" && (overwrite || existingSSOProviderSettings.IDPName == "") {
+				invalid.Append("idp_name", "required")
+			}
+		} else {
+			validateSSOProviderSettings(&p.SSOSettings.SSOProviderSettings, existingSSOProviderSettings, invalid, overwrite)
+		}
 
 		if !lic.IsPremium() {
 			if p.SSOSettings.EnableJITProvisioning {
@@ -2639,6 +2729,70 @@ func validateSSOSettings(p fleet.AppConfig, existing *fleet.AppConfig, invalid *
 	}
 }
 
+// validateOIDCSettings validates the incoming OIDC provider settings. existing
+// holds the persisted settings that fields missing from a patch fall back to,
+// it is nil in GitOps runs.
+func validateOIDCSettings(incoming *fleet.OIDCSettings, existing *fleet.OIDCSettings, invalid *fleet.InvalidArgumentError) {
+	if existing == nil {
+		existing = &fleet.OIDCSettings{}
+	}
+	incoming.IssuerURL = strings.TrimSpace(incoming.IssuerURL)
+	incoming.ClientID = strings.TrimSpace(incoming.ClientID)
+
+	switch {
+	case incoming.IssuerURL == "":
+		if existing.IssuerURL == "" {
+			invalid.Append("oidc.issuer_url", "required")
+		}
+	default:
+		if u, err := url.ParseRequestURI(incoming.IssuerURL); err != nil {
+			invalid.Append("oidc.issuer_url", err.Error())
+		} else if u.Scheme != "https" && u.Scheme != "http" {
+			invalid.Append("oidc.issuer_url", "must be either https or http")
+		} else if u.RawQuery != "" || u.Fragment != "" {
+			invalid.Append("oidc.issuer_url", "must not contain a query or fragment")
+		}
+	}
+	if incoming.ClientID == "" && existing.ClientID == "" {
+		invalid.Append("oidc.client_id", "required")
+	}
+	if incoming.ClientSecret == fleet.MaskedPassword && existing.ClientSecret == "" {
+		invalid.Append("oidc.client_secret", "no client secret is currently set")
+	}
+}
+
+// validateSCIMUserProvisioningSettings validates the mapping of SCIM groups
+// to Fleet roles. Fleets are referenced by name and resolved when users are
+// provisioned, so that the mapping can be applied by GitOps before the fleets
+// it references are created.
+func validateSCIMUserProvisioningSettings(incoming *fleet.SCIMUserProvisioningSettings, invalid *fleet.InvalidArgumentError, lic *fleet.LicenseInfo) {
+	if incoming.Enable && !lic.IsPremium() {
+		invalid.Append("scim_user_provisioning.enable", ErrMissingLicense.Error())
+		return
+	}
+
+	type mappingKey struct{ group, team string }
+	seen := make(map[mappingKey]struct{}, len(incoming.RoleMappings))
+	for i := range incoming.RoleMappings {
+		m := &incoming.RoleMappings[i]
+		m.Group = strings.TrimSpace(m.Group)
+		m.Team = strings.TrimSpace(m.Team)
+		field := fmt.Sprintf("scim_user_provisioning.role_mappings[%d]", i)
+
+		if m.Group == "" {
+			invalid.Append(field+".group", "required")
+		}
+		if !fleet.ValidSCIMMappedRole(m.Role) {
+			invalid.Append(field+".role", fmt.Sprintf("invalid role %q, must be one of admin, maintainer, technician, observer_plus or observer", m.Role))
+		}
+		key := mappingKey{group: m.Group, team: m.Team}
+		if _, ok := seen[key]; ok {
+			invalid.Append(field, fmt.Sprintf("group %q is mapped more than once to the same fleet", m.Group))
+		}
+		seen[key] = struct{}{}
+	}
+}
+
 // gitopsHistoricalDataView is the narrow tri-state view of
 // features.historical_data used to detect which sub-keys were absent
 // from a gitops payload. Pointer fields distinguish "absent" from
@@ -2651,6 +2805,9 @@ type gitopsHistoricalDataView struct {
 		HistoricalData struct {
 			Uptime          *bool `json:"uptime"`
 			Vulnerabilities *bool `json:"vulnerabilities"`
+			Policies        *bool `json:"policies"`
+			MDMEnrollment   *bool `json:"mdm_enrollment"`
+			DiskEncryption  *bool `json:"disk_encryption"`
 		} `json:"historical_data"`
 	} `json:"features"`
 }
@@ -2679,6 +2836,18 @@ func applyHistoricalDataOverwriteDefaults(p []byte, cfg *fleet.AppConfig) {
 	if v := view.Features.HistoricalData.Vulnerabilities; v != nil {
 		cfg.Features.HistoricalData.Vulnerabilities = *v
 	}
+	cfg.Features.HistoricalData.Policies = defaults.HistoricalData.Policies
+	if v := view.Features.HistoricalData.Policies; v != nil {
+		cfg.Features.HistoricalData.Policies = *v
+	}
+	cfg.Features.HistoricalData.MDMEnrollment = defaults.HistoricalData.MDMEnrollment
+	if v := view.Features.HistoricalData.MDMEnrollment; v != nil {
+		cfg.Features.HistoricalData.MDMEnrollment = *v
+	}
+	cfg.Features.HistoricalData.DiskEncryption = defaults.HistoricalData.DiskEncryption
+	if v := view.Features.HistoricalData.DiskEncryption; v != nil {
+		cfg.Features.HistoricalData.DiskEncryption = *v
+	}
 }
 
 // //////////////////////////////////////////////////////////////////////////////
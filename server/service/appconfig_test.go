@@ -645,6 +645,102 @@ func TestSSOValidationValidatesSchemaInMetadataURL(t *testing.T) {
 	}
 }
 
+func TestSSOValidationOIDC(t *testing.T) {
+	oidcConfig := func(oidc *fleet.OIDCSettings) fleet.AppConfig {
+		return fleet.AppConfig{
+			SSOSettings: &fleet.SSOSettings{
+				EnableSSO:           true,
+				SSOProviderSettings: fleet.SSOProviderSettings{IDPName: "Keycloak"},
+				OIDC:                oidc,
+			},
+		}
+	}
+	existing := &fleet.AppConfig{
+		SSOSettings: &fleet.SSOSettings{
+			SSOProviderSettings: fleet.SSOProviderSettings{IDPName: "Keycloak"},
+			OIDC: &fleet.OIDCSettings{
+				IssuerURL:    "https://idp.example.com/realms/fleet",
+				ClientID:     "fleet",
+				ClientSecret: "secret",
+			},
+		},
+	}
+
+	cases := []struct {
+		name      string
+		config    fleet.AppConfig
+		existing  *fleet.AppConfig
+		overwrite bool
+		errs      []string
+	}{
+		{
+			name:   "valid",
+			config: oidcConfig(&fleet.OIDCSettings{IssuerURL: "https://idp.example.com/realms/fleet", ClientID: "fleet"}),
+		},
+		{
+			name: "no SAML metadata or entity ID needed",
+			config: fleet.AppConfig{SSOSettings: &fleet.SSOSettings{
+				EnableSSO: true,
+				OIDC:      &fleet.OIDCSettings{IssuerURL: "https://idp.example.com", ClientID: "fleet"},
+			}},
+			errs: []string{"idp_name"},
+		},
+		{
+			name:   "missing issuer and client ID",
+			config: oidcConfig(&fleet.OIDCSettings{}),
+			errs:   []string{"oidc.issuer_url", "oidc.client_id"},
+		},
+		{
+			name:   "invalid issuer scheme",
+			config: oidcConfig(&fleet.OIDCSettings{IssuerURL: "ftp://idp.example.com", ClientID: "fleet"}),
+			errs:   []string{"must be either https or http"},
+		},
+		{
+			name:   "issuer with query",
+			config: oidcConfig(&fleet.OIDCSettings{IssuerURL: "https://idp.example.com?tenant=1", ClientID: "fleet"}),
+			errs:   []string{"must not contain a query or fragment"},
+		},
+		{
+			name:   "masked secret without existing secret",
+			config: oidcConfig(&fleet.OIDCSettings{IssuerURL: "https://idp.example.com", ClientID: "fleet", ClientSecret: fleet.MaskedPassword}),
+			errs:   []string{"oidc.client_secret"},
+		},
+		{
+			name:     "patch falls back to existing settings",
+			config:   oidcConfig(&fleet.OIDCSettings{ClientSecret: fleet.MaskedPassword}),
+			existing: existing,
+		},
+		{
+			name:      "gitops requires all settings",
+			config:    oidcConfig(&fleet.OIDCSettings{ClientSecret: fleet.MaskedPassword}),
+			existing:  existing,
+			overwrite: true,
+			errs:      []string{"oidc.issuer_url", "oidc.client_id", "oidc.client_secret"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			existing := c.existing
+			if existing == nil {
+				existing = &fleet.AppConfig{}
+			}
+			invalid := &fleet.InvalidArgumentError{}
+			validateSSOSettings(c.config, existing, invalid, &fleet.LicenseInfo{}, c.overwrite)
+			if len(c.errs) == 0 {
+				require.False(t, invalid.HasErrors(), invalid.Error())
+				return
+			}
+			require.True(t, invalid.HasErrors())
+			errs := fmt.Sprint(invalid.Invalid())
+			for _, e := range c.errs {
+				assert.Contains(t, errs, e)
+			}
+			assert.NotContains(t, errs, "metadata")
+			assert.NotContains(t, errs, "entity_id")
+		})
+	}
+}
+
 func TestJITProvisioning(t *testing.T) {
 	config := fleet.AppConfig{
 		SSOSettings: &fleet.SSOSettings{
@@ -685,6 +781,57 @@ func TestJITProvisioning(t *testing.T) {
 	})
 }
 
+func TestValidateSCIMUserProvisioningSettings(t *testing.T) {
+	premium := &fleet.LicenseInfo{Tier: fleet.TierPremium}
+
+	t.Run("requires a premium license to enable", func(t *testing.T) {
+		invalid := &fleet.InvalidArgumentError{}
+		validateSCIMUserProvisioningSettings(&fleet.SCIMUserProvisioningSettings{Enable: true}, invalid, &fleet.LicenseInfo{})
+		require.True(t, invalid.HasErrors())
+		assert.Contains(t, invalid.Error(), "scim_user_provisioning.enable")
+		assert.Contains(t, invalid.Error(), "missing or invalid license")
+
+		invalid = &fleet.InvalidArgumentError{}
+		validateSCIMUserProvisioningSettings(&fleet.SCIMUserProvisioningSettings{Enable: false}, invalid, &fleet.LicenseInfo{})
+		require.False(t, invalid.HasErrors())
+	})
+
+	t.Run("valid mappings", func(t *testing.T) {
+		settings := &fleet.SCIMUserProvisioningSettings{
+			Enable: true,
+			RoleMappings: []fleet.SCIMRoleMapping{
+				{Group: " fleet-admins ", Role: fleet.RoleAdmin},
+				{Group: "ws-it", Role: fleet.RoleMaintainer, Team: " Workstations"},
+				{Group: "ws-it", Role: fleet.RoleObserver, Team: "Servers"},
+			},
+		}
+		invalid := &fleet.InvalidArgumentError{}
+		validateSCIMUserProvisioningSettings(settings, invalid, premium)
+		require.False(t, invalid.HasErrors())
+		assert.Equal(t, "fleet-admins", settings.RoleMappings[0].Group)
+		assert.Equal(t, "Workstations", settings.RoleMappings[1].Team)
+	})
+
+	t.Run("invalid mappings", func(t *testing.T) {
+		invalid := &fleet.InvalidArgumentError{}
+		validateSCIMUserProvisioningSettings(&fleet.SCIMUserProvisioningSettings{
+			Enable: true,
+			RoleMappings: []fleet.SCIMRoleMapping{
+				{Group: "", Role: fleet.RoleAdmin},
+				{Group: "ci", Role: fleet.RoleGitOps},
+				{Group: "ws-it", Role: fleet.RoleMaintainer, Team: "Workstations"},
+				{Group: "ws-it", Role: fleet.RoleObserver, Team: "Workstations "},
+			},
+		}, invalid, premium)
+		errs := invalid.Invalid()
+		require.Len(t, errs, 3)
+		assert.Equal(t, "scim_user_provisioning.role_mappings[0].group", errs[0]["name"])
+		assert.Equal(t, "scim_user_provisioning.role_mappings[1].role", errs[1]["name"])
+		assert.Equal(t, "scim_user_provisioning.role_mappings[3]", errs[2]["name"])
+		assert.Equal(t, `group "ws-it" is mapped more than once to the same fleet`, errs[2]["reason"])
+	})
+}
+
 func TestAppConfigSecretsObfuscated(t *testing.T) {
 	ds := new(mock.Store)
 	svc, ctx := newTestService(t, ds, nil, nil)
@@ -1169,6 +1316,10 @@ func TestTransparencyURLDowngradeLicense(t *testing.T) {
 	require.Error(t, err)
 	require.ErrorContains(t, err, "missing or invalid license")
 
+	// requiring MFA fails
+	_, err = svc.ModifyAppConfig(ctx, []byte(`{"server_settings":{"require_mfa_above_observer":true}}`), fleet.ApplySpecOptions{})
+	require.ErrorContains(t, err, "missing or invalid license")
+
 	// setting unrelated config value does not fail and resets transparency url to ""
 	raw, err = json.Marshal(fleet.OrgInfo{OrgName: "f1337"})
 	require.NoError(t, err)
@@ -3109,6 +3260,15 @@ func TestModifyAppConfigGitOpsExceptionActivities(t *testing.T) {
 func TestModifyAppConfigGitOpsHistoricalDataDefaults(t *testing.T) {
 	admin := &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}
 
+	allOn := fleet.HistoricalDataSettings{
+		Uptime: true, Vulnerabilities: true, Policies: true, MDMEnrollment: true, DiskEncryption: true,
+	}
+	with := func(mutate func(*fleet.HistoricalDataSettings)) fleet.HistoricalDataSettings {
+		h := allOn
+		mutate(&h)
+		return h
+	}
+
 	testCases := []struct {
 		name      string
 		initial   fleet.HistoricalDataSettings
@@ -3118,45 +3278,53 @@ func TestModifyAppConfigGitOpsHistoricalDataDefaults(t *testing.T) {
 	}{
 		{
 			name:      "overwrite: payload omits historical_data entirely (old fleetctl)",
-			initial:   fleet.HistoricalDataSettings{Uptime: true, Vulnerabilities: true},
+			initial:   allOn,
 			payload:   `{"features":{"enable_software_inventory":true}}`,
 			overwrite: true,
-			expected:  fleet.HistoricalDataSettings{Uptime: true, Vulnerabilities: true},
+			expected:  allOn,
 		},
 		{
 			name:      "overwrite: payload omits historical_data, prior values were false",
-			initial:   fleet.HistoricalDataSettings{Uptime: false, Vulnerabilities: false},
+			initial:   fleet.HistoricalDataSettings{},
 			payload:   `{"features":{"enable_software_inventory":true}}`,
 			overwrite: true,
-			expected:  fleet.HistoricalDataSettings{Uptime: true, Vulnerabilities: true},
+			expected:  allOn,
 		},
 		{
 			name:      "overwrite: payload sets historical_data to empty map",
-			initial:   fleet.HistoricalDataSettings{Uptime: true, Vulnerabilities: true},
+			initial:   allOn,
 			payload:   `{"features":{"historical_data":{}}}`,
 			overwrite: true,
-			expected:  fleet.HistoricalDataSettings{Uptime: true, Vulnerabilities: true},
+			expected:  allOn,
 		},
 		{
 			name:      "overwrite: payload partially specifies historical_data (uptime false)",
-			initial:   fleet.HistoricalDataSettings{Uptime: true, Vulnerabilities: true},
+			initial:   allOn,
 			payload:   `{"features":{"historical_data":{"uptime":false}}}`,
 			overwrite: true,
-			expected:  fleet.HistoricalDataSettings{Uptime: false, Vulnerabilities: true},
+			expected:  with(func(h *fleet.HistoricalDataSettings) { h.Uptime = false }),
+		},
+		{
+			name:      "overwrite: payload partially specifies historical_data (compliance datasets false)",
+			initial:   allOn,
+			payload:   `{"features":{"historical_data":{"policies":false,"mdm_enrollment":false,"disk_encryption":false}}}`,
+			overwrite: true,
+			expected:  fleet.HistoricalDataSettings{Uptime: true, Vulnerabilities: true},
 		},
 		{
-			name:      "overwrite: payload fully specifies historical_data",
-			initial:   fleet.HistoricalDataSettings{Uptime: true, Vulnerabilities: true},
-			payload:   `{"features":{"historical_data":{"uptime":false,"vulnerabilities":false}}}`,
+			name:    "overwrite: payload fully specifies historical_data",
+			initial: allOn,
+			payload: `{"features":{"historical_data":{"uptime":false,"vulnerabilities":false,` +
+				`"policies":false,"mdm_enrollment":false,"disk_encryption":false}}}`,
 			overwrite: true,
-			expected:  fleet.HistoricalDataSettings{Uptime: false, Vulnerabilities: false},
+			expected:  fleet.HistoricalDataSettings{},
 		},
 		{
 			name:      "patch (non-overwrite): payload omits historical_data, prior values preserved",
-			initial:   fleet.HistoricalDataSettings{Uptime: false, Vulnerabilities: true},
+			initial:   with(func(h *fleet.HistoricalDataSettings) { h.Uptime = false; h.Policies = false }),
 			payload:   `{"org_info":{"org_name":"Renamed"}}`,
 			overwrite: false,
-			expected:  fleet.HistoricalDataSettings{Uptime: false, Vulnerabilities: true},
+			expected:  with(func(h *fleet.HistoricalDataSettings) { h.Uptime = false; h.Policies = false }),
 		},
 	}
 
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+//////////////////////////////////////////////////////////////////////////////////
+// List fleetd caching proxies
+//////////////////////////////////////////////////////////////////////////////////
+
+func listCacheProxiesEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.ListCacheProxiesRequest)
+	proxies, err := svc.ListCacheProxies(ctx, req.TeamID)
+	if err != nil {
+		return fleet.ListCacheProxiesResponse{Err: err}, nil
+	}
+	return fleet.ListCacheProxiesResponse{CacheProxies: proxies}, nil
+}
+
+func (svc *Service) ListCacheProxies(ctx context.Context, teamID *uint) ([]*fleet.HostCacheProxy, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true, TeamID: teamID}
+
+	proxies, err := svc.ds.ListHostCacheProxies(ctx, filter)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list cache proxies")
+	}
+	for _, p := range proxies {
+		p.HitRate = p.CacheProxyStats.HitRate()
+	}
+	if proxies == nil {
+		proxies = []*fleet.HostCacheProxy{}
+	}
+	return proxies, nil
+}
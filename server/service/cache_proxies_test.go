@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListCacheProxies(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	var gotFilter fleet.TeamFilter
+	ds.ListHostCacheProxiesFunc = func(ctx context.Context, filter fleet.TeamFilter) ([]*fleet.HostCacheProxy, error) {
+		gotFilter = filter
+		if filter.TeamID != nil {
+			return nil, nil
+		}
+		return []*fleet.HostCacheProxy{
+			{HostID: 1, CacheProxyStats: fleet.CacheProxyStats{Hits: 3, Misses: 1}},
+			{HostID: 2},
+		}, nil
+	}
+
+	_, err := svc.ListCacheProxies(ctx, nil)
+	checkAuthErr(t, true, err)
+
+	user := &fleet.User{ID: 1, GlobalRole: new(fleet.RoleObserver)}
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: user})
+	proxies, err := svc.ListCacheProxies(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, proxies, 2)
+	assert.InDelta(t, 0.75, proxies[0].HitRate, 0.0001)
+	assert.Zero(t, proxies[1].HitRate)
+	assert.Equal(t, user, gotFilter.User)
+	assert.True(t, gotFilter.IncludeObserver)
+	assert.Nil(t, gotFilter.TeamID)
+
+	teamID := uint(1)
+	proxies, err = svc.ListCacheProxies(ctx, &teamID)
+	require.NoError(t, err)
+	assert.NotNil(t, proxies)
+	assert.Empty(t, proxies)
+	assert.Equal(t, &teamID, gotFilter.TeamID)
+}
+
+func TestReportCacheProxyStats(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	var gotHostID uint
+	var gotStats fleet.CacheProxyStats
+	ds.UpsertHostCacheProxyStatsFunc = func(ctx context.Context, hostID uint, stats fleet.CacheProxyStats) error {
+		gotHostID, gotStats = hostID, stats
+		return nil
+	}
+
+	err := svc.ReportCacheProxyStats(ctx, fleet.CacheProxyStats{Hits: 1})
+	require.Error(t, err)
+	assert.False(t, ds.UpsertHostCacheProxyStatsFuncInvoked)
+
+	stats := fleet.CacheProxyStats{Hits: 1, Misses: 2, HitBytes: 3, MissBytes: 4, CachedItems: 5, CachedBytes: 6}
+	ctx = hostctx.NewContext(ctx, &fleet.Host{ID: 42})
+	require.NoError(t, svc.ReportCacheProxyStats(ctx, stats))
+	assert.EqualValues(t, 42, gotHostID)
+	assert.Equal(t, stats, gotStats)
+}
@@ -46,7 +46,45 @@ var RecentCalendarUpdateDuration = 10 * time.Second
 type Config struct {
 	config.CalendarConfig
 	fleet.GoogleCalendarIntegration
-	ServerURL string
+	// MicrosoftCalendar is set instead of GoogleCalendarIntegration when the Microsoft 365 calendar integration is
+	// configured, along with the Microsoft Graph credential of its tenant.
+	MicrosoftCalendar        *fleet.MicrosoftCalendarIntegration
+	MicrosoftGraphCredential *fleet.MicrosoftGraphCredential
+	ServerURL                string
+}
+
+// NewConfig returns the configuration of the calendar integration configured in appConfig, or nil if none is. The
+// Microsoft Graph credential of a Microsoft 365 calendar integration is loaded from the stored credentials.
+func NewConfig(ctx context.Context, ds fleet.Datastore, appConfig *fleet.AppConfig, serverConfig config.CalendarConfig) (*Config, error) {
+	cfg := &Config{
+		CalendarConfig: serverConfig,
+		ServerURL:      appConfig.ServerSettings.ServerURL,
+	}
+	switch {
+	case len(appConfig.Integrations.GoogleCalendar) > 0:
+		cfg.GoogleCalendarIntegration = *appConfig.Integrations.GoogleCalendar[0]
+	case len(appConfig.Integrations.MicrosoftCalendar) > 0:
+		msCal := *appConfig.Integrations.MicrosoftCalendar[0]
+		cfg.MicrosoftCalendar = &msCal
+		if strings.EqualFold(msCal.TenantID, calendar.MicrosoftMockTenantID) {
+			// the mock does not need a credential
+			return cfg, nil
+		}
+		creds, err := ds.ListMicrosoftGraphCredentials(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list microsoft graph credentials: %w", err)
+		}
+		for _, cred := range creds {
+			if strings.EqualFold(cred.TenantID, msCal.TenantID) {
+				cfg.MicrosoftGraphCredential = cred
+				return cfg, nil
+			}
+		}
+		return nil, fmt.Errorf("no Microsoft Graph credential is configured for the calendar tenant %s", msCal.TenantID)
+	default:
+		return nil, nil
+	}
+	return cfg, nil
 }
 
 // PolicyLiteWithMeta is a wrapper around fleet.PolicyLite that includes a tag for policy's description/resolution.
@@ -64,6 +102,15 @@ func ClassifyRemoteError(err error) (isRemote bool, statusCode int, body string)
 }
 
 func CreateUserCalendarFromConfig(ctx context.Context, config *Config, logger *slog.Logger) fleet.UserCalendar {
+	if config.MicrosoftCalendar != nil {
+		return calendar.NewMicrosoftCalendar(&calendar.MicrosoftCalendarConfig{
+			Context:           ctx,
+			IntegrationConfig: config.MicrosoftCalendar,
+			Credential:        config.MicrosoftGraphCredential,
+			ServerURL:         config.ServerURL,
+			Logger:            logger.With("component", "microsoft_calendar"),
+		})
+	}
 	googleCalendarConfig := calendar.GoogleCalendarConfig{
 		Context:           ctx,
 		IntegrationConfig: &config.GoogleCalendarIntegration,
@@ -1,11 +1,20 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/fleetdm/fleet/v4/server/authz"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/contexts/logging"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
@@ -21,6 +30,12 @@ type createDistributedQueryCampaignRequest struct {
 	QuerySQL string            `json:"query"`
 	QueryID  *uint             `json:"query_id" renameto:"report_id"`
 	Selected fleet.HostTargets `json:"selected"`
+	// Deferred creates a campaign that collects results from hosts as they
+	// check in, until DeferredTTL (24h by default) elapses.
+	Deferred    bool           `json:"deferred"`
+	DeferredTTL fleet.Duration `json:"deferred_ttl"`
+	// SaveResults stores the results of a live campaign as they are streamed.
+	SaveResults bool `json:"save_results"`
 }
 
 type createDistributedQueryCampaignResponse struct {
@@ -32,7 +47,17 @@ func (r createDistributedQueryCampaignResponse) Error() error { return r.Err }
 
 func createDistributedQueryCampaignEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
 	req := request.(*createDistributedQueryCampaignRequest)
-	campaign, err := svc.NewDistributedQueryCampaign(ctx, req.QuerySQL, req.QueryID, req.Selected)
+	var campaign *fleet.DistributedQueryCampaign
+	var err error
+	if req.Deferred || req.SaveResults {
+		campaign, err = svc.NewDistributedQueryCampaignWithOptions(ctx, req.QuerySQL, req.QueryID, req.Selected, fleet.DistributedQueryCampaignOptions{
+			Deferred:    req.Deferred,
+			DeferredTTL: req.DeferredTTL.ValueOr(fleet.DeferredQueryDefaultTTL),
+			SaveResults: req.SaveResults,
+		})
+	} else {
+		campaign, err = svc.NewDistributedQueryCampaign(ctx, req.QuerySQL, req.QueryID, req.Selected)
+	}
 	if err != nil {
 		return createDistributedQueryCampaignResponse{Err: err}, nil
 	}
@@ -40,6 +65,29 @@ func createDistributedQueryCampaignEndpoint(ctx context.Context, request interfa
 }
 
 func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString string, queryID *uint, targets fleet.HostTargets) (*fleet.DistributedQueryCampaign, error) {
+	return svc.newDistributedQueryCampaign(ctx, queryString, queryID, targets, fleet.DistributedQueryCampaignOptions{})
+}
+
+func (svc *Service) NewDistributedQueryCampaignWithOptions(ctx context.Context, queryString string, queryID *uint, targets fleet.HostTargets, opts fleet.DistributedQueryCampaignOptions) (*fleet.DistributedQueryCampaign, error) {
+	if err := validateDistributedQueryCampaignOptions(opts); err != nil {
+		// skipauth: the request is invalid regardless of the user's permissions.
+		svc.authz.SkipAuthorization(ctx)
+		return nil, err
+	}
+	return svc.newDistributedQueryCampaign(ctx, queryString, queryID, targets, opts)
+}
+
+func validateDistributedQueryCampaignOptions(opts fleet.DistributedQueryCampaignOptions) error {
+	if opts.Deferred && (opts.DeferredTTL <= 0 || opts.DeferredTTL > fleet.DeferredQueryMaxTTL) {
+		return fleet.NewInvalidArgumentError("deferred_ttl",
+			fmt.Sprintf("must be greater than 0 and at most %s", fleet.DeferredQueryMaxTTL))
+	}
+	return nil
+}
+
+// newDistributedQueryCampaign creates a live query campaign, or a deferred
+// one if opts.Deferred is set.
+func (svc *Service) newDistributedQueryCampaign(ctx context.Context, queryString string, queryID *uint, targets fleet.HostTargets, opts fleet.DistributedQueryCampaignOptions) (*fleet.DistributedQueryCampaign, error) {
 	if err := svc.StatusLiveQuery(ctx); err != nil {
 		return nil, err
 	}
@@ -91,11 +139,20 @@ func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString
 
 	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: query.ObserverCanRun, ObserverTeamID: query.TeamID}
 
-	campaign, err := svc.ds.NewDistributedQueryCampaign(ctx, &fleet.DistributedQueryCampaign{
-		QueryID: query.ID,
-		Status:  fleet.QueryWaiting,
-		UserID:  vc.UserID(),
-	})
+	newCampaign := &fleet.DistributedQueryCampaign{
+		QueryID:     query.ID,
+		Status:      fleet.QueryWaiting,
+		UserID:      vc.UserID(),
+		SaveResults: opts.SaveResults && !opts.Deferred,
+	}
+	if opts.Deferred {
+		// Deferred campaigns are not waiting for a client to stream their
+		// results, they run as soon as they are created.
+		newCampaign.Status = fleet.QueryRunning
+		newCampaign.Deferred = true
+		newCampaign.ExpiresAt = new(svc.clock.Now().UTC().Add(opts.DeferredTTL).Truncate(time.Second))
+	}
+	campaign, err := svc.ds.NewDistributedQueryCampaign(ctx, newCampaign)
 	if err != nil {
 		return nil, ctxerr.Wrap(ctx, err, "new campaign")
 	}
@@ -166,6 +223,22 @@ func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString
 		return nil, ctxerr.Wrap(ctx, err, "run query")
 	}
 
+	if campaign.Deferred {
+		// Live campaigns record their activity when their results stream ends,
+		// deferred campaigns are never streamed so record it now.
+		activity := fleet.ActivityTypeLiveQuery{
+			TargetsCount: campaign.Metrics.TotalHosts,
+			QuerySQL:     queryString,
+			Deferred:     true,
+		}
+		if query.Saved {
+			activity.QueryName = &query.Name
+		}
+		if err := svc.NewActivity(ctx, authz.UserFromContext(ctx), activity); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "create activity for deferred live query")
+		}
+	}
+
 	return campaign, nil
 }
 
@@ -174,9 +247,12 @@ func (svc *Service) NewDistributedQueryCampaign(ctx context.Context, queryString
 ////////////////////////////////////////////////////////////////////////////////
 
 type createDistributedQueryCampaignByIdentifierRequest struct {
-	QuerySQL string                                       `json:"query"`
-	QueryID  *uint                                        `json:"query_id" renameto:"report_id"`
-	Selected distributedQueryCampaignTargetsByIdentifiers `json:"selected"`
+	QuerySQL    string                                       `json:"query"`
+	QueryID     *uint                                        `json:"query_id" renameto:"report_id"`
+	Selected    distributedQueryCampaignTargetsByIdentifiers `json:"selected"`
+	Deferred    bool                                         `json:"deferred"`
+	DeferredTTL fleet.Duration                               `json:"deferred_ttl"`
+	SaveResults bool                                         `json:"save_results"`
 }
 
 type distributedQueryCampaignTargetsByIdentifiers struct {
@@ -189,7 +265,18 @@ func createDistributedQueryCampaignByIdentifierEndpoint(ctx context.Context, req
 	error,
 ) {
 	req := request.(*createDistributedQueryCampaignByIdentifierRequest)
-	campaign, err := svc.NewDistributedQueryCampaignByIdentifiers(ctx, req.QuerySQL, req.QueryID, req.Selected.Hosts, req.Selected.Labels)
+	var campaign *fleet.DistributedQueryCampaign
+	var err error
+	if req.Deferred || req.SaveResults {
+		campaign, err = svc.NewDistributedQueryCampaignByIdentifiersWithOptions(ctx, req.QuerySQL, req.QueryID, req.Selected.Hosts,
+			req.Selected.Labels, fleet.DistributedQueryCampaignOptions{
+				Deferred:    req.Deferred,
+				DeferredTTL: req.DeferredTTL.ValueOr(fleet.DeferredQueryDefaultTTL),
+				SaveResults: req.SaveResults,
+			})
+	} else {
+		campaign, err = svc.NewDistributedQueryCampaignByIdentifiers(ctx, req.QuerySQL, req.QueryID, req.Selected.Hosts, req.Selected.Labels)
+	}
 	if err != nil {
 		return createDistributedQueryCampaignResponse{Err: err}, nil
 	}
@@ -197,30 +284,53 @@ func createDistributedQueryCampaignByIdentifierEndpoint(ctx context.Context, req
 }
 
 func (svc *Service) NewDistributedQueryCampaignByIdentifiers(ctx context.Context, queryString string, queryID *uint, hostIdentifiers []string, labels []string) (*fleet.DistributedQueryCampaign, error) {
+	targets, err := svc.hostTargetsByIdentifiers(ctx, hostIdentifiers, labels)
+	if err != nil {
+		return nil, err
+	}
+	return svc.NewDistributedQueryCampaign(ctx, queryString, queryID, targets)
+}
+
+func (svc *Service) NewDistributedQueryCampaignByIdentifiersWithOptions(ctx context.Context, queryString string, queryID *uint, hostIdentifiers []string, labels []string, opts fleet.DistributedQueryCampaignOptions) (*fleet.DistributedQueryCampaign, error) {
+	if err := validateDistributedQueryCampaignOptions(opts); err != nil {
+		// skipauth: the request is invalid regardless of the user's permissions.
+		svc.authz.SkipAuthorization(ctx)
+		return nil, err
+	}
+	targets, err := svc.hostTargetsByIdentifiers(ctx, hostIdentifiers, labels)
+	if err != nil {
+		return nil, err
+	}
+	return svc.NewDistributedQueryCampaignWithOptions(ctx, queryString, queryID, targets, opts)
+}
+
+// hostTargetsByIdentifiers resolves the hosts (by hostname, UUID or hardware
+// serial) and labels (by name) to target with a campaign.
+func (svc *Service) hostTargetsByIdentifiers(ctx context.Context, hostIdentifiers []string, labels []string) (fleet.HostTargets, error) {
 	vc, ok := viewer.FromContext(ctx)
 	if !ok {
-		return nil, fleet.ErrNoContext
+		return fleet.HostTargets{}, fleet.ErrNoContext
 	}
 	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true}
 
 	hostIDs, err := svc.ds.HostIDsByIdentifier(ctx, filter, hostIdentifiers)
 	if err != nil {
-		return nil, ctxerr.Wrap(ctx, err, "finding host IDs")
+		return fleet.HostTargets{}, ctxerr.Wrap(ctx, err, "finding host IDs")
 	}
 
 	if err := svc.authz.Authorize(ctx, &fleet.Label{}, fleet.ActionRead); err != nil {
-		return nil, err
+		return fleet.HostTargets{}, err
 	}
 	labelMap, err := svc.ds.LabelIDsByName(ctx, labels, fleet.TeamFilter{User: vc.User})
 	if err != nil {
-		return nil, ctxerr.Wrap(ctx, err, "finding label IDs")
+		return fleet.HostTargets{}, ctxerr.Wrap(ctx, err, "finding label IDs")
 	}
 
 	// DetectMissingLabels will return the list of labels that are not found in the database
 	// These labels are considered invalid
 	invalidLabels := fleet.DetectMissingLabels(labelMap, labels)
 	if len(invalidLabels) > 0 {
-		return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{
+		return fleet.HostTargets{}, ctxerr.Wrap(ctx, &fleet.BadRequestError{
 			Message: fmt.Sprintf("%s %s.", fleet.InvalidLabelSpecifiedErrMsg, strings.Join(invalidLabels, ", ")),
 		}, "invalid labels")
 	}
@@ -230,6 +340,559 @@ func (svc *Service) NewDistributedQueryCampaignByIdentifiers(ctx context.Context
 		labelIDs = append(labelIDs, labelID)
 	}
 
-	targets := fleet.HostTargets{HostIDs: hostIDs, LabelIDs: labelIDs}
-	return svc.NewDistributedQueryCampaign(ctx, queryString, queryID, targets)
+	return fleet.HostTargets{HostIDs: hostIDs, LabelIDs: labelIDs}, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get Query Campaign
+////////////////////////////////////////////////////////////////////////////////
+
+type getQueryCampaignRequest struct {
+	ID uint `url:"id"`
+}
+
+type getQueryCampaignResponse struct {
+	Campaign *fleet.QueryCampaignProgress `json:"campaign,omitempty"`
+	Err      error                        `json:"error,omitempty"`
+}
+
+func (r getQueryCampaignResponse) Error() error { return r.Err }
+
+func getQueryCampaignEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*getQueryCampaignRequest)
+	campaign, err := svc.GetQueryCampaign(ctx, req.ID)
+	if err != nil {
+		return getQueryCampaignResponse{Err: err}, nil
+	}
+	return getQueryCampaignResponse{Campaign: campaign}, nil
+}
+
+func (svc *Service) GetQueryCampaign(ctx context.Context, id uint) (*fleet.QueryCampaignProgress, error) {
+	campaign, err := svc.authorizeCampaignWithResults(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := svc.ds.DistributedQueryCampaignTargetIDs(ctx, campaign.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get campaign targets")
+	}
+	filter := fleet.TeamFilter{User: authz.UserFromContext(ctx), IncludeObserver: true}
+	campaign.Metrics, err = svc.ds.CountHostsInTargets(ctx, filter, *targets, svc.clock.Now())
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "counting hosts")
+	}
+	responded, err := svc.ds.CountCampaignQueryResults(ctx, campaign.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "counting results")
+	}
+
+	return &fleet.QueryCampaignProgress{
+		DistributedQueryCampaign: campaign,
+		TargetedHosts:            campaign.Metrics.TotalHosts,
+		RespondedHosts:           responded,
+	}, nil
+}
+
+// authorizeCampaignWithResults loads the campaign with stored results with the
+// given ID and checks that it belongs to the current user.
+func (svc *Service) authorizeCampaignWithResults(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+	// Explicitly set ObserverCanRun: true in this check because we check that
+	// the user trying to read results is the same user that initiated the
+	// query, as when streaming live query results.
+	if err := svc.authz.Authorize(ctx, &fleet.TargetedQuery{Query: &fleet.Query{ObserverCanRun: true}}, fleet.ActionRun); err != nil {
+		return nil, err
+	}
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+
+	// A missing campaign is reported the same way as another user's campaign
+	// so that the existence of campaigns is not leaked.
+	campaign, err := svc.ds.DistributedQueryCampaign(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, authz.ForbiddenWithInternal("campaign not found", vc.User, nil, fleet.ActionRead)
+		}
+		return nil, ctxerr.Wrap(ctx, err, "get campaign")
+	}
+	if campaign.UserID != vc.UserID() {
+		return nil, authz.ForbiddenWithInternal("campaign user ID does not match", vc.User, campaign, fleet.ActionRead)
+	}
+	if !campaign.HasStoredResults() {
+		return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: "campaign results are not stored"})
+	}
+	return campaign, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Campaign Query Results
+////////////////////////////////////////////////////////////////////////////////
+
+type listCampaignQueryResultsRequest struct {
+	ID          uint              `url:"id"`
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type listCampaignQueryResultsResponse struct {
+	Results []*fleet.CampaignQueryResult `json:"results"`
+	Meta    *fleet.PaginationMetadata    `json:"meta"`
+	Err     error                        `json:"error,omitempty"`
+}
+
+func (r listCampaignQueryResultsResponse) Error() error { return r.Err }
+
+func listCampaignQueryResultsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*listCampaignQueryResultsRequest)
+	results, meta, err := svc.ListCampaignQueryResults(ctx, req.ID, req.ListOptions)
+	if err != nil {
+		return listCampaignQueryResultsResponse{Err: err}, nil
+	}
+	return listCampaignQueryResultsResponse{Results: results, Meta: meta}, nil
+}
+
+func (svc *Service) ListCampaignQueryResults(ctx context.Context, id uint, opts fleet.ListOptions) ([]*fleet.CampaignQueryResult, *fleet.PaginationMetadata, error) {
+	if _, err := svc.authorizeCampaignWithResults(ctx, id); err != nil {
+		return nil, nil, err
+	}
+
+	// cursor-based pagination is not supported
+	opts.After = ""
+	// no matching query support
+	opts.MatchQuery = ""
+	// always include metadata
+	opts.IncludeMetadata = true
+
+	results, meta, err := svc.ds.ListCampaignQueryResults(ctx, id, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := svc.loadCampaignQueryResultsData(ctx, results); err != nil {
+		return nil, nil, err
+	}
+	return results, meta, nil
+}
+
+// saveCampaignQueryResult stores the result of a host for a campaign. Rows
+// larger than fleet.MaxStoredCampaignResultSize are put in the campaign
+// results store, when one is configured.
+func (svc *Service) saveCampaignQueryResult(ctx context.Context, campaignID uint, res fleet.DistributedQueryResult) error {
+	result := &fleet.CampaignQueryResult{
+		HostID:          res.Host.ID,
+		Hostname:        res.Host.Hostname,
+		HostDisplayName: res.Host.DisplayName,
+		Rows:            res.Rows,
+		Error:           res.Error,
+		Stats:           res.Stats,
+	}
+
+	if svc.campaignResultsStore != nil && len(res.Rows) > 0 {
+		data, err := json.Marshal(res.Rows)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "marshal campaign query result rows")
+		}
+		if len(data) > fleet.MaxStoredCampaignResultSize {
+			// the key is used as a file name by the store, so it must not
+			// contain any "/".
+			key := fmt.Sprintf("%d-%d", campaignID, res.Host.ID)
+			if err := svc.campaignResultsStore.Put(ctx, key, bytes.NewReader(data)); err != nil {
+				return ctxerr.Wrap(ctx, err, "put campaign query result rows in store")
+			}
+			result.DataKey = &key
+			result.Rows = nil
+		}
+	}
+
+	return svc.ds.SaveCampaignQueryResult(ctx, campaignID, result)
+}
+
+// loadCampaignQueryResultsData loads the rows of the results that are in the
+// campaign results store.
+func (svc *Service) loadCampaignQueryResultsData(ctx context.Context, results []*fleet.CampaignQueryResult) error {
+	for _, res := range results {
+		if res.DataKey == nil {
+			continue
+		}
+		if svc.campaignResultsStore == nil {
+			return ctxerr.New(ctx, "campaign query result rows are stored but no campaign results store is configured")
+		}
+		rc, _, err := svc.campaignResultsStore.Get(ctx, *res.DataKey)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get campaign query result rows from store")
+		}
+		err = json.NewDecoder(rc).Decode(&res.Rows)
+		rc.Close()
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "decode campaign query result rows")
+		}
+	}
+	return nil
+}
+
+// listAllCampaignQueryResults returns all the stored results of a campaign,
+// ordered by host ID.
+func (svc *Service) listAllCampaignQueryResults(ctx context.Context, id uint) ([]*fleet.CampaignQueryResult, error) {
+	var all []*fleet.CampaignQueryResult
+	opts := fleet.ListOptions{PerPage: campaignQueryResultsExportPageSize, IncludeMetadata: true}
+	for {
+		results, meta, err := svc.ds.ListCampaignQueryResults(ctx, id, opts)
+		if err != nil {
+			return nil, err
+		}
+		if err := svc.loadCampaignQueryResultsData(ctx, results); err != nil {
+			return nil, err
+		}
+		all = append(all, results...)
+		if !meta.HasNextResults {
+			return all, nil
+		}
+		opts.Page++
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// List Query Campaigns
+////////////////////////////////////////////////////////////////////////////////
+
+type listQueryCampaignsRequest struct {
+	QueryID     *uint             `query:"query_id,optional" renameto:"report_id"`
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type listQueryCampaignsResponse struct {
+	Campaigns []*fleet.QueryCampaignSummary `json:"campaigns"`
+	Meta      *fleet.PaginationMetadata     `json:"meta"`
+	Err       error                         `json:"error,omitempty"`
+}
+
+func (r listQueryCampaignsResponse) Error() error { return r.Err }
+
+func listQueryCampaignsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*listQueryCampaignsRequest)
+	campaigns, meta, err := svc.ListQueryCampaigns(ctx, req.QueryID, req.ListOptions)
+	if err != nil {
+		return listQueryCampaignsResponse{Err: err}, nil
+	}
+	return listQueryCampaignsResponse{Campaigns: campaigns, Meta: meta}, nil
+}
+
+func (svc *Service) ListQueryCampaigns(ctx context.Context, queryID *uint, opts fleet.ListOptions) ([]*fleet.QueryCampaignSummary, *fleet.PaginationMetadata, error) {
+	// Users can only list the campaigns they created, see
+	// authorizeCampaignWithResults.
+	if err := svc.authz.Authorize(ctx, &fleet.TargetedQuery{Query: &fleet.Query{ObserverCanRun: true}}, fleet.ActionRun); err != nil {
+		return nil, nil, err
+	}
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, nil, fleet.ErrNoContext
+	}
+
+	// cursor-based pagination is not supported
+	opts.After = ""
+	// no matching query support
+	opts.MatchQuery = ""
+	// always include metadata
+	opts.IncludeMetadata = true
+
+	return svc.ds.ListQueryCampaigns(ctx, fleet.ListQueryCampaignsOptions{
+		ListOptions: opts,
+		UserID:      vc.UserID(),
+		QueryID:     queryID,
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Export Campaign Query Results
+////////////////////////////////////////////////////////////////////////////////
+
+type exportCampaignQueryResultsRequest struct {
+	ID     uint   `url:"id"`
+	Format string `query:"format,optional"`
+}
+
+type exportCampaignQueryResultsResponse struct {
+	CampaignID uint                         `json:"-"`
+	Format     string                       `json:"-"`
+	Results    []*fleet.CampaignQueryResult `json:"-"` // they get rendered explicitly, in csv or json
+	Err        error                        `json:"error,omitempty"`
+}
+
+func (r exportCampaignQueryResultsResponse) Error() error { return r.Err }
+
+// HijackRender writes the results as CSV, with one record per row returned by
+// each host, or as a JSON array of the results of each host. In CSV, hosts that
+// returned no rows or an error get a single record with empty columns.
+func (r exportCampaignQueryResultsResponse) HijackRender(ctx context.Context, w http.ResponseWriter) {
+	filename := fmt.Sprintf("Campaign %d results %s.%s", r.CampaignID, time.Now().Format("2006-01-02"), r.Format)
+	w.Header().Add("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if r.Format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		results := r.Results
+		if results == nil {
+			results = []*fleet.CampaignQueryResult{}
+		}
+		if err := json.NewEncoder(w).Encode(results); err != nil {
+			logging.WithErr(ctx, err)
+		}
+		return
+	}
+
+	columnSet := make(map[string]struct{})
+	for _, res := range r.Results {
+		for _, row := range res.Rows {
+			for col := range row {
+				columnSet[col] = struct{}{}
+			}
+		}
+	}
+	columns := make([]string, 0, len(columnSet))
+	for col := range columnSet {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	header := append([]string{"host_id", "hostname", "host_display_name", "error"}, columns...)
+	records := [][]string{header}
+	for _, res := range r.Results {
+		var errMsg string
+		if res.Error != nil {
+			errMsg = *res.Error
+		}
+		hostFields := []string{fmt.Sprint(res.HostID), res.Hostname, res.HostDisplayName, errMsg}
+
+		rows := res.Rows
+		if len(rows) == 0 {
+			rows = []map[string]string{nil}
+		}
+		for _, row := range rows {
+			record := make([]string, 0, len(header))
+			record = append(record, hostFields...)
+			for _, col := range columns {
+				record = append(record, row[col])
+			}
+			for i, cell := range record {
+				record[i] = sanitizeCSVFormula(cell)
+			}
+			records = append(records, record)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+
+	if err := csv.NewWriter(w).WriteAll(records); err != nil {
+		logging.WithErr(ctx, err)
+	}
+}
+
+func exportCampaignQueryResultsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*exportCampaignQueryResultsRequest)
+
+	var all []*fleet.CampaignQueryResult
+	opts := fleet.ListOptions{PerPage: campaignQueryResultsExportPageSize}
+	for {
+		results, meta, err := svc.ListCampaignQueryResults(ctx, req.ID, opts)
+		if err != nil {
+			return exportCampaignQueryResultsResponse{Err: err}, nil
+		}
+		all = append(all, results...)
+		if !meta.HasNextResults {
+			break
+		}
+		opts.Page++
+	}
+
+	// the format is validated once the campaign is authorized
+	format := req.Format
+	switch format {
+	case "":
+		format = "csv"
+	case "csv", "json":
+	default:
+		return exportCampaignQueryResultsResponse{
+			Err: fleet.NewInvalidArgumentError("format", `must be "csv" or "json"`),
+		}, nil
+	}
+	return exportCampaignQueryResultsResponse{CampaignID: req.ID, Format: format, Results: all}, nil
+}
+
+const campaignQueryResultsExportPageSize = 1000
+
+////////////////////////////////////////////////////////////////////////////////
+// Diff Campaign Query Results
+////////////////////////////////////////////////////////////////////////////////
+
+type diffCampaignQueryResultsRequest struct {
+	ID                 uint `url:"id"`
+	PreviousCampaignID uint `query:"previous_campaign_id"`
+}
+
+type diffCampaignQueryResultsResponse struct {
+	*fleet.QueryCampaignResultsDiff
+	Err error `json:"error,omitempty"`
+}
+
+func (r diffCampaignQueryResultsResponse) Error() error { return r.Err }
+
+func diffCampaignQueryResultsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*diffCampaignQueryResultsRequest)
+	diff, err := svc.DiffCampaignQueryResults(ctx, req.ID, req.PreviousCampaignID)
+	if err != nil {
+		return diffCampaignQueryResultsResponse{Err: err}, nil
+	}
+	return diffCampaignQueryResultsResponse{QueryCampaignResultsDiff: diff}, nil
+}
+
+func (svc *Service) DiffCampaignQueryResults(ctx context.Context, id uint, previousID uint) (*fleet.QueryCampaignResultsDiff, error) {
+	campaign, err := svc.authorizeCampaignWithResults(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := svc.authorizeCampaignWithResults(ctx, previousID)
+	if err != nil {
+		return nil, err
+	}
+
+	if campaign.QueryID != previous.QueryID {
+		// Live queries that are not saved get a new query for each campaign,
+		// compare their SQL instead.
+		query, err := svc.ds.Query(ctx, campaign.QueryID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get campaign query")
+		}
+		previousQuery, err := svc.ds.Query(ctx, previous.QueryID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get previous campaign query")
+		}
+		if query.Saved || previousQuery.Saved || strings.TrimSpace(query.Query) != strings.TrimSpace(previousQuery.Query) {
+			return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{Message: "campaigns must be runs of the same query"})
+		}
+	}
+
+	results, err := svc.listAllCampaignQueryResults(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+	previousResults, err := svc.listAllCampaignQueryResults(ctx, previous.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fleet.QueryCampaignResultsDiff{
+		CampaignID:         campaign.ID,
+		PreviousCampaignID: previous.ID,
+		Hosts:              diffCampaignQueryResults(results, previousResults),
+	}, nil
+}
+
+// diffCampaignQueryResults returns the hosts whose results differ, ordered by
+// host ID. The rows of a host are compared as a multiset, their order does not
+// matter.
+func diffCampaignQueryResults(results, previousResults []*fleet.CampaignQueryResult) []fleet.HostQueryResultsDiff {
+	byHost := make(map[uint]*fleet.CampaignQueryResult, len(results))
+	for _, res := range results {
+		byHost[res.HostID] = res
+	}
+	previousByHost := make(map[uint]*fleet.CampaignQueryResult, len(previousResults))
+	for _, res := range previousResults {
+		previousByHost[res.HostID] = res
+	}
+
+	hostIDs := make([]uint, 0, len(byHost)+len(previousByHost))
+	for id := range byHost {
+		hostIDs = append(hostIDs, id)
+	}
+	for id := range previousByHost {
+		if _, ok := byHost[id]; !ok {
+			hostIDs = append(hostIDs, id)
+		}
+	}
+	slices.Sort(hostIDs)
+
+	diffs := []fleet.HostQueryResultsDiff{}
+	for _, id := range hostIDs {
+		cur, prev := byHost[id], previousByHost[id]
+		switch {
+		case prev == nil:
+			diffs = append(diffs, fleet.HostQueryResultsDiff{
+				HostID:          cur.HostID,
+				Hostname:        cur.Hostname,
+				HostDisplayName: cur.HostDisplayName,
+				Status:          fleet.HostQueryResultsNew,
+				Added:           nonNilRows(cur.Rows),
+				Removed:         []map[string]string{},
+				Error:           cur.Error,
+			})
+		case cur == nil:
+			diffs = append(diffs, fleet.HostQueryResultsDiff{
+				HostID:          prev.HostID,
+				Hostname:        prev.Hostname,
+				HostDisplayName: prev.HostDisplayName,
+				Status:          fleet.HostQueryResultsMissing,
+				Added:           []map[string]string{},
+				Removed:         nonNilRows(prev.Rows),
+				PreviousError:   prev.Error,
+			})
+		default:
+			added, removed := diffRows(cur.Rows, prev.Rows)
+			if len(added) == 0 && len(removed) == 0 && ptr.ValOrZero(cur.Error) == ptr.ValOrZero(prev.Error) {
+				continue
+			}
+			diffs = append(diffs, fleet.HostQueryResultsDiff{
+				HostID:          cur.HostID,
+				Hostname:        cur.Hostname,
+				HostDisplayName: cur.HostDisplayName,
+				Status:          fleet.HostQueryResultsChanged,
+				Added:           added,
+				Removed:         removed,
+				Error:           cur.Error,
+				PreviousError:   prev.Error,
+			})
+		}
+	}
+	return diffs
+}
+
+// diffRows returns the rows that are only in rows (added) and only in
+// previousRows (removed), counting duplicated rows.
+func diffRows(rows, previousRows []map[string]string) (added, removed []map[string]string) {
+	// maps are marshaled with sorted keys, so equal rows have the same key
+	rowKey := func(row map[string]string) string {
+		b, _ := json.Marshal(row)
+		return string(b)
+	}
+
+	previousCounts := make(map[string]int, len(previousRows))
+	for _, row := range previousRows {
+		previousCounts[rowKey(row)]++
+	}
+
+	added, removed = []map[string]string{}, []map[string]string{}
+	for _, row := range rows {
+		k := rowKey(row)
+		if previousCounts[k] > 0 {
+			previousCounts[k]--
+			continue
+		}
+		added = append(added, row)
+	}
+	for _, row := range previousRows {
+		k := rowKey(row)
+		if previousCounts[k] > 0 {
+			previousCounts[k]--
+			removed = append(removed, row)
+		}
+	}
+	return added, removed
+}
+
+func nonNilRows(rows []map[string]string) []map[string]string {
+	if rows == nil {
+		return []map[string]string{}
+	}
+	return rows
 }
@@ -1,10 +1,21 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
+	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/mock"
@@ -421,3 +432,405 @@ func TestLiveQueryLabelBypassPrevented(t *testing.T) {
 		})
 	}
 }
+
+func TestDeferredLiveQuery(t *testing.T) {
+	ds := new(mock.Store)
+	qr := pubsub.NewInmemQueryResults()
+	opts := &TestServerOpts{}
+	svc, ctx := newTestService(t, ds, qr, nopLiveQuery{}, opts)
+
+	admin := &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}
+	otherAdmin := &fleet.User{ID: 2, GlobalRole: ptr.String(fleet.RoleAdmin)}
+	query := &fleet.Query{ID: 1, Name: "q1", Query: "SELECT 1", Saved: true}
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
+		return query, nil
+	}
+	var created *fleet.DistributedQueryCampaign
+	ds.NewDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaign, error) {
+		camp.ID = 10
+		created = camp
+		return camp, nil
+	}
+	ds.NewDistributedQueryCampaignTargetFunc = func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
+		return target, nil
+	}
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filters fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+		return []uint{1, 2, 3}, nil
+	}
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filters fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+		return fleet.TargetMetrics{TotalHosts: 3, OnlineHosts: 1, OfflineHosts: 2}, nil
+	}
+	var activities []fleet.ActivityTypeLiveQuery
+	opts.ActivityMock.NewActivityFunc = func(_ context.Context, _ *activity_api.User, act activity_api.ActivityDetails) error {
+		activities = append(activities, act.(fleet.ActivityTypeLiveQuery))
+		return nil
+	}
+
+	adminCtx := viewer.NewContext(ctx, viewer.Viewer{User: admin})
+	targets := fleet.HostTargets{HostIDs: []uint{1, 2, 3}}
+
+	// invalid TTLs
+	for _, ttl := range []time.Duration{0, -time.Hour, fleet.DeferredQueryMaxTTL + time.Second} {
+		_, err := svc.NewDistributedQueryCampaignWithOptions(adminCtx, "", &query.ID, targets,
+			fleet.DistributedQueryCampaignOptions{Deferred: true, DeferredTTL: ttl})
+		var iae *fleet.InvalidArgumentError
+		require.ErrorAs(t, err, &iae, ttl)
+		require.Contains(t, err.Error(), "deferred_ttl")
+	}
+	require.Nil(t, created)
+
+	before := time.Now().UTC()
+	campaign, err := svc.NewDistributedQueryCampaignWithOptions(adminCtx, "", &query.ID, targets,
+		fleet.DistributedQueryCampaignOptions{Deferred: true, DeferredTTL: 2 * time.Hour})
+	require.NoError(t, err)
+	require.True(t, campaign.Deferred)
+	require.Equal(t, fleet.QueryRunning, campaign.Status)
+	require.NotNil(t, campaign.ExpiresAt)
+	require.WithinDuration(t, before.Add(2*time.Hour), *campaign.ExpiresAt, 5*time.Second)
+	require.Len(t, activities, 1)
+	require.True(t, activities[0].Deferred)
+	require.Equal(t, uint(3), activities[0].TargetsCount)
+	require.Equal(t, ptr.String("q1"), activities[0].QueryName)
+
+	// regular live queries are not deferred and don't create an activity yet
+	campaign, err = svc.NewDistributedQueryCampaign(adminCtx, "", &query.ID, targets)
+	require.NoError(t, err)
+	require.False(t, campaign.Deferred)
+	require.Nil(t, campaign.ExpiresAt)
+	require.Equal(t, fleet.QueryWaiting, campaign.Status)
+	require.Len(t, activities, 1)
+
+	campaigns := map[uint]*fleet.DistributedQueryCampaign{
+		10: {ID: 10, UserID: admin.ID, Deferred: true, Status: fleet.QueryRunning},
+		11: {ID: 11, UserID: admin.ID},
+	}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		c, ok := campaigns[id]
+		if !ok {
+			return nil, sql.ErrNoRows
+		}
+		return c, nil
+	}
+	ds.DistributedQueryCampaignTargetIDsFunc = func(ctx context.Context, id uint) (*fleet.HostTargets, error) {
+		return &targets, nil
+	}
+	ds.CountCampaignQueryResultsFunc = func(ctx context.Context, campaignID uint) (uint, error) {
+		return 2, nil
+	}
+	ds.ListCampaignQueryResultsFunc = func(ctx context.Context, campaignID uint, opts fleet.ListOptions) ([]*fleet.CampaignQueryResult, *fleet.PaginationMetadata, error) {
+		require.True(t, opts.IncludeMetadata)
+		return []*fleet.CampaignQueryResult{{HostID: 1}, {HostID: 2}}, &fleet.PaginationMetadata{}, nil
+	}
+
+	got, err := svc.GetQueryCampaign(adminCtx, 10)
+	require.NoError(t, err)
+	require.Equal(t, uint(3), got.TargetedHosts)
+	require.Equal(t, uint(2), got.RespondedHosts)
+	results, meta, err := svc.ListCampaignQueryResults(adminCtx, 10, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.NotNil(t, meta)
+
+	// live campaigns have no stored results
+	_, err = svc.GetQueryCampaign(adminCtx, 11)
+	var bre *fleet.BadRequestError
+	require.ErrorAs(t, err, &bre)
+
+	// only the user that created the campaign can access it, and missing
+	// campaigns cannot be told apart from other users' campaigns
+	otherCtx := viewer.NewContext(ctx, viewer.Viewer{User: otherAdmin})
+	_, err = svc.GetQueryCampaign(otherCtx, 10)
+	checkAuthErr(t, true, err)
+	_, _, err = svc.ListCampaignQueryResults(otherCtx, 10, fleet.ListOptions{})
+	checkAuthErr(t, true, err)
+	_, err = svc.GetQueryCampaign(adminCtx, 12)
+	checkAuthErr(t, true, err)
+}
+
+func TestExportCampaignQueryResultsCSV(t *testing.T) {
+	errMsg := "no such table: foo"
+	resp := exportCampaignQueryResultsResponse{
+		CampaignID: 1,
+		Format:     "csv",
+		Results: []*fleet.CampaignQueryResult{
+			{HostID: 1, Hostname: "h1", HostDisplayName: "H1", Rows: []map[string]string{{"b": "1", "a": "2"}, {"c": "=3"}}},
+			{HostID: 2, Hostname: "h2", HostDisplayName: "H2", Error: &errMsg},
+			{HostID: 3, Hostname: "h3", HostDisplayName: "H3", Rows: []map[string]string{}},
+		},
+	}
+	rec := httptest.NewRecorder()
+	resp.HijackRender(t.Context(), rec)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"host_id", "hostname", "host_display_name", "error", "a", "b", "c"},
+		{"1", "h1", "H1", "", "2", "1", ""},
+		{"1", "h1", "H1", "", "", "", "'=3"},
+		{"2", "h2", "H2", errMsg, "", "", ""},
+		{"3", "h3", "H3", "", "", "", ""},
+	}, records)
+}
+
+func TestExportCampaignQueryResultsJSON(t *testing.T) {
+	resp := exportCampaignQueryResultsResponse{
+		CampaignID: 1,
+		Format:     "json",
+		Results: []*fleet.CampaignQueryResult{
+			{HostID: 1, Hostname: "h1", HostDisplayName: "H1", Rows: []map[string]string{{"a": "=1"}}},
+		},
+	}
+	rec := httptest.NewRecorder()
+	resp.HijackRender(t.Context(), rec)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Header().Get("Content-Disposition"), ".json")
+
+	var results []*fleet.CampaignQueryResult
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&results))
+	require.Len(t, results, 1)
+	// values are not sanitized in JSON
+	require.Equal(t, []map[string]string{{"a": "=1"}}, results[0].Rows)
+}
+
+type memCampaignResultsStore struct {
+	data map[string][]byte
+}
+
+func (s *memCampaignResultsStore) Put(_ context.Context, key string, content io.ReadSeeker) error {
+	b, err := io.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	s.data[key] = b
+	return nil
+}
+
+func (s *memCampaignResultsStore) Get(_ context.Context, key string) (io.ReadCloser, int64, error) {
+	b, ok := s.data[key]
+	if !ok {
+		return nil, 0, errors.New("not found")
+	}
+	return io.NopCloser(bytes.NewReader(b)), int64(len(b)), nil
+}
+
+func (s *memCampaignResultsStore) Cleanup(_ context.Context, usedKeys []string, _ time.Time) (int, error) {
+	var n int
+	for k := range s.data {
+		if !slices.Contains(usedKeys, k) {
+			delete(s.data, k)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func TestSavedLiveQueryResults(t *testing.T) {
+	ds := new(mock.Store)
+	store := &memCampaignResultsStore{data: make(map[string][]byte)}
+	opts := &TestServerOpts{CampaignResultsStore: store}
+	svc, ctx := newTestService(t, ds, pubsub.NewInmemQueryResults(), nopLiveQuery{}, opts)
+
+	admin := &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}
+	query := &fleet.Query{ID: 1, Name: "q1", Query: "SELECT 1", Saved: true}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
+		return query, nil
+	}
+	ds.NewDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaign, error) {
+		camp.ID = 10
+		return camp, nil
+	}
+	ds.NewDistributedQueryCampaignTargetFunc = func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
+		return target, nil
+	}
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filters fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+		return []uint{1, 2}, nil
+	}
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filters fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+		return fleet.TargetMetrics{TotalHosts: 2, OnlineHosts: 2}, nil
+	}
+	opts.ActivityMock.NewActivityFunc = func(_ context.Context, _ *activity_api.User, act activity_api.ActivityDetails) error {
+		t.Fatal("live campaigns record their activity when their results stream ends")
+		return nil
+	}
+
+	adminCtx := viewer.NewContext(ctx, viewer.Viewer{User: admin})
+	campaign, err := svc.NewDistributedQueryCampaignWithOptions(adminCtx, "", &query.ID, fleet.HostTargets{HostIDs: []uint{1, 2}},
+		fleet.DistributedQueryCampaignOptions{SaveResults: true})
+	require.NoError(t, err)
+	require.True(t, campaign.SaveResults)
+	require.False(t, campaign.Deferred)
+	require.Equal(t, fleet.QueryWaiting, campaign.Status)
+
+	saved := make(map[uint]*fleet.CampaignQueryResult)
+	ds.SaveCampaignQueryResultFunc = func(ctx context.Context, campaignID uint, result *fleet.CampaignQueryResult) error {
+		require.Equal(t, uint(10), campaignID)
+		saved[result.HostID] = result
+		return nil
+	}
+
+	// small results are stored in the database
+	stats := &fleet.Stats{WallTimeMs: 10}
+	err = svc.(validationMiddleware).Service.(*Service).saveCampaignQueryResult(ctx, 10, fleet.DistributedQueryResult{
+		Host:  fleet.ResultHostData{ID: 1, Hostname: "h1", DisplayName: "H1"},
+		Rows:  []map[string]string{{"a": "1"}},
+		Stats: stats,
+	})
+	require.NoError(t, err)
+	require.Nil(t, saved[1].DataKey)
+	require.Equal(t, []map[string]string{{"a": "1"}}, saved[1].Rows)
+	require.Equal(t, stats, saved[1].Stats)
+	require.Empty(t, store.data)
+
+	// large results spill to the store
+	largeRows := []map[string]string{{"a": strings.Repeat("x", fleet.MaxStoredCampaignResultSize)}}
+	err = svc.(validationMiddleware).Service.(*Service).saveCampaignQueryResult(ctx, 10, fleet.DistributedQueryResult{
+		Host: fleet.ResultHostData{ID: 2, Hostname: "h2", DisplayName: "H2"},
+		Rows: largeRows,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, saved[2].DataKey)
+	require.Equal(t, "10-2", *saved[2].DataKey)
+	require.Nil(t, saved[2].Rows)
+	require.Contains(t, store.data, "10-2")
+
+	// stored rows are loaded when listing the results
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return &fleet.DistributedQueryCampaign{ID: id, UserID: admin.ID, SaveResults: true, Status: fleet.QueryComplete}, nil
+	}
+	ds.ListCampaignQueryResultsFunc = func(ctx context.Context, campaignID uint, opts fleet.ListOptions) ([]*fleet.CampaignQueryResult, *fleet.PaginationMetadata, error) {
+		return []*fleet.CampaignQueryResult{
+			{HostID: 1, Rows: saved[1].Rows},
+			{HostID: 2, DataKey: saved[2].DataKey},
+		}, &fleet.PaginationMetadata{}, nil
+	}
+	results, _, err := svc.ListCampaignQueryResults(adminCtx, 10, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, largeRows, results[1].Rows)
+
+	// campaigns with stored results are listed for the current user
+	ds.ListQueryCampaignsFunc = func(ctx context.Context, opts fleet.ListQueryCampaignsOptions) ([]*fleet.QueryCampaignSummary, *fleet.PaginationMetadata, error) {
+		require.Equal(t, admin.ID, opts.UserID)
+		require.Equal(t, ptr.Uint(1), opts.QueryID)
+		require.True(t, opts.IncludeMetadata)
+		return []*fleet.QueryCampaignSummary{{ID: 10, QueryID: 1, SaveResults: true, RespondedHosts: 2}}, &fleet.PaginationMetadata{}, nil
+	}
+	summaries, meta, err := svc.ListQueryCampaigns(adminCtx, ptr.Uint(1), fleet.ListOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	require.Len(t, summaries, 1)
+	require.Equal(t, uint(2), summaries[0].RespondedHosts)
+}
+
+func TestDiffCampaignQueryResults(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	admin := &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}
+	adminCtx := viewer.NewContext(ctx, viewer.Viewer{User: admin})
+
+	campaigns := map[uint]*fleet.DistributedQueryCampaign{
+		1: {ID: 1, QueryID: 1, UserID: admin.ID, SaveResults: true},
+		2: {ID: 2, QueryID: 1, UserID: admin.ID, Deferred: true},
+		// ad hoc queries with the same SQL as query 2
+		3: {ID: 3, QueryID: 3, UserID: admin.ID, SaveResults: true},
+		4: {ID: 4, QueryID: 4, UserID: admin.ID, SaveResults: true},
+		// a different query
+		5: {ID: 5, QueryID: 5, UserID: admin.ID, SaveResults: true},
+	}
+	queries := map[uint]*fleet.Query{
+		1: {ID: 1, Query: "SELECT 1", Saved: true},
+		3: {ID: 3, Query: "SELECT * FROM osquery_info"},
+		4: {ID: 4, Query: "SELECT * FROM osquery_info "},
+		5: {ID: 5, Query: "SELECT * FROM system_info"},
+	}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		c, ok := campaigns[id]
+		if !ok {
+			return nil, sql.ErrNoRows
+		}
+		return c, nil
+	}
+	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
+		return queries[id], nil
+	}
+
+	errMsg := "failed"
+	results := map[uint][]*fleet.CampaignQueryResult{
+		1: {
+			{HostID: 1, Rows: []map[string]string{{"a": "1"}, {"a": "1"}, {"a": "2"}}},
+			{HostID: 2, Rows: []map[string]string{{"a": "1"}}},
+			{HostID: 3, Error: &errMsg},
+			{HostID: 5, Rows: []map[string]string{{"b": "1"}}},
+		},
+		2: {
+			{HostID: 1, Rows: []map[string]string{{"a": "2"}, {"a": "1"}, {"a": "3"}}},
+			{HostID: 2, Rows: []map[string]string{{"a": "1"}}},
+			{HostID: 3, Rows: []map[string]string{}},
+			{HostID: 4, Rows: []map[string]string{{"c": "1"}}},
+		},
+	}
+	ds.ListCampaignQueryResultsFunc = func(ctx context.Context, campaignID uint, opts fleet.ListOptions) ([]*fleet.CampaignQueryResult, *fleet.PaginationMetadata, error) {
+		return results[campaignID], &fleet.PaginationMetadata{}, nil
+	}
+
+	diff, err := svc.DiffCampaignQueryResults(adminCtx, 1, 2)
+	require.NoError(t, err)
+	require.Equal(t, uint(1), diff.CampaignID)
+	require.Equal(t, uint(2), diff.PreviousCampaignID)
+	require.Equal(t, []fleet.HostQueryResultsDiff{
+		{
+			HostID:  1,
+			Status:  fleet.HostQueryResultsChanged,
+			Added:   []map[string]string{{"a": "1"}},
+			Removed: []map[string]string{{"a": "3"}},
+		},
+		{
+			HostID:  3,
+			Status:  fleet.HostQueryResultsChanged,
+			Added:   []map[string]string{},
+			Removed: []map[string]string{},
+			Error:   &errMsg,
+		},
+		{
+			HostID:  4,
+			Status:  fleet.HostQueryResultsMissing,
+			Added:   []map[string]string{},
+			Removed: []map[string]string{{"c": "1"}},
+		},
+		{
+			HostID:  5,
+			Status:  fleet.HostQueryResultsNew,
+			Added:   []map[string]string{{"b": "1"}},
+			Removed: []map[string]string{},
+		},
+	}, diff.Hosts)
+
+	// ad hoc queries with the same SQL can be compared
+	_, err = svc.DiffCampaignQueryResults(adminCtx, 4, 3)
+	require.NoError(t, err)
+
+	// runs of different queries cannot be compared
+	var bre *fleet.BadRequestError
+	_, err = svc.DiffCampaignQueryResults(adminCtx, 5, 3)
+	require.ErrorAs(t, err, &bre)
+	_, err = svc.DiffCampaignQueryResults(adminCtx, 1, 3)
+	require.ErrorAs(t, err, &bre)
+
+	// campaigns of other users cannot be compared
+	otherCtx := viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 2, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+	_, err = svc.DiffCampaignQueryResults(otherCtx, 1, 2)
+	checkAuthErr(t, true, err)
+	_, err = svc.DiffCampaignQueryResults(adminCtx, 1, 42)
+	checkAuthErr(t, true, err)
+}
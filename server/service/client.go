@@ -499,6 +499,26 @@ func getProfilesContents(baseDir string, macProfiles, windowsProfiles, androidPr
 	return result, nil
 }
 
+// GitOpsProfilesContents returns the configuration profiles of the GitOps
+// controls the way they are sent to the server when applied, so that they can
+// be compared against the profiles currently on the server.
+func GitOpsProfilesContents(baseDir string, controls spec.GitOpsControls) ([]fleet.MDMProfileBatchPayload, error) {
+	appCfg := map[string]interface{}{
+		"mdm": map[string]interface{}{
+			"macos_settings":   controls.MacOSSettings,
+			"windows_settings": controls.WindowsSettings,
+			"android_settings": controls.AndroidSettings,
+		},
+	}
+	return getProfilesContents(
+		baseDir,
+		extractAppCfgMacOSCustomSettings(appCfg),
+		extractAppCfgWindowsCustomSettings(appCfg),
+		extractAppCfgAndroidCustomSettings(appCfg),
+		true,
+	)
+}
+
 // getAssetsContents reads and env-expands the Apple DDM asset files referenced
 // by the given specs, returning them as batch payloads. As with profiles,
 // FLEET_SECRET_ variables are left for the server to expand.
@@ -2359,9 +2379,15 @@ func (c *Client) DoGitOps(
 		if zendesk, ok := integrations.(map[string]interface{})["zendesk"]; !ok || zendesk == nil {
 			integrations.(map[string]interface{})["zendesk"] = []interface{}{}
 		}
+		if serviceNow, ok := integrations.(map[string]any)["servicenow"]; !ok || serviceNow == nil {
+			integrations.(map[string]any)["servicenow"] = []any{}
+		}
 		if googleCal, ok := integrations.(map[string]interface{})["google_calendar"]; !ok || googleCal == nil {
 			integrations.(map[string]interface{})["google_calendar"] = []interface{}{}
 		}
+		if msCal, ok := integrations.(map[string]any)["microsoft_calendar"]; !ok || msCal == nil {
+			integrations.(map[string]any)["microsoft_calendar"] = []any{}
+		}
 		// Google Workspace is cleared when it is not set, set to empty, or when all
 		// of its entries have only empty fields (e.g. from unset GitOps variables),
 		// so the declarative "absent means remove" behavior holds.
@@ -2428,6 +2454,15 @@ func (c *Client) DoGitOps(
 			vulnerabilitiesWebhook.(map[string]any)["enable_vulnerabilities_webhook"] = false
 		}
 
+		gitOpsDriftWebhook, ok := webhookSettings.(map[string]any)["gitops_drift_webhook"]
+		if !ok || gitOpsDriftWebhook == nil {
+			gitOpsDriftWebhook = map[string]any{}
+			webhookSettings.(map[string]any)["gitops_drift_webhook"] = gitOpsDriftWebhook
+		}
+		if _, ok := gitOpsDriftWebhook.(map[string]any)["enable_gitops_drift_webhook"]; !ok {
+			gitOpsDriftWebhook.(map[string]any)["enable_gitops_drift_webhook"] = false
+		}
+
 		// Ensure mdm config exists
 		mdmConfig, ok := group.AppConfig.(map[string]interface{})["mdm"]
 		if !ok || mdmConfig == nil {
@@ -2552,6 +2587,18 @@ func (c *Client) DoGitOps(
 			return nil, err
 		}
 
+		// Custom roles are global-only. Unlike custom host vitals, an absent
+		// `custom_roles:` key leaves the existing roles untouched.
+		if err := c.doGitOpsCustomRoles(incoming, logFn, dryRun); err != nil {
+			return nil, err
+		}
+
+		// Vulnerability exceptions are global-only (scoped to a fleet by name),
+		// and like custom roles an absent key leaves them untouched.
+		if err := c.doGitOpsVulnerabilityExceptions(incoming, logFn, dryRun); err != nil {
+			return nil, err
+		}
+
 	} else if !incoming.IsNoTeam() {
 		team = make(map[string]interface{})
 		team["name"] = *incoming.TeamName
@@ -3428,6 +3475,148 @@ func (c *Client) doGitOpsCustomHostVitals(config *spec.GitOps, logFn func(format
 	return c.SaveCustomHostVitals(desired, false)
 }
 
+// doGitOpsCustomRoles reconciles custom roles against the `custom_roles:` key
+// of the global config. Roles are matched by name (case-insensitively, like
+// the server does); roles absent from the list are deleted.
+func (c *Client) doGitOpsCustomRoles(config *spec.GitOps, logFn func(format string, args ...any), dryRun bool) error {
+	if config.TeamName != nil || !config.CustomRolesPresent {
+		return nil
+	}
+
+	existing, err := c.ListCustomRoles()
+	if err != nil {
+		return err
+	}
+
+	existingByName := make(map[string]fleet.CustomRole, len(existing))
+	for _, r := range existing {
+		existingByName[strings.ToLower(r.Name)] = r
+	}
+	desiredNames := make(map[string]struct{}, len(config.CustomRoles))
+	var toAdd, toUpdate, toDelete []string
+	for _, r := range config.CustomRoles {
+		key := strings.ToLower(r.Name)
+		desiredNames[key] = struct{}{}
+		prev, ok := existingByName[key]
+		switch {
+		case !ok:
+			toAdd = append(toAdd, r.Name)
+		case prev.Name != r.Name || prev.Description != r.Description || !slices.Equal(prev.Permissions, r.Permissions):
+			toUpdate = append(toUpdate, r.Name)
+		}
+	}
+	for _, r := range existing {
+		if _, ok := desiredNames[strings.ToLower(r.Name)]; !ok {
+			toDelete = append(toDelete, r.Name)
+		}
+	}
+
+	if dryRun {
+		for _, name := range toDelete {
+			logFn("[-] would've deleted custom role '%s'\n", name)
+		}
+		for _, name := range toUpdate {
+			logFn("[+] would've updated custom role '%s'\n", name)
+		}
+		for _, name := range toAdd {
+			logFn("[+] would've created custom role '%s'\n", name)
+		}
+		return c.ApplyCustomRoles(config.CustomRoles, true)
+	}
+
+	for _, name := range toDelete {
+		logFn("[-] deleting custom role '%s'\n", name)
+	}
+	for _, name := range toUpdate {
+		logFn("[+] updating custom role '%s'\n", name)
+	}
+	for _, name := range toAdd {
+		logFn("[+] creating custom role '%s'\n", name)
+	}
+	return c.ApplyCustomRoles(config.CustomRoles, false)
+}
+
+// doGitOpsVulnerabilityExceptions reconciles vulnerability exceptions against
+// the `vulnerability_exceptions:` key of the global config. Exceptions are
+// matched by CVE, software title and fleet name; exceptions absent from the
+// list are deleted.
+func (c *Client) doGitOpsVulnerabilityExceptions(config *spec.GitOps, logFn func(format string, args ...any), dryRun bool) error {
+	if config.TeamName != nil || !config.VulnerabilityExceptionsPresent {
+		return nil
+	}
+
+	existing, err := c.ListVulnerabilityExceptions()
+	if err != nil {
+		return err
+	}
+
+	scopeKey := func(cve string, titleID *uint, fleetName string) string {
+		var id uint
+		if titleID != nil {
+			id = *titleID
+		}
+		return fmt.Sprintf("%s|%d|%s", cve, id, fleetName)
+	}
+	describe := func(cve string, titleID *uint, fleetName string) string {
+		desc := cve
+		if titleID != nil {
+			desc += fmt.Sprintf(" (software title %d)", *titleID)
+		}
+		if fleetName != "" {
+			desc += fmt.Sprintf(" for fleet '%s'", fleetName)
+		}
+		return desc
+	}
+
+	existingByKey := make(map[string]fleet.VulnerabilityException, len(existing))
+	for _, e := range existing {
+		existingByKey[scopeKey(e.CVE, e.SoftwareTitleID, ptr.ValOrZero(e.TeamName))] = e
+	}
+	desired := make(map[string]struct{}, len(config.VulnerabilityExceptions))
+	var toAdd, toUpdate, toDelete []string
+	for _, e := range config.VulnerabilityExceptions {
+		key := scopeKey(e.CVE, e.SoftwareTitleID, e.Fleet)
+		desired[key] = struct{}{}
+		prev, ok := existingByKey[key]
+		switch {
+		case !ok:
+			toAdd = append(toAdd, describe(e.CVE, e.SoftwareTitleID, e.Fleet))
+		case prev.Reason != e.Reason || prev.Owner != e.Owner || !prev.ExpiresAt.Equal(e.ExpiresAt):
+			toUpdate = append(toUpdate, describe(e.CVE, e.SoftwareTitleID, e.Fleet))
+		}
+	}
+	for _, e := range existing {
+		teamName := ptr.ValOrZero(e.TeamName)
+		if _, ok := desired[scopeKey(e.CVE, e.SoftwareTitleID, teamName)]; !ok {
+			toDelete = append(toDelete, describe(e.CVE, e.SoftwareTitleID, teamName))
+		}
+	}
+
+	if dryRun {
+		for _, desc := range toDelete {
+			logFn("[-] would've deleted vulnerability exception %s\n", desc)
+		}
+		for _, desc := range toUpdate {
+			logFn("[+] would've updated vulnerability exception %s\n", desc)
+		}
+		for _, desc := range toAdd {
+			logFn("[+] would've created vulnerability exception %s\n", desc)
+		}
+		return c.ApplyVulnerabilityExceptions(config.VulnerabilityExceptions, true)
+	}
+
+	for _, desc := range toDelete {
+		logFn("[-] deleting vulnerability exception %s\n", desc)
+	}
+	for _, desc := range toUpdate {
+		logFn("[+] updating vulnerability exception %s\n", desc)
+	}
+	for _, desc := range toAdd {
+		logFn("[+] creating vulnerability exception %s\n", desc)
+	}
+	return c.ApplyVulnerabilityExceptions(config.VulnerabilityExceptions, false)
+}
+
 // resolvePolicySoftwareTitleID attempts to resolve the software title ID for a
 // policy by trying each available identifier in order: URL, App Store ID, hash,
 // then FMA slug. Returns the resolved title ID and true if found, or 0 and
@@ -4151,11 +4340,10 @@ func ensureHistoricalDataDefaults(features map[string]any) error {
 	case !ok:
 		return fmt.Errorf("features.historical_data must be a map, got %T", raw)
 	}
-	if v, ok := historicalData["uptime"]; !ok || v == nil {
-		historicalData["uptime"] = true
-	}
-	if v, ok := historicalData["vulnerabilities"]; !ok || v == nil {
-		historicalData["vulnerabilities"] = true
+	for _, key := range []string{"uptime", "vulnerabilities", "policies", "mdm_enrollment", "disk_encryption"} {
+		if v, ok := historicalData[key]; !ok || v == nil {
+			historicalData[key] = true
+		}
 	}
 	return nil
 }
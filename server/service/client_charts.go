@@ -0,0 +1,34 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// ExportChart streams the raw history of a chart metric to w, in the format
+// requested in query (json or csv). query carries the export parameters
+// (format, start_time, end_time, with_host_ids) and any chart filters, using
+// the same names as the HTTP API.
+func (c *Client) ExportChart(metric string, query url.Values, w io.Writer) error {
+	path := "/api/latest/fleet/charts/" + url.PathEscape(metric) + "/export"
+	response, err := c.AuthenticatedDo("GET", path, query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", path, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf(
+			"export chart received status %d: %s",
+			response.StatusCode,
+			extractServerErrorText(response.Body),
+		)
+	}
+
+	if _, err := io.Copy(w, response.Body); err != nil {
+		return fmt.Errorf("read chart export: %w", err)
+	}
+	return nil
+}
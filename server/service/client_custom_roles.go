@@ -0,0 +1,27 @@
+package service
+
+import (
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// ApplyCustomRoles reconciles the custom roles with specs: roles are created
+// or updated by name, and roles absent from specs are deleted.
+func (c *Client) ApplyCustomRoles(specs []fleet.CustomRoleSpec, dryRun bool) error {
+	verb, path := "PUT", "/api/latest/fleet/spec/custom_roles"
+	params := fleet.ApplyCustomRolesRequest{
+		CustomRoles: specs,
+		DryRun:      dryRun,
+	}
+	var responseBody fleet.ApplyCustomRolesResponse
+	return c.authenticatedRequest(params, verb, path, &responseBody)
+}
+
+// ListCustomRoles returns all custom roles.
+func (c *Client) ListCustomRoles() ([]fleet.CustomRole, error) {
+	verb, path := "GET", "/api/latest/fleet/custom_roles"
+	var responseBody fleet.ListCustomRolesResponse
+	if err := c.authenticatedRequest(nil, verb, path, &responseBody); err != nil {
+		return nil, err
+	}
+	return responseBody.CustomRoles, nil
+}
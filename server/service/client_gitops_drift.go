@@ -0,0 +1,43 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/platform/endpointer"
+)
+
+// SaveGitOpsSnapshot captures the live state of the resources of a fleet (the
+// global config if teamID is nil) as the state last applied by GitOps.
+func (c *Client) SaveGitOpsSnapshot(teamID *uint) error {
+	verb, path := "POST", "/api/latest/fleet/gitops/snapshots"
+	params := fleet.SaveGitOpsSnapshotRequest{TeamID: teamID}
+	var responseBody fleet.SaveGitOpsSnapshotResponse
+	return c.authenticatedRequest(params, verb, path, &responseBody)
+}
+
+// GetGitOpsDrift returns the changes made outside of GitOps since the
+// snapshot of a fleet (all the snapshots if teamID is nil) was captured.
+func (c *Client) GetGitOpsDrift(teamID *uint) ([]*fleet.GitOpsDrift, error) {
+	verb, path := "GET", "/api/latest/fleet/gitops/drift"
+	query := url.Values{}
+	if teamID != nil {
+		query.Set("fleet_id", fmt.Sprint(*teamID))
+	}
+	var raw json.RawMessage
+	if err := c.authenticatedRequestWithQuery(nil, verb, path, &raw, query.Encode()); err != nil {
+		return nil, err
+	}
+	// the server renders the new names of the renamed keys (e.g. fleet_id)
+	var responseBody fleet.GetGitOpsDriftResponse
+	raw, _, err := endpointer.RewriteDeprecatedKeys(raw, endpointer.ExtractAliasRules(responseBody))
+	if err != nil {
+		return nil, fmt.Errorf("rewrite gitops drift keys: %w", err)
+	}
+	if err := json.Unmarshal(raw, &responseBody); err != nil {
+		return nil, fmt.Errorf("decode %s %s response: %w", verb, path, err)
+	}
+	return responseBody.Drift, nil
+}
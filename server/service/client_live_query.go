@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"flag"
+	"fmt"
 	"net/http"
 	"sync/atomic"
 	"time"
@@ -20,10 +21,11 @@ import (
 // LiveQueryResultsHandler provides access to all of the information about an
 // incoming stream of live query results.
 type LiveQueryResultsHandler struct {
-	errors  chan error
-	results chan fleet.DistributedQueryResult
-	totals  atomic.Value // real type: targetTotals
-	status  atomic.Value // real type: campaignStatus
+	errors     chan error
+	results    chan fleet.DistributedQueryResult
+	totals     atomic.Value // real type: targetTotals
+	status     atomic.Value // real type: campaignStatus
+	campaignID uint
 }
 
 func NewLiveQueryResultsHandler() *LiveQueryResultsHandler {
@@ -53,6 +55,11 @@ func (h *LiveQueryResultsHandler) Totals() *targetTotals {
 	return nil
 }
 
+// CampaignID returns the ID of the live query campaign.
+func (h *LiveQueryResultsHandler) CampaignID() uint {
+	return h.campaignID
+}
+
 func (h *LiveQueryResultsHandler) Status() *campaignStatus {
 	s := h.status.Load()
 	if s != nil {
@@ -69,11 +76,26 @@ func (c *Client) LiveQuery(query string, queryID *uint, labels []string, hostIde
 func (c *Client) LiveQueryWithContext(
 	ctx context.Context, query string, queryID *uint, labels []string, hostIdentifiers []string,
 ) (*LiveQueryResultsHandler, error) {
-	req := createDistributedQueryCampaignByIdentifierRequest{
+	return c.liveQuery(ctx, createDistributedQueryCampaignByIdentifierRequest{
 		QueryID:  queryID,
 		QuerySQL: query,
 		Selected: distributedQueryCampaignTargetsByIdentifiers{Labels: labels, Hosts: hostIdentifiers},
-	}
+	})
+}
+
+// SavedLiveQuery is like LiveQuery but the results are also stored by the
+// server as they are streamed. They are retrieved later with
+// ListCampaignQueryResults using the campaign ID of the returned handler.
+func (c *Client) SavedLiveQuery(query string, queryID *uint, labels []string, hostIdentifiers []string) (*LiveQueryResultsHandler, error) {
+	return c.liveQuery(context.Background(), createDistributedQueryCampaignByIdentifierRequest{
+		QueryID:     queryID,
+		QuerySQL:    query,
+		Selected:    distributedQueryCampaignTargetsByIdentifiers{Labels: labels, Hosts: hostIdentifiers},
+		SaveResults: true,
+	})
+}
+
+func (c *Client) liveQuery(ctx context.Context, req createDistributedQueryCampaignByIdentifierRequest) (*LiveQueryResultsHandler, error) {
 	verb, path := "POST", "/api/latest/fleet/reports/run_by_identifiers"
 	var responseBody createDistributedQueryCampaignResponse
 	err := c.authenticatedRequest(req, verb, path, &responseBody)
@@ -126,6 +148,7 @@ func (c *Client) LiveQueryWithContext(
 	}
 
 	resHandler := NewLiveQueryResultsHandler()
+	resHandler.campaignID = responseBody.Campaign.ID
 	go func() {
 		defer conn.Close()
 		for {
@@ -183,3 +206,47 @@ func (c *Client) LiveQueryWithContext(
 
 	return resHandler, nil
 }
+
+// DeferredLiveQuery creates a deferred live query, which collects the results
+// of the targeted hosts as they check in until ttl elapses. The results are
+// retrieved with ListCampaignQueryResults.
+func (c *Client) DeferredLiveQuery(
+	query string, queryID *uint, labels []string, hostIdentifiers []string, ttl time.Duration,
+) (*fleet.DistributedQueryCampaign, error) {
+	req := createDistributedQueryCampaignByIdentifierRequest{
+		QueryID:     queryID,
+		QuerySQL:    query,
+		Selected:    distributedQueryCampaignTargetsByIdentifiers{Labels: labels, Hosts: hostIdentifiers},
+		Deferred:    true,
+		DeferredTTL: fleet.Duration{Duration: ttl},
+	}
+	verb, path := "POST", "/api/latest/fleet/reports/run_by_identifiers"
+	var responseBody createDistributedQueryCampaignResponse
+	if err := c.authenticatedRequest(req, verb, path, &responseBody); err != nil {
+		return nil, fmt.Errorf("create deferred live query: %w", err)
+	}
+	return responseBody.Campaign, nil
+}
+
+// GetQueryCampaign returns the live query campaign with stored results with
+// the given ID along with its progress.
+func (c *Client) GetQueryCampaign(id uint) (*fleet.QueryCampaignProgress, error) {
+	verb, path := "GET", fmt.Sprintf("/api/latest/fleet/reports/campaigns/%d", id)
+	var responseBody getQueryCampaignResponse
+	if err := c.authenticatedRequest(nil, verb, path, &responseBody); err != nil {
+		return nil, err
+	}
+	return responseBody.Campaign, nil
+}
+
+// ListCampaignQueryResults returns a page of the stored results of the live
+// query campaign with the given ID.
+func (c *Client) ListCampaignQueryResults(id uint, page, perPage uint) ([]*fleet.CampaignQueryResult, *fleet.PaginationMetadata, error) {
+	verb, path := "GET", fmt.Sprintf("/api/latest/fleet/reports/campaigns/%d/results", id)
+	query := fmt.Sprintf("page=%d&per_page=%d", page, perPage)
+	var responseBody listCampaignQueryResultsResponse
+	if err := c.authenticatedRequestWithQuery(nil, verb, path, &responseBody, query); err != nil {
+		return nil, nil, err
+	}
+	return responseBody.Results, responseBody.Meta, nil
+}
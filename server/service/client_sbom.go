@@ -0,0 +1,45 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// HostSBOM writes the software bill of materials of a host to w, in the
+// requested format (cyclonedx or spdx).
+func (c *Client) HostSBOM(hostID uint, format string, w io.Writer) error {
+	return c.getSBOM(fmt.Sprintf("/api/latest/fleet/hosts/%d/sbom", hostID), format, w)
+}
+
+// TeamSBOM writes the software bill of materials of a fleet (0 for hosts not
+// assigned to a fleet) to w, in the requested format (cyclonedx or spdx).
+func (c *Client) TeamSBOM(teamID uint, format string, w io.Writer) error {
+	return c.getSBOM(fmt.Sprintf("/api/latest/fleet/fleets/%d/sbom", teamID), format, w)
+}
+
+func (c *Client) getSBOM(path, format string, w io.Writer) error {
+	query := url.Values{}
+	if format != "" {
+		query.Set("format", format)
+	}
+	response, err := c.AuthenticatedDo("GET", path, query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", path, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf(
+			"get SBOM received status %d: %s",
+			response.StatusCode,
+			extractServerErrorText(response.Body),
+		)
+	}
+
+	if _, err := io.Copy(w, response.Body); err != nil {
+		return fmt.Errorf("read SBOM: %w", err)
+	}
+	return nil
+}
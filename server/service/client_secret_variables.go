@@ -1,6 +1,10 @@
 package service
 
-import "github.com/fleetdm/fleet/v4/server/fleet"
+import (
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
 
 func (c *Client) SaveSecretVariables(secretVariables []fleet.SecretVariable, dryRun bool) error {
 	verb, path := "PUT", "/api/latest/fleet/spec/secret_variables"
@@ -11,3 +15,22 @@ func (c *Client) SaveSecretVariables(secretVariables []fleet.SecretVariable, dry
 	var responseBody fleet.CreateSecretVariablesResponse
 	return c.authenticatedRequest(params, verb, path, &responseBody)
 }
+
+// ListSecretVariables returns all the secret variables stored on the server.
+// Only their names are returned, never their values.
+func (c *Client) ListSecretVariables() ([]fleet.SecretVariableIdentifier, error) {
+	const perPage = 1000
+	verb, path := "GET", "/api/latest/fleet/custom_variables"
+	var all []fleet.SecretVariableIdentifier
+	for page := 0; ; page++ {
+		var responseBody fleet.ListSecretVariablesResponse
+		err := c.authenticatedRequestWithQuery(nil, verb, path, &responseBody, fmt.Sprintf("per_page=%d&page=%d", perPage, page))
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, responseBody.CustomVariables...)
+		if len(responseBody.CustomVariables) < perPage {
+			return all, nil
+		}
+	}
+}
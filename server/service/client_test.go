@@ -1,6 +1,7 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -1306,6 +1307,9 @@ func TestResolvePolicySoftwareTitleID(t *testing.T) {
 }
 
 func TestEnsureHistoricalDataDefaults(t *testing.T) {
+	allOn := map[string]any{
+		"uptime": true, "vulnerabilities": true, "policies": true, "mdm_enrollment": true, "disk_encryption": true,
+	}
 	cases := []struct {
 		name      string
 		features  map[string]any
@@ -1315,22 +1319,28 @@ func TestEnsureHistoricalDataDefaults(t *testing.T) {
 		{
 			name:      "missing key gets defaults",
 			features:  map[string]any{},
-			wantValue: map[string]any{"uptime": true, "vulnerabilities": true},
+			wantValue: allOn,
 		},
 		{
 			name:      "nil value gets defaults",
 			features:  map[string]any{"historical_data": nil},
-			wantValue: map[string]any{"uptime": true, "vulnerabilities": true},
+			wantValue: allOn,
 		},
 		{
-			name:      "partial map fills missing sub-keys",
-			features:  map[string]any{"historical_data": map[string]any{"uptime": false}},
-			wantValue: map[string]any{"uptime": false, "vulnerabilities": true},
+			name:     "partial map fills missing sub-keys",
+			features: map[string]any{"historical_data": map[string]any{"uptime": false, "policies": false}},
+			wantValue: map[string]any{
+				"uptime": false, "vulnerabilities": true, "policies": false, "mdm_enrollment": true, "disk_encryption": true,
+			},
 		},
 		{
-			name:      "explicit values are preserved",
-			features:  map[string]any{"historical_data": map[string]any{"uptime": false, "vulnerabilities": false}},
-			wantValue: map[string]any{"uptime": false, "vulnerabilities": false},
+			name: "explicit values are preserved",
+			features: map[string]any{"historical_data": map[string]any{
+				"uptime": false, "vulnerabilities": false, "policies": false, "mdm_enrollment": false, "disk_encryption": false,
+			}},
+			wantValue: map[string]any{
+				"uptime": false, "vulnerabilities": false, "policies": false, "mdm_enrollment": false, "disk_encryption": false,
+			},
 		},
 		{
 			name:     "scalar value is rejected",
@@ -1356,6 +1366,35 @@ func TestEnsureHistoricalDataDefaults(t *testing.T) {
 	}
 }
 
+func TestExportChart(t *testing.T) {
+	var gotPath, gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.Path, r.URL.RawQuery
+		if r.URL.Query().Get("format") == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"Bad request","errors":[{"name":"base","reason":"invalid format"}]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv")
+		_, _ = w.Write([]byte("entity_id,valid_from,valid_to,host_count\n,2026-03-01T00:00:00Z,,3\n"))
+	}))
+	t.Cleanup(srv.Close)
+	client, err := NewClient(srv.URL, true, "", "")
+	require.NoError(t, err)
+	client.SetToken("test-token")
+
+	var buf bytes.Buffer
+	err = client.ExportChart("uptime", url.Values{"format": {"csv"}, "fleet_id": {"0"}}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "/api/latest/fleet/charts/uptime/export", gotPath)
+	assert.Equal(t, "fleet_id=0&format=csv", gotQuery)
+	assert.Equal(t, "entity_id,valid_from,valid_to,host_count\n,2026-03-01T00:00:00Z,,3\n", buf.String())
+
+	err = client.ExportChart("uptime", url.Values{"format": {"bad"}}, &buf)
+	require.ErrorContains(t, err, "export chart received status 400")
+	require.ErrorContains(t, err, "invalid format")
+}
+
 func TestApplySoftwareInstallersProgress(t *testing.T) {
 	pkg := func(name string, status fleet.SoftwarePackageDownloadStatus) fleet.SoftwarePackageDownloadProgress {
 		return fleet.SoftwarePackageDownloadProgress{Name: name, Status: status}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// ApplyVulnerabilityExceptions reconciles the vulnerability exceptions with
+// specs: exceptions are created or updated by CVE, software title and fleet,
+// and exceptions absent from specs are deleted.
+func (c *Client) ApplyVulnerabilityExceptions(specs []fleet.VulnerabilityExceptionSpec, dryRun bool) error {
+	verb, path := "PUT", "/api/latest/fleet/spec/vulnerability_exceptions"
+	params := fleet.ApplyVulnerabilityExceptionsRequest{
+		VulnerabilityExceptions: specs,
+		DryRun:                  dryRun,
+	}
+	var responseBody fleet.ApplyVulnerabilityExceptionsResponse
+	return c.authenticatedRequest(params, verb, path, &responseBody)
+}
+
+// ListVulnerabilityExceptions returns all vulnerability exceptions, including
+// the expired ones.
+func (c *Client) ListVulnerabilityExceptions() ([]fleet.VulnerabilityException, error) {
+	verb, path := "GET", "/api/latest/fleet/vulnerability_exceptions"
+	var responseBody fleet.ListVulnerabilityExceptionsResponse
+	if err := c.authenticatedRequest(nil, verb, path, &responseBody); err != nil {
+		return nil, err
+	}
+	return responseBody.VulnerabilityExceptions, nil
+}
@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/license"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"golang.org/x/text/unicode/norm"
+)
+
+//////////////////////////////////////////////////////////////////////////////////
+// List custom roles
+//////////////////////////////////////////////////////////////////////////////////
+
+func listCustomRolesEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	roles, err := svc.ListCustomRoles(ctx)
+	if err != nil {
+		return fleet.ListCustomRolesResponse{Err: err}, nil
+	}
+	return fleet.ListCustomRolesResponse{CustomRoles: roles}, nil
+}
+
+func (svc *Service) ListCustomRoles(ctx context.Context) ([]fleet.CustomRole, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.CustomRole{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	roles, err := svc.ds.ListCustomRoles(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list custom roles")
+	}
+	return roles, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Get custom role
+//////////////////////////////////////////////////////////////////////////////////
+
+func getCustomRoleEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.GetCustomRoleRequest)
+	role, err := svc.GetCustomRole(ctx, req.ID)
+	if err != nil {
+		return fleet.GetCustomRoleResponse{Err: err}, nil
+	}
+	return fleet.GetCustomRoleResponse{CustomRole: role}, nil
+}
+
+func (svc *Service) GetCustomRole(ctx context.Context, id uint) (*fleet.CustomRole, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.CustomRole{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	role, err := svc.ds.CustomRole(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get custom role")
+	}
+	return role, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Create custom role
+//////////////////////////////////////////////////////////////////////////////////
+
+func createCustomRoleEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.CreateCustomRoleRequest)
+	role, err := svc.CreateCustomRole(ctx, req.CustomRolePayload)
+	if err != nil {
+		return fleet.CreateCustomRoleResponse{Err: err}, nil
+	}
+	return fleet.CreateCustomRoleResponse{CustomRole: role}, nil
+}
+
+func (svc *Service) CreateCustomRole(ctx context.Context, p fleet.CustomRolePayload) (*fleet.CustomRole, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.CustomRole{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	if !license.IsPremium(ctx) {
+		return nil, fleet.ErrMissingLicense
+	}
+
+	role := &fleet.CustomRole{}
+	if p.Name != nil {
+		role.Name = strings.TrimSpace(*p.Name)
+	}
+	if p.Description != nil {
+		role.Description = *p.Description
+	}
+	if p.Permissions != nil {
+		role.Permissions = *p.Permissions
+	}
+	if err := fleet.ValidateCustomRole(role.Name, role.Permissions); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate custom role")
+	}
+
+	role, err := svc.ds.NewCustomRole(ctx, role)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create custom role")
+	}
+
+	if err := svc.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeCreatedCustomRole{
+			CustomRoleID:   role.ID,
+			CustomRoleName: role.Name,
+		},
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for custom role creation")
+	}
+
+	return role, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Modify custom role
+//////////////////////////////////////////////////////////////////////////////////
+
+func modifyCustomRoleEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.ModifyCustomRoleRequest)
+	role, err := svc.ModifyCustomRole(ctx, req.ID, req.CustomRolePayload)
+	if err != nil {
+		return fleet.ModifyCustomRoleResponse{Err: err}, nil
+	}
+	return fleet.ModifyCustomRoleResponse{CustomRole: role}, nil
+}
+
+func (svc *Service) ModifyCustomRole(ctx context.Context, id uint, p fleet.CustomRolePayload) (*fleet.CustomRole, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.CustomRole{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	if !license.IsPremium(ctx) {
+		return nil, fleet.ErrMissingLicense
+	}
+
+	role, err := svc.ds.CustomRole(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get custom role")
+	}
+	if p.Name != nil {
+		role.Name = strings.TrimSpace(*p.Name)
+	}
+	if p.Description != nil {
+		role.Description = *p.Description
+	}
+	if p.Permissions != nil {
+		role.Permissions = *p.Permissions
+	}
+	if err := fleet.ValidateCustomRole(role.Name, role.Permissions); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "validate custom role")
+	}
+
+	role, err = svc.ds.SaveCustomRole(ctx, role)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "save custom role")
+	}
+
+	if err := svc.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeEditedCustomRole{
+			CustomRoleID:   role.ID,
+			CustomRoleName: role.Name,
+		},
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for custom role edit")
+	}
+
+	return role, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Delete custom role
+//////////////////////////////////////////////////////////////////////////////////
+
+func deleteCustomRoleEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.DeleteCustomRoleRequest)
+	err := svc.DeleteCustomRole(ctx, req.ID)
+	return fleet.DeleteCustomRoleResponse{Err: err}, nil
+}
+
+func (svc *Service) DeleteCustomRole(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.CustomRole{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	role, err := svc.ds.CustomRole(ctx, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get custom role")
+	}
+	if err := svc.ds.DeleteCustomRole(ctx, id); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete custom role")
+	}
+
+	if err := svc.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeDeletedCustomRole{
+			CustomRoleID:   role.ID,
+			CustomRoleName: role.Name,
+		},
+	); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for custom role deletion")
+	}
+
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Set user custom roles
+//////////////////////////////////////////////////////////////////////////////////
+
+func setUserCustomRolesEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.SetUserCustomRolesRequest)
+	user, err := svc.SetUserCustomRoles(ctx, req.ID, req.CustomRoles)
+	if err != nil {
+		return fleet.SetUserCustomRolesResponse{Err: err}, nil
+	}
+	return fleet.SetUserCustomRolesResponse{User: user}, nil
+}
+
+func (svc *Service) SetUserCustomRoles(ctx context.Context, userID uint, roles []fleet.UserCustomRole) (*fleet.User, error) {
+	user, err := svc.ds.UserByID(ctx, userID)
+	if err != nil {
+		setAuthCheckedOnPreAuthErr(ctx)
+		return nil, ctxerr.Wrap(ctx, err, "get user")
+	}
+
+	// Assigning custom roles is the same privileged operation as changing the
+	// user's role. Managing the role definitions (e.g. as gitops) isn't enough.
+	if err := svc.authz.Authorize(ctx, user, fleet.ActionWriteRole); err != nil {
+		return nil, err
+	}
+	// Custom roles can be assigned globally or for any fleet, so team admins,
+	// who can change the roles of their fleets' users, can't assign them.
+	if currentUser := authz.UserFromContext(ctx); currentUser == nil || currentUser.GlobalRole == nil {
+		return nil, authz.ForbiddenWithInternal(
+			"cannot assign custom roles as a fleet member",
+			currentUser, user, fleet.ActionWriteRole,
+		)
+	}
+	if !license.IsPremium(ctx) && len(roles) > 0 {
+		return nil, fleet.ErrMissingLicense
+	}
+
+	existing, err := svc.ds.ListCustomRoles(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list custom roles")
+	}
+	roleNames := make(map[uint]string, len(existing))
+	for _, r := range existing {
+		roleNames[r.ID] = r.Name
+	}
+
+	type assignment struct {
+		roleID uint
+		teamID uint
+	}
+	seen := make(map[assignment]bool, len(roles))
+	grants := make([]fleet.ActivityUserCustomRoleGrant, 0, len(roles))
+	for _, r := range roles {
+		name, ok := roleNames[r.CustomRoleID]
+		if !ok {
+			return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("custom_roles",
+				fmt.Sprintf("custom role with id %d doesn't exist", r.CustomRoleID)))
+		}
+		grant := fleet.ActivityUserCustomRoleGrant{CustomRoleID: r.CustomRoleID, CustomRoleName: name}
+
+		a := assignment{roleID: r.CustomRoleID}
+		if r.TeamID != nil {
+			team, err := svc.ds.TeamLite(ctx, *r.TeamID)
+			if err != nil {
+				if fleet.IsNotFound(err) {
+					return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("custom_roles",
+						fmt.Sprintf("fleet with id %d doesn't exist", *r.TeamID)))
+				}
+				return nil, ctxerr.Wrap(ctx, err, "get team")
+			}
+			a.teamID = team.ID
+			grant.TeamID = &team.ID
+			grant.TeamName = &team.Name
+		}
+		if seen[a] {
+			return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("custom_roles",
+				fmt.Sprintf("custom role %q is assigned more than once for the same scope", name)))
+		}
+		seen[a] = true
+		grants = append(grants, grant)
+	}
+
+	if err := svc.ds.SetUserCustomRoles(ctx, user.ID, roles); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "set user custom roles")
+	}
+
+	if err := svc.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeChangedUserCustomRoles{
+			UserID:      user.ID,
+			UserName:    user.Name,
+			UserEmail:   user.Email,
+			CustomRoles: grants,
+		},
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for user custom roles change")
+	}
+
+	// reload the user to return its custom roles with their permissions
+	user, err = svc.ds.UserByID(ctx, user.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "reload user")
+	}
+	return user, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Apply custom roles (spec)
+//////////////////////////////////////////////////////////////////////////////////
+
+func applyCustomRolesEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.ApplyCustomRolesRequest)
+	err := svc.ApplyCustomRoles(ctx, req.CustomRoles, req.DryRun)
+	return fleet.ApplyCustomRolesResponse{Err: err}, nil
+}
+
+func (svc *Service) ApplyCustomRoles(ctx context.Context, specs []fleet.CustomRoleSpec, dryRun bool) error {
+	if err := svc.authz.Authorize(ctx, &fleet.CustomRole{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+	if !license.IsPremium(ctx) && len(specs) > 0 {
+		return fleet.ErrMissingLicense
+	}
+
+	// Names are unique in the database under the utf8mb4_unicode_ci collation
+	// (case-insensitive), so dedupe on that same basis.
+	seen := make(map[string]string, len(specs)) // collation key -> original name
+	for i, spec := range specs {
+		specs[i].Name = strings.TrimSpace(spec.Name)
+		if err := fleet.ValidateCustomRole(specs[i].Name, spec.Permissions); err != nil {
+			return ctxerr.Wrap(ctx, err, "validate custom role")
+		}
+		key := norm.NFC.String(strings.ToLower(specs[i].Name))
+		if prev, ok := seen[key]; ok {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("custom_roles",
+				fmt.Sprintf("duplicate custom role names: %q and %q must differ by more than letter case", prev, specs[i].Name)))
+		}
+		seen[key] = specs[i].Name
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	created, edited, deleted, err := svc.ds.ApplyCustomRoles(ctx, specs)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "apply custom roles")
+	}
+
+	user := authz.UserFromContext(ctx)
+	for _, role := range created {
+		if err := svc.NewActivity(ctx, user, fleet.ActivityTypeCreatedCustomRole{
+			CustomRoleID:   role.ID,
+			CustomRoleName: role.Name,
+		}); err != nil {
+			return ctxerr.Wrap(ctx, err, "create activity for custom role creation")
+		}
+	}
+	for _, role := range edited {
+		if err := svc.NewActivity(ctx, user, fleet.ActivityTypeEditedCustomRole{
+			CustomRoleID:   role.ID,
+			CustomRoleName: role.Name,
+		}); err != nil {
+			return ctxerr.Wrap(ctx, err, "create activity for custom role edit")
+		}
+	}
+	for _, role := range deleted {
+		if err := svc.NewActivity(ctx, user, fleet.ActivityTypeDeletedCustomRole{
+			CustomRoleID:   role.ID,
+			CustomRoleName: role.Name,
+		}); err != nil {
+			return ctxerr.Wrap(ctx, err, "create activity for custom role deletion")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCustomRolesAuth(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+
+	perms := []fleet.CustomRolePermission{{Object: "host_script_result", Action: fleet.ActionWrite}}
+	ds.ListCustomRolesFunc = func(ctx context.Context) ([]fleet.CustomRole, error) {
+		return []fleet.CustomRole{{ID: 1, Name: "Helpdesk", Permissions: perms}}, nil
+	}
+	ds.CustomRoleFunc = func(ctx context.Context, id uint) (*fleet.CustomRole, error) {
+		return &fleet.CustomRole{ID: id, Name: "Helpdesk", Permissions: perms}, nil
+	}
+	ds.NewCustomRoleFunc = func(ctx context.Context, role *fleet.CustomRole) (*fleet.CustomRole, error) {
+		role.ID = 1
+		return role, nil
+	}
+	ds.SaveCustomRoleFunc = func(ctx context.Context, role *fleet.CustomRole) (*fleet.CustomRole, error) {
+		return role, nil
+	}
+	ds.DeleteCustomRoleFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		return &fleet.User{ID: id}, nil
+	}
+	ds.SetUserCustomRolesFunc = func(ctx context.Context, userID uint, roles []fleet.UserCustomRole) error {
+		return nil
+	}
+	ds.ApplyCustomRolesFunc = func(ctx context.Context, specs []fleet.CustomRoleSpec) ([]fleet.CustomRole, []fleet.CustomRole, []fleet.CustomRole, error) {
+		return nil, nil, nil, nil
+	}
+
+	cases := []struct {
+		name    string
+		user    *fleet.User
+		allowed bool
+	}{
+		{"global admin", &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}, true},
+		{"global gitops", &fleet.User{ID: 2, GlobalRole: new(fleet.RoleGitOps)}, true},
+		{"global maintainer", &fleet.User{ID: 3, GlobalRole: new(fleet.RoleMaintainer)}, false},
+		{"global observer", &fleet.User{ID: 4, GlobalRole: new(fleet.RoleObserver)}, false},
+		{"team admin", &fleet.User{ID: 5, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, false},
+		{
+			// custom roles can't grant permissions on custom roles
+			"observer with a custom role",
+			&fleet.User{ID: 6, GlobalRole: new(fleet.RoleObserver), CustomRoles: []fleet.UserCustomRole{{CustomRoleID: 1, Permissions: perms}}},
+			false,
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.ListCustomRoles(ctx)
+			checkAuthErr(t, !tt.allowed, err)
+
+			_, err = svc.GetCustomRole(ctx, 1)
+			checkAuthErr(t, !tt.allowed, err)
+
+			_, err = svc.CreateCustomRole(ctx, fleet.CustomRolePayload{Name: new("Helpdesk"), Permissions: &perms})
+			checkAuthErr(t, !tt.allowed, err)
+
+			_, err = svc.ModifyCustomRole(ctx, 1, fleet.CustomRolePayload{Description: new("Runs scripts")})
+			checkAuthErr(t, !tt.allowed, err)
+
+			err = svc.DeleteCustomRole(ctx, 1)
+			checkAuthErr(t, !tt.allowed, err)
+
+			err = svc.ApplyCustomRoles(ctx, []fleet.CustomRoleSpec{{Name: "Helpdesk", Permissions: perms}}, false)
+			checkAuthErr(t, !tt.allowed, err)
+		})
+	}
+}
+
+func TestSetUserCustomRolesAuth(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+
+	ds.ListCustomRolesFunc = func(ctx context.Context) ([]fleet.CustomRole, error) {
+		return []fleet.CustomRole{{ID: 1, Name: "Helpdesk"}}, nil
+	}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		// the target user is a member of fleet 1
+		return &fleet.User{ID: id, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}, nil
+	}
+	ds.SetUserCustomRolesFunc = func(ctx context.Context, userID uint, roles []fleet.UserCustomRole) error {
+		return nil
+	}
+
+	cases := []struct {
+		name    string
+		user    *fleet.User
+		allowed bool
+	}{
+		{"global admin", &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}, true},
+		// gitops manages the custom role definitions, not their assignments
+		{"global gitops", &fleet.User{ID: 2, GlobalRole: new(fleet.RoleGitOps)}, false},
+		{"global maintainer", &fleet.User{ID: 3, GlobalRole: new(fleet.RoleMaintainer)}, false},
+		{"global observer", &fleet.User{ID: 4, GlobalRole: new(fleet.RoleObserver)}, false},
+		// team admins can change the role of their fleet's users, but custom
+		// roles aren't limited to their fleet
+		{"team admin of the user's fleet", &fleet.User{ID: 5, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, false},
+		{"team gitops", &fleet.User{ID: 6, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleGitOps}}}, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.SetUserCustomRoles(ctx, 10, []fleet.UserCustomRole{{CustomRoleID: 1}})
+			checkAuthErr(t, !tt.allowed, err)
+
+			// including assigning custom roles to oneself
+			_, err = svc.SetUserCustomRoles(ctx, tt.user.ID, []fleet.UserCustomRole{{CustomRoleID: 1}})
+			checkAuthErr(t, !tt.allowed, err)
+		})
+	}
+}
+
+func TestCustomRolesRequirePremium(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}})
+
+	perms := []fleet.CustomRolePermission{{Object: "host_script_result", Action: fleet.ActionWrite}}
+	_, err := svc.CreateCustomRole(ctx, fleet.CustomRolePayload{Name: new("Helpdesk"), Permissions: &perms})
+	require.ErrorIs(t, err, fleet.ErrMissingLicense)
+
+	err = svc.ApplyCustomRoles(ctx, []fleet.CustomRoleSpec{{Name: "Helpdesk", Permissions: perms}}, true)
+	require.ErrorIs(t, err, fleet.ErrMissingLicense)
+
+	// clearing all custom roles is allowed, e.g. after a downgrade
+	ds.ApplyCustomRolesFunc = func(ctx context.Context, specs []fleet.CustomRoleSpec) ([]fleet.CustomRole, []fleet.CustomRole, []fleet.CustomRole, error) {
+		return nil, nil, nil, nil
+	}
+	require.NoError(t, svc.ApplyCustomRoles(ctx, nil, false))
+}
+
+func TestCreateCustomRoleValidation(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}})
+
+	ds.NewCustomRoleFunc = func(ctx context.Context, role *fleet.CustomRole) (*fleet.CustomRole, error) {
+		role.ID = 1
+		return role, nil
+	}
+
+	cases := []struct {
+		name    string
+		payload fleet.CustomRolePayload
+		errMsg  string
+	}{
+		{
+			"missing name",
+			fleet.CustomRolePayload{Permissions: &[]fleet.CustomRolePermission{{Object: "query", Action: fleet.ActionWrite}}},
+			"Custom role name can't be empty",
+		},
+		{
+			"built-in role name",
+			fleet.CustomRolePayload{Name: new("Admin"), Permissions: &[]fleet.CustomRolePermission{{Object: "query", Action: fleet.ActionWrite}}},
+			"is reserved for a built-in role",
+		},
+		{
+			"no permissions",
+			fleet.CustomRolePayload{Name: new("Helpdesk")},
+			"must have at least one permission",
+		},
+		{
+			"privilege escalation",
+			fleet.CustomRolePayload{Name: new("Helpdesk"), Permissions: &[]fleet.CustomRolePermission{{Object: "user", Action: fleet.ActionWrite}}},
+			`Object "user" can't be granted by a custom role`,
+		},
+		{
+			"host transfer",
+			fleet.CustomRolePayload{Name: new("Helpdesk"), Permissions: &[]fleet.CustomRolePermission{{Object: "host", Action: fleet.ActionTransferHost}}},
+			`Action "transfer_host" can't be granted on object "host"`,
+		},
+		{
+			"unknown action",
+			fleet.CustomRolePayload{Name: new("Helpdesk"), Permissions: &[]fleet.CustomRolePermission{{Object: "policy", Action: fleet.ActionRun}}},
+			`Action "run" can't be granted on object "policy"`,
+		},
+		{
+			"duplicate permission",
+			fleet.CustomRolePayload{Name: new("Helpdesk"), Permissions: &[]fleet.CustomRolePermission{
+				{Object: "query", Action: fleet.ActionWrite},
+				{Object: "query", Action: fleet.ActionWrite},
+			}},
+			"Duplicate permission",
+		},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ds.NewCustomRoleFuncInvoked = false
+			_, err := svc.CreateCustomRole(ctx, tt.payload)
+			require.ErrorContains(t, err, tt.errMsg)
+			require.False(t, ds.NewCustomRoleFuncInvoked)
+		})
+	}
+}
+
+func TestSetUserCustomRoles(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	opts := &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}}
+	svc, ctx := newTestService(t, ds, nil, nil, opts)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}})
+
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		return &fleet.User{ID: id, Name: "Jane", Email: "jane@example.com"}, nil
+	}
+	ds.ListCustomRolesFunc = func(ctx context.Context) ([]fleet.CustomRole, error) {
+		return []fleet.CustomRole{{ID: 1, Name: "Helpdesk"}}, nil
+	}
+	ds.TeamLiteFunc = func(ctx context.Context, tid uint) (*fleet.TeamLite, error) {
+		if tid != 3 {
+			return nil, newNotFoundError()
+		}
+		return &fleet.TeamLite{ID: tid, Name: "Workstations"}, nil
+	}
+	ds.SetUserCustomRolesFunc = func(ctx context.Context, userID uint, roles []fleet.UserCustomRole) error {
+		return nil
+	}
+
+	t.Run("rejects unknown roles and teams", func(t *testing.T) {
+		_, err := svc.SetUserCustomRoles(ctx, 2, []fleet.UserCustomRole{{CustomRoleID: 2}})
+		require.ErrorContains(t, err, "custom role with id 2 doesn't exist")
+		_, err = svc.SetUserCustomRoles(ctx, 2, []fleet.UserCustomRole{{CustomRoleID: 1, TeamID: new(uint(4))}})
+		require.ErrorContains(t, err, "fleet with id 4 doesn't exist")
+		require.False(t, ds.SetUserCustomRolesFuncInvoked)
+	})
+
+	t.Run("rejects duplicate assignments", func(t *testing.T) {
+		_, err := svc.SetUserCustomRoles(ctx, 2, []fleet.UserCustomRole{
+			{CustomRoleID: 1, TeamID: new(uint(3))},
+			{CustomRoleID: 1, TeamID: new(uint(3))},
+		})
+		require.ErrorContains(t, err, "assigned more than once")
+		require.False(t, ds.SetUserCustomRolesFuncInvoked)
+	})
+
+	t.Run("emits an activity", func(t *testing.T) {
+		var activities []activity_api.ActivityDetails
+		opts.ActivityMock.NewActivityFunc = func(_ context.Context, _ *activity_api.User, activity activity_api.ActivityDetails) error {
+			activities = append(activities, activity)
+			return nil
+		}
+		_, err := svc.SetUserCustomRoles(ctx, 2, []fleet.UserCustomRole{{CustomRoleID: 1}, {CustomRoleID: 1, TeamID: new(uint(3))}})
+		require.NoError(t, err)
+		require.True(t, ds.SetUserCustomRolesFuncInvoked)
+		require.Equal(t, []activity_api.ActivityDetails{fleet.ActivityTypeChangedUserCustomRoles{
+			UserID:    2,
+			UserName:  "Jane",
+			UserEmail: "jane@example.com",
+			CustomRoles: []fleet.ActivityUserCustomRoleGrant{
+				{CustomRoleID: 1, CustomRoleName: "Helpdesk"},
+				{CustomRoleID: 1, CustomRoleName: "Helpdesk", TeamID: new(uint(3)), TeamName: new("Workstations")},
+			},
+		}}, activities)
+	})
+}
@@ -0,0 +1,264 @@
+package externalsvc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
+)
+
+// ServiceNow incident states, see
+// https://docs.servicenow.com/bundle/washingtondc-it-service-management/page/product/incident-management/concept/c_IncidentManagementStateModel.html
+const (
+	ServiceNowIncidentStateResolved = "6"
+	ServiceNowIncidentStateClosed   = "7"
+	ServiceNowIncidentStateCanceled = "8"
+)
+
+// serviceNowTimeLayout is the format of the date-time fields (e.g.
+// sys_updated_on) returned by the Table API, always in UTC.
+const serviceNowTimeLayout = "2006-01-02 15:04:05"
+
+// ServiceNow is a ServiceNow client to be used to make requests to the
+// ServiceNow Table API.
+type ServiceNow struct {
+	client *http.Client
+	opts   ServiceNowOptions
+}
+
+// ServiceNowOptions defines the options to configure a ServiceNow client.
+type ServiceNowOptions struct {
+	URL      string
+	Username string
+	Password string
+}
+
+// ServiceNowIncident is an incident record of the ServiceNow incident table.
+type ServiceNowIncident struct {
+	SysID         string `json:"sys_id"`
+	Number        string `json:"number"`
+	State         string `json:"state"`
+	CorrelationID string `json:"correlation_id"`
+	SysUpdatedOn  string `json:"sys_updated_on"`
+}
+
+// UpdatedAt returns the time the incident was last updated, or the zero time
+// if it could not be parsed.
+func (i *ServiceNowIncident) UpdatedAt() time.Time {
+	t, _ := time.ParseInLocation(serviceNowTimeLayout, i.SysUpdatedOn, time.UTC)
+	return t
+}
+
+// ServiceNowError is the error returned when the ServiceNow API responds with
+// an unsuccessful status code.
+type ServiceNowError struct {
+	StatusCode int
+	Message    string
+	// RetryAfter is the raw value of the Retry-After response header, if any.
+	RetryAfter string
+}
+
+func (e *ServiceNowError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ServiceNow API request failed with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("ServiceNow API request failed with status %d: %s", e.StatusCode, e.Message)
+}
+
+// NewServiceNowClient returns a ServiceNow client to use to make requests to
+// the ServiceNow external service.
+func NewServiceNowClient(opts *ServiceNowOptions) (*ServiceNow, error) {
+	u, err := url.Parse(opts.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" && u.Scheme != "http" || u.Host == "" {
+		return nil, errors.New("ServiceNow URL must be https or http, and have a host")
+	}
+	return &ServiceNow{
+		client: fleethttp.NewClient(),
+		opts:   *opts,
+	}, nil
+}
+
+// GetAssignmentGroup checks that the assignment group (its name or sys_id)
+// exists. It can be used to test in one request the authentication and
+// connection parameters to the ServiceNow instance as well as the existence
+// of the group.
+func (s *ServiceNow) GetAssignmentGroup(ctx context.Context, group string) error {
+	q := url.Values{}
+	q.Set("sysparm_query", "sys_id="+group+"^ORname="+group)
+	q.Set("sysparm_fields", "sys_id")
+	q.Set("sysparm_limit", "1")
+
+	var groups []struct {
+		SysID string `json:"sys_id"`
+	}
+	if err := s.doWithRetry(ctx, http.MethodGet, "/api/now/table/sys_user_group?"+q.Encode(), nil, &groups); err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		return fmt.Errorf("assignment group %q not found", group)
+	}
+	return nil
+}
+
+// FindOpenServiceNowIncident returns the most recent incident with that
+// correlation ID that is not resolved, closed or canceled. It returns nil if
+// there is none.
+func (s *ServiceNow) FindOpenServiceNowIncident(ctx context.Context, correlationID string) (*ServiceNowIncident, error) {
+	q := url.Values{}
+	q.Set("sysparm_query", fmt.Sprintf("correlation_id=%s^stateNOT IN%s,%s,%s^ORDERBYDESCsys_created_on",
+		correlationID, ServiceNowIncidentStateResolved, ServiceNowIncidentStateClosed, ServiceNowIncidentStateCanceled))
+	q.Set("sysparm_fields", "sys_id,number,state,correlation_id,sys_updated_on")
+	q.Set("sysparm_limit", "1")
+
+	var incidents []*ServiceNowIncident
+	if err := s.doWithRetry(ctx, http.MethodGet, "/api/now/table/incident?"+q.Encode(), nil, &incidents); err != nil {
+		return nil, err
+	}
+	if len(incidents) == 0 {
+		return nil, nil
+	}
+	return incidents[0], nil
+}
+
+// CreateServiceNowIncident creates an incident with the provided fields. It
+// returns the created incident or an error.
+func (s *ServiceNow) CreateServiceNowIncident(ctx context.Context, fields map[string]string) (*ServiceNowIncident, error) {
+	var incident ServiceNowIncident
+	if err := s.doWithRetry(ctx, http.MethodPost, "/api/now/table/incident", fields, &incident); err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// UpdateServiceNowIncident updates the provided fields of the incident
+// identified by sysID. It returns the updated incident or an error.
+func (s *ServiceNow) UpdateServiceNowIncident(ctx context.Context, sysID string, fields map[string]string) (*ServiceNowIncident, error) {
+	var incident ServiceNowIncident
+	if err := s.doWithRetry(ctx, http.MethodPatch, "/api/now/table/incident/"+url.PathEscape(sysID), fields, &incident); err != nil {
+		return nil, err
+	}
+	return &incident, nil
+}
+
+// ServiceNowConfigMatches returns true if the ServiceNow client has been
+// configured using those same options.
+func (s *ServiceNow) ServiceNowConfigMatches(opts *ServiceNowOptions) bool {
+	return s.opts == *opts
+}
+
+func (s *ServiceNow) do(ctx context.Context, method, path string, body any, result any) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(s.opts.URL, "/")+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.opts.Username, s.opts.Password)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snErr := &ServiceNowError{StatusCode: resp.StatusCode, RetryAfter: resp.Header.Get("Retry-After")}
+		var errBody struct {
+			Error struct {
+				Message string `json:"message"`
+				Detail  string `json:"detail"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(respBody, &errBody) == nil {
+			snErr.Message = errBody.Error.Message
+			if errBody.Error.Detail != "" {
+				snErr.Message += ": " + errBody.Error.Detail
+			}
+		}
+		return snErr
+	}
+
+	// the Table API wraps the record(s) in a "result" key
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &envelope); err != nil {
+		return fmt.Errorf("decode ServiceNow response: %w", err)
+	}
+	if err := json.Unmarshal(envelope.Result, result); err != nil {
+		return fmt.Errorf("decode ServiceNow response result: %w", err)
+	}
+	return nil
+}
+
+func (s *ServiceNow) doWithRetry(ctx context.Context, method, path string, body any, result any) error {
+	op := func() error {
+		err := s.do(ctx, method, path, body, result)
+		if err == nil {
+			return nil
+		}
+
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			if netErr.Timeout() {
+				// retryable error
+				return err
+			}
+		}
+
+		var snErr *ServiceNowError
+		if errors.As(err, &snErr) {
+			if snErr.StatusCode >= http.StatusInternalServerError {
+				// 500+ status, can be worth retrying
+				return err
+			}
+			if snErr.StatusCode == http.StatusTooManyRequests {
+				// handle 429 rate-limits, see
+				// https://docs.servicenow.com/bundle/washingtondc-api-reference/page/integrate/inbound-rest/concept/inbound-REST-API-rate-limiting.html
+				// for details.
+				afterSecs, err := strconv.ParseInt(snErr.RetryAfter, 10, 0)
+				if err == nil && (time.Duration(afterSecs)*time.Second) < maxWaitForRetryAfter {
+					// the retry-after duration is reasonable, wait for it and return a
+					// retryable error so that we try again.
+					time.Sleep(time.Duration(afterSecs) * time.Second)
+					return errors.New("retry after requested delay")
+				}
+			}
+		}
+
+		// at this point, this is a non-retryable error
+		return backoff.Permanent(err)
+	}
+
+	boff := backoff.WithMaxRetries(backoff.NewConstantBackOff(retryBackoff), uint64(maxRetries))
+	return backoff.Retry(op, boff)
+}
@@ -0,0 +1,151 @@
+package externalsvc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceNow(t *testing.T) {
+	var countCalls int
+	var gotBody map[string]string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		countCalls++
+
+		switch _, p, _ := r.BasicAuth(); p {
+		case "fail":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case "unauthorized":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"User Not Authenticated","detail":"Required to provide Auth information"},"status":"failure"}`))
+			return
+		case "retrysmall":
+			if countCalls == 1 {
+				w.Header().Add("Retry-After", "1")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		case "retrybig":
+			if countCalls == 1 {
+				w.Header().Add("Retry-After", "12345")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/now/table/sys_user_group":
+			if r.URL.Query().Get("sysparm_query") == "sys_id=Service Desk^ORname=Service Desk" {
+				_, _ = w.Write([]byte(`{"result":[{"sys_id":"d625dccec0a8016700a222a0f7900d06"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"result":[]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/api/now/table/incident":
+			if r.URL.Query().Get("sysparm_query") == "correlation_id=fleet-policy-1^stateNOT IN6,7,8^ORDERBYDESCsys_created_on" {
+				_, _ = w.Write([]byte(`{"result":[{"sys_id":"abc","number":"INC0010001","state":"1","correlation_id":"fleet-policy-1","sys_updated_on":"2024-05-06 07:08:09"}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"result":[]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/api/now/table/incident":
+			gotBody = nil
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"result":{"sys_id":"def","number":"INC0010002","state":"1"}}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/api/now/table/incident/abc":
+			gotBody = nil
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&gotBody))
+			_, _ = w.Write([]byte(`{"result":{"sys_id":"abc","number":"INC0010001","state":"6"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	newClient := func(t *testing.T, password string) *ServiceNow {
+		client, err := NewServiceNowClient(&ServiceNowOptions{
+			URL:      srv.URL,
+			Username: "fleet",
+			Password: password,
+		})
+		require.NoError(t, err)
+		return client
+	}
+
+	t.Run("invalid url", func(t *testing.T) {
+		_, err := NewServiceNowClient(&ServiceNowOptions{URL: "not a url"})
+		require.Error(t, err)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		countCalls = 0
+		_, err := newClient(t, "fail").CreateServiceNowIncident(t.Context(), map[string]string{"short_description": "test"})
+		require.Error(t, err)
+		require.Equal(t, 6, countCalls) // original + 5 retries
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		countCalls = 0
+		err := newClient(t, "unauthorized").GetAssignmentGroup(t.Context(), "Service Desk")
+		require.ErrorContains(t, err, "User Not Authenticated")
+		require.Equal(t, 1, countCalls) // not retried
+	})
+
+	t.Run("retry after small", func(t *testing.T) {
+		countCalls = 0
+		start := time.Now()
+		_, err := newClient(t, "retrysmall").CreateServiceNowIncident(t.Context(), map[string]string{"short_description": "test"})
+		require.NoError(t, err)
+		require.GreaterOrEqual(t, time.Since(start), time.Second)
+		require.Equal(t, 2, countCalls)
+	})
+
+	t.Run("retry after too big", func(t *testing.T) {
+		countCalls = 0
+		_, err := newClient(t, "retrybig").CreateServiceNowIncident(t.Context(), map[string]string{"short_description": "test"})
+		require.Error(t, err)
+		require.Equal(t, 1, countCalls)
+	})
+
+	t.Run("assignment group", func(t *testing.T) {
+		client := newClient(t, "ok")
+		require.NoError(t, client.GetAssignmentGroup(t.Context(), "Service Desk"))
+		require.ErrorContains(t, client.GetAssignmentGroup(t.Context(), "No Such Group"), "not found")
+	})
+
+	t.Run("incidents", func(t *testing.T) {
+		client := newClient(t, "ok")
+
+		incident, err := client.FindOpenServiceNowIncident(t.Context(), "fleet-policy-1")
+		require.NoError(t, err)
+		require.NotNil(t, incident)
+		assert.Equal(t, "INC0010001", incident.Number)
+		assert.Equal(t, time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC), incident.UpdatedAt())
+
+		incident, err = client.FindOpenServiceNowIncident(t.Context(), "fleet-policy-2")
+		require.NoError(t, err)
+		require.Nil(t, incident)
+
+		incident, err = client.CreateServiceNowIncident(t.Context(), map[string]string{"short_description": "test", "correlation_id": "fleet-policy-2"})
+		require.NoError(t, err)
+		assert.Equal(t, "INC0010002", incident.Number)
+		assert.Equal(t, map[string]string{"short_description": "test", "correlation_id": "fleet-policy-2"}, gotBody)
+
+		incident, err = client.UpdateServiceNowIncident(t.Context(), "abc", map[string]string{"state": ServiceNowIncidentStateResolved})
+		require.NoError(t, err)
+		assert.Equal(t, ServiceNowIncidentStateResolved, incident.State)
+		assert.Equal(t, map[string]string{"state": "6"}, gotBody)
+	})
+
+	t.Run("config matches", func(t *testing.T) {
+		client := newClient(t, "ok")
+		require.True(t, client.ServiceNowConfigMatches(&ServiceNowOptions{URL: srv.URL, Username: "fleet", Password: "ok"}))
+		require.False(t, client.ServiceNowConfigMatches(&ServiceNowOptions{URL: srv.URL, Username: "fleet", Password: "changed"}))
+	})
+}
@@ -0,0 +1,410 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/worker"
+	"github.com/hashicorp/go-multierror"
+)
+
+//////////////////////////////////////////////////////////////////////////////////
+// Save GitOps snapshot
+//////////////////////////////////////////////////////////////////////////////////
+
+func saveGitOpsSnapshotEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.SaveGitOpsSnapshotRequest)
+	snapshot, err := svc.SaveGitOpsSnapshot(ctx, req.TeamID)
+	if err != nil {
+		return fleet.SaveGitOpsSnapshotResponse{Err: err}, nil
+	}
+	return fleet.SaveGitOpsSnapshotResponse{
+		TeamID:    req.TeamID,
+		Checksum:  snapshot.Checksum,
+		AppliedAt: snapshot.AppliedAt,
+	}, nil
+}
+
+func (svc *Service) SaveGitOpsSnapshot(ctx context.Context, teamID *uint) (*fleet.GitOpsSnapshot, error) {
+	if err := svc.authorizeGitOpsDrift(ctx, teamID); err != nil {
+		return nil, err
+	}
+
+	var id uint
+	if teamID != nil {
+		id = *teamID
+	}
+	resources, _, err := gitOpsLiveResources(ctx, svc.ds, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get live gitops resources")
+	}
+	checksum, err := resources.Checksum()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "gitops snapshot checksum")
+	}
+	snapshot := &fleet.GitOpsSnapshot{
+		TeamID:    id,
+		Resources: resources,
+		Checksum:  checksum,
+		AppliedAt: svc.clock.Now().UTC(),
+	}
+	if err := svc.ds.SaveGitOpsSnapshot(ctx, snapshot); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "save gitops snapshot")
+	}
+	return snapshot, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Get GitOps drift
+//////////////////////////////////////////////////////////////////////////////////
+
+func getGitOpsDriftEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.GetGitOpsDriftRequest)
+	drift, err := svc.GetGitOpsDrift(ctx, req.TeamID)
+	if err != nil {
+		return fleet.GetGitOpsDriftResponse{Err: err}, nil
+	}
+	return fleet.GetGitOpsDriftResponse{Drift: drift}, nil
+}
+
+func (svc *Service) GetGitOpsDrift(ctx context.Context, teamID *uint) ([]*fleet.GitOpsDrift, error) {
+	if err := svc.authorizeGitOpsDrift(ctx, teamID); err != nil {
+		return nil, err
+	}
+
+	var snapshots []*fleet.GitOpsSnapshot
+	if teamID != nil {
+		snapshot, err := svc.ds.GitOpsSnapshot(ctx, *teamID)
+		if err != nil {
+			if fleet.IsNotFound(err) {
+				return []*fleet.GitOpsDrift{}, nil
+			}
+			return nil, ctxerr.Wrap(ctx, err, "get gitops snapshot")
+		}
+		snapshots = append(snapshots, snapshot)
+	} else {
+		var err error
+		if snapshots, err = svc.ds.ListGitOpsSnapshots(ctx); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "list gitops snapshots")
+		}
+	}
+
+	// The drift is computed on request rather than read from the last check
+	// of the cron, so that changes are reported right away.
+	drift := make([]*fleet.GitOpsDrift, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		d, err := checkGitOpsSnapshot(ctx, svc.ds, snapshot, svc.clock.Now().UTC())
+		if err != nil {
+			if fleet.IsNotFound(err) {
+				// the fleet was deleted since the snapshot was listed
+				continue
+			}
+			return nil, err
+		}
+		drift = append(drift, d)
+	}
+	return drift, nil
+}
+
+// authorizeGitOpsDrift authorizes the GitOps snapshot and drift actions, which
+// require write access to the global config, or to the fleet if teamID is set.
+func (svc *Service) authorizeGitOpsDrift(ctx context.Context, teamID *uint) error {
+	if teamID != nil && *teamID == 0 {
+		svc.authz.SkipAuthorization(ctx) // skipauth: the request is rejected
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("fleet_id", "GitOps snapshots are not supported for unassigned hosts, omit fleet_id for the global config"))
+	}
+	if teamID != nil {
+		return svc.authz.Authorize(ctx, &fleet.Team{ID: *teamID}, fleet.ActionWrite)
+	}
+	return svc.authz.Authorize(ctx, &fleet.AppConfig{}, fleet.ActionWrite)
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// GitOps drift check
+//////////////////////////////////////////////////////////////////////////////////
+
+// CheckGitOpsDrift compares the live state of the resources applied by GitOps
+// against their snapshots. A new drift, or a drift that changed since the last
+// check, is reported through a detected_gitops_drift activity and the GitOps
+// drift webhook if enabled. The snapshots of deleted fleets are removed.
+func CheckGitOpsDrift(ctx context.Context, ds fleet.Datastore, logger *slog.Logger, newActivityFn fleet.NewActivityFunc) error {
+	snapshots, err := ds.ListGitOpsSnapshots(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list gitops snapshots")
+	}
+	if len(snapshots) == 0 {
+		return nil
+	}
+	appConfig, err := ds.AppConfig(ctx)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get app config")
+	}
+
+	var errs *multierror.Error
+	for _, snapshot := range snapshots {
+		if err := checkAndReportGitOpsDrift(ctx, ds, logger, newActivityFn, appConfig.WebhookSettings.GitOpsDriftWebhook, snapshot); err != nil {
+			errs = multierror.Append(errs, ctxerr.Wrapf(ctx, err, "check gitops drift of fleet %d", snapshot.TeamID))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+func checkAndReportGitOpsDrift(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger *slog.Logger,
+	newActivityFn fleet.NewActivityFunc,
+	webhook fleet.GitOpsDriftWebhookSettings,
+	snapshot *fleet.GitOpsSnapshot,
+) error {
+	drift, err := checkGitOpsSnapshot(ctx, ds, snapshot, time.Now().UTC())
+	if err != nil {
+		if fleet.IsNotFound(err) && snapshot.TeamID != 0 {
+			logger.InfoContext(ctx, "deleting gitops snapshot of deleted fleet", "fleet_id", snapshot.TeamID)
+			return ds.DeleteGitOpsSnapshot(ctx, snapshot.TeamID)
+		}
+		return err
+	}
+
+	checksum, err := fleet.GitOpsDriftChecksum(drift.Changes)
+	if err != nil {
+		return err
+	}
+	// no drift, or already reported
+	alreadyReported := checksum == "" || checksum == snapshot.DriftChecksum
+	if err := ds.UpdateGitOpsSnapshotDrift(ctx, snapshot.TeamID, drift.Changes, checksum, drift.CheckedAt); err != nil {
+		return err
+	}
+	if alreadyReported {
+		return nil
+	}
+
+	logger.InfoContext(ctx, "gitops drift detected", "fleet_id", snapshot.TeamID, "changes", len(drift.Changes))
+	if err := newActivityFn(ctx, nil, fleet.ActivityTypeDetectedGitOpsDrift{
+		TeamID:   drift.TeamID,
+		TeamName: drift.TeamName,
+		Changes:  len(drift.Changes),
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for gitops drift")
+	}
+
+	if !webhook.Enable {
+		return nil
+	}
+	payload, err := json.Marshal(map[string]any{
+		"text": gitOpsDriftWebhookText(drift),
+		"data": map[string]any{
+			"fleet_id":   drift.TeamID,
+			"fleet_name": drift.TeamName,
+			"applied_at": drift.AppliedAt,
+			"checked_at": drift.CheckedAt,
+			"changes":    drift.Changes,
+		},
+	})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal gitops drift payload")
+	}
+	if _, err := worker.DeliverWebhook(ctx, ds, logger, worker.WebhookDeliveryRequest{
+		Type:    fleet.WebhookTypeGitOpsDrift,
+		URL:     webhook.DestinationURL,
+		Payload: payload,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "deliver gitops drift webhook")
+	}
+	return nil
+}
+
+func gitOpsDriftWebhookText(drift *fleet.GitOpsDrift) string {
+	scope := "the global config"
+	if drift.TeamName != nil {
+		scope = fmt.Sprintf("fleet %q", *drift.TeamName)
+	}
+	return fmt.Sprintf(
+		"%d resource(s) of %s applied by GitOps on %s were changed outside of GitOps. "+
+			"You've been sent this message because the GitOps drift webhook is enabled in your Fleet instance.",
+		len(drift.Changes), scope, drift.AppliedAt.Format(time.RFC3339),
+	)
+}
+
+// checkGitOpsSnapshot returns the drift of the live resources from the
+// snapshot. It returns a not found error if the fleet of the snapshot was
+// deleted.
+func checkGitOpsSnapshot(ctx context.Context, ds fleet.Datastore, snapshot *fleet.GitOpsSnapshot, now time.Time) (*fleet.GitOpsDrift, error) {
+	live, fleetName, err := gitOpsLiveResources(ctx, ds, snapshot.TeamID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get live gitops resources")
+	}
+	drift := &fleet.GitOpsDrift{
+		AppliedAt: snapshot.AppliedAt,
+		CheckedAt: now,
+		Changes:   snapshot.Resources.Diff(fleetName, live),
+	}
+	if snapshot.TeamID != 0 {
+		drift.TeamID = ptr.Uint(snapshot.TeamID)
+		drift.TeamName = ptr.String(fleetName)
+	}
+	if drift.Changes == nil {
+		drift.Changes = []fleet.GitOpsResourceChange{}
+	}
+	return drift, nil
+}
+
+// gitOpsDriftOrgSettings are the app config keys compared for drift, the
+// other keys are either not managed by GitOps or hold server state.
+var gitOpsDriftOrgSettings = []string{
+	"org_info", "server_settings", "smtp_settings", "host_expiry_settings", "activity_expiry_settings",
+	"features", "agent_options", "sso_settings", "fleet_desktop", "vulnerability_settings",
+	"webhook_settings", "integrations", "mdm", "gitops",
+}
+
+// gitOpsDriftStateFields are the (dot-separated) fields of the settings that
+// are computed by the server, and so never compared for drift.
+var gitOpsDriftStateFields = []string{
+	"smtp_settings.configured",
+	"mdm.enabled_and_configured",
+	"mdm.apple_bm_enabled_and_configured",
+	"mdm.apple_bm_terms_expired",
+	"mdm.windows_enabled_and_configured",
+	"mdm.android_enabled_and_configured",
+}
+
+var (
+	gitOpsDriftPolicyFields = []string{
+		"query", "description", "resolution", "platform", "critical", "calendar_events_enabled",
+		"conditional_access_enabled", "labels_include_any", "labels_include_all", "labels_exclude_any", "labels_exclude_all",
+	}
+	gitOpsDriftReportFields = []string{
+		"query", "description", "interval", "platform", "min_osquery_version", "automations_enabled",
+		"logging", "observer_can_run", "discard_data", "labels_include_any", "labels_include_all", "log_destinations",
+	}
+	gitOpsDriftLabelFields = []string{"query", "description", "platform", "label_membership_type", "criteria"}
+)
+
+// gitOpsLiveResources returns the normalized state of the GitOps-managed
+// resources of a fleet (0 for the global config) and the name of the fleet
+// (empty for the global config). The global config covers the org settings,
+// global policies, reports and labels, and a fleet its settings, policies,
+// reports and labels.
+func gitOpsLiveResources(ctx context.Context, ds fleet.Datastore, teamID uint) (fleet.GitOpsResources, string, error) {
+	resources := fleet.GitOpsResources{}
+	var (
+		fleetName string
+		policies  []*fleet.Policy
+		err       error
+	)
+
+	if teamID == 0 {
+		appConfig, err := ds.AppConfig(ctx)
+		if err != nil {
+			return nil, "", ctxerr.Wrap(ctx, err, "get app config")
+		}
+		appConfig = appConfig.Copy()
+		appConfig.Obfuscate()
+		settings, err := gitOpsDriftFields(appConfig, gitOpsDriftOrgSettings)
+		if err != nil {
+			return nil, "", err
+		}
+		resources[fleet.GitOpsResourceOrgSettings] = map[string]map[string]any{fleet.GitOpsResourceOrgSettings: settings}
+
+		if policies, err = ds.ListGlobalPolicies(ctx, fleet.ListOptions{}, ""); err != nil {
+			return nil, "", ctxerr.Wrap(ctx, err, "list global policies")
+		}
+	} else {
+		team, err := ds.TeamWithExtras(ctx, teamID)
+		if err != nil {
+			return nil, "", ctxerr.Wrap(ctx, err, "get fleet")
+		}
+		fleetName = team.Name
+		team.Config.WebhookSettings.MaskSecrets()
+		settings, err := gitOpsDriftFields(team.Config, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		resources[fleet.GitOpsResourceFleetSettings] = map[string]map[string]any{fleetName: settings}
+
+		if policies, _, err = ds.ListTeamPolicies(ctx, teamID, fleet.ListOptions{}, fleet.ListOptions{}, "", ""); err != nil {
+			return nil, "", ctxerr.Wrap(ctx, err, "list fleet policies")
+		}
+	}
+
+	resources[fleet.GitOpsResourcePolicy] = make(map[string]map[string]any, len(policies))
+	for _, p := range policies {
+		if resources[fleet.GitOpsResourcePolicy][p.Name], err = gitOpsDriftFields(p, gitOpsDriftPolicyFields); err != nil {
+			return nil, "", err
+		}
+	}
+
+	queryOpts := fleet.ListQueryOptions{}
+	if teamID != 0 {
+		queryOpts.TeamID = &teamID
+	}
+	reports, _, _, _, err := ds.ListQueries(ctx, queryOpts)
+	if err != nil {
+		return nil, "", ctxerr.Wrap(ctx, err, "list reports")
+	}
+	resources[fleet.GitOpsResourceReport] = make(map[string]map[string]any, len(reports))
+	for _, q := range reports {
+		if resources[fleet.GitOpsResourceReport][q.Name], err = gitOpsDriftFields(q, gitOpsDriftReportFields); err != nil {
+			return nil, "", err
+		}
+	}
+
+	labels, err := ds.ListLabels(ctx, fleet.TeamFilter{
+		User:   &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)},
+		TeamID: &teamID,
+	}, fleet.ListOptions{}, false)
+	if err != nil {
+		return nil, "", ctxerr.Wrap(ctx, err, "list labels")
+	}
+	resources[fleet.GitOpsResourceLabel] = make(map[string]map[string]any, len(labels))
+	for _, l := range labels {
+		if l.LabelType == fleet.LabelTypeBuiltIn {
+			continue
+		}
+		// the labels of a fleet are listed along with the global labels
+		var labelTeamID uint
+		if l.TeamID != nil {
+			labelTeamID = *l.TeamID
+		}
+		if labelTeamID != teamID {
+			continue
+		}
+		if resources[fleet.GitOpsResourceLabel][l.Name], err = gitOpsDriftFields(l, gitOpsDriftLabelFields); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return resources, fleetName, nil
+}
+
+// gitOpsDriftFields returns the normalized fields of v restricted to keys (all
+// fields if nil), without the fields computed by the server.
+func gitOpsDriftFields(v any, keys []string) (map[string]any, error) {
+	fields, err := fleet.NormalizeGitOpsFields(v)
+	if err != nil {
+		return nil, err
+	}
+	if keys != nil {
+		projected := make(map[string]any, len(keys))
+		for _, k := range keys {
+			if val, ok := fields[k]; ok {
+				projected[k] = val
+			}
+		}
+		fields = projected
+	}
+	for _, field := range gitOpsDriftStateFields {
+		parent, key, _ := strings.Cut(field, ".")
+		if m, ok := fields[parent].(map[string]any); ok {
+			delete(m, key)
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitOpsDriftAuth(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	mockGitOpsLiveResources(ds, []*fleet.Policy{}, nil)
+
+	ds.SaveGitOpsSnapshotFunc = func(ctx context.Context, snapshot *fleet.GitOpsSnapshot) error {
+		return nil
+	}
+	ds.GitOpsSnapshotFunc = func(ctx context.Context, teamID uint) (*fleet.GitOpsSnapshot, error) {
+		return nil, newNotFoundError()
+	}
+	ds.ListGitOpsSnapshotsFunc = func(ctx context.Context) ([]*fleet.GitOpsSnapshot, error) {
+		return nil, nil
+	}
+
+	teamID := uint(1)
+	cases := []struct {
+		name          string
+		user          *fleet.User
+		globalAllowed bool
+		teamAllowed   bool
+	}{
+		{"global admin", &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}, true, true},
+		{"global gitops", &fleet.User{ID: 2, GlobalRole: new(fleet.RoleGitOps)}, true, true},
+		{"global maintainer", &fleet.User{ID: 3, GlobalRole: new(fleet.RoleMaintainer)}, false, false},
+		{"team admin", &fleet.User{ID: 4, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: teamID}, Role: fleet.RoleAdmin}}}, false, true},
+		{"team gitops", &fleet.User{ID: 5, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: teamID}, Role: fleet.RoleGitOps}}}, false, true},
+		{"team maintainer", &fleet.User{ID: 6, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: teamID}, Role: fleet.RoleMaintainer}}}, false, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.SaveGitOpsSnapshot(ctx, nil)
+			checkAuthErr(t, !tt.globalAllowed, err)
+			_, err = svc.GetGitOpsDrift(ctx, nil)
+			checkAuthErr(t, !tt.globalAllowed, err)
+
+			_, err = svc.SaveGitOpsSnapshot(ctx, &teamID)
+			checkAuthErr(t, !tt.teamAllowed, err)
+			_, err = svc.GetGitOpsDrift(ctx, &teamID)
+			checkAuthErr(t, !tt.teamAllowed, err)
+		})
+	}
+
+	// the unassigned hosts have no snapshot
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}})
+	_, err := svc.SaveGitOpsSnapshot(ctx, new(uint(0)))
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+}
+
+func TestCheckGitOpsDrift(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: new(fleet.RoleGitOps)}})
+
+	policies := []*fleet.Policy{{PolicyData: fleet.PolicyData{ID: 1, Name: "foo", Query: "SELECT 1"}}}
+	var webhookURL string
+	mockGitOpsLiveResources(ds, policies, func(ac *fleet.AppConfig) {
+		ac.WebhookSettings.GitOpsDriftWebhook = fleet.GitOpsDriftWebhookSettings{Enable: true, DestinationURL: webhookURL}
+	})
+
+	var snapshot *fleet.GitOpsSnapshot
+	ds.SaveGitOpsSnapshotFunc = func(ctx context.Context, s *fleet.GitOpsSnapshot) error {
+		snapshot = s
+		return nil
+	}
+	ds.ListGitOpsSnapshotsFunc = func(ctx context.Context) ([]*fleet.GitOpsSnapshot, error) {
+		return []*fleet.GitOpsSnapshot{snapshot}, nil
+	}
+	ds.UpdateGitOpsSnapshotDriftFunc = func(ctx context.Context, teamID uint, drift []fleet.GitOpsResourceChange, driftChecksum string, checkedAt time.Time) error {
+		snapshot.Drift = drift
+		snapshot.DriftChecksum = driftChecksum
+		snapshot.CheckedAt = &checkedAt
+		return nil
+	}
+
+	var payloads []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var payload map[string]any
+		require.NoError(t, json.Unmarshal(b, &payload))
+		payloads = append(payloads, payload)
+	}))
+	t.Cleanup(srv.Close)
+	webhookURL = srv.URL
+	ds.NewWebhookDeliveryFunc = func(ctx context.Context, d *fleet.WebhookDelivery) (*fleet.WebhookDelivery, error) {
+		d.Status = fleet.WebhookDeliveryStatusPending
+		return d, nil
+	}
+	ds.UpdateWebhookDeliveryFunc = func(ctx context.Context, d *fleet.WebhookDelivery) error {
+		return nil
+	}
+
+	var activities []fleet.ActivityDetails
+	newActivityFn := func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		activities = append(activities, activity)
+		return nil
+	}
+	logger := slog.New(slog.DiscardHandler)
+
+	_, err := svc.SaveGitOpsSnapshot(ctx, nil)
+	require.NoError(t, err)
+	require.NotNil(t, snapshot)
+	require.NotEmpty(t, snapshot.Checksum)
+
+	// no drift yet
+	require.NoError(t, CheckGitOpsDrift(ctx, ds, logger, newActivityFn))
+	require.Empty(t, activities)
+	require.Empty(t, payloads)
+	require.NotNil(t, snapshot.CheckedAt)
+
+	// the policy is changed through the API
+	policies[0].Query = "SELECT 2"
+	require.NoError(t, CheckGitOpsDrift(ctx, ds, logger, newActivityFn))
+	require.Len(t, activities, 1)
+	assert.Equal(t, fleet.ActivityTypeDetectedGitOpsDrift{Changes: 1}, activities[0])
+	require.Len(t, payloads, 1)
+	data := payloads[0]["data"].(map[string]any)
+	assert.Nil(t, data["fleet_id"])
+	assert.Len(t, data["changes"], 1)
+	require.Len(t, snapshot.Drift, 1)
+	assert.Equal(t, fleet.GitOpsResourcePolicy, snapshot.Drift[0].Kind)
+	assert.Equal(t, "foo", snapshot.Drift[0].Name)
+
+	// the same drift is reported only once
+	require.NoError(t, CheckGitOpsDrift(ctx, ds, logger, newActivityFn))
+	require.Len(t, activities, 1)
+	require.Len(t, payloads, 1)
+
+	// the drift is returned by the API
+	drift, err := svc.GetGitOpsDrift(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, drift, 1)
+	assert.Nil(t, drift[0].TeamID)
+	assert.Equal(t, snapshot.Drift, drift[0].Changes)
+
+	// reverting the change clears the drift
+	policies[0].Query = "SELECT 1"
+	require.NoError(t, CheckGitOpsDrift(ctx, ds, logger, newActivityFn))
+	require.Len(t, activities, 1)
+	assert.Empty(t, snapshot.Drift)
+	assert.Empty(t, snapshot.DriftChecksum)
+}
+
+func TestCheckGitOpsDriftDeletedFleet(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+
+	ds.ListGitOpsSnapshotsFunc = func(ctx context.Context) ([]*fleet.GitOpsSnapshot, error) {
+		return []*fleet.GitOpsSnapshot{{TeamID: 3, Resources: fleet.GitOpsResources{}}}, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.TeamWithExtrasFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return nil, newNotFoundError()
+	}
+	var deleted []uint
+	ds.DeleteGitOpsSnapshotFunc = func(ctx context.Context, teamID uint) error {
+		deleted = append(deleted, teamID)
+		return nil
+	}
+
+	newActivityFn := func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		t.Fatalf("unexpected activity %s", activity.ActivityName())
+		return nil
+	}
+	require.NoError(t, CheckGitOpsDrift(t.Context(), ds, slog.New(slog.DiscardHandler), newActivityFn))
+	assert.Equal(t, []uint{3}, deleted)
+}
+
+// mockGitOpsLiveResources mocks the datastore methods used to get the live
+// state of the global resources compared for drift, with the given global
+// policies and no reports nor labels.
+func mockGitOpsLiveResources(ds *mock.Store, policies []*fleet.Policy, modifyAppConfig func(*fleet.AppConfig)) {
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		ac := &fleet.AppConfig{OrgInfo: fleet.OrgInfo{OrgName: "Acme"}}
+		if modifyAppConfig != nil {
+			modifyAppConfig(ac)
+		}
+		return ac, nil
+	}
+	ds.TeamWithExtrasFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		return &fleet.Team{ID: tid, Name: "team"}, nil
+	}
+	ds.ListGlobalPoliciesFunc = func(ctx context.Context, opts fleet.ListOptions, platform string) ([]*fleet.Policy, error) {
+		return policies, nil
+	}
+	ds.ListTeamPoliciesFunc = func(ctx context.Context, teamID uint, opts fleet.ListOptions, iopts fleet.ListOptions, automationType fleet.PolicyAutomationType, platform string) ([]*fleet.Policy, []*fleet.Policy, error) {
+		return nil, nil, nil
+	}
+	ds.ListQueriesFunc = func(ctx context.Context, opt fleet.ListQueryOptions) ([]*fleet.Query, int, int, *fleet.PaginationMetadata, error) {
+		return nil, 0, 0, nil, nil
+	}
+	ds.ListLabelsFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.ListOptions, includeHostCounts bool) ([]*fleet.Label, error) {
+		return nil, nil
+	}
+}
@@ -288,7 +288,7 @@ func (svc *Service) ResetAutomation(ctx context.Context, teamIDs, policyIDs []ui
 	if err != nil {
 		return err
 	}
-	allAutoPolicies := automationPolicies(ac.WebhookSettings.FailingPoliciesWebhook, ac.Integrations.Jira, ac.Integrations.Zendesk)
+	allAutoPolicies := automationPolicies(ac.WebhookSettings.FailingPoliciesWebhook, ac.Integrations.Jira, ac.Integrations.Zendesk, ac.Integrations.ServiceNow)
 	pIDs := make(map[uint]struct{})
 	for _, id := range policyIDs {
 		pIDs[id] = struct{}{}
@@ -341,7 +341,7 @@ func (svc *Service) ResetAutomation(ctx context.Context, teamIDs, policyIDs []ui
 			}
 			teamConfig = t.Config
 		}
-		for pID := range teamAutomationPolicies(teamConfig.WebhookSettings.FailingPoliciesWebhook, teamConfig.Integrations.Jira, teamConfig.Integrations.Zendesk) {
+		for pID := range teamAutomationPolicies(teamConfig.WebhookSettings.FailingPoliciesWebhook, teamConfig.Integrations.Jira, teamConfig.Integrations.Zendesk, teamConfig.Integrations.ServiceNow) {
 			allAutoPolicies[pID] = struct{}{}
 		}
 	}
@@ -365,7 +365,7 @@ func (svc *Service) ResetAutomation(ctx context.Context, teamIDs, policyIDs []ui
 	return nil
 }
 
-func automationPolicies(wh fleet.FailingPoliciesWebhookSettings, ji []*fleet.JiraIntegration, zi []*fleet.ZendeskIntegration) map[uint]struct{} {
+func automationPolicies(wh fleet.FailingPoliciesWebhookSettings, ji []*fleet.JiraIntegration, zi []*fleet.ZendeskIntegration, si []*fleet.ServiceNowIntegration) map[uint]struct{} {
 	enabled := wh.Enable
 	for _, j := range ji {
 		if j.EnableFailingPolicies {
@@ -377,6 +377,11 @@ func automationPolicies(wh fleet.FailingPoliciesWebhookSettings, ji []*fleet.Jir
 			enabled = true
 		}
 	}
+	for _, sn := range si {
+		if sn.EnableFailingPolicies {
+			enabled = true
+		}
+	}
 	pols := make(map[uint]struct{}, len(wh.PolicyIDs))
 	if !enabled {
 		return pols
@@ -387,7 +392,7 @@ func automationPolicies(wh fleet.FailingPoliciesWebhookSettings, ji []*fleet.Jir
 	return pols
 }
 
-func teamAutomationPolicies(wh fleet.FailingPoliciesWebhookSettings, ji []*fleet.TeamJiraIntegration, zi []*fleet.TeamZendeskIntegration) map[uint]struct{} {
+func teamAutomationPolicies(wh fleet.FailingPoliciesWebhookSettings, ji []*fleet.TeamJiraIntegration, zi []*fleet.TeamZendeskIntegration, si []*fleet.TeamServiceNowIntegration) map[uint]struct{} {
 	enabled := wh.Enable
 	for _, j := range ji {
 		if j.EnableFailingPolicies {
@@ -399,6 +404,11 @@ func teamAutomationPolicies(wh fleet.FailingPoliciesWebhookSettings, ji []*fleet
 			enabled = true
 		}
 	}
+	for _, sn := range si {
+		if sn.EnableFailingPolicies {
+			enabled = true
+		}
+	}
 	pols := make(map[uint]struct{}, len(wh.PolicyIDs))
 	if !enabled {
 		return pols
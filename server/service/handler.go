@@ -303,6 +303,14 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.POST("/api/_version_/fleet/trigger", triggerEndpoint, triggerRequest{})
 
 	ue.GET("/api/_version_/fleet/me", meEndpoint, getMeRequest{})
+	ue.GET("/api/_version_/fleet/me/mfa", getUserMFAEndpoint, nil)
+	ue.POST("/api/_version_/fleet/me/mfa/totp", beginTOTPEnrollmentEndpoint, nil)
+	ue.POST("/api/_version_/fleet/me/mfa/totp/confirm", confirmTOTPEnrollmentEndpoint, confirmTOTPEnrollmentRequest{})
+	ue.DELETE("/api/_version_/fleet/me/mfa/totp", disableTOTPEndpoint, mfaPasswordRequest{})
+	ue.POST("/api/_version_/fleet/me/mfa/webauthn/registration", beginWebAuthnRegistrationEndpoint, nil)
+	ue.POST("/api/_version_/fleet/me/mfa/webauthn", finishWebAuthnRegistrationEndpoint, finishWebAuthnRegistrationRequest{})
+	ue.DELETE("/api/_version_/fleet/me/mfa/webauthn/{id:[0-9]+}", deleteWebAuthnCredentialEndpoint, deleteWebAuthnCredentialRequest{})
+	ue.POST("/api/_version_/fleet/me/mfa/recovery_codes", regenerateMFARecoveryCodesEndpoint, mfaPasswordRequest{})
 	ue.GET("/api/_version_/fleet/sessions/{id:[0-9]+}", getInfoAboutSessionEndpoint, getInfoAboutSessionRequest{})
 	ue.DELETE("/api/_version_/fleet/sessions/{id:[0-9]+}", deleteSessionEndpoint, deleteSessionRequest{})
 
@@ -329,6 +337,7 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.PATCH("/api/_version_/fleet/fleets/{id:[0-9]+}/users", addTeamUsersEndpoint, modifyTeamUsersRequest{})
 	ue.DELETE("/api/_version_/fleet/fleets/{id:[0-9]+}/users", deleteTeamUsersEndpoint, modifyTeamUsersRequest{})
 	ue.GET("/api/_version_/fleet/fleets/{id:[0-9]+}/secrets", teamEnrollSecretsEndpoint, teamEnrollSecretsRequest{})
+	ue.GET("/api/_version_/fleet/fleets/{id:[0-9]+}/sbom", getTeamSBOMEndpoint, getTeamSBOMRequest{})
 
 	ue.GET("/api/_version_/fleet/users", listUsersEndpoint, listUsersRequest{})
 	ue.POST("/api/_version_/fleet/users/admin", createUserEndpoint, createUserRequest{})
@@ -338,6 +347,7 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.PATCH("/api/_version_/fleet/users/{id:[0-9]+}", modifyUserEndpoint, modifyUserRequest{})
 	ue.DELETE("/api/_version_/fleet/users/{id:[0-9]+}", deleteUserEndpoint, deleteUserRequest{})
 	ue.POST("/api/_version_/fleet/users/{id:[0-9]+}/require_password_reset", requirePasswordResetEndpoint, requirePasswordResetRequest{})
+	ue.DELETE("/api/_version_/fleet/users/{id:[0-9]+}/mfa", resetUserMFAEndpoint, resetUserMFARequest{})
 	ue.GET("/api/_version_/fleet/users/{id:[0-9]+}/sessions", getInfoAboutSessionsForUserEndpoint, getInfoAboutSessionsForUserRequest{})
 	ue.DELETE("/api/_version_/fleet/users/{id:[0-9]+}/sessions", deleteSessionsForUserEndpoint, deleteSessionsForUserRequest{})
 	ue.POST("/api/_version_/fleet/change_password", changePasswordEndpoint, changePasswordRequest{})
@@ -385,6 +395,7 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.GET("/api/_version_/fleet/reports/{id:[0-9]+}", getQueryEndpoint, fleet.GetQueryRequest{})
 	ue.GET("/api/_version_/fleet/reports", listQueriesEndpoint, fleet.ListQueriesRequest{})
 	ue.GET("/api/_version_/fleet/reports/{id:[0-9]+}/report", getQueryReportEndpoint, fleet.GetQueryReportRequest{})
+	ue.GET("/api/_version_/fleet/reports/{id:[0-9]+}/report/diff", diffQueryReportEndpoint, fleet.DiffQueryReportRequest{})
 	ue.POST("/api/_version_/fleet/reports", createQueryEndpoint, fleet.CreateQueryRequest{})
 	ue.PATCH("/api/_version_/fleet/reports/{id:[0-9]+}", modifyQueryEndpoint, fleet.ModifyQueryRequest{})
 	ue.DELETE("/api/_version_/fleet/reports/{name}", deleteQueryEndpoint, fleet.DeleteQueryRequest{})
@@ -473,6 +484,28 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	// Vulnerabilities
 	ue.GET("/api/_version_/fleet/vulnerabilities", listVulnerabilitiesEndpoint, listVulnerabilitiesRequest{})
 	ue.GET("/api/_version_/fleet/vulnerabilities/{cve}", getVulnerabilityEndpoint, getVulnerabilityRequest{})
+	ue.WithRequestBodySizeLimit(fleet.VEXDocumentMaxSize).POST("/api/_version_/fleet/vex_documents", uploadVEXDocumentEndpoint, uploadVEXDocumentRequest{})
+	ue.GET("/api/_version_/fleet/vex_documents", listVEXDocumentsEndpoint, nil)
+	ue.DELETE("/api/_version_/fleet/vex_documents/{id:[0-9]+}", deleteVEXDocumentEndpoint, deleteVEXDocumentRequest{})
+	ue.GET("/api/_version_/fleet/vex_suppressions", listVEXSuppressionsEndpoint, listVEXSuppressionsRequest{})
+	ue.GET("/api/_version_/fleet/vulnerability_exceptions", listVulnerabilityExceptionsEndpoint, fleet.ListVulnerabilityExceptionsRequest{})
+	ue.GET("/api/_version_/fleet/vulnerability_exceptions/{id:[0-9]+}", getVulnerabilityExceptionEndpoint, fleet.GetVulnerabilityExceptionRequest{})
+	ue.POST("/api/_version_/fleet/vulnerability_exceptions", createVulnerabilityExceptionEndpoint, fleet.CreateVulnerabilityExceptionRequest{})
+	ue.PATCH("/api/_version_/fleet/vulnerability_exceptions/{id:[0-9]+}", modifyVulnerabilityExceptionEndpoint, fleet.ModifyVulnerabilityExceptionRequest{})
+	ue.DELETE("/api/_version_/fleet/vulnerability_exceptions/{id:[0-9]+}", deleteVulnerabilityExceptionEndpoint, fleet.DeleteVulnerabilityExceptionRequest{})
+	ue.PUT("/api/_version_/fleet/spec/vulnerability_exceptions", applyVulnerabilityExceptionsEndpoint, fleet.ApplyVulnerabilityExceptionsRequest{})
+
+	// Webhook deliveries
+	ue.GET("/api/_version_/fleet/webhooks/deliveries", listWebhookDeliveriesEndpoint, fleet.ListWebhookDeliveriesRequest{})
+	ue.GET("/api/_version_/fleet/webhooks/deliveries/{id:[0-9]+}", getWebhookDeliveryEndpoint, fleet.GetWebhookDeliveryRequest{})
+	ue.POST("/api/_version_/fleet/webhooks/deliveries/{id:[0-9]+}/redeliver", redeliverWebhookDeliveryEndpoint, fleet.RedeliverWebhookDeliveryRequest{})
+
+	// GitOps drift
+	ue.POST("/api/_version_/fleet/gitops/snapshots", saveGitOpsSnapshotEndpoint, fleet.SaveGitOpsSnapshotRequest{})
+	ue.GET("/api/_version_/fleet/gitops/drift", getGitOpsDriftEndpoint, fleet.GetGitOpsDriftRequest{})
+
+	// fleetd caching proxies
+	ue.GET("/api/_version_/fleet/cache_proxies", listCacheProxiesEndpoint, fleet.ListCacheProxiesRequest{})
 
 	// Hosts
 	ue.GET("/api/_version_/fleet/host_summary", getHostSummaryEndpoint, getHostSummaryRequest{})
@@ -501,6 +534,7 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/labels", addLabelsToHostEndpoint, addLabelsToHostRequest{})
 	ue.DELETE("/api/_version_/fleet/hosts/{id:[0-9]+}/labels", removeLabelsFromHostEndpoint, fleet.RemoveLabelsFromHostRequest{})
 	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/software", getHostSoftwareEndpoint, getHostSoftwareRequest{})
+	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/sbom", getHostSBOMEndpoint, getHostSBOMRequest{})
 	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/certificates", listHostCertificatesEndpoint, listHostCertificatesRequest{})
 	ue.POST("/api/_version_/fleet/hosts/{id:[0-9]+}/certificates/{template_id:[0-9]+}/resend", resendHostCertificateTemplateEndpoint, resendHostCertificateTemplateRequest{})
 	ue.GET("/api/_version_/fleet/hosts/{id:[0-9]+}/recovery_lock_password", getHostRecoveryLockPasswordEndpoint, getHostRecoveryLockPasswordRequest{})
@@ -534,6 +568,12 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.POST("/api/_version_/fleet/reports/run_by_identifiers", createDistributedQueryCampaignByIdentifierEndpoint, createDistributedQueryCampaignByIdentifierRequest{})
 	// This endpoint is deprecated and maintained for backwards compatibility. This and above endpoint are functionally equivalent
 	ue.POST("/api/_version_/fleet/reports/run_by_names", createDistributedQueryCampaignByIdentifierEndpoint, createDistributedQueryCampaignByIdentifierRequest{})
+	// The results of deferred live queries are persisted and retrieved with the following endpoints instead of websockets.
+	ue.GET("/api/_version_/fleet/reports/campaigns", listQueryCampaignsEndpoint, listQueryCampaignsRequest{})
+	ue.GET("/api/_version_/fleet/reports/campaigns/{id:[0-9]+}", getQueryCampaignEndpoint, getQueryCampaignRequest{})
+	ue.GET("/api/_version_/fleet/reports/campaigns/{id:[0-9]+}/results", listCampaignQueryResultsEndpoint, listCampaignQueryResultsRequest{})
+	ue.GET("/api/_version_/fleet/reports/campaigns/{id:[0-9]+}/results/export", exportCampaignQueryResultsEndpoint, exportCampaignQueryResultsRequest{})
+	ue.GET("/api/_version_/fleet/reports/campaigns/{id:[0-9]+}/results/diff", diffCampaignQueryResultsEndpoint, diffCampaignQueryResultsRequest{})
 
 	ue.GET("/api/_version_/fleet/packs/{id:[0-9]+}/scheduled", getScheduledQueriesInPackEndpoint, fleet.GetScheduledQueriesInPackRequest{})
 	ue.EndingAtVersion("v1").POST("/api/_version_/fleet/schedule", scheduleQueryEndpoint, fleet.ScheduleQueryRequest{})
@@ -616,6 +656,15 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ue.PUT("/api/_version_/fleet/hosts/{host_id:[0-9]+}/custom_host_vitals/{id:[0-9]+}", setHostCustomHostVitalValueEndpoint, fleet.SetHostCustomHostVitalValueRequest{})
 	ue.PUT("/api/_version_/fleet/spec/custom_host_vitals", upsertCustomHostVitalsEndpoint, fleet.UpsertCustomHostVitalsRequest{})
 
+	// Custom roles
+	ue.GET("/api/_version_/fleet/custom_roles", listCustomRolesEndpoint, fleet.ListCustomRolesRequest{})
+	ue.GET("/api/_version_/fleet/custom_roles/{id:[0-9]+}", getCustomRoleEndpoint, fleet.GetCustomRoleRequest{})
+	ue.POST("/api/_version_/fleet/custom_roles", createCustomRoleEndpoint, fleet.CreateCustomRoleRequest{})
+	ue.PATCH("/api/_version_/fleet/custom_roles/{id:[0-9]+}", modifyCustomRoleEndpoint, fleet.ModifyCustomRoleRequest{})
+	ue.DELETE("/api/_version_/fleet/custom_roles/{id:[0-9]+}", deleteCustomRoleEndpoint, fleet.DeleteCustomRoleRequest{})
+	ue.PUT("/api/_version_/fleet/users/{id:[0-9]+}/custom_roles", setUserCustomRolesEndpoint, fleet.SetUserCustomRolesRequest{})
+	ue.PUT("/api/_version_/fleet/spec/custom_roles", applyCustomRolesEndpoint, fleet.ApplyCustomRolesRequest{})
+
 	// API end-points
 	ue.GET("/api/_version_/fleet/rest_api", listAPIEndpointsEndpoint, listAPIEndpointsRequest{})
 
@@ -1069,6 +1118,8 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	oeWindowsMDM.POST("/api/fleet/orbit/managed_local_account", postOrbitManagedLocalAccountEndpoint, fleet.OrbitPostManagedLocalAccountRequest{})
 
 	oe.POST("/api/fleet/orbit/luks_data", postOrbitLUKSEndpoint, fleet.OrbitPostLUKSRequest{})
+	oe.POST("/api/fleet/orbit/cache_proxy/stats", postOrbitCacheProxyStatsEndpoint, fleet.OrbitPostCacheProxyStatsRequest{})
+	oe.POST("/api/fleet/orbit/cache_proxy/software_install/package", orbitCacheProxyDownloadSoftwareInstallerEndpoint, fleet.OrbitCacheProxyDownloadSoftwareInstallerRequest{})
 
 	// unauthenticated endpoints - most of those are either login-related,
 	// invite-related or host-enrolling. So they typically do some kind of
@@ -1226,6 +1277,8 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ne.WithCustomMiddleware(ssoLimiter).
 		WithRequestBodySizeLimit(fleet.MaxSSOCallbackSize).
 		POST("/api/v1/fleet/sso/callback", makeCallbackSSOEndpoint(config.Server.URLPrefix), callbackSSORequest{})
+	ne.WithCustomMiddleware(ssoLimiter).
+		GET(oidcCallbackPath, makeCallbackOIDCEndpoint(config.Server.URLPrefix), callbackOIDCRequest{})
 	ne.GET("/api/v1/fleet/sso", settingsSSOEndpoint, nil)
 
 	// the websocket distributed query results endpoint is a bit different - the
@@ -1236,6 +1289,11 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 	ne.UsePathPrefix().PathHandler("GET", "/api/_version_/fleet/results/",
 		makeStreamDistributedQueryCampaignResultsHandler(config.Server, svc, logger))
 
+	// the orbit notifications websocket works the same way, fleetd sends its
+	// orbit node key once the websocket session is established.
+	ne.UsePathPrefix().PathHandler("GET", orbitNotificationsPath,
+		makeOrbitNotificationsHandler(config.Server, svc, logger))
+
 	quota := throttled.RateQuota{MaxRate: throttled.PerHour(10), MaxBurst: forgotPasswordRateLimitMaxBurst}
 	ne.
 		WithCustomMiddleware(limiter.Limit("forgot_password", quota)).
@@ -1245,6 +1303,8 @@ func attachFleetAPIRoutes(r *mux.Router, svc fleet.Service, config config.FleetC
 		POST("/api/_version_/fleet/login", loginEndpoint, fleet.LoginRequest{})
 	ne.WithCustomMiddleware(limiter.Limit("mfa", throttled.RateQuota{MaxRate: loginRateLimit, MaxBurst: 9})).
 		POST("/api/_version_/fleet/sessions", sessionCreateEndpoint, sessionCreateRequest{})
+	ne.WithCustomMiddleware(limiter.Limit("login_mfa", throttled.RateQuota{MaxRate: loginRateLimit, MaxBurst: 9})).
+		POST("/api/_version_/fleet/sessions/mfa", completeLoginMFAEndpoint, fleet.MFAVerification{})
 
 	ne.HEAD("/api/fleet/device/ping", devicePingEndpoint, devicePingRequest{})
 
@@ -1364,6 +1364,8 @@ func (svc *Service) AddHostsToTeam(ctx context.Context, teamID *uint, hostIDs []
 	if err := svc.ds.AddHostsToTeam(ctx, fleet.NewAddHostsToTeamParams(teamID, hostIDs)); err != nil {
 		return err
 	}
+	// the orbit config of the hosts depends on their fleet
+	svc.NotifyOrbitHosts(ctx, fleet.OrbitNotificationHintConfig, hostIDs...)
 
 	androidUUIDs, err := svc.ds.ListMDMAndroidUUIDsToHostIDs(ctx, hostIDs)
 	if err != nil {
@@ -1548,6 +1550,8 @@ func (svc *Service) AddHostsToTeamByFilter(ctx context.Context, teamID *uint, fi
 	if err := svc.ds.AddHostsToTeam(ctx, fleet.NewAddHostsToTeamParams(teamID, hostIDs)); err != nil {
 		return err
 	}
+	// the orbit config of the hosts depends on their fleet
+	svc.NotifyOrbitHosts(ctx, fleet.OrbitNotificationHintConfig, hostIDs...)
 
 	// Create pending certificate template records for Android hosts before
 	// marking profiles pending
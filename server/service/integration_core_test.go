@@ -1067,6 +1067,9 @@ func (s *integrationTestSuite) TestAppConfigHistoricalData() {
 	require.NoError(t, err)
 	require.True(t, loadedCfg.Features.HistoricalData.Uptime, "pre-change row reads back as default true")
 	require.True(t, loadedCfg.Features.HistoricalData.Vulnerabilities, "pre-change row reads back as default true")
+	require.True(t, loadedCfg.Features.HistoricalData.Policies, "pre-change row reads back as default true")
+	require.True(t, loadedCfg.Features.HistoricalData.MDMEnrollment, "pre-change row reads back as default true")
+	require.True(t, loadedCfg.Features.HistoricalData.DiskEncryption, "pre-change row reads back as default true")
 }
 
 func (s *integrationTestSuite) TestUserRolesSpec() {
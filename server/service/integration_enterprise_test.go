@@ -314,7 +314,9 @@ func (s *integrationEnterpriseTestSuite) TestTeamSpecs() {
 		EnableHostUsers:         false,
 		EnableSoftwareInventory: false,
 		AdditionalQueries:       new(json.RawMessage(`{"foo": "bar"}`)),
-		HistoricalData:          fleet.HistoricalDataSettings{Uptime: true, Vulnerabilities: true},
+		HistoricalData: fleet.HistoricalDataSettings{
+			Uptime: true, Vulnerabilities: true, Policies: true, MDMEnrollment: true, DiskEncryption: true,
+		},
 	}, team.Config.Features)
 	require.Equal(t, fleet.TeamMDM{
 		MacOSUpdates: fleet.AppleOSUpdateSettings{
@@ -1778,6 +1780,9 @@ func (s *integrationEnterpriseTestSuite) TestModifyTeamHistoricalData() {
 	s.DoJSON("POST", "/api/latest/fleet/teams", createPayload, http.StatusOK, &createResp)
 	require.True(t, createResp.Team.Config.Features.HistoricalData.Uptime, "new fleet defaults uptime=true")
 	require.True(t, createResp.Team.Config.Features.HistoricalData.Vulnerabilities, "new fleet defaults vulnerabilities=true")
+	require.True(t, createResp.Team.Config.Features.HistoricalData.Policies, "new fleet defaults policies=true")
+	require.True(t, createResp.Team.Config.Features.HistoricalData.MDMEnrollment, "new fleet defaults mdm_enrollment=true")
+	require.True(t, createResp.Team.Config.Features.HistoricalData.DiskEncryption, "new fleet defaults disk_encryption=true")
 	teamID := createResp.Team.ID
 	t.Cleanup(func() {
 		require.NoError(t, s.ds.DeleteTeam(ctx, teamID))
@@ -3536,7 +3541,7 @@ func (s *integrationEnterpriseTestSuite) TestFailingPolicyAutomationFiresHostAct
 
 	activitySvc := mysqltest.NewTestActivityService(t, s.ds)
 	require.NoError(t, webhooks.SendFailingPoliciesBatchedPOSTs(
-		ctx, policy, failingPolicySet, 0, serverURL, webhookURL, time.Now(),
+		ctx, s.ds, policy, failingPolicySet, 0, serverURL, webhookURL, time.Now(),
 		slog.New(slog.DiscardHandler), activitySvc,
 	))
 
@@ -3661,7 +3666,7 @@ func (s *integrationEnterpriseTestSuite) TestGlobalPolicyAutomationFiresEachFlee
 
 	activitySvc := mysqltest.NewTestActivityService(t, s.ds)
 	require.NoError(t, webhooks.SendFailingPoliciesBatchedPOSTs(
-		ctx, policy, failingPolicySet, 0, serverURL, webhookURL, time.Now(),
+		ctx, s.ds, policy, failingPolicySet, 0, serverURL, webhookURL, time.Now(),
 		slog.New(slog.DiscardHandler), activitySvc,
 	))
 
@@ -14034,6 +14039,34 @@ func (s *integrationEnterpriseTestSuite) TestSoftwareInstallerUploadDownloadAndD
 		}, http.StatusOK)
 		checkDownloadResponse(t, r, payload.Filename)
 
+		// caching proxies download the installer by its hash, with their own node key
+		installerMeta, err := s.ds.GetSoftwareInstallerMetadataByID(context.Background(), installerID)
+		require.NoError(t, err)
+		proxyInTeam := createOrbitEnrolledHost(t, "linux", "orbit-proxy-team", s.ds)
+		require.NoError(t, s.ds.AddHostsToTeam(context.Background(), fleet.NewAddHostsToTeamParams(&createTeamResp.Team.ID, []uint{proxyInTeam.ID})))
+		proxyNoTeam := createOrbitEnrolledHost(t, "linux", "orbit-proxy-no-team", s.ds)
+		cacheProxyDownload := func(proxy *fleet.Host, alt, hash string, expectedStatus int) *http.Response {
+			return s.Do("POST", "/api/fleet/orbit/cache_proxy/software_install/package?alt="+alt, fleet.OrbitCacheProxyDownloadSoftwareInstallerRequest{
+				HashSHA256:   hash,
+				OrbitNodeKey: *proxy.OrbitNodeKey,
+			}, expectedStatus)
+		}
+
+		// a host that doesn't run as a caching proxy can't download it
+		cacheProxyDownload(proxyInTeam, "media", installerMeta.StorageID, http.StatusForbidden)
+		for _, h := range []*fleet.Host{proxyInTeam, proxyNoTeam} {
+			s.Do("POST", "/api/fleet/orbit/cache_proxy/stats", fleet.OrbitPostCacheProxyStatsRequest{
+				OrbitNodeKey: *h.OrbitNodeKey,
+			}, http.StatusNoContent)
+		}
+		r = cacheProxyDownload(proxyInTeam, "FOOBAR", installerMeta.StorageID, http.StatusBadRequest)
+		require.Contains(t, extractServerErrorText(r.Body), "only alt=media is supported")
+		r = cacheProxyDownload(proxyInTeam, "media", installerMeta.StorageID, http.StatusOK)
+		checkDownloadResponse(t, r, payload.Filename)
+		// nor can a caching proxy of another fleet, nor with an unknown hash
+		cacheProxyDownload(proxyNoTeam, "media", installerMeta.StorageID, http.StatusForbidden)
+		cacheProxyDownload(proxyInTeam, "media", strings.Repeat("0", 64), http.StatusForbidden)
+
 		// Get execution ID, normally comes from orbit config
 		installUUID := getLatestSoftwareInstallExecID(t, s.ds, hostInTeam.ID)
 
@@ -14048,6 +14081,9 @@ func (s *integrationEnterpriseTestSuite) TestSoftwareInstallerUploadDownloadAndD
 			},
 		}, http.StatusNoContent)
 
+		// nor can the caching proxy, no host is about to install it anymore
+		cacheProxyDownload(proxyInTeam, "media", installerMeta.StorageID, http.StatusForbidden)
+
 		// while the installer exists, the install results carry its hash
 		beforeDelete, err := s.ds.GetSoftwareInstallResults(context.Background(), installUUID)
 		require.NoError(t, err)
@@ -32021,6 +32057,11 @@ func (s *integrationEnterpriseTestSuite) TestAPIOnlyUserCanReachGitOpsEndpoints(
 	assertNot403("POST", "/api/latest/fleet/spec/reports", emptySpecs)
 	assertNot403("POST", "/api/latest/fleet/spec/fleets", emptySpecs)
 	assertNot403("PUT", "/api/latest/fleet/spec/secret_variables", map[string]any{"secret_variables": []any{}})
+	assertNot403("GET", "/api/latest/fleet/custom_roles", nil)
+	assertNot403("PUT", "/api/latest/fleet/spec/custom_roles", map[string]any{"custom_roles": []any{}, "dry_run": true})
+	assertNot403("GET", "/api/latest/fleet/vulnerability_exceptions", nil)
+	assertNot403("PUT", "/api/latest/fleet/spec/vulnerability_exceptions", map[string]any{"vulnerability_exceptions": []any{}, "dry_run": true})
+	assertNot403("POST", "/api/latest/fleet/gitops/snapshots", map[string]any{"fleet_id": 999999})
 	assertNot403("GET", "/api/latest/fleet/policies", nil)
 	assertNot403("GET", "/api/latest/fleet/configuration_profiles", nil)
 	assertNot403("GET", "/api/latest/fleet/scripts", nil)
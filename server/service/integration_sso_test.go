@@ -15,6 +15,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
 	"testing"
@@ -25,6 +26,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/datastore/redis/redistest"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/sso/oidctest"
 	"github.com/fleetdm/fleet/v4/server/test"
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
@@ -636,3 +638,84 @@ func (s *integrationSSOTestSuite) TestSSOServerURL() {
 	require.NotNil(t, authReq.AssertionConsumerServiceURL)
 	assert.Equal(t, "https://admin.localhost:8080/api/v1/fleet/sso/callback", authReq.AssertionConsumerServiceURL)
 }
+
+func (s *integrationSSOTestSuite) TestSSOLoginOIDC() {
+	t := s.T()
+	iss := oidctest.NewIssuer(t)
+
+	acResp := appConfigResponse{}
+	s.DoJSON("PATCH", "/api/latest/fleet/config", json.RawMessage(fmt.Sprintf(`{
+		"server_settings": {
+			"server_url": "https://localhost:8080"
+		},
+		"sso_settings": {
+			"enable_sso": true,
+			"idp_name": "Test OIDC",
+			"oidc": {
+				"issuer_url": %q,
+				"client_id": %q,
+				"client_secret": %q
+			}
+		}
+	}`, iss.URL, oidctest.ClientID, oidctest.ClientSecret)), http.StatusOK, &acResp)
+	require.NotNil(t, acResp.SSOSettings)
+	require.NotNil(t, acResp.SSOSettings.OIDC)
+	require.Equal(t, fleet.MaskedPassword, acResp.SSOSettings.OIDC.ClientSecret)
+	t.Cleanup(func() {
+		s.DoJSON("PATCH", "/api/latest/fleet/config", json.RawMessage(`{"sso_settings": {"enable_sso": false, "oidc": null}}`), http.StatusOK, &appConfigResponse{})
+	})
+
+	// sending back the masked secret keeps the stored one
+	s.DoJSON("PATCH", "/api/latest/fleet/config", json.RawMessage(`{"sso_settings": {"oidc": {"client_secret": "********"}}}`), http.StatusOK, &acResp)
+	ac, err := s.ds.AppConfig(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, oidctest.ClientSecret, ac.SSOSettings.OIDC.ClientSecret)
+
+	params := fleet.UserPayload{
+		Name:       ptr.String("OIDC User"),
+		Email:      ptr.String("oidc_user@example.com"),
+		GlobalRole: ptr.String(fleet.RoleObserver),
+		SSOEnabled: ptr.Bool(true),
+	}
+	s.Do("POST", "/api/latest/fleet/users/admin", &params, http.StatusOK)
+
+	login := func(claims map[string]any) (string, []*http.Cookie) {
+		prevCookieSecure := cookieSecure
+		t.Cleanup(func() { cookieSecure = prevCookieSecure })
+		cookieSecure = false
+
+		res := s.DoRawNoAuth("POST", "/api/v1/fleet/sso", []byte(`{"relay_url": "/dashboard"}`), http.StatusOK)
+		var resIni initiateSSOResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&resIni))
+		res.Body.Close()
+
+		callback, err := url.Parse(iss.Authorize(resIni.URL, claims))
+		require.NoError(t, err)
+		require.Equal(t, "/api/v1/fleet/sso/oidc/callback", callback.Path)
+
+		req, err := http.NewRequest("GET", s.server.URL+callback.Path+"?"+callback.RawQuery, nil)
+		require.NoError(t, err)
+		for _, c := range res.Cookies() {
+			req.AddCookie(c)
+		}
+		res, err = fleethttp.NewClient(fleethttp.WithFollowRedir(false)).Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return string(body), res.Cookies()
+	}
+
+	body, cookies := login(map[string]any{"email": "oidc_user@example.com"})
+	require.Contains(t, body, "Redirecting to Fleet at /dashboard ...")
+	require.True(t, slices.ContainsFunc(cookies, func(c *http.Cookie) bool { return c.Name == "__Host-token" && c.Value != "" }))
+
+	// unknown users can't log in on free plans
+	body, _ = login(map[string]any{"email": "oidc_unknown@example.com"})
+	require.Contains(t, body, "/login?status=account_invalid")
+
+	// tokens for another client are rejected
+	iss.OnToken(func(claims map[string]any) { claims["aud"] = "other-client" })
+	body, _ = login(map[string]any{"email": "oidc_user@example.com"})
+	require.Contains(t, body, "/login?status=error")
+}
@@ -28,6 +28,11 @@ func AuthViewer(ctx context.Context, sessionKey string, svc fleet.Service) (*vie
 	if err != nil {
 		return nil, fleet.NewAuthRequiredError(err.Error())
 	}
+	if user.Disabled {
+		// Sessions are revoked when a user is disabled, this only guards
+		// against a session created concurrently.
+		return nil, fleet.NewAuthRequiredError("user is disabled")
+	}
 	return &viewer.Viewer{User: user, Session: session}, nil
 }
 
@@ -788,6 +788,7 @@ func (svc *Service) GetOrbitConfig(ctx context.Context) (fleet.OrbitConfig, erro
 			NudgeConfig:      nudgeConfig,
 			UpdateChannels:   updateChannels,
 			DebugLogging:     debugLogging,
+			CacheProxyURL:    opts.Orbit.CacheProxyURL(host.PrimaryIP),
 		}, nil
 	}
 
@@ -869,6 +870,7 @@ func (svc *Service) GetOrbitConfig(ctx context.Context) (fleet.OrbitConfig, erro
 		NudgeConfig:      nudgeConfig,
 		UpdateChannels:   updateChannels,
 		DebugLogging:     debugLogging,
+		CacheProxyURL:    opts.Orbit.CacheProxyURL(host.PrimaryIP),
 	}, nil
 }
 
@@ -1561,6 +1563,33 @@ func (svc *Service) EscrowLUKSData(ctx context.Context, passphrase string, salt
 	return nil
 }
 
+/////////////////////////////////////////////////////////////////////////////////
+// Post Orbit caching proxy statistics
+/////////////////////////////////////////////////////////////////////////////////
+
+func postOrbitCacheProxyStatsEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.OrbitPostCacheProxyStatsRequest)
+	if err := svc.ReportCacheProxyStats(ctx, req.CacheProxyStats); err != nil {
+		return fleet.OrbitPostCacheProxyStatsResponse{Err: err}, nil
+	}
+	return fleet.OrbitPostCacheProxyStatsResponse{}, nil
+}
+
+func (svc *Service) ReportCacheProxyStats(ctx context.Context, stats fleet.CacheProxyStats) error {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return newOsqueryError("internal error: missing host from request context")
+	}
+
+	if err := svc.ds.UpsertHostCacheProxyStats(ctx, host.ID, stats); err != nil {
+		return ctxerr.Wrap(ctx, err, "save cache proxy stats")
+	}
+	return nil
+}
+
 /////////////////////////////////////////////////////////////////////////////////
 // Post Orbit Windows managed local account password
 /////////////////////////////////////////////////////////////////////////////////
@@ -1812,6 +1841,32 @@ func (svc *Service) OrbitDownloadSoftwareInstaller(ctx context.Context, installe
 	return nil, fleet.ErrMissingLicense
 }
 
+/////////////////////////////////////////////////////////////////////////////////
+// Download Orbit software installer for a caching proxy
+/////////////////////////////////////////////////////////////////////////////////
+
+func orbitCacheProxyDownloadSoftwareInstallerEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.OrbitCacheProxyDownloadSoftwareInstallerRequest)
+
+	if req.Alt != "media" {
+		return orbitDownloadSoftwareInstallerResponse{Err: &fleet.BadRequestError{Message: "only alt=media is supported"}}, nil
+	}
+
+	p, err := svc.OrbitCacheProxyDownloadSoftwareInstaller(ctx, req.HashSHA256)
+	if err != nil {
+		return orbitDownloadSoftwareInstallerResponse{Err: err}, nil
+	}
+	return orbitDownloadSoftwareInstallerResponse{payload: p}, nil
+}
+
+func (svc *Service) OrbitCacheProxyDownloadSoftwareInstaller(ctx context.Context, hashSHA256 string) (*fleet.DownloadSoftwareInstallerPayload, error) {
+	// skipauth: No authorization check needed due to implementation returning
+	// only license error.
+	svc.authz.SkipAuthorization(ctx)
+
+	return nil, fleet.ErrMissingLicense
+}
+
 /////////////////////////////////////////////////////////////////////////////////
 // Post Orbit software install result
 /////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/websocket"
+	gws "github.com/gorilla/websocket"
+	"github.com/igm/sockjs-go/v3/sockjs"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// Stream notification hints to fleetd
+////////////////////////////////////////////////////////////////////////////////
+
+// orbitNotificationsPath is the path prefix of the websocket used by fleetd to
+// receive notification hints. fleetd connects to the raw websocket under it,
+// i.e. /api/fleet/orbit/notifications/websocket.
+const orbitNotificationsPath = "/api/fleet/orbit/notifications/"
+
+func makeOrbitNotificationsHandler(config config.ServerConfig, svc fleet.Service, logger *slog.Logger) func(string) http.Handler {
+	opt := sockjs.DefaultOptions
+	opt.Websocket = true
+	opt.RawWebsocket = true
+
+	if config.WebsocketsAllowUnsafeOrigin {
+		opt.CheckOrigin = func(r *http.Request) bool {
+			return true
+		}
+		// sockjs uses gorilla websockets under-the-hood see https://github.com/igm/sockjs-go/blob/master/v3/sockjs/rawwebsocket.go#L12-L14
+		opt.WebsocketUpgrader = &gws.Upgrader{
+			CheckOrigin: func(r *http.Request) bool {
+				return true
+			},
+		}
+	}
+
+	sockHandler := func(session sockjs.Session) {
+		conn := &websocket.Conn{Session: session}
+		defer func() {
+			if p := recover(); p != nil {
+				logger.ErrorContext(context.TODO(), "panic in orbit notifications handler", "err", p)
+				conn.WriteJSONError("panic in orbit notifications handler") //nolint:errcheck
+			}
+			session.Close(0, "none")
+		}()
+
+		// the session's context is done once the host disconnects
+		ctx := session.Context()
+
+		// Receive the orbit node key, sent as the auth token
+		nodeKey, err := conn.ReadAuthToken()
+		if err != nil {
+			logger.DebugContext(ctx, "failed to read orbit node key", "err", err)
+			return
+		}
+
+		host, _, err := svc.AuthenticateOrbitHost(ctx, string(nodeKey))
+		if err != nil {
+			logger.DebugContext(ctx, "orbit notifications authentication failed", "err", err)
+			conn.WriteJSONError("unauthorized") //nolint:errcheck
+			return
+		}
+
+		svc.StreamOrbitNotifications(hostctx.NewContext(ctx, host), conn)
+	}
+
+	return func(path string) http.Handler {
+		// important: sockjs' path must not have the trailing path, but the mux
+		// needs it in order to match it as a path prefix (subtree).
+		mux := http.NewServeMux()
+		mux.Handle(path, sockjs.NewHandler(strings.TrimSuffix(path, "/"), opt, sockHandler))
+		return mux
+	}
+}
+
+func (svc *Service) StreamOrbitNotifications(ctx context.Context, conn *websocket.Conn) {
+	// this is not a user-authenticated endpoint
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		conn.WriteJSONError("internal error: missing host from request context") //nolint:errcheck
+		return
+	}
+	if svc.orbitNotifier == nil {
+		conn.WriteJSONError("orbit notifications are not available") //nolint:errcheck
+		return
+	}
+
+	hints, err := svc.orbitNotifier.Subscribe(ctx, host.ID)
+	if err != nil {
+		svc.logger.ErrorContext(ctx, "subscribe to orbit notifications", "host_id", host.ID, "err", err)
+		conn.WriteJSONError("error subscribing to orbit notifications") //nolint:errcheck
+		return
+	}
+
+	// The channel is closed once ctx is done, i.e. once the host disconnects.
+	for hint := range hints {
+		if err := conn.WriteJSONMessage(fleet.OrbitNotificationMessageType, fleet.OrbitNotification{Hint: hint}); err != nil {
+			svc.logger.DebugContext(ctx, "write orbit notification", "host_id", host.ID, "err", err)
+			return
+		}
+	}
+}
+
+// NotifyOrbitHosts tells the fleetd agents of the hosts that something changed
+// for them. It is the single place where the mutation paths (core and premium)
+// publish hints. It is best-effort, the hosts that miss the hint get the change
+// on their next config poll.
+func (svc *Service) NotifyOrbitHosts(ctx context.Context, hint fleet.OrbitNotificationHint, hostIDs ...uint) {
+	if svc.orbitNotifier == nil {
+		return
+	}
+	if err := svc.orbitNotifier.Notify(ctx, hint, hostIDs...); err != nil {
+		svc.logger.ErrorContext(ctx, "notify orbit hosts", "hint", hint, "err", err)
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/pubsub"
+	ws "github.com/fleetdm/fleet/v4/server/websocket"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamOrbitNotifications(t *testing.T) {
+	ds := new(mock.DataStore)
+	notifier := pubsub.NewInmemOrbitNotifier()
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{OrbitNotifier: notifier})
+
+	ds.LoadHostByOrbitNodeKeyFunc = func(ctx context.Context, nodeKey string) (*fleet.Host, error) {
+		if nodeKey != "host-1-key" {
+			return nil, newNotFoundError()
+		}
+		return &fleet.Host{ID: 1, HasHostIdentityCert: ptr.Bool(false)}, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+
+	pathHandler := makeOrbitNotificationsHandler(config.TestConfig().Server, svc, slog.New(slog.DiscardHandler))
+	s := httptest.NewServer(pathHandler(orbitNotificationsPath))
+	t.Cleanup(s.Close)
+	u := "ws" + strings.TrimPrefix(s.URL, "http") + orbitNotificationsPath + "websocket"
+
+	connect := func(nodeKey string) <-chan ws.JSONMessage {
+		conn, _, err := websocket.DefaultDialer.Dial(u, nil)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+
+		require.NoError(t, conn.WriteJSON(ws.JSONMessage{
+			Type: "auth",
+			Data: map[string]any{"token": nodeKey},
+		}))
+
+		msgs := make(chan ws.JSONMessage)
+		go func() {
+			defer close(msgs)
+			for {
+				var msg ws.JSONMessage
+				if err := conn.ReadJSON(&msg); err != nil {
+					return
+				}
+				msgs <- msg
+			}
+		}()
+		return msgs
+	}
+
+	t.Run("invalid node key", func(t *testing.T) {
+		msgs := connect("nope")
+		msg := <-msgs
+		assert.Equal(t, "error", msg.Type)
+		assert.Equal(t, "unauthorized", msg.Data)
+
+		_, ok := <-msgs
+		assert.False(t, ok, "the connection should be closed")
+	})
+
+	t.Run("hints", func(t *testing.T) {
+		msgs := connect("host-1-key")
+
+		// the host is subscribed asynchronously, notify until it gets the hint
+		var msg ws.JSONMessage
+		require.Eventually(t, func() bool {
+			require.NoError(t, notifier.Notify(ctx, fleet.OrbitNotificationHintScripts, 1))
+			select {
+			case msg = <-msgs:
+				return true
+			case <-time.After(50 * time.Millisecond):
+				return false
+			}
+		}, 5*time.Second, 10*time.Millisecond)
+		assert.Equal(t, fleet.OrbitNotificationMessageType, msg.Type)
+		assert.Equal(t, map[string]any{"hint": "scripts"}, msg.Data)
+
+		// drop the hints of the extra attempts, hints for other hosts are not sent
+		for drained := false; !drained; {
+			select {
+			case _, ok := <-msgs:
+				require.True(t, ok, "the connection should be open")
+			case <-time.After(200 * time.Millisecond):
+				drained = true
+			}
+		}
+		require.NoError(t, notifier.Notify(ctx, fleet.OrbitNotificationHintConfig, 2))
+		select {
+		case msg := <-msgs:
+			t.Fatalf("unexpected message %+v", msg)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/fleetdm/fleet/v4/ee/server/service/hostidentity/httpsig"
 	"github.com/fleetdm/fleet/v4/server"
 	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
+	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
 	"github.com/fleetdm/fleet/v4/server/contexts/license"
@@ -1605,7 +1606,7 @@ func processCalendarPolicies(
 	policyResults map[uint]*bool,
 	logger *slog.Logger,
 ) error {
-	if len(appConfig.Integrations.GoogleCalendar) == 0 || host.TeamID == nil {
+	if !appConfig.Integrations.CalendarConfigured() || host.TeamID == nil {
 		return nil
 	}
 
@@ -1965,6 +1966,11 @@ func globalPolicyAutomationsEnabled(webhookSettings fleet.WebhookSettings, integ
 			return true
 		}
 	}
+	for _, sn := range integrations.ServiceNow {
+		if sn.EnableFailingPolicies {
+			return true
+		}
+	}
 	return false
 }
 
@@ -1982,6 +1988,11 @@ func teamPolicyAutomationsEnabled(webhookSettings fleet.TeamWebhookSettings, int
 			return true
 		}
 	}
+	for _, sn := range integrations.ServiceNow {
+		if sn.EnableFailingPolicies {
+			return true
+		}
+	}
 	return false
 }
 
@@ -2112,6 +2123,18 @@ func (svc *Service) ingestDistributedQuery(
 			return newOsqueryError("loading orphaned campaign: " + err.Error())
 		}
 
+		if campaign.Deferred {
+			// Deferred campaigns never have subscribers, their results are
+			// stored to be retrieved later.
+			if err := svc.saveCampaignQueryResult(ctx, campaign.ID, res); err != nil {
+				return newOsqueryError("saving deferred campaign results: " + err.Error())
+			}
+			if err := svc.liveQueryStore.QueryCompletedByHost(strconv.Itoa(campaignID), host.ID); err != nil {
+				return newOsqueryError("record query completion: " + err.Error())
+			}
+			return nil
+		}
+
 		if campaign.CreatedAt.After(svc.clock.Now().Add(-1 * time.Minute)) {
 			// Give the client a minute to connect before considering the
 			// campaign orphaned.
@@ -3433,9 +3456,15 @@ func (svc *Service) SubmitResultLogs(ctx context.Context, logs []json.RawMessage
 	if !queryReportsDisabled {
 		maxQueryReportRows := appConfig.ServerSettings.GetQueryReportCap()
 		svc.saveResultLogsToQueryReports(ctx, unmarshaledResults, queriesDBData, maxQueryReportRows)
+		if appConfig.ServerSettings.ReportHistoryEnabled() {
+			svc.saveResultLogsToQueryReportHistory(ctx, unmarshaledResults, queriesDBData, appConfig.ServerSettings.GetReportHistoryCap())
+		}
 	}
 
+	// filteredQueries holds the Fleet query of each of filteredLogs (nil if unknown), which
+	// decides the log destinations it is written to.
 	var filteredLogs []json.RawMessage
+	var filteredQueries []*fleet.Query
 	for i, unmarshaledResult := range unmarshaledResults {
 		if unmarshaledResult == nil {
 			// Ignore results that could not be unmarshaled, and those dropped above for not
@@ -3451,6 +3480,7 @@ func (svc *Service) SubmitResultLogs(ctx context.Context, logs []json.RawMessage
 			// the results for it here. Eventually the query will be removed from the host schedule
 			// and thus Fleet won't receive any further results anymore.
 			filteredLogs = append(filteredLogs, logs[i])
+			filteredQueries = append(filteredQueries, queriesDBData[unmarshaledResult.QueryName])
 			continue
 		}
 
@@ -3464,6 +3494,7 @@ func (svc *Service) SubmitResultLogs(ctx context.Context, logs []json.RawMessage
 			// the results for it here. Eventually the query will be removed from the host schedule
 			// and thus Fleet won't receive any further results anymore.
 			filteredLogs = append(filteredLogs, logs[i])
+			filteredQueries = append(filteredQueries, nil)
 			continue
 		}
 
@@ -3473,25 +3504,73 @@ func (svc *Service) SubmitResultLogs(ctx context.Context, logs []json.RawMessage
 		}
 
 		filteredLogs = append(filteredLogs, logs[i])
+		filteredQueries = append(filteredQueries, dbQuery)
 	}
 
 	if len(filteredLogs) == 0 {
 		return nil
 	}
 
-	if err := svc.osqueryLogWriter.Result.Write(ctx, filteredLogs); err != nil {
-		osqueryErr := newOsqueryError(
-			"error writing result logs " +
-				"(if the logging destination is down, you can reduce frequency/size of osquery logs by " +
-				"increasing logger_tls_period and decreasing logger_tls_max_lines): " + err.Error(),
-		)
-		// Attempting to write a large amount of data is the most likely explanation for this error.
-		osqueryErr.StatusCode = http.StatusRequestEntityTooLarge
-		return osqueryErr
+	// Group the logs by log destination, so that each destination gets a single write.
+	destinationLogs := make(map[string][]json.RawMessage)
+	for i, resultLog := range filteredLogs {
+		for _, name := range svc.resultLogDestinations(ctx, filteredQueries[i]) {
+			destinationLogs[name] = append(destinationLogs[name], resultLog)
+		}
+	}
+	names := make([]string, 0, len(destinationLogs))
+	for name := range destinationLogs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		writer := svc.osqueryLogWriter.Result
+		target := ""
+		if name != config.DefaultResultLogDestination {
+			writer = svc.osqueryLogWriter.ResultDestinations[name]
+			target = "to log destination " + name + " "
+		}
+		if err := writer.Write(ctx, destinationLogs[name]); err != nil {
+			osqueryErr := newOsqueryError(
+				"error writing result logs " + target +
+					"(if the logging destination is down, you can reduce frequency/size of osquery logs by " +
+					"increasing logger_tls_period and decreasing logger_tls_max_lines): " + err.Error(),
+			)
+			// Attempting to write a large amount of data is the most likely explanation for this error.
+			osqueryErr.StatusCode = http.StatusRequestEntityTooLarge
+			return osqueryErr
+		}
 	}
 	return nil
 }
 
+// resultLogDestinations returns the names of the log destinations that results of
+// dbQuery are written to. Results of queries unknown to Fleet, or without log
+// destinations, go to the default one. Names that are not configured (e.g. removed
+// from the config after the query was saved) also fall back to the default one, so
+// that the results are not lost.
+func (svc *Service) resultLogDestinations(ctx context.Context, dbQuery *fleet.Query) []string {
+	if dbQuery == nil || len(dbQuery.LogDestinations) == 0 {
+		return []string{config.DefaultResultLogDestination}
+	}
+
+	names := make([]string, 0, len(dbQuery.LogDestinations))
+	for _, name := range dbQuery.LogDestinations {
+		if name != config.DefaultResultLogDestination {
+			if _, ok := svc.osqueryLogWriter.ResultDestinations[name]; !ok {
+				svc.logger.WarnContext(ctx, "unknown log destination for query results, using the default one",
+					"query_id", dbQuery.ID, "log_destination", name)
+				name = config.DefaultResultLogDestination
+			}
+		}
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // dropResultsNotScheduledForHost sets to nil the results whose query Fleet knows but did
 // not put on the submitting host's schedule. Entries are nilled in place rather than
 // removed because the caller pairs them positionally with the raw logs.
@@ -3592,24 +3671,8 @@ func (svc *Service) saveResultLogsToQueryReports(
 	rowsAddedByQuery := make(map[uint]int)
 
 	for _, result := range unmarshaledResultsFiltered {
-		dbQuery, ok := queriesDBData[result.QueryName]
-		if !ok {
-			// Means the query does not exist with such name anymore. Thus we ignore its result.
-			continue
-		}
-
-		if dbQuery.DiscardData || dbQuery.Logging != fleet.LoggingSnapshot {
-			// Ignore result if query is marked as discard data or if logging is not snapshot
-			continue
-		}
-
-		hostTeamID := uint(0)
-		if host.TeamID != nil {
-			hostTeamID = *host.TeamID
-		}
-		if dbQuery.TeamID != nil && *dbQuery.TeamID != hostTeamID {
-			// The host was transferred to another team/global so we ignore the incoming results
-			// of this query that belong to a different team.
+		dbQuery := reportQueryForResult(host, result, queriesDBData)
+		if dbQuery == nil {
 			continue
 		}
 
@@ -3640,6 +3703,87 @@ func (svc *Service) saveResultLogsToQueryReports(
 	}
 }
 
+// reportQueryForResult returns the query of a result if the result is to be
+// stored in its report, nil otherwise.
+func reportQueryForResult(host *fleet.Host, result *fleet.ScheduledQueryResult, queriesDBData map[string]*fleet.Query) *fleet.Query {
+	dbQuery, ok := queriesDBData[result.QueryName]
+	if !ok {
+		// Means the query does not exist with such name anymore. Thus we ignore its result.
+		return nil
+	}
+
+	if dbQuery.DiscardData || dbQuery.Logging != fleet.LoggingSnapshot {
+		// Ignore result if query is marked as discard data or if logging is not snapshot
+		return nil
+	}
+
+	hostTeamID := uint(0)
+	if host.TeamID != nil {
+		hostTeamID = *host.TeamID
+	}
+	if dbQuery.TeamID != nil && *dbQuery.TeamID != hostTeamID {
+		// The host was transferred to another team/global so we ignore the incoming results
+		// of this query that belong to a different team.
+		return nil
+	}
+	return dbQuery
+}
+
+// saveResultLogsToQueryReportHistory stores every result batch of reports in
+// their history. Unlike the reports, which only keep the most recent result of
+// each host, results cached by osquery while offline are stored too.
+func (svc *Service) saveResultLogsToQueryReportHistory(
+	ctx context.Context,
+	unmarshaledResults []*fleet.ScheduledQueryResult,
+	queriesDBData map[string]*fleet.Query,
+	maxReportHistoryRows int,
+) {
+	// skipauth: Authorization is currently for user endpoints only.
+	svc.authz.SkipAuthorization(ctx)
+
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		svc.logger.ErrorContext(ctx, "getting host from context")
+		return
+	}
+
+	now := time.Now()
+	var batches []*fleet.QueryResultHistoryBatch
+	for _, result := range transformEventFormatToSnapshotFormat(unmarshaledResults) {
+		if result == nil {
+			// This is a result that failed to unmarshal.
+			continue
+		}
+		dbQuery := reportQueryForResult(host, result, queriesDBData)
+		if dbQuery == nil {
+			continue
+		}
+		if len(result.Snapshot) > maxReportHistoryRows {
+			// The batch would be removed from the history right away.
+			continue
+		}
+
+		// The batch is timestamped with the time osquery ran the query, which
+		// cannot be after the results were received.
+		fetchedAt := now
+		if result.UnixTime > 0 {
+			if ranAt := time.Unix(int64(result.UnixTime), 0); ranAt.Before(now) { //nolint:gosec // dismiss G115
+				fetchedAt = ranAt
+			}
+		}
+		batches = append(batches, &fleet.QueryResultHistoryBatch{
+			QueryID:   dbQuery.ID,
+			HostID:    host.ID,
+			FetchedAt: fetchedAt,
+			Rows:      result.Snapshot,
+		})
+	}
+
+	if err := svc.ds.InsertQueryResultHistory(ctx, batches); err != nil {
+		svc.logger.ErrorContext(ctx, "insert query result history", "err", err, "host_id", host.ID)
+	}
+}
+
 // transformEventFormatToSnapshotFormat transforms results that are in "event format" to "snapshot format".
 // This is needed to support query reports for hosts that are configured with `--logger_snapshot_event_type=true`
 // in their agent options.
@@ -1099,6 +1099,169 @@ func TestSubmitResultLogsToQueryResultsDoesNotCountNullDataRows(t *testing.T) {
 	assert.True(t, ds.OverwriteQueryResultRowsFuncInvoked)
 }
 
+func TestSubmitResultLogsToQueryReportHistory(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	host := fleet.Host{
+		ID: 999,
+	}
+	ctx = hostctx.NewContext(ctx, &host)
+
+	future := time.Now().Add(time.Hour).Unix()
+	logs := []string{
+		// results cached by osquery while offline
+		`{"snapshot":[{"name":"a"}],"action":"snapshot","name":"pack/Global/history","hostIdentifier":"1379f59d98f4","unixTime":1484078931}`,
+		`{"snapshot":[{"name":"a"},{"name":"b"}],"action":"snapshot","name":"pack/Global/history","hostIdentifier":"1379f59d98f4","unixTime":1484079931}`,
+		`{"snapshot":[],"action":"snapshot","name":"pack/Global/history","hostIdentifier":"1379f59d98f4","unixTime":` + fmt.Sprint(future) + `}`,
+		`{"snapshot":[{"name":"c"}],"action":"snapshot","name":"pack/Global/discarded","hostIdentifier":"1379f59d98f4","unixTime":1484078931}`,
+	}
+
+	logJSON := fmt.Sprintf("[%s]", strings.Join(logs, ","))
+	var results []json.RawMessage
+	err := json.Unmarshal([]byte(logJSON), &results)
+	require.NoError(t, err)
+
+	reportHistoryRetentionDays := 7
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			ServerSettings: fleet.ServerSettings{
+				ReportHistoryRetentionDays: reportHistoryRetentionDays,
+			},
+		}, nil
+	}
+
+	ds.QueryByNameFunc = func(ctx context.Context, teamID *uint, name string) (*fleet.Query, error) {
+		switch name {
+		case "history":
+			return &fleet.Query{ID: 1, Name: name, Logging: fleet.LoggingSnapshot}, nil
+		case "discarded":
+			return &fleet.Query{ID: 2, Name: name, Logging: fleet.LoggingSnapshot, DiscardData: true}, nil
+		}
+		return nil, newNotFoundError()
+	}
+	ds.QueriesPerHostFunc = func(ctx context.Context, hostID uint, teamID *uint) ([]uint, error) {
+		return []uint{1, 2}, nil
+	}
+	ds.OverwriteQueryResultRowsFunc = func(ctx context.Context, rows []*fleet.ScheduledQueryResultRow, maxQueryReportRows int) (int, error) {
+		return len(rows), nil
+	}
+	var batches []*fleet.QueryResultHistoryBatch
+	ds.InsertQueryResultHistoryFunc = func(ctx context.Context, b []*fleet.QueryResultHistoryBatch) error {
+		batches = b
+		return nil
+	}
+
+	before := time.Now()
+	err = svc.SubmitResultLogs(ctx, results)
+	require.NoError(t, err)
+
+	// every batch of the report is kept, not only the most recent one
+	require.True(t, ds.InsertQueryResultHistoryFuncInvoked)
+	require.Len(t, batches, 3)
+	for _, batch := range batches {
+		require.Equal(t, uint(1), batch.QueryID)
+		require.Equal(t, uint(999), batch.HostID)
+	}
+	require.Equal(t, time.Unix(1484078931, 0), batches[0].FetchedAt)
+	require.Len(t, batches[0].Rows, 1)
+	require.Equal(t, time.Unix(1484079931, 0), batches[1].FetchedAt)
+	require.Len(t, batches[1].Rows, 2)
+	// a batch cannot be fetched after it was received
+	require.Empty(t, batches[2].Rows)
+	require.False(t, batches[2].FetchedAt.Before(before))
+	require.True(t, batches[2].FetchedAt.Before(time.Unix(future, 0)))
+
+	// nothing is kept when report history is disabled
+	ds.InsertQueryResultHistoryFuncInvoked = false
+	reportHistoryRetentionDays = 0
+	err = svc.SubmitResultLogs(ctx, results)
+	require.NoError(t, err)
+	require.False(t, ds.InsertQueryResultHistoryFuncInvoked)
+}
+
+type failingJSONLogger struct{}
+
+func (failingJSONLogger) Write(ctx context.Context, logs []json.RawMessage) error {
+	return errors.New("destination down")
+}
+
+func TestSubmitResultLogsToLogDestinations(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = hostctx.NewContext(ctx, &fleet.Host{ID: 999})
+
+	logs := []string{
+		`{"snapshot":[{"a":"1"}],"action":"snapshot","name":"pack/Global/plain","hostIdentifier":"1379f59d98f4","unixTime":1484078931}`,
+		`{"snapshot":[{"a":"2"}],"action":"snapshot","name":"pack/Global/security","hostIdentifier":"1379f59d98f4","unixTime":1484078931}`,
+		`{"snapshot":[{"a":"3"}],"action":"snapshot","name":"pack/Global/both","hostIdentifier":"1379f59d98f4","unixTime":1484078931}`,
+		`{"snapshot":[{"a":"4"}],"action":"snapshot","name":"pack/Global/removed","hostIdentifier":"1379f59d98f4","unixTime":1484078931}`,
+		`{"snapshot":[{"a":"5"}],"action":"snapshot","name":"pack/Global/unknown","hostIdentifier":"1379f59d98f4","unixTime":1484078931}`,
+	}
+	newResults := func() []json.RawMessage {
+		var results []json.RawMessage
+		require.NoError(t, json.Unmarshal([]byte(fmt.Sprintf("[%s]", strings.Join(logs, ","))), &results))
+		return results
+	}
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	queries := map[string]*fleet.Query{
+		"plain":    {ID: 1},
+		"security": {ID: 2, LogDestinations: fleet.LogDestinations{"security"}},
+		"both":     {ID: 3, LogDestinations: fleet.LogDestinations{"default", "security"}},
+		// "removed" is no longer configured, so its results fall back to the default destination.
+		"removed": {ID: 4, LogDestinations: fleet.LogDestinations{"removed"}},
+	}
+	ds.QueryByNameFunc = func(ctx context.Context, teamID *uint, name string) (*fleet.Query, error) {
+		q, ok := queries[name]
+		if !ok {
+			return nil, newNotFoundError()
+		}
+		q.Name = name
+		q.Logging = fleet.LoggingSnapshot
+		q.AutomationsEnabled = true
+		q.DiscardData = true
+		return q, nil
+	}
+	ds.QueriesPerHostFunc = func(ctx context.Context, hostID uint, teamID *uint) ([]uint, error) {
+		return []uint{1, 2, 3, 4}, nil
+	}
+
+	defaultDestination := &testJSONLogger{}
+	securityDestination := &testJSONLogger{}
+	serv := ((svc.(validationMiddleware)).Service).(*Service)
+	serv.osqueryLogWriter = &OsqueryLogger{
+		Result: defaultDestination,
+		ResultDestinations: map[string]fleet.JSONLogger{
+			"security": securityDestination,
+		},
+	}
+
+	logNames := func(logs []json.RawMessage) []string {
+		var names []string
+		for _, l := range logs {
+			var result fleet.ScheduledQueryResult
+			require.NoError(t, json.Unmarshal(l, &result))
+			names = append(names, result.QueryName)
+		}
+		return names
+	}
+
+	require.NoError(t, svc.SubmitResultLogs(ctx, newResults()))
+	assert.Equal(t, []string{"pack/Global/plain", "pack/Global/both", "pack/Global/removed", "pack/Global/unknown"}, logNames(defaultDestination.logs))
+	assert.Equal(t, []string{"pack/Global/security", "pack/Global/both"}, logNames(securityDestination.logs))
+
+	// A failing destination is reported to osquery so that it retries.
+	serv.osqueryLogWriter.ResultDestinations["security"] = failingJSONLogger{}
+	err := svc.SubmitResultLogs(ctx, newResults())
+	require.Error(t, err)
+	var osqueryErr *OsqueryError
+	require.ErrorAs(t, err, &osqueryErr)
+	assert.Contains(t, osqueryErr.Error(), "log destination security")
+}
+
 func TestSubmitResultLogsQueryNotScheduledForHost(t *testing.T) {
 	const (
 		reportQueryID = 42
@@ -3069,6 +3232,60 @@ func TestIngestDistributedQueryOrphanedStop(t *testing.T) {
 	lq.AssertExpectations(t)
 }
 
+func TestIngestDistributedQueryDeferredCampaign(t *testing.T) {
+	mockClock := clock.NewMockClock()
+	ds := new(mock.Store)
+	rs := pubsub.NewInmemQueryResults()
+	lq := live_query_mock.New(t)
+	svc := &Service{
+		ds:             ds,
+		resultStore:    rs,
+		liveQueryStore: lq,
+		logger:         slog.New(slog.DiscardHandler),
+		clock:          mockClock,
+	}
+
+	// deferred campaigns never have listeners, even long after their creation
+	campaign := &fleet.DistributedQueryCampaign{
+		ID: 42,
+		UpdateCreateTimestamps: fleet.UpdateCreateTimestamps{
+			CreateTimestamp: fleet.CreateTimestamp{
+				CreatedAt: mockClock.Now().Add(-2 * time.Hour),
+			},
+		},
+		Status:   fleet.QueryRunning,
+		Deferred: true,
+	}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return campaign, nil
+	}
+	var saved *fleet.CampaignQueryResult
+	ds.SaveCampaignQueryResultFunc = func(ctx context.Context, campaignID uint, result *fleet.CampaignQueryResult) error {
+		require.Equal(t, campaign.ID, campaignID)
+		saved = result
+		return nil
+	}
+	lq.On("QueryCompletedByHost", "42", uint(1)).Return(nil)
+
+	host := fleet.Host{ID: 1, Hostname: "foo", ComputerName: "Foo"}
+	rows := []map[string]string{{"a": "b"}}
+	err := svc.ingestDistributedQuery(context.Background(), host, "fleet_distributed_query_42", rows, "", nil)
+	require.NoError(t, err)
+	require.NotNil(t, saved)
+	assert.Equal(t, uint(1), saved.HostID)
+	assert.Equal(t, "foo", saved.Hostname)
+	assert.Equal(t, "Foo", saved.HostDisplayName)
+	assert.Equal(t, rows, saved.Rows)
+	assert.Nil(t, saved.Error)
+	assert.False(t, ds.SaveDistributedQueryCampaignFuncInvoked)
+	lq.AssertExpectations(t)
+
+	err = svc.ingestDistributedQuery(context.Background(), host, "fleet_distributed_query_42", nil, "no such table: foo", nil)
+	require.NoError(t, err)
+	require.NotNil(t, saved.Error)
+	assert.Equal(t, "no such table: foo", *saved.Error)
+}
+
 func TestIngestDistributedQueryRecordCompletionError(t *testing.T) {
 	mockClock := clock.NewMockClock()
 	ds := new(mock.Store)
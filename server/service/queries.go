@@ -2,9 +2,11 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/server/authz"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
@@ -148,6 +150,10 @@ func (svc *Service) ListQueries(ctx context.Context, opt fleet.ListOptions, team
 
 func getQueryReportEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
 	req := request.(*fleet.GetQueryReportRequest)
+	if req.From != "" || req.To != "" {
+		return getQueryReportHistory(ctx, req, svc)
+	}
+
 	queryReportResults, reportClipped, err := svc.GetQueryReportResults(ctx, req.ID, req.TeamID)
 	if err != nil {
 		return fleet.GetQueryReportResponse{Err: err}, nil
@@ -164,6 +170,48 @@ func getQueryReportEndpoint(ctx context.Context, request interface{}, svc fleet.
 	}, nil
 }
 
+func getQueryReportHistory(ctx context.Context, req *fleet.GetQueryReportRequest, svc fleet.Service) (fleet.Errorer, error) {
+	from, err := parseQueryReportTime(ctx, "from", req.From)
+	if err != nil {
+		return fleet.GetQueryReportResponse{Err: err}, nil
+	}
+	to, err := parseQueryReportTime(ctx, "to", req.To)
+	if err != nil {
+		return fleet.GetQueryReportResponse{Err: err}, nil
+	}
+
+	queryReportResults, err := svc.GetQueryReportHistory(ctx, req.ID, req.TeamID, from, to)
+	if err != nil {
+		return fleet.GetQueryReportResponse{Err: err}, nil
+	}
+	// Return an empty array if there are no results stored.
+	results := []fleet.HostQueryResultRow{}
+	if len(queryReportResults) > 0 {
+		results = queryReportResults
+	}
+	return fleet.GetQueryReportResponse{
+		QueryID: req.ID,
+		Results: results,
+	}, nil
+}
+
+// parseQueryReportTime parses an optional RFC 3339 timestamp of a report
+// request, the zero time is returned if it is empty.
+func parseQueryReportTime(ctx context.Context, name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		// The request fails before any authorization check.
+		setAuthCheckedOnPreAuthErr(ctx)
+		return time.Time{}, ctxerr.Wrap(ctx, &fleet.BadRequestError{
+			Message: fmt.Sprintf("invalid %s value: %q (must be an RFC 3339 timestamp)", name, value),
+		})
+	}
+	return t, nil
+}
+
 func (svc *Service) GetQueryReportResults(ctx context.Context, id uint, teamID *uint) ([]fleet.HostQueryResultRow, bool, error) {
 	// Load query first to get its teamID.
 	query, err := svc.ds.Query(ctx, id)
@@ -204,6 +252,146 @@ func (svc *Service) GetQueryReportResults(ctx context.Context, id uint, teamID *
 	return queryReportResults, reportClipped, nil
 }
 
+func (svc *Service) GetQueryReportHistory(ctx context.Context, id uint, teamID *uint, from, to time.Time) ([]fleet.HostQueryResultRow, error) {
+	query, err := svc.ds.Query(ctx, id)
+	if err != nil {
+		setAuthCheckedOnPreAuthErr(ctx)
+		return nil, ctxerr.Wrap(ctx, err, "get query from datastore")
+	}
+	if err := svc.authz.Authorize(ctx, query, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	if to.IsZero() {
+		to = svc.clock.Now()
+	}
+	if from.After(to) {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("from", "must be before to"))
+	}
+	if query.DiscardData {
+		return nil, nil
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true, TeamID: teamID}
+
+	historyRows, err := svc.ds.QueryResultHistoryRows(ctx, id, from, to, filter)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get query report history")
+	}
+	results, err := fleet.MapQueryReportResultsToRows(historyRows)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "map db rows to results")
+	}
+	return results, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Diff Query Report
+////////////////////////////////////////////////////////////////////////////////
+
+func diffQueryReportEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.DiffQueryReportRequest)
+	from, err := parseQueryReportTime(ctx, "from", req.From)
+	if err != nil {
+		return fleet.DiffQueryReportResponse{Err: err}, nil
+	}
+	to, err := parseQueryReportTime(ctx, "to", req.To)
+	if err != nil {
+		return fleet.DiffQueryReportResponse{Err: err}, nil
+	}
+
+	diff, err := svc.DiffQueryReport(ctx, req.ID, req.TeamID, from, to)
+	if err != nil {
+		return fleet.DiffQueryReportResponse{Err: err}, nil
+	}
+	return fleet.DiffQueryReportResponse{QueryReportDiff: diff}, nil
+}
+
+func (svc *Service) DiffQueryReport(ctx context.Context, id uint, teamID *uint, from, to time.Time) (*fleet.QueryReportDiff, error) {
+	query, err := svc.ds.Query(ctx, id)
+	if err != nil {
+		setAuthCheckedOnPreAuthErr(ctx)
+		return nil, ctxerr.Wrap(ctx, err, "get query from datastore")
+	}
+	if err := svc.authz.Authorize(ctx, query, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	if from.IsZero() || to.IsZero() {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("from", "from and to are required"))
+	}
+	if from.After(to) {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("from", "must be before to"))
+	}
+
+	diff := &fleet.QueryReportDiff{
+		QueryID: id,
+		From:    from,
+		To:      to,
+		Hosts:   []fleet.HostQueryResultsDiff{},
+	}
+	if query.DiscardData {
+		return diff, nil
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true, TeamID: teamID}
+
+	fromRows, err := svc.ds.QueryResultHistorySnapshot(ctx, id, from, filter)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get query report history at from")
+	}
+	toRows, err := svc.ds.QueryResultHistorySnapshot(ctx, id, to, filter)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get query report history at to")
+	}
+	fromResults, err := queryReportSnapshotResults(fromRows)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "map db rows to results")
+	}
+	toResults, err := queryReportSnapshotResults(toRows)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "map db rows to results")
+	}
+	diff.Hosts = diffCampaignQueryResults(toResults, fromResults)
+	return diff, nil
+}
+
+// queryReportSnapshotResults groups the rows of a report history snapshot,
+// which are ordered by host, into one result per host. The results are shaped
+// like campaign results to be compared with diffCampaignQueryResults.
+func queryReportSnapshotResults(rows []*fleet.ScheduledQueryResultRow) ([]*fleet.CampaignQueryResult, error) {
+	var results []*fleet.CampaignQueryResult
+	for _, row := range rows {
+		if len(results) == 0 || results[len(results)-1].HostID != row.HostID {
+			results = append(results, &fleet.CampaignQueryResult{
+				HostID:          row.HostID,
+				Hostname:        row.Hostname.String,
+				HostDisplayName: row.HostDisplayName(),
+				Rows:            []map[string]string{},
+				CreatedAt:       row.LastFetched,
+			})
+		}
+		if row.Data == nil {
+			continue
+		}
+		var columns map[string]string
+		if err := json.Unmarshal(*row.Data, &columns); err != nil {
+			return nil, err
+		}
+		res := results[len(results)-1]
+		res.Rows = append(res.Rows, columns)
+	}
+	return results, nil
+}
+
 func (svc *Service) QueryReportIsClipped(ctx context.Context, queryID uint, maxQueryReportRows int) (bool, error) {
 	query, err := svc.ds.Query(ctx, queryID)
 	if err != nil {
@@ -262,6 +450,11 @@ func (svc *Service) NewQuery(ctx context.Context, p fleet.QueryPayload) (*fleet.
 			Message: fmt.Sprintf("query payload verification: %s", err),
 		})
 	}
+	if p.LogDestinations != nil {
+		if err := svc.verifyLogDestinations(*p.LogDestinations); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "verify log destinations")
+		}
+	}
 
 	query := &fleet.Query{Saved: true, TeamID: p.TeamID}
 
@@ -312,6 +505,9 @@ func (svc *Service) NewQuery(ctx context.Context, p fleet.QueryPayload) (*fleet.
 	if len(p.LabelsIncludeAll) > 0 {
 		query.LabelsIncludeAll = fleet.LabelNamesToIdents(p.LabelsIncludeAll)
 	}
+	if p.LogDestinations != nil && len(*p.LogDestinations) > 0 {
+		query.LogDestinations = *p.LogDestinations
+	}
 
 	logging.WithExtras(ctx, "name", query.Name, "sql", query.Query)
 
@@ -397,6 +593,11 @@ func (svc *Service) modifyLoadedQuery(ctx context.Context, query *fleet.Query, p
 			Message: fmt.Sprintf("query payload verification: %s", err),
 		})
 	}
+	if p.LogDestinations != nil {
+		if err := svc.verifyLogDestinations(*p.LogDestinations); err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "verify log destinations")
+		}
+	}
 
 	// We use query.TeamID because we do not allow changing the team.
 	if err := verifyLabelsToAssociate(ctx, svc.ds, query.TeamID, slices.Concat(p.LabelsIncludeAny, p.LabelsIncludeAll), authz.UserFromContext(ctx)); err != nil {
@@ -459,6 +660,13 @@ func (svc *Service) modifyLoadedQuery(ctx context.Context, query *fleet.Query, p
 		query.LabelsIncludeAny = fleet.LabelNamesToIdents(p.LabelsIncludeAny)
 		query.LabelsIncludeAll = fleet.LabelNamesToIdents(p.LabelsIncludeAll)
 	}
+	// An empty list resets the query to the default log destination.
+	if p.LogDestinations != nil {
+		query.LogDestinations = nil
+		if len(*p.LogDestinations) > 0 {
+			query.LogDestinations = *p.LogDestinations
+		}
+	}
 
 	logging.WithExtras(ctx, "name", query.Name, "sql", query.Query)
 
@@ -774,6 +982,10 @@ func (svc *Service) ApplyQuerySpecs(ctx context.Context, specs []*fleet.QuerySpe
 				Message: fmt.Sprintf("invalid query spec: %s", err),
 			})
 		}
+		if err := svc.verifyLogDestinations(spec.LogDestinations); err != nil {
+			setAuthCheckedOnPreAuthErr(ctx)
+			return ctxerr.Wrap(ctx, err, "verify log destinations")
+		}
 		query, err := svc.queryFromSpec(ctx, spec)
 		if err != nil {
 			setAuthCheckedOnPreAuthErr(ctx)
@@ -897,9 +1109,21 @@ func (svc *Service) queryFromSpec(ctx context.Context, spec *fleet.QuerySpec) (*
 		DiscardData:        spec.DiscardData,
 		LabelsIncludeAny:   includeAny,
 		LabelsIncludeAll:   includeAll,
+		LogDestinations:    spec.LogDestinations,
 	}, nil
 }
 
+// verifyLogDestinations checks that each of names is the default result log
+// destination or one configured in osquery.result_log_destinations.
+func (svc *Service) verifyLogDestinations(names []string) error {
+	for _, name := range names {
+		if !svc.config.Osquery.HasResultLogDestination(name) {
+			return fleet.NewInvalidArgumentError("log_destinations", fmt.Sprintf("unknown log destination %q", name))
+		}
+	}
+	return nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // Get Query Specs
 ////////////////////////////////////////////////////////////////////////////////
@@ -959,6 +1183,7 @@ func (svc *Service) specFromQuery(ctx context.Context, query *fleet.Query) (*fle
 		DiscardData:        query.DiscardData,
 		LabelsIncludeAny:   fleet.LabelIdentsToNames(query.LabelsIncludeAny),
 		LabelsIncludeAll:   fleet.LabelIdentsToNames(query.LabelsIncludeAll),
+		LogDestinations:    query.LogDestinations,
 	}, nil
 }
 
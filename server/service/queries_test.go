@@ -7,6 +7,7 @@ import (
 	"time"
 
 	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
+	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/datastore/mysql/mysqltest"
 	"github.com/fleetdm/fleet/v4/server/fleet"
@@ -315,6 +316,82 @@ func TestApplyQuerySpecsEmptyLabelsNotPremium(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestQueryLogDestinations(t *testing.T) {
+	ds := new(mock.Store)
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	var saved *fleet.Query
+	ds.NewQueryFunc = func(ctx context.Context, query *fleet.Query, opts ...fleet.OptionalArg) (*fleet.Query, error) {
+		saved = query
+		return query, nil
+	}
+	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
+		return &fleet.Query{ID: id, Name: "q", Query: "select 1", Logging: fleet.LoggingSnapshot, LogDestinations: fleet.LogDestinations{"security"}}, nil
+	}
+	ds.SaveQueryFunc = func(ctx context.Context, query *fleet.Query, shouldDiscardResults bool, shouldDeleteStats bool) error {
+		saved = query
+		return nil
+	}
+	ds.QueryByNameFunc = func(ctx context.Context, teamID *uint, name string) (*fleet.Query, error) {
+		return nil, newNotFoundError()
+	}
+	var applied []*fleet.Query
+	ds.ApplyQueriesFunc = func(ctx context.Context, authorID uint, queries []*fleet.Query, queriesToDiscardResults map[uint]struct{}) error {
+		applied = queries
+		return nil
+	}
+
+	cfg := config.TestConfig()
+	cfg.Osquery.ResultLogDestinations = []config.ResultLogDestination{{Name: "security", Plugin: "stdout"}}
+	svc, ctx := newTestServiceWithConfig(t, ds, cfg, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	// Configured destinations, including the default one, are accepted.
+	_, err := svc.NewQuery(ctx, fleet.QueryPayload{
+		Name:            ptr.String("q"),
+		Query:           ptr.String("select 1"),
+		LogDestinations: &[]string{"security", "default"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, fleet.LogDestinations{"security", "default"}, saved.LogDestinations)
+
+	// Unknown destinations are rejected.
+	_, err = svc.NewQuery(ctx, fleet.QueryPayload{
+		Name:            ptr.String("q"),
+		Query:           ptr.String("select 1"),
+		LogDestinations: &[]string{"unknown"},
+	})
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+	require.Contains(t, err.Error(), `unknown log destination "unknown"`)
+
+	// Duplicates are rejected.
+	_, err = svc.NewQuery(ctx, fleet.QueryPayload{
+		Name:            ptr.String("q"),
+		Query:           ptr.String("select 1"),
+		LogDestinations: &[]string{"security", "security"},
+	})
+	require.Error(t, err)
+
+	// Modifying without the field keeps them, an empty list resets them.
+	_, err = svc.ModifyQuery(ctx, 1, fleet.QueryPayload{Description: ptr.String("desc")})
+	require.NoError(t, err)
+	require.Equal(t, fleet.LogDestinations{"security"}, saved.LogDestinations)
+	_, err = svc.ModifyQuery(ctx, 1, fleet.QueryPayload{LogDestinations: &[]string{}})
+	require.NoError(t, err)
+	require.Nil(t, saved.LogDestinations)
+	_, err = svc.ModifyQuery(ctx, 1, fleet.QueryPayload{LogDestinations: &[]string{"unknown"}})
+	require.ErrorAs(t, err, &iae)
+
+	// Specs are validated the same way.
+	require.NoError(t, svc.ApplyQuerySpecs(ctx, []*fleet.QuerySpec{{Name: "q", Query: "select 1", LogDestinations: []string{"security"}}}))
+	require.Len(t, applied, 1)
+	require.Equal(t, fleet.LogDestinations{"security"}, applied[0].LogDestinations)
+	err = svc.ApplyQuerySpecs(ctx, []*fleet.QuerySpec{{Name: "q", Query: "select 1", LogDestinations: []string{"unknown"}}})
+	require.ErrorAs(t, err, &iae)
+}
+
 // similar for modify
 func TestQueryPayloadValidationModify(t *testing.T) {
 	ds := new(mock.Store)
@@ -989,6 +1066,104 @@ func TestQueryReportReturnsNilIfDiscardDataIsTrue(t *testing.T) {
 	require.False(t, reportClipped)
 }
 
+func TestQueryReportHistory(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	viewerCtx := viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{
+		ID:         1,
+		GlobalRole: ptr.String(fleet.RoleAdmin),
+	}})
+
+	ds.QueryFunc = func(ctx context.Context, queryID uint) (*fleet.Query, error) {
+		return &fleet.Query{ID: queryID}, nil
+	}
+	from := time.Now().Add(-time.Hour).UTC()
+	var gotFrom, gotTo time.Time
+	ds.QueryResultHistoryRowsFunc = func(ctx context.Context, queryID uint, from, to time.Time, filter fleet.TeamFilter) ([]*fleet.ScheduledQueryResultRow, error) {
+		gotFrom, gotTo = from, to
+		return []*fleet.ScheduledQueryResultRow{
+			{QueryID: queryID, HostID: 1, Data: ptr.RawMessage(json.RawMessage(`{"foo": "bar"}`)), LastFetched: from},
+			{QueryID: queryID, HostID: 1, Data: ptr.RawMessage(json.RawMessage(`{"foo": "baz"}`)), LastFetched: from.Add(time.Minute)},
+		}, nil
+	}
+
+	// to defaults to now
+	results, err := svc.GetQueryReportHistory(viewerCtx, 1, nil, from, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Equal(t, map[string]string{"foo": "baz"}, results[1].Columns)
+	require.Equal(t, from.Add(time.Minute), results[1].LastFetched)
+	require.Equal(t, from, gotFrom)
+	require.WithinDuration(t, time.Now(), gotTo, time.Minute)
+
+	_, err = svc.GetQueryReportHistory(viewerCtx, 1, nil, from, from.Add(-time.Second))
+	require.ErrorContains(t, err, "must be before to")
+}
+
+func TestDiffQueryReport(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	viewerCtx := viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{
+		ID:         1,
+		GlobalRole: ptr.String(fleet.RoleAdmin),
+	}})
+
+	ds.QueryFunc = func(ctx context.Context, queryID uint) (*fleet.Query, error) {
+		return &fleet.Query{ID: queryID}, nil
+	}
+	to := time.Now().UTC()
+	from := to.Add(-time.Hour)
+	row := func(hostID uint, data string) *fleet.ScheduledQueryResultRow {
+		r := &fleet.ScheduledQueryResultRow{QueryID: 1, HostID: hostID}
+		if data != "" {
+			r.Data = ptr.RawMessage(json.RawMessage(data))
+		}
+		return r
+	}
+	ds.QueryResultHistorySnapshotFunc = func(ctx context.Context, queryID uint, at time.Time, filter fleet.TeamFilter) ([]*fleet.ScheduledQueryResultRow, error) {
+		if at.Equal(from) {
+			return []*fleet.ScheduledQueryResultRow{
+				row(1, `{"name": "a"}`),
+				row(1, `{"name": "b"}`),
+				row(2, `{"name": "c"}`),
+				row(3, `{"name": "d"}`),
+			}, nil
+		}
+		return []*fleet.ScheduledQueryResultRow{
+			row(1, `{"name": "a"}`),
+			row(1, `{"name": "e"}`),
+			row(2, `{"name": "c"}`),
+			// the host's latest result has no rows
+			row(3, ""),
+			row(4, `{"name": "f"}`),
+		}, nil
+	}
+
+	diff, err := svc.DiffQueryReport(viewerCtx, 1, nil, from, to)
+	require.NoError(t, err)
+	require.Equal(t, uint(1), diff.QueryID)
+	require.Len(t, diff.Hosts, 3)
+
+	require.Equal(t, uint(1), diff.Hosts[0].HostID)
+	require.Equal(t, fleet.HostQueryResultsChanged, diff.Hosts[0].Status)
+	require.Equal(t, []map[string]string{{"name": "e"}}, diff.Hosts[0].Added)
+	require.Equal(t, []map[string]string{{"name": "b"}}, diff.Hosts[0].Removed)
+
+	require.Equal(t, uint(3), diff.Hosts[1].HostID)
+	require.Equal(t, fleet.HostQueryResultsChanged, diff.Hosts[1].Status)
+	require.Empty(t, diff.Hosts[1].Added)
+	require.Equal(t, []map[string]string{{"name": "d"}}, diff.Hosts[1].Removed)
+
+	require.Equal(t, uint(4), diff.Hosts[2].HostID)
+	require.Equal(t, fleet.HostQueryResultsNew, diff.Hosts[2].Status)
+	require.Equal(t, []map[string]string{{"name": "f"}}, diff.Hosts[2].Added)
+
+	_, err = svc.DiffQueryReport(viewerCtx, 1, nil, from, time.Time{})
+	require.ErrorContains(t, err, "from and to are required")
+	_, err = svc.DiffQueryReport(viewerCtx, 1, nil, to, from)
+	require.ErrorContains(t, err, "must be before to")
+}
+
 func TestInheritedQueryReportTeamPermissions(t *testing.T) {
 	ds := mysqltest.CreateMySQLDS(t)
 	defer ds.Close()
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/license"
+	"github.com/fleetdm/fleet/v4/server/contexts/logging"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/sbom"
+	"github.com/fleetdm/fleet/v4/server/version"
+	"github.com/google/uuid"
+)
+
+// sbomSoftwarePageSize is the number of software loaded at once to build an
+// SBOM.
+const sbomSoftwarePageSize = 1000
+
+type sbomResponse struct {
+	Err error `json:"error,omitempty"`
+
+	// fields used by HijackRender for the response.
+	format   fleet.SBOMFormat
+	filename string
+	content  []byte
+}
+
+func (r sbomResponse) Error() error { return r.Err }
+
+func (r sbomResponse) HijackRender(ctx context.Context, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", r.format.ContentType())
+	w.Header().Set("Content-Length", strconv.Itoa(len(r.content)))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment;filename="%s%s"`, r.filename, r.format.FileExtension()))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if n, err := w.Write(r.content); err != nil {
+		logging.WithExtras(ctx, "sbom_write_error", err, "bytes_written", n)
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Host SBOM
+/////////////////////////////////////////////////////////////////////////////////
+
+type getHostSBOMRequest struct {
+	ID     uint   `url:"id"`
+	Format string `query:"format,optional"`
+}
+
+func getHostSBOMEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*getHostSBOMRequest)
+	content, err := svc.HostSBOM(ctx, req.ID, fleet.SBOMFormat(req.Format))
+	if err != nil {
+		return sbomResponse{Err: err}, nil
+	}
+	// the format was validated by the service
+	format, _ := fleet.ParseSBOMFormat(req.Format)
+	return sbomResponse{format: format, filename: fmt.Sprintf("host-%d-sbom", req.ID), content: content}, nil
+}
+
+func (svc *Service) HostSBOM(ctx context.Context, hostID uint, format fleet.SBOMFormat) ([]byte, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+	host, err := svc.ds.HostLite(ctx, hostID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get host lite")
+	}
+	// Authorize again with team loaded now that we have team_id
+	if err := svc.authz.Authorize(ctx, host, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	format, err = fleet.ParseSBOMFormat(string(format))
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+
+	software, err := svc.listSBOMSoftware(ctx, fleet.SoftwareListOptions{HostID: &host.ID})
+	if err != nil {
+		return nil, err
+	}
+	return svc.encodeSBOM(ctx, format, sbom.Input{
+		Host: &sbom.Host{
+			ID:             host.ID,
+			Hostname:       host.DisplayName(),
+			UUID:           host.UUID,
+			HardwareSerial: host.HardwareSerial,
+			Platform:       host.Platform,
+			OSVersion:      host.OSVersion,
+		},
+		Software: software,
+	})
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Fleet SBOM
+/////////////////////////////////////////////////////////////////////////////////
+
+type getTeamSBOMRequest struct {
+	ID     uint   `url:"id"`
+	Format string `query:"format,optional"`
+}
+
+func getTeamSBOMEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*getTeamSBOMRequest)
+	content, err := svc.TeamSBOM(ctx, req.ID, fleet.SBOMFormat(req.Format))
+	if err != nil {
+		return sbomResponse{Err: err}, nil
+	}
+	// the format was validated by the service
+	format, _ := fleet.ParseSBOMFormat(req.Format)
+	return sbomResponse{format: format, filename: fmt.Sprintf("fleet-%d-sbom", req.ID), content: content}, nil
+}
+
+func (svc *Service) TeamSBOM(ctx context.Context, teamID uint, format fleet.SBOMFormat) ([]byte, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.AuthzSoftwareInventory{TeamID: &teamID}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	format, err := fleet.ParseSBOMFormat(string(format))
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+
+	team := &sbom.Team{ID: teamID}
+	if teamID != 0 {
+		if !license.IsPremium(ctx) {
+			return nil, fleet.ErrMissingLicense
+		}
+		tm, err := svc.ds.TeamLite(ctx, teamID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "get team")
+		}
+		team.Name = tm.Name
+	}
+
+	// Software of a fleet is listed from the aggregated host counts, like the
+	// software versions endpoint does.
+	software, err := svc.listSBOMSoftware(ctx, fleet.SoftwareListOptions{TeamID: &teamID, WithHostCounts: true})
+	if err != nil {
+		return nil, err
+	}
+	return svc.encodeSBOM(ctx, format, sbom.Input{Team: team, Software: software})
+}
+
+// listSBOMSoftware loads all the software matching opts, with their
+// vulnerabilities. CVE scores are included in Fleet Premium.
+func (svc *Service) listSBOMSoftware(ctx context.Context, opts fleet.SoftwareListOptions) ([]fleet.Software, error) {
+	opts.ListOptions = fleet.ListOptions{OrderKey: "id", PerPage: sbomSoftwarePageSize}
+	opts.IncludeCVEScores = license.IsPremium(ctx)
+
+	var software []fleet.Software
+	for {
+		page, _, err := svc.ds.ListSoftware(ctx, opts)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "list software for SBOM")
+		}
+		software = append(software, page...)
+		if len(page) < sbomSoftwarePageSize {
+			return software, nil
+		}
+		opts.ListOptions.Page++
+	}
+}
+
+func (svc *Service) encodeSBOM(ctx context.Context, format fleet.SBOMFormat, in sbom.Input) ([]byte, error) {
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	in.DocumentID = uuid.NewString()
+	in.Created = time.Now()
+	in.ToolVersion = version.Version().Version
+	in.Namespace = appConfig.ServerSettings.ServerURL
+	in.OrgName = appConfig.OrgInfo.OrgName
+
+	content, err := sbom.Encode(format, in)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "encode SBOM")
+	}
+	return content, nil
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSBOMAuth(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return &fleet.Host{ID: id, TeamID: new(uint(1))}, nil
+	}
+	ds.TeamLiteFunc = func(ctx context.Context, tid uint) (*fleet.TeamLite, error) {
+		return &fleet.TeamLite{ID: tid, Name: "team"}, nil
+	}
+	ds.ListSoftwareFunc = func(ctx context.Context, opt fleet.SoftwareListOptions) ([]fleet.Software, *fleet.PaginationMetadata, error) {
+		return nil, nil, nil
+	}
+
+	cases := []struct {
+		name        string
+		user        *fleet.User
+		hostAllowed bool
+		teamAllowed bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: new(fleet.RoleAdmin)}, true, true},
+		{"global observer", &fleet.User{GlobalRole: new(fleet.RoleObserver)}, true, true},
+		// gitops users can read the software inventory, but not hosts
+		{"global gitops", &fleet.User{GlobalRole: new(fleet.RoleGitOps)}, false, true},
+		{"team observer", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver}}}, true, true},
+		{"other team admin", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleAdmin}}}, false, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.HostSBOM(ctx, 1, "")
+			checkAuthErr(t, !tt.hostAllowed, err)
+
+			_, err = svc.TeamSBOM(ctx, 1, fleet.SBOMFormatSPDX)
+			checkAuthErr(t, !tt.teamAllowed, err)
+		})
+	}
+}
+
+func TestHostSBOM(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: new(fleet.RoleAdmin)}})
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.example.com"}}, nil
+	}
+	ds.HostLiteFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return &fleet.Host{ID: id, Hostname: "web-1", Platform: "ubuntu"}, nil
+	}
+	// return two full pages and a partial one
+	var pages []uint
+	ds.ListSoftwareFunc = func(ctx context.Context, opt fleet.SoftwareListOptions) ([]fleet.Software, *fleet.PaginationMetadata, error) {
+		require.Equal(t, uint(1), *opt.HostID)
+		require.True(t, opt.IncludeCVEScores)
+		pages = append(pages, opt.ListOptions.Page)
+		n := sbomSoftwarePageSize
+		if opt.ListOptions.Page == 2 {
+			n = 1
+		}
+		software := make([]fleet.Software, n)
+		for i := range software {
+			software[i] = fleet.Software{ID: opt.ListOptions.Page*sbomSoftwarePageSize + uint(i) + 1, Name: "curl", Version: "7.81.0", Source: "deb_packages"} //nolint:gosec // dismiss G115
+		}
+		return software, nil, nil
+	}
+
+	_, err := svc.HostSBOM(ctx, 1, "pdf")
+	require.ErrorContains(t, err, `invalid format "pdf"`)
+	require.False(t, ds.ListSoftwareFuncInvoked)
+
+	content, err := svc.HostSBOM(ctx, 1, "")
+	require.NoError(t, err)
+	require.Equal(t, []uint{0, 1, 2}, pages)
+
+	var doc struct {
+		BOMFormat  string `json:"bomFormat"`
+		Components []struct {
+			PURL string `json:"purl"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(content, &doc))
+	require.Equal(t, "CycloneDX", doc.BOMFormat)
+	require.Len(t, doc.Components, 2*sbomSoftwarePageSize+1)
+	require.Equal(t, "pkg:deb/ubuntu/curl@7.81.0", doc.Components[0].PURL)
+}
+
+func TestTeamSBOM(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: new(fleet.RoleAdmin)}})
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.ListSoftwareFunc = func(ctx context.Context, opt fleet.SoftwareListOptions) ([]fleet.Software, *fleet.PaginationMetadata, error) {
+		require.Equal(t, uint(0), *opt.TeamID)
+		require.True(t, opt.WithHostCounts)
+		// CVE scores are a Fleet Premium feature
+		require.False(t, opt.IncludeCVEScores)
+		return []fleet.Software{{ID: 1, Name: "curl", Version: "7.81.0", Source: "deb_packages", HostsCount: 3}}, nil, nil
+	}
+
+	// fleets require Fleet Premium
+	_, err := svc.TeamSBOM(ctx, 1, fleet.SBOMFormatSPDX)
+	require.ErrorIs(t, err, fleet.ErrMissingLicense)
+
+	// hosts not assigned to a fleet don't
+	content, err := svc.TeamSBOM(ctx, 0, fleet.SBOMFormatSPDX)
+	require.NoError(t, err)
+	var doc struct {
+		SPDXVersion string `json:"spdxVersion"`
+		Name        string `json:"name"`
+	}
+	require.NoError(t, json.Unmarshal(content, &doc))
+	require.Equal(t, "SPDX-2.3", doc.SPDXVersion)
+	require.Equal(t, "Unassigned", doc.Name)
+}
@@ -252,7 +252,9 @@ func (svc *Service) RunHostScript(ctx context.Context, request *fleet.HostScript
 	}
 
 	// create the script execution request, the host will be notified of the
-	// script execution request via the orbit config's Notifications mechanism.
+	// script execution request via the orbit config's Notifications mechanism,
+	// which it fetches right away if it is connected to the notifications
+	// websocket.
 	if ctxUser := authz.UserFromContext(ctx); ctxUser != nil {
 		request.UserID = &ctxUser.ID
 	}
@@ -262,6 +264,7 @@ func (svc *Service) RunHostScript(ctx context.Context, request *fleet.HostScript
 		return nil, ctxerr.Wrap(ctx, err, "create script execution request")
 	}
 	script.Hostname = host.DisplayName()
+	svc.NotifyOrbitHosts(ctx, fleet.OrbitNotificationHintScripts, host.ID)
 
 	if asyncExecution {
 		// async execution, return
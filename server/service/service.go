@@ -85,6 +85,14 @@ type Service struct {
 	// orgLogoStore stores the bytes of customer-uploaded org logos.
 	orgLogoStore fleet.OrgLogoStore
 
+	// campaignResultsStore stores the saved results of campaigns that are too
+	// large for the database. It is nil if no store is configured.
+	campaignResultsStore fleet.CampaignResultsStore
+
+	// orbitNotifier pushes notification hints to the connected fleetd agents.
+	// It is nil if notifications are not available.
+	orbitNotifier fleet.OrbitNotifier
+
 	// packConfigCache caches marshaled pack config JSON per (teamID, queryReportsDisabled).
 	// Avoids redundant DB queries and JSON marshaling for identical pack configs.
 	packConfigCache *gocache.Cache
@@ -133,6 +141,9 @@ type OsqueryLogger struct {
 	//
 	// See https://osquery.readthedocs.io/en/stable/deployment/logging/#results-logs
 	Result fleet.JSONLogger
+	// ResultDestinations holds the named result log destinations that reports can
+	// route their results to, keyed by name. Result is the "default" destination.
+	ResultDestinations map[string]fleet.JSONLogger
 }
 
 // NewService creates a new service from the config struct
@@ -163,6 +174,8 @@ func NewService(
 	keyValueStore fleet.KeyValueStore,
 	androidSvc android.Service,
 	orgLogoStore fleet.OrgLogoStore,
+	campaignResultsStore fleet.CampaignResultsStore,
+	orbitNotifier fleet.OrbitNotifier,
 ) (fleet.Service, error) {
 	authorizer, err := authz.NewAuthorizer()
 	if err != nil {
@@ -203,6 +216,8 @@ func NewService(
 		keyValueStore:                   keyValueStore,
 		androidSvc:                      androidSvc,
 		orgLogoStore:                    orgLogoStore,
+		campaignResultsStore:            campaignResultsStore,
+		orbitNotifier:                   orbitNotifier,
 		packConfigCache:                 gocache.New(1*time.Minute, 30*time.Second),
 	}
 	return validationMiddleware{svc, ds, sso}, nil
@@ -238,6 +238,14 @@ func (svc *Service) LoggingConfig(ctx context.Context) (*fleet.Logging, error) {
 					SourceType: conf.Splunk.SourceType,
 				},
 			}
+		case "otlp":
+			*lp.target = fleet.LoggingPlugin{
+				Plugin: "otlp",
+				Config: fleet.OTLPConfig{
+					Endpoint: conf.OTLP.Endpoint,
+					Protocol: conf.OTLP.Protocol,
+				},
+			}
 		default:
 			return nil, ctxerr.Errorf(ctx, "unrecognized logging plugin: %s", lp.plugin)
 		}
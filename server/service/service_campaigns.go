@@ -121,6 +121,14 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 		return
 	}
 
+	// Deferred campaigns persist their results in the database and must keep
+	// running until they expire, so they cannot be streamed (completing the
+	// stream would complete the campaign).
+	if campaign.Deferred {
+		conn.WriteJSONError("deferred campaign results must be retrieved via the campaign results API") //nolint:errcheck
+		return
+	}
+
 	// Open the channel from which we will receive incoming query results
 	// (probably from the redis pubsub implementation)
 	readChan, cancelFunc, err := svc.GetCampaignReader(ctx, campaign)
@@ -246,6 +254,12 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 
 				// Calculate result size for performance stats
 				outputSize := calculateOutputSize(&perfStatsTracker, &res)
+				if campaign.SaveResults {
+					// saved before the rows get the hostname fields added
+					if err := svc.saveCampaignQueryResult(ctx, campaign.ID, res); err != nil {
+						logger.ErrorContext(ctx, "error saving campaign result", "host_id", res.Host.ID, "err", err)
+					}
+				}
 				mapHostnameRows(&res)
 				err = conn.WriteJSONMessage("result", res)
 				if perfStatsTracker.saveStats && res.Stats != nil {
@@ -117,7 +117,10 @@ func (svc *Service) DeleteSession(ctx context.Context, id uint) error {
 
 type loginMfaResponse struct {
 	Message string `json:"message"`
-	Err     error  `json:"error,omitempty"`
+	// LoginMFAChallenge is set when the login must be completed with a second
+	// factor (see completeLoginMFAEndpoint) rather than the emailed link.
+	*fleet.LoginMFAChallenge
+	Err error `json:"error,omitempty"`
 }
 
 func (r loginMfaResponse) Status() int { return http.StatusAccepted }
@@ -133,9 +136,21 @@ func loginEndpoint(ctx context.Context, request interface{}, svc fleet.Service)
 		if errors.Is(err, sendingMFAEmail) {
 			return loginMfaResponse{Message: "We sent an email to you. Please click the magic link in the email to sign in."}, nil
 		}
+		if challengeErr, ok := errors.AsType[*mfaChallengeError](err); ok {
+			message := "Please verify your identity with your second factor to sign in."
+			if challengeErr.challenge.EnrollmentRequired {
+				message = "Please set up a second factor to sign in."
+			}
+			return loginMfaResponse{Message: message, LoginMFAChallenge: challengeErr.challenge}, nil
+		}
 
 		return fleet.LoginResponse{Err: err}, nil
 	}
+	return newLoginResponse(ctx, svc, user, session), nil
+}
+
+// newLoginResponse returns the response of a successful login of the user.
+func newLoginResponse(ctx context.Context, svc fleet.Service, user *fleet.User, session *fleet.Session) fleet.LoginResponse {
 	// Add viewer to context to allow access to service teams for list of available teams.
 	ctx = viewer.NewContext(ctx, viewer.Viewer{
 		User:    user,
@@ -146,7 +161,7 @@ func loginEndpoint(ctx context.Context, request interface{}, svc fleet.Service)
 		if errors.Is(err, fleet.ErrMissingLicense) {
 			availableTeams = []*fleet.TeamSummary{}
 		} else {
-			return fleet.LoginResponse{Err: err}, nil
+			return fleet.LoginResponse{Err: err}
 		}
 	}
 
@@ -162,12 +177,27 @@ func loginEndpoint(ctx context.Context, request interface{}, svc fleet.Service)
 		AvailableTeams: availableTeams,
 		Token:          session.Key,
 		TokenExpiresAt: tokenExpiresAt,
-	}, nil
+	}
 }
 
 //goland:noinspection GoErrorStringFormat
 var sendingMFAEmail = errors.New("sending MFA email")
 
+// mfaChallengeError is returned by Login when the user must complete the login
+// with a second factor.
+type mfaChallengeError struct {
+	challenge *fleet.LoginMFAChallenge
+}
+
+func (e *mfaChallengeError) Error() string { return "second factor required" }
+
+// isPendingMFA returns true if the login error only means that the user must
+// complete the login with a second factor.
+func isPendingMFA(err error) bool {
+	_, ok := errors.AsType[*mfaChallengeError](err)
+	return ok || errors.Is(err, sendingMFAEmail)
+}
+
 func (svc *Service) Login(ctx context.Context, email, password string, supportsEmailVerification bool) (*fleet.User, *fleet.Session, error) {
 	// skipauth: No user context available yet to authorize against.
 	svc.authz.SkipAuthorization(ctx)
@@ -183,7 +213,7 @@ func (svc *Service) Login(ctx context.Context, email, password string, supportsE
 	// take ~1s and frustrate a timing attack.
 	var err error
 	defer func(start time.Time) {
-		if err != nil && !errors.Is(err, sendingMFAEmail) {
+		if err != nil && !isPendingMFA(err) {
 			if err := svc.NewActivity(
 				ctx, nil, fleet.ActivityTypeUserFailedLogin{
 					Email:    email,
@@ -210,9 +240,23 @@ func (svc *Service) Login(ctx context.Context, email, password string, supportsE
 		return nil, nil, fleet.NewAuthFailedError("invalid password")
 	}
 
+	if user.Disabled {
+		err = fleet.NewAuthFailedError("user is disabled")
+		return nil, nil, err
+	}
+
 	if user.SSOEnabled {
 		err = fleet.NewAuthFailedError("password login disabled for sso users")
 		return nil, nil, err
+	} else if user.HasSecondFactor() {
+		challenge, chErr := svc.newLoginMFAChallenge(ctx, user)
+		if chErr != nil {
+			err = fleet.NewAuthFailedError(chErr.Error())
+			return nil, nil, err
+		}
+		svc.recordMFARequested(ctx, email)
+		err = &mfaChallengeError{challenge: challenge}
+		return nil, nil, err
 	} else if user.MFAEnabled {
 		if !supportsEmailVerification {
 			err = fleet.NewAuthFailedError("client with no MFA email support")
@@ -223,24 +267,26 @@ func (svc *Service) Login(ctx context.Context, email, password string, supportsE
 			return nil, nil, fleet.NewAuthFailedError(err.Error())
 		}
 
-		// A correct password on an MFA-enabled account triggers a verification
-		// email. Record it so this event is visible in the activity feed and
-		// audit stream, since it is otherwise the only observable signal that a
-		// valid password was submitted.
-		if actErr := svc.NewActivity(
-			ctx, nil, fleet.ActivityTypeUserMFARequested{
-				Email:    email,
-				PublicIP: publicip.FromContext(ctx),
-			}); actErr != nil {
-			logging.WithExtras(logging.WithNoUser(ctx),
-				"msg", "failed to generate MFA requested activity",
-			)
-		}
-
+		svc.recordMFARequested(ctx, email)
 		err = sendingMFAEmail
 		return nil, nil, err
 	}
 
+	required, err := svc.mfaRequired(ctx, user)
+	if err != nil {
+		return nil, nil, fleet.NewAuthFailedError(err.Error())
+	}
+	if required {
+		challenge, chErr := svc.newEnrollmentMFAChallenge(ctx, user)
+		if chErr != nil {
+			err = fleet.NewAuthFailedError(chErr.Error())
+			return nil, nil, err
+		}
+		svc.recordMFARequested(ctx, email)
+		err = &mfaChallengeError{challenge: challenge}
+		return nil, nil, err
+	}
+
 	// Do not allow login if on Fleet Free and the user has a Premium-only role.
 	if !license.IsPremium(ctx) {
 		if fleet.PremiumRolesPresent(user.GlobalRole, user.Teams) {
@@ -263,6 +309,22 @@ func (svc *Service) Login(ctx context.Context, email, password string, supportsE
 	return user, session, nil
 }
 
+// recordMFARequested records that a correct password was submitted for a user
+// who must complete the login with a second factor or the emailed link, since
+// it is otherwise the only observable signal that a valid password was
+// submitted.
+func (svc *Service) recordMFARequested(ctx context.Context, email string) {
+	if err := svc.NewActivity(
+		ctx, nil, fleet.ActivityTypeUserMFARequested{
+			Email:    email,
+			PublicIP: publicip.FromContext(ctx),
+		}); err != nil {
+		logging.WithExtras(logging.WithNoUser(ctx),
+			"msg", "failed to generate MFA requested activity",
+		)
+	}
+}
+
 func (svc *Service) makeSession(ctx context.Context, userID uint) (*fleet.Session, error) {
 	return svc.ds.NewSession(ctx, userID, svc.config.Session.KeySize)
 }
@@ -285,33 +347,7 @@ func sessionCreateEndpoint(ctx context.Context, request interface{}, svc fleet.S
 	if err != nil {
 		return fleet.LoginResponse{Err: err}, nil
 	}
-	// Add viewer to context to allow access to service teams for list of available teams.
-	ctx = viewer.NewContext(ctx, viewer.Viewer{
-		User:    user,
-		Session: session,
-	})
-	availableTeams, err := svc.ListAvailableTeamsForUser(ctx, user)
-	if err != nil {
-		if errors.Is(err, fleet.ErrMissingLicense) {
-			availableTeams = []*fleet.TeamSummary{}
-		} else {
-			return fleet.LoginResponse{Err: err}, nil
-		}
-	}
-
-	// Calculate token expiration time if session duration is configured
-	var tokenExpiresAt *time.Time
-	if sessionDuration := svc.GetSessionDuration(ctx); sessionDuration > 0 {
-		expiresAt := time.Now().Add(sessionDuration).UTC()
-		tokenExpiresAt = &expiresAt
-	}
-
-	return fleet.LoginResponse{
-		User:           user,
-		AvailableTeams: availableTeams,
-		Token:          session.Key,
-		TokenExpiresAt: tokenExpiresAt,
-	}, nil
+	return newLoginResponse(ctx, svc, user, session), nil
 }
 
 func (svc *Service) CompleteMFA(ctx context.Context, token string) (*fleet.Session, *fleet.User, error) {
@@ -485,6 +521,23 @@ func (svc *Service) InitiateSSO(ctx context.Context, redirectURL string) (sessio
 		return "", 0, "", ctxerr.Wrap(ctx, badRequest("invalid sso redirect url scheme: "+parsedUrl.Scheme))
 	}
 
+	sessionDurationSeconds = int(svc.config.Auth.SsoSessionValidityPeriod.Seconds())
+
+	if appConfig.SSOSettings.OIDCConfigured() {
+		provider, err := svc.oidcProvider(ctx, appConfig)
+		if err != nil {
+			return "", 0, "", ctxerr.Wrap(ctx, err, "InitiateSSO creating OIDC provider")
+		}
+		sessionID, idpURL, err = sso.CreateOIDCAuthorizationRequest(
+			ctx, provider, svc.ssoSessionStore, redirectURL,
+			uint(sessionDurationSeconds), //nolint:gosec // dismiss G115
+		)
+		if err != nil {
+			return "", 0, "", ctxerr.Wrap(ctx, err, "InitiateSSO creating OIDC authorization")
+		}
+		return sessionID, sessionDurationSeconds, idpURL, nil
+	}
+
 	serverURL := appConfig.ServerSettings.ServerURL
 	// Use SSO server URL if configured, otherwise use the server URL
 	ssoURL := serverURL
@@ -522,7 +575,6 @@ func (svc *Service) InitiateSSO(ctx context.Context, redirectURL string) (sessio
 		return "", 0, "", ctxerr.Wrap(ctx, err, "failed to create provider from configured metadata")
 	}
 
-	sessionDurationSeconds = int(svc.config.Auth.SsoSessionValidityPeriod.Seconds())
 	sessionID, idpURL, err = sso.CreateAuthorizationRequest(
 		ctx, samlProvider, svc.ssoSessionStore, redirectURL,
 		uint(sessionDurationSeconds), //nolint:gosec // dismiss G115
@@ -639,32 +691,46 @@ func makeCallbackSSOEndpoint(urlPrefix string) handlerFunc {
 	return func(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
 		callbackRequest := request.(*callbackSSORequest)
 		session, userID, err := getSSOSession(ctx, svc, callbackRequest)
-		var resp callbackSSOResponse
-		if err != nil {
-			if err := svc.NewActivity(ctx, nil, fleet.ActivityTypeUserFailedLogin{
-				Email:    userID,
-				PublicIP: publicip.FromContext(ctx),
-			}); err != nil {
-				logging.WithLevel(logging.WithExtras(logging.WithNoUser(ctx),
-					"msg", "failed to generate failed login activity",
-				), slog.LevelInfo)
-			}
+		return makeCallbackSSOResponse(ctx, svc, urlPrefix, session, userID, err)
+	}
+}
+
+// makeCallbackSSOResponse renders the page that completes an SSO login, it
+// redirects to the original URL on success and to the login page otherwise.
+func makeCallbackSSOResponse(
+	ctx context.Context,
+	svc fleet.Service,
+	urlPrefix string,
+	session *fleet.SSOSession,
+	userID string,
+	err error,
+) (fleet.Errorer, error) {
+	var resp callbackSSOResponse
+	if err != nil {
+		if err := svc.NewActivity(ctx, nil, fleet.ActivityTypeUserFailedLogin{
+			Email:    userID,
+			PublicIP: publicip.FromContext(ctx),
+		}); err != nil {
+			logging.WithLevel(logging.WithExtras(logging.WithNoUser(ctx),
+				"msg", "failed to generate failed login activity",
+			), slog.LevelInfo)
+		}
 
-			var ssoErr *ssoError
+		var ssoErr *ssoError
 
-			status := ssoOtherError
-			if errors.As(err, &ssoErr) {
-				status = ssoErr.code
-			}
-			// redirect to login page on front end if there was some problem,
-			// errors should still be logged
-			session = &fleet.SSOSession{
-				RedirectURL: urlPrefix + "/login?status=" + string(status),
-				Token:       "",
-			}
-			resp.Err = err
+		status := ssoOtherError
+		if errors.As(err, &ssoErr) {
+			status = ssoErr.code
+		}
+		// redirect to login page on front end if there was some problem,
+		// errors should still be logged
+		session = &fleet.SSOSession{
+			RedirectURL: urlPrefix + "/login?status=" + string(status),
+			Token:       "",
 		}
-		relayStateLoadPage := ` <html>
+		resp.Err = err
+	}
+	relayStateLoadPage := ` <html>
      <script type='text/javascript'>
      var redirectURL = {{ .RedirectURL }};
      window.location = redirectURL;
@@ -674,20 +740,19 @@ func makeCallbackSSOEndpoint(urlPrefix string) handlerFunc {
      </body>
      </html>
     `
-		tmpl, err := template.New("relayStateLoader").Parse(relayStateLoadPage)
-		if err != nil {
-			return nil, err
-		}
-		var writer bytes.Buffer
-		err = tmpl.Execute(&writer, session)
-		if err != nil {
-			return nil, err
-		}
-		resp.content = writer.String()
-		resp.token = session.Token
-		resp.expires = svc.GetSessionDuration(ctx)
-		return resp, nil
+	tmpl, err := template.New("relayStateLoader").Parse(relayStateLoadPage)
+	if err != nil {
+		return nil, err
+	}
+	var writer bytes.Buffer
+	err = tmpl.Execute(&writer, session)
+	if err != nil {
+		return nil, err
 	}
+	resp.content = writer.String()
+	resp.token = session.Token
+	resp.expires = svc.GetSessionDuration(ctx)
+	return resp, nil
 }
 
 func getSSOSession(
@@ -699,7 +764,17 @@ func getSSOSession(
 	if err != nil {
 		return nil, "", err
 	}
+	return loginSSOAuth(ctx, svc, auth, redirectURL)
+}
 
+// loginSSOAuth gets (or creates) the user authenticated by the identity
+// provider and logs them in.
+func loginSSOAuth(
+	ctx context.Context,
+	svc fleet.Service,
+	auth fleet.Auth,
+	redirectURL string,
+) (session *fleet.SSOSession, userID string, err error) {
 	user, err := svc.GetSSOUser(ctx, auth)
 	if err != nil {
 		return nil, auth.UserID(), err
@@ -733,6 +808,11 @@ func (svc *Service) InitSSOCallback(
 		err := ctxerr.New(ctx, "organization not configured to use sso")
 		return nil, "", ctxerr.Wrap(ctx, newSSOError(err, ssoOrgDisabled), "callback sso")
 	}
+	if appConfig.SSOSettings.OIDCConfigured() {
+		// Reject SAML responses (e.g. IdP-initiated logins) once SSO has been
+		// switched to an OIDC provider.
+		return nil, "", ctxerr.Wrap(ctx, fleet.NewAuthFailedError("organization configured to use OIDC"))
+	}
 
 	serverURL := appConfig.ServerSettings.ServerURL
 	// Use SSO server URL if configured, otherwise use the server URL
@@ -774,6 +854,120 @@ func (svc *Service) InitSSOCallback(
 	return auth, redirectURL, nil
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// Callback OIDC
+////////////////////////////////////////////////////////////////////////////////
+
+const oidcCallbackPath = "/api/v1/fleet/sso/oidc/callback"
+
+type callbackOIDCRequest struct {
+	sessionID string
+	state     string
+	code      string
+	idpError  string
+}
+
+func (callbackOIDCRequest) DecodeRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	var req callbackOIDCRequest
+	cs, err := r.Cookie(cookieNameSSOSession)
+	switch {
+	case err == nil:
+		req.sessionID = cs.Value
+	case errors.Is(err, http.ErrNoCookie):
+		// Rejected by InitOIDCCallback, so that the failed login is recorded.
+	default:
+		return nil, ctxerr.Wrap(ctx, &fleet.BadRequestError{
+			Message: "failed to read SSO cookie session ID",
+		}, "cookie session ID in OIDC callback")
+	}
+
+	q := r.URL.Query()
+	req.state = q.Get("state")
+	req.code = q.Get("code")
+	if idpErr := q.Get("error"); idpErr != "" {
+		req.idpError = idpErr
+		if desc := q.Get("error_description"); desc != "" {
+			req.idpError += ": " + desc
+		}
+	}
+	return &req, nil
+}
+
+func makeCallbackOIDCEndpoint(urlPrefix string) handlerFunc {
+	return func(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+		req := request.(*callbackOIDCRequest)
+		var (
+			session *fleet.SSOSession
+			userID  string
+		)
+		auth, redirectURL, err := svc.InitOIDCCallback(ctx, req.sessionID, req.state, req.code, req.idpError)
+		if err == nil {
+			session, userID, err = loginSSOAuth(ctx, svc, auth, redirectURL)
+		}
+		return makeCallbackSSOResponse(ctx, svc, urlPrefix, session, userID, err)
+	}
+}
+
+// InitOIDCCallback completes the OpenID Connect authorization code flow
+// started by InitiateSSO and returns the authenticated identity.
+func (svc *Service) InitOIDCCallback(
+	ctx context.Context,
+	sessionID, state, code, idpError string,
+) (auth fleet.Auth, redirectURL string, err error) {
+	// skipauth: User context does not yet exist. Unauthenticated users may
+	// hit the SSO callback.
+	svc.authz.SkipAuthorization(ctx)
+
+	logging.WithLevel(logging.WithNoUser(ctx), slog.LevelInfo)
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, "", ctxerr.Wrap(ctx, err, "get config for sso")
+	}
+
+	if appConfig.SSOSettings == nil || !appConfig.SSOSettings.EnableSSO {
+		err := ctxerr.New(ctx, "organization not configured to use sso")
+		return nil, "", ctxerr.Wrap(ctx, newSSOError(err, ssoOrgDisabled), "callback oidc")
+	}
+	if !appConfig.SSOSettings.OIDCConfigured() {
+		return nil, "", ctxerr.Wrap(ctx, fleet.NewAuthFailedError("organization not configured to use OIDC"))
+	}
+	if idpError != "" {
+		if sessionID != "" {
+			// The session can't be completed anymore, don't wait for it to expire.
+			_, _ = svc.ssoSessionStore.Fullfill(sessionID)
+		}
+		return nil, "", ctxerr.Wrap(ctx, fleet.NewAuthFailedError("OIDC provider returned an error: "+idpError))
+	}
+
+	provider, err := svc.oidcProvider(ctx, appConfig)
+	if err != nil {
+		return nil, "", ctxerr.Wrap(ctx, newSSOError(err, ssoOtherError), "create OIDC provider")
+	}
+	auth, redirectURL, err = sso.ValidateOIDCCallback(ctx, provider, svc.ssoSessionStore, sessionID, state, code)
+	if err != nil {
+		// As with SAML, clients get redirected to /login?status=error, the
+		// AuthFailedError is for consistency with other unauthorized accesses.
+		return nil, "", ctxerr.Wrap(ctx, fleet.NewAuthFailedError(err.Error()))
+	}
+	return auth, redirectURL, nil
+}
+
+// oidcProvider returns the OIDC provider configured in the SSO settings,
+// using the SSO server URL (or the server URL) for the callback.
+func (svc *Service) oidcProvider(ctx context.Context, appConfig *fleet.AppConfig) (*sso.OIDCProvider, error) {
+	ssoURL := appConfig.ServerSettings.ServerURL
+	if appConfig.SSOSettings.SSOServerURL != "" {
+		ssoURL = appConfig.SSOSettings.SSOServerURL
+	}
+	parsedURL, err := url.Parse(ssoURL)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, badRequest("invalid SSO URL: "+err.Error()))
+	}
+	callbackURL := sso.CallbackURL(parsedURL, svc.config.Server.URLPrefix, oidcCallbackPath)
+	return sso.NewOIDCProvider(ctx, appConfig.SSOSettings.OIDC, callbackURL.String())
+}
+
 func (svc *Service) GetSSOUser(ctx context.Context, auth fleet.Auth) (*fleet.User, error) {
 	user, err := svc.ds.UserByEmail(ctx, auth.UserID())
 	if err != nil {
@@ -794,6 +988,10 @@ func (svc *Service) LoginSSOUser(ctx context.Context, user *fleet.User, redirect
 		err := ctxerr.New(ctx, "user not configured to use sso")
 		return nil, ctxerr.Wrap(ctx, newSSOError(err, ssoAccountDisabled))
 	}
+	if user.Disabled {
+		err := ctxerr.New(ctx, "user is disabled")
+		return nil, ctxerr.Wrap(ctx, newSSOError(err, ssoAccountDisabled))
+	}
 
 	// Do not allow login if on Fleet Free and the user has a Premium-only role.
 	if !license.IsPremium(ctx) {
@@ -17,6 +17,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/mock"
 	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/sso/oidctest"
 	"github.com/fleetdm/fleet/v4/server/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -770,3 +771,56 @@ func TestDecodeCallbackRequestSAMLResponseSizeCap(t *testing.T) {
 		require.Equal(t, "<x/>", string(decoded))
 	})
 }
+
+func TestInitiateSSOWithOIDC(t *testing.T) {
+	ds := new(mock.Store)
+	cfg := config.TestConfig()
+	cfg.Server.URLPrefix = "/apps/fleet"
+	svc, ctx := newTestServiceWithConfig(t, ds, cfg, nil, nil, &TestServerOpts{
+		Pool: redistest.NopRedis(),
+	})
+
+	iss := oidctest.NewIssuer(t)
+	appConfig := &fleet.AppConfig{
+		ServerSettings: fleet.ServerSettings{
+			ServerURL: "https://fleet.example.com",
+		},
+		SSOSettings: &fleet.SSOSettings{
+			EnableSSO:           true,
+			SSOServerURL:        "https://admin.fleet.example.com",
+			SSOProviderSettings: fleet.SSOProviderSettings{IDPName: "Test OIDC"},
+			OIDC: &fleet.OIDCSettings{
+				IssuerURL: iss.URL,
+				ClientID:  oidctest.ClientID,
+			},
+		},
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appConfig, nil
+	}
+
+	sessionID, _, idpURL, err := svc.InitiateSSO(ctx, "/dashboard")
+	require.NoError(t, err)
+	require.NotEmpty(t, sessionID)
+
+	parsed, err := url.Parse(idpURL)
+	require.NoError(t, err)
+	assert.Equal(t, iss.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "https://admin.fleet.example.com/apps/fleet/api/v1/fleet/sso/oidc/callback", parsed.Query().Get("redirect_uri"))
+	assert.Empty(t, parsed.Query().Get("SAMLRequest"))
+
+	// SAML responses are rejected while OIDC is configured.
+	_, _, err = svc.InitSSOCallback(ctx, "", []byte("<Response/>"))
+	var authErr *fleet.AuthFailedError
+	require.ErrorAs(t, err, &authErr)
+
+	// The OIDC callback is rejected if the provider returned an error.
+	_, _, err = svc.InitOIDCCallback(ctx, "", "state", "", "access_denied: user cancelled")
+	require.ErrorAs(t, err, &authErr)
+	assert.Contains(t, authErr.Internal(), "access_denied")
+
+	// Or if OIDC isn't configured.
+	appConfig.SSOSettings.OIDC = nil
+	_, _, err = svc.InitOIDCCallback(ctx, "sessionID", "state", "code", "")
+	require.ErrorAs(t, err, &authErr)
+}
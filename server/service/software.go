@@ -110,9 +110,12 @@ func (svc *Service) ListSoftware(ctx context.Context, opt fleet.SoftwareListOpti
 	if err != nil {
 		return nil, nil, err
 	}
-	if !lic.IsPremium() && (opt.MaximumCVSS > 0 || opt.MinimumCVSS > 0 || opt.KnownExploit) {
+	if !lic.IsPremium() && (opt.MaximumCVSS > 0 || opt.MinimumCVSS > 0 || opt.MaximumEPSS > 0 || opt.MinimumEPSS > 0 || opt.KnownExploit) {
 		return nil, nil, fleet.ErrMissingLicense
 	}
+	if err := fleet.ValidateEPSSProbabilityRange(opt.MinimumEPSS, opt.MaximumEPSS); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "validate epss probability filters")
+	}
 
 	// default sort order to hosts_count descending
 	if opt.ListOptions.OrderKey == "" {
@@ -266,9 +269,12 @@ func (svc Service) CountSoftware(ctx context.Context, opt fleet.SoftwareListOpti
 	}
 
 	// Vulnerability filters are only available in premium
-	if !lic.IsPremium() && (opt.MaximumCVSS > 0 || opt.MinimumCVSS > 0 || opt.KnownExploit) {
+	if !lic.IsPremium() && (opt.MaximumCVSS > 0 || opt.MinimumCVSS > 0 || opt.MaximumEPSS > 0 || opt.MinimumEPSS > 0 || opt.KnownExploit) {
 		return 0, fleet.ErrMissingLicense
 	}
+	if err := fleet.ValidateEPSSProbabilityRange(opt.MinimumEPSS, opt.MaximumEPSS); err != nil {
+		return 0, ctxerr.Wrap(ctx, err, "validate epss probability filters")
+	}
 
 	// required for vulnerability filters
 	if lic.IsPremium() {
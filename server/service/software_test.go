@@ -50,6 +50,37 @@ func TestService_ListSoftware(t *testing.T) {
 	assert.Nil(t, calledWithTeamID)
 	assert.Equal(t, fleet.ListOptions{PerPage: 11, Page: 2, OrderKey: "id", OrderDirection: fleet.OrderAscending}, calledWithOpt.ListOptions)
 	assert.True(t, calledWithOpt.WithHostCounts)
+
+	// EPSS filters are only available in premium
+	ds.ListSoftwareFuncInvoked = false
+	_, _, err = svc.ListSoftware(ctx, fleet.SoftwareListOptions{VulnerableOnly: true, MinimumEPSS: 0.5})
+	require.ErrorIs(t, err, fleet.ErrMissingLicense)
+	assert.False(t, ds.ListSoftwareFuncInvoked)
+}
+
+func TestService_ListSoftwareEPSSRange(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}})
+
+	ds.ListSoftwareFunc = func(ctx context.Context, opt fleet.SoftwareListOptions) ([]fleet.Software, *fleet.PaginationMetadata, error) {
+		return []fleet.Software{}, &fleet.PaginationMetadata{}, nil
+	}
+	ds.CountSoftwareFunc = func(ctx context.Context, opt fleet.SoftwareListOptions) (int, error) {
+		return 0, nil
+	}
+
+	var invalidErr *fleet.InvalidArgumentError
+	_, _, err := svc.ListSoftware(ctx, fleet.SoftwareListOptions{VulnerableOnly: true, MinimumEPSS: 0.9, MaximumEPSS: 0.1})
+	require.ErrorAs(t, err, &invalidErr)
+	require.ErrorContains(t, err, "must be less than or equal to max_epss_probability")
+	_, err = svc.CountSoftware(ctx, fleet.SoftwareListOptions{VulnerableOnly: true, MaximumEPSS: 1.1})
+	require.ErrorAs(t, err, &invalidErr)
+	require.False(t, ds.ListSoftwareFuncInvoked)
+	require.False(t, ds.CountSoftwareFuncInvoked)
+
+	_, _, err = svc.ListSoftware(ctx, fleet.SoftwareListOptions{VulnerableOnly: true, MinimumEPSS: 0.1, MaximumEPSS: 0.9})
+	require.NoError(t, err)
 }
 
 func TestServiceSoftwareInventoryAuth(t *testing.T) {
@@ -86,9 +86,12 @@ func (svc *Service) ListSoftwareTitles(
 		return nil, 0, nil, fleet.ErrMissingLicense
 	}
 
-	if !lic.IsPremium() && (opt.MaximumCVSS > 0 || opt.MinimumCVSS > 0 || opt.KnownExploit) {
+	if !lic.IsPremium() && (opt.MaximumCVSS > 0 || opt.MinimumCVSS > 0 || opt.MaximumEPSS > 0 || opt.MinimumEPSS > 0 || opt.KnownExploit) {
 		return nil, 0, nil, fleet.ErrMissingLicense
 	}
+	if err := fleet.ValidateEPSSProbabilityRange(opt.MinimumEPSS, opt.MaximumEPSS); err != nil {
+		return nil, 0, nil, ctxerr.Wrap(ctx, err, "validate epss probability filters")
+	}
 
 	// always include metadata for software titles
 	opt.ListOptions.IncludeMetadata = true
@@ -94,8 +94,9 @@ func getTeamEndpoint(ctx context.Context, request interface{}, svc fleet.Service
 					HostActivitiesWebhook:  team.Config.WebhookSettings.HostActivitiesWebhook,
 				},
 				Integrations: fleet.DefaultTeamIntegrations{
-					Jira:    team.Config.Integrations.Jira,
-					Zendesk: team.Config.Integrations.Zendesk,
+					Jira:       team.Config.Integrations.Jira,
+					Zendesk:    team.Config.Integrations.Zendesk,
+					ServiceNow: team.Config.Integrations.ServiceNow,
 				},
 			},
 		}
@@ -185,8 +186,9 @@ func modifyTeamEndpoint(ctx context.Context, request interface{}, svc fleet.Serv
 					HostActivitiesWebhook:  team.Config.WebhookSettings.HostActivitiesWebhook,
 				},
 				Integrations: fleet.DefaultTeamIntegrations{
-					Jira:    team.Config.Integrations.Jira,
-					Zendesk: team.Config.Integrations.Zendesk,
+					Jira:       team.Config.Integrations.Jira,
+					Zendesk:    team.Config.Integrations.Zendesk,
+					ServiceNow: team.Config.Integrations.ServiceNow,
 				},
 			},
 		}
@@ -343,14 +343,18 @@ func TestApplyTeamSpecs(t *testing.T) {
 		EnableSoftwareInventory: true,
 		AdditionalQueries:       ptr.RawMessage(json.RawMessage(`{"foo": "bar"}`)),
 		// Hydrated to match what teams loaded from the DB look like after
-		// migration 20260423161823 backfills historical_data. Without these
-		// defaults, the "Features for existing teams" cases below would
+		// migrations 20260423161823 and 20261017143015 backfill
+		// historical_data. Without these defaults, the "Features for
+		// existing teams" cases below would
 		// diff against the team-spec-applied defaults inside
 		// editTeamFromSpec and spuriously emit historical_data enable
 		// activities — which the test's activity callback doesn't expect.
 		HistoricalData: fleet.HistoricalDataSettings{
 			Uptime:          true,
 			Vulnerabilities: true,
+			Policies:        true,
+			MDMEnrollment:   true,
+			DiskEncryption:  true,
 		},
 	}
 
@@ -379,7 +383,7 @@ func TestApplyTeamSpecs(t *testing.T) {
 					EnableHostUsers:         true,
 					EnableSoftwareInventory: false,
 					AdditionalQueries:       nil,
-					HistoricalData:          fleet.HistoricalDataSettings{Uptime: true, Vulnerabilities: true},
+					HistoricalData:          baseFeatures.HistoricalData,
 				},
 			},
 			{
@@ -390,7 +394,7 @@ func TestApplyTeamSpecs(t *testing.T) {
 					EnableHostUsers:         false,
 					EnableSoftwareInventory: true,
 					AdditionalQueries:       nil,
-					HistoricalData:          fleet.HistoricalDataSettings{Uptime: true, Vulnerabilities: true},
+					HistoricalData:          baseFeatures.HistoricalData,
 				},
 			},
 			{
@@ -405,7 +409,7 @@ func TestApplyTeamSpecs(t *testing.T) {
 					EnableHostUsers:         false,
 					EnableSoftwareInventory: false,
 					AdditionalQueries:       ptr.RawMessage([]byte(`{"example": "query"}`)),
-					HistoricalData:          fleet.HistoricalDataSettings{Uptime: true, Vulnerabilities: true},
+					HistoricalData:          baseFeatures.HistoricalData,
 				},
 			},
 		}
@@ -83,6 +83,8 @@ type TestServerOpts struct {
 	SoftwareInstallStore            fleet.SoftwareInstallerStore
 	BootstrapPackageStore           fleet.MDMBootstrapPackageStore
 	SoftwareTitleIconStore          fleet.SoftwareTitleIconStore
+	CampaignResultsStore            fleet.CampaignResultsStore
+	OrbitNotifier                   fleet.OrbitNotifier
 	KeyValueStore                   fleet.KeyValueStore
 	EnableSCEPProxy                 bool
 	WithDEPWebview                  bool
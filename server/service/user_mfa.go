@@ -0,0 +1,900 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/license"
+	"github.com/fleetdm/fleet/v4/server/contexts/logging"
+	"github.com/fleetdm/fleet/v4/server/contexts/publicip"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mfa"
+)
+
+// mfaTOTPIssuer is the issuer displayed by authenticator apps for the TOTP
+// secrets of Fleet users, when the organization name is not set.
+const mfaTOTPIssuer = "Fleet"
+
+// mfaRequired returns true if the MFA policy requires the user to use a second
+// factor to log in with a password.
+func (svc *Service) mfaRequired(ctx context.Context, user *fleet.User) (bool, error) {
+	if user.SSOEnabled || user.APIOnly || !user.HasRoleAboveObserver() || !license.IsPremium(ctx) {
+		return false, nil
+	}
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return false, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	return appConfig.ServerSettings.RequireMFAAboveObserver, nil
+}
+
+// webAuthnRelyingParty returns the relying party of the WebAuthn ceremonies,
+// derived from the Fleet server URL.
+func (svc *Service) webAuthnRelyingParty(ctx context.Context) (mfa.RelyingParty, error) {
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return mfa.RelyingParty{}, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	name := appConfig.OrgInfo.OrgName
+	if name == "" {
+		name = mfaTOTPIssuer
+	}
+	rp, err := mfa.NewRelyingParty(name, appConfig.ServerSettings.ServerURL)
+	if err != nil {
+		return mfa.RelyingParty{}, ctxerr.Wrap(ctx, err, "webauthn relying party")
+	}
+	return rp, nil
+}
+
+// webAuthnUserHandle returns the opaque identifier of the user in the WebAuthn
+// credentials.
+func webAuthnUserHandle(userID uint) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(userID))
+}
+
+// webAuthnUser returns the user in the form used by the WebAuthn ceremonies.
+func webAuthnUser(user *fleet.User, creds []*fleet.WebAuthnCredential) mfa.User {
+	res := mfa.User{
+		Handle:      webAuthnUserHandle(user.ID),
+		Name:        user.Email,
+		DisplayName: user.Name,
+		Credentials: make([]mfa.Credential, 0, len(creds)),
+	}
+	for _, c := range creds {
+		res.Credentials = append(res.Credentials, c.Credential())
+	}
+	return res
+}
+
+// newTOTPEnrollment stores a new pending TOTP secret for the user and returns
+// it in the forms expected by authenticator apps.
+func (svc *Service) newTOTPEnrollment(ctx context.Context, user *fleet.User) (*fleet.TOTPEnrollment, error) {
+	secret, err := mfa.GenerateTOTPSecret()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "generate totp secret")
+	}
+	if err := svc.ds.SetUserPendingTOTPSecret(ctx, user.ID, secret); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "save pending totp secret")
+	}
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get app config")
+	}
+	issuer := appConfig.OrgInfo.OrgName
+	if issuer == "" {
+		issuer = mfaTOTPIssuer
+	}
+	uri := mfa.TOTPKeyURI(issuer, user.Email, secret)
+	qrCode, err := mfa.QRCodePNG(uri)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "generate totp qr code")
+	}
+	return &fleet.TOTPEnrollment{
+		Secret: mfa.EncodeTOTPSecret(secret),
+		URI:    uri,
+		QRCode: qrCode,
+	}, nil
+}
+
+// newWebAuthnCreationOptions starts the registration of a WebAuthn credential
+// for the user. It returns the challenge to store with the MFA challenge and
+// the options to pass to the browser.
+func (svc *Service) newWebAuthnCreationOptions(ctx context.Context, user *fleet.User) ([]byte, *mfa.CreationOptions, error) {
+	rp, err := svc.webAuthnRelyingParty(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	existing, err := svc.ds.ListUserWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "list webauthn credentials")
+	}
+	challenge, opts, err := rp.BeginRegistration(webAuthnUser(user, existing))
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "begin webauthn registration")
+	}
+	return challenge, opts, nil
+}
+
+// newLoginMFAChallenge starts the second step of the password login of a user
+// who enrolled a second factor.
+func (svc *Service) newLoginMFAChallenge(ctx context.Context, user *fleet.User) (*fleet.LoginMFAChallenge, error) {
+	res := &fleet.LoginMFAChallenge{}
+	if user.TOTPEnabled {
+		res.Methods = append(res.Methods, fleet.MFAMethodTOTP)
+	}
+
+	var webAuthnChallenge []byte
+	if user.WebAuthnEnabled {
+		creds, err := svc.ds.ListUserWebAuthnCredentials(ctx, user.ID)
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "list webauthn credentials")
+		}
+		rp, err := svc.webAuthnRelyingParty(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var opts *mfa.RequestOptions
+		webAuthnChallenge, opts, err = rp.BeginLogin(webAuthnUser(user, creds))
+		if err != nil {
+			return nil, ctxerr.Wrap(ctx, err, "begin webauthn login")
+		}
+		res.WebAuthn = opts
+		res.Methods = append(res.Methods, fleet.MFAMethodWebAuthn)
+	}
+
+	count, err := svc.ds.CountUserMFARecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "count recovery codes")
+	}
+	if count > 0 {
+		res.Methods = append(res.Methods, fleet.MFAMethodRecoveryCode)
+	}
+
+	challenge, err := svc.ds.NewUserMFAChallenge(ctx, user.ID, fleet.MFAChallengeLogin, webAuthnChallenge)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create mfa challenge")
+	}
+	res.Token = challenge.Token
+	return res, nil
+}
+
+// newEnrollmentMFAChallenge starts the second step of the password login of a
+// user who must enroll a second factor to log in.
+func (svc *Service) newEnrollmentMFAChallenge(ctx context.Context, user *fleet.User) (*fleet.LoginMFAChallenge, error) {
+	totp, err := svc.newTOTPEnrollment(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	webAuthnChallenge, opts, err := svc.newWebAuthnCreationOptions(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := svc.ds.NewUserMFAChallenge(ctx, user.ID, fleet.MFAChallengeEnrollment, webAuthnChallenge)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create mfa challenge")
+	}
+	return &fleet.LoginMFAChallenge{
+		Token:                challenge.Token,
+		Methods:              []string{fleet.MFAMethodTOTP, fleet.MFAMethodWebAuthn},
+		EnrollmentRequired:   true,
+		TOTP:                 totp,
+		WebAuthnRegistration: opts,
+	}, nil
+}
+
+// newRecoveryCodes replaces the recovery codes of the user and returns the
+// new codes, which are only stored hashed.
+func (svc *Service) newRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes, err := mfa.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "generate recovery codes")
+	}
+	hashes := make([][]byte, 0, len(codes))
+	for _, c := range codes {
+		hashes = append(hashes, mfa.HashRecoveryCode(c))
+	}
+	if err := svc.ds.ReplaceUserMFARecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "save recovery codes")
+	}
+	return codes, nil
+}
+
+// ensureRecoveryCodes generates recovery codes for the user if they have none,
+// e.g. after the enrollment of their first second factor. It returns nil if
+// the user already had recovery codes.
+func (svc *Service) ensureRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	count, err := svc.ds.CountUserMFARecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "count recovery codes")
+	}
+	if count > 0 {
+		return nil, nil
+	}
+	return svc.newRecoveryCodes(ctx, userID)
+}
+
+// confirmTOTP confirms the pending TOTP secret of the user with a first code
+// from their authenticator app.
+func (svc *Service) confirmTOTP(ctx context.Context, userID uint, code string) error {
+	totp, err := svc.ds.UserTOTP(ctx, userID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			return fleet.NewInvalidArgumentError("code", "no pending authenticator app enrollment")
+		}
+		return ctxerr.Wrap(ctx, err, "get totp secret")
+	}
+	if totp.ConfirmedAt != nil {
+		return fleet.NewInvalidArgumentError("code", "authenticator app already enabled")
+	}
+	step, ok := mfa.ValidateTOTP(totp.Secret, code, svc.clock.Now(), 0)
+	if !ok {
+		return fleet.NewInvalidArgumentError("code", "invalid authenticator app code")
+	}
+	if err := svc.ds.ConfirmUserTOTP(ctx, userID, step); err != nil {
+		return ctxerr.Wrap(ctx, err, "confirm totp secret")
+	}
+	return nil
+}
+
+// registerWebAuthnCredential verifies the credential created by the browser
+// for the WebAuthn challenge and stores it.
+func (svc *Service) registerWebAuthnCredential(ctx context.Context, user *fleet.User, challenge []byte, name string, resp mfa.RegistrationResponse) (*fleet.WebAuthnCredential, error) {
+	if len(challenge) == 0 {
+		return nil, fleet.NewInvalidArgumentError("webauthn_registration", "no pending security key registration")
+	}
+	if name == "" {
+		name = "Security key"
+	}
+	rp, err := svc.webAuthnRelyingParty(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cred, err := rp.FinishRegistration(webAuthnUser(user, nil), challenge, resp)
+	if err != nil {
+		return nil, fleet.NewInvalidArgumentError("webauthn_registration", err.Error())
+	}
+	stored, err := svc.ds.NewUserWebAuthnCredential(ctx, &fleet.WebAuthnCredential{
+		UserID:         user.ID,
+		Name:           name,
+		CredentialID:   cred.ID,
+		PublicKey:      cred.PublicKey,
+		SignCount:      cred.SignCount,
+		BackupEligible: cred.BackupEligible,
+	})
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "save webauthn credential")
+	}
+	return stored, nil
+}
+
+// verifySecondFactor verifies the second factor sent to complete a login
+// challenge.
+func (svc *Service) verifySecondFactor(ctx context.Context, user *fleet.User, challenge *fleet.UserMFAChallenge, v fleet.MFAVerification) error {
+	switch {
+	case v.TOTPCode != "":
+		if !user.TOTPEnabled {
+			return fleet.NewAuthFailedError("authenticator app not enabled")
+		}
+		totp, err := svc.ds.UserTOTP(ctx, user.ID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get totp secret")
+		}
+		step, ok := mfa.ValidateTOTP(totp.Secret, v.TOTPCode, svc.clock.Now(), totp.LastUsedStep)
+		if !ok {
+			return fleet.NewAuthFailedError("invalid authenticator app code")
+		}
+		used, err := svc.ds.UseUserTOTPStep(ctx, user.ID, step)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "record totp step")
+		}
+		if !used {
+			return fleet.NewAuthFailedError("authenticator app code already used")
+		}
+
+	case v.RecoveryCode != "":
+		ok, err := svc.ds.ConsumeUserMFARecoveryCode(ctx, user.ID, mfa.HashRecoveryCode(v.RecoveryCode))
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "consume recovery code")
+		}
+		if !ok {
+			return fleet.NewAuthFailedError("invalid recovery code")
+		}
+
+	case v.WebAuthn != nil:
+		if len(challenge.WebAuthnChallenge) == 0 {
+			return fleet.NewAuthFailedError("no webauthn challenge")
+		}
+		creds, err := svc.ds.ListUserWebAuthnCredentials(ctx, user.ID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "list webauthn credentials")
+		}
+		rp, err := svc.webAuthnRelyingParty(ctx)
+		if err != nil {
+			return err
+		}
+		used, err := rp.FinishLogin(webAuthnUser(user, creds), challenge.WebAuthnChallenge, *v.WebAuthn)
+		if err != nil {
+			return fleet.NewAuthFailedError(err.Error())
+		}
+		for _, c := range creds {
+			if bytes.Equal(c.CredentialID, used.ID) {
+				if err := svc.ds.UpdateUserWebAuthnCredentialSignCount(ctx, c.ID, used.SignCount); err != nil {
+					return ctxerr.Wrap(ctx, err, "update webauthn sign count")
+				}
+				break
+			}
+		}
+
+	default:
+		return fleet.NewAuthFailedError("second factor required")
+	}
+	return nil
+}
+
+// enrollSecondFactor enrolls the second factor sent to complete a login
+// challenge that requires enrollment. It returns the new recovery codes of
+// the user.
+func (svc *Service) enrollSecondFactor(ctx context.Context, user *fleet.User, challenge *fleet.UserMFAChallenge, v fleet.MFAVerification) ([]string, error) {
+	switch {
+	case v.TOTPCode != "":
+		if err := svc.confirmTOTP(ctx, user.ID, v.TOTPCode); err != nil {
+			return nil, err
+		}
+	case v.WebAuthnRegistration != nil:
+		if _, err := svc.registerWebAuthnCredential(ctx, user, challenge.WebAuthnChallenge, v.CredentialName, *v.WebAuthnRegistration); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fleet.NewAuthFailedError("second factor enrollment required")
+	}
+	return svc.ensureRecoveryCodes(ctx, user.ID)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Complete login with a second factor
+////////////////////////////////////////////////////////////////////////////////
+
+func completeLoginMFAEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.MFAVerification)
+	session, user, recoveryCodes, err := svc.CompleteLoginMFA(ctx, *req)
+	if err != nil {
+		return fleet.LoginResponse{Err: err}, nil
+	}
+	resp := newLoginResponse(ctx, svc, user, session)
+	resp.RecoveryCodes = recoveryCodes
+	return resp, nil
+}
+
+func (svc *Service) CompleteLoginMFA(ctx context.Context, v fleet.MFAVerification) (session *fleet.Session, user *fleet.User, recoveryCodes []string, err error) {
+	// skipauth: No user context available yet to authorize against.
+	svc.authz.SkipAuthorization(ctx)
+
+	defer func(start time.Time) { // force MFA failures to take at least a second for brute force/timing attack resistance
+		if err != nil {
+			time.Sleep(time.Until(start.Add(1 * time.Second)))
+		}
+	}(time.Now())
+
+	var factors int
+	for _, set := range []bool{v.TOTPCode != "", v.RecoveryCode != "", v.WebAuthn != nil, v.WebAuthnRegistration != nil} {
+		if set {
+			factors++
+		}
+	}
+	if factors != 1 {
+		return nil, nil, nil, fleet.NewInvalidArgumentError("mfa_token", "exactly one second factor must be provided")
+	}
+
+	challenge, err := svc.ds.UserMFAChallenge(ctx, v.Token)
+	if err != nil {
+		return nil, nil, nil, fleet.NewAuthFailedError(err.Error())
+	}
+	if challenge.Purpose != fleet.MFAChallengeLogin && challenge.Purpose != fleet.MFAChallengeEnrollment {
+		return nil, nil, nil, fleet.NewAuthFailedError("invalid mfa token")
+	}
+	user, err = svc.ds.UserByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, nil, nil, fleet.NewAuthFailedError(err.Error())
+	}
+	if user.SSOEnabled {
+		return nil, nil, nil, fleet.NewAuthFailedError("password login disabled for sso users")
+	}
+	if user.Disabled {
+		return nil, nil, nil, fleet.NewAuthFailedError("user is disabled")
+	}
+
+	var verifyErr error
+	if challenge.Purpose == fleet.MFAChallengeEnrollment {
+		recoveryCodes, verifyErr = svc.enrollSecondFactor(ctx, user, challenge, v)
+	} else {
+		verifyErr = svc.verifySecondFactor(ctx, user, challenge, v)
+	}
+	if verifyErr != nil {
+		if err := svc.ds.RecordUserMFAChallengeFailure(ctx, challenge.Token); err != nil {
+			logging.WithExtras(logging.WithNoUser(ctx),
+				"msg", "failed to record mfa challenge failure", "err", err,
+			)
+		}
+		if err := svc.NewActivity(
+			ctx, nil, fleet.ActivityTypeUserFailedLogin{
+				Email:    user.Email,
+				PublicIP: publicip.FromContext(ctx),
+			}); err != nil {
+			logging.WithExtras(logging.WithNoUser(ctx),
+				"msg", "failed to generate failed login activity",
+			)
+		}
+		if _, ok := errors.AsType[*fleet.AuthFailedError](verifyErr); ok {
+			return nil, nil, nil, verifyErr
+		}
+		return nil, nil, nil, fleet.NewAuthFailedError(verifyErr.Error())
+	}
+
+	// consuming the challenge fails if it was concurrently used to log in
+	if err = svc.ds.ConsumeUserMFAChallenge(ctx, challenge.Token); err != nil {
+		return nil, nil, nil, fleet.NewAuthFailedError(err.Error())
+	}
+
+	logging.WithLevel(logging.WithExtras(logging.WithNoUser(ctx),
+		"op", "login_mfa",
+		"email", user.Email,
+		"public_ip", publicip.FromContext(ctx),
+	), slog.LevelInfo)
+
+	// Do not allow login if on Fleet Free and the user has a Premium-only role.
+	if !license.IsPremium(ctx) {
+		if fleet.PremiumRolesPresent(user.GlobalRole, user.Teams) {
+			return nil, nil, nil, fleet.ErrMissingLicense
+		}
+	}
+
+	session, err = svc.makeSession(ctx, user.ID)
+	if err != nil {
+		return nil, nil, nil, fleet.NewAuthFailedError(err.Error())
+	}
+
+	if err = svc.NewActivity(
+		ctx, user, fleet.ActivityTypeUserLoggedIn{
+			PublicIP: publicip.FromContext(ctx),
+		}); err != nil {
+		return nil, nil, nil, err
+	}
+	return session, user, recoveryCodes, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Get the second factors of the current user
+////////////////////////////////////////////////////////////////////////////////
+
+type getUserMFAResponse struct {
+	*fleet.UserMFAStatus
+	Err error `json:"error,omitempty"`
+}
+
+func (r getUserMFAResponse) Error() error { return r.Err }
+
+func getUserMFAEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	status, err := svc.GetUserMFA(ctx)
+	if err != nil {
+		return getUserMFAResponse{Err: err}, nil
+	}
+	return getUserMFAResponse{UserMFAStatus: status}, nil
+}
+
+// mfaViewer returns the current user, once authorized to manage their own
+// second factors.
+func (svc *Service) mfaViewer(ctx context.Context) (*fleet.User, error) {
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	if err := svc.authz.Authorize(ctx, vc.User, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	return vc.User, nil
+}
+
+func (svc *Service) GetUserMFA(ctx context.Context) (*fleet.UserMFAStatus, error) {
+	user, err := svc.mfaViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	creds, err := svc.ds.ListUserWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list webauthn credentials")
+	}
+	count, err := svc.ds.CountUserMFARecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "count recovery codes")
+	}
+	required, err := svc.mfaRequired(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return &fleet.UserMFAStatus{
+		TOTPEnabled:            user.TOTPEnabled,
+		WebAuthnCredentials:    creds,
+		RecoveryCodesRemaining: count,
+		EmailEnabled:           user.MFAEnabled,
+		Required:               required,
+	}, nil
+}
+
+// checkCanEnrollMFA returns an error if second factors don't apply to the
+// user.
+func checkCanEnrollMFA(user *fleet.User) error {
+	if user.SSOEnabled {
+		return fleet.NewInvalidArgumentError("user", "second factors are not used by single sign on users")
+	}
+	return nil
+}
+
+// checkCanRemoveMFA returns an error if the MFA policy requires the user to
+// keep a second factor and remaining is false.
+func (svc *Service) checkCanRemoveMFA(ctx context.Context, user *fleet.User, password string, remaining bool) error {
+	if err := user.ValidatePassword(password); err != nil {
+		return fleet.NewPermissionError("incorrect password")
+	}
+	if remaining || user.MFAEnabled {
+		return nil
+	}
+	required, err := svc.mfaRequired(ctx, user)
+	if err != nil {
+		return err
+	}
+	if required {
+		return fleet.NewInvalidArgumentError("user", "a second factor is required for your role, enroll another one before removing this one")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Enroll an authenticator app
+////////////////////////////////////////////////////////////////////////////////
+
+type beginTOTPEnrollmentResponse struct {
+	*fleet.TOTPEnrollment
+	Err error `json:"error,omitempty"`
+}
+
+func (r beginTOTPEnrollmentResponse) Error() error { return r.Err }
+
+func beginTOTPEnrollmentEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	enrollment, err := svc.BeginTOTPEnrollment(ctx)
+	if err != nil {
+		return beginTOTPEnrollmentResponse{Err: err}, nil
+	}
+	return beginTOTPEnrollmentResponse{TOTPEnrollment: enrollment}, nil
+}
+
+func (svc *Service) BeginTOTPEnrollment(ctx context.Context) (*fleet.TOTPEnrollment, error) {
+	user, err := svc.mfaViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCanEnrollMFA(user); err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+	if user.TOTPEnabled {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("totp", "authenticator app already enabled"))
+	}
+	return svc.newTOTPEnrollment(ctx, user)
+}
+
+type confirmTOTPEnrollmentRequest struct {
+	Code string `json:"code"`
+}
+
+type mfaRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+	Err           error    `json:"error,omitempty"`
+}
+
+func (r mfaRecoveryCodesResponse) Error() error { return r.Err }
+
+func confirmTOTPEnrollmentEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*confirmTOTPEnrollmentRequest)
+	codes, err := svc.ConfirmTOTPEnrollment(ctx, req.Code)
+	if err != nil {
+		return mfaRecoveryCodesResponse{Err: err}, nil
+	}
+	return mfaRecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (svc *Service) ConfirmTOTPEnrollment(ctx context.Context, code string) ([]string, error) {
+	user, err := svc.mfaViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCanEnrollMFA(user); err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+	if err := svc.confirmTOTP(ctx, user.ID, code); err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+	return svc.ensureRecoveryCodes(ctx, user.ID)
+}
+
+type mfaPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+type disableTOTPResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r disableTOTPResponse) Error() error { return r.Err }
+
+func disableTOTPEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*mfaPasswordRequest)
+	err := svc.DisableTOTP(ctx, req.Password)
+	return disableTOTPResponse{Err: err}, nil
+}
+
+func (svc *Service) DisableTOTP(ctx context.Context, password string) error {
+	user, err := svc.mfaViewer(ctx)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ctxerr.Wrap(ctx, newNotFoundError())
+	}
+	if err := svc.checkCanRemoveMFA(ctx, user, password, user.WebAuthnEnabled); err != nil {
+		return ctxerr.Wrap(ctx, err)
+	}
+	if err := svc.ds.DeleteUserTOTP(ctx, user.ID); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete totp secret")
+	}
+	if !user.WebAuthnEnabled {
+		// recovery codes are only useful along with a second factor
+		if err := svc.ds.ReplaceUserMFARecoveryCodes(ctx, user.ID, nil); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete recovery codes")
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Register a security key or passkey
+////////////////////////////////////////////////////////////////////////////////
+
+type beginWebAuthnRegistrationResponse struct {
+	*fleet.WebAuthnRegistration
+	Err error `json:"error,omitempty"`
+}
+
+func (r beginWebAuthnRegistrationResponse) Error() error { return r.Err }
+
+func beginWebAuthnRegistrationEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	reg, err := svc.BeginWebAuthnRegistration(ctx)
+	if err != nil {
+		return beginWebAuthnRegistrationResponse{Err: err}, nil
+	}
+	return beginWebAuthnRegistrationResponse{WebAuthnRegistration: reg}, nil
+}
+
+func (svc *Service) BeginWebAuthnRegistration(ctx context.Context) (*fleet.WebAuthnRegistration, error) {
+	user, err := svc.mfaViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCanEnrollMFA(user); err != nil {
+		return nil, ctxerr.Wrap(ctx, err)
+	}
+	webAuthnChallenge, opts, err := svc.newWebAuthnCreationOptions(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := svc.ds.NewUserMFAChallenge(ctx, user.ID, fleet.MFAChallengeWebAuthnRegistration, webAuthnChallenge)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create mfa challenge")
+	}
+	return &fleet.WebAuthnRegistration{
+		Token:   challenge.Token,
+		Options: *opts,
+	}, nil
+}
+
+type finishWebAuthnRegistrationRequest struct {
+	Token      string                   `json:"mfa_token"`
+	Name       string                   `json:"name"`
+	Credential mfa.RegistrationResponse `json:"credential"`
+}
+
+type finishWebAuthnRegistrationResponse struct {
+	Credential    *fleet.WebAuthnCredential `json:"credential,omitempty"`
+	RecoveryCodes []string                  `json:"recovery_codes,omitempty"`
+	Err           error                     `json:"error,omitempty"`
+}
+
+func (r finishWebAuthnRegistrationResponse) Error() error { return r.Err }
+
+func finishWebAuthnRegistrationEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*finishWebAuthnRegistrationRequest)
+	cred, codes, err := svc.FinishWebAuthnRegistration(ctx, req.Token, req.Name, req.Credential)
+	if err != nil {
+		return finishWebAuthnRegistrationResponse{Err: err}, nil
+	}
+	return finishWebAuthnRegistrationResponse{Credential: cred, RecoveryCodes: codes}, nil
+}
+
+func (svc *Service) FinishWebAuthnRegistration(ctx context.Context, token, name string, resp mfa.RegistrationResponse) (*fleet.WebAuthnCredential, []string, error) {
+	user, err := svc.mfaViewer(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkCanEnrollMFA(user); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err)
+	}
+
+	challenge, err := svc.ds.UserMFAChallenge(ctx, token)
+	if err != nil && !fleet.IsNotFound(err) {
+		return nil, nil, ctxerr.Wrap(ctx, err, "get mfa challenge")
+	}
+	if challenge == nil || challenge.UserID != user.ID || challenge.Purpose != fleet.MFAChallengeWebAuthnRegistration {
+		return nil, nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("mfa_token", "invalid or expired registration"))
+	}
+
+	cred, err := svc.registerWebAuthnCredential(ctx, user, challenge.WebAuthnChallenge, name, resp)
+	if err != nil {
+		if _, ok := errors.AsType[*fleet.InvalidArgumentError](err); ok {
+			if err := svc.ds.RecordUserMFAChallengeFailure(ctx, token); err != nil {
+				return nil, nil, ctxerr.Wrap(ctx, err, "record mfa challenge failure")
+			}
+		}
+		return nil, nil, ctxerr.Wrap(ctx, err)
+	}
+	if err := svc.ds.ConsumeUserMFAChallenge(ctx, token); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "consume mfa challenge")
+	}
+	codes, err := svc.ensureRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cred, codes, nil
+}
+
+type deleteWebAuthnCredentialRequest struct {
+	ID       uint   `url:"id"`
+	Password string `json:"password"`
+}
+
+type deleteWebAuthnCredentialResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteWebAuthnCredentialResponse) Error() error { return r.Err }
+
+func deleteWebAuthnCredentialEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*deleteWebAuthnCredentialRequest)
+	err := svc.DeleteWebAuthnCredential(ctx, req.ID, req.Password)
+	return deleteWebAuthnCredentialResponse{Err: err}, nil
+}
+
+func (svc *Service) DeleteWebAuthnCredential(ctx context.Context, id uint, password string) error {
+	user, err := svc.mfaViewer(ctx)
+	if err != nil {
+		return err
+	}
+
+	creds, err := svc.ds.ListUserWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "list webauthn credentials")
+	}
+	var found bool
+	for _, c := range creds {
+		if c.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ctxerr.Wrap(ctx, newNotFoundError())
+	}
+	remaining := user.TOTPEnabled || len(creds) > 1
+	if err := svc.checkCanRemoveMFA(ctx, user, password, remaining); err != nil {
+		return ctxerr.Wrap(ctx, err)
+	}
+
+	if err := svc.ds.DeleteUserWebAuthnCredential(ctx, user.ID, id); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete webauthn credential")
+	}
+	if !remaining {
+		// recovery codes are only useful along with a second factor
+		if err := svc.ds.ReplaceUserMFARecoveryCodes(ctx, user.ID, nil); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete recovery codes")
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Regenerate recovery codes
+////////////////////////////////////////////////////////////////////////////////
+
+func regenerateMFARecoveryCodesEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*mfaPasswordRequest)
+	codes, err := svc.RegenerateMFARecoveryCodes(ctx, req.Password)
+	if err != nil {
+		return mfaRecoveryCodesResponse{Err: err}, nil
+	}
+	return mfaRecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (svc *Service) RegenerateMFARecoveryCodes(ctx context.Context, password string) ([]string, error) {
+	user, err := svc.mfaViewer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := user.ValidatePassword(password); err != nil {
+		return nil, ctxerr.Wrap(ctx, fleet.NewPermissionError("incorrect password"))
+	}
+	if !user.HasSecondFactor() {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("user", "recovery codes require an authenticator app, security key or passkey"))
+	}
+	return svc.newRecoveryCodes(ctx, user.ID)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Reset the second factors of a user
+////////////////////////////////////////////////////////////////////////////////
+
+type resetUserMFARequest struct {
+	ID uint `url:"id"`
+}
+
+type resetUserMFAResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r resetUserMFAResponse) Error() error { return r.Err }
+
+func resetUserMFAEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*resetUserMFARequest)
+	err := svc.ResetUserMFA(ctx, req.ID)
+	return resetUserMFAResponse{Err: err}, nil
+}
+
+func (svc *Service) ResetUserMFA(ctx context.Context, userID uint) error {
+	// Only global admins can change the credentials of other users.
+	if err := svc.authz.Authorize(ctx, &fleet.User{ID: userID}, fleet.ActionChangePassword); err != nil {
+		return err
+	}
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return fleet.ErrNoContext
+	}
+	if vc.UserID() == userID {
+		return ctxerr.Wrap(ctx, fleet.NewPermissionError("cannot reset your own second factors"))
+	}
+
+	user, err := svc.ds.UserByID(ctx, userID)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get user")
+	}
+	if err := svc.ds.ResetUserMFA(ctx, user.ID); err != nil {
+		return ctxerr.Wrap(ctx, err, "reset user mfa")
+	}
+
+	if err := svc.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeResetUserMFA{
+			UserID:    user.ID,
+			UserName:  user.Name,
+			UserEmail: user.Email,
+		},
+	); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for user mfa reset")
+	}
+	return nil
+}
@@ -0,0 +1,503 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/WatchBeam/clock"
+	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mfa"
+	"github.com/fleetdm/fleet/v4/server/mfa/mfatest"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mfaTestStore is an in-memory implementation of the datastore methods used
+// by the MFA flows.
+type mfaTestStore struct {
+	users         map[uint]*fleet.User
+	totp          map[uint]*fleet.UserTOTP
+	webAuthn      []*fleet.WebAuthnCredential
+	recoveryCodes map[uint][][]byte
+	challenges    map[string]*fleet.UserMFAChallenge
+	appConfig     *fleet.AppConfig
+}
+
+func newMFATestStore(ds *mock.Store, users ...*fleet.User) *mfaTestStore {
+	s := &mfaTestStore{
+		users:         make(map[uint]*fleet.User),
+		totp:          make(map[uint]*fleet.UserTOTP),
+		recoveryCodes: make(map[uint][][]byte),
+		challenges:    make(map[string]*fleet.UserMFAChallenge),
+		appConfig: &fleet.AppConfig{
+			OrgInfo:        fleet.OrgInfo{OrgName: "Acme"},
+			ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.example.com"},
+		},
+	}
+	for _, u := range users {
+		s.users[u.ID] = u
+	}
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return s.appConfig, nil
+	}
+	ds.UserByEmailFunc = func(ctx context.Context, email string) (*fleet.User, error) {
+		for _, u := range s.users {
+			if u.Email == email {
+				return s.user(u), nil
+			}
+		}
+		return nil, newNotFoundError()
+	}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		if u, ok := s.users[id]; ok {
+			return s.user(u), nil
+		}
+		return nil, newNotFoundError()
+	}
+	ds.NewSessionFunc = func(ctx context.Context, userID uint, sessionKeySize int) (*fleet.Session, error) {
+		return &fleet.Session{UserID: userID, Key: "session-key"}, nil
+	}
+
+	ds.SetUserPendingTOTPSecretFunc = func(ctx context.Context, userID uint, secret []byte) error {
+		if t := s.totp[userID]; t != nil && t.ConfirmedAt != nil {
+			return nil
+		}
+		s.totp[userID] = &fleet.UserTOTP{UserID: userID, Secret: secret}
+		return nil
+	}
+	ds.UserTOTPFunc = func(ctx context.Context, userID uint) (*fleet.UserTOTP, error) {
+		if t := s.totp[userID]; t != nil {
+			return t, nil
+		}
+		return nil, newNotFoundError()
+	}
+	ds.ConfirmUserTOTPFunc = func(ctx context.Context, userID uint, step int64) error {
+		t := s.totp[userID]
+		if t == nil || t.ConfirmedAt != nil {
+			return newNotFoundError()
+		}
+		t.ConfirmedAt = ptr.Time(time.Now())
+		t.LastUsedStep = step
+		return nil
+	}
+	ds.UseUserTOTPStepFunc = func(ctx context.Context, userID uint, step int64) (bool, error) {
+		t := s.totp[userID]
+		if t == nil || t.LastUsedStep >= step {
+			return false, nil
+		}
+		t.LastUsedStep = step
+		return true, nil
+	}
+	ds.DeleteUserTOTPFunc = func(ctx context.Context, userID uint) error {
+		delete(s.totp, userID)
+		return nil
+	}
+
+	ds.ReplaceUserMFARecoveryCodesFunc = func(ctx context.Context, userID uint, codeHashes [][]byte) error {
+		s.recoveryCodes[userID] = codeHashes
+		return nil
+	}
+	ds.CountUserMFARecoveryCodesFunc = func(ctx context.Context, userID uint) (int, error) {
+		return len(s.recoveryCodes[userID]), nil
+	}
+	ds.ConsumeUserMFARecoveryCodeFunc = func(ctx context.Context, userID uint, codeHash []byte) (bool, error) {
+		for i, h := range s.recoveryCodes[userID] {
+			if bytes.Equal(h, codeHash) {
+				s.recoveryCodes[userID] = append(s.recoveryCodes[userID][:i], s.recoveryCodes[userID][i+1:]...)
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	ds.NewUserWebAuthnCredentialFunc = func(ctx context.Context, cred *fleet.WebAuthnCredential) (*fleet.WebAuthnCredential, error) {
+		cred.ID = uint(len(s.webAuthn) + 1) //nolint:gosec // dismiss G115
+		s.webAuthn = append(s.webAuthn, cred)
+		return cred, nil
+	}
+	ds.ListUserWebAuthnCredentialsFunc = func(ctx context.Context, userID uint) ([]*fleet.WebAuthnCredential, error) {
+		var res []*fleet.WebAuthnCredential
+		for _, c := range s.webAuthn {
+			if c.UserID == userID {
+				res = append(res, c)
+			}
+		}
+		return res, nil
+	}
+	ds.UpdateUserWebAuthnCredentialSignCountFunc = func(ctx context.Context, id uint, signCount uint32) error {
+		for _, c := range s.webAuthn {
+			if c.ID == id {
+				c.SignCount = signCount
+			}
+		}
+		return nil
+	}
+	ds.DeleteUserWebAuthnCredentialFunc = func(ctx context.Context, userID, id uint) error {
+		for i, c := range s.webAuthn {
+			if c.ID == id && c.UserID == userID {
+				s.webAuthn = append(s.webAuthn[:i], s.webAuthn[i+1:]...)
+				return nil
+			}
+		}
+		return newNotFoundError()
+	}
+	ds.ResetUserMFAFunc = func(ctx context.Context, userID uint) error {
+		delete(s.totp, userID)
+		delete(s.recoveryCodes, userID)
+		var creds []*fleet.WebAuthnCredential
+		for _, c := range s.webAuthn {
+			if c.UserID != userID {
+				creds = append(creds, c)
+			}
+		}
+		s.webAuthn = creds
+		return nil
+	}
+
+	ds.NewUserMFAChallengeFunc = func(ctx context.Context, userID uint, purpose string, webAuthnChallenge []byte) (*fleet.UserMFAChallenge, error) {
+		c := &fleet.UserMFAChallenge{
+			Token:             "token-" + purpose + "-" + string(rune('a'+len(s.challenges))),
+			UserID:            userID,
+			Purpose:           purpose,
+			WebAuthnChallenge: webAuthnChallenge,
+			CreatedAt:         time.Now(),
+		}
+		s.challenges[c.Token] = c
+		return c, nil
+	}
+	ds.UserMFAChallengeFunc = func(ctx context.Context, token string) (*fleet.UserMFAChallenge, error) {
+		if c, ok := s.challenges[token]; ok && c.FailedAttempts < fleet.MFAChallengeMaxFailures {
+			return c, nil
+		}
+		return nil, newNotFoundError()
+	}
+	ds.RecordUserMFAChallengeFailureFunc = func(ctx context.Context, token string) error {
+		if c, ok := s.challenges[token]; ok {
+			c.FailedAttempts++
+		}
+		return nil
+	}
+	ds.ConsumeUserMFAChallengeFunc = func(ctx context.Context, token string) error {
+		if _, ok := s.challenges[token]; !ok {
+			return newNotFoundError()
+		}
+		delete(s.challenges, token)
+		return nil
+	}
+	return s
+}
+
+// user sets the computed MFA columns of the user, as the datastore does when
+// loading users.
+func (s *mfaTestStore) user(u *fleet.User) *fleet.User {
+	u.TOTPEnabled = s.totp[u.ID] != nil && s.totp[u.ID].ConfirmedAt != nil
+	u.WebAuthnEnabled = false
+	for _, c := range s.webAuthn {
+		if c.UserID == u.ID {
+			u.WebAuthnEnabled = true
+		}
+	}
+	return u
+}
+
+// loginChallenge logs in with a password and returns the MFA challenge.
+func loginChallenge(t *testing.T, ctx context.Context, svc fleet.Service, email string) *fleet.LoginMFAChallenge {
+	resp, err := loginEndpoint(ctx, &fleet.LoginRequest{Email: email, Password: test.GoodPassword}, svc)
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+	mfaResp, ok := resp.(loginMfaResponse)
+	require.True(t, ok, "expected a MFA response, got %T", resp)
+	require.Equal(t, http.StatusAccepted, mfaResp.Status())
+	require.NotNil(t, mfaResp.LoginMFAChallenge)
+	require.NotEmpty(t, mfaResp.Token)
+	return mfaResp.LoginMFAChallenge
+}
+
+func newMFATestUser(t *testing.T, id uint, email string, globalRole string) *fleet.User {
+	u := &fleet.User{ID: id, Email: email, Name: email, GlobalRole: ptr.String(globalRole)}
+	require.NoError(t, u.SetPassword(test.GoodPassword, 10, 10))
+	return u
+}
+
+func TestLoginWithTOTP(t *testing.T) {
+	ds := new(mock.Store)
+	mockClock := clock.NewMockClock()
+	opts := &TestServerOpts{Clock: mockClock}
+	svc, ctx := newTestService(t, ds, nil, nil, opts)
+
+	user := newMFATestUser(t, 1, "totp@example.com", fleet.RoleMaintainer)
+	store := newMFATestStore(ds, user)
+	userCtx := viewer.NewContext(ctx, viewer.Viewer{User: user})
+
+	// enroll an authenticator app
+	enrollment, err := svc.BeginTOTPEnrollment(userCtx)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/Acme:totp@example.com?")
+	assert.NotEmpty(t, enrollment.QRCode)
+	secret := store.totp[user.ID].Secret
+	assert.Equal(t, mfa.EncodeTOTPSecret(secret), enrollment.Secret)
+
+	_, err = svc.ConfirmTOTPEnrollment(userCtx, "000000")
+	require.Error(t, err)
+	codes, err := svc.ConfirmTOTPEnrollment(userCtx, mfa.TOTPCode(secret, mockClock.Now()))
+	require.NoError(t, err)
+	require.Len(t, codes, mfa.RecoveryCodeCount)
+	require.True(t, store.user(user).TOTPEnabled)
+
+	_, err = svc.BeginTOTPEnrollment(userCtx)
+	require.ErrorContains(t, err, "authenticator app already enabled")
+
+	var activities []string
+	opts.ActivityMock.NewActivityFunc = func(_ context.Context, _ *activity_api.User, activity activity_api.ActivityDetails) error {
+		activities = append(activities, activity.ActivityName())
+		return nil
+	}
+
+	// the password login now requires a second factor
+	challenge := loginChallenge(t, ctx, svc, user.Email)
+	assert.Equal(t, []string{fleet.MFAMethodTOTP, fleet.MFAMethodRecoveryCode}, challenge.Methods)
+	assert.Nil(t, challenge.WebAuthn)
+	assert.False(t, challenge.EnrollmentRequired)
+	assert.Equal(t, []string{fleet.ActivityTypeUserMFARequested{}.ActivityName()}, activities)
+
+	// the code used to confirm the enrollment can't be replayed
+	_, _, _, err = svc.CompleteLoginMFA(ctx, fleet.MFAVerification{Token: challenge.Token, TOTPCode: mfa.TOTPCode(secret, mockClock.Now())})
+	var authErr *fleet.AuthFailedError
+	require.ErrorAs(t, err, &authErr)
+	assert.Equal(t, uint(1), store.challenges[challenge.Token].FailedAttempts)
+	assert.Contains(t, activities, fleet.ActivityTypeUserFailedLogin{}.ActivityName())
+
+	// exactly one second factor is accepted
+	_, _, _, err = svc.CompleteLoginMFA(ctx, fleet.MFAVerification{Token: challenge.Token, TOTPCode: "123456", RecoveryCode: codes[0]})
+	require.ErrorContains(t, err, "exactly one second factor must be provided")
+
+	mockClock.AddTime(fleet.MFAChallengeTTL / 20)
+	session, loggedIn, newCodes, err := svc.CompleteLoginMFA(ctx, fleet.MFAVerification{Token: challenge.Token, TOTPCode: mfa.TOTPCode(secret, mockClock.Now())})
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, session.UserID)
+	assert.Equal(t, user.ID, loggedIn.ID)
+	assert.Nil(t, newCodes)
+	assert.Equal(t, fleet.ActivityTypeUserLoggedIn{}.ActivityName(), activities[len(activities)-1])
+	assert.NotContains(t, store.challenges, challenge.Token)
+
+	// a recovery code can be used once instead
+	challenge = loginChallenge(t, ctx, svc, user.Email)
+	resp, err := completeLoginMFAEndpoint(ctx, &fleet.MFAVerification{Token: challenge.Token, RecoveryCode: "  " + codes[0] + " "}, svc)
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+	assert.Equal(t, "session-key", resp.(fleet.LoginResponse).Token)
+	assert.Len(t, store.recoveryCodes[user.ID], mfa.RecoveryCodeCount-1)
+
+	challenge = loginChallenge(t, ctx, svc, user.Email)
+	_, _, _, err = svc.CompleteLoginMFA(ctx, fleet.MFAVerification{Token: challenge.Token, RecoveryCode: codes[0]})
+	require.ErrorAs(t, err, &authErr)
+
+	// the challenge is invalidated after too many failures
+	for range fleet.MFAChallengeMaxFailures - 1 {
+		_, _, _, err = svc.CompleteLoginMFA(ctx, fleet.MFAVerification{Token: challenge.Token, TOTPCode: "000000"})
+		require.ErrorAs(t, err, &authErr)
+	}
+	_, _, _, err = svc.CompleteLoginMFA(ctx, fleet.MFAVerification{Token: challenge.Token, RecoveryCode: codes[1]})
+	require.ErrorAs(t, err, &authErr)
+	assert.Len(t, store.recoveryCodes[user.ID], mfa.RecoveryCodeCount-1)
+
+	// disabling the authenticator app requires the password and removes the
+	// recovery codes
+	err = svc.DisableTOTP(userCtx, "wrong")
+	require.ErrorContains(t, err, "incorrect password")
+	require.NoError(t, svc.DisableTOTP(userCtx, test.GoodPassword))
+	assert.Empty(t, store.totp)
+	assert.Empty(t, store.recoveryCodes[user.ID])
+
+	resp, err = loginEndpoint(ctx, &fleet.LoginRequest{Email: user.Email, Password: test.GoodPassword}, svc)
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+	assert.Equal(t, "session-key", resp.(fleet.LoginResponse).Token)
+}
+
+func TestLoginWithWebAuthn(t *testing.T) {
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	user := newMFATestUser(t, 1, "webauthn@example.com", fleet.RoleObserver)
+	store := newMFATestStore(ds, user)
+	userCtx := viewer.NewContext(ctx, viewer.Viewer{User: user})
+	authn := mfatest.NewAuthenticator(t, "https://fleet.example.com")
+
+	reg, err := svc.BeginWebAuthnRegistration(userCtx)
+	require.NoError(t, err)
+	assert.Equal(t, "fleet.example.com", reg.Options.RelyingParty.ID)
+	assert.Equal(t, "Acme", reg.Options.RelyingParty.Name)
+
+	// credentials created for another origin are rejected
+	other := mfatest.NewAuthenticator(t, "https://evil.example.com")
+	_, _, err = svc.FinishWebAuthnRegistration(userCtx, reg.Token, "Evil", other.Register(reg.Options))
+	require.Error(t, err)
+	assert.Equal(t, uint(1), store.challenges[reg.Token].FailedAttempts)
+
+	cred, codes, err := svc.FinishWebAuthnRegistration(userCtx, reg.Token, "YubiKey", authn.Register(reg.Options))
+	require.NoError(t, err)
+	assert.Equal(t, "YubiKey", cred.Name)
+	assert.Len(t, codes, mfa.RecoveryCodeCount)
+	require.True(t, store.user(user).WebAuthnEnabled)
+
+	// the registration can't be completed twice
+	_, _, err = svc.FinishWebAuthnRegistration(userCtx, reg.Token, "YubiKey", authn.Register(reg.Options))
+	require.ErrorContains(t, err, "invalid or expired registration")
+
+	status, err := svc.GetUserMFA(userCtx)
+	require.NoError(t, err)
+	assert.False(t, status.TOTPEnabled)
+	assert.False(t, status.Required)
+	assert.Equal(t, mfa.RecoveryCodeCount, status.RecoveryCodesRemaining)
+	require.Len(t, status.WebAuthnCredentials, 1)
+	assert.Equal(t, "YubiKey", status.WebAuthnCredentials[0].Name)
+
+	challenge := loginChallenge(t, ctx, svc, user.Email)
+	assert.Equal(t, []string{fleet.MFAMethodWebAuthn, fleet.MFAMethodRecoveryCode}, challenge.Methods)
+	require.NotNil(t, challenge.WebAuthn)
+	require.Len(t, challenge.WebAuthn.AllowedCredentials, 1)
+
+	// an assertion signed with another key is rejected
+	forged := mfatest.NewAuthenticator(t, "https://fleet.example.com")
+	forged.CredentialID = authn.CredentialID
+	forgedAssertion := forged.Assert(*challenge.WebAuthn)
+	_, _, _, err = svc.CompleteLoginMFA(ctx, fleet.MFAVerification{Token: challenge.Token, WebAuthn: &forgedAssertion})
+	var authErr *fleet.AuthFailedError
+	require.ErrorAs(t, err, &authErr)
+
+	assertion := authn.Assert(*challenge.WebAuthn)
+	session, _, _, err := svc.CompleteLoginMFA(ctx, fleet.MFAVerification{Token: challenge.Token, WebAuthn: &assertion})
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, session.UserID)
+	assert.Equal(t, authn.SignCount, store.webAuthn[0].SignCount)
+
+	// the last credential can be removed when MFA is not required
+	err = svc.DeleteWebAuthnCredential(userCtx, cred.ID+1, test.GoodPassword)
+	var nfe fleet.NotFoundError
+	require.ErrorAs(t, err, &nfe)
+	require.NoError(t, svc.DeleteWebAuthnCredential(userCtx, cred.ID, test.GoodPassword))
+	assert.Empty(t, store.webAuthn)
+	assert.Empty(t, store.recoveryCodes[user.ID])
+}
+
+func TestLoginMFAEnrollmentRequired(t *testing.T) {
+	ds := new(mock.Store)
+	mockClock := clock.NewMockClock()
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{
+		License: &fleet.LicenseInfo{Tier: fleet.TierPremium},
+		Clock:   mockClock,
+	})
+
+	maintainer := newMFATestUser(t, 1, "maintainer@example.com", fleet.RoleMaintainer)
+	observer := newMFATestUser(t, 2, "observer@example.com", fleet.RoleObserver)
+	teamAdmin := newMFATestUser(t, 3, "teamadmin@example.com", fleet.RoleObserver)
+	teamAdmin.Teams = []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}
+	apiOnly := newMFATestUser(t, 4, "api@example.com", fleet.RoleAdmin)
+	apiOnly.APIOnly = true
+	store := newMFATestStore(ds, maintainer, observer, teamAdmin, apiOnly)
+	store.appConfig.ServerSettings.RequireMFAAboveObserver = true
+	ds.TeamsSummaryFunc = func(ctx context.Context) ([]*fleet.TeamSummary, error) {
+		return nil, nil
+	}
+
+	for _, u := range []*fleet.User{observer, apiOnly} {
+		resp, err := loginEndpoint(ctx, &fleet.LoginRequest{Email: u.Email, Password: test.GoodPassword}, svc)
+		require.NoError(t, err)
+		require.NoError(t, resp.Error())
+		assert.IsType(t, fleet.LoginResponse{}, resp, u.Email)
+	}
+
+	// enroll an authenticator app to log in
+	challenge := loginChallenge(t, ctx, svc, maintainer.Email)
+	assert.True(t, challenge.EnrollmentRequired)
+	assert.Equal(t, []string{fleet.MFAMethodTOTP, fleet.MFAMethodWebAuthn}, challenge.Methods)
+	require.NotNil(t, challenge.TOTP)
+	require.NotNil(t, challenge.WebAuthnRegistration)
+
+	var authErr *fleet.AuthFailedError
+	_, _, _, err := svc.CompleteLoginMFA(ctx, fleet.MFAVerification{Token: challenge.Token, RecoveryCode: "abcd"})
+	require.ErrorAs(t, err, &authErr)
+
+	secret := store.totp[maintainer.ID].Secret
+	resp, err := completeLoginMFAEndpoint(ctx, &fleet.MFAVerification{Token: challenge.Token, TOTPCode: mfa.TOTPCode(secret, mockClock.Now())}, svc)
+	require.NoError(t, err)
+	require.NoError(t, resp.Error())
+	loginResp := resp.(fleet.LoginResponse)
+	assert.Equal(t, "session-key", loginResp.Token)
+	assert.Len(t, loginResp.RecoveryCodes, mfa.RecoveryCodeCount)
+	assert.NotNil(t, store.totp[maintainer.ID].ConfirmedAt)
+
+	// the only second factor can't be removed while MFA is required
+	maintainerCtx := viewer.NewContext(ctx, viewer.Viewer{User: store.user(maintainer)})
+	err = svc.DisableTOTP(maintainerCtx, test.GoodPassword)
+	require.ErrorContains(t, err, "a second factor is required for your role")
+
+	// or enroll a security key
+	challenge = loginChallenge(t, ctx, svc, teamAdmin.Email)
+	assert.True(t, challenge.EnrollmentRequired)
+	authn := mfatest.NewAuthenticator(t, "https://fleet.example.com")
+	registration := authn.Register(*challenge.WebAuthnRegistration)
+	_, _, codes, err := svc.CompleteLoginMFA(ctx, fleet.MFAVerification{Token: challenge.Token, WebAuthnRegistration: &registration, CredentialName: "Passkey"})
+	require.NoError(t, err)
+	assert.Len(t, codes, mfa.RecoveryCodeCount)
+	require.Len(t, store.webAuthn, 1)
+	assert.Equal(t, "Passkey", store.webAuthn[0].Name)
+
+	// the pending secret generated by the challenge was not confirmed
+	assert.Nil(t, store.totp[teamAdmin.ID].ConfirmedAt)
+	teamAdminCtx := viewer.NewContext(ctx, viewer.Viewer{User: store.user(teamAdmin)})
+	err = svc.DeleteWebAuthnCredential(teamAdminCtx, store.webAuthn[0].ID, test.GoodPassword)
+	require.ErrorContains(t, err, "a second factor is required for your role")
+
+	codes, err = svc.RegenerateMFARecoveryCodes(teamAdminCtx, test.GoodPassword)
+	require.NoError(t, err)
+	assert.Len(t, codes, mfa.RecoveryCodeCount)
+	_, err = svc.RegenerateMFARecoveryCodes(viewer.NewContext(ctx, viewer.Viewer{User: observer}), test.GoodPassword)
+	require.ErrorContains(t, err, "recovery codes require")
+}
+
+func TestResetUserMFA(t *testing.T) {
+	ds := new(mock.Store)
+	opts := &TestServerOpts{}
+	svc, ctx := newTestService(t, ds, nil, nil, opts)
+
+	user := newMFATestUser(t, 42, "lost@example.com", fleet.RoleMaintainer)
+	store := newMFATestStore(ds, user)
+	store.totp[user.ID] = &fleet.UserTOTP{UserID: user.ID, Secret: []byte("secret"), ConfirmedAt: ptr.Time(time.Now())}
+	store.recoveryCodes[user.ID] = [][]byte{mfa.HashRecoveryCode("code")}
+
+	var activity *fleet.ActivityTypeResetUserMFA
+	opts.ActivityMock.NewActivityFunc = func(_ context.Context, _ *activity_api.User, a activity_api.ActivityDetails) error {
+		if act, ok := a.(fleet.ActivityTypeResetUserMFA); ok {
+			activity = &act
+		}
+		return nil
+	}
+
+	for _, u := range []*fleet.User{test.UserMaintainer, test.UserTeamAdminTeam1, test.UserObserver} {
+		err := svc.ResetUserMFA(test.UserContext(ctx, u), user.ID)
+		require.ErrorContains(t, err, "forbidden", u.ID)
+	}
+	require.Nil(t, activity)
+	require.NotEmpty(t, store.totp)
+
+	selfAdmin := newMFATestUser(t, test.UserAdmin.ID, test.UserAdmin.Email, fleet.RoleAdmin)
+	err := svc.ResetUserMFA(test.UserContext(ctx, selfAdmin), selfAdmin.ID)
+	require.ErrorContains(t, err, "cannot reset your own second factors")
+
+	require.NoError(t, svc.ResetUserMFA(test.UserContext(ctx, test.UserAdmin), user.ID))
+	assert.Empty(t, store.totp)
+	assert.Empty(t, store.recoveryCodes)
+	require.NotNil(t, activity)
+	assert.Equal(t, fleet.ActivityTypeResetUserMFA{UserID: user.ID, UserName: user.Name, UserEmail: user.Email}, *activity)
+}
@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/license"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/vulnerabilities/vex"
+)
+
+//////////////////////////////////////////////////////////////////////////////////
+// Upload VEX document
+//////////////////////////////////////////////////////////////////////////////////
+
+type uploadVEXDocumentRequest struct {
+	Name     string
+	Document *multipart.FileHeader
+}
+
+func (uploadVEXDocumentRequest) DecodeRequest(ctx context.Context, r *http.Request) (any, error) {
+	if err := parseMultipartForm(ctx, r, fleet.VEXDocumentMaxSize); err != nil {
+		return nil, &fleet.BadRequestError{
+			Message:     "failed to parse multipart form",
+			InternalErr: err,
+		}
+	}
+
+	doc, ok := r.MultipartForm.File["document"]
+	if !ok || len(doc) < 1 {
+		return nil, &fleet.BadRequestError{Message: "no file headers for document"}
+	}
+
+	// the name defaults to the file name
+	name := doc[0].Filename
+	if val := r.MultipartForm.Value["name"]; len(val) > 0 && strings.TrimSpace(val[0]) != "" {
+		name = val[0]
+	}
+
+	return &uploadVEXDocumentRequest{Name: name, Document: doc[0]}, nil
+}
+
+type uploadVEXDocumentResponse struct {
+	VEXDocument *fleet.VEXDocument `json:"vex_document,omitempty"`
+	Err         error              `json:"error,omitempty"`
+}
+
+func (r uploadVEXDocumentResponse) Error() error { return r.Err }
+
+func uploadVEXDocumentEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*uploadVEXDocumentRequest)
+	ff, err := req.Document.Open()
+	if err != nil {
+		return uploadVEXDocumentResponse{Err: err}, nil
+	}
+	defer ff.Close()
+
+	doc, err := svc.UploadVEXDocument(ctx, req.Name, ff)
+	if err != nil {
+		return uploadVEXDocumentResponse{Err: err}, nil
+	}
+	return uploadVEXDocumentResponse{VEXDocument: doc}, nil
+}
+
+func (svc *Service) UploadVEXDocument(ctx context.Context, name string, r io.Reader) (*fleet.VEXDocument, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.VEXDocument{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	if !license.IsPremium(ctx) {
+		return nil, fleet.ErrMissingLicense
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("name", "VEX document name can't be empty"))
+	}
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "read VEX document")
+	}
+	doc, err := vex.Parse(b)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("document", err.Error()))
+	}
+	sum := sha256.Sum256(b)
+	doc.Name = name
+	doc.SHA256 = hex.EncodeToString(sum[:])
+	doc.Source = fleet.VEXDocumentSourceAPI
+
+	created, err := svc.ds.NewVEXDocument(ctx, doc)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create VEX document")
+	}
+
+	if err := svc.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeAddedVEXDocument{
+			VEXDocumentID:   created.ID,
+			VEXDocumentName: created.Name,
+			StatementsCount: len(doc.Statements),
+		},
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for VEX document upload")
+	}
+
+	return created, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// List VEX documents
+//////////////////////////////////////////////////////////////////////////////////
+
+type listVEXDocumentsResponse struct {
+	VEXDocuments []*fleet.VEXDocument `json:"vex_documents"`
+	Err          error                `json:"error,omitempty"`
+}
+
+func (r listVEXDocumentsResponse) Error() error { return r.Err }
+
+func listVEXDocumentsEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	docs, err := svc.ListVEXDocuments(ctx)
+	if err != nil {
+		return listVEXDocumentsResponse{Err: err}, nil
+	}
+	return listVEXDocumentsResponse{VEXDocuments: docs}, nil
+}
+
+func (svc *Service) ListVEXDocuments(ctx context.Context) ([]*fleet.VEXDocument, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.VEXDocument{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	if !license.IsPremium(ctx) {
+		return nil, fleet.ErrMissingLicense
+	}
+
+	docs, err := svc.ds.ListVEXDocuments(ctx)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "list VEX documents")
+	}
+	return docs, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Delete VEX document
+//////////////////////////////////////////////////////////////////////////////////
+
+type deleteVEXDocumentRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteVEXDocumentResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteVEXDocumentResponse) Error() error { return r.Err }
+
+func deleteVEXDocumentEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*deleteVEXDocumentRequest)
+	err := svc.DeleteVEXDocument(ctx, req.ID)
+	return deleteVEXDocumentResponse{Err: err}, nil
+}
+
+func (svc *Service) DeleteVEXDocument(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.VEXDocument{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+	if !license.IsPremium(ctx) {
+		return fleet.ErrMissingLicense
+	}
+
+	doc, err := svc.ds.VEXDocument(ctx, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get VEX document")
+	}
+	if err := svc.ds.DeleteVEXDocument(ctx, id); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete VEX document")
+	}
+
+	if err := svc.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeDeletedVEXDocument{
+			VEXDocumentID:   doc.ID,
+			VEXDocumentName: doc.Name,
+		},
+	); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for VEX document deletion")
+	}
+
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// List VEX suppressions
+//////////////////////////////////////////////////////////////////////////////////
+
+type listVEXSuppressionsRequest struct {
+	fleet.VEXSuppressionListOptions
+}
+
+type listVEXSuppressionsResponse struct {
+	Suppressions []fleet.VEXSuppression    `json:"vex_suppressions"`
+	Meta         *fleet.PaginationMetadata `json:"meta,omitempty"`
+	Err          error                     `json:"error,omitempty"`
+}
+
+func (r listVEXSuppressionsResponse) Error() error { return r.Err }
+
+func listVEXSuppressionsEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*listVEXSuppressionsRequest)
+	suppressions, meta, err := svc.ListVEXSuppressions(ctx, req.VEXSuppressionListOptions)
+	if err != nil {
+		return listVEXSuppressionsResponse{Err: err}, nil
+	}
+	return listVEXSuppressionsResponse{Suppressions: suppressions, Meta: meta}, nil
+}
+
+func (svc *Service) ListVEXSuppressions(ctx context.Context, opts fleet.VEXSuppressionListOptions) ([]fleet.VEXSuppression, *fleet.PaginationMetadata, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.VEXDocument{}, fleet.ActionRead); err != nil {
+		return nil, nil, err
+	}
+	if !license.IsPremium(ctx) {
+		return nil, nil, fleet.ErrMissingLicense
+	}
+
+	opts.ListOptions.IncludeMetadata = true
+	suppressions, meta, err := svc.ds.ListVEXSuppressions(ctx, opts)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "list VEX suppressions")
+	}
+	return suppressions, meta, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testOpenVEXDocument = `{
+	"@context": "https://openvex.dev/ns/v0.2.0",
+	"@id": "https://acme.example/vex/1",
+	"author": "ACME",
+	"statements": [{"vulnerability": "CVE-2024-0001", "products": ["pkg:deb/debian/curl"], "status": "not_affected"}]
+}`
+
+func TestVEXDocumentsAuth(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+
+	ds.NewVEXDocumentFunc = func(ctx context.Context, doc *fleet.VEXDocument) (*fleet.VEXDocument, error) {
+		return doc, nil
+	}
+	ds.ListVEXDocumentsFunc = func(ctx context.Context) ([]*fleet.VEXDocument, error) {
+		return nil, nil
+	}
+	ds.VEXDocumentFunc = func(ctx context.Context, id uint) (*fleet.VEXDocument, error) {
+		return &fleet.VEXDocument{ID: id}, nil
+	}
+	ds.DeleteVEXDocumentFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+	ds.ListVEXSuppressionsFunc = func(ctx context.Context, opts fleet.VEXSuppressionListOptions) ([]fleet.VEXSuppression, *fleet.PaginationMetadata, error) {
+		return nil, &fleet.PaginationMetadata{}, nil
+	}
+
+	cases := []struct {
+		name         string
+		user         *fleet.User
+		readAllowed  bool
+		writeAllowed bool
+	}{
+		{"global admin", &fleet.User{GlobalRole: new(fleet.RoleAdmin)}, true, true},
+		{"global maintainer", &fleet.User{GlobalRole: new(fleet.RoleMaintainer)}, true, true},
+		{"global gitops", &fleet.User{GlobalRole: new(fleet.RoleGitOps)}, true, true},
+		{"global observer", &fleet.User{GlobalRole: new(fleet.RoleObserver)}, true, false},
+		{"team admin", &fleet.User{Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, false, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, err := svc.UploadVEXDocument(ctx, "acme.json", strings.NewReader(testOpenVEXDocument))
+			checkAuthErr(t, !tt.writeAllowed, err)
+
+			err = svc.DeleteVEXDocument(ctx, 1)
+			checkAuthErr(t, !tt.writeAllowed, err)
+
+			_, err = svc.ListVEXDocuments(ctx)
+			checkAuthErr(t, !tt.readAllowed, err)
+
+			_, _, err = svc.ListVEXSuppressions(ctx, fleet.VEXSuppressionListOptions{})
+			checkAuthErr(t, !tt.readAllowed, err)
+		})
+	}
+}
+
+func TestUploadVEXDocument(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	opts := &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}}
+	svc, ctx := newTestService(t, ds, nil, nil, opts)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: new(fleet.RoleAdmin)}})
+
+	ds.NewVEXDocumentFunc = func(ctx context.Context, doc *fleet.VEXDocument) (*fleet.VEXDocument, error) {
+		require.Equal(t, "acme.json", doc.Name)
+		require.Equal(t, fleet.VEXFormatOpenVEX, doc.Format)
+		require.Equal(t, "ACME", doc.Author)
+		require.Equal(t, fleet.VEXDocumentSourceAPI, doc.Source)
+		require.Len(t, doc.SHA256, 64)
+		require.Len(t, doc.Statements, 1)
+		doc.ID = 7
+		return doc, nil
+	}
+	var activities []activity_api.ActivityDetails
+	opts.ActivityMock.NewActivityFunc = func(_ context.Context, _ *activity_api.User, activity activity_api.ActivityDetails) error {
+		activities = append(activities, activity)
+		return nil
+	}
+
+	_, err := svc.UploadVEXDocument(ctx, " ", strings.NewReader(testOpenVEXDocument))
+	require.ErrorContains(t, err, "VEX document name can't be empty")
+
+	_, err = svc.UploadVEXDocument(ctx, "sbom.json", strings.NewReader(`{"bomFormat": "CycloneDX"}`))
+	var iae *fleet.InvalidArgumentError
+	require.ErrorAs(t, err, &iae)
+	require.False(t, ds.NewVEXDocumentFuncInvoked)
+
+	doc, err := svc.UploadVEXDocument(ctx, " acme.json ", strings.NewReader(testOpenVEXDocument))
+	require.NoError(t, err)
+	require.EqualValues(t, 7, doc.ID)
+	require.Equal(t, []activity_api.ActivityDetails{
+		fleet.ActivityTypeAddedVEXDocument{VEXDocumentID: 7, VEXDocumentName: "acme.json", StatementsCount: 1},
+	}, activities)
+
+	// VEX documents require Fleet Premium
+	svc, ctx = newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{GlobalRole: new(fleet.RoleAdmin)}})
+	_, err = svc.UploadVEXDocument(ctx, "acme.json", strings.NewReader(testOpenVEXDocument))
+	require.ErrorIs(t, err, fleet.ErrMissingLicense)
+}
@@ -94,9 +94,12 @@ func (svc *Service) ListVulnerabilities(ctx context.Context, opt fleet.VulnListO
 		return nil, nil, badRequest("invalid order key")
 	}
 
-	if opt.KnownExploit && !opt.IsEE {
+	if opt.HasCVEMetaFilters() && !opt.IsEE {
 		return nil, nil, fleet.ErrMissingLicense
 	}
+	if err := fleet.ValidateEPSSProbabilityRange(opt.MinimumEPSS, opt.MaximumEPSS); err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "validate epss probability filters")
+	}
 
 	vulns, meta, err := svc.ds.ListVulnerabilities(ctx, opt)
 	if err != nil {
@@ -50,6 +50,42 @@ func TestListVulnerabilities(t *testing.T) {
 		_, _, err = svc.ListVulnerabilities(ctx, opts)
 		require.NoError(t, err)
 	})
+
+	t.Run("cve meta filters require premium", func(t *testing.T) {
+		for _, opts := range []fleet.VulnListOptions{
+			{KnownExploit: true},
+			{MinimumEPSS: 0.5},
+			{MaximumEPSS: 0.1},
+		} {
+			_, _, err := svc.ListVulnerabilities(ctx, opts)
+			require.ErrorIs(t, err, fleet.ErrMissingLicense)
+
+			opts.IsEE = true
+			_, _, err = svc.ListVulnerabilities(ctx, opts)
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("epss probabilities must be a valid range", func(t *testing.T) {
+		for _, opts := range []fleet.VulnListOptions{
+			{IsEE: true, MinimumEPSS: -0.1},
+			{IsEE: true, MinimumEPSS: 1.5},
+			{IsEE: true, MaximumEPSS: 2},
+			{IsEE: true, MinimumEPSS: 0.6, MaximumEPSS: 0.4},
+		} {
+			ds.ListVulnerabilitiesFuncInvoked = false
+			_, _, err := svc.ListVulnerabilities(ctx, opts)
+			// invalid arguments are returned as 422s
+			var invalidErr *fleet.InvalidArgumentError
+			require.ErrorAs(t, err, &invalidErr)
+			require.False(t, ds.ListVulnerabilitiesFuncInvoked)
+		}
+
+		_, _, err := svc.ListVulnerabilities(ctx, fleet.VulnListOptions{IsEE: true, MinimumEPSS: 0.4, MaximumEPSS: 0.4})
+		require.NoError(t, err)
+		_, _, err = svc.ListVulnerabilities(ctx, fleet.VulnListOptions{IsEE: true, MinimumEPSS: 1})
+		require.NoError(t, err)
+	})
 }
 
 func TestVulnerabilitesAuth(t *testing.T) {
@@ -0,0 +1,407 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/license"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+//////////////////////////////////////////////////////////////////////////////////
+// List vulnerability exceptions
+//////////////////////////////////////////////////////////////////////////////////
+
+func listVulnerabilityExceptionsEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.ListVulnerabilityExceptionsRequest)
+	exceptions, meta, err := svc.ListVulnerabilityExceptions(ctx, req.VulnerabilityExceptionListOptions)
+	if err != nil {
+		return fleet.ListVulnerabilityExceptionsResponse{Err: err}, nil
+	}
+	return fleet.ListVulnerabilityExceptionsResponse{VulnerabilityExceptions: exceptions, Meta: meta}, nil
+}
+
+func (svc *Service) ListVulnerabilityExceptions(ctx context.Context, opts fleet.VulnerabilityExceptionListOptions) ([]fleet.VulnerabilityException, *fleet.PaginationMetadata, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.VulnerabilityException{}, fleet.ActionRead); err != nil {
+		return nil, nil, err
+	}
+	if !license.IsPremium(ctx) {
+		return nil, nil, fleet.ErrMissingLicense
+	}
+
+	opts.CVE = fleet.NormalizeCVE(opts.CVE)
+	opts.ListOptions.IncludeMetadata = true
+	exceptions, meta, err := svc.ds.ListVulnerabilityExceptions(ctx, opts)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "list vulnerability exceptions")
+	}
+	return exceptions, meta, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Get vulnerability exception
+//////////////////////////////////////////////////////////////////////////////////
+
+func getVulnerabilityExceptionEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.GetVulnerabilityExceptionRequest)
+	exception, err := svc.GetVulnerabilityException(ctx, req.ID)
+	if err != nil {
+		return fleet.GetVulnerabilityExceptionResponse{Err: err}, nil
+	}
+	return fleet.GetVulnerabilityExceptionResponse{VulnerabilityException: exception}, nil
+}
+
+func (svc *Service) GetVulnerabilityException(ctx context.Context, id uint) (*fleet.VulnerabilityException, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.VulnerabilityException{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	if !license.IsPremium(ctx) {
+		return nil, fleet.ErrMissingLicense
+	}
+
+	exception, err := svc.ds.VulnerabilityException(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get vulnerability exception")
+	}
+	return exception, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Create vulnerability exception
+//////////////////////////////////////////////////////////////////////////////////
+
+func createVulnerabilityExceptionEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.CreateVulnerabilityExceptionRequest)
+	exception, err := svc.CreateVulnerabilityException(ctx, req.VulnerabilityExceptionPayload)
+	if err != nil {
+		return fleet.CreateVulnerabilityExceptionResponse{Err: err}, nil
+	}
+	return fleet.CreateVulnerabilityExceptionResponse{VulnerabilityException: exception}, nil
+}
+
+func (svc *Service) CreateVulnerabilityException(ctx context.Context, p fleet.VulnerabilityExceptionPayload) (*fleet.VulnerabilityException, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.VulnerabilityException{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	if !license.IsPremium(ctx) {
+		return nil, fleet.ErrMissingLicense
+	}
+
+	exception := &fleet.VulnerabilityException{
+		SoftwareTitleID: p.SoftwareTitleID,
+		TeamID:          p.TeamID,
+	}
+	if p.CVE != nil {
+		exception.CVE = fleet.NormalizeCVE(*p.CVE)
+	}
+	applyVulnerabilityExceptionPayload(exception, p)
+	if err := svc.validateVulnerabilityException(ctx, exception); err != nil {
+		return nil, err
+	}
+
+	exception, err := svc.ds.NewVulnerabilityException(ctx, exception)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create vulnerability exception")
+	}
+
+	if err := svc.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeCreatedVulnerabilityException{
+			VulnerabilityExceptionID: exception.ID,
+			CVE:                      exception.CVE,
+			SoftwareTitleID:          exception.SoftwareTitleID,
+			SoftwareTitleName:        exception.SoftwareTitleName,
+			TeamID:                   exception.TeamID,
+			TeamName:                 exception.TeamName,
+			ExpiresAt:                exception.ExpiresAt,
+		},
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for vulnerability exception creation")
+	}
+
+	return exception, nil
+}
+
+// applyVulnerabilityExceptionPayload sets the modifiable fields of the
+// exception from the payload.
+func applyVulnerabilityExceptionPayload(e *fleet.VulnerabilityException, p fleet.VulnerabilityExceptionPayload) {
+	if p.Reason != nil {
+		e.Reason = strings.TrimSpace(*p.Reason)
+	}
+	if p.Owner != nil {
+		e.Owner = strings.TrimSpace(*p.Owner)
+	}
+	if p.ExpiresAt != nil {
+		// DATETIME(6) has microsecond precision
+		e.ExpiresAt = p.ExpiresAt.UTC().Truncate(time.Microsecond)
+	}
+}
+
+// validateVulnerabilityException validates an exception created or modified
+// via the API, which must expire in the future, and checks that its team
+// exists.
+func (svc *Service) validateVulnerabilityException(ctx context.Context, e *fleet.VulnerabilityException) error {
+	if err := fleet.ValidateVulnerabilityException(*e); err != nil {
+		return ctxerr.Wrap(ctx, err, "validate vulnerability exception")
+	}
+	if !e.ExpiresAt.After(svc.clock.Now()) {
+		return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("expires_at", "Vulnerability exception expiration date must be in the future"))
+	}
+	if e.TeamID != nil {
+		exists, err := svc.ds.TeamExists(ctx, *e.TeamID)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "check team exists")
+		}
+		if !exists {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("fleet_id", fmt.Sprintf("Fleet %d doesn't exist", *e.TeamID)))
+		}
+	}
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Modify vulnerability exception
+//////////////////////////////////////////////////////////////////////////////////
+
+func modifyVulnerabilityExceptionEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.ModifyVulnerabilityExceptionRequest)
+	exception, err := svc.ModifyVulnerabilityException(ctx, req.ID, req.VulnerabilityExceptionPayload)
+	if err != nil {
+		return fleet.ModifyVulnerabilityExceptionResponse{Err: err}, nil
+	}
+	return fleet.ModifyVulnerabilityExceptionResponse{VulnerabilityException: exception}, nil
+}
+
+func (svc *Service) ModifyVulnerabilityException(ctx context.Context, id uint, p fleet.VulnerabilityExceptionPayload) (*fleet.VulnerabilityException, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.VulnerabilityException{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+	if !license.IsPremium(ctx) {
+		return nil, fleet.ErrMissingLicense
+	}
+
+	exception, err := svc.ds.VulnerabilityException(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get vulnerability exception")
+	}
+	if p.CVE != nil || p.SoftwareTitleID != nil || p.TeamID != nil {
+		return nil, ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("cve",
+			"The CVE, software title and fleet of a vulnerability exception can't be modified, delete it and create a new one instead"))
+	}
+	applyVulnerabilityExceptionPayload(exception, p)
+	if err := svc.validateVulnerabilityException(ctx, exception); err != nil {
+		return nil, err
+	}
+
+	exception, err = svc.ds.SaveVulnerabilityException(ctx, exception)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "save vulnerability exception")
+	}
+
+	if err := svc.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeEditedVulnerabilityException{
+			VulnerabilityExceptionID: exception.ID,
+			CVE:                      exception.CVE,
+			SoftwareTitleID:          exception.SoftwareTitleID,
+			SoftwareTitleName:        exception.SoftwareTitleName,
+			TeamID:                   exception.TeamID,
+			TeamName:                 exception.TeamName,
+			ExpiresAt:                exception.ExpiresAt,
+		},
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for vulnerability exception edit")
+	}
+
+	return exception, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Delete vulnerability exception
+//////////////////////////////////////////////////////////////////////////////////
+
+func deleteVulnerabilityExceptionEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.DeleteVulnerabilityExceptionRequest)
+	err := svc.DeleteVulnerabilityException(ctx, req.ID)
+	return fleet.DeleteVulnerabilityExceptionResponse{Err: err}, nil
+}
+
+func (svc *Service) DeleteVulnerabilityException(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.VulnerabilityException{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	exception, err := svc.ds.VulnerabilityException(ctx, id)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get vulnerability exception")
+	}
+	if err := svc.ds.DeleteVulnerabilityException(ctx, id); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete vulnerability exception")
+	}
+
+	if err := svc.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		deletedVulnerabilityExceptionActivity(*exception),
+	); err != nil {
+		return ctxerr.Wrap(ctx, err, "create activity for vulnerability exception deletion")
+	}
+
+	return nil
+}
+
+func deletedVulnerabilityExceptionActivity(e fleet.VulnerabilityException) fleet.ActivityTypeDeletedVulnerabilityException {
+	return fleet.ActivityTypeDeletedVulnerabilityException{
+		VulnerabilityExceptionID: e.ID,
+		CVE:                      e.CVE,
+		SoftwareTitleID:          e.SoftwareTitleID,
+		SoftwareTitleName:        e.SoftwareTitleName,
+		TeamID:                   e.TeamID,
+		TeamName:                 e.TeamName,
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Apply vulnerability exceptions (spec)
+//////////////////////////////////////////////////////////////////////////////////
+
+func applyVulnerabilityExceptionsEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.ApplyVulnerabilityExceptionsRequest)
+	err := svc.ApplyVulnerabilityExceptions(ctx, req.VulnerabilityExceptions, req.DryRun)
+	return fleet.ApplyVulnerabilityExceptionsResponse{Err: err}, nil
+}
+
+func (svc *Service) ApplyVulnerabilityExceptions(ctx context.Context, specs []fleet.VulnerabilityExceptionSpec, dryRun bool) error {
+	if err := svc.authz.Authorize(ctx, &fleet.VulnerabilityException{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+	if !license.IsPremium(ctx) && len(specs) > 0 {
+		return fleet.ErrMissingLicense
+	}
+
+	// Unlike exceptions created via the API, exceptions applied from specs can
+	// be expired: they stay in the GitOps repository as a record of the
+	// accepted risk.
+	exceptions := make([]fleet.VulnerabilityException, 0, len(specs))
+	teamIDsByName := make(map[string]uint)
+	seen := make(map[string]struct{}, len(specs))
+	for _, spec := range specs {
+		exception := fleet.VulnerabilityException{
+			CVE:             fleet.NormalizeCVE(spec.CVE),
+			SoftwareTitleID: spec.SoftwareTitleID,
+			Reason:          strings.TrimSpace(spec.Reason),
+			Owner:           strings.TrimSpace(spec.Owner),
+			ExpiresAt:       spec.ExpiresAt.UTC().Truncate(time.Microsecond),
+		}
+		if err := fleet.ValidateVulnerabilityException(exception); err != nil {
+			return ctxerr.Wrap(ctx, err, "validate vulnerability exception")
+		}
+
+		if name := strings.TrimSpace(spec.Fleet); name != "" {
+			teamID, ok := teamIDsByName[name]
+			if !ok {
+				team, err := svc.ds.TeamByName(ctx, name)
+				if err != nil {
+					if fleet.IsNotFound(err) {
+						return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("fleet",
+							fmt.Sprintf("Vulnerability exception for %s: fleet %q doesn't exist", exception.CVE, name)))
+					}
+					return ctxerr.Wrap(ctx, err, "get fleet by name")
+				}
+				teamID = team.ID
+				teamIDsByName[name] = teamID
+			}
+			exception.TeamID = &teamID
+		}
+
+		if _, ok := seen[exception.ScopeKey()]; ok {
+			return ctxerr.Wrap(ctx, fleet.NewInvalidArgumentError("vulnerability_exceptions",
+				fmt.Sprintf("duplicate vulnerability exceptions for %s with the same software title and fleet", exception.CVE)))
+		}
+		seen[exception.ScopeKey()] = struct{}{}
+		exceptions = append(exceptions, exception)
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	created, edited, deleted, err := svc.ds.ApplyVulnerabilityExceptions(ctx, exceptions)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "apply vulnerability exceptions")
+	}
+
+	// the names of the software titles and teams aren't loaded for the created
+	// and edited exceptions, they are only set in the activities if the
+	// exceptions are listed again
+	user := authz.UserFromContext(ctx)
+	for _, e := range created {
+		if err := svc.NewActivity(ctx, user, fleet.ActivityTypeCreatedVulnerabilityException{
+			VulnerabilityExceptionID: e.ID,
+			CVE:                      e.CVE,
+			SoftwareTitleID:          e.SoftwareTitleID,
+			TeamID:                   e.TeamID,
+			ExpiresAt:                e.ExpiresAt,
+		}); err != nil {
+			return ctxerr.Wrap(ctx, err, "create activity for vulnerability exception creation")
+		}
+	}
+	for _, e := range edited {
+		if err := svc.NewActivity(ctx, user, fleet.ActivityTypeEditedVulnerabilityException{
+			VulnerabilityExceptionID: e.ID,
+			CVE:                      e.CVE,
+			SoftwareTitleID:          e.SoftwareTitleID,
+			TeamID:                   e.TeamID,
+			ExpiresAt:                e.ExpiresAt,
+		}); err != nil {
+			return ctxerr.Wrap(ctx, err, "create activity for vulnerability exception edit")
+		}
+	}
+	for _, e := range deleted {
+		if err := svc.NewActivity(ctx, user, deletedVulnerabilityExceptionActivity(e)); err != nil {
+			return ctxerr.Wrap(ctx, err, "create activity for vulnerability exception deletion")
+		}
+	}
+
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Expire vulnerability exceptions
+//////////////////////////////////////////////////////////////////////////////////
+
+func (svc *Service) ExpireVulnerabilityExceptions(ctx context.Context) error {
+	// skipauth: Called by the cleanups cron job, not by users.
+	svc.authz.SkipAuthorization(ctx)
+
+	expired, err := svc.ds.ExpireVulnerabilityExceptions(ctx, svc.clock.Now())
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "expire vulnerability exceptions")
+	}
+
+	for _, e := range expired {
+		if err := svc.NewActivity(
+			ctx,
+			nil, // Fleet automation user
+			fleet.ActivityTypeExpiredVulnerabilityException{
+				VulnerabilityExceptionID: e.ID,
+				CVE:                      e.CVE,
+				SoftwareTitleID:          e.SoftwareTitleID,
+				SoftwareTitleName:        e.SoftwareTitleName,
+				TeamID:                   e.TeamID,
+				TeamName:                 e.TeamName,
+				Owner:                    e.Owner,
+				ExpiresAt:                e.ExpiresAt,
+			},
+		); err != nil {
+			return ctxerr.Wrap(ctx, err, "create activity for vulnerability exception expiration")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVulnerabilityExceptionsAuth(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	ds.ListVulnerabilityExceptionsFunc = func(ctx context.Context, opts fleet.VulnerabilityExceptionListOptions) ([]fleet.VulnerabilityException, *fleet.PaginationMetadata, error) {
+		return nil, &fleet.PaginationMetadata{}, nil
+	}
+	ds.VulnerabilityExceptionFunc = func(ctx context.Context, id uint) (*fleet.VulnerabilityException, error) {
+		return &fleet.VulnerabilityException{ID: id, CVE: "CVE-2024-1234", Reason: "Accepted", Owner: "secops", ExpiresAt: expiresAt}, nil
+	}
+	ds.NewVulnerabilityExceptionFunc = func(ctx context.Context, e *fleet.VulnerabilityException) (*fleet.VulnerabilityException, error) {
+		e.ID = 1
+		return e, nil
+	}
+	ds.SaveVulnerabilityExceptionFunc = func(ctx context.Context, e *fleet.VulnerabilityException) (*fleet.VulnerabilityException, error) {
+		return e, nil
+	}
+	ds.DeleteVulnerabilityExceptionFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+	ds.ApplyVulnerabilityExceptionsFunc = func(ctx context.Context, exceptions []fleet.VulnerabilityException) ([]fleet.VulnerabilityException, []fleet.VulnerabilityException, []fleet.VulnerabilityException, error) {
+		return nil, nil, nil, nil
+	}
+
+	cases := []struct {
+		name         string
+		user         *fleet.User
+		readAllowed  bool
+		writeAllowed bool
+	}{
+		{"global admin", &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}, true, true},
+		{"global maintainer", &fleet.User{ID: 2, GlobalRole: new(fleet.RoleMaintainer)}, true, true},
+		{"global gitops", &fleet.User{ID: 3, GlobalRole: new(fleet.RoleGitOps)}, true, true},
+		{"global observer", &fleet.User{ID: 4, GlobalRole: new(fleet.RoleObserver)}, true, false},
+		{"team admin", &fleet.User{ID: 5, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, false, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, _, err := svc.ListVulnerabilityExceptions(ctx, fleet.VulnerabilityExceptionListOptions{})
+			checkAuthErr(t, !tt.readAllowed, err)
+
+			_, err = svc.GetVulnerabilityException(ctx, 1)
+			checkAuthErr(t, !tt.readAllowed, err)
+
+			_, err = svc.CreateVulnerabilityException(ctx, fleet.VulnerabilityExceptionPayload{
+				CVE:       new("CVE-2024-1234"),
+				Reason:    new("Accepted"),
+				Owner:     new("secops"),
+				ExpiresAt: &expiresAt,
+			})
+			checkAuthErr(t, !tt.writeAllowed, err)
+
+			_, err = svc.ModifyVulnerabilityException(ctx, 1, fleet.VulnerabilityExceptionPayload{Reason: new("Still accepted")})
+			checkAuthErr(t, !tt.writeAllowed, err)
+
+			err = svc.DeleteVulnerabilityException(ctx, 1)
+			checkAuthErr(t, !tt.writeAllowed, err)
+
+			err = svc.ApplyVulnerabilityExceptions(ctx, nil, true)
+			checkAuthErr(t, !tt.writeAllowed, err)
+		})
+	}
+}
+
+func TestVulnerabilityExceptionsRequirePremium(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}})
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	_, err := svc.CreateVulnerabilityException(ctx, fleet.VulnerabilityExceptionPayload{
+		CVE:       new("CVE-2024-1234"),
+		Reason:    new("Accepted"),
+		Owner:     new("secops"),
+		ExpiresAt: &expiresAt,
+	})
+	require.ErrorIs(t, err, fleet.ErrMissingLicense)
+
+	err = svc.ApplyVulnerabilityExceptions(ctx, []fleet.VulnerabilityExceptionSpec{
+		{CVE: "CVE-2024-1234", Reason: "Accepted", Owner: "secops", ExpiresAt: expiresAt},
+	}, true)
+	require.ErrorIs(t, err, fleet.ErrMissingLicense)
+
+	// clearing all exceptions is allowed, e.g. after a downgrade
+	ds.ApplyVulnerabilityExceptionsFunc = func(ctx context.Context, exceptions []fleet.VulnerabilityException) ([]fleet.VulnerabilityException, []fleet.VulnerabilityException, []fleet.VulnerabilityException, error) {
+		return nil, nil, nil, nil
+	}
+	require.NoError(t, svc.ApplyVulnerabilityExceptions(ctx, nil, false))
+}
+
+func TestCreateVulnerabilityException(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	opts := &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}}
+	svc, ctx := newTestService(t, ds, nil, nil, opts)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}})
+
+	ds.NewVulnerabilityExceptionFunc = func(ctx context.Context, e *fleet.VulnerabilityException) (*fleet.VulnerabilityException, error) {
+		e.ID = 1
+		return e, nil
+	}
+	ds.TeamExistsFunc = func(ctx context.Context, teamID uint) (bool, error) {
+		return teamID == 3, nil
+	}
+
+	future := time.Now().Add(24 * time.Hour)
+	past := time.Now().Add(-time.Hour)
+	validPayload := func() fleet.VulnerabilityExceptionPayload {
+		return fleet.VulnerabilityExceptionPayload{
+			CVE:       new(" cve-2024-1234 "),
+			Reason:    new("Not exploitable"),
+			Owner:     new("secops@example.com"),
+			ExpiresAt: &future,
+		}
+	}
+
+	cases := []struct {
+		name   string
+		modify func(p *fleet.VulnerabilityExceptionPayload)
+		errMsg string
+	}{
+		{"invalid CVE", func(p *fleet.VulnerabilityExceptionPayload) { p.CVE = new("GHSA-1234") }, "Invalid CVE"},
+		{"missing reason", func(p *fleet.VulnerabilityExceptionPayload) { p.Reason = nil }, "reason can't be empty"},
+		{"missing owner", func(p *fleet.VulnerabilityExceptionPayload) { p.Owner = new("  ") }, "owner can't be empty"},
+		{"missing expiration", func(p *fleet.VulnerabilityExceptionPayload) { p.ExpiresAt = nil }, "must have an expiration date"},
+		{"expiration in the past", func(p *fleet.VulnerabilityExceptionPayload) { p.ExpiresAt = &past }, "must be in the future"},
+		{"unknown fleet", func(p *fleet.VulnerabilityExceptionPayload) { p.TeamID = new(uint(4)) }, "Fleet 4 doesn't exist"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ds.NewVulnerabilityExceptionFuncInvoked = false
+			p := validPayload()
+			tt.modify(&p)
+			_, err := svc.CreateVulnerabilityException(ctx, p)
+			require.ErrorContains(t, err, tt.errMsg)
+			require.False(t, ds.NewVulnerabilityExceptionFuncInvoked)
+		})
+	}
+
+	t.Run("creates and emits an activity", func(t *testing.T) {
+		var activities []activity_api.ActivityDetails
+		opts.ActivityMock.NewActivityFunc = func(_ context.Context, _ *activity_api.User, activity activity_api.ActivityDetails) error {
+			activities = append(activities, activity)
+			return nil
+		}
+		p := validPayload()
+		p.TeamID = new(uint(3))
+		exception, err := svc.CreateVulnerabilityException(ctx, p)
+		require.NoError(t, err)
+		require.Equal(t, "CVE-2024-1234", exception.CVE)
+		require.Equal(t, future.UTC().Truncate(time.Microsecond), exception.ExpiresAt)
+		require.Equal(t, []activity_api.ActivityDetails{fleet.ActivityTypeCreatedVulnerabilityException{
+			VulnerabilityExceptionID: 1,
+			CVE:                      "CVE-2024-1234",
+			TeamID:                   new(uint(3)),
+			ExpiresAt:                exception.ExpiresAt,
+		}}, activities)
+	})
+}
+
+func TestModifyVulnerabilityExceptionScopeIsImmutable(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil, &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}})
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}})
+
+	ds.VulnerabilityExceptionFunc = func(ctx context.Context, id uint) (*fleet.VulnerabilityException, error) {
+		return &fleet.VulnerabilityException{ID: id, CVE: "CVE-2024-1234", Reason: "Accepted", Owner: "secops", ExpiresAt: time.Now().Add(time.Hour)}, nil
+	}
+
+	_, err := svc.ModifyVulnerabilityException(ctx, 1, fleet.VulnerabilityExceptionPayload{CVE: new("CVE-2024-5678")})
+	require.ErrorContains(t, err, "can't be modified")
+	_, err = svc.ModifyVulnerabilityException(ctx, 1, fleet.VulnerabilityExceptionPayload{TeamID: new(uint(1))})
+	require.ErrorContains(t, err, "can't be modified")
+	require.False(t, ds.SaveVulnerabilityExceptionFuncInvoked)
+}
+
+func TestApplyVulnerabilityExceptions(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	opts := &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}}
+	svc, ctx := newTestService(t, ds, nil, nil, opts)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: new(fleet.RoleGitOps)}})
+
+	ds.TeamByNameFunc = func(ctx context.Context, name string) (*fleet.Team, error) {
+		if name != "Workstations" {
+			return nil, newNotFoundError()
+		}
+		return &fleet.Team{ID: 3, Name: name}, nil
+	}
+	var applied []fleet.VulnerabilityException
+	ds.ApplyVulnerabilityExceptionsFunc = func(ctx context.Context, exceptions []fleet.VulnerabilityException) ([]fleet.VulnerabilityException, []fleet.VulnerabilityException, []fleet.VulnerabilityException, error) {
+		applied = exceptions
+		return []fleet.VulnerabilityException{{ID: 1, CVE: "CVE-2024-1234"}},
+			nil,
+			[]fleet.VulnerabilityException{{ID: 2, CVE: "CVE-2023-0001", TeamID: new(uint(3)), TeamName: new("Workstations")}},
+			nil
+	}
+	var activities []activity_api.ActivityDetails
+	opts.ActivityMock.NewActivityFunc = func(_ context.Context, _ *activity_api.User, activity activity_api.ActivityDetails) error {
+		activities = append(activities, activity)
+		return nil
+	}
+
+	// expired exceptions are allowed in specs, they just don't hide anything
+	expired := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("rejects an unknown fleet", func(t *testing.T) {
+		err := svc.ApplyVulnerabilityExceptions(ctx, []fleet.VulnerabilityExceptionSpec{
+			{CVE: "CVE-2024-1234", Fleet: "Servers", Reason: "Accepted", Owner: "secops", ExpiresAt: expired},
+		}, false)
+		require.ErrorContains(t, err, `fleet "Servers" doesn't exist`)
+		require.False(t, ds.ApplyVulnerabilityExceptionsFuncInvoked)
+	})
+
+	t.Run("rejects duplicates", func(t *testing.T) {
+		err := svc.ApplyVulnerabilityExceptions(ctx, []fleet.VulnerabilityExceptionSpec{
+			{CVE: "CVE-2024-1234", Fleet: "Workstations", Reason: "Accepted", Owner: "secops", ExpiresAt: expired},
+			{CVE: "cve-2024-1234", Fleet: "Workstations", Reason: "Accepted again", Owner: "secops", ExpiresAt: expired},
+		}, false)
+		require.ErrorContains(t, err, "duplicate vulnerability exceptions for CVE-2024-1234")
+		require.False(t, ds.ApplyVulnerabilityExceptionsFuncInvoked)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		err := svc.ApplyVulnerabilityExceptions(ctx, []fleet.VulnerabilityExceptionSpec{
+			{CVE: "CVE-2024-1234", Reason: "Accepted", Owner: "secops", ExpiresAt: expired},
+		}, true)
+		require.NoError(t, err)
+		require.False(t, ds.ApplyVulnerabilityExceptionsFuncInvoked)
+		require.Empty(t, activities)
+	})
+
+	t.Run("applies and emits activities", func(t *testing.T) {
+		err := svc.ApplyVulnerabilityExceptions(ctx, []fleet.VulnerabilityExceptionSpec{
+			{CVE: "cve-2024-1234", Reason: "Accepted", Owner: "secops", ExpiresAt: expired},
+			{CVE: "CVE-2024-1234", Fleet: "Workstations", SoftwareTitleID: new(uint(7)), Reason: "Accepted", Owner: "secops", ExpiresAt: expired},
+		}, false)
+		require.NoError(t, err)
+		require.True(t, ds.ApplyVulnerabilityExceptionsFuncInvoked)
+		require.Len(t, applied, 2)
+		require.Equal(t, "CVE-2024-1234", applied[0].CVE)
+		require.Nil(t, applied[0].TeamID)
+		require.Equal(t, new(uint(3)), applied[1].TeamID)
+		require.Equal(t, new(uint(7)), applied[1].SoftwareTitleID)
+		require.Equal(t, []activity_api.ActivityDetails{
+			fleet.ActivityTypeCreatedVulnerabilityException{VulnerabilityExceptionID: 1, CVE: "CVE-2024-1234"},
+			fleet.ActivityTypeDeletedVulnerabilityException{
+				VulnerabilityExceptionID: 2,
+				CVE:                      "CVE-2023-0001",
+				TeamID:                   new(uint(3)),
+				TeamName:                 new("Workstations"),
+			},
+		}, activities)
+	})
+}
+
+func TestExpireVulnerabilityExceptions(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	opts := &TestServerOpts{License: &fleet.LicenseInfo{Tier: fleet.TierPremium}}
+	svc, ctx := newTestService(t, ds, nil, nil, opts)
+
+	expiresAt := time.Now().Add(-time.Minute).UTC()
+	ds.ExpireVulnerabilityExceptionsFunc = func(ctx context.Context, now time.Time) ([]fleet.VulnerabilityException, error) {
+		return []fleet.VulnerabilityException{{
+			ID:                1,
+			CVE:               "CVE-2024-1234",
+			SoftwareTitleID:   new(uint(7)),
+			SoftwareTitleName: new("openssl"),
+			Owner:             "secops",
+			ExpiresAt:         expiresAt,
+		}}, nil
+	}
+	var users []*activity_api.User
+	var activities []activity_api.ActivityDetails
+	opts.ActivityMock.NewActivityFunc = func(_ context.Context, user *activity_api.User, activity activity_api.ActivityDetails) error {
+		users = append(users, user)
+		activities = append(activities, activity)
+		return nil
+	}
+
+	require.NoError(t, svc.ExpireVulnerabilityExceptions(ctx))
+	require.Equal(t, []*activity_api.User{nil}, users)
+	require.Equal(t, []activity_api.ActivityDetails{fleet.ActivityTypeExpiredVulnerabilityException{
+		VulnerabilityExceptionID: 1,
+		CVE:                      "CVE-2024-1234",
+		SoftwareTitleID:          new(uint(7)),
+		SoftwareTitleName:        new("openssl"),
+		Owner:                    "secops",
+		ExpiresAt:                expiresAt,
+	}}, activities)
+}
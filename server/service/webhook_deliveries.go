@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	platform_http "github.com/fleetdm/fleet/v4/server/platform/http"
+	"github.com/fleetdm/fleet/v4/server/worker"
+)
+
+//////////////////////////////////////////////////////////////////////////////////
+// List webhook deliveries
+//////////////////////////////////////////////////////////////////////////////////
+
+func listWebhookDeliveriesEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.ListWebhookDeliveriesRequest)
+	deliveries, meta, err := svc.ListWebhookDeliveries(ctx, req.WebhookDeliveryListOptions)
+	if err != nil {
+		return fleet.ListWebhookDeliveriesResponse{Err: err}, nil
+	}
+	return fleet.ListWebhookDeliveriesResponse{WebhookDeliveries: deliveries, Meta: meta}, nil
+}
+
+func (svc *Service) ListWebhookDeliveries(ctx context.Context, opts fleet.WebhookDeliveryListOptions) ([]fleet.WebhookDelivery, *fleet.PaginationMetadata, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.WebhookDelivery{}, fleet.ActionRead); err != nil {
+		return nil, nil, err
+	}
+
+	invalid := &fleet.InvalidArgumentError{}
+	if opts.WebhookType != "" && !opts.WebhookType.IsValid() {
+		invalid.Append("webhook_type", "invalid webhook type")
+	}
+	if opts.Status != "" && !opts.Status.IsValid() {
+		invalid.Append("status", "must be one of pending, success or failed")
+	}
+	if invalid.HasErrors() {
+		return nil, nil, ctxerr.Wrap(ctx, invalid)
+	}
+
+	opts.ListOptions.IncludeMetadata = true
+	deliveries, meta, err := svc.ds.ListWebhookDeliveries(ctx, opts)
+	if err != nil {
+		return nil, nil, ctxerr.Wrap(ctx, err, "list webhook deliveries")
+	}
+	for i := range deliveries {
+		maskWebhookDeliveryURL(&deliveries[i])
+	}
+	return deliveries, meta, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Get webhook delivery
+//////////////////////////////////////////////////////////////////////////////////
+
+func getWebhookDeliveryEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.GetWebhookDeliveryRequest)
+	delivery, err := svc.GetWebhookDelivery(ctx, req.ID)
+	if err != nil {
+		return fleet.GetWebhookDeliveryResponse{Err: err}, nil
+	}
+	return fleet.GetWebhookDeliveryResponse{WebhookDelivery: delivery}, nil
+}
+
+func (svc *Service) GetWebhookDelivery(ctx context.Context, id uint) (*fleet.WebhookDelivery, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.WebhookDelivery{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	delivery, err := svc.ds.WebhookDelivery(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get webhook delivery")
+	}
+	maskWebhookDeliveryURL(delivery)
+	return delivery, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////
+// Redeliver webhook delivery
+//////////////////////////////////////////////////////////////////////////////////
+
+func redeliverWebhookDeliveryEndpoint(ctx context.Context, request any, svc fleet.Service) (fleet.Errorer, error) {
+	req := request.(*fleet.RedeliverWebhookDeliveryRequest)
+	delivery, err := svc.RedeliverWebhookDelivery(ctx, req.ID)
+	if err != nil {
+		return fleet.RedeliverWebhookDeliveryResponse{Err: err}, nil
+	}
+	return fleet.RedeliverWebhookDeliveryResponse{WebhookDelivery: delivery}, nil
+}
+
+func (svc *Service) RedeliverWebhookDelivery(ctx context.Context, id uint) (*fleet.WebhookDelivery, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.WebhookDelivery{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	delivery, err := worker.RedeliverWebhook(ctx, svc.ds, svc.logger, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "redeliver webhook")
+	}
+
+	if err := svc.NewActivity(
+		ctx,
+		authz.UserFromContext(ctx),
+		fleet.ActivityTypeRedeliveredWebhook{
+			WebhookDeliveryID: delivery.ID,
+			RedeliveryOfID:    id,
+			WebhookType:       delivery.WebhookType,
+			TeamID:            delivery.TeamID,
+		},
+	); err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create activity for webhook redelivery")
+	}
+
+	maskWebhookDeliveryURL(delivery)
+	return delivery, nil
+}
+
+// maskWebhookDeliveryURL masks the secrets that may be part of the query
+// string of a webhook URL (e.g. tokens of Slack or Teams webhooks).
+func maskWebhookDeliveryURL(d *fleet.WebhookDelivery) {
+	d.URL = platform_http.MaskSecretURLParams(d.URL)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	platform_http "github.com/fleetdm/fleet/v4/server/platform/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDeliveriesAuth(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+
+	ds.ListWebhookDeliveriesFunc = func(ctx context.Context, opts fleet.WebhookDeliveryListOptions) ([]fleet.WebhookDelivery, *fleet.PaginationMetadata, error) {
+		return nil, &fleet.PaginationMetadata{}, nil
+	}
+	ds.WebhookDeliveryFunc = func(ctx context.Context, id uint) (*fleet.WebhookDelivery, error) {
+		return &fleet.WebhookDelivery{ID: id, WebhookType: fleet.WebhookTypeActivities}, nil
+	}
+
+	cases := []struct {
+		name    string
+		user    *fleet.User
+		allowed bool
+	}{
+		{"global admin", &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}, true},
+		{"global maintainer", &fleet.User{ID: 2, GlobalRole: new(fleet.RoleMaintainer)}, false},
+		{"global observer", &fleet.User{ID: 3, GlobalRole: new(fleet.RoleObserver)}, false},
+		{"team admin", &fleet.User{ID: 4, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleAdmin}}}, false},
+	}
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := viewer.NewContext(ctx, viewer.Viewer{User: tt.user})
+
+			_, _, err := svc.ListWebhookDeliveries(ctx, fleet.WebhookDeliveryListOptions{})
+			checkAuthErr(t, !tt.allowed, err)
+
+			_, err = svc.GetWebhookDelivery(ctx, 1)
+			checkAuthErr(t, !tt.allowed, err)
+
+			// only checking the authorization, the redelivery itself is tested
+			// below
+			if !tt.allowed {
+				_, err = svc.RedeliverWebhookDelivery(ctx, 1)
+				checkAuthErr(t, true, err)
+			}
+		})
+	}
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	t.Parallel()
+	ds := new(mock.Store)
+	svc, ctx := newTestService(t, ds, nil, nil)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}})
+
+	ds.ListWebhookDeliveriesFunc = func(ctx context.Context, opts fleet.WebhookDeliveryListOptions) ([]fleet.WebhookDelivery, *fleet.PaginationMetadata, error) {
+		assert.True(t, opts.ListOptions.IncludeMetadata)
+		return []fleet.WebhookDelivery{
+			{ID: 1, URL: "https://hooks.example.com/in?token=s3cr3t"},
+		}, &fleet.PaginationMetadata{}, nil
+	}
+
+	deliveries, meta, err := svc.ListWebhookDeliveries(ctx, fleet.WebhookDeliveryListOptions{Status: fleet.WebhookDeliveryStatusFailed})
+	require.NoError(t, err)
+	require.NotNil(t, meta)
+	require.Len(t, deliveries, 1)
+	assert.NotContains(t, deliveries[0].URL, "s3cr3t")
+
+	_, _, err = svc.ListWebhookDeliveries(ctx, fleet.WebhookDeliveryListOptions{Status: "unknown"})
+	require.ErrorContains(t, err, "status")
+	_, _, err = svc.ListWebhookDeliveries(ctx, fleet.WebhookDeliveryListOptions{WebhookType: "unknown"})
+	require.ErrorContains(t, err, "webhook_type")
+}
+
+func TestRedeliverWebhookDelivery(t *testing.T) {
+	t.Parallel()
+
+	var gotBody []byte
+	var gotSignature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSignature = r.Header.Get(platform_http.WebhookSignatureHeader)
+	}))
+	t.Cleanup(srv.Close)
+
+	ds := new(mock.Store)
+	opts := &TestServerOpts{}
+	svc, ctx := newTestService(t, ds, nil, nil, opts)
+	ctx = viewer.NewContext(ctx, viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: new(fleet.RoleAdmin)}})
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		ac := &fleet.AppConfig{}
+		ac.WebhookSettings.VulnerabilitiesWebhook.Secret = "s3cr3t"
+		return ac, nil
+	}
+	ds.WebhookDeliveryFunc = func(ctx context.Context, id uint) (*fleet.WebhookDelivery, error) {
+		return &fleet.WebhookDelivery{
+			ID:          id,
+			WebhookType: fleet.WebhookTypeVulnerabilities,
+			URL:         srv.URL,
+			Status:      fleet.WebhookDeliveryStatusFailed,
+			Payload:     json.RawMessage(`{"vulnerability":{"cve":"CVE-2024-1234"}}`),
+		}, nil
+	}
+	var created *fleet.WebhookDelivery
+	ds.NewWebhookDeliveryFunc = func(ctx context.Context, d *fleet.WebhookDelivery) (*fleet.WebhookDelivery, error) {
+		d.ID = 2
+		d.Status = fleet.WebhookDeliveryStatusPending
+		created = d
+		return d, nil
+	}
+	ds.UpdateWebhookDeliveryFunc = func(ctx context.Context, d *fleet.WebhookDelivery) error {
+		return nil
+	}
+	var activities []activity_api.ActivityDetails
+	opts.ActivityMock.NewActivityFunc = func(_ context.Context, _ *activity_api.User, activity activity_api.ActivityDetails) error {
+		activities = append(activities, activity)
+		return nil
+	}
+
+	delivery, err := svc.RedeliverWebhookDelivery(ctx, 1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, delivery.ID)
+	assert.Equal(t, fleet.WebhookDeliveryStatusSuccess, delivery.Status)
+	assert.EqualValues(t, 1, delivery.Attempts)
+	require.NotNil(t, created.RedeliveryOfID)
+	assert.EqualValues(t, 1, *created.RedeliveryOfID)
+
+	assert.JSONEq(t, `{"vulnerability":{"cve":"CVE-2024-1234"}}`, string(gotBody))
+	require.NoError(t, platform_http.VerifyWebhookSignature("s3cr3t", gotSignature, gotBody, time.Minute, time.Now()))
+
+	require.Equal(t, []activity_api.ActivityDetails{fleet.ActivityTypeRedeliveredWebhook{
+		WebhookDeliveryID: 2,
+		RedeliveryOfID:    1,
+		WebhookType:       fleet.WebhookTypeVulnerabilities,
+	}}, activities)
+}
@@ -123,6 +123,12 @@ func (s *mockStore) create(sessionID, requestID, originalURL, metadata string, l
 	return nil
 }
 
+func (s *mockStore) put(sessionID string, session *Session, lifetimeSecs uint) error {
+	s.session = session
+	s.sessionLifetime = time.Duration(lifetimeSecs) * time.Second // nolint:gosec // dismiss G115
+	return nil
+}
+
 func (s *mockStore) get(sessionID string) (*Session, error) {
 	if s.session == nil {
 		return nil, fleet.NewAuthRequiredError("session not found")
@@ -0,0 +1,507 @@
+package sso
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/pkg/fleethttp"
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// oidcDiscoveryTTL is how long a provider's discovery document is cached.
+	oidcDiscoveryTTL = time.Hour
+	// oidcKeySetTTL is how long a provider's signing keys are trusted before
+	// they are fetched again, regardless of the key IDs seen in tokens.
+	oidcKeySetTTL = time.Hour
+	// oidcClockSkew is the leeway applied to the time-based ID token claims.
+	oidcClockSkew = time.Minute
+	// maxOIDCDocumentSize bounds the discovery and JWKS documents read from
+	// the provider.
+	maxOIDCDocumentSize = 1 << 20
+)
+
+// oidcKeySetMinRefreshInterval bounds how often an unknown key ID triggers a
+// refetch of the provider's JWKS, so that forged tokens with made-up key IDs
+// can't be used to hammer the provider. It is a variable for tests.
+var oidcKeySetMinRefreshInterval = 10 * time.Second
+
+// oidcSigningAlgs are the ID token signing algorithms accepted by Fleet. Only
+// asymmetric algorithms are supported, the keys are taken from the JWKS.
+var oidcSigningAlgs = []string{
+	string(jose.RS256), string(jose.RS384), string(jose.RS512),
+	string(jose.PS256), string(jose.PS384), string(jose.PS512),
+	string(jose.ES256), string(jose.ES384), string(jose.ES512),
+	string(jose.EdDSA),
+}
+
+// Default claim names and scopes used when the OIDC settings leave them empty.
+const (
+	defaultOIDCEmailClaim = "email"
+	defaultOIDCNameClaim  = "name"
+)
+
+var defaultOIDCScopes = []string{"email", "profile"}
+
+// OIDCDiscovery is the subset of the OpenID Provider Metadata used by Fleet.
+type OIDCDiscovery struct {
+	Issuer                           string   `json:"issuer"`
+	AuthorizationEndpoint            string   `json:"authorization_endpoint"`
+	TokenEndpoint                    string   `json:"token_endpoint"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	CodeChallengeMethodsSupported    []string `json:"code_challenge_methods_supported"`
+}
+
+// OIDCProvider authenticates users against an OpenID Connect provider with the
+// authorization code flow and PKCE.
+type OIDCProvider struct {
+	settings    fleet.OIDCSettings
+	redirectURL string
+	discovery   *OIDCDiscovery
+	keySet      *oidcKeySet
+	client      *http.Client
+	now         func() time.Time
+}
+
+// NewOIDCProvider returns a provider for the given settings. The provider's
+// discovery document is fetched from the issuer, or taken from the cache if it
+// was fetched recently. redirectURL is the Fleet callback URL registered with
+// the provider.
+func NewOIDCProvider(ctx context.Context, settings *fleet.OIDCSettings, redirectURL string) (*OIDCProvider, error) {
+	if settings == nil {
+		return nil, errors.New("missing OIDC settings")
+	}
+	s := *settings
+	s.IssuerURL = strings.TrimSpace(s.IssuerURL)
+	s.ClientID = strings.TrimSpace(s.ClientID)
+	if s.IssuerURL == "" {
+		return nil, errors.New("missing OIDC issuer URL")
+	}
+
+	client := fleethttp.NewClient(fleethttp.WithTimeout(10 * time.Second))
+	discovery, err := oidcProviders.discovery(ctx, client, s.IssuerURL)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "OIDC discovery")
+	}
+	return &OIDCProvider{
+		settings:    s,
+		redirectURL: redirectURL,
+		discovery:   discovery,
+		keySet:      oidcProviders.keySet(discovery.JWKSURI),
+		client:      client,
+		now:         time.Now,
+	}, nil
+}
+
+func (p *OIDCProvider) oauth2Config() *oauth2.Config {
+	scopes := p.settings.Scopes
+	if len(scopes) == 0 {
+		scopes = defaultOIDCScopes
+	}
+	// The openid scope is always requested, and must be requested once.
+	scopes = append([]string{"openid"}, slices.DeleteFunc(slices.Clone(scopes), func(s string) bool { return s == "openid" })...)
+	return &oauth2.Config{
+		ClientID:     p.settings.ClientID,
+		ClientSecret: p.settings.ClientSecret,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  p.discovery.AuthorizationEndpoint,
+			TokenURL: p.discovery.TokenEndpoint,
+		},
+		RedirectURL: p.redirectURL,
+		Scopes:      scopes,
+	}
+}
+
+// CreateOIDCAuthorizationRequest creates a new OIDC authorization request and
+// stores its state, nonce and PKCE verifier in a new session in sessionStore.
+// It returns the session identifier and the provider URL to redirect the user
+// to. If sessionTTLSeconds is 0 then a default of 5 minutes of TTL is used.
+func CreateOIDCAuthorizationRequest(
+	ctx context.Context,
+	provider *OIDCProvider,
+	sessionStore SessionStore,
+	originalURL string,
+	sessionTTLSeconds uint,
+) (sessionID string, idpURL string, err error) {
+	if methods := provider.discovery.CodeChallengeMethodsSupported; len(methods) > 0 && !slices.Contains(methods, "S256") {
+		return "", "", ctxerr.New(ctx, "OIDC provider does not support the S256 PKCE code challenge method")
+	}
+
+	sessionID, err = generateSessionID()
+	if err != nil {
+		return "", "", ctxerr.Wrap(ctx, err, "generate session ID")
+	}
+	state, err := server.GenerateRandomURLSafeText(24)
+	if err != nil {
+		return "", "", ctxerr.Wrap(ctx, err, "generate state")
+	}
+	nonce, err := server.GenerateRandomURLSafeText(24)
+	if err != nil {
+		return "", "", ctxerr.Wrap(ctx, err, "generate nonce")
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	sessionLifetimeSeconds := cacheLifetimeSeconds
+	if sessionTTLSeconds > 0 {
+		sessionLifetimeSeconds = sessionTTLSeconds
+	}
+	err = sessionStore.put(sessionID, &Session{
+		OriginalURL: originalURL,
+		OIDC: &OIDCSession{
+			State:        state,
+			Nonce:        nonce,
+			CodeVerifier: verifier,
+			Issuer:       provider.discovery.Issuer,
+		},
+	}, sessionLifetimeSeconds)
+	if err != nil {
+		return "", "", fmt.Errorf("caching SSO session while creating OIDC auth request: %w", err)
+	}
+
+	idpURL = provider.oauth2Config().AuthCodeURL(state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	)
+	return sessionID, idpURL, nil
+}
+
+// ValidateOIDCCallback completes the authorization code flow of the session
+// identified by sessionID: it checks the returned state, exchanges the code
+// for an ID token and validates the token. The session is removed from the
+// store, so a callback can only be processed once.
+func ValidateOIDCCallback(
+	ctx context.Context,
+	provider *OIDCProvider,
+	sessionStore SessionStore,
+	sessionID, state, code string,
+) (auth fleet.Auth, redirectURL string, err error) {
+	if sessionID == "" {
+		// Unlike SAML, there are no IdP-initiated logins with OIDC.
+		return nil, "", errors.New("missing SSO session")
+	}
+	session, err := sessionStore.Fullfill(sessionID)
+	if err != nil {
+		return nil, "", fmt.Errorf("validate request in session: %w", err)
+	}
+	if session.OIDC == nil {
+		return nil, "", errors.New("SSO session was not created for an OIDC login")
+	}
+	if subtle.ConstantTimeCompare([]byte(state), []byte(session.OIDC.State)) != 1 {
+		return nil, "", errors.New("state does not match SSO session")
+	}
+	if session.OIDC.Issuer != provider.discovery.Issuer {
+		return nil, "", errors.New("OIDC issuer changed during SSO session")
+	}
+	if code == "" {
+		return nil, "", errors.New("missing authorization code")
+	}
+
+	token, err := provider.oauth2Config().Exchange(
+		context.WithValue(ctx, oauth2.HTTPClient, provider.client),
+		code,
+		oauth2.VerifierOption(session.OIDC.CodeVerifier),
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("exchange authorization code: %w", err)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, "", errors.New("token response does not contain an ID token")
+	}
+	claims, err := provider.verifyIDToken(ctx, rawIDToken, session.OIDC.Nonce)
+	if err != nil {
+		return nil, "", fmt.Errorf("verify ID token: %w", err)
+	}
+	auth, err = newOIDCAuth(&provider.settings, claims)
+	if err != nil {
+		return nil, "", err
+	}
+	return auth, session.OriginalURL, nil
+}
+
+// verifyIDToken checks the signature and the standard claims of the ID token
+// and returns all its claims.
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, rawIDToken, nonce string) (map[string]any, error) {
+	tok, err := jwt.ParseSigned(rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	if len(tok.Headers) != 1 {
+		return nil, errors.New("ID token must have exactly one signature")
+	}
+	alg := tok.Headers[0].Algorithm
+	if !slices.Contains(oidcSigningAlgs, alg) ||
+		(len(p.discovery.IDTokenSigningAlgValuesSupported) > 0 && !slices.Contains(p.discovery.IDTokenSigningAlgValuesSupported, alg)) {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+
+	now := p.now()
+	key, err := p.keySet.key(ctx, p.client, tok.Headers[0].KeyID, now)
+	if err != nil {
+		return nil, err
+	}
+	if key.Algorithm != "" && key.Algorithm != alg {
+		return nil, fmt.Errorf("signing algorithm %q does not match key algorithm %q", alg, key.Algorithm)
+	}
+
+	var (
+		std    jwt.Claims
+		claims map[string]any
+	)
+	if err := tok.Claims(key, &std, &claims); err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+	if std.Expiry == nil || std.IssuedAt == nil {
+		return nil, errors.New("missing exp or iat claim")
+	}
+	if err := std.ValidateWithLeeway(jwt.Expected{
+		Issuer:   p.discovery.Issuer,
+		Audience: jwt.Audience{p.settings.ClientID},
+		Time:     now,
+	}, oidcClockSkew); err != nil {
+		return nil, err
+	}
+	azp, _ := claims["azp"].(string)
+	if (len(std.Audience) > 1 || azp != "") && azp != p.settings.ClientID {
+		return nil, errors.New("invalid authorized party (azp) claim")
+	}
+	tokenNonce, _ := claims["nonce"].(string)
+	if subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, errors.New("nonce does not match SSO session")
+	}
+	return claims, nil
+}
+
+// oidcAuth implements fleet.Auth for a validated ID token. The role claims are
+// exposed as the attributes understood by fleet.RolesFromSSOAttributes so that
+// JIT provisioning works the same way as with SAML.
+type oidcAuth struct {
+	email       string
+	displayName string
+	attrs       []fleet.SAMLAttribute
+}
+
+var _ fleet.Auth = oidcAuth{}
+
+// UserID partially implements the fleet.Auth interface.
+func (a oidcAuth) UserID() string { return a.email }
+
+// UserDisplayName partially implements the fleet.Auth interface.
+func (a oidcAuth) UserDisplayName() string { return a.displayName }
+
+// AssertionAttributes partially implements the fleet.Auth interface.
+func (a oidcAuth) AssertionAttributes() []fleet.SAMLAttribute { return a.attrs }
+
+func newOIDCAuth(settings *fleet.OIDCSettings, claims map[string]any) (oidcAuth, error) {
+	emailClaim := cmp.Or(settings.EmailClaim, defaultOIDCEmailClaim)
+	nameClaim := cmp.Or(settings.NameClaim, defaultOIDCNameClaim)
+	globalRoleClaim := cmp.Or(settings.GlobalRoleClaim, fleet.SSOGlobalRoleAttrName)
+	fleetRolePrefix := cmp.Or(settings.FleetRoleClaimPrefix, fleet.SSOFleetRoleAttrNamePrefix)
+
+	email, _ := claims[emailClaim].(string)
+	if email == "" {
+		return oidcAuth{}, fmt.Errorf("ID token is missing the %q claim", emailClaim)
+	}
+	// Only checked for the standard claim, custom email claims are assumed to
+	// be managed by the provider's administrators.
+	if emailClaim == defaultOIDCEmailClaim {
+		if verified, ok := claims["email_verified"].(bool); ok && !verified {
+			return oidcAuth{}, errors.New("email address is not verified by the OIDC provider")
+		}
+	}
+	displayName, _ := claims[nameClaim].(string)
+
+	var attrs []fleet.SAMLAttribute
+	for name, value := range claims {
+		var attrName string
+		switch {
+		case name == globalRoleClaim:
+			attrName = fleet.SSOGlobalRoleAttrName
+		case strings.HasPrefix(name, fleetRolePrefix):
+			attrName = fleet.SSOFleetRoleAttrNamePrefix + strings.TrimPrefix(name, fleetRolePrefix)
+		default:
+			continue
+		}
+		values, err := claimValues(value)
+		if err != nil {
+			return oidcAuth{}, fmt.Errorf("invalid %q claim: %w", name, err)
+		}
+		attrs = append(attrs, fleet.SAMLAttribute{Name: attrName, Values: values})
+	}
+	// Claims are in a map, keep the attributes in a stable order.
+	slices.SortFunc(attrs, func(a, b fleet.SAMLAttribute) int { return strings.Compare(a.Name, b.Name) })
+
+	return oidcAuth{email: email, displayName: displayName, attrs: attrs}, nil
+}
+
+// claimValues converts a string or string array claim to attribute values.
+func claimValues(value any) ([]fleet.SAMLAttributeValue, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []fleet.SAMLAttributeValue{{Value: v}}, nil
+	case []any:
+		values := make([]fleet.SAMLAttributeValue, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected string values, got %T", item)
+			}
+			values = append(values, fleet.SAMLAttributeValue{Value: s})
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("expected a string or an array of strings, got %T", value)
+	}
+}
+
+// oidcProviders caches the discovery documents and signing keys of the OIDC
+// providers, they are shared by all the SSO requests of the process.
+var oidcProviders = &oidcCache{
+	discoveries: make(map[string]cachedDiscovery),
+	keySets:     make(map[string]*oidcKeySet),
+}
+
+type oidcCache struct {
+	mu          sync.Mutex
+	discoveries map[string]cachedDiscovery
+	keySets     map[string]*oidcKeySet
+}
+
+type cachedDiscovery struct {
+	discovery *OIDCDiscovery
+	fetchedAt time.Time
+}
+
+func (c *oidcCache) discovery(ctx context.Context, client *http.Client, issuer string) (*OIDCDiscovery, error) {
+	c.mu.Lock()
+	cached, ok := c.discoveries[issuer]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < oidcDiscoveryTTL {
+		return cached.discovery, nil
+	}
+
+	var discovery OIDCDiscovery
+	if err := getOIDCDocument(ctx, client, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	// The issuer must be exactly the configured one, see
+	// https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderConfigurationValidation
+	if discovery.Issuer != issuer {
+		return nil, fmt.Errorf("discovery document issuer %q does not match issuer URL %q", discovery.Issuer, issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing the authorization, token or JWKS endpoint")
+	}
+
+	c.mu.Lock()
+	c.discoveries[issuer] = cachedDiscovery{discovery: &discovery, fetchedAt: time.Now()}
+	c.mu.Unlock()
+	return &discovery, nil
+}
+
+func (c *oidcCache) keySet(jwksURI string) *oidcKeySet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ks, ok := c.keySets[jwksURI]
+	if !ok {
+		ks = &oidcKeySet{uri: jwksURI}
+		c.keySets[jwksURI] = ks
+	}
+	return ks
+}
+
+// oidcKeySet holds the signing keys published by a provider. Keys are fetched
+// again when they get older than oidcKeySetTTL, or when a token is signed with
+// an unknown key ID, which is how key rotation is detected.
+type oidcKeySet struct {
+	uri       string
+	mu        sync.Mutex
+	keys      []jose.JSONWebKey
+	fetchedAt time.Time
+}
+
+func (ks *oidcKeySet) key(ctx context.Context, client *http.Client, kid string, now time.Time) (*jose.JSONWebKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if !ks.fetchedAt.IsZero() && now.Sub(ks.fetchedAt) < oidcKeySetTTL {
+		if key := findSigningKey(ks.keys, kid); key != nil {
+			return key, nil
+		}
+		if now.Sub(ks.fetchedAt) < oidcKeySetMinRefreshInterval {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+
+	var set jose.JSONWebKeySet
+	if err := getOIDCDocument(ctx, client, ks.uri, &set); err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	ks.keys = set.Keys
+	ks.fetchedAt = now
+
+	if key := findSigningKey(ks.keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// findSigningKey returns the public signing key with the given ID. Tokens
+// without a key ID are accepted only if the provider publishes a single
+// signing key.
+func findSigningKey(keys []jose.JSONWebKey, kid string) *jose.JSONWebKey {
+	var candidates []jose.JSONWebKey
+	for _, key := range keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if !key.IsPublic() {
+			continue
+		}
+		if kid == "" || key.KeyID == kid {
+			candidates = append(candidates, key)
+		}
+	}
+	if len(candidates) != 1 {
+		return nil
+	}
+	return &candidates[0]
+}
+
+func getOIDCDocument(ctx context.Context, client *http.Client, docURL string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, docURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OIDC provider at %s returned %s", docURL, resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxOIDCDocumentSize)).Decode(v); err != nil {
+		return fmt.Errorf("decode %s: %w", docURL, err)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package sso
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/sso/oidctest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testOIDCRedirectURL = "https://fleet.example.com/api/v1/fleet/sso/oidc/callback"
+
+func newTestOIDCProvider(t *testing.T, iss *oidctest.Issuer, settings fleet.OIDCSettings) *OIDCProvider {
+	settings.IssuerURL = iss.URL
+	if settings.ClientID == "" {
+		settings.ClientID = oidctest.ClientID
+	}
+	if settings.ClientSecret == "" {
+		settings.ClientSecret = oidctest.ClientSecret
+	}
+	provider, err := NewOIDCProvider(t.Context(), &settings, testOIDCRedirectURL)
+	require.NoError(t, err)
+	return provider
+}
+
+// oidcLogin runs the whole authorization code flow against the test issuer.
+func oidcLogin(t *testing.T, iss *oidctest.Issuer, provider *OIDCProvider, claims map[string]any) (fleet.Auth, string, error) {
+	store := &mockStore{}
+	sessionID, idpURL, err := CreateOIDCAuthorizationRequest(t.Context(), provider, store, "/dashboard", 0)
+	require.NoError(t, err)
+
+	callback, err := url.Parse(iss.Authorize(idpURL, claims))
+	require.NoError(t, err)
+	return ValidateOIDCCallback(t.Context(), provider, store, sessionID, callback.Query().Get("state"), callback.Query().Get("code"))
+}
+
+func TestOIDCAuthorizationRequest(t *testing.T) {
+	iss := oidctest.NewIssuer(t)
+	provider := newTestOIDCProvider(t, iss, fleet.OIDCSettings{Scopes: []string{"email", "openid", "groups"}})
+
+	store := &mockStore{}
+	sessionID, idpURL, err := CreateOIDCAuthorizationRequest(t.Context(), provider, store, "/hosts", 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, sessionID)
+	require.NotNil(t, store.session)
+	require.NotNil(t, store.session.OIDC)
+	assert.Equal(t, "/hosts", store.session.OriginalURL)
+	assert.Equal(t, iss.URL, store.session.OIDC.Issuer)
+	assert.Equal(t, 5*time.Minute, store.sessionLifetime)
+
+	u, err := url.Parse(idpURL)
+	require.NoError(t, err)
+	assert.Equal(t, iss.URL+"/authorize", u.Scheme+"://"+u.Host+u.Path)
+	q := u.Query()
+	assert.Equal(t, "code", q.Get("response_type"))
+	assert.Equal(t, oidctest.ClientID, q.Get("client_id"))
+	assert.Equal(t, testOIDCRedirectURL, q.Get("redirect_uri"))
+	assert.Equal(t, "openid email groups", q.Get("scope"))
+	assert.Equal(t, store.session.OIDC.State, q.Get("state"))
+	assert.Equal(t, store.session.OIDC.Nonce, q.Get("nonce"))
+	assert.Equal(t, "S256", q.Get("code_challenge_method"))
+	assert.NotEmpty(t, q.Get("code_challenge"))
+	assert.NotContains(t, idpURL, store.session.OIDC.CodeVerifier)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	iss := oidctest.NewIssuer(t)
+	provider := newTestOIDCProvider(t, iss, fleet.OIDCSettings{})
+
+	auth, redirectURL, err := oidcLogin(t, iss, provider, map[string]any{
+		"email":                       "sso@example.com",
+		"email_verified":              true,
+		"name":                        "SSO User",
+		"FLEET_JIT_USER_ROLE_GLOBAL":  "null",
+		"FLEET_JIT_USER_ROLE_FLEET_3": []any{"observer", "maintainer"},
+		"FLEET_JIT_USER_ROLE_FLEET_4": nil,
+		"some_other_claim_not_mapped": "foo",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/dashboard", redirectURL)
+	assert.Equal(t, "sso@example.com", auth.UserID())
+	assert.Equal(t, "SSO User", auth.UserDisplayName())
+	assert.Equal(t, []fleet.SAMLAttribute{
+		{Name: "FLEET_JIT_USER_ROLE_FLEET_3", Values: []fleet.SAMLAttributeValue{{Value: "observer"}, {Value: "maintainer"}}},
+		{Name: "FLEET_JIT_USER_ROLE_FLEET_4"},
+		{Name: "FLEET_JIT_USER_ROLE_GLOBAL", Values: []fleet.SAMLAttributeValue{{Value: "null"}}},
+	}, auth.AssertionAttributes())
+
+	roles, _, err := fleet.RolesFromSSOAttributes(auth.AssertionAttributes())
+	require.NoError(t, err)
+	assert.Nil(t, roles.Global)
+	assert.Equal(t, []fleet.TeamRole{{ID: 3, Role: fleet.RoleMaintainer}}, roles.Teams)
+}
+
+func TestOIDCLoginConfigurableClaims(t *testing.T) {
+	iss := oidctest.NewIssuer(t)
+	provider := newTestOIDCProvider(t, iss, fleet.OIDCSettings{
+		EmailClaim:           "upn",
+		NameClaim:            "preferred_username",
+		GlobalRoleClaim:      "fleet_role",
+		FleetRoleClaimPrefix: "fleet_role_",
+	})
+
+	auth, _, err := oidcLogin(t, iss, provider, map[string]any{
+		"email":              "ignored@example.com",
+		"email_verified":     false,
+		"upn":                "upn@example.com",
+		"preferred_username": "upn",
+		"fleet_role":         "observer",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "upn@example.com", auth.UserID())
+	assert.Equal(t, "upn", auth.UserDisplayName())
+
+	roles, _, err := fleet.RolesFromSSOAttributes(auth.AssertionAttributes())
+	require.NoError(t, err)
+	require.NotNil(t, roles.Global)
+	assert.Equal(t, fleet.RoleObserver, *roles.Global)
+	assert.Empty(t, roles.Teams)
+
+	auth, _, err = oidcLogin(t, iss, provider, map[string]any{
+		"upn":           "upn@example.com",
+		"fleet_role_12": "technician",
+	})
+	require.NoError(t, err)
+	roles, _, err = fleet.RolesFromSSOAttributes(auth.AssertionAttributes())
+	require.NoError(t, err)
+	assert.Nil(t, roles.Global)
+	assert.Equal(t, []fleet.TeamRole{{ID: 12, Role: fleet.RoleTechnician}}, roles.Teams)
+}
+
+func TestOIDCLoginFailures(t *testing.T) {
+	iss := oidctest.NewIssuer(t)
+	provider := newTestOIDCProvider(t, iss, fleet.OIDCSettings{})
+	claims := map[string]any{"email": "sso@example.com"}
+
+	t.Run("state mismatch", func(t *testing.T) {
+		store := &mockStore{}
+		sessionID, idpURL, err := CreateOIDCAuthorizationRequest(t.Context(), provider, store, "/", 0)
+		require.NoError(t, err)
+		callback, err := url.Parse(iss.Authorize(idpURL, claims))
+		require.NoError(t, err)
+		_, _, err = ValidateOIDCCallback(t.Context(), provider, store, sessionID, "other-state", callback.Query().Get("code"))
+		require.ErrorContains(t, err, "state does not match")
+	})
+
+	t.Run("missing session", func(t *testing.T) {
+		_, _, err := ValidateOIDCCallback(t.Context(), provider, &mockStore{}, "", "state", "code")
+		require.ErrorContains(t, err, "missing SSO session")
+	})
+
+	t.Run("SAML session", func(t *testing.T) {
+		store := &mockStore{session: &Session{RequestID: "id", OriginalURL: "/"}}
+		_, _, err := ValidateOIDCCallback(t.Context(), provider, store, "sessionID", "state", "code")
+		require.ErrorContains(t, err, "not created for an OIDC login")
+	})
+
+	t.Run("wrong PKCE verifier", func(t *testing.T) {
+		store := &mockStore{}
+		sessionID, idpURL, err := CreateOIDCAuthorizationRequest(t.Context(), provider, store, "/", 0)
+		require.NoError(t, err)
+		callback, err := url.Parse(iss.Authorize(idpURL, claims))
+		require.NoError(t, err)
+		store.session.OIDC.CodeVerifier = "not-the-verifier-used-for-the-challenge-0123456789"
+		_, _, err = ValidateOIDCCallback(t.Context(), provider, store, sessionID, callback.Query().Get("state"), callback.Query().Get("code"))
+		require.ErrorContains(t, err, "exchange authorization code")
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		_, _, err := oidcLogin(t, iss, provider, map[string]any{"email": "sso@example.com", "email_verified": false})
+		require.ErrorContains(t, err, "not verified")
+	})
+
+	t.Run("missing email", func(t *testing.T) {
+		_, _, err := oidcLogin(t, iss, provider, map[string]any{"name": "No Email"})
+		require.ErrorContains(t, err, `missing the "email" claim`)
+	})
+
+	t.Run("invalid role claim", func(t *testing.T) {
+		_, _, err := oidcLogin(t, iss, provider, map[string]any{"email": "sso@example.com", "FLEET_JIT_USER_ROLE_GLOBAL": 1})
+		require.ErrorContains(t, err, "invalid \"FLEET_JIT_USER_ROLE_GLOBAL\" claim")
+	})
+
+	tokenCases := []struct {
+		name   string
+		modify func(claims map[string]any)
+		errMsg string
+	}{
+		{"nonce mismatch", func(c map[string]any) { c["nonce"] = "other" }, "nonce does not match"},
+		{"wrong audience", func(c map[string]any) { c["aud"] = "other-client" }, "audience"},
+		{"wrong issuer", func(c map[string]any) { c["iss"] = "https://evil.example.com" }, "issuer"},
+		{"expired", func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, "expired"},
+		{"missing expiry", func(c map[string]any) { delete(c, "exp") }, "missing exp"},
+		{"other authorized party", func(c map[string]any) {
+			c["aud"] = []string{oidctest.ClientID, "other-client"}
+			c["azp"] = "other-client"
+		}, "authorized party"},
+	}
+	for _, tc := range tokenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			iss := oidctest.NewIssuer(t)
+			provider := newTestOIDCProvider(t, iss, fleet.OIDCSettings{})
+			iss.OnToken(tc.modify)
+			_, _, err := oidcLogin(t, iss, provider, claims)
+			require.ErrorContains(t, err, tc.errMsg)
+		})
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	oldInterval := oidcKeySetMinRefreshInterval
+	t.Cleanup(func() { oidcKeySetMinRefreshInterval = oldInterval })
+
+	iss := oidctest.NewIssuer(t)
+	provider := newTestOIDCProvider(t, iss, fleet.OIDCSettings{})
+	claims := map[string]any{"email": "sso@example.com"}
+
+	_, _, err := oidcLogin(t, iss, provider, claims)
+	require.NoError(t, err)
+	_, _, err = oidcLogin(t, iss, provider, claims)
+	require.NoError(t, err)
+	require.Equal(t, 1, iss.JWKSHits(), "keys are cached")
+
+	// A token signed with an unknown key shortly after the last fetch is
+	// rejected without hitting the provider.
+	oidcKeySetMinRefreshInterval = time.Hour
+	iss.RotateKey(false)
+	_, _, err = oidcLogin(t, iss, provider, claims)
+	require.ErrorContains(t, err, "unknown signing key")
+	require.Equal(t, 1, iss.JWKSHits())
+
+	// Otherwise the keys are fetched again to pick up the new key.
+	oidcKeySetMinRefreshInterval = 0
+	_, _, err = oidcLogin(t, iss, provider, claims)
+	require.NoError(t, err)
+	require.Equal(t, 2, iss.JWKSHits())
+
+	// Keys are also refreshed once they are too old, so that removed keys stop
+	// being trusted.
+	provider.now = func() time.Time { return time.Now().Add(2 * oidcKeySetTTL) }
+	iss.OnToken(func(c map[string]any) {
+		c["iat"] = provider.now().Unix()
+		c["exp"] = provider.now().Add(time.Minute).Unix()
+	})
+	_, _, err = oidcLogin(t, iss, provider, claims)
+	require.NoError(t, err)
+	require.Equal(t, 3, iss.JWKSHits())
+}
+
+func TestOIDCDiscovery(t *testing.T) {
+	iss := oidctest.NewIssuer(t)
+
+	// The issuer must match exactly.
+	_, err := NewOIDCProvider(t.Context(), &fleet.OIDCSettings{IssuerURL: iss.URL + "/"}, testOIDCRedirectURL)
+	require.ErrorContains(t, err, "does not match issuer URL")
+
+	_, err = NewOIDCProvider(t.Context(), &fleet.OIDCSettings{IssuerURL: iss.URL + "/not-found"}, testOIDCRedirectURL)
+	require.ErrorContains(t, err, "404")
+
+	_, err = NewOIDCProvider(context.Background(), &fleet.OIDCSettings{}, testOIDCRedirectURL)
+	require.ErrorContains(t, err, "missing OIDC issuer URL")
+
+	provider, err := NewOIDCProvider(t.Context(), &fleet.OIDCSettings{IssuerURL: " " + iss.URL + " ", ClientID: oidctest.ClientID}, testOIDCRedirectURL)
+	require.NoError(t, err)
+	assert.Equal(t, iss.URL+"/token", provider.discovery.TokenEndpoint)
+	assert.Equal(t, iss.URL+"/keys", provider.discovery.JWKSURI)
+}
@@ -0,0 +1,244 @@
+// Package oidctest provides an in-process OpenID Connect issuer to test the
+// Fleet OIDC login flow end-to-end without a real identity provider.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// ClientID is the client ID accepted by the issuer.
+	ClientID = "fleet-test-client"
+	// ClientSecret is the client secret accepted by the issuer.
+	ClientSecret = "fleet-test-secret"
+)
+
+// Issuer is an OpenID Connect provider served by an httptest.Server. It
+// supports discovery, the authorization code flow with PKCE (S256) and key
+// rotation.
+type Issuer struct {
+	// URL is the issuer URL, also the base URL of the server.
+	URL string
+
+	t      testing.TB
+	server *httptest.Server
+
+	mu         sync.Mutex
+	keys       []*rsa.PrivateKey
+	kids       []string
+	codes      map[string]authorization
+	jwksHits   int
+	tokenHooks []func(claims map[string]any)
+}
+
+type authorization struct {
+	redirectURI   string
+	codeChallenge string
+	nonce         string
+	claims        map[string]any
+}
+
+// NewIssuer starts a new issuer with a single signing key. The server is
+// closed when the test ends.
+func NewIssuer(t testing.TB) *Issuer {
+	iss := &Issuer{
+		t:     t,
+		codes: make(map[string]authorization),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", iss.handleDiscovery)
+	mux.HandleFunc("/keys", iss.handleKeys)
+	mux.HandleFunc("/token", iss.handleToken)
+	iss.server = httptest.NewServer(mux)
+	t.Cleanup(iss.server.Close)
+	iss.URL = iss.server.URL
+	iss.RotateKey(false)
+	return iss
+}
+
+// RotateKey creates a new signing key that is used for all the ID tokens
+// issued afterwards. If keepOld is true, the previous keys are still
+// published in the JWKS.
+func (iss *Issuer) RotateKey(keepOld bool) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(iss.t, err)
+	kid := randomString(iss.t, 8)
+
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	if !keepOld {
+		iss.keys, iss.kids = nil, nil
+	}
+	iss.keys = append(iss.keys, key)
+	iss.kids = append(iss.kids, kid)
+}
+
+// JWKSHits returns the number of times the JWKS was fetched.
+func (iss *Issuer) JWKSHits() int {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	return iss.jwksHits
+}
+
+// OnToken registers a function that can modify the claims of the ID tokens
+// before they are signed, e.g. to issue invalid tokens.
+func (iss *Issuer) OnToken(fn func(claims map[string]any)) {
+	iss.mu.Lock()
+	defer iss.mu.Unlock()
+	iss.tokenHooks = append(iss.tokenHooks, fn)
+}
+
+// Authorize plays the role of the user signing in at the provider: it takes
+// the authorization URL generated by Fleet and returns the URL the browser is
+// redirected to, carrying the authorization code. The claims are added to the
+// ID token issued for that code.
+func (iss *Issuer) Authorize(authURL string, claims map[string]any) string {
+	u, err := url.Parse(authURL)
+	require.NoError(iss.t, err)
+	q := u.Query()
+	require.Equal(iss.t, "code", q.Get("response_type"))
+	require.Equal(iss.t, ClientID, q.Get("client_id"))
+	require.Equal(iss.t, "S256", q.Get("code_challenge_method"))
+	require.NotEmpty(iss.t, q.Get("code_challenge"))
+	require.NotEmpty(iss.t, q.Get("state"))
+
+	code := randomString(iss.t, 16)
+	iss.mu.Lock()
+	iss.codes[code] = authorization{
+		redirectURI:   q.Get("redirect_uri"),
+		codeChallenge: q.Get("code_challenge"),
+		nonce:         q.Get("nonce"),
+		claims:        claims,
+	}
+	iss.mu.Unlock()
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	require.NoError(iss.t, err)
+	rq := redirect.Query()
+	rq.Set("code", code)
+	rq.Set("state", q.Get("state"))
+	redirect.RawQuery = rq.Encode()
+	return redirect.String()
+}
+
+func (iss *Issuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                iss.URL,
+		"authorization_endpoint":                iss.URL + "/authorize",
+		"token_endpoint":                        iss.URL + "/token",
+		"jwks_uri":                              iss.URL + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (iss *Issuer) handleKeys(w http.ResponseWriter, r *http.Request) {
+	iss.mu.Lock()
+	iss.jwksHits++
+	var set jose.JSONWebKeySet
+	for i, key := range iss.keys {
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key:       key.Public(),
+			KeyID:     iss.kids[i],
+			Algorithm: string(jose.RS256),
+			Use:       "sig",
+		})
+	}
+	iss.mu.Unlock()
+	writeJSON(w, http.StatusOK, set)
+}
+
+func (iss *Issuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != ClientID || clientSecret != ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	iss.mu.Lock()
+	code := r.PostForm.Get("code")
+	authz, ok := iss.codes[code]
+	delete(iss.codes, code)
+	iss.mu.Unlock()
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != authz.redirectURI {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(challenge[:]) != authz.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "PKCE verification failed"})
+		return
+	}
+
+	now := time.Now()
+	claims := map[string]any{
+		"iss":   iss.URL,
+		"sub":   randomString(iss.t, 8),
+		"aud":   ClientID,
+		"iat":   now.Unix(),
+		"exp":   now.Add(5 * time.Minute).Unix(),
+		"nonce": authz.nonce,
+	}
+	for k, v := range authz.claims {
+		claims[k] = v
+	}
+
+	iss.mu.Lock()
+	for _, hook := range iss.tokenHooks {
+		hook(claims)
+	}
+	key, kid := iss.keys[len(iss.keys)-1], iss.kids[len(iss.kids)-1]
+	iss.mu.Unlock()
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", kid),
+	)
+	require.NoError(iss.t, err)
+	idToken, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	require.NoError(iss.t, err)
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(iss.t, 16),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func randomString(t testing.TB, n int) string {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return hex.EncodeToString(b)
+}
@@ -44,6 +44,23 @@ type Session struct {
 	OriginalURL string `json:"original_url"`
 	// Additional request data that may be needed to complete the SSO process.
 	RequestData SSORequestData `json:"request_data,omitempty"`
+	// OIDC holds the state of an OpenID Connect authorization request, it is
+	// only set for sessions created by CreateOIDCAuthorizationRequest.
+	OIDC *OIDCSession `json:"oidc,omitempty"`
+}
+
+// OIDCSession is the state needed to validate the response to an OpenID
+// Connect authorization request.
+type OIDCSession struct {
+	// State must match the "state" parameter of the callback.
+	State string `json:"state"`
+	// Nonce must match the "nonce" claim of the ID token.
+	Nonce string `json:"nonce"`
+	// CodeVerifier is the PKCE verifier sent with the token request.
+	CodeVerifier string `json:"code_verifier"`
+	// Issuer is the issuer the request was sent to, so that a callback can't
+	// be completed against a different provider after a settings change.
+	Issuer string `json:"issuer"`
 }
 
 // SessionStore persists state of a sso session across process boundries and
@@ -53,6 +70,7 @@ type Session struct {
 // a reasonable amount of time, it automatically expires and is removed.
 type SessionStore interface {
 	create(sessionID, requestID, originalURL, metadata string, lifetimeSecs uint, requestData SSORequestData) error
+	put(sessionID string, session *Session, lifetimeSecs uint) error
 	get(sessionID string) (*Session, error)
 	expire(sessionID string) error
 	// Fullfill loads a session with the given session ID, deletes it and returns it.
@@ -69,18 +87,21 @@ type store struct {
 }
 
 func (s *store) create(sessionID, requestID, originalURL, metadata string, lifetimeSecs uint, requestData SSORequestData) error {
+	return s.put(sessionID, &Session{
+		RequestID:   requestID,
+		Metadata:    metadata,
+		OriginalURL: originalURL,
+		RequestData: requestData,
+	}, lifetimeSecs)
+}
+
+func (s *store) put(sessionID string, session *Session, lifetimeSecs uint) error {
 	if len(sessionID) < 8 {
 		return errors.New("request id must be 8 or more characters in length")
 	}
 	conn := redis.ConfigureDoer(s.pool, s.pool.Get())
 	defer conn.Close()
 
-	session := Session{
-		RequestID:   requestID,
-		Metadata:    metadata,
-		OriginalURL: originalURL,
-		RequestData: requestData,
-	}
 	var writer bytes.Buffer
 	err := json.NewEncoder(&writer).Encode(session)
 	if err != nil {
@@ -44,6 +44,33 @@ func (fs FSClient) MacOfficeReleaseNotes() ([]MetadataFileName, error) {
 	return fs.list(macOfficeReleaseNotesPrefix, NewMacOfficeRelNotesMetadata)
 }
 
+// EPSSFeeds returns all the dated EPSS scores feeds found at the top level of 'dir'. The undated
+// EPSSCurrentFeed is not included.
+func (fs FSClient) EPSSFeeds() ([]MetadataFileName, error) {
+	entries, err := os.ReadDir(fs.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []MetadataFileName
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, epssFilePrefix) || name == EPSSCurrentFeed {
+			continue
+		}
+		// mirrors may contain other files, e.g. checksums
+		if !strings.HasSuffix(name, ".csv") && !strings.HasSuffix(name, ".csv.gz") {
+			continue
+		}
+		mfn, err := NewEPSSMetadata(name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, mfn)
+	}
+	return result, nil
+}
+
 func (fs FSClient) list(
 	prefix string,
 	ctor func(filePath string) (MetadataFileName, error),
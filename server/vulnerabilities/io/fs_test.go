@@ -119,4 +119,53 @@ func TestFSClient(t *testing.T) {
 			require.Contains(t, r, c)
 		})
 	})
+	t.Run("EPSSFeeds", func(t *testing.T) {
+		t.Run("directory does not exists", func(t *testing.T) {
+			sut := NewFSClient("asdf")
+			_, err := sut.EPSSFeeds()
+			require.Error(t, err)
+		})
+
+		t.Run("when files contain the wrong date format", func(t *testing.T) {
+			path := t.TempDir()
+			sut := NewFSClient(path)
+
+			f, err := os.Create(filepath.Join(path, "epss_scores-2024_01_01.csv.gz"))
+			require.NoError(t, err)
+			f.Close()
+
+			_, err = sut.EPSSFeeds()
+			require.Error(t, err)
+		})
+
+		t.Run("returns the dated EPSS feeds", func(t *testing.T) {
+			path := t.TempDir()
+			sut := NewFSClient(path)
+
+			for _, name := range []string{
+				"epss_scores-2024-01-01.csv.gz",
+				"epss_scores-2024-01-02.csv",
+				"epss_scores-2024-01-02.csv.gz.sha256",
+				EPSSCurrentFeed,
+				"README.md",
+			} {
+				f, err := os.Create(filepath.Join(path, name))
+				require.NoError(t, err)
+				f.Close()
+			}
+			require.NoError(t, os.Mkdir(filepath.Join(path, "epss_scores-2024-01-03"), 0o755))
+
+			r, err := sut.EPSSFeeds()
+			require.NoError(t, err)
+
+			var names []string
+			for _, mfn := range r {
+				names = append(names, mfn.String())
+			}
+			require.ElementsMatch(t, []string{
+				"epss_scores-2024-01-01.csv.gz",
+				"epss_scores-2024-01-02.csv",
+			}, names)
+		})
+	})
 }
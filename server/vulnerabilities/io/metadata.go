@@ -13,6 +13,12 @@ const (
 	macOfficeReleaseNotesPrefix = "fleet_macoffice_release_notes_"
 	fileExt                     = "json"
 	dateLayout                  = "2006_01_02"
+
+	// EPSS feeds are published by FIRST as epss_scores-YYYY-MM-DD.csv.gz, mirrors are expected to keep
+	// those names.
+	epssFilePrefix  = "epss_scores-"
+	epssDateLayout  = "2006-01-02"
+	EPSSCurrentFeed = "epss_scores-current.csv.gz"
 )
 
 // MSRC Bulletins and other metadata files are published as assets to GH and copies are downloaded to the local FS. The file name
@@ -21,6 +27,8 @@ const (
 type MetadataFileName struct {
 	prefix   string
 	filename string
+	// layout is the date layout used in the file name, dateLayout if empty.
+	layout string
 }
 
 func NewMSRCMetadata(filename string) (MetadataFileName, error) {
@@ -50,7 +58,26 @@ func NewWinOfficeMetadata(filename string) (MetadataFileName, error) {
 	return mfn, err
 }
 
+func NewEPSSMetadata(filename string) (MetadataFileName, error) {
+	mfn := MetadataFileName{prefix: epssFilePrefix, filename: filename, layout: epssDateLayout}
+
+	// Check that the filename contains a valid timestamp
+	_, err := mfn.date()
+
+	return mfn, err
+}
+
 func (mfn MetadataFileName) date() (time.Time, error) {
+	if mfn.layout != "" {
+		timeRaw, ok := strings.CutPrefix(mfn.filename, mfn.prefix)
+		if !ok {
+			return time.Now(), errors.New("invalid file name")
+		}
+		// strip all extensions, e.g. .csv.gz
+		timeRaw, _, _ = strings.Cut(timeRaw, ".")
+		return time.Parse(mfn.layout, timeRaw)
+	}
+
 	parts := strings.Split(mfn.filename, "-")
 
 	if len(parts) != 2 {
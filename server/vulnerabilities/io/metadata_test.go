@@ -37,6 +37,17 @@ func TestSecurityBulletinName(t *testing.T) {
 		require.Contains(t, result, strconv.Itoa(now.Day()))
 	})
 
+	t.Run("NewEPSSMetadata", func(t *testing.T) {
+		sut, err := NewEPSSMetadata("epss_scores-2024-03-05.csv.gz")
+		require.NoError(t, err)
+		d, err := sut.date()
+		require.NoError(t, err)
+		require.Equal(t, time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), d)
+
+		_, err = NewEPSSMetadata(EPSSCurrentFeed)
+		require.Error(t, err)
+	})
+
 	t.Run("String", func(t *testing.T) {
 		sut, err := NewMSRCMetadata("Windows_10-2022_09_10.json")
 		require.NoError(t, err)
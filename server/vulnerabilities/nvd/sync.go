@@ -1,6 +1,7 @@
 package nvd
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/csv"
 	"encoding/json"
@@ -19,6 +20,7 @@ import (
 	"github.com/fleetdm/fleet/v4/server/contexts/license"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/ptr"
+	vulnio "github.com/fleetdm/fleet/v4/server/vulnerabilities/io"
 	"github.com/fleetdm/fleet/v4/server/vulnerabilities/nvd/tools/cvefeed"
 	feednvd "github.com/fleetdm/fleet/v4/server/vulnerabilities/nvd/tools/cvefeed/nvd"
 )
@@ -29,6 +31,7 @@ type SyncOptions struct {
 	CPETranslationsURL   string
 	CVEFeedPrefixURL     string
 	CISAKnownExploitsURL string
+	EPSSFeedURL          string
 	Debug                bool
 }
 
@@ -53,7 +56,7 @@ func Sync(ctx context.Context, opts SyncOptions, logger *slog.Logger) error {
 	}
 	logger.DebugContext(ctx, "CVEs synced", "duration", time.Since(start))
 
-	if err := DownloadEPSSFeed(opts.VulnPath); err != nil {
+	if err := DownloadEPSSFeed(opts.VulnPath, opts.EPSSFeedURL); err != nil {
 		return fmt.Errorf("sync EPSS CVE feed: %w", err)
 	}
 
@@ -69,21 +72,98 @@ const (
 	epssFilename = "epss_scores-current.csv.gz"
 )
 
-// DownloadEPSSFeed downloads the EPSS scores feed.
-func DownloadEPSSFeed(vulnPath string) error {
-	urlString := epssFeedsURL + "/" + epssFilename
-	u, err := url.Parse(urlString)
+// DownloadEPSSFeed downloads the EPSS scores feed. epssFeedURL can be the URL of a mirror of the
+// feed, or a local file or directory for air-gapped deployments. When it is a directory, the most
+// recent epss_scores-YYYY-MM-DD.csv[.gz] feed it contains is used, falling back to
+// epss_scores-current.csv.gz. If epssFeedURL is empty, the feed is downloaded from FIRST.
+func DownloadEPSSFeed(vulnPath string, epssFeedURL string) error {
+	path := filepath.Join(vulnPath, strings.TrimSuffix(epssFilename, ".gz"))
+
+	if epssFeedURL == "" {
+		epssFeedURL = epssFeedsURL + "/" + epssFilename
+	}
+	if filepath.IsAbs(epssFeedURL) {
+		return copyLocalEPSSFeed(epssFeedURL, path)
+	}
+
+	u, err := url.Parse(epssFeedURL)
 	if err != nil {
 		return fmt.Errorf("parse url: %w", err)
 	}
-	path := filepath.Join(vulnPath, strings.TrimSuffix(epssFilename, ".gz"))
 
-	client := fleethttp.NewClient()
-	err = download.DownloadAndExtract(client, u, path)
+	switch u.Scheme {
+	case "http", "https":
+		client := fleethttp.NewClient()
+		if err := download.DownloadAndExtract(client, u, path); err != nil {
+			return fmt.Errorf("download %s: %w", u, err)
+		}
+		return nil
+	case "file":
+		return copyLocalEPSSFeed(u.Path, path)
+	case "":
+		return copyLocalEPSSFeed(epssFeedURL, path)
+	default:
+		return fmt.Errorf("unsupported EPSS feed URL scheme: %s", u.Scheme)
+	}
+}
+
+// copyLocalEPSSFeed extracts (or copies, if not compressed) the local EPSS feed found at src to dst.
+func copyLocalEPSSFeed(src string, dst string) error {
+	info, err := os.Stat(src)
 	if err != nil {
-		return fmt.Errorf("download %s: %w", u, err)
+		return fmt.Errorf("stat EPSS feed: %w", err)
 	}
+	if info.IsDir() {
+		feeds, err := vulnio.NewFSClient(src).EPSSFeeds()
+		if err != nil {
+			return fmt.Errorf("list EPSS feeds: %w", err)
+		}
+		var latest vulnio.MetadataFileName
+		for _, f := range feeds {
+			if latest.Before(f) {
+				latest = f
+			}
+		}
+		if latest.String() != "" {
+			src = filepath.Join(src, latest.String())
+		} else {
+			src = filepath.Join(src, vulnio.EPSSCurrentFeed)
+		}
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open EPSS feed: %w", err)
+	}
+	defer in.Close()
 
+	r := io.Reader(in)
+	if strings.HasSuffix(src, ".gz") {
+		gr, err := gzip.NewReader(in)
+		if err != nil {
+			return fmt.Errorf("gzip reader: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	// atomically write to dst, like download.Download does
+	tmp, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst))
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	defer tmp.Close()
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		return fmt.Errorf("copy EPSS feed: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temporary file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("rename temporary file: %w", err)
+	}
 	return nil
 }
 
@@ -1,8 +1,11 @@
 package nvd
 
 import (
+	"compress/gzip"
 	"context"
+	"io"
 	"log/slog"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -20,12 +23,69 @@ func TestDownloadEPSSFeed(t *testing.T) {
 
 	tempDir := t.TempDir()
 
-	err := DownloadEPSSFeed(tempDir)
+	err := DownloadEPSSFeed(tempDir, "")
 	require.NoError(t, err)
 
 	assert.FileExists(t, filepath.Join(tempDir, strings.TrimSuffix(epssFilename, ".gz")))
 }
 
+func TestDownloadEPSSFeedLocalMirror(t *testing.T) {
+	writeFeed := func(t *testing.T, path string, content string) {
+		f, err := os.Create(path)
+		require.NoError(t, err)
+		defer f.Close()
+		w := io.Writer(f)
+		if strings.HasSuffix(path, ".gz") {
+			gw := gzip.NewWriter(f)
+			defer gw.Close()
+			w = gw
+		}
+		_, err = io.WriteString(w, content)
+		require.NoError(t, err)
+	}
+	readScores := func(t *testing.T, vulnPath string) string {
+		b, err := os.ReadFile(filepath.Join(vulnPath, strings.TrimSuffix(epssFilename, ".gz")))
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	mirror := t.TempDir()
+	writeFeed(t, filepath.Join(mirror, "epss_scores-current.csv.gz"), "current")
+	writeFeed(t, filepath.Join(mirror, "epss_scores-2024-01-01.csv.gz"), "2024-01-01")
+	writeFeed(t, filepath.Join(mirror, "epss_scores-2024-01-03.csv"), "2024-01-03")
+	writeFeed(t, filepath.Join(mirror, "epss_scores-2024-01-02.csv.gz"), "2024-01-02")
+
+	t.Run("directory uses the most recent feed", func(t *testing.T) {
+		vulnPath := t.TempDir()
+		require.NoError(t, DownloadEPSSFeed(vulnPath, mirror))
+		require.Equal(t, "2024-01-03", readScores(t, vulnPath))
+
+		require.NoError(t, DownloadEPSSFeed(vulnPath, "file://"+mirror))
+		require.Equal(t, "2024-01-03", readScores(t, vulnPath))
+	})
+
+	t.Run("directory without dated feeds", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFeed(t, filepath.Join(dir, "epss_scores-current.csv.gz"), "current")
+
+		vulnPath := t.TempDir()
+		require.NoError(t, DownloadEPSSFeed(vulnPath, dir))
+		require.Equal(t, "current", readScores(t, vulnPath))
+
+		require.Error(t, DownloadEPSSFeed(vulnPath, t.TempDir()))
+	})
+
+	t.Run("file", func(t *testing.T) {
+		vulnPath := t.TempDir()
+		require.NoError(t, DownloadEPSSFeed(vulnPath, filepath.Join(mirror, "epss_scores-2024-01-01.csv.gz")))
+		require.Equal(t, "2024-01-01", readScores(t, vulnPath))
+	})
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		require.ErrorContains(t, DownloadEPSSFeed(t.TempDir(), "ftp://example.com/epss_scores-current.csv.gz"), "unsupported")
+	})
+}
+
 func TestDownloadCISAKnownExploitsFeed(t *testing.T) {
 	nettest.Run(t)
 
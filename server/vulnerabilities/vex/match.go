@@ -0,0 +1,104 @@
+package vex
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/sbom"
+	"github.com/fleetdm/fleet/v4/server/vulnerabilities/nvd/tools/wfn"
+)
+
+// Matches returns true if the product of a VEX statement, a package URL or a
+// CPE, identifies the software of the candidate. A product without a version
+// matches all the versions of the software.
+func Matches(product string, c fleet.VEXCandidate) bool {
+	switch {
+	case strings.HasPrefix(product, "pkg:"):
+		return matchPURL(product, sbom.PackageURL(c.Software, ""))
+	case strings.HasPrefix(product, "cpe:"):
+		return c.CPE != "" && matchCPE(product, c.CPE)
+	default:
+		return false
+	}
+}
+
+func matchPURL(product, software string) bool {
+	p, ok := parsePURL(product)
+	if !ok {
+		return false
+	}
+	s, ok := parsePURL(software)
+	if !ok {
+		return false
+	}
+
+	if p.typ != s.typ || !strings.EqualFold(p.name, s.name) {
+		return false
+	}
+	// The namespace of Linux packages is the distribution, which Fleet can only
+	// guess from the vendor of the software, so it is not compared for them.
+	if p.typ != "deb" && p.typ != "rpm" && p.namespace != "" && s.namespace != "" &&
+		!strings.EqualFold(p.namespace, s.namespace) {
+		return false
+	}
+	return p.version == "" || p.version == s.version
+}
+
+type packageURL struct {
+	typ       string
+	namespace string
+	name      string
+	version   string
+}
+
+// parsePURL parses the components of a package URL used for matching, see
+// https://github.com/package-url/purl-spec/blob/master/PURL-SPECIFICATION.rst.
+func parsePURL(s string) (packageURL, bool) {
+	s, ok := strings.CutPrefix(s, "pkg:")
+	if !ok {
+		return packageURL{}, false
+	}
+	// subpath and qualifiers are not used for matching
+	s, _, _ = strings.Cut(s, "#")
+	s, _, _ = strings.Cut(s, "?")
+
+	var p packageURL
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		p.version, s = s[i+1:], s[:i]
+	}
+	typ, path, ok := strings.Cut(strings.Trim(s, "/"), "/")
+	if !ok || path == "" {
+		return packageURL{}, false
+	}
+	p.typ = strings.ToLower(typ)
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		p.namespace, path = path[:i], path[i+1:]
+	}
+	p.name = path
+
+	for _, v := range []*string{&p.namespace, &p.name, &p.version} {
+		unescaped, err := url.PathUnescape(*v)
+		if err != nil {
+			return packageURL{}, false
+		}
+		*v = unescaped
+	}
+	return p, true
+}
+
+func matchCPE(product, software string) bool {
+	p, err := wfn.Parse(product)
+	if err != nil {
+		return false
+	}
+	s, err := wfn.Parse(software)
+	if err != nil {
+		return false
+	}
+
+	if p.Part != s.Part || !strings.EqualFold(p.Vendor, s.Vendor) || !strings.EqualFold(p.Product, s.Product) {
+		return false
+	}
+	return p.Version == wfn.Any || p.Version == wfn.NA || p.Version == s.Version
+}
@@ -0,0 +1,54 @@
+package vex
+
+import (
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatches(t *testing.T) {
+	curl := fleet.VEXCandidate{Software: fleet.Software{Name: "curl", Version: "7.88.1", Source: "deb_packages", Arch: "amd64"}}
+	openssl := fleet.VEXCandidate{
+		Software: fleet.Software{Name: "openssl", Version: "3.0.11", Source: "rpm_packages", Release: "1.el9", Vendor: "Red Hat, Inc."},
+		CPE:      "cpe:2.3:a:openssl:openssl:3.0.11:*:*:*:*:*:*:*",
+	}
+	lib := fleet.VEXCandidate{Software: fleet.Software{Name: "@acme/lib", Version: "1.2.3", Source: "npm_packages"}}
+
+	cases := []struct {
+		product   string
+		candidate fleet.VEXCandidate
+		want      bool
+	}{
+		// package URLs, the distribution namespace of Linux packages is ignored
+		{"pkg:deb/debian/curl", curl, true},
+		{"pkg:deb/ubuntu/curl@7.88.1?arch=amd64", curl, true},
+		{"pkg:deb/debian/curl@7.88.2", curl, false},
+		{"pkg:deb/debian/wget", curl, false},
+		{"pkg:rpm/redhat/curl@7.88.1", curl, false},
+		{"pkg:rpm/redhat/openssl@3.0.11-1.el9", openssl, true},
+		{"pkg:rpm/fedora/openssl@3.0.11", openssl, false},
+		{"pkg:npm/%40acme/lib@1.2.3", lib, true},
+		{"pkg:npm/%40other/lib@1.2.3", lib, false},
+		{"pkg:npm/lib", lib, true},
+		{"pkg:invalid", curl, false},
+
+		// CPEs, a product without version matches all versions
+		{"cpe:2.3:a:openssl:openssl:3.0.11:*:*:*:*:*:*:*", openssl, true},
+		{"cpe:2.3:a:openssl:openssl:*:*:*:*:*:*:*:*", openssl, true},
+		{"cpe:2.3:a:openssl:openssl:-:*:*:*:*:*:*:*", openssl, true},
+		{"cpe:/a:openssl:openssl:3.0.11", openssl, true},
+		{"cpe:2.3:a:openssl:openssl:3.0.12:*:*:*:*:*:*:*", openssl, false},
+		{"cpe:2.3:a:openssl:libssl:3.0.11:*:*:*:*:*:*:*", openssl, false},
+		{"cpe:2.3:o:openssl:openssl:3.0.11:*:*:*:*:*:*:*", openssl, false},
+		// software without a CPE can't match a CPE
+		{"cpe:2.3:a:haxx:curl:7.88.1:*:*:*:*:*:*:*", curl, false},
+
+		{"https://acme.example/products/widget", curl, false},
+	}
+	for _, c := range cases {
+		t.Run(c.product, func(t *testing.T) {
+			require.Equal(t, c.want, Matches(c.product, c.candidate))
+		})
+	}
+}
@@ -0,0 +1,310 @@
+// Package vex ingests VEX (Vulnerability Exploitability eXchange) documents, in
+// the OpenVEX (https://openvex.dev) and CSAF VEX
+// (https://docs.oasis-open.org/csaf/csaf/v2.0/csaf-v2.0.html) formats, and
+// suppresses the software vulnerabilities they declare as not exploitable.
+package vex
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// ErrUnknownFormat is returned when a document is neither an OpenVEX nor a
+// CSAF VEX document.
+var ErrUnknownFormat = errors.New("document is not an OpenVEX or CSAF VEX document")
+
+// Parse parses an OpenVEX or CSAF VEX document. Only the statements about
+// products identified by a package URL or a CPE are returned, as Fleet has no
+// other way to match them with software.
+func Parse(b []byte) (*fleet.VEXDocument, error) {
+	var probe struct {
+		Context  string `json:"@context"`
+		Document *struct {
+			Category string `json:"category"`
+		} `json:"document"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return nil, fmt.Errorf("unmarshal VEX document: %w", err)
+	}
+
+	switch {
+	case strings.Contains(probe.Context, "openvex"):
+		return parseOpenVEX(b)
+	case probe.Document != nil && probe.Document.Category == "csaf_vex":
+		return parseCSAF(b)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+type openVEXDocument struct {
+	ID         string             `json:"@id"`
+	Author     string             `json:"author"`
+	Statements []openVEXStatement `json:"statements"`
+}
+
+type openVEXStatement struct {
+	// Vulnerability is an object with a name in OpenVEX v0.2, a string in
+	// earlier versions.
+	Vulnerability json.RawMessage `json:"vulnerability"`
+	// Products are objects in OpenVEX v0.2, strings in earlier versions.
+	Products        []json.RawMessage `json:"products"`
+	Status          fleet.VEXStatus   `json:"status"`
+	Justification   string            `json:"justification"`
+	ImpactStatement string            `json:"impact_statement"`
+}
+
+type openVEXProduct struct {
+	ID          string `json:"@id"`
+	Identifiers struct {
+		PURL  string `json:"purl"`
+		CPE23 string `json:"cpe23"`
+		CPE22 string `json:"cpe22"`
+	} `json:"identifiers"`
+}
+
+func parseOpenVEX(b []byte) (*fleet.VEXDocument, error) {
+	var doc openVEXDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshal OpenVEX document: %w", err)
+	}
+
+	res := &fleet.VEXDocument{
+		Format:     fleet.VEXFormatOpenVEX,
+		DocumentID: doc.ID,
+		Author:     doc.Author,
+	}
+	for i, st := range doc.Statements {
+		cve, err := openVEXVulnerability(st.Vulnerability)
+		if err != nil {
+			return nil, fmt.Errorf("statement %d: %w", i, err)
+		}
+		if err := validateStatus(st.Status); err != nil {
+			return nil, fmt.Errorf("statement %d: %w", i, err)
+		}
+
+		for _, raw := range st.Products {
+			for _, product := range openVEXProductIDs(raw) {
+				res.Statements = append(res.Statements, fleet.VEXStatement{
+					CVE:             cve,
+					Product:         product,
+					Status:          st.Status,
+					Justification:   st.Justification,
+					ImpactStatement: st.ImpactStatement,
+				})
+			}
+		}
+	}
+	return res, nil
+}
+
+func openVEXVulnerability(raw json.RawMessage) (string, error) {
+	var name string
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+		var v struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return "", fmt.Errorf("unmarshal vulnerability: %w", err)
+		}
+		name = v.Name
+	} else if err := json.Unmarshal(raw, &name); err != nil {
+		return "", fmt.Errorf("unmarshal vulnerability: %w", err)
+	}
+
+	if name == "" {
+		return "", errors.New("missing vulnerability name")
+	}
+	return strings.ToUpper(name), nil
+}
+
+// openVEXProductIDs returns the package URLs and CPEs identifying an OpenVEX
+// product.
+func openVEXProductIDs(raw json.RawMessage) []string {
+	var ids []string
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		ids = append(ids, s)
+	} else {
+		var p openVEXProduct
+		if err := json.Unmarshal(raw, &p); err != nil {
+			return nil
+		}
+		ids = append(ids, p.ID, p.Identifiers.PURL, p.Identifiers.CPE23, p.Identifiers.CPE22)
+	}
+	return matchableIDs(ids)
+}
+
+type csafDocument struct {
+	Document struct {
+		Publisher struct {
+			Name string `json:"name"`
+		} `json:"publisher"`
+		Tracking struct {
+			ID string `json:"id"`
+		} `json:"tracking"`
+	} `json:"document"`
+	ProductTree     csafProductTree     `json:"product_tree"`
+	Vulnerabilities []csafVulnerability `json:"vulnerabilities"`
+}
+
+type csafProductTree struct {
+	Branches         []csafBranch      `json:"branches"`
+	FullProductNames []csafFullProduct `json:"full_product_names"`
+	Relationships    []struct {
+		FullProductName csafFullProduct `json:"full_product_name"`
+	} `json:"relationships"`
+}
+
+type csafBranch struct {
+	Branches []csafBranch     `json:"branches"`
+	Product  *csafFullProduct `json:"product"`
+}
+
+type csafFullProduct struct {
+	ProductID string `json:"product_id"`
+	Helper    struct {
+		PURL string `json:"purl"`
+		CPE  string `json:"cpe"`
+	} `json:"product_identification_helper"`
+}
+
+type csafVulnerability struct {
+	CVE           string              `json:"cve"`
+	ProductStatus map[string][]string `json:"product_status"`
+	Flags         []struct {
+		Label      string   `json:"label"`
+		ProductIDs []string `json:"product_ids"`
+	} `json:"flags"`
+	Threats []struct {
+		Category   string   `json:"category"`
+		Details    string   `json:"details"`
+		ProductIDs []string `json:"product_ids"`
+	} `json:"threats"`
+}
+
+// csafStatuses maps the CSAF product status groups to VEX statuses.
+var csafStatuses = map[string]fleet.VEXStatus{
+	"known_not_affected":  fleet.VEXStatusNotAffected,
+	"fixed":               fleet.VEXStatusFixed,
+	"first_fixed":         fleet.VEXStatusFixed,
+	"known_affected":      fleet.VEXStatusAffected,
+	"first_affected":      fleet.VEXStatusAffected,
+	"last_affected":       fleet.VEXStatusAffected,
+	"under_investigation": fleet.VEXStatusUnderInvestigation,
+}
+
+func parseCSAF(b []byte) (*fleet.VEXDocument, error) {
+	var doc csafDocument
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshal CSAF document: %w", err)
+	}
+
+	products := make(map[string][]string)
+	addProduct := func(p csafFullProduct) {
+		if p.ProductID != "" {
+			products[p.ProductID] = matchableIDs([]string{p.Helper.PURL, p.Helper.CPE})
+		}
+	}
+	var walk func([]csafBranch)
+	walk = func(branches []csafBranch) {
+		for _, br := range branches {
+			if br.Product != nil {
+				addProduct(*br.Product)
+			}
+			walk(br.Branches)
+		}
+	}
+	walk(doc.ProductTree.Branches)
+	for _, p := range doc.ProductTree.FullProductNames {
+		addProduct(p)
+	}
+	for _, r := range doc.ProductTree.Relationships {
+		addProduct(r.FullProductName)
+	}
+
+	res := &fleet.VEXDocument{
+		Format:     fleet.VEXFormatCSAF,
+		DocumentID: doc.Document.Tracking.ID,
+		Author:     doc.Document.Publisher.Name,
+	}
+	for i, v := range doc.Vulnerabilities {
+		if v.CVE == "" {
+			// vulnerabilities without a CVE can't be matched with Fleet's
+			// vulnerabilities
+			continue
+		}
+
+		// justifications and impact statements are given per product
+		justifications := make(map[string]string)
+		for _, f := range v.Flags {
+			for _, id := range f.ProductIDs {
+				justifications[id] = f.Label
+			}
+		}
+		impacts := make(map[string]string)
+		for _, t := range v.Threats {
+			if t.Category != "impact" {
+				continue
+			}
+			for _, id := range t.ProductIDs {
+				impacts[id] = t.Details
+			}
+		}
+
+		for group, ids := range v.ProductStatus {
+			status, ok := csafStatuses[group]
+			if !ok {
+				// e.g. "recommended", which is not a VEX status
+				continue
+			}
+			for _, id := range ids {
+				pids, ok := products[id]
+				if !ok {
+					return nil, fmt.Errorf("vulnerability %d: unknown product %q", i, id)
+				}
+				for _, product := range pids {
+					res.Statements = append(res.Statements, fleet.VEXStatement{
+						CVE:             strings.ToUpper(v.CVE),
+						Product:         product,
+						Status:          status,
+						Justification:   justifications[id],
+						ImpactStatement: impacts[id],
+					})
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+func validateStatus(s fleet.VEXStatus) error {
+	switch s {
+	case fleet.VEXStatusNotAffected, fleet.VEXStatusAffected, fleet.VEXStatusFixed, fleet.VEXStatusUnderInvestigation:
+		return nil
+	default:
+		return fmt.Errorf("invalid status %q", s)
+	}
+}
+
+// matchableIDs returns the unique package URLs and CPEs of ids.
+func matchableIDs(ids []string) []string {
+	var res []string
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if !strings.HasPrefix(id, "pkg:") && !strings.HasPrefix(id, "cpe:") {
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			res = append(res, id)
+		}
+	}
+	return res
+}
@@ -0,0 +1,147 @@
+package vex
+
+import (
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOpenVEX(t *testing.T) {
+	doc, err := Parse([]byte(`{
+		"@context": "https://openvex.dev/ns/v0.2.0",
+		"@id": "https://acme.example/vex/2024-001",
+		"author": "ACME Security",
+		"statements": [
+			{
+				"vulnerability": {"name": "cve-2024-0001"},
+				"products": [
+					{"@id": "pkg:deb/debian/curl@7.88.1"},
+					{"@id": "https://acme.example/products/widget", "identifiers": {"cpe23": "cpe:2.3:a:acme:widget:1.0:*:*:*:*:*:*:*"}},
+					{"@id": "https://acme.example/products/unidentified"}
+				],
+				"status": "not_affected",
+				"justification": "vulnerable_code_not_in_execute_path",
+				"impact_statement": "The affected function is never called."
+			},
+			{
+				"vulnerability": "CVE-2024-0002",
+				"products": ["pkg:npm/%40acme/lib@1.2.3"],
+				"status": "fixed"
+			}
+		]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, fleet.VEXFormatOpenVEX, doc.Format)
+	require.Equal(t, "https://acme.example/vex/2024-001", doc.DocumentID)
+	require.Equal(t, "ACME Security", doc.Author)
+	require.Equal(t, []fleet.VEXStatement{
+		{
+			CVE:             "CVE-2024-0001",
+			Product:         "pkg:deb/debian/curl@7.88.1",
+			Status:          fleet.VEXStatusNotAffected,
+			Justification:   "vulnerable_code_not_in_execute_path",
+			ImpactStatement: "The affected function is never called.",
+		},
+		{
+			CVE:             "CVE-2024-0001",
+			Product:         "cpe:2.3:a:acme:widget:1.0:*:*:*:*:*:*:*",
+			Status:          fleet.VEXStatusNotAffected,
+			Justification:   "vulnerable_code_not_in_execute_path",
+			ImpactStatement: "The affected function is never called.",
+		},
+		{
+			CVE:     "CVE-2024-0002",
+			Product: "pkg:npm/%40acme/lib@1.2.3",
+			Status:  fleet.VEXStatusFixed,
+		},
+	}, doc.Statements)
+
+	_, err = Parse([]byte(`{"@context": "https://openvex.dev/ns/v0.2.0", "statements": [{"vulnerability": "CVE-2024-0001", "status": "unknown"}]}`))
+	require.ErrorContains(t, err, `invalid status "unknown"`)
+
+	_, err = Parse([]byte(`{"@context": "https://openvex.dev/ns/v0.2.0", "statements": [{"vulnerability": {}, "status": "fixed"}]}`))
+	require.ErrorContains(t, err, "missing vulnerability name")
+}
+
+func TestParseCSAF(t *testing.T) {
+	doc, err := Parse([]byte(`{
+		"document": {
+			"category": "csaf_vex",
+			"publisher": {"name": "ACME PSIRT"},
+			"tracking": {"id": "ACME-VEX-2024-0001"}
+		},
+		"product_tree": {
+			"branches": [{
+				"category": "vendor",
+				"name": "ACME",
+				"branches": [{
+					"category": "product_version",
+					"name": "1.0",
+					"product": {
+						"product_id": "WIDGET-1.0",
+						"product_identification_helper": {"cpe": "cpe:2.3:a:acme:widget:1.0:*:*:*:*:*:*:*"}
+					}
+				}]
+			}],
+			"full_product_names": [{
+				"product_id": "CURL",
+				"product_identification_helper": {"purl": "pkg:deb/debian/curl@7.88.1"}
+			}],
+			"relationships": [{
+				"full_product_name": {"product_id": "WIDGET-ON-LINUX"}
+			}]
+		},
+		"vulnerabilities": [
+			{
+				"cve": "CVE-2024-0001",
+				"product_status": {
+					"known_not_affected": ["WIDGET-1.0", "WIDGET-ON-LINUX"],
+					"known_affected": ["CURL"],
+					"recommended": ["CURL"]
+				},
+				"flags": [{"label": "component_not_present", "product_ids": ["WIDGET-1.0"]}],
+				"threats": [
+					{"category": "impact", "details": "Widget doesn't ship the component.", "product_ids": ["WIDGET-1.0"]},
+					{"category": "exploit_status", "details": "none", "product_ids": ["CURL"]}
+				]
+			},
+			{
+				"ids": [{"system_name": "ACME", "text": "ACME-1"}],
+				"product_status": {"fixed": ["CURL"]}
+			}
+		]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, fleet.VEXFormatCSAF, doc.Format)
+	require.Equal(t, "ACME-VEX-2024-0001", doc.DocumentID)
+	require.Equal(t, "ACME PSIRT", doc.Author)
+	require.ElementsMatch(t, []fleet.VEXStatement{
+		{
+			CVE:             "CVE-2024-0001",
+			Product:         "cpe:2.3:a:acme:widget:1.0:*:*:*:*:*:*:*",
+			Status:          fleet.VEXStatusNotAffected,
+			Justification:   "component_not_present",
+			ImpactStatement: "Widget doesn't ship the component.",
+		},
+		{
+			CVE:     "CVE-2024-0001",
+			Product: "pkg:deb/debian/curl@7.88.1",
+			Status:  fleet.VEXStatusAffected,
+		},
+	}, doc.Statements)
+
+	_, err = Parse([]byte(`{"document": {"category": "csaf_vex"}, "vulnerabilities": [{"cve": "CVE-2024-0001", "product_status": {"fixed": ["NOPE"]}}]}`))
+	require.ErrorContains(t, err, `unknown product "NOPE"`)
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	_, err := Parse([]byte(`{"bomFormat": "CycloneDX"}`))
+	require.ErrorIs(t, err, ErrUnknownFormat)
+
+	_, err = Parse([]byte(`{"document": {"category": "csaf_security_advisory"}}`))
+	require.ErrorIs(t, err, ErrUnknownFormat)
+
+	_, err = Parse([]byte(`not json`))
+	require.ErrorContains(t, err, "unmarshal VEX document")
+}
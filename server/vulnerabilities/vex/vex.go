@@ -0,0 +1,203 @@
+package vex
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// Apply matches the suppressing statements of all the VEX documents against
+// the software vulnerabilities, replaces the VEX suppressions with the
+// matching ones and deletes the suppressed software vulnerabilities. It
+// returns the suppressed vulnerabilities, so they can be excluded from the
+// vulnerability automations.
+//
+// It must run after the vulnerability matchers, as they re-insert the
+// suppressed software vulnerabilities on every run.
+func Apply(ctx context.Context, ds fleet.Datastore, logger *slog.Logger) ([]fleet.SoftwareVulnerability, error) {
+	statements, err := ds.ListVEXStatements(ctx, fleet.VEXSuppressingStatuses)
+	if err != nil {
+		return nil, fmt.Errorf("list VEX statements: %w", err)
+	}
+
+	byCVE := make(map[string][]fleet.VEXStatement)
+	for _, st := range statements {
+		byCVE[st.CVE] = append(byCVE[st.CVE], st)
+	}
+	cves := make([]string, 0, len(byCVE))
+	for cve := range byCVE {
+		cves = append(cves, cve)
+	}
+
+	var candidates []fleet.VEXCandidate
+	if len(cves) > 0 {
+		candidates, err = ds.ListVEXCandidates(ctx, cves)
+		if err != nil {
+			return nil, fmt.Errorf("list VEX candidates: %w", err)
+		}
+	}
+
+	var suppressions []fleet.VEXSuppression
+	var suppressed []fleet.SoftwareVulnerability
+	for _, c := range candidates {
+		for _, st := range byCVE[c.CVE] {
+			if !Matches(st.Product, c) {
+				continue
+			}
+			suppressions = append(suppressions, fleet.VEXSuppression{
+				SoftwareID:     c.ID,
+				CVE:            c.CVE,
+				VEXStatementID: st.ID,
+			})
+			suppressed = append(suppressed, fleet.SoftwareVulnerability{SoftwareID: c.ID, CVE: c.CVE})
+			// the first matching statement wins
+			break
+		}
+	}
+
+	if err := ds.ReplaceVEXSuppressions(ctx, suppressions); err != nil {
+		return nil, fmt.Errorf("replace VEX suppressions: %w", err)
+	}
+	logger.DebugContext(ctx, "applied VEX statements", "statements", len(statements), "suppressed", len(suppressed))
+	return suppressed, nil
+}
+
+// ImportDirectory synchronizes the VEX documents of the directory dir (the
+// *.json files at its top level) with the VEX documents of the
+// fleet.VEXDocumentSourceDirectory source: documents are named after their
+// file, new and modified files are (re-)imported and the documents of the
+// removed files are deleted. Documents that can't be imported are logged and
+// skipped.
+//
+// Like the documents uploaded via the API, the imported and deleted documents
+// create activities, without a user. newActivityFn may be nil when the Fleet
+// service isn't available (e.g. the vuln_processing command), no activities
+// are created then.
+func ImportDirectory(ctx context.Context, ds fleet.Datastore, logger *slog.Logger, dir string, newActivityFn fleet.NewActivityFunc) error {
+	newActivity := func(activity fleet.ActivityDetails) error {
+		if newActivityFn == nil {
+			return nil
+		}
+		if err := newActivityFn(ctx, nil, activity); err != nil {
+			return fmt.Errorf("create activity for VEX document: %w", err)
+		}
+		return nil
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("read VEX directory: %w", err)
+	}
+
+	existing, err := ds.ListVEXDocuments(ctx)
+	if err != nil {
+		return fmt.Errorf("list VEX documents: %w", err)
+	}
+	byName := make(map[string]*fleet.VEXDocument, len(existing))
+	for _, doc := range existing {
+		byName[doc.Name] = doc
+	}
+
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".json") {
+			continue
+		}
+		name := entry.Name()
+		seen[name] = true
+		logger := logger.With("file", name)
+
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			logger.ErrorContext(ctx, "read VEX document", "err", err)
+			continue
+		}
+		sum := sha256.Sum256(b)
+		checksum := hex.EncodeToString(sum[:])
+
+		prev := byName[name]
+		if prev != nil && prev.Source != fleet.VEXDocumentSourceDirectory {
+			logger.WarnContext(ctx, "skipping VEX document, a document with the same name was uploaded via the API")
+			continue
+		}
+		if prev != nil && prev.SHA256 == checksum {
+			continue
+		}
+
+		doc, err := Parse(b)
+		if err != nil {
+			logger.ErrorContext(ctx, "parse VEX document", "err", err)
+			continue
+		}
+		doc.Name = name
+		doc.SHA256 = checksum
+		doc.Source = fleet.VEXDocumentSourceDirectory
+
+		if prev != nil {
+			if err := ds.DeleteVEXDocument(ctx, prev.ID); err != nil {
+				return fmt.Errorf("delete modified VEX document %s: %w", name, err)
+			}
+			if err := newActivity(fleet.ActivityTypeDeletedVEXDocument{
+				VEXDocumentID:   prev.ID,
+				VEXDocumentName: prev.Name,
+			}); err != nil {
+				return err
+			}
+		}
+		created, err := ds.NewVEXDocument(ctx, doc)
+		if err != nil {
+			return fmt.Errorf("create VEX document %s: %w", name, err)
+		}
+		if err := newActivity(fleet.ActivityTypeAddedVEXDocument{
+			VEXDocumentID:   created.ID,
+			VEXDocumentName: created.Name,
+			StatementsCount: len(doc.Statements),
+		}); err != nil {
+			return err
+		}
+		logger.InfoContext(ctx, "imported VEX document", "statements", len(doc.Statements))
+	}
+
+	for _, doc := range existing {
+		if doc.Source != fleet.VEXDocumentSourceDirectory || seen[doc.Name] {
+			continue
+		}
+		if err := ds.DeleteVEXDocument(ctx, doc.ID); err != nil {
+			return fmt.Errorf("delete removed VEX document %s: %w", doc.Name, err)
+		}
+		if err := newActivity(fleet.ActivityTypeDeletedVEXDocument{
+			VEXDocumentID:   doc.ID,
+			VEXDocumentName: doc.Name,
+		}); err != nil {
+			return err
+		}
+		logger.InfoContext(ctx, "deleted VEX document of removed file", "file", doc.Name)
+	}
+	return nil
+}
+
+// ExcludeSuppressed returns the vulnerabilities of vulns that are not in
+// suppressed.
+func ExcludeSuppressed(vulns, suppressed []fleet.SoftwareVulnerability) []fleet.SoftwareVulnerability {
+	if len(suppressed) == 0 {
+		return vulns
+	}
+	keys := make(map[string]struct{}, len(suppressed))
+	for _, v := range suppressed {
+		keys[v.Key()] = struct{}{}
+	}
+	res := make([]fleet.SoftwareVulnerability, 0, len(vulns))
+	for _, v := range vulns {
+		if _, ok := keys[v.Key()]; !ok {
+			res = append(res, v)
+		}
+	}
+	return res
+}
@@ -0,0 +1,163 @@
+package vex
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+
+	ds.ListVEXStatementsFunc = func(ctx context.Context, statuses []fleet.VEXStatus) ([]fleet.VEXStatement, error) {
+		require.Equal(t, fleet.VEXSuppressingStatuses, statuses)
+		return []fleet.VEXStatement{
+			{ID: 1, CVE: "CVE-2024-0001", Product: "pkg:deb/debian/curl@7.88.1", Status: fleet.VEXStatusNotAffected},
+			{ID: 2, CVE: "CVE-2024-0001", Product: "pkg:deb/debian/curl", Status: fleet.VEXStatusNotAffected},
+			{ID: 3, CVE: "CVE-2024-0002", Product: "cpe:2.3:a:openssl:openssl:*:*:*:*:*:*:*:*", Status: fleet.VEXStatusFixed},
+		}, nil
+	}
+	ds.ListVEXCandidatesFunc = func(ctx context.Context, cves []string) ([]fleet.VEXCandidate, error) {
+		require.ElementsMatch(t, []string{"CVE-2024-0001", "CVE-2024-0002"}, cves)
+		return []fleet.VEXCandidate{
+			{Software: fleet.Software{ID: 1, Name: "curl", Version: "7.88.1", Source: "deb_packages"}, CVE: "CVE-2024-0001"},
+			{Software: fleet.Software{ID: 2, Name: "curl", Version: "8.5.0", Source: "deb_packages"}, CVE: "CVE-2024-0001"},
+			{Software: fleet.Software{ID: 3, Name: "wget", Version: "1.21", Source: "deb_packages"}, CVE: "CVE-2024-0001"},
+			{Software: fleet.Software{ID: 4, Name: "openssl", Version: "3.0.11", Source: "deb_packages"}, CVE: "CVE-2024-0002", CPE: "cpe:2.3:a:openssl:openssl:3.0.11:*:*:*:*:*:*:*"},
+		}, nil
+	}
+	var replaced []fleet.VEXSuppression
+	ds.ReplaceVEXSuppressionsFunc = func(ctx context.Context, suppressions []fleet.VEXSuppression) error {
+		replaced = suppressions
+		return nil
+	}
+
+	suppressed, err := Apply(ctx, ds, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	require.Equal(t, []fleet.SoftwareVulnerability{
+		{SoftwareID: 1, CVE: "CVE-2024-0001"},
+		{SoftwareID: 2, CVE: "CVE-2024-0001"},
+		{SoftwareID: 4, CVE: "CVE-2024-0002"},
+	}, suppressed)
+	// the first matching statement is recorded
+	require.Equal(t, []fleet.VEXSuppression{
+		{SoftwareID: 1, CVE: "CVE-2024-0001", VEXStatementID: 1},
+		{SoftwareID: 2, CVE: "CVE-2024-0001", VEXStatementID: 2},
+		{SoftwareID: 4, CVE: "CVE-2024-0002", VEXStatementID: 3},
+	}, replaced)
+
+	// without statements, the suppressions are cleared
+	ds.ListVEXStatementsFunc = func(ctx context.Context, statuses []fleet.VEXStatus) ([]fleet.VEXStatement, error) {
+		return nil, nil
+	}
+	ds.ListVEXCandidatesFuncInvoked = false
+	suppressed, err = Apply(ctx, ds, slog.New(slog.DiscardHandler))
+	require.NoError(t, err)
+	require.Empty(t, suppressed)
+	require.Empty(t, replaced)
+	require.False(t, ds.ListVEXCandidatesFuncInvoked)
+}
+
+func TestImportDirectory(t *testing.T) {
+	ctx := context.Background()
+	ds := new(mock.Store)
+	dir := t.TempDir()
+
+	openVEX := []byte(`{"@context": "https://openvex.dev/ns/v0.2.0", "statements": [{"vulnerability": "CVE-2024-0001", "products": ["pkg:deb/debian/curl"], "status": "not_affected"}]}`)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.json"), openVEX, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "unchanged.json"), openVEX, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "modified.json"), openVEX, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "uploaded.json"), openVEX, 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "invalid.json"), []byte(`{}`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte(`not a VEX document`), 0o600))
+
+	ds.ListVEXDocumentsFunc = func(ctx context.Context) ([]*fleet.VEXDocument, error) {
+		return []*fleet.VEXDocument{
+			{ID: 1, Name: "unchanged.json", SHA256: sha256Hex(openVEX), Source: fleet.VEXDocumentSourceDirectory},
+			{ID: 2, Name: "modified.json", SHA256: "outdated", Source: fleet.VEXDocumentSourceDirectory},
+			{ID: 3, Name: "removed.json", Source: fleet.VEXDocumentSourceDirectory},
+			{ID: 4, Name: "uploaded.json", Source: fleet.VEXDocumentSourceAPI},
+			{ID: 5, Name: "api-only.json", Source: fleet.VEXDocumentSourceAPI},
+		}, nil
+	}
+	var deleted []uint
+	ds.DeleteVEXDocumentFunc = func(ctx context.Context, id uint) error {
+		deleted = append(deleted, id)
+		return nil
+	}
+	var created []string
+	ds.NewVEXDocumentFunc = func(ctx context.Context, doc *fleet.VEXDocument) (*fleet.VEXDocument, error) {
+		require.Equal(t, fleet.VEXDocumentSourceDirectory, doc.Source)
+		require.Equal(t, sha256Hex(openVEX), doc.SHA256)
+		require.Len(t, doc.Statements, 1)
+		created = append(created, doc.Name)
+		doc.ID = uint(100 + len(created))
+		return doc, nil
+	}
+	var activities []fleet.ActivityDetails
+	newActivity := func(ctx context.Context, user *fleet.User, activity fleet.ActivityDetails) error {
+		require.Nil(t, user)
+		activities = append(activities, activity)
+		return nil
+	}
+
+	require.NoError(t, ImportDirectory(ctx, ds, slog.New(slog.DiscardHandler), dir, newActivity))
+	require.ElementsMatch(t, []string{"modified.json", "new.json"}, created)
+	// the modified document is replaced, the removed one deleted
+	require.ElementsMatch(t, []uint{2, 3}, deleted)
+
+	// the imports and deletions create activities without a user
+	var added []fleet.ActivityTypeAddedVEXDocument
+	var removed []fleet.ActivityTypeDeletedVEXDocument
+	for _, a := range activities {
+		switch a := a.(type) {
+		case fleet.ActivityTypeAddedVEXDocument:
+			require.NotZero(t, a.VEXDocumentID)
+			require.Equal(t, 1, a.StatementsCount)
+			added = append(added, a)
+		case fleet.ActivityTypeDeletedVEXDocument:
+			removed = append(removed, a)
+		default:
+			t.Fatalf("unexpected activity %T", a)
+		}
+	}
+	require.Len(t, added, 2)
+	require.ElementsMatch(t, []fleet.ActivityTypeDeletedVEXDocument{
+		{VEXDocumentID: 2, VEXDocumentName: "modified.json"},
+		{VEXDocumentID: 3, VEXDocumentName: "removed.json"},
+	}, removed)
+
+	// without the Fleet service, no activities are created
+	activities = nil
+	require.NoError(t, ImportDirectory(ctx, ds, slog.New(slog.DiscardHandler), dir, nil))
+	require.Empty(t, activities)
+
+	require.Error(t, ImportDirectory(ctx, ds, slog.New(slog.DiscardHandler), filepath.Join(dir, "missing"), newActivity))
+}
+
+func TestExcludeSuppressed(t *testing.T) {
+	vulns := []fleet.SoftwareVulnerability{
+		{SoftwareID: 1, CVE: "CVE-2024-0001"},
+		{SoftwareID: 1, CVE: "CVE-2024-0002"},
+		{SoftwareID: 2, CVE: "CVE-2024-0001"},
+	}
+	require.Equal(t, vulns, ExcludeSuppressed(vulns, nil))
+	require.Equal(t, []fleet.SoftwareVulnerability{
+		{SoftwareID: 1, CVE: "CVE-2024-0002"},
+		{SoftwareID: 2, CVE: "CVE-2024-0001"},
+	}, ExcludeSuppressed(vulns, []fleet.SoftwareVulnerability{{SoftwareID: 1, CVE: "CVE-2024-0001"}}))
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
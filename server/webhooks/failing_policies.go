@@ -3,7 +3,6 @@ package webhooks
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"log/slog"
 	"net/url"
 	"path"
@@ -16,6 +15,8 @@ import (
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/platform/endpointer"
 	fleethttp "github.com/fleetdm/fleet/v4/server/platform/http"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/worker"
 )
 
 // recordWebhookFailedActivity records a failed_automation_webhook
@@ -27,45 +28,28 @@ func recordWebhookFailedActivity(
 	newActivitySvc activity_api.NewActivityService,
 	policy *fleet.Policy,
 	batch []fleet.PolicySetHost,
-	postErr error,
+	delivery *fleet.WebhookDelivery,
 	logger *slog.Logger,
 ) {
-	var statusCode int
-	if sc, ok := errors.AsType[interface {
-		error
-		StatusCode() int
-	}](postErr); ok {
-		statusCode = sc.StatusCode()
-	}
-
-	errResponse := ""
-	if b, ok := errors.AsType[interface {
-		error
-		Body() string
-	}](postErr); ok {
-		errResponse = b.Body()
-	}
-	if errResponse == "" {
-		// network-level failures (e.g. connection refused) have no server
-		// response; fall back to the (masked) error message.
-		errResponse = fleethttp.MaskURLError(postErr).Error()
-	}
-	hostIDs := make([]uint, len(batch))
-	for i, host := range batch {
-		hostIDs[i] = host.ID
-	}
-
 	if err := newActivitySvc.NewActivity(ctx, nil, fleet.ActivityTypeFailedAutomationWebhook{
 		PolicyID:      policy.ID,
-		HostIDList:    hostIDs,
-		StatusCode:    statusCode,
-		ErrorResponse: errResponse,
+		HostIDList:    batchHostIDs(batch),
+		StatusCode:    ptr.ValOrZero(delivery.ResponseStatusCode),
+		ErrorResponse: delivery.Error,
 	}); err != nil {
 		logger.WarnContext(ctx, "failed to record webhook policy automation failure activity",
 			"policy_id", policy.ID, "err", err)
 	}
 }
 
+func batchHostIDs(batch []fleet.PolicySetHost) []uint {
+	hostIDs := make([]uint, len(batch))
+	for i, host := range batch {
+		hostIDs[i] = host.ID
+	}
+	return hostIDs
+}
+
 // recordWebhookRanActivity records a ran_automation_webhook activity
 // for every host in a batch whose POST was accepted by the remote server.
 // Failures to record are logged and swallowed so they don't affect the send.
@@ -76,13 +60,9 @@ func recordWebhookRanActivity(
 	batch []fleet.PolicySetHost,
 	logger *slog.Logger,
 ) {
-	hostIDs := make([]uint, len(batch))
-	for i, host := range batch {
-		hostIDs[i] = host.ID
-	}
 	if err := newActivitySvc.NewActivity(ctx, nil, fleet.ActivityTypeRanAutomationWebhook{
 		PolicyID:   policy.ID,
-		HostIDList: hostIDs,
+		HostIDList: batchHostIDs(batch),
 	}); err != nil {
 		logger.WarnContext(ctx, "failed to record webhook policy automation queued activity",
 			"policy_id", policy.ID, "err", err)
@@ -91,9 +71,11 @@ func recordWebhookRanActivity(
 
 // SendFailingPoliciesBatchedPOSTs sends a failing policy to the provided
 // webhook URL. It sends in batches if hostBatchSize > 0. After a successful
-// send, the corresponding hosts are removed from the failing policies set.
+// send, or once a send that failed with a retryable error is queued for
+// retry, the corresponding hosts are removed from the failing policies set.
 func SendFailingPoliciesBatchedPOSTs(
 	ctx context.Context,
+	ds fleet.Datastore,
 	policy *fleet.Policy,
 	failingPoliciesSet fleet.FailingPolicySet,
 	hostBatchSize int,
@@ -153,11 +135,29 @@ func SendFailingPoliciesBatchedPOSTs(
 			jsonBytes = endpointer.DuplicateJSONKeys(jsonBytes, rules, endpointer.DuplicateJSONKeysOpts{Compact: true})
 		}
 
-		if err := fleethttp.PostJSONWithTimeout(ctx, webhookURL.String(), json.RawMessage(jsonBytes), logger); err != nil {
-			recordWebhookFailedActivity(ctx, newActivitySvc, policy, batch, err, logger)
+		// The policy's fleet is also the fleet of the webhook settings (nil for
+		// global policies, 0 for "Unassigned").
+		delivery, err := worker.DeliverWebhook(ctx, ds, logger, worker.WebhookDeliveryRequest{
+			Type:    fleet.WebhookTypeFailingPolicies,
+			TeamID:  policy.TeamID,
+			URL:     webhookURL.String(),
+			Payload: jsonBytes,
+			FailingPolicy: &worker.WebhookDeliveryFailingPolicy{
+				PolicyID: policy.ID,
+				HostIDs:  batchHostIDs(batch),
+			},
+		})
+		if err != nil {
+			if delivery != nil {
+				recordWebhookFailedActivity(ctx, newActivitySvc, policy, batch, delivery, logger)
+			}
 			return ctxerr.Wrapf(ctx, fleethttp.MaskURLError(err), "posting to %q", fleethttp.MaskSecretURLParams(webhookURL.String()))
 		}
-		recordWebhookRanActivity(ctx, newActivitySvc, policy, batch, logger)
+		// a pending delivery is retried by the worker, which records the
+		// automation activity once it succeeds or fails for good.
+		if delivery.Status == fleet.WebhookDeliveryStatusSuccess {
+			recordWebhookRanActivity(ctx, newActivitySvc, policy, batch, logger)
+		}
 		if err := failingPoliciesSet.RemoveHosts(policy.ID, batch); err != nil {
 			return ctxerr.Wrapf(ctx, err, "removing hosts %+v from failing policies set %d", batch, policy.ID)
 		}
@@ -25,6 +25,7 @@ import (
 
 func TestTriggerFailingPoliciesWebhookBasic(t *testing.T) {
 	ds := new(mock.Store)
+	mockWebhookDeliveries(ds)
 
 	requestBody := ""
 
@@ -100,7 +101,7 @@ func TestTriggerFailingPoliciesWebhookBasic(t *testing.T) {
 			return err
 		}
 		return SendFailingPoliciesBatchedPOSTs(
-			context.Background(), pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, mockClock, slog.New(slog.DiscardHandler), &mock.MockActivityService{})
+			context.Background(), ds, pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, mockClock, slog.New(slog.DiscardHandler), &mock.MockActivityService{})
 	})
 	require.NoError(t, err)
 	timestamp, err := mockClock.MarshalJSON()
@@ -161,7 +162,7 @@ func TestTriggerFailingPoliciesWebhookBasic(t *testing.T) {
 			return err
 		}
 		return SendFailingPoliciesBatchedPOSTs(
-			context.Background(), pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, mockClock, slog.New(slog.DiscardHandler), &mock.MockActivityService{})
+			context.Background(), ds, pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, mockClock, slog.New(slog.DiscardHandler), &mock.MockActivityService{})
 	})
 	require.NoError(t, err)
 	assert.Empty(t, requestBody)
@@ -184,6 +185,7 @@ func TestTriggerFailingPoliciesWebhookTeam(t *testing.T) {
 	})
 
 	ds := new(mock.Store)
+	mockWebhookDeliveries(ds)
 
 	teamID := uint(1)
 
@@ -292,7 +294,7 @@ func TestTriggerFailingPoliciesWebhookTeam(t *testing.T) {
 			return err
 		}
 		return SendFailingPoliciesBatchedPOSTs(
-			context.Background(), pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, now, slog.New(slog.DiscardHandler), &mock.MockActivityService{})
+			context.Background(), ds, pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, now, slog.New(slog.DiscardHandler), &mock.MockActivityService{})
 	})
 	require.NoError(t, err)
 
@@ -350,7 +352,7 @@ func TestTriggerFailingPoliciesWebhookTeam(t *testing.T) {
 			return err
 		}
 		return SendFailingPoliciesBatchedPOSTs(
-			context.Background(), pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, now, slog.New(slog.DiscardHandler), &mock.MockActivityService{})
+			context.Background(), ds, pol, failingPolicySet, cfg.HostBatchSize, serverURL, cfg.WebhookURL, now, slog.New(slog.DiscardHandler), &mock.MockActivityService{})
 	})
 	require.NoError(t, err)
 	assert.Empty(t, webhookBody)
@@ -378,13 +380,15 @@ func TestSendFailingPoliciesWebhookRecordsFailureActivity(t *testing.T) {
 
 	t.Run("records one activity per failed batch with status and body", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-			w.WriteHeader(http.StatusInternalServerError)
+			w.WriteHeader(http.StatusBadRequest)
 			_, _ = w.Write([]byte("boom"))
 		}))
 		t.Cleanup(ts.Close)
 		webhookURL, err := url.Parse(ts.URL)
 		require.NoError(t, err)
 
+		ds := new(mock.Store)
+		mockWebhookDeliveries(ds)
 		failingPolicySet := service.NewMemFailingPolicySet()
 		for _, host := range makeHosts(2) {
 			require.NoError(t, failingPolicySet.AddHost(p.ID, host))
@@ -398,7 +402,7 @@ func TestSendFailingPoliciesWebhookRecordsFailureActivity(t *testing.T) {
 		}}
 
 		err = SendFailingPoliciesBatchedPOSTs(
-			t.Context(), p, failingPolicySet, 0, serverURL, webhookURL, now,
+			t.Context(), ds, p, failingPolicySet, 0, serverURL, webhookURL, now,
 			slog.New(slog.DiscardHandler), newActivitySvc,
 		)
 		require.Error(t, err)
@@ -408,7 +412,7 @@ func TestSendFailingPoliciesWebhookRecordsFailureActivity(t *testing.T) {
 		require.True(t, ok)
 		assert.Equal(t, p.ID, act.PolicyID)
 		assert.Equal(t, []uint{1, 2}, act.HostIDList)
-		assert.Equal(t, http.StatusInternalServerError, act.StatusCode)
+		assert.Equal(t, http.StatusBadRequest, act.StatusCode)
 		assert.Equal(t, "boom", act.ErrorResponse)
 
 		// hosts are not removed from the set on failure (kept for retry)
@@ -417,6 +421,49 @@ func TestSendFailingPoliciesWebhookRecordsFailureActivity(t *testing.T) {
 		assert.Len(t, setHosts, 2)
 	})
 
+	t.Run("queues retryable failures without recording an activity", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		t.Cleanup(ts.Close)
+		webhookURL, err := url.Parse(ts.URL)
+		require.NoError(t, err)
+
+		ds := new(mock.Store)
+		wd := mockWebhookDeliveries(ds)
+		failingPolicySet := service.NewMemFailingPolicySet()
+		for _, host := range makeHosts(2) {
+			require.NoError(t, failingPolicySet.AddHost(p.ID, host))
+		}
+
+		var recorded []fleet.ActivityDetails
+		newActivitySvc := &mock.MockActivityService{NewActivityFunc: func(_ context.Context, _ *activity_api.User, activity fleet.ActivityDetails) error {
+			recorded = append(recorded, activity)
+			return nil
+		}}
+
+		err = SendFailingPoliciesBatchedPOSTs(
+			t.Context(), ds, p, failingPolicySet, 0, serverURL, webhookURL, now,
+			slog.New(slog.DiscardHandler), newActivitySvc,
+		)
+		require.NoError(t, err)
+		// the activity is recorded by the worker once the retries succeed or
+		// fail for good
+		assert.Empty(t, recorded)
+
+		require.Len(t, wd.deliveries, 1)
+		assert.Equal(t, fleet.WebhookDeliveryStatusPending, wd.deliveries[0].Status)
+		assert.Equal(t, fleet.WebhookTypeFailingPolicies, wd.deliveries[0].WebhookType)
+		require.Len(t, wd.jobs, 1)
+		require.NotNil(t, wd.jobs[0].Args)
+		assert.JSONEq(t, `{"delivery_id":1,"failing_policy":{"policy_id":7,"host_ids":[1,2]}}`, string(*wd.jobs[0].Args))
+
+		// hosts are removed from the set, the worker retries the delivery
+		setHosts, err := failingPolicySet.ListHosts(p.ID)
+		require.NoError(t, err)
+		assert.Empty(t, setHosts)
+	})
+
 	t.Run("records no failure activity on success", func(t *testing.T) {
 		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 			w.WriteHeader(http.StatusOK)
@@ -425,6 +472,8 @@ func TestSendFailingPoliciesWebhookRecordsFailureActivity(t *testing.T) {
 		webhookURL, err := url.Parse(ts.URL)
 		require.NoError(t, err)
 
+		ds := new(mock.Store)
+		mockWebhookDeliveries(ds)
 		failingPolicySet := service.NewMemFailingPolicySet()
 		for _, host := range makeHosts(2) {
 			require.NoError(t, failingPolicySet.AddHost(p.ID, host))
@@ -437,7 +486,7 @@ func TestSendFailingPoliciesWebhookRecordsFailureActivity(t *testing.T) {
 		}}
 
 		err = SendFailingPoliciesBatchedPOSTs(
-			t.Context(), p, failingPolicySet, 0, serverURL, webhookURL, now,
+			t.Context(), ds, p, failingPolicySet, 0, serverURL, webhookURL, now,
 			slog.New(slog.DiscardHandler), newActivitySvc,
 		)
 		require.NoError(t, err)
@@ -478,6 +527,8 @@ func TestSendFailingPoliciesWebhookRecordsQueuedActivity(t *testing.T) {
 		webhookURL, err := url.Parse(ts.URL)
 		require.NoError(t, err)
 
+		ds := new(mock.Store)
+		mockWebhookDeliveries(ds)
 		failingPolicySet := service.NewMemFailingPolicySet()
 		for _, host := range makeHosts(3) {
 			require.NoError(t, failingPolicySet.AddHost(p.ID, host))
@@ -494,7 +545,7 @@ func TestSendFailingPoliciesWebhookRecordsQueuedActivity(t *testing.T) {
 
 		// batch size of 2 over 3 hosts => 2 batches => 2 sent activities
 		err = SendFailingPoliciesBatchedPOSTs(
-			t.Context(), p, failingPolicySet, 2, serverURL, webhookURL, now,
+			t.Context(), ds, p, failingPolicySet, 2, serverURL, webhookURL, now,
 			slog.New(slog.DiscardHandler), newActivitySvc,
 		)
 		require.NoError(t, err)
@@ -615,6 +666,8 @@ func TestSendBatchedPOSTs(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			allHosts = []uint{}
 			requestCount = 0
+			ds := new(mock.Store)
+			mockWebhookDeliveries(ds)
 			hosts := makeHosts(tc.hostCount)
 			failingPolicySet := service.NewMemFailingPolicySet()
 			for _, host := range hosts {
@@ -627,6 +680,7 @@ func TestSendBatchedPOSTs(t *testing.T) {
 
 			err = SendFailingPoliciesBatchedPOSTs(
 				context.Background(),
+				ds,
 				p,
 				failingPolicySet,
 				tc.batchSize,
@@ -2,12 +2,13 @@ package webhooks
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 
-	"github.com/fleetdm/fleet/v4/server"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/worker"
 	"github.com/hashicorp/go-multierror"
 )
 
@@ -67,8 +68,16 @@ func processWebhook(ctx context.Context, ds fleet.Datastore, teamID *uint, setti
 			payload["data"].(map[string]any)["team_id"] = *teamID
 		}
 
-		err = server.PostJSONWithTimeout(ctx, url, &payload, logger)
+		body, err := json.Marshal(payload)
 		if err != nil {
+			return ctxerr.Wrap(ctx, err, "marshal host status payload")
+		}
+		if _, err := worker.DeliverWebhook(ctx, ds, logger, worker.WebhookDeliveryRequest{
+			Type:    fleet.WebhookTypeHostStatus,
+			TeamID:  teamID,
+			URL:     url,
+			Payload: body,
+		}); err != nil {
 			return ctxerr.Wrapf(ctx, err, "posting to %s", url)
 		}
 	}
@@ -16,6 +16,7 @@ import (
 
 func TestTriggerHostStatusWebhook(t *testing.T) {
 	ds := new(mock.Store)
+	mockWebhookDeliveries(ds)
 
 	requestBody := ""
 	count := 0
@@ -73,6 +74,7 @@ func TestTriggerHostStatusWebhook(t *testing.T) {
 
 func TestTriggerHostStatusWebhookTeam(t *testing.T) {
 	ds := new(mock.Store)
+	mockWebhookDeliveries(ds)
 
 	requestBody := ""
 	count := 0
@@ -0,0 +1,56 @@
+package webhooks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+)
+
+// webhookDeliveries records the webhook deliveries and the retry jobs created
+// through a mock datastore.
+type webhookDeliveries struct {
+	mu         sync.Mutex
+	deliveries []*fleet.WebhookDelivery
+	jobs       []*fleet.Job
+}
+
+// mockWebhookDeliveries sets up the datastore functions used to deliver
+// webhooks. The app config and fleets default to having no webhook secret nor
+// custom headers, unless the test sets them.
+func mockWebhookDeliveries(ds *mock.Store) *webhookDeliveries {
+	wd := &webhookDeliveries{}
+	ds.NewWebhookDeliveryFunc = func(ctx context.Context, d *fleet.WebhookDelivery) (*fleet.WebhookDelivery, error) {
+		wd.mu.Lock()
+		defer wd.mu.Unlock()
+		d.ID = uint(len(wd.deliveries) + 1) //nolint:gosec // dismiss G115
+		d.Status = fleet.WebhookDeliveryStatusPending
+		wd.deliveries = append(wd.deliveries, d)
+		return d, nil
+	}
+	ds.UpdateWebhookDeliveryFunc = func(ctx context.Context, d *fleet.WebhookDelivery) error {
+		return nil
+	}
+	ds.NewJobFunc = func(ctx context.Context, job *fleet.Job) (*fleet.Job, error) {
+		wd.mu.Lock()
+		defer wd.mu.Unlock()
+		wd.jobs = append(wd.jobs, job)
+		return job, nil
+	}
+	if ds.AppConfigFunc == nil {
+		ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+			return &fleet.AppConfig{}, nil
+		}
+	}
+	if ds.TeamLitesByIDsFunc == nil {
+		ds.TeamLitesByIDsFunc = func(ctx context.Context, ids []uint) ([]*fleet.TeamLite, error) {
+			teams := make([]*fleet.TeamLite, 0, len(ids))
+			for _, id := range ids {
+				teams = append(teams, &fleet.TeamLite{ID: id})
+			}
+			return teams, nil
+		}
+	}
+	return wd
+}
@@ -2,13 +2,15 @@ package webhooks
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 	"net/url"
+	"slices"
 	"time"
 
-	"github.com/fleetdm/fleet/v4/server"
 	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/worker"
 )
 
 // TriggerVulnerabilitiesWebhook performs the webhook requests for vulnerabilities.
@@ -46,13 +48,30 @@ func TriggerVulnerabilitiesWebhook(
 			return ctxerr.Wrap(ctx, err, "get hosts by software ids")
 		}
 
+		// hosts where the CVE is covered by an active vulnerability exception
+		// for their fleet are not reported
+		exceptedIDs, err := ds.VulnerabilityExceptedHostIDs(ctx, cve, sIDs)
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "get vulnerability excepted host ids")
+		}
+		if len(exceptedIDs) > 0 {
+			excepted := make(map[uint]struct{}, len(exceptedIDs))
+			for _, id := range exceptedIDs {
+				excepted[id] = struct{}{}
+			}
+			hosts = slices.DeleteFunc(hosts, func(h fleet.HostVulnerabilitySummary) bool {
+				_, ok := excepted[h.ID]
+				return ok
+			})
+		}
+
 		for len(hosts) > 0 {
 			limit := len(hosts)
 			if batchSize > 0 && len(hosts) > batchSize {
 				limit = batchSize
 			}
 			payload := mapper.GetPayload(serverURL, hosts[:limit], cve, args.Meta[cve])
-			if err := sendVulnerabilityHostBatch(ctx, targetURL, payload, args.Time, logger); err != nil {
+			if err := sendVulnerabilityHostBatch(ctx, ds, targetURL, payload, args.Time, logger); err != nil {
 				return ctxerr.Wrap(ctx, err, "send vulnerability host batch")
 			}
 			hosts = hosts[limit:]
@@ -62,13 +81,21 @@ func TriggerVulnerabilitiesWebhook(
 	return nil
 }
 
-func sendVulnerabilityHostBatch(ctx context.Context, targetURL string, vuln WebhookPayload, now time.Time, logger *slog.Logger) error {
+func sendVulnerabilityHostBatch(ctx context.Context, ds fleet.Datastore, targetURL string, vuln WebhookPayload, now time.Time, logger *slog.Logger) error {
 	payload := map[string]interface{}{
 		"timestamp":     now,
 		"vulnerability": vuln,
 	}
 
-	if err := server.PostJSONWithTimeout(ctx, targetURL, &payload, logger); err != nil {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "marshal vulnerability payload")
+	}
+	if _, err := worker.DeliverWebhook(ctx, ds, logger, worker.WebhookDeliveryRequest{
+		Type:    fleet.WebhookTypeVulnerabilities,
+		URL:     targetURL,
+		Payload: body,
+	}); err != nil {
 		return ctxerr.Wrapf(ctx, err, "posting to %s", targetURL)
 	}
 	return nil
@@ -20,6 +20,7 @@ import (
 func TestTriggerVulnerabilitiesWebhook(t *testing.T) {
 	ctx := context.Background()
 	ds := new(mock.Store)
+	mockWebhookDeliveries(ds)
 	logger := slog.New(slog.DiscardHandler)
 	mapper := Mapper{}
 
@@ -181,6 +182,9 @@ func TestTriggerVulnerabilitiesWebhook(t *testing.T) {
 				ds.HostVulnSummariesBySoftwareIDsFunc = func(ctx context.Context, softwareIDs []uint) ([]fleet.HostVulnerabilitySummary, error) {
 					return c.hosts, nil
 				}
+				ds.VulnerabilityExceptedHostIDsFunc = func(ctx context.Context, cve string, softwareIDs []uint) ([]uint, error) {
+					return nil, nil
+				}
 
 				appCfg := *appCfg
 				appCfg.WebhookSettings.VulnerabilitiesWebhook.DestinationURL = srv.URL
@@ -202,4 +206,56 @@ func TestTriggerVulnerabilitiesWebhook(t *testing.T) {
 			})
 		}
 	})
+
+	t.Run("excepted hosts", func(t *testing.T) {
+		now := time.Now()
+
+		var requests []string
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			requests = append(requests, string(b))
+			_, err = w.Write(nil)
+			assert.NoError(t, err)
+		}))
+		defer srv.Close()
+
+		ds.HostVulnSummariesBySoftwareIDsFunc = func(ctx context.Context, softwareIDs []uint) ([]fleet.HostVulnerabilitySummary, error) {
+			return []fleet.HostVulnerabilitySummary{
+				{ID: 1, Hostname: "h1", DisplayName: "d1"},
+				{ID: 2, Hostname: "h2", DisplayName: "d2"},
+			}, nil
+		}
+		ds.VulnerabilityExceptedHostIDsFunc = func(ctx context.Context, cve string, softwareIDs []uint) ([]uint, error) {
+			switch cve {
+			case "CVE-2012-1234":
+				return []uint{1}, nil
+			case "CVE-2012-4567":
+				return []uint{1, 2}, nil
+			}
+			return nil, nil
+		}
+
+		appCfg := *appCfg
+		appCfg.WebhookSettings.VulnerabilitiesWebhook.DestinationURL = srv.URL
+		args := VulnArgs{
+			Vulnerablities: []fleet.SoftwareVulnerability{
+				{CVE: "CVE-2012-1234", SoftwareID: 1},
+				{CVE: "CVE-2012-4567", SoftwareID: 1},
+			},
+			AppConfig: &appCfg,
+			Time:      now,
+		}
+
+		err := TriggerVulnerabilitiesWebhook(ctx, ds, logger, args, &mapper)
+		require.NoError(t, err)
+		assert.True(t, ds.VulnerabilityExceptedHostIDsFuncInvoked)
+
+		// only the non-excepted host is reported for the first CVE, and nothing
+		// for the second one
+		require.Len(t, requests, 1)
+		assert.Contains(t, requests[0], `"cve":"CVE-2012-1234"`)
+		assert.Contains(t, requests[0], `"hostname":"h2"`)
+		assert.NotContains(t, requests[0], `"hostname":"h1"`)
+	})
 }
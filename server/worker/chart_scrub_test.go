@@ -22,6 +22,9 @@ type fakeChartService struct {
 func (f *fakeChartService) GetChartData(_ context.Context, _ string, _ chart_api.RequestOpts) (*chart_api.Response, error) {
 	panic("not used")
 }
+func (f *fakeChartService) ExportChartData(_ context.Context, _ string, _ chart_api.ExportOpts) (*chart_api.Export, error) {
+	panic("not used")
+}
 func (f *fakeChartService) RegisterDataset(_ chart_api.Dataset) { panic("not used") }
 func (f *fakeChartService) CollectDatasets(_ context.Context, _ time.Time, _ chart_api.CollectScopeFn) error {
 	panic("not used")
@@ -0,0 +1,618 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/pkg/str"
+	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/contexts/license"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/service/externalsvc"
+)
+
+// serviceNowName is the name of the job as registered in the worker.
+const serviceNowName = "servicenow"
+
+// serviceNowCorrelationDisplay is the value of the correlation_display field
+// of the incidents created by Fleet, it identifies the source of the
+// correlation ID in ServiceNow.
+const serviceNowCorrelationDisplay = "Fleet"
+
+// ServiceNow incident fields are plain text, so unlike the Jira and Zendesk
+// templates, these do not use any markup.
+var serviceNowTemplates = struct {
+	VulnSummary              *template.Template
+	VulnDescription          *template.Template
+	FailingPolicySummary     *template.Template
+	FailingPolicyDescription *template.Template
+	ResolvedPolicyNotes      *template.Template
+}{
+	VulnSummary: template.Must(template.New("").Parse(
+		`Vulnerability {{ .CVE }} detected on {{ .HostCount }} host(s)`,
+	)),
+
+	VulnDescription: template.Must(template.New("").Funcs(template.FuncMap{
+		// CISAKnownExploit is *bool, so any condition check on it in the template
+		// will test if nil or not, and not its actual boolean value. Hence, "deref".
+		"deref": func(b *bool) bool { return *b },
+	}).Parse(
+		`See vulnerability (CVE) details in National Vulnerability Database (NVD) here: {{ .NVDURL }}{{ .CVE }}
+{{ if .IsPremium }}{{ if .EPSSProbability }}
+Probability of exploit (reported by FIRST.org/epss): {{ .EPSSProbability }}{{ end }}{{ if .CVSSScore }}
+CVSS score (reported by NVD): {{ .CVSSScore }}{{ end }}{{ if .CVEPublished }}
+Published (reported by NVD): {{ .CVEPublished }}{{ end }}{{ if .CISAKnownExploit }}
+Known exploits (reported by CISA): {{ if deref .CISAKnownExploit }}Yes{{ else }}No{{ end }}{{ end }}
+{{ end }}
+Affected hosts:
+{{ $end := len .Hosts }}{{ if gt $end 50 }}{{ $end = 50 }}{{ end }}{{ range slice .Hosts 0 $end }}
+- {{ .DisplayName }}: {{ $.FleetURL }}/hosts/{{ .ID }}{{ end }}
+
+View the affected software and more affected hosts in the Software page of Fleet ({{ .FleetURL }}/software/manage) by searching for "{{ .CVE }}".
+
+This incident was created automatically by your Fleet ServiceNow integration.
+`)),
+
+	FailingPolicySummary: template.Must(template.New("").Parse(
+		`{{ .PolicyName }} policy failed on {{ .HostCount }} host(s)`,
+	)),
+
+	FailingPolicyDescription: template.Must(template.New("").Parse(
+		`{{ if .PolicyCritical }}This policy is marked as Critical in Fleet.
+
+{{ end }}Hosts:
+{{ $end := len .Hosts }}{{ if gt $end 50 }}{{ $end = 50 }}{{ end }}{{ range slice .Hosts 0 $end }}
+- {{ .DisplayName }}: {{ $.FleetURL }}/hosts/{{ .ID }}{{ end }}
+
+View hosts that failed {{ .PolicyName }} in Fleet: {{ .FleetURL }}/hosts/manage/?order_key=hostname&order_direction=asc&{{ if .TeamID }}fleet_id={{ .TeamID }}&{{ end }}policy_id={{ .PolicyID }}&policy_response=failing
+
+This incident was created automatically by your Fleet ServiceNow integration.
+`)),
+
+	ResolvedPolicyNotes: template.Must(template.New("").Parse(
+		`{{ .PolicyName }} policy is now passing on all hosts.
+
+This incident was resolved automatically by your Fleet ServiceNow integration.`,
+	)),
+}
+
+// serviceNowTplArgs are the arguments available to the built-in templates as
+// well as to the field mapping templates of the integration. The same struct
+// is used for vulnerabilities and failing policies so that a field mapping
+// template can reference any field, the fields that do not apply to the type
+// of incident being created are left to their zero value.
+type serviceNowTplArgs struct {
+	FleetURL  string
+	Hosts     []serviceNowTplHost
+	HostCount int
+
+	// failing policy fields
+	PolicyID       uint
+	PolicyName     string
+	PolicyCritical bool
+	TeamID         *uint
+
+	// vulnerability fields
+	CVE    string
+	NVDURL string
+
+	IsPremium bool
+
+	// the following fields are only included in the incident for premium licenses.
+	EPSSProbability  *float64
+	CVSSScore        *float64
+	CISAKnownExploit *bool
+	CVEPublished     *time.Time
+}
+
+type serviceNowTplHost struct {
+	ID          uint
+	DisplayName string
+}
+
+// ServiceNowClient defines the method required for the client that makes API
+// calls to ServiceNow.
+type ServiceNowClient interface {
+	FindOpenServiceNowIncident(ctx context.Context, correlationID string) (*externalsvc.ServiceNowIncident, error)
+	CreateServiceNowIncident(ctx context.Context, fields map[string]string) (*externalsvc.ServiceNowIncident, error)
+	UpdateServiceNowIncident(ctx context.Context, sysID string, fields map[string]string) (*externalsvc.ServiceNowIncident, error)
+	ServiceNowConfigMatches(opts *externalsvc.ServiceNowOptions) bool
+}
+
+// ServiceNow is the job processor for ServiceNow integrations.
+type ServiceNow struct {
+	FleetURL       string
+	Datastore      fleet.Datastore
+	Log            *slog.Logger
+	NewClientFunc  func(*externalsvc.ServiceNowOptions) (ServiceNowClient, error)
+	NewActivitySvc activity_api.NewActivityService
+
+	// mu protects concurrent access to clientsCache, so that the job processor
+	// can potentially be run concurrently.
+	mu sync.Mutex
+	// map of integration type + team ID to ServiceNow client (empty team ID for
+	// global), e.g. "vuln:123", "failingPolicy:", etc.
+	clientsCache map[string]ServiceNowClient
+}
+
+// returns nil, nil, nil if there is no integration enabled for that message.
+// The integration configuration is returned along with the client, as it
+// holds the settings of the incidents to create.
+func (s *ServiceNow) getClient(ctx context.Context, args serviceNowArgs) (ServiceNowClient, *fleet.ServiceNowIntegration, error) {
+	var teamID uint
+	var useTeamCfg bool
+
+	intgType := args.integrationType()
+	key := intgType + ":"
+	if tmID := args.teamID(); intgType == intgTypeFailingPolicy && tmID != nil {
+		teamID = *tmID
+		useTeamCfg = true
+		key += fmt.Sprint(teamID)
+	}
+
+	ac, err := s.Datastore.AppConfig(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// load the config that would be used to create the client first - it is
+	// needed to check if an existing client is configured the same or if its
+	// configuration has changed since it was created.
+	intgs := ac.Integrations
+	if useTeamCfg {
+		tm, err := s.Datastore.TeamLite(ctx, teamID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		intgs, err = tm.Config.Integrations.MatchWithIntegrations(ac.Integrations)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var intg *fleet.ServiceNowIntegration
+	for _, sn := range intgs.ServiceNow {
+		if (intgType == intgTypeVuln && sn.EnableSoftwareVulnerabilities) ||
+			(intgType == intgTypeFailingPolicy && sn.EnableFailingPolicies) {
+			intg = sn
+			break
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.clientsCache == nil {
+		s.clientsCache = make(map[string]ServiceNowClient)
+	}
+	if intg == nil {
+		// no integration configured, clear any existing one
+		delete(s.clientsCache, key)
+		return nil, nil, nil
+	}
+
+	opts := &externalsvc.ServiceNowOptions{
+		URL:      intg.URL,
+		Username: intg.Username,
+		Password: intg.Password,
+	}
+
+	// check if the existing one can be reused
+	if cli := s.clientsCache[key]; cli != nil && cli.ServiceNowConfigMatches(opts) {
+		return cli, intg, nil
+	}
+
+	// otherwise create a new one
+	cli, err := s.NewClientFunc(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.clientsCache[key] = cli
+	return cli, intg, nil
+}
+
+// Name returns the name of the job.
+func (s *ServiceNow) Name() string {
+	return serviceNowName
+}
+
+// serviceNowArgs are the arguments for the ServiceNow integration job.
+type serviceNowArgs struct {
+	Vulnerability  *vulnArgs           `json:"vulnerability,omitempty"`
+	FailingPolicy  *failingPolicyArgs  `json:"failing_policy,omitempty"`
+	ResolvedPolicy *resolvedPolicyArgs `json:"resolved_policy,omitempty"`
+}
+
+// resolvedPolicyArgs are the arguments of a job that resolves the open
+// incident of a policy that is now passing.
+type resolvedPolicyArgs struct {
+	PolicyID   uint   `json:"policy_id"`
+	PolicyName string `json:"policy_name"`
+	TeamID     *uint  `json:"team_id"`
+}
+
+func (a *serviceNowArgs) integrationType() string {
+	if a.FailingPolicy == nil && a.ResolvedPolicy == nil {
+		return intgTypeVuln
+	}
+	// resolving a policy's incident uses the failing policies integration
+	return intgTypeFailingPolicy
+}
+
+func (a *serviceNowArgs) teamID() *uint {
+	switch {
+	case a.FailingPolicy != nil:
+		return a.FailingPolicy.TeamID
+	case a.ResolvedPolicy != nil:
+		return a.ResolvedPolicy.TeamID
+	}
+	return nil
+}
+
+// Run executes the servicenow job.
+func (s *ServiceNow) Run(ctx context.Context, argsJSON json.RawMessage) error {
+	var args serviceNowArgs
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal args")
+	}
+
+	cli, intg, err := s.getClient(ctx, args)
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "get ServiceNow client")
+	}
+	if cli == nil {
+		// this message was queued when an integration was enabled, but since
+		// then it has been disabled, so return success to mark the message
+		// as processed.
+		return nil
+	}
+
+	switch {
+	case args.ResolvedPolicy != nil:
+		return s.runResolvedPolicy(ctx, cli, args)
+	case args.FailingPolicy != nil:
+		return s.runFailingPolicy(ctx, cli, intg, args)
+	default:
+		return s.runVuln(ctx, cli, intg, args)
+	}
+}
+
+// OnFinalFailure records a failed_automation_ticket host activity once
+// the worker has exhausted all retries for a failing-policy job. Vulnerability
+// and resolved policy jobs are ignored as they are not tied to failing hosts.
+func (s *ServiceNow) OnFinalFailure(ctx context.Context, argsJSON json.RawMessage, jobErr string) error {
+	var args serviceNowArgs
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal args")
+	}
+	if args.FailingPolicy == nil {
+		return nil
+	}
+
+	return s.NewActivitySvc.NewActivity(ctx, nil, fleet.ActivityTypeFailedAutomationTicket{
+		PolicyID:      args.FailingPolicy.PolicyID,
+		HostIDList:    args.FailingPolicy.hostIDs(),
+		Type:          "servicenow",
+		ErrorResponse: str.TruncateErrorResponse(jobErr),
+	})
+}
+
+func (s *ServiceNow) runVuln(ctx context.Context, cli ServiceNowClient, intg *fleet.ServiceNowIntegration, args serviceNowArgs) error {
+	vargs := args.Vulnerability
+	if vargs == nil {
+		return errors.New("invalid job args")
+	}
+
+	var hosts []fleet.HostVulnerabilitySummary
+	var err error
+
+	// Default to deprecated method in case we are processing an 'old' job payload
+	if len(vargs.AffectedSoftwareIDs) == 0 {
+		hosts, err = s.Datastore.HostsByCVE(ctx, vargs.CVE)
+	} else {
+		hosts, err = s.Datastore.HostVulnSummariesBySoftwareIDs(ctx, vargs.AffectedSoftwareIDs)
+	}
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "fetching hosts")
+	}
+
+	tplArgs := &serviceNowTplArgs{
+		FleetURL:         s.FleetURL,
+		HostCount:        len(hosts),
+		CVE:              vargs.CVE,
+		NVDURL:           nvdCVEURL,
+		IsPremium:        license.IsPremium(ctx),
+		EPSSProbability:  vargs.EPSSProbability,
+		CVSSScore:        vargs.CVSSScore,
+		CISAKnownExploit: vargs.CISAKnownExploit,
+		CVEPublished:     vargs.CVEPublished,
+	}
+	for _, h := range hosts {
+		tplArgs.Hosts = append(tplArgs.Hosts, serviceNowTplHost{ID: h.ID, DisplayName: h.DisplayName})
+	}
+
+	incident, created, err := s.createOrUpdateIncident(ctx, cli, intg, "fleet-cve-"+vargs.CVE,
+		serviceNowTemplates.VulnSummary, serviceNowTemplates.VulnDescription, tplArgs)
+	if err != nil {
+		return err
+	}
+	s.Log.DebugContext(ctx, "sent servicenow incident for cve",
+		"cve", vargs.CVE,
+		"incident", incident.Number,
+		"created", created,
+	)
+	return nil
+}
+
+func (s *ServiceNow) runFailingPolicy(ctx context.Context, cli ServiceNowClient, intg *fleet.ServiceNowIntegration, args serviceNowArgs) error {
+	pargs := args.FailingPolicy
+	tplArgs := &serviceNowTplArgs{
+		FleetURL:       s.FleetURL,
+		HostCount:      len(pargs.Hosts),
+		PolicyID:       pargs.PolicyID,
+		PolicyName:     pargs.PolicyName,
+		PolicyCritical: pargs.PolicyCritical,
+		TeamID:         pargs.TeamID,
+	}
+	for _, h := range pargs.Hosts {
+		tplArgs.Hosts = append(tplArgs.Hosts, serviceNowTplHost{ID: h.ID, DisplayName: h.DisplayName})
+	}
+
+	incident, created, err := s.createOrUpdateIncident(ctx, cli, intg, serviceNowPolicyCorrelationID(pargs.PolicyID),
+		serviceNowTemplates.FailingPolicySummary, serviceNowTemplates.FailingPolicyDescription, tplArgs)
+	if err != nil {
+		return err
+	}
+	// the policy is checked for resolution once it passes again
+	if err := s.Datastore.RecordServiceNowPolicyIncident(ctx, pargs.PolicyID); err != nil {
+		return ctxerr.Wrap(ctx, err, "record servicenow policy incident")
+	}
+
+	attrs := []any{
+		"policy_id", pargs.PolicyID,
+		"policy_name", pargs.PolicyName,
+		"incident", incident.Number,
+		"created", created,
+	}
+	if pargs.TeamID != nil {
+		attrs = append(attrs, "team_id", *pargs.TeamID)
+	}
+	s.Log.DebugContext(ctx, "sent servicenow incident for failing policy", attrs...)
+
+	if err := s.NewActivitySvc.NewActivity(ctx, nil, fleet.ActivityTypeRanAutomationTicket{
+		PolicyID:   pargs.PolicyID,
+		HostIDList: pargs.hostIDs(),
+		Type:       "servicenow",
+		TicketKey:  incident.Number,
+	}); err != nil {
+		s.Log.WarnContext(ctx, "failed to record servicenow policy automation queued activity",
+			"policy_id", pargs.PolicyID, "err", err)
+	}
+	return nil
+}
+
+func (s *ServiceNow) runResolvedPolicy(ctx context.Context, cli ServiceNowClient, args serviceNowArgs) error {
+	rargs := args.ResolvedPolicy
+
+	incident, err := cli.FindOpenServiceNowIncident(ctx, serviceNowPolicyCorrelationID(rargs.PolicyID))
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "find open incident")
+	}
+	if incident == nil {
+		// e.g. resolved in ServiceNow, there is nothing left to resolve
+		if err := s.Datastore.DeleteServiceNowPolicyIncident(ctx, rargs.PolicyID); err != nil {
+			return ctxerr.Wrap(ctx, err, "delete servicenow policy incident")
+		}
+		return nil
+	}
+
+	// check that the policy is still passing, and that it was passing after the
+	// last update of the incident - otherwise the counts may be stale and not
+	// yet reflect the failures reported in the incident.
+	policies, err := s.Datastore.PoliciesByID(ctx, []uint{rargs.PolicyID})
+	if err != nil && !fleet.IsNotFound(err) {
+		return ctxerr.Wrap(ctx, err, "get policy")
+	}
+	policy := policies[rargs.PolicyID]
+	if policy == nil || policy.FailingHostCount > 0 || policy.PassingHostCount == 0 ||
+		policy.HostCountUpdatedAt == nil || !policy.HostCountUpdatedAt.After(incident.UpdatedAt()) {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := serviceNowTemplates.ResolvedPolicyNotes.Execute(&buf, rargs); err != nil {
+		return ctxerr.Wrap(ctx, err, "execute resolved notes template")
+	}
+	if _, err := cli.UpdateServiceNowIncident(ctx, incident.SysID, map[string]string{
+		"state":       externalsvc.ServiceNowIncidentStateResolved,
+		"close_code":  "Solution provided",
+		"close_notes": buf.String(),
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "resolve incident")
+	}
+	if err := s.Datastore.DeleteServiceNowPolicyIncident(ctx, rargs.PolicyID); err != nil {
+		return ctxerr.Wrap(ctx, err, "delete servicenow policy incident")
+	}
+	s.Log.DebugContext(ctx, "resolved servicenow incident for passing policy",
+		"policy_id", rargs.PolicyID,
+		"incident", incident.Number,
+	)
+	return nil
+}
+
+// createOrUpdateIncident creates an incident with the rendered templates, or
+// if an incident with the same correlation ID is still open, adds the
+// rendered description as work notes to it instead. It returns the incident
+// and whether it was created.
+func (s *ServiceNow) createOrUpdateIncident(ctx context.Context, cli ServiceNowClient, intg *fleet.ServiceNowIntegration,
+	correlationID string, summaryTpl, descTpl *template.Template, args *serviceNowTplArgs,
+) (*externalsvc.ServiceNowIncident, bool, error) {
+	var buf bytes.Buffer
+	if err := summaryTpl.Execute(&buf, args); err != nil {
+		return nil, false, ctxerr.Wrap(ctx, err, "execute summary template")
+	}
+	summary := buf.String()
+
+	buf.Reset() // reuse buffer
+	if err := descTpl.Execute(&buf, args); err != nil {
+		return nil, false, ctxerr.Wrap(ctx, err, "execute description template")
+	}
+	description := buf.String()
+
+	existing, err := cli.FindOpenServiceNowIncident(ctx, correlationID)
+	if err != nil {
+		return nil, false, ctxerr.Wrap(ctx, err, "find open incident")
+	}
+	if existing != nil {
+		updated, err := cli.UpdateServiceNowIncident(ctx, existing.SysID, map[string]string{
+			"work_notes": summary + "\n\n" + description,
+		})
+		if err != nil {
+			return nil, false, ctxerr.Wrap(ctx, err, "update incident")
+		}
+		if updated.Number == "" {
+			updated.Number = existing.Number
+		}
+		return updated, false, nil
+	}
+
+	fields := map[string]string{
+		"short_description": summary,
+		"description":       description,
+	}
+	if intg.AssignmentGroup != "" {
+		fields["assignment_group"] = intg.AssignmentGroup
+	}
+	if intg.Category != "" {
+		fields["category"] = intg.Category
+	}
+	for field, text := range intg.FieldMapping {
+		tpl, err := template.New(field).Parse(text)
+		if err != nil {
+			return nil, false, ctxerr.Wrapf(ctx, err, "parse field mapping template for %s", field)
+		}
+		buf.Reset()
+		if err := tpl.Execute(&buf, args); err != nil {
+			return nil, false, ctxerr.Wrapf(ctx, err, "execute field mapping template for %s", field)
+		}
+		fields[field] = buf.String()
+	}
+	// set last so that the field mapping cannot break the deduplication
+	fields["correlation_id"] = correlationID
+	fields["correlation_display"] = serviceNowCorrelationDisplay
+
+	created, err := cli.CreateServiceNowIncident(ctx, fields)
+	if err != nil {
+		return nil, false, ctxerr.Wrap(ctx, err, "create incident")
+	}
+	return created, true, nil
+}
+
+func serviceNowPolicyCorrelationID(policyID uint) string {
+	return fmt.Sprintf("fleet-policy-%d", policyID)
+}
+
+// QueueServiceNowVulnJobs queues the ServiceNow vulnerability jobs to process
+// asynchronously via the worker.
+func QueueServiceNowVulnJobs(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger *slog.Logger,
+	recentVulns []fleet.SoftwareVulnerability,
+	cveMeta map[string]fleet.CVEMeta,
+) error {
+	logger.InfoContext(ctx, "servicenow integration enabled", "recent_vulns", len(recentVulns))
+
+	// for troubleshooting, log in debug level the CVEs that we will process
+	// (cannot be done in the loop below as we want to add the debug log
+	// _before_ we start processing them).
+	cves := make([]string, 0, len(recentVulns))
+	for _, vuln := range recentVulns {
+		cves = append(cves, vuln.GetCVE())
+	}
+	sort.Strings(cves)
+	logger.DebugContext(ctx, "recent CVEs to process", "recent_cves", fmt.Sprintf("%v", cves))
+
+	cveGrouped := make(map[string][]uint)
+	for _, v := range recentVulns {
+		cveGrouped[v.GetCVE()] = append(cveGrouped[v.GetCVE()], v.Affected())
+	}
+
+	for cve, sIDs := range cveGrouped {
+		args := vulnArgs{CVE: cve, AffectedSoftwareIDs: sIDs}
+		if meta, ok := cveMeta[cve]; ok {
+			args.EPSSProbability = meta.EPSSProbability
+			args.CVSSScore = meta.CVSSScore
+			args.CISAKnownExploit = meta.CISAKnownExploit
+			args.CVEPublished = meta.Published
+		}
+		job, err := QueueJob(ctx, ds, serviceNowName, serviceNowArgs{Vulnerability: &args})
+		if err != nil {
+			return ctxerr.Wrap(ctx, err, "queueing job")
+		}
+		logger.DebugContext(ctx, "queued servicenow vuln job", "job_id", job.ID)
+	}
+	return nil
+}
+
+// QueueServiceNowFailingPolicyJob queues a ServiceNow job for a failing policy
+// to process asynchronously via the worker.
+func QueueServiceNowFailingPolicyJob(ctx context.Context, ds fleet.Datastore, logger *slog.Logger,
+	policy *fleet.Policy, hosts []fleet.PolicySetHost,
+) error {
+	attrs := []any{
+		"enabled", "true",
+		"failing_policy", policy.ID,
+		"hosts_count", len(hosts),
+	}
+	if policy.TeamID != nil {
+		attrs = append(attrs, "team_id", *policy.TeamID)
+	}
+	if len(hosts) == 0 {
+		logger.DebugContext(ctx, "skipping, no host", attrs...)
+		return nil
+	}
+
+	logger.InfoContext(ctx, "queueing ServiceNow failing policy job", attrs...)
+
+	args := &failingPolicyArgs{
+		PolicyID:       policy.ID,
+		PolicyName:     policy.Name,
+		PolicyCritical: policy.Critical,
+		TeamID:         policy.TeamID,
+		Hosts:          hosts,
+	}
+	job, err := QueueJob(ctx, ds, serviceNowName, serviceNowArgs{FailingPolicy: args})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "queueing job")
+	}
+	logger.DebugContext(ctx, "queued servicenow failing policy job", "job_id", job.ID)
+	return nil
+}
+
+// QueueServiceNowResolvedPolicyJob queues a ServiceNow job to resolve the open
+// incident (if any) of a policy that is now passing, to process
+// asynchronously via the worker.
+func QueueServiceNowResolvedPolicyJob(ctx context.Context, ds fleet.Datastore, logger *slog.Logger, policy *fleet.Policy) error {
+	args := &resolvedPolicyArgs{
+		PolicyID:   policy.ID,
+		PolicyName: policy.Name,
+		TeamID:     policy.TeamID,
+	}
+	job, err := QueueJob(ctx, ds, serviceNowName, serviceNowArgs{ResolvedPolicy: args})
+	if err != nil {
+		return ctxerr.Wrap(ctx, err, "queueing job")
+	}
+	logger.DebugContext(ctx, "queued servicenow resolved policy job", "job_id", job.ID, "policy_id", policy.ID)
+	return nil
+}
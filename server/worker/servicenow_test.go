@@ -0,0 +1,277 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
+	"github.com/fleetdm/fleet/v4/server/contexts/license"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/service/externalsvc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serviceNowStandIn is a minimal stand-in for the ServiceNow incident Table
+// API, it keeps the incidents in memory.
+type serviceNowStandIn struct {
+	incidents map[string]map[string]string // by sys_id
+	created   []map[string]string
+	updated   []map[string]string
+}
+
+func (sn *serviceNowStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/now/table/incident":
+		// only the correlation_id condition of the query is supported
+		q := r.URL.Query().Get("sysparm_query")
+		correlationID := strings.TrimPrefix(strings.Split(q, "^")[0], "correlation_id=")
+		result := []map[string]string{}
+		for _, inc := range sn.incidents {
+			if inc["correlation_id"] == correlationID && inc["state"] != externalsvc.ServiceNowIncidentStateResolved {
+				result = append(result, inc)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": result})
+
+	case r.Method == http.MethodPost && r.URL.Path == "/api/now/table/incident":
+		var fields map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		sn.created = append(sn.created, fields)
+
+		inc := map[string]string{
+			"sys_id":         "sys" + string(rune('a'+len(sn.incidents))),
+			"number":         "INC00" + string(rune('1'+len(sn.incidents))),
+			"state":          "1",
+			"correlation_id": fields["correlation_id"],
+			"sys_updated_on": time.Now().UTC().Add(-time.Hour).Format("2006-01-02 15:04:05"),
+		}
+		sn.incidents[inc["sys_id"]] = inc
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": inc})
+
+	case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/api/now/table/incident/"):
+		inc := sn.incidents[strings.TrimPrefix(r.URL.Path, "/api/now/table/incident/")]
+		if inc == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var fields map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		sn.updated = append(sn.updated, fields)
+		if state, ok := fields["state"]; ok {
+			inc["state"] = state
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"result": inc})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestServiceNowRun(t *testing.T) {
+	ctx := license.NewContext(t.Context(), &fleet.LicenseInfo{Tier: fleet.TierPremium})
+
+	standIn := &serviceNowStandIn{incidents: make(map[string]map[string]string)}
+	srv := httptest.NewServer(standIn)
+	t.Cleanup(srv.Close)
+
+	intg := &fleet.ServiceNowIntegration{
+		URL:                           srv.URL,
+		Username:                      "fleet",
+		Password:                      "secret",
+		AssignmentGroup:               "Service Desk",
+		Category:                      "Security",
+		EnableFailingPolicies:         true,
+		EnableSoftwareVulnerabilities: true,
+		FieldMapping: map[string]string{
+			"urgency":     `{{ if .PolicyCritical }}1{{ else }}3{{ end }}`,
+			"u_fleet_ref": `{{ if .CVE }}{{ .CVE }}{{ else }}policy {{ .PolicyID }}{{ end }}`,
+		},
+	}
+
+	ds := new(mock.Store)
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{Integrations: fleet.Integrations{
+			ServiceNow: []*fleet.ServiceNowIntegration{intg},
+		}}, nil
+	}
+	ds.TeamLiteFunc = func(ctx context.Context, tid uint) (*fleet.TeamLite, error) {
+		tm := &fleet.TeamLite{ID: tid}
+		if tid == 1 {
+			// team 1 has the integration disabled
+			tm.Config.Integrations.ServiceNow = []*fleet.TeamServiceNowIntegration{
+				{URL: srv.URL, AssignmentGroup: "Service Desk", EnableFailingPolicies: false},
+			}
+		}
+		return tm, nil
+	}
+	ds.HostVulnSummariesBySoftwareIDsFunc = func(ctx context.Context, softwareIDs []uint) ([]fleet.HostVulnerabilitySummary, error) {
+		return []fleet.HostVulnerabilitySummary{{ID: 1, DisplayName: "host1"}}, nil
+	}
+	passing := &fleet.Policy{PolicyData: fleet.PolicyData{ID: 2, Name: "p2"}, PassingHostCount: 2}
+	ds.PoliciesByIDFunc = func(ctx context.Context, ids []uint) (map[uint]*fleet.Policy, error) {
+		return map[uint]*fleet.Policy{passing.ID: passing}, nil
+	}
+	// the policies with a recorded open incident
+	openIncidents := make(map[uint]bool)
+	ds.RecordServiceNowPolicyIncidentFunc = func(ctx context.Context, policyID uint) error {
+		openIncidents[policyID] = true
+		return nil
+	}
+	ds.DeleteServiceNowPolicyIncidentFunc = func(ctx context.Context, policyID uint) error {
+		delete(openIncidents, policyID)
+		return nil
+	}
+
+	var activities []fleet.ActivityDetails
+	job := &ServiceNow{
+		FleetURL:  "https://fleet.example.com",
+		Datastore: ds,
+		Log:       slog.New(slog.DiscardHandler),
+		NewClientFunc: func(opts *externalsvc.ServiceNowOptions) (ServiceNowClient, error) {
+			return externalsvc.NewServiceNowClient(opts)
+		},
+		NewActivitySvc: &mock.MockActivityService{NewActivityFunc: func(_ context.Context, _ *activity_api.User, activity fleet.ActivityDetails) error {
+			activities = append(activities, activity)
+			return nil
+		}},
+	}
+
+	failingArgs := func(t *testing.T, teamID *uint, hostIDs ...uint) json.RawMessage {
+		args := &failingPolicyArgs{PolicyID: 2, PolicyName: "p2", PolicyCritical: true, TeamID: teamID}
+		for _, id := range hostIDs {
+			args.Hosts = append(args.Hosts, fleet.PolicySetHost{ID: id, DisplayName: "host" + string(rune('0'+id))})
+		}
+		b, err := json.Marshal(serviceNowArgs{FailingPolicy: args})
+		require.NoError(t, err)
+		return b
+	}
+
+	t.Run("failing policy creates incident", func(t *testing.T) {
+		require.NoError(t, job.Run(ctx, failingArgs(t, nil, 1, 2)))
+
+		require.Len(t, standIn.created, 1)
+		fields := standIn.created[0]
+		assert.Equal(t, "p2 policy failed on 2 host(s)", fields["short_description"])
+		assert.Contains(t, fields["description"], "This policy is marked as Critical in Fleet.")
+		assert.Contains(t, fields["description"], "- host1: https://fleet.example.com/hosts/1")
+		assert.Equal(t, "Service Desk", fields["assignment_group"])
+		assert.Equal(t, "Security", fields["category"])
+		assert.Equal(t, "fleet-policy-2", fields["correlation_id"])
+		assert.Equal(t, "Fleet", fields["correlation_display"])
+		assert.Equal(t, "1", fields["urgency"])
+		assert.Equal(t, "policy 2", fields["u_fleet_ref"])
+
+		require.Equal(t, []fleet.ActivityDetails{fleet.ActivityTypeRanAutomationTicket{
+			PolicyID:   2,
+			HostIDList: []uint{1, 2},
+			Type:       "servicenow",
+			TicketKey:  "INC001",
+		}}, activities)
+		require.Equal(t, map[uint]bool{2: true}, openIncidents)
+	})
+
+	t.Run("repeated failure updates the open incident", func(t *testing.T) {
+		activities = nil
+		require.NoError(t, job.Run(ctx, failingArgs(t, nil, 3)))
+
+		require.Len(t, standIn.created, 1)
+		require.Len(t, standIn.updated, 1)
+		assert.Contains(t, standIn.updated[0]["work_notes"], "p2 policy failed on 1 host(s)")
+		require.Len(t, activities, 1)
+		assert.Equal(t, "INC001", activities[0].(fleet.ActivityTypeRanAutomationTicket).TicketKey)
+	})
+
+	resolveArgs := json.RawMessage(`{"resolved_policy":{"policy_id":2,"policy_name":"p2"}}`)
+
+	t.Run("passing policy with stale counts is not resolved", func(t *testing.T) {
+		passing.HostCountUpdatedAt = ptr.Time(time.Now().Add(-2 * time.Hour))
+		require.NoError(t, job.Run(ctx, resolveArgs))
+		require.Len(t, standIn.updated, 1)
+		assert.Equal(t, "1", standIn.incidents["sysa"]["state"])
+		// it will be checked again on the next run
+		require.Equal(t, map[uint]bool{2: true}, openIncidents)
+	})
+
+	t.Run("passing policy resolves the incident", func(t *testing.T) {
+		passing.HostCountUpdatedAt = ptr.Time(time.Now())
+		require.NoError(t, job.Run(ctx, resolveArgs))
+		require.Len(t, standIn.updated, 2)
+		assert.Equal(t, externalsvc.ServiceNowIncidentStateResolved, standIn.updated[1]["state"])
+		assert.Contains(t, standIn.updated[1]["close_notes"], "p2 policy is now passing on all hosts.")
+		assert.Equal(t, externalsvc.ServiceNowIncidentStateResolved, standIn.incidents["sysa"]["state"])
+		require.Empty(t, openIncidents)
+
+		// nothing left to resolve
+		require.NoError(t, job.Run(ctx, resolveArgs))
+		require.Len(t, standIn.updated, 2)
+	})
+
+	t.Run("incident resolved in servicenow is no longer checked", func(t *testing.T) {
+		openIncidents[3] = true
+		require.NoError(t, job.Run(ctx, json.RawMessage(`{"resolved_policy":{"policy_id":3,"policy_name":"p3"}}`)))
+		require.Len(t, standIn.updated, 2)
+		require.Empty(t, openIncidents)
+	})
+
+	t.Run("new failure after resolution creates a new incident", func(t *testing.T) {
+		require.NoError(t, job.Run(ctx, failingArgs(t, nil, 1)))
+		require.Len(t, standIn.created, 2)
+		assert.Equal(t, "fleet-policy-2", standIn.created[1]["correlation_id"])
+	})
+
+	t.Run("vulnerability", func(t *testing.T) {
+		require.NoError(t, job.Run(ctx, json.RawMessage(`{"vulnerability":{"cve":"CVE-2024-1234","affected_software":[1],"cvss_score":9.8}}`)))
+		require.Len(t, standIn.created, 3)
+		fields := standIn.created[2]
+		assert.Equal(t, "Vulnerability CVE-2024-1234 detected on 1 host(s)", fields["short_description"])
+		assert.Contains(t, fields["description"], "CVSS score (reported by NVD): 9.8")
+		assert.Equal(t, "fleet-cve-CVE-2024-1234", fields["correlation_id"])
+		assert.Equal(t, "3", fields["urgency"])
+		assert.Equal(t, "CVE-2024-1234", fields["u_fleet_ref"])
+	})
+
+	t.Run("integration disabled for team", func(t *testing.T) {
+		activities = nil
+		require.NoError(t, job.Run(ctx, failingArgs(t, ptr.Uint(1), 1)))
+		require.Len(t, standIn.created, 3)
+		require.Empty(t, activities)
+	})
+}
+
+func TestServiceNowOnFinalFailure(t *testing.T) {
+	ctx := t.Context()
+
+	var recorded []fleet.ActivityDetails
+	job := &ServiceNow{
+		Log: slog.New(slog.DiscardHandler),
+		NewActivitySvc: &mock.MockActivityService{NewActivityFunc: func(_ context.Context, _ *activity_api.User, activity fleet.ActivityDetails) error {
+			recorded = append(recorded, activity)
+			return nil
+		}},
+	}
+
+	require.NoError(t, job.OnFinalFailure(ctx, json.RawMessage(`{"failing_policy":{"policy_id":6,"hosts":[{"id":3}]}}`), "boom"))
+	require.NoError(t, job.OnFinalFailure(ctx, json.RawMessage(`{"resolved_policy":{"policy_id":6}}`), "boom"))
+	require.Equal(t, []fleet.ActivityDetails{fleet.ActivityTypeFailedAutomationTicket{
+		PolicyID:      6,
+		HostIDList:    []uint{3},
+		Type:          "servicenow",
+		ErrorResponse: "boom",
+	}}, recorded)
+}
@@ -0,0 +1,333 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
+	"github.com/fleetdm/fleet/v4/server/contexts/ctxerr"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	platformhttp "github.com/fleetdm/fleet/v4/server/platform/http"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+)
+
+// webhookDeliveryName is the name of the job retrying the webhook deliveries
+// that failed with a retryable error.
+const webhookDeliveryName = "webhook_delivery"
+
+// WebhookDeliveryFailingPolicy identifies the failing policy and hosts of a
+// failing policies webhook delivery, so that the ran or failed automation
+// activity is recorded once a retried delivery succeeds or fails for good.
+type WebhookDeliveryFailingPolicy struct {
+	PolicyID uint   `json:"policy_id"`
+	HostIDs  []uint `json:"host_ids"`
+}
+
+// WebhookDeliveryRequest is a payload to deliver to a webhook.
+type WebhookDeliveryRequest struct {
+	Type fleet.WebhookType
+	// TeamID is the fleet of the webhook settings, nil for the global settings
+	// and 0 for "Unassigned". It is used to look up the secret and custom
+	// headers of the webhook at each attempt.
+	TeamID  *uint
+	URL     string
+	Payload json.RawMessage
+	// FailingPolicy is only set for failing policies webhooks.
+	FailingPolicy *WebhookDeliveryFailingPolicy
+}
+
+type webhookDeliveryArgs struct {
+	DeliveryID    uint                          `json:"delivery_id"`
+	FailingPolicy *WebhookDeliveryFailingPolicy `json:"failing_policy,omitempty"`
+}
+
+// DeliverWebhook records a webhook delivery and attempts it right away. If the
+// attempt fails with a retryable error (no response, 408, 429 or 5xx), a job is
+// queued to retry it with backoff, the returned delivery is pending and the
+// returned error is nil. If it fails with any other error, the returned
+// delivery is failed and the error is returned.
+func DeliverWebhook(ctx context.Context, ds fleet.Datastore, logger *slog.Logger, req WebhookDeliveryRequest) (*fleet.WebhookDelivery, error) {
+	d, err := ds.NewWebhookDelivery(ctx, &fleet.WebhookDelivery{
+		WebhookType: req.Type,
+		TeamID:      req.TeamID,
+		URL:         req.URL,
+		Payload:     req.Payload,
+	})
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create webhook delivery")
+	}
+	d.Payload = req.Payload
+	return d, deliverWebhook(ctx, ds, logger, d, req.FailingPolicy)
+}
+
+// RedeliverWebhook sends the payload of a previous webhook delivery again, as
+// a new delivery.
+func RedeliverWebhook(ctx context.Context, ds fleet.Datastore, logger *slog.Logger, id uint) (*fleet.WebhookDelivery, error) {
+	orig, err := ds.WebhookDelivery(ctx, id)
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "get webhook delivery")
+	}
+	d, err := ds.NewWebhookDelivery(ctx, &fleet.WebhookDelivery{
+		WebhookType:    orig.WebhookType,
+		TeamID:         orig.TeamID,
+		URL:            orig.URL,
+		Payload:        orig.Payload,
+		RedeliveryOfID: &orig.ID,
+	})
+	if err != nil {
+		return nil, ctxerr.Wrap(ctx, err, "create webhook redelivery")
+	}
+	d.Payload = orig.Payload
+	// a failed redelivery is reflected in its status, it is not an error of
+	// the redelivery request
+	_ = deliverWebhook(ctx, ds, logger, d, nil)
+	return d, nil
+}
+
+func deliverWebhook(ctx context.Context, ds fleet.Datastore, logger *slog.Logger, d *fleet.WebhookDelivery, failingPolicy *WebhookDeliveryFailingPolicy) error {
+	err := attemptWebhookDelivery(ctx, ds, logger, d)
+	if err == nil || d.Status != fleet.WebhookDeliveryStatusPending {
+		return err
+	}
+
+	logger.WarnContext(ctx, "webhook delivery failed, queued for retry",
+		"delivery_id", d.ID, "webhook_type", d.WebhookType, "err", err)
+	if _, err := QueueJob(ctx, ds, webhookDeliveryName, webhookDeliveryArgs{
+		DeliveryID:    d.ID,
+		FailingPolicy: failingPolicy,
+	}); err != nil {
+		return ctxerr.Wrap(ctx, err, "queue webhook delivery retry")
+	}
+	return nil
+}
+
+// attemptWebhookDelivery POSTs the payload of the delivery, signed with the
+// current secret of its webhook, and saves the result. The delivery is left
+// pending if the attempt failed with a retryable error.
+func attemptWebhookDelivery(ctx context.Context, ds fleet.Datastore, logger *slog.Logger, d *fleet.WebhookDelivery) error {
+	settings, err := webhookDeliverySettings(ctx, ds, d.WebhookType, d.TeamID)
+	var res platformhttp.WebhookResult
+	if err == nil {
+		res, err = platformhttp.PostWebhook(ctx, platformhttp.WebhookRequest{
+			URL:        d.URL,
+			Body:       d.Payload,
+			DeliveryID: strconv.FormatUint(uint64(d.ID), 10),
+			Secret:     settings.Secret,
+			Headers:    settings.Headers,
+		}, logger)
+	}
+
+	now := time.Now().UTC()
+	d.Attempts++
+	d.LastAttemptAt = &now
+	d.ResponseStatusCode = nil
+	d.LatencyMs = nil
+	d.Error = ""
+	if res.StatusCode != 0 {
+		d.ResponseStatusCode = &res.StatusCode
+	}
+	if res.Latency > 0 {
+		latency := uint(res.Latency.Milliseconds()) //nolint:gosec // dismiss G115
+		d.LatencyMs = &latency
+	}
+	switch {
+	case err == nil:
+		d.Status = fleet.WebhookDeliveryStatusSuccess
+	case fleet.IsNotFound(err):
+		// the fleet of the webhook was deleted
+		d.Status = fleet.WebhookDeliveryStatusFailed
+		d.Error = err.Error()
+	default:
+		d.Status = fleet.WebhookDeliveryStatusFailed
+		if platformhttp.IsRetryableWebhookStatus(res.StatusCode) {
+			d.Status = fleet.WebhookDeliveryStatusPending
+		}
+		d.Error = webhookDeliveryError(err)
+	}
+
+	if saveErr := ds.UpdateWebhookDelivery(ctx, d); saveErr != nil {
+		return ctxerr.Wrap(ctx, saveErr, "save webhook delivery")
+	}
+	if err != nil {
+		return ctxerr.Wrapf(ctx, err, "deliver %s webhook", d.WebhookType)
+	}
+	return nil
+}
+
+// webhookDeliveryError returns the error to record for a failed delivery: the
+// body of the response if any, the (masked) error otherwise.
+func webhookDeliveryError(err error) string {
+	if b, ok := errors.AsType[interface {
+		error
+		Body() string
+		StatusCode() int
+	}](err); ok {
+		if b.Body() != "" {
+			return b.Body()
+		}
+		return http.StatusText(b.StatusCode())
+	}
+	return platformhttp.MaskURLError(err).Error()
+}
+
+// webhookFleetDeletedError is returned when the fleet of a webhook delivery
+// was deleted since it was created.
+type webhookFleetDeletedError struct{}
+
+func (webhookFleetDeletedError) Error() string    { return "the fleet of the webhook was deleted" }
+func (webhookFleetDeletedError) IsNotFound() bool { return true }
+
+// webhookDeliverySettings returns the current secret and custom headers of the
+// webhook of the given type and fleet.
+func webhookDeliverySettings(ctx context.Context, ds fleet.Datastore, typ fleet.WebhookType, teamID *uint) (fleet.WebhookDeliverySettings, error) {
+	if teamID == nil {
+		appConfig, err := ds.AppConfig(ctx)
+		if err != nil {
+			return fleet.WebhookDeliverySettings{}, ctxerr.Wrap(ctx, err, "get app config")
+		}
+		webhooks := appConfig.WebhookSettings
+		switch typ {
+		case fleet.WebhookTypeActivities:
+			return webhooks.ActivitiesWebhook.WebhookDeliverySettings, nil
+		case fleet.WebhookTypeHostStatus:
+			return webhooks.HostStatusWebhook.WebhookDeliverySettings, nil
+		case fleet.WebhookTypeFailingPolicies:
+			return webhooks.FailingPoliciesWebhook.WebhookDeliverySettings, nil
+		case fleet.WebhookTypeVulnerabilities:
+			return webhooks.VulnerabilitiesWebhook.WebhookDeliverySettings, nil
+		case fleet.WebhookTypeGitOpsDrift:
+			return webhooks.GitOpsDriftWebhook.WebhookDeliverySettings, nil
+		}
+		return fleet.WebhookDeliverySettings{}, ctxerr.Errorf(ctx, "unsupported global webhook type %q", typ)
+	}
+
+	teams, err := ds.TeamLitesByIDs(ctx, []uint{*teamID})
+	if err != nil {
+		return fleet.WebhookDeliverySettings{}, ctxerr.Wrap(ctx, err, "get fleet")
+	}
+	if len(teams) == 0 {
+		return fleet.WebhookDeliverySettings{}, ctxerr.Wrap(ctx, webhookFleetDeletedError{})
+	}
+	webhooks := teams[0].Config.WebhookSettings
+	switch typ {
+	case fleet.WebhookTypeHostStatus:
+		if webhooks.HostStatusWebhook != nil {
+			return webhooks.HostStatusWebhook.WebhookDeliverySettings, nil
+		}
+		return fleet.WebhookDeliverySettings{}, nil
+	case fleet.WebhookTypeFailingPolicies:
+		return webhooks.FailingPoliciesWebhook.WebhookDeliverySettings, nil
+	case fleet.WebhookTypeHostActivities:
+		if webhooks.HostActivitiesWebhook != nil {
+			return webhooks.HostActivitiesWebhook.WebhookDeliverySettings, nil
+		}
+		return fleet.WebhookDeliverySettings{}, nil
+	}
+	return fleet.WebhookDeliverySettings{}, ctxerr.Errorf(ctx, "unsupported fleet webhook type %q", typ)
+}
+
+// WebhookDelivery is the job retrying the webhook deliveries that failed with
+// a retryable error. The worker's backoff applies between attempts.
+type WebhookDelivery struct {
+	Datastore      fleet.Datastore
+	Log            *slog.Logger
+	NewActivitySvc activity_api.NewActivityService
+}
+
+// Name returns the name of the job.
+func (w *WebhookDelivery) Name() string {
+	return webhookDeliveryName
+}
+
+// Run retries the webhook delivery. It returns an error only if the delivery
+// failed with a retryable error.
+func (w *WebhookDelivery) Run(ctx context.Context, argsJSON json.RawMessage) error {
+	var args webhookDeliveryArgs
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal args")
+	}
+
+	d, err := w.Datastore.WebhookDelivery(ctx, args.DeliveryID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			// the delivery was cleaned up
+			return nil
+		}
+		return ctxerr.Wrap(ctx, err, "get webhook delivery")
+	}
+	if d.Status != fleet.WebhookDeliveryStatusPending {
+		return nil
+	}
+
+	err = attemptWebhookDelivery(ctx, w.Datastore, w.Log, d)
+	switch {
+	case err == nil:
+		w.recordRanAutomation(ctx, args.FailingPolicy)
+		return nil
+	case d.Status == fleet.WebhookDeliveryStatusPending:
+		return err
+	default:
+		w.Log.WarnContext(ctx, "webhook delivery failed", "delivery_id", d.ID, "err", err)
+		w.recordFailedAutomation(ctx, args.FailingPolicy, d)
+		return nil
+	}
+}
+
+// OnFinalFailure marks the delivery as failed once the worker has exhausted
+// all retries.
+func (w *WebhookDelivery) OnFinalFailure(ctx context.Context, argsJSON json.RawMessage, jobErr string) error {
+	var args webhookDeliveryArgs
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return ctxerr.Wrap(ctx, err, "unmarshal args")
+	}
+
+	d, err := w.Datastore.WebhookDelivery(ctx, args.DeliveryID)
+	if err != nil {
+		if fleet.IsNotFound(err) {
+			return nil
+		}
+		return ctxerr.Wrap(ctx, err, "get webhook delivery")
+	}
+	if d.Status != fleet.WebhookDeliveryStatusPending {
+		return nil
+	}
+	d.Status = fleet.WebhookDeliveryStatusFailed
+	if err := w.Datastore.UpdateWebhookDelivery(ctx, d); err != nil {
+		return ctxerr.Wrap(ctx, err, "save webhook delivery")
+	}
+	w.recordFailedAutomation(ctx, args.FailingPolicy, d)
+	return nil
+}
+
+func (w *WebhookDelivery) recordRanAutomation(ctx context.Context, fp *WebhookDeliveryFailingPolicy) {
+	if fp == nil {
+		return
+	}
+	if err := w.NewActivitySvc.NewActivity(ctx, nil, fleet.ActivityTypeRanAutomationWebhook{
+		PolicyID:   fp.PolicyID,
+		HostIDList: fp.HostIDs,
+	}); err != nil {
+		w.Log.WarnContext(ctx, "failed to record webhook policy automation activity",
+			"policy_id", fp.PolicyID, "err", err)
+	}
+}
+
+func (w *WebhookDelivery) recordFailedAutomation(ctx context.Context, fp *WebhookDeliveryFailingPolicy, d *fleet.WebhookDelivery) {
+	if fp == nil {
+		return
+	}
+	if err := w.NewActivitySvc.NewActivity(ctx, nil, fleet.ActivityTypeFailedAutomationWebhook{
+		PolicyID:      fp.PolicyID,
+		HostIDList:    fp.HostIDs,
+		StatusCode:    ptr.ValOrZero(d.ResponseStatusCode),
+		ErrorResponse: d.Error,
+	}); err != nil {
+		w.Log.WarnContext(ctx, "failed to record webhook policy automation failure activity",
+			"policy_id", fp.PolicyID, "err", err)
+	}
+}
@@ -0,0 +1,184 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	activity_api "github.com/fleetdm/fleet/v4/server/activity/api"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	platformhttp "github.com/fleetdm/fleet/v4/server/platform/http"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDelivery(t *testing.T) {
+	ctx := t.Context()
+	logger := slog.New(slog.DiscardHandler)
+
+	status := http.StatusOK
+	var gotHeader http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	ds := new(mock.Store)
+	deliveries := map[uint]*fleet.WebhookDelivery{}
+	ds.NewWebhookDeliveryFunc = func(ctx context.Context, d *fleet.WebhookDelivery) (*fleet.WebhookDelivery, error) {
+		d.ID = uint(len(deliveries) + 1) //nolint:gosec // dismiss G115
+		d.Status = fleet.WebhookDeliveryStatusPending
+		stored := *d
+		deliveries[d.ID] = &stored
+		return d, nil
+	}
+	ds.UpdateWebhookDeliveryFunc = func(ctx context.Context, d *fleet.WebhookDelivery) error {
+		stored := *d
+		deliveries[d.ID] = &stored
+		return nil
+	}
+	ds.WebhookDeliveryFunc = func(ctx context.Context, id uint) (*fleet.WebhookDelivery, error) {
+		stored := *deliveries[id]
+		return &stored, nil
+	}
+	var jobs []*fleet.Job
+	ds.NewJobFunc = func(ctx context.Context, job *fleet.Job) (*fleet.Job, error) {
+		jobs = append(jobs, job)
+		return job, nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.TeamLitesByIDsFunc = func(ctx context.Context, ids []uint) ([]*fleet.TeamLite, error) {
+		if ids[0] == 1 {
+			tm := &fleet.TeamLite{ID: 1}
+			tm.Config.WebhookSettings.FailingPoliciesWebhook.Secret = "s3cr3t"
+			tm.Config.WebhookSettings.FailingPoliciesWebhook.Headers = map[string]string{"Authorization": "Bearer token"}
+			return []*fleet.TeamLite{tm}, nil
+		}
+		return nil, nil
+	}
+
+	var activities []fleet.ActivityDetails
+	job := &WebhookDelivery{
+		Datastore: ds,
+		Log:       logger,
+		NewActivitySvc: &mock.MockActivityService{NewActivityFunc: func(_ context.Context, _ *activity_api.User, activity fleet.ActivityDetails) error {
+			activities = append(activities, activity)
+			return nil
+		}},
+	}
+
+	req := WebhookDeliveryRequest{
+		Type:          fleet.WebhookTypeFailingPolicies,
+		TeamID:        ptr.Uint(1),
+		URL:           srv.URL,
+		Payload:       json.RawMessage(`{"a":1}`),
+		FailingPolicy: &WebhookDeliveryFailingPolicy{PolicyID: 3, HostIDs: []uint{4, 5}},
+	}
+
+	t.Run("delivered with the fleet's secret and headers", func(t *testing.T) {
+		status = http.StatusOK
+		d, err := DeliverWebhook(ctx, ds, logger, req)
+		require.NoError(t, err)
+		assert.Equal(t, fleet.WebhookDeliveryStatusSuccess, d.Status)
+		assert.EqualValues(t, 1, d.Attempts)
+		assert.Equal(t, ptr.Int(http.StatusOK), d.ResponseStatusCode)
+		assert.Empty(t, jobs)
+
+		assert.Equal(t, "Bearer token", gotHeader.Get("Authorization"))
+		require.NoError(t, platformhttp.VerifyWebhookSignature("s3cr3t", gotHeader.Get(platformhttp.WebhookSignatureHeader), req.Payload, 0, *d.LastAttemptAt))
+	})
+
+	t.Run("retryable failure is queued then succeeds", func(t *testing.T) {
+		jobs, activities = nil, nil
+		status = http.StatusServiceUnavailable
+		d, err := DeliverWebhook(ctx, ds, logger, req)
+		require.NoError(t, err)
+		assert.Equal(t, fleet.WebhookDeliveryStatusPending, d.Status)
+		assert.Equal(t, ptr.Int(http.StatusServiceUnavailable), d.ResponseStatusCode)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, webhookDeliveryName, jobs[0].Name)
+
+		// still failing: the job returns an error so the worker retries it
+		err = job.Run(ctx, *jobs[0].Args)
+		require.Error(t, err)
+		assert.EqualValues(t, 2, deliveries[d.ID].Attempts)
+		assert.Empty(t, activities)
+
+		status = http.StatusOK
+		require.NoError(t, job.Run(ctx, *jobs[0].Args))
+		assert.Equal(t, fleet.WebhookDeliveryStatusSuccess, deliveries[d.ID].Status)
+		assert.EqualValues(t, 3, deliveries[d.ID].Attempts)
+		require.Equal(t, []fleet.ActivityDetails{fleet.ActivityTypeRanAutomationWebhook{
+			PolicyID:   3,
+			HostIDList: []uint{4, 5},
+		}}, activities)
+
+		// already delivered, not attempted again
+		require.NoError(t, job.Run(ctx, *jobs[0].Args))
+		assert.EqualValues(t, 3, deliveries[d.ID].Attempts)
+	})
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		jobs, activities = nil, nil
+		status = http.StatusBadGateway
+		d, err := DeliverWebhook(ctx, ds, logger, req)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+
+		require.NoError(t, job.OnFinalFailure(ctx, *jobs[0].Args, "failed"))
+		assert.Equal(t, fleet.WebhookDeliveryStatusFailed, deliveries[d.ID].Status)
+		require.Equal(t, []fleet.ActivityDetails{fleet.ActivityTypeFailedAutomationWebhook{
+			PolicyID:      3,
+			HostIDList:    []uint{4, 5},
+			StatusCode:    http.StatusBadGateway,
+			ErrorResponse: http.StatusText(http.StatusBadGateway),
+		}}, activities)
+	})
+
+	t.Run("permanent failure is not retried", func(t *testing.T) {
+		jobs = nil
+		status = http.StatusUnauthorized
+		d, err := DeliverWebhook(ctx, ds, logger, req)
+		require.Error(t, err)
+		assert.Equal(t, fleet.WebhookDeliveryStatusFailed, d.Status)
+		assert.Empty(t, jobs)
+	})
+
+	t.Run("deleted fleet", func(t *testing.T) {
+		jobs = nil
+		status = http.StatusOK
+		r := req
+		r.TeamID = ptr.Uint(2)
+		d, err := DeliverWebhook(ctx, ds, logger, r)
+		require.Error(t, err)
+		assert.Equal(t, fleet.WebhookDeliveryStatusFailed, d.Status)
+		assert.Contains(t, d.Error, "deleted")
+		assert.Empty(t, jobs)
+	})
+
+	t.Run("redelivery", func(t *testing.T) {
+		status = http.StatusOK
+		orig, err := DeliverWebhook(ctx, ds, logger, WebhookDeliveryRequest{
+			Type:    fleet.WebhookTypeActivities,
+			URL:     srv.URL,
+			Payload: json.RawMessage(`{"b":2}`),
+		})
+		require.NoError(t, err)
+
+		status = http.StatusNotFound
+		d, err := RedeliverWebhook(ctx, ds, logger, orig.ID)
+		require.NoError(t, err)
+		assert.NotEqual(t, orig.ID, d.ID)
+		assert.Equal(t, &orig.ID, d.RedeliveryOfID)
+		assert.Equal(t, fleet.WebhookDeliveryStatusFailed, d.Status)
+		assert.Empty(t, gotHeader.Get(platformhttp.WebhookSignatureHeader))
+	})
+}
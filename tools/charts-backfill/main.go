@@ -43,7 +43,10 @@ import (
 // dataset (see server/chart/datasets.go) so backfilled data is shaped like
 // what production will eventually produce.
 var snapshotDatasets = map[string]struct{}{
-	"cve": {},
+	"cve":             {},
+	"policy":          {},
+	"mdm_enrollment":  {},
+	"disk_encryption": {},
 }
 
 // scdOpenSentinel mirrors the constant in server/chart/internal/mysql/data.go.
@@ -278,6 +278,7 @@ var activityTemplatesByCategory = map[string][]fleet.ActivityDetails{
 		fleet.ActivityTypeDisabledGitOpsMode{},
 		fleet.ActivityTypeEnabledGitOpsException{},
 		fleet.ActivityTypeDisabledGitOpsException{},
+		fleet.ActivityTypeDetectedGitOpsDrift{},
 		fleet.ActivityTypeEnabledHistoricalDataset{},
 		fleet.ActivityTypeDisabledHistoricalDataset{},
 		fleet.ActivityTypeEnabledMacosSetupEndUserAuth{},
@@ -354,6 +355,13 @@ var activityTemplatesByCategory = map[string][]fleet.ActivityDetails{
 		fleet.ActivityEditedAppStoreApp{},
 		fleet.ActivityTypeCanceledInstallAppStoreApp{},
 		fleet.ActivityTypeCanceledSetupExperience{},
+		fleet.ActivityTypeAddedVEXDocument{},
+		fleet.ActivityTypeDeletedVEXDocument{},
+		fleet.ActivityTypeCreatedVulnerabilityException{},
+		fleet.ActivityTypeEditedVulnerabilityException{},
+		fleet.ActivityTypeDeletedVulnerabilityException{},
+		fleet.ActivityTypeExpiredVulnerabilityException{},
+		fleet.ActivityTypeRedeliveredWebhook{},
 	},
 	CategoryHosts: {
 		fleet.ActivityTypeDeletedHost{},
@@ -389,6 +397,10 @@ var activityTemplatesByCategory = map[string][]fleet.ActivityDetails{
 		fleet.ActivityTypeDeletedUserGlobalRole{},
 		fleet.ActivityTypeChangedUserTeamRole{},
 		fleet.ActivityTypeDeletedUserTeamRole{},
+		fleet.ActivityTypeCreatedCustomRole{},
+		fleet.ActivityTypeEditedCustomRole{},
+		fleet.ActivityTypeDeletedCustomRole{},
+		fleet.ActivityTypeChangedUserCustomRoles{},
 	},
 	CategoryTeams: {
 		fleet.ActivityTypeCreatedTeam{},
@@ -125,6 +125,12 @@ export const CRONS: CronInfo[] = [
     interval: "5m",
     note: "Marks batch activities as completed.",
   },
+  {
+    name: "gitops_drift",
+    group: "maintenance",
+    interval: "1h",
+    note: "Reports changes made outside of GitOps since the last apply.",
+  },
   {
     name: "upcoming_activities_maintenance",
     group: "maintenance",